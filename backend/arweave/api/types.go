@@ -0,0 +1,109 @@
+// Package api has type definitions for Arweave gateways, bundlers and
+// path manifests.
+package api
+
+import (
+	"fmt"
+)
+
+// Manifest constants
+const (
+	ManifestType        = "arweave/paths"
+	ManifestVersion     = "0.2.0"
+	ManifestContentType = "application/x.arweave-manifest+json"
+)
+
+// Manifest is an Arweave path manifest mapping paths to transactions
+//
+// Rclone stores the size, modification time and hashes of each path in
+// the Rclone section which gateways ignore.
+type Manifest struct {
+	Manifest string                  `json:"manifest"`
+	Version  string                  `json:"version"`
+	Index    *ManifestIndex          `json:"index,omitempty"`
+	Paths    map[string]ManifestPath `json:"paths"`
+	Rclone   map[string]FileInfo     `json:"rclone,omitempty"`
+}
+
+// ManifestIndex is the path served for the root of the manifest
+type ManifestIndex struct {
+	Path string `json:"path"`
+}
+
+// ManifestPath is the transaction a path of a manifest points to
+type ManifestPath struct {
+	ID string `json:"id"`
+}
+
+// FileInfo is what rclone stores about each path in a manifest
+type FileInfo struct {
+	Size    int64             `json:"size"`
+	ModTime int64             `json:"mtime"` // Unix time in nanoseconds
+	Hashes  map[string]string `json:"hashes,omitempty"`
+}
+
+// Tag is a name value pair attached to a transaction
+type Tag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// UploadResponse is returned by a bundler after posting a data item
+type UploadResponse struct {
+	ID        string `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Owner     string `json:"owner"`
+}
+
+// GraphQLRequest is a query for the GraphQL endpoint of a gateway
+type GraphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+// GraphQLTagFilter selects transactions with a tag
+type GraphQLTagFilter struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// GraphQLResponse is the response to a GraphQLRequest for transactions
+type GraphQLResponse struct {
+	Data struct {
+		Transactions struct {
+			Edges []struct {
+				Node Transaction `json:"node"`
+			} `json:"edges"`
+		} `json:"transactions"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Transaction is a transaction or data item returned by GraphQL
+type Transaction struct {
+	ID   string `json:"id"`
+	Tags []Tag  `json:"tags"`
+}
+
+// Tag returns the value of the named tag or "" if not found
+func (t *Transaction) Tag(name string) string {
+	for _, tag := range t.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+// Error is returned by gateways and bundlers
+type Error struct {
+	Message    string
+	StatusCode int
+}
+
+// Error satisfies the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.StatusCode)
+}
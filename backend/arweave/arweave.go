@@ -0,0 +1,1119 @@
+// Package arweave provides an interface to the Arweave permanent
+// storage network.
+package arweave
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/arweave/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/batcher"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/env"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep        = 10 * time.Millisecond
+	maxSleep        = 2 * time.Second
+	decayConstant   = 2                // bigger for slower decay, exponential
+	memoryThreshold = 16 * 1024 * 1024 // files bigger than this are spooled to disk
+	tempFilePrefix  = "rclone-arweave-"
+	appName         = "rclone"
+	maxManifests    = 100 // number of manifests to look through for the latest
+)
+
+// Tags written to data items
+const (
+	tagContentType = "Content-Type"
+	tagAppName     = "App-Name"
+	tagManifest    = "Rclone-Manifest"
+	tagSequence    = "Rclone-Sequence"
+)
+
+// hashes stored in the manifest for each file
+var supportedHashes = hash.NewHashSet(hash.MD5, hash.SHA256)
+
+var (
+	errorReadOnly = errors.New("arweave remote is read only as no wallet is configured or manifest_id is set")
+
+	// Configure the batcher
+	defaultBatcherOptions = batcher.Options{
+		MaxBatchSize:          10000,
+		DefaultTimeoutSync:    500 * time.Millisecond,
+		DefaultTimeoutAsync:   10 * time.Second,
+		DefaultBatchSizeAsync: 1000,
+	}
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "arweave",
+		Description: "Arweave",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		Options: append([]fs.Option{{
+			Name: "wallet_file",
+			Help: `Path to the Arweave wallet file.
+
+This is the JSON keyfile of the wallet used to sign uploads and to
+find the manifests written by rclone.
+
+Leave blank to use wallet or to read a manifest set with manifest_id.`,
+		}, {
+			Name: "wallet",
+			Help: `Arweave wallet JSON blob.
+
+Leave blank normally. Needed only if you want to use a wallet
+instead of a wallet file.`,
+			Sensitive: true,
+		}, {
+			Name: "gateway",
+			Help: `URL of the Arweave gateway.
+
+This is used to read files and manifests and to find the latest
+manifest with GraphQL.`,
+			Default: "https://arweave.net",
+		}, {
+			Name: "upload_url",
+			Help: `URL to post signed data items to.
+
+The bundler must be funded for the wallet for uploads over its free
+limit.`,
+			Default: "https://upload.ardrive.io/v1/tx",
+			Examples: []fs.OptionExample{{
+				Value: "https://upload.ardrive.io/v1/tx",
+				Help:  "ArDrive Turbo",
+			}, {
+				Value: "https://node1.irys.xyz/tx/arweave",
+				Help:  "Irys (formerly Bundlr)",
+			}},
+		}, {
+			Name: "manifest_name",
+			Help: `Name of the manifest to use.
+
+Each wallet can hold several independent trees of files, each
+stored in its own chain of manifests with a different name.`,
+			Default:  "rclone",
+			Advanced: true,
+		}, {
+			Name: "manifest_id",
+			Help: `ID of a manifest to read.
+
+If set, the files in this manifest are read and the remote is read
+only. Use this to read a manifest written by another wallet or tool.`,
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			Default: encoder.Base |
+				encoder.EncodeInvalidUtf8,
+		}}, defaultBatcherOptions.FsOptions("Each batch of files is published as one new manifest.\n\n")...),
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	WalletFile   string               `config:"wallet_file"`
+	Wallet       string               `config:"wallet"`
+	Gateway      string               `config:"gateway"`
+	UploadURL    string               `config:"upload_url"`
+	ManifestName string               `config:"manifest_name"`
+	ManifestID   string               `config:"manifest_id"`
+	Enc          encoder.MultiEncoder `config:"encoding"`
+	BatchMode    string               `config:"batch_mode"`
+	BatchSize    int                  `config:"batch_size"`
+	BatchTimeout fs.Duration          `config:"batch_timeout"`
+}
+
+// Fs represents a path in an Arweave manifest
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on if any
+	opt      Options      // parsed config options
+	features *fs.Features // optional features
+	gw       *rest.Client // the connection to the gateway
+	up       *rest.Client // the connection to the bundler
+	pacer    *fs.Pacer    // pacer for API calls
+	wallet   *wallet      // the wallet signing uploads, nil if read only
+	batcher  *batcher.Batcher[int64, struct{}]
+
+	state *manifestState // the manifest shared with other Fs using it
+}
+
+// manifestState is the manifest shared by all the Fs using it, so
+// changes made through one are seen by the others and are published
+// in the same manifest.
+type manifestState struct {
+	publishMu sync.Mutex // held while loading or publishing the manifest
+	loaded    bool       // set once the manifest has been loaded
+
+	mu         sync.Mutex
+	manifest   *api.Manifest       // the current manifest
+	id         string              // ID of the last manifest published
+	sequence   int64               // sequence number of the last manifest published
+	generation int64               // incremented on every change to manifest
+	published  int64               // generation of the last manifest published
+	dirs       map[string]struct{} // directories made with Mkdir by path
+}
+
+var (
+	statesMu sync.Mutex
+	states   = map[string]*manifestState{} // manifest states by key
+)
+
+// getState returns the manifest state for key, making it if needed
+func getState(key string) *manifestState {
+	statesMu.Lock()
+	defer statesMu.Unlock()
+	state, found := states[key]
+	if !found {
+		state = &manifestState{
+			manifest: newManifest(),
+			dirs:     make(map[string]struct{}),
+		}
+		states[key] = state
+	}
+	return state
+}
+
+// Object describes a file in an Arweave manifest
+type Object struct {
+	fs     *Fs
+	remote string
+	id     string       // transaction or data item ID of the file
+	info   api.FileInfo // size is -1 if not known
+}
+
+// change is a change to a path of the manifest
+type change struct {
+	path   string        // encoded path in the manifest
+	id     string        // ID to point the path at, "" to delete it
+	info   *api.FileInfo // what rclone knows about the file
+	remove bool          // set to remove the path
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	if f.opt.ManifestID != "" {
+		return fmt.Sprintf("Arweave manifest %s path %q", f.opt.ManifestID, f.root)
+	}
+	return fmt.Sprintf("Arweave manifest %q path %q", f.opt.ManifestName, f.root)
+}
+
+// Precision of the modification times stored in the manifest
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the hashes stored in the manifest
+func (f *Fs) Hashes() hash.Set {
+	return supportedHashes
+}
+
+// Features for this fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// errorHandler parses a non 2xx error response
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("error when trying to read error body: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fs.ErrorObjectNotFound
+	}
+	return &api.Error{
+		Message:    strings.TrimSpace(string(body)),
+		StatusCode: resp.StatusCode,
+	}
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// manifestPath returns the encoded path of remote in the manifest
+func (f *Fs) manifestPath(remote string) string {
+	return f.opt.Enc.FromStandardPath(path.Join(f.root, remote))
+}
+
+// newManifest returns an empty manifest
+func newManifest() *api.Manifest {
+	return &api.Manifest{
+		Manifest: api.ManifestType,
+		Version:  api.ManifestVersion,
+		Paths:    map[string]api.ManifestPath{},
+		Rclone:   map[string]api.FileInfo{},
+	}
+}
+
+// cloneManifest returns a copy of m which can be changed independently
+func cloneManifest(m *api.Manifest) *api.Manifest {
+	c := newManifest()
+	c.Index = m.Index
+	for p, entry := range m.Paths {
+		c.Paths[p] = entry
+	}
+	for p, info := range m.Rclone {
+		c.Rclone[p] = info
+	}
+	return c
+}
+
+// findManifest returns the ID and sequence number of the latest
+// manifest published by the wallet, or "" if there isn't one
+func (f *Fs) findManifest(ctx context.Context) (id string, sequence int64, err error) {
+	request := api.GraphQLRequest{
+		Query: `query($owners: [String!], $tags: [TagFilter!], $first: Int) {
+  transactions(owners: $owners, tags: $tags, first: $first, sort: HEIGHT_DESC) {
+    edges { node { id tags { name value } } }
+  }
+}`,
+		Variables: map[string]any{
+			"owners": []string{f.wallet.address},
+			"tags": []api.GraphQLTagFilter{
+				{Name: tagAppName, Values: []string{appName}},
+				{Name: tagManifest, Values: []string{f.opt.ManifestName}},
+			},
+			"first": maxManifests,
+		},
+	}
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/graphql",
+	}
+	var result api.GraphQLResponse
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err := f.gw.CallJSON(ctx, &opts, &request, &result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to query manifests: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		return "", 0, fmt.Errorf("failed to query manifests: %s", result.Errors[0].Message)
+	}
+	// Manifests in the same block or still pending aren't ordered
+	// so use the highest sequence number
+	sequence = -1
+	for _, edge := range result.Data.Transactions.Edges {
+		n, err := strconv.ParseInt(edge.Node.Tag(tagSequence), 10, 64)
+		if err != nil {
+			fs.Debugf(f, "Ignoring manifest %s with bad sequence: %v", edge.Node.ID, err)
+			continue
+		}
+		if n > sequence {
+			id, sequence = edge.Node.ID, n
+		}
+	}
+	if id == "" {
+		return "", 0, nil
+	}
+	return id, sequence, nil
+}
+
+// readManifest reads the manifest with id
+func (f *Fs) readManifest(ctx context.Context, id string) (*api.Manifest, error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   "/raw/" + id,
+	}
+	var m api.Manifest
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.gw.CallJSON(ctx, &opts, nil, &m)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", id, err)
+	}
+	if m.Manifest != api.ManifestType {
+		return nil, fmt.Errorf("%s is not a path manifest", id)
+	}
+	if m.Paths == nil {
+		m.Paths = map[string]api.ManifestPath{}
+	}
+	if m.Rclone == nil {
+		m.Rclone = map[string]api.FileInfo{}
+	}
+	return &m, nil
+}
+
+// loadManifest reads the latest manifest if it hasn't been read yet
+func (f *Fs) loadManifest(ctx context.Context) error {
+	f.state.publishMu.Lock()
+	defer f.state.publishMu.Unlock()
+	if f.state.loaded {
+		return nil
+	}
+	id, sequence := f.opt.ManifestID, int64(0)
+	if id == "" {
+		var err error
+		id, sequence, err = f.findManifest(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	m := newManifest()
+	if id != "" {
+		var err error
+		m, err = f.readManifest(ctx, id)
+		if err != nil {
+			return err
+		}
+		fs.Debugf(f, "Read manifest %s with %d paths", id, len(m.Paths))
+	}
+	f.state.mu.Lock()
+	f.state.manifest = m
+	f.state.id = id
+	f.state.sequence = sequence
+	f.state.mu.Unlock()
+	f.state.loaded = true
+	return nil
+}
+
+// spool reads in into memory or a temporary file, computing the
+// hashes of it, so it can be read again after signing
+//
+// The cleanup function should be called when out is finished with
+// regardless of whether this function returned an error or not.
+func spool(in io.Reader, size int64) (out io.ReaderAt, n int64, sum384 []byte, hashes map[hash.Type]string, cleanup func(), err error) {
+	// nothing to clean up by default
+	cleanup = func() {}
+
+	hasher, err := hash.NewMultiHasherTypes(supportedHashes)
+	if err != nil {
+		return nil, 0, nil, nil, cleanup, err
+	}
+	h384 := sha512.New384()
+	in = io.TeeReader(in, io.MultiWriter(hasher, h384))
+
+	// don't spool small files to disk
+	if size >= 0 && size <= memoryThreshold {
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return nil, 0, nil, nil, cleanup, err
+		}
+		return bytes.NewReader(data), int64(len(data)), h384.Sum(nil), hasher.Sums(), cleanup, nil
+	}
+	tempFile, err := os.CreateTemp("", tempFilePrefix)
+	if err != nil {
+		return nil, 0, nil, nil, cleanup, err
+	}
+	_ = os.Remove(tempFile.Name()) // Delete the file - may not work on Windows
+	cleanup = func() {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name()) // may be deleted already
+	}
+	n, err = io.Copy(tempFile, in)
+	if err != nil {
+		return nil, 0, nil, nil, cleanup, err
+	}
+	return tempFile, n, h384.Sum(nil), hasher.Sums(), cleanup, nil
+}
+
+// upload signs size bytes of in as a data item with tags and posts it
+// to the bundler returning its ID.
+func (f *Fs) upload(ctx context.Context, tags []api.Tag, in io.ReaderAt, size int64, sum384 []byte) (string, error) {
+	if f.wallet == nil {
+		return "", errorReadOnly
+	}
+	tags = append(tags, api.Tag{Name: tagAppName, Value: appName})
+	item, err := f.wallet.signDataItem(tags, sum384, size)
+	if err != nil {
+		return "", err
+	}
+	length := int64(len(item.header)) + size
+	opts := rest.Opts{
+		Method:        "POST",
+		RootURL:       f.opt.UploadURL,
+		ContentType:   "application/octet-stream",
+		ContentLength: &length,
+	}
+	var result api.UploadResponse
+	err = f.pacer.Call(func() (bool, error) {
+		opts.Body = io.MultiReader(bytes.NewReader(item.header), io.NewSectionReader(in, 0, size))
+		resp, err := f.up.CallJSON(ctx, &opts, nil, &result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload data item: %w", err)
+	}
+	if result.ID != "" && result.ID != item.id {
+		return "", fmt.Errorf("bundler returned ID %q for data item %q", result.ID, item.id)
+	}
+	return item.id, nil
+}
+
+// apply changes to the in memory manifest returning the generation
+// which needs publishing for them to persist
+func (f *Fs) apply(changes ...change) int64 {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+	for _, c := range changes {
+		if c.remove {
+			delete(f.state.manifest.Paths, c.path)
+			delete(f.state.manifest.Rclone, c.path)
+			// Keep the directories the file was in until they are removed
+			for dir := path.Dir(f.opt.Enc.ToStandardPath(c.path)); dir != "."; dir = path.Dir(dir) {
+				f.state.dirs[dir] = struct{}{}
+			}
+			continue
+		}
+		f.state.manifest.Paths[c.path] = api.ManifestPath{ID: c.id}
+		if c.info != nil {
+			f.state.manifest.Rclone[c.path] = *c.info
+		} else {
+			delete(f.state.manifest.Rclone, c.path)
+		}
+	}
+	f.state.generation++
+	return f.state.generation
+}
+
+// commit makes sure generation of the manifest gets published, waiting
+// for it unless batching asynchronously
+func (f *Fs) commit(ctx context.Context, name string, generation int64) error {
+	if f.batcher.Batching() {
+		_, err := f.batcher.Commit(ctx, name, generation)
+		return err
+	}
+	return f.publish(ctx)
+}
+
+// commitBatch publishes the manifest for a batch of changes
+func (f *Fs) commitBatch(ctx context.Context, generations []int64, results []struct{}, errors []error) error {
+	return f.publish(ctx)
+}
+
+// publish uploads the current manifest if it has changed since it
+// was last published
+func (f *Fs) publish(ctx context.Context) error {
+	f.state.publishMu.Lock()
+	defer f.state.publishMu.Unlock()
+
+	f.state.mu.Lock()
+	generation := f.state.generation
+	if generation == f.state.published {
+		f.state.mu.Unlock()
+		return nil
+	}
+	m := cloneManifest(f.state.manifest)
+	sequence := f.state.sequence + 1
+	f.state.mu.Unlock()
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	sum384 := sha512.Sum384(data)
+	id, err := f.upload(ctx, []api.Tag{
+		{Name: tagContentType, Value: api.ManifestContentType},
+		{Name: tagManifest, Value: f.opt.ManifestName},
+		{Name: tagSequence, Value: strconv.FormatInt(sequence, 10)},
+	}, bytes.NewReader(data), int64(len(data)), sum384[:])
+	if err != nil {
+		return fmt.Errorf("failed to publish manifest: %w", err)
+	}
+	fs.Debugf(f, "Published manifest %s with %d paths", id, len(m.Paths))
+
+	f.state.mu.Lock()
+	f.state.id = id
+	f.state.sequence = sequence
+	f.state.published = generation
+	f.state.mu.Unlock()
+	return nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	prefix := path.Join(f.root, dir)
+	if prefix != "" {
+		prefix += "/"
+	}
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+	dirs := make(map[string]struct{})
+	for manifestPath, entry := range f.state.manifest.Paths {
+		filePath := f.opt.Enc.ToStandardPath(manifestPath)
+		if !strings.HasPrefix(filePath, prefix) {
+			continue
+		}
+		name, _, isDir := strings.Cut(filePath[len(prefix):], "/")
+		remote := path.Join(dir, name)
+		if isDir {
+			if _, found := dirs[remote]; !found {
+				dirs[remote] = struct{}{}
+				entries = append(entries, fs.NewDir(remote, time.Time{}))
+			}
+			continue
+		}
+		entries = append(entries, f.newObject(remote, entry.ID, manifestPath))
+	}
+	if _, found := f.state.dirs[path.Join(f.root, dir)]; len(entries) == 0 && prefix != "" && !found {
+		return nil, fs.ErrorDirNotFound
+	}
+	return entries, nil
+}
+
+// newObject makes an Object from the manifest entry at manifestPath
+//
+// Call with f.state.mu held
+func (f *Fs) newObject(remote, id, manifestPath string) *Object {
+	info, found := f.state.manifest.Rclone[manifestPath]
+	if !found {
+		info.Size = -1
+	}
+	return &Object{
+		fs:     f,
+		remote: remote,
+		id:     id,
+		info:   info,
+	}
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	manifestPath := f.manifestPath(remote)
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+	entry, found := f.state.manifest.Paths[manifestPath]
+	if !found {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return f.newObject(remote, entry.ID, manifestPath), nil
+}
+
+// Put the object into the manifest
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	err := o.Update(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// PutStream uploads to the manifest with an unknown size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory
+//
+// Directories only exist as paths of files in the manifest, so it is
+// just remembered until a file is put in it.
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+	for dirPath := path.Join(f.root, dir); dirPath != "." && dirPath != ""; dirPath = path.Dir(dirPath) {
+		f.state.dirs[dirPath] = struct{}{}
+	}
+	return nil
+}
+
+// Rmdir deletes the directory
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	entries, err := f.List(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	f.state.mu.Lock()
+	delete(f.state.dirs, path.Join(f.root, dir))
+	f.state.mu.Unlock()
+	return nil
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// This points the new path at the data of the source in the manifest.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	if f.wallet == nil {
+		return nil, errorReadOnly
+	}
+	o := &Object{
+		fs:     f,
+		remote: remote,
+		id:     srcObj.id,
+		info:   srcObj.info,
+	}
+	err := f.commit(ctx, remote, f.apply(o.change()))
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	if f.wallet == nil || srcObj.fs.wallet == nil {
+		return nil, errorReadOnly
+	}
+	if srcObj.fs.state != f.state {
+		fs.Debugf(src, "Can't move - different manifest")
+		return nil, fs.ErrorCantMove
+	}
+	o := &Object{
+		fs:     f,
+		remote: remote,
+		id:     srcObj.id,
+		info:   srcObj.info,
+	}
+	remove := change{path: srcObj.fs.manifestPath(srcObj.remote), remove: true}
+	err := f.commit(ctx, remote, f.apply(remove, o.change()))
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	if f.wallet == nil || srcFs.wallet == nil {
+		return errorReadOnly
+	}
+	if srcFs.state != f.state {
+		fs.Debugf(srcFs, "Can't move directory - different manifest")
+		return fs.ErrorCantDirMove
+	}
+	srcPath := srcFs.manifestPath(srcRemote)
+	dstPath := f.manifestPath(dstRemote)
+
+	f.state.mu.Lock()
+	var removes, adds []change
+	for p, entry := range f.state.manifest.Paths {
+		rest, found := strings.CutPrefix(p, srcPath+"/")
+		if srcPath == "" {
+			rest, found = p, true
+		}
+		if !found {
+			continue
+		}
+		c := change{path: path.Join(dstPath, rest), id: entry.ID}
+		if info, found := f.state.manifest.Rclone[p]; found {
+			c.info = &info
+		}
+		removes = append(removes, change{path: p, remove: true})
+		adds = append(adds, c)
+	}
+	if len(adds) == 0 {
+		f.state.mu.Unlock()
+		return fs.ErrorDirNotFound
+	}
+	for p := range f.state.manifest.Paths {
+		if p == dstPath || strings.HasPrefix(p, dstPath+"/") {
+			f.state.mu.Unlock()
+			return fs.ErrorDirExists
+		}
+	}
+	f.state.mu.Unlock()
+
+	err := f.commit(ctx, dstRemote, f.apply(append(removes, adds...)...))
+	if err != nil {
+		return err
+	}
+	// The source directory is gone so forget any made with Mkdir
+	srcDir := path.Join(srcFs.root, srcRemote)
+	f.state.mu.Lock()
+	for dir := range f.state.dirs {
+		if dir == srcDir || strings.HasPrefix(dir, srcDir+"/") {
+			delete(f.state.dirs, dir)
+		}
+	}
+	f.state.mu.Unlock()
+	return nil
+}
+
+// Shutdown the backend, publishing any outstanding manifest changes.
+func (f *Fs) Shutdown(ctx context.Context) error {
+	f.batcher.Shutdown()
+	if f.wallet == nil {
+		return nil
+	}
+	return f.publish(ctx)
+}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "manifest":
+		if f.wallet != nil {
+			err = f.publish(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+		f.state.mu.Lock()
+		defer f.state.mu.Unlock()
+		if f.state.id == "" {
+			return nil, errors.New("no manifest has been published yet")
+		}
+		return map[string]string{
+			"id":  f.state.id,
+			"url": strings.TrimSuffix(f.opt.Gateway, "/") + "/" + f.state.id + "/",
+		}, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "manifest",
+	Short: "Show the ID of the current manifest.",
+	Long: `This publishes any outstanding changes then shows the ID of the
+manifest and the URL where the files can be read on the gateway.
+
+    rclone backend manifest remote:
+
+The manifest ID can be shared to give read access to the files with
+the manifest_id option, for example
+
+    rclone ls :arweave,manifest_id=ID:
+`,
+}}
+
+// NewFs constructs an Fs from the path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	root = strings.Trim(root, "/")
+	f := &Fs{
+		name: name,
+		opt:  *opt,
+		root: root,
+	}
+	f.pacer = fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant)))
+
+	// Read the wallet
+	walletJSON := []byte(opt.Wallet)
+	if opt.Wallet == "" && opt.WalletFile != "" {
+		walletJSON, err = os.ReadFile(env.ShellExpand(opt.WalletFile))
+		if err != nil {
+			return nil, fmt.Errorf("error opening wallet file: %w", err)
+		}
+	}
+	if len(walletJSON) > 0 && opt.ManifestID == "" {
+		f.wallet, err = parseWallet(walletJSON)
+		if err != nil {
+			return nil, err
+		}
+	} else if opt.ManifestID == "" {
+		return nil, errors.New("need a wallet or a manifest_id")
+	}
+
+	// Share the manifest with the other Fs using it
+	if f.wallet != nil {
+		f.state = getState(opt.Gateway + " " + f.wallet.address + " " + opt.ManifestName)
+	} else {
+		f.state = getState(opt.Gateway + " " + opt.ManifestID)
+	}
+
+	f.features = (&fs.Features{}).Fill(ctx, f)
+	if f.wallet == nil {
+		f.features.PutStream = nil
+		f.features.Copy = nil
+		f.features.Move = nil
+		f.features.DirMove = nil
+	}
+
+	client := fshttp.NewClient(ctx)
+	f.gw = rest.NewClient(client).SetRoot(strings.TrimSuffix(opt.Gateway, "/")).SetErrorHandler(errorHandler)
+	f.up = rest.NewClient(client).SetErrorHandler(errorHandler)
+
+	batcherOptions := defaultBatcherOptions
+	batcherOptions.Mode = f.opt.BatchMode
+	batcherOptions.Size = f.opt.BatchSize
+	batcherOptions.Timeout = time.Duration(f.opt.BatchTimeout)
+	f.batcher, err = batcher.New(ctx, f, f.commitBatch, batcherOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = f.loadManifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check to see if the root is actually an existing file
+	if root != "" {
+		remote := path.Base(root)
+		f.root = path.Dir(root)
+		if f.root == "." {
+			f.root = ""
+		}
+		_, err := f.NewObject(ctx, remote)
+		if err != nil {
+			// File doesn't exist so return old f
+			f.root = root
+			return f, nil
+		}
+		// return an error with an fs which points to the parent
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash of the object stored in the manifest
+func (o *Object) Hash(ctx context.Context, ty hash.Type) (string, error) {
+	if !supportedHashes.Contains(ty) {
+		return "", hash.ErrUnsupported
+	}
+	return o.info.Hashes[ty.String()], nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.info.Size
+}
+
+// ModTime returns the modification time stored in the manifest, or
+// the default time if there isn't one
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if o.info.ModTime == 0 {
+		return time.Time(fs.GetConfig(ctx).DefaultTime)
+	}
+	return time.Unix(0, o.info.ModTime)
+}
+
+// change returns the change to the manifest which stores the object
+func (o *Object) change() change {
+	info := o.info
+	return change{path: o.fs.manifestPath(o.remote), id: o.id, info: &info}
+}
+
+// SetModTime sets the modification time stored in the manifest
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	if o.fs.wallet == nil {
+		return errorReadOnly
+	}
+	o.info.ModTime = modTime.UnixNano()
+	return o.fs.commit(ctx, o.remote, o.fs.apply(o.change()))
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// ID returns the ID of the transaction or data item with the data
+func (o *Object) ID() string {
+	return o.id
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	if o.info.Size == 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	fs.FixRangeOption(options, o.info.Size)
+	opts := rest.Opts{
+		Method:  "GET",
+		Path:    "/raw/" + o.id,
+		Options: options,
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.gw.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The data is uploaded as a new data item and the manifest pointed at
+// it. The old data stays on Arweave.
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	if o.fs.wallet == nil {
+		return errorReadOnly
+	}
+	data, size, sum384, hashes, cleanup, err := spool(in, src.Size())
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+	id, err := o.fs.upload(ctx, []api.Tag{
+		{Name: tagContentType, Value: fs.MimeType(ctx, src)},
+	}, data, size, sum384)
+	if err != nil {
+		return err
+	}
+	o.id = id
+	o.info = api.FileInfo{
+		Size:    size,
+		ModTime: src.ModTime(ctx).UnixNano(),
+		Hashes:  make(map[string]string, len(hashes)),
+	}
+	for ty, sum := range hashes {
+		o.info.Hashes[ty.String()] = sum
+	}
+	return o.fs.commit(ctx, o.remote, o.fs.apply(o.change()))
+}
+
+// Remove an object from the manifest
+//
+// The data stays on Arweave.
+func (o *Object) Remove(ctx context.Context) error {
+	if o.fs.wallet == nil {
+		return errorReadOnly
+	}
+	return o.fs.commit(ctx, o.remote, o.fs.apply(change{path: o.fs.manifestPath(o.remote), remove: true}))
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.Copier      = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.DirMover    = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.Shutdowner  = (*Fs)(nil)
+	_ fs.Commander   = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+	_ fs.IDer        = (*Object)(nil)
+)
@@ -0,0 +1,527 @@
+package arweave
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/arweave/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testWalletOnce sync.Once
+	testWalletJSON []byte
+)
+
+// testWallet returns the JSON of a wallet made once per test run as
+// making 4096 bit keys is slow
+func testWallet(t *testing.T) []byte {
+	testWalletOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, ownerSize*8)
+		require.NoError(t, err)
+		enc := func(i interface{ Bytes() []byte }) string {
+			return b64.EncodeToString(i.Bytes())
+		}
+		testWalletJSON, err = json.Marshal(jwk{
+			Kty: "RSA",
+			N:   enc(key.N),
+			E:   b64.EncodeToString(binary.BigEndian.AppendUint32(nil, uint32(key.E))[1:]),
+			D:   enc(key.D),
+			P:   enc(key.Primes[0]),
+			Q:   enc(key.Primes[1]),
+		})
+		require.NoError(t, err)
+	})
+	return testWalletJSON
+}
+
+// fakeItem is a data item stored by fakeArweave
+type fakeItem struct {
+	owner string
+	tags  []api.Tag
+	data  []byte
+}
+
+// fakeArweave is a bundler and gateway which stores data items in memory
+type fakeArweave struct {
+	t     *testing.T
+	url   string
+	mu    sync.Mutex
+	items map[string]*fakeItem
+	order []string // ids in the order they were posted
+}
+
+func newFakeArweave(t *testing.T) *fakeArweave {
+	f := &fakeArweave{
+		t:     t,
+		items: make(map[string]*fakeItem),
+	}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	f.url = server.URL
+	return f
+}
+
+func (f *fakeArweave) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/tx":
+		f.serveUpload(w, r)
+	case r.Method == "POST" && r.URL.Path == "/graphql":
+		f.serveGraphQL(w, r)
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/raw/"):
+		f.mu.Lock()
+		item := f.items[strings.TrimPrefix(r.URL.Path, "/raw/")]
+		f.mu.Unlock()
+		if item == nil {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(item.data))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// decodeTags decodes Avro encoded tags
+func decodeTags(b []byte) (tags []api.Tag, err error) {
+	r := bytes.NewReader(b)
+	readLong := func() (int64, error) {
+		u, err := binary.ReadUvarint(r)
+		return int64(u>>1) ^ -int64(u&1), err
+	}
+	readString := func() (string, error) {
+		n, err := readLong()
+		if err != nil {
+			return "", err
+		}
+		buf := make([]byte, n)
+		_, err = io.ReadFull(r, buf)
+		return string(buf), err
+	}
+	for len(b) > 0 {
+		n, err := readLong()
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			break
+		}
+		for range n {
+			var tag api.Tag
+			if tag.Name, err = readString(); err != nil {
+				return nil, err
+			}
+			if tag.Value, err = readString(); err != nil {
+				return nil, err
+			}
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// parseDataItem parses and verifies a signed data item returning its id
+func parseDataItem(b []byte) (id string, item *fakeItem, err error) {
+	const fixed = 2 + 512 + 512 + 1 + 1 + 8 + 8
+	if len(b) < fixed {
+		return "", nil, errors.New("data item too short")
+	}
+	if binary.LittleEndian.Uint16(b) != signatureTypeArweave {
+		return "", nil, errors.New("bad signature type")
+	}
+	signature := b[2:514]
+	owner := b[514:1026]
+	if b[1026] != 0 || b[1027] != 0 {
+		return "", nil, errors.New("unexpected target or anchor")
+	}
+	nTags := binary.LittleEndian.Uint64(b[1028:])
+	nTagBytes := binary.LittleEndian.Uint64(b[1036:])
+	if uint64(len(b)-fixed) < nTagBytes {
+		return "", nil, errors.New("tags too long")
+	}
+	tagBytes := b[fixed : fixed+int(nTagBytes)]
+	data := b[fixed+int(nTagBytes):]
+	tags, err := decodeTags(tagBytes)
+	if err != nil {
+		return "", nil, err
+	}
+	if uint64(len(tags)) != nTags {
+		return "", nil, fmt.Errorf("expecting %d tags got %d", nTags, len(tags))
+	}
+	dataSum := sha512.Sum384(data)
+	message := deepHashList(
+		deepHashBytes([]byte("dataitem")),
+		deepHashBytes([]byte("1")),
+		deepHashBytes([]byte("1")),
+		deepHashBytes(owner),
+		deepHashBytes(nil),
+		deepHashBytes(nil),
+		deepHashBytes(tagBytes),
+		deepHashBlob(dataSum[:], int64(len(data))),
+	)
+	digest := sha256.Sum256(message)
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(owner), E: 65537}
+	err = rsa.VerifyPSS(pub, crypto.SHA256, digest[:], signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+	if err != nil {
+		return "", nil, fmt.Errorf("bad signature: %w", err)
+	}
+	idSum := sha256.Sum256(signature)
+	ownerSum := sha256.Sum256(owner)
+	return b64.EncodeToString(idSum[:]), &fakeItem{
+		owner: b64.EncodeToString(ownerSum[:]),
+		tags:  tags,
+		data:  bytes.Clone(data),
+	}, nil
+}
+
+func (f *fakeArweave) serveUpload(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, item, err := parseDataItem(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	if _, found := f.items[id]; !found {
+		f.order = append(f.order, id)
+	}
+	f.items[id] = item
+	f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(api.UploadResponse{ID: id, Owner: item.owner})
+}
+
+func (f *fakeArweave) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Variables struct {
+			Owners []string               `json:"owners"`
+			Tags   []api.GraphQLTagFilter `json:"tags"`
+			First  int                    `json:"first"`
+		} `json:"variables"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var response api.GraphQLResponse
+	edges := &response.Data.Transactions.Edges
+	f.mu.Lock()
+	// newest first
+	for i := len(f.order) - 1; i >= 0 && len(*edges) < request.Variables.First; i-- {
+		id := f.order[i]
+		item := f.items[id]
+		tx := api.Transaction{ID: id, Tags: item.tags}
+		match := len(request.Variables.Owners) == 0
+		for _, owner := range request.Variables.Owners {
+			match = match || owner == item.owner
+		}
+		for _, filter := range request.Variables.Tags {
+			match = match && tx.Tag(filter.Name) != "" && contains(filter.Values, tx.Tag(filter.Name))
+		}
+		if match {
+			*edges = append(*edges, struct {
+				Node api.Transaction `json:"node"`
+			}{Node: tx})
+		}
+	}
+	f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// forgetStates forgets the manifests read as if rclone was restarted
+func forgetStates() {
+	statesMu.Lock()
+	states = map[string]*manifestState{}
+	statesMu.Unlock()
+}
+
+// newTestFs makes an Fs pointing at fake
+func newTestFs(t *testing.T, fake *fakeArweave, m configmap.Simple) *Fs {
+	for _, opt := range fs.MustFind("arweave").Options {
+		if _, found := m.Get(opt.Name); !found && opt.String() != "" {
+			m.Set(opt.Name, opt.String())
+		}
+	}
+	m.Set("gateway", fake.url)
+	m.Set("upload_url", fake.url+"/tx")
+	if _, found := m.Get("manifest_id"); !found {
+		if _, found := m.Get("wallet_file"); !found {
+			m.Set("wallet", string(testWallet(t)))
+		}
+	}
+	f, err := NewFs(context.Background(), "arweave", "", m)
+	require.NoError(t, err)
+	return f.(*Fs)
+}
+
+func TestFakeArweave(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	fake := newFakeArweave(t)
+	name := "TestArweaveFake"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "arweave"},
+			{Name: name, Key: "wallet", Value: string(testWallet(t))},
+			{Name: name, Key: "gateway", Value: fake.url},
+			{Name: name, Key: "upload_url", Value: fake.url + "/tx"},
+		},
+		QuickTestOK: true,
+	})
+}
+
+func TestParseWallet(t *testing.T) {
+	w, err := parseWallet(testWallet(t))
+	require.NoError(t, err)
+	assert.Len(t, w.owner, ownerSize)
+	assert.Len(t, w.address, 43)
+
+	_, err = parseWallet([]byte(`{"kty":"EC"}`))
+	assert.ErrorContains(t, err, "RSA")
+	_, err = parseWallet([]byte(`{"kty":"RSA","n":"AQAB"}`))
+	assert.ErrorContains(t, err, "missing")
+
+	// 2048 bit keys aren't Arweave wallets
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	small, err := json.Marshal(jwk{
+		Kty: "RSA",
+		N:   b64.EncodeToString(key.N.Bytes()),
+		E:   "AQAB",
+		D:   b64.EncodeToString(key.D.Bytes()),
+		P:   b64.EncodeToString(key.Primes[0].Bytes()),
+		Q:   b64.EncodeToString(key.Primes[1].Bytes()),
+	})
+	require.NoError(t, err)
+	_, err = parseWallet(small)
+	assert.ErrorContains(t, err, "4096 bit")
+}
+
+func TestDeepHash(t *testing.T) {
+	// The deep hash of a list is chained from the deep hashes of
+	// its items
+	a, b := deepHashBytes([]byte("a")), deepHashBytes([]byte("b"))
+	assert.NotEqual(t, deepHashList(a, b), deepHashList(b, a))
+	assert.Len(t, deepHashList(), sha512.Size384)
+	sum := sha512.Sum384([]byte("a"))
+	assert.Equal(t, a, deepHashBlob(sum[:], 1))
+	assert.NotEqual(t, a, deepHashBlob(sum[:], 2))
+}
+
+func TestDataItem(t *testing.T) {
+	w, err := parseWallet(testWallet(t))
+	require.NoError(t, err)
+	tags := []api.Tag{
+		{Name: "Content-Type", Value: "text/plain"},
+		{Name: "Empty", Value: ""},
+	}
+	data := []byte("hello world")
+	sum := sha512.Sum384(data)
+	item, err := w.signDataItem(tags, sum[:], int64(len(data)))
+	require.NoError(t, err)
+
+	id, got, err := parseDataItem(append(bytes.Clone(item.header), data...))
+	require.NoError(t, err)
+	assert.Equal(t, item.id, id)
+	assert.Equal(t, w.address, got.owner)
+	assert.Equal(t, tags, got.tags)
+	assert.Equal(t, data, got.data)
+
+	// Changing the data must break the signature
+	_, _, err = parseDataItem(append(bytes.Clone(item.header), "hello World"...))
+	assert.ErrorContains(t, err, "bad signature")
+
+	// No tags
+	item, err = w.signDataItem(nil, sum[:], int64(len(data)))
+	require.NoError(t, err)
+	_, got, err = parseDataItem(append(bytes.Clone(item.header), data...))
+	require.NoError(t, err)
+	assert.Empty(t, got.tags)
+
+	// Too many tags
+	_, err = w.signDataItem(make([]api.Tag, maxTags+1), sum[:], 0)
+	assert.ErrorContains(t, err, "too many tags")
+}
+
+func TestManifest(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	fake := newFakeArweave(t)
+	walletFile := filepath.Join(t.TempDir(), "wallet.json")
+	require.NoError(t, os.WriteFile(walletFile, testWallet(t), 0600))
+	f := newTestFs(t, fake, configmap.Simple{"wallet_file": walletFile, "batch_mode": "async"})
+
+	_, err := f.Command(ctx, "manifest", nil, nil)
+	assert.ErrorContains(t, err, "no manifest")
+
+	modTime := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	contents := "hello arweave"
+	src := object.NewStaticObjectInfo("dir/file.txt", modTime, int64(len(contents)), true, nil, nil)
+	o, err := f.Put(ctx, strings.NewReader(contents), src)
+	require.NoError(t, err)
+	_, err = f.Put(ctx, strings.NewReader("two"), object.NewStaticObjectInfo("dir/two.txt", modTime, 3, true, nil, nil))
+	require.NoError(t, err)
+
+	// Async changes are visible straight away
+	entries, err := f.List(ctx, "dir")
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// Publish the manifest
+	out, err := f.Command(ctx, "manifest", nil, nil)
+	require.NoError(t, err)
+	manifestID := out.(map[string]string)["id"]
+	assert.Equal(t, fake.url+"/"+manifestID+"/", out.(map[string]string)["url"])
+
+	// Both files were published in one manifest
+	var manifests []*fakeItem
+	fake.mu.Lock()
+	for _, id := range fake.order {
+		item := fake.items[id]
+		if item.tags[0].Value == api.ManifestContentType {
+			manifests = append(manifests, item)
+		}
+	}
+	fake.mu.Unlock()
+	require.Len(t, manifests, 1)
+	var m api.Manifest
+	require.NoError(t, json.Unmarshal(manifests[0].data, &m))
+	assert.Equal(t, api.ManifestType, m.Manifest)
+	assert.Equal(t, o.(*Object).id, m.Paths["dir/file.txt"].ID)
+	assert.Equal(t, api.FileInfo{
+		Size:    int64(len(contents)),
+		ModTime: modTime.UnixNano(),
+		Hashes: map[string]string{
+			"md5":    o.(*Object).info.Hashes["md5"],
+			"sha256": o.(*Object).info.Hashes["sha256"],
+		},
+	}, m.Rclone["dir/file.txt"])
+	md5, err := o.Hash(ctx, hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "3602e17e84aba27dfd3238adec0b7522", md5)
+	assert.Equal(t, "1", tagValue(manifests[0].tags, tagSequence))
+
+	// A new Fs in the same process shares the manifest
+	require.NoError(t, o.Remove(ctx))
+	sub, err := NewFs(ctx, "arweave", "dir", configmap.Simple{
+		"wallet_file": walletFile, "gateway": fake.url, "upload_url": fake.url + "/tx",
+		"manifest_name": "rclone", "batch_mode": "sync",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, f.state, sub.(*Fs).state)
+	_, err = sub.NewObject(ctx, "file.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	require.NoError(t, f.Shutdown(ctx))
+
+	// A new process finds the latest manifest
+	forgetStates()
+	f2 := newTestFs(t, fake, configmap.Simple{})
+	entries, err = f2.List(ctx, "dir")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "dir/two.txt", entries[0].Remote())
+	assert.Equal(t, int64(2), f2.state.sequence)
+
+	// A manifest can be read by ID without a wallet
+	f3 := newTestFs(t, fake, configmap.Simple{"manifest_id": manifestID})
+	o3, err := f3.NewObject(ctx, "dir/file.txt")
+	require.NoError(t, err)
+	assert.True(t, o3.ModTime(ctx).Equal(modTime))
+	in, err := o3.Open(ctx, &fs.RangeOption{Start: 6, End: -1})
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "arweave", string(data))
+
+	// and is read only
+	_, err = f3.Put(ctx, strings.NewReader(contents), src)
+	assert.Equal(t, errorReadOnly, err)
+	assert.Equal(t, errorReadOnly, o3.Remove(ctx))
+	assert.Nil(t, f3.Features().Copy)
+}
+
+// tagValue returns the value of the named tag
+func tagValue(tags []api.Tag, name string) string {
+	tx := api.Transaction{Tags: tags}
+	return tx.Tag(name)
+}
+
+func TestManifestSequence(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	fake := newFakeArweave(t)
+	f := newTestFs(t, fake, configmap.Simple{"batch_mode": "off"})
+	for i := range 3 {
+		name := "file" + strconv.Itoa(i)
+		_, err := f.Put(ctx, strings.NewReader(name), object.NewStaticObjectInfo(name, time.Now(), int64(len(name)), true, nil, nil))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(3), f.state.sequence)
+
+	// Manifests with other names aren't seen
+	other := newTestFs(t, fake, configmap.Simple{"manifest_name": "other"})
+	entries, err := other.List(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// The highest sequence wins even if it isn't the newest
+	fake.mu.Lock()
+	n := len(fake.order)
+	fake.order[n-1], fake.order[n-3] = fake.order[n-3], fake.order[n-1]
+	fake.mu.Unlock()
+	forgetStates()
+	f2 := newTestFs(t, fake, configmap.Simple{})
+	entries, err = f2.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
@@ -0,0 +1,17 @@
+// Test Arweave filesystem interface
+package arweave_test
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/backend/arweave"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestArweave:",
+		NilObject:  (*arweave.Object)(nil),
+	})
+}
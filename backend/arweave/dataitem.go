@@ -0,0 +1,196 @@
+package arweave
+
+// This file implements the parts of ANS-104 needed to sign data items
+// with an Arweave wallet so they can be posted to a bundler.
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/rclone/rclone/backend/arweave/api"
+)
+
+const (
+	signatureTypeArweave = 1
+	ownerSize            = 512 // size of the modulus of an Arweave wallet
+	maxTags              = 128
+	maxTagNameSize       = 1024
+	maxTagValueSize      = 3072
+)
+
+// b64 is the encoding used for ids, addresses and keys
+var b64 = base64.RawURLEncoding
+
+// wallet is an Arweave wallet, a 4096 bit RSA key
+type wallet struct {
+	key     *rsa.PrivateKey
+	owner   []byte // the modulus of the key
+	address string // base64url of the sha256 of the owner
+}
+
+// jwk is an RSA private key as stored in an Arweave wallet file
+type jwk struct {
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	D   string `json:"d"`
+	P   string `json:"p"`
+	Q   string `json:"q"`
+}
+
+// parseWallet reads an Arweave wallet from its JWK JSON
+func parseWallet(data []byte) (*wallet, error) {
+	var key jwk
+	err := json.Unmarshal(data, &key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse wallet: %w", err)
+	}
+	if key.Kty != "RSA" {
+		return nil, fmt.Errorf("wallet must be an RSA key not %q", key.Kty)
+	}
+	var ints [5]*big.Int
+	for i, s := range []string{key.N, key.E, key.D, key.P, key.Q} {
+		b, err := b64.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil, errors.New("wallet is missing parts of the private key")
+		}
+		ints[i] = new(big.Int).SetBytes(b)
+	}
+	if !ints[1].IsInt64() {
+		return nil, errors.New("wallet has a bad public exponent")
+	}
+	priv := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: ints[0], E: int(ints[1].Int64())},
+		D:         ints[2],
+		Primes:    []*big.Int{ints[3], ints[4]},
+	}
+	err = priv.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("wallet key is invalid: %w", err)
+	}
+	priv.Precompute()
+	owner := priv.N.Bytes()
+	if len(owner) != ownerSize {
+		return nil, fmt.Errorf("wallet must be a %d bit key not %d bit", ownerSize*8, priv.N.BitLen())
+	}
+	sum := sha256.Sum256(owner)
+	return &wallet{
+		key:     priv,
+		owner:   owner,
+		address: b64.EncodeToString(sum[:]),
+	}, nil
+}
+
+// deepHashBlob returns the deep hash of a blob from its sha384 and size
+func deepHashBlob(sum []byte, size int64) []byte {
+	tag := sha512.Sum384([]byte("blob" + strconv.FormatInt(size, 10)))
+	h := sha512.New384()
+	_, _ = h.Write(tag[:])
+	_, _ = h.Write(sum)
+	return h.Sum(nil)
+}
+
+// deepHashList returns the deep hash of a list from the deep hashes
+// of its items
+func deepHashList(items ...[]byte) []byte {
+	acc := sha512.Sum384([]byte("list" + strconv.Itoa(len(items))))
+	for _, item := range items {
+		h := sha512.New384()
+		_, _ = h.Write(acc[:])
+		_, _ = h.Write(item)
+		h.Sum(acc[:0])
+	}
+	return acc[:]
+}
+
+// deepHashBytes returns the deep hash of b
+func deepHashBytes(b []byte) []byte {
+	sum := sha512.Sum384(b)
+	return deepHashBlob(sum[:], int64(len(b)))
+}
+
+// appendAvroLong appends n as an Avro long
+func appendAvroLong(b []byte, n int64) []byte {
+	return binary.AppendUvarint(b, uint64((n<<1)^(n>>63)))
+}
+
+// appendAvroBytes appends v as Avro bytes
+func appendAvroBytes(b []byte, v string) []byte {
+	b = appendAvroLong(b, int64(len(v)))
+	return append(b, v...)
+}
+
+// encodeTags encodes tags as an Avro array of name value records
+func encodeTags(tags []api.Tag) ([]byte, error) {
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("too many tags: %d > %d", len(tags), maxTags)
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	b := appendAvroLong(nil, int64(len(tags)))
+	for _, tag := range tags {
+		if tag.Name == "" || len(tag.Name) > maxTagNameSize || len(tag.Value) > maxTagValueSize {
+			return nil, fmt.Errorf("tag %q is too long or empty", tag.Name)
+		}
+		b = appendAvroBytes(b, tag.Name)
+		b = appendAvroBytes(b, tag.Value)
+	}
+	return appendAvroLong(b, 0), nil
+}
+
+// dataItem is a signed data item without its data
+type dataItem struct {
+	id     string // base64url of the sha256 of the signature
+	header []byte // everything in the data item before the data
+}
+
+// signDataItem signs a data item with tags for size bytes of data
+// having the sha384 dataSum returning the header to send before the
+// data.
+func (w *wallet) signDataItem(tags []api.Tag, dataSum []byte, size int64) (*dataItem, error) {
+	tagBytes, err := encodeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	message := deepHashList(
+		deepHashBytes([]byte("dataitem")),
+		deepHashBytes([]byte("1")),
+		deepHashBytes([]byte(strconv.Itoa(signatureTypeArweave))),
+		deepHashBytes(w.owner),
+		deepHashBytes(nil), // target
+		deepHashBytes(nil), // anchor
+		deepHashBytes(tagBytes),
+		deepHashBlob(dataSum, size),
+	)
+	digest := sha256.Sum256(message)
+	signature, err := rsa.SignPSS(rand.Reader, w.key, crypto.SHA256, digest[:], &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign data item: %w", err)
+	}
+	b := binary.LittleEndian.AppendUint16(nil, signatureTypeArweave)
+	b = append(b, signature...)
+	b = append(b, w.owner...)
+	b = append(b, 0) // no target
+	b = append(b, 0) // no anchor
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(tags)))
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(tagBytes)))
+	b = append(b, tagBytes...)
+	id := sha256.Sum256(signature)
+	return &dataItem{
+		id:     b64.EncodeToString(id[:]),
+		header: b,
+	}, nil
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/chunksize"
 	"github.com/rclone/rclone/fs/config"
 	"github.com/rclone/rclone/fs/config/configmap"
@@ -45,12 +46,14 @@ import (
 	"github.com/rclone/rclone/fs/fshttp"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/list"
+	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/lib/atexit"
 	"github.com/rclone/rclone/lib/bucket"
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/env"
 	"github.com/rclone/rclone/lib/multipart"
 	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/restore"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -90,6 +93,7 @@ func init() {
 		Name:        "azureblob",
 		Description: "Microsoft Azure Blob Storage",
 		NewFs:       NewFs,
+		CommandHelp: commandHelp,
 		Options: []fs.Option{{
 			Name: "account",
 			Help: `Azure Storage Account Name.
@@ -382,7 +386,8 @@ rclone performs "Set Tier" operation on blobs while uploading, if objects
 are not modified, specifying "access tier" to new one will have no effect.
 If blobs are in "archive tier" at remote, trying to perform data transfer
 operations from remote will not be allowed. User should first restore by
-tiering blob to "Hot", "Cool" or "Cold".`,
+tiering blob to "Hot", "Cool" or "Cold", or use
+--azureblob-archive-tier-rehydrate.`,
 			Advanced: true,
 		}, {
 			Name:    "archive_tier_delete",
@@ -402,6 +407,55 @@ replacement.  This has the potential for data loss if the upload fails
 archive tier blobs early may be chargable.
 `, errCantUpdateArchiveTierBlobs),
 			Advanced: true,
+		}, {
+			Name: "archive_tier_rehydrate",
+			Help: `Tier to rehydrate archive tier blobs to when reading them.
+
+Archive tier blobs can't be read until they have been rehydrated to
+the hot, cool or cold tier, which can take up to 15 hours. Normally
+rclone gives an error when reading an archive tier blob.
+
+If this is set to hot, cool or cold, then when rclone reads an archive
+tier blob it starts rehydrating it to this tier, using the
+--azureblob-rehydrate-priority, and gives an error saying the
+rehydration has started. Run rclone again when the rehydration has
+finished to read the blob.
+
+See the "rehydrate-and-copy" backend command for a way of waiting for
+the rehydrations to finish.`,
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "Don't rehydrate archive tier blobs",
+			}, {
+				Value: string(blob.AccessTierHot),
+				Help:  "Rehydrate to the hot tier",
+			}, {
+				Value: string(blob.AccessTierCool),
+				Help:  "Rehydrate to the cool tier",
+			}, {
+				Value: string(blob.AccessTierCold),
+				Help:  "Rehydrate to the cold tier",
+			}},
+			Advanced: true,
+		}, {
+			Name:    "rehydrate_priority",
+			Default: string(blob.RehydratePriorityStandard),
+			Help: `Priority of rehydrating archive tier blobs.
+
+This is used when changing the tier of an archive tier blob, when
+rehydrating it with --azureblob-archive-tier-rehydrate and by the
+"rehydrate-and-copy" backend command.
+
+High priority rehydrations may finish in under an hour for blobs
+smaller than 10 GiB, but cost more.`,
+			Examples: []fs.OptionExample{{
+				Value: string(blob.RehydratePriorityStandard),
+				Help:  "Standard priority - may take up to 15 hours",
+			}, {
+				Value: string(blob.RehydratePriorityHigh),
+				Help:  "High priority - may take under an hour",
+			}},
+			Advanced: true,
 		}, {
 			Name: "disable_checksum",
 			Help: `Don't store MD5 checksum with object metadata.
@@ -529,6 +583,8 @@ type Options struct {
 	ListChunkSize              uint                 `config:"list_chunk"`
 	AccessTier                 string               `config:"access_tier"`
 	ArchiveTierDelete          bool                 `config:"archive_tier_delete"`
+	ArchiveTierRehydrate       string               `config:"archive_tier_rehydrate"`
+	RehydratePriority          string               `config:"rehydrate_priority"`
 	UseEmulator                bool                 `config:"use_emulator"`
 	DisableCheckSum            bool                 `config:"disable_checksum"`
 	Enc                        encoder.MultiEncoder `config:"encoding"`
@@ -559,19 +615,21 @@ type Fs struct {
 	pacer         *fs.Pacer                    // To pace and retry the API calls
 	uploadToken   *pacer.TokenDispenser        // control concurrency
 	publicAccess  container.PublicAccessType   // Container Public Access Level
+	rehydrate     blob.RehydratePriority       // priority for rehydrating archive tier blobs
 }
 
 // Object describes an azure object
 type Object struct {
-	fs         *Fs               // what this object is part of
-	remote     string            // The remote path
-	modTime    time.Time         // The modified time of the object if known
-	md5        string            // MD5 hash if known
-	size       int64             // Size of the object
-	mimeType   string            // Content-Type of the object
-	accessTier blob.AccessTier   // Blob Access Tier
-	meta       map[string]string // blob metadata - take metadataMu when accessing
-	tags       map[string]string // blob tags
+	fs         *Fs                // what this object is part of
+	remote     string             // The remote path
+	modTime    time.Time          // The modified time of the object if known
+	md5        string             // MD5 hash if known
+	size       int64              // Size of the object
+	mimeType   string             // Content-Type of the object
+	accessTier blob.AccessTier    // Blob Access Tier
+	archive    blob.ArchiveStatus // rehydration status if rehydrating from archive tier
+	meta       map[string]string  // blob metadata - take metadataMu when accessing
+	tags       map[string]string  // blob tags
 }
 
 // ------------------------------------------------------------
@@ -628,6 +686,24 @@ func validateAccessTier(tier string) bool {
 		strings.EqualFold(tier, string(blob.AccessTierArchive))
 }
 
+// validateRehydrateTier checks if tier is one archive tier blobs can
+// be rehydrated to
+func validateRehydrateTier(tier string) bool {
+	return validateAccessTier(tier) && !strings.EqualFold(tier, string(blob.AccessTierArchive))
+}
+
+// parseRehydratePriority parses the priority for rehydrating archive
+// tier blobs
+func parseRehydratePriority(priority string) (blob.RehydratePriority, error) {
+	for _, p := range blob.PossibleRehydratePriorityValues() {
+		if strings.EqualFold(priority, string(p)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("supported rehydrate priorities are %s and %s, not %q",
+		string(blob.RehydratePriorityStandard), string(blob.RehydratePriorityHigh), priority)
+}
+
 // validatePublicAccess checks if azureblob supports use supplied public access level
 func validatePublicAccess(publicAccess string) bool {
 	switch publicAccess {
@@ -780,6 +856,15 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 			string(blob.AccessTierHot), string(blob.AccessTierCool), string(blob.AccessTierCold), string(blob.AccessTierArchive))
 	}
 
+	if opt.ArchiveTierRehydrate != "" && !validateRehydrateTier(opt.ArchiveTierRehydrate) {
+		return nil, fmt.Errorf("supported archive tier rehydrate tiers are %s, %s and %s",
+			string(blob.AccessTierHot), string(blob.AccessTierCool), string(blob.AccessTierCold))
+	}
+	rehydrate, err := parseRehydratePriority(opt.RehydratePriority)
+	if err != nil {
+		return nil, err
+	}
+
 	if !validatePublicAccess((opt.PublicAccess)) {
 		return nil, fmt.Errorf("supported public access level are %s and %s",
 			string(container.PublicAccessTypeBlob), string(container.PublicAccessTypeContainer))
@@ -794,6 +879,7 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		uploadToken: pacer.NewTokenDispenser(ci.Transfers),
 		cache:       bucket.NewCache(),
 		cntSVCcache: make(map[string]*container.Client, 1),
+		rehydrate:   rehydrate,
 	}
 	f.publicAccess = container.PublicAccessType(opt.PublicAccess)
 	f.setRoot(root)
@@ -2070,6 +2156,11 @@ func (o *Object) decodeMetaDataFromPropertiesResponse(info *blob.GetPropertiesRe
 	} else {
 		o.accessTier = blob.AccessTier(*info.AccessTier)
 	}
+	if info.ArchiveStatus == nil {
+		o.archive = blob.ArchiveStatus("")
+	} else {
+		o.archive = blob.ArchiveStatus(*info.ArchiveStatus)
+	}
 	o.setMetadata(metadata)
 
 	return nil
@@ -2160,6 +2251,11 @@ func (o *Object) decodeMetaDataFromBlob(info *container.BlobItem) (err error) {
 	} else {
 		o.accessTier = *info.Properties.AccessTier
 	}
+	if info.Properties.ArchiveStatus == nil {
+		o.archive = blob.ArchiveStatus("")
+	} else {
+		o.archive = *info.Properties.ArchiveStatus
+	}
 	o.setMetadata(metadata)
 
 	return nil
@@ -2294,7 +2390,7 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.Read
 	var offset int64
 	var count int64
 	if o.AccessTier() == blob.AccessTierArchive {
-		return nil, fmt.Errorf("blob in archive tier, you need to set tier to hot, cool, cold first")
+		return nil, o.archiveTierReadError(ctx)
 	}
 	fs.FixRangeOption(options, o.size)
 	for _, option := range options {
@@ -3012,29 +3108,65 @@ func (o *Object) SetTier(tier string) error {
 		return nil
 	}
 	desiredAccessTier := blob.AccessTier(tier)
+	err := o.setTier(context.Background(), desiredAccessTier, o.fs.rehydrate)
+	if err != nil {
+		return err
+	}
+
+	// Set access tier on local object also, this typically
+	// gets updated on get blob properties
+	o.accessTier = desiredAccessTier
+	fs.Debugf(o, "Successfully changed object tier to %s", tier)
+
+	return nil
+}
+
+// setTier sets the tier of the blob, using priority if it is being
+// rehydrated from the archive tier
+func (o *Object) setTier(ctx context.Context, tier blob.AccessTier, priority blob.RehydratePriority) error {
 	blb := o.getBlobSVC()
-	ctx := context.Background()
-	priority := blob.RehydratePriorityStandard
 	opt := blob.SetTierOptions{
 		RehydratePriority: &priority,
 	}
 	err := o.fs.pacer.Call(func() (bool, error) {
-		_, err := blb.SetTier(ctx, desiredAccessTier, &opt)
+		_, err := blb.SetTier(ctx, tier, &opt)
 		return o.fs.shouldRetry(ctx, err)
 	})
-
 	if err != nil {
 		return fmt.Errorf("failed to set Blob Tier: %w", err)
 	}
+	return nil
+}
 
-	// Set access tier on local object also, this typically
-	// gets updated on get blob properties
-	o.accessTier = desiredAccessTier
-	fs.Debugf(o, "Successfully changed object tier to %s", tier)
-
+// rehydrate starts rehydrating the archive tier blob to tier
+func (o *Object) rehydrate(ctx context.Context, tier blob.AccessTier, priority blob.RehydratePriority) error {
+	err := o.setTier(ctx, tier, priority)
+	if err != nil {
+		return err
+	}
+	o.archive = blob.ArchiveStatus("rehydrate-pending-to-" + strings.ToLower(string(tier)))
 	return nil
 }
 
+// archiveTierReadError returns the error for reading an archive tier
+// blob, starting its rehydration if --azureblob-archive-tier-rehydrate
+// is set
+func (o *Object) archiveTierReadError(ctx context.Context) error {
+	tier := o.fs.opt.ArchiveTierRehydrate
+	switch {
+	case o.archive != "":
+		return fserrors.NoRetryError(fmt.Errorf("blob in archive tier is being rehydrated (%s), try again when it has finished", o.archive))
+	case tier == "":
+		return fserrors.NoRetryError(errors.New("blob in archive tier, you need to set tier to hot, cool, cold first"))
+	}
+	err := o.rehydrate(ctx, blob.AccessTier(tier), o.fs.rehydrate)
+	if err != nil {
+		return fmt.Errorf("failed to start rehydrating archive tier blob: %w", err)
+	}
+	fs.Infof(o, "Started rehydrating to %s tier with %s priority", tier, o.fs.rehydrate)
+	return fserrors.NoRetryError(fmt.Errorf("blob in archive tier, started rehydrating it to %s tier, try again when it has finished", tier))
+}
+
 // GetTier returns object tier in azure as string
 func (o *Object) GetTier() string {
 	return string(o.accessTier)
@@ -3048,6 +3180,243 @@ func parseTier(tier string) *blob.AccessTier {
 	return &msTier
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "rehydrate-status",
+	Short: "Show the status of archive tier blobs",
+	Long: `This command shows the blobs in the archive tier and whether they
+are being rehydrated.
+
+Usage Examples:
+
+    rclone backend rehydrate-status azureblob:container/path/to/directory
+    rclone backend rehydrate-status -o all azureblob:container/path/to/directory
+
+This command obeys the filters.
+
+It returns a list of status dictionaries with Remote, Tier and
+ArchiveStatus keys. The ArchiveStatus is empty if the blob isn't being
+rehydrated, or is rehydrate-pending-to-hot, rehydrate-pending-to-cool
+or rehydrate-pending-to-cold if it is.
+
+    [
+        {
+            "Remote": "file.txt",
+            "Tier": "Archive",
+            "ArchiveStatus": "rehydrate-pending-to-hot"
+        },
+        {
+            "Remote": "test.txt",
+            "Tier": "Archive",
+            "ArchiveStatus": ""
+        }
+    ]
+`,
+	Opts: map[string]string{
+		"all": "if set then show all blobs, not just ones in the archive tier",
+	},
+}, {
+	Name:  "rehydrate-and-copy",
+	Short: "Rehydrate archive tier blobs then copy them",
+	Long: `This command rehydrates blobs in the archive tier, waits for the
+rehydrations to finish, then copies the blobs to the destination given.
+
+Usage Examples:
+
+    rclone backend rehydrate-and-copy azureblob:container/path/to/directory remote:dest -o tier=Cool
+    rclone backend rehydrate-and-copy azureblob:container remote:dest -o priority=High -o max-wait=6h
+
+Rehydrations are started for archive tier blobs which aren't being
+rehydrated already, then the archive status is polled, waiting
+poll-interval between checks, doubling each time up to 30 minutes.
+Blobs which aren't in the archive tier, or have finished rehydrating,
+are copied as soon as they are found. Blobs which are the same at the
+destination aren't copied again.
+
+The tier defaults to --azureblob-archive-tier-rehydrate or Hot if that
+isn't set and the priority defaults to --azureblob-rehydrate-priority.
+
+This command obeys the filters. Test first with --interactive/-i or
+--dry-run flags
+
+    rclone --dry-run backend rehydrate-and-copy --include "*.txt" azureblob:container/path remote:dest
+
+It returns a list of status dictionaries with Remote and Status keys.
+The Status is one of Copied, Unchanged, Not rehydrated, Timed out
+waiting for rehydration or an error message starting Rehydrate failed
+or Copy failed.
+
+    [
+        {
+            "Remote": "test.txt",
+            "Status": "Copied"
+        },
+        {
+            "Remote": "test/file4.txt",
+            "Status": "Timed out waiting for rehydration"
+        }
+    ]
+
+If any blobs fail to rehydrate or copy then rclone will exit with a
+non zero exit code after printing the list.
+`,
+	Opts: map[string]string{
+		"tier":          "Tier to rehydrate to: Hot|Cool|Cold",
+		"priority":      "Priority of rehydration: Standard|High",
+		"poll-interval": "Initial time to wait between checking rehydrations (default 1m)",
+		"max-wait":      "Maximum time to wait for rehydrations (default unlimited)",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "rehydrate-status":
+		_, all := opt["all"]
+		return f.rehydrateStatus(ctx, all)
+	case "rehydrate-and-copy":
+		if len(arg) != 1 {
+			return nil, errors.New("need exactly 1 argument, the destination")
+		}
+		dstFs, err := cache.Get(ctx, arg[0])
+		if err != nil {
+			return nil, fmt.Errorf("couldn't find destination: %w", err)
+		}
+		tier := f.opt.ArchiveTierRehydrate
+		if opt["tier"] != "" {
+			tier = opt["tier"]
+		} else if tier == "" {
+			tier = string(blob.AccessTierHot)
+		}
+		if !validateRehydrateTier(tier) {
+			return nil, fmt.Errorf("can't rehydrate to tier %q", tier)
+		}
+		priority := f.rehydrate
+		if opt["priority"] != "" {
+			priority, err = parseRehydratePriority(opt["priority"])
+			if err != nil {
+				return nil, err
+			}
+		}
+		pollInterval := time.Minute
+		if opt["poll-interval"] != "" {
+			pollInterval, err = fs.ParseDuration(opt["poll-interval"])
+			if err != nil {
+				return nil, fmt.Errorf("bad poll-interval: %w", err)
+			}
+		}
+		var maxWait time.Duration
+		if opt["max-wait"] != "" {
+			maxWait, err = fs.ParseDuration(opt["max-wait"])
+			if err != nil {
+				return nil, fmt.Errorf("bad max-wait: %w", err)
+			}
+		}
+		return f.rehydrateAndCopy(ctx, dstFs, blob.AccessTier(tier), priority, pollInterval, maxWait)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// Returned from "rehydrate-status"
+type rehydrateStatusOut struct {
+	Remote        string
+	Tier          string
+	ArchiveStatus string
+}
+
+// rehydrateStatus lists the blobs in the archive tier, or all the
+// blobs if all is set, with their rehydration status
+func (f *Fs) rehydrateStatus(ctx context.Context, all bool) (out []rehydrateStatusOut, err error) {
+	var outMu sync.Mutex
+	out = []rehydrateStatusOut{}
+	err = operations.ListFn(ctx, f, func(obj fs.Object) {
+		o, ok := obj.(*Object)
+		if !ok || (!all && o.accessTier != blob.AccessTierArchive) {
+			return
+		}
+		outMu.Lock()
+		out = append(out, rehydrateStatusOut{
+			Remote:        o.remote,
+			Tier:          string(o.accessTier),
+			ArchiveStatus: string(o.archive),
+		})
+		outMu.Unlock()
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Remote < out[j].Remote
+	})
+	return out, nil
+}
+
+// errNotAzureBlob is returned when rehydrating an object from another backend
+var errNotAzureBlob = errors.New("not an Azure blob")
+
+// getRehydrateState works out the rehydration state from the tier and
+// archive status of a blob
+func getRehydrateState(tier blob.AccessTier, archive blob.ArchiveStatus) restore.State {
+	switch {
+	case !strings.EqualFold(string(tier), string(blob.AccessTierArchive)):
+		return restore.Done
+	case archive != "":
+		return restore.Ongoing
+	default:
+		return restore.Needed
+	}
+}
+
+// readRehydrateState reads whether the blob needs rehydrating before
+// it can be read
+func (o *Object) readRehydrateState(ctx context.Context) (restore.State, error) {
+	if getRehydrateState(o.accessTier, o.archive) == restore.Done {
+		return restore.Done, nil
+	}
+	_, err := o.readMetaDataAlways(ctx)
+	if err != nil {
+		return restore.Needed, err
+	}
+	return getRehydrateState(o.accessTier, o.archive), nil
+}
+
+// rehydrateAndCopy rehydrates the archive tier blobs in f to tier,
+// waiting for the rehydrations to finish, then copies them to dstFs.
+//
+// It waits at most maxWait for rehydrations if it is set.
+func (f *Fs) rehydrateAndCopy(ctx context.Context, dstFs fs.Fs, tier blob.AccessTier, priority blob.RehydratePriority, pollInterval, maxWait time.Duration) ([]*restore.Status, error) {
+	return restore.Copy(ctx, f, dstFs, restore.Options{
+		Verb:         "rehydrate",
+		Noun:         "rehydration",
+		Past:         "rehydrated",
+		Ongoing:      "Rehydrating",
+		PollInterval: pollInterval,
+		MaxWait:      maxWait,
+		State: func(ctx context.Context, obj fs.Object) (restore.State, error) {
+			o, ok := obj.(*Object)
+			if !ok {
+				return restore.Needed, errNotAzureBlob
+			}
+			return o.readRehydrateState(ctx)
+		},
+		Start: func(ctx context.Context, obj fs.Object) error {
+			err := obj.(*Object).rehydrate(ctx, tier, priority)
+			if err == nil {
+				fs.Infof(obj, "Started rehydrating to %s tier with %s priority", tier, priority)
+			}
+			return err
+		},
+	})
+}
+
 // Check the interfaces are satisfied
 var (
 	_ fs.Fs              = &Fs{}
@@ -3056,6 +3425,7 @@ var (
 	_ fs.Purger          = &Fs{}
 	_ fs.ListRer         = &Fs{}
 	_ fs.OpenChunkWriter = &Fs{}
+	_ fs.Commander       = &Fs{}
 	_ fs.Object          = &Object{}
 	_ fs.MimeTyper       = &Object{}
 	_ fs.GetTierer       = &Object{}
@@ -5,14 +5,17 @@ package azureblob
 import (
 	"context"
 	"encoding/base64"
+	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/fstests"
 	"github.com/rclone/rclone/lib/random"
+	"github.com/rclone/rclone/lib/restore"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -45,6 +48,54 @@ func TestBlockIDCreator(t *testing.T) {
 	assert.ErrorContains(t, bic2.checkID(chunkNumber, got), "random bytes")
 }
 
+func TestParseRehydratePriority(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    blob.RehydratePriority
+		wantErr bool
+	}{
+		{"Standard", blob.RehydratePriorityStandard, false},
+		{"standard", blob.RehydratePriorityStandard, false},
+		{"HIGH", blob.RehydratePriorityHigh, false},
+		{"", "", true},
+		{"Expedited", "", true},
+	} {
+		got, err := parseRehydratePriority(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+		} else {
+			assert.NoError(t, err, test.in)
+		}
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestValidateRehydrateTier(t *testing.T) {
+	assert.True(t, validateRehydrateTier("Hot"))
+	assert.True(t, validateRehydrateTier("cool"))
+	assert.True(t, validateRehydrateTier("Cold"))
+	assert.False(t, validateRehydrateTier("Archive"))
+	assert.False(t, validateRehydrateTier(""))
+	assert.False(t, validateRehydrateTier("potato"))
+}
+
+func TestGetRehydrateState(t *testing.T) {
+	for _, test := range []struct {
+		tier    blob.AccessTier
+		archive blob.ArchiveStatus
+		want    restore.State
+	}{
+		{blob.AccessTierHot, "", restore.Done},
+		{blob.AccessTierCool, "", restore.Done},
+		{"", "", restore.Done},
+		{blob.AccessTierArchive, "", restore.Needed},
+		{blob.AccessTierArchive, blob.ArchiveStatusRehydratePendingToHot, restore.Ongoing},
+		{"archive", blob.ArchiveStatusRehydratePendingToCold, restore.Ongoing},
+	} {
+		assert.Equal(t, test.want, getRehydrateState(test.tier, test.archive), fmt.Sprintf("%q %q", test.tier, test.archive))
+	}
+}
+
 func (f *Fs) testFeatures(t *testing.T) {
 	// Check first feature flags are set on this remote
 	enabled := f.Features().SetTier
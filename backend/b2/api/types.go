@@ -38,6 +38,8 @@ type Bucket struct {
 	Name           string          `json:"bucketName"`
 	Type           string          `json:"bucketType"`
 	LifecycleRules []LifecycleRule `json:"lifecycleRules,omitempty"`
+
+	ReplicationConfiguration *ReplicationConfigurationResponse `json:"replicationConfiguration,omitempty"`
 }
 
 // LifecycleRule is a single lifecycle rule
@@ -48,6 +50,54 @@ type LifecycleRule struct {
 	FileNamePrefix                                  string `json:"fileNamePrefix"`
 }
 
+// ReplicationRule is a single replication rule on a source bucket
+type ReplicationRule struct {
+	DestinationBucketID  string `json:"destinationBucketId"`  // The bucket files are replicated to
+	FileNamePrefix       string `json:"fileNamePrefix"`       // Only files with this prefix are replicated
+	IncludeExistingFiles bool   `json:"includeExistingFiles"` // Whether files uploaded before the rule are replicated
+	IsEnabled            bool   `json:"isEnabled"`            // Whether the rule is active
+	Priority             int    `json:"priority"`             // Which rule wins if more than one matches - higher is more important
+	ReplicationRuleName  string `json:"replicationRuleName"`  // Name of the rule, unique within the bucket
+}
+
+// ReplicationSource is the configuration of a bucket as a replication source
+type ReplicationSource struct {
+	ReplicationRules       []ReplicationRule `json:"replicationRules"`
+	SourceApplicationKeyID string            `json:"sourceApplicationKeyId"` // Key used to read the files to replicate
+}
+
+// ReplicationDestination is the configuration of a bucket as a replication destination
+type ReplicationDestination struct {
+	SourceToDestinationKeyMapping map[string]string `json:"sourceToDestinationKeyMapping"` // Source key ID to the key used to write the replicas
+}
+
+// ReplicationConfiguration describes the replication of a bucket
+type ReplicationConfiguration struct {
+	AsReplicationSource      *ReplicationSource      `json:"asReplicationSource,omitempty"`
+	AsReplicationDestination *ReplicationDestination `json:"asReplicationDestination,omitempty"`
+}
+
+// ReplicationConfigurationResponse is the replication configuration
+// as returned in a bucket listing
+type ReplicationConfigurationResponse struct {
+	IsClientAuthorizedToRead bool                      `json:"isClientAuthorizedToRead"`
+	Value                    *ReplicationConfiguration `json:"value"`
+}
+
+// Server side encryption modes and algorithms
+const (
+	SSEModeCustomer    = "SSE-C"
+	SSEAlgorithmAES256 = "AES256"
+)
+
+// ServerSideEncryption describes how a file is encrypted
+type ServerSideEncryption struct {
+	Mode           string `json:"mode,omitempty"`           // SSE-B2 or SSE-C
+	Algorithm      string `json:"algorithm,omitempty"`      // Only AES256 is supported
+	CustomerKey    string `json:"customerKey,omitempty"`    // The base64 encoded key for SSE-C
+	CustomerKeyMd5 string `json:"customerKeyMd5,omitempty"` // The base64 encoded MD5 of the key for SSE-C
+}
+
 // Timestamp is a UTC time when this file was uploaded. It is a base
 // 10 number of milliseconds since midnight, January 1, 1970 UTC. This
 // fits in a 64 bit integer such as the type "long" in the programming
@@ -265,6 +315,8 @@ type StartLargeFileRequest struct {
 	Name        string            `json:"fileName"`    // The name of the file. See Files for requirements on file names.
 	ContentType string            `json:"contentType"` // The MIME type of the content of the file, which will be returned in the Content-Type header when downloading the file. Use the Content-Type b2/x-auto to automatically set the stored Content-Type post upload. In the case where a file extension is absent or the lookup fails, the Content-Type is set to application/octet-stream.
 	Info        map[string]string `json:"fileInfo"`    // A JSON object holding the name/value pairs for the custom file info.
+
+	ServerSideEncryption *ServerSideEncryption `json:"serverSideEncryption,omitempty"` // How to encrypt the file
 }
 
 // StartLargeFileResponse is the response to StartLargeFileRequest
@@ -332,6 +384,9 @@ type CopyFileRequest struct {
 	ContentType       string            `json:"contentType,omitempty"`         // The MIME type of the content of the file (REPLACE only)
 	Info              map[string]string `json:"fileInfo,omitempty"`            // This field stores the metadata that will be stored with the file. (REPLACE only)
 	DestBucketID      string            `json:"destinationBucketId,omitempty"` // The destination ID of the bucket if set, if not the source bucket will be used
+
+	SourceServerSideEncryption *ServerSideEncryption `json:"sourceServerSideEncryption,omitempty"`      // How the source is encrypted - needed for SSE-C
+	DestServerSideEncryption   *ServerSideEncryption `json:"destinationServerSideEncryption,omitempty"` // How to encrypt the new file
 }
 
 // CopyPartRequest is the request for b2_copy_part - the response is UploadPartResponse
@@ -340,6 +395,9 @@ type CopyPartRequest struct {
 	LargeFileID string `json:"largeFileId"`     // The ID of the large file the part will belong to, as returned by b2_start_large_file.
 	PartNumber  int64  `json:"partNumber"`      // Which part this is (starting from 1)
 	Range       string `json:"range,omitempty"` // The range of bytes to copy. If not provided, the whole source file will be copied.
+
+	SourceServerSideEncryption *ServerSideEncryption `json:"sourceServerSideEncryption,omitempty"`      // How the source is encrypted - needed for SSE-C
+	DestServerSideEncryption   *ServerSideEncryption `json:"destinationServerSideEncryption,omitempty"` // How the large file is encrypted - needed for SSE-C
 }
 
 // UpdateBucketRequest describes a request to modify a B2 bucket
@@ -348,4 +406,6 @@ type UpdateBucketRequest struct {
 	AccountID      string          `json:"accountId"`
 	Type           string          `json:"bucketType,omitempty"`
 	LifecycleRules []LifecycleRule `json:"lifecycleRules,omitempty"`
+
+	ReplicationConfiguration *ReplicationConfiguration `json:"replicationConfiguration,omitempty"`
 }
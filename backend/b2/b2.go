@@ -8,12 +8,15 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/md5"
 	"crypto/sha1"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	gohash "hash"
 	"io"
+	"maps"
 	"net/http"
 	"path"
 	"slices"
@@ -37,7 +40,6 @@ import (
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/multipart"
 	"github.com/rclone/rclone/lib/pacer"
-	"github.com/rclone/rclone/lib/pool"
 	"github.com/rclone/rclone/lib/rest"
 )
 
@@ -52,6 +54,9 @@ const (
 	idHeader            = "X-Bz-File-Id"
 	nameHeader          = "X-Bz-File-Name"
 	timestampHeader     = "X-Bz-Upload-Timestamp"
+	sseAlgorithmHeader  = "X-Bz-Server-Side-Encryption-Customer-Algorithm"
+	sseKeyHeader        = "X-Bz-Server-Side-Encryption-Customer-Key"
+	sseKeyMD5Header     = "X-Bz-Server-Side-Encryption-Customer-Key-Md5"
 	retryAfterHeader    = "Retry-After"
 	minSleep            = 10 * time.Millisecond
 	maxSleep            = 5 * time.Minute
@@ -242,6 +247,56 @@ See: [rclone backend lifecycle](#lifecycle) for setting lifecycles after bucket
 `,
 			Default:  0,
 			Advanced: true,
+		}, {
+			Name: "sse_customer_algorithm",
+			Help: `If using SSE-C, the server-side encryption algorithm used when storing this object in B2.
+
+This is set automatically if sse_customer_key is set.`,
+			Advanced: true,
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "None",
+			}, {
+				Value: "AES256",
+				Help:  "Advanced Encryption Standard (256 bits key length)",
+			}},
+		}, {
+			Name: "sse_customer_key",
+			Help: `To use SSE-C you may provide the secret encryption key used to encrypt/decrypt your data.
+
+This must be 32 bytes long. Alternatively you can provide --b2-sse-customer-key-base64.
+
+B2 doesn't keep the key, so files uploaded with it can only be read
+with the same key.`,
+			Advanced: true,
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "None",
+			}},
+			Sensitive: true,
+		}, {
+			Name: "sse_customer_key_base64",
+			Help: `To use SSE-C you may provide the secret encryption key encoded in base64 format to encrypt/decrypt your data.
+
+Alternatively you can provide --b2-sse-customer-key.`,
+			Advanced: true,
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "None",
+			}},
+			Sensitive: true,
+		}, {
+			Name: "sse_customer_key_md5",
+			Help: `If using SSE-C you may provide the secret encryption key MD5 checksum (optional).
+
+If you leave it blank, this is calculated automatically from the sse_customer_key provided.
+`,
+			Advanced: true,
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "None",
+			}},
+			Sensitive: true,
 		}, {
 			Name:     config.ConfigEncoding,
 			Help:     config.ConfigEncodingHelp,
@@ -273,6 +328,10 @@ type Options struct {
 	DownloadURL                   string               `config:"download_url"`
 	DownloadAuthorizationDuration fs.Duration          `config:"download_auth_duration"`
 	Lifecycle                     int                  `config:"lifecycle"`
+	SSECustomerAlgorithm          string               `config:"sse_customer_algorithm"`
+	SSECustomerKey                string               `config:"sse_customer_key"`
+	SSECustomerKeyBase64          string               `config:"sse_customer_key_base64"`
+	SSECustomerKeyMD5             string               `config:"sse_customer_key_md5"`
 	Enc                           encoder.MultiEncoder `config:"encoding"`
 }
 
@@ -504,6 +563,10 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	if opt.Endpoint == "" {
 		opt.Endpoint = defaultEndpoint
 	}
+	err = checkSSECustomer(opt)
+	if err != nil {
+		return nil, fmt.Errorf("b2: %w", err)
+	}
 	ci := fs.GetConfig(ctx)
 	f := &Fs{
 		name:        name,
@@ -564,6 +627,67 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	return f, nil
 }
 
+// checkSSECustomer checks the SSE-C options, filling in the ones
+// which can be derived from the others
+func checkSSECustomer(opt *Options) error {
+	if opt.SSECustomerKeyBase64 != "" && opt.SSECustomerKey != "" {
+		return errors.New("can't use sse_customer_key and sse_customer_key_base64 at the same time")
+	} else if opt.SSECustomerKeyBase64 != "" {
+		decoded, err := base64.StdEncoding.DecodeString(opt.SSECustomerKeyBase64)
+		if err != nil {
+			return fmt.Errorf("could not decode sse_customer_key_base64: %w", err)
+		}
+		opt.SSECustomerKey = string(decoded)
+	} else {
+		opt.SSECustomerKeyBase64 = base64.StdEncoding.EncodeToString([]byte(opt.SSECustomerKey))
+	}
+	if opt.SSECustomerKey == "" {
+		if opt.SSECustomerAlgorithm != "" || opt.SSECustomerKeyMD5 != "" {
+			return errors.New("sse_customer_key must be set to use SSE-C")
+		}
+		return nil
+	}
+	if opt.SSECustomerAlgorithm == "" {
+		opt.SSECustomerAlgorithm = api.SSEAlgorithmAES256
+	}
+	if opt.SSECustomerAlgorithm != api.SSEAlgorithmAES256 {
+		return fmt.Errorf("sse_customer_algorithm %q not supported - only %q is", opt.SSECustomerAlgorithm, api.SSEAlgorithmAES256)
+	}
+	if len(opt.SSECustomerKey) != 32 {
+		return fmt.Errorf("sse_customer_key must be 32 bytes long for %s but is %d bytes", api.SSEAlgorithmAES256, len(opt.SSECustomerKey))
+	}
+	if opt.SSECustomerKeyMD5 == "" {
+		md5sumBinary := md5.Sum([]byte(opt.SSECustomerKey))
+		opt.SSECustomerKeyMD5 = base64.StdEncoding.EncodeToString(md5sumBinary[:])
+	}
+	return nil
+}
+
+// sseCustomer returns the SSE-C encryption to send in API requests
+// or nil if SSE-C isn't in use
+func (f *Fs) sseCustomer() *api.ServerSideEncryption {
+	if f.opt.SSECustomerKey == "" {
+		return nil
+	}
+	return &api.ServerSideEncryption{
+		Mode:           api.SSEModeCustomer,
+		Algorithm:      f.opt.SSECustomerAlgorithm,
+		CustomerKey:    f.opt.SSECustomerKeyBase64,
+		CustomerKeyMd5: f.opt.SSECustomerKeyMD5,
+	}
+}
+
+// addSSECustomerHeaders adds the SSE-C headers to headers for
+// uploads and downloads if SSE-C is in use
+func (f *Fs) addSSECustomerHeaders(headers map[string]string) {
+	if f.opt.SSECustomerKey == "" {
+		return
+	}
+	headers[sseAlgorithmHeader] = f.opt.SSECustomerAlgorithm
+	headers[sseKeyHeader] = f.opt.SSECustomerKeyBase64
+	headers[sseKeyMD5Header] = f.opt.SSECustomerKeyMD5
+}
+
 // authorizeAccount gets the API endpoint and auth token.  Can be used
 // for reauthentication too.
 func (f *Fs) authorizeAccount(ctx context.Context) error {
@@ -645,22 +769,21 @@ func (f *Fs) clearUploadURL(bucketID string) {
 	f.uploadMu.Unlock()
 }
 
-// getRW gets a RW buffer and an upload token
+// getRW gets a buffer and an upload token
 //
 // If noBuf is set then it just gets an upload token
-func (f *Fs) getRW(noBuf bool) (rw *pool.RW) {
+func (f *Fs) getRW(noBuf bool) (rw multipart.Buffer) {
 	f.uploadToken.Get()
 	if !noBuf {
-		rw = multipart.NewRW()
+		rw = multipart.NewBuffer(nil)
 	}
 	return rw
 }
 
-// putRW returns a RW buffer to the memory pool and returns an upload
-// token
+// putRW frees a buffer and returns an upload token
 //
 // If buf is nil then it just returns the upload token
-func (f *Fs) putRW(rw *pool.RW) {
+func (f *Fs) putRW(rw multipart.Buffer) {
 	if rw != nil {
 		_ = rw.Close()
 	}
@@ -1399,9 +1522,19 @@ func (f *Fs) copy(ctx context.Context, dstObj *Object, srcObj *Object, newInfo *
 		Path:   "/b2_copy_file",
 	}
 	var request = api.CopyFileRequest{
-		SourceID:     srcObj.id,
-		Name:         f.opt.Enc.FromStandardPath(dstPath),
-		DestBucketID: destBucketID,
+		SourceID:                   srcObj.id,
+		Name:                       f.opt.Enc.FromStandardPath(dstPath),
+		DestBucketID:               destBucketID,
+		SourceServerSideEncryption: srcObj.fs.sseCustomer(),
+		DestServerSideEncryption:   f.sseCustomer(),
+	}
+	// B2 can't copy the metadata of files encrypted with SSE-C so
+	// read it and send it with the copy
+	if newInfo == nil && (request.SourceServerSideEncryption != nil || request.DestServerSideEncryption != nil) {
+		newInfo, err = srcObj.getMetaData(ctx)
+		if err != nil {
+			return err
+		}
 	}
 	if newInfo == nil {
 		request.MetadataDirective = "COPY"
@@ -1819,10 +1952,12 @@ var _ io.ReadCloser = &openFile{}
 
 func (o *Object) getOrHead(ctx context.Context, method string, options []fs.OpenOption) (resp *http.Response, info *api.File, err error) {
 	opts := rest.Opts{
-		Method:     method,
-		Options:    options,
-		NoResponse: method == "HEAD",
+		Method:       method,
+		Options:      options,
+		NoResponse:   method == "HEAD",
+		ExtraHeaders: map[string]string{},
 	}
+	o.fs.addSSECustomerHeaders(opts.ExtraHeaders)
 
 	// Use downloadUrl from backblaze if downloadUrl is not set
 	// otherwise use the custom downloadUrl
@@ -2098,6 +2233,7 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		},
 		ContentLength: &size,
 	}
+	o.fs.addSSECustomerHeaders(opts.ExtraHeaders)
 	var response api.FileInfo
 	// Don't retry, return a retry error instead
 	err = o.fs.pacer.CallNoRetry(func() (bool, error) {
@@ -2326,6 +2462,251 @@ func (f *Fs) lifecycleCommand(ctx context.Context, name string, arg []string, op
 	return bucket.LifecycleRules, nil
 }
 
+var replicationHelp = fs.CommandHelp{
+	Name:  "replication",
+	Short: "Read or set the replication rules for a bucket",
+	Long: `This command can be used to read or set the replication
+configuration for a bucket.
+
+Usage Examples:
+
+To show the current replication configuration:
+
+    rclone backend replication b2:bucket
+
+This will dump something like this.
+
+    {
+        "asReplicationSource": {
+            "replicationRules": [
+                {
+                    "destinationBucketId": "aea8c5bc362ac55d8ca20113",
+                    "fileNamePrefix": "",
+                    "includeExistingFiles": false,
+                    "isEnabled": true,
+                    "priority": 1,
+                    "replicationRuleName": "backup"
+                }
+            ],
+            "sourceApplicationKeyId": "0014ab1234567890000000123"
+        }
+    }
+
+If the bucket isn't replicated it will just return {}.
+
+To add a rule replicating the bucket to another bucket in the same
+account, or to change the rule with that name:
+
+    rclone backend replication b2:bucket -o name=backup -o destination=otherbucket -o source-key-id=0014ab1234567890000000123
+
+Use destination-id instead of destination to give the ID of a bucket
+in a different account. The prefix, priority, include-existing and
+enabled options can also be given when adding or changing a rule.
+
+B2 reads the files to replicate with the source-key-id application
+key. This only needs to be given once. The destination bucket must
+also have a key to write the replicas, which can be set by running
+this on the destination bucket:
+
+    rclone backend replication b2:otherbucket -o source-key-id=0014ab1234567890000000123 -o destination-key-id=0014ab1234567890000000456
+
+To delete a rule:
+
+    rclone backend replication b2:bucket -o delete=backup
+
+This will run and then print the new replication configuration as above.
+
+Files encrypted with SSE-C can't be replicated by B2.
+
+See: https://www.backblaze.com/docs/cloud-storage-cloud-replication
+`,
+	Opts: map[string]string{
+		"name":               "Name of the replication rule to add or change",
+		"destination":        "Bucket in the same account to replicate to",
+		"destination-id":     "ID of the bucket to replicate to",
+		"prefix":             "Only replicate files with names starting with this",
+		"priority":           "Priority of the rule if more than one matches a file - higher wins (default 1)",
+		"include-existing":   "Set to true to replicate files uploaded before the rule was added",
+		"enabled":            "Set to false to disable the rule",
+		"source-key-id":      "ID of the application key used to read the files to replicate",
+		"destination-key-id": "ID of the application key used to write the replicas of source-key-id",
+		"delete":             "Name of the replication rule to delete",
+		"delete-key-mapping": "Remove the destination key for this source key ID",
+	},
+}
+
+// editReplication returns a copy of config changed as described by
+// opt and whether anything was changed
+//
+// destinationID is the ID of the bucket to replicate to, if set.
+func editReplication(config *api.ReplicationConfiguration, opt map[string]string, destinationID string) (newConfig *api.ReplicationConfiguration, changed bool, err error) {
+	src := &api.ReplicationSource{}
+	if config.AsReplicationSource != nil {
+		*src = *config.AsReplicationSource
+		src.ReplicationRules = slices.Clone(src.ReplicationRules)
+	}
+	dst := &api.ReplicationDestination{}
+	if config.AsReplicationDestination != nil {
+		dst.SourceToDestinationKeyMapping = maps.Clone(config.AsReplicationDestination.SourceToDestinationKeyMapping)
+	}
+	findRule := func(name string) int {
+		return slices.IndexFunc(src.ReplicationRules, func(rule api.ReplicationRule) bool {
+			return rule.ReplicationRuleName == name
+		})
+	}
+	if name := opt["delete"]; name != "" {
+		i := findRule(name)
+		if i < 0 {
+			return nil, false, fmt.Errorf("replication rule %q not found", name)
+		}
+		src.ReplicationRules = slices.Delete(src.ReplicationRules, i, i+1)
+		changed = true
+	}
+	if keyID := opt["delete-key-mapping"]; keyID != "" {
+		if _, ok := dst.SourceToDestinationKeyMapping[keyID]; !ok {
+			return nil, false, fmt.Errorf("no destination key for source key %q", keyID)
+		}
+		delete(dst.SourceToDestinationKeyMapping, keyID)
+		changed = true
+	}
+	if name := opt["name"]; name != "" {
+		rule := api.ReplicationRule{
+			ReplicationRuleName: name,
+			IsEnabled:           true,
+			Priority:            1,
+		}
+		i := findRule(name)
+		if i >= 0 {
+			rule = src.ReplicationRules[i]
+		}
+		if destinationID != "" {
+			rule.DestinationBucketID = destinationID
+		}
+		if rule.DestinationBucketID == "" {
+			return nil, false, errors.New("destination or destination-id required to add a replication rule")
+		}
+		if prefix, ok := opt["prefix"]; ok {
+			rule.FileNamePrefix = prefix
+		}
+		if priorityStr := opt["priority"]; priorityStr != "" {
+			rule.Priority, err = strconv.Atoi(priorityStr)
+			if err != nil {
+				return nil, false, fmt.Errorf("bad priority: %w", err)
+			}
+		}
+		if existingStr := opt["include-existing"]; existingStr != "" {
+			rule.IncludeExistingFiles, err = strconv.ParseBool(existingStr)
+			if err != nil {
+				return nil, false, fmt.Errorf("bad include-existing: %w", err)
+			}
+		}
+		if enabledStr := opt["enabled"]; enabledStr != "" {
+			rule.IsEnabled, err = strconv.ParseBool(enabledStr)
+			if err != nil {
+				return nil, false, fmt.Errorf("bad enabled: %w", err)
+			}
+		}
+		if i >= 0 {
+			src.ReplicationRules[i] = rule
+		} else {
+			src.ReplicationRules = append(src.ReplicationRules, rule)
+		}
+		changed = true
+	}
+	sourceKeyID := opt["source-key-id"]
+	if destKeyID := opt["destination-key-id"]; destKeyID != "" {
+		if sourceKeyID == "" {
+			return nil, false, errors.New("source-key-id required with destination-key-id")
+		}
+		if dst.SourceToDestinationKeyMapping == nil {
+			dst.SourceToDestinationKeyMapping = make(map[string]string, 1)
+		}
+		dst.SourceToDestinationKeyMapping[sourceKeyID] = destKeyID
+		changed = true
+	} else if sourceKeyID != "" {
+		src.SourceApplicationKeyID = sourceKeyID
+		changed = true
+	}
+	if len(src.ReplicationRules) > 0 && src.SourceApplicationKeyID == "" {
+		return nil, false, errors.New("source-key-id required to add a replication rule")
+	}
+	newConfig = &api.ReplicationConfiguration{}
+	if len(src.ReplicationRules) > 0 {
+		newConfig.AsReplicationSource = src
+	}
+	if len(dst.SourceToDestinationKeyMapping) > 0 {
+		newConfig.AsReplicationDestination = dst
+	}
+	return newConfig, changed, nil
+}
+
+func (f *Fs) replicationCommand(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	bucketName, _ := f.split("")
+	if bucketName == "" {
+		return nil, errors.New("bucket required")
+	}
+	var bucket *api.Bucket
+	err = f.listBucketsToFn(ctx, bucketName, func(b *api.Bucket) error {
+		bucket = b
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if bucket == nil {
+		return nil, fs.ErrorDirNotFound
+	}
+	config := &api.ReplicationConfiguration{}
+	if rc := bucket.ReplicationConfiguration; rc != nil {
+		if !rc.IsClientAuthorizedToRead {
+			return nil, errors.New("application key isn't allowed to read the replication configuration")
+		}
+		if rc.Value != nil {
+			config = rc.Value
+		}
+	}
+
+	destinationID := opt["destination-id"]
+	if destination := opt["destination"]; destination != "" {
+		if destinationID != "" {
+			return nil, errors.New("can't use destination and destination-id at the same time")
+		}
+		destinationID, err = f.getBucketID(ctx, destination)
+		if err != nil {
+			return nil, fmt.Errorf("replication destination: %w", err)
+		}
+	}
+	newConfig, changed, err := editReplication(config, opt, destinationID)
+	if err != nil {
+		return nil, err
+	}
+	if !changed || operations.SkipDestructive(ctx, name, "update replication rules") {
+		return config, nil
+	}
+
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/b2_update_bucket",
+	}
+	var request = api.UpdateBucketRequest{
+		ID:                       bucket.ID,
+		AccountID:                f.info.AccountID,
+		ReplicationConfiguration: newConfig,
+	}
+	var response api.Bucket
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(ctx, &opts, &request, &response)
+		return f.shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rc := response.ReplicationConfiguration; rc != nil && rc.Value != nil {
+		return rc.Value, nil
+	}
+	return newConfig, nil
+}
+
 var cleanupHelp = fs.CommandHelp{
 	Name:  "cleanup",
 	Short: "Remove unfinished large file uploads.",
@@ -2374,6 +2755,7 @@ func (f *Fs) cleanupHiddenCommand(ctx context.Context, name string, arg []string
 
 var commandHelp = []fs.CommandHelp{
 	lifecycleHelp,
+	replicationHelp,
 	cleanupHelp,
 	cleanupHiddenHelp,
 }
@@ -2391,6 +2773,8 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 	switch name {
 	case "lifecycle":
 		return f.lifecycleCommand(ctx, name, arg, opt)
+	case "replication":
+		return f.replicationCommand(ctx, name, arg, opt)
 	case "cleanup":
 		return f.cleanupCommand(ctx, name, arg, opt)
 	case "cleanup-hidden":
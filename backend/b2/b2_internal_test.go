@@ -186,6 +186,153 @@ func TestParseTimeString(t *testing.T) {
 
 }
 
+func TestCheckSSECustomer(t *testing.T) {
+	const key = "0123456789abcdef0123456789abcdef"
+	const keyBase64 = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	const keyMD5 = "hRasmdxgYDKV3nvbahU1MA=="
+	for _, test := range []struct {
+		name      string
+		in        Options
+		want      Options
+		wantError string
+	}{
+		{"None", Options{}, Options{}, ""},
+		{"Key", Options{SSECustomerKey: key}, Options{SSECustomerAlgorithm: "AES256", SSECustomerKey: key, SSECustomerKeyBase64: keyBase64, SSECustomerKeyMD5: keyMD5}, ""},
+		{"KeyBase64", Options{SSECustomerKeyBase64: keyBase64}, Options{SSECustomerAlgorithm: "AES256", SSECustomerKey: key, SSECustomerKeyBase64: keyBase64, SSECustomerKeyMD5: keyMD5}, ""},
+		{"KeyMD5", Options{SSECustomerKey: key, SSECustomerKeyMD5: "potato"}, Options{SSECustomerAlgorithm: "AES256", SSECustomerKey: key, SSECustomerKeyBase64: keyBase64, SSECustomerKeyMD5: "potato"}, ""},
+		{"Both", Options{SSECustomerKey: key, SSECustomerKeyBase64: keyBase64}, Options{}, "can't use sse_customer_key and sse_customer_key_base64 at the same time"},
+		{"BadBase64", Options{SSECustomerKeyBase64: "!"}, Options{}, "could not decode sse_customer_key_base64: illegal base64 data at input byte 0"},
+		{"NoKey", Options{SSECustomerAlgorithm: "AES256"}, Options{}, "sse_customer_key must be set to use SSE-C"},
+		{"BadAlgorithm", Options{SSECustomerAlgorithm: "AES128", SSECustomerKey: key}, Options{}, `sse_customer_algorithm "AES128" not supported - only "AES256" is`},
+		{"ShortKey", Options{SSECustomerKey: "potato"}, Options{}, "sse_customer_key must be 32 bytes long for AES256 but is 6 bytes"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opt := test.in
+			err := checkSSECustomer(&opt)
+			if test.wantError != "" {
+				assert.EqualError(t, err, test.wantError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, opt)
+		})
+	}
+}
+
+func TestEditReplication(t *testing.T) {
+	rule := api.ReplicationRule{
+		DestinationBucketID: "dst",
+		IsEnabled:           true,
+		Priority:            1,
+		ReplicationRuleName: "backup",
+	}
+	config := &api.ReplicationConfiguration{
+		AsReplicationSource: &api.ReplicationSource{
+			ReplicationRules:       []api.ReplicationRule{rule},
+			SourceApplicationKeyID: "srckey",
+		},
+	}
+
+	t.Run("NoChange", func(t *testing.T) {
+		newConfig, changed, err := editReplication(config, map[string]string{}, "")
+		require.NoError(t, err)
+		assert.False(t, changed)
+		assert.Equal(t, config, newConfig)
+	})
+
+	t.Run("Add", func(t *testing.T) {
+		newConfig, changed, err := editReplication(&api.ReplicationConfiguration{}, map[string]string{
+			"name":             "backup",
+			"source-key-id":    "srckey",
+			"prefix":           "dir/",
+			"priority":         "10",
+			"include-existing": "true",
+		}, "dst")
+		require.NoError(t, err)
+		assert.True(t, changed)
+		want := rule
+		want.FileNamePrefix = "dir/"
+		want.Priority = 10
+		want.IncludeExistingFiles = true
+		assert.Equal(t, []api.ReplicationRule{want}, newConfig.AsReplicationSource.ReplicationRules)
+		assert.Equal(t, "srckey", newConfig.AsReplicationSource.SourceApplicationKeyID)
+		assert.Nil(t, newConfig.AsReplicationDestination)
+	})
+
+	t.Run("Change", func(t *testing.T) {
+		newConfig, changed, err := editReplication(config, map[string]string{
+			"name":    "backup",
+			"enabled": "false",
+		}, "")
+		require.NoError(t, err)
+		assert.True(t, changed)
+		want := rule
+		want.IsEnabled = false
+		assert.Equal(t, []api.ReplicationRule{want}, newConfig.AsReplicationSource.ReplicationRules)
+		// check the original wasn't modified
+		assert.True(t, config.AsReplicationSource.ReplicationRules[0].IsEnabled)
+	})
+
+	t.Run("AddSecond", func(t *testing.T) {
+		newConfig, _, err := editReplication(config, map[string]string{"name": "other"}, "dst2")
+		require.NoError(t, err)
+		require.Equal(t, 2, len(newConfig.AsReplicationSource.ReplicationRules))
+		assert.Equal(t, "dst2", newConfig.AsReplicationSource.ReplicationRules[1].DestinationBucketID)
+		assert.Equal(t, 1, len(config.AsReplicationSource.ReplicationRules))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		newConfig, changed, err := editReplication(config, map[string]string{"delete": "backup"}, "")
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Nil(t, newConfig.AsReplicationSource)
+		assert.Equal(t, 1, len(config.AsReplicationSource.ReplicationRules))
+
+		_, _, err = editReplication(config, map[string]string{"delete": "potato"}, "")
+		assert.EqualError(t, err, `replication rule "potato" not found`)
+	})
+
+	t.Run("KeyMapping", func(t *testing.T) {
+		newConfig, changed, err := editReplication(&api.ReplicationConfiguration{}, map[string]string{
+			"source-key-id":      "srckey",
+			"destination-key-id": "dstkey",
+		}, "")
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Nil(t, newConfig.AsReplicationSource)
+		assert.Equal(t, map[string]string{"srckey": "dstkey"}, newConfig.AsReplicationDestination.SourceToDestinationKeyMapping)
+
+		newConfig, changed, err = editReplication(newConfig, map[string]string{"delete-key-mapping": "srckey"}, "")
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Nil(t, newConfig.AsReplicationDestination)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, test := range []struct {
+			opt           map[string]string
+			destinationID string
+			wantError     string
+		}{
+			{map[string]string{"name": "new"}, "", "destination or destination-id required to add a replication rule"},
+			{map[string]string{"name": "new"}, "dst", ""},
+			{map[string]string{"name": "backup", "priority": "high"}, "", `bad priority: strconv.Atoi: parsing "high": invalid syntax`},
+			{map[string]string{"name": "backup", "enabled": "potato"}, "", `bad enabled: strconv.ParseBool: parsing "potato": invalid syntax`},
+			{map[string]string{"destination-key-id": "dstkey"}, "", "source-key-id required with destination-key-id"},
+			{map[string]string{"delete-key-mapping": "srckey"}, "", `no destination key for source key "srckey"`},
+		} {
+			_, _, err := editReplication(config, test.opt, test.destinationID)
+			if test.wantError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.wantError)
+			}
+		}
+		_, _, err := editReplication(&api.ReplicationConfiguration{}, map[string]string{"name": "new"}, "dst")
+		assert.EqualError(t, err, "source-key-id required to add a replication rule")
+	})
+}
+
 // Return a map of the headers in the options with keys stripped of the "x-bz-info-" prefix
 func OpenOptionToMetaData(options []fs.OpenOption) map[string]string {
 	var headers = make(map[string]string)
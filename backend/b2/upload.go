@@ -20,6 +20,7 @@ import (
 	"github.com/rclone/rclone/fs/chunksize"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/lib/atexit"
+	"github.com/rclone/rclone/lib/multipart"
 	"github.com/rclone/rclone/lib/pool"
 	"github.com/rclone/rclone/lib/rest"
 	"golang.org/x/sync/errgroup"
@@ -110,8 +111,9 @@ func (f *Fs) newLargeUpload(ctx context.Context, o *Object, in io.Reader, src fs
 		return nil, err
 	}
 	var request = api.StartLargeFileRequest{
-		BucketID: bucketID,
-		Name:     f.opt.Enc.FromStandardPath(bucketPath),
+		BucketID:             bucketID,
+		Name:                 f.opt.Enc.FromStandardPath(bucketPath),
+		ServerSideEncryption: f.sseCustomer(),
 	}
 	optionsToSend := make([]fs.OpenOption, 0, len(options))
 	if newInfo == nil {
@@ -294,6 +296,7 @@ func (up *largeUpload) WriteChunk(ctx context.Context, chunkNumber int, reader i
 			},
 			ContentLength: &sizeWithHash,
 		}
+		up.f.addSSECustomerHeaders(opts.ExtraHeaders)
 
 		var response api.UploadPartResponse
 
@@ -333,6 +336,9 @@ func (up *largeUpload) copyChunk(ctx context.Context, part int, partSize int64)
 			LargeFileID: up.id,
 			PartNumber:  int64(part + 1),
 			Range:       fmt.Sprintf("bytes=%d-%d", offset, offset+partSize-1),
+
+			SourceServerSideEncryption: up.src.fs.sseCustomer(),
+			DestServerSideEncryption:   up.f.sseCustomer(),
 		}
 		var response api.UploadPartResponse
 		resp, err := up.f.srv.CallJSON(ctx, &opts, &request, &response)
@@ -400,7 +406,7 @@ func (up *largeUpload) Abort(ctx context.Context) error {
 // reaches EOF.
 //
 // Note that initialUploadBlock must be returned to f.putBuf()
-func (up *largeUpload) Stream(ctx context.Context, initialUploadBlock *pool.RW) (err error) {
+func (up *largeUpload) Stream(ctx context.Context, initialUploadBlock multipart.Buffer) (err error) {
 	defer atexit.OnError(&err, func() { _ = up.Abort(ctx) })()
 	fs.Debugf(up.o, "Starting streaming of large file (id %q)", up.id)
 	var (
@@ -411,7 +417,7 @@ func (up *largeUpload) Stream(ctx context.Context, initialUploadBlock *pool.RW)
 	up.parts = 0
 	for part := 0; hasMoreParts; part++ {
 		// Get a block of memory from the pool and token which limits concurrency.
-		var rw *pool.RW
+		var rw multipart.Buffer
 		if part == 0 {
 			rw = initialUploadBlock
 		} else {
@@ -26,6 +26,7 @@ import (
 	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
 	"github.com/rclone/rclone/fs/fspath"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
@@ -36,8 +37,8 @@ import (
 // and optional metadata object. If it's present,
 // meta object is named after the original file.
 //
-// The only supported metadata format is simplejson atm.
-// It supports only per-file meta objects that are rudimentary,
+// The supported metadata formats are simplejson and sidecarjson atm.
+// They support only per-file meta objects that are rudimentary,
 // used mostly for consistency checks (lazily for performance reasons).
 // Other formats can be developed that use an external meta store
 // free of these limitations, but this needs some support from
@@ -75,8 +76,9 @@ import (
 // Control chunks have in that position a short lowercase alphanumeric
 // string (starting with a letter) prepended by underscore.
 //
-// Metadata format v1 does not define any control chunk types,
-// they are currently ignored aka reserved.
+// Metadata format v1 defines only the "map" control chunk used by
+// the sidecarjson format (see chunkmap.go), other control chunk types
+// are currently ignored aka reserved.
 // In future they can be used to implement resumable uploads etc.
 const (
 	ctrlTypeRegStr   = `[a-z][a-z0-9]{2,6}`
@@ -146,6 +148,7 @@ func init() {
 		Name:        "chunker",
 		Description: "Transparently chunk/split large files",
 		NewFs:       NewFs,
+		CommandHelp: commandHelp,
 		Options: []fs.Option{{
 			Name:     "remote",
 			Required: true,
@@ -196,7 +199,22 @@ Requires hash type "none".`,
 				Help: `Simple JSON supports hash sums and chunk validation.
 
 It has the following fields: ver, size, nchunks, md5, sha1.`,
+			}, {
+				Value: "sidecarjson",
+				Help: `Simple JSON plus a sidecar chunk map protected by an HMAC.
+
+The chunk map lists sizes and hashsums of all data chunks
+and can be checked with the "verify" backend command.`,
 			}},
+		}, {
+			Name:       "map_key",
+			Advanced:   true,
+			IsPassword: true,
+			Help: `Key for the HMAC of sidecar chunk maps.
+
+Only used with meta format "sidecarjson". Without a key the HMAC
+only detects accidental damage of chunk maps, set a key to also
+detect deliberate tampering.`,
 		}, {
 			Name:     "hash_type",
 			Advanced: false,
@@ -310,6 +328,14 @@ func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs,
 	}
 	f.dirSort = true // processEntries requires that meta Objects prerun data chunks atm.
 
+	if opt.MapKey != "" {
+		mapKey, err := obscure.Reveal(opt.MapKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt map key: %w", err)
+		}
+		f.mapKey = []byte(mapKey)
+	}
+
 	if err := f.configure(opt.NameFormat, opt.MetaFormat, opt.HashType, opt.Transactions); err != nil {
 		return nil, err
 	}
@@ -369,6 +395,7 @@ type Options struct {
 	NameFormat   string        `config:"name_format"`
 	StartFrom    int           `config:"start_from"`
 	MetaFormat   string        `config:"meta_format"`
+	MapKey       string        `config:"map_key"`
 	HashType     string        `config:"hash_type"`
 	FailHard     bool          `config:"fail_hard"`
 	Transactions string        `config:"transactions"`
@@ -381,6 +408,8 @@ type Fs struct {
 	base         fs.Fs          // remote wrapped by chunker overlay
 	wrapper      fs.Fs          // wrapper is used by SetWrapper
 	useMeta      bool           // false if metadata format is 'none'
+	useMap       bool           // true if metadata format is 'sidecarjson'
+	mapKey       []byte         // key for the HMAC of chunk maps
 	useMD5       bool           // mutually exclusive with useSHA1
 	useSHA1      bool           // mutually exclusive with useMD5
 	hashFallback bool           // allows fallback from MD5 to SHA1 and vice versa
@@ -423,8 +452,13 @@ func (f *Fs) setMetaFormat(metaFormat string) error {
 	switch metaFormat {
 	case "none":
 		f.useMeta = false
+		f.useMap = false
 	case "simplejson":
 		f.useMeta = true
+		f.useMap = false
+	case "sidecarjson":
+		f.useMeta = true
+		f.useMap = true
 	default:
 		return fmt.Errorf("unsupported meta format '%s'", metaFormat)
 	}
@@ -796,6 +830,12 @@ func (f *Fs) processEntries(ctx context.Context, origEntries fs.DirEntries, dirP
 			// this is some kind of chunk
 			// metobject should have been created above if present
 			mainObject := byRemote[mainRemote]
+			if f.useMap && ctrlType == ctrlTypeMap && xactID == "" {
+				if mainObject != nil {
+					mainObject.chunkMap = entry
+				}
+				break
+			}
 			isSpecial := xactID != txnByRemote[mainRemote] || ctrlType != ""
 			if mainObject == nil && f.useMeta && !isSpecial {
 				fs.Debugf(f, "skip orphan data chunk %q", remote)
@@ -975,6 +1015,10 @@ func (f *Fs) scanObject(ctx context.Context, remote string, quickScan bool) (fs.
 		if !sameMain {
 			continue // skip alien chunks
 		}
+		if f.useMap && ctrlType == ctrlTypeMap && xactID == "" {
+			o.chunkMap = entry
+			continue
+		}
 		if ctrlType != "" || xactID != currentXactID {
 			if f.useMeta {
 				// temporary/control chunk calls for lazy metadata read
@@ -1066,7 +1110,7 @@ func (o *Object) readMetadata(ctx context.Context) error {
 	}
 
 	switch o.f.opt.MetaFormat {
-	case "simplejson":
+	case "simplejson", "sidecarjson":
 		metaInfo, madeByChunker, err := unmarshalSimpleJSON(ctx, metaObject, metadata)
 		if o.unsure {
 			o.unsure = false
@@ -1125,7 +1169,7 @@ func (o *Object) readXactID(ctx context.Context) (xactID string, err error) {
 	}
 
 	switch o.f.opt.MetaFormat {
-	case "simplejson":
+	case "simplejson", "sidecarjson":
 		if len(data) > maxMetadataSizeWritten {
 			return "", nil // this was likely not a metadata object, return empty xactID but don't throw error
 		}
@@ -1243,6 +1287,10 @@ func (f *Fs) put(
 		c.chunkLimit = c.chunkSize
 
 		c.chunks = append(c.chunks, chunk)
+		if c.chunkHasher != nil {
+			c.chunkSums = append(c.chunkSums, hex.EncodeToString(c.chunkHasher.Sum(nil)))
+			c.chunkHasher.Reset()
+		}
 	}
 
 	// Validate uploaded size
@@ -1318,7 +1366,7 @@ func (f *Fs) put(
 	// Update meta object
 	var metadata []byte
 	switch f.opt.MetaFormat {
-	case "simplejson":
+	case "simplejson", "sidecarjson":
 		c.updateHashes()
 		metadata, err = marshalSimpleJSON(ctx, sizeTotal, len(c.chunks), c.md5, c.sha1, xactID)
 	}
@@ -1330,9 +1378,19 @@ func (f *Fs) put(
 		return nil, err
 	}
 
+	// Write the chunk map after metadata so it never outlives a failed upload
+	var mapObject fs.Object
+	if f.useMap {
+		mapObject, err = f.putChunkMap(ctx, c, src, baseRemote, sizeTotal, xactID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write chunk map: %w", err)
+		}
+	}
+
 	o := f.newObject("", metaObject, c.chunks)
 	o.size = sizeTotal
 	o.xactID = xactID
+	o.chunkMap = mapObject
 	return o, nil
 }
 
@@ -1353,6 +1411,8 @@ type chunkingReader struct {
 	smallHead    []byte
 	fs           *Fs
 	hasher       gohash.Hash
+	chunkHasher  gohash.Hash // hashes single chunks for the chunk map
+	chunkSums    []string
 	md5          string
 	sha1         string
 }
@@ -1366,6 +1426,9 @@ func (f *Fs) newChunkingReader(src fs.ObjectInfo) *chunkingReader {
 	c.chunkLimit = c.chunkSize
 	c.sizeLeft = c.sizeTotal
 	c.expectSingle = c.sizeTotal >= 0 && c.sizeTotal <= c.chunkSize
+	if f.useMap {
+		c.chunkHasher = f.newChunkHasher()
+	}
 	return c
 }
 
@@ -1446,6 +1509,9 @@ func (c *chunkingReader) Read(buf []byte) (bytesRead int, err error) {
 		return
 	}
 	c.accountBytes(int64(bytesRead))
+	if c.chunkHasher != nil {
+		_, _ = c.chunkHasher.Write(buf[:bytesRead])
+	}
 	if c.chunkNo == 0 && c.expectSingle && bytesRead > 0 && c.readCount <= maxMetadataSize {
 		c.smallHead = append(c.smallHead, buf[:bytesRead]...)
 	}
@@ -1468,7 +1534,7 @@ func (c *chunkingReader) accountBytes(bytesRead int64) {
 
 // dummyRead updates accounting, hashsums, etc. by simulating reads
 func (c *chunkingReader) dummyRead(in io.Reader, size int64) error {
-	if c.hasher == nil && c.readCount+size > maxMetadataSize {
+	if c.hasher == nil && c.chunkHasher == nil && c.readCount+size > maxMetadataSize {
 		c.accountBytes(size)
 		return nil
 	}
@@ -1505,6 +1571,11 @@ func (f *Fs) removeOldChunks(ctx context.Context, remote string) {
 				fs.Errorf(chunk, "Failed to remove old chunk: %v", err)
 			}
 		}
+		if oldObject.chunkMap != nil {
+			if err := oldObject.chunkMap.Remove(ctx); err != nil {
+				fs.Errorf(oldObject.chunkMap, "Failed to remove old chunk map: %v", err)
+			}
+		}
 	}
 }
 
@@ -1670,7 +1741,13 @@ func (o *Object) Remove(ctx context.Context) (err error) {
 		}
 	}
 
-	// There are no known control chunks to remove atm.
+	// Remove the chunk map, the only known control chunk atm.
+	if o.chunkMap != nil {
+		mapErr := o.chunkMap.Remove(ctx)
+		if err == nil {
+			err = mapErr
+		}
+	}
 	return err
 }
 
@@ -1714,8 +1791,13 @@ func (f *Fs) copyOrMove(ctx context.Context, o *Object, remote string, do copyMo
 		newChunks = append(newChunks, chunkResult)
 	}
 
+	// Copy or move the chunk map, the only known control chunk atm.
+	var mapObject fs.Object
+	if err == nil && o.chunkMap != nil && f.useMap {
+		mapObject, err = do(ctx, o.chunkMap, f.makeChunkName(remote, -1, ctrlTypeMap, ""))
+	}
+
 	// Copy or move old metadata.
-	// There are no known control chunks to move/copy atm.
 	var metaObject fs.Object
 	if err == nil && o.main != nil {
 		metaObject, err = do(ctx, o.main, remote)
@@ -1724,11 +1806,15 @@ func (f *Fs) copyOrMove(ctx context.Context, o *Object, remote string, do copyMo
 		for _, chunk := range newChunks {
 			silentlyRemove(ctx, chunk)
 		}
+		if mapObject != nil {
+			silentlyRemove(ctx, mapObject)
+		}
 		return nil, err
 	}
 
 	// Create wrapping object, calculate and validate total size
 	newObj := f.newObject(remote, metaObject, newChunks)
+	newObj.chunkMap = mapObject
 	err = newObj.validate()
 	if err != nil {
 		silentlyRemove(ctx, newObj)
@@ -1738,7 +1824,7 @@ func (f *Fs) copyOrMove(ctx context.Context, o *Object, remote string, do copyMo
 	// Update metadata
 	var metadata []byte
 	switch f.opt.MetaFormat {
-	case "simplejson":
+	case "simplejson", "sidecarjson":
 		metadata, err = marshalSimpleJSON(ctx, newObj.size, len(newChunks), md5, sha1, o.xactID)
 		if err == nil {
 			metaInfo := f.wrapInfo(metaObject, "", int64(len(metadata)))
@@ -1788,7 +1874,7 @@ func (f *Fs) okForServerSide(ctx context.Context, src fs.Object, opName string)
 		// ensure object is composite if need to re-read metadata
 		_ = obj.readMetadata(ctx)
 	}
-	requireMetaHash := obj.isComposite() && f.opt.MetaFormat == "simplejson"
+	requireMetaHash := obj.isComposite() && f.useMeta
 	if !requireMetaHash && !f.hashAll {
 		ok = true // hash is not required for metadata
 		return
@@ -1998,6 +2084,7 @@ type Object struct {
 	xIDCached bool        // true if xactID has been read
 	unsure    bool        // true if need to read metadata to detect object type
 	xactID    string      // transaction ID for "norename" or empty string for "renamed" chunks
+	chunkMap  fs.Object   // sidecar chunk map if meta format is 'sidecarjson'
 	md5       string
 	sha1      string
 	f         *Fs
@@ -2583,6 +2670,7 @@ var (
 	_ fs.Wrapper         = (*Fs)(nil)
 	_ fs.ChangeNotifier  = (*Fs)(nil)
 	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
 	_ fs.ObjectInfo      = (*ObjectInfo)(nil)
 	_ fs.Object          = (*Object)(nil)
 	_ fs.ObjectUnWrapper = (*Object)(nil)
@@ -3,6 +3,7 @@ package chunker
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/obscure"
 	"github.com/rclone/rclone/fs/fspath"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/object"
@@ -899,6 +901,90 @@ func testMD5AllSlow(t *testing.T, f *Fs) {
 	require.NoError(t, operations.Purge(ctx, baseFs, ""))
 }
 
+// Test that verify detects damage of composite files with sidecar chunk maps
+func testChunkMap(t *testing.T, f *Fs) {
+	ctx := context.Background()
+	fsResult := deriveFs(ctx, t, f, "chunkmap", settings{
+		"chunk_size":   "3B",
+		"name_format":  "*.#",
+		"hash_type":    "md5",
+		"transactions": "rename",
+		"meta_format":  "sidecarjson",
+		"map_key":      obscure.MustObscure("potato"),
+	})
+	chunkFs, ok := fsResult.(*Fs)
+	require.True(t, ok, "fs must be a chunker remote")
+	baseFs := chunkFs.base
+	defer func() {
+		require.NoError(t, operations.Purge(ctx, baseFs, ""))
+	}()
+	verify := func() error {
+		_, err := chunkFs.Command(ctx, "verify", nil, map[string]string{"hash": ""})
+		return err
+	}
+
+	obj := testPutFile(ctx, t, chunkFs, "file", "abcdefgh", "put composite file", true)
+	assert.NotNil(t, obj.(*Object).chunkMap, "chunk map must be created")
+	_ = testPutFile(ctx, t, chunkFs, "small", "ab", "put small file", true)
+	list, err := baseFs.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 6, len(list), "meta object, chunk map, 3 chunks and small file")
+	require.NoError(t, verify())
+
+	// Copy keeps the chunk map
+	copied, err := operations.Copy(ctx, chunkFs, nil, "copy", obj)
+	require.NoError(t, err)
+	assert.NotNil(t, copied.(*Object).chunkMap, "chunk map must be copied")
+	require.NoError(t, verify())
+	require.NoError(t, copied.Remove(ctx))
+	_, err = baseFs.NewObject(ctx, chunkFs.makeChunkName("copy", -1, ctrlTypeMap, ""))
+	assert.Equal(t, fs.ErrorObjectNotFound, err, "chunk map must be removed")
+
+	// Swap the first two chunks
+	move := func(from, to string) {
+		o, err := baseFs.NewObject(ctx, from)
+		require.NoError(t, err)
+		_, err = operations.Move(ctx, baseFs, nil, to, o)
+		require.NoError(t, err)
+	}
+	chunk0 := chunkFs.makeChunkName("file", 0, "", "")
+	chunk1 := chunkFs.makeChunkName("file", 1, "", "")
+	move(chunk0, "swap")
+	move(chunk1, chunk0)
+	move("swap", chunk1)
+	if _, err := baseFs.NewObject(ctx, chunk0); err == nil && baseFs.Hashes().Contains(hash.MD5) {
+		assert.Error(t, verify(), "swapped chunks must be detected")
+	}
+	move(chunk0, "swap")
+	move(chunk1, chunk0)
+	move("swap", chunk1)
+	require.NoError(t, verify())
+
+	// Remove the last chunk
+	chunk2 := chunkFs.makeChunkName("file", 2, "", "")
+	last, err := baseFs.NewObject(ctx, chunk2)
+	require.NoError(t, err)
+	require.NoError(t, last.Remove(ctx))
+	assert.Error(t, verify(), "missing chunk must be detected")
+	_ = testPutFile(ctx, t, chunkFs, "file", "abcdefgh", "put composite file again", true)
+	require.NoError(t, verify())
+
+	// Tamper with the chunk map
+	mapRemote := chunkFs.makeChunkName("file", -1, ctrlTypeMap, "")
+	mapObj, err := baseFs.NewObject(ctx, mapRemote)
+	require.NoError(t, err)
+	m, err := chunkFs.readChunkMap(ctx, mapObj)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), m.Size)
+	require.Len(t, m.Chunks, 3)
+	assert.Equal(t, "900150983cd24fb0d6963f7d28e17f72", m.Chunks[0].MD5)
+	m.Chunks[0], m.Chunks[1] = m.Chunks[1], m.Chunks[0]
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	_ = testPutFile(ctx, t, baseFs, mapRemote, string(data), "tamper chunk map", false)
+	assert.Error(t, verify(), "tampered chunk map must be detected")
+}
+
 // InternalTest dispatches all internal tests
 func (f *Fs) InternalTest(t *testing.T) {
 	t.Run("PutLarge", func(t *testing.T) {
@@ -934,6 +1020,9 @@ func (f *Fs) InternalTest(t *testing.T) {
 	t.Run("MD5AllSlow", func(t *testing.T) {
 		testMD5AllSlow(t, f)
 	})
+	t.Run("ChunkMap", func(t *testing.T) {
+		testChunkMap(t, f)
+	})
 }
 
 var _ fstests.InternalTester = (*Fs)(nil)
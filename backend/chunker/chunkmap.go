@@ -0,0 +1,364 @@
+package chunker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	gohash "hash"
+	"io"
+	"path"
+	"sort"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/walk"
+)
+
+// Meta format `sidecarjson` writes the usual simplejson meta object
+// and additionally keeps a chunk map in a control chunk next to it.
+//
+// The chunk map lists the size and hashsum of every data chunk and
+// is signed with an HMAC, so `rclone backend verify` can detect
+// missing, truncated or swapped chunks from directory listings and
+// hashsums of the wrapped remote, without downloading the data.
+const (
+	ctrlTypeMap       = "map"
+	chunkMapVersion   = 1
+	maxChunkMapSize   = 64 * 1024 * 1024
+	chunkMapHMACLabel = "rclone chunker map"
+)
+
+// chunkMap is the content of the chunk map control chunk
+type chunkMap struct {
+	Version int             `json:"ver"`
+	Size    int64           `json:"size"`           // total size of data chunks
+	MD5     string          `json:"md5,omitempty"`  // MD5 of composite file
+	SHA1    string          `json:"sha1,omitempty"` // SHA1 of composite file
+	XactID  string          `json:"txn,omitempty"`  // transaction ID of norename data chunks
+	Chunks  []chunkMapEntry `json:"chunks"`         // data chunks in order
+	HMAC    string          `json:"hmac,omitempty"` // HMAC-SHA256 of the map with this field empty
+}
+
+// chunkMapEntry describes a single data chunk
+type chunkMapEntry struct {
+	Size int64  `json:"size"`
+	MD5  string `json:"md5,omitempty"`
+	SHA1 string `json:"sha1,omitempty"`
+}
+
+// mapHashType returns the hash type used for chunks in the chunk map
+func (f *Fs) mapHashType() hash.Type {
+	if f.useSHA1 {
+		return hash.SHA1
+	}
+	return hash.MD5
+}
+
+// newChunkHasher returns a hasher for single chunks of the chunk map
+func (f *Fs) newChunkHasher() gohash.Hash {
+	if f.mapHashType() == hash.SHA1 {
+		return sha1.New()
+	}
+	return md5.New()
+}
+
+// sign calculates the HMAC of the map
+func (m *chunkMap) sign(key []byte) (string, error) {
+	unsigned := *m
+	unsigned.HMAC = ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, append([]byte(chunkMapHMACLabel), key...))
+	_, _ = mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// marshalChunkMap signs the chunk map and encodes it as JSON
+func marshalChunkMap(m *chunkMap, key []byte) (data []byte, err error) {
+	m.Version = chunkMapVersion
+	if m.HMAC, err = m.sign(key); err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
+}
+
+// unmarshalChunkMap decodes a chunk map and checks its HMAC
+func unmarshalChunkMap(data []byte, key []byte) (*chunkMap, error) {
+	var m chunkMap
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid chunk map: %w", err)
+	}
+	if m.Version < 1 {
+		return nil, errors.New("invalid chunk map: wrong version")
+	}
+	if m.Version > chunkMapVersion {
+		return nil, ErrMetaUnknown
+	}
+	want, err := m.sign(key)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(want), []byte(m.HMAC)) {
+		return nil, errors.New("chunk map HMAC mismatch - map is damaged or the key is wrong")
+	}
+	var total int64
+	for _, entry := range m.Chunks {
+		total += entry.Size
+	}
+	if total != m.Size {
+		return nil, fmt.Errorf("invalid chunk map: chunks add up to %d bytes, expected %d", total, m.Size)
+	}
+	return &m, nil
+}
+
+// readChunkMap reads and checks the chunk map stored in mapObject
+func (f *Fs) readChunkMap(ctx context.Context, mapObject fs.Object) (*chunkMap, error) {
+	if mapObject.Size() > maxChunkMapSize {
+		return nil, errors.New("chunk map is too big")
+	}
+	in, err := mapObject.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(in, maxChunkMapSize+1))
+	_ = in.Close() // ensure file handle is freed on windows
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalChunkMap(data, f.mapKey)
+}
+
+// putChunkMap writes the chunk map for a freshly uploaded composite file
+func (f *Fs) putChunkMap(ctx context.Context, c *chunkingReader, src fs.ObjectInfo, remote string, sizeTotal int64, xactID string) (fs.Object, error) {
+	m := &chunkMap{
+		Size:   sizeTotal,
+		MD5:    c.md5,
+		SHA1:   c.sha1,
+		XactID: xactID,
+		Chunks: make([]chunkMapEntry, len(c.chunks)),
+	}
+	for i, chunk := range c.chunks {
+		m.Chunks[i].Size = chunk.Size()
+		if i < len(c.chunkSums) {
+			if f.mapHashType() == hash.SHA1 {
+				m.Chunks[i].SHA1 = c.chunkSums[i]
+			} else {
+				m.Chunks[i].MD5 = c.chunkSums[i]
+			}
+		}
+	}
+	data, err := marshalChunkMap(m, f.mapKey)
+	if err != nil {
+		return nil, err
+	}
+	mapRemote := f.makeChunkName(remote, -1, ctrlTypeMap, "")
+	info := f.wrapInfo(src, mapRemote, int64(len(data)))
+	return f.base.Put(ctx, bytes.NewReader(data), info)
+}
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "verify",
+	Short: "Verify composite files against their chunk maps",
+	Long: `This checks composite files written with the "sidecarjson" meta
+format against their HMAC protected chunk maps.
+
+It reports chunk maps which are damaged or were signed with a different
+key, and data chunks which are missing, unexpected, have the wrong size
+or the wrong hashsum, for example because two chunks were swapped.
+
+Only directory listings and hashsums reported by the wrapped remote are
+used, so no file data is downloaded. If the wrapped remote computes
+hashsums slowly (e.g. local) or doesn't support the hash type, only
+sizes are checked unless the "hash" option is given. Filters apply.
+
+Usage Examples:
+
+    rclone backend verify chunker:
+    rclone backend verify chunker:path/to/dir -o hash
+    rclone rc backend/command command=verify fs=chunker:
+`,
+	Opts: map[string]string{
+		"hash": "Check hashsums even if the wrapped remote has to read the data to calculate them",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "verify":
+		_, forceHash := opt["hash"]
+		return nil, f.verify(ctx, forceHash)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// verifyGroup collects the wrapped objects of a composite file
+type verifyGroup struct {
+	main   fs.Object                    // meta object
+	ctrl   fs.Object                    // chunk map
+	chunks map[string]map[int]fs.Object // data chunks by xactID and number
+}
+
+// verify checks all composite files under the root against their
+// chunk maps and returns an error if any problems are found
+func (f *Fs) verify(ctx context.Context, forceHash bool) error {
+	if !f.useMap {
+		return errors.New("verify requires the sidecarjson meta format")
+	}
+	hashType := f.mapHashType()
+	useHash := forceHash || (f.base.Hashes().Contains(hashType) && !f.base.Features().SlowHash)
+	if !useHash {
+		fs.Infof(f, "Not checking %v hashsums of chunks, use -o hash to force", hashType)
+	}
+	fi := filter.GetConfig(ctx)
+
+	var checked, failed int
+	err := walk.Walk(ctx, f.base, "", true, -1, func(dir string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		groups := map[string]*verifyGroup{}
+		group := func(remote string) *verifyGroup {
+			g := groups[remote]
+			if g == nil {
+				g = &verifyGroup{chunks: map[string]map[int]fs.Object{}}
+				groups[remote] = g
+			}
+			return g
+		}
+		for _, entry := range entries {
+			o, ok := entry.(fs.Object)
+			if !ok {
+				continue
+			}
+			mainRemote, chunkNo, ctrlType, xactID := f.parseChunkName(o.Remote())
+			switch {
+			case mainRemote == "":
+				group(o.Remote()).main = o
+			case ctrlType == ctrlTypeMap && xactID == "":
+				group(mainRemote).ctrl = o
+			case ctrlType == "":
+				g := group(mainRemote)
+				if g.chunks[xactID] == nil {
+					g.chunks[xactID] = map[int]fs.Object{}
+				}
+				g.chunks[xactID][chunkNo] = o
+			}
+		}
+		for remote, g := range groups {
+			if g.ctrl == nil && len(g.chunks[""]) == 0 {
+				continue // non-chunked file or transaction in progress
+			}
+			if !fi.IncludeRemote(remote) {
+				continue
+			}
+			checked++
+			problems := f.verifyObject(ctx, remote, g, hashType, useHash)
+			for _, problem := range problems {
+				fs.Errorf(remote, "%s", problem)
+			}
+			if len(problems) > 0 {
+				failed++
+			} else {
+				fs.Debugf(remote, "OK")
+			}
+		}
+		return nil
+	})
+	fs.Infof(f, "Summary: %d composite files checked, %d failed", checked, failed)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d composite file(s) failed verification", failed)
+	}
+	return nil
+}
+
+// verifyObject returns the problems found with a single composite file
+func (f *Fs) verifyObject(ctx context.Context, remote string, g *verifyGroup, hashType hash.Type, useHash bool) (problems []string) {
+	if g.ctrl == nil {
+		return []string{"chunk map is missing"}
+	}
+	if g.main == nil {
+		problems = append(problems, "meta object is missing")
+	}
+	m, err := f.readChunkMap(ctx, g.ctrl)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	chunks := g.chunks[m.XactID]
+	chunkName := func(chunkNo int) string {
+		return path.Base(f.makeChunkName(remote, chunkNo, "", m.XactID))
+	}
+
+	// index expected hashsums to spot chunks stored in the wrong place
+	sums := make([]string, len(m.Chunks))
+	wantAt := map[string]int{}
+	for i, entry := range m.Chunks {
+		sums[i] = entry.MD5
+		if hashType == hash.SHA1 {
+			sums[i] = entry.SHA1
+		}
+		if _, found := wantAt[sums[i]]; sums[i] != "" && !found {
+			wantAt[sums[i]] = i
+		}
+	}
+
+	for i, entry := range m.Chunks {
+		chunk := chunks[i]
+		switch {
+		case chunk == nil:
+			problems = append(problems, fmt.Sprintf("chunk %d (%s) is missing", i+f.opt.StartFrom, chunkName(i)))
+			continue
+		case chunk.Size() != entry.Size:
+			problems = append(problems, fmt.Sprintf("chunk %d has size %d, expected %d", i+f.opt.StartFrom, chunk.Size(), entry.Size))
+			continue
+		case !useHash || sums[i] == "":
+			continue
+		}
+		sum, err := chunk.Hash(ctx, hashType)
+		if err != nil || sum == "" {
+			fs.Debugf(chunk, "Can't check %v hashsum: %v", hashType, err)
+			continue
+		}
+		if sum == sums[i] {
+			continue
+		}
+		if j, found := wantAt[sum]; found {
+			problems = append(problems, fmt.Sprintf("chunk %d holds the data of chunk %d - chunks swapped?", i+f.opt.StartFrom, j+f.opt.StartFrom))
+		} else {
+			problems = append(problems, fmt.Sprintf("chunk %d has wrong %v hashsum", i+f.opt.StartFrom, hashType))
+		}
+	}
+	var extra []int
+	for chunkNo := range chunks {
+		if chunkNo >= len(m.Chunks) {
+			extra = append(extra, chunkNo)
+		}
+	}
+	sort.Ints(extra)
+	for _, chunkNo := range extra {
+		problems = append(problems, fmt.Sprintf("unexpected chunk %d (%s)", chunkNo+f.opt.StartFrom, chunkName(chunkNo)))
+	}
+	return problems
+}
@@ -32,6 +32,7 @@ func init() {
 		Name:        "combine",
 		Description: "Combine several remotes into one",
 		NewFs:       NewFs,
+		CommandHelp: commandHelp,
 		MetadataInfo: &fs.MetadataInfo{
 			Help: `Any metadata supported by the underlying remote is read and written.`,
 		},
@@ -65,13 +66,16 @@ type Options struct {
 
 // Fs represents a combine of upstreams
 type Fs struct {
-	name      string               // name of this remote
-	features  *fs.Features         // optional features
-	opt       Options              // options for this Fs
-	root      string               // the path we are working on
-	hashSet   hash.Set             // common hashes
-	when      time.Time            // directory times
-	upstreams map[string]*upstream // map of upstreams
+	name       string                     // name of this remote
+	opt        Options                    // options for this Fs
+	root       string                     // the path we are working on
+	when       time.Time                  // directory times
+	mu         sync.RWMutex               // protects the following
+	features   *fs.Features               // optional features
+	hashSet    hash.Set                   // common hashes
+	upstreams  map[string]*upstream       // map of upstreams
+	notifyFunc func(string, fs.EntryType) // set by ChangeNotify
+	notifySync chan struct{}              // set by ChangeNotify - signalled when the upstreams change
 }
 
 // adjustment stores the info to add a prefix to a path or chop characters off
@@ -128,6 +132,7 @@ type upstream struct {
 	f              fs.Fs
 	parent         *Fs
 	dir            string     // directory the upstream is mounted
+	remote         string     // remote the upstream was made from
 	pathAdjustment adjustment // how to fiddle with the path
 }
 
@@ -144,6 +149,7 @@ func (f *Fs) newUpstream(ctx context.Context, dir, remote string) (*upstream, er
 		f:              uFs,
 		parent:         f,
 		dir:            dir,
+		remote:         remote,
 		pathAdjustment: newAdjustment(f.root, dir),
 	}
 	cache.PinUntilFinalized(u.f, u)
@@ -189,19 +195,9 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (outFs fs
 	for _, upstream := range opt.Upstreams {
 		upstream := upstream
 		g.Go(func() (err error) {
-			equal := strings.IndexRune(upstream, '=')
-			if equal < 0 {
-				return fmt.Errorf("no \"=\" in upstream definition %q", upstream)
-			}
-			dir, remote := upstream[:equal], upstream[equal+1:]
-			if dir == "" {
-				return fmt.Errorf("empty dir in upstream definition %q", upstream)
-			}
-			if remote == "" {
-				return fmt.Errorf("empty remote in upstream definition %q", upstream)
-			}
-			if strings.ContainsRune(dir, '/') {
-				return fmt.Errorf("dirs can't contain / (yet): %q", dir)
+			dir, remote, err := parseUpstream(upstream)
+			if err != nil {
+				return err
 			}
 			u, err := f.newUpstream(gCtx, dir, remote)
 			if err != nil {
@@ -221,7 +217,40 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (outFs fs
 	if err != nil {
 		return nil, err
 	}
-	// check features
+	f.features = f.makeFeatures(ctx)
+
+	// Get common intersection of hashes
+	f.hashSet = f.commonHashes()
+
+	// Check to see if the root is actually a file
+	if f.root != "" && !isDir {
+		_, err := f.NewObject(ctx, "")
+		if err != nil {
+			if err == fs.ErrorObjectNotFound || err == fs.ErrorNotAFile || err == fs.ErrorIsDir {
+				// File doesn't exist or is a directory so return old f
+				return f, nil
+			}
+			return nil, err
+		}
+
+		// Check to see if the root path is actually an existing file
+		f.root = path.Dir(f.root)
+		if f.root == "." {
+			f.root = ""
+		}
+		// Adjust path adjustment to remove leaf
+		for _, u := range f.upstreams {
+			u.pathAdjustment = newAdjustment(f.root, u.dir)
+		}
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// makeFeatures works out the features of f from its upstreams
+//
+// Call with f.mu held or before f is in use.
+func (f *Fs) makeFeatures(ctx context.Context) *fs.Features {
 	var features = (&fs.Features{
 		CaseInsensitive:          true,
 		DuplicateFiles:           false,
@@ -322,9 +351,32 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (outFs fs
 	// show that we wrap other backends
 	features.Overlay = true
 
-	f.features = features
+	return features
+}
 
-	// Get common intersection of hashes
+// parseUpstream splits an upstream definition of the form dir=remote:path
+func parseUpstream(upstream string) (dir, remote string, err error) {
+	equal := strings.IndexRune(upstream, '=')
+	if equal < 0 {
+		return "", "", fmt.Errorf("no \"=\" in upstream definition %q", upstream)
+	}
+	dir, remote = upstream[:equal], upstream[equal+1:]
+	if dir == "" {
+		return "", "", fmt.Errorf("empty dir in upstream definition %q", upstream)
+	}
+	if remote == "" {
+		return "", "", fmt.Errorf("empty remote in upstream definition %q", upstream)
+	}
+	if strings.ContainsRune(dir, '/') {
+		return "", "", fmt.Errorf("dirs can't contain / (yet): %q", dir)
+	}
+	return dir, remote, nil
+}
+
+// commonHashes returns the intersection of the hashes of the upstreams
+//
+// Call with f.mu held or before f is in use.
+func (f *Fs) commonHashes() hash.Set {
 	var hashSet hash.Set
 	var first = true
 	for _, u := range f.upstreams {
@@ -335,37 +387,24 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (outFs fs
 			hashSet = hashSet.Overlap(u.f.Hashes())
 		}
 	}
-	f.hashSet = hashSet
-
-	// Check to see if the root is actually a file
-	if f.root != "" && !isDir {
-		_, err := f.NewObject(ctx, "")
-		if err != nil {
-			if err == fs.ErrorObjectNotFound || err == fs.ErrorNotAFile || err == fs.ErrorIsDir {
-				// File doesn't exist or is a directory so return old f
-				return f, nil
-			}
-			return nil, err
-		}
+	return hashSet
+}
 
-		// Check to see if the root path is actually an existing file
-		f.root = path.Dir(f.root)
-		if f.root == "." {
-			f.root = ""
-		}
-		// Adjust path adjustment to remove leaf
-		for _, u := range f.upstreams {
-			u.pathAdjustment = newAdjustment(f.root, u.dir)
-		}
-		return f, fs.ErrorIsFile
+// upstreamList returns a snapshot of the current upstreams
+func (f *Fs) upstreamList() []*upstream {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	us := make([]*upstream, 0, len(f.upstreams))
+	for _, u := range f.upstreams {
+		us = append(us, u)
 	}
-	return f, nil
+	return us
 }
 
 // Run a function over all the upstreams in parallel
 func (f *Fs) multithread(ctx context.Context, fn func(context.Context, *upstream) error) error {
 	g, gCtx := errgroup.WithContext(ctx)
-	for _, u := range f.upstreams {
+	for _, u := range f.upstreamList() {
 		u := u
 		g.Go(func() (err error) {
 			return fn(gCtx, u)
@@ -389,6 +428,8 @@ func join(elem ...string) string {
 // find the upstream for the remote passed in, returning the upstream and the adjusted path
 func (f *Fs) findUpstream(remote string) (u *upstream, uRemote string, err error) {
 	// defer log.Trace(remote, "")("f=%v, uRemote=%q, err=%v", &u, &uRemote, &err)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	for _, u := range f.upstreams {
 		uRemote, err = u.pathAdjustment.undo(remote)
 		if err == nil {
@@ -415,6 +456,8 @@ func (f *Fs) String() string {
 
 // Features returns the optional features of this Fs
 func (f *Fs) Features() *fs.Features {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	return f.features
 }
 
@@ -433,6 +476,8 @@ func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 
 // Hashes returns hash.HashNone to indicate remote hashing is unavailable
 func (f *Fs) Hashes() hash.Set {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	return f.hashSet
 }
 
@@ -630,36 +675,94 @@ func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string
 // regularly. When the channel gets closed, the implementation
 // should stop polling and release resources.
 func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), ch <-chan time.Duration) {
-	var uChans []chan time.Duration
+	notifySync := make(chan struct{}, 1)
+	f.mu.Lock()
+	f.notifyFunc = notifyFunc
+	f.notifySync = notifySync
+	f.mu.Unlock()
+	go f.changeNotifyLoop(ctx, ch, notifySync)
+}
 
-	for _, u := range f.upstreams {
-		u := u
-		if do := u.f.Features().ChangeNotify; do != nil {
-			ch := make(chan time.Duration)
-			uChans = append(uChans, ch)
-			wrappedNotifyFunc := func(path string, entryType fs.EntryType) {
-				newPath, err := u.pathAdjustment.do(path)
-				if err != nil {
-					fs.Logf(f, "ChangeNotify: unable to process %q: %s", path, err)
-					return
-				}
-				fs.Debugf(f, "ChangeNotify: path %q entryType %d", newPath, entryType)
-				notifyFunc(newPath, entryType)
+// changeNotifyLoop subscribes to ChangeNotify on the upstreams,
+// keeping the subscriptions in step with the upstreams whenever
+// notifySync is signalled, and passes on the poll intervals from ch.
+func (f *Fs) changeNotifyLoop(ctx context.Context, ch <-chan time.Duration, notifySync <-chan struct{}) {
+	var (
+		uChans      = map[fs.Fs]chan time.Duration{}
+		interval    time.Duration
+		gotInterval = false
+	)
+	syncUpstreams := func() {
+		// Find the upstream Fs which can notify
+		want := map[fs.Fs]func(context.Context, func(string, fs.EntryType), <-chan time.Duration){}
+		for _, u := range f.upstreamList() {
+			if do := u.f.Features().ChangeNotify; do != nil {
+				want[u.f] = do
+			}
+		}
+		for uFs, c := range uChans {
+			if _, found := want[uFs]; !found {
+				close(c)
+				delete(uChans, uFs)
+			}
+		}
+		for uFs, do := range want {
+			if _, found := uChans[uFs]; found {
+				continue
+			}
+			c := make(chan time.Duration)
+			uChans[uFs] = c
+			do(ctx, f.upstreamNotifyFunc(uFs), c)
+			if gotInterval {
+				c <- interval
 			}
-			do(ctx, wrappedNotifyFunc, ch)
 		}
 	}
-
-	go func() {
-		for i := range ch {
+	syncUpstreams()
+	for {
+		select {
+		case i, ok := <-ch:
+			if !ok {
+				for _, c := range uChans {
+					close(c)
+				}
+				return
+			}
+			interval, gotInterval = i, true
 			for _, c := range uChans {
 				c <- i
 			}
+		case <-notifySync:
+			syncUpstreams()
+		}
+	}
+}
+
+// upstreamNotifyFunc returns a function to pass to the ChangeNotify
+// of uFs which passes on changes for all the upstreams currently
+// using uFs with their paths adjusted.
+func (f *Fs) upstreamNotifyFunc(uFs fs.Fs) func(string, fs.EntryType) {
+	return func(path string, entryType fs.EntryType) {
+		var newPaths []string
+		f.mu.RLock()
+		notifyFunc := f.notifyFunc
+		for _, u := range f.upstreams {
+			if u.f != uFs {
+				continue
+			}
+			newPath, err := u.pathAdjustment.do(path)
+			if err != nil {
+				fs.Logf(f, "ChangeNotify: unable to process %q: %s", path, err)
+				continue
+			}
+			newPaths = append(newPaths, newPath)
 		}
-		for _, c := range uChans {
-			close(c)
+		f.mu.RUnlock()
+		for _, newPath := range newPaths {
+			fs.Debugf(f, "ChangeNotify: path %q entryType %d", newPath, entryType)
+			notifyFunc(newPath, entryType)
 		}
-	}()
+	}
 }
 
 // DirCacheFlush resets the directory cache - used in testing
@@ -737,7 +840,7 @@ func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
 		Free:    new(int64),
 		Objects: new(int64),
 	}
-	for _, u := range f.upstreams {
+	for _, u := range f.upstreamList() {
 		doAbout := u.f.Features().About
 		if doAbout == nil {
 			continue
@@ -832,9 +935,10 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
 	// defer log.Trace(f, "dir=%q", dir)("entries = %v, err=%v", &entries, &err)
 	if f.root == "" && dir == "" {
-		entries := make(fs.DirEntries, 0, len(f.upstreams))
-		for combineDir := range f.upstreams {
-			d := fs.NewLimitedDirWrapper(combineDir, fs.NewDir(combineDir, f.when))
+		us := f.upstreamList()
+		entries := make(fs.DirEntries, 0, len(us))
+		for _, u := range us {
+			d := fs.NewLimitedDirWrapper(u.dir, fs.NewDir(u.dir, f.when))
 			entries = append(entries, d)
 		}
 		return callback(entries)
@@ -943,7 +1047,7 @@ func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 // Precision is the greatest Precision of all upstreams
 func (f *Fs) Precision() time.Duration {
 	var greatestPrecision time.Duration
-	for _, u := range f.upstreams {
+	for _, u := range f.upstreamList() {
 		uPrecision := u.f.Precision()
 		if uPrecision > greatestPrecision {
 			greatestPrecision = uPrecision
@@ -1073,6 +1177,221 @@ func (f *Fs) OpenWriterAt(ctx context.Context, remote string, size int64) (fs.Wr
 	return do(ctx, uRemote, size)
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "upstreams",
+	Short: "Show the current upstreams",
+	Long: `This returns a map of the directories in the combine remote to the
+remotes mounted on them.
+
+Usage Example:
+
+    rclone backend upstreams combine:
+    rclone rc backend/command command=upstreams fs=combine:
+`,
+}, {
+	Name:  "add",
+	Short: "Add upstreams",
+	Long: `This adds the upstreams given as arguments to the combine remote.
+They take the same form as in the upstreams config setting.
+
+Usage Example:
+
+    rclone backend add combine: dir=remote:path [dir2=remote2:path...]
+    rclone rc backend/command command=add fs=combine: dir=remote:path
+
+The changes take effect immediately in any running mount or serve
+using the remote (use it through the rc to change a running instance),
+but they are not written to the config file.
+`,
+}, {
+	Name:  "remove",
+	Short: "Remove upstreams",
+	Long: `This removes the upstreams mounted on the directories given as
+arguments from the combine remote. No files are deleted.
+
+Usage Example:
+
+    rclone backend remove combine: dir [dir2...]
+    rclone rc backend/command command=remove fs=combine: dir
+`,
+}, {
+	Name:  "rename",
+	Short: "Rename the directory an upstream is mounted on",
+	Long: `This moves the upstream mounted on the first directory given to the
+second. No files are moved.
+
+Usage Example:
+
+    rclone backend rename combine: olddir newdir
+    rclone rc backend/command command=rename fs=combine: olddir newdir
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "upstreams":
+		out := map[string]string{}
+		for _, u := range f.upstreamList() {
+			out[u.dir] = u.remote
+		}
+		return out, nil
+	case "add":
+		if len(arg) == 0 {
+			return nil, errors.New("need at least one dir=remote:path argument")
+		}
+		return nil, f.addUpstreams(ctx, arg)
+	case "remove":
+		if len(arg) == 0 {
+			return nil, errors.New("need at least one dir argument")
+		}
+		return nil, f.removeUpstreams(ctx, arg)
+	case "rename":
+		if len(arg) != 2 {
+			return nil, errors.New("need old and new dir arguments")
+		}
+		return nil, f.renameUpstream(ctx, arg[0], arg[1])
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// addUpstreams makes the upstreams from the definitions passed in
+// and adds them to f
+func (f *Fs) addUpstreams(ctx context.Context, defs []string) error {
+	newUpstreams := make(map[string]*upstream, len(defs))
+	for _, def := range defs {
+		dir, remote, err := parseUpstream(def)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(remote, f.name+":") {
+			return errors.New("can't point combine remote at itself")
+		}
+		if _, found := newUpstreams[dir]; found {
+			return fmt.Errorf("duplicate directory name %q", dir)
+		}
+		u, err := f.newUpstream(ctx, dir, remote)
+		if err != nil {
+			return err
+		}
+		newUpstreams[dir] = u
+	}
+	f.mu.Lock()
+	for dir := range newUpstreams {
+		if _, found := f.upstreams[dir]; found {
+			f.mu.Unlock()
+			return fmt.Errorf("duplicate directory name %q", dir)
+		}
+	}
+	for dir, u := range newUpstreams {
+		f.upstreams[dir] = u
+		fs.Infof(f, "Added upstream %q=%q", dir, u.remote)
+	}
+	f.upstreamsChanged(ctx)
+	f.mu.Unlock()
+	f.notifyDirs(newUpstreams)
+	return nil
+}
+
+// removeUpstreams removes the upstreams mounted on dirs from f
+func (f *Fs) removeUpstreams(ctx context.Context, dirs []string) error {
+	f.mu.Lock()
+	removed := make(map[string]*upstream, len(dirs))
+	for _, dir := range dirs {
+		u, found := f.upstreams[dir]
+		if !found {
+			f.mu.Unlock()
+			return fmt.Errorf("no upstream on directory %q", dir)
+		}
+		removed[dir] = u
+	}
+	if len(removed) >= len(f.upstreams) {
+		f.mu.Unlock()
+		return errors.New("can't remove all the upstreams")
+	}
+	for dir := range removed {
+		delete(f.upstreams, dir)
+		fs.Infof(f, "Removed upstream %q", dir)
+	}
+	f.upstreamsChanged(ctx)
+	f.mu.Unlock()
+	f.notifyDirs(removed)
+	return nil
+}
+
+// renameUpstream moves the upstream mounted on oldDir to newDir
+func (f *Fs) renameUpstream(ctx context.Context, oldDir, newDir string) error {
+	if newDir == "" || strings.ContainsRune(newDir, '/') {
+		return fmt.Errorf("invalid directory name %q", newDir)
+	}
+	f.mu.Lock()
+	u, found := f.upstreams[oldDir]
+	if !found {
+		f.mu.Unlock()
+		return fmt.Errorf("no upstream on directory %q", oldDir)
+	}
+	if _, found := f.upstreams[newDir]; found {
+		f.mu.Unlock()
+		return fmt.Errorf("duplicate directory name %q", newDir)
+	}
+	newU := &upstream{
+		f:              u.f,
+		parent:         f,
+		dir:            newDir,
+		remote:         u.remote,
+		pathAdjustment: newAdjustment(f.root, newDir),
+	}
+	delete(f.upstreams, oldDir)
+	f.upstreams[newDir] = newU
+	fs.Infof(f, "Renamed upstream %q to %q", oldDir, newDir)
+	f.upstreamsChanged(ctx)
+	f.mu.Unlock()
+	f.notifyDirs(map[string]*upstream{oldDir: u, newDir: newU})
+	return nil
+}
+
+// upstreamsChanged updates the state derived from the upstreams
+//
+// Call with f.mu held.
+func (f *Fs) upstreamsChanged(ctx context.Context) {
+	f.hashSet = f.commonHashes()
+	f.features = f.makeFeatures(ctx)
+	// Tell changeNotifyLoop to resubscribe if running
+	if f.notifySync != nil {
+		select {
+		case f.notifySync <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// notifyDirs tells any ChangeNotify listener that the directories
+// the upstreams are mounted on have changed
+func (f *Fs) notifyDirs(us map[string]*upstream) {
+	f.mu.RLock()
+	notifyFunc := f.notifyFunc
+	f.mu.RUnlock()
+	if notifyFunc == nil {
+		return
+	}
+	for _, u := range us {
+		dir, err := u.pathAdjustment.do("")
+		if err != nil {
+			continue
+		}
+		notifyFunc(dir, fs.EntryDirectory)
+	}
+}
+
 // Object describes a wrapped Object
 //
 // This is a wrapped Object which knows its path prefix
@@ -1180,6 +1499,7 @@ var (
 	_ fs.Copier          = (*Fs)(nil)
 	_ fs.Mover           = (*Fs)(nil)
 	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
 	_ fs.DirCacheFlusher = (*Fs)(nil)
 	_ fs.ChangeNotifier  = (*Fs)(nil)
 	_ fs.Abouter         = (*Fs)(nil)
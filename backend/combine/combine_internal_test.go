@@ -1,10 +1,16 @@
 package combine
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	_ "github.com/rclone/rclone/backend/local"
+	_ "github.com/rclone/rclone/backend/memory"
+	"github.com/rclone/rclone/fs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAdjustmentDo(t *testing.T) {
@@ -92,3 +98,78 @@ func TestAdjustmentUndo(t *testing.T) {
 	}
 
 }
+
+func TestUpstreamCommands(t *testing.T) {
+	ctx := context.Background()
+	f, err := fs.NewFs(ctx, ":combine,upstreams='a=:memory:combine-a b=:memory:combine-b':")
+	require.NoError(t, err)
+	cf := f.(*Fs)
+
+	listRoot := func() (dirs []string) {
+		entries, err := f.List(ctx, "")
+		require.NoError(t, err)
+		for _, entry := range entries {
+			dirs = append(dirs, entry.Remote())
+		}
+		return dirs
+	}
+	var notified []string
+	cf.ChangeNotify(ctx, func(path string, entryType fs.EntryType) {
+		assert.Equal(t, fs.EntryDirectory, entryType)
+		notified = append(notified, path)
+	}, make(chan time.Duration))
+
+	out, err := cf.Command(ctx, "upstreams", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": ":memory:combine-a", "b": ":memory:combine-b"}, out)
+
+	_, err = cf.Command(ctx, "add", []string{"c=:memory:combine-c"}, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, listRoot())
+	assert.Equal(t, []string{"c"}, notified)
+
+	_, err = cf.Command(ctx, "add", []string{"c=:memory:combine-d"}, nil)
+	assert.ErrorContains(t, err, "duplicate")
+	_, err = cf.Command(ctx, "add", []string{"no-equals"}, nil)
+	assert.Error(t, err)
+
+	_, err = cf.Command(ctx, "rename", []string{"c", "d"}, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "d"}, listRoot())
+	u, uRemote, err := cf.findUpstream("d/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "d", u.dir)
+	assert.Equal(t, "file.txt", uRemote)
+
+	// Changes in the upstream are notified under its new name
+	notified = nil
+	cf.upstreamNotifyFunc(u.f)("dir", fs.EntryDirectory)
+	assert.Equal(t, []string{"d/dir"}, notified)
+
+	_, err = cf.Command(ctx, "remove", []string{"a", "d"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, listRoot())
+	_, _, err = cf.findUpstream("a/file.txt")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+
+	_, err = cf.Command(ctx, "remove", []string{"b"}, nil)
+	assert.Error(t, err)
+	_, err = cf.Command(ctx, "remove", []string{"nope"}, nil)
+	assert.Error(t, err)
+}
+
+func TestUpstreamFeatures(t *testing.T) {
+	ctx := context.Background()
+	f, err := fs.NewFs(ctx, ":combine,upstreams='a="+t.TempDir()+"':")
+	require.NoError(t, err)
+	cf := f.(*Fs)
+
+	// local supports OpenWriterAt but memory doesn't
+	assert.NotNil(t, f.Features().OpenWriterAt)
+	_, err = cf.Command(ctx, "add", []string{"b=:memory:combine-features"}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, f.Features().OpenWriterAt)
+	_, err = cf.Command(ctx, "remove", []string{"b"}, nil)
+	require.NoError(t, err)
+	assert.NotNil(t, f.Features().OpenWriterAt)
+}
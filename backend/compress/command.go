@@ -0,0 +1,159 @@
+package compress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// Suffix used for the staged copy of an object while it is being
+// recompressed
+const recompressSuffix = ".rclone-recompress"
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "recompress",
+	Short: "Rewrite stored objects with the configured compression",
+	Long: `This rewrites the objects under the remote so that they are stored
+with the currently configured compression mode and level.
+
+Each object is streamed through rclone, recompressed into a staged copy
+next to the original and the MD5 of the uncompressed data is checked
+against the stored value before the original is replaced. Filters
+apply so it can be used to migrate part of a remote.
+
+Objects already stored with the configured mode are skipped. As the
+compression level isn't recorded with the object, use the "force"
+option to rewrite them after changing the level.
+
+Usage Examples:
+
+    rclone backend recompress compress:
+    rclone backend recompress compress:path/to/dir -o force
+    rclone rc backend/command command=recompress fs=compress: -o force
+
+Use --dry-run to see which objects would be rewritten.
+`,
+	Opts: map[string]string{
+		"force": "Recompress all objects, even those already in the configured mode",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "recompress":
+		_, force := opt["force"]
+		return nil, f.recompressAll(ctx, force)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// recompressAll rewrites every object matching the filters which
+// isn't stored in the configured compression mode (or every object if
+// force is set)
+func (f *Fs) recompressAll(ctx context.Context, force bool) error {
+	var done, skipped, failed int
+	err := operations.ListFn(ctx, f, func(obj fs.Object) {
+		o, ok := obj.(*Object)
+		if !ok || strings.HasSuffix(o.Remote(), recompressSuffix) {
+			return
+		}
+		if err := o.loadMetadataIfNotLoaded(ctx); err != nil {
+			fs.Errorf(o, "Failed to read metadata: %v", err)
+			failed++
+			return
+		}
+		if !force && o.meta.Mode == f.mode {
+			skipped++
+			return
+		}
+		if operations.SkipDestructive(ctx, o, "recompress") {
+			return
+		}
+		tr := accounting.Stats(ctx).NewTransfer(o, f)
+		err := f.recompress(ctx, o, tr)
+		tr.Done(ctx, err)
+		if err != nil {
+			fs.Errorf(o, "Failed to recompress: %v", err)
+			failed++
+			return
+		}
+		done++
+	})
+	fs.Infof(f, "Summary: %d recompressed, %d skipped, %d failed", done, skipped, failed)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to recompress %d object(s)", failed)
+	}
+	return nil
+}
+
+// recompress streams o into a staged object written with the current
+// settings, checks the data arrived intact and then replaces o with
+// it.
+func (f *Fs) recompress(ctx context.Context, o *Object, tr *accounting.Transfer) (err error) {
+	remote := o.Remote()
+	in, err := o.Open(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open: %w", err)
+	}
+	in = tr.Account(ctx, in).WithBuffer()
+	staged, err := f.Put(ctx, in, fs.NewOverrideRemote(o, remote+recompressSuffix))
+	closeErr := in.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if staged != nil {
+			_ = staged.Remove(ctx)
+		}
+		return fmt.Errorf("failed to write staged copy: %w", err)
+	}
+	newObj, ok := staged.(*Object)
+	if !ok {
+		_ = staged.Remove(ctx)
+		return errors.New("staged copy has unexpected type")
+	}
+	if newObj.meta.MD5 != o.meta.MD5 {
+		_ = newObj.Remove(ctx)
+		return fmt.Errorf("corrupted on transfer: md5 differs %q vs %q", o.meta.MD5, newObj.meta.MD5)
+	}
+
+	// Prefer a server-side move of the staged copy over the original
+	if f.Fs.Features().Move != nil {
+		_, err = f.Move(ctx, newObj, remote)
+		if err != nil {
+			return fmt.Errorf("failed to move staged copy into place: %w", err)
+		}
+		return nil
+	}
+
+	// Otherwise copy the staged data over the original
+	fs.Debugf(o, "Underlying remote can't move, updating from staged copy")
+	rc, err := newObj.Open(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open staged copy: %w", err)
+	}
+	err = o.Update(ctx, rc, fs.NewOverrideRemote(newObj, remote))
+	fs.CheckClose(rc, &err)
+	if err != nil {
+		return fmt.Errorf("failed to update from staged copy: %w", err)
+	}
+	return newObj.Remove(ctx)
+}
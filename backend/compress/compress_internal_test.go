@@ -0,0 +1,56 @@
+package compress
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecompress(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	tempRoot, err := fstest.LocalRemote()
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tempRoot)
+	}()
+
+	// Write a compressible file without compression
+	plainFs, err := fs.NewFs(ctx, fmt.Sprintf(`:compress,remote="%s",mode=none:`, tempRoot))
+	require.NoError(t, err)
+	contents := strings.Repeat("compress me please ", 10000)
+	item := fstest.Item{Path: "dir/file.txt", ModTime: fstest.Time("2001-02-03T04:05:06.499999999Z")}
+	o := fstests.PutTestContents(ctx, t, plainFs, &item, contents, true)
+	require.Equal(t, Uncompressed, o.(*Object).meta.Mode)
+
+	// Recompress it with gzip
+	gzipFs, err := fs.NewFs(ctx, fmt.Sprintf(`:compress,remote="%s",mode=gzip:`, tempRoot))
+	require.NoError(t, err)
+	_, err = gzipFs.(*Fs).Command(ctx, "recompress", nil, nil)
+	require.NoError(t, err)
+
+	o, err = gzipFs.NewObject(ctx, item.Path)
+	require.NoError(t, err)
+	assert.Equal(t, Gzip, o.(*Object).meta.Mode)
+	assert.Equal(t, int64(len(contents)), o.Size())
+	assert.Equal(t, contents, fstests.ReadObject(ctx, t, o, -1))
+
+	// No staged copies or stale data should be left behind
+	entries, err := gzipFs.List(ctx, "dir")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Running it again should skip the object
+	_, err = gzipFs.(*Fs).Command(ctx, "recompress", nil, nil)
+	require.NoError(t, err)
+}
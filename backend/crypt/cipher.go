@@ -56,6 +56,7 @@ var (
 	ErrorNotAnEncryptedFile      = errors.New("not an encrypted file - does not match suffix")
 	ErrorBadSeek                 = errors.New("Seek beyond end of file")
 	ErrorSuffixMissingDot        = errors.New("suffix config setting should include a '.'")
+	ErrorMetadataTooShort        = errors.New("metadata value is too short to be encrypted")
 	defaultSalt                  = []byte{0xA8, 0x0D, 0xF4, 0x3A, 0x8F, 0xBD, 0x03, 0x08, 0xA7, 0xCA, 0xB8, 0x3E, 0x58, 0x1F, 0x86, 0xB1}
 	obfuscQuoteRune              = '!'
 )
@@ -1145,6 +1146,73 @@ func (c *Cipher) DecryptedSize(size int64) (int64, error) {
 	return decryptedSize, nil
 }
 
+// encryptMetadataValue encrypts a metadata value
+//
+// This uses secretbox with a random nonce which is stored in front of
+// the ciphertext. The result is encoded with URL safe base64 so it can
+// be stored in the metadata of any remote.
+func (c *Cipher) encryptMetadataValue(plaintext string) (string, error) {
+	var nonce nonce
+	err := nonce.fromReader(c.cryptoRand)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := secretbox.Seal(nonce[:], []byte(plaintext), nonce.pointer(), &c.dataKey)
+	return base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+// decryptMetadataValue decrypts a metadata value encrypted with
+// encryptMetadataValue
+func (c *Cipher) decryptMetadataValue(ciphertext string) (string, error) {
+	rawCiphertext, err := base64.RawURLEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(rawCiphertext) < fileNonceSize+secretbox.Overhead {
+		return "", ErrorMetadataTooShort
+	}
+	var nonce nonce
+	nonce.fromBuf(rawCiphertext[:fileNonceSize])
+	plaintext, ok := secretbox.Open(nil, rawCiphertext[fileNonceSize:], nonce.pointer(), &c.dataKey)
+	if !ok {
+		return "", ErrorEncryptedBadBlock
+	}
+	return string(plaintext), nil
+}
+
+// encryptMetadataKey encrypts a metadata key
+//
+// This uses EME in the same way as encryptSegment so the same key
+// always encrypts to the same thing. The result is always encoded
+// with lower case base32 as many remotes treat metadata keys case
+// insensitively.
+func (c *Cipher) encryptMetadataKey(plaintext string) string {
+	if plaintext == "" {
+		return ""
+	}
+	paddedPlaintext := pkcs7.Pad(nameCipherBlockSize, []byte(plaintext))
+	ciphertext := eme.Transform(c.block, c.nameTweak[:], paddedPlaintext, eme.DirectionEncrypt)
+	return caseInsensitiveBase32Encoding{}.EncodeToString(ciphertext)
+}
+
+// decryptMetadataKey decrypts a metadata key encrypted with
+// encryptMetadataKey
+func (c *Cipher) decryptMetadataKey(ciphertext string) (string, error) {
+	rawCiphertext, err := caseInsensitiveBase32Encoding{}.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(rawCiphertext) == 0 || len(rawCiphertext)%nameCipherBlockSize != 0 {
+		return "", ErrorNotAMultipleOfBlocksize
+	}
+	paddedPlaintext := eme.Transform(c.block, c.nameTweak[:], rawCiphertext, eme.DirectionDecrypt)
+	plaintext, err := pkcs7.Unpad(nameCipherBlockSize, paddedPlaintext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
 // check interfaces
 var (
 	_ io.ReadCloser  = (*decrypter)(nil)
@@ -1640,3 +1640,45 @@ func TestKey(t *testing.T) {
 	assert.Equal(t, [32]byte{}, c.nameKey)
 	assert.Equal(t, [16]byte{}, c.nameTweak)
 }
+
+func TestMetadataValueEncryption(t *testing.T) {
+	c, err := newCipher(NameEncryptionStandard, "", "", true, nil)
+	require.NoError(t, err)
+	c.cryptoRand = &zeroes{} // zero out the nonce
+
+	for _, plaintext := range []string{"", "2006-01-02T15:04:05.999999999Z", "ünicode value"} {
+		encrypted, err := c.encryptMetadataValue(plaintext)
+		require.NoError(t, err)
+		assert.NotEqual(t, plaintext, encrypted)
+		decrypted, err := c.decryptMetadataValue(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, decrypted)
+	}
+
+	encrypted, err := c.encryptMetadataValue("potato")
+	require.NoError(t, err)
+	assert.Equal(t, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAZ7_ZrpT5UFsBM34zi5EulLZRz56K6g", encrypted)
+
+	_, err = c.decryptMetadataValue("potato")
+	assert.Equal(t, ErrorMetadataTooShort, err)
+	_, err = c.decryptMetadataValue("not base64!")
+	assert.Error(t, err)
+	_, err = c.decryptMetadataValue("B" + encrypted[1:])
+	assert.Equal(t, ErrorEncryptedBadBlock, err)
+}
+
+func TestMetadataKeyEncryption(t *testing.T) {
+	c, err := newCipher(NameEncryptionStandard, "", "", true, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "", c.encryptMetadataKey(""))
+	encrypted := c.encryptMetadataKey("owner")
+	assert.Equal(t, strings.ToLower(encrypted), encrypted)
+	assert.Equal(t, encrypted, c.encryptMetadataKey("owner"))
+	decrypted, err := c.decryptMetadataKey(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "owner", decrypted)
+
+	_, err = c.decryptMetadataKey("mtime")
+	assert.Error(t, err)
+}
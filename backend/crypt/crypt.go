@@ -30,7 +30,9 @@ func init() {
 		NewFs:       NewFs,
 		CommandHelp: commandHelp,
 		MetadataInfo: &fs.MetadataInfo{
-			Help: `Any metadata supported by the underlying remote is read and written.`,
+			Help: `Any metadata supported by the underlying remote is read and written.
+
+Metadata is stored unencrypted unless metadata_encryption is set.`,
 		},
 		Options: []fs.Option{{
 			Name:     "remote",
@@ -172,10 +174,41 @@ Setting suffix to "none" will result in an empty suffix. This may be useful
 when the path length is critical.`,
 			Default:  ".bin",
 			Advanced: true,
+		}, {
+			Name: "metadata_encryption",
+			Help: `How to encrypt object metadata.
+
+Metadata is only written when --metadata is in use. By default it is
+passed to the underlying remote unencrypted.
+
+Keys which the underlying remote uses as system metadata (e.g. "mtime"
+or "content-type") are always passed unencrypted as the remote needs
+to interpret them. Directory metadata is not encrypted.`,
+			Default: metadataEncryptionOff,
+			Examples: []fs.OptionExample{
+				{
+					Value: metadataEncryptionOff,
+					Help:  "Don't encrypt metadata.",
+				}, {
+					Value: metadataEncryptionValues,
+					Help:  "Encrypt metadata values, leave the keys intact.",
+				}, {
+					Value: metadataEncryptionAll,
+					Help:  "Encrypt metadata keys and values.",
+				},
+			},
+			Advanced: true,
 		}},
 	})
 }
 
+// Metadata encryption modes
+const (
+	metadataEncryptionOff    = "off"
+	metadataEncryptionValues = "values"
+	metadataEncryptionAll    = "keys_and_values"
+)
+
 // newCipherForConfig constructs a Cipher for the given config name
 func newCipherForConfig(opt *Options) (*Cipher, error) {
 	mode, err := NewNameEncryptionMode(opt.FilenameEncryption)
@@ -232,6 +265,11 @@ func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs,
 	if err != nil {
 		return nil, err
 	}
+	switch opt.MetadataEncryption {
+	case metadataEncryptionOff, metadataEncryptionValues, metadataEncryptionAll:
+	default:
+		return nil, fmt.Errorf("unknown metadata_encryption %q", opt.MetadataEncryption)
+	}
 	remote := opt.Remote
 	if strings.HasPrefix(remote, name+":") {
 		return nil, errors.New("can't point crypt remote at itself - check the value of the remote setting")
@@ -264,6 +302,13 @@ func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs,
 		cipher: cipher,
 	}
 	cache.PinUntilFinalized(f.Fs, f)
+	// Find the system metadata of the wrapped remote so we can leave it alone
+	if opt.MetadataEncryption != metadataEncryptionOff {
+		fsInfo, _, _, _, parseErr := fs.ParseRemote(fs.ConfigString(wrappedFs))
+		if parseErr == nil && fsInfo.MetadataInfo != nil {
+			f.metadataSystem = fsInfo.MetadataInfo.System
+		}
+	}
 	// Correct root if definitely pointing to a file
 	if err == fs.ErrorIsFile {
 		f.root = path.Dir(f.root)
@@ -314,6 +359,7 @@ type Options struct {
 	FilenameEncoding        string `config:"filename_encoding"`
 	Suffix                  string `config:"suffix"`
 	StrictNames             bool   `config:"strict_names"`
+	MetadataEncryption      string `config:"metadata_encryption"`
 }
 
 // Fs represents a wrapped fs.Fs
@@ -325,6 +371,8 @@ type Fs struct {
 	opt      Options
 	features *fs.Features // optional features
 	cipher   *Cipher
+	// system metadata of the wrapped remote which isn't encrypted
+	metadataSystem map[string]fs.MetadataHelp
 }
 
 // Name of the remote (as passed into NewFs)
@@ -497,6 +545,11 @@ type putFn func(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ..
 func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options []fs.OpenOption, put putFn) (fs.Object, error) {
 	ci := fs.GetConfig(ctx)
 
+	options, err := f.encryptMetadataOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
 	if f.opt.NoDataEncryption {
 		o, err := put(ctx, in, f.newObjectInfo(src, nonce{}), options...)
 		if err == nil && o != nil {
@@ -576,6 +629,107 @@ func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, opt
 	return f.put(ctx, in, src, options, f.Fs.Features().PutStream)
 }
 
+// encryptMetadata returns a copy of metadata with the values, and
+// the keys if configured, encrypted
+//
+// Keys the wrapped remote uses for system metadata are left intact.
+func (f *Fs) encryptMetadata(metadata fs.Metadata) (fs.Metadata, error) {
+	if metadata == nil || f.opt.MetadataEncryption == metadataEncryptionOff {
+		return metadata, nil
+	}
+	out := make(fs.Metadata, len(metadata))
+	for k, v := range metadata {
+		if _, isSystem := f.metadataSystem[k]; isSystem {
+			out[k] = v
+			continue
+		}
+		encryptedValue, err := f.cipher.encryptMetadataValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt metadata %q: %w", k, err)
+		}
+		if f.opt.MetadataEncryption == metadataEncryptionAll {
+			k = f.cipher.encryptMetadataKey(k)
+		}
+		out[k] = encryptedValue
+	}
+	return out, nil
+}
+
+// decryptMetadata reverses encryptMetadata
+//
+// Keys and values which can't be decrypted are returned as is.
+func (f *Fs) decryptMetadata(metadata fs.Metadata) fs.Metadata {
+	if metadata == nil || f.opt.MetadataEncryption == metadataEncryptionOff {
+		return metadata
+	}
+	out := make(fs.Metadata, len(metadata))
+	for k, v := range metadata {
+		if _, isSystem := f.metadataSystem[k]; isSystem {
+			out[k] = v
+			continue
+		}
+		if f.opt.MetadataEncryption == metadataEncryptionAll {
+			decryptedKey, err := f.cipher.decryptMetadataKey(k)
+			if err != nil {
+				fs.Debugf(f, "Undecryptable metadata key %q: %v", k, err)
+			} else {
+				k = decryptedKey
+			}
+		}
+		decryptedValue, err := f.cipher.decryptMetadataValue(v)
+		if err != nil {
+			fs.Debugf(f, "Undecryptable metadata value for %q: %v", k, err)
+		} else {
+			v = decryptedValue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// encryptMetadataOptions encrypts the metadata in any
+// fs.MetadataOption in options, returning a new slice if needed
+func (f *Fs) encryptMetadataOptions(options []fs.OpenOption) ([]fs.OpenOption, error) {
+	if f.opt.MetadataEncryption == metadataEncryptionOff {
+		return options, nil
+	}
+	var newOptions []fs.OpenOption
+	for i, option := range options {
+		metadataOption, ok := option.(fs.MetadataOption)
+		if !ok {
+			continue
+		}
+		if newOptions == nil {
+			newOptions = append([]fs.OpenOption(nil), options...)
+		}
+		metadata, err := f.encryptMetadata(fs.Metadata(metadataOption))
+		if err != nil {
+			return nil, err
+		}
+		newOptions[i] = fs.MetadataOption(metadata)
+	}
+	if newOptions == nil {
+		return options, nil
+	}
+	return newOptions, nil
+}
+
+// encryptMetadataSet returns a context with any --metadata-set
+// encrypted for server-side operations on the wrapped remote
+func (f *Fs) encryptMetadataSet(ctx context.Context) (context.Context, error) {
+	ci := fs.GetConfig(ctx)
+	if ci.MetadataSet == nil || f.opt.MetadataEncryption == metadataEncryptionOff {
+		return ctx, nil
+	}
+	metadata, err := f.encryptMetadata(ci.MetadataSet)
+	if err != nil {
+		return nil, err
+	}
+	newCtx, newCi := fs.AddConfig(ctx)
+	newCi.MetadataSet = metadata
+	return newCtx, nil
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hash.None)
@@ -658,6 +812,10 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	if !ok {
 		return nil, fs.ErrorCantCopy
 	}
+	ctx, err := f.encryptMetadataSet(ctx)
+	if err != nil {
+		return nil, err
+	}
 	oResult, err := do(ctx, o.Object, f.cipher.EncryptFileName(remote))
 	if err != nil {
 		return nil, err
@@ -683,6 +841,10 @@ func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	if !ok {
 		return nil, fs.ErrorCantMove
 	}
+	ctx, err := f.encryptMetadataSet(ctx)
+	if err != nil {
+		return nil, err
+	}
 	oResult, err := do(ctx, o.Object, f.cipher.EncryptFileName(remote))
 	if err != nil {
 		return nil, err
@@ -1224,7 +1386,11 @@ func (o *ObjectInfo) Metadata(ctx context.Context) (fs.Metadata, error) {
 	if !ok {
 		return nil, nil
 	}
-	return do.Metadata(ctx)
+	metadata, err := do.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return o.f.encryptMetadata(metadata)
 }
 
 // MimeType returns the content type of the Object if
@@ -1278,7 +1444,11 @@ func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
 	if !ok {
 		return nil, nil
 	}
-	return do.Metadata(ctx)
+	metadata, err := do.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return o.f.decryptMetadata(metadata), nil
 }
 
 // SetMetadata sets metadata for an Object
@@ -1289,6 +1459,10 @@ func (o *Object) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
 	if !ok {
 		return fs.ErrorNotImplemented
 	}
+	metadata, err := o.f.encryptMetadata(metadata)
+	if err != nil {
+		return err
+	}
 	return do.SetMetadata(ctx, metadata)
 }
 
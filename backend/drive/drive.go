@@ -48,6 +48,7 @@ import (
 	"github.com/rclone/rclone/lib/readers"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	"golang.org/x/sync/errgroup"
 	drive_v2 "google.golang.org/api/drive/v2"
 	drive "google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
@@ -921,6 +922,12 @@ func (f *Fs) shouldRetry(ctx context.Context, err error) (bool, error) {
 		}
 		if len(gerr.Errors) > 0 {
 			reason := gerr.Errors[0].Reason
+			switch reason {
+			case "rateLimitExceeded", "userRateLimitExceeded":
+				err = fserrors.CategoryError(err, fserrors.CategoryRateLimit)
+			case "downloadQuotaExceeded", "quotaExceeded", "storageQuotaExceeded", "teamDriveFileLimitExceeded":
+				err = fserrors.CategoryError(err, fserrors.CategoryQuota)
+			}
 			if reason == "rateLimitExceeded" || reason == "userRateLimitExceeded" {
 				if f.opt.StopOnUploadLimit && gerr.Errors[0].Message == "User rate limit exceeded." {
 					fs.Errorf(f, "Received upload limit error: %v", err)
@@ -3638,6 +3645,320 @@ func (f *Fs) rescue(ctx context.Context, dirID string, delete bool) (err error)
 	})
 }
 
+// listDirRaw calls fn for each item in the directory with ID dirID
+// without resolving shortcuts
+func (f *Fs) listDirRaw(ctx context.Context, dirID string, fn func(*drive.File)) error {
+	query := fmt.Sprintf("'%s' in parents and trashed=false", actualID(dirID))
+	return f.queryFn(ctx, query, func(item *drive.File) {
+		item.Name = f.opt.Enc.ToStandardName(item.Name)
+		fn(item)
+	})
+}
+
+// Problems with shortcuts found by "shortcuts"
+const (
+	shortcutMissingTarget     = "missing target"
+	shortcutTrashedTarget     = "trashed target"
+	shortcutOrphanedTarget    = "orphaned target"
+	shortcutDuplicatedTarget  = "duplicated target"
+	shortcutDuplicateShortcut = "duplicate shortcut"
+)
+
+// Returned from "shortcuts"
+type shortcutReport struct {
+	Remote   string
+	ID       string
+	TargetID string
+	Problem  string
+	Deleted  bool `json:",omitempty"`
+}
+
+// duplicateShortcuts returns the IDs of the shortcuts in items which
+// point to the same target as an earlier shortcut in items
+func duplicateShortcuts(items []*drive.File) map[string]bool {
+	seen := map[string]bool{}
+	dups := map[string]bool{}
+	for _, item := range items {
+		if !isShortcut(item) {
+			continue
+		}
+		targetID := item.ShortcutDetails.TargetId
+		if seen[targetID] {
+			dups[item.Id] = true
+		}
+		seen[targetID] = true
+	}
+	return dups
+}
+
+// targetProblem returns the problem with the shortcut target, given
+// the result of reading it and the number of items with the same name
+// in its parent, or "" if there isn't one
+func targetProblem(target *drive.File, err error, sameName int) string {
+	var gerr *googleapi.Error
+	switch {
+	case errors.As(err, &gerr) && gerr.Code == 404:
+		return shortcutMissingTarget
+	case err != nil:
+		return fmt.Sprintf("failed to read target: %v", err)
+	case target.Trashed:
+		return shortcutTrashedTarget
+	case len(target.Parents) == 0:
+		return shortcutOrphanedTarget
+	case sameName > 1:
+		return shortcutDuplicatedTarget
+	}
+	return ""
+}
+
+// shortcutCanDelete returns true if the shortcut with problem can be
+// deleted without losing anything
+func shortcutCanDelete(problem string) bool {
+	switch problem {
+	case shortcutMissingTarget, shortcutTrashedTarget, shortcutDuplicateShortcut:
+		return true
+	}
+	return false
+}
+
+// checkShortcutTarget reads the target with ID targetID and returns
+// the problem with it or "" if there isn't one
+func (f *Fs) checkShortcutTarget(ctx context.Context, targetID string) string {
+	target, err := f.getFile(ctx, targetID, "id,name,parents,trashed")
+	sameName := 0
+	if err == nil && !target.Trashed && len(target.Parents) > 0 {
+		name := strings.ReplaceAll(target.Name, `\`, `\\`)
+		name = strings.ReplaceAll(name, `'`, `\'`)
+		query := fmt.Sprintf("name='%s' and '%s' in parents and trashed=false", name, target.Parents[0])
+		err = f.queryFn(ctx, query, func(*drive.File) {
+			sameName++
+		})
+	}
+	return targetProblem(target, err, sameName)
+}
+
+// shortcuts reports the shortcuts in dir and its subdirectories
+// whose targets are missing, trashed, orphaned or duplicated and the
+// shortcuts which duplicate other shortcuts in the same directory.
+//
+// If all is set then all shortcuts are reported. If delete is set then
+// the shortcuts which can be deleted without losing anything are
+// moved to the trash.
+func (f *Fs) shortcuts(ctx context.Context, dir string, all, delete bool) (out []shortcutReport, err error) {
+	dirID, err := f.dirCache.FindDir(ctx, dir, false)
+	if err != nil {
+		return nil, err
+	}
+	out = []shortcutReport{}
+	targetProblems := map[string]string{}
+	var walkDir func(dir, dirID string) error
+	walkDir = func(dir, dirID string) error {
+		var items []*drive.File
+		err := f.listDirRaw(ctx, dirID, func(item *drive.File) {
+			items = append(items, item)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list %q: %w", dir, err)
+		}
+		dups := duplicateShortcuts(items)
+		for _, item := range items {
+			remote := path.Join(dir, item.Name)
+			if item.MimeType == driveFolderType {
+				err = walkDir(remote, item.Id)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if !isShortcut(item) {
+				continue
+			}
+			targetID := item.ShortcutDetails.TargetId
+			problem, ok := targetProblems[targetID]
+			if !ok {
+				problem = f.checkShortcutTarget(ctx, targetID)
+				targetProblems[targetID] = problem
+			}
+			if dups[item.Id] {
+				problem = shortcutDuplicateShortcut
+			}
+			if problem == "" && !all {
+				continue
+			}
+			report := shortcutReport{
+				Remote:   remote,
+				ID:       item.Id,
+				TargetID: targetID,
+				Problem:  problem,
+			}
+			if delete && shortcutCanDelete(problem) && !operations.SkipDestructive(ctx, remote, "delete shortcut") {
+				err = f.delete(ctx, item.Id, true)
+				if err != nil {
+					fs.Errorf(remote, "Failed to delete shortcut: %v", fs.CountError(ctx, err))
+				} else {
+					fs.Infof(remote, "Deleted shortcut with %s", problem)
+					report.Deleted = true
+				}
+			}
+			out = append(out, report)
+		}
+		return nil
+	}
+	err = walkDir(dir, dirID)
+	return out, err
+}
+
+type migrateResult struct {
+	Moved   int
+	Copied  int
+	Created int
+	Errors  int
+}
+
+func (r migrateResult) Error() string {
+	return fmt.Sprintf("%d errors while migrating - see log", r.Errors)
+}
+
+// Default number of items to move at once in "migrate"
+const defaultMigrateBatchSize = 100
+
+// migrate moves the tree at the root of f into dstFs which must be a shared
+// drive, preserving the IDs of the files and directories where
+// possible.
+//
+// Directories are moved whole if possible, otherwise they are created
+// in dstFs and their contents are moved into them. Files which can't
+// be moved are copied if copyUnmovable is set.
+func (f *Fs) migrate(ctx context.Context, dstFs *Fs, batchSize int, copyUnmovable bool) (r migrateResult, err error) {
+	if !dstFs.isTeamDrive {
+		return r, errors.New("destination must be a shared drive")
+	}
+	srcDirID, err := f.dirCache.FindDir(ctx, "", false)
+	if err != nil {
+		return r, err
+	}
+	var mu sync.Mutex
+	count := func(n *int, err error, remote string, what string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			r.Errors++
+			fs.Errorf(remote, "Failed to %s: %v", what, fs.CountError(ctx, err))
+			return
+		}
+		*n++
+		fs.Infof(remote, "%s", what)
+	}
+
+	// moveItem moves item from srcDirID to dstDirID
+	moveItem := func(item *drive.File, srcDirID, dstDirID string) error {
+		return f.pacer.Call(func() (bool, error) {
+			_, err := f.svc.Files.Update(item.Id, nil).
+				RemoveParents(srcDirID).
+				AddParents(dstDirID).
+				Fields("").
+				SupportsAllDrives(true).
+				Context(ctx).Do()
+			return f.shouldRetry(ctx, err)
+		})
+	}
+
+	// copyItem copies item into dstDirID giving it a new ID
+	copyItem := func(item *drive.File, dstDirID string) error {
+		copyInfo := drive.File{
+			Name:    item.Name,
+			Parents: []string{dstDirID},
+		}
+		return dstFs.pacer.Call(func() (bool, error) {
+			_, err := dstFs.svc.Files.Copy(item.Id, &copyInfo).
+				Fields("").
+				SupportsAllDrives(true).
+				Context(ctx).Do()
+			return dstFs.shouldRetry(ctx, err)
+		})
+	}
+
+	var migrateDir func(dir, srcDirID string) error
+	migrateDir = func(dir, srcDirID string) error {
+		dstDirID, err := dstFs.dirCache.FindDir(ctx, dir, true)
+		if err != nil {
+			return fmt.Errorf("failed to make destination directory %q: %w", dir, err)
+		}
+		dstDirID = actualID(dstDirID)
+		var items []*drive.File
+		err = f.listDirRaw(ctx, srcDirID, func(item *drive.File) {
+			items = append(items, item)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list %q: %w", dir, err)
+		}
+		var subDirs []*drive.File
+		for batch := range slices.Chunk(items, batchSize) {
+			g, gCtx := errgroup.WithContext(ctx)
+			g.SetLimit(f.ci.Checkers)
+			for _, item := range batch {
+				remote := path.Join(dir, item.Name)
+				if item.MimeType == driveFolderType {
+					// Only move the directory whole if it isn't there already
+					_, err := dstFs.dirCache.FindDir(gCtx, remote, false)
+					if err == nil {
+						mu.Lock()
+						subDirs = append(subDirs, item)
+						mu.Unlock()
+						continue
+					}
+				}
+				if operations.SkipDestructive(gCtx, remote, "migrate") {
+					continue
+				}
+				g.Go(func() error {
+					err := moveItem(item, srcDirID, dstDirID)
+					if err == nil {
+						count(&r.Moved, nil, remote, "moved")
+						return nil
+					}
+					if item.MimeType == driveFolderType {
+						fs.Debugf(remote, "Couldn't move directory whole so moving its contents: %v", err)
+						mu.Lock()
+						subDirs = append(subDirs, item)
+						mu.Unlock()
+						return nil
+					}
+					if !copyUnmovable {
+						count(nil, err, remote, "move")
+						return nil
+					}
+					fs.Debugf(remote, "Couldn't move so copying: %v", err)
+					err = copyItem(item, dstDirID)
+					count(&r.Copied, err, remote, "copied")
+					return nil
+				})
+			}
+			_ = g.Wait()
+			fs.Infof(f, "Migrated %d items, %d errors", r.Moved+r.Copied, r.Errors)
+		}
+		for _, item := range subDirs {
+			remote := path.Join(dir, item.Name)
+			if _, err := dstFs.dirCache.FindDir(ctx, remote, false); err != nil {
+				r.Created++
+			}
+			err = migrateDir(remote, item.Id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = migrateDir("", srcDirID)
+	if err != nil {
+		return r, err
+	}
+	if r.Errors != 0 {
+		return r, r
+	}
+	return r, nil
+}
+
 var commandHelp = []fs.CommandHelp{{
 	Name:  "get",
 	Short: "Get command for fetching the drive config parameters",
@@ -3882,6 +4203,89 @@ Third delete all orphaned files to the trash
 
     rclone backend rescue drive: -o delete
 `,
+}, {
+	Name:  "shortcuts",
+	Short: "Report or delete shortcuts with problems",
+	Long: `This command finds the shortcuts in the directory passed in and its
+subdirectories which have problems with their targets.
+
+Usage:
+
+    rclone backend shortcuts drive:
+    rclone backend shortcuts drive: subdir -o all
+    rclone backend --interactive shortcuts drive:directory -o delete
+
+The problems reported are
+
+- "missing target" - the target doesn't exist or can't be read
+- "trashed target" - the target is in the trash
+- "orphaned target" - the target isn't in any folder
+- "duplicated target" - the target has the same name as another item
+  in its folder so may be removed by "rclone dedupe"
+- "duplicate shortcut" - another shortcut in the same folder points to
+  the same target
+
+With the "delete" option the shortcuts with missing or trashed targets
+and duplicate shortcuts are moved to the trash. Use the
+--interactive/-i or --dry-run flag to see what would be deleted first.
+
+Result:
+
+    [
+        {
+            "Remote": "dir/shortcut.txt",
+            "ID": "1aBcDeFgHiJkLmNoPqRsTuVwXyZ",
+            "TargetID": "1zYxWvUtSrQpOnMlKjIhGfEdCbA",
+            "Problem": "trashed target",
+            "Deleted": true
+        }
+    ]
+`,
+	Opts: map[string]string{
+		"all":    "if set then report all shortcuts, not just ones with problems",
+		"delete": "if set then trash shortcuts with missing or trashed targets and duplicate shortcuts",
+	},
+}, {
+	Name:  "migrate",
+	Short: "Move a directory tree into a Shared Drive",
+	Long: `This command moves the directory passed in and everything in it into
+the Shared Drive remote given as an argument, keeping the IDs of the
+files and directories where possible, so links to them keep working.
+
+Usage:
+
+    rclone backend migrate drive:path/to/dir shareddrive:path/to/dest
+    rclone backend migrate drive:path/to/dir shareddrive: -o copy -o batch-size=50
+
+Directories are moved whole if possible. If that isn't allowed, for
+example if you aren't a manager of the Shared Drive, or there is a
+directory with the same name in the destination already, then the
+directory is made in the destination and its contents are moved into
+it.
+
+Files you don't own can't be moved into a Shared Drive. With the "copy"
+option these files are copied instead, which gives the copies new IDs.
+
+Items are moved batch-size at a time (default 100) with --checkers
+moves running at once. Empty directories are left behind when their
+contents have been moved and can be removed with "rclone rmdirs".
+
+Use the --interactive/-i or --dry-run flag to see what would be moved
+first.
+
+Result:
+
+    {
+        "Moved": 17,
+        "Copied": 2,
+        "Created": 1,
+        "Errors": 0
+    }
+`,
+	Opts: map[string]string{
+		"copy":       "if set then copy the files which can't be moved",
+		"batch-size": "number of items to move at once (default 100)",
+	},
 }}
 
 // Command the backend to run a named command
@@ -4025,6 +4429,35 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 			return nil, errors.New("syntax error: need 0 or 1 args or -o delete")
 		}
 		return nil, f.rescue(ctx, dirID, delete)
+	case "shortcuts":
+		dir := ""
+		if len(arg) > 0 {
+			dir = arg[0]
+		}
+		_, all := opt["all"]
+		_, delete := opt["delete"]
+		return f.shortcuts(ctx, dir, all, delete)
+	case "migrate":
+		if len(arg) != 1 {
+			return nil, errors.New("need exactly 1 argument, the destination")
+		}
+		dstFsI, err := cache.Get(ctx, arg[0])
+		if err != nil {
+			return nil, fmt.Errorf("couldn't find destination: %w", err)
+		}
+		dstFs, ok := dstFsI.(*Fs)
+		if !ok {
+			return nil, errors.New("destination is not a drive backend")
+		}
+		batchSize := defaultMigrateBatchSize
+		if opt["batch-size"] != "" {
+			batchSize, err = strconv.Atoi(opt["batch-size"])
+			if err != nil || batchSize <= 0 {
+				return nil, fmt.Errorf("bad batch-size %q", opt["batch-size"])
+			}
+		}
+		_, copyUnmovable := opt["copy"]
+		return f.migrate(ctx, dstFs, batchSize, copyUnmovable)
 	default:
 		return nil, fs.ErrorCommandNotFound
 	}
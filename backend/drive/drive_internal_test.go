@@ -435,6 +435,48 @@ func (f *Fs) InternalTestShortcuts(t *testing.T) {
 	})
 }
 
+func TestDuplicateShortcuts(t *testing.T) {
+	shortcut := func(id, targetID string) *drive.File {
+		return &drive.File{
+			Id:              id,
+			MimeType:        shortcutMimeType,
+			ShortcutDetails: &drive.FileShortcutDetails{TargetId: targetID},
+		}
+	}
+	items := []*drive.File{
+		shortcut("s1", "t1"),
+		{Id: "f1", MimeType: "text/plain"},
+		shortcut("s2", "t2"),
+		shortcut("s3", "t1"),
+		shortcut("s4", "t1"),
+	}
+	assert.Equal(t, map[string]bool{"s3": true, "s4": true}, duplicateShortcuts(items))
+	assert.Equal(t, map[string]bool{}, duplicateShortcuts(nil))
+}
+
+func TestTargetProblem(t *testing.T) {
+	ok := &drive.File{Parents: []string{"p"}}
+	for _, test := range []struct {
+		target    *drive.File
+		err       error
+		sameName  int
+		want      string
+		canDelete bool
+	}{
+		{ok, nil, 1, "", false},
+		{nil, &googleapi.Error{Code: 404}, 0, shortcutMissingTarget, true},
+		{nil, errors.New("potato"), 0, "failed to read target: potato", false},
+		{&drive.File{Trashed: true, Parents: []string{"p"}}, nil, 0, shortcutTrashedTarget, true},
+		{&drive.File{}, nil, 0, shortcutOrphanedTarget, false},
+		{ok, nil, 2, shortcutDuplicatedTarget, false},
+	} {
+		got := targetProblem(test.target, test.err, test.sameName)
+		assert.Equal(t, test.want, got)
+		assert.Equal(t, test.canDelete, shortcutCanDelete(got), got)
+	}
+	assert.True(t, shortcutCanDelete(shortcutDuplicateShortcut))
+}
+
 // TestIntegration/FsMkdir/FsPutFiles/Internal/UnTrash
 func (f *Fs) InternalTestUnTrash(t *testing.T) {
 	ctx := context.Background()
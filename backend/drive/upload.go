@@ -22,6 +22,7 @@ import (
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/lib/pool"
 	"github.com/rclone/rclone/lib/readers"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
@@ -168,7 +169,8 @@ func (rx *resumableUpload) Upload(ctx context.Context) (*drive.File, error) {
 	start := int64(0)
 	var StatusCode int
 	var err error
-	buf := make([]byte, int(rx.f.opt.ChunkSize))
+	buf := pool.GetBuffer(int(rx.f.opt.ChunkSize))
+	defer pool.PutBuffer(buf)
 	for finished := false; !finished; {
 		var reqSize int64
 		var chunk io.ReadSeeker
@@ -23,6 +23,8 @@ type digest struct {
 	totalHash   hash.Hash
 	sumCalled   bool
 	writtenMore bool
+	chunk       bool   // set if hashing a chunk for Combine
+	blockSums   []byte // hashes of the blocks if chunk is set
 }
 
 // New returns a new hash.Hash computing the Dropbox checksum.
@@ -35,6 +37,12 @@ func New() hash.Hash {
 // writeBlockHash writes the current block hash into the total hash
 func (d *digest) writeBlockHash() {
 	blockHash := d.blockHash.Sum(nil)
+	if d.chunk {
+		d.blockSums = append(d.blockSums, blockHash...)
+		d.n = 0
+		d.blockHash.Reset()
+		return
+	}
 	_, err := d.totalHash.Write(blockHash)
 	if err != nil {
 		panic(hashReturnedError)
@@ -85,6 +93,9 @@ func (d *digest) Sum(b []byte) []byte {
 	if d.n != 0 {
 		d.writeBlockHash()
 	}
+	if d.chunk {
+		return append(b, d.blockSums...)
+	}
 	return d.totalHash.Sum(b)
 }
 
@@ -95,6 +106,7 @@ func (d *digest) Reset() {
 	d.blockHash = sha256.New()
 	d.sumCalled = false
 	d.writtenMore = false
+	d.blockSums = nil
 }
 
 // Size returns the number of bytes Sum will return.
@@ -110,6 +122,30 @@ func (d *digest) BlockSize() int {
 	return d.totalHash.BlockSize()
 }
 
+// ChunkSize returns the size which the chunks of the data hashed in
+// parallel must be a multiple of.
+func (d *digest) ChunkSize() int64 {
+	return bytesPerBlock
+}
+
+// NewChunk returns a hash.Hash to hash a chunk of the data in
+// parallel. Its Sum returns the hashes of the blocks in the chunk.
+func (d *digest) NewChunk() hash.Hash {
+	c := &digest{chunk: true}
+	c.Reset()
+	return c
+}
+
+// Combine returns the Dropbox checksum of the data from the sums of
+// its chunks in order.
+func (d *digest) Combine(sums [][]byte, size int64) []byte {
+	totalHash := sha256.New()
+	for _, sum := range sums {
+		_, _ = totalHash.Write(sum)
+	}
+	return totalHash.Sum(nil)
+}
+
 // Sum returns the Dropbox checksum of the data.
 func Sum(data []byte) [Size]byte {
 	var d digest
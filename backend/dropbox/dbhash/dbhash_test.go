@@ -6,7 +6,9 @@ import (
 	"testing"
 
 	"github.com/rclone/rclone/backend/dropbox/dbhash"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func testChunk(t *testing.T, chunk int) {
@@ -65,6 +67,30 @@ func TestSumCalledTwice(t *testing.T) {
 	assert.Panics(t, func() { d.Sum(nil) })
 }
 
+func TestChunker(t *testing.T) {
+	const blockSize = 4 * 1024 * 1024
+	data := make([]byte, 2*blockSize+1)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	c, ok := dbhash.New().(hash.Chunker)
+	require.True(t, ok)
+	assert.Equal(t, int64(blockSize), c.ChunkSize())
+	for _, n := range []int{0, 1, blockSize, blockSize + 1, 2*blockSize + 1} {
+		want := dbhash.Sum(data[:n])
+		for _, chunkSize := range []int{blockSize, 2 * blockSize} {
+			var sums [][]byte
+			for i := 0; i < n || i == 0; i += chunkSize {
+				h := c.NewChunk()
+				_, err := h.Write(data[i:min(i+chunkSize, n)])
+				require.NoError(t, err)
+				sums = append(sums, h.Sum(nil))
+			}
+			assert.Equal(t, want[:32], c.Combine(sums, int64(n)), fmt.Sprintf("length %d chunk size %d", n, chunkSize))
+		}
+	}
+}
+
 func TestSize(t *testing.T) {
 	d := dbhash.New()
 	assert.Equal(t, 32, d.Size())
@@ -52,6 +52,7 @@ import (
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/oauthutil"
 	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/pool"
 	"github.com/rclone/rclone/lib/readers"
 	"golang.org/x/oauth2"
 )
@@ -139,12 +140,26 @@ var (
 	exportKnownAPIFormats = map[exportAPIFormat]exportExtension{
 		"markdown": "md",
 		"html":     "html",
+		"docx":     "docx",
+		"xlsx":     "xlsx",
+		"pptx":     "pptx",
 	}
 	// Populated based on exportKnownAPIFormats
 	exportKnownExtensions = map[exportExtension]exportAPIFormat{}
 
 	paperExtension         = ".paper"
 	paperTemplateExtension = ".papert"
+
+	// Extensions of the cloud native documents which are exported
+	// with each export extension. These are replaced by the export
+	// extension in listings.
+	exportSourceExtensions = map[exportExtension][]string{
+		"md":   {paperExtension},
+		"html": {paperExtension},
+		"docx": {".gdoc"},
+		"xlsx": {".gsheet"},
+		"pptx": {".gslides"},
+	}
 )
 
 // Gets an oauth config with the right scopes
@@ -265,12 +280,13 @@ folders.`,
 			Help: `Comma separated list of preferred formats for exporting files
 
 Certain Dropbox files can only be accessed by exporting them to another format.
-These include Dropbox Paper documents.
+These include Dropbox Paper documents and Google Docs, Sheets and Slides
+documents stored in Dropbox.
 
 For each such file, rclone will choose the first format on this list that Dropbox
 considers valid. If none is valid, it will choose Dropbox's default format.
 
-Known formats include: "html", "md" (markdown)`,
+Known formats include: "html", "md" (markdown), "docx", "xlsx", "pptx"`,
 			Default:  fs.CommaSepList{"html", "md"},
 			Advanced: true,
 		}, {
@@ -750,9 +766,12 @@ func (f *Fs) possibleMetadatas(ctx context.Context, filePath string) (ret []<-ch
 		return
 	}
 
-	// Otherwise, try both `foo.md` coming from `foo`, or from `foo.paper`
+	// Otherwise, try `foo.md` coming from `foo`, or from `foo.paper`,
+	// or `foo.docx` coming from `foo` or `foo.gdoc` etc
 	ret = append(ret, f.getMetadataForExt(ctx, base, ext))
-	ret = append(ret, f.getMetadataForExt(ctx, base+paperExtension, ext))
+	for _, sourceExt := range exportSourceExtensions[ext] {
+		ret = append(ret, f.getMetadataForExt(ctx, base+sourceExt, ext))
+	}
 	return
 }
 
@@ -1724,8 +1743,10 @@ func (o *Object) setMetadataForExport(info *files.FileMetadata) {
 		o.exportType = exportHide
 	} else {
 		o.exportType = exportExportable
-		// get rid of any paper extension, if present
-		o.remote = strings.TrimSuffix(o.remote, paperExtension)
+		// get rid of any paper or other cloud document extension, if present
+		for _, sourceExt := range exportSourceExtensions[exportExt] {
+			o.remote = strings.TrimSuffix(o.remote, sourceExt)
+		}
 		// add the export extension
 		o.remote += "." + string(exportExt)
 	}
@@ -1896,7 +1917,8 @@ func (o *Object) uploadChunked(ctx context.Context, in0 io.Reader, commitInfo *f
 
 	// write chunks
 	in := readers.NewCountingReader(in0)
-	buf := make([]byte, int(chunkSize))
+	buf := pool.GetBuffer(int(chunkSize))
+	defer pool.PutBuffer(buf)
 	cursor := files.UploadSessionCursor{
 		SessionId: res.SessionId,
 		Offset:    0,
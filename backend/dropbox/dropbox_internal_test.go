@@ -50,6 +50,29 @@ func TestInternalCheckPathLength(t *testing.T) {
 	}
 }
 
+func TestInternalSetMetadataForExport(t *testing.T) {
+	f := &Fs{exportExts: []exportExtension{"html", "md"}}
+	for _, test := range []struct {
+		remote  string
+		info    files.ExportInfo
+		want    string
+		wantExp exportType
+	}{
+		{"doc.paper", files.ExportInfo{ExportAs: "markdown", ExportOptions: []string{"markdown", "html"}}, "doc.html", exportExportable},
+		{"doc.papert", files.ExportInfo{ExportAs: "markdown"}, "doc.papert.md", exportExportable},
+		{"doc.gdoc", files.ExportInfo{ExportAs: "docx"}, "doc.docx", exportExportable},
+		{"sheet.gsheet", files.ExportInfo{ExportAs: "xlsx"}, "sheet.xlsx", exportExportable},
+		{"slides.gslides", files.ExportInfo{ExportAs: "pptx"}, "slides.pptx", exportExportable},
+		{"doc", files.ExportInfo{ExportAs: "docx"}, "doc.docx", exportExportable},
+		{"potato.gdoc", files.ExportInfo{ExportAs: "potato"}, "potato.gdoc", exportHide},
+	} {
+		o := &Object{fs: f, remote: test.remote}
+		o.setMetadataForExport(&files.FileMetadata{ExportInfo: &test.info})
+		assert.Equal(t, test.wantExp, o.exportType, test.remote)
+		assert.Equal(t, test.want, o.remote, test.remote)
+	}
+}
+
 func (f *Fs) importPaperForTest(t *testing.T) {
 	content := `# test doc
 
@@ -25,11 +25,13 @@ import (
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/fshttp"
 	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/list"
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/env"
 	"github.com/rclone/rclone/lib/pacer"
 	"github.com/rclone/rclone/lib/proxy"
 	"github.com/rclone/rclone/lib/readers"
+	"golang.org/x/sync/errgroup"
 )
 
 var (
@@ -895,6 +897,69 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 	return entries, nil
 }
 
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// It should call callback for each tranche of entries read.
+// These need not be returned in any particular order.  If
+// callback returns an error then the listing will stop
+// immediately.
+//
+// Directories are listed in parallel using up to --checkers
+// connections from the pool, which is much quicker than listing
+// them one at a time on large trees.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	list := list.NewHelper(callback)
+	var mu sync.Mutex // protects list
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(f.ci.Checkers)
+	var listR func(dir string) error
+	listR = func(dir string) error {
+		entries, err := f.List(gCtx, dir)
+		if err != nil {
+			return err
+		}
+		var dirs []string
+		mu.Lock()
+		for _, entry := range entries {
+			err = list.Add(entry)
+			if err != nil {
+				break
+			}
+			if _, ok := entry.(fs.Directory); ok {
+				dirs = append(dirs, entry.Remote())
+			}
+		}
+		mu.Unlock()
+		if err != nil {
+			return err
+		}
+		for _, dir := range dirs {
+			// List in a new goroutine if there is a free slot,
+			// otherwise in this one to avoid deadlock.
+			if !g.TryGo(func() error { return listR(dir) }) {
+				err = listR(dir)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	g.Go(func() error { return listR(dir) })
+	err = g.Wait()
+	if err != nil {
+		return err
+	}
+	return list.Flush()
+}
+
 // Hashes are not supported
 func (f *Fs) Hashes() hash.Set {
 	return 0
@@ -1367,6 +1432,7 @@ var (
 	_ fs.Fs          = &Fs{}
 	_ fs.Mover       = &Fs{}
 	_ fs.DirMover    = &Fs{}
+	_ fs.ListRer     = &Fs{}
 	_ fs.PutStreamer = &Fs{}
 	_ fs.Shutdowner  = &Fs{}
 	_ fs.Object      = &Object{}
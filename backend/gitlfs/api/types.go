@@ -0,0 +1,96 @@
+// Package api has type definitions for the Git LFS batch API
+//
+// See https://github.com/git-lfs/git-lfs/blob/main/docs/api/batch.md
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// Batch API constants
+const (
+	MediaType         = "application/vnd.git-lfs+json"
+	OperationDownload = "download"
+	OperationUpload   = "upload"
+	TransferBasic     = "basic"
+	HashAlgoSHA256    = "sha256"
+)
+
+// Ref is the git ref a batch request is for
+type Ref struct {
+	Name string `json:"name"`
+}
+
+// ObjectSpec identifies an LFS object by its SHA256 and size
+type ObjectSpec struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// BatchRequest is sent to the objects/batch endpoint
+type BatchRequest struct {
+	Operation string       `json:"operation"`
+	Transfers []string     `json:"transfers,omitempty"`
+	Ref       *Ref         `json:"ref,omitempty"`
+	Objects   []ObjectSpec `json:"objects"`
+	HashAlgo  string       `json:"hash_algo,omitempty"`
+}
+
+// Action is how to transfer an object
+type Action struct {
+	Href      string            `json:"href"`
+	Header    map[string]string `json:"header,omitempty"`
+	ExpiresIn int64             `json:"expires_in,omitempty"` // seconds
+	ExpiresAt time.Time         `json:"expires_at,omitempty"`
+}
+
+// Actions are the transfers the server wants done for an object
+type Actions struct {
+	Download *Action `json:"download,omitempty"`
+	Upload   *Action `json:"upload,omitempty"`
+	Verify   *Action `json:"verify,omitempty"`
+}
+
+// ObjectError is the error for a single object in a batch response
+type ObjectError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error satisfies the error interface
+func (e *ObjectError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// Object is an object in a batch response
+type Object struct {
+	OID           string       `json:"oid"`
+	Size          int64        `json:"size"`
+	Authenticated bool         `json:"authenticated,omitempty"`
+	Actions       *Actions     `json:"actions,omitempty"`
+	Error         *ObjectError `json:"error,omitempty"`
+}
+
+// BatchResponse is returned from the objects/batch endpoint
+type BatchResponse struct {
+	Transfer string   `json:"transfer,omitempty"`
+	Objects  []Object `json:"objects"`
+	HashAlgo string   `json:"hash_algo,omitempty"`
+}
+
+// Error is returned by the git and LFS servers on failure
+type Error struct {
+	Message          string `json:"message"`
+	DocumentationURL string `json:"documentation_url,omitempty"`
+	RequestID        string `json:"request_id,omitempty"`
+	StatusCode       int    `json:"-"`
+}
+
+// Error satisfies the error interface
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("HTTP error %d", e.StatusCode)
+	}
+	return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
+}
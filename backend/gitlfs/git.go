@@ -0,0 +1,387 @@
+package gitlfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+// This implements just enough of version 2 of the git protocol over
+// HTTP to find the commit a ref points to and fetch its trees and
+// small blobs, which include the LFS pointers.
+//
+// See https://git-scm.com/docs/protocol-v2
+
+// pkt-line special packets
+const (
+	pktFlush = -1 // 0000
+	pktDelim = -2 // 0001
+	pktEnd   = -3 // 0002
+)
+
+// maxPointerSize is the biggest an LFS pointer can be
+const maxPointerSize = 1024
+
+// pktLine formats s as a pkt-line
+func pktLine(s string) string {
+	return fmt.Sprintf("%04x%s", len(s)+4, s)
+}
+
+// readPktLine reads a pkt-line from r returning its contents or
+// pktFlush, pktDelim or pktEnd for the special packets
+func readPktLine(r io.Reader) (line []byte, special int, err error) {
+	var lenHex [4]byte
+	if _, err = io.ReadFull(r, lenHex[:]); err != nil {
+		return nil, 0, fmt.Errorf("failed to read pkt-line: %w", err)
+	}
+	n, err := strconv.ParseUint(string(lenHex[:]), 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("bad pkt-line length %q", lenHex[:])
+	}
+	switch n {
+	case 0:
+		return nil, pktFlush, nil
+	case 1:
+		return nil, pktDelim, nil
+	case 2:
+		return nil, pktEnd, nil
+	case 3:
+		return nil, 0, errors.New("bad pkt-line length 3")
+	}
+	line = make([]byte, n-4)
+	if _, err = io.ReadFull(r, line); err != nil {
+		return nil, 0, fmt.Errorf("failed to read pkt-line: %w", err)
+	}
+	return line, 0, nil
+}
+
+// sideBandReader reads the data channel of a side-band-64k stream
+// of pkt-lines
+type sideBandReader struct {
+	r    io.Reader
+	buf  []byte
+	done bool
+}
+
+// Read the pack data into p, logging progress and returning errors
+func (s *sideBandReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		line, special, err := readPktLine(s.r)
+		if err != nil {
+			return 0, err
+		}
+		if special != 0 {
+			s.done = true
+			continue
+		}
+		if len(line) == 0 {
+			continue
+		}
+		switch line[0] {
+		case 1:
+			s.buf = line[1:]
+		case 2:
+			fs.Debugf(nil, "git: %s", bytes.TrimSpace(line[1:]))
+		case 3:
+			return 0, fmt.Errorf("git server error: %s", bytes.TrimSpace(line[1:]))
+		default:
+			return 0, fmt.Errorf("bad side band %d", line[0])
+		}
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// gitCapabilities reads the capability advertisement of the server
+func (f *Fs) gitCapabilities(ctx context.Context) (caps map[string]string, err error) {
+	var resp *http.Response
+	opts := rest.Opts{
+		Method:       "GET",
+		RootURL:      f.gitURL,
+		Path:         "/info/refs",
+		Parameters:   map[string][]string{"service": {"git-upload-pack"}},
+		ExtraHeaders: map[string]string{"Git-Protocol": "version=2"},
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.Call(ctx, &opts)
+		if err != nil {
+			return shouldRetry(ctx, resp, err)
+		}
+		defer fs.CheckClose(resp.Body, &err)
+		caps, err = parseCapabilities(bufio.NewReader(resp.Body))
+		return false, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git capabilities: %w", err)
+	}
+	if format, ok := caps["object-format"]; ok && format != "sha1" {
+		return nil, fmt.Errorf("git object format %q isn't supported", format)
+	}
+	return caps, nil
+}
+
+// parseCapabilities parses a protocol version 2 capability
+// advertisement
+func parseCapabilities(r io.Reader) (map[string]string, error) {
+	caps := map[string]string{}
+	first := true
+	for {
+		line, special, err := readPktLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if special == pktFlush {
+			if first {
+				// end of the "# service=" header
+				continue
+			}
+			return caps, nil
+		}
+		s := strings.TrimSuffix(string(line), "\n")
+		if first {
+			if strings.HasPrefix(s, "# service=") {
+				continue
+			}
+			if s != "version 2" {
+				return nil, errors.New("git server doesn't support protocol version 2")
+			}
+			first = false
+			continue
+		}
+		key, value, _ := strings.Cut(s, "=")
+		caps[key] = value
+	}
+}
+
+// gitCommand runs a protocol version 2 command returning the
+// response which must be closed
+func (f *Fs) gitCommand(ctx context.Context, command string, args []string) (resp *http.Response, err error) {
+	var body strings.Builder
+	body.WriteString(pktLine("command=" + command + "\n"))
+	body.WriteString(pktLine("agent=" + fs.GetConfig(ctx).UserAgent + "\n"))
+	body.WriteString(pktLine("object-format=sha1\n"))
+	body.WriteString("0001")
+	for _, arg := range args {
+		body.WriteString(pktLine(arg + "\n"))
+	}
+	body.WriteString("0000")
+	request := body.String()
+	opts := rest.Opts{
+		Method:      "POST",
+		RootURL:     f.gitURL,
+		Path:        "/git-upload-pack",
+		ContentType: "application/x-git-upload-pack-request",
+		ExtraHeaders: map[string]string{
+			"Accept":       "application/x-git-upload-pack-result",
+			"Git-Protocol": "version=2",
+		},
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		opts.Body = strings.NewReader(request)
+		resp, err = f.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %w", command, err)
+	}
+	return resp, nil
+}
+
+var commitIDRe = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// resolveRef finds the commit that ref points to, returning it and
+// the full name of the ref
+//
+// ref may be a full ref name, a branch or tag name, a commit ID or
+// empty for HEAD.
+func (f *Fs) resolveRef(ctx context.Context, ref string) (commit, refName string, err error) {
+	if commitIDRe.MatchString(ref) {
+		return ref, "", nil
+	}
+	var candidates []string
+	switch {
+	case ref == "" || ref == "HEAD":
+		candidates = []string{"HEAD"}
+	case strings.HasPrefix(ref, "refs/"):
+		candidates = []string{ref}
+	default:
+		candidates = []string{"refs/heads/" + ref, "refs/tags/" + ref}
+	}
+	args := []string{"peel", "symrefs"}
+	for _, candidate := range candidates {
+		args = append(args, "ref-prefix "+candidate)
+	}
+	resp, err := f.gitCommand(ctx, "ls-refs", args)
+	if err != nil {
+		return "", "", err
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	refs := map[string]string{}
+	symrefs := map[string]string{}
+	r := bufio.NewReader(resp.Body)
+	for {
+		line, special, err := readPktLine(r)
+		if err != nil {
+			return "", "", err
+		}
+		if special != 0 {
+			break
+		}
+		// <oid> <refname> [symref-target:<target>] [peeled:<oid>]
+		fields := strings.Fields(string(line))
+		if len(fields) < 2 {
+			continue
+		}
+		oid, name := fields[0], fields[1]
+		for _, attr := range fields[2:] {
+			if peeled, ok := strings.CutPrefix(attr, "peeled:"); ok {
+				oid = peeled
+			} else if target, ok := strings.CutPrefix(attr, "symref-target:"); ok {
+				symrefs[name] = target
+			}
+		}
+		refs[name] = oid
+	}
+	for _, candidate := range candidates {
+		if oid, ok := refs[candidate]; ok {
+			if target, ok := symrefs[candidate]; ok {
+				candidate = target
+			}
+			if candidate == "HEAD" {
+				candidate = ""
+			}
+			return oid, candidate, nil
+		}
+	}
+	if ref == "" {
+		return "", "", errors.New("git repository has no HEAD - is it empty?")
+	}
+	return "", "", fmt.Errorf("git ref %q not found", ref)
+}
+
+// fetchCommit fetches the commit with its trees and small blobs
+func (f *Fs) fetchCommit(ctx context.Context, commit string, caps map[string]string) (objects map[string]*gitObject, err error) {
+	args := []string{"no-progress", "ofs-delta"}
+	fetchCaps := strings.Fields(caps["fetch"])
+	for _, c := range fetchCaps {
+		switch c {
+		case "shallow":
+			args = append(args, "deepen 1")
+		case "filter":
+			args = append(args, fmt.Sprintf("filter blob:limit=%d", maxPointerSize))
+		}
+	}
+	args = append(args, "want "+commit, "done")
+	resp, err := f.gitCommand(ctx, "fetch", args)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	r := bufio.NewReader(resp.Body)
+	// Skip sections until the packfile
+	for {
+		line, special, err := readPktLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if special == pktFlush || special == pktEnd {
+			return nil, errors.New("git fetch returned no packfile")
+		}
+		if special == 0 {
+			s := strings.TrimSuffix(string(line), "\n")
+			if s == "packfile" {
+				break
+			}
+			if msg, ok := strings.CutPrefix(s, "ERR "); ok {
+				return nil, fmt.Errorf("git server error: %s", msg)
+			}
+		}
+	}
+	objects, err = readPack(&sideBandReader{r: r})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git pack: %w", err)
+	}
+	return objects, nil
+}
+
+// pointer is a parsed LFS pointer
+type pointer struct {
+	oid  string // SHA256 of the object
+	size int64  // size of the object
+}
+
+var oidRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// parsePointer parses data as an LFS pointer returning nil if it
+// isn't one
+//
+// See https://github.com/git-lfs/git-lfs/blob/main/docs/spec.md
+func parsePointer(data []byte) *pointer {
+	if len(data) > maxPointerSize || !bytes.HasPrefix(data, []byte("version https://git-lfs.github.com/spec/v1\n")) {
+		return nil
+	}
+	p := &pointer{size: -1}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "oid":
+			if oid, ok := strings.CutPrefix(value, "sha256:"); ok && oidRe.MatchString(oid) {
+				p.oid = oid
+			}
+		case "size":
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil && size >= 0 {
+				p.size = size
+			}
+		}
+	}
+	if p.oid == "" || p.size < 0 {
+		return nil
+	}
+	return p
+}
+
+// walkTree calls fn for each LFS pointer in the tree with ID id
+func walkTree(objects map[string]*gitObject, id, dir string, fn func(remote string, p *pointer)) error {
+	tree := objects[id]
+	if tree == nil || tree.typ != objTree {
+		return fmt.Errorf("git tree %s missing from pack", id)
+	}
+	entries, err := parseTree(tree.data)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		remote := path.Join(dir, entry.name)
+		switch entry.mode {
+		case "40000", "040000":
+			if err := walkTree(objects, entry.id, remote, fn); err != nil {
+				return err
+			}
+		case "100644", "100755":
+			blob := objects[entry.id]
+			if blob == nil || blob.typ != objBlob {
+				// too big to be a pointer so filtered out
+				continue
+			}
+			if p := parsePointer(blob.data); p != nil {
+				fn(remote, p)
+			}
+		}
+	}
+	return nil
+}
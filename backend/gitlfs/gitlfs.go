@@ -0,0 +1,756 @@
+// Package gitlfs provides an interface to the objects stored on a
+// Git LFS server
+package gitlfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/gitlfs/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2   // bigger for slower decay, exponential
+	batchSize     = 100 // objects to ask about in each batch request
+	expiryLeeway  = time.Minute
+)
+
+var (
+	errNoPointer = errors.New("there is no LFS pointer for this path in the git ref - commit and push the pointer first")
+	errNoRemove  = errors.New("git lfs servers can't delete objects")
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "gitlfs",
+		Description: "Git LFS",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "url",
+			Help: `URL of the git repository.
+
+E.g. "https://github.com/owner/repo.git". Only https and http URLs
+are supported.`,
+			Required: true,
+		}, {
+			Name: "ref",
+			Help: `Git ref to read the LFS pointers from.
+
+This can be a branch, a tag, a full ref name such as
+"refs/heads/main" or a commit ID.
+
+Leave blank to use the default branch.`,
+		}, {
+			Name: "user",
+			Help: "User name.",
+		}, {
+			Name:       "pass",
+			Help:       `Password or access token.`,
+			IsPassword: true,
+		}, {
+			Name: "lfs_url",
+			Help: `URL of the LFS server.
+
+Leave blank to use the LFS server of the repository, which is the
+repository URL with ".git/info/lfs" added. Set this if the
+repository's .lfsconfig points somewhere else.`,
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			Default: (encoder.Base |
+				encoder.EncodeInvalidUtf8),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	URL    string               `config:"url"`
+	Ref    string               `config:"ref"`
+	User   string               `config:"user"`
+	Pass   string               `config:"pass"`
+	LFSURL string               `config:"lfs_url"`
+	Enc    encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents the LFS objects in a git ref
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	opt      Options      // parsed options
+	features *fs.Features // optional features
+	srv      *rest.Client // the connection to the git and LFS servers
+	xfer     *rest.Client // the connection for transfers, without auth
+	pacer    *fs.Pacer    // pacer for API calls
+	gitURL   string       // URL of the git repository
+	lfsURL   string       // URL of the LFS server
+	commit   string       // the commit the ref points to
+	refName  string       // full name of the ref or "" for a commit ID
+	modTime  time.Time    // time of the commit
+
+	mu    sync.Mutex                 // protects the below
+	files map[string]*lfsFile        // files by path
+	dirs  map[string]map[string]bool // names of the entries in each directory, true if a directory
+}
+
+// lfsFile is an LFS pointer in the git tree and what is known about
+// its object on the LFS server
+type lfsFile struct {
+	pointer
+	checked  bool        // set if we've asked the LFS server about it
+	present  bool        // set if the LFS server has it
+	download *api.Action // how to download it if known
+	expires  time.Time   // when download expires, zero for never
+}
+
+// Object describes an LFS object
+type Object struct {
+	fs     *Fs      // what this object is part of
+	remote string   // the remote path
+	file   *lfsFile // the pointer for this object
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("Git LFS %s at %s path %q", f.gitURL, f.commit, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision returns the precision of this Fs
+func (f *Fs) Precision() time.Duration {
+	return fs.ModTimeNotSupported
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.SHA256)
+}
+
+// errorHandler parses a non 2xx error response
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("error when trying to read error body: %w", err)
+	}
+	errResponse := &api.Error{}
+	if strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		_ = json.Unmarshal(body, errResponse)
+	}
+	if errResponse.Message == "" {
+		errResponse.Message = strings.TrimSpace(string(body))
+	}
+	errResponse.StatusCode = resp.StatusCode
+	return errResponse
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// defaultLFSURL returns the LFS server URL for the repository at
+// gitURL as described in the LFS server discovery docs
+func defaultLFSURL(gitURL string) string {
+	if strings.HasSuffix(gitURL, ".git") {
+		return gitURL + "/info/lfs"
+	}
+	return gitURL + ".git/info/lfs"
+}
+
+// NewFs constructs an Fs from the path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	gitURL := strings.TrimSuffix(opt.URL, "/")
+	u, err := url.Parse(gitURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("url %q must be https or http", opt.URL)
+	}
+	if opt.Pass != "" {
+		opt.Pass, err = obscure.Reveal(opt.Pass)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt password: %w", err)
+		}
+	}
+	lfsURL := strings.TrimSuffix(opt.LFSURL, "/")
+	if lfsURL == "" {
+		lfsURL = defaultLFSURL(gitURL)
+	}
+	client := fshttp.NewClient(ctx)
+	f := &Fs{
+		name:   name,
+		root:   strings.Trim(root, "/"),
+		opt:    *opt,
+		srv:    rest.NewClient(client).SetErrorHandler(errorHandler),
+		xfer:   rest.NewClient(client).SetErrorHandler(errorHandler),
+		pacer:  fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+		gitURL: gitURL,
+		lfsURL: lfsURL,
+	}
+	if opt.User != "" || opt.Pass != "" {
+		f.srv.SetUserPass(opt.User, opt.Pass)
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: false,
+	}).Fill(ctx, f)
+
+	err = f.readIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check to see if the root is a file
+	f.mu.Lock()
+	_, isFile := f.files[f.root]
+	f.mu.Unlock()
+	if isFile {
+		newRoot := path.Dir(f.root)
+		if newRoot == "." {
+			newRoot = ""
+		}
+		f.root = newRoot
+		// return an error with an fs which points to the parent
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// readIndex reads the LFS pointers in the ref from the git server
+func (f *Fs) readIndex(ctx context.Context) error {
+	caps, err := f.gitCapabilities(ctx)
+	if err != nil {
+		return err
+	}
+	f.commit, f.refName, err = f.resolveRef(ctx, f.opt.Ref)
+	if err != nil {
+		return err
+	}
+	objects, err := f.fetchCommit(ctx, f.commit, caps)
+	if err != nil {
+		return err
+	}
+	commit := objects[f.commit]
+	if commit == nil || commit.typ != objCommit {
+		return fmt.Errorf("git commit %s missing from pack", f.commit)
+	}
+	tree, when, err := parseCommit(commit.data)
+	if err != nil {
+		return err
+	}
+	f.modTime = time.Unix(when, 0)
+	f.files = map[string]*lfsFile{}
+	f.dirs = map[string]map[string]bool{"": {}}
+	err = walkTree(objects, tree, "", func(remote string, p *pointer) {
+		elements := strings.Split(remote, "/")
+		for i, element := range elements {
+			elements[i] = f.opt.Enc.ToStandardName(element)
+		}
+		dir := ""
+		for _, element := range elements[:len(elements)-1] {
+			child := path.Join(dir, element)
+			f.dirs[dir][element] = true
+			if f.dirs[child] == nil {
+				f.dirs[child] = map[string]bool{}
+			}
+			dir = child
+		}
+		f.dirs[dir][elements[len(elements)-1]] = false
+		f.files[path.Join(elements...)] = &lfsFile{pointer: *p}
+	})
+	if err != nil {
+		return err
+	}
+	fs.Debugf(f, "Found %d LFS pointers in commit %s", len(f.files), f.commit)
+	return nil
+}
+
+// batch makes a batch request for the objects
+func (f *Fs) batch(ctx context.Context, operation string, objects []api.ObjectSpec) (result *api.BatchResponse, err error) {
+	var resp *http.Response
+	request := api.BatchRequest{
+		Operation: operation,
+		Transfers: []string{api.TransferBasic},
+		Objects:   objects,
+		HashAlgo:  api.HashAlgoSHA256,
+	}
+	if f.refName != "" {
+		request.Ref = &api.Ref{Name: f.refName}
+	}
+	opts := rest.Opts{
+		Method:      "POST",
+		RootURL:     f.lfsURL,
+		Path:        "/objects/batch",
+		ContentType: api.MediaType,
+		ExtraHeaders: map[string]string{
+			"Accept": api.MediaType,
+		},
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		result = new(api.BatchResponse)
+		resp, err = f.srv.CallJSON(ctx, &opts, &request, result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("git lfs %s batch failed: %w", operation, err)
+	}
+	if result.Transfer != "" && result.Transfer != api.TransferBasic {
+		return nil, fmt.Errorf("git lfs server wants unsupported transfer %q", result.Transfer)
+	}
+	return result, nil
+}
+
+// checkFiles asks the LFS server whether it has the objects for
+// files, noting how to download the ones it has
+func (f *Fs) checkFiles(ctx context.Context, files []*lfsFile) error {
+	for len(files) > 0 {
+		n := min(len(files), batchSize)
+		chunk := files[:n]
+		files = files[n:]
+		specs := make([]api.ObjectSpec, len(chunk))
+		for i, file := range chunk {
+			specs[i] = api.ObjectSpec{OID: file.oid, Size: file.size}
+		}
+		result, err := f.batch(ctx, api.OperationDownload, specs)
+		if err != nil {
+			return err
+		}
+		byOID := make(map[string]*api.Object, len(result.Objects))
+		for i := range result.Objects {
+			byOID[result.Objects[i].OID] = &result.Objects[i]
+		}
+		now := time.Now()
+		f.mu.Lock()
+		for _, file := range chunk {
+			object := byOID[file.oid]
+			if object == nil {
+				f.mu.Unlock()
+				return fmt.Errorf("git lfs server didn't return object %s", file.oid)
+			}
+			file.checked = true
+			file.present = false
+			file.download = nil
+			switch {
+			case object.Error != nil && (object.Error.Code == http.StatusNotFound || object.Error.Code == http.StatusGone):
+			case object.Error != nil:
+				f.mu.Unlock()
+				return fmt.Errorf("git lfs object %s: %w", file.oid, object.Error)
+			case object.Actions == nil || object.Actions.Download == nil:
+				f.mu.Unlock()
+				return fmt.Errorf("git lfs server returned no download for object %s", file.oid)
+			default:
+				file.present = true
+				file.download = object.Actions.Download
+				file.expires = actionExpiry(file.download, now)
+			}
+		}
+		f.mu.Unlock()
+	}
+	return nil
+}
+
+// actionExpiry returns when action expires or the zero time if it
+// doesn't
+func actionExpiry(action *api.Action, now time.Time) time.Time {
+	if action.ExpiresIn > 0 {
+		return now.Add(time.Duration(action.ExpiresIn) * time.Second)
+	}
+	return action.ExpiresAt
+}
+
+// checkUnknown checks the files in the list which haven't been
+// checked yet
+func (f *Fs) checkUnknown(ctx context.Context, files []*lfsFile) error {
+	var unknown []*lfsFile
+	f.mu.Lock()
+	for _, file := range files {
+		if !file.checked {
+			unknown = append(unknown, file)
+		}
+	}
+	f.mu.Unlock()
+	if len(unknown) == 0 {
+		return nil
+	}
+	return f.checkFiles(ctx, unknown)
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// Only LFS objects which the LFS server has are listed.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	fullDir := path.Join(f.root, dir)
+	f.mu.Lock()
+	children, ok := f.dirs[fullDir]
+	var files []*lfsFile
+	var names []string
+	for name, isDir := range children {
+		if isDir {
+			entries = append(entries, fs.NewDir(path.Join(dir, name), f.modTime))
+		} else {
+			names = append(names, name)
+			files = append(files, f.files[path.Join(fullDir, name)])
+		}
+	}
+	f.mu.Unlock()
+	if !ok {
+		return nil, fs.ErrorDirNotFound
+	}
+	err = f.checkUnknown(ctx, files)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, file := range files {
+		if file.present {
+			entries = append(entries, &Object{
+				fs:     f,
+				remote: path.Join(dir, names[i]),
+				file:   file,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	p := path.Join(f.root, remote)
+	f.mu.Lock()
+	file, ok := f.files[p]
+	_, isDir := f.dirs[p]
+	f.mu.Unlock()
+	if !ok {
+		if isDir {
+			return nil, fs.ErrorIsDir
+		}
+		return nil, fs.ErrorObjectNotFound
+	}
+	err := f.checkUnknown(ctx, []*lfsFile{file})
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	present := file.present
+	f.mu.Unlock()
+	if !present {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return &Object{
+		fs:     f,
+		remote: remote,
+		file:   file,
+	}, nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// The path must have an LFS pointer in the git ref and the data must
+// match it.
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	remote := src.Remote()
+	f.mu.Lock()
+	file, ok := f.files[path.Join(f.root, remote)]
+	f.mu.Unlock()
+	if !ok {
+		return nil, errNoPointer
+	}
+	o := &Object{
+		fs:     f,
+		remote: remote,
+		file:   file,
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Directories come from the git tree so this does nothing.
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return nil
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	f.mu.Lock()
+	_, ok := f.dirs[path.Join(f.root, dir)]
+	f.mu.Unlock()
+	if !ok {
+		return fs.ErrorDirNotFound
+	}
+	return fs.ErrorDirectoryNotEmpty
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the SHA256 of the object which is its LFS object ID
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if t != hash.SHA256 {
+		return "", hash.ErrUnsupported
+	}
+	return o.file.oid, nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.file.size
+}
+
+// ModTime returns the time of the commit as LFS objects don't have
+// modification times
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.fs.modTime
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Storable returns whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// actionOpts makes the options for calling action
+//
+// The credentials are sent if the action is on the LFS server and
+// doesn't have its own authorization.
+func (o *Object) actionOpts(method string, action *api.Action) *rest.Opts {
+	opts := &rest.Opts{
+		Method:       method,
+		RootURL:      action.Href,
+		ExtraHeaders: map[string]string{},
+	}
+	hasAuth := false
+	for k, v := range action.Header {
+		opts.ExtraHeaders[k] = v
+		if strings.EqualFold(k, "Authorization") {
+			hasAuth = true
+		}
+	}
+	if !hasAuth && (o.fs.opt.User != "" || o.fs.opt.Pass != "") {
+		actionURL, err := url.Parse(action.Href)
+		lfsURL, lfsErr := url.Parse(o.fs.lfsURL)
+		if err == nil && lfsErr == nil && actionURL.Host == lfsURL.Host {
+			opts.UserName = o.fs.opt.User
+			opts.Password = o.fs.opt.Pass
+		}
+	}
+	return opts
+}
+
+// downloadAction returns a current download action for the object
+func (o *Object) downloadAction(ctx context.Context) (*api.Action, error) {
+	f := o.fs
+	f.mu.Lock()
+	file := o.file
+	valid := file.present && file.download != nil && (file.expires.IsZero() || time.Until(file.expires) > expiryLeeway)
+	if !valid {
+		file.checked = false
+	}
+	f.mu.Unlock()
+	if !valid {
+		err := f.checkFiles(ctx, []*lfsFile{file})
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !file.present {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return file.download, nil
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	action, err := o.downloadAction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fs.FixRangeOption(options, o.file.size)
+	opts := o.actionOpts("GET", action)
+	opts.Options = options
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.xfer.Call(ctx, opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download LFS object %s: %w", o.file.oid, err)
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The data must match the LFS pointer for the object in the git ref.
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	f := o.fs
+	size := src.Size()
+	if size >= 0 && size != o.file.size {
+		return fmt.Errorf("size %d doesn't match the LFS pointer size %d", size, o.file.size)
+	}
+	if sha, err := src.Hash(ctx, hash.SHA256); err == nil && sha != "" && sha != o.file.oid {
+		return fmt.Errorf("SHA256 %s doesn't match the LFS pointer %s", sha, o.file.oid)
+	}
+	spec := api.ObjectSpec{OID: o.file.oid, Size: o.file.size}
+	result, err := f.batch(ctx, api.OperationUpload, []api.ObjectSpec{spec})
+	if err != nil {
+		return err
+	}
+	if len(result.Objects) != 1 {
+		return errors.New("git lfs server returned the wrong number of objects")
+	}
+	object := result.Objects[0]
+	if object.Error != nil {
+		return fmt.Errorf("git lfs object %s: %w", o.file.oid, object.Error)
+	}
+	if object.Actions != nil && object.Actions.Upload != nil {
+		hasher := sha256.New()
+		opts := o.actionOpts("PUT", object.Actions.Upload)
+		opts.Body = io.TeeReader(in, hasher)
+		opts.ContentLength = &o.file.size
+		opts.ContentType = "application/octet-stream"
+		opts.NoResponse = true
+		var resp *http.Response
+		err = f.pacer.CallNoRetry(func() (bool, error) {
+			resp, err = f.xfer.Call(ctx, opts)
+			return shouldRetry(ctx, resp, err)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload LFS object %s: %w", o.file.oid, err)
+		}
+		if sum := hex.EncodeToString(hasher.Sum(nil)); sum != o.file.oid {
+			return fmt.Errorf("uploaded data has SHA256 %s which doesn't match the LFS pointer %s", sum, o.file.oid)
+		}
+		if verify := object.Actions.Verify; verify != nil {
+			opts := o.actionOpts("POST", verify)
+			opts.ContentType = api.MediaType
+			opts.ExtraHeaders["Accept"] = api.MediaType
+			opts.NoResponse = true
+			err = f.pacer.Call(func() (bool, error) {
+				resp, err = f.xfer.CallJSON(ctx, opts, &spec, nil)
+				return shouldRetry(ctx, resp, err)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to verify LFS object %s: %w", o.file.oid, err)
+			}
+		}
+	} else {
+		fs.Debugf(o, "LFS server already has object %s", o.file.oid)
+	}
+	// Forget any download action so it is fetched again
+	f.mu.Lock()
+	o.file.checked = false
+	o.file.present = true
+	o.file.download = nil
+	f.mu.Unlock()
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	return errNoRemove
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs     = &Fs{}
+	_ fs.Object = &Object{}
+)
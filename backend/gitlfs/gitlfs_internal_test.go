@@ -0,0 +1,469 @@
+package gitlfs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/gitlfs/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPackObject is an object to write into a test pack
+type testPackObject struct {
+	typ  int
+	data []byte
+	// if >= 0 write data as an ofs delta against the object with
+	// this index, copying its first byte and inserting the rest
+	deltaOf int
+}
+
+// writePack makes a git pack holding objects
+func writePack(objects []testPackObject) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("PACK")
+	_ = binary.Write(&buf, binary.BigEndian, uint32(2))
+	_ = binary.Write(&buf, binary.BigEndian, uint32(len(objects)))
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		typ, data := object.typ, object.data
+		if object.deltaOf >= 0 && i > 0 && object.deltaOf < i {
+			typ = objOfsDelta
+			base := objects[object.deltaOf].data
+			var delta []byte
+			delta = binary.AppendUvarint(delta, uint64(len(base)))
+			delta = binary.AppendUvarint(delta, uint64(len(data)))
+			// copy the first byte of the base then insert the rest
+			delta = append(delta, 0x80|0x10, 1)
+			rest := data[1:]
+			for len(rest) > 0 {
+				n := min(len(rest), 127)
+				delta = append(delta, byte(n))
+				delta = append(delta, rest[:n]...)
+				rest = rest[n:]
+			}
+			data = delta
+		}
+		size := len(data)
+		c := byte(typ<<4) | byte(size&0x0f)
+		size >>= 4
+		for size > 0 {
+			buf.WriteByte(c | 0x80)
+			c = byte(size & 0x7f)
+			size >>= 7
+		}
+		buf.WriteByte(c)
+		if typ == objOfsDelta {
+			// offset encoding used by git
+			offset := offsets[i] - offsets[object.deltaOf]
+			var enc []byte
+			enc = append(enc, byte(offset&0x7f))
+			for offset >>= 7; offset > 0; offset >>= 7 {
+				offset--
+				enc = append([]byte{0x80 | byte(offset&0x7f)}, enc...)
+			}
+			buf.Write(enc)
+		}
+		zw := zlib.NewWriter(&buf)
+		_, _ = zw.Write(data)
+		_ = zw.Close()
+	}
+	sum := sha256.Sum256(buf.Bytes()) // trailer isn't checked
+	buf.Write(sum[:20])
+	return buf.Bytes()
+}
+
+// treeData makes the contents of a git tree
+func treeData(entries []treeEntry) []byte {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		id, _ := hex.DecodeString(entry.id)
+		fmt.Fprintf(&buf, "%s %s\x00", entry.mode, entry.name)
+		buf.Write(id)
+	}
+	return buf.Bytes()
+}
+
+// pointerData makes an LFS pointer for contents
+func pointerData(contents string) []byte {
+	return fmt.Appendf(nil, "version https://git-lfs.github.com/spec/v1\noid sha256:%x\nsize %d\n", sha256.Sum256([]byte(contents)), len(contents))
+}
+
+func oidOf(contents string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(contents)))
+}
+
+const testUser, testPass = "user", "token"
+
+var testCommitTime = time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+
+// testServer is a fake git and LFS server
+type testServer struct {
+	t      *testing.T
+	ts     *httptest.Server
+	pack   []byte
+	commit string
+	mu     sync.Mutex
+	store  map[string][]byte // LFS objects by oid
+}
+
+// newTestServer makes a repository with:
+//
+//	big.bin      - LFS object on the server
+//	missing.bin  - LFS object not on the server
+//	readme.txt   - ordinary file
+//	dir/a.bin    - LFS object on the server, stored as a delta
+func newTestServer(t *testing.T) *testServer {
+	s := &testServer{
+		t:     t,
+		store: map[string][]byte{},
+	}
+	bigPointer := pointerData("big contents")
+	missingPointer := pointerData("missing contents")
+	aPointer := pointerData("a contents")
+	readme := []byte("not a pointer\n")
+	s.store[oidOf("big contents")] = []byte("big contents")
+	s.store[oidOf("a contents")] = []byte("a contents")
+
+	subTree := treeData([]treeEntry{
+		{mode: "100644", name: "a.bin", id: objectID(objBlob, aPointer)},
+	})
+	rootTree := treeData([]treeEntry{
+		{mode: "100644", name: "big.bin", id: objectID(objBlob, bigPointer)},
+		{mode: "40000", name: "dir", id: objectID(objTree, subTree)},
+		{mode: "100755", name: "missing.bin", id: objectID(objBlob, missingPointer)},
+		{mode: "100644", name: "readme.txt", id: objectID(objBlob, readme)},
+	})
+	commit := fmt.Appendf(nil, "tree %s\nauthor A U Thor <a@example.com> %d +0000\ncommitter A U Thor <a@example.com> %d +0000\n\nmessage\n",
+		objectID(objTree, rootTree), testCommitTime.Unix(), testCommitTime.Unix())
+	s.commit = objectID(objCommit, commit)
+	s.pack = writePack([]testPackObject{
+		{typ: objCommit, data: commit, deltaOf: -1},
+		{typ: objTree, data: rootTree, deltaOf: -1},
+		{typ: objTree, data: subTree, deltaOf: -1},
+		{typ: objBlob, data: bigPointer, deltaOf: -1},
+		{typ: objBlob, data: missingPointer, deltaOf: -1},
+		{typ: objBlob, data: readme, deltaOf: -1},
+		{typ: objBlob, data: aPointer, deltaOf: 3},
+	})
+	s.ts = httptest.NewServer(s)
+	t.Cleanup(s.ts.Close)
+	return s
+}
+
+func writePktLines(w io.Writer, lines ...string) {
+	for _, line := range lines {
+		switch line {
+		case "0000", "0001":
+			_, _ = io.WriteString(w, line)
+		default:
+			_, _ = io.WriteString(w, pktLine(line))
+		}
+	}
+}
+
+// ServeHTTP implements the fake servers
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t := s.t
+	if strings.HasPrefix(r.URL.Path, "/repo.git/") {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != testUser || pass != testPass {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/repo.git/info/refs":
+		assert.Equal(t, "git-upload-pack", r.URL.Query().Get("service"))
+		assert.Equal(t, "version=2", r.Header.Get("Git-Protocol"))
+		writePktLines(w, "# service=git-upload-pack\n", "0000", "version 2\n", "ls-refs=unborn\n", "fetch=shallow filter\n", "object-format=sha1\n", "0000")
+	case r.Method == "POST" && r.URL.Path == "/repo.git/git-upload-pack":
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case bytes.Contains(body, []byte("command=ls-refs")):
+			writePktLines(w,
+				s.commit+" HEAD symref-target:refs/heads/main\n",
+				s.commit+" refs/heads/main\n",
+				strings.Repeat("1", 40)+" refs/tags/v1 peeled:"+s.commit+"\n",
+				"0000")
+		case bytes.Contains(body, []byte("command=fetch")):
+			assert.Contains(t, string(body), "want "+s.commit)
+			assert.Contains(t, string(body), "deepen 1")
+			assert.Contains(t, string(body), "filter blob:limit=1024")
+			writePktLines(w, "shallow-info\n", "shallow "+s.commit+"\n", "0001", "packfile\n", "\x02counting objects\n")
+			for pack := s.pack; len(pack) > 0; {
+				n := min(len(pack), 50)
+				writePktLines(w, "\x01"+string(pack[:n]))
+				pack = pack[n:]
+			}
+			writePktLines(w, "0000")
+		default:
+			http.Error(w, "bad command", http.StatusBadRequest)
+		}
+	case r.Method == "POST" && r.URL.Path == "/repo.git/info/lfs/objects/batch":
+		var request api.BatchRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, &api.Ref{Name: "refs/heads/main"}, request.Ref)
+		var response api.BatchResponse
+		s.mu.Lock()
+		for _, spec := range request.Objects {
+			object := api.Object{OID: spec.OID, Size: spec.Size}
+			_, present := s.store[spec.OID]
+			switch {
+			case request.Operation == api.OperationDownload && present:
+				object.Actions = &api.Actions{Download: &api.Action{
+					Href:      s.ts.URL + "/objects/" + spec.OID,
+					Header:    map[string]string{"Authorization": "RemoteAuth secret"},
+					ExpiresIn: 3600,
+				}}
+			case request.Operation == api.OperationDownload:
+				object.Error = &api.ObjectError{Code: 404, Message: "Object does not exist"}
+			case request.Operation == api.OperationUpload && !present:
+				object.Actions = &api.Actions{
+					Upload: &api.Action{
+						Href:   s.ts.URL + "/objects/" + spec.OID,
+						Header: map[string]string{"Authorization": "RemoteAuth secret"},
+					},
+					Verify: &api.Action{Href: s.ts.URL + "/repo.git/info/lfs/verify"},
+				}
+			}
+			response.Objects = append(response.Objects, object)
+		}
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", api.MediaType)
+		_ = json.NewEncoder(w).Encode(&response)
+	case r.Method == "POST" && r.URL.Path == "/repo.git/info/lfs/verify":
+		var spec api.ObjectSpec
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&spec))
+		s.mu.Lock()
+		data, ok := s.store[spec.OID]
+		s.mu.Unlock()
+		if !ok || int64(len(data)) != spec.Size {
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	case strings.HasPrefix(r.URL.Path, "/objects/"):
+		if r.Header.Get("Authorization") != "RemoteAuth secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		oid := strings.TrimPrefix(r.URL.Path, "/objects/")
+		switch r.Method {
+		case "GET":
+			s.mu.Lock()
+			data, ok := s.store[oid]
+			s.mu.Unlock()
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			http.ServeContent(w, r, oid, time.Time{}, bytes.NewReader(data))
+		case "PUT":
+			data, _ := io.ReadAll(r.Body)
+			if oidOf(string(data)) != oid {
+				http.Error(w, "bad oid", http.StatusUnprocessableEntity)
+				return
+			}
+			s.mu.Lock()
+			s.store[oid] = data
+			s.mu.Unlock()
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *testServer) config() configmap.Simple {
+	return configmap.Simple{
+		"type": "gitlfs",
+		"url":  s.ts.URL + "/repo.git",
+		"user": testUser,
+		"pass": obscure.MustObscure(testPass),
+	}
+}
+
+func TestParsePointer(t *testing.T) {
+	p := parsePointer(pointerData("hello"))
+	require.NotNil(t, p)
+	assert.Equal(t, oidOf("hello"), p.oid)
+	assert.Equal(t, int64(5), p.size)
+
+	assert.Nil(t, parsePointer([]byte("hello")))
+	assert.Nil(t, parsePointer([]byte("version https://git-lfs.github.com/spec/v1\noid sha256:1234\nsize 5\n")))
+	assert.Nil(t, parsePointer([]byte("version https://git-lfs.github.com/spec/v1\noid sha256:"+oidOf("x")+"\n")))
+}
+
+func TestApplyDelta(t *testing.T) {
+	base := []byte("hello world")
+	delta := []byte{11, 12, 0x91, 6, 5, 2, 'o', 'h', 0x90, 5}
+	got, err := applyDelta(base, delta)
+	require.NoError(t, err)
+	assert.Equal(t, "worldohhello", string(got))
+
+	_, err = applyDelta(base, []byte{10, 1, 1, 'x'})
+	assert.Error(t, err)
+	_, err = applyDelta(base, []byte{11, 5, 0x91, 8, 5})
+	assert.Error(t, err)
+}
+
+func TestReadPack(t *testing.T) {
+	objects, err := readPack(bytes.NewReader(writePack([]testPackObject{
+		{typ: objBlob, data: []byte("base object"), deltaOf: -1},
+		{typ: objBlob, data: []byte("bigger object"), deltaOf: 0},
+	})))
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(objects))
+	object := objects[objectID(objBlob, []byte("bigger object"))]
+	require.NotNil(t, object)
+	assert.Equal(t, "bigger object", string(object.data))
+
+	_, err = readPack(bytes.NewReader([]byte("PACX\x00\x00\x00\x02\x00\x00\x00\x00")))
+	assert.Error(t, err)
+}
+
+func TestDefaultLFSURL(t *testing.T) {
+	assert.Equal(t, "https://example.com/foo/bar.git/info/lfs", defaultLFSURL("https://example.com/foo/bar"))
+	assert.Equal(t, "https://example.com/foo/bar.git/info/lfs", defaultLFSURL("https://example.com/foo/bar.git"))
+}
+
+func TestGitLFS(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	f, err := NewFs(ctx, "TestGitLFS", "", s.config())
+	require.NoError(t, err)
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	sort.Sort(entries)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+	}
+	assert.Equal(t, []string{"big.bin", "dir"}, names)
+	assert.True(t, testCommitTime.Equal(entries[0].ModTime(ctx)))
+
+	entries, err = f.List(ctx, "dir")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "dir/a.bin", entries[0].Remote())
+
+	_, err = f.List(ctx, "notfound")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	o, err := f.NewObject(ctx, "big.bin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("big contents")), o.Size())
+	sha, err := o.Hash(ctx, hash.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, oidOf("big contents"), sha)
+
+	in, err := o.Open(ctx, &fs.RangeOption{Start: 4, End: 6})
+	require.NoError(t, err)
+	got, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "con", string(got))
+
+	_, err = f.NewObject(ctx, "missing.bin")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	_, err = f.NewObject(ctx, "readme.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// Upload to a path with no pointer
+	src := object.NewStaticObjectInfo("readme.txt", time.Now(), 5, true, nil, nil)
+	_, err = f.Put(ctx, strings.NewReader("hello"), src)
+	assert.Equal(t, errNoPointer, err)
+
+	// Upload the wrong data for the pointer
+	src = object.NewStaticObjectInfo("missing.bin", time.Now(), int64(len("missing contentz")), true, nil, nil)
+	_, err = f.Put(ctx, strings.NewReader("missing contentz"), src)
+	assert.Error(t, err)
+
+	// Upload the right data
+	src = object.NewStaticObjectInfo("missing.bin", time.Now(), int64(len("missing contents")), true, nil, nil)
+	o, err = f.Put(ctx, strings.NewReader("missing contents"), src)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("missing contents"), s.store[oidOf("missing contents")])
+
+	in, err = o.Open(ctx)
+	require.NoError(t, err)
+	got, err = io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "missing contents", string(got))
+
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(entries))
+
+	assert.Equal(t, errNoRemove, o.Remove(ctx))
+}
+
+func TestRef(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	fsi, err := NewFs(ctx, "TestGitLFS", "", s.config())
+	require.NoError(t, err)
+	f := fsi.(*Fs)
+
+	for _, test := range []struct {
+		ref         string
+		wantRefName string
+		wantErr     bool
+	}{
+		{ref: "", wantRefName: "refs/heads/main"},
+		{ref: "main", wantRefName: "refs/heads/main"},
+		{ref: "refs/heads/main", wantRefName: "refs/heads/main"},
+		{ref: "v1", wantRefName: "refs/tags/v1"},
+		{ref: s.commit, wantRefName: ""},
+		{ref: "notfound", wantErr: true},
+	} {
+		commit, refName, err := f.resolveRef(ctx, test.ref)
+		if test.wantErr {
+			assert.Error(t, err, test.ref)
+			continue
+		}
+		require.NoError(t, err, test.ref)
+		assert.Equal(t, s.commit, commit, test.ref)
+		assert.Equal(t, test.wantRefName, refName, test.ref)
+	}
+}
+
+func TestRootIsFile(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	f, err := NewFs(ctx, "TestGitLFS", "dir/a.bin", s.config())
+	assert.Equal(t, fs.ErrorIsFile, err)
+	assert.Equal(t, "dir", f.Root())
+	_, err = f.NewObject(ctx, "a.bin")
+	require.NoError(t, err)
+}
+
+func TestBadAuth(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	m := s.config()
+	m["pass"] = obscure.MustObscure("wrong")
+	_, err := NewFs(ctx, "TestGitLFS", "", m)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
@@ -0,0 +1,357 @@
+package gitlfs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Git object types as stored in a pack
+const (
+	objCommit   = 1
+	objTree     = 2
+	objBlob     = 3
+	objTag      = 4
+	objOfsDelta = 6
+	objRefDelta = 7
+)
+
+// maxKeepSize is the biggest blob whose contents are kept when
+// reading a pack. LFS pointers are much smaller than this, but small
+// blobs may be the bases of deltas for pointers.
+const maxKeepSize = 1024 * 1024
+
+// gitObject is an object read from a pack
+type gitObject struct {
+	typ  int    // objCommit, objTree, objBlob or objTag
+	data []byte // contents, nil if the blob was too big to keep
+}
+
+// packEntry is an entry in a pack before deltas are resolved
+type packEntry struct {
+	offset  int64  // offset of the entry in the pack
+	typ     int    // type of the entry
+	data    []byte // contents or delta, nil if not kept
+	baseOff int64  // offset of the base for objOfsDelta
+	baseID  string // ID of the base for objRefDelta
+	id      string // ID once resolved
+}
+
+// countingReader counts the bytes read through it so the offsets of
+// the pack entries are known
+//
+// It implements io.ByteReader so the zlib reader doesn't read past
+// the end of each entry.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+// Read bytes into p
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ReadByte reads a single byte
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// objectID returns the git ID of an object of typ with data
+func objectID(typ int, data []byte) string {
+	var name string
+	switch typ {
+	case objCommit:
+		name = "commit"
+	case objTree:
+		name = "tree"
+	case objBlob:
+		name = "blob"
+	case objTag:
+		name = "tag"
+	}
+	h := sha1.New()
+	_, _ = fmt.Fprintf(h, "%s %d\x00", name, len(data))
+	_, _ = h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readPack reads the git pack in r returning the objects in it by ID
+//
+// The contents of blobs bigger than maxKeepSize are discarded.
+func readPack(r io.Reader) (map[string]*gitObject, error) {
+	cr := &countingReader{r: bufio.NewReader(r)}
+	var header [12]byte
+	if _, err := io.ReadFull(cr, header[:]); err != nil {
+		return nil, fmt.Errorf("failed to read pack header: %w", err)
+	}
+	if string(header[:4]) != "PACK" {
+		return nil, errors.New("bad pack signature")
+	}
+	if version := binary.BigEndian.Uint32(header[4:8]); version != 2 && version != 3 {
+		return nil, fmt.Errorf("unsupported pack version %d", version)
+	}
+	count := binary.BigEndian.Uint32(header[8:12])
+	entries := make([]*packEntry, 0, count)
+	byOffset := make(map[int64]*packEntry, count)
+	for range count {
+		entry, err := readPackEntry(cr)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+		byOffset[entry.offset] = entry
+	}
+
+	// Resolve the deltas, repeating until no more can be resolved
+	// as a delta may be based on a later one.
+	objects := make(map[string]*gitObject, count)
+	for _, entry := range entries {
+		if entry.typ != objOfsDelta && entry.typ != objRefDelta {
+			entry.id = objectID(entry.typ, entry.data)
+			objects[entry.id] = &gitObject{typ: entry.typ, data: entry.data}
+		}
+	}
+	for {
+		progress, unresolved := false, 0
+		for _, entry := range entries {
+			if entry.id != "" {
+				continue
+			}
+			var base *gitObject
+			if entry.typ == objOfsDelta {
+				if baseEntry := byOffset[entry.baseOff]; baseEntry != nil && baseEntry.id != "" {
+					base = objects[baseEntry.id]
+				}
+			} else {
+				base = objects[entry.baseID]
+			}
+			if base == nil {
+				unresolved++
+				continue
+			}
+			progress = true
+			if base.data == nil || entry.data == nil {
+				// Can't resolve a delta of a discarded blob, but it
+				// must be a blob too so isn't needed
+				entry.id = "discarded"
+				continue
+			}
+			data, err := applyDelta(base.data, entry.data)
+			if err != nil {
+				return nil, err
+			}
+			entry.id = objectID(base.typ, data)
+			objects[entry.id] = &gitObject{typ: base.typ, data: data}
+		}
+		if unresolved == 0 {
+			break
+		}
+		if !progress {
+			return nil, fmt.Errorf("pack has %d deltas with missing bases", unresolved)
+		}
+	}
+	return objects, nil
+}
+
+// readPackEntry reads the next entry of the pack from cr
+func readPackEntry(cr *countingReader) (*packEntry, error) {
+	entry := &packEntry{offset: cr.n}
+	c, err := cr.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack entry: %w", err)
+	}
+	entry.typ = int(c>>4) & 7
+	size := uint64(c & 0x0f)
+	for shift := 4; c&0x80 != 0; shift += 7 {
+		c, err = cr.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pack entry: %w", err)
+		}
+		size |= uint64(c&0x7f) << shift
+	}
+	switch entry.typ {
+	case objCommit, objTree, objBlob, objTag:
+	case objOfsDelta:
+		c, err = cr.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read delta offset: %w", err)
+		}
+		offset := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			c, err = cr.ReadByte()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read delta offset: %w", err)
+			}
+			offset = ((offset + 1) << 7) | int64(c&0x7f)
+		}
+		entry.baseOff = entry.offset - offset
+	case objRefDelta:
+		var id [20]byte
+		if _, err = io.ReadFull(cr, id[:]); err != nil {
+			return nil, fmt.Errorf("failed to read delta base: %w", err)
+		}
+		entry.baseID = hex.EncodeToString(id[:])
+	default:
+		return nil, fmt.Errorf("bad pack entry type %d", entry.typ)
+	}
+	zr, err := zlib.NewReader(cr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack entry: %w", err)
+	}
+	if entry.typ == objBlob && size > maxKeepSize {
+		_, err = io.Copy(io.Discard, zr)
+	} else {
+		var buf bytes.Buffer
+		buf.Grow(int(min(size, maxKeepSize)))
+		_, err = io.Copy(&buf, zr)
+		entry.data = buf.Bytes()
+		if err == nil && uint64(len(entry.data)) != size {
+			err = errors.New("wrong size")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack entry: %w", err)
+	}
+	if err = zr.Close(); err != nil {
+		return nil, fmt.Errorf("failed to read pack entry: %w", err)
+	}
+	return entry, nil
+}
+
+// deltaSize reads a size from the start of a delta
+func deltaSize(delta []byte) (size uint64, rest []byte, err error) {
+	for shift := 0; ; shift += 7 {
+		if len(delta) == 0 || shift > 63 {
+			return 0, nil, errors.New("bad delta header")
+		}
+		c := delta[0]
+		delta = delta[1:]
+		size |= uint64(c&0x7f) << shift
+		if c&0x80 == 0 {
+			return size, delta, nil
+		}
+	}
+}
+
+// applyDelta applies the git delta to base
+func applyDelta(base, delta []byte) ([]byte, error) {
+	baseSize, delta, err := deltaSize(delta)
+	if err != nil {
+		return nil, err
+	}
+	if baseSize != uint64(len(base)) {
+		return nil, errors.New("delta base has the wrong size")
+	}
+	resultSize, delta, err := deltaSize(delta)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]byte, 0, resultSize)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		if op&0x80 == 0 {
+			// insert the next op bytes
+			n := int(op)
+			if n == 0 || n > len(delta) {
+				return nil, errors.New("bad delta insert")
+			}
+			result = append(result, delta[:n]...)
+			delta = delta[n:]
+			continue
+		}
+		// copy from the base
+		var offset, size uint64
+		for i := range 7 {
+			if op&(1<<i) == 0 {
+				continue
+			}
+			if len(delta) == 0 {
+				return nil, errors.New("bad delta copy")
+			}
+			if i < 4 {
+				offset |= uint64(delta[0]) << (8 * i)
+			} else {
+				size |= uint64(delta[0]) << (8 * (i - 4))
+			}
+			delta = delta[1:]
+		}
+		if size == 0 {
+			size = 0x10000
+		}
+		if offset+size > uint64(len(base)) {
+			return nil, errors.New("delta copy out of range")
+		}
+		result = append(result, base[offset:offset+size]...)
+	}
+	if uint64(len(result)) != resultSize {
+		return nil, errors.New("delta result has the wrong size")
+	}
+	return result, nil
+}
+
+// treeEntry is an entry in a git tree
+type treeEntry struct {
+	mode string
+	name string
+	id   string
+}
+
+// parseTree parses the contents of a git tree object
+func parseTree(data []byte) ([]treeEntry, error) {
+	var entries []treeEntry
+	for len(data) > 0 {
+		space := bytes.IndexByte(data, ' ')
+		if space < 0 {
+			return nil, errors.New("bad tree entry mode")
+		}
+		mode := string(data[:space])
+		data = data[space+1:]
+		nul := bytes.IndexByte(data, 0)
+		if nul < 0 || len(data) < nul+21 {
+			return nil, errors.New("bad tree entry name")
+		}
+		name := string(data[:nul])
+		id := hex.EncodeToString(data[nul+1 : nul+21])
+		data = data[nul+21:]
+		entries = append(entries, treeEntry{mode: mode, name: name, id: id})
+	}
+	return entries, nil
+}
+
+// parseCommit returns the tree ID and commit time of a git commit
+func parseCommit(data []byte) (tree string, when int64, err error) {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			// end of headers
+			break
+		}
+		if t, ok := bytes.CutPrefix(line, []byte("tree ")); ok {
+			tree = string(t)
+		} else if c, ok := bytes.CutPrefix(line, []byte("committer ")); ok {
+			// committer Name <email> 1234567890 +0000
+			fields := bytes.Fields(c)
+			if len(fields) >= 2 {
+				when, _ = strconv.ParseInt(string(fields[len(fields)-2]), 10, 64)
+			}
+		}
+	}
+	if len(tree) != 40 {
+		return "", 0, errors.New("commit has no tree")
+	}
+	return tree, when, nil
+}
@@ -46,9 +46,34 @@ type CreateAlbum struct {
 	Album *Album `json:"album"`
 }
 
+// Photo is the metadata specific to photos
+type Photo struct {
+	CameraMake      string  `json:"cameraMake,omitempty"`
+	CameraModel     string  `json:"cameraModel,omitempty"`
+	FocalLength     float64 `json:"focalLength,omitempty"`
+	ApertureFNumber float64 `json:"apertureFNumber,omitempty"`
+	IsoEquivalent   int     `json:"isoEquivalent,omitempty"`
+	ExposureTime    string  `json:"exposureTime,omitempty"`
+}
+
+// Video is the metadata specific to videos
+type Video struct {
+	CameraMake  string  `json:"cameraMake,omitempty"`
+	CameraModel string  `json:"cameraModel,omitempty"`
+	Fps         float64 `json:"fps,omitempty"`
+	Status      string  `json:"status,omitempty"` // PROCESSING, READY or FAILED
+}
+
+// ContributorInfo is who added a media item to a shared album
+type ContributorInfo struct {
+	ProfilePictureBaseURL string `json:"profilePictureBaseUrl"`
+	DisplayName           string `json:"displayName"`
+}
+
 // MediaItem is a photo or video
 type MediaItem struct {
 	ID            string `json:"id"`
+	Description   string `json:"description,omitempty"`
 	ProductURL    string `json:"productUrl"`
 	BaseURL       string `json:"baseUrl"`
 	MimeType      string `json:"mimeType"`
@@ -56,9 +81,11 @@ type MediaItem struct {
 		CreationTime time.Time `json:"creationTime"`
 		Width        string    `json:"width"`
 		Height       string    `json:"height"`
-		Photo        struct{}  `json:"photo"`
+		Photo        *Photo    `json:"photo,omitempty"`
+		Video        *Video    `json:"video,omitempty"`
 	} `json:"mediaMetadata"`
-	Filename string `json:"filename"`
+	ContributorInfo *ContributorInfo `json:"contributorInfo,omitempty"`
+	Filename        string           `json:"filename"`
 }
 
 // MediaItems is returned from mediaitems.list, mediaitems.search
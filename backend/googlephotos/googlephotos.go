@@ -81,6 +81,70 @@ var (
 	}
 )
 
+// Media quality as reported in the metadata
+const (
+	qualityOriginal     = "original"
+	qualityStorageSaver = "storage-saver"
+	qualityUnknown      = "unknown"
+)
+
+// Storage saver resizes photos and videos bigger than these
+//
+// See: https://support.google.com/photos/answer/6220791
+const (
+	storageSaverPixels      = 16_000_000 // photos are resized to 16 MP
+	storageSaverVideoHeight = 1080       // videos are resized to 1080p
+)
+
+var systemMetadataInfo = map[string]fs.MetadataHelp{
+	"mtime": {
+		Help:     "Time the photo or video was taken",
+		Type:     "RFC 3339",
+		Example:  "2006-01-02T15:04:05Z",
+		ReadOnly: true,
+	},
+	"width": {
+		Help:     "Width of the photo or video in pixels",
+		Type:     "int",
+		Example:  "4032",
+		ReadOnly: true,
+	},
+	"height": {
+		Help:     "Height of the photo or video in pixels",
+		Type:     "int",
+		Example:  "3024",
+		ReadOnly: true,
+	},
+	"description": {
+		Help:     "Description of the media item",
+		Type:     "string",
+		ReadOnly: true,
+	},
+	"camera-make": {
+		Help:     "Make of the camera",
+		Type:     "string",
+		Example:  "Google",
+		ReadOnly: true,
+	},
+	"camera-model": {
+		Help:     "Model of the camera",
+		Type:     "string",
+		Example:  "Pixel 8",
+		ReadOnly: true,
+	},
+	"contributor": {
+		Help:     "Name of the user who added the media item to a shared album",
+		Type:     "string",
+		ReadOnly: true,
+	},
+	"quality": {
+		Help:     "Whether the stored media is the original: original, storage-saver or unknown",
+		Type:     "string",
+		Example:  qualityOriginal,
+		ReadOnly: true,
+	},
+}
+
 // Register with Fs
 func init() {
 	fs.Register(&fs.RegInfo{
@@ -88,6 +152,11 @@ func init() {
 		Prefix:      "gphotos",
 		Description: "Google Photos",
 		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		MetadataInfo: &fs.MetadataInfo{
+			System: systemMetadataInfo,
+			Help:   `The metadata is read only and comes from the Google Photos API.`,
+		},
 		Config: func(ctx context.Context, name string, m configmap.Mapper, config fs.ConfigIn) (*fs.ConfigOut, error) {
 			// Parse config into Options struct
 			opt := new(Options)
@@ -241,6 +310,7 @@ type Object struct {
 	bytes    int64     // Bytes in the object
 	modTime  time.Time // Modified time of the object
 	mimeType string
+	info     *api.MediaItem // the media item, if read
 }
 
 // ------------------------------------------------------------
@@ -363,6 +433,7 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	}
 	f.features = (&fs.Features{
 		ReadMimeType: true,
+		ReadMetadata: true,
 	}).Fill(ctx, f)
 	f.srv.SetErrorHandler(errorHandler)
 
@@ -902,6 +973,7 @@ func (o *Object) setMetaData(info *api.MediaItem) {
 	o.bytes = -1 // FIXME
 	o.mimeType = info.MimeType
 	o.modTime = info.MediaMetadata.CreationTime
+	o.info = info
 }
 
 // readMetaData gets the metadata if it hasn't already been fetched
@@ -982,6 +1054,77 @@ func (o *Object) Storable() bool {
 	return true
 }
 
+// mediaQuality works out whether the bytes Google Photos has for item
+// are the original or have been compressed by storage saver
+//
+// The API doesn't say, so this uses the sizes storage saver resizes
+// to. Anything bigger than those must be an original and photos of
+// exactly 16 MP must have been resized. Anything else could be either.
+func mediaQuality(item *api.MediaItem) string {
+	width, _ := strconv.ParseInt(item.MediaMetadata.Width, 10, 64)
+	height, _ := strconv.ParseInt(item.MediaMetadata.Height, 10, 64)
+	if width <= 0 || height <= 0 {
+		return qualityUnknown
+	}
+	if item.MediaMetadata.Video != nil || strings.HasPrefix(item.MimeType, "video/") {
+		if min(width, height) > storageSaverVideoHeight {
+			return qualityOriginal
+		}
+		return qualityUnknown
+	}
+	// Allow for the rounding of the resized width and height
+	pixels := width * height
+	switch {
+	case pixels > storageSaverPixels*1001/1000:
+		return qualityOriginal
+	case pixels >= storageSaverPixels*999/1000:
+		return qualityStorageSaver
+	}
+	return qualityUnknown
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (metadata fs.Metadata, err error) {
+	err = o.readMetaData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if o.info == nil {
+		return nil, nil
+	}
+	info := o.info
+	metadata = make(fs.Metadata, 8)
+	metadata["mtime"] = o.modTime.Format(time.RFC3339)
+	if info.MediaMetadata.Width != "" {
+		metadata["width"] = info.MediaMetadata.Width
+	}
+	if info.MediaMetadata.Height != "" {
+		metadata["height"] = info.MediaMetadata.Height
+	}
+	if info.Description != "" {
+		metadata["description"] = info.Description
+	}
+	var cameraMake, cameraModel string
+	if photo := info.MediaMetadata.Photo; photo != nil {
+		cameraMake, cameraModel = photo.CameraMake, photo.CameraModel
+	} else if video := info.MediaMetadata.Video; video != nil {
+		cameraMake, cameraModel = video.CameraMake, video.CameraModel
+	}
+	if cameraMake != "" {
+		metadata["camera-make"] = cameraMake
+	}
+	if cameraModel != "" {
+		metadata["camera-model"] = cameraModel
+	}
+	if info.ContributorInfo != nil && info.ContributorInfo.DisplayName != "" {
+		metadata["contributor"] = info.ContributorInfo.DisplayName
+	}
+	metadata["quality"] = mediaQuality(info)
+	return metadata, nil
+}
+
 // downloadURL returns the URL for a full bytes download for the object
 func (o *Object) downloadURL() string {
 	url := o.url + "=d"
@@ -1228,12 +1371,79 @@ func (o *Object) ID() string {
 	return o.id
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "quality",
+	Short: "Show which media items are originals.",
+	Long: `This command shows whether the bytes Google Photos has for each
+media item in a directory are the original or have been compressed by
+storage saver. See the [original quality](#original-quality) section
+for how this is worked out.
+
+    rclone backend quality gphotos:album/Holidays
+
+This prints a JSON object of the file names with "original",
+"storage-saver" or "unknown". Use the "only" option to show just the
+items with that quality, for example to find the compressed items:
+
+    rclone backend quality gphotos:media/by-year/2015 -o only=storage-saver
+`,
+	Opts: map[string]string{
+		"only": "Only show the items with this quality",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "quality":
+		return f.qualityCommand(ctx, opt)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// qualityCommand returns the quality of the media items in the root
+func (f *Fs) qualityCommand(ctx context.Context, opt map[string]string) (out map[string]string, err error) {
+	only := opt["only"]
+	switch only {
+	case "", qualityOriginal, qualityStorageSaver, qualityUnknown:
+	default:
+		return nil, fmt.Errorf("unknown quality %q", only)
+	}
+	entries, err := f.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	out = make(map[string]string, len(entries))
+	for _, entry := range entries {
+		o, ok := entry.(*Object)
+		if !ok || o.info == nil {
+			continue
+		}
+		quality := mediaQuality(o.info)
+		if only == "" || quality == only {
+			out[o.remote] = quality
+		}
+	}
+	return out, nil
+}
+
 // Check the interfaces are satisfied
 var (
 	_ fs.Fs           = &Fs{}
 	_ fs.UserInfoer   = &Fs{}
 	_ fs.Disconnecter = &Fs{}
+	_ fs.Commander    = &Fs{}
 	_ fs.Object       = &Object{}
 	_ fs.MimeTyper    = &Object{}
 	_ fs.IDer         = &Object{}
+	_ fs.Metadataer   = &Object{}
 )
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rclone/rclone/backend/googlephotos/api"
 	_ "github.com/rclone/rclone/backend/local"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/hash"
@@ -295,3 +296,33 @@ func TestFindID(t *testing.T) {
 	ID = ID[1:]
 	assert.Equal(t, "", findID("potato {"+ID+"}.txt"))
 }
+
+func TestMediaQuality(t *testing.T) {
+	for _, test := range []struct {
+		mimeType string
+		width    string
+		height   string
+		video    bool
+		want     string
+	}{
+		{"image/jpeg", "", "", false, qualityUnknown},
+		{"image/jpeg", "potato", "3024", false, qualityUnknown},
+		{"image/jpeg", "4032", "3024", false, qualityUnknown},
+		{"image/jpeg", "4619", "3464", false, qualityStorageSaver},
+		{"image/jpeg", "3464", "4619", false, qualityStorageSaver},
+		{"image/jpeg", "4608", "3456", false, qualityUnknown},
+		{"image/jpeg", "8160", "6120", false, qualityOriginal},
+		{"video/mp4", "1920", "1080", true, qualityUnknown},
+		{"video/mp4", "1080", "1920", false, qualityUnknown},
+		{"video/mp4", "3840", "2160", true, qualityOriginal},
+		{"image/gif", "3840", "2160", true, qualityOriginal},
+	} {
+		item := &api.MediaItem{MimeType: test.mimeType}
+		item.MediaMetadata.Width = test.width
+		item.MediaMetadata.Height = test.height
+		if test.video {
+			item.MediaMetadata.Video = &api.Video{}
+		}
+		assert.Equal(t, test.want, mediaQuality(item), fmt.Sprintf("%+v", test))
+	}
+}
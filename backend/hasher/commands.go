@@ -35,7 +35,7 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 		if len(arg) != 2 {
 			return nil, errors.New("please provide checksum type and path to sum file")
 		}
-		return nil, f.dbImport(ctx, arg[0], arg[1], sticky)
+		return nil, f.dbImport(ctx, arg[0], arg[1], sticky, opt)
 	default:
 		return nil, fs.ErrorCommandNotFound
 	}
@@ -60,18 +60,44 @@ Usage Example:
 	Name:  "import",
 	Short: "Import a SUM file",
 	Long: `Amend hash cache from a SUM file and bind checksums to files by size/time.
+
+Besides the md5sum/sha1sum format, SFV files (*.sfv, crc32 only) and
+Internet Archive item manifests (*_files.xml, md5, sha1 or crc32) are
+recognized by their file name. Use the "format" option to override.
+
+Paths in the SUM file are relative to the hasher remote. Use the
+"strip" option to remove a leading directory from them and the
+"prefix" option to add one.
+
 Usage Example:
     rclone backend import hasher:subdir md5 /path/to/sum.md5
+    rclone backend import hasher:item sha1 /path/to/item_files.xml
+    rclone backend import hasher:subdir crc32 /path/to/album.sfv -o prefix=album
 `,
+	Opts: importOpts,
 }, {
 	Name:  "stickyimport",
 	Short: "Perform fast import of a SUM file",
 	Long: `Fill hash cache from a SUM file without verifying file fingerprints.
+
+The imported checksums are trusted for any file with a matching path.
+This seeds the cache from previously collected fixity data without
+reading or even listing the files. Accepts the same formats and
+options as "import".
+
 Usage Example:
     rclone backend stickyimport hasher:subdir md5 remote:path/to/sum.md5
+    rclone backend stickyimport hasher:item md5 remote:item/item_files.xml -o strip=item
 `,
+	Opts: importOpts,
 }}
 
+var importOpts = map[string]string{
+	"format": "Format of the SUM file: sum, sfv or iaxml (default: guess from the file name)",
+	"strip":  "Leading directory to remove from paths in the SUM file",
+	"prefix": "Directory to prepend to paths in the SUM file",
+}
+
 func (f *Fs) dbDump(ctx context.Context, full bool, root string) error {
 	if root == "" {
 		remoteFs, err := cache.Get(ctx, f.opt.Remote)
@@ -102,7 +128,7 @@ func (f *Fs) dbDump(ctx context.Context, full bool, root string) error {
 	return err
 }
 
-func (f *Fs) dbImport(ctx context.Context, hashName, sumRemote string, sticky bool) error {
+func (f *Fs) dbImport(ctx context.Context, hashName, sumRemote string, sticky bool, opt map[string]string) error {
 	var hashType hash.Type
 	if err := hashType.Set(hashName); err != nil {
 		return err
@@ -136,10 +162,15 @@ func (f *Fs) dbImport(ctx context.Context, hashName, sumRemote string, sticky bo
 	if err != nil {
 		return fmt.Errorf("cannot open sum file: %w", err)
 	}
-	hashes, err := operations.ParseSumFile(ctx, sumObj)
+	format := opt["format"]
+	if format == "" {
+		format = guessSumFormat(sumPath)
+	}
+	hashes, err := parseSumFile(ctx, sumObj, format, hashType)
 	if err != nil {
 		return fmt.Errorf("failed to parse sum file: %w", err)
 	}
+	hashes = mapSumPaths(hashes, opt["strip"], opt["prefix"])
 
 	if sticky {
 		rootPath := f.Fs.Root()
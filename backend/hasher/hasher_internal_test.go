@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/fstests"
@@ -78,3 +80,76 @@ func (f *Fs) InternalTest(t *testing.T) {
 }
 
 var _ fstests.InternalTester = (*Fs)(nil)
+
+func TestGuessSumFormat(t *testing.T) {
+	assert.Equal(t, sumFormatSum, guessSumFormat("dir/MD5SUMS"))
+	assert.Equal(t, sumFormatSFV, guessSumFormat("dir/album.SFV"))
+	assert.Equal(t, sumFormatIAXML, guessSumFormat("remote:item/item_files.xml"))
+}
+
+func TestParseSFV(t *testing.T) {
+	const sfv = `; Generated by some tool
+; comment
+
+track 01.flac  0A1B2C3D
+sub\track02.flac DEADBEEF
+not a valid line
+`
+	hashes, err := parseSFV(strings.NewReader(sfv))
+	require.NoError(t, err)
+	assert.Equal(t, operations.HashSums{
+		"track 01.flac":    "0a1b2c3d",
+		"sub/track02.flac": "deadbeef",
+	}, hashes)
+}
+
+func TestParseIAFilesXML(t *testing.T) {
+	const filesXML = `<?xml version="1.0" encoding="UTF-8"?>
+<files>
+  <file name="item_meta.xml" source="metadata">
+    <md5>9E107D9D372BB6826BD81D3542A419D6</md5>
+    <sha1>2fd4e1c67a2d28fced849ee1bb76e7391b93eb12</sha1>
+  </file>
+  <file name="dir/song.mp3" source="original">
+    <md5>e4d909c290d0fb1ca068ffaddf22cbd0</md5>
+    <crc32>414fa339</crc32>
+  </file>
+  <file name="item_files.xml" source="original"/>
+</files>
+`
+	hashes, err := parseIAFilesXML(strings.NewReader(filesXML), hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, operations.HashSums{
+		"item_meta.xml": "9e107d9d372bb6826bd81d3542a419d6",
+		"dir/song.mp3":  "e4d909c290d0fb1ca068ffaddf22cbd0",
+	}, hashes)
+
+	hashes, err = parseIAFilesXML(strings.NewReader(filesXML), hash.CRC32)
+	require.NoError(t, err)
+	assert.Equal(t, operations.HashSums{"dir/song.mp3": "414fa339"}, hashes)
+
+	_, err = parseIAFilesXML(strings.NewReader(filesXML), hash.SHA256)
+	assert.Error(t, err)
+}
+
+func TestMapSumPaths(t *testing.T) {
+	hashes := operations.HashSums{
+		"item/a.txt":     "1",
+		"item/sub/b.txt": "2",
+		"other/c.txt":    "3",
+	}
+	assert.Equal(t, hashes, mapSumPaths(hashes, "", ""))
+	assert.Equal(t, operations.HashSums{
+		"a.txt":     "1",
+		"sub/b.txt": "2",
+	}, mapSumPaths(hashes, "item/", ""))
+	assert.Equal(t, operations.HashSums{
+		"new/a.txt":     "1",
+		"new/sub/b.txt": "2",
+	}, mapSumPaths(hashes, "item", "/new"))
+	assert.Equal(t, operations.HashSums{
+		"x/item/a.txt":     "1",
+		"x/item/sub/b.txt": "2",
+		"x/other/c.txt":    "3",
+	}, mapSumPaths(hashes, "", "x"))
+}
@@ -0,0 +1,148 @@
+package hasher
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// Formats of SUM files accepted by import
+const (
+	sumFormatSum   = "sum"   // output of md5sum, sha1sum, rclone hashsum
+	sumFormatSFV   = "sfv"   // simple file verification, crc32 only
+	sumFormatIAXML = "iaxml" // Internet Archive _files.xml item manifest
+)
+
+// guessSumFormat works out the format of a SUM file from its name
+func guessSumFormat(name string) string {
+	name = strings.ToLower(path.Base(name))
+	switch {
+	case strings.HasSuffix(name, ".sfv"):
+		return sumFormatSFV
+	case strings.HasSuffix(name, "_files.xml"):
+		return sumFormatIAXML
+	default:
+		return sumFormatSum
+	}
+}
+
+// parseSumFile reads checksums of hashType from sumObj in the given format
+func parseSumFile(ctx context.Context, sumObj fs.Object, format string, hashType hash.Type) (operations.HashSums, error) {
+	switch format {
+	case sumFormatSum:
+		return operations.ParseSumFile(ctx, sumObj)
+	case sumFormatSFV, sumFormatIAXML:
+	default:
+		return nil, fmt.Errorf("unknown sum file format %q", format)
+	}
+	rd, err := operations.Open(ctx, sumObj)
+	if err != nil {
+		return nil, err
+	}
+	var hashes operations.HashSums
+	if format == sumFormatSFV {
+		if hashType != hash.CRC32 {
+			return nil, fmt.Errorf("sfv files only contain %v checksums", hash.CRC32)
+		}
+		hashes, err = parseSFV(rd)
+	} else {
+		hashes, err = parseIAFilesXML(rd, hashType)
+	}
+	if closeErr := rd.Close(); err == nil {
+		err = closeErr
+	}
+	return hashes, err
+}
+
+var sfvLine = regexp.MustCompile(`^(.+?)\s+([0-9A-Fa-f]{8})$`)
+
+// parseSFV parses a simple file verification file
+//
+// Each line holds a file name followed by its crc32. Lines starting
+// with ";" are comments.
+func parseSFV(in io.Reader) (operations.HashSums, error) {
+	hashes := operations.HashSums{}
+	scanner := bufio.NewScanner(in)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		fields := sfvLine.FindStringSubmatch(line)
+		if fields == nil {
+			fs.Logf(nil, "improperly formatted sfv line %d", lineNo)
+			continue
+		}
+		hashes[strings.ReplaceAll(fields[1], `\`, "/")] = strings.ToLower(fields[2])
+	}
+	return hashes, scanner.Err()
+}
+
+// iaFiles is the layout of an Internet Archive _files.xml manifest
+type iaFiles struct {
+	Files []struct {
+		Name  string `xml:"name,attr"`
+		MD5   string `xml:"md5"`
+		SHA1  string `xml:"sha1"`
+		CRC32 string `xml:"crc32"`
+	} `xml:"file"`
+}
+
+// parseIAFilesXML parses an Internet Archive _files.xml item manifest
+func parseIAFilesXML(in io.Reader, hashType hash.Type) (operations.HashSums, error) {
+	var files iaFiles
+	if err := xml.NewDecoder(in).Decode(&files); err != nil {
+		return nil, err
+	}
+	hashes := operations.HashSums{}
+	for _, file := range files.Files {
+		var sum string
+		switch hashType {
+		case hash.MD5:
+			sum = file.MD5
+		case hash.SHA1:
+			sum = file.SHA1
+		case hash.CRC32:
+			sum = file.CRC32
+		default:
+			return nil, fmt.Errorf("_files.xml doesn't contain %v checksums", hashType)
+		}
+		if file.Name != "" && sum != "" {
+			hashes[file.Name] = strings.ToLower(sum)
+		}
+	}
+	return hashes, nil
+}
+
+// mapSumPaths removes the strip directory from the front of the paths
+// in hashes and adds prefix in its place
+//
+// Paths outside strip are dropped.
+func mapSumPaths(hashes operations.HashSums, strip, prefix string) operations.HashSums {
+	strip = strings.Trim(strip, "/")
+	prefix = strings.Trim(prefix, "/")
+	if strip == "" && prefix == "" {
+		return hashes
+	}
+	mapped := make(operations.HashSums, len(hashes))
+	for remote, sum := range hashes {
+		if strip != "" {
+			if !strings.HasPrefix(remote, strip+"/") {
+				fs.Debugf(remote, "Not under %q - skipping", strip)
+				continue
+			}
+			remote = remote[len(strip)+1:]
+		}
+		mapped[path.Join(prefix, remote)] = sum
+	}
+	return mapped
+}
@@ -4,8 +4,10 @@ package hdfs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/user"
 	"path"
@@ -16,9 +18,11 @@ import (
 	krb "github.com/jcmturner/gokrb5/v8/client"
 	"github.com/jcmturner/gokrb5/v8/config"
 	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fshttp"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/lib/pacer"
 )
@@ -40,9 +44,50 @@ const (
 	decayConstant = 2 // bigger for slower decay, exponential
 )
 
-// copy-paste from https://github.com/colinmarc/hdfs/blob/master/cmd/hdfs/kerberos.go
-func getKerberosClient() (*krb.Client, error) {
-	configPath := os.Getenv("KRB5_CONFIG")
+const defaultNamenodePort = "8020"
+
+// namenodeAddresses returns the name nodes with the default port
+// added to any which don't have one.
+func namenodeAddresses(namenodes []string) []string {
+	addresses := make([]string, 0, len(namenodes))
+	for _, namenode := range namenodes {
+		namenode = strings.TrimSpace(namenode)
+		if namenode == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(namenode); err != nil {
+			namenode = net.JoinHostPort(strings.Trim(namenode, "[]"), defaultNamenodePort)
+		}
+		addresses = append(addresses, namenode)
+	}
+	return addresses
+}
+
+// splitPrincipal splits a principal of the form user@REALM into user
+// and realm, using defaultRealm if no realm is given.
+func splitPrincipal(principal, defaultRealm string) (user, realm string, err error) {
+	user, realm, found := strings.Cut(principal, "@")
+	if !found || realm == "" {
+		realm = defaultRealm
+	}
+	if user == "" {
+		return "", "", fmt.Errorf("no user in kerberos principal %q", principal)
+	}
+	if realm == "" {
+		return "", "", fmt.Errorf("no realm in kerberos principal %q and no default_realm in kerberos config", principal)
+	}
+	return user, realm, nil
+}
+
+// getKerberosClient returns a logged in kerberos client, either using
+// the keytab if configured or the credentials cache otherwise.
+//
+// based on https://github.com/colinmarc/hdfs/blob/master/cmd/hdfs/kerberos.go
+func getKerberosClient(opt *Options) (*krb.Client, error) {
+	configPath := opt.KerberosConfig
+	if configPath == "" {
+		configPath = os.Getenv("KRB5_CONFIG")
+	}
 	if configPath == "" {
 		configPath = "/etc/krb5.conf"
 	}
@@ -52,6 +97,10 @@ func getKerberosClient() (*krb.Client, error) {
 		return nil, err
 	}
 
+	if opt.KerberosKeytab != "" {
+		return getKerberosKeytabClient(opt, cfg)
+	}
+
 	// Determine the ccache location from the environment, falling back to the
 	// default location.
 	ccachePath := os.Getenv("KRB5CCNAME")
@@ -83,6 +132,30 @@ func getKerberosClient() (*krb.Client, error) {
 	return client, nil
 }
 
+// getKerberosKeytabClient logs in to kerberos with the configured keytab
+func getKerberosKeytabClient(opt *Options, cfg *config.Config) (*krb.Client, error) {
+	if opt.KerberosPrincipal == "" {
+		return nil, errors.New("kerberos_principal must be set when using kerberos_keytab")
+	}
+	username, realm, err := splitPrincipal(opt.KerberosPrincipal, cfg.LibDefaults.DefaultRealm)
+	if err != nil {
+		return nil, err
+	}
+
+	kt, err := keytab.Load(opt.KerberosKeytab)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load keytab: %w", err)
+	}
+
+	client := krb.NewWithKeytab(username, realm, kt, cfg, krb.DisablePAFXFAST(true))
+	err = client.Login()
+	if err != nil {
+		return nil, fmt.Errorf("kerberos login as %s@%s failed: %w", username, realm, err)
+	}
+
+	return client, nil
+}
+
 // NewFs constructs an Fs from the path
 func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
 	opt := new(Options)
@@ -91,13 +164,27 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		return nil, err
 	}
 
+	addresses := namenodeAddresses(opt.Namenode)
+	if len(addresses) == 0 {
+		return nil, errors.New("no namenodes configured")
+	}
+
+	// Use rclone's dialer so that a dead name node times out quickly
+	// and the client fails over to the next one.
+	dialer := fshttp.NewDialer(ctx)
 	options := hdfs.ClientOptions{
-		Addresses:           opt.Namenode,
+		Addresses:           addresses,
 		UseDatanodeHostname: false,
+		NamenodeDialFunc:    dialer.DialContext,
+		DatanodeDialFunc:    dialer.DialContext,
+	}
+
+	if opt.KerberosKeytab != "" && opt.ServicePrincipalName == "" {
+		return nil, errors.New("service_principal_name must be set when using kerberos_keytab")
 	}
 
 	if opt.ServicePrincipalName != "" {
-		options.KerberosClient, err = getKerberosClient()
+		options.KerberosClient, err = getKerberosClient(opt)
 		if err != nil {
 			return nil, fmt.Errorf("problem with kerberos authentication: %w", err)
 		}
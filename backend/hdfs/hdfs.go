@@ -18,8 +18,15 @@ func init() {
 		Description: "Hadoop distributed file system",
 		NewFs:       NewFs,
 		Options: []fs.Option{{
-			Name:      "namenode",
-			Help:      "Hadoop name nodes and ports.\n\nE.g. \"namenode-1:8020,namenode-2:8020,...\" to connect to host namenodes at port 8020.",
+			Name: "namenode",
+			Help: `Hadoop name nodes and ports.
+
+E.g. "namenode-1:8020,namenode-2:8020,..." to connect to host namenodes at port 8020.
+
+If more than one name node is given, as is usual for a high
+availability cluster, rclone will fail over to the next name node if
+the current one is unreachable or is in standby. If the port is left
+off then the default name node port 8020 is used.`,
 			Required:  true,
 			Sensitive: true,
 			Default:   fs.CommaSepList{},
@@ -40,6 +47,33 @@ Enables KERBEROS authentication. Specifies the Service Principal Name
 for namenode running as service 'hdfs' with FQDN 'namenode.hadoop.docker'.`,
 			Advanced:  true,
 			Sensitive: true,
+		}, {
+			Name: "kerberos_keytab",
+			Help: `Path to a Kerberos keytab file.
+
+If set then rclone will log in to Kerberos using the keytab rather
+than reading tickets from the credentials cache, so there is no need
+to run kinit beforehand. The ticket is renewed automatically as
+needed.
+
+Requires --hdfs-service-principal-name and --hdfs-kerberos-principal
+to be set.`,
+			Advanced: true,
+		}, {
+			Name: "kerberos_principal",
+			Help: `Kerberos principal to log in as when using a keytab.
+
+E.g. "rclone@EXAMPLE.COM". If the realm is left off then the default
+realm from the Kerberos config is used.`,
+			Advanced:  true,
+			Sensitive: true,
+		}, {
+			Name: "kerberos_config",
+			Help: `Path to the Kerberos config file.
+
+If not set then the KRB5_CONFIG environment variable is used, falling
+back to "/etc/krb5.conf".`,
+			Advanced: true,
 		}, {
 			Name: "data_transfer_protection",
 			Help: `Kerberos data transfer protection: authentication|integrity|privacy.
@@ -68,6 +102,9 @@ type Options struct {
 	Namenode               fs.CommaSepList      `config:"namenode"`
 	Username               string               `config:"username"`
 	ServicePrincipalName   string               `config:"service_principal_name"`
+	KerberosKeytab         string               `config:"kerberos_keytab"`
+	KerberosPrincipal      string               `config:"kerberos_principal"`
+	KerberosConfig         string               `config:"kerberos_config"`
 	DataTransferProtection string               `config:"data_transfer_protection"`
 	Enc                    encoder.MultiEncoder `config:"encoding"`
 }
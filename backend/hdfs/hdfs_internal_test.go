@@ -0,0 +1,46 @@
+//go:build !plan9
+
+package hdfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamenodeAddresses(t *testing.T) {
+	assert.Equal(t, []string{}, namenodeAddresses(nil))
+	assert.Equal(t, []string{
+		"nn1:8020",
+		"nn2:9000",
+		"[::1]:8020",
+		"[::1]:9000",
+	}, namenodeAddresses([]string{"nn1", " nn2:9000 ", "", "[::1]", "[::1]:9000"}))
+}
+
+func TestSplitPrincipal(t *testing.T) {
+	for _, test := range []struct {
+		principal    string
+		defaultRealm string
+		wantUser     string
+		wantRealm    string
+		wantErr      bool
+	}{
+		{"rclone@EXAMPLE.COM", "", "rclone", "EXAMPLE.COM", false},
+		{"rclone@EXAMPLE.COM", "OTHER.COM", "rclone", "EXAMPLE.COM", false},
+		{"rclone", "EXAMPLE.COM", "rclone", "EXAMPLE.COM", false},
+		{"rclone@", "EXAMPLE.COM", "rclone", "EXAMPLE.COM", false},
+		{"rclone", "", "", "", true},
+		{"@EXAMPLE.COM", "", "", "", true},
+	} {
+		user, realm, err := splitPrincipal(test.principal, test.defaultRealm)
+		if test.wantErr {
+			require.Error(t, err, test.principal)
+			continue
+		}
+		require.NoError(t, err, test.principal)
+		assert.Equal(t, test.wantUser, user, test.principal)
+		assert.Equal(t, test.wantRealm, realm, test.principal)
+	}
+}
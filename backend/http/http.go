@@ -23,7 +23,6 @@ import (
 	"github.com/rclone/rclone/fs/fshttp"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/lib/rest"
-	"golang.org/x/net/html"
 )
 
 var (
@@ -93,6 +92,53 @@ sizes of any files, and some files that don't exist may be in the listing.`,
 			Name:    "no_escape",
 			Help:    "Do not escape URL metacharacters in path names.",
 			Default: false,
+		}, {
+			Name: "index_format",
+			Help: `Format of the directory indexes.
+
+Normally rclone reads HTML directory indexes, or JSON ones if the
+server returns them with Content-Type: application/json. This can be
+used to force the format if the server doesn't set the Content-Type
+correctly, or to read directories from an S3 bucket listing.
+
+HTML and JSON indexes split into several pages will be followed
+using rel="next" links, either in the page or in a Link: header.`,
+			Default:  "",
+			Advanced: true,
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "Detect the format from the Content-Type",
+			}, {
+				Value: "html",
+				Help:  "HTML index with links to the files and directories",
+			}, {
+				Value: "json",
+				Help:  "JSON index as produced by nginx autoindex_format json or caddy browse",
+			}, {
+				Value: "s3",
+				Help:  "S3 bucket listing - the url should point at the bucket, eg https://bucket.s3.amazonaws.com/",
+			}},
+		}, {
+			Name: "manifest",
+			Help: `URL of a manifest file listing all the files.
+
+If set then rclone will read the list of files and directories from
+this file instead of reading directory indexes. This is useful for
+sites which don't have directory indexes but do publish a list of
+their files.
+
+The URL may be relative to the url of the remote.
+
+Each line of the file has the path of a file relative to the url,
+optionally followed by a tab and the size of the file in bytes, and
+optionally then another tab and the modification time of the file in
+RFC3339 format. Paths ending in / are directories. Blank lines and
+lines starting with # are ignored.
+
+If the size isn't given rclone will use a HEAD request to find it
+unless --http-no-head is set.`,
+			Default:  "",
+			Advanced: true,
 		}},
 	}
 	fs.Register(fsi)
@@ -100,11 +146,13 @@ sizes of any files, and some files that don't exist may be in the listing.`,
 
 // Options defines the configuration for this backend
 type Options struct {
-	Endpoint string          `config:"url"`
-	NoSlash  bool            `config:"no_slash"`
-	NoHead   bool            `config:"no_head"`
-	Headers  fs.CommaSepList `config:"headers"`
-	NoEscape bool            `config:"no_escape"`
+	Endpoint    string          `config:"url"`
+	NoSlash     bool            `config:"no_slash"`
+	NoHead      bool            `config:"no_head"`
+	Headers     fs.CommaSepList `config:"headers"`
+	NoEscape    bool            `config:"no_escape"`
+	IndexFormat string          `config:"index_format"`
+	Manifest    string          `config:"manifest"`
 }
 
 // Fs stores the interface to the remote HTTP files
@@ -117,6 +165,8 @@ type Fs struct {
 	endpoint    *url.URL
 	endpointURL string // endpoint as a string
 	httpClient  *http.Client
+	manifestMu  sync.Mutex // protects manifest
+	manifest    manifest   // parsed manifest if using one
 }
 
 // Object is a remote object that has been stat'd (so it exists, but is not necessarily open for reading)
@@ -398,35 +448,8 @@ func parseName(base *url.URL, name string) (string, error) {
 // Parse turns HTML for a directory into names
 // base should be the base URL to resolve any relative names from
 func parse(base *url.URL, in io.Reader) (names []string, err error) {
-	doc, err := html.Parse(in)
-	if err != nil {
-		return nil, err
-	}
-	var (
-		walk func(*html.Node)
-		seen = make(map[string]struct{})
-	)
-	walk = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "a" {
-			for _, a := range n.Attr {
-				if a.Key == "href" {
-					name, err := parseName(base, a.Val)
-					if err == nil {
-						if _, found := seen[name]; !found {
-							names = append(names, name)
-							seen[name] = struct{}{}
-						}
-					}
-					break
-				}
-			}
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			walk(c)
-		}
-	}
-	walk(doc)
-	return names, nil
+	names, _, err = parseHTMLIndex(base, in)
+	return names, err
 }
 
 // Adds the configured headers to the request if any
@@ -443,45 +466,233 @@ func (f *Fs) addHeaders(req *http.Request) {
 	addHeaders(req, &f.opt)
 }
 
-// Read the directory passed in
-func (f *Fs) readDir(ctx context.Context, dir string) (names []string, err error) {
-	URL := f.url(dir)
-	u, err := url.Parse(URL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to readDir: %w", err)
-	}
-	if !strings.HasSuffix(URL, "/") {
-		return nil, fmt.Errorf("internal error: readDir URL %q didn't end in /", URL)
-	}
-	// Do the request
+// maxIndexPages is the maximum number of pages of a paginated
+// directory index which will be read
+const maxIndexPages = 10000
+
+// getIndex fetches the index page at URL
+//
+// The caller must close the body of the response if no error is
+// returned.
+func (f *Fs) getIndex(ctx context.Context, URL string) (res *http.Response, err error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", URL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("readDir failed: %w", err)
 	}
 	f.addHeaders(req)
-	res, err := f.httpClient.Do(req)
-	if err == nil {
-		defer fs.CheckClose(res.Body, &err)
-		if res.StatusCode == http.StatusNotFound {
-			return nil, fs.ErrorDirNotFound
-		}
+	if f.opt.IndexFormat == "json" {
+		req.Header.Set("Accept", "application/json")
+	}
+	res, err = f.httpClient.Do(req)
+	if err == nil && res.StatusCode == http.StatusNotFound {
+		_ = res.Body.Close()
+		return nil, fs.ErrorDirNotFound
 	}
 	err = statusError(res, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to readDir: %w", err)
 	}
+	return res, nil
+}
+
+// Read the directory passed in
+func (f *Fs) readDir(ctx context.Context, dir string) (entries []indexEntry, err error) {
+	if f.opt.Manifest != "" {
+		return f.readDirManifest(ctx, dir)
+	}
+	if f.opt.IndexFormat == "s3" {
+		return f.readDirS3(ctx, dir)
+	}
+	URL := f.url(dir)
+	if !strings.HasSuffix(URL, "/") {
+		return nil, fmt.Errorf("internal error: readDir URL %q didn't end in /", URL)
+	}
+	seen := map[string]struct{}{}
+	for pageURL := URL; pageURL != ""; {
+		if _, found := seen[pageURL]; found {
+			fs.Debugf(f, "Stopping reading index at %q as seen it already", pageURL)
+			break
+		}
+		seen[pageURL] = struct{}{}
+		if len(seen) > maxIndexPages {
+			return nil, fmt.Errorf("readDir: more than %d pages in index", maxIndexPages)
+		}
+		pageEntries, next, err := f.readIndexPage(ctx, pageURL)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, pageEntries...)
+		pageURL = ""
+		if next != "" {
+			u, err := url.Parse(URL)
+			if err != nil {
+				return nil, fmt.Errorf("failed to readDir: %w", err)
+			}
+			pageURL, err = sameSite(u, next)
+			if err != nil {
+				fs.Debugf(f, "Ignoring next page link %q: %v", next, err)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// readIndexPage reads a single page of a directory index returning
+// the link to the next page if any.
+func (f *Fs) readIndexPage(ctx context.Context, pageURL string) (entries []indexEntry, next string, err error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to readDir: %w", err)
+	}
+	res, err := f.getIndex(ctx, pageURL)
+	if err != nil {
+		return nil, "", err
+	}
+	defer fs.CheckClose(res.Body, &err)
+	next = parseLinkNext(res.Header)
 
 	contentType := strings.SplitN(res.Header.Get("Content-Type"), ";", 2)[0]
-	switch contentType {
-	case "text/html":
-		names, err = parse(u, res.Body)
+	format := f.opt.IndexFormat
+	if format == "" {
+		switch contentType {
+		case "text/html":
+			format = "html"
+		case "application/json":
+			format = "json"
+		default:
+			return nil, "", fmt.Errorf("can't parse content type %q", contentType)
+		}
+	}
+	switch format {
+	case "html":
+		var (
+			names    []string
+			htmlNext string
+		)
+		// Resolve names relative to the directory not the page
+		u.RawQuery = ""
+		names, htmlNext, err = parseHTMLIndex(u, res.Body)
+		if err != nil {
+			return nil, "", fmt.Errorf("readDir: %w", err)
+		}
+		entries = namesToEntries(names)
+		if next == "" {
+			next = htmlNext
+		}
+	case "json":
+		entries, err = parseJSONIndex(res.Body)
 		if err != nil {
-			return nil, fmt.Errorf("readDir: %w", err)
+			return nil, "", fmt.Errorf("readDir: %w", err)
 		}
 	default:
-		return nil, fmt.Errorf("can't parse content type %q", contentType)
+		return nil, "", fmt.Errorf("unknown index_format %q", format)
+	}
+	return entries, next, nil
+}
+
+// readDirS3 reads a directory from an S3 bucket listing
+//
+// The url should point at the bucket using virtual host style
+// addressing, eg https://bucket.s3.amazonaws.com/
+func (f *Fs) readDirS3(ctx context.Context, dir string) (entries []indexEntry, err error) {
+	u, err := url.Parse(f.url(dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to readDir: %w", err)
+	}
+	prefix := strings.TrimPrefix(u.Path, "/")
+	listURL := url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host, Path: "/"}
+	token := ""
+	for page := 0; ; page++ {
+		if page >= maxIndexPages {
+			return nil, fmt.Errorf("readDir: more than %d pages in listing", maxIndexPages)
+		}
+		query := url.Values{
+			"list-type": {"2"},
+			"delimiter": {"/"},
+			"prefix":    {prefix},
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		listURL.RawQuery = query.Encode()
+		var pageEntries []indexEntry
+		pageEntries, token, err = f.readS3Page(ctx, listURL.String(), prefix)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, pageEntries...)
+		if token == "" {
+			break
+		}
+	}
+	// S3 has no directories so an empty listing means not found
+	if len(entries) == 0 && dir != "" {
+		return nil, fs.ErrorDirNotFound
+	}
+	return entries, nil
+}
+
+// readS3Page reads a single page of an S3 bucket listing
+func (f *Fs) readS3Page(ctx context.Context, listURL, prefix string) (entries []indexEntry, token string, err error) {
+	res, err := f.getIndex(ctx, listURL)
+	if err != nil {
+		return nil, "", err
 	}
-	return names, nil
+	defer fs.CheckClose(res.Body, &err)
+	entries, token, err = parseS3Index(res.Body, prefix)
+	if err != nil {
+		return nil, "", fmt.Errorf("readDir: %w", err)
+	}
+	return entries, token, nil
+}
+
+// loadManifest reads the manifest if it hasn't been read already
+func (f *Fs) loadManifest(ctx context.Context) (m manifest, err error) {
+	f.manifestMu.Lock()
+	defer f.manifestMu.Unlock()
+	if f.manifest != nil {
+		return f.manifest, nil
+	}
+	base, err := url.Parse(f.opt.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u, err := rest.URLJoin(base, f.opt.Manifest)
+	if err != nil {
+		return nil, fmt.Errorf("bad manifest URL: %w", err)
+	}
+	fs.Debugf(f, "Reading manifest from %q", u.String())
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	f.addHeaders(req)
+	res, err := f.httpClient.Do(req)
+	err = statusError(res, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	defer fs.CheckClose(res.Body, &err)
+	m, err = parseManifest(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.manifest = m
+	return m, nil
+}
+
+// readDirManifest reads a directory from the manifest
+func (f *Fs) readDirManifest(ctx context.Context, dir string) (entries []indexEntry, err error) {
+	m, err := f.loadManifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.Trim(path.Join(f.root, dir), "/")
+	entries, found := m[key]
+	if !found && key != "" {
+		return nil, fs.ErrorDirNotFound
+	}
+	return entries, nil
 }
 
 // List the objects and directories in dir into entries.  The
@@ -497,7 +708,7 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 	if !strings.HasSuffix(dir, "/") && dir != "" {
 		dir += "/"
 	}
-	names, err := f.readDir(ctx, dir)
+	index, err := f.readDir(ctx, dir)
 	if err != nil {
 		return nil, fmt.Errorf("error listing %q: %w", dir, err)
 	}
@@ -533,13 +744,20 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 			}
 		}()
 	}
-	for _, name := range names {
-		isDir := name[len(name)-1] == '/'
-		name = strings.TrimRight(name, "/")
-		remote := path.Join(dir, name)
-		if isDir {
+	for _, entry := range index {
+		remote := path.Join(dir, strings.TrimRight(entry.name, "/"))
+		switch {
+		case entry.isDir():
 			add(fs.NewDir(remote, time.Time{}))
-		} else {
+		case entry.known:
+			add(&Object{
+				fs:          f,
+				remote:      remote,
+				size:        entry.size,
+				modTime:     entry.modTime,
+				contentType: fs.MimeTypeFromName(remote),
+			})
+		default:
 			in <- remote
 		}
 	}
@@ -760,6 +978,9 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 			return nil, fmt.Errorf("updating session: %w", err)
 		}
 		f.opt = newOpt
+		f.manifestMu.Lock()
+		f.manifest = nil
+		f.manifestMu.Unlock()
 		keys := []string{}
 		for k := range opt {
 			keys = append(keys, k)
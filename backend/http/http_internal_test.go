@@ -535,3 +535,217 @@ func TestFsNoSlashRoots(t *testing.T) {
 		}
 	}
 }
+
+func TestParseLinkNext(t *testing.T) {
+	for _, test := range []struct {
+		link string
+		want string
+	}{
+		{"", ""},
+		{`<https://example.com/dir/?page=2>; rel="next"`, "https://example.com/dir/?page=2"},
+		{`<?page=1>; rel="prev", <?page=3>; rel=next`, "?page=3"},
+		{`<?page=3>; rel="next last"`, "?page=3"},
+		{`<?page=1>; rel="first"`, ""},
+	} {
+		header := http.Header{}
+		if test.link != "" {
+			header.Set("Link", test.link)
+		}
+		assert.Equal(t, test.want, parseLinkNext(header), test.link)
+	}
+}
+
+func TestParseHTMLIndexNext(t *testing.T) {
+	u, err := url.Parse("http://example.com/dir/")
+	require.NoError(t, err)
+	names, next, err := parseHTMLIndex(u, strings.NewReader(`<html><head><link rel="next" href="?page=2"></head>
+<body><a href="file">file</a> <a href="sub/">sub/</a> <a href="?page=0" rel="prev">prev</a></body></html>`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"file", "sub/"}, names)
+	assert.Equal(t, "?page=2", next)
+}
+
+func TestParseJSONIndex(t *testing.T) {
+	// nginx autoindex_format json
+	entries, err := parseJSONIndex(strings.NewReader(`[
+{ "name":"dir", "type":"directory", "mtime":"Mon, 02 Jan 2023 15:04:05 GMT" },
+{ "name":"file.txt", "type":"file", "mtime":"Mon, 02 Jan 2023 15:04:05 GMT", "size":42 },
+{ "name":"link", "type":"other", "mtime":"Mon, 02 Jan 2023 15:04:05 GMT" },
+{ "name":"..", "type":"directory" }
+]`))
+	require.NoError(t, err)
+	modTime := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	assert.Equal(t, []indexEntry{
+		{name: "dir/"},
+		{name: "file.txt", size: 42, modTime: modTime, known: true},
+		{name: "link"},
+	}, entries)
+
+	// caddy browse
+	entries, err = parseJSONIndex(strings.NewReader(`[
+{"name":"sub/","size":4096,"url":"./sub/","mod_time":"2023-01-02T15:04:05Z","mode":2147484141,"is_dir":true,"is_symlink":false},
+{"name":"file.txt","size":42,"url":"./file.txt","mod_time":"2023-01-02T15:04:05Z","mode":420,"is_dir":false,"is_symlink":false}
+]`))
+	require.NoError(t, err)
+	assert.Equal(t, []indexEntry{
+		{name: "sub/"},
+		{name: "file.txt", size: 42, modTime: modTime, known: true},
+	}, entries)
+
+	_, err = parseJSONIndex(strings.NewReader(`{"not":"a list"}`))
+	assert.Error(t, err)
+}
+
+func TestParseS3Index(t *testing.T) {
+	entries, next, err := parseS3Index(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Name>bucket</Name>
+  <Prefix>dir/</Prefix>
+  <IsTruncated>true</IsTruncated>
+  <NextContinuationToken>token</NextContinuationToken>
+  <Contents><Key>dir/</Key><LastModified>2023-01-02T15:04:05.000Z</LastModified><Size>0</Size></Contents>
+  <Contents><Key>dir/file.txt</Key><LastModified>2023-01-02T15:04:05.000Z</LastModified><Size>42</Size></Contents>
+  <CommonPrefixes><Prefix>dir/sub/</Prefix></CommonPrefixes>
+</ListBucketResult>`), "dir/")
+	require.NoError(t, err)
+	assert.Equal(t, "token", next)
+	assert.Equal(t, []indexEntry{
+		{name: "file.txt", size: 42, modTime: time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC), known: true},
+		{name: "sub/"},
+	}, entries)
+}
+
+func TestParseManifest(t *testing.T) {
+	m, err := parseManifest(strings.NewReader(`# manifest
+a/b/file1	10	2023-01-02T15:04:05Z
+/a/file2	20
+file3
+
+empty/
+`))
+	require.NoError(t, err)
+	modTime := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	assert.Equal(t, manifest{
+		"a/b": {{name: "file1", size: 10, modTime: modTime, known: true}},
+		"a": {
+			{name: "b/"},
+			{name: "file2", size: 20, modTime: timeUnset, known: true},
+		},
+		"": {
+			{name: "a/"},
+			{name: "file3", modTime: timeUnset},
+			{name: "empty/"},
+		},
+	}, m)
+
+	_, err = parseManifest(strings.NewReader("file\tpotato\n"))
+	assert.ErrorContains(t, err, "line 1")
+}
+
+// listNames lists dir returning the names of the entries with a
+// trailing / on directories and the sizes of the objects
+func listNames(t *testing.T, f fs.Fs, dir string) (names []string, sizes map[string]int64) {
+	entries, err := f.List(context.Background(), dir)
+	require.NoError(t, err)
+	sizes = map[string]int64{}
+	for _, entry := range entries {
+		if _, ok := entry.(fs.Directory); ok {
+			names = append(names, entry.Remote()+"/")
+		} else {
+			names = append(names, entry.Remote())
+			sizes[entry.Remote()] = entry.Size()
+		}
+	}
+	sort.Strings(names)
+	return names, sizes
+}
+
+func TestListIndexFormats(t *testing.T) {
+	heads := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			heads++
+			w.Header().Set("Content-Length", "7")
+			return
+		}
+		switch r.URL.Path {
+		case "/paged/":
+			switch r.URL.Query().Get("page") {
+			case "":
+				_, _ = fmt.Fprint(w, `<html><body><a href="a">a</a><a href="sub/">sub/</a><a rel="next" href="?page=2">next</a></body></html>`)
+			case "2":
+				w.Header().Set("Link", `</paged/?page=3>; rel="next"`)
+				_, _ = fmt.Fprint(w, `<html><body><a href="b">b</a><a rel="next" href="?page=2">loop</a></body></html>`)
+			case "3":
+				_, _ = fmt.Fprint(w, `<html><body><a href="c">c</a><a rel="next" href="http://other.example.com/">elsewhere</a></body></html>`)
+			}
+		case "/json/":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(w, `[{"name":"d","type":"directory"},{"name":"f","type":"file","size":3,"mtime":"Mon, 02 Jan 2023 15:04:05 GMT"}]`)
+		case "/manifest.txt":
+			_, _ = fmt.Fprint(w, "x/y\t5\nx/z\nw\n")
+		case "/":
+			assert.Equal(t, "/", r.URL.Query().Get("delimiter"))
+			assert.Equal(t, "s3dir/", r.URL.Query().Get("prefix"))
+			if r.URL.Query().Get("continuation-token") == "" {
+				_, _ = fmt.Fprint(w, `<ListBucketResult><IsTruncated>true</IsTruncated><NextContinuationToken>t</NextContinuationToken><Contents><Key>s3dir/one</Key><Size>1</Size><LastModified>2023-01-02T15:04:05Z</LastModified></Contents></ListBucketResult>`)
+			} else {
+				_, _ = fmt.Fprint(w, `<ListBucketResult><CommonPrefixes><Prefix>s3dir/sub/</Prefix></CommonPrefixes></ListBucketResult>`)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	configfile.Install()
+
+	newFs := func(root string, m configmap.Simple) fs.Fs {
+		m["type"] = "http"
+		m["url"] = ts.URL
+		f, err := NewFs(context.Background(), remoteName, root, m)
+		require.NoError(t, err)
+		return f
+	}
+
+	t.Run("Paginated", func(t *testing.T) {
+		f := newFs("paged/", configmap.Simple{})
+		names, _ := listNames(t, f, "")
+		assert.Equal(t, []string{"a", "b", "c", "sub/"}, names)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		heads = 0
+		f := newFs("json/", configmap.Simple{})
+		names, sizes := listNames(t, f, "")
+		assert.Equal(t, []string{"d/", "f"}, names)
+		assert.Equal(t, int64(3), sizes["f"])
+		assert.Equal(t, 0, heads)
+	})
+
+	t.Run("Manifest", func(t *testing.T) {
+		heads = 0
+		f := newFs("", configmap.Simple{"manifest": "manifest.txt"})
+		names, _ := listNames(t, f, "")
+		assert.Equal(t, []string{"w", "x/"}, names)
+		names, sizes := listNames(t, f, "x")
+		assert.Equal(t, []string{"x/y", "x/z"}, names)
+		assert.Equal(t, int64(5), sizes["x/y"])
+		assert.Equal(t, int64(7), sizes["x/z"])
+		assert.Equal(t, 2, heads) // for w and x/z
+		_, err := f.List(context.Background(), "nope")
+		assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+
+		// Check the root is taken into account
+		f = newFs("x/", configmap.Simple{"manifest": "/manifest.txt"})
+		names, _ = listNames(t, f, "")
+		assert.Equal(t, []string{"y", "z"}, names)
+	})
+
+	t.Run("S3", func(t *testing.T) {
+		f := newFs("s3dir/", configmap.Simple{"index_format": "s3"})
+		names, sizes := listNames(t, f, "")
+		assert.Equal(t, []string{"one", "sub/"}, names)
+		assert.Equal(t, int64(1), sizes["one"])
+	})
+}
@@ -0,0 +1,315 @@
+package http
+
+// Parsers for the different kinds of directory index
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/lib/rest"
+	"golang.org/x/net/html"
+)
+
+// indexEntry is a directory entry read from an index
+type indexEntry struct {
+	name    string    // leaf name, with a trailing / for directories
+	size    int64     // size if known
+	modTime time.Time // modification time if known
+	known   bool      // set if size and modTime are known so no HEAD is needed
+}
+
+// isDir returns true if the entry is a directory
+func (e indexEntry) isDir() bool {
+	return strings.HasSuffix(e.name, "/")
+}
+
+// namesToEntries converts names with unknown info into entries
+func namesToEntries(names []string) []indexEntry {
+	entries := make([]indexEntry, len(names))
+	for i, name := range names {
+		entries[i] = indexEntry{name: name}
+	}
+	return entries
+}
+
+// validName returns true if name is usable as a leaf name
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.Contains(name, "/")
+}
+
+// sameSite checks next is on the same host and scheme as base and
+// returns it as an absolute URL
+func sameSite(base *url.URL, next string) (string, error) {
+	u, err := rest.URLJoin(base, next)
+	if err != nil {
+		return "", errURLJoinFailed
+	}
+	if base.Host != u.Host {
+		return "", errHostMismatch
+	}
+	if base.Scheme != u.Scheme {
+		return "", errSchemeMismatch
+	}
+	return u.String(), nil
+}
+
+// hasRel returns true if the rel attribute value contains want
+func hasRel(rel, want string) bool {
+	for _, token := range strings.Fields(rel) {
+		if strings.EqualFold(token, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLinkNext returns the URL of the rel="next" link in the Link
+// headers or "" if not found
+//
+// See https://datatracker.ietf.org/doc/html/rfc8288
+func parseLinkNext(header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(strings.TrimSpace(key), "rel") && hasRel(strings.Trim(value, `"`), "next") {
+					return target[1 : len(target)-1]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// parseHTMLIndex turns HTML for a directory into names
+//
+// base should be the base URL to resolve any relative names from.
+//
+// It also returns the href of any <a> or <link> with rel="next" so
+// paginated indexes can be read.
+func parseHTMLIndex(base *url.URL, in io.Reader) (names []string, next string, err error) {
+	doc, err := html.Parse(in)
+	if err != nil {
+		return nil, "", err
+	}
+	var (
+		walk func(*html.Node)
+		seen = make(map[string]struct{})
+	)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.Data == "a" || n.Data == "link") {
+			var href, rel string
+			for _, a := range n.Attr {
+				switch a.Key {
+				case "href":
+					href = a.Val
+				case "rel":
+					rel = a.Val
+				}
+			}
+			if hasRel(rel, "next") {
+				if next == "" {
+					next = href
+				}
+			} else if n.Data == "a" && href != "" {
+				name, err := parseName(base, href)
+				if err == nil {
+					if _, found := seen[name]; !found {
+						names = append(names, name)
+						seen[name] = struct{}{}
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return names, next, nil
+}
+
+// jsonIndexItem is an entry in a JSON directory index as produced by
+// nginx with "autoindex_format json" or caddy with "browse" and an
+// "Accept: application/json" header
+type jsonIndexItem struct {
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`     // nginx: file, directory or other
+	IsDir   bool      `json:"is_dir"`   // caddy
+	Size    *int64    `json:"size"`     // size of files
+	MTime   string    `json:"mtime"`    // nginx: RFC1123 time
+	ModTime time.Time `json:"mod_time"` // caddy: RFC3339 time
+}
+
+// parseJSONIndex turns a JSON directory index into entries
+func parseJSONIndex(in io.Reader) (entries []indexEntry, err error) {
+	var items []jsonIndexItem
+	err = json.NewDecoder(in).Decode(&items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON index: %w", err)
+	}
+	for _, item := range items {
+		isDir := item.IsDir || item.Type == "directory" || strings.HasSuffix(item.Name, "/")
+		name := strings.TrimSuffix(item.Name, "/")
+		if !validName(name) {
+			continue
+		}
+		if isDir {
+			entries = append(entries, indexEntry{name: name + "/"})
+			continue
+		}
+		entry := indexEntry{name: name}
+		modTime := item.ModTime
+		if item.MTime != "" {
+			if t, err := http.ParseTime(item.MTime); err == nil {
+				modTime = t
+			}
+		}
+		if item.Size != nil && !modTime.IsZero() {
+			entry.size = *item.Size
+			entry.modTime = modTime
+			entry.known = true
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// s3ListResult is the XML returned by an S3 ListObjectsV2 call
+type s3ListResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	IsTruncated           bool     `xml:"IsTruncated"`
+	NextContinuationToken string   `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+// parseS3Index turns an S3 bucket listing of prefix into entries
+//
+// It returns the continuation token if the listing is truncated.
+func parseS3Index(in io.Reader, prefix string) (entries []indexEntry, next string, err error) {
+	var result s3ListResult
+	err = xml.NewDecoder(in).Decode(&result)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse S3 listing: %w", err)
+	}
+	for _, object := range result.Contents {
+		name := strings.TrimPrefix(object.Key, prefix)
+		if !strings.HasPrefix(object.Key, prefix) || !validName(name) {
+			continue
+		}
+		entries = append(entries, indexEntry{
+			name:    name,
+			size:    object.Size,
+			modTime: object.LastModified,
+			known:   true,
+		})
+	}
+	for _, commonPrefix := range result.CommonPrefixes {
+		name := strings.TrimSuffix(strings.TrimPrefix(commonPrefix.Prefix, prefix), "/")
+		if !strings.HasPrefix(commonPrefix.Prefix, prefix) || !validName(name) {
+			continue
+		}
+		entries = append(entries, indexEntry{name: name + "/"})
+	}
+	if result.IsTruncated {
+		if result.NextContinuationToken == "" {
+			return nil, "", errors.New("S3 listing truncated but no continuation token")
+		}
+		next = result.NextContinuationToken
+	}
+	return entries, next, nil
+}
+
+// manifest is a parsed manifest file
+//
+// It maps directory paths ("" for the root) to their entries
+type manifest map[string][]indexEntry
+
+// add entry to dir, adding any missing parent directories
+func (m manifest) add(seen map[string]struct{}, dir string, entry indexEntry) {
+	key := path.Join(dir, entry.name)
+	if _, found := seen[key]; found {
+		return
+	}
+	seen[key] = struct{}{}
+	m[dir] = append(m[dir], entry)
+	if dir != "" {
+		parent, leaf := path.Split(dir)
+		m.add(seen, strings.TrimSuffix(parent, "/"), indexEntry{name: leaf + "/"})
+	}
+}
+
+// parseManifest reads a manifest file
+//
+// Each line has the path of a file relative to the root of the
+// remote, optionally followed by a tab and its size in bytes, and
+// optionally another tab and its modification time in RFC3339
+// format. Paths ending in / are directories. Blank lines and lines
+// starting with # are ignored.
+func parseManifest(in io.Reader) (m manifest, err error) {
+	m = manifest{}
+	seen := map[string]struct{}{}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		isDir := strings.HasSuffix(fields[0], "/")
+		p := path.Clean("/" + fields[0])[1:]
+		if p == "" {
+			continue
+		}
+		dir, leaf := path.Split(p)
+		dir = strings.TrimSuffix(dir, "/")
+		if isDir {
+			m.add(seen, dir, indexEntry{name: leaf + "/"})
+			continue
+		}
+		entry := indexEntry{name: leaf, modTime: timeUnset}
+		if len(fields) > 1 {
+			entry.size, err = strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("manifest line %d: bad size: %w", lineNumber, err)
+			}
+			entry.known = true
+		}
+		if len(fields) > 2 {
+			entry.modTime, err = time.Parse(time.RFC3339, fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("manifest line %d: bad modification time: %w", lineNumber, err)
+			}
+		}
+		m.add(seen, dir, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return m, nil
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
 	"path"
@@ -21,6 +22,7 @@ import (
 
 	"github.com/ncw/swift/v2"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/config"
 	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/rclone/rclone/fs/config/configstruct"
@@ -29,9 +31,11 @@ import (
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/lib/bucket"
 	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/multipart"
 	"github.com/rclone/rclone/lib/pacer"
 	"github.com/rclone/rclone/lib/random"
 	"github.com/rclone/rclone/lib/rest"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/singleflight"
 )
 
@@ -46,6 +50,7 @@ func init() {
 		Name:        "internetarchive",
 		Description: "Internet Archive",
 		NewFs:       NewFs,
+		CommandHelp: commandHelp,
 
 		MetadataInfo: &fs.MetadataInfo{
 			System: map[string]fs.MetadataHelp{
@@ -137,6 +142,14 @@ If there are multiple values for a key, only the first one is returned.
 This is a limitation of Rclone, that supports one value per one key.
 
 Owner is able to add custom keys. Metadata feature grabs all the keys including them.
+
+The root directory of an item has the item metadata, such as title,
+description, collection and subject. Keys with multiple values, like
+collection and subject, have them separated by ";" there. Setting the
+metadata of the root directory writes it to the item with the metadata
+write API, apart from the identifier, uploader, addeddate and
+publicdate keys which are read only. If the item doesn't exist yet it
+is made with the metadata.
 `,
 		},
 
@@ -172,6 +185,64 @@ Format is key=value and the 'x-archive-meta-' prefix is automatically added.`,
 The derive process produces a number of secondary files from an upload to make an upload more usable on the web.
 Setting this to false is useful for uploading files that are already in a format that IA can display or reduce burden on IA's infrastructure.`,
 			Default: true,
+		}, {
+			Name: "item_collection",
+			Help: `Collection to put new items in.
+
+This is used when rclone makes an item, either when making the
+directory of the item or uploading the first file to it. It doesn't
+change the collection of existing items.
+
+Leave blank to let Internet Archive choose the collection.`,
+			Default:  "",
+			Advanced: true,
+		}, {
+			Name: "item_mediatype",
+			Help: `Mediatype of new items.
+
+This is used when rclone makes an item, like item_collection. It is
+one of texts, etree, audio, movies, software, image, data, web or
+collection.
+
+Leave blank to let Internet Archive choose the mediatype.`,
+			Default:  "",
+			Advanced: true,
+		}, {
+			Name: "item_noindex",
+			Help: `Make new items with noindex set so they aren't shown in search results.
+
+This is used when rclone makes an item, like item_collection.`,
+			Default:  false,
+			Advanced: true,
+		}, {
+			Name: "show_derivatives",
+			Help: `Show derivative files in a read only .derivatives directory.
+
+IA makes derivative files, such as thumbnails, OCR text and EPUBs,
+from the files uploaded to an item. Normally these are shown next to
+the files they were made from. If this is set they are shown in a
+.derivatives directory in the root of each item instead, with the same
+paths, where they can be listed and copied but not changed or deleted.
+A sync to an item will fail trying to delete them unless the directory
+is excluded with --exclude "/.derivatives/**".`,
+			Default:  false,
+			Advanced: true,
+		}, {
+			Name: "list_collection",
+			Help: `Collection to list the items of in the root.
+
+IA has too many items to list them all so listing the root of the
+remote normally returns nothing. If this is set, the items in this
+collection are listed there instead, as directories.
+
+The items are found with the scrape API in pages of up to 10,000, so
+recursive listings, like "rclone lsf -R remote:", read the files of
+the items while the next page is being found, reading the files of
+several items at once.
+
+Using a root of collection/<name>, like remote:collection/<name>/,
+lists the items of the collection <name> in it instead.`,
+			Advanced: true,
 		}, {
 			Name: "disable_checksum",
 			Help: `Don't ask the server to test against MD5 checksum calculated by rclone.
@@ -181,6 +252,45 @@ This is great for data integrity checking but can cause long delays for
 large files to start uploading.`,
 			Default:  true,
 			Advanced: true,
+		}, {
+			Name: "upload_cutoff",
+			Help: `Cutoff for switching to multipart upload.
+
+Files at least this size are uploaded in chunks of chunk_size with an
+S3 multipart upload, so an upload which fails part way through doesn't
+have to start again from the beginning. The minimum is 0 and the
+maximum is 5 GiB.`,
+			Default:  defaultUploadCutoff,
+			Advanced: true,
+		}, {
+			Name: "chunk_size",
+			Help: `Chunk size to use for uploading.
+
+Files bigger than upload_cutoff are uploaded in chunks of this size.
+It is increased if needed so the file fits in 10,000 chunks.
+
+upload_concurrency chunks of this size are buffered in memory per
+transfer.`,
+			Default:  defaultChunkSize,
+			Advanced: true,
+		}, {
+			Name: "upload_concurrency",
+			Help: `Concurrency for multipart uploads.
+
+This is the number of chunks of the same file that are uploaded
+concurrently.`,
+			Default:  4,
+			Advanced: true,
+		}, {
+			Name: "leave_parts_on_error",
+			Help: `If true avoid aborting a multipart upload which fails, leaving its parts on IAS3.
+
+When rclone uploads a file with an unfinished multipart upload of the
+same source, for example from an rclone which was stopped, it resumes
+it, only uploading the chunks which aren't there already. Set this so that
+uploads which fail can be resumed too.`,
+			Default:  false,
+			Advanced: true,
 		}, {
 			Name: "wait_archive",
 			Help: `Timeout for waiting the server's processing tasks (specifically archive and book_op) to finish.
@@ -188,6 +298,94 @@ Only enable if you need to be guaranteed to be reflected after write operations.
 0 to disable waiting. No errors to be thrown in case of timeout.`,
 			Default:  fs.Duration(0),
 			Advanced: true,
+		}, {
+			Name: "wait_task",
+			Help: `Timeout for waiting for the catalog tasks of the item to finish after an upload.
+
+If set, after each upload rclone waits until there are no queued or
+running tasks on the item, such as the task set with task_cmd and the
+archive and derive tasks queued by the upload, so the upload only
+succeeds once the item has been fully processed.
+
+An error is returned if the tasks haven't finished within the timeout
+or one of them fails. This needs access_key_id and secret_access_key
+to be set. 0 to disable waiting.`,
+			Default:  fs.Duration(0),
+			Advanced: true,
+		}, {
+			Name: "verify_after_upload",
+			Help: `Check the checksums IA has for uploaded files.
+
+If set, once an upload has finished, and the wait for wait_task is
+over, the item metadata is read again and the MD5, SHA1 and CRC32 IA
+has for the file are checked against those of the data uploaded. This
+finds files which have been damaged, for example by deriving.
+
+This needs wait_archive to be set so that IA has processed the upload
+before it is checked.`,
+			Default:  false,
+			Advanced: true,
+		}, {
+			Name: "max_tasks_queued",
+			Help: `Pause uploads to an item while it has this many catalog tasks queued.
+
+IA processes the uploads to an item with catalog tasks which queue up
+when lots of files are uploaded, slowing down the item and making IAS3
+return "SlowDown" errors. If this is set, before each upload rclone
+checks the number of queued tasks on the item and waits until it is
+below this. This needs access_key_id and secret_access_key to be set.
+
+0 to disable.`,
+			Default:  0,
+			Advanced: true,
+		}, {
+			Name: "task_cmd",
+			Help: `Catalog task to queue for the item before each upload.
+
+By default a no-op fixer.php task is queued so that uploads to the
+same item aren't combined and delayed by the archive. Set this to
+another task, for example derive.php or bup.php, to queue that
+instead, or to an empty string to not queue any task.
+
+Tasks can only be queued if access_key_id and secret_access_key are set.`,
+			Default:  "fixer.php",
+			Advanced: true,
+		}, {
+			Name: "task_args",
+			Help: `Arguments for the catalog task queued before each upload.
+
+This is a JSON object which is sent as the args of the task set
+with task_cmd, for example '{"noop":"1"}' for a no-op fixer task or
+'{"remove_derivatives":"1"}' for derive.php.`,
+			Default:  `{"noop":"1"}`,
+			Advanced: true,
+		}, {
+			Name: "download_torrent",
+			Help: `Download large files with BitTorrent.
+
+If set, files of at least torrent_cutoff are downloaded from the
+peers of the archive.org torrent of their item, and from its webseeds
+if that fails, rather than with a single HTTP download which
+archive.org may throttle. This needs the torrent backend.
+
+Only public items have torrents and the torrent of an item can be out
+of date, so files which aren't in it or whose size or MD5 don't match
+are downloaded over HTTP as usual, as are files of items without a
+torrent.`,
+			Default:  false,
+			Advanced: true,
+		}, {
+			Name:     "torrent_cutoff",
+			Help:     `Files smaller than this are downloaded over HTTP when download_torrent is set.`,
+			Default:  fs.SizeSuffix(100 * fs.Mebi),
+			Advanced: true,
+		}, {
+			Name: "torrent_peers",
+			Help: `Number of BitTorrent peers to download from at once with download_torrent.
+
+Set to 0 to only download from the webseeds of the torrent.`,
+			Default:  4,
+			Advanced: true,
 		}, {
 			Name:     config.ConfigEncoding,
 			Help:     config.ConfigEncodingHelp,
@@ -204,6 +402,22 @@ Only enable if you need to be guaranteed to be reflected after write operations.
 		}})
 }
 
+// derivativesDir is the directory in the root of each item which
+// derivative files are shown in if show_derivatives is set
+const derivativesDir = ".derivatives"
+
+// errDerivativesReadOnly is returned when trying to change files in
+// derivativesDir
+var errDerivativesReadOnly = fmt.Errorf("files in %s are read only: %w", derivativesDir, fs.ErrorPermissionDenied)
+
+// collectionDir is the first directory of roots which are
+// collections, like collection/<name>
+const collectionDir = "collection"
+
+// number of items to ask the scrape API for at once, which is the
+// most it allows
+const scrapePageSize = 10000
+
 // maximum size of an item. this is constant across all items
 // NOTE: 1TiB is the hard max but is not recommended, 320GiB is a compromise
 const iaItemMaxSize int64 = 343597383680
@@ -216,29 +430,63 @@ var roMetadataKey = map[string]any{
 	"viruscheck": nil, "summation": nil,
 }
 
+// item metadata keys that are not writeable
+var roItemMetadataKey = map[string]any{
+	"identifier": nil, "uploader": nil, "addeddate": nil, "publicdate": nil,
+}
+
+// item metadata keys which can have multiple values, written as
+// a list if the value has several separated by ";"
+var listItemMetadataKey = map[string]any{
+	"collection": nil, "subject": nil,
+}
+
 // Options defines the configuration for this backend
 type Options struct {
-	AccessKeyID     string               `config:"access_key_id"`
-	SecretAccessKey string               `config:"secret_access_key"`
-	Endpoint        string               `config:"endpoint"`
-	FrontEndpoint   string               `config:"front_endpoint"`
-	DisableChecksum bool                 `config:"disable_checksum"`
-	ItemMetadata    []string             `config:"item_metadata"`
-	ItemDerive      bool                 `config:"item_derive"`
-	WaitArchive     fs.Duration          `config:"wait_archive"`
-	Enc             encoder.MultiEncoder `config:"encoding"`
+	AccessKeyID       string               `config:"access_key_id"`
+	SecretAccessKey   string               `config:"secret_access_key"`
+	Endpoint          string               `config:"endpoint"`
+	FrontEndpoint     string               `config:"front_endpoint"`
+	DisableChecksum   bool                 `config:"disable_checksum"`
+	ItemMetadata      []string             `config:"item_metadata"`
+	ItemDerive        bool                 `config:"item_derive"`
+	ItemCollection    string               `config:"item_collection"`
+	ItemMediatype     string               `config:"item_mediatype"`
+	ItemNoindex       bool                 `config:"item_noindex"`
+	ShowDerivatives   bool                 `config:"show_derivatives"`
+	ListCollection    string               `config:"list_collection"`
+	UploadCutoff      fs.SizeSuffix        `config:"upload_cutoff"`
+	ChunkSize         fs.SizeSuffix        `config:"chunk_size"`
+	UploadConcurrency int                  `config:"upload_concurrency"`
+	LeavePartsOnError bool                 `config:"leave_parts_on_error"`
+	WaitArchive       fs.Duration          `config:"wait_archive"`
+	VerifyAfterUpload bool                 `config:"verify_after_upload"`
+	WaitTask          fs.Duration          `config:"wait_task"`
+	MaxTasksQueued    int                  `config:"max_tasks_queued"`
+	TaskCmd           string               `config:"task_cmd"`
+	TaskArgs          string               `config:"task_args"`
+	DownloadTorrent   bool                 `config:"download_torrent"`
+	TorrentCutoff     fs.SizeSuffix        `config:"torrent_cutoff"`
+	TorrentPeers      int                  `config:"torrent_peers"`
+	Enc               encoder.MultiEncoder `config:"encoding"`
 }
 
 // Fs represents an IAS3 remote
 type Fs struct {
-	name     string       // name of this remote
-	root     string       // the path we are working on if any
-	opt      Options      // parsed config options
-	features *fs.Features // optional features
-	srv      *rest.Client // the connection to IAS3
-	front    *rest.Client // the connection to frontend
-	pacer    *fs.Pacer    // pacer for API calls
+	name     string         // name of this remote
+	root     string         // the path we are working on if any
+	opt      Options        // parsed config options
+	features *fs.Features   // optional features
+	srv      *rest.Client   // the connection to IAS3
+	front    *rest.Client   // the connection to frontend
+	pacer    *fs.Pacer      // pacer for API calls
+	taskArgs map[string]any // parsed task_args
 	ctx      context.Context
+
+	torrentMu sync.Mutex       // protects torrents
+	torrents  map[string]fs.Fs // torrent remotes of items, nil if the item has none
+
+	collection string // collection the remote is in if its root is collection/<name>
 }
 
 // Object describes a file at IA
@@ -253,10 +501,19 @@ type Object struct {
 	rawData json.RawMessage
 }
 
+// Directory describes a directory at IA
+//
+// Directories are made up from the paths of the files, apart from the
+// root of an item which has the item metadata.
+type Directory struct {
+	*fs.Dir
+	fs *Fs // reference to Fs
+}
+
 // IAFile represents a subset of object in MetadataResponse.Files
 type IAFile struct {
-	Name string `json:"name"`
-	// Source     string `json:"source"`
+	Name        string          `json:"name"`
+	Source      string          `json:"source"`
 	Mtime       string          `json:"mtime"`
 	RcloneMtime json.RawMessage `json:"rclone-mtime"`
 	UpdateTrack json.RawMessage `json:"rclone-update-track"`
@@ -271,8 +528,9 @@ type IAFile struct {
 
 // MetadataResponse represents subset of the JSON object returned by (frontend)/metadata/
 type MetadataResponse struct {
-	Files    []IAFile `json:"files"`
-	ItemSize int64    `json:"item_size"`
+	Files    []IAFile                   `json:"files"`
+	ItemSize int64                      `json:"item_size"`
+	Metadata map[string]json.RawMessage `json:"metadata"`
 }
 
 // MetadataResponseRaw is the form of MetadataResponse to deal with metadata
@@ -297,6 +555,20 @@ type TaskResponse struct {
 	} `json:"value"`
 }
 
+// ScrapeResponse represents the response from the scrape API
+type ScrapeResponse struct {
+	Items  []ScrapeItem `json:"items"`
+	Count  int          `json:"count"`
+	Total  int          `json:"total"`
+	Cursor string       `json:"cursor"`
+}
+
+// ScrapeItem represents an item returned by the scrape API
+type ScrapeItem struct {
+	Identifier string `json:"identifier"`
+	PublicDate string `json:"publicdate"`
+}
+
 // Name of the remote (as passed into NewFs)
 func (f *Fs) Name() string {
 	return f.name
@@ -304,6 +576,9 @@ func (f *Fs) Name() string {
 
 // Root of the remote (as passed into NewFs)
 func (f *Fs) Root() string {
+	if f.collection != "" {
+		return path.Join(collectionDir, f.collection, f.root)
+	}
 	return f.root
 }
 
@@ -311,6 +586,9 @@ func (f *Fs) Root() string {
 func (f *Fs) String() string {
 	bucket, file := f.split("")
 	if bucket == "" {
+		if f.collection != "" {
+			return fmt.Sprintf("Internet Archive collection %s", f.collection)
+		}
 		return "Internet Archive root"
 	}
 	if file == "" {
@@ -345,6 +623,11 @@ var retryErrorCodes = []int{
 	503, // Service Unavailable/Slow Down - "Reduce your request rate"
 }
 
+const (
+	retryAfterHeader = "Retry-After"
+	slowDownSleep    = 30 * time.Second // time to wait after a SlowDown error without a Retry-After
+)
+
 // NewFs constructs an Fs from the path
 func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
 	// Parse config into Options struct
@@ -364,20 +647,59 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		return nil, err
 	}
 
+	var taskArgs map[string]any
+	if opt.TaskArgs != "" {
+		err = json.Unmarshal([]byte(opt.TaskArgs), &taskArgs)
+		if err != nil {
+			return nil, fmt.Errorf("task_args must be a JSON object: %w", err)
+		}
+	}
+
+	err = checkUploadCutoff(opt.UploadCutoff)
+	if err != nil {
+		return nil, fmt.Errorf("internetarchive: upload cutoff: %w", err)
+	}
+	err = checkUploadChunkSize(opt.ChunkSize)
+	if err != nil {
+		return nil, fmt.Errorf("internetarchive: chunk size: %w", err)
+	}
+
+	if opt.VerifyAfterUpload && opt.WaitArchive == 0 {
+		return nil, errors.New("internetarchive: verify_after_upload needs wait_archive to be set")
+	}
+
 	root = strings.Trim(root, "/")
 
+	// A root of collection/<name> lists the items in the collection
+	collection, root, isCollection := parseCollectionRoot(root)
+	if isCollection {
+		opt.ListCollection = collection
+		if opt.ItemCollection == "" {
+			opt.ItemCollection = collection
+		}
+	}
+
 	f := &Fs{
-		name: name,
-		opt:  *opt,
-		ctx:  ctx,
+		name:       name,
+		opt:        *opt,
+		taskArgs:   taskArgs,
+		ctx:        ctx,
+		torrents:   map[string]fs.Fs{},
+		collection: collection,
 	}
 	f.setRoot(root)
 	f.features = (&fs.Features{
-		BucketBased:   true,
-		ReadMetadata:  true,
-		WriteMetadata: true,
-		UserMetadata:  true,
+		BucketBased:      true,
+		ReadMetadata:     true,
+		WriteMetadata:    true,
+		UserMetadata:     true,
+		ReadDirMetadata:  true,
+		WriteDirMetadata: true,
+		UserDirMetadata:  true,
 	}).Fill(ctx, f)
+	// Multi-thread copies need the object to be readable as soon as
+	// the upload finishes which it isn't until IA has processed it
+	f.features.OpenChunkWriter = nil
 
 	f.srv = rest.NewClient(fshttp.NewClient(ctx))
 	f.srv.SetRoot(ep.String())
@@ -402,6 +724,17 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	return f, nil
 }
 
+// parseCollectionRoot returns the collection and the root in it if
+// root is collection/<name>/...
+func parseCollectionRoot(root string) (collection, rest string, ok bool) {
+	rest, ok = strings.CutPrefix(root, collectionDir+"/")
+	if !ok || rest == "" {
+		return "", root, false
+	}
+	collection, rest, _ = strings.Cut(rest, "/")
+	return collection, rest, true
+}
+
 // setRoot changes the root of the Fs
 func (f *Fs) setRoot(root string) {
 	f.root = strings.Trim(root, "/")
@@ -455,6 +788,9 @@ func (o *Object) SetModTime(ctx context.Context, t time.Time) (err error) {
 	if reqDir == "" {
 		return fs.ErrorCantSetModTime
 	}
+	if err = o.fs.checkWritable(reqDir); err != nil {
+		return err
+	}
 
 	// https://archive.org/services/docs/api/md-write.html
 	// the following code might be useful for modifying metadata of an uploaded file
@@ -468,12 +804,25 @@ func (o *Object) SetModTime(ctx context.Context, t time.Time) (err error) {
 			"path":  "/rclone-mtime",
 			"value": t.Format(time.RFC3339Nano),
 		}}
+	err = o.fs.writeMetadata(ctx, bucket, fmt.Sprintf("files/%s", reqDir), patch)
+	if err != nil {
+		return err
+	}
+	o.modTime = t
+	return nil
+}
+
+// writeMetadata applies the JSON patch to the target of the metadata
+// of item, either "metadata" for the item metadata or "files/<path>"
+// for a file in it.
+func (f *Fs) writeMetadata(ctx context.Context, item, target string, patch any) error {
+	// https://archive.org/services/docs/api/md-write.html
 	res, err := json.Marshal(patch)
 	if err != nil {
 		return err
 	}
 	params := url.Values{}
-	params.Add("-target", fmt.Sprintf("files/%s", reqDir))
+	params.Add("-target", target)
 	params.Add("-patch", string(res))
 	body := []byte(params.Encode())
 	bodyLen := int64(len(body))
@@ -483,26 +832,26 @@ func (o *Object) SetModTime(ctx context.Context, t time.Time) (err error) {
 	// make a POST request to (frontend)/metadata/:item/
 	opts := rest.Opts{
 		Method:        "POST",
-		Path:          path.Join("/metadata/", bucket),
+		Path:          path.Join("/metadata/", item),
 		Body:          bytes.NewReader(body),
 		ContentLength: &bodyLen,
 		ContentType:   "application/x-www-form-urlencoded",
 	}
 
-	err = o.fs.pacer.Call(func() (bool, error) {
-		resp, err = o.fs.front.CallJSON(ctx, &opts, nil, &result)
-		return o.fs.shouldRetry(resp, err)
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.front.CallJSON(ctx, &opts, nil, &result)
+		return f.shouldRetry(resp, err)
 	})
 	if err != nil {
 		return err
 	}
-
-	if result.Success {
-		o.modTime = t
-		return nil
+	if !result.Success {
+		return errors.New(result.Error)
 	}
 
-	return errors.New(result.Error)
+	// the cached metadata of the item is now out of date
+	metadataCache.Delete(item)
+	return nil
 }
 
 // List files and directories in a directory
@@ -512,7 +861,16 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 		if reqDir != "" {
 			return nil, fs.ErrorListBucketRequired
 		}
-		return entries, nil
+		if f.opt.ListCollection == "" {
+			return entries, nil
+		}
+		err = f.listCollection(ctx, func(items []*Directory) error {
+			for _, item := range items {
+				entries = append(entries, item)
+			}
+			return nil
+		})
+		return entries, err
 	}
 	grandparent := f.opt.Enc.ToStandardPath(strings.Trim(path.Join(bucket, reqDir), "/") + "/")
 
@@ -529,7 +887,7 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 				entries = append(entries, obj)
 			}
 		}
-		dire, ok := ent.(*fs.Dir)
+		dire, ok := ent.(*Directory)
 		if ok && strings.HasPrefix(dire.Remote(), grandparent) {
 			path := trimPathPrefix(dire.Remote(), grandparent, f.opt.Enc)
 			if !strings.Contains(path, "/") {
@@ -542,8 +900,91 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 	return entries, nil
 }
 
-// Mkdir can't be performed on IA like git repositories
+// Mkdir makes the item if dir is the root of an item which doesn't
+// exist yet
+//
+// Other directories can't be made on IA like git repositories
 func (f *Fs) Mkdir(ctx context.Context, dir string) (err error) {
+	_, err = f.makeItem(ctx, dir, nil)
+	return err
+}
+
+// makeItem makes the item if dir is the root of an item which
+// doesn't exist yet, setting the item metadata from the options and
+// metadata if not nil.
+//
+// It returns true if the item was made.
+func (f *Fs) makeItem(ctx context.Context, dir string, metadata fs.Metadata) (made bool, err error) {
+	item, itemPath := f.split(dir)
+	if item == "" || itemPath != "" {
+		return false, nil
+	}
+	result, err := f.requestMetadata(ctx, item)
+	if err != nil {
+		return false, err
+	}
+	if result.Metadata != nil {
+		// item exists already
+		return false, nil
+	}
+
+	headers := map[string]string{
+		"x-amz-auto-make-bucket":     "1",
+		"x-archive-auto-make-bucket": "1",
+	}
+	headers, err = f.appendItemMetadataHeaders(headers, item, metadata)
+	if err != nil {
+		return false, err
+	}
+
+	// make a PUT request at (IAS3)/:item
+	var resp *http.Response
+	var size int64
+	opts := rest.Opts{
+		Method:        "PUT",
+		Path:          "/" + url.PathEscape(item),
+		ContentLength: &size,
+		ExtraHeaders:  headers,
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.Call(ctx, &opts)
+		return f.shouldRetry(resp, err)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to make item: %w", err)
+	}
+	fs.Debugf(item, "Made item")
+
+	// the cached metadata says the item doesn't exist
+	metadataCache.Delete(item)
+	return true, nil
+}
+
+// MkdirMetadata makes the directory passed in as dir.
+//
+// Only the root of an item can have metadata, which is set as the
+// item metadata, making the item if it doesn't exist.
+//
+// It returns the directory that was created.
+func (f *Fs) MkdirMetadata(ctx context.Context, dir string, metadata fs.Metadata) (fs.Directory, error) {
+	d := f.newDirectory(dir, time.Unix(0, 0))
+	made, err := f.makeItem(ctx, dir, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if metadata != nil && !made {
+		err := d.SetMetadata(ctx, metadata)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// DirSetModTime does nothing as the modification times of directories
+// are made up from the files in them
+func (f *Fs) DirSetModTime(ctx context.Context, dir string, modTime time.Time) error {
+	fs.Debugf(dir, "Can't set modification time of directories on IA - ignoring")
 	return nil
 }
 
@@ -590,27 +1031,20 @@ func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options .
 		size:    src.Size(),
 	}
 
-	// Submit a no-op fixer task to avoid snowballing behavior
-	bucket, _ := f.split(src.Remote())
-	if bucket != "" {
-		if f.opt.AccessKeyID != "" && f.opt.SecretAccessKey != "" {
-			// Only submit fixer if we have credentials (anonymous users can't submit tasks)
-			fs.LogPrintf(fs.LogLevelInfo, o, "Submitting no-op fixer task for bucket %s to avoid snowballing", bucket)
-			err := f.submitFixerNoopTask(ctx, bucket)
-			if err != nil {
-				// Log but continue with upload even if fixer task fails
-				fs.Logf(o, "Failed to submit no-op fixer task: %v", err)
-			} else {
-				fs.Debugf(o, "Submitted no-op fixer task to avoid snowballing")
-			}
-		} else {
-			fs.LogPrintf(fs.LogLevelInfo, o, "Skipping fixer task - anonymous access doesn't support task submission")
-		}
-	} else {
-		fs.LogPrintf(fs.LogLevelInfo, o, "Skipping fixer task - couldn't determine bucket name from path %q", src.Remote())
+	_, bucketPath := o.split()
+	err := f.checkWritable(bucketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = f.waitTasksQueued(ctx, o)
+	if err != nil {
+		return nil, err
 	}
 
-	err := o.Update(ctx, in, src, options...)
+	f.submitUploadTask(ctx, o)
+
+	err = o.update(ctx, in, src, options...)
 	if err == nil {
 		return o, nil
 	}
@@ -627,9 +1061,60 @@ func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration,
 		return "", err
 	}
 	bucket, bucketPath := f.split(remote)
+	bucketPath, _ = f.inDerivatives(bucketPath)
 	return path.Join(f.opt.FrontEndpoint, "/download/", bucket, quotePath(bucketPath)), nil
 }
 
+// waitTasksQueued waits until the item o is in has fewer than
+// max_tasks_queued catalog tasks queued, if set
+func (f *Fs) waitTasksQueued(ctx context.Context, o *Object) error {
+	bucket, _ := o.split()
+	if f.opt.MaxTasksQueued <= 0 || bucket == "" || f.opt.AccessKeyID == "" || f.opt.SecretAccessKey == "" {
+		return nil
+	}
+	for {
+		tasks, err := f.listTasks(ctx, bucket, false)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			// Log but continue with upload if the tasks can't be read
+			fs.Logf(o, "Failed to read the queued tasks: %v", err)
+			return nil
+		}
+		queued := tasks.Summary["queued"]
+		if queued < f.opt.MaxTasksQueued {
+			return nil
+		}
+		fs.Infof(o, "Pausing upload as %s has %d tasks queued", bucket, queued)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(tasksPollInterval):
+		}
+	}
+}
+
+// submitUploadTask submits a task, by default a no-op fixer, for the
+// item before uploading o to it to avoid snowballing behavior
+func (f *Fs) submitUploadTask(ctx context.Context, o *Object) {
+	bucket, _ := o.split()
+	switch {
+	case f.opt.TaskCmd == "":
+	case bucket == "":
+		fs.LogPrintf(fs.LogLevelInfo, o, "Skipping %s task - couldn't determine bucket name from path %q", f.opt.TaskCmd, o.remote)
+	case f.opt.AccessKeyID == "" || f.opt.SecretAccessKey == "":
+		fs.LogPrintf(fs.LogLevelInfo, o, "Skipping %s task - anonymous access doesn't support task submission", f.opt.TaskCmd)
+	default:
+		fs.LogPrintf(fs.LogLevelInfo, o, "Submitting %s task for bucket %s", f.opt.TaskCmd, bucket)
+		err := f.submitTask(ctx, bucket)
+		if err != nil {
+			// Log but continue with upload even if the task fails
+			fs.Logf(o, "Failed to submit %s task: %v", f.opt.TaskCmd, err)
+		}
+	}
+}
+
 // Copy src to this remote using server-side copy operations.
 //
 // This is stored with the remote path given.
@@ -646,18 +1131,32 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (_ fs.Objec
 		fs.Debugf(src, "Can't copy - not same remote type")
 		return nil, fs.ErrorCantCopy
 	}
-	srcBucket, srcPath := srcObj.split()
+	srcBucket, srcPath := srcObj.itemPath()
+	if err = f.checkWritable(dstPath); err != nil {
+		return nil, err
+	}
 
 	if dstBucket == srcBucket && dstPath == srcPath {
 		// https://github.com/jjjake/internetarchive/blob/2456376533251df9d05e0a14d796ec1ced4959f5/internetarchive/cli/ia_copy.py#L68
 		fs.Debugf(src, "Can't copy - the source and destination files cannot be the same!")
 		return nil, fs.ErrorCantCopy
 	}
+	if srcObj.fs.opt.Endpoint != f.opt.Endpoint {
+		fs.Debugf(src, "Can't copy - not same endpoint")
+		return nil, fs.ErrorCantCopy
+	}
+
+	err = f.waitTasksQueued(ctx, &Object{fs: f, remote: remote})
+	if err != nil {
+		return nil, err
+	}
+
+	f.submitUploadTask(ctx, &Object{fs: f, remote: remote})
 
 	updateTracker := random.String(32)
 	headers := map[string]string{
+		"x-amz-auto-make-bucket":     "1",
 		"x-archive-auto-make-bucket": "1",
-		"x-archive-queue-derive":     "0",
 		"x-archive-keep-old-version": "0",
 		"x-amz-copy-source":          quotePath(path.Join("/", srcBucket, srcPath)),
 		"x-amz-metadata-directive":   "COPY",
@@ -670,6 +1169,14 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (_ fs.Objec
 		"x-archive-filemeta-rclone-update-track": updateTracker,
 	}
 
+	// This is IA's ITEM metadata used if the copy makes the item
+	headers, err = f.appendItemMetadataHeaders(headers, dstBucket, nil)
+	if err != nil {
+		return nil, err
+	}
+	// the source has been derived already
+	headers["x-archive-queue-derive"] = "0"
+
 	// make a PUT request at (IAS3)/:item/:path without body
 	var resp *http.Response
 	opts := rest.Opts{
@@ -688,7 +1195,14 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (_ fs.Objec
 
 	// we can't update/find metadata here as IA will also
 	// queue server-side copy as well as upload/delete.
-	return f.waitFileUpload(ctx, trimPathPrefix(path.Join(dstBucket, dstPath), f.root, f.opt.Enc), updateTracker, srcObj.size)
+	dstObj, err := f.waitFileUpload(ctx, trimPathPrefix(path.Join(dstBucket, dstPath), f.root, f.opt.Enc), updateTracker, srcObj.size)
+	if err == nil && f.opt.WaitTask > 0 {
+		_, err = f.waitTasks(ctx, dstBucket, time.Duration(f.opt.WaitTask))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dstObj, nil
 }
 
 // ListR lists the objects and directories of the Fs starting
@@ -708,19 +1222,68 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (_ fs.Objec
 // Don't implement this unless you have a more efficient way
 // of listing recursively than doing a directory traversal.
 func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
-	var allEntries, entries fs.DirEntries
 	bucket, reqDir := f.split(dir)
 	if bucket == "" {
 		if reqDir != "" {
 			return fs.ErrorListBucketRequired
 		}
-		return callback(entries)
+		if f.opt.ListCollection == "" {
+			return callback(nil)
+		}
+		return f.listRCollection(ctx, callback)
+	}
+	entries, err := f.listR(ctx, bucket, reqDir)
+	if err != nil {
+		return err
 	}
+	return callback(entries)
+}
+
+// listRCollection lists the items of the collection and their files
+// recursively into callback
+//
+// The files of the items in each page from the scrape API are listed
+// while the next page is found.
+func (f *Fs) listRCollection(ctx context.Context, callback fs.ListRCallback) (err error) {
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(fs.GetConfig(ctx).Checkers + 1)
+	var mu sync.Mutex // serialises calls to callback
+	g.Go(func() error {
+		return f.listCollection(gCtx, func(items []*Directory) error {
+			entries := make(fs.DirEntries, 0, len(items))
+			for _, item := range items {
+				entries = append(entries, item)
+			}
+			mu.Lock()
+			err := callback(entries)
+			mu.Unlock()
+			if err != nil {
+				return err
+			}
+			for _, item := range items {
+				g.Go(func() error {
+					entries, err := f.listR(gCtx, item.Remote(), "")
+					if err != nil {
+						return fmt.Errorf("failed to list item %q: %w", item.Remote(), err)
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					return callback(entries)
+				})
+			}
+			return nil
+		})
+	})
+	return g.Wait()
+}
+
+// listR lists everything in reqDir of bucket recursively
+func (f *Fs) listR(ctx context.Context, bucket, reqDir string) (entries fs.DirEntries, err error) {
 	grandparent := f.opt.Enc.ToStandardPath(strings.Trim(path.Join(bucket, reqDir), "/") + "/")
 
-	allEntries, err = f.listAllUnconstrained(ctx, bucket)
+	allEntries, err := f.listAllUnconstrained(ctx, bucket)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	for _, ent := range allEntries {
 		obj, ok := ent.(*Object)
@@ -728,14 +1291,14 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 			obj.remote = trimPathPrefix(obj.remote, f.root, f.opt.Enc)
 			entries = append(entries, obj)
 		}
-		dire, ok := ent.(*fs.Dir)
+		dire, ok := ent.(*Directory)
 		if ok && strings.HasPrefix(dire.Remote(), grandparent) {
 			dire.SetRemote(trimPathPrefix(dire.Remote(), f.root, f.opt.Enc))
 			entries = append(entries, dire)
 		}
 	}
 
-	return callback(entries)
+	return entries, nil
 }
 
 // CleanUp removes all files inside history/
@@ -811,11 +1374,20 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.Read
 		optionsFixed = append(optionsFixed, opt)
 	}
 
+	if o.fs.opt.DownloadTorrent && o.size >= int64(o.fs.opt.TorrentCutoff) {
+		in, err = o.openTorrent(ctx, optionsFixed...)
+		if err == nil {
+			return in, nil
+		}
+		fs.Debugf(o, "Downloading over HTTP as can't download with BitTorrent: %v", err)
+	}
+
 	var resp *http.Response
 	// make a GET request to (frontend)/download/:item/:path
+	item, itemPath := o.itemPath()
 	opts := rest.Opts{
 		Method:  "GET",
-		Path:    path.Join("/download/", o.fs.root, quotePath(o.fs.opt.Enc.FromStandardPath(o.remote))),
+		Path:    path.Join("/download/", item, quotePath(itemPath)),
 		Options: optionsFixed,
 	}
 	err = o.fs.pacer.Call(func() (bool, error) {
@@ -828,36 +1400,216 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.Read
 	return resp.Body, nil
 }
 
-// Update the Object from in with modTime and size
-func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
-	bucket, bucketPath := o.split()
-	modTime := src.ModTime(ctx)
-	size := src.Size()
-	updateTracker := random.String(32)
-
-	// Set the mtime in the metadata
-	// internetarchive backend builds at header level as IAS3 has extension outside X-Amz-
-	headers := map[string]string{
-		// https://github.com/jjjake/internetarchive/blob/2456376533251df9d05e0a14d796ec1ced4959f5/internetarchive/iarequest.py#L158
-		"x-amz-filemeta-rclone-mtime":        modTime.Format(time.RFC3339Nano),
-		"x-amz-filemeta-rclone-update-track": updateTracker,
-
-		// we add some more headers for intuitive actions
-		"x-amz-auto-make-bucket":     "1", // create an item if does not exist, do nothing if already
-		"x-archive-auto-make-bucket": "1", // same as above in IAS3 original way
-		"x-archive-keep-old-version": "0", // do not keep old versions (a.k.a. trashes in other clouds)
-		"x-archive-cascade-delete":   "1", // enable "cascate delete" (delete all derived files in addition to the file itself)
-	}
-
-	if size >= 0 {
-		headers["Content-Length"] = fmt.Sprintf("%d", size)
-		headers["x-archive-size-hint"] = fmt.Sprintf("%d", size)
-	}
-
-	// This is IA's ITEM metadata, not file metadata
-	headers, err = o.appendItemMetadataHeaders(headers, o.fs.opt)
+// openTorrent opens the object for read with the torrent remote of
+// its item, checking the file in the torrent is the same as the object
+func (o *Object) openTorrent(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	item, _ := o.split()
+	_, itemPath := bucket.Split(path.Join(o.fs.root, o.remote))
+	itemPath, _ = o.fs.inDerivatives(itemPath)
+	torrentFs, err := o.fs.itemTorrent(ctx, item)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	torrentObj, err := torrentFs.NewObject(ctx, itemPath)
+	if err != nil {
+		return nil, err
+	}
+	if torrentObj.Size() != o.size {
+		return nil, errors.New("file in torrent has a different size")
+	}
+	if o.md5 != "" {
+		md5, err := torrentObj.Hash(ctx, hash.MD5)
+		if err == nil && md5 != "" && md5 != o.md5 {
+			return nil, errors.New("file in torrent has a different MD5")
+		}
+	}
+	return torrentObj.Open(ctx, options...)
+}
+
+// itemTorrent returns a torrent remote reading the archive.org
+// torrent of item
+//
+// The result is remembered if the torrent was read or the item
+// definitely has no torrent. Other errors aren't so reading the
+// torrent is tried again next time.
+func (f *Fs) itemTorrent(ctx context.Context, item string) (fs.Fs, error) {
+	f.torrentMu.Lock()
+	defer f.torrentMu.Unlock()
+	torrentFs, ok := f.torrents[item]
+	if !ok {
+		torrentPath := path.Join("/download", quotePath(item), quotePath(item)+"_archive.torrent")
+		torrentURL := strings.TrimSuffix(f.opt.FrontEndpoint, "/") + torrentPath
+		remote := fmt.Sprintf(":torrent,peers=%d,torrent='%s':", f.opt.TorrentPeers, strings.ReplaceAll(torrentURL, "'", "''"))
+		var err error
+		torrentFs, err = cache.Get(ctx, remote)
+		if err != nil {
+			if !f.torrentMissing(ctx, torrentPath) {
+				return nil, fmt.Errorf("can't read the torrent of item %q: %w", item, err)
+			}
+			fs.Debugf(f, "Item %q has no torrent: %v", item, err)
+			torrentFs = nil
+		}
+		f.torrents[item] = torrentFs
+	}
+	if torrentFs == nil {
+		return nil, fmt.Errorf("item %q has no torrent", item)
+	}
+	return torrentFs, nil
+}
+
+// torrentMissing returns true if the front end says there is no file
+// at torrentPath
+func (f *Fs) torrentMissing(ctx context.Context, torrentPath string) bool {
+	opts := rest.Opts{
+		Method:     "HEAD",
+		Path:       torrentPath,
+		NoResponse: true,
+	}
+	var resp *http.Response
+	err := f.pacer.Call(func() (bool, error) {
+		var err error
+		resp, err = f.front.Call(ctx, &opts)
+		return f.shouldRetry(resp, err)
+	})
+	return err != nil && resp != nil && resp.StatusCode == http.StatusNotFound
+}
+
+// Update the Object from in with modTime and size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	_, bucketPath := o.split()
+	err = o.fs.checkWritable(bucketPath)
+	if err != nil {
+		return err
+	}
+	err = o.fs.waitTasksQueued(ctx, o)
+	if err != nil {
+		return err
+	}
+	return o.update(ctx, in, src, options...)
+}
+
+// update the Object from in with modTime and size
+func (o *Object) update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	size := src.Size()
+	var hasher *hash.MultiHasher
+	if o.fs.opt.VerifyAfterUpload {
+		hasher, err = hash.NewMultiHasherTypes(o.fs.Hashes())
+		if err != nil {
+			return err
+		}
+		in = io.TeeReader(in, hasher)
+	}
+	var updateTracker string
+	if size >= int64(o.fs.opt.UploadCutoff) {
+		var chunkWriter fs.ChunkWriter
+		chunkWriter, err = multipart.UploadMultipart(ctx, src, in, multipart.UploadMultipartOptions{
+			Open:        o.fs,
+			OpenOptions: options,
+		})
+		if err == nil {
+			updateTracker = chunkWriter.(*iaChunkWriter).updateTracker
+		}
+	} else {
+		updateTracker = random.String(32)
+		err = o.upload(ctx, in, src, updateTracker, options...)
+	}
+
+	// we can't update/find metadata here as IA will "ingest" uploaded file(s)
+	// upon uploads. (you can find its progress at https://archive.org/history/ItemNameHere )
+	// or we have to wait for finish? (needs polling (frontend)/metadata/:item or scraping (frontend)/history/:item)
+	var newObj *Object
+	if err == nil {
+		newObj, err = o.fs.waitFileUpload(ctx, o.remote, updateTracker, size)
+	} else {
+		newObj = &Object{}
+	}
+	o.crc32 = newObj.crc32
+	o.md5 = newObj.md5
+	o.sha1 = newObj.sha1
+	o.modTime = newObj.modTime
+	o.size = newObj.size
+	if err == nil && o.fs.opt.WaitTask > 0 {
+		bucket, _ := o.split()
+		_, err = o.fs.waitTasks(ctx, bucket, time.Duration(o.fs.opt.WaitTask))
+	}
+	if err == nil && hasher != nil {
+		err = o.verifyUpload(ctx, updateTracker, hasher.Sums())
+	}
+	return err
+}
+
+// verifyUpload reads the item metadata again to check the checksums
+// IA has for the upload with updateTracker match sums
+func (o *Object) verifyUpload(ctx context.Context, updateTracker string, sums map[hash.Type]string) error {
+	bucket, bucketPath := o.split()
+	metadataCache.Delete(bucket)
+	result, err := o.fs.requestMetadata(ctx, bucket)
+	if err != nil {
+		return fmt.Errorf("failed to verify upload: %w", err)
+	}
+	var iaFile *IAFile
+	for i := range result.Files {
+		if result.Files[i].Name == bucketPath {
+			iaFile = &result.Files[i]
+			break
+		}
+	}
+	if iaFile == nil {
+		return errors.New("failed to verify upload: file not found in the item")
+	}
+	fileTrackers, _ := listOrString(iaFile.UpdateTrack)
+	if !slices.Contains(fileTrackers, updateTracker) {
+		return errors.New("failed to verify upload: upload not processed by IA yet - increase wait_archive")
+	}
+	got := map[hash.Type]string{
+		hash.MD5:   iaFile.Md5,
+		hash.SHA1:  iaFile.Sha1,
+		hash.CRC32: iaFile.Crc32,
+	}
+	for _, ty := range o.fs.Hashes().Array() {
+		if got[ty] == "" {
+			return fmt.Errorf("failed to verify upload: IA has no %v checksum", ty)
+		}
+		if !hash.Equals(sums[ty], got[ty]) {
+			return fmt.Errorf("corrupted on transfer: %v checksums differ %q vs %q", ty, sums[ty], got[ty])
+		}
+	}
+	o.md5 = iaFile.Md5
+	o.sha1 = iaFile.Sha1
+	o.crc32 = iaFile.Crc32
+	return nil
+}
+
+// uploadHeaders returns the headers for uploading src to the object
+// with the item metadata and the file metadata and modification time
+// of src, setting updateTracker to find the upload with.
+func (o *Object) uploadHeaders(ctx context.Context, src fs.ObjectInfo, updateTracker string, options []fs.OpenOption) (headers map[string]string, err error) {
+	bucket, _ := o.split()
+	modTime := src.ModTime(ctx)
+	size := src.Size()
+
+	// Set the mtime in the metadata
+	// internetarchive backend builds at header level as IAS3 has extension outside X-Amz-
+	headers = map[string]string{
+		// https://github.com/jjjake/internetarchive/blob/2456376533251df9d05e0a14d796ec1ced4959f5/internetarchive/iarequest.py#L158
+		"x-amz-filemeta-rclone-mtime":        modTime.Format(time.RFC3339Nano),
+		"x-amz-filemeta-rclone-update-track": updateTracker,
+
+		// we add some more headers for intuitive actions
+		"x-amz-auto-make-bucket":     "1", // create an item if does not exist, do nothing if already
+		"x-archive-auto-make-bucket": "1", // same as above in IAS3 original way
+		"x-archive-keep-old-version": "0", // do not keep old versions (a.k.a. trashes in other clouds)
+		"x-archive-cascade-delete":   "1", // enable "cascate delete" (delete all derived files in addition to the file itself)
+	}
+
+	if size >= 0 {
+		headers["x-archive-size-hint"] = fmt.Sprintf("%d", size)
+	}
+
+	// This is IA's ITEM metadata, not file metadata
+	headers, err = o.fs.appendItemMetadataHeaders(headers, bucket, nil)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get file metadata
@@ -877,6 +1629,20 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 			headers[fmt.Sprintf("x-amz-filemeta-%s", mk)] = mv
 		}
 	}
+	return headers, nil
+}
+
+// upload src to the object with a single PUT request
+func (o *Object) upload(ctx context.Context, in io.Reader, src fs.ObjectInfo, updateTracker string, options ...fs.OpenOption) (err error) {
+	bucket, bucketPath := o.split()
+	size := src.Size()
+	headers, err := o.uploadHeaders(ctx, src, updateTracker, options)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		headers["Content-Length"] = fmt.Sprintf("%d", size)
+	}
 
 	// read the md5sum if available
 	var md5sumHex string
@@ -904,30 +1670,17 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		resp, err = o.fs.srv.Call(ctx, &opts)
 		return o.fs.shouldRetry(resp, err)
 	})
-
-	// we can't update/find metadata here as IA will "ingest" uploaded file(s)
-	// upon uploads. (you can find its progress at https://archive.org/history/ItemNameHere )
-	// or we have to wait for finish? (needs polling (frontend)/metadata/:item or scraping (frontend)/history/:item)
-	var newObj *Object
-	if err == nil {
-		newObj, err = o.fs.waitFileUpload(ctx, o.remote, updateTracker, size)
-	} else {
-		newObj = &Object{}
-	}
-	o.crc32 = newObj.crc32
-	o.md5 = newObj.md5
-	o.sha1 = newObj.sha1
-	o.modTime = newObj.modTime
-	o.size = newObj.size
 	return err
 }
 
-func (o *Object) appendItemMetadataHeaders(headers map[string]string, options Options) (newHeaders map[string]string, err error) {
+// appendItemMetadataHeaders adds the headers to set the metadata of
+// item when it is made, from the options and metadata if not nil
+func (f *Fs) appendItemMetadataHeaders(headers map[string]string, item string, metadata fs.Metadata) (newHeaders map[string]string, err error) {
 	metadataCounter := make(map[string]int)
 	metadataValues := make(map[string][]string)
 
 	// First pass: count occurrences and collect values
-	for _, v := range options.ItemMetadata {
+	for _, v := range f.opt.ItemMetadata {
 		parts := strings.SplitN(v, "=", 2)
 		if len(parts) != 2 {
 			return newHeaders, errors.New("item metadata key=value should be in the form key=value")
@@ -937,6 +1690,35 @@ func (o *Object) appendItemMetadataHeaders(headers map[string]string, options Op
 		metadataValues[key] = append(metadataValues[key], value)
 	}
 
+	// Use the defaults for new items unless set above
+	for key, value := range map[string]string{
+		"collection": f.opt.ItemCollection,
+		"mediatype":  f.opt.ItemMediatype,
+	} {
+		if value != "" && metadataCounter[key] == 0 {
+			metadataCounter[key] = 1
+			metadataValues[key] = []string{value}
+		}
+	}
+	if f.opt.ItemNoindex && metadataCounter["noindex"] == 0 {
+		metadataCounter["noindex"] = 1
+		metadataValues["noindex"] = []string{"true"}
+	}
+
+	// Metadata passed in overrides the options
+	for key, value := range metadata {
+		key = strings.ToLower(key)
+		if _, ok := roItemMetadataKey[key]; ok || key == "mtime" || value == "" {
+			continue
+		}
+		values := []string{value}
+		if _, ok := listItemMetadataKey[key]; ok {
+			values = splitItemMetadataList(value)
+		}
+		metadataCounter[key] = len(values)
+		metadataValues[key] = values
+	}
+
 	// Second pass: add headers with appropriate prefixes
 	for key, count := range metadataCounter {
 		if count == 1 {
@@ -950,17 +1732,17 @@ func (o *Object) appendItemMetadataHeaders(headers map[string]string, options Op
 		}
 	}
 
-	if o.fs.opt.ItemDerive {
+	if f.opt.ItemDerive {
 		headers["x-archive-queue-derive"] = "1"
 	} else {
 		headers["x-archive-queue-derive"] = "0"
 	}
 
-	fs.Debugf(o, "Setting IA item derive: %t", o.fs.opt.ItemDerive)
+	fs.Debugf(item, "Setting IA item derive: %t", f.opt.ItemDerive)
 
 	for k, v := range headers {
 		if strings.HasPrefix(k, "x-archive-meta") {
-			fs.Debugf(o, "Setting IA item metadata: %s=%s", k, v)
+			fs.Debugf(item, "Setting IA item metadata: %s=%s", k, v)
 		}
 	}
 
@@ -970,6 +1752,9 @@ func (o *Object) appendItemMetadataHeaders(headers map[string]string, options Op
 // Remove an object
 func (o *Object) Remove(ctx context.Context) (err error) {
 	bucket, bucketPath := o.split()
+	if err = o.fs.checkWritable(bucketPath); err != nil {
+		return err
+	}
 
 	// make a DELETE request at (IAS3)/:item/:path
 	var resp *http.Response
@@ -1027,8 +1812,162 @@ func (o *Object) Metadata(ctx context.Context) (m fs.Metadata, err error) {
 	return
 }
 
+// newDirectory makes a Directory for remote
+func (f *Fs) newDirectory(remote string, modTime time.Time) *Directory {
+	return &Directory{
+		Dir: fs.NewDir(remote, modTime),
+		fs:  f,
+	}
+}
+
+// Fs returns the parent Fs
+func (d *Directory) Fs() fs.Info {
+	return d.fs
+}
+
+// Metadata returns the item metadata for the root of an item
+//
+// It returns nil for other directories
+func (d *Directory) Metadata(ctx context.Context) (fs.Metadata, error) {
+	item, dir := d.fs.split(d.Remote())
+	if item == "" || dir != "" {
+		return nil, nil
+	}
+	return d.fs.itemMetadata(ctx, item)
+}
+
+// SetMetadata sets the item metadata for the root of an item
+//
+// It does nothing for other directories
+func (d *Directory) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	item, dir := d.fs.split(d.Remote())
+	if item == "" || dir != "" {
+		fs.Debugf(d, "Can't set metadata on directories other than the root of an item - ignoring")
+		return nil
+	}
+	return d.fs.setItemMetadata(ctx, item, metadata)
+}
+
+// itemMetadata returns the metadata of item or nil if it doesn't exist
+//
+// Keys with multiple values, like subject, have them separated by ";"
+func (f *Fs) itemMetadata(ctx context.Context, item string) (m fs.Metadata, err error) {
+	result, err := f.requestMetadata(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	if result.Metadata == nil {
+		return nil, nil
+	}
+	m = make(fs.Metadata, len(result.Metadata))
+	for k, v := range result.Metadata {
+		items, err := listOrString(v)
+		if len(items) == 0 || err != nil {
+			// skip: an entry failed to parse
+			continue
+		}
+		m[k] = strings.Join(items, ";")
+	}
+	return m, nil
+}
+
+// splitItemMetadataList splits a value with several entries
+// separated by ";"
+func splitItemMetadataList(value string) []string {
+	values := strings.Split(value, ";")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
+
+// escapes a key for use in a JSON patch path
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// setItemMetadata writes the keys in metadata which have changed to the
+// metadata of item
+//
+// Keys set to an empty value are removed.
+func (f *Fs) setItemMetadata(ctx context.Context, item string, metadata fs.Metadata) error {
+	current, err := f.itemMetadata(ctx, item)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		fs.Logf(item, "Can't set metadata as the item doesn't exist yet - use --internetarchive-item-metadata to set it on upload")
+		return nil
+	}
+	var patch []map[string]any
+	for _, k := range slices.Sorted(maps.Keys(metadata)) {
+		v := metadata[k]
+		k = strings.ToLower(k)
+		if _, ok := roItemMetadataKey[k]; ok {
+			fs.LogPrintf(fs.LogLevelWarning, item, "setting or modifying read-only key %s is requested, skipping", k)
+			continue
+		} else if k == "mtime" {
+			// items don't have a modification time to set
+			continue
+		}
+		old, exists := current[k]
+		op := map[string]any{
+			"path": "/" + jsonPointerEscaper.Replace(k),
+		}
+		switch {
+		case v == old:
+			continue
+		case v == "":
+			if !exists {
+				continue
+			}
+			op["op"] = "remove"
+		default:
+			op["op"] = "add"
+			op["value"] = v
+			if _, ok := listItemMetadataKey[k]; ok && strings.Contains(v, ";") {
+				op["value"] = splitItemMetadataList(v)
+			}
+		}
+		patch = append(patch, op)
+	}
+	if len(patch) == 0 {
+		fs.Debugf(item, "Item metadata unchanged")
+		return nil
+	}
+	return f.writeMetadata(ctx, item, "metadata", patch)
+}
+
+// parseRetryAfter returns the time to wait from a Retry-After header
+// which can be a number of seconds or an HTTP date, or 0 if it isn't
+// set or can't be parsed
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	fs.Debugf(nil, "Failed to parse %s header %q", retryAfterHeader, value)
+	return 0
+}
+
 func (f *Fs) shouldRetry(resp *http.Response, err error) (bool, error) {
 	if resp != nil {
+		// IA returns 503 SlowDown when the catalog queues are too
+		// deep and 429 when rate limiting, saying how long to wait
+		// for with Retry-After if we are lucky
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			retryAfter := parseRetryAfter(resp.Header.Get(retryAfterHeader))
+			if retryAfter <= 0 && err != nil && strings.Contains(err.Error(), "SlowDown") {
+				retryAfter = slowDownSleep
+			}
+			if retryAfter > 0 {
+				fs.Debugf(f, "IA is overloaded - waiting %v before trying again", retryAfter)
+				return true, pacer.RetryAfterError(err, retryAfter)
+			}
+		}
 		if slices.Contains(retryErrorCodes, resp.StatusCode) {
 			return true, err
 		}
@@ -1051,6 +1990,40 @@ func (o *Object) split() (bucket, bucketPath string) {
 	return o.fs.split(o.remote)
 }
 
+// itemPath returns the item of the object and its path in the item,
+// which isn't bucketPath for derivative files shown in derivativesDir
+func (o *Object) itemPath() (item, itemPath string) {
+	item, bucketPath := o.split()
+	itemPath, _ = o.fs.inDerivatives(bucketPath)
+	return item, itemPath
+}
+
+// inDerivatives returns the path in the item of the derivative file
+// at bucketPath and true if bucketPath is in derivativesDir, or
+// bucketPath and false if not.
+func (f *Fs) inDerivatives(bucketPath string) (itemPath string, ok bool) {
+	if !f.opt.ShowDerivatives {
+		return bucketPath, false
+	}
+	if bucketPath == derivativesDir {
+		return "", true
+	}
+	itemPath, ok = strings.CutPrefix(bucketPath, derivativesDir+"/")
+	if !ok {
+		return bucketPath, false
+	}
+	return itemPath, true
+}
+
+// checkWritable returns an error if bucketPath is in derivativesDir
+// which is read only
+func (f *Fs) checkWritable(bucketPath string) error {
+	if _, ok := f.inDerivatives(bucketPath); ok {
+		return fserrors.NoRetryError(errDerivativesReadOnly)
+	}
+	return nil
+}
+
 func (f *Fs) requestMetadata(ctx context.Context, bucket string) (result *MetadataResponse, err error) {
 	// Use singleflight to coalesce identical requests
 	resp, err, shared := metadataSingle.Do(bucket, func() (interface{}, error) {
@@ -1086,6 +2059,50 @@ func (f *Fs) requestMetadata(ctx context.Context, bucket string) (result *Metada
 	return resp.(*MetadataResponse), nil
 }
 
+// listCollection finds the items in the collection ListCollection
+// with the scrape API, calling fn with each page of them as directories
+func (f *Fs) listCollection(ctx context.Context, fn func(items []*Directory) error) error {
+	params := url.Values{}
+	params.Set("q", fmt.Sprintf("collection:%q", f.opt.ListCollection))
+	params.Set("fields", "identifier,publicdate")
+	params.Set("count", strconv.Itoa(scrapePageSize))
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       "/services/search/v1/scrape",
+		Parameters: params,
+	}
+	for {
+		var result ScrapeResponse
+		var resp *http.Response
+		err := f.pacer.Call(func() (bool, error) {
+			var err error
+			resp, err = f.front.CallJSON(ctx, &opts, nil, &result)
+			return f.shouldRetry(resp, err)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list collection %q: %w", f.opt.ListCollection, err)
+		}
+		items := make([]*Directory, 0, len(result.Items))
+		for _, item := range result.Items {
+			if item.Identifier == "" {
+				continue
+			}
+			modTime, err := time.Parse(time.RFC3339, item.PublicDate)
+			if err != nil {
+				modTime = time.Unix(0, 0)
+			}
+			items = append(items, f.newDirectory(f.opt.Enc.ToStandardName(item.Identifier), modTime))
+		}
+		if err = fn(items); err != nil {
+			return err
+		}
+		if result.Cursor == "" {
+			return nil
+		}
+		params.Set("cursor", result.Cursor)
+	}
+}
+
 // list up all files/directories without any filters
 func (f *Fs) listAllUnconstrained(ctx context.Context, bucket string) (entries fs.DirEntries, err error) {
 	result, err := f.requestMetadata(ctx, bucket)
@@ -1097,8 +2114,12 @@ func (f *Fs) listAllUnconstrained(ctx context.Context, bucket string) (entries f
 		"": time.Unix(0, 0),
 	}
 	for _, file := range result.Files {
-		dir := strings.Trim(betterPathDir(file.Name), "/")
-		nameWithBucket := path.Join(bucket, file.Name)
+		name := file.Name
+		if f.opt.ShowDerivatives && file.Source == "derivative" {
+			name = path.Join(derivativesDir, name)
+		}
+		dir := strings.Trim(betterPathDir(name), "/")
+		nameWithBucket := path.Join(bucket, name)
 
 		mtimeTime := file.parseMtime()
 
@@ -1109,13 +2130,13 @@ func (f *Fs) listAllUnconstrained(ctx context.Context, bucket string) (entries f
 				break
 			}
 			// directory
-			d := fs.NewDir(f.opt.Enc.ToStandardPath(path.Join(bucket, child)), mtimeTime)
+			d := f.newDirectory(f.opt.Enc.ToStandardPath(path.Join(bucket, child)), mtimeTime)
 			entries = append(entries, d)
 
 			knownDirs[child] = mtimeTime
 			child = strings.Trim(betterPathDir(child), "/")
 		}
-		if _, ok := knownDirs[betterPathDir(file.Name)]; !ok {
+		if _, ok := knownDirs[betterPathDir(name)]; !ok {
 			continue
 		}
 
@@ -1138,6 +2159,9 @@ func (f *Fs) waitFileUpload(ctx context.Context, reqPath, tracker string, newSiz
 		size:    -1,
 	}
 
+	// the cached metadata is from before the upload
+	metadataCache.Delete(bucket)
+
 	if f.opt.WaitArchive == 0 {
 		// user doesn't want to poll, let's not
 		ret2, err := f.NewObject(ctx, reqPath)
@@ -1165,6 +2189,7 @@ func (f *Fs) waitFileUpload(ctx context.Context, reqPath, tracker string, newSiz
 			if !isFirstTime {
 				// depending on the queue, it takes time
 				time.Sleep(10 * time.Second)
+				metadataCache.Delete(bucket)
 			}
 			metadata, err := f.requestMetadata(ctx, bucket)
 			if err != nil {
@@ -1397,31 +2422,32 @@ func quotePath(s string) string {
 	return strings.Join(newValues, "/")
 }
 
-// submitFixerNoopTask submits a fixer.php task with noop=1 for the specified bucket/item
-// This prevents the "snowballing" behavior where multiple uploads to the same item get combined and delayed
-func (f *Fs) submitFixerNoopTask(ctx context.Context, bucket string) error {
+// submitTask submits the task_cmd catalog task with task_args for the specified bucket/item
+//
+// By default this is a fixer.php task with noop=1 which prevents the
+// "snowballing" behavior where multiple uploads to the same item get
+// combined and delayed
+func (f *Fs) submitTask(ctx context.Context, bucket string) error {
 	if f.opt.AccessKeyID == "" || f.opt.SecretAccessKey == "" {
-		fs.LogPrintf(fs.LogLevelInfo, f, "Skipping fixer task submission - anonymous users cannot submit tasks")
 		return errors.New("anonymous users cannot submit tasks, please configure access_key_id and secret_access_key")
 	}
 
-	// Use noop:1 for a no-op fixer task
-	args := map[string]string{
-		"noop": "1",
+	args := f.taskArgs
+	if args == nil {
+		args = map[string]any{}
 	}
 
 	// Prepare the task payload
-	payload := map[string]interface{}{
+	payload := map[string]any{
 		"identifier": bucket,
-		"cmd":        "fixer.php",
+		"cmd":        f.opt.TaskCmd,
 		"args":       args,
 		"priority":   0, // Default priority
 	}
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		fs.LogPrintf(fs.LogLevelInfo, f, "Failed to marshal fixer task payload: %v", err)
-		return err
+		return fmt.Errorf("failed to marshal task payload: %w", err)
 	}
 
 	// Set up the request to the tasks API
@@ -1442,30 +2468,318 @@ func (f *Fs) submitFixerNoopTask(ctx context.Context, bucket string) error {
 		resp, err = f.front.CallJSON(ctx, &opts, nil, &result)
 		return f.shouldRetry(resp, err)
 	})
-
 	if err != nil {
-		fs.LogPrintf(fs.LogLevelInfo, f, "Failed to submit fixer task: %v", err)
 		return err
 	}
 
 	if !result.Success {
-		errMsg := fmt.Sprintf("Failed to submit fixer task: %s", resp.Status)
-		fs.LogPrintf(fs.LogLevelInfo, f, errMsg)
-		return fmt.Errorf(errMsg)
+		return fmt.Errorf("task not accepted: %s", resp.Status)
 	}
 
-	fs.LogPrintf(fs.LogLevelInfo, f, "Successfully submitted no-op fixer task ID %d for bucket %s", result.Value.TaskID, bucket)
-	fs.Debugf(f, "Successfully submitted no-op fixer task ID %d for bucket %s", result.Value.TaskID, bucket)
+	fs.Debugf(f, "Successfully submitted %s task ID %d for bucket %s", f.opt.TaskCmd, result.Value.TaskID, bucket)
+	return nil
+}
+
+// taskState is the wait_admin state of a catalog task
+//
+// The API returns this as either a number or a string
+type taskState string
+
+// UnmarshalJSON reads a number or a string
+func (s *taskState) UnmarshalJSON(data []byte) error {
+	*s = taskState(strings.Trim(string(data), `"`))
 	return nil
 }
 
+// String returns a description of the state
+func (s taskState) String() string {
+	switch s {
+	case "0":
+		return "queued"
+	case "1":
+		return "running"
+	case "2":
+		return "error"
+	case "9":
+		return "paused"
+	case "":
+		return "finished"
+	}
+	return "unknown (" + string(s) + ")"
+}
+
+// CatalogTask is a task in the response from the tasks API
+type CatalogTask struct {
+	TaskID     int64          `json:"task_id"`
+	Cmd        string         `json:"cmd"`
+	Args       map[string]any `json:"args"`
+	SubmitTime string         `json:"submittime"`
+	Submitter  string         `json:"submitter"`
+	Priority   int            `json:"priority"`
+	WaitAdmin  taskState      `json:"wait_admin"`
+}
+
+// TasksResponse represents the response from listing the tasks of an item
+type TasksResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+	Value   struct {
+		Summary map[string]int `json:"summary"`
+		Catalog []CatalogTask  `json:"catalog"`
+		History []CatalogTask  `json:"history"`
+	} `json:"value"`
+}
+
+// taskInfo describes a task in the output of the tasks command
+type taskInfo struct {
+	ID        int64          `json:"id"`
+	Cmd       string         `json:"cmd"`
+	State     string         `json:"state"`
+	Submitted string         `json:"submitted"`
+	Submitter string         `json:"submitter"`
+	Args      map[string]any `json:"args,omitempty"`
+	Log       string         `json:"log"`
+}
+
+// tasksOutput is the output of the tasks command
+type tasksOutput struct {
+	Item    string         `json:"item"`
+	Summary map[string]int `json:"summary"`
+	Tasks   []taskInfo     `json:"tasks"`
+}
+
+// The URL of the log of a catalog task
+const taskLogURL = "https://catalogd.archive.org/log/%d"
+
+// listTasks reads the tasks for item from the tasks API, including
+// the finished ones if history is set
+func (f *Fs) listTasks(ctx context.Context, item string, history bool) (out *tasksOutput, err error) {
+	params := url.Values{}
+	params.Set("identifier", item)
+	params.Set("summary", "1")
+	params.Set("catalog", "1")
+	if history {
+		params.Set("history", "1")
+	} else {
+		params.Set("history", "0")
+	}
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       "/services/tasks.php",
+		RootURL:    f.opt.FrontEndpoint,
+		Parameters: params,
+	}
+	var result TasksResponse
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.front.CallJSON(ctx, &opts, nil, &result)
+		return f.shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("failed to list tasks: %s", result.Error)
+	}
+	out = &tasksOutput{
+		Item:    item,
+		Summary: result.Value.Summary,
+		Tasks:   []taskInfo{},
+	}
+	add := func(tasks []CatalogTask, finished bool) {
+		for _, task := range tasks {
+			state := task.WaitAdmin.String()
+			if finished {
+				state = "finished"
+			}
+			out.Tasks = append(out.Tasks, taskInfo{
+				ID:        task.TaskID,
+				Cmd:       task.Cmd,
+				State:     state,
+				Submitted: task.SubmitTime,
+				Submitter: task.Submitter,
+				Args:      task.Args,
+				Log:       fmt.Sprintf(taskLogURL, task.TaskID),
+			})
+		}
+	}
+	add(result.Value.Catalog, false)
+	add(result.Value.History, true)
+	return out, nil
+}
+
+var tasksHelp = fs.CommandHelp{
+	Name:  "tasks",
+	Short: "Show the catalog tasks of an item.",
+	Long: `This command lists the queued and running catalog tasks of an item,
+such as derives, with their IDs, states and the URLs of their logs.
+
+    rclone backend tasks internetarchive:item
+
+The state of each task is one of queued, running, error or paused, or
+finished if the history option is used to show finished tasks too.
+
+    rclone backend tasks -o history internetarchive:item
+
+The wait option waits until there are no queued or running tasks on
+the item, for up to the duration given, before showing the tasks. This
+is useful to wait for a derive to finish before downloading the files
+it makes. An error is returned if the tasks haven't finished in time
+or one of them fails.
+
+    rclone backend tasks -o wait=1h internetarchive:item
+`,
+	Opts: map[string]string{
+		"history": "Show finished tasks too",
+		"wait":    "Wait up to this long for queued and running tasks to finish",
+	},
+}
+
+// The interval between checks of the tasks when waiting for them
+var tasksPollInterval = 10 * time.Second
+
+func (f *Fs) tasksCommand(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	item, _ := f.split("")
+	if item == "" {
+		return nil, errors.New("need an item, e.g. internetarchive:item")
+	}
+	_, history := opt["history"]
+	var wait time.Duration
+	if opt["wait"] != "" {
+		wait, err = fs.ParseDuration(opt["wait"])
+		if err != nil {
+			return nil, fmt.Errorf("bad wait: %w", err)
+		}
+	}
+	if wait == 0 {
+		return f.listTasks(ctx, item, history)
+	}
+	tasks, err := f.waitTasks(ctx, item, wait)
+	if err != nil || !history {
+		return tasks, err
+	}
+	return f.listTasks(ctx, item, history)
+}
+
+// waitTasks waits for up to timeout for the queued and running tasks
+// on item to finish returning the final list of tasks.
+//
+// It returns an error if the timeout expires or any of the tasks
+// fail.
+func (f *Fs) waitTasks(ctx context.Context, item string, timeout time.Duration) (tasks *tasksOutput, err error) {
+	if f.opt.AccessKeyID == "" || f.opt.SecretAccessKey == "" {
+		return nil, errors.New("anonymous users cannot read tasks, please configure access_key_id and secret_access_key")
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		tasks, err = f.listTasks(ctx, item, false)
+		if err != nil {
+			return nil, err
+		}
+		pending := 0
+		for _, task := range tasks.Tasks {
+			switch task.State {
+			case "queued", "running":
+				pending++
+			case "error":
+				return tasks, fmt.Errorf("%s task %d on %s failed - see %s", task.Cmd, task.ID, item, task.Log)
+			}
+		}
+		if pending == 0 {
+			return tasks, nil
+		}
+		if time.Now().After(deadline) {
+			return tasks, fmt.Errorf("timed out waiting for %d tasks on %s to finish", pending, item)
+		}
+		fs.Infof(f, "Waiting for %d tasks on %s to finish", pending, item)
+		select {
+		case <-ctx.Done():
+			return tasks, ctx.Err()
+		case <-time.After(tasksPollInterval):
+		}
+	}
+}
+
+var metadataHelp = fs.CommandHelp{
+	Name:  "metadata",
+	Short: "Show or set the metadata of an item.",
+	Long: `This command shows the item metadata of an item, such as its title,
+description, collection and subject tags. This is the same as the
+metadata of the root directory of the item.
+
+    rclone backend metadata internetarchive:item
+
+Any options given are set in the item metadata with the metadata write
+API, before showing the updated metadata. Setting a key to an empty
+value removes it.
+
+    rclone backend metadata internetarchive:item -o title="My item" -o subject="one;two"
+
+The collection and subject keys can have several values which are
+separated by ";". The identifier, uploader, addeddate and publicdate
+keys can't be changed.
+`,
+}
+
+func (f *Fs) metadataCommand(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	item, _ := f.split("")
+	if item == "" {
+		return nil, errors.New("need an item, e.g. internetarchive:item")
+	}
+	if len(opt) > 0 {
+		m, err := f.itemMetadata(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			return nil, fmt.Errorf("item %q not found: %w", item, fs.ErrorDirNotFound)
+		}
+		err = f.setItemMetadata(ctx, item, fs.Metadata(opt))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f.itemMetadata(ctx, item)
+}
+
+var commandHelp = []fs.CommandHelp{
+	tasksHelp,
+	metadataHelp,
+}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "tasks":
+		return f.tasksCommand(ctx, name, arg, opt)
+	case "metadata":
+		return f.metadataCommand(ctx, name, arg, opt)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
 var (
-	_ fs.Fs           = &Fs{}
-	_ fs.Copier       = &Fs{}
-	_ fs.ListRer      = &Fs{}
-	_ fs.CleanUpper   = &Fs{}
-	_ fs.PublicLinker = &Fs{}
-	_ fs.Abouter      = &Fs{}
-	_ fs.Object       = &Object{}
-	_ fs.Metadataer   = &Object{}
+	_ fs.Fs              = &Fs{}
+	_ fs.Copier          = &Fs{}
+	_ fs.ListRer         = &Fs{}
+	_ fs.CleanUpper      = &Fs{}
+	_ fs.PublicLinker    = &Fs{}
+	_ fs.Abouter         = &Fs{}
+	_ fs.Commander       = &Fs{}
+	_ fs.MkdirMetadataer = &Fs{}
+	_ fs.DirSetModTimer  = &Fs{}
+	_ fs.Object          = &Object{}
+	_ fs.Metadataer      = &Object{}
+	_ fs.Directory       = &Directory{}
+	_ fs.Metadataer      = &Directory{}
+	_ fs.SetMetadataer   = &Directory{}
 )
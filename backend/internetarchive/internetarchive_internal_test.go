@@ -1,21 +1,38 @@
 package internetarchive
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc32"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	_ "github.com/rclone/rclone/backend/torrent"
+	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/lib/kv"
+	"github.com/rclone/rclone/lib/pacer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-// Test the submitFixerTask function with a mock server
+// Test the submitTask function with a mock server
 func TestSubmitFixerTask(t *testing.T) {
 	// Set up the mock server for the tasks API
 	var receivedPayload map[string]interface{}
@@ -75,10 +92,14 @@ func TestSubmitFixerTask(t *testing.T) {
 	ctx := context.Background()
 	m := configmap.Simple{
 		"type":              "internetarchive",
+		"upload_cutoff":     "200Mi",
+		"chunk_size":        "32Mi",
 		"access_key_id":     "test_key",
 		"secret_access_key": "test_secret",
 		"endpoint":          mockServer.URL,
 		"front_endpoint":    mockServer.URL,
+		"task_cmd":          "fixer.php",
+		"task_args":         `{"noop":"1"}`,
 	}
 
 	// Create a new Fs
@@ -86,7 +107,7 @@ func TestSubmitFixerTask(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test submitting a fixer task
-	err = fsObj.(*Fs).submitFixerNoopTask(ctx, "test_bucket")
+	err = fsObj.(*Fs).submitTask(ctx, "test_bucket")
 	require.NoError(t, err)
 
 	// Verify the task API was called
@@ -103,7 +124,92 @@ func TestSubmitFixerTask(t *testing.T) {
 	assert.Equal(t, "1", args["noop"], "Args should contain noop=1")
 }
 
-// Test that Put calls submitFixerTask
+// Test the task_cmd and task_args options
+func TestSubmitCustomTask(t *testing.T) {
+	var receivedPayload map[string]any
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/services/tasks.php") {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(body, &receivedPayload))
+			w.Header().Set("Content-Type", "application/json")
+			_, err = w.Write([]byte(`{"success":true,"value":{"task_id":42}}`))
+			require.NoError(t, err)
+		}
+	}))
+	defer mockServer.Close()
+
+	ctx := context.Background()
+	m := configmap.Simple{
+		"type":              "internetarchive",
+		"upload_cutoff":     "200Mi",
+		"chunk_size":        "32Mi",
+		"access_key_id":     "test_key",
+		"secret_access_key": "test_secret",
+		"endpoint":          mockServer.URL,
+		"front_endpoint":    mockServer.URL,
+		"task_cmd":          "derive.php",
+		"task_args":         `{"remove_derivatives":"1","formats":["PDF","EPUB"]}`,
+	}
+	fsObj, err := NewFs(ctx, "test", "", m)
+	require.NoError(t, err)
+
+	err = fsObj.(*Fs).submitTask(ctx, "test_bucket")
+	require.NoError(t, err)
+	assert.Equal(t, "test_bucket", receivedPayload["identifier"])
+	assert.Equal(t, "derive.php", receivedPayload["cmd"])
+	assert.Equal(t, map[string]any{
+		"remove_derivatives": "1",
+		"formats":            []any{"PDF", "EPUB"},
+	}, receivedPayload["args"])
+
+	// Invalid args are rejected
+	m["task_args"] = `["not", "an", "object"]`
+	_, err = NewFs(ctx, "test", "", m)
+	assert.ErrorContains(t, err, "task_args must be a JSON object")
+
+	// No args sends an empty object
+	m["task_args"] = ""
+	fsObj, err = NewFs(ctx, "test", "", m)
+	require.NoError(t, err)
+	err = fsObj.(*Fs).submitTask(ctx, "test_bucket")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{}, receivedPayload["args"])
+}
+
+// Test that Put doesn't submit a task if task_cmd is empty
+func TestPutNoTask(t *testing.T) {
+	wasTaskCalled := false
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/services/tasks.php") {
+			wasTaskCalled = true
+		}
+	}))
+	defer mockServer.Close()
+
+	ctx := context.Background()
+	m := configmap.Simple{
+		"type":              "internetarchive",
+		"upload_cutoff":     "200Mi",
+		"chunk_size":        "32Mi",
+		"access_key_id":     "test_key",
+		"secret_access_key": "test_secret",
+		"endpoint":          mockServer.URL,
+		"front_endpoint":    mockServer.URL,
+		"task_cmd":          "",
+	}
+	fsObj, err := NewFs(ctx, "test", "", m)
+	require.NoError(t, err)
+	src := &Object{
+		fs:     fsObj.(*Fs),
+		remote: "test_bucket/test.txt",
+		size:   9,
+	}
+	_, _ = fsObj.Put(ctx, strings.NewReader("test data"), src)
+	assert.False(t, wasTaskCalled, "No task should be submitted when task_cmd is empty")
+}
+
+// Test that Put calls submitTask
 func TestPutCallsFixerTask(t *testing.T) {
 	// Set up the mock server
 	wasTaskCalled := false
@@ -155,10 +261,14 @@ func TestPutCallsFixerTask(t *testing.T) {
 	ctx := context.Background()
 	m := configmap.Simple{
 		"type":              "internetarchive",
+		"upload_cutoff":     "200Mi",
+		"chunk_size":        "32Mi",
 		"access_key_id":     "test_key",
 		"secret_access_key": "test_secret",
 		"endpoint":          mockServer.URL,
 		"front_endpoint":    mockServer.URL,
+		"task_cmd":          "fixer.php",
+		"task_args":         `{"noop":"1"}`,
 	}
 
 	// Create a new Fs
@@ -183,3 +293,1071 @@ func TestPutCallsFixerTask(t *testing.T) {
 	// Verify the task API was called
 	assert.True(t, wasTaskCalled, "The fixer task should have been called during Put")
 }
+
+// Test the tasks backend command
+func TestTasksCommand(t *testing.T) {
+	var calls int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/services/tasks.php") || r.Method != "GET" {
+			return
+		}
+		calls++
+		assert.Equal(t, "test_item", r.URL.Query().Get("identifier"))
+		catalog := `[{"task_id":2,"cmd":"derive.php","args":{"remove_derivatives":"1"},"submittime":"2025-01-02 03:04:05","submitter":"me@example.com","wait_admin":1}]`
+		if calls > 2 {
+			catalog = `[]`
+		}
+		history := `[]`
+		if r.URL.Query().Get("history") == "1" {
+			history = `[{"task_id":1,"cmd":"fixer.php","args":{"noop":"1"},"submittime":"2025-01-01 00:00:00","submitter":"me@example.com"}]`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"success":true,"value":{"summary":{"queued":0,"running":1,"error":0,"paused":0},"catalog":` + catalog + `,"history":` + history + `}}`))
+		require.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	ctx := context.Background()
+	m := configmap.Simple{
+		"type":              "internetarchive",
+		"upload_cutoff":     "200Mi",
+		"chunk_size":        "32Mi",
+		"access_key_id":     "test_key",
+		"secret_access_key": "test_secret",
+		"endpoint":          mockServer.URL,
+		"front_endpoint":    mockServer.URL,
+	}
+	fsObj, err := NewFs(ctx, "test", "test_item", m)
+	require.NoError(t, err)
+	f := fsObj.(*Fs)
+
+	out, err := f.Command(ctx, "tasks", nil, nil)
+	require.NoError(t, err)
+	tasks := out.(*tasksOutput)
+	assert.Equal(t, "test_item", tasks.Item)
+	assert.Equal(t, 1, tasks.Summary["running"])
+	require.Equal(t, 1, len(tasks.Tasks))
+	assert.Equal(t, taskInfo{
+		ID:        2,
+		Cmd:       "derive.php",
+		State:     "running",
+		Submitted: "2025-01-02 03:04:05",
+		Submitter: "me@example.com",
+		Args:      map[string]any{"remove_derivatives": "1"},
+		Log:       "https://catalogd.archive.org/log/2",
+	}, tasks.Tasks[0])
+
+	out, err = f.Command(ctx, "tasks", nil, map[string]string{"history": ""})
+	require.NoError(t, err)
+	tasks = out.(*tasksOutput)
+	require.Equal(t, 2, len(tasks.Tasks))
+	assert.Equal(t, "finished", tasks.Tasks[1].State)
+	assert.Equal(t, int64(1), tasks.Tasks[1].ID)
+
+	// Wait for the running task to finish
+	oldInterval := tasksPollInterval
+	tasksPollInterval = time.Millisecond
+	defer func() { tasksPollInterval = oldInterval }()
+	out, err = f.Command(ctx, "tasks", nil, map[string]string{"wait": "1m"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(out.(*tasksOutput).Tasks))
+	assert.Equal(t, 3, calls)
+
+	_, err = f.Command(ctx, "tasks", nil, map[string]string{"wait": "potato"})
+	assert.ErrorContains(t, err, "bad wait")
+
+	_, err = f.Command(ctx, "potato", nil, nil)
+	assert.Equal(t, fs.ErrorCommandNotFound, err)
+}
+
+// Test that Put waits for the tasks on the item with wait_task
+func TestPutWaitTask(t *testing.T) {
+	var taskListCalls int
+	taskState := `1`
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/services/tasks.php") && r.Method == "GET":
+			taskListCalls++
+			catalog := `[]`
+			if taskListCalls == 1 {
+				catalog = `[{"task_id":7,"cmd":"archive.php","wait_admin":` + taskState + `}]`
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, err := w.Write([]byte(`{"success":true,"value":{"catalog":` + catalog + `}}`))
+			require.NoError(t, err)
+		case strings.HasSuffix(r.URL.Path, "/services/tasks.php"):
+			w.Header().Set("Content-Type", "application/json")
+			_, err := w.Write([]byte(`{"success":true,"value":{"task_id":6}}`))
+			require.NoError(t, err)
+		case strings.HasPrefix(r.URL.Path, "/metadata/"):
+			w.Header().Set("Content-Type", "application/json")
+			_, err := w.Write([]byte(`{"files":[],"item_size":0}`))
+			require.NoError(t, err)
+		}
+	}))
+	defer mockServer.Close()
+
+	oldInterval := tasksPollInterval
+	tasksPollInterval = time.Millisecond
+	defer func() { tasksPollInterval = oldInterval }()
+
+	ctx := context.Background()
+	m := configmap.Simple{
+		"type":              "internetarchive",
+		"upload_cutoff":     "200Mi",
+		"chunk_size":        "32Mi",
+		"access_key_id":     "test_key",
+		"secret_access_key": "test_secret",
+		"endpoint":          mockServer.URL,
+		"front_endpoint":    mockServer.URL,
+		"task_cmd":          "fixer.php",
+		"wait_task":         "1m",
+	}
+	fsObj, err := NewFs(ctx, "test", "", m)
+	require.NoError(t, err)
+	src := &Object{
+		fs:     fsObj.(*Fs),
+		remote: "test_bucket/test.txt",
+		size:   9,
+	}
+	_, err = fsObj.Put(ctx, strings.NewReader("test data"), src)
+	require.NoError(t, err)
+	assert.Equal(t, 2, taskListCalls, "should poll until the running task finished")
+
+	// A failed task fails the upload
+	taskListCalls, taskState = 0, `2`
+	_, err = fsObj.Put(ctx, strings.NewReader("test data"), src)
+	assert.ErrorContains(t, err, "archive.php task 7 on test_bucket failed")
+}
+
+// Test reading and writing the item metadata on the root of an item
+func TestItemMetadata(t *testing.T) {
+	var patches [][]map[string]any
+	metadata := map[string]any{
+		"identifier": "test_meta_item",
+		"title":      "Old title",
+		"subject":    []string{"one", "two"},
+		"collection": "opensource",
+		"notes":      "to be removed",
+	}
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/metadata/test_meta_item") {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == "POST" {
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "metadata", r.PostForm.Get("-target"))
+			var patch []map[string]any
+			require.NoError(t, json.Unmarshal([]byte(r.PostForm.Get("-patch")), &patch))
+			patches = append(patches, patch)
+			for _, op := range patch {
+				key := strings.TrimPrefix(op["path"].(string), "/")
+				if op["op"] == "remove" {
+					delete(metadata, key)
+				} else {
+					metadata[key] = op["value"]
+				}
+			}
+			_, err := w.Write([]byte(`{"success":true}`))
+			require.NoError(t, err)
+			return
+		}
+		response, err := json.Marshal(map[string]any{
+			"files":    []IAFile{},
+			"metadata": metadata,
+		})
+		require.NoError(t, err)
+		_, err = w.Write(response)
+		require.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	ctx := context.Background()
+	m := configmap.Simple{
+		"type":              "internetarchive",
+		"upload_cutoff":     "200Mi",
+		"chunk_size":        "32Mi",
+		"access_key_id":     "test_key",
+		"secret_access_key": "test_secret",
+		"endpoint":          mockServer.URL,
+		"front_endpoint":    mockServer.URL,
+	}
+	fsObj, err := NewFs(ctx, "test", "test_meta_item", m)
+	require.NoError(t, err)
+	f := fsObj.(*Fs)
+	assert.True(t, f.Features().WriteDirMetadata)
+
+	dir, err := f.MkdirMetadata(ctx, "", nil)
+	require.NoError(t, err)
+	got, err := dir.(fs.Metadataer).Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, fs.Metadata{
+		"identifier": "test_meta_item",
+		"title":      "Old title",
+		"subject":    "one;two",
+		"collection": "opensource",
+		"notes":      "to be removed",
+	}, got)
+
+	// Only the changed keys are written
+	err = dir.(fs.SetMetadataer).SetMetadata(ctx, fs.Metadata{
+		"identifier": "new_identifier",
+		"mtime":      "2025-01-02T03:04:05Z",
+		"Title":      "New title",
+		"subject":    "one; two; three",
+		"collection": "opensource",
+		"notes":      "",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(patches))
+	assert.Equal(t, []map[string]any{
+		{"op": "add", "path": "/title", "value": "New title"},
+		{"op": "remove", "path": "/notes"},
+		{"op": "add", "path": "/subject", "value": []any{"one", "two", "three"}},
+	}, patches[0])
+
+	// The cache is refreshed after writing
+	out, err := f.Command(ctx, "metadata", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, fs.Metadata{
+		"identifier": "test_meta_item",
+		"title":      "New title",
+		"subject":    "one;two;three",
+		"collection": "opensource",
+	}, out)
+
+	// Nothing is written if nothing has changed
+	_, err = f.Command(ctx, "metadata", nil, map[string]string{"title": "New title"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(patches))
+
+	// Directories other than the root of the item don't have metadata
+	dir, err = f.MkdirMetadata(ctx, "dir", fs.Metadata{"title": "Ignored"})
+	require.NoError(t, err)
+	got, err = dir.(fs.Metadataer).Metadata(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	assert.Equal(t, 1, len(patches))
+}
+
+// Test that Mkdir makes new items with the item options
+func TestMkdirMakesItem(t *testing.T) {
+	var made []http.Header
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/test_new_item":
+			made = append(made, r.Header)
+		case r.Method == "GET" && r.URL.Path == "/metadata/test_new_item":
+			// IA returns an empty object for items which don't exist
+			w.Header().Set("Content-Type", "application/json")
+			_, err := w.Write([]byte(`{}`))
+			require.NoError(t, err)
+		}
+	}))
+	defer mockServer.Close()
+
+	ctx := context.Background()
+	m := configmap.Simple{
+		"type":              "internetarchive",
+		"upload_cutoff":     "200Mi",
+		"chunk_size":        "32Mi",
+		"access_key_id":     "test_key",
+		"secret_access_key": "test_secret",
+		"endpoint":          mockServer.URL,
+		"front_endpoint":    mockServer.URL,
+		"item_metadata":     "title=My item",
+		"item_collection":   "test_collection",
+		"item_mediatype":    "data",
+		"item_noindex":      "true",
+	}
+	fsObj, err := NewFs(ctx, "test", "test_new_item", m)
+	require.NoError(t, err)
+	f := fsObj.(*Fs)
+
+	// Directories in items aren't made
+	require.NoError(t, f.Mkdir(ctx, "dir"))
+	assert.Equal(t, 0, len(made))
+
+	require.NoError(t, f.Mkdir(ctx, ""))
+	require.Equal(t, 1, len(made))
+	assert.Equal(t, "1", made[0].Get("x-archive-auto-make-bucket"))
+	assert.Equal(t, "My item", made[0].Get("x-archive-meta-title"))
+	assert.Equal(t, "test_collection", made[0].Get("x-archive-meta-collection"))
+	assert.Equal(t, "data", made[0].Get("x-archive-meta-mediatype"))
+	assert.Equal(t, "true", made[0].Get("x-archive-meta-noindex"))
+
+	// Metadata overrides the options
+	_, err = f.MkdirMetadata(ctx, "", fs.Metadata{
+		"collection": "one;two",
+		"mtime":      "2025-01-02T03:04:05Z",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(made))
+	assert.Equal(t, "", made[1].Get("x-archive-meta-collection"))
+	assert.Equal(t, "one", made[1].Get("x-archive-meta01-collection"))
+	assert.Equal(t, "two", made[1].Get("x-archive-meta02-collection"))
+	assert.Equal(t, "", made[1].Get("x-archive-meta-mtime"))
+	assert.Equal(t, "My item", made[1].Get("x-archive-meta-title"))
+}
+
+// Test server-side copy between items
+func TestCopyBetweenItems(t *testing.T) {
+	var copyHeaders http.Header
+	var taskItem string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/services/tasks.php":
+			var payload map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			taskItem, _ = payload["identifier"].(string)
+			_, err := w.Write([]byte(`{"success":true,"value":{"task_id":42}}`))
+			require.NoError(t, err)
+		case r.Method == "PUT" && r.URL.Path == "/copy_item_b/dir/file.txt":
+			copyHeaders = r.Header
+		case r.URL.Path == "/metadata/copy_item_a":
+			_, err := w.Write([]byte(`{"metadata":{"identifier":"copy_item_a"},"files":[{"name":"file.txt","size":"9","md5":"eb733a00c0c9d336e65691a37ab54293","rclone-mtime":"2025-01-02T03:04:05Z"}]}`))
+			require.NoError(t, err)
+		case r.URL.Path == "/metadata/copy_item_b":
+			files := `[]`
+			if copyHeaders != nil {
+				files = `[{"name":"dir/file.txt","size":"9","md5":"eb733a00c0c9d336e65691a37ab54293","rclone-mtime":"2025-01-02T03:04:05Z"}]`
+			}
+			_, err := w.Write([]byte(`{"files":` + files + `}`))
+			require.NoError(t, err)
+		}
+	}))
+	defer mockServer.Close()
+
+	ctx := context.Background()
+	m := configmap.Simple{
+		"type":              "internetarchive",
+		"upload_cutoff":     "200Mi",
+		"chunk_size":        "32Mi",
+		"access_key_id":     "test_key",
+		"secret_access_key": "test_secret",
+		"endpoint":          mockServer.URL,
+		"front_endpoint":    mockServer.URL,
+		"task_cmd":          "fixer.php",
+		"task_args":         `{"noop":"1"}`,
+		"item_collection":   "test_collection",
+		"item_derive":       "true",
+	}
+	fsObj, err := NewFs(ctx, "test", "", m)
+	require.NoError(t, err)
+	f := fsObj.(*Fs)
+
+	// List the destination so its metadata is cached before the copy
+	_, err = f.List(ctx, "copy_item_b")
+	require.NoError(t, err)
+
+	src, err := f.NewObject(ctx, "copy_item_a/file.txt")
+	require.NoError(t, err)
+	dst, err := f.Copy(ctx, src, "copy_item_b/dir/file.txt")
+	require.NoError(t, err)
+
+	require.NotNil(t, copyHeaders)
+	assert.Equal(t, "/copy_item_a/file.txt", copyHeaders.Get("x-amz-copy-source"))
+	assert.Equal(t, "1", copyHeaders.Get("x-amz-auto-make-bucket"))
+	assert.Equal(t, "test_collection", copyHeaders.Get("x-archive-meta-collection"))
+	assert.Equal(t, "0", copyHeaders.Get("x-archive-queue-derive"))
+	assert.Equal(t, "copy_item_b", taskItem)
+
+	// The copy is found in the fresh metadata of the destination
+	assert.Equal(t, "copy_item_b/dir/file.txt", dst.Remote())
+	assert.Equal(t, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), dst.ModTime(ctx).UTC())
+}
+
+// Test files are downloaded with the torrent of their item
+func TestDownloadTorrent(t *testing.T) {
+	files := map[string]string{
+		"big.bin":   "hello world",
+		"stale.bin": "potato",
+		"small.txt": "hi",
+	}
+	var torrentFetches, webseedCalls, httpCalls atomic.Int32
+	var mockServer *httptest.Server
+	mockServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/metadata/torrent_item":
+			var iaFiles []string
+			for name, contents := range files {
+				iaFiles = append(iaFiles, fmt.Sprintf(`{"name":%q,"size":"%d","md5":"%x"}`, name, len(contents), md5.Sum([]byte(contents))))
+			}
+			_, err := w.Write([]byte(`{"metadata":{"identifier":"torrent_item"},"files":[` + strings.Join(iaFiles, ",") + `]}`))
+			require.NoError(t, err)
+		case r.URL.Path == "/download/torrent_item/torrent_item_archive.torrent":
+			torrentFetches.Add(1)
+			// The torrent has an old version of stale.bin
+			var torrentFiles string
+			for _, name := range []string{"big.bin", "stale.bin"} {
+				contents := files[name]
+				if name == "stale.bin" {
+					contents = "tomato"
+				}
+				torrentFiles += fmt.Sprintf("d6:lengthi%de3:md532:%x4:pathl%d:%see", len(contents), md5.Sum([]byte(contents)), len(name), name)
+			}
+			webseed := mockServer.URL + "/webseed/"
+			_, err := fmt.Fprintf(w, "d4:infod5:filesl%se4:name12:torrent_item12:piece lengthi16384e6:pieces0:e8:url-list%d:%se", torrentFiles, len(webseed), webseed)
+			require.NoError(t, err)
+		default:
+			name, ok := strings.CutPrefix(r.URL.Path, "/webseed/torrent_item/")
+			if ok {
+				webseedCalls.Add(1)
+			} else if name, ok = strings.CutPrefix(r.URL.Path, "/download/torrent_item/"); ok {
+				httpCalls.Add(1)
+			}
+			contents, found := files[name]
+			if !found {
+				http.NotFound(w, r)
+				return
+			}
+			http.ServeContent(w, r, name, time.Time{}, strings.NewReader(contents))
+		}
+	}))
+	defer mockServer.Close()
+
+	ctx := context.Background()
+	m := configmap.Simple{
+		"type":             "internetarchive",
+		"upload_cutoff":    "200Mi",
+		"chunk_size":       "32Mi",
+		"endpoint":         mockServer.URL,
+		"front_endpoint":   mockServer.URL,
+		"download_torrent": "true",
+		"torrent_cutoff":   "5B",
+		"torrent_peers":    "0",
+	}
+	fsObj, err := NewFs(ctx, "test", "torrent_item", m)
+	require.NoError(t, err)
+
+	read := func(remote string, options ...fs.OpenOption) string {
+		o, err := fsObj.NewObject(ctx, remote)
+		require.NoError(t, err)
+		in, err := o.Open(ctx, options...)
+		require.NoError(t, err)
+		got, err := io.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		return string(got)
+	}
+
+	// Big files in the torrent are read from it
+	assert.Equal(t, "hello world", read("big.bin"))
+	assert.Equal(t, "world", read("big.bin", &fs.RangeOption{Start: 6, End: -1}))
+	assert.Equal(t, int32(2), webseedCalls.Load())
+	assert.Equal(t, int32(0), httpCalls.Load())
+
+	// Files which don't match the torrent and small files aren't
+	assert.Equal(t, "potato", read("stale.bin"))
+	assert.Equal(t, "hi", read("small.txt"))
+	assert.Equal(t, int32(2), webseedCalls.Load())
+	assert.Equal(t, int32(2), httpCalls.Load())
+	assert.Equal(t, int32(1), torrentFetches.Load())
+}
+
+// Test only items which definitely have no torrent are remembered
+func TestItemTorrentErrors(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		status = map[string]int{"missing": http.StatusNotFound, "flaky": http.StatusBadGateway}
+		gets   = map[string]int{}
+	)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		item, ok := strings.CutPrefix(r.URL.Path, "/download/")
+		item, _, _ = strings.Cut(item, "/")
+		if !ok || r.URL.Path != "/download/"+item+"/"+item+"_archive.torrent" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == "GET" {
+			gets[item]++
+		}
+		if status[item] != http.StatusOK {
+			http.Error(w, "torrent error", status[item])
+			return
+		}
+		_, _ = fmt.Fprintf(w, "d4:infod5:filesld6:lengthi5e4:pathl8:file.bineee4:name%d:%s12:piece lengthi16384e6:pieces0:ee", len(item), item)
+	}))
+	defer mockServer.Close()
+
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.LowLevelRetries = 1
+	m := configmap.Simple{
+		"type":           "internetarchive",
+		"upload_cutoff":  "200Mi",
+		"chunk_size":     "32Mi",
+		"endpoint":       mockServer.URL,
+		"front_endpoint": mockServer.URL,
+		"torrent_peers":  "0",
+	}
+	f, err := NewFs(ctx, "test", "", m)
+	require.NoError(t, err)
+	iaFs := f.(*Fs)
+
+	// An item without a torrent is remembered
+	_, err = iaFs.itemTorrent(ctx, "missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has no torrent")
+	_, err = iaFs.itemTorrent(ctx, "missing")
+	require.Error(t, err)
+	mu.Lock()
+	assert.Equal(t, 1, gets["missing"])
+	mu.Unlock()
+
+	// Other errors aren't so the torrent is read when it works
+	_, err = iaFs.itemTorrent(ctx, "flaky")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "has no torrent")
+	mu.Lock()
+	status["flaky"] = http.StatusOK
+	mu.Unlock()
+	torrentFs, err := iaFs.itemTorrent(ctx, "flaky")
+	require.NoError(t, err)
+	_, err = torrentFs.NewObject(ctx, "file.bin")
+	assert.NoError(t, err)
+	_, err = iaFs.itemTorrent(ctx, "flaky")
+	require.NoError(t, err)
+	mu.Lock()
+	assert.Equal(t, 2, gets["flaky"])
+	mu.Unlock()
+}
+
+// fakeMultipart is an IAS3 server supporting multipart uploads of
+// test_item/big.bin
+type fakeMultipart struct {
+	mu        sync.Mutex
+	parts     map[int][]byte // uploaded parts by part number
+	resumeID  string         // ID of an unfinished upload to list if set
+	headers   http.Header    // headers the upload was started with
+	puts      []int          // part numbers uploaded
+	failPart  int            // part number to fail uploading if set
+	completed []byte         // the file once completed
+	aborted   bool           // set if the upload was aborted
+}
+
+func (s *fakeMultipart) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	query := r.URL.Query()
+	switch {
+	case r.URL.Path == "/metadata/test_item":
+		_, _ = w.Write([]byte(`{"files":[]}`))
+	case r.URL.Path == "/test_item" && query.Has("uploads"):
+		if s.resumeID == "" || query.Get("prefix") != "big.bin" {
+			_, _ = w.Write([]byte(`<ListMultipartUploadsResult></ListMultipartUploadsResult>`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `<ListMultipartUploadsResult><Upload><Key>other.bin</Key><UploadId>other</UploadId></Upload><Upload><Key>big.bin</Key><UploadId>%s</UploadId></Upload></ListMultipartUploadsResult>`, s.resumeID)
+	case r.URL.Path != "/test_item/big.bin":
+		http.NotFound(w, r)
+	case r.Method == "POST" && query.Has("uploads"):
+		s.headers = r.Header
+		_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><UploadId>new-id</UploadId></InitiateMultipartUploadResult>`))
+	case r.Method == "GET":
+		var list strings.Builder
+		for partNumber, data := range s.parts {
+			fmt.Fprintf(&list, `<Part><PartNumber>%d</PartNumber><ETag>"%x"</ETag><Size>%d</Size></Part>`, partNumber, md5.Sum(data), len(data))
+		}
+		_, _ = fmt.Fprintf(w, `<ListPartsResult><IsTruncated>false</IsTruncated>%s</ListPartsResult>`, list.String())
+	case r.Method == "PUT":
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		data, _ := io.ReadAll(r.Body)
+		sum := md5.Sum(data)
+		if partNumber == s.failPart || r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+			http.Error(w, "bad part", http.StatusBadRequest)
+			return
+		}
+		s.puts = append(s.puts, partNumber)
+		s.parts[partNumber] = data
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sum))
+	case r.Method == "POST":
+		var complete CompleteMultipartUpload
+		_ = xml.NewDecoder(r.Body).Decode(&complete)
+		s.completed = nil
+		for i, part := range complete.Parts {
+			data := s.parts[part.PartNumber]
+			if part.PartNumber != i+1 || part.ETag != fmt.Sprintf(`"%x"`, md5.Sum(data)) {
+				_, _ = w.Write([]byte(`<Error><Code>InvalidPart</Code><Message>bad part</Message></Error>`))
+				return
+			}
+			s.completed = append(s.completed, data...)
+		}
+		_, _ = w.Write([]byte(`<CompleteMultipartUploadResult><ETag>"done"</ETag></CompleteMultipartUploadResult>`))
+	case r.Method == "DELETE":
+		s.aborted = true
+	}
+}
+
+func TestMultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	data := []byte(strings.Repeat("0123456789", 1200000)) // 3 parts of 5 MiB
+	modTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	src := object.NewStaticObjectInfo("test_item/big.bin", modTime, int64(len(data)), true, nil, nil)
+
+	// upload data to the fake server returning the error, with the
+	// upload IDs in trackers recorded as started with those trackers
+	upload := func(s *fakeMultipart, leavePartsOnError bool, trackers map[string]string) error {
+		mockServer := httptest.NewServer(s)
+		defer mockServer.Close()
+		m := configmap.Simple{
+			"type":                 "internetarchive",
+			"access_key_id":        "test_key",
+			"secret_access_key":    "test_secret",
+			"endpoint":             mockServer.URL,
+			"front_endpoint":       mockServer.URL,
+			"upload_cutoff":        "5Mi",
+			"chunk_size":           "5Mi",
+			"upload_concurrency":   "2",
+			"leave_parts_on_error": fmt.Sprint(leavePartsOnError),
+		}
+		f, err := NewFs(ctx, "test", "", m)
+		require.NoError(t, err)
+		assert.Nil(t, f.Features().OpenChunkWriter)
+		// hold the database open so it isn't dropped between uses
+		db, err := kv.Start(ctx, uploadsFacility, f)
+		require.NoError(t, err)
+		defer func() { _ = db.Stop(false) }()
+		for uploadID, tracker := range trackers {
+			f.(*Fs).setUploadTracker(ctx, uploadID, tracker)
+		}
+		_, err = f.Put(ctx, bytes.NewReader(data), src)
+		return err
+	}
+
+	t.Run("Upload", func(t *testing.T) {
+		s := &fakeMultipart{parts: map[int][]byte{}}
+		require.NoError(t, upload(s, false, nil))
+		assert.Equal(t, data, s.completed)
+		assert.ElementsMatch(t, []int{1, 2, 3}, s.puts)
+		assert.Equal(t, modTime.Format(time.RFC3339Nano), s.headers.Get("x-amz-filemeta-rclone-mtime"))
+		assert.Equal(t, multipartTracker(ctx, "test_item/big.bin", src), s.headers.Get("x-amz-filemeta-rclone-update-track"))
+		assert.Equal(t, fmt.Sprint(len(data)), s.headers.Get("x-archive-size-hint"))
+		assert.False(t, s.aborted)
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		s := &fakeMultipart{parts: map[int][]byte{}, failPart: 2}
+		require.Error(t, upload(s, false, nil))
+		assert.True(t, s.aborted)
+	})
+
+	t.Run("LeavePartsOnError", func(t *testing.T) {
+		s := &fakeMultipart{parts: map[int][]byte{}, failPart: 2}
+		require.Error(t, upload(s, true, nil))
+		assert.False(t, s.aborted)
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		// Part 1 has been uploaded and part 2 has the wrong data
+		s := &fakeMultipart{
+			parts: map[int][]byte{
+				1: data[:5*1024*1024],
+				2: []byte("potato"),
+			},
+			resumeID: "old-id",
+		}
+		require.NoError(t, upload(s, false, map[string]string{
+			"old-id": multipartTracker(ctx, "test_item/big.bin", src),
+		}))
+		assert.Nil(t, s.headers, "shouldn't start a new upload")
+		assert.ElementsMatch(t, []int{2, 3}, s.puts)
+		assert.Equal(t, data, s.completed)
+		assert.False(t, s.aborted)
+	})
+
+	// Unfinished uploads of other sources must be aborted and not
+	// resumed as they have the wrong modtime and metadata
+	for name, trackers := range map[string]map[string]string{
+		"ResumeOtherSource": {"old-id": "other-tracker"},
+		"ResumeUnknown":     nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := &fakeMultipart{
+				parts:    map[int][]byte{1: data[:5*1024*1024]},
+				resumeID: "old-id",
+			}
+			require.NoError(t, upload(s, false, trackers))
+			assert.True(t, s.aborted)
+			assert.NotNil(t, s.headers, "should start a new upload")
+			assert.ElementsMatch(t, []int{1, 2, 3}, s.puts)
+			assert.Equal(t, data, s.completed)
+		})
+	}
+}
+
+func TestShouldRetryRetryAfter(t *testing.T) {
+	f := &Fs{}
+	errSlowDown := errors.New(`HTTP error 503 (503 Service Unavailable) returned body: "<Error><Code>SlowDown</Code></Error>"`)
+	for _, test := range []struct {
+		status     int
+		retryAfter string
+		err        error
+		wantRetry  bool
+		wantWait   time.Duration
+	}{
+		{http.StatusServiceUnavailable, "120", errSlowDown, true, 120 * time.Second},
+		{http.StatusTooManyRequests, "5", errors.New("too many"), true, 5 * time.Second},
+		{http.StatusServiceUnavailable, "", errSlowDown, true, slowDownSleep},
+		{http.StatusServiceUnavailable, "", errors.New("unavailable"), true, 0},
+		{http.StatusServiceUnavailable, "soon", errors.New("unavailable"), true, 0},
+		{http.StatusInternalServerError, "10", errors.New("internal"), true, 0},
+		{http.StatusNotFound, "", errors.New("not found"), false, 0},
+	} {
+		resp := &http.Response{StatusCode: test.status, Header: http.Header{}}
+		if test.retryAfter != "" {
+			resp.Header.Set("Retry-After", test.retryAfter)
+		}
+		retry, err := f.shouldRetry(resp, test.err)
+		assert.Equal(t, test.wantRetry, retry, test)
+		wait, isRetryAfter := pacer.IsRetryAfter(err)
+		assert.Equal(t, test.wantWait != 0, isRetryAfter, test)
+		assert.Equal(t, test.wantWait, wait, test)
+		assert.Equal(t, test.err.Error(), err.Error(), test)
+	}
+
+	// An HTTP date is used too
+	wait := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.InDelta(t, float64(time.Hour), float64(wait), float64(2*time.Second))
+}
+
+// Test uploads wait for the queued tasks to drop below max_tasks_queued
+func TestMaxTasksQueued(t *testing.T) {
+	var taskListCalls, taskSubmitCalls int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/services/tasks.php" && r.Method == "GET":
+			taskListCalls++
+			assert.Equal(t, 0, taskSubmitCalls, "shouldn't submit a task while paused")
+			queued := max(4-taskListCalls, 0)
+			_, err := fmt.Fprintf(w, `{"success":true,"value":{"summary":{"queued":%d,"running":1},"catalog":[]}}`, queued)
+			require.NoError(t, err)
+		case r.URL.Path == "/services/tasks.php":
+			taskSubmitCalls++
+			_, err := w.Write([]byte(`{"success":true,"value":{"task_id":6}}`))
+			require.NoError(t, err)
+		case strings.HasPrefix(r.URL.Path, "/metadata/"):
+			_, err := w.Write([]byte(`{"files":[]}`))
+			require.NoError(t, err)
+		}
+	}))
+	defer mockServer.Close()
+
+	oldInterval := tasksPollInterval
+	tasksPollInterval = time.Millisecond
+	defer func() { tasksPollInterval = oldInterval }()
+
+	ctx := context.Background()
+	m := configmap.Simple{
+		"type":              "internetarchive",
+		"upload_cutoff":     "200Mi",
+		"chunk_size":        "32Mi",
+		"access_key_id":     "test_key",
+		"secret_access_key": "test_secret",
+		"endpoint":          mockServer.URL,
+		"front_endpoint":    mockServer.URL,
+		"task_cmd":          "fixer.php",
+		"max_tasks_queued":  "2",
+	}
+	fsObj, err := NewFs(ctx, "test", "", m)
+	require.NoError(t, err)
+	src := &Object{
+		fs:     fsObj.(*Fs),
+		remote: "test_bucket/test.txt",
+		size:   9,
+	}
+	_, err = fsObj.Put(ctx, strings.NewReader("test data"), src)
+	require.NoError(t, err)
+	assert.Equal(t, 3, taskListCalls, "should poll until fewer than 2 tasks are queued")
+	assert.Equal(t, 1, taskSubmitCalls)
+
+	// The wait can be cancelled
+	taskListCalls = -100
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	taskSubmitCalls = 0
+	_, err = fsObj.Put(ctx, strings.NewReader("test data"), src)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// Test derivative files are shown read only in derivativesDir
+func TestShowDerivatives(t *testing.T) {
+	files := map[string]string{
+		"book.pdf":      "original",
+		"book.epub":     "derivative",
+		"book_djvu.txt": "derivative",
+	}
+	var writes atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			writes.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		switch {
+		case r.URL.Path == "/metadata/deriv_item":
+			var iaFiles []string
+			for name, source := range files {
+				iaFiles = append(iaFiles, fmt.Sprintf(`{"name":%q,"source":%q,"size":"%d"}`, name, source, len(name)))
+			}
+			_, err := w.Write([]byte(`{"metadata":{"identifier":"deriv_item"},"files":[` + strings.Join(iaFiles, ",") + `]}`))
+			require.NoError(t, err)
+		default:
+			name, ok := strings.CutPrefix(r.URL.Path, "/download/deriv_item/")
+			if _, found := files[name]; !ok || !found {
+				http.NotFound(w, r)
+				return
+			}
+			http.ServeContent(w, r, name, time.Time{}, strings.NewReader(name))
+		}
+	}))
+	defer mockServer.Close()
+
+	ctx := context.Background()
+	m := configmap.Simple{
+		"type":             "internetarchive",
+		"upload_cutoff":    "200Mi",
+		"chunk_size":       "32Mi",
+		"endpoint":         mockServer.URL,
+		"front_endpoint":   mockServer.URL,
+		"show_derivatives": "true",
+	}
+	fsObj, err := NewFs(ctx, "test", "deriv_item", m)
+	require.NoError(t, err)
+
+	names := func(dir string) (names []string) {
+		entries, err := fsObj.List(ctx, dir)
+		require.NoError(t, err)
+		for _, entry := range entries {
+			names = append(names, entry.Remote())
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"book.pdf", ".derivatives"}, names(""))
+	assert.ElementsMatch(t, []string{".derivatives/book.epub", ".derivatives/book_djvu.txt"}, names(".derivatives"))
+
+	// Derivative files are read from their real paths
+	o, err := fsObj.NewObject(ctx, ".derivatives/book.epub")
+	require.NoError(t, err)
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	got, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "book.epub", string(got))
+
+	// But can't be changed
+	assert.ErrorIs(t, o.Remove(ctx), fs.ErrorPermissionDenied)
+	assert.ErrorIs(t, o.SetModTime(ctx, time.Now()), fs.ErrorPermissionDenied)
+	src := object.NewStaticObjectInfo(".derivatives/book.epub", time.Now(), 3, true, nil, nil)
+	assert.ErrorIs(t, o.Update(ctx, strings.NewReader("new"), src), fs.ErrorPermissionDenied)
+	src = object.NewStaticObjectInfo(".derivatives/new.txt", time.Now(), 3, true, nil, nil)
+	_, err = fsObj.Put(ctx, strings.NewReader("new"), src)
+	assert.ErrorIs(t, err, fs.ErrorPermissionDenied)
+	assert.Equal(t, int32(0), writes.Load())
+}
+
+// Test listing the items of a collection with the scrape API
+func TestListCollection(t *testing.T) {
+	pages := map[string]string{
+		"":     `{"items":[{"identifier":"item1","publicdate":"2020-01-02T03:04:05Z"},{"identifier":"item2"}],"count":2,"total":3,"cursor":"next"}`,
+		"next": `{"items":[{"identifier":"item3"}],"count":1,"total":3}`,
+	}
+	var scrapes atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/search/v1/scrape" {
+			scrapes.Add(1)
+			assert.Equal(t, `collection:"test_collection"`, r.URL.Query().Get("q"))
+			assert.Equal(t, "10000", r.URL.Query().Get("count"))
+			page, ok := pages[r.URL.Query().Get("cursor")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, err := w.Write([]byte(page))
+			require.NoError(t, err)
+			return
+		}
+		item, ok := strings.CutPrefix(r.URL.Path, "/metadata/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, err := fmt.Fprintf(w, `{"metadata":{"identifier":%q},"files":[{"name":"dir/%s.txt","size":"1"}]}`, item, item)
+		require.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	ctx := context.Background()
+	m := configmap.Simple{
+		"type":            "internetarchive",
+		"upload_cutoff":   "200Mi",
+		"chunk_size":      "32Mi",
+		"endpoint":        mockServer.URL,
+		"front_endpoint":  mockServer.URL,
+		"list_collection": "test_collection",
+	}
+	fsObj, err := NewFs(ctx, "test", "", m)
+	require.NoError(t, err)
+
+	entries, err := fsObj.List(ctx, "")
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+	}
+	assert.Equal(t, []string{"item1", "item2", "item3"}, names)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), entries[0].ModTime(ctx))
+	assert.Equal(t, int32(2), scrapes.Load())
+
+	var mu sync.Mutex
+	names = nil
+	err = fsObj.Features().ListR(ctx, "", func(entries fs.DirEntries) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, entry := range entries {
+			names = append(names, entry.Remote())
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"item1", "item1/dir", "item1/dir/item1.txt",
+		"item2", "item2/dir", "item2/dir/item2.txt",
+		"item3", "item3/dir", "item3/dir/item3.txt",
+	}, names)
+	assert.Equal(t, int32(4), scrapes.Load())
+
+	// Without list_collection nothing is listed in the root
+	m["list_collection"] = ""
+	fsObj, err = NewFs(ctx, "test", "", m)
+	require.NoError(t, err)
+	entries, err = fsObj.List(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// Test checking the checksums of uploads
+func TestVerifyAfterUpload(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		data    []byte
+		tracker string
+		badMD5  bool
+	)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/test_bucket/test.txt":
+			var err error
+			data, err = io.ReadAll(r.Body)
+			require.NoError(t, err)
+			tracker = r.Header.Get("x-amz-filemeta-rclone-update-track")
+		case r.URL.Path == "/metadata/test_bucket":
+			md5sum := fmt.Sprintf("%x", md5.Sum(data))
+			if badMD5 {
+				md5sum = fmt.Sprintf("%x", md5.Sum([]byte("potato")))
+			}
+			_, err := fmt.Fprintf(w, `{"files":[{"name":"test.txt","size":"%d","md5":%q,"sha1":"%x","crc32":"%08x","rclone-update-track":%q}]}`,
+				len(data), md5sum, sha1.Sum(data), crc32.ChecksumIEEE(data), tracker)
+			require.NoError(t, err)
+		case strings.HasSuffix(r.URL.Path, "/services/tasks.php"):
+			_, err := w.Write([]byte(`{"success":true,"value":{"task_id":6}}`))
+			require.NoError(t, err)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer mockServer.Close()
+
+	ctx := context.Background()
+	m := configmap.Simple{
+		"type":                "internetarchive",
+		"upload_cutoff":       "200Mi",
+		"chunk_size":          "32Mi",
+		"endpoint":            mockServer.URL,
+		"front_endpoint":      mockServer.URL,
+		"verify_after_upload": "true",
+	}
+	_, err := NewFs(ctx, "test", "", m)
+	assert.ErrorContains(t, err, "verify_after_upload needs wait_archive")
+
+	m["wait_archive"] = "1m"
+	fsObj, err := NewFs(ctx, "test", "", m)
+	require.NoError(t, err)
+	src := object.NewStaticObjectInfo("test_bucket/test.txt", time.Now(), 9, true, nil, nil)
+	o, err := fsObj.Put(ctx, strings.NewReader("test data"), src)
+	require.NoError(t, err)
+	sha1sum, err := o.Hash(ctx, hash.SHA1)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%x", sha1.Sum([]byte("test data"))), sha1sum)
+
+	// Damaged uploads fail
+	mu.Lock()
+	badMD5 = true
+	mu.Unlock()
+	_, err = fsObj.Put(ctx, strings.NewReader("test data"), src)
+	assert.ErrorContains(t, err, "corrupted on transfer: md5 checksums differ")
+}
+
+// Test remotes with a root of collection/<name>
+func TestCollectionRoot(t *testing.T) {
+	var made atomic.Value
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/services/search/v1/scrape":
+			assert.Equal(t, `collection:"test_collection"`, r.URL.Query().Get("q"))
+			_, err := w.Write([]byte(`{"items":[{"identifier":"coll_item1"},{"identifier":"coll_item2"}],"count":2,"total":2}`))
+			require.NoError(t, err)
+		case r.URL.Path == "/metadata/coll_item1":
+			_, err := w.Write([]byte(`{"metadata":{"identifier":"coll_item1"},"files":[{"name":"file.txt","size":"1"}]}`))
+			require.NoError(t, err)
+		case r.URL.Path == "/metadata/coll_item2" || r.URL.Path == "/metadata/coll_new_item":
+			_, err := w.Write([]byte(`{}`))
+			require.NoError(t, err)
+		case r.Method == "PUT" && r.URL.Path == "/coll_new_item":
+			made.Store(r.Header.Get("x-archive-meta-collection"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer mockServer.Close()
+
+	ctx := context.Background()
+	m := configmap.Simple{
+		"type":           "internetarchive",
+		"upload_cutoff":  "200Mi",
+		"chunk_size":     "32Mi",
+		"endpoint":       mockServer.URL,
+		"front_endpoint": mockServer.URL,
+	}
+	names := func(f fs.Fs, dir string) (names []string) {
+		entries, err := f.List(ctx, dir)
+		require.NoError(t, err)
+		for _, entry := range entries {
+			names = append(names, entry.Remote())
+		}
+		return names
+	}
+
+	f, err := NewFs(ctx, "test", "collection/test_collection/", m)
+	require.NoError(t, err)
+	assert.Equal(t, "collection/test_collection", f.Root())
+	assert.Equal(t, "Internet Archive collection test_collection", f.String())
+	assert.Equal(t, []string{"coll_item1", "coll_item2"}, names(f, ""))
+	assert.Equal(t, []string{"coll_item1/file.txt"}, names(f, "coll_item1"))
+
+	// New items are made in the collection
+	require.NoError(t, f.Mkdir(ctx, "coll_new_item"))
+	assert.Equal(t, "test_collection", made.Load())
+
+	f, err = NewFs(ctx, "test", "collection/test_collection/coll_item1", m)
+	require.NoError(t, err)
+	assert.Equal(t, "collection/test_collection/coll_item1", f.Root())
+	assert.Equal(t, []string{"file.txt"}, names(f, ""))
+}
@@ -0,0 +1,455 @@
+package internetarchive
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/chunksize"
+	"github.com/rclone/rclone/lib/kv"
+	"github.com/rclone/rclone/lib/pool"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minChunkSize        = 5 * fs.Mebi // smallest part IAS3 accepts apart from the last
+	defaultChunkSize    = 32 * fs.Mebi
+	defaultUploadCutoff = 200 * fs.Mebi
+	maxUploadCutoff     = 5 * fs.Gibi
+	maxUploadParts      = 10000 // maximum number of parts in a multipart upload
+	uploadsFacility     = "internetarchive-uploads"
+)
+
+// InitiateMultipartUploadResult is the response to starting a
+// multipart upload
+type InitiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+// ListMultipartUploadsResult is the response to listing the unfinished
+// multipart uploads of an item
+type ListMultipartUploadsResult struct {
+	Uploads []struct {
+		Key       string    `xml:"Key"`
+		UploadID  string    `xml:"UploadId"`
+		Initiated time.Time `xml:"Initiated"`
+	} `xml:"Upload"`
+}
+
+// ListPartsResult is the response to listing the parts of a multipart
+// upload
+type ListPartsResult struct {
+	IsTruncated          bool         `xml:"IsTruncated"`
+	NextPartNumberMarker int          `xml:"NextPartNumberMarker"`
+	Parts                []UploadPart `xml:"Part"`
+}
+
+// UploadPart is a part of a multipart upload
+type UploadPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+	Size       int64  `xml:"Size,omitempty"`
+}
+
+// CompleteMultipartUpload is the request to finish a multipart upload
+type CompleteMultipartUpload struct {
+	XMLName xml.Name     `xml:"CompleteMultipartUpload"`
+	Parts   []UploadPart `xml:"Part"`
+}
+
+// CompleteMultipartUploadResult is the response to finishing a
+// multipart upload
+//
+// IAS3 can return an error in the body with a 200 status, in which
+// case Code and Message are set.
+type CompleteMultipartUploadResult struct {
+	ETag    string `xml:"ETag"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func checkUploadChunkSize(cs fs.SizeSuffix) error {
+	if cs < minChunkSize {
+		return fmt.Errorf("%s is less than %s", cs, minChunkSize)
+	}
+	return nil
+}
+
+func checkUploadCutoff(cs fs.SizeSuffix) error {
+	if cs > maxUploadCutoff {
+		return fmt.Errorf("%s is greater than %s", cs, maxUploadCutoff)
+	}
+	return nil
+}
+
+// iaChunkWriter uploads a file to IAS3 with a multipart upload
+type iaChunkWriter struct {
+	f             *Fs
+	o             *Object
+	path          string             // escaped path of the file on IAS3
+	uploadID      string             // ID of the multipart upload
+	updateTracker string             // tracker set in the headers of the upload
+	uploaded      map[int]UploadPart // parts already uploaded if resuming
+	partsMu       sync.Mutex         // protects parts
+	parts         []UploadPart       // parts uploaded so far
+}
+
+// multipartTracker returns the update tracker for a multipart upload
+// of src to remote
+//
+// This is the same for uploads of the same file so an upload which is
+// resumed has the tracker it was started with.
+func multipartTracker(ctx context.Context, remote string, src fs.ObjectInfo) string {
+	sum := md5.Sum(fmt.Appendf(nil, "%s\x00%d\x00%d", remote, src.Size(), src.ModTime(ctx).UnixNano()))
+	return hex.EncodeToString(sum[:])
+}
+
+// IAS3 doesn't return the headers a multipart upload was started
+// with, so the update tracker of each upload started is kept in a
+// database indexed by upload ID. This means only uploads of the same
+// source, which have the right tracker, modtime and metadata, are
+// resumed.
+
+// kvGetTracker reads the update tracker of an upload
+type kvGetTracker struct {
+	uploadID string
+	tracker  string
+}
+
+func (op *kvGetTracker) Do(ctx context.Context, b kv.Bucket) error {
+	op.tracker = string(b.Get([]byte(op.uploadID)))
+	return nil
+}
+
+// kvPutTracker records the update tracker of an upload
+type kvPutTracker struct {
+	uploadID string
+	tracker  string
+}
+
+func (op *kvPutTracker) Do(ctx context.Context, b kv.Bucket) error {
+	return b.Put([]byte(op.uploadID), []byte(op.tracker))
+}
+
+// kvDelTracker removes the update tracker of an upload
+type kvDelTracker struct {
+	uploadID string
+}
+
+func (op *kvDelTracker) Do(ctx context.Context, b kv.Bucket) error {
+	return b.Delete([]byte(op.uploadID))
+}
+
+// doUploads runs op on the database of multipart uploads
+func (f *Fs) doUploads(ctx context.Context, write bool, op kv.Op) error {
+	db, err := kv.Start(ctx, uploadsFacility, f)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Stop(false)
+	}()
+	err = db.Do(write, op)
+	if errors.Is(err, kv.ErrEmpty) {
+		err = nil
+	}
+	return err
+}
+
+// uploadTracker returns the update tracker the multipart upload
+// uploadID was started with or "" if it isn't known
+func (f *Fs) uploadTracker(ctx context.Context, uploadID string) string {
+	op := &kvGetTracker{uploadID: uploadID}
+	if err := f.doUploads(ctx, false, op); err != nil {
+		fs.Debugf(f, "Failed to read multipart upload database: %v", err)
+	}
+	return op.tracker
+}
+
+// setUploadTracker records the update tracker of the multipart upload
+// uploadID, or forgets it if tracker is ""
+func (f *Fs) setUploadTracker(ctx context.Context, uploadID, tracker string) {
+	var op kv.Op = &kvPutTracker{uploadID: uploadID, tracker: tracker}
+	if tracker == "" {
+		op = &kvDelTracker{uploadID: uploadID}
+	}
+	if err := f.doUploads(ctx, true, op); err != nil {
+		fs.Debugf(f, "Failed to write multipart upload database: %v", err)
+	}
+}
+
+// OpenChunkWriter returns the chunk size and a ChunkWriter
+//
+// If there is an unfinished multipart upload of the same source it is
+// resumed.
+func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ChunkWriter, err error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	bucket, bucketPath := o.split()
+	if bucket == "" || bucketPath == "" {
+		return info, nil, fs.ErrorListBucketRequired
+	}
+	chunkSize := f.opt.ChunkSize
+	if size := src.Size(); size >= 0 {
+		chunkSize = chunksize.Calculator(src, size, maxUploadParts, f.opt.ChunkSize)
+	}
+	w := &iaChunkWriter{
+		f:             f,
+		o:             o,
+		path:          "/" + url.PathEscape(path.Join(bucket, bucketPath)),
+		updateTracker: multipartTracker(ctx, remote, src),
+	}
+	err = w.resume(ctx, bucket, bucketPath)
+	if err != nil {
+		fs.Debugf(o, "Failed to find a multipart upload to resume: %v", err)
+	}
+	if w.uploadID == "" {
+		headers, err := o.uploadHeaders(ctx, src, w.updateTracker, options)
+		if err != nil {
+			return info, nil, err
+		}
+		var result InitiateMultipartUploadResult
+		opts := rest.Opts{
+			Method:       "POST",
+			Path:         w.path,
+			Parameters:   url.Values{"uploads": {""}},
+			ExtraHeaders: headers,
+		}
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err := f.srv.CallXML(ctx, &opts, nil, &result)
+			return f.shouldRetry(resp, err)
+		})
+		if err != nil {
+			return info, nil, fmt.Errorf("failed to start multipart upload: %w", err)
+		}
+		if result.UploadID == "" {
+			return info, nil, errors.New("failed to start multipart upload: no upload ID returned")
+		}
+		w.uploadID = result.UploadID
+		f.setUploadTracker(ctx, w.uploadID, w.updateTracker)
+	}
+	info = fs.ChunkWriterInfo{
+		ChunkSize:         int64(chunkSize),
+		Concurrency:       f.opt.UploadConcurrency,
+		LeavePartsOnError: f.opt.LeavePartsOnError,
+	}
+	fs.Debugf(o, "open chunk writer: multipart upload: %v", w.uploadID)
+	return info, w, nil
+}
+
+// resume finds the latest unfinished multipart upload of the same
+// source and the parts uploaded to it, if any
+//
+// Unfinished uploads of the file which were started for a different
+// source are aborted as their headers are wrong for this one.
+func (w *iaChunkWriter) resume(ctx context.Context, bucket, bucketPath string) error {
+	var uploads ListMultipartUploadsResult
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       "/" + url.PathEscape(bucket),
+		Parameters: url.Values{"uploads": {""}, "prefix": {bucketPath}},
+	}
+	err := w.f.pacer.Call(func() (bool, error) {
+		resp, err := w.f.srv.CallXML(ctx, &opts, nil, &uploads)
+		return w.f.shouldRetry(resp, err)
+	})
+	if err != nil {
+		return err
+	}
+	var uploadID string
+	var initiated time.Time
+	for _, upload := range uploads.Uploads {
+		if upload.Key != bucketPath {
+			continue
+		}
+		if w.f.uploadTracker(ctx, upload.UploadID) != w.updateTracker {
+			fs.Debugf(w.o, "Aborting multipart upload %q which isn't of this source", upload.UploadID)
+			if err := w.f.abortUpload(ctx, w.path, upload.UploadID); err != nil {
+				fs.Debugf(w.o, "%v", err)
+			}
+			continue
+		}
+		if uploadID == "" || upload.Initiated.After(initiated) {
+			uploadID, initiated = upload.UploadID, upload.Initiated
+		}
+	}
+	if uploadID == "" {
+		return nil
+	}
+	uploaded := map[int]UploadPart{}
+	marker := 0
+	for {
+		var result ListPartsResult
+		opts := rest.Opts{
+			Method:     "GET",
+			Path:       w.path,
+			Parameters: url.Values{"uploadId": {uploadID}, "part-number-marker": {strconv.Itoa(marker)}},
+		}
+		err = w.f.pacer.Call(func() (bool, error) {
+			resp, err := w.f.srv.CallXML(ctx, &opts, nil, &result)
+			return w.f.shouldRetry(resp, err)
+		})
+		if err != nil {
+			return err
+		}
+		for _, part := range result.Parts {
+			uploaded[part.PartNumber] = part
+		}
+		if !result.IsTruncated || result.NextPartNumberMarker <= marker {
+			break
+		}
+		marker = result.NextPartNumberMarker
+	}
+	fs.Infof(w.o, "Resuming multipart upload %q with %d parts uploaded", uploadID, len(uploaded))
+	w.uploadID = uploadID
+	w.uploaded = uploaded
+	return nil
+}
+
+// addPart records a part as uploaded
+func (w *iaChunkWriter) addPart(partNumber int, eTag string) {
+	w.partsMu.Lock()
+	defer w.partsMu.Unlock()
+	w.parts = append(w.parts, UploadPart{PartNumber: partNumber, ETag: eTag})
+}
+
+// WriteChunk will write chunk number with reader bytes, where chunk number >= 0
+func (w *iaChunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (int64, error) {
+	if chunkNumber < 0 {
+		return -1, fmt.Errorf("invalid chunk number provided: %v", chunkNumber)
+	}
+	// Only account after the checksum read has been done
+	if do, ok := reader.(pool.DelayAccountinger); ok {
+		do.DelayAccounting(2)
+	}
+
+	m := md5.New()
+	size, err := io.Copy(m, reader)
+	if err != nil {
+		return -1, err
+	}
+	// If no data read and not the first chunk, don't write the chunk
+	if size == 0 && chunkNumber != 0 {
+		return 0, nil
+	}
+	md5sum := m.Sum(nil)
+	partNumber := chunkNumber + 1
+
+	// Skip the part if the upload being resumed has it already
+	if part, ok := w.uploaded[partNumber]; ok && part.Size == size && strings.Trim(part.ETag, `"`) == hex.EncodeToString(md5sum) {
+		fs.Debugf(w.o, "multipart upload: part %d already uploaded", partNumber)
+		// Read the chunk again so it is accounted
+		if _, err = reader.Seek(0, io.SeekStart); err == nil {
+			_, err = io.Copy(io.Discard, reader)
+		}
+		if err != nil {
+			return -1, err
+		}
+		w.addPart(partNumber, part.ETag)
+		return size, nil
+	}
+
+	var resp *http.Response
+	opts := rest.Opts{
+		Method:        "PUT",
+		Path:          w.path,
+		Parameters:    url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {w.uploadID}},
+		Body:          reader,
+		ContentLength: &size,
+		ExtraHeaders:  map[string]string{"Content-MD5": base64.StdEncoding.EncodeToString(md5sum)},
+		NoResponse:    true,
+	}
+	err = w.f.pacer.Call(func() (bool, error) {
+		// rewind the reader on retry and after reading md5
+		_, err := reader.Seek(0, io.SeekStart)
+		if err != nil {
+			return false, err
+		}
+		resp, err = w.f.srv.Call(ctx, &opts)
+		return w.f.shouldRetry(resp, err)
+	})
+	if err != nil {
+		return -1, fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+	}
+	w.addPart(partNumber, resp.Header.Get("ETag"))
+	fs.Debugf(w.o, "multipart upload: wrote part %d size %v", partNumber, fs.SizeSuffix(size))
+	return size, nil
+}
+
+// abortUpload aborts the multipart upload uploadID of the file at the
+// escaped path p
+func (f *Fs) abortUpload(ctx context.Context, p, uploadID string) error {
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       p,
+		Parameters: url.Values{"uploadId": {uploadID}},
+		NoResponse: true,
+	}
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.Call(ctx, &opts)
+		return f.shouldRetry(resp, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to abort multipart upload %q: %w", uploadID, err)
+	}
+	f.setUploadTracker(ctx, uploadID, "")
+	return nil
+}
+
+// Abort the multipart upload
+func (w *iaChunkWriter) Abort(ctx context.Context) error {
+	err := w.f.abortUpload(ctx, w.path, w.uploadID)
+	if err != nil {
+		return err
+	}
+	fs.Debugf(w.o, "multipart upload %q aborted", w.uploadID)
+	return nil
+}
+
+// Close and finalise the multipart upload
+func (w *iaChunkWriter) Close(ctx context.Context) error {
+	sort.Slice(w.parts, func(i, j int) bool {
+		return w.parts[i].PartNumber < w.parts[j].PartNumber
+	})
+	var result CompleteMultipartUploadResult
+	opts := rest.Opts{
+		Method:     "POST",
+		Path:       w.path,
+		Parameters: url.Values{"uploadId": {w.uploadID}},
+	}
+	err := w.f.pacer.Call(func() (bool, error) {
+		resp, err := w.f.srv.CallXML(ctx, &opts, &CompleteMultipartUpload{Parts: w.parts}, &result)
+		return w.f.shouldRetry(resp, err)
+	})
+	if err == nil && result.Code != "" {
+		err = fmt.Errorf("%s: %s", result.Code, result.Message)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload %q: %w", w.uploadID, err)
+	}
+	w.f.setUploadTracker(ctx, w.uploadID, "")
+	fs.Debugf(w.o, "multipart upload %q finished", w.uploadID)
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.OpenChunkWriter = (*Fs)(nil)
+	_ fs.ChunkWriter     = (*iaChunkWriter)(nil)
+)
@@ -0,0 +1,100 @@
+// Package api provides types used by the Kubo RPC API.
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// Types of MFS entries returned by files/ls
+const (
+	TypeFile      = 0
+	TypeDirectory = 1
+)
+
+// Types of UnixFS links returned by ls
+const (
+	LinkTypeDirectory = 1
+	LinkTypeFile      = 2
+)
+
+// Error is returned by the RPC API on failure
+//
+// See https://docs.ipfs.tech/reference/kubo/rpc/#http-status-codes
+type Error struct {
+	Message    string `json:"Message"`
+	Code       int    `json:"Code"`
+	Type       string `json:"Type"`
+	StatusCode int    `json:"-"`
+}
+
+// Error returns a string for the error and satisfies the error interface
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("kubo error: HTTP status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("kubo error: %s (HTTP status %d)", e.Message, e.StatusCode)
+}
+
+// Entry is an MFS directory entry returned by files/ls
+type Entry struct {
+	Name string `json:"Name"`
+	Type int    `json:"Type"`
+	Size int64  `json:"Size"`
+	Hash string `json:"Hash"`
+}
+
+// FilesLs is the response of files/ls
+type FilesLs struct {
+	Entries []Entry `json:"Entries"`
+}
+
+// Stat is the response of files/stat
+type Stat struct {
+	Hash           string `json:"Hash"`
+	Size           int64  `json:"Size"`
+	CumulativeSize int64  `json:"CumulativeSize"`
+	Blocks         int    `json:"Blocks"`
+	Type           string `json:"Type"` // "file" or "directory"
+	Mtime          int64  `json:"Mtime,omitempty"`
+	MtimeNsecs     int64  `json:"MtimeNsecs,omitempty"`
+}
+
+// IsDir returns true if the node is a directory
+func (s *Stat) IsDir() bool {
+	return s.Type == "directory"
+}
+
+// ModTime returns the modification time stored with the node if any
+func (s *Stat) ModTime() time.Time {
+	if s.Mtime == 0 && s.MtimeNsecs == 0 {
+		return time.Time{}
+	}
+	return time.Unix(s.Mtime, s.MtimeNsecs)
+}
+
+// Link is a link of an immutable UnixFS directory returned by ls
+type Link struct {
+	Name string `json:"Name"`
+	Hash string `json:"Hash"`
+	Size int64  `json:"Size"`
+	Type int    `json:"Type"`
+}
+
+// LsObject is a single resolved path returned by ls
+type LsObject struct {
+	Hash  string `json:"Hash"`
+	Links []Link `json:"Links"`
+}
+
+// Ls is the response of ls
+type Ls struct {
+	Objects []LsObject `json:"Objects"`
+}
+
+// RepoStat is the response of repo/stat
+type RepoStat struct {
+	RepoSize   int64 `json:"RepoSize"`
+	StorageMax int64 `json:"StorageMax"`
+	NumObjects int64 `json:"NumObjects"`
+}
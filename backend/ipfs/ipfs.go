@@ -0,0 +1,813 @@
+// Package ipfs provides an interface to IPFS via the RPC API of a Kubo node.
+package ipfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/backend/ipfs/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+	apiPrefix     = "/api/v0/"
+)
+
+// errorReadOnly is returned when trying to modify an /ipfs or /ipns path
+var errorReadOnly = errors.New("ipfs and ipns paths are read only, use an MFS path to write")
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "ipfs",
+		Description: "IPFS via a Kubo node",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		MetadataInfo: &fs.MetadataInfo{
+			System: map[string]fs.MetadataHelp{
+				"cid": {
+					Help:     "Content identifier of the file",
+					Type:     "string",
+					Example:  "bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy",
+					ReadOnly: true,
+				},
+			},
+			Help: `The CID of every file is returned as metadata, so it can be seen
+with "rclone lsjson -M". Directory CIDs are returned as the ID.`,
+		},
+		Options: []fs.Option{{
+			Name: "api_url",
+			Help: `URL of the Kubo RPC API, like http://127.0.0.1:5001.
+
+Keep default if the Kubo node runs on localhost.`,
+			Default:   "http://127.0.0.1:5001",
+			Sensitive: true,
+		}, {
+			Name: "api_token",
+			Help: `Bearer token for the Kubo RPC API.
+
+Only needed if the node is configured with API.Authorizations.`,
+			IsPassword: true,
+		}, {
+			Name: "cid_version",
+			Help: `CID version to use for files and directories written to MFS.
+
+Leave blank to use the default of the Kubo node.`,
+			Advanced: true,
+			Examples: []fs.OptionExample{{
+				Value: "0",
+				Help:  "CIDv0, implies sha2-256 and no raw leaves",
+			}, {
+				Value: "1",
+				Help:  "CIDv1, uses raw leaves for file data",
+			}},
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			Default: encoder.Base |
+				encoder.EncodeInvalidUtf8,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	APIURL     string               `config:"api_url"`
+	APIToken   string               `config:"api_token"`
+	CIDVersion string               `config:"cid_version"`
+	Enc        encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents a path in the MFS or an immutable path of a Kubo node
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on if any
+	opt      Options      // parsed config options
+	features *fs.Features // optional features
+	srv      *rest.Client // the connection to the Kubo node
+	pacer    *fs.Pacer    // pacer for API calls
+	readOnly bool         // set if root is an /ipfs or /ipns path
+}
+
+// Object describes an IPFS file
+type Object struct {
+	fs      *Fs
+	remote  string
+	size    int64
+	cid     string
+	modTime time.Time
+}
+
+// isImmutablePath returns true if root is an /ipfs or /ipns path
+func isImmutablePath(root string) bool {
+	first, rest, _ := strings.Cut(strings.Trim(root, "/"), "/")
+	return (first == "ipfs" || first == "ipns") && rest != ""
+}
+
+// absPath returns the path of remote as passed to the API
+func (f *Fs) absPath(remote string) string {
+	return "/" + f.opt.Enc.FromStandardPath(path.Join(f.root, remote))
+}
+
+// call runs an RPC API command with the parameters given and decodes
+// the JSON response into result if it isn't nil
+func (f *Fs) call(ctx context.Context, command string, params url.Values, result any) (err error) {
+	var resp *http.Response
+	opts := rest.Opts{
+		Method:     "POST",
+		Path:       apiPrefix + command,
+		Parameters: params,
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		if result != nil {
+			resp, err = f.srv.CallJSON(ctx, &opts, nil, result)
+		} else {
+			opts.NoResponse = true
+			resp, err = f.srv.Call(ctx, &opts)
+		}
+		return f.shouldRetry(resp, err)
+	})
+	return err
+}
+
+// stat returns information about the node at absPath
+func (f *Fs) stat(ctx context.Context, absPath string) (*api.Stat, error) {
+	var result api.Stat
+	err := f.call(ctx, "files/stat", url.Values{"arg": {absPath}}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// writeParams returns the parameters shared by MFS commands creating nodes
+func (f *Fs) writeParams(args ...string) url.Values {
+	params := url.Values{"arg": args}
+	if f.opt.CIDVersion != "" {
+		params.Set("cid-version", f.opt.CIDVersion)
+	}
+	return params
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// ModTime returns the modification time stored with the node, if any,
+// or the default time otherwise
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if o.modTime.IsZero() {
+		ci := fs.GetConfig(ctx)
+		return time.Time(ci.DefaultTime)
+	}
+	return o.modTime
+}
+
+// Size is the file length
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Hash is not supported
+func (o *Object) Hash(ctx context.Context, ty hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// ID returns the CID of the Object
+func (o *Object) ID() string {
+	return o.cid
+}
+
+// Metadata returns the CID of the Object as metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	return fs.Metadata{"cid": o.cid}, nil
+}
+
+// Storable returns if this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// SetModTime is not supported
+func (o *Object) SetModTime(ctx context.Context, t time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	var offset, count int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, count = x.Decode(o.size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	if count == 0 || offset >= o.size {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	command, countParam := "files/read", "count"
+	if o.fs.readOnly {
+		command, countParam = "cat", "length"
+	}
+	opts := rest.Opts{
+		Method:     "POST",
+		Path:       apiPrefix + command,
+		Parameters: url.Values{"arg": {o.fs.absPath(o.remote)}},
+	}
+	if offset > 0 {
+		opts.Parameters.Set("offset", strconv.FormatInt(offset, 10))
+	}
+	if count > 0 {
+		opts.Parameters.Set(countParam, strconv.FormatInt(count, 10))
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return o.fs.shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	if o.fs.readOnly {
+		return errorReadOnly
+	}
+	opts := rest.Opts{
+		Method:               "POST",
+		Path:                 apiPrefix + "files/write",
+		Body:                 in,
+		Parameters:           o.fs.writeParams(o.fs.absPath(o.remote)),
+		MultipartParams:      url.Values{},
+		MultipartContentName: "file",
+		MultipartFileName:    path.Base(o.remote),
+		NoResponse:           true,
+	}
+	opts.Parameters.Set("create", "true")
+	opts.Parameters.Set("truncate", "true")
+	opts.Parameters.Set("parents", "true")
+	// A retry would need to read in again, so don't use the pacer
+	_, err = o.fs.srv.CallJSON(ctx, &opts, nil, nil)
+	if err != nil {
+		return err
+	}
+	return o.readMetaData(ctx)
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) (err error) {
+	if o.fs.readOnly {
+		return errorReadOnly
+	}
+	return o.fs.call(ctx, "files/rm", url.Values{"arg": {o.fs.absPath(o.remote)}}, nil)
+}
+
+// readMetaData refreshes the size and CID of the object
+func (o *Object) readMetaData(ctx context.Context) (err error) {
+	stat, err := o.fs.stat(ctx, o.fs.absPath(o.remote))
+	if err != nil {
+		return err
+	}
+	if stat.IsDir() {
+		return fs.ErrorIsDir
+	}
+	o.size = stat.Size
+	o.cid = stat.Hash
+	o.modTime = stat.ModTime()
+	return nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("IPFS %s path %s", f.opt.APIURL, f.absPath(""))
+}
+
+// Precision is unsupported because ModTime can't be set
+func (f *Fs) Precision() time.Duration {
+	return fs.ModTimeNotSupported
+}
+
+// Hashes are not supported, CIDs are returned as metadata instead
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// Features for this fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// List files and directories in a directory
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	ci := fs.GetConfig(ctx)
+	dirPath := f.absPath(dir)
+	stat, err := f.stat(ctx, dirPath)
+	if errors.Is(err, fs.ErrorObjectNotFound) {
+		return nil, fs.ErrorDirNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return nil, fs.ErrorDirNotFound
+	}
+	if f.readOnly {
+		return f.listImmutable(ctx, dir, dirPath)
+	}
+
+	params := url.Values{"arg": {dirPath}}
+	params.Set("long", "true")
+	params.Set("U", "true")
+	var result api.FilesLs
+	err = f.call(ctx, "files/ls", params, &result)
+	if errors.Is(err, fs.ErrorObjectNotFound) {
+		return nil, fs.ErrorDirNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range result.Entries {
+		remote := path.Join(dir, f.opt.Enc.ToStandardName(entry.Name))
+		if entry.Type == api.TypeDirectory {
+			entries = append(entries, fs.NewDir(remote, time.Time(ci.DefaultTime)).SetID(entry.Hash))
+			continue
+		}
+		entries = append(entries, &Object{
+			fs:     f,
+			remote: remote,
+			size:   entry.Size,
+			cid:    entry.Hash,
+		})
+	}
+	return entries, nil
+}
+
+// listImmutable lists an /ipfs or /ipns directory
+func (f *Fs) listImmutable(ctx context.Context, dir, dirPath string) (entries fs.DirEntries, err error) {
+	ci := fs.GetConfig(ctx)
+	params := url.Values{"arg": {dirPath}}
+	params.Set("resolve-type", "true")
+	params.Set("size", "true")
+	params.Set("stream", "false")
+	var result api.Ls
+	err = f.call(ctx, "ls", params, &result)
+	if errors.Is(err, fs.ErrorObjectNotFound) {
+		return nil, fs.ErrorDirNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range result.Objects {
+		for _, link := range object.Links {
+			if link.Name == "" {
+				continue // data block of a chunked file
+			}
+			remote := path.Join(dir, f.opt.Enc.ToStandardName(link.Name))
+			switch link.Type {
+			case api.LinkTypeDirectory:
+				entries = append(entries, fs.NewDir(remote, time.Time(ci.DefaultTime)).SetID(link.Hash))
+			case api.LinkTypeFile:
+				entries = append(entries, &Object{
+					fs:     f,
+					remote: remote,
+					size:   link.Size,
+					cid:    link.Hash,
+				})
+			default:
+				fs.Debugf(f, "Ignoring %q of unknown type %d", remote, link.Type)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	err := o.readMetaData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Put the object into MFS
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	err := o.Update(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// PutStream uploads to MFS with an unknown size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir creates a directory
+func (f *Fs) Mkdir(ctx context.Context, dir string) (err error) {
+	if f.readOnly {
+		return errorReadOnly
+	}
+	return f.mkdir(ctx, f.absPath(dir))
+}
+
+// mkdir creates the directory at dirPath and any missing parents
+func (f *Fs) mkdir(ctx context.Context, dirPath string) error {
+	params := f.writeParams(dirPath)
+	params.Set("parents", "true")
+	return f.call(ctx, "files/mkdir", params, nil)
+}
+
+// Rmdir removes an empty directory
+func (f *Fs) Rmdir(ctx context.Context, dir string) (err error) {
+	if f.readOnly {
+		return errorReadOnly
+	}
+	entries, err := f.List(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	dirPath := f.absPath(dir)
+	if dirPath == "/" {
+		return nil // the MFS root can't be removed
+	}
+	params := url.Values{"arg": {dirPath}}
+	params.Set("recursive", "true")
+	return f.call(ctx, "files/rm", params, nil)
+}
+
+// Purge deletes all the files in the directory
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	if f.readOnly {
+		return errorReadOnly
+	}
+	dirPath := f.absPath(dir)
+	if dirPath == "/" {
+		return fs.ErrorCantPurge
+	}
+	if _, err := f.List(ctx, dir); err != nil {
+		return err
+	}
+	params := url.Values{"arg": {dirPath}}
+	params.Set("recursive", "true")
+	return f.call(ctx, "files/rm", params, nil)
+}
+
+// prepareDst removes any existing file at remote and makes sure its
+// parent directory exists
+func (f *Fs) prepareDst(ctx context.Context, remote string) error {
+	dstPath := f.absPath(remote)
+	stat, err := f.stat(ctx, dstPath)
+	switch {
+	case err == nil && stat.IsDir():
+		return fs.ErrorIsDir
+	case err == nil:
+		if err := f.call(ctx, "files/rm", url.Values{"arg": {dstPath}}, nil); err != nil {
+			return fmt.Errorf("failed to remove existing file: %w", err)
+		}
+	case !errors.Is(err, fs.ErrorObjectNotFound):
+		return err
+	}
+	return f.mkdir(ctx, path.Dir(dstPath))
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// As IPFS is content addressed this only links the CID of src into
+// MFS, so src may also be an /ipfs or /ipns path.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.fs.opt.APIURL != f.opt.APIURL || srcObj.cid == "" {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	if f.readOnly {
+		return nil, errorReadOnly
+	}
+	if err := f.prepareDst(ctx, remote); err != nil {
+		return nil, err
+	}
+	params := url.Values{"arg": {"/ipfs/" + srcObj.cid, f.absPath(remote)}}
+	if err := f.call(ctx, "files/cp", params, nil); err != nil {
+		return nil, err
+	}
+	return f.NewObject(ctx, remote)
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.fs.opt.APIURL != f.opt.APIURL || srcObj.fs.readOnly {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	if f.readOnly {
+		return nil, errorReadOnly
+	}
+	if err := f.prepareDst(ctx, remote); err != nil {
+		return nil, err
+	}
+	params := url.Values{"arg": {srcObj.fs.absPath(srcObj.remote), f.absPath(remote)}}
+	if err := f.call(ctx, "files/mv", params, nil); err != nil {
+		return nil, err
+	}
+	return f.NewObject(ctx, remote)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok || srcFs.opt.APIURL != f.opt.APIURL || srcFs.readOnly {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	if f.readOnly {
+		return errorReadOnly
+	}
+	srcPath, dstPath := srcFs.absPath(srcRemote), f.absPath(dstRemote)
+	if srcPath == "/" {
+		return fs.ErrorCantDirMove
+	}
+	_, err := f.stat(ctx, dstPath)
+	if err == nil {
+		return fs.ErrorDirExists
+	}
+	if !errors.Is(err, fs.ErrorObjectNotFound) {
+		return err
+	}
+	if err := f.mkdir(ctx, path.Dir(dstPath)); err != nil {
+		return err
+	}
+	return f.call(ctx, "files/mv", url.Values{"arg": {srcPath, dstPath}}, nil)
+}
+
+// About gets quota information from the repo of the Kubo node
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	var result api.RepoStat
+	if err := f.call(ctx, "repo/stat", url.Values{"size-only": {"true"}}, &result); err != nil {
+		return nil, err
+	}
+	usage := &fs.Usage{
+		Used: fs.NewUsageValue(result.RepoSize),
+	}
+	if result.StorageMax > 0 {
+		usage.Total = fs.NewUsageValue(result.StorageMax)
+		usage.Free = fs.NewUsageValue(max(result.StorageMax-result.RepoSize, 0))
+	}
+	return usage, nil
+}
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "cid",
+	Short: "Show the CIDs of paths",
+	Long: `This shows the CIDs of the files or directories given, or of the
+root if no paths are given.
+
+Usage Examples:
+
+    rclone backend cid ipfs:
+    rclone backend cid ipfs:path file1 dir2
+    rclone rc backend/command command=cid fs=ipfs:path -a file1
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "cid":
+		if len(arg) == 0 {
+			arg = []string{""}
+		}
+		cids := make(map[string]string, len(arg))
+		for _, remote := range arg {
+			stat, err := f.stat(ctx, f.absPath(remote))
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", remote, err)
+			}
+			cids[remote] = stat.Hash
+		}
+		return cids, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// NewFs constructs an Fs from the path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	opt.APIURL = strings.TrimSuffix(opt.APIURL, "/")
+	u, err := url.Parse(opt.APIURL)
+	if err != nil {
+		return nil, err
+	}
+
+	rootIsDir := strings.HasSuffix(root, "/")
+	root = strings.Trim(root, "/")
+
+	f := &Fs{
+		name:     name,
+		opt:      *opt,
+		root:     root,
+		readOnly: isImmutablePath(root),
+	}
+	f.pacer = fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant)))
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+		ReadMetadata:            true,
+	}).Fill(ctx, f)
+	if f.readOnly {
+		f.features.PutStream = nil
+		f.features.Move = nil
+		f.features.DirMove = nil
+		f.features.Purge = nil
+	}
+
+	f.srv = rest.NewClient(fshttp.NewClient(ctx)).SetRoot(u.String()).SetErrorHandler(errorHandler)
+	if opt.APIToken != "" {
+		token, err := obscure.Reveal(opt.APIToken)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt API token: %w", err)
+		}
+		f.srv.SetHeader("Authorization", "Bearer "+token)
+	}
+
+	// Check to see if the root is actually an existing file, the
+	// CID at the start of an immutable path is always the root
+	if root != "" && !rootIsDir && (!f.readOnly || strings.Count(root, "/") > 1) {
+		remote := path.Base(root)
+		f.root = path.Dir(root)
+		if f.root == "." {
+			f.root = ""
+		}
+		_, err := f.NewObject(ctx, remote)
+		if err != nil {
+			if errors.Is(err, fs.ErrorObjectNotFound) || errors.Is(err, fs.ErrorIsDir) {
+				// File doesn't exist so return old f
+				f.root = root
+				return f, nil
+			}
+			return nil, err
+		}
+		// return an error with an fs which points to the parent
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// errorHandler translates Kubo errors into native rclone filesystem errors.
+//
+// Kubo returns HTTP 500 for all command errors so this needs to match
+// on the error messages.
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("error when trying to read error body: %w", err)
+	}
+	errResponse := new(api.Error)
+	err = json.Unmarshal(body, &errResponse)
+	if err != nil {
+		// Set the Message to be the body if we can't parse the JSON
+		errResponse.Message = strings.TrimSpace(string(body))
+	}
+	errResponse.StatusCode = resp.StatusCode
+
+	msg := errResponse.Message
+	switch {
+	case strings.Contains(msg, "file does not exist"),
+		strings.HasPrefix(msg, "no link named"),
+		strings.Contains(msg, "could not resolve name"):
+		return fs.ErrorObjectNotFound
+	}
+	return errResponse
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func (f *Fs) shouldRetry(resp *http.Response, err error) (bool, error) {
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		return true, err
+	}
+	return fserrors.ShouldRetry(err), err
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.Copier      = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.DirMover    = (*Fs)(nil)
+	_ fs.Purger      = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.Abouter     = (*Fs)(nil)
+	_ fs.Commander   = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+	_ fs.IDer        = (*Object)(nil)
+	_ fs.Metadataer  = (*Object)(nil)
+)
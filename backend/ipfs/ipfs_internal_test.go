@@ -0,0 +1,413 @@
+package ipfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rclone/rclone/backend/ipfs/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNode is a file or directory of fakeKubo
+type fakeNode struct {
+	dir      bool
+	data     []byte
+	children map[string]*fakeNode
+}
+
+func newFakeDir() *fakeNode {
+	return &fakeNode{dir: true, children: map[string]*fakeNode{}}
+}
+
+func (n *fakeNode) clone() *fakeNode {
+	c := &fakeNode{dir: n.dir, data: append([]byte(nil), n.data...)}
+	if n.dir {
+		c.children = make(map[string]*fakeNode, len(n.children))
+		for name, child := range n.children {
+			c.children[name] = child.clone()
+		}
+	}
+	return c
+}
+
+// fakeKubo implements the parts of the Kubo RPC API used by the backend
+// on an in-memory MFS
+type fakeKubo struct {
+	mu   sync.Mutex
+	root *fakeNode
+	cids map[string]*fakeNode // immutable snapshots by CID
+}
+
+func newFakeKubo() *fakeKubo {
+	return &fakeKubo{root: newFakeDir(), cids: map[string]*fakeNode{}}
+}
+
+// cid returns the CID of n and remembers a snapshot of it
+func (k *fakeKubo) cid(n *fakeNode) string {
+	h := sha256.New()
+	if n.dir {
+		names := make([]string, 0, len(n.children))
+		for name := range n.children {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			_, _ = fmt.Fprintf(h, "%s:%s\n", name, k.cid(n.children[name]))
+		}
+	} else {
+		_, _ = h.Write(n.data)
+	}
+	cid := "bafy" + hex.EncodeToString(h.Sum(nil))[:40]
+	if n.dir {
+		cid = "bafd" + cid[4:]
+	}
+	if _, found := k.cids[cid]; !found {
+		k.cids[cid] = n.clone()
+	}
+	return cid
+}
+
+var errFakeNotFound = fmt.Errorf("file does not exist")
+
+// resolve finds the node at p, which is an MFS or /ipfs path
+func (k *fakeKubo) resolve(p string) (node, parent *fakeNode, name string, err error) {
+	if !strings.HasPrefix(p, "/") {
+		return nil, nil, "", fmt.Errorf("paths must start with a leading slash")
+	}
+	node = k.root
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	if parts[0] == "ipfs" && len(parts) > 1 {
+		node = k.cids[parts[1]]
+		if node == nil {
+			return nil, nil, "", fmt.Errorf("could not resolve name")
+		}
+		parts = parts[2:]
+	} else if parts[0] == "" {
+		parts = nil
+	}
+	for _, part := range parts {
+		if node == nil || !node.dir {
+			return nil, nil, "", errFakeNotFound
+		}
+		parent, name = node, part
+		node = node.children[part]
+	}
+	if node == nil && parent == nil {
+		return nil, nil, "", errFakeNotFound
+	}
+	return node, parent, name, nil
+}
+
+// mkdirAll makes the directory at p
+func (k *fakeKubo) mkdirAll(p string) (*fakeNode, error) {
+	node := k.root
+	for _, part := range strings.Split(strings.Trim(p, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		child := node.children[part]
+		if child == nil {
+			child = newFakeDir()
+			node.children[part] = child
+		}
+		if !child.dir {
+			return nil, fmt.Errorf("not a directory")
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// parentOf returns the parent directory of p for creating a node
+func (k *fakeKubo) parentOf(p string, parents bool) (*fakeNode, string, error) {
+	dir, name := path.Split(strings.TrimRight(p, "/"))
+	if parents {
+		parent, err := k.mkdirAll(dir)
+		return parent, name, err
+	}
+	parent, _, _, err := k.resolve(path.Clean(dir))
+	if err == nil && (parent == nil || !parent.dir) {
+		err = errFakeNotFound
+	}
+	return parent, name, err
+}
+
+func (k *fakeKubo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	result, err := k.handle(r, w)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(api.Error{Message: err.Error(), Type: "error"})
+		return
+	}
+	if result != nil {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	}
+}
+
+func (k *fakeKubo) handle(r *http.Request, w http.ResponseWriter) (any, error) {
+	if r.Method != "POST" {
+		return nil, fmt.Errorf("405 - Method Not Allowed")
+	}
+	q := r.URL.Query()
+	args := q["arg"]
+	command := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	if command == "repo/stat" {
+		return api.RepoStat{RepoSize: 1000, StorageMax: 10000}, nil
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("argument \"path\" is required")
+	}
+	parents := q.Get("parents") == "true"
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	switch command {
+	case "files/stat":
+		node, _, _, err := k.resolve(args[0])
+		if err != nil {
+			return nil, err
+		} else if node == nil {
+			return nil, errFakeNotFound
+		}
+		stat := api.Stat{Hash: k.cid(node), Size: int64(len(node.data)), Type: "file"}
+		if node.dir {
+			stat.Type, stat.Size = "directory", 0
+		}
+		return stat, nil
+	case "files/ls":
+		node, _, name, err := k.resolve(args[0])
+		if err != nil {
+			return nil, err
+		} else if node == nil {
+			return nil, errFakeNotFound
+		}
+		result := api.FilesLs{Entries: []api.Entry{}}
+		if !node.dir {
+			result.Entries = append(result.Entries, api.Entry{Name: name, Size: int64(len(node.data)), Hash: k.cid(node)})
+			return result, nil
+		}
+		for childName, child := range node.children {
+			entry := api.Entry{Name: childName, Hash: k.cid(child), Size: int64(len(child.data))}
+			if child.dir {
+				entry.Type = api.TypeDirectory
+			}
+			result.Entries = append(result.Entries, entry)
+		}
+		return result, nil
+	case "ls":
+		node, _, _, err := k.resolve(args[0])
+		if err != nil {
+			return nil, err
+		} else if node == nil {
+			return nil, fmt.Errorf("no link named %q", path.Base(args[0]))
+		}
+		object := api.LsObject{Hash: k.cid(node), Links: []api.Link{}}
+		for childName, child := range node.children {
+			link := api.Link{Name: childName, Hash: k.cid(child), Size: int64(len(child.data)), Type: api.LinkTypeFile}
+			if child.dir {
+				link.Type = api.LinkTypeDirectory
+			}
+			object.Links = append(object.Links, link)
+		}
+		return api.Ls{Objects: []api.LsObject{object}}, nil
+	case "files/read", "cat":
+		node, _, _, err := k.resolve(args[0])
+		if err != nil {
+			return nil, err
+		} else if node == nil {
+			return nil, errFakeNotFound
+		} else if node.dir {
+			return nil, fmt.Errorf("this dag node is a directory")
+		}
+		data := node.data[min(offset, len(node.data)):]
+		count := q.Get("count") + q.Get("length")
+		if count != "" {
+			n, _ := strconv.Atoi(count)
+			data = data[:min(n, len(data))]
+		}
+		_, _ = w.Write(data)
+		return nil, nil
+	case "files/write":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		parent, name, err := k.parentOf(args[0], parents)
+		if err != nil {
+			return nil, err
+		}
+		node := parent.children[name]
+		if node == nil {
+			if q.Get("create") != "true" {
+				return nil, errFakeNotFound
+			}
+			node = &fakeNode{}
+			parent.children[name] = node
+		}
+		if node.dir {
+			return nil, fmt.Errorf("%s is a directory", args[0])
+		}
+		node.data = data
+		return nil, nil
+	case "files/mkdir":
+		if node, _, _, err := k.resolve(args[0]); err == nil && node != nil {
+			if parents && node.dir {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("file already exists")
+		}
+		parent, name, err := k.parentOf(args[0], parents)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" {
+			parent.children[name] = newFakeDir()
+		}
+		return nil, nil
+	case "files/rm":
+		node, parent, name, err := k.resolve(args[0])
+		if err != nil {
+			return nil, err
+		} else if node == nil {
+			return nil, errFakeNotFound
+		} else if parent == nil {
+			return nil, fmt.Errorf("cannot delete root")
+		} else if node.dir && q.Get("recursive") != "true" {
+			return nil, fmt.Errorf("%s is a directory, use -r to remove directories", args[0])
+		}
+		delete(parent.children, name)
+		return nil, nil
+	case "files/mv", "files/cp":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("two arguments required")
+		}
+		node, srcParent, srcName, err := k.resolve(args[0])
+		if err != nil {
+			return nil, err
+		} else if node == nil {
+			return nil, errFakeNotFound
+		}
+		dstParent, dstName, err := k.parentOf(args[1], parents)
+		if err != nil {
+			return nil, err
+		}
+		if dstParent.children[dstName] != nil {
+			return nil, fmt.Errorf("directory already has entry by that name")
+		}
+		if strings.HasSuffix(r.URL.Path, "mv") {
+			delete(srcParent.children, srcName)
+		} else {
+			node = node.clone()
+		}
+		dstParent.children[dstName] = node
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown command %q", r.URL.Path)
+}
+
+// newFakeRemote starts a fakeKubo and returns a connection string for it
+func newFakeRemote(t *testing.T) string {
+	srv := httptest.NewServer(newFakeKubo())
+	t.Cleanup(srv.Close)
+	return fmt.Sprintf(":ipfs,api_url='%s':", srv.URL)
+}
+
+// TestFakeKubo runs the integration tests against a fake Kubo node
+func TestFakeKubo(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	srv := httptest.NewServer(newFakeKubo())
+	defer srv.Close()
+	name := "TestIPFSFake"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "ipfs"},
+			{Name: name, Key: "api_url", Value: srv.URL},
+		},
+		QuickTestOK: true,
+	})
+}
+
+// TestImmutablePaths checks reading /ipfs paths and copying by CID
+func TestImmutablePaths(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	remote := newFakeRemote(t)
+	f, err := fs.NewFs(ctx, remote+"src")
+	require.NoError(t, err)
+	item := fstest.Item{Path: "dir/file.txt", ModTime: fstest.Time("2001-02-03T04:05:06.499999999Z")}
+	contents := "hello ipfs"
+	o := fstests.PutTestContents(ctx, t, f, &item, contents, true)
+	cid := o.(fs.IDer).ID()
+	assert.NotEmpty(t, cid)
+	meta, err := fs.GetMetadata(ctx, o)
+	require.NoError(t, err)
+	assert.Equal(t, cid, meta["cid"])
+
+	out, err := f.(fs.Commander).Command(ctx, "cid", nil, nil)
+	require.NoError(t, err)
+	rootCID := out.(map[string]string)[""]
+	require.NotEmpty(t, rootCID)
+
+	// Read the snapshot by CID
+	ro, err := fs.NewFs(ctx, remote+"/ipfs/"+rootCID)
+	require.NoError(t, err)
+	fstest.CheckListingWithPrecision(t, ro, []fstest.Item{item}, []string{"dir"}, fs.ModTimeNotSupported)
+	roObj, err := ro.NewObject(ctx, item.Path)
+	require.NoError(t, err)
+	assert.Equal(t, cid, roObj.(fs.IDer).ID())
+	assert.Equal(t, contents, fstests.ReadObject(ctx, t, roObj, -1))
+	assert.Equal(t, contents[2:5], fstests.ReadObject(ctx, t, roObj, -1, &fs.RangeOption{Start: 2, End: 4}))
+	_, err = ro.Put(ctx, strings.NewReader("x"), object.NewStaticObjectInfo("new", item.ModTime, 1, true, nil, ro))
+	assert.ErrorIs(t, err, errorReadOnly)
+
+	// The snapshot doesn't change when MFS does
+	require.NoError(t, o.Remove(ctx))
+	roObj, err = ro.NewObject(ctx, item.Path)
+	require.NoError(t, err)
+	assert.Equal(t, contents, fstests.ReadObject(ctx, t, roObj, -1))
+
+	// Copy out of the snapshot back into MFS server-side
+	dst, err := fs.NewFs(ctx, remote+"dst")
+	require.NoError(t, err)
+	dstObj, err := dst.Features().Copy(ctx, roObj, "copied.txt")
+	require.NoError(t, err)
+	assert.Equal(t, cid, dstObj.(fs.IDer).ID())
+	differ, err := operations.CheckIdenticalDownload(ctx, dstObj, roObj)
+	require.NoError(t, err)
+	assert.False(t, differ)
+
+	// A file root points to its parent
+	fileFs, err := fs.NewFs(ctx, remote+"/ipfs/"+rootCID+"/dir/file.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+	assert.Equal(t, "ipfs/"+rootCID+"/dir", fileFs.Root())
+}
@@ -268,6 +268,46 @@ enabled, rclone will no longer update the modtime after copying a file.`,
 				Default:  false,
 				Advanced: true,
 			},
+			{
+				Name: "tape_mode",
+				Help: `Write files in a way which suits sequential media such as LTFS tapes.
+
+Tape drives are very slow if they have to seek, which happens if
+several files are written at once or if a file is written out of
+order. Setting this flag makes rclone:
+
+- Write one file at a time, whatever --transfers is set to, choosing
+  the next file from the same directory as the last where possible
+  so directories are written together.
+- Write each file strictly from start to finish, so multi-thread
+  downloads are disabled.
+- Not preallocate space or make sparse files.
+- Not stat the file after it has been written to check it, using the
+  number of bytes written and the modification time set instead.
+
+Using --transfers greater than 1 is still useful as the next file can
+be read from the source while the current one is being written.`,
+				Default:  false,
+				Advanced: true,
+			},
+			{
+				Name: "hash_streams",
+				Help: `Number of streams to use when calculating hashes of big files.
+
+Hashes which are made from the hashes of blocks of the file, such as
+the Dropbox hash and the OneDrive QuickXorHash, can be calculated in
+parallel by hashing different parts of the file at once. This is much
+quicker for big files on fast disks such as NVMe SSDs.
+
+This sets the maximum number of parts of a file to hash at once. Files
+smaller than 16 MiB are always hashed in one go. Set this to 1 to
+disable parallel hashing, which may be quicker for spinning disks.
+
+Hashes such as MD5 and SHA-1 are always calculated in one stream as
+they can't be calculated in parts.`,
+				Default:  4,
+				Advanced: true,
+			},
 			{
 				Name: "time_type",
 				Help: `Set what kind of time is returned.
@@ -330,7 +370,9 @@ type Options struct {
 	NoPreAllocate     bool                 `config:"no_preallocate"`
 	NoSparse          bool                 `config:"no_sparse"`
 	NoSetModTime      bool                 `config:"no_set_modtime"`
+	TapeMode          bool                 `config:"tape_mode"`
 	TimeType          timeType             `config:"time_type"`
+	HashStreams       int                  `config:"hash_streams"`
 	Enc               encoder.MultiEncoder `config:"encoding"`
 	NoClone           bool                 `config:"no_clone"`
 }
@@ -347,6 +389,7 @@ type Fs struct {
 	warnedMu       sync.Mutex          // used for locking access to 'warned'.
 	warned         map[string]struct{} // whether we have warned about this string
 	xattrSupported atomic.Int32        // whether xattrs are supported
+	tape           tapeScheduler       // orders writes in tape mode
 
 	// do os.Lstat or os.Stat
 	lstat        func(name string) (os.FileInfo, error)
@@ -430,6 +473,13 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		// Disable server-side copy when --local-no-clone is set
 		f.features.Copy = nil
 	}
+	if opt.TapeMode {
+		// Only write files sequentially and don't allocate
+		// space which isn't written to
+		f.features.OpenWriterAt = nil
+		f.opt.NoPreAllocate = true
+		f.opt.NoSparse = true
+	}
 
 	// Check to see if this points to a file
 	fi, err := f.lstat(f.root)
@@ -1116,32 +1166,14 @@ func (o *Object) Hash(ctx context.Context, r hash.Type) (string, error) {
 	o.fs.objectMetaMu.RUnlock()
 
 	if changed || !hashFound {
-		var in io.ReadCloser
-
-		if !o.translatedLink {
-			var fd *os.File
-			fd, err = file.Open(o.path)
-			if fd != nil {
-				in = newFadviseReadCloser(o, fd, 0, 0)
-			}
+		var hashes map[hash.Type]string
+		if o.fs.opt.HashStreams > 1 && !o.translatedLink && hash.Parallel(r) {
+			hashes, err = o.hashParallel(ctx, hash.NewHashSet(r))
 		} else {
-			in, err = o.openTranslatedLink(0, -1)
-		}
-		// If not checking for updates, only read size given
-		if o.fs.opt.NoCheckUpdated {
-			in = readers.NewLimitedReadCloser(in, o.size)
+			hashes, err = o.hashStream(ctx, hash.NewHashSet(r))
 		}
 		if err != nil {
-			return "", fmt.Errorf("hash: failed to open: %w", err)
-		}
-		var hashes map[hash.Type]string
-		hashes, err = hash.StreamTypes(readers.NewContextReader(ctx, in), hash.NewHashSet(r))
-		closeErr := in.Close()
-		if err != nil {
-			return "", fmt.Errorf("hash: failed to read: %w", err)
-		}
-		if closeErr != nil {
-			return "", fmt.Errorf("hash: failed to close: %w", closeErr)
+			return "", err
 		}
 		hashValue = hashes[r]
 		o.fs.objectMetaMu.Lock()
@@ -1155,6 +1187,66 @@ func (o *Object) Hash(ctx context.Context, r hash.Type) (string, error) {
 	return hashValue, nil
 }
 
+// hashStream calculates the hashes in set of the file reading it from
+// start to finish
+func (o *Object) hashStream(ctx context.Context, set hash.Set) (hashes map[hash.Type]string, err error) {
+	var in io.ReadCloser
+
+	if !o.translatedLink {
+		var fd *os.File
+		fd, err = file.Open(o.path)
+		if fd != nil {
+			in = newFadviseReadCloser(o, fd, 0, 0)
+		}
+	} else {
+		in, err = o.openTranslatedLink(0, -1)
+	}
+	// If not checking for updates, only read size given
+	if o.fs.opt.NoCheckUpdated {
+		in = readers.NewLimitedReadCloser(in, o.size)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("hash: failed to open: %w", err)
+	}
+	hashes, err = hash.StreamTypes(readers.NewContextReader(ctx, in), set)
+	closeErr := in.Close()
+	if err != nil {
+		return nil, fmt.Errorf("hash: failed to read: %w", err)
+	}
+	if closeErr != nil {
+		return nil, fmt.Errorf("hash: failed to close: %w", closeErr)
+	}
+	return hashes, nil
+}
+
+// hashParallel calculates the hashes in set of the file using up to
+// --local-hash-streams streams at once for the types which allow it
+func (o *Object) hashParallel(ctx context.Context, set hash.Set) (hashes map[hash.Type]string, err error) {
+	fd, err := file.Open(o.path)
+	if err != nil {
+		return nil, fmt.Errorf("hash: failed to open: %w", err)
+	}
+	defer func() {
+		closeErr := fd.Close()
+		if err == nil && closeErr != nil {
+			err = fmt.Errorf("hash: failed to close: %w", closeErr)
+		}
+	}()
+	size := o.Size()
+	if !o.fs.opt.NoCheckUpdated {
+		fi, err := fd.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("hash: failed to stat: %w", err)
+		}
+		size = fi.Size()
+	}
+	hashes, err = hash.ReaderAtTypes(ctx, fd, size, set, o.fs.opt.HashStreams)
+	if err != nil {
+		return nil, fmt.Errorf("hash: failed to read: %w", err)
+	}
+	return hashes, nil
+}
+
 // Size returns the size of an object in bytes
 func (o *Object) Size() int64 {
 	o.fs.objectMetaMu.RLock()
@@ -1379,6 +1471,16 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	// Wipe hashes before update
 	o.clearHashCache()
 
+	tapeMode := o.fs.opt.TapeMode && !o.translatedLink
+	if tapeMode {
+		// Wait for our turn to write
+		err = o.fs.tape.acquire(ctx, o.path)
+		if err != nil {
+			return err
+		}
+		defer o.fs.tape.release()
+	}
+
 	var symlinkData bytes.Buffer
 	// If the object is a regular file, create it.
 	// If it is a translated link, just read in the contents, and
@@ -1419,7 +1521,7 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		in = io.TeeReader(in, hasher)
 	}
 
-	_, err = io.Copy(out, in)
+	written, err := io.Copy(out, in)
 	closeErr := out.Close()
 	if err == nil {
 		err = closeErr
@@ -1460,7 +1562,12 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	}
 
 	// Set the mtime
-	err = o.SetModTime(ctx, src.ModTime(ctx))
+	if tapeMode {
+		// Don't read the file back from the tape
+		err = o.setWrittenMetadata(written, src.ModTime(ctx))
+	} else {
+		err = o.SetModTime(ctx, src.ModTime(ctx))
+	}
 	if err != nil {
 		return err
 	}
@@ -1474,11 +1581,34 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	if err != nil {
 		return fmt.Errorf("failed to set metadata: %w", err)
 	}
+	if tapeMode && meta == nil {
+		return nil
+	}
 
 	// ReRead info now that we have finished
 	return o.lstat()
 }
 
+// setWrittenMetadata sets the modification time of a file which has
+// just had size bytes written to it and records the info without
+// calling stat on it.
+func (o *Object) setWrittenMetadata(size int64, modTime time.Time) error {
+	if o.fs.opt.NoSetModTime {
+		modTime = time.Now()
+	} else {
+		err := o.setTimes(modTime, modTime)
+		if err != nil {
+			return err
+		}
+	}
+	o.fs.objectMetaMu.Lock()
+	o.size = size
+	o.modTime = modTime
+	o.mode = 0666
+	o.fs.objectMetaMu.Unlock()
+	return nil
+}
+
 var sparseWarning sync.Once
 
 // OpenWriterAt opens with a handle for random access writes
@@ -1524,6 +1654,63 @@ func (f *Fs) OpenWriterAt(ctx context.Context, remote string, size int64) (fs.Wr
 	return out, nil
 }
 
+// HardLink makes a hard link to the object at remote in f, which must
+// be on the local disk, and returns the new object.
+func (o *Object) HardLink(ctx context.Context, f fs.Fs, remote string) (fs.Object, error) {
+	fdst, ok := f.(*Fs)
+	if !ok || o.translatedLink {
+		return nil, fs.ErrorCantCopy
+	}
+	dstObj := fdst.newObject(remote)
+	if dstObj.translatedLink {
+		return nil, fs.ErrorCantCopy
+	}
+	err := dstObj.mkdirAll()
+	if err != nil {
+		return nil, err
+	}
+	err = os.Link(o.path, dstObj.path)
+	if err != nil {
+		return nil, err
+	}
+	return dstObj, dstObj.lstat()
+}
+
+// partialUpdate is a file open for random access writes by OpenPartialUpdate
+type partialUpdate struct {
+	*os.File
+	o *Object
+}
+
+// Close the file and read the new size and modification time
+func (pu *partialUpdate) Close() error {
+	err := pu.File.Close()
+	pu.o.clearHashCache()
+	if err != nil {
+		return err
+	}
+	return pu.o.lstat()
+}
+
+// OpenPartialUpdate opens the existing object for random access writes
+// without truncating it and sets its size to size.
+func (o *Object) OpenPartialUpdate(ctx context.Context, size int64) (fs.WriterAtCloser, error) {
+	if o.translatedLink {
+		return nil, errors.New("can't open a symlink for random writing")
+	}
+	out, err := file.OpenFile(o.path, os.O_WRONLY, 0666)
+	if err != nil {
+		return nil, err
+	}
+	o.clearHashCache()
+	err = out.Truncate(size)
+	if err != nil {
+		_ = out.Close()
+		return nil, err
+	}
+	return &partialUpdate{File: out, o: o}, nil
+}
+
 // setMetadata sets the file info from the os.FileInfo passed in
 func (o *Object) setMetadata(info os.FileInfo) {
 	// if not checking updated then don't update the stat
@@ -1692,18 +1879,20 @@ func (d *Directory) Hash() {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs              = &Fs{}
-	_ fs.PutStreamer     = &Fs{}
-	_ fs.Mover           = &Fs{}
-	_ fs.DirMover        = &Fs{}
-	_ fs.Commander       = &Fs{}
-	_ fs.OpenWriterAter  = &Fs{}
-	_ fs.DirSetModTimer  = &Fs{}
-	_ fs.MkdirMetadataer = &Fs{}
-	_ fs.Object          = &Object{}
-	_ fs.Metadataer      = &Object{}
-	_ fs.SetMetadataer   = &Object{}
-	_ fs.Directory       = &Directory{}
-	_ fs.SetModTimer     = &Directory{}
-	_ fs.SetMetadataer   = &Directory{}
+	_ fs.Fs                 = &Fs{}
+	_ fs.PutStreamer        = &Fs{}
+	_ fs.Mover              = &Fs{}
+	_ fs.DirMover           = &Fs{}
+	_ fs.Commander          = &Fs{}
+	_ fs.OpenWriterAter     = &Fs{}
+	_ fs.DirSetModTimer     = &Fs{}
+	_ fs.MkdirMetadataer    = &Fs{}
+	_ fs.Object             = &Object{}
+	_ fs.Metadataer         = &Object{}
+	_ fs.SetMetadataer      = &Object{}
+	_ fs.OpenPartialUpdater = &Object{}
+	_ fs.HardLinker         = &Object{}
+	_ fs.Directory          = &Directory{}
+	_ fs.SetModTimer        = &Directory{}
+	_ fs.SetMetadataer      = &Directory{}
 )
@@ -0,0 +1,99 @@
+package local
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+)
+
+// tapeWaiter is a transfer waiting for its turn to write
+type tapeWaiter struct {
+	path  string
+	ready chan struct{}
+}
+
+// tapeScheduler lets one file at a time be written in tape mode.
+//
+// Sequential media like LTFS formatted tapes are very slow if several
+// files are written at once, as the drive has to keep switching
+// between them. When a write finishes the next file is chosen from
+// those waiting, preferring files in the same directory as the last
+// one and then the lowest path, so directories are written in
+// batches.
+type tapeScheduler struct {
+	mu      sync.Mutex
+	busy    bool          // set if a file is being written
+	lastDir string        // directory of the last file admitted
+	waiting []*tapeWaiter // transfers waiting to write
+}
+
+// acquire waits until it is the turn of path to be written
+//
+// release must be called when the write is finished if this returns
+// no error.
+func (ts *tapeScheduler) acquire(ctx context.Context, path string) error {
+	ts.mu.Lock()
+	if !ts.busy {
+		ts.busy = true
+		ts.lastDir = filepath.Dir(path)
+		ts.mu.Unlock()
+		return nil
+	}
+	w := &tapeWaiter{
+		path:  path,
+		ready: make(chan struct{}),
+	}
+	ts.waiting = append(ts.waiting, w)
+	ts.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		ts.mu.Lock()
+		for i, x := range ts.waiting {
+			if x == w {
+				ts.waiting = append(ts.waiting[:i], ts.waiting[i+1:]...)
+				ts.mu.Unlock()
+				return ctx.Err()
+			}
+		}
+		ts.mu.Unlock()
+		// We were admitted while being cancelled so pass
+		// our turn on
+		ts.release()
+		return ctx.Err()
+	}
+}
+
+// release finishes a write and admits the next waiting one
+func (ts *tapeScheduler) release() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if len(ts.waiting) == 0 {
+		ts.busy = false
+		return
+	}
+	best := 0
+	for i, w := range ts.waiting[1:] {
+		if ts.before(w.path, ts.waiting[best].path) {
+			best = i + 1
+		}
+	}
+	w := ts.waiting[best]
+	ts.waiting = append(ts.waiting[:best], ts.waiting[best+1:]...)
+	ts.lastDir = filepath.Dir(w.path)
+	close(w.ready)
+}
+
+// before returns true if a should be written before b
+//
+// Call with the lock held.
+func (ts *tapeScheduler) before(a, b string) bool {
+	aSame := filepath.Dir(a) == ts.lastDir
+	bSame := filepath.Dir(b) == ts.lastDir
+	if aSame != bSame {
+		return aSame
+	}
+	return a < b
+}
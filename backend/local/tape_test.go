@@ -0,0 +1,107 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check the scheduler admits one writer at a time in directory order
+func TestTapeScheduler(t *testing.T) {
+	ctx := context.Background()
+	var ts tapeScheduler
+	require.NoError(t, ts.acquire(ctx, filepath.Join("a", "1")))
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	paths := []string{
+		filepath.Join("b", "1"),
+		filepath.Join("a", "3"),
+		filepath.Join("c", "1"),
+		filepath.Join("a", "2"),
+		filepath.Join("b", "0"),
+	}
+	for _, p := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, ts.acquire(ctx, p))
+			mu.Lock()
+			order = append(order, p)
+			mu.Unlock()
+			ts.release()
+		}()
+	}
+	// Wait for them all to be queued
+	for {
+		ts.mu.Lock()
+		n := len(ts.waiting)
+		ts.mu.Unlock()
+		if n == len(paths) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ts.release()
+	wg.Wait()
+
+	assert.Equal(t, []string{
+		filepath.Join("a", "2"),
+		filepath.Join("a", "3"),
+		filepath.Join("b", "0"),
+		filepath.Join("b", "1"),
+		filepath.Join("c", "1"),
+	}, order)
+	assert.False(t, ts.busy)
+}
+
+// Check a cancelled waiter is removed from the queue
+func TestTapeSchedulerCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var ts tapeScheduler
+	require.NoError(t, ts.acquire(context.Background(), "a"))
+	cancel()
+	assert.Equal(t, context.Canceled, ts.acquire(ctx, "b"))
+	assert.Len(t, ts.waiting, 0)
+	ts.release()
+	assert.False(t, ts.busy)
+}
+
+// Check files written in tape mode have the expected info
+func TestTapeMode(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	fsys, err := NewFs(ctx, "local", dir, configmap.Simple{"tape_mode": "true"})
+	require.NoError(t, err)
+	f := fsys.(*Fs)
+	assert.Nil(t, f.Features().OpenWriterAt)
+	assert.True(t, f.opt.NoPreAllocate)
+	assert.True(t, f.opt.NoSparse)
+
+	when := fstest.Time("2001-02-03T04:05:06.499999999Z")
+	b := bytes.NewBufferString("hello tape")
+	src := object.NewStaticObjectInfo("dir/file.txt", when, -1, true, nil, f)
+	o, err := f.Put(ctx, b, src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), o.Size())
+	assert.True(t, when.Equal(o.ModTime(ctx)))
+
+	// Check it matches what is on disk
+	o2, err := f.NewObject(ctx, "dir/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, o.Size(), o2.Size())
+	fstest.AssertTimeEqualWithPrecision(t, "dir/file.txt", when, o2.ModTime(ctx), f.Precision())
+	assert.False(t, f.tape.busy)
+}
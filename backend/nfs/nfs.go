@@ -0,0 +1,751 @@
+// Package nfs provides an interface to NFS servers
+package nfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/readers"
+	nfsc "github.com/willscott/go-nfs-client/nfs"
+	"github.com/willscott/go-nfs-client/nfs/rpc"
+	"github.com/willscott/go-nfs-client/nfs/util"
+)
+
+const (
+	minSleep         = 10 * time.Millisecond
+	maxSleep         = 2 * time.Second
+	decayConstant    = 2 // bigger for slower decay, exponential
+	dirPerm          = 0755
+	filePerm         = 0644
+	defaultWriteSize = 64 * 1024 // used if the server doesn't say
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "nfs",
+		Description: "NFS",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:      "host",
+			Help:      "NFS server host to connect to.\n\nE.g. \"nfs.example.com\".",
+			Required:  true,
+			Sensitive: true,
+		}, {
+			Name: "export",
+			Help: `Path of the export on the NFS server.
+
+This is the part after the colon when the export is mounted, so
+"/srv/data" for "nfs.example.com:/srv/data".`,
+			Required: true,
+		}, {
+			Name: "port",
+			Help: `NFS port number.
+
+Leave as 0 to find the NFS and MOUNT services with the portmapper on
+port 111 of the server.
+
+If this is set, the MOUNT service must be on the same port as the NFS
+service. This is the case for servers without a portmapper, such as
+rclone serve nfs.`,
+			Default: 0,
+		}, {
+			Name: "uid",
+			Help: `User ID to send to the server.
+
+The server checks permissions using this user ID. Servers normally
+map user ID 0 (root) to an anonymous user.
+
+Leave as -1 to use the user ID rclone is running as.`,
+			Default:  -1,
+			Advanced: true,
+		}, {
+			Name: "gid",
+			Help: `Group ID to send to the server.
+
+Leave as -1 to use the group ID rclone is running as.`,
+			Default:  -1,
+			Advanced: true,
+		}, {
+			Name: "machine_name",
+			Help: `Machine name to send to the server.
+
+Leave blank to use the host name of this machine.`,
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			Default:  encoder.Base,
+		}},
+	})
+
+	// Send the log messages from the client library to the rclone
+	// log rather than to stderr.
+	util.DefaultLogger = logger{}
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Host        string               `config:"host"`
+	Export      string               `config:"export"`
+	Port        int                  `config:"port"`
+	UID         int                  `config:"uid"`
+	GID         int                  `config:"gid"`
+	MachineName string               `config:"machine_name"`
+	Enc         encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents a directory on an NFS export
+type Fs struct {
+	name      string       // name of this remote
+	root      string       // the path we are working on
+	opt       Options      // parsed options
+	features  *fs.Features // optional features
+	pacer     *fs.Pacer    // pacer for operations
+	mnt       *nfsc.Mount  // connection to the MOUNT service
+	target    *nfsc.Target // the mounted export
+	writeSize int          // preferred size of writes
+	shutdown  sync.Once    // makes sure Shutdown only runs once
+}
+
+// Object describes a file on an NFS export
+type Object struct {
+	fs      *Fs
+	remote  string
+	size    int64
+	modTime time.Time
+}
+
+// logger sends log messages from the NFS client library to the
+// rclone debug log. Any errors are returned to rclone too.
+type logger struct{}
+
+func (logger) SetDebug(bool) {}
+
+func (logger) Errorf(format string, args ...any) {
+	fs.Debugf("nfs", format, args...)
+}
+
+func (logger) Debugf(format string, args ...any) {
+	fs.Debugf("nfs", format, args...)
+}
+
+func (logger) Infof(format string, args ...any) {
+	fs.Debugf("nfs", format, args...)
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	if f.root == "" {
+		return fmt.Sprintf("NFS export %s:%s", f.opt.Host, f.opt.Export)
+	}
+	return fmt.Sprintf("NFS export %s:%s path %s", f.opt.Host, f.opt.Export, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the modification times NFS servers store
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// shouldRetry returns a boolean as to whether this err deserves to be
+// retried. It returns the err as a convenience
+func shouldRetry(ctx context.Context, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	if err == nil {
+		return false, nil
+	}
+	return fserrors.ShouldRetry(err), err
+}
+
+// translateError turns NFS errors into rclone errors
+func translateError(err error, isDir bool) error {
+	switch {
+	case err == nil:
+		return nil
+	case os.IsNotExist(err), nfsc.IsNotDirError(err):
+		if isDir {
+			return fs.ErrorDirNotFound
+		}
+		return fs.ErrorObjectNotFound
+	case nfsc.IsNotEmptyError(err):
+		return fs.ErrorDirectoryNotEmpty
+	}
+	return err
+}
+
+// remotePath returns the path on the export of remote
+func (f *Fs) remotePath(remote string) string {
+	return f.opt.Enc.FromStandardPath(path.Join(f.root, remote))
+}
+
+// parentDir returns the parent directory of p on the export
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+// sameExport returns true if other is on the same export as f
+func (f *Fs) sameExport(other *Fs) bool {
+	return f.opt.Host == other.opt.Host && f.opt.Port == other.opt.Port && f.opt.Export == other.opt.Export
+}
+
+// NewFs constructs an Fs from the path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.Host == "" {
+		return nil, errors.New("host not set")
+	}
+	if opt.Export == "" {
+		return nil, errors.New("export not set")
+	}
+	if opt.UID < 0 {
+		opt.UID = max(os.Getuid(), 0)
+	}
+	if opt.GID < 0 {
+		opt.GID = max(os.Getgid(), 0)
+	}
+	if opt.MachineName == "" {
+		opt.MachineName, _ = os.Hostname()
+	}
+	f := &Fs{
+		name:  name,
+		root:  strings.Trim(root, "/"),
+		opt:   *opt,
+		pacer: fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(ctx, f)
+
+	err = f.mount(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check to see if the root is a file
+	if f.root != "" {
+		attr, _, err := f.lookup(ctx, f.remotePath(""))
+		if err == nil && !attr.IsDir() {
+			newRoot := path.Dir(f.root)
+			if newRoot == "." {
+				newRoot = ""
+			}
+			f.root = newRoot
+			// return an error with an fs which points to the parent
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// mount connects to the server and mounts the export
+func (f *Fs) mount(ctx context.Context) (err error) {
+	var mnt *nfsc.Mount
+	err = f.pacer.Call(func() (bool, error) {
+		if f.opt.Port == 0 {
+			mnt, err = nfsc.DialMount(f.opt.Host, 0)
+		} else {
+			var client *rpc.Client
+			client, err = nfsc.DialServiceAtPort(f.opt.Host, f.opt.Port)
+			if err == nil {
+				// With no Addr set the export is used
+				// over the same connection
+				mnt = &nfsc.Mount{Client: client}
+			}
+		}
+		return shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to NFS server: %w", err)
+	}
+	auth := rpc.NewAuthUnix(f.opt.MachineName, uint32(f.opt.UID), uint32(f.opt.GID)).Auth()
+	target, err := mnt.Mount(f.opt.Export, auth)
+	if err != nil {
+		mnt.Close()
+		return fmt.Errorf("failed to mount %q: %w", f.opt.Export, err)
+	}
+	f.mnt = mnt
+	f.target = target
+	f.writeSize = defaultWriteSize
+	info, err := target.FSInfo()
+	if err == nil && info.WTPref > 0 {
+		f.writeSize = int(info.WTPref)
+	}
+	return nil
+}
+
+// Shutdown unmounts the export and closes the connections
+func (f *Fs) Shutdown(ctx context.Context) (err error) {
+	f.shutdown.Do(func() {
+		err = f.mnt.Unmount()
+		if f.target.Client != f.mnt.Client {
+			f.target.Close()
+		}
+		f.mnt.Close()
+	})
+	return err
+}
+
+// lookup returns the attributes and file handle of p
+func (f *Fs) lookup(ctx context.Context, p string) (attr *nfsc.Fattr, fh []byte, err error) {
+	err = f.pacer.Call(func() (bool, error) {
+		var info os.FileInfo
+		info, fh, err = f.target.Lookup(p)
+		if err == nil {
+			attr = info.(*nfsc.Fattr)
+		}
+		return shouldRetry(ctx, err)
+	})
+	return attr, fh, err
+}
+
+// setattr sets the attributes in sattr on p
+func (f *Fs) setattr(ctx context.Context, p string, sattr nfsc.Sattr3) error {
+	return f.pacer.Call(func() (bool, error) {
+		err := f.target.Setattr(p, sattr)
+		return shouldRetry(ctx, err)
+	})
+}
+
+// newObject makes an Object from remote and its attributes
+func (f *Fs) newObject(remote string, attr *nfsc.Fattr) *Object {
+	return &Object{
+		fs:      f,
+		remote:  remote,
+		size:    attr.Size(),
+		modTime: attr.ModTime(),
+	}
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	var items []*nfsc.EntryPlus
+	err = f.pacer.Call(func() (bool, error) {
+		items, err = f.target.ReadDirPlus(f.remotePath(dir))
+		return shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return nil, translateError(err, true)
+	}
+	for _, item := range items {
+		remote := path.Join(dir, f.opt.Enc.ToStandardName(item.FileName))
+		if !item.Attr.IsSet {
+			fs.Debugf(f, "Skipping %q: no attributes returned", remote)
+			continue
+		}
+		switch item.Attr.Attr.Type {
+		case nfsc.NF3Dir:
+			entries = append(entries, fs.NewDir(remote, item.ModTime()))
+		case nfsc.NF3Reg:
+			entries = append(entries, f.newObject(remote, &item.Attr.Attr))
+		default:
+			fs.Debugf(f, "Skipping %q: not a regular file or directory", remote)
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	attr, _, err := f.lookup(ctx, f.remotePath(remote))
+	if err != nil {
+		return nil, translateError(err, false)
+	}
+	if attr.IsDir() {
+		return nil, fs.ErrorIsDir
+	}
+	if attr.Type != nfsc.NF3Reg {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return f.newObject(remote, attr), nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	err := o.Update(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// mkdir makes the directory p on the export and any parent
+// directories it needs
+func (f *Fs) mkdir(ctx context.Context, p string) error {
+	if p == "" {
+		return nil
+	}
+	attr, _, err := f.lookup(ctx, p)
+	if err == nil {
+		if attr.IsDir() {
+			return nil
+		}
+		return fmt.Errorf("%q already exists and is not a directory", p)
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+	err = f.mkdir(ctx, parentDir(p))
+	if err != nil {
+		return err
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		_, err = f.target.Mkdir(p, dirPerm)
+		return shouldRetry(ctx, err)
+	})
+	if os.IsExist(err) {
+		// Made by someone else in the meantime
+		return nil
+	}
+	return err
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.mkdir(ctx, f.remotePath(dir))
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	p := f.remotePath(dir)
+	if p == "" {
+		return errors.New("can't remove the root of the export")
+	}
+	err := f.pacer.Call(func() (bool, error) {
+		err := f.target.RmDir(p)
+		return shouldRetry(ctx, err)
+	})
+	return translateError(err, true)
+}
+
+// Purge deletes all the files in the directory
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	p := f.remotePath(dir)
+	if p == "" {
+		return fs.ErrorCantPurge
+	}
+	attr, _, err := f.lookup(ctx, p)
+	if err != nil {
+		return translateError(err, true)
+	}
+	if !attr.IsDir() {
+		return fs.ErrorDirNotFound
+	}
+	return f.pacer.Call(func() (bool, error) {
+		err := f.target.RemoveAll(p)
+		return shouldRetry(ctx, err)
+	})
+}
+
+// rename renames srcPath to dstPath making the parent directories
+// of dstPath if needed
+func (f *Fs) rename(ctx context.Context, srcPath, dstPath string) error {
+	err := f.mkdir(ctx, parentDir(dstPath))
+	if err != nil {
+		return fmt.Errorf("failed to make parent directories: %w", err)
+	}
+	return f.pacer.Call(func() (bool, error) {
+		err := f.target.Rename(srcPath, dstPath)
+		return shouldRetry(ctx, err)
+	})
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || !f.sameExport(srcObj.fs) {
+		fs.Debugf(src, "Can't move - not same export")
+		return nil, fs.ErrorCantMove
+	}
+	err := f.rename(ctx, srcObj.path(), f.remotePath(remote))
+	if err != nil {
+		return nil, fmt.Errorf("move failed: %w", err)
+	}
+	return f.NewObject(ctx, remote)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok || !f.sameExport(srcFs) {
+		fs.Debugf(srcFs, "Can't move directory - not same export")
+		return fs.ErrorCantDirMove
+	}
+	srcPath := srcFs.remotePath(srcRemote)
+	dstPath := f.remotePath(dstRemote)
+	if srcPath == "" {
+		fs.Debugf(srcFs, "Can't move the root of the export")
+		return fs.ErrorCantDirMove
+	}
+	_, _, err := f.lookup(ctx, dstPath)
+	if err == nil {
+		return fs.ErrorDirExists
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	err = f.rename(ctx, srcPath, dstPath)
+	if err != nil {
+		return fmt.Errorf("dirmove failed: %w", translateError(err, true))
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// path returns the path of the object on the export
+func (o *Object) path() string {
+	return o.fs.remotePath(o.remote)
+}
+
+// Hash returns the requested hash of the object
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of the object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// Storable returns whether the object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// stat reads the attributes of the object from the server
+func (o *Object) stat(ctx context.Context) error {
+	attr, _, err := o.fs.lookup(ctx, o.path())
+	if err != nil {
+		return translateError(err, false)
+	}
+	o.size = attr.Size()
+	o.modTime = attr.ModTime()
+	return nil
+}
+
+// nfsTime converts t into the form NFS servers set it from
+func nfsTime(t time.Time) nfsc.SetTime {
+	return nfsc.SetTime{
+		SetIt: nfsc.SetToClientTime,
+		Time: nfsc.NFS3Time{
+			Seconds:  uint32(t.Unix()),
+			Nseconds: uint32(t.Nanosecond()),
+		},
+	}
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	err := o.fs.setattr(ctx, o.path(), nfsc.Sattr3{Mtime: nfsTime(modTime)})
+	if err != nil {
+		return translateError(err, false)
+	}
+	return o.stat(ctx)
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	var file *nfsc.File
+	err = o.fs.pacer.Call(func() (bool, error) {
+		file, err = o.fs.target.Open(o.path())
+		return shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open failed: %w", translateError(err, false))
+	}
+	if offset > 0 {
+		_, err = file.Seek(offset, io.SeekStart)
+		if err != nil {
+			return nil, fmt.Errorf("open seek failed: %w", err)
+		}
+	}
+	// Closing the file commits writes so isn't needed for reads
+	return readers.NewLimitedReadCloser(io.NopCloser(file), limit), nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	f := o.fs
+	p := o.path()
+	err = f.mkdir(ctx, parentDir(p))
+	if err != nil {
+		return fmt.Errorf("failed to make parent directories: %w", err)
+	}
+	var file *nfsc.File
+	err = f.pacer.Call(func() (bool, error) {
+		file, err = f.target.OpenFile(p, filePerm)
+		return shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return fmt.Errorf("update failed to open: %w", err)
+	}
+	// Truncate the file in case it existed already
+	err = f.setattr(ctx, p, nfsc.Sattr3{Size: nfsc.SetSize{SetIt: true}})
+	if err == nil {
+		_, err = io.CopyBuffer(file, in, make([]byte, f.writeSize))
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fs.Debugf(o, "Removing failed upload: %v", err)
+		removeErr := f.target.Remove(p)
+		if removeErr != nil {
+			fs.Debugf(o, "Failed to remove failed upload: %v", removeErr)
+		}
+		return fmt.Errorf("update failed: %w", err)
+	}
+	err = f.setattr(ctx, p, nfsc.Sattr3{Mtime: nfsTime(src.ModTime(ctx))})
+	if err != nil {
+		return fmt.Errorf("update failed to set modification time: %w", err)
+	}
+	return o.stat(ctx)
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	err := o.fs.pacer.Call(func() (bool, error) {
+		err := o.fs.target.Remove(o.path())
+		return shouldRetry(ctx, err)
+	})
+	return translateError(err, false)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = &Fs{}
+	_ fs.PutStreamer = &Fs{}
+	_ fs.Mover       = &Fs{}
+	_ fs.DirMover    = &Fs{}
+	_ fs.Purger      = &Fs{}
+	_ fs.Shutdowner  = &Fs{}
+	_ fs.Object      = &Object{}
+)
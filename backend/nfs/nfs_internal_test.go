@@ -0,0 +1,123 @@
+//go:build unix
+
+package nfs
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	billy "github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gonfs "github.com/willscott/go-nfs"
+	nfshelper "github.com/willscott/go-nfs/helpers"
+)
+
+// changeFS adds the billy.Change interface to an osfs so the
+// server can set modification times
+type changeFS struct {
+	billy.Filesystem
+	dir string
+}
+
+func (c changeFS) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(filepath.Join(c.dir, name), mode)
+}
+
+func (c changeFS) Lchown(name string, uid, gid int) error {
+	return nil
+}
+
+func (c changeFS) Chown(name string, uid, gid int) error {
+	return nil
+}
+
+func (c changeFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return os.Chtimes(filepath.Join(c.dir, name), atime, mtime)
+}
+
+// newServer starts an NFS server exporting a temporary directory
+// and returns its port and the directory
+func newServer(t *testing.T) (port int, dir string) {
+	dir = t.TempDir()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	handler := nfshelper.NewNullAuthHandler(changeFS{Filesystem: osfs.New(dir), dir: dir})
+	go func() {
+		_ = gonfs.Serve(listener, nfshelper.NewCachingHandler(handler, 65536))
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, dir
+}
+
+// TestNFSFake runs the integration tests against a local NFS server
+func TestNFSFake(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	port, _ := newServer(t)
+	name := "TestNFSFake"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "nfs"},
+			{Name: name, Key: "host", Value: "127.0.0.1"},
+			{Name: name, Key: "port", Value: strconv.Itoa(port)},
+			{Name: name, Key: "export", Value: "/"},
+		},
+		QuickTestOK: true,
+	})
+}
+
+// TestRootIsFile checks a root pointing at a file is detected
+func TestRootIsFile(t *testing.T) {
+	port, dir := newServer(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "dir"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dir", "file.txt"), []byte("hello"), 0644))
+	m := configmap.Simple{
+		"type":   "nfs",
+		"host":   "127.0.0.1",
+		"port":   strconv.Itoa(port),
+		"export": "/",
+	}
+
+	f, err := NewFs(context.Background(), "TestNFS", "dir/file.txt", m)
+	assert.Equal(t, fs.ErrorIsFile, err)
+	require.NotNil(t, f)
+	assert.Equal(t, "dir", f.Root())
+
+	o, err := f.NewObject(context.Background(), "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), o.Size())
+
+	_, err = f.NewObject(context.Background(), "missing.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	_, err = f.List(context.Background(), "missing")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+}
+
+func TestParentDir(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"file", ""},
+		{"dir/file", "dir"},
+		{"a/b/c", "a/b"},
+	} {
+		assert.Equal(t, test.want, parentDir(test.in), test.in)
+	}
+}
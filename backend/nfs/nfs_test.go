@@ -0,0 +1,17 @@
+// Test NFS filesystem interface
+package nfs_test
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/backend/nfs"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestNFS:",
+		NilObject:  (*nfs.Object)(nil),
+	})
+}
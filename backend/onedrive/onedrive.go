@@ -41,6 +41,7 @@ import (
 	"github.com/rclone/rclone/lib/pacer"
 	"github.com/rclone/rclone/lib/readers"
 	"github.com/rclone/rclone/lib/rest"
+	"github.com/rclone/rclone/lib/version"
 )
 
 const (
@@ -70,6 +71,7 @@ var (
 	commonPathPrefix = "/common" // prefix for the paths if tenant isn't known
 	authPath         = "/oauth2/v2.0/authorize"
 	tokenPath        = "/oauth2/v2.0/token"
+	deviceAuthPath   = "/oauth2/v2.0/devicecode"
 
 	scopeAccess             = fs.SpaceSepList{"Files.Read", "Files.ReadWrite", "Files.Read.All", "Files.ReadWrite.All", "Sites.Read.All", "offline_access"}
 	scopeAccessWithoutSites = fs.SpaceSepList{"Files.Read", "Files.ReadWrite", "Files.Read.All", "Files.ReadWrite.All", "offline_access"}
@@ -115,6 +117,7 @@ func init() {
 		Description: "Microsoft OneDrive",
 		NewFs:       NewFs,
 		Config:      Config,
+		CommandHelp: commandHelp,
 		MetadataInfo: &fs.MetadataInfo{
 			System: systemMetadataInfo,
 			Help:   metadataHelp,
@@ -254,6 +257,26 @@ modification time and removes all but the last version.
 
 **NB** Onedrive personal can't currently delete versions so don't use
 this flag there.
+`,
+			Advanced: true,
+		}, {
+			Name:    "versions",
+			Default: false,
+			Help: `Include old versions in directory listings.
+
+Old versions of files are shown with the time of the version added
+to their names, like "file-v2006-01-02-150405-000.txt". They can be
+read but not modified.
+
+Reading the versions takes an extra API call for each file listed so
+this is much slower than a normal listing.
+
+Note that when using this no write operations are permitted, so you
+can't upload, copy, move or delete files or create, move or remove
+directories.
+
+See the "restore-version" backend command for how to make an old
+version the current version of a file.
 `,
 			Advanced: true,
 		}, {
@@ -560,6 +583,7 @@ func makeOauthConfig(ctx context.Context, opt *Options) (*oauthutil.Config, erro
 	}
 	oauthConfig.TokenURL = authEndpoint[opt.Region] + prefix + tokenPath
 	oauthConfig.AuthURL = authEndpoint[opt.Region] + prefix + authPath
+	oauthConfig.DeviceAuthURL = authEndpoint[opt.Region] + prefix + deviceAuthPath
 
 	// Check to see if we are using client credentials flow
 	if opt.ClientCredentials {
@@ -758,6 +782,7 @@ type Options struct {
 	ServerSideAcrossConfigs bool                 `config:"server_side_across_configs"`
 	ListChunk               int64                `config:"list_chunk"`
 	NoVersions              bool                 `config:"no_versions"`
+	Versions                bool                 `config:"versions"`
 	HardDelete              bool                 `config:"hard_delete"`
 	LinkScope               string               `config:"link_scope"`
 	LinkType                string               `config:"link_type"`
@@ -800,6 +825,7 @@ type Object struct {
 	hash          string    // Hash of the content, usually QuickXorHash but set as hash_type
 	mimeType      string    // Content-Type of object from server (may not be as uploaded)
 	meta          *Metadata // metadata properties
+	versionID     string    // ID of the version if this is an old version
 }
 
 // Directory describes a OneDrive directory
@@ -853,6 +879,7 @@ var retryErrorCodes = []int{
 var (
 	gatewayTimeoutError     sync.Once
 	errAsyncJobAccessDenied = errors.New("async job failed - access denied")
+	errNotWithVersions      = errors.New("can't modify or delete files or directories in --onedrive-versions mode")
 )
 
 // shouldRetry returns a boolean as to whether this resp and err
@@ -1191,6 +1218,12 @@ func (f *Fs) newObjectWithInfo(ctx context.Context, remote string, info *api.Ite
 // NewObject finds the Object at remote.  If it can't be found
 // it returns the error fs.ErrorObjectNotFound.
 func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if f.opt.Versions && version.Match(remote) {
+		o, err := f.findVersion(ctx, remote)
+		if err != fs.ErrorObjectNotFound {
+			return o, err
+		}
+	}
 	return f.newObjectWithInfo(ctx, remote, nil)
 }
 
@@ -1362,7 +1395,10 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 			return nil
 		}
 		entries = append(entries, entry)
-		return nil
+		return f.addVersions(ctx, entry, func(o *Object) error {
+			entries = append(entries, o)
+			return nil
+		})
 	})
 	if err != nil {
 		return nil, err
@@ -1464,6 +1500,12 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 		if err != nil {
 			return err
 		}
+		err = f.addVersions(ctx, entry, func(o *Object) error {
+			return list.Add(o)
+		})
+		if err != nil {
+			return err
+		}
 		// If this is a shared folder, we'll need list it too
 		if info.RemoteItem != nil && info.RemoteItem.Folder != nil {
 			fs.Debugf(remote, "Listing shared directory")
@@ -1522,6 +1564,9 @@ func (f *Fs) createObject(ctx context.Context, remote string, modTime time.Time,
 //
 // The new object may have been created if an error is returned
 func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	if f.opt.Versions {
+		return nil, errNotWithVersions
+	}
 	remote := src.Remote()
 	size := src.Size()
 	modTime := src.ModTime(ctx)
@@ -1535,6 +1580,9 @@ func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options .
 
 // Mkdir creates the container if it doesn't exist
 func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	if f.opt.Versions {
+		return errNotWithVersions
+	}
 	_, err := f.dirCache.FindDir(ctx, dir, true)
 	return err
 }
@@ -1558,6 +1606,9 @@ func (f *Fs) deleteObject(ctx context.Context, id string) error {
 // purgeCheck removes the root directory, if check is set then it
 // refuses to do so if it has anything in
 func (f *Fs) purgeCheck(ctx context.Context, dir string, check bool) error {
+	if f.opt.Versions {
+		return errNotWithVersions
+	}
 	root := path.Join(f.root, dir)
 	if root == "" {
 		return errors.New("can't purge root directory")
@@ -1663,6 +1714,13 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (dst fs.Obj
 		fs.Debugf(src, "Can't copy - not same remote type")
 		return nil, fs.ErrorCantCopy
 	}
+	if srcObj.versionID != "" {
+		fs.Debugf(src, "Can't copy - old version")
+		return nil, fs.ErrorCantCopy
+	}
+	if f.opt.Versions {
+		return nil, errNotWithVersions
+	}
 
 	if (f.driveType == driveTypePersonal && srcObj.fs.driveType != driveTypePersonal) || (f.driveType != driveTypePersonal && srcObj.fs.driveType == driveTypePersonal) {
 		fs.Debugf(src, "Can't server-side copy - cross-drive between OneDrive Personal and OneDrive for business (SharePoint)")
@@ -1782,6 +1840,9 @@ func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object,
 		fs.Debugf(src, "Can't move - not same remote type")
 		return nil, fs.ErrorCantMove
 	}
+	if f.opt.Versions || srcObj.fs.opt.Versions {
+		return nil, errNotWithVersions
+	}
 
 	// Create temporary object
 	dstObj, leaf, directoryID, err := f.createObject(ctx, remote, srcObj.modTime, srcObj.size)
@@ -1852,6 +1913,9 @@ func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string
 		fs.Debugf(srcFs, "Can't move directory - not same remote type")
 		return fs.ErrorCantDirMove
 	}
+	if f.opt.Versions || srcFs.opt.Versions {
+		return errNotWithVersions
+	}
 
 	srcID, _, _, dstDirectoryID, dstLeaf, err := f.dirCache.DirMove(ctx, srcFs.dirCache, srcFs.root, srcRemote, f.root, dstRemote)
 	if err != nil {
@@ -2083,22 +2147,31 @@ func (f *Fs) CleanUp(ctx context.Context) error {
 	return err
 }
 
-// Finds and removes any old versions for o
-func (o *Object) deleteVersions(ctx context.Context) error {
+// Reads the versions of o, the current version first
+func (o *Object) listVersions(ctx context.Context) ([]api.Version, error) {
 	opts := o.fs.newOptsCall(o.id, "GET", "/versions")
 	var versions api.VersionsResponse
 	err := o.fs.pacer.Call(func() (bool, error) {
 		resp, err := o.fs.srv.CallJSON(ctx, &opts, nil, &versions)
 		return shouldRetry(ctx, resp, err)
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+	return versions.Versions, nil
+}
+
+// Finds and removes any old versions for o
+func (o *Object) deleteVersions(ctx context.Context) error {
+	versions, err := o.listVersions(ctx)
 	if err != nil {
 		return err
 	}
-	if len(versions.Versions) < 2 {
+	if len(versions) < 2 {
 		return nil
 	}
-	for _, version := range versions.Versions[1:] {
-		err = o.deleteVersion(ctx, version.ID)
+	for _, v := range versions[1:] {
+		err = o.deleteVersion(ctx, v.ID)
 		if err != nil {
 			return err
 		}
@@ -2120,6 +2193,87 @@ func (o *Object) deleteVersion(ctx context.Context, ID string) error {
 	})
 }
 
+// newVersion returns an Object for an old version of o
+func (o *Object) newVersion(v *api.Version) *Object {
+	remote := version.Add(o.remote, v.LastModifiedDateTime)
+	meta := o.fs.newMetadata(remote)
+	meta.mimeType = o.mimeType
+	meta.mtime = v.LastModifiedDateTime
+	meta.btime = o.tryGetBtime(v.LastModifiedDateTime)
+	meta.normalizedID = o.id
+	return &Object{
+		fs:          o.fs,
+		remote:      remote,
+		hasMetaData: true,
+		size:        int64(v.Size),
+		modTime:     v.LastModifiedDateTime,
+		id:          o.id,
+		mimeType:    o.mimeType,
+		meta:        meta,
+		versionID:   v.ID,
+	}
+}
+
+// addVersions calls fn with an Object for each old version of entry
+// if --onedrive-versions is set
+func (f *Fs) addVersions(ctx context.Context, entry fs.DirEntry, fn func(o *Object) error) error {
+	o, ok := entry.(*Object)
+	if !f.opt.Versions || !ok || o.isOneNoteFile {
+		return nil
+	}
+	versions, err := o.listVersions(ctx)
+	if err != nil {
+		return err
+	}
+	// The first version is the current one
+	for i := 1; i < len(versions); i++ {
+		err = fn(o.newVersion(&versions[i]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findVersion finds the old version with the versioned name remote
+//
+// It returns fs.ErrorObjectNotFound if it isn't an old version.
+func (f *Fs) findVersion(ctx context.Context, remote string) (*Object, error) {
+	t, current := version.Remove(remote)
+	if t.IsZero() {
+		return nil, fs.ErrorObjectNotFound
+	}
+	obj, err := f.newObjectWithInfo(ctx, current, nil)
+	if err != nil {
+		return nil, err
+	}
+	o := obj.(*Object)
+	versions, err := o.listVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(versions); i++ {
+		if v := o.newVersion(&versions[i]); v.remote == remote {
+			return v, nil
+		}
+	}
+	return nil, fs.ErrorObjectNotFound
+}
+
+// restoreVersion makes the old version v the current version of the
+// file
+func (f *Fs) restoreVersion(ctx context.Context, v *Object) error {
+	if operations.SkipDestructive(ctx, v, "restore version") {
+		return nil
+	}
+	opts := f.newOptsCall(v.id, "POST", "/versions/"+v.versionID+"/restoreVersion")
+	opts.NoResponse = true
+	return f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+}
+
 // ------------------------------------------------------------
 
 // Fs returns the parent Fs
@@ -2303,6 +2457,9 @@ func (o *Object) setModTime(ctx context.Context, modTime time.Time) (*api.Item,
 
 // SetModTime sets the modification time of the local fs object
 func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	if o.fs.opt.Versions {
+		return errNotWithVersions
+	}
 	info, err := o.setModTime(ctx, modTime)
 	if err != nil {
 		return err
@@ -2327,6 +2484,9 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.Read
 	fs.FixRangeOption(options, o.size)
 	var resp *http.Response
 	opts := o.fs.newOptsCall(o.id, "GET", "/content")
+	if o.versionID != "" {
+		opts = o.fs.newOptsCall(o.id, "GET", "/versions/"+o.versionID+"/content")
+	}
 	opts.Options = options
 	if o.fs.opt.AVOverride {
 		opts.Parameters = url.Values{"AVOverride": {"1"}}
@@ -2607,6 +2767,9 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	if o.hasMetaData && o.isOneNoteFile {
 		return errors.New("can't upload content to a OneNote file")
 	}
+	if o.fs.opt.Versions {
+		return errNotWithVersions
+	}
 
 	// Only start the renewer if we have a valid one
 	if o.fs.tokenRenewer != nil {
@@ -2641,6 +2804,9 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 
 // Remove an object
 func (o *Object) Remove(ctx context.Context) error {
+	if o.fs.opt.Versions {
+		return errNotWithVersions
+	}
 	return o.fs.deleteObject(ctx, o.id)
 }
 
@@ -2972,6 +3138,73 @@ func withTrailingColon(remotePath string) string {
 	return remotePath + ":"
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "restore-version",
+	Short: "Make old versions of files the current versions",
+	Long: `This command restores old versions of files, making a new current
+version of each file with the contents of the old version.
+
+The arguments are the names of the old versions, as shown in listings
+with the --onedrive-versions flag, relative to the remote.
+
+Usage Examples:
+
+    rclone lsl --onedrive-versions onedrive:path/to/dir
+    rclone backend restore-version onedrive:path/to/dir file-v2024-01-02-150405-000.txt
+
+Test first with the --interactive/-i or --dry-run flags.
+
+It returns a list of status dictionaries with Remote and Status
+keys. The Status will be OK if it was successful or an error message
+if not.
+
+    [
+        {
+            "Remote": "file-v2024-01-02-150405-000.txt",
+            "Status": "OK"
+        }
+    ]
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "restore-version":
+		if len(arg) == 0 {
+			return nil, errors.New("need at least 1 argument, the old versions to restore")
+		}
+		type status struct {
+			Remote string
+			Status string
+		}
+		out := []status{}
+		for _, remote := range arg {
+			st := status{Remote: remote, Status: "OK"}
+			v, err := f.findVersion(ctx, remote)
+			if err == nil {
+				err = f.restoreVersion(ctx, v)
+			}
+			if err != nil {
+				st.Status = err.Error()
+				fs.Errorf(remote, "Failed to restore version: %v", fs.CountError(ctx, err))
+			}
+			out = append(out, st)
+		}
+		return out, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
 // Check the interfaces are satisfied
 var (
 	_ fs.Fs              = (*Fs)(nil)
@@ -2985,6 +3218,7 @@ var (
 	_ fs.CleanUpper      = (*Fs)(nil)
 	_ fs.ListRer         = (*Fs)(nil)
 	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
 	_ fs.Object          = (*Object)(nil)
 	_ fs.MimeTyper       = &Object{}
 	_ fs.IDer            = &Object{}
@@ -15,6 +15,7 @@ import (
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/fstests"
 	"github.com/rclone/rclone/lib/random"
+	"github.com/rclone/rclone/lib/version"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -323,6 +324,55 @@ func (f *Fs) TestServerSideCopyMove(t *testing.T, r *fstest.Run) {
 	f.compareMeta(t, expectedMeta, actualMeta, true)
 }
 
+// TestVersions tests listing and restoring old versions
+func (f *Fs) TestVersions(t *testing.T, r *fstest.Run) {
+	file1 := r.WriteObject(ctx, randomFilename(), content, t2)
+	r.WriteObject(ctx, file1.Path, "new contents", t1)
+
+	f.opt.Versions = true
+	defer func() { f.opt.Versions = false }()
+
+	// Find an old version with the original content
+	var old fs.Object
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	for _, entry := range entries {
+		o, ok := entry.(*Object)
+		if !ok || o.versionID == "" {
+			continue
+		}
+		_, current := version.Remove(o.Remote())
+		assert.Equal(t, file1.Path, current)
+		if fstests.ReadObject(ctx, t, o, -1) == content {
+			old = o
+		}
+	}
+	require.NotNil(t, old, "old version not found")
+
+	// Check it can be found by name and is read only
+	o, err := f.NewObject(ctx, old.Remote())
+	require.NoError(t, err)
+	assert.Equal(t, content, fstests.ReadObject(ctx, t, o, -1))
+	assert.Equal(t, errNotWithVersions, o.Remove(ctx))
+
+	// The directories are read only too
+	dir := randomFilename()
+	assert.Equal(t, errNotWithVersions, f.Mkdir(ctx, dir))
+	assert.Equal(t, errNotWithVersions, f.Rmdir(ctx, dir))
+	assert.Equal(t, errNotWithVersions, f.Purge(ctx, dir))
+	assert.Equal(t, errNotWithVersions, f.DirMove(ctx, f, dir, randomFilename()))
+
+	// Restore it
+	out, err := f.Command(ctx, "restore-version", []string{old.Remote()}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, fmt.Sprint(out), "OK")
+
+	f.opt.Versions = false
+	o, err = f.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+	assert.Equal(t, content, fstests.ReadObject(ctx, t, o, -1))
+}
+
 // TestMetadataMapper tests adding permissions with the --metadata-mapper
 func (f *Fs) TestMetadataMapper(t *testing.T, r *fstest.Run) {
 	// setup
@@ -512,6 +562,9 @@ func (f *Fs) InternalTest(t *testing.T) {
 	testF, r = newTestF()
 	t.Run("TestServerSideCopyMove", func(t *testing.T) { testF.TestServerSideCopyMove(t, r) })
 	testF.resetTestDefaults(r)
+	testF, r = newTestF()
+	t.Run("TestVersions", func(t *testing.T) { testF.TestVersions(t, r) })
+	testF.resetTestDefaults(r)
 	t.Run("TestMetadataMapper", func(t *testing.T) { testF.TestMetadataMapper(t, r) })
 	testF.resetTestDefaults(r)
 }
@@ -42,8 +42,9 @@ const (
 )
 
 type quickXorHash struct {
-	data [dataSize]byte
-	size uint64
+	data  [dataSize]byte
+	size  uint64
+	chunk bool // set if hashing a chunk for Combine
 }
 
 // New returns a new hash.Hash computing the quickXorHash checksum.
@@ -113,13 +114,16 @@ func (q *quickXorHash) checkSum() (h [Size + 1]byte) {
 // Sum appends the current hash to b and returns the resulting slice.
 // It does not change the underlying hash state.
 func (q *quickXorHash) Sum(b []byte) []byte {
+	if q.chunk {
+		return append(b, q.data[:]...)
+	}
 	hash := q.checkSum()
 	return append(b, hash[:Size]...)
 }
 
 // Reset resets the Hash to its initial state.
 func (q *quickXorHash) Reset() {
-	*q = quickXorHash{}
+	*q = quickXorHash{chunk: q.chunk}
 }
 
 // Size returns the number of bytes Sum will return.
@@ -135,6 +139,34 @@ func (q *quickXorHash) BlockSize() int {
 	return BlockSize
 }
 
+// ChunkSize returns the size which the chunks of the data hashed in
+// parallel must be a multiple of.
+//
+// Each byte of the data is XORed into the state at its offset modulo
+// this size so chunks starting at a multiple of it can be hashed from
+// an empty state and the states XORed together.
+func (q *quickXorHash) ChunkSize() int64 {
+	return dataSize
+}
+
+// NewChunk returns a hash.Hash to hash a chunk of the data in
+// parallel. Its Sum returns the state after hashing the chunk.
+func (q *quickXorHash) NewChunk() hash.Hash {
+	return &quickXorHash{chunk: true}
+}
+
+// Combine returns the quickXorHash checksum of the data from the sums
+// of its chunks in order.
+func (q *quickXorHash) Combine(sums [][]byte, size int64) []byte {
+	var d quickXorHash
+	for _, sum := range sums {
+		xorBytes(d.data[:], sum)
+	}
+	d.size = uint64(size)
+	h := d.checkSum()
+	return h[:Size]
+}
+
 // Sum returns the quickXorHash checksum of the data.
 func Sum(data []byte) (h [Size]byte) {
 	var d quickXorHash
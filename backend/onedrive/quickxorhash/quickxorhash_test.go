@@ -143,6 +143,26 @@ func TestQuickXorHashByBlock(t *testing.T) {
 	}
 }
 
+func TestQuickXorHashChunks(t *testing.T) {
+	in := make([]byte, 5*dataSize+123)
+	_, err := rand.Read(in)
+	require.NoError(t, err)
+	q := New().(*quickXorHash)
+	want := Sum(in)
+	for _, chunkSize := range []int{dataSize, 2 * dataSize, 5 * dataSize, 6 * dataSize} {
+		var sums [][]byte
+		for i := 0; i < len(in); i += chunkSize {
+			end := min(i+chunkSize, len(in))
+			h := q.NewChunk()
+			_, err := h.Write(in[i:end])
+			require.NoError(t, err)
+			sums = append(sums, h.Sum(nil))
+		}
+		assert.Equal(t, want[:], q.Combine(sums, int64(len(in))), chunkSize)
+	}
+	assert.Equal(t, int64(dataSize), q.ChunkSize())
+}
+
 func TestSize(t *testing.T) {
 	d := New()
 	assert.Equal(t, 20, d.Size())
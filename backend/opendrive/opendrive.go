@@ -24,6 +24,7 @@ import (
 	"github.com/rclone/rclone/lib/dircache"
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/pool"
 	"github.com/rclone/rclone/lib/readers"
 	"github.com/rclone/rclone/lib/rest"
 )
@@ -1036,7 +1037,8 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	// resp.Body.Close()
 	// fs.Debugf(nil, "PostOpen: %#v", openResponse)
 
-	buf := make([]byte, o.fs.opt.ChunkSize)
+	buf := pool.GetBuffer(int(o.fs.opt.ChunkSize))
+	defer pool.PutBuffer(buf)
 	chunkOffset := int64(0)
 	remainingBytes := size
 	chunkCounter := 0
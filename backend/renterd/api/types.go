@@ -0,0 +1,136 @@
+// Package api provides types used by the renterd API.
+package api
+
+import (
+	"strings"
+	"time"
+)
+
+// Bucket is returned by GET /api/bus/buckets
+type Bucket struct {
+	CreatedAt time.Time    `json:"createdAt"`
+	Name      string       `json:"name"`
+	Policy    BucketPolicy `json:"policy"`
+}
+
+// BucketPolicy is the access policy of a bucket
+type BucketPolicy struct {
+	PublicReadAccess bool `json:"publicReadAccess"`
+}
+
+// BucketCreateRequest is sent to POST /api/bus/buckets
+type BucketCreateRequest struct {
+	Name   string       `json:"name"`
+	Policy BucketPolicy `json:"policy"`
+}
+
+// ObjectMetadata describes an object or a directory in a listing
+//
+// Directory names end in /
+type ObjectMetadata struct {
+	ETag     string    `json:"eTag"`
+	Health   float64   `json:"health"`
+	ModTime  time.Time `json:"modTime"`
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	MimeType string    `json:"mimeType"`
+}
+
+// Object is an object with its user metadata
+type Object struct {
+	ObjectMetadata
+	Metadata map[string]string `json:"metadata"`
+}
+
+// ObjectsResponse is returned by GET /api/bus/objects/*path
+//
+// Entries is set if path is a directory (ends in /) otherwise Object
+// is set.
+type ObjectsResponse struct {
+	HasMore bool             `json:"hasMore"`
+	Entries []ObjectMetadata `json:"entries"`
+	Object  *Object          `json:"object"`
+}
+
+// Modes for ObjectsRenameRequest
+const (
+	RenameModeSingle = "single" // rename a single object
+	RenameModeMulti  = "multi"  // rename all objects with the prefix
+)
+
+// ObjectsRenameRequest is sent to POST /api/bus/objects/rename
+type ObjectsRenameRequest struct {
+	Bucket string `json:"bucket"`
+	Force  bool   `json:"force"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Mode   string `json:"mode"`
+}
+
+// CopyObjectsRequest is sent to POST /api/bus/objects/copy
+type CopyObjectsRequest struct {
+	SourceBucket      string            `json:"sourceBucket"`
+	SourcePath        string            `json:"sourcePath"`
+	DestinationBucket string            `json:"destinationBucket"`
+	DestinationPath   string            `json:"destinationPath"`
+	MimeType          string            `json:"mimeType,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+}
+
+// MultipartCreateRequest is sent to POST /api/bus/multipart/create
+type MultipartCreateRequest struct {
+	Bucket      string            `json:"bucket"`
+	Path        string            `json:"path"`
+	GenerateKey bool              `json:"generateKey"`
+	MimeType    string            `json:"mimeType,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// MultipartCreateResponse is returned by POST /api/bus/multipart/create
+type MultipartCreateResponse struct {
+	UploadID string `json:"uploadID"`
+}
+
+// MultipartCompletedPart is a part of a multipart upload
+type MultipartCompletedPart struct {
+	PartNumber int    `json:"partNumber"`
+	ETag       string `json:"eTag"`
+}
+
+// MultipartCompleteRequest is sent to POST /api/bus/multipart/complete
+type MultipartCompleteRequest struct {
+	Bucket   string                   `json:"bucket"`
+	Path     string                   `json:"path"`
+	UploadID string                   `json:"uploadID"`
+	Parts    []MultipartCompletedPart `json:"parts"`
+}
+
+// MultipartAbortRequest is sent to POST /api/bus/multipart/abort
+type MultipartAbortRequest struct {
+	Bucket   string `json:"bucket"`
+	Path     string `json:"path"`
+	UploadID string `json:"uploadID"`
+}
+
+// Error is returned by renterd as a plain text body with an HTTP
+// error status
+type Error struct {
+	Message    string
+	Status     string
+	StatusCode int
+}
+
+// Error returns a string for the error and satisfies the error interface
+func (e *Error) Error() string {
+	var out []string
+	if e.Message != "" {
+		out = append(out, e.Message)
+	}
+	if e.Status != "" {
+		out = append(out, e.Status)
+	}
+	if len(out) == 0 {
+		return "renterd error"
+	}
+	return strings.Join(out, ": ")
+}
@@ -0,0 +1,214 @@
+package renterd
+
+// Multipart uploads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/renterd/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/chunksize"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+var warnStreamUpload sync.Once
+
+// chunkWriter uploads the parts of a multipart upload
+type chunkWriter struct {
+	f          *Fs
+	o          *Object
+	bucket     string
+	bucketPath string
+	uploadID   string
+	chunkSize  int64
+	partsMu    sync.Mutex // protects parts
+	parts      []api.MultipartCompletedPart
+}
+
+// OpenChunkWriter returns the chunk size and a ChunkWriter
+//
+// Pass in the remote and the src object
+// You can also use options to hint at the desired chunk size
+func (f *Fs) OpenChunkWriter(ctx context.Context, remote string, src fs.ObjectInfo, options ...fs.OpenOption) (info fs.ChunkWriterInfo, writer fs.ChunkWriter, err error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	bucket, bucketPath := o.split()
+	err = f.makeBucket(ctx, bucket)
+	if err != nil {
+		return info, nil, err
+	}
+
+	size := src.Size()
+	chunkSize := f.opt.ChunkSize
+	if size < 0 {
+		warnStreamUpload.Do(func() {
+			fs.Logf(f, "Streaming uploads using chunk size %v will have maximum file size of %v",
+				f.opt.ChunkSize, fs.SizeSuffix(int64(chunkSize)*maxUploadParts))
+		})
+	} else {
+		chunkSize = chunksize.Calculator(src, size, maxUploadParts, chunkSize)
+	}
+
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/bus/multipart/create",
+	}
+	req := api.MultipartCreateRequest{
+		Bucket:      bucket,
+		Path:        "/" + bucketPath,
+		GenerateKey: true,
+		MimeType:    fs.MimeType(ctx, src),
+		Metadata: map[string]string{
+			metaMtime: src.ModTime(ctx).Format(time.RFC3339Nano),
+		},
+	}
+	var result api.MultipartCreateResponse
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(ctx, &opts, &req, &result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return info, nil, fmt.Errorf("create multipart upload failed: %w", err)
+	}
+	fs.Debugf(o, "open chunk writer: started multipart upload: %v", result.UploadID)
+
+	w := &chunkWriter{
+		f:          f,
+		o:          o,
+		bucket:     bucket,
+		bucketPath: bucketPath,
+		uploadID:   result.UploadID,
+		chunkSize:  int64(chunkSize),
+	}
+	info = fs.ChunkWriterInfo{
+		ChunkSize:   int64(chunkSize),
+		Concurrency: f.opt.UploadConcurrency,
+	}
+	return info, w, nil
+}
+
+// WriteChunk will write chunk number with reader bytes, where chunk number >= 0
+func (w *chunkWriter) WriteChunk(ctx context.Context, chunkNumber int, reader io.ReadSeeker) (bytesWritten int64, err error) {
+	if chunkNumber < 0 {
+		return -1, fmt.Errorf("invalid chunk number provided: %v", chunkNumber)
+	}
+	size, err := reader.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1, err
+	}
+	// If no data read, don't write the chunk unless it is the only one
+	if size == 0 && chunkNumber > 0 {
+		return 0, nil
+	}
+	// renterd requires 1 <= partNumber <= 10000
+	partNumber := chunkNumber + 1
+	opts := rest.Opts{
+		Method:        "PUT",
+		Path:          objectPath("/worker/multipart", w.bucketPath),
+		Parameters:    bucketParams(w.bucket),
+		ContentLength: &size,
+		NoResponse:    true,
+	}
+	opts.Parameters.Set("uploadid", w.uploadID)
+	opts.Parameters.Set("partnumber", strconv.Itoa(partNumber))
+	// The encryption of the part depends on where it is in the object
+	opts.Parameters.Set("offset", strconv.FormatInt(int64(chunkNumber)*w.chunkSize, 10))
+	var resp *http.Response
+	err = w.f.pacer.Call(func() (bool, error) {
+		// rewind the reader on retry
+		_, err = reader.Seek(0, io.SeekStart)
+		if err != nil {
+			return false, err
+		}
+		opts.Body = reader
+		resp, err = w.f.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return -1, fmt.Errorf("multipart upload failed to upload part %d: %w", partNumber, err)
+	}
+	eTag := resp.Header.Get("ETag")
+	if eTag == "" {
+		return -1, fmt.Errorf("multipart upload of part %d returned no ETag", partNumber)
+	}
+	w.partsMu.Lock()
+	w.parts = append(w.parts, api.MultipartCompletedPart{
+		PartNumber: partNumber,
+		ETag:       eTag,
+	})
+	w.partsMu.Unlock()
+	return size, nil
+}
+
+// Close complete chunked writer finalising the file.
+func (w *chunkWriter) Close(ctx context.Context) (err error) {
+	w.partsMu.Lock()
+	parts := w.parts
+	w.partsMu.Unlock()
+	if len(parts) == 0 {
+		return errors.New("multipart upload has no parts")
+	}
+	// Parts must be in ascending order
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+	opts := rest.Opts{
+		Method:     "POST",
+		Path:       "/bus/multipart/complete",
+		NoResponse: true,
+	}
+	req := api.MultipartCompleteRequest{
+		Bucket:   w.bucket,
+		Path:     "/" + w.bucketPath,
+		UploadID: w.uploadID,
+		Parts:    parts,
+	}
+	var resp *http.Response
+	err = w.f.pacer.Call(func() (bool, error) {
+		resp, err = w.f.srv.CallJSON(ctx, &opts, &req, nil)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	fs.Debugf(w.o, "multipart upload %v finished", w.uploadID)
+	return nil
+}
+
+// Abort chunk write
+func (w *chunkWriter) Abort(ctx context.Context) (err error) {
+	opts := rest.Opts{
+		Method:     "POST",
+		Path:       "/bus/multipart/abort",
+		NoResponse: true,
+	}
+	req := api.MultipartAbortRequest{
+		Bucket:   w.bucket,
+		Path:     "/" + w.bucketPath,
+		UploadID: w.uploadID,
+	}
+	var resp *http.Response
+	err = w.f.pacer.Call(func() (bool, error) {
+		resp, err = w.f.srv.CallJSON(ctx, &opts, &req, nil)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to abort multipart upload %q: %w", w.uploadID, err)
+	}
+	fs.Debugf(w.o, "multipart upload %q aborted", w.uploadID)
+	return nil
+}
+
+// Check the interfaces are satisfied
+var _ fs.ChunkWriter = (*chunkWriter)(nil)
@@ -0,0 +1,1036 @@
+// Package renterd provides an interface to Sia storage via the
+// bus and worker API of renterd.
+package renterd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/backend/renterd/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/bucket"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/multipart"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep         = 10 * time.Millisecond
+	maxSleep         = 2 * time.Second
+	decayConstant    = 2 // bigger for slower decay, exponential
+	listLimit        = 1000
+	maxUploadParts   = 10000
+	minChunkSize     = fs.SizeSuffix(1024 * 1024)
+	metaHeaderPrefix = "X-Sia-Meta-" // prefix for user metadata headers
+	metaMtime        = "mtime"       // key for the modification time in the user metadata
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "renterd",
+		Description: "Sia via renterd",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "api_url",
+			Help: `renterd API URL, like http://renterd.host:9980/api.
+
+Keep default if renterd runs on localhost.`,
+			Default:   "http://127.0.0.1:9980/api",
+			Sensitive: true,
+		}, {
+			Name: "api_password",
+			Help: `renterd API password.
+
+This is the password set with RENTERD_API_PASSWORD or http.password
+in renterd.yml.`,
+			IsPassword: true,
+		}, {
+			Name: "upload_cutoff",
+			Help: `Cutoff for switching to multipart upload.
+
+Any files larger than this will be uploaded in chunks of chunk_size
+using the multipart API of renterd.`,
+			Default:  fs.SizeSuffix(200 * 1024 * 1024),
+			Advanced: true,
+		}, {
+			Name: "chunk_size",
+			Help: `Chunk size to use for multipart uploads.
+
+renterd stores data in slabs which are 40 MiB with the default
+redundancy settings, so chunks which are a multiple of this waste the
+least space.
+
+Files of unknown size are uploaded with this chunk size which limits
+their maximum size to 10,000 chunks.`,
+			Default:  fs.SizeSuffix(40 * 1024 * 1024),
+			Advanced: true,
+		}, {
+			Name: "upload_concurrency",
+			Help: `Concurrency for multipart uploads.
+
+This is the number of chunks of the same file that are uploaded
+concurrently.`,
+			Default:  4,
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			Default: encoder.EncodeInvalidUtf8 |
+				encoder.EncodeSlash |
+				encoder.EncodeDot,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	APIURL            string               `config:"api_url"`
+	APIPassword       string               `config:"api_password"`
+	UploadCutoff      fs.SizeSuffix        `config:"upload_cutoff"`
+	ChunkSize         fs.SizeSuffix        `config:"chunk_size"`
+	UploadConcurrency int                  `config:"upload_concurrency"`
+	Enc               encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents a remote renterd
+type Fs struct {
+	name          string        // name of this remote
+	root          string        // the path we are working on if any
+	opt           Options       // parsed config options
+	features      *fs.Features  // optional features
+	srv           *rest.Client  // the connection to renterd
+	pacer         *fs.Pacer     // pacer for API calls
+	rootBucket    string        // bucket part of root (if any)
+	rootDirectory string        // directory part of root (if any)
+	cache         *bucket.Cache // cache for bucket creation status
+}
+
+// Object describes a renterd object
+type Object struct {
+	fs       *Fs
+	remote   string
+	size     int64
+	modTime  time.Time         // time the object was uploaded
+	mimeType string            // MimeType of object - may be ""
+	eTag     string            // ETag of the object
+	meta     map[string]string // user metadata - nil if not read yet
+}
+
+// ------------------------------------------------------------
+
+// parsePath parses a remote 'url'
+func parsePath(path string) (root string) {
+	root = strings.Trim(path, "/")
+	return
+}
+
+// split returns bucket and bucketPath from the rootRelativePath
+// relative to f.root
+func (f *Fs) split(rootRelativePath string) (bucketName, bucketPath string) {
+	bucketName, bucketPath = bucket.Split(path.Join(f.root, rootRelativePath))
+	return f.opt.Enc.FromStandardName(bucketName), f.opt.Enc.FromStandardPath(bucketPath)
+}
+
+// split returns bucket and bucketPath from the object
+func (o *Object) split() (bucket, bucketPath string) {
+	return o.fs.split(o.remote)
+}
+
+func checkUploadChunkSize(cs fs.SizeSuffix) error {
+	if cs < minChunkSize {
+		return fmt.Errorf("%s is less than %s", cs, minChunkSize)
+	}
+	return nil
+}
+
+func (f *Fs) setUploadChunkSize(cs fs.SizeSuffix) (old fs.SizeSuffix, err error) {
+	err = checkUploadChunkSize(cs)
+	if err == nil {
+		old, f.opt.ChunkSize = f.opt.ChunkSize, cs
+	}
+	return
+}
+
+func (f *Fs) setUploadCutoff(cs fs.SizeSuffix) (old fs.SizeSuffix, err error) {
+	old, f.opt.UploadCutoff = f.opt.UploadCutoff, cs
+	return
+}
+
+// setRoot changes the root of the Fs
+func (f *Fs) setRoot(root string) {
+	f.root = parsePath(root)
+	f.rootBucket, f.rootDirectory = bucket.Split(f.root)
+}
+
+// objectPath returns the API path for bucketPath under prefix
+//
+// renterd object paths are absolute so always start with /
+func objectPath(prefix, bucketPath string) string {
+	return prefix + rest.URLPathEscape("/"+bucketPath)
+}
+
+// bucketParams returns the URL parameters selecting bucket
+func bucketParams(bucket string) url.Values {
+	return url.Values{"bucket": {bucket}}
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+//
+// renterd returns its errors as plain text.
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("error when trying to read error body: %w", err)
+	}
+	return &api.Error{
+		Message:    strings.TrimSpace(string(body)),
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+	}
+}
+
+// isNotFound returns true if err is a 404 from renterd
+func isNotFound(err error) bool {
+	var apiErr *api.Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// NewFs constructs an Fs from the path, bucket:path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	err = checkUploadChunkSize(opt.ChunkSize)
+	if err != nil {
+		return nil, fmt.Errorf("renterd: chunk size: %w", err)
+	}
+	if opt.UploadConcurrency < 1 {
+		opt.UploadConcurrency = 1
+	}
+	opt.APIURL = strings.TrimSuffix(opt.APIURL, "/")
+	u, err := url.Parse(opt.APIURL)
+	if err != nil {
+		return nil, fmt.Errorf("renterd: couldn't parse api_url: %w", err)
+	}
+
+	f := &Fs{
+		name:  name,
+		opt:   *opt,
+		cache: bucket.NewCache(),
+	}
+	f.setRoot(root)
+	f.pacer = fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant)))
+	f.features = (&fs.Features{
+		ReadMimeType:      true,
+		WriteMimeType:     true,
+		BucketBased:       true,
+		BucketBasedRootOK: true,
+		SlowModTime:       true,
+	}).Fill(ctx, f)
+
+	f.srv = rest.NewClient(fshttp.NewClient(ctx))
+	f.srv.SetRoot(u.String())
+	f.srv.SetErrorHandler(errorHandler)
+	if opt.APIPassword != "" {
+		password, err := obscure.Reveal(opt.APIPassword)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt API password: %w", err)
+		}
+		f.srv.SetUserPass("", password)
+	}
+
+	if f.rootBucket != "" && f.rootDirectory != "" {
+		// Check to see if the object exists
+		_, err := f.stat(ctx, f.rootBucket, f.opt.Enc.FromStandardPath(f.rootDirectory))
+		if err == nil {
+			newRoot := path.Dir(f.root)
+			if newRoot == "." {
+				newRoot = ""
+			}
+			f.setRoot(newRoot)
+			// return an error with an fs which points to the parent
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	if f.rootBucket == "" {
+		return "renterd root"
+	}
+	if f.rootDirectory == "" {
+		return fmt.Sprintf("renterd bucket %s", f.rootBucket)
+	}
+	return fmt.Sprintf("renterd bucket %s path %s", f.rootBucket, f.rootDirectory)
+}
+
+// Precision of the modification times stored in the metadata
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes are not exposed anywhere
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// stat reads the metadata of the object at bucketPath
+//
+// It returns fs.ErrorObjectNotFound if it isn't found.
+func (f *Fs) stat(ctx context.Context, bucket, bucketPath string) (info *api.Object, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       objectPath("/bus/objects", bucketPath),
+		Parameters: bucketParams(bucket),
+	}
+	opts.Parameters.Set("onlymetadata", "true")
+	var result api.ObjectsResponse
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(ctx, &opts, nil, &result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, err
+	}
+	if result.Object == nil {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return result.Object, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	return f.newObjectWithInfo(ctx, remote, nil)
+}
+
+// Return an Object from a path
+//
+// If it can't be found it returns the error ErrorObjectNotFound.
+func (f *Fs) newObjectWithInfo(ctx context.Context, remote string, info *api.ObjectMetadata) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	if info != nil {
+		o.setMetaData(info)
+	} else {
+		err := o.readMetaData(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// listFn is called from list to handle an object or directory
+type listFn func(remote string, info *api.ObjectMetadata, isDirectory bool) error
+
+// list the objects and directories in directory of bucket into the
+// function supplied
+//
+// prefix is the part of the path to remove from the names
+func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBucket bool, limit int, fn listFn) error {
+	if prefix != "" {
+		prefix += "/"
+	}
+	if directory != "" {
+		directory += "/"
+	}
+	if limit <= 0 || limit > listLimit {
+		limit = listLimit
+	}
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       objectPath("/bus/objects", directory),
+		Parameters: bucketParams(bucket),
+	}
+	opts.Parameters.Set("limit", strconv.Itoa(limit))
+	for {
+		var result api.ObjectsResponse
+		var resp *http.Response
+		err := f.pacer.Call(func() (bool, error) {
+			var err error
+			resp, err = f.srv.CallJSON(ctx, &opts, nil, &result)
+			return shouldRetry(ctx, resp, err)
+		})
+		if err != nil {
+			if isNotFound(err) {
+				return fs.ErrorDirNotFound
+			}
+			return err
+		}
+		for i := range result.Entries {
+			info := &result.Entries[i]
+			remote := strings.TrimPrefix(info.Name, "/")
+			isDirectory := strings.HasSuffix(remote, "/")
+			remote = f.opt.Enc.ToStandardPath(strings.TrimSuffix(remote, "/"))
+			if !strings.HasPrefix(remote, prefix) {
+				fs.Logf(f, "Odd name received %q", remote)
+				continue
+			}
+			remote = remote[len(prefix):]
+			if addBucket {
+				remote = path.Join(bucket, remote)
+			}
+			err = fn(remote, info, isDirectory)
+			if err != nil {
+				return err
+			}
+		}
+		if !result.HasMore || len(result.Entries) == 0 {
+			break
+		}
+		opts.Parameters.Set("marker", result.Entries[len(result.Entries)-1].Name)
+	}
+	return nil
+}
+
+// listDir lists files and directories to out
+func (f *Fs) listDir(ctx context.Context, bucket, directory, prefix string, addBucket bool) (entries fs.DirEntries, err error) {
+	err = f.list(ctx, bucket, directory, prefix, addBucket, 0, func(remote string, info *api.ObjectMetadata, isDirectory bool) error {
+		if isDirectory {
+			entries = append(entries, fs.NewDir(remote, info.ModTime).SetSize(info.Size))
+			return nil
+		}
+		o, err := f.newObjectWithInfo(ctx, remote, info)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, o)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// bucket must be present if listing succeeded
+	f.cache.MarkOK(bucket)
+	// directories only exist if they have objects in
+	if len(entries) == 0 && directory != "" {
+		return nil, fs.ErrorDirNotFound
+	}
+	return entries, nil
+}
+
+// listBuckets lists the buckets to out
+func (f *Fs) listBuckets(ctx context.Context) (entries fs.DirEntries, err error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   "/bus/buckets",
+	}
+	var result []api.Bucket
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(ctx, &opts, nil, &result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, bucket := range result {
+		entries = append(entries, fs.NewDir(f.opt.Enc.ToStandardName(bucket.Name), bucket.CreatedAt))
+		f.cache.MarkOK(bucket.Name)
+	}
+	return entries, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	bucket, directory := f.split(dir)
+	if bucket == "" {
+		if directory != "" {
+			return nil, fs.ErrorListBucketRequired
+		}
+		return f.listBuckets(ctx)
+	}
+	return f.listDir(ctx, bucket, directory, f.rootDirectory, f.rootBucket == "")
+}
+
+// isEmpty returns true if there are no objects in directory of bucket
+func (f *Fs) isEmpty(ctx context.Context, bucket, directory string) (empty bool, err error) {
+	empty = true
+	errFound := errors.New("found")
+	err = f.list(ctx, bucket, directory, directory, false, 1, func(remote string, info *api.ObjectMetadata, isDirectory bool) error {
+		empty = false
+		return errFound
+	})
+	if err == errFound {
+		err = nil
+	}
+	return empty, err
+}
+
+// Put the object into the bucket
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir creates the bucket if it doesn't exist
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	bucket, _ := f.split(dir)
+	return f.makeBucket(ctx, bucket)
+}
+
+// bucketExists returns true if the bucket exists
+func (f *Fs) bucketExists(ctx context.Context, bucket string) (bool, error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   "/bus/bucket/" + rest.URLPathEscape(bucket),
+	}
+	var result api.Bucket
+	var resp *http.Response
+	err := f.pacer.Call(func() (bool, error) {
+		var err error
+		resp, err = f.srv.CallJSON(ctx, &opts, nil, &result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// makeBucket creates the bucket if it doesn't exist
+func (f *Fs) makeBucket(ctx context.Context, bucket string) error {
+	return f.cache.Create(bucket, func() error {
+		found, err := f.bucketExists(ctx, bucket)
+		if err != nil || found {
+			return err
+		}
+		opts := rest.Opts{
+			Method:     "POST",
+			Path:       "/bus/buckets",
+			NoResponse: true,
+		}
+		req := api.BucketCreateRequest{
+			Name: bucket,
+		}
+		var resp *http.Response
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.srv.CallJSON(ctx, &opts, &req, nil)
+			return shouldRetry(ctx, resp, err)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create bucket %q: %w", bucket, err)
+		}
+		fs.Infof(f, "Bucket %q created", bucket)
+		return nil
+	}, func() (bool, error) {
+		return f.bucketExists(ctx, bucket)
+	})
+}
+
+// Rmdir deletes the bucket if the fs is at the root
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	bucket, directory := f.split(dir)
+	if bucket == "" || directory != "" {
+		return nil
+	}
+	empty, err := f.isEmpty(ctx, bucket, "")
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	return f.cache.Remove(bucket, func() error {
+		opts := rest.Opts{
+			Method:     "DELETE",
+			Path:       "/bus/bucket/" + rest.URLPathEscape(bucket),
+			NoResponse: true,
+		}
+		var resp *http.Response
+		err := f.pacer.Call(func() (bool, error) {
+			var err error
+			resp, err = f.srv.Call(ctx, &opts)
+			return shouldRetry(ctx, resp, err)
+		})
+		if err != nil {
+			if isNotFound(err) {
+				return fs.ErrorDirNotFound
+			}
+			return fmt.Errorf("failed to delete bucket %q: %w", bucket, err)
+		}
+		fs.Infof(f, "Bucket %q deleted", bucket)
+		return nil
+	})
+}
+
+// Purge deletes all the files in the directory
+//
+// Optional interface: Only implement this if you have a way of
+// deleting all the files quicker than just running Remove() on the
+// result of List()
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	bucket, directory := f.split(dir)
+	if bucket == "" {
+		return fs.ErrorCantPurge
+	}
+	prefix := ""
+	if directory != "" {
+		prefix = directory + "/"
+	}
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       objectPath("/worker/objects", prefix),
+		Parameters: bucketParams(bucket),
+		NoResponse: true,
+	}
+	opts.Parameters.Set("batch", "true")
+	var resp *http.Response
+	err := f.pacer.Call(func() (bool, error) {
+		var err error
+		resp, err = f.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to purge %q: %w", dir, err)
+	}
+	return f.Rmdir(ctx, dir)
+}
+
+// sameServer returns true if src is a renterd Fs on the same server as f
+func (f *Fs) sameServer(src fs.Info) (*Fs, bool) {
+	srcFs, ok := src.(*Fs)
+	if !ok || srcFs.opt.APIURL != f.opt.APIURL {
+		return nil, false
+	}
+	return srcFs, true
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	if _, ok := f.sameServer(srcObj.fs); !ok {
+		fs.Debugf(src, "Can't copy - not same renterd")
+		return nil, fs.ErrorCantCopy
+	}
+	dstBucket, dstPath := f.split(remote)
+	err := f.makeBucket(ctx, dstBucket)
+	if err != nil {
+		return nil, err
+	}
+	// Read the metadata so the modification time is preserved
+	if srcObj.meta == nil {
+		err = srcObj.readMetaData(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	srcBucket, srcPath := srcObj.split()
+	opts := rest.Opts{
+		Method:     "POST",
+		Path:       "/bus/objects/copy",
+		NoResponse: true,
+	}
+	req := api.CopyObjectsRequest{
+		SourceBucket:      srcBucket,
+		SourcePath:        "/" + srcPath,
+		DestinationBucket: dstBucket,
+		DestinationPath:   "/" + dstPath,
+		MimeType:          srcObj.mimeType,
+		Metadata:          srcObj.meta,
+	}
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(ctx, &opts, &req, nil)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("copy failed: %w", err)
+	}
+	return f.NewObject(ctx, remote)
+}
+
+// rename renames from to to in bucket using mode
+func (f *Fs) rename(ctx context.Context, bucket, from, to, mode string) error {
+	opts := rest.Opts{
+		Method:     "POST",
+		Path:       "/bus/objects/rename",
+		NoResponse: true,
+	}
+	req := api.ObjectsRenameRequest{
+		Bucket: bucket,
+		Force:  true,
+		From:   from,
+		To:     to,
+		Mode:   mode,
+	}
+	var resp *http.Response
+	return f.pacer.Call(func() (bool, error) {
+		var err error
+		resp, err = f.srv.CallJSON(ctx, &opts, &req, nil)
+		return shouldRetry(ctx, resp, err)
+	})
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	if _, ok := f.sameServer(srcObj.fs); !ok {
+		fs.Debugf(src, "Can't move - not same renterd")
+		return nil, fs.ErrorCantMove
+	}
+	srcBucket, srcPath := srcObj.split()
+	dstBucket, dstPath := f.split(remote)
+	if srcBucket != dstBucket {
+		fs.Debugf(src, "Can't move - objects can only be renamed within a bucket")
+		return nil, fs.ErrorCantMove
+	}
+	err := f.rename(ctx, srcBucket, "/"+srcPath, "/"+dstPath, api.RenameModeSingle)
+	if err != nil {
+		return nil, fmt.Errorf("move failed: %w", err)
+	}
+	return f.NewObject(ctx, remote)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := f.sameServer(src)
+	if !ok {
+		fs.Debugf(src, "Can't move directory - not same renterd")
+		return fs.ErrorCantDirMove
+	}
+	srcBucket, srcPath := srcFs.split(srcRemote)
+	dstBucket, dstPath := f.split(dstRemote)
+	if dstBucket != "" {
+		empty, err := f.isEmpty(ctx, dstBucket, dstPath)
+		if err != nil && err != fs.ErrorDirNotFound {
+			return err
+		}
+		if !empty {
+			return fs.ErrorDirExists
+		}
+	}
+	if srcBucket != dstBucket || srcPath == "" || dstPath == "" {
+		fs.Debugf(srcFs, "Can't move directory - directories can only be renamed within a bucket")
+		return fs.ErrorCantDirMove
+	}
+	err := f.rename(ctx, srcBucket, "/"+srcPath+"/", "/"+dstPath+"/", api.RenameModeMulti)
+	if err != nil {
+		return fmt.Errorf("directory move failed: %w", err)
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash is not supported
+func (o *Object) Hash(ctx context.Context, ty hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// setMetaData sets the metadata from info
+func (o *Object) setMetaData(info *api.ObjectMetadata) {
+	o.size = info.Size
+	o.modTime = info.ModTime
+	o.mimeType = info.MimeType
+	o.eTag = info.ETag
+}
+
+// readMetaData gets the metadata if it hasn't already been fetched
+func (o *Object) readMetaData(ctx context.Context) error {
+	bucket, bucketPath := o.split()
+	info, err := o.fs.stat(ctx, bucket, bucketPath)
+	if err != nil {
+		return err
+	}
+	o.setMetaData(&info.ObjectMetadata)
+	o.meta = info.Metadata
+	if o.meta == nil {
+		o.meta = map[string]string{}
+	}
+	return nil
+}
+
+// ModTime returns the modification time of the object
+//
+// It attempts to read the objects mtime and if that isn't present the
+// time the object was uploaded is returned
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if o.meta == nil {
+		err := o.readMetaData(ctx)
+		if err != nil {
+			fs.Logf(o, "Failed to read metadata: %v", err)
+			return o.modTime
+		}
+	}
+	for key, value := range o.meta {
+		if !strings.EqualFold(key, metaMtime) {
+			continue
+		}
+		modTime, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			fs.Debugf(o, "Failed to parse mod time %q: %v", value, err)
+			break
+		}
+		return modTime
+	}
+	return o.modTime
+}
+
+// SetModTime is not supported as renterd can't update the metadata
+// of an existing object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTimeWithoutDelete
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	bucket, bucketPath := o.split()
+	fs.FixRangeOption(options, o.size)
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       objectPath("/worker/objects", bucketPath),
+		Parameters: bucketParams(bucket),
+		Options:    options,
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	bucket, bucketPath := o.split()
+	err = o.fs.makeBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	size := src.Size()
+	if size < 0 || size >= int64(o.fs.opt.UploadCutoff) {
+		_, err = multipart.UploadMultipart(ctx, src, in, multipart.UploadMultipartOptions{
+			Open:        o.fs,
+			OpenOptions: options,
+		})
+	} else {
+		err = o.uploadSinglepart(ctx, bucket, bucketPath, in, src, size, options)
+	}
+	if err != nil {
+		return err
+	}
+	// Read the metadata back to find the size, ETag and upload time
+	o.meta = nil
+	return o.readMetaData(ctx)
+}
+
+// uploadSinglepart uploads in with a single PUT
+func (o *Object) uploadSinglepart(ctx context.Context, bucket, bucketPath string, in io.Reader, src fs.ObjectInfo, size int64, options []fs.OpenOption) error {
+	opts := rest.Opts{
+		Method:        "PUT",
+		Path:          objectPath("/worker/objects", bucketPath),
+		Parameters:    bucketParams(bucket),
+		Body:          in,
+		ContentLength: &size,
+		ExtraHeaders: map[string]string{
+			metaHeaderPrefix + metaMtime: src.ModTime(ctx).Format(time.RFC3339Nano),
+		},
+		Options:    options,
+		NoResponse: true,
+	}
+	if mimeType := fs.MimeType(ctx, src); mimeType != "" {
+		opts.Parameters.Set("mimetype", mimeType)
+	}
+	var resp *http.Response
+	return o.fs.pacer.CallNoRetry(func() (bool, error) {
+		var err error
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	bucket, bucketPath := o.split()
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       objectPath("/worker/objects", bucketPath),
+		Parameters: bucketParams(bucket),
+		NoResponse: true,
+	}
+	var resp *http.Response
+	err := o.fs.pacer.Call(func() (bool, error) {
+		var err error
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if isNotFound(err) {
+		return fs.ErrorObjectNotFound
+	}
+	return err
+}
+
+// MimeType of an Object if known, "" otherwise
+func (o *Object) MimeType(ctx context.Context) string {
+	return o.mimeType
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = &Fs{}
+	_ fs.Copier          = &Fs{}
+	_ fs.Mover           = &Fs{}
+	_ fs.DirMover        = &Fs{}
+	_ fs.Purger          = &Fs{}
+	_ fs.PutStreamer     = &Fs{}
+	_ fs.OpenChunkWriter = &Fs{}
+	_ fs.Object          = &Object{}
+	_ fs.MimeTyper       = &Object{}
+)
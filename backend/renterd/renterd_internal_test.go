@@ -0,0 +1,451 @@
+package renterd
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/renterd/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fakePassword = "potato"
+
+// fakeObject is an object stored in fakeRenterd
+type fakeObject struct {
+	data     []byte
+	modTime  time.Time
+	mimeType string
+	meta     map[string]string
+}
+
+func (o *fakeObject) info(name string) api.ObjectMetadata {
+	sum := md5.Sum(o.data)
+	return api.ObjectMetadata{
+		ETag:     hex.EncodeToString(sum[:]),
+		Health:   1,
+		ModTime:  o.modTime,
+		Name:     name,
+		Size:     int64(len(o.data)),
+		MimeType: o.mimeType,
+	}
+}
+
+// fakeUpload is a multipart upload in progress
+type fakeUpload struct {
+	bucket   string
+	path     string
+	mimeType string
+	meta     map[string]string
+	parts    map[int][]byte
+}
+
+// fakeRenterd implements the parts of the renterd bus and worker
+// API used by the backend
+type fakeRenterd struct {
+	mu      sync.Mutex
+	buckets map[string]map[string]*fakeObject // bucket -> path -> object
+	created map[string]time.Time
+	uploads map[string]*fakeUpload
+	nextID  int
+}
+
+func newFakeRenterd() *fakeRenterd {
+	return &fakeRenterd{
+		buckets: map[string]map[string]*fakeObject{},
+		created: map[string]time.Time{},
+		uploads: map[string]*fakeUpload{},
+	}
+}
+
+// fakeError is returned by the handlers to set the status code
+type fakeError struct {
+	status int
+	msg    string
+}
+
+func (e fakeError) Error() string {
+	return e.msg
+}
+
+var (
+	errBucketNotFound = fakeError{http.StatusNotFound, "bucket not found"}
+	errObjectNotFound = fakeError{http.StatusNotFound, "object not found"}
+)
+
+func (r *fakeRenterd) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if _, password, ok := req.BasicAuth(); !ok || password != fakePassword {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	r.mu.Lock()
+	result, err := r.handle(w, req)
+	r.mu.Unlock()
+	if err != nil {
+		status := http.StatusInternalServerError
+		if e, ok := err.(fakeError); ok {
+			status = e.status
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	switch result := result.(type) {
+	case nil:
+		w.WriteHeader(http.StatusOK)
+	case *fakeObject:
+		w.Header().Set("Content-Type", result.mimeType)
+		http.ServeContent(w, req, "", result.modTime, bytes.NewReader(result.data))
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	}
+}
+
+// bucket returns the objects in the bucket named in the request
+func (r *fakeRenterd) bucket(req *http.Request) (map[string]*fakeObject, error) {
+	objects, ok := r.buckets[req.URL.Query().Get("bucket")]
+	if !ok {
+		return nil, errBucketNotFound
+	}
+	return objects, nil
+}
+
+func (r *fakeRenterd) handle(w http.ResponseWriter, req *http.Request) (any, error) {
+	p := req.URL.Path
+	q := req.URL.Query()
+	switch {
+	case p == "/bus/buckets" && req.Method == "GET":
+		buckets := []api.Bucket{}
+		for name, created := range r.created {
+			buckets = append(buckets, api.Bucket{Name: name, CreatedAt: created})
+		}
+		return buckets, nil
+	case p == "/bus/buckets" && req.Method == "POST":
+		var create api.BucketCreateRequest
+		if err := json.NewDecoder(req.Body).Decode(&create); err != nil {
+			return nil, fakeError{http.StatusBadRequest, err.Error()}
+		}
+		if _, found := r.buckets[create.Name]; found {
+			return nil, fakeError{http.StatusConflict, "bucket already exists"}
+		}
+		r.buckets[create.Name] = map[string]*fakeObject{}
+		r.created[create.Name] = time.Now()
+		return nil, nil
+	case strings.HasPrefix(p, "/bus/bucket/"):
+		name := strings.TrimPrefix(p, "/bus/bucket/")
+		objects, found := r.buckets[name]
+		if !found {
+			return nil, errBucketNotFound
+		}
+		if req.Method == "DELETE" {
+			if len(objects) > 0 {
+				return nil, fakeError{http.StatusConflict, "bucket not empty"}
+			}
+			delete(r.buckets, name)
+			delete(r.created, name)
+			return nil, nil
+		}
+		return api.Bucket{Name: name, CreatedAt: r.created[name]}, nil
+	case p == "/bus/objects/rename":
+		return r.rename(req)
+	case p == "/bus/objects/copy":
+		return r.copy(req)
+	case strings.HasPrefix(p, "/bus/objects/"):
+		objects, err := r.bucket(req)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(p, "/bus/objects")
+		if strings.HasSuffix(name, "/") {
+			return r.list(objects, name, q.Get("marker"), q.Get("limit"))
+		}
+		o, found := objects[name]
+		if !found {
+			return nil, errObjectNotFound
+		}
+		return api.ObjectsResponse{Object: &api.Object{ObjectMetadata: o.info(name), Metadata: o.meta}}, nil
+	case strings.HasPrefix(p, "/bus/multipart/"):
+		return r.multipart(req, strings.TrimPrefix(p, "/bus/multipart/"))
+	case strings.HasPrefix(p, "/worker/objects/"):
+		objects, err := r.bucket(req)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(p, "/worker/objects")
+		switch req.Method {
+		case "GET":
+			o, found := objects[name]
+			if !found {
+				return nil, errObjectNotFound
+			}
+			return o, nil
+		case "PUT":
+			data, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			meta := map[string]string{}
+			for key := range req.Header {
+				if strings.HasPrefix(key, metaHeaderPrefix) {
+					meta[strings.TrimPrefix(key, metaHeaderPrefix)] = req.Header.Get(key)
+				}
+			}
+			objects[name] = &fakeObject{data: data, modTime: time.Now(), mimeType: q.Get("mimetype"), meta: meta}
+			return nil, nil
+		case "DELETE":
+			if q.Get("batch") == "true" {
+				for key := range objects {
+					if strings.HasPrefix(key, name) {
+						delete(objects, key)
+					}
+				}
+				return nil, nil
+			}
+			if _, found := objects[name]; !found {
+				return nil, errObjectNotFound
+			}
+			delete(objects, name)
+			return nil, nil
+		}
+	case strings.HasPrefix(p, "/worker/multipart/") && req.Method == "PUT":
+		upload, found := r.uploads[q.Get("uploadid")]
+		if !found {
+			return nil, fakeError{http.StatusNotFound, "multipart upload not found"}
+		}
+		partNumber, err := strconv.Atoi(q.Get("partnumber"))
+		if err != nil || partNumber < 1 {
+			return nil, fakeError{http.StatusBadRequest, "bad part number"}
+		}
+		if _, err := strconv.ParseInt(q.Get("offset"), 10, 64); err != nil {
+			return nil, fakeError{http.StatusBadRequest, "bad offset"}
+		}
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		upload.parts[partNumber] = data
+		sum := md5.Sum(data)
+		w.Header().Set("ETag", hex.EncodeToString(sum[:]))
+		return nil, nil
+	}
+	return nil, fakeError{http.StatusNotFound, fmt.Sprintf("unknown endpoint %s %s", req.Method, p)}
+}
+
+// list the directory dir of objects
+func (r *fakeRenterd) list(objects map[string]*fakeObject, dir, marker, limitString string) (any, error) {
+	limit, err := strconv.Atoi(limitString)
+	if err != nil {
+		limit = -1
+	}
+	entries := map[string]api.ObjectMetadata{}
+	for name, o := range objects {
+		if !strings.HasPrefix(name, dir) {
+			continue
+		}
+		leaf, _, isDir := strings.Cut(strings.TrimPrefix(name, dir), "/")
+		if isDir {
+			entry := entries[dir+leaf+"/"]
+			entry.Name = dir + leaf + "/"
+			entry.Size += int64(len(o.data))
+			entries[entry.Name] = entry
+		} else {
+			entries[name] = o.info(name)
+		}
+	}
+	var result api.ObjectsResponse
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		if name > marker {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if limit >= 0 && len(names) > limit {
+		names = names[:limit]
+		result.HasMore = true
+	}
+	for _, name := range names {
+		result.Entries = append(result.Entries, entries[name])
+	}
+	return result, nil
+}
+
+// rename objects
+func (r *fakeRenterd) rename(req *http.Request) (any, error) {
+	var rename api.ObjectsRenameRequest
+	if err := json.NewDecoder(req.Body).Decode(&rename); err != nil {
+		return nil, fakeError{http.StatusBadRequest, err.Error()}
+	}
+	objects, found := r.buckets[rename.Bucket]
+	if !found {
+		return nil, errBucketNotFound
+	}
+	switch rename.Mode {
+	case api.RenameModeSingle:
+		o, found := objects[rename.From]
+		if !found {
+			return nil, errObjectNotFound
+		}
+		delete(objects, rename.From)
+		objects[rename.To] = o
+	case api.RenameModeMulti:
+		for name, o := range objects {
+			if strings.HasPrefix(name, rename.From) {
+				delete(objects, name)
+				objects[rename.To+strings.TrimPrefix(name, rename.From)] = o
+			}
+		}
+	default:
+		return nil, fakeError{http.StatusBadRequest, "bad mode"}
+	}
+	return nil, nil
+}
+
+// copy an object
+func (r *fakeRenterd) copy(req *http.Request) (any, error) {
+	var cp api.CopyObjectsRequest
+	if err := json.NewDecoder(req.Body).Decode(&cp); err != nil {
+		return nil, fakeError{http.StatusBadRequest, err.Error()}
+	}
+	src, srcFound := r.buckets[cp.SourceBucket]
+	dst, dstFound := r.buckets[cp.DestinationBucket]
+	if !srcFound || !dstFound {
+		return nil, errBucketNotFound
+	}
+	o, found := src[cp.SourcePath]
+	if !found {
+		return nil, errObjectNotFound
+	}
+	dst[cp.DestinationPath] = &fakeObject{data: o.data, modTime: time.Now(), mimeType: cp.MimeType, meta: cp.Metadata}
+	return nil, nil
+}
+
+// multipart handles the multipart calls on the bus
+func (r *fakeRenterd) multipart(req *http.Request, action string) (any, error) {
+	switch action {
+	case "create":
+		var create api.MultipartCreateRequest
+		if err := json.NewDecoder(req.Body).Decode(&create); err != nil {
+			return nil, fakeError{http.StatusBadRequest, err.Error()}
+		}
+		if _, found := r.buckets[create.Bucket]; !found {
+			return nil, errBucketNotFound
+		}
+		r.nextID++
+		id := strconv.Itoa(r.nextID)
+		r.uploads[id] = &fakeUpload{
+			bucket:   create.Bucket,
+			path:     create.Path,
+			mimeType: create.MimeType,
+			meta:     create.Metadata,
+			parts:    map[int][]byte{},
+		}
+		return api.MultipartCreateResponse{UploadID: id}, nil
+	case "complete":
+		var complete api.MultipartCompleteRequest
+		if err := json.NewDecoder(req.Body).Decode(&complete); err != nil {
+			return nil, fakeError{http.StatusBadRequest, err.Error()}
+		}
+		upload, found := r.uploads[complete.UploadID]
+		if !found {
+			return nil, fakeError{http.StatusNotFound, "multipart upload not found"}
+		}
+		var data []byte
+		for i, part := range complete.Parts {
+			if i > 0 && part.PartNumber <= complete.Parts[i-1].PartNumber {
+				return nil, fakeError{http.StatusBadRequest, "parts out of order"}
+			}
+			partData, found := upload.parts[part.PartNumber]
+			sum := md5.Sum(partData)
+			if !found || hex.EncodeToString(sum[:]) != part.ETag {
+				return nil, fakeError{http.StatusBadRequest, "bad part"}
+			}
+			data = append(data, partData...)
+		}
+		r.buckets[upload.bucket][upload.path] = &fakeObject{data: data, modTime: time.Now(), mimeType: upload.mimeType, meta: upload.meta}
+		delete(r.uploads, complete.UploadID)
+		return nil, nil
+	case "abort":
+		var abort api.MultipartAbortRequest
+		if err := json.NewDecoder(req.Body).Decode(&abort); err != nil {
+			return nil, fakeError{http.StatusBadRequest, err.Error()}
+		}
+		delete(r.uploads, abort.UploadID)
+		return nil, nil
+	}
+	return nil, fakeError{http.StatusNotFound, "unknown multipart action"}
+}
+
+// TestFakeRenterd runs the integration tests against a fake renterd
+func TestFakeRenterd(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	srv := httptest.NewServer(newFakeRenterd())
+	defer srv.Close()
+	name := "TestRenterdFake"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":rclone",
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "renterd"},
+			{Name: name, Key: "api_url", Value: srv.URL},
+			{Name: name, Key: "api_password", Value: obscure.MustObscure(fakePassword)},
+		},
+		ChunkedUpload: fstests.ChunkedUploadConfig{
+			MinChunkSize: minChunkSize,
+		},
+		QuickTestOK: true,
+	})
+}
+
+// TestListPaging checks listings longer than a page are read completely
+func TestListPaging(t *testing.T) {
+	ctx := context.Background()
+	r := newFakeRenterd()
+	r.buckets["bucket"] = map[string]*fakeObject{}
+	const n = 2*listLimit + 10
+	for i := range n {
+		r.buckets["bucket"][fmt.Sprintf("/dir/file%05d", i)] = &fakeObject{data: []byte("hello"), modTime: time.Now()}
+	}
+	r.buckets["bucket"]["/dir/sub/file"] = &fakeObject{modTime: time.Now()}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	f, err := fs.NewFs(ctx, fmt.Sprintf(":renterd,api_url='%s',api_password='%s':bucket", srv.URL, obscure.MustObscure(fakePassword)))
+	require.NoError(t, err)
+	entries, err := f.List(ctx, "dir")
+	require.NoError(t, err)
+	require.Len(t, entries, n+1)
+	objects, dirs := 0, 0
+	for _, entry := range entries {
+		if _, ok := entry.(fs.Directory); ok {
+			dirs++
+			assert.Equal(t, "dir/sub", entry.Remote())
+		} else {
+			objects++
+		}
+	}
+	assert.Equal(t, 1, dirs)
+	assert.Equal(t, n, objects)
+
+	_, err = f.List(ctx, "missing")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+}
@@ -0,0 +1,977 @@
+package rsyncd
+
+// This file implements the client side of version 29 of the rsync
+// protocol as spoken by rsync daemons.
+//
+// Version 29 is the newest version of the protocol which doesn't
+// need incremental recursion or varint encoding and is spoken by all
+// rsync daemons since rsync 2.6.4.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/crypto/md4" //nolint:staticcheck // MD4 is what version 29 of the rsync protocol uses
+)
+
+const (
+	protocolVersion = 29
+	chunkSize       = 32 * 1024 // largest literal data token we send
+	ndxDone         = -1        // file index meaning end of phase
+	maxPhase        = 2         // number of phases after the first
+	checksumSize    = md4.Size  // size of the whole file checksum
+)
+
+// Multiplexed message tags
+const (
+	mplexBase      = 7
+	msgData        = 0
+	msgErrorXfer   = 1
+	msgInfo        = 2
+	msgError       = 3
+	msgWarning     = 4
+	msgErrorSocket = 5
+	msgLog         = 6
+	msgClient      = 7
+	msgErrorUTF8   = 8
+	msgIOError     = 22
+	msgErrorExit   = 86
+)
+
+// File list flags
+const (
+	xmitTopDir        = 1 << 0
+	xmitSameMode      = 1 << 1
+	xmitExtendedFlags = 1 << 2
+	xmitSameUID       = 1 << 3
+	xmitSameGID       = 1 << 4
+	xmitSameName      = 1 << 5
+	xmitLongName      = 1 << 6
+	xmitSameTime      = 1 << 7
+)
+
+// Item flags sent with file indexes
+const (
+	itemBasisTypeFollows = 1 << 11
+	itemXNameFollows     = 1 << 12
+	itemTransfer         = 1 << 15
+)
+
+// File modes
+const (
+	modeTypeMask = 0170000
+	modeDir      = 0040000
+	modeRegular  = 0100000
+)
+
+// fileEntry is an entry in a file list
+type fileEntry struct {
+	name    string // path relative to the root of the transfer
+	size    int64
+	modTime int64 // Unix time in seconds
+	mode    uint32
+}
+
+// isDir returns true if the entry is a directory
+func (e *fileEntry) isDir() bool {
+	return e.mode&modeTypeMask == modeDir
+}
+
+// isRegular returns true if the entry is a regular file
+func (e *fileEntry) isRegular() bool {
+	return e.mode&modeTypeMask == modeRegular
+}
+
+// conn is a connection to an rsync daemon
+type conn struct {
+	nc        net.Conn
+	br        *bufio.Reader // reads from the daemon
+	bw        *bufio.Writer // writes to the daemon
+	logger    any           // what to log messages against
+	seed      int32         // checksum seed sent by the daemon
+	multiplex bool          // set if input is multiplexed
+	remaining int           // bytes of data left in the current message
+	errors    []string      // error messages sent by the daemon
+	buf       [8]byte
+}
+
+// newConn makes a conn from a connected net.Conn
+func newConn(nc net.Conn, logger any) *conn {
+	return &conn{
+		nc:     nc,
+		br:     bufio.NewReader(nc),
+		bw:     bufio.NewWriter(nc),
+		logger: logger,
+	}
+}
+
+// Close the connection
+func (c *conn) Close() error {
+	return c.nc.Close()
+}
+
+// wrapError adds the errors sent by the daemon to err
+func (c *conn) wrapError(err error) error {
+	if err == nil || len(c.errors) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w: %s", err, strings.Join(c.errors, "; "))
+}
+
+// readLine reads a line of the daemon handshake
+func (c *conn) readLine() (string, error) {
+	line, err := c.br.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read from rsync daemon: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// writeLine writes a line of the daemon handshake
+func (c *conn) writeLine(line string) error {
+	_, err := c.bw.WriteString(line + "\n")
+	if err != nil {
+		return err
+	}
+	return c.bw.Flush()
+}
+
+// greet exchanges protocol versions with the daemon
+func (c *conn) greet() error {
+	err := c.writeLine(fmt.Sprintf("@RSYNCD: %d.0", protocolVersion))
+	if err != nil {
+		return err
+	}
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	greeting, found := strings.CutPrefix(line, "@RSYNCD: ")
+	if !found {
+		return fmt.Errorf("not an rsync daemon: %q", line)
+	}
+	version, _, _ := strings.Cut(greeting, " ")
+	version, _, _ = strings.Cut(version, ".")
+	remote, err := strconv.Atoi(version)
+	if err != nil {
+		return fmt.Errorf("bad rsync daemon greeting %q: %w", line, err)
+	}
+	if remote < protocolVersion {
+		return fmt.Errorf("rsync daemon protocol version %d is too old, need %d", remote, protocolVersion)
+	}
+	return nil
+}
+
+// authHash returns the response to the challenge from the daemon
+func authHash(password, challenge string) string {
+	h := md4.New()
+	_, _ = h.Write([]byte{0, 0, 0, 0}) // checksum seed of 0
+	_, _ = h.Write([]byte(password))
+	_, _ = h.Write([]byte(challenge))
+	return base64.RawStdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// daemonError makes an error from an "@ERROR: message" line
+func daemonError(line string) error {
+	msg := strings.TrimPrefix(strings.TrimPrefix(line, "@ERROR"), ":")
+	return fmt.Errorf("rsync daemon: %s", strings.TrimSpace(msg))
+}
+
+// selectModule selects the module, authenticating if required
+func (c *conn) selectModule(module, user, password string) error {
+	err := c.writeLine(module)
+	if err != nil {
+		return err
+	}
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "@RSYNCD: OK":
+			return nil
+		case strings.HasPrefix(line, "@RSYNCD: AUTHREQD "):
+			if user == "" {
+				return fmt.Errorf("rsync module %q needs a user and password", module)
+			}
+			challenge := strings.TrimPrefix(line, "@RSYNCD: AUTHREQD ")
+			err = c.writeLine(user + " " + authHash(password, challenge))
+			if err != nil {
+				return err
+			}
+		case line == "@RSYNCD: EXIT":
+			return fmt.Errorf("rsync daemon closed connection to module %q", module)
+		case strings.HasPrefix(line, "@ERROR"):
+			return daemonError(line)
+		default:
+			fs.Debugf(c.logger, "motd: %s", line)
+		}
+	}
+}
+
+// module is a module listed by the daemon
+type module struct {
+	name    string
+	comment string
+}
+
+// listModules reads the list of modules from the daemon
+func (c *conn) listModules() (modules []module, err error) {
+	err = c.writeLine("#list")
+	if err != nil {
+		return nil, err
+	}
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case line == "@RSYNCD: EXIT":
+			return modules, nil
+		case strings.HasPrefix(line, "@ERROR"):
+			return nil, daemonError(line)
+		case strings.HasPrefix(line, "@RSYNCD: "):
+			continue
+		}
+		name, comment, found := strings.Cut(line, "\t")
+		if !found {
+			// probably part of the motd
+			fs.Debugf(c.logger, "motd: %s", line)
+			continue
+		}
+		modules = append(modules, module{
+			name:    strings.TrimSpace(name),
+			comment: strings.TrimSpace(comment),
+		})
+	}
+}
+
+// start sends the arguments for the server side of the transfer to
+// the daemon and reads the checksum seed.
+func (c *conn) start(args []string) error {
+	for _, arg := range args {
+		if strings.ContainsRune(arg, '\n') {
+			return fmt.Errorf("can't send %q to rsync daemon as it contains a new line", arg)
+		}
+		_, err := c.bw.WriteString(arg + "\n")
+		if err != nil {
+			return err
+		}
+	}
+	err := c.writeLine("")
+	if err != nil {
+		return err
+	}
+	seed, err := c.readInt()
+	if err != nil {
+		return err
+	}
+	c.seed = seed
+	// All output from the daemon is multiplexed from now on
+	c.multiplex = true
+	return nil
+}
+
+// Read reads data from the daemon, demultiplexing it if needed
+func (c *conn) Read(p []byte) (n int, err error) {
+	if !c.multiplex {
+		return c.br.Read(p)
+	}
+	for c.remaining == 0 {
+		err = c.readMessage()
+		if err != nil {
+			return 0, err
+		}
+	}
+	if len(p) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err = c.br.Read(p)
+	c.remaining -= n
+	return n, err
+}
+
+// readMessage reads the header of a multiplexed message, handling
+// it if it isn't data
+func (c *conn) readMessage() error {
+	_, err := io.ReadFull(c.br, c.buf[:4])
+	if err != nil {
+		return err
+	}
+	header := binary.LittleEndian.Uint32(c.buf[:4])
+	tag := int(header>>24) - mplexBase
+	size := int(header & 0xFFFFFF)
+	if tag == msgData {
+		c.remaining = size
+		return nil
+	}
+	payload := make([]byte, size)
+	_, err = io.ReadFull(c.br, payload)
+	if err != nil {
+		return err
+	}
+	msg := strings.TrimRight(string(payload), "\n")
+	switch tag {
+	case msgInfo, msgClient:
+		fs.Debugf(c.logger, "rsync: %s", msg)
+	case msgLog:
+		fs.Debugf(c.logger, "rsync log: %s", msg)
+	case msgWarning:
+		fs.Logf(c.logger, "rsync: %s", msg)
+	case msgError, msgErrorXfer, msgErrorSocket, msgErrorUTF8:
+		fs.Debugf(c.logger, "rsync error: %s", msg)
+		c.errors = append(c.errors, msg)
+	case msgIOError, msgErrorExit:
+		if size >= 4 {
+			fs.Debugf(c.logger, "rsync exit/io error code %d", int32(binary.LittleEndian.Uint32(payload)))
+		}
+	default:
+		fs.Debugf(c.logger, "Ignoring rsync message %d length %d", tag, size)
+	}
+	return nil
+}
+
+// readFull reads exactly len(p) bytes
+func (c *conn) readFull(p []byte) error {
+	_, err := io.ReadFull(c, p)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return c.wrapError(err)
+}
+
+// readByte reads a byte
+func (c *conn) readByte() (byte, error) {
+	err := c.readFull(c.buf[:1])
+	return c.buf[0], err
+}
+
+// readShort reads an unsigned 16 bit int
+func (c *conn) readShort() (uint16, error) {
+	err := c.readFull(c.buf[:2])
+	return binary.LittleEndian.Uint16(c.buf[:2]), err
+}
+
+// readInt reads a signed 32 bit int
+func (c *conn) readInt() (int32, error) {
+	err := c.readFull(c.buf[:4])
+	return int32(binary.LittleEndian.Uint32(c.buf[:4])), err
+}
+
+// readLongint reads a signed 64 bit int sent as 32 bits if it fits
+func (c *conn) readLongint() (int64, error) {
+	n, err := c.readInt()
+	if err != nil || n != -1 {
+		return int64(n), err
+	}
+	err = c.readFull(c.buf[:8])
+	return int64(binary.LittleEndian.Uint64(c.buf[:8])), err
+}
+
+// writeByte writes a byte
+func (c *conn) writeByte(b byte) {
+	_ = c.bw.WriteByte(b)
+}
+
+// writeShort writes an unsigned 16 bit int
+func (c *conn) writeShort(n uint16) {
+	_, _ = c.bw.Write(binary.LittleEndian.AppendUint16(c.buf[:0], n))
+}
+
+// writeInt writes a signed 32 bit int
+func (c *conn) writeInt(n int32) {
+	_, _ = c.bw.Write(binary.LittleEndian.AppendUint32(c.buf[:0], uint32(n)))
+}
+
+// writeLongint writes a signed 64 bit int as 32 bits if it fits
+func (c *conn) writeLongint(n int64) {
+	if n >= 0 && n <= 0x7FFFFFFF {
+		c.writeInt(int32(n))
+		return
+	}
+	c.writeInt(-1)
+	_, _ = c.bw.Write(binary.LittleEndian.AppendUint64(c.buf[:0], uint64(n)))
+}
+
+// flush the output, returning any write errors
+func (c *conn) flush() error {
+	return c.bw.Flush()
+}
+
+// sendRules sends filter rules, each like "+ /name" or "- *"
+func (c *conn) sendRules(rules []string) {
+	for _, rule := range rules {
+		c.writeInt(int32(len(rule)))
+		_, _ = c.bw.WriteString(rule)
+	}
+	c.writeInt(0)
+}
+
+// readFileList reads a file list from the daemon returning it sorted
+// in the order the daemon uses for file indexes.
+func (c *conn) readFileList() (entries []*fileEntry, err error) {
+	var last fileEntry
+	for {
+		b, err := c.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 {
+			break
+		}
+		flags := int(b)
+		if flags&xmitExtendedFlags != 0 {
+			b, err = c.readByte()
+			if err != nil {
+				return nil, err
+			}
+			flags |= int(b) << 8
+		}
+		var prefix int
+		if flags&xmitSameName != 0 {
+			b, err = c.readByte()
+			if err != nil {
+				return nil, err
+			}
+			prefix = int(b)
+		}
+		var suffix int
+		if flags&xmitLongName != 0 {
+			n, err := c.readInt()
+			if err != nil {
+				return nil, err
+			}
+			suffix = int(n)
+		} else {
+			b, err = c.readByte()
+			if err != nil {
+				return nil, err
+			}
+			suffix = int(b)
+		}
+		if prefix > len(last.name) || suffix < 0 || suffix > 64*1024 {
+			return nil, errors.New("corrupt file list from rsync daemon")
+		}
+		name := make([]byte, prefix+suffix)
+		copy(name, last.name[:prefix])
+		err = c.readFull(name[prefix:])
+		if err != nil {
+			return nil, err
+		}
+		entry := fileEntry{
+			name:    string(name),
+			modTime: last.modTime,
+			mode:    last.mode,
+		}
+		entry.size, err = c.readLongint()
+		if err != nil {
+			return nil, err
+		}
+		if flags&xmitSameTime == 0 {
+			n, err := c.readInt()
+			if err != nil {
+				return nil, err
+			}
+			entry.modTime = int64(n)
+		}
+		if flags&xmitSameMode == 0 {
+			n, err := c.readInt()
+			if err != nil {
+				return nil, err
+			}
+			entry.mode = uint32(n)
+		}
+		// We don't ask for owners, devices, links, hard links
+		// or checksums so nothing more is sent
+		last = entry
+		entries = append(entries, &entry)
+	}
+	ioError, err := c.readInt()
+	if err != nil {
+		return nil, err
+	}
+	if ioError != 0 {
+		fs.Debugf(c.logger, "rsync daemon had I/O errors making file list: %d", ioError)
+	}
+	sortFileList(entries)
+	return entries, nil
+}
+
+// writeFileList writes the file list in entries
+func (c *conn) writeFileList(entries []*fileEntry) {
+	for _, entry := range entries {
+		// Send every entry in full. The flags can't be 0 as that
+		// marks the end of the list, so directories other than
+		// the top one send an empty set of extended flags.
+		flags := xmitTopDir
+		if entry.isDir() && entry.name != "." {
+			flags = xmitExtendedFlags
+		}
+		if len(entry.name) > 255 {
+			flags |= xmitLongName
+		}
+		if flags&xmitExtendedFlags != 0 {
+			c.writeShort(uint16(flags))
+		} else {
+			c.writeByte(byte(flags))
+		}
+		if flags&xmitLongName != 0 {
+			c.writeInt(int32(len(entry.name)))
+		} else {
+			c.writeByte(byte(len(entry.name)))
+		}
+		_, _ = c.bw.WriteString(entry.name)
+		c.writeLongint(entry.size)
+		c.writeInt(int32(entry.modTime))
+		c.writeInt(int32(entry.mode))
+	}
+	c.writeByte(0)
+	c.writeInt(0) // no I/O errors
+}
+
+// finish ends a transfer where no more files are wanted by running
+// through the remaining phases, reading the stats and saying goodbye.
+//
+// sent is the number of end of phase markers already sent.
+func (c *conn) finish(sent int) error {
+	for ; sent <= maxPhase; sent++ {
+		c.writeInt(ndxDone)
+	}
+	err := c.flush()
+	if err != nil {
+		return err
+	}
+	// The daemon echoes the end of each phase
+	for range maxPhase + 1 {
+		ndx, err := c.readInt()
+		if err != nil {
+			return err
+		}
+		if ndx != ndxDone {
+			return c.wrapError(fmt.Errorf("unexpected file index %d from rsync daemon", ndx))
+		}
+	}
+	// Read the stats: total read, total written, total size, file
+	// list build time, file list transfer time
+	for range 5 {
+		_, err := c.readLongint()
+		if err != nil {
+			return err
+		}
+	}
+	// Say goodbye
+	c.writeInt(ndxDone)
+	return c.flush()
+}
+
+// requestFile asks the daemon to send the whole of the file with index ndx
+func (c *conn) requestFile(ndx int) error {
+	c.writeInt(int32(ndx))
+	c.writeShort(itemTransfer)
+	// No checksums for blocks as we want the whole file
+	for range 4 {
+		c.writeInt(0)
+	}
+	// End of the first phase
+	c.writeInt(ndxDone)
+	return c.flush()
+}
+
+// readFileStart reads the index, attributes and checksum header the
+// daemon sends before the data of a file
+func (c *conn) readFileStart() (ndx int, err error) {
+	n, err := c.readInt()
+	if err != nil {
+		return 0, err
+	}
+	if n == ndxDone {
+		return 0, c.wrapError(errors.New("rsync daemon didn't send file"))
+	}
+	iflags, err := c.readShort()
+	if err != nil {
+		return 0, err
+	}
+	err = c.readAttrs(iflags)
+	if err != nil {
+		return 0, err
+	}
+	var sumHead [4]int32
+	for i := range sumHead {
+		sumHead[i], err = c.readInt()
+		if err != nil {
+			return 0, err
+		}
+	}
+	if sumHead[0] != 0 {
+		return 0, fmt.Errorf("rsync daemon sent %d block checksums but none were sent", sumHead[0])
+	}
+	return int(n), nil
+}
+
+// readAttrs reads the parts sent after the item flags
+func (c *conn) readAttrs(iflags uint16) error {
+	if iflags&itemBasisTypeFollows != 0 {
+		_, err := c.readByte()
+		if err != nil {
+			return err
+		}
+	}
+	if iflags&itemXNameFollows != 0 {
+		b, err := c.readByte()
+		if err != nil {
+			return err
+		}
+		n := int(b)
+		if n&0x80 != 0 {
+			b2, err := c.readByte()
+			if err != nil {
+				return err
+			}
+			n = (n&^0x80)<<8 | int(b2)
+		}
+		err = c.readFull(make([]byte, n))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileReader reads the data of a file sent by the daemon, checking
+// its checksum at the end.
+type fileReader struct {
+	c       *conn
+	hash    hash.Hash
+	literal int   // bytes of literal data left in the current token
+	err     error // sticky error
+	done    bool  // set at the end of the file
+}
+
+// newFileReader reads the tokens of a file from c
+func newFileReader(c *conn) *fileReader {
+	h := md4.New()
+	_, _ = h.Write(binary.LittleEndian.AppendUint32(nil, uint32(c.seed)))
+	return &fileReader{
+		c:    c,
+		hash: h,
+	}
+}
+
+// Read the data of the file
+func (r *fileReader) Read(p []byte) (n int, err error) {
+	for r.err == nil && r.literal == 0 && !r.done {
+		r.err = r.readToken()
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.done {
+		return 0, io.EOF
+	}
+	if len(p) > r.literal {
+		p = p[:r.literal]
+	}
+	n, err = io.ReadFull(r.c, p)
+	r.literal -= n
+	_, _ = r.hash.Write(p[:n])
+	if err != nil {
+		r.err = r.c.wrapError(err)
+		return n, r.err
+	}
+	return n, nil
+}
+
+// readToken reads the next token, checking the checksum at the end
+func (r *fileReader) readToken() error {
+	token, err := r.c.readInt()
+	if err != nil {
+		return err
+	}
+	switch {
+	case token > 0:
+		r.literal = int(token)
+		return nil
+	case token < 0:
+		return fmt.Errorf("rsync daemon sent block match %d but no blocks were sent", -(token + 1))
+	}
+	sum := make([]byte, checksumSize)
+	err = r.c.readFull(sum)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sum, r.hash.Sum(nil)) {
+		return errors.New("rsync file checksum mismatch")
+	}
+	r.done = true
+	return nil
+}
+
+// sendFile sends the data of the file with index ndx to the daemon
+// when it asks for it, then ends the transfer.
+//
+// If ndx is negative then no file is sent.
+func (c *conn) sendFile(ndx int, in io.Reader, size int64) error {
+	sent := false
+	phase := 0
+	for {
+		n, err := c.readInt()
+		if err != nil {
+			return err
+		}
+		if n == ndxDone {
+			phase++
+			if phase > maxPhase {
+				break
+			}
+			c.writeInt(ndxDone)
+			err = c.flush()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		iflags, err := c.readShort()
+		if err != nil {
+			return err
+		}
+		err = c.readAttrs(iflags)
+		if err != nil {
+			return err
+		}
+		if iflags&itemTransfer == 0 {
+			continue
+		}
+		if int(n) != ndx {
+			return c.wrapError(fmt.Errorf("rsync daemon asked for unexpected file index %d", n))
+		}
+		if sent {
+			return c.wrapError(errors.New("rsync daemon asked for file to be sent again"))
+		}
+		var sumHead [4]int32
+		for i := range sumHead {
+			sumHead[i], err = c.readInt()
+			if err != nil {
+				return err
+			}
+		}
+		// Discard any block checksums as we send the whole file
+		count, s2length := sumHead[0], sumHead[2]
+		if count < 0 || s2length < 0 || s2length > checksumSize {
+			return errors.New("bad checksum header from rsync daemon")
+		}
+		for range count {
+			err = c.readFull(make([]byte, 4+s2length))
+			if err != nil {
+				return err
+			}
+		}
+		c.writeInt(n)
+		c.writeShort(iflags &^ (itemBasisTypeFollows | itemXNameFollows))
+		for _, n := range sumHead {
+			c.writeInt(n)
+		}
+		err = c.sendData(in, size)
+		if err != nil {
+			return err
+		}
+		sent = true
+	}
+	c.writeInt(ndxDone)
+	err := c.flush()
+	if err != nil {
+		return err
+	}
+	// Read the goodbye
+	n, err := c.readInt()
+	if err != nil {
+		return err
+	}
+	if n != ndxDone {
+		return c.wrapError(fmt.Errorf("unexpected file index %d from rsync daemon", n))
+	}
+	if ndx >= 0 && !sent {
+		return c.wrapError(errors.New("rsync daemon didn't ask for file"))
+	}
+	return nil
+}
+
+// sendData sends size bytes of in as literal data tokens followed
+// by the checksum
+func (c *conn) sendData(in io.Reader, size int64) error {
+	h := md4.New()
+	_, _ = h.Write(binary.LittleEndian.AppendUint32(nil, uint32(c.seed)))
+	buf := make([]byte, chunkSize)
+	for size > 0 {
+		n := min(int64(len(buf)), size)
+		_, err := io.ReadFull(in, buf[:n])
+		if err != nil {
+			return fmt.Errorf("failed to read data to send: %w", err)
+		}
+		_, _ = h.Write(buf[:n])
+		c.writeInt(int32(n))
+		_, err = c.bw.Write(buf[:n])
+		if err != nil {
+			return err
+		}
+		size -= n
+	}
+	c.writeInt(0)
+	_, _ = c.bw.Write(h.Sum(nil))
+	return c.flush()
+}
+
+// The states and types used in comparing file names
+const (
+	stateDir = iota
+	stateSlash
+	stateBase
+	stateTrailing
+)
+
+const (
+	typePath = iota
+	typeItem
+)
+
+// nameCmp is the state of one side of a file name comparison
+type nameCmp struct {
+	entry *fileEntry
+	base  string // base name of the entry
+	s     string // what is left to compare of the current part
+	state int
+	typ   int
+}
+
+// next moves on to the next part of the name
+func (n *nameCmp) next() {
+	switch n.state {
+	case stateDir:
+		n.state = stateSlash
+		n.s = "/"
+	case stateSlash:
+		n.typ = typeItem
+		if n.entry.isDir() {
+			n.typ = typePath
+		}
+		n.s = n.base
+		n.state = stateBase
+	case stateBase:
+		n.state = stateTrailing
+		if n.typ == typePath {
+			n.s = "/"
+			return
+		}
+		n.typ = typeItem
+	case stateTrailing:
+		n.typ = typeItem
+	}
+}
+
+// newNameCmp starts a comparison of entry with directory dir
+func newNameCmp(entry *fileEntry, dir, base string, sameDir bool) *nameCmp {
+	n := &nameCmp{entry: entry, base: base}
+	if sameDir || dir == "" {
+		n.typ = typeItem
+		if entry.isDir() {
+			n.typ = typePath
+		}
+		n.s = base
+		n.state = stateBase
+		// "." sorts first
+		if n.typ == typePath && base == "." {
+			n.typ = typeItem
+			n.state = stateTrailing
+			n.s = ""
+		}
+	} else {
+		n.typ = typePath
+		n.s = dir
+		n.state = stateDir
+	}
+	return n
+}
+
+// splitName splits a file list name into directory and base name
+func splitName(name string) (dir, base string) {
+	i := strings.LastIndexByte(name, '/')
+	if i < 0 {
+		return "", name
+	}
+	return name[:i], name[i+1:]
+}
+
+// compareFileNames compares the names of two entries in the way
+// rsync sorts file lists, which is with files before directories.
+func compareFileNames(a, b *fileEntry) int {
+	dirA, baseA := splitName(a.name)
+	dirB, baseB := splitName(b.name)
+	sameDir := dirA == dirB
+	n1 := newNameCmp(a, dirA, baseA, sameDir)
+	n2 := newNameCmp(b, dirB, baseB, sameDir)
+	if n1.typ != n2.typ {
+		if n1.typ == typePath {
+			return 1
+		}
+		return -1
+	}
+	for {
+		if n1.s == "" {
+			if n1.state == stateTrailing && n2.s == "" && n2.state == stateTrailing {
+				return 0
+			}
+			n1.next()
+			if n2.s != "" && n1.typ != n2.typ {
+				if n1.typ == typePath {
+					return 1
+				}
+				return -1
+			}
+		}
+		if n2.s == "" {
+			n2.next()
+			if n1.s != "" && n1.typ != n2.typ {
+				if n1.typ == typePath {
+					return 1
+				}
+				return -1
+			}
+		}
+		var c1, c2 int
+		if n1.s != "" {
+			c1 = int(n1.s[0])
+			n1.s = n1.s[1:]
+		}
+		if n2.s != "" {
+			c2 = int(n2.s[0])
+			n2.s = n2.s[1:]
+		}
+		if c1 != c2 {
+			return c1 - c2
+		}
+		if c1 == 0 && n1.state == stateTrailing && n2.state == stateTrailing {
+			return 0
+		}
+	}
+}
+
+// sortFileList sorts entries in the order rsync uses for file indexes
+func sortFileList(entries []*fileEntry) {
+	// insertion sort as it is stable and the lists are mostly sorted
+	for i := 1; i < len(entries); i++ {
+		for j := i; j > 0 && compareFileNames(entries[j-1], entries[j]) > 0; j-- {
+			entries[j-1], entries[j] = entries[j], entries[j-1]
+		}
+	}
+}
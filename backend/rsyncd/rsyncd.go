@@ -0,0 +1,828 @@
+// Package rsyncd provides an interface to rsync daemons
+package rsyncd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/list"
+	"github.com/rclone/rclone/lib/bucket"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/readers"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+	dirMode       = modeDir | 0755
+	fileMode      = modeRegular | 0644
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "rsyncd",
+		Description: "Rsync daemon",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:      "host",
+			Help:      "Rsync daemon host to connect to.\n\nE.g. \"rsync.example.com\".",
+			Required:  true,
+			Sensitive: true,
+		}, {
+			Name:    "port",
+			Help:    "Rsync daemon port number.",
+			Default: 873,
+		}, {
+			Name: "user",
+			Help: `Rsync user name.
+
+Leave blank for modules which don't need authentication.`,
+			Sensitive: true,
+		}, {
+			Name:       "pass",
+			Help:       "Rsync password.\n\nThis is the password from the secrets file of the module.",
+			IsPassword: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			// Rsync daemons store file names as bytes on a
+			// unix file system. Control characters are encoded
+			// as version 29 of the protocol separates its
+			// arguments with new lines.
+			Default: (encoder.Base |
+				encoder.EncodeCtl),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Host string               `config:"host"`
+	Port int                  `config:"port"`
+	User string               `config:"user"`
+	Pass string               `config:"pass"`
+	Enc  encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents a remote rsync daemon
+type Fs struct {
+	name          string       // name of this remote
+	root          string       // the path we are working on
+	opt           Options      // parsed options
+	features      *fs.Features // optional features
+	pass          string       // revealed password
+	pacer         *fs.Pacer    // pacer for connections
+	rootModule    string       // module part of root (if any)
+	rootDirectory string       // directory part of root (if any)
+}
+
+// Object describes a file on an rsync daemon
+type Object struct {
+	fs      *Fs
+	remote  string
+	size    int64
+	modTime time.Time
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	if f.rootModule == "" {
+		return fmt.Sprintf("rsync daemon %s", f.opt.Host)
+	}
+	if f.rootDirectory == "" {
+		return fmt.Sprintf("rsync daemon %s module %s", f.opt.Host, f.rootModule)
+	}
+	return fmt.Sprintf("rsync daemon %s module %s path %s", f.opt.Host, f.rootModule, f.rootDirectory)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the modification times rsync daemons store
+func (f *Fs) Precision() time.Duration {
+	return time.Second
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// shouldRetry returns a boolean as to whether this err deserves to be
+// retried. It returns the err as a convenience
+func shouldRetry(ctx context.Context, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	if err == nil {
+		return false, nil
+	}
+	// Daemons which are busy say "max connections (N) reached -- try again later"
+	if strings.Contains(err.Error(), "try again later") {
+		return true, err
+	}
+	return fserrors.ShouldRetry(err), err
+}
+
+// parsePath parses a remote 'url'
+func parsePath(path string) (root string) {
+	root = strings.Trim(path, "/")
+	return
+}
+
+// split returns module and modulePath from the rootRelativePath
+// relative to f.root
+func (f *Fs) split(rootRelativePath string) (moduleName, modulePath string) {
+	return bucket.Split(path.Join(f.root, rootRelativePath))
+}
+
+// split returns module and modulePath from the object
+func (o *Object) split() (moduleName, modulePath string) {
+	return o.fs.split(o.remote)
+}
+
+// setRoot changes the root of the Fs
+func (f *Fs) setRoot(root string) {
+	f.root = parsePath(root)
+	f.rootModule, f.rootDirectory = bucket.Split(f.root)
+}
+
+// NewFs constructs an Fs from the path, module:path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.Host == "" {
+		return nil, errors.New("host not set")
+	}
+	f := &Fs{
+		name:  name,
+		opt:   *opt,
+		pacer: fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+	}
+	if opt.Pass != "" {
+		f.pass, err = obscure.Reveal(opt.Pass)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt password: %w", err)
+		}
+	}
+	f.setRoot(root)
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		BucketBasedRootOK:       true,
+	}).Fill(ctx, f)
+	if f.rootModule != "" && f.rootDirectory != "" {
+		// Check to see if the root is actually an existing file
+		oldRoot := f.root
+		newRoot, leaf := path.Split(oldRoot)
+		f.setRoot(newRoot)
+		_, err := f.NewObject(ctx, leaf)
+		if err != nil {
+			// File doesn't exist so return old f
+			f.setRoot(oldRoot)
+			return f, nil
+		}
+		// return an error with an fs which points to the parent
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// connect opens a connection to the daemon and selects module if set
+func (f *Fs) connect(ctx context.Context, module string) (*conn, error) {
+	addr := net.JoinHostPort(f.opt.Host, strconv.Itoa(f.opt.Port))
+	nc, err := fshttp.NewDialer(ctx).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to rsync daemon: %w", err)
+	}
+	c := newConn(nc, f)
+	err = c.greet()
+	if err == nil && module != "" {
+		err = c.selectModule(f.opt.Enc.FromStandardName(module), f.opt.User, f.pass)
+	}
+	if err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// escapeWild escapes name if it contains the wildcards rsync uses in
+// path arguments and filter rules.
+func escapeWild(name string) string {
+	if !strings.ContainsAny(name, "*?[") {
+		return name
+	}
+	var out strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(`*?[\`, r) {
+			out.WriteRune('\\')
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// daemonPath returns the path of modulePath in module to send to the
+// daemon.
+//
+// Paths of files the daemon sends are globbed by it so wildcards
+// need escaping.
+func (f *Fs) daemonPath(module, modulePath string, sending bool) string {
+	p := f.opt.Enc.FromStandardPath(modulePath)
+	if sending {
+		p = escapeWild(p)
+	}
+	return f.opt.Enc.FromStandardName(module) + "/" + p
+}
+
+// readFileList asks the daemon to send the list of files at
+// modulePath in module.
+//
+// If modulePath is a directory, set dir to read its contents and
+// recurse to read them recursively. Otherwise the entry for
+// modulePath itself is read.
+//
+// It returns fs.ErrorDirNotFound if modulePath isn't found.
+func (f *Fs) readFileList(ctx context.Context, module, modulePath string, dir, recurse bool) (entries []*fileEntry, err error) {
+	args := []string{"--server", "--sender", "-d"}
+	if recurse {
+		args[2] = "-r"
+	}
+	p := f.daemonPath(module, modulePath, true)
+	if dir && modulePath != "" {
+		p += "/"
+	}
+	args = append(args, ".", p)
+	err = f.pacer.Call(func() (bool, error) {
+		entries, err = f.readFileListOnce(ctx, module, args)
+		return shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fs.ErrorDirNotFound
+	}
+	return entries, nil
+}
+
+// readFileListOnce runs one attempt of readFileList
+func (f *Fs) readFileListOnce(ctx context.Context, module string, args []string) (entries []*fileEntry, err error) {
+	c, err := f.connect(ctx, module)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(c, &err)
+	err = c.start(args)
+	if err != nil {
+		return nil, err
+	}
+	c.sendRules(nil)
+	err = c.flush()
+	if err != nil {
+		return nil, err
+	}
+	entries, err = c.readFileList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file list: %w", err)
+	}
+	if len(entries) == 0 {
+		// The daemon stops straight away if it has nothing to send
+		fs.Debugf(f, "Empty file list: %s", strings.Join(c.errors, "; "))
+		return nil, nil
+	}
+	err = c.finish(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finish listing: %w", err)
+	}
+	return entries, nil
+}
+
+// newDirEntry makes a directory entry from a file list entry or
+// returns nil if it isn't a file or a directory
+func (f *Fs) newDirEntry(remote string, entry *fileEntry) fs.DirEntry {
+	modTime := time.Unix(entry.modTime, 0)
+	switch {
+	case entry.isDir():
+		return fs.NewDir(remote, modTime)
+	case entry.isRegular():
+		return &Object{
+			fs:      f,
+			remote:  remote,
+			size:    entry.size,
+			modTime: modTime,
+		}
+	}
+	return nil
+}
+
+// listModules lists the modules of the daemon
+func (f *Fs) listModules(ctx context.Context) (entries fs.DirEntries, err error) {
+	var modules []module
+	err = f.pacer.Call(func() (bool, error) {
+		c, err := f.connect(ctx, "")
+		if err != nil {
+			return shouldRetry(ctx, err)
+		}
+		modules, err = c.listModules()
+		_ = c.Close()
+		return shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list modules: %w", err)
+	}
+	for _, module := range modules {
+		entries = append(entries, fs.NewDir(f.opt.Enc.ToStandardName(module.name), time.Time{}))
+	}
+	return entries, nil
+}
+
+// listDir lists directory in module calling fn with each entry
+func (f *Fs) listDir(ctx context.Context, module, directory, prefix string, recurse bool, fn func(fs.DirEntry) error) error {
+	fileList, err := f.readFileList(ctx, module, directory, true, recurse)
+	if err != nil {
+		return err
+	}
+	if !fileList[0].isDir() {
+		return fs.ErrorDirNotFound
+	}
+	for _, entry := range fileList {
+		if entry.name == "." {
+			continue
+		}
+		remote := path.Join(prefix, f.opt.Enc.ToStandardPath(entry.name))
+		d := f.newDirEntry(remote, entry)
+		if d == nil {
+			continue
+		}
+		err = fn(d)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	module, directory := f.split(dir)
+	if module == "" {
+		if directory != "" {
+			return nil, fs.ErrorListBucketRequired
+		}
+		return f.listModules(ctx)
+	}
+	err = f.listDir(ctx, module, directory, dir, false, func(entry fs.DirEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// It should call callback for each tranche of entries read.
+// These need not be returned in any particular order.  If
+// callback returns an error then the listing will stop
+// immediately.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	module, directory := f.split(dir)
+	list := list.NewHelper(callback)
+	if module == "" {
+		if directory != "" {
+			return fs.ErrorListBucketRequired
+		}
+		entries, err := f.listModules(ctx)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			err = list.Add(entry)
+			if err != nil {
+				return err
+			}
+			module := entry.Remote()
+			err = f.listDir(ctx, module, "", module, true, list.Add)
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		err = f.listDir(ctx, module, directory, dir, true, list.Add)
+		if err != nil {
+			return err
+		}
+	}
+	return list.Flush()
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	module, modulePath := f.split(remote)
+	if module == "" || modulePath == "" {
+		return nil, fs.ErrorObjectNotFound
+	}
+	fileList, err := f.readFileList(ctx, module, modulePath, false, false)
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		return nil, fs.ErrorObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry := fileList[0]
+	if entry.isDir() {
+		return nil, fs.ErrorIsDir
+	}
+	o, ok := f.newDirEntry(remote, entry).(*Object)
+	if !ok {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return o, nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// parentEntries returns file list entries for "." and each of the
+// directories modulePath is in.
+func parentEntries(modulePath string) []*fileEntry {
+	now := time.Now().Unix()
+	entries := []*fileEntry{{name: ".", modTime: now, mode: dirMode}}
+	dir := path.Dir(modulePath)
+	if dir == "." {
+		return entries
+	}
+	for i := range dir {
+		if dir[i] == '/' {
+			entries = append(entries, &fileEntry{name: dir[:i], modTime: now, mode: dirMode})
+		}
+	}
+	return append(entries, &fileEntry{name: dir, modTime: now, mode: dirMode})
+}
+
+// send sends the entries to the root of module, sending the data
+// in in for the entry with index ndx if it is not negative.
+//
+// If rules are set then they are sent as filter rules and files
+// on the daemon which aren't in entries and aren't excluded are
+// deleted.
+func (f *Fs) send(ctx context.Context, module, directory string, entries []*fileEntry, ndx int, in io.Reader, rules []string) (err error) {
+	c, err := f.connect(ctx, module)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(c, &err)
+	args := []string{"--server", "-rtOIW"}
+	if rules != nil {
+		args = append(args, "--delete")
+	}
+	args = append(args, ".", f.daemonPath(module, directory, false)+"/")
+	err = c.start(args)
+	if err != nil {
+		return err
+	}
+	if rules != nil {
+		c.sendRules(rules)
+	}
+	c.writeFileList(entries)
+	err = c.flush()
+	if err != nil {
+		return err
+	}
+	// The daemon asks for files by their index in the sorted list
+	var size int64
+	if ndx >= 0 {
+		want := entries[ndx]
+		size = want.size
+		sortFileList(entries)
+		for i, entry := range entries {
+			if entry == want {
+				ndx = i
+			}
+		}
+	}
+	err = c.sendFile(ndx, in, size)
+	if err != nil {
+		return err
+	}
+	if len(c.errors) > 0 {
+		return errors.New(strings.Join(c.errors, "; "))
+	}
+	return nil
+}
+
+// Mkdir creates the module or directory if it doesn't exist
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	module, modulePath := f.split(dir)
+	if module == "" || modulePath == "" {
+		// Modules can't be created so assume they exist
+		return nil
+	}
+	entries := parentEntries(modulePath)
+	entries = append(entries, &fileEntry{
+		name:    f.opt.Enc.FromStandardPath(modulePath),
+		modTime: time.Now().Unix(),
+		mode:    dirMode,
+	})
+	for _, entry := range entries[1:] {
+		entry.name = f.opt.Enc.FromStandardPath(entry.name)
+	}
+	err := f.send(ctx, module, "", entries, -1, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to make directory: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the file or directory at modulePath in module
+func (f *Fs) remove(ctx context.Context, module, modulePath string) error {
+	dir, leaf := path.Split(modulePath)
+	dir = strings.TrimSuffix(dir, "/")
+	rules := []string{
+		"+ /" + escapeWild(f.opt.Enc.FromStandardName(leaf)),
+		"- *",
+	}
+	entries := []*fileEntry{{name: ".", modTime: time.Now().Unix(), mode: dirMode}}
+	return f.send(ctx, module, dir, entries, -1, nil, rules)
+}
+
+// Rmdir deletes the directory if it is empty
+//
+// Modules can't be removed so removing one does nothing.
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	module, modulePath := f.split(dir)
+	if module == "" || modulePath == "" {
+		return nil
+	}
+	fileList, err := f.readFileList(ctx, module, modulePath, true, false)
+	if err != nil {
+		return err
+	}
+	if !fileList[0].isDir() {
+		return fs.ErrorDirNotFound
+	}
+	if len(fileList) > 1 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	err = f.remove(ctx, module, modulePath)
+	if err != nil {
+		return fmt.Errorf("failed to remove directory: %w", err)
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash of an object returning a lowercase hex string
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the object
+//
+// Rsync can only set the time by uploading the file again.
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Storable returns whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// download reads the file from the daemon
+type download struct {
+	c *conn
+	r *fileReader
+}
+
+// Read the data of the file
+func (d *download) Read(p []byte) (int, error) {
+	return d.r.Read(p)
+}
+
+// Close the download, finishing the transfer if all the file was read
+func (d *download) Close() (err error) {
+	defer fs.CheckClose(d.c, &err)
+	if !d.r.done {
+		// Stop the transfer by closing the connection
+		return nil
+	}
+	return d.c.finish(1)
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	module, modulePath := o.split()
+	c, err := o.fs.connect(ctx, module)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = c.Close()
+		}
+	}()
+	err = c.start([]string{"--server", "--sender", ".", o.fs.daemonPath(module, modulePath, true)})
+	if err != nil {
+		return nil, err
+	}
+	c.sendRules(nil)
+	err = c.flush()
+	if err != nil {
+		return nil, err
+	}
+	fileList, err := c.readFileList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file list: %w", err)
+	}
+	ndx := -1
+	for i, entry := range fileList {
+		if entry.isRegular() {
+			ndx = i
+			break
+		}
+	}
+	if ndx < 0 {
+		return nil, fs.ErrorObjectNotFound
+	}
+	err = c.requestFile(ndx)
+	if err != nil {
+		return nil, err
+	}
+	gotNdx, err := c.readFileStart()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start download: %w", err)
+	}
+	if gotNdx != ndx {
+		return nil, fmt.Errorf("rsync daemon sent file index %d instead of %d", gotNdx, ndx)
+	}
+	in = &download{c: c, r: newFileReader(c)}
+	if offset > 0 {
+		_, err = io.CopyN(io.Discard, in, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seek to %d: %w", offset, err)
+		}
+	}
+	if limit >= 0 {
+		in = readers.NewLimitedReadCloser(in, limit)
+	}
+	return in, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	size := src.Size()
+	if size < 0 {
+		return errors.New("rsyncd can't upload files of unknown size")
+	}
+	modTime := src.ModTime(ctx)
+	module, modulePath := o.split()
+	if module == "" || modulePath == "" {
+		return errors.New("can't upload files to the root or a module root")
+	}
+	entries := parentEntries(modulePath)
+	entries = append(entries, &fileEntry{
+		name:    modulePath,
+		size:    size,
+		modTime: modTime.Unix(),
+		mode:    fileMode,
+	})
+	for _, entry := range entries[1:] {
+		entry.name = o.fs.opt.Enc.FromStandardPath(entry.name)
+	}
+	err := o.fs.send(ctx, module, "", entries, len(entries)-1, in, nil)
+	if err != nil {
+		return fmt.Errorf("failed to upload: %w", err)
+	}
+	o.size = size
+	o.modTime = time.Unix(modTime.Unix(), 0)
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	module, modulePath := o.split()
+	err := o.fs.remove(ctx, module, modulePath)
+	if err != nil {
+		return fmt.Errorf("failed to remove: %w", err)
+	}
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs      = &Fs{}
+	_ fs.ListRer = &Fs{}
+	_ fs.Object  = &Object{}
+)
@@ -0,0 +1,979 @@
+package rsyncd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/md4" //nolint:staticcheck // needed to check the rsync protocol
+)
+
+const (
+	fakeUser      = "user"
+	fakePass      = "secret password"
+	fakeChallenge = "9sYUxuTqJtXwSZJ4mqhNIA"
+	fakeSeed      = 0x4a3b2c1d
+	fakeChunkSize = 10000 // size of data tokens the fake sends
+)
+
+// fakeDaemon is an rsync daemon speaking version 29 of the protocol
+// which serves the modules "test" and "secret" from directories in
+// root. The "secret" module needs fakeUser and fakePass and isn't
+// listed.
+//
+// It is written independently of the client to check it.
+type fakeDaemon struct {
+	t    *testing.T
+	root string
+	host string
+	port int
+}
+
+// newFakeDaemon starts a fake daemon which is stopped at the end of t
+func newFakeDaemon(t *testing.T) *fakeDaemon {
+	root := t.TempDir()
+	for _, module := range []string{"test", "secret"} {
+		require.NoError(t, os.Mkdir(filepath.Join(root, module), 0777))
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	addr := l.Addr().(*net.TCPAddr)
+	d := &fakeDaemon{
+		t:    t,
+		root: root,
+		host: addr.IP.String(),
+		port: addr.Port,
+	}
+	go func() {
+		for {
+			nc, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go d.serve(nc)
+		}
+	}()
+	return d
+}
+
+// serve a connection
+func (d *fakeDaemon) serve(nc net.Conn) {
+	defer func() { _ = nc.Close() }()
+	fc := &fakeConn{
+		d:  d,
+		nc: nc,
+		br: bufio.NewReader(nc),
+	}
+	fc.out = bufio.NewWriter(nc)
+	err := fc.run()
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, net.ErrClosed) {
+		d.t.Logf("fake daemon: %v", err)
+	}
+}
+
+// mplexWriter writes data as multiplexed data messages
+type mplexWriter struct {
+	w io.Writer
+}
+
+// Write p as a data message
+func (m mplexWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	header := binary.LittleEndian.AppendUint32(nil, uint32(mplexBase+msgData)<<24|uint32(len(p)))
+	_, err := m.w.Write(append(header, p...))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// fakeConn is the daemon end of a connection
+type fakeConn struct {
+	d   *fakeDaemon
+	nc  net.Conn
+	br  *bufio.Reader
+	out *bufio.Writer
+}
+
+// message sends a message which isn't data
+func (fc *fakeConn) message(tag int, msg string) {
+	_ = fc.out.Flush()
+	header := binary.LittleEndian.AppendUint32(nil, uint32(mplexBase+tag)<<24|uint32(len(msg)))
+	_, _ = fc.nc.Write(append(header, msg...))
+}
+
+func (fc *fakeConn) readLine() (string, error) {
+	line, err := fc.br.ReadString('\n')
+	return strings.TrimSuffix(line, "\n"), err
+}
+
+func (fc *fakeConn) writeLine(line string) {
+	_, _ = fc.out.WriteString(line + "\n")
+	_ = fc.out.Flush()
+}
+
+func (fc *fakeConn) readBytes(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	_, err := io.ReadFull(fc.br, buf)
+	return buf, err
+}
+
+func (fc *fakeConn) readByte() (byte, error) {
+	return fc.br.ReadByte()
+}
+
+func (fc *fakeConn) readShort() (int, error) {
+	b, err := fc.readBytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.LittleEndian.Uint16(b)), nil
+}
+
+func (fc *fakeConn) readInt() (int, error) {
+	b, err := fc.readBytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return int(int32(binary.LittleEndian.Uint32(b))), nil
+}
+
+func (fc *fakeConn) readLongint() (int64, error) {
+	n, err := fc.readInt()
+	if err != nil || n != -1 {
+		return int64(n), err
+	}
+	b, err := fc.readBytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(b)), nil
+}
+
+func (fc *fakeConn) writeInt(n int) {
+	_, _ = fc.out.Write(binary.LittleEndian.AppendUint32(nil, uint32(int32(n))))
+}
+
+func (fc *fakeConn) writeShort(n int) {
+	_, _ = fc.out.Write(binary.LittleEndian.AppendUint16(nil, uint16(n)))
+}
+
+func (fc *fakeConn) writeLongint(n int64) {
+	if n <= 0x7FFFFFFF {
+		fc.writeInt(int(n))
+		return
+	}
+	fc.writeInt(-1)
+	_, _ = fc.out.Write(binary.LittleEndian.AppendUint64(nil, uint64(n)))
+}
+
+// fakeAuthHash is the response the daemon expects to the challenge
+func fakeAuthHash(password string) string {
+	h := md4.New()
+	_, _ = h.Write(make([]byte, 4))
+	_, _ = h.Write([]byte(password + fakeChallenge))
+	return base64.RawStdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// run the daemon end of the connection
+func (fc *fakeConn) run() error {
+	fc.writeLine("@RSYNCD: 31.0 sha512 sha256 sha1 md5 md4")
+	line, err := fc.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "@RSYNCD: 29.") {
+		return fmt.Errorf("bad greeting %q", line)
+	}
+	fc.writeLine("Welcome to the fake rsync daemon")
+	module, err := fc.readLine()
+	if err != nil {
+		return err
+	}
+	switch module {
+	case "#list":
+		fc.writeLine("test           \tTest module")
+		fc.writeLine("@RSYNCD: EXIT")
+		return nil
+	case "test":
+	case "secret":
+		fc.writeLine("@RSYNCD: AUTHREQD " + fakeChallenge)
+		line, err = fc.readLine()
+		if err != nil {
+			return err
+		}
+		if line != fakeUser+" "+fakeAuthHash(fakePass) {
+			fc.writeLine("@ERROR: auth failed on module secret")
+			return nil
+		}
+	default:
+		fc.writeLine(fmt.Sprintf("@ERROR: Unknown module '%s'", module))
+		return nil
+	}
+	fc.writeLine("@RSYNCD: OK")
+
+	// Read the arguments
+	var sender, recurse, del, dot bool
+	var paths []string
+	for {
+		arg, err := fc.readLine()
+		if err != nil {
+			return err
+		}
+		if arg == "" {
+			break
+		}
+		switch {
+		case dot:
+			paths = append(paths, arg)
+		case arg == ".":
+			dot = true
+		case arg == "--server":
+		case arg == "--sender":
+			sender = true
+		case arg == "--delete":
+			del = true
+		case strings.HasPrefix(arg, "--"):
+			return fmt.Errorf("unknown option %q", arg)
+		case strings.HasPrefix(arg, "-"):
+			recurse = recurse || strings.Contains(arg, "r")
+		}
+	}
+	if len(paths) != 1 {
+		return fmt.Errorf("need 1 path, got %q", paths)
+	}
+	p, found := strings.CutPrefix(paths[0], module)
+	if !found {
+		return fmt.Errorf("path %q not in module %q", paths[0], module)
+	}
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		p = "."
+	}
+	moduleDir := filepath.Join(fc.d.root, module)
+
+	// Send the seed then multiplex the output
+	fc.writeInt(fakeSeed)
+	_ = fc.out.Flush()
+	fc.out = bufio.NewWriter(mplexWriter{w: fc.nc})
+	if sender {
+		return fc.sender(moduleDir, p, recurse)
+	}
+	return fc.receiver(moduleDir, p, del)
+}
+
+// fakeLess sorts file lists in the order rsync does
+func fakeLess(a, b *fileEntry) bool {
+	if a.name == "." || b.name == "." {
+		return a.name == "." && b.name != "."
+	}
+	as, bs := strings.Split(a.name, "/"), strings.Split(b.name, "/")
+	for i := 0; ; i++ {
+		if i == len(as) || i == len(bs) {
+			// the shorter is a parent of the longer
+			return len(as) < len(bs)
+		}
+		aDir := i < len(as)-1 || a.isDir()
+		bDir := i < len(bs)-1 || b.isDir()
+		if aDir != bDir {
+			// files come before directories
+			return bDir
+		}
+		if as[i] != bs[i] {
+			if aDir {
+				return as[i]+"/" < bs[i]+"/"
+			}
+			return as[i] < bs[i]
+		}
+	}
+}
+
+// fakeSort sorts entries in the order rsync does
+func fakeSort(entries []*fileEntry) {
+	slices.SortStableFunc(entries, func(a, b *fileEntry) int {
+		if fakeLess(a, b) {
+			return -1
+		} else if fakeLess(b, a) {
+			return 1
+		}
+		return 0
+	})
+}
+
+// unescape removes the escaping from paths and rules with wildcards
+func unescape(s string) string {
+	if !strings.ContainsAny(s, "*?[") {
+		return s
+	}
+	var out strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// readRules reads a filter list
+func (fc *fakeConn) readRules() (rules []string, err error) {
+	for {
+		n, err := fc.readInt()
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return rules, nil
+		}
+		rule, err := fc.readBytes(n)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, string(rule))
+	}
+}
+
+// newEntry makes a file list entry
+func newEntry(name string, fi os.FileInfo) *fileEntry {
+	e := &fileEntry{
+		name:    name,
+		size:    fi.Size(),
+		modTime: fi.ModTime().Unix(),
+		mode:    uint32(fi.Mode().Perm()) | modeRegular,
+	}
+	if fi.IsDir() {
+		e.mode = uint32(fi.Mode().Perm()) | modeDir
+		e.size = 0
+	}
+	return e
+}
+
+// walk adds the entries in dir to entries
+func walk(dir, prefix string, recurse bool, entries []*fileEntry) ([]*fileEntry, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, de := range des {
+		fi, err := de.Info()
+		if err != nil {
+			return nil, err
+		}
+		name := path.Join(prefix, de.Name())
+		entries = append(entries, newEntry(name, fi))
+		if de.IsDir() && recurse {
+			entries, err = walk(filepath.Join(dir, de.Name()), name, true, entries)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return entries, nil
+}
+
+// sender sends the files at p
+func (fc *fakeConn) sender(moduleDir, p string, recurse bool) error {
+	_, err := fc.readRules()
+	if err != nil {
+		return err
+	}
+	p = unescape(p)
+	contents := p == "." || strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	local := filepath.Join(moduleDir, filepath.FromSlash(p))
+	fi, err := os.Stat(local)
+	if err != nil {
+		fc.message(msgErrorXfer, fmt.Sprintf("rsync: link_stat %q failed: No such file or directory (2)\n", p))
+		_ = fc.out.WriteByte(0)
+		fc.writeInt(1)
+		return fc.out.Flush()
+	}
+	var entries []*fileEntry
+	if fi.IsDir() && contents {
+		entries, err = walk(local, "", recurse, []*fileEntry{newEntry(".", fi)})
+	} else {
+		base := path.Base(p)
+		entries = []*fileEntry{newEntry(base, fi)}
+		if fi.IsDir() && recurse {
+			entries, err = walk(local, base, true, entries)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	dir := local
+	if !contents {
+		dir = filepath.Dir(local)
+	}
+
+	// Send the list backwards to check the client sorts it,
+	// using all the compression flags
+	fc.message(msgInfo, "building file list\n")
+	var last fileEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		flags := 0
+		prefix := 0
+		for prefix < len(e.name) && prefix < len(last.name) && prefix < 255 && e.name[prefix] == last.name[prefix] {
+			prefix++
+		}
+		if prefix > 0 {
+			flags |= xmitSameName
+		}
+		suffix := e.name[prefix:]
+		if len(suffix) > 255 {
+			flags |= xmitLongName
+		}
+		if e.modTime == last.modTime {
+			flags |= xmitSameTime
+		}
+		if e.mode == last.mode {
+			flags |= xmitSameMode
+		}
+		if e.name == "." {
+			flags |= xmitTopDir
+		}
+		if flags == 0 {
+			flags = xmitExtendedFlags
+		}
+		if flags&xmitExtendedFlags != 0 {
+			fc.writeShort(flags)
+		} else {
+			_ = fc.out.WriteByte(byte(flags))
+		}
+		if flags&xmitSameName != 0 {
+			_ = fc.out.WriteByte(byte(prefix))
+		}
+		if flags&xmitLongName != 0 {
+			fc.writeInt(len(suffix))
+		} else {
+			_ = fc.out.WriteByte(byte(len(suffix)))
+		}
+		_, _ = fc.out.WriteString(suffix)
+		fc.writeLongint(e.size)
+		if flags&xmitSameTime == 0 {
+			fc.writeInt(int(e.modTime))
+		}
+		if flags&xmitSameMode == 0 {
+			fc.writeInt(int(e.mode))
+		}
+		last = *e
+	}
+	_ = fc.out.WriteByte(0)
+	fc.writeInt(0)
+	err = fc.out.Flush()
+	if err != nil {
+		return err
+	}
+	fakeSort(entries)
+
+	// Send the files asked for
+	phase := 0
+	for {
+		ndx, err := fc.readInt()
+		if err != nil {
+			return err
+		}
+		if ndx == ndxDone {
+			phase++
+			if phase > maxPhase {
+				break
+			}
+			fc.writeInt(ndxDone)
+			err = fc.out.Flush()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		iflags, err := fc.readShort()
+		if err != nil {
+			return err
+		}
+		if iflags != itemTransfer {
+			return fmt.Errorf("unexpected iflags %#x", iflags)
+		}
+		for i := range 4 {
+			n, err := fc.readInt()
+			if err != nil {
+				return err
+			}
+			if n != 0 {
+				return fmt.Errorf("unexpected sum head %d = %d", i, n)
+			}
+		}
+		if ndx < 0 || ndx >= len(entries) || !entries[ndx].isRegular() {
+			return fmt.Errorf("bad file index %d", ndx)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(entries[ndx].name)))
+		if err != nil {
+			return err
+		}
+		fc.writeInt(ndx)
+		fc.writeShort(iflags)
+		for range 4 {
+			fc.writeInt(0)
+		}
+		h := md4.New()
+		_, _ = h.Write(binary.LittleEndian.AppendUint32(nil, fakeSeed))
+		_, _ = h.Write(data)
+		for len(data) > 0 {
+			n := min(len(data), fakeChunkSize)
+			fc.writeInt(n)
+			_, _ = fc.out.Write(data[:n])
+			data = data[n:]
+		}
+		fc.writeInt(0)
+		_, _ = fc.out.Write(h.Sum(nil))
+		err = fc.out.Flush()
+		if err != nil {
+			return err
+		}
+	}
+	fc.writeInt(ndxDone)
+	// Stats
+	for range 5 {
+		fc.writeLongint(0)
+	}
+	err = fc.out.Flush()
+	if err != nil {
+		return err
+	}
+	goodbye, err := fc.readInt()
+	if err != nil {
+		return err
+	}
+	if goodbye != ndxDone {
+		return fmt.Errorf("bad goodbye %d", goodbye)
+	}
+	return nil
+}
+
+// readFileList reads a file list from the client
+func (fc *fakeConn) readFileList() (entries []*fileEntry, err error) {
+	var last fileEntry
+	for {
+		flags, err := fc.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if flags == 0 {
+			break
+		}
+		xflags := int(flags)
+		if xflags&xmitExtendedFlags != 0 {
+			b, err := fc.readByte()
+			if err != nil {
+				return nil, err
+			}
+			xflags |= int(b) << 8
+		}
+		prefix := 0
+		if xflags&xmitSameName != 0 {
+			b, err := fc.readByte()
+			if err != nil {
+				return nil, err
+			}
+			prefix = int(b)
+		}
+		var suffix int
+		if xflags&xmitLongName != 0 {
+			suffix, err = fc.readInt()
+		} else {
+			var b byte
+			b, err = fc.readByte()
+			suffix = int(b)
+		}
+		if err != nil {
+			return nil, err
+		}
+		name, err := fc.readBytes(suffix)
+		if err != nil {
+			return nil, err
+		}
+		e := last
+		e.name = last.name[:prefix] + string(name)
+		e.size, err = fc.readLongint()
+		if err != nil {
+			return nil, err
+		}
+		if xflags&xmitSameTime == 0 {
+			n, err := fc.readInt()
+			if err != nil {
+				return nil, err
+			}
+			e.modTime = int64(n)
+		}
+		if xflags&xmitSameMode == 0 {
+			n, err := fc.readInt()
+			if err != nil {
+				return nil, err
+			}
+			e.mode = uint32(n)
+		}
+		last = e
+		entries = append(entries, &e)
+	}
+	_, err = fc.readInt() // io_error
+	return entries, err
+}
+
+// deleteExtra deletes files in dir which aren't in entries and
+// aren't excluded by rules
+func (fc *fakeConn) deleteExtra(dir string, entries []*fileEntry, rules []string) error {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, de := range des {
+		name := de.Name()
+		if slices.ContainsFunc(entries, func(e *fileEntry) bool { return e.name == name }) {
+			continue
+		}
+		include := true
+		for _, rule := range rules {
+			pattern := rule[2:]
+			if pattern == "*" || unescape(pattern) == "/"+name {
+				include = rule[0] == '+'
+				break
+			}
+		}
+		if !include {
+			continue
+		}
+		err = os.Remove(filepath.Join(dir, name))
+		if err != nil {
+			fc.message(msgError, fmt.Sprintf("cannot delete non-empty directory: %s\n", name))
+		}
+	}
+	return nil
+}
+
+// receiver receives files into p
+func (fc *fakeConn) receiver(moduleDir, p string, del bool) error {
+	var rules []string
+	var err error
+	if del {
+		rules, err = fc.readRules()
+		if err != nil {
+			return err
+		}
+	}
+	entries, err := fc.readFileList()
+	if err != nil {
+		return err
+	}
+	fakeSort(entries)
+	dest := filepath.Join(moduleDir, filepath.FromSlash(strings.TrimSuffix(p, "/")))
+	if del {
+		err = fc.deleteExtra(dest, entries, rules)
+		if err != nil {
+			return err
+		}
+	}
+	for ndx, e := range entries {
+		local := filepath.Join(dest, filepath.FromSlash(e.name))
+		switch {
+		case e.isDir():
+			err = os.Mkdir(local, 0777)
+			if err == nil {
+				// Tell the sender about the new directory
+				fc.writeInt(ndx)
+				fc.writeShort(1 << 13)
+			} else if !os.IsExist(err) {
+				fc.message(msgError, fmt.Sprintf("mkdir %q failed: %v\n", e.name, err))
+			}
+		case e.isRegular():
+			fc.writeInt(ndx)
+			fc.writeShort(itemTransfer)
+			for range 4 {
+				fc.writeInt(0)
+			}
+		}
+	}
+	fc.writeInt(ndxDone)
+	err = fc.out.Flush()
+	if err != nil {
+		return err
+	}
+	phase := 0
+	for {
+		ndx, err := fc.readInt()
+		if err != nil {
+			return err
+		}
+		if ndx == ndxDone {
+			phase++
+			if phase > maxPhase {
+				break
+			}
+			fc.writeInt(ndxDone)
+			err = fc.out.Flush()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if ndx < 0 || ndx >= len(entries) || !entries[ndx].isRegular() {
+			return fmt.Errorf("bad file index %d", ndx)
+		}
+		iflags, err := fc.readShort()
+		if err != nil {
+			return err
+		}
+		if iflags != itemTransfer {
+			return fmt.Errorf("unexpected iflags %#x", iflags)
+		}
+		for range 4 {
+			_, err = fc.readInt()
+			if err != nil {
+				return err
+			}
+		}
+		h := md4.New()
+		_, _ = h.Write(binary.LittleEndian.AppendUint32(nil, fakeSeed))
+		var data bytes.Buffer
+		for {
+			n, err := fc.readInt()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				break
+			}
+			if n < 0 {
+				return fmt.Errorf("unexpected block match %d", n)
+			}
+			b, err := fc.readBytes(n)
+			if err != nil {
+				return err
+			}
+			data.Write(b)
+		}
+		_, _ = h.Write(data.Bytes())
+		sum, err := fc.readBytes(md4.Size)
+		if err != nil {
+			return err
+		}
+		e := entries[ndx]
+		if !bytes.Equal(sum, h.Sum(nil)) {
+			fc.message(msgErrorXfer, fmt.Sprintf("checksum mismatch on %q\n", e.name))
+			continue
+		}
+		if int64(data.Len()) != e.size {
+			return fmt.Errorf("size mismatch on %q: %d != %d", e.name, data.Len(), e.size)
+		}
+		local := filepath.Join(dest, filepath.FromSlash(e.name))
+		tmp := local + ".tmp"
+		err = os.WriteFile(tmp, data.Bytes(), 0666)
+		if err != nil {
+			return err
+		}
+		modTime := time.Unix(e.modTime, 0)
+		err = os.Chtimes(tmp, modTime, modTime)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(tmp, local)
+		if err != nil {
+			fc.message(msgErrorXfer, fmt.Sprintf("rename %q failed: %v\n", e.name, err))
+		}
+	}
+	// Say goodbye
+	fc.writeInt(ndxDone)
+	return fc.out.Flush()
+}
+
+// TestRsyncdFake runs the integration tests against the fake daemon
+func TestRsyncdFake(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	fake := newFakeDaemon(t)
+	name := "TestRsyncdFake"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":test",
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "rsyncd"},
+			{Name: name, Key: "host", Value: fake.host},
+			{Name: name, Key: "port", Value: strconv.Itoa(fake.port)},
+		},
+		QuickTestOK: true,
+	})
+}
+
+// newTestFs makes an Fs for the fake daemon
+func newTestFs(t *testing.T, fake *fakeDaemon, root string, extra configmap.Simple) fs.Fs {
+	m := configmap.Simple{
+		"type": "rsyncd",
+		"host": fake.host,
+		"port": strconv.Itoa(fake.port),
+	}
+	for k, v := range extra {
+		m[k] = v
+	}
+	f, err := NewFs(context.Background(), "TestRsyncd", root, m)
+	require.NoError(t, err)
+	return f
+}
+
+func TestListModules(t *testing.T) {
+	fake := newFakeDaemon(t)
+	f := newTestFs(t, fake, "", nil)
+	entries, err := f.List(context.Background(), "")
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+	}
+	assert.Equal(t, []string{"test"}, names)
+}
+
+func TestAuth(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeDaemon(t)
+	require.NoError(t, os.WriteFile(filepath.Join(fake.root, "secret", "file.txt"), []byte("hello"), 0666))
+
+	// No user
+	f := newTestFs(t, fake, "secret", nil)
+	_, err := f.List(ctx, "")
+	assert.ErrorContains(t, err, "needs a user and password")
+
+	// Wrong password
+	f = newTestFs(t, fake, "secret", configmap.Simple{
+		"user": fakeUser,
+		"pass": obscure.MustObscure("wrong"),
+	})
+	_, err = f.List(ctx, "")
+	assert.ErrorContains(t, err, "auth failed")
+
+	// Right password
+	f = newTestFs(t, fake, "secret", configmap.Simple{
+		"user": fakeUser,
+		"pass": obscure.MustObscure(fakePass),
+	})
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "file.txt", entries[0].Remote())
+	assert.Equal(t, int64(5), entries[0].Size())
+}
+
+func TestUnknownModule(t *testing.T) {
+	fake := newFakeDaemon(t)
+	f := newTestFs(t, fake, "potato", nil)
+	_, err := f.List(context.Background(), "")
+	assert.ErrorContains(t, err, "Unknown module")
+}
+
+func TestCompareFileNames(t *testing.T) {
+	dir := func(name string) *fileEntry { return &fileEntry{name: name, mode: dirMode} }
+	file := func(name string) *fileEntry { return &fileEntry{name: name, mode: fileMode} }
+	// The order rsync sends files in: "." first, then files before
+	// directories in each directory with the contents of each
+	// directory straight after it.
+	want := []*fileEntry{
+		dir("."),
+		file("B"),
+		file("a"),
+		file("a.txt"),
+		file("z"),
+		dir("a.dir"),
+		file("a.dir/x"),
+		dir("a"),
+		file("a/z"),
+		dir("a/b-c"),
+		dir("a/b"),
+		file("a/b/c"),
+		dir("b"),
+	}
+	// directories compare as if they end in "/"
+	assert.True(t, compareFileNames(dir("a.dir"), dir("a")) < 0)
+	assert.True(t, compareFileNames(dir("a/b-c"), dir("a/b")) < 0)
+	assert.Equal(t, 0, compareFileNames(file("a/b"), file("a/b")))
+	got := slices.Clone(want)
+	slices.Reverse(got)
+	sortFileList(got)
+	var wantNames, gotNames []string
+	for i := range want {
+		wantNames = append(wantNames, want[i].name)
+		gotNames = append(gotNames, got[i].name)
+	}
+	assert.Equal(t, wantNames, gotNames)
+	// Check the fake sorts the same way
+	slices.Reverse(got)
+	fakeSort(got)
+	gotNames = gotNames[:0]
+	for i := range got {
+		gotNames = append(gotNames, got[i].name)
+	}
+	assert.Equal(t, wantNames, gotNames)
+}
+
+func TestIntEncoding(t *testing.T) {
+	var buf bytes.Buffer
+	c := &conn{br: bufio.NewReader(&buf), bw: bufio.NewWriter(&buf)}
+	values := []int64{0, 1, 0x7FFFFFFF, 0x80000000, 1 << 40, -2}
+	for _, v := range values {
+		c.writeLongint(v)
+	}
+	c.writeShort(0xFEDC)
+	c.writeInt(-1)
+	require.NoError(t, c.flush())
+	assert.Equal(t, 4+4+4+12+12+12+2+4, buf.Len())
+	for _, want := range values {
+		got, err := c.readLongint()
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	s, err := c.readShort()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0xFEDC), s)
+	n, err := c.readInt()
+	require.NoError(t, err)
+	assert.Equal(t, int32(-1), n)
+}
+
+func TestEscapeWild(t *testing.T) {
+	assert.Equal(t, `plain\name`, escapeWild(`plain\name`))
+	assert.Equal(t, `a\*b\?c\[d\\e`, escapeWild(`a*b?c[d\e`))
+	assert.Equal(t, `a*b?c[d\e`, unescape(escapeWild(`a*b?c[d\e`)))
+}
+
+func TestParentEntries(t *testing.T) {
+	var names []string
+	for _, e := range parentEntries("a/b/c/file.txt") {
+		assert.True(t, e.isDir())
+		names = append(names, e.name)
+	}
+	assert.Equal(t, []string{".", "a", "a/b", "a/b/c"}, names)
+	assert.Len(t, parentEntries("file.txt"), 1)
+}
@@ -0,0 +1,17 @@
+// Test Rsyncd filesystem interface
+package rsyncd_test
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/backend/rsyncd"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestRsyncd:test",
+		NilObject:  (*rsyncd.Object)(nil),
+	})
+}
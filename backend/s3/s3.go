@@ -41,6 +41,7 @@ import (
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/chunksize"
 	"github.com/rclone/rclone/fs/config"
 	"github.com/rclone/rclone/fs/config/configmap"
@@ -58,6 +59,7 @@ import (
 	"github.com/rclone/rclone/lib/pool"
 	"github.com/rclone/rclone/lib/readers"
 	"github.com/rclone/rclone/lib/rest"
+	"github.com/rclone/rclone/lib/restore"
 	"github.com/rclone/rclone/lib/version"
 	"golang.org/x/net/http/httpguts"
 	"golang.org/x/sync/errgroup"
@@ -2229,6 +2231,27 @@ If you leave it blank, this is calculated automatically from the sse_customer_ke
 				Value: "DEEP_ARCHIVE",
 				Help:  "Deep archive storage mode",
 			}},
+		}, {
+			Name: "object_lock",
+			Help: strings.ReplaceAll(`Object Lock settings for new objects.
+
+This is a comma separated list of
+
+- |GOVERNANCE:DURATION| or |COMPLIANCE:DURATION| to set the retention
+  mode and keep objects for DURATION after they are uploaded,
+  e.g. |GOVERNANCE:30d|. An RFC 3339 date can be used instead of the
+  duration to keep objects until then.
+- |legal-hold| to put a legal hold on objects.
+
+For example |COMPLIANCE:1y,legal-hold|.
+
+The bucket must have Object Lock enabled. If |--metadata| is in use,
+the object-lock metadata of an object overrides these settings.
+
+See the [Object Lock section](#object-lock) for more info.
+`, "|", "`"),
+			Default:  fs.CommaSepList{},
+			Advanced: true,
 		}, {
 			Name: "upload_cutoff",
 			Help: `Cutoff for switching to chunked upload.
@@ -2901,6 +2924,21 @@ var systemMetadataInfo = map[string]fs.MetadataHelp{
 		Example:  "2006-01-02T15:04:05.999999999Z07:00",
 		ReadOnly: true,
 	},
+	"object-lock-mode": {
+		Help:    "Object Lock retention mode, set when uploading",
+		Type:    "GOVERNANCE or COMPLIANCE",
+		Example: "GOVERNANCE",
+	},
+	"object-lock-retain-until-date": {
+		Help:    "Object Lock retention date, set when uploading",
+		Type:    "RFC 3339",
+		Example: "2030-01-02T15:04:05Z",
+	},
+	"object-lock-legal-hold-status": {
+		Help:    "Object Lock legal hold, set when uploading",
+		Type:    "ON or OFF",
+		Example: "ON",
+	},
 }
 
 // Options defines the configuration for this backend
@@ -2924,6 +2962,7 @@ type Options struct {
 	SSECustomerKeyBase64  string               `config:"sse_customer_key_base64"`
 	SSECustomerKeyMD5     string               `config:"sse_customer_key_md5"`
 	StorageClass          string               `config:"storage_class"`
+	ObjectLock            fs.CommaSepList      `config:"object_lock"`
 	UploadCutoff          fs.SizeSuffix        `config:"upload_cutoff"`
 	CopyCutoff            fs.SizeSuffix        `config:"copy_cutoff"`
 	ChunkSize             fs.SizeSuffix        `config:"chunk_size"`
@@ -2986,6 +3025,7 @@ type Fs struct {
 	versioningMu   sync.Mutex
 	versioning     fs.Tristate // if set bucket is using versions
 	warnCompressed sync.Once   // warn once about compressed files
+	objectLock     objectLock  // parsed --s3-object-lock
 }
 
 // Object describes a s3 object
@@ -3009,6 +3049,11 @@ type Object struct {
 	contentDisposition *string // Content-Disposition: header
 	contentEncoding    *string // Content-Encoding: header
 	contentLanguage    *string // Content-Language: header
+
+	// Object Lock settings, if read
+	objectLockMode        types.ObjectLockMode
+	objectLockRetainUntil *time.Time
+	objectLockLegalHold   types.ObjectLockLegalHoldStatus
 }
 
 // safely dereference the pointer, returning a zero T if nil
@@ -3692,6 +3737,63 @@ func setQuirks(opt *Options) {
 	}
 }
 
+// objectLock is the parsed --s3-object-lock
+type objectLock struct {
+	mode        types.ObjectLockMode
+	retain      time.Duration // keep for this long after upload if set
+	retainUntil time.Time     // keep until this time if set
+	legalHold   bool
+}
+
+// parseObjectLock parses the --s3-object-lock settings
+func parseObjectLock(settings fs.CommaSepList) (lock objectLock, err error) {
+	for _, setting := range settings {
+		if strings.EqualFold(setting, "legal-hold") {
+			lock.legalHold = true
+			continue
+		}
+		mode, retention, ok := strings.Cut(setting, ":")
+		if !ok {
+			return lock, fmt.Errorf("expecting MODE:DURATION or legal-hold but got %q", setting)
+		}
+		lock.mode = types.ObjectLockMode(strings.ToUpper(mode))
+		if lock.mode != types.ObjectLockModeGovernance && lock.mode != types.ObjectLockModeCompliance {
+			return lock, fmt.Errorf("unknown Object Lock mode %q - must be GOVERNANCE or COMPLIANCE", mode)
+		}
+		if retainUntil, err := time.Parse(time.RFC3339, retention); err == nil {
+			lock.retainUntil = retainUntil
+			lock.retain = 0
+			continue
+		}
+		retain, err := fs.ParseDuration(retention)
+		if err != nil {
+			return lock, fmt.Errorf("bad retention %q: %w", retention, err)
+		}
+		if retain <= 0 {
+			return lock, fmt.Errorf("retention %q must be positive", retention)
+		}
+		lock.retain = retain
+		lock.retainUntil = time.Time{}
+	}
+	return lock, nil
+}
+
+// set fills in the Object Lock settings of a request which aren't
+// set already, keeping the object until now plus the retention
+func (lock *objectLock) set(now time.Time, mode *types.ObjectLockMode, retainUntil **time.Time, legalHold *types.ObjectLockLegalHoldStatus) {
+	if lock.mode != "" && *mode == "" && *retainUntil == nil {
+		*mode = lock.mode
+		until := lock.retainUntil
+		if lock.retain > 0 {
+			until = now.Add(lock.retain)
+		}
+		*retainUntil = &until
+	}
+	if lock.legalHold && *legalHold == "" {
+		*legalHold = types.ObjectLockLegalHoldStatusOn
+	}
+}
+
 // setRoot changes the root of the Fs
 func (f *Fs) setRoot(root string) {
 	f.root = parsePath(root)
@@ -3724,6 +3826,10 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	if opt.BucketACL == "" {
 		opt.BucketACL = opt.ACL
 	}
+	lock, err := parseObjectLock(opt.ObjectLock)
+	if err != nil {
+		return nil, fmt.Errorf("s3: --s3-object-lock: %w", err)
+	}
 	if opt.SSECustomerKeyBase64 != "" && opt.SSECustomerKey != "" {
 		return nil, errors.New("s3: can't use sse_customer_key and sse_customer_key_base64 at the same time")
 	} else if opt.SSECustomerKeyBase64 != "" {
@@ -3756,15 +3862,16 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	pc.SetRetries(2)
 
 	f := &Fs{
-		name:    name,
-		opt:     *opt,
-		ci:      ci,
-		ctx:     ctx,
-		c:       c,
-		pacer:   pc,
-		cache:   bucket.NewCache(),
-		srv:     srv,
-		srvRest: rest.NewClient(fshttp.NewClient(ctx)),
+		name:       name,
+		opt:        *opt,
+		ci:         ci,
+		ctx:        ctx,
+		c:          c,
+		pacer:      pc,
+		cache:      bucket.NewCache(),
+		srv:        srv,
+		srvRest:    rest.NewClient(fshttp.NewClient(ctx)),
+		objectLock: lock,
 	}
 	if opt.ServerSideEncryption == "aws:kms" || opt.SSECustomerAlgorithm != "" {
 		// From: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTCommonResponseHeaders.html
@@ -5046,6 +5153,7 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object,
 		setFrom_s3CopyObjectInput_s3PutObjectInput(&req, ui.req)
 		req.MetadataDirective = types.MetadataDirectiveReplace
 	}
+	f.objectLock.set(time.Now(), &req.ObjectLockMode, &req.ObjectLockRetainUntilDate, &req.ObjectLockLegalHoldStatus)
 
 	err = f.copy(ctx, &req, dstBucket, dstPath, srcBucket, srcPath, srcObj)
 	if err != nil {
@@ -5176,6 +5284,56 @@ It returns a list of status dictionaries.
 	Opts: map[string]string{
 		"all": "if set then show all objects, not just ones with restore status",
 	},
+}, {
+	Name:  "restore-and-copy",
+	Short: "Restore objects from GLACIER or INTELLIGENT-TIERING archive tier then copy them",
+	Long: `This command restores objects from GLACIER or DEEP_ARCHIVE storage or
+from the INTELLIGENT-TIERING archive tiers, waits for the restores to
+finish, then copies the objects to the destination given.
+
+Usage Examples:
+
+    rclone backend restore-and-copy s3:bucket/path/to/directory remote:dest -o priority=Bulk -o lifetime=1
+    rclone backend restore-and-copy s3:bucket remote:dest -o priority=Standard -o max-wait=24h
+
+Restores are started for archived objects which aren't being restored
+already, then the restore status is polled, waiting poll-interval
+between checks, doubling each time up to 30 minutes. Objects which
+don't need restoring, or have finished restoring, are copied as soon
+as they are found. Objects which are the same at the destination
+aren't copied again.
+
+This command obeys the filters. Test first with --interactive/-i or
+--dry-run flags
+
+    rclone --dry-run backend restore-and-copy --include "*.txt" s3:bucket/path remote:dest
+
+It returns a list of status dictionaries with Remote and Status keys.
+The Status is one of Copied, Unchanged, Not restored, Timed out
+waiting for restore or an error message starting Restore failed or
+Copy failed.
+
+    [
+        {
+            "Remote": "test.txt",
+            "Status": "Copied"
+        },
+        {
+            "Remote": "test/file4.txt",
+            "Status": "Timed out waiting for restore"
+        }
+    ]
+
+If any objects fail to restore or copy then rclone will exit with a
+non zero exit code after printing the list.
+`,
+	Opts: map[string]string{
+		"priority":      "Priority of restore: Standard|Expedited|Bulk",
+		"lifetime":      "Lifetime of the active copy in days, ignored for INTELLIGENT-TIERING storage",
+		"description":   "The optional description for the job.",
+		"poll-interval": "Time to wait before first checking on the restores, default 1m",
+		"max-wait":      "Maximum time to wait for the restores, default no limit",
+	},
 }, {
 	Name:  "list-multipart-uploads",
 	Short: "List the unfinished multipart uploads",
@@ -5287,26 +5445,9 @@ It doesn't return anything.
 func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
 	switch name {
 	case "restore":
-		req := s3.RestoreObjectInput{
-			//Bucket:         &f.rootBucket,
-			//Key:            &encodedDirectory,
-			RestoreRequest: &types.RestoreRequest{},
-		}
-		if lifetime := opt["lifetime"]; lifetime != "" {
-			ilifetime, err := strconv.ParseInt(lifetime, 10, 32)
-			if err != nil {
-				return nil, fmt.Errorf("bad lifetime: %w", err)
-			}
-			ilifetime32 := int32(ilifetime)
-			req.RestoreRequest.Days = &ilifetime32
-		}
-		if priority := opt["priority"]; priority != "" {
-			req.RestoreRequest.GlacierJobParameters = &types.GlacierJobParameters{
-				Tier: types.Tier(priority),
-			}
-		}
-		if description := opt["description"]; description != "" {
-			req.RestoreRequest.Description = &description
+		req, err := restoreRequest(opt)
+		if err != nil {
+			return nil, err
 		}
 		type status struct {
 			Status string
@@ -5332,22 +5473,11 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 				st.Status = "Not an S3 object"
 				return
 			}
-			if o.storageClass == nil || (*o.storageClass != "GLACIER" && *o.storageClass != "DEEP_ARCHIVE" && *o.storageClass != "INTELLIGENT_TIERING") {
+			if !o.isArchived() {
 				st.Status = "Not GLACIER or DEEP_ARCHIVE or INTELLIGENT_TIERING storage class"
 				return
 			}
-			bucket, bucketPath := o.split()
-			reqCopy := req
-			if *o.storageClass == "INTELLIGENT_TIERING" {
-				reqCopy.RestoreRequest.Days = nil
-			}
-			reqCopy.Bucket = &bucket
-			reqCopy.Key = &bucketPath
-			reqCopy.VersionId = o.versionID
-			err = f.pacer.Call(func() (bool, error) {
-				_, err = f.c.RestoreObject(ctx, &reqCopy)
-				return f.shouldRetry(ctx, err)
-			})
+			err := o.restore(ctx, req)
 			if err != nil {
 				st.Status = err.Error()
 			}
@@ -5356,6 +5486,33 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 			return out, err
 		}
 		return out, nil
+	case "restore-and-copy":
+		if len(arg) != 1 {
+			return nil, errors.New("need exactly 1 argument, the destination")
+		}
+		dstFs, err := cache.Get(ctx, arg[0])
+		if err != nil {
+			return nil, fmt.Errorf("couldn't find destination: %w", err)
+		}
+		req, err := restoreRequest(opt)
+		if err != nil {
+			return nil, err
+		}
+		pollInterval := time.Minute
+		if opt["poll-interval"] != "" {
+			pollInterval, err = fs.ParseDuration(opt["poll-interval"])
+			if err != nil {
+				return nil, fmt.Errorf("bad poll-interval: %w", err)
+			}
+		}
+		var maxWait time.Duration
+		if opt["max-wait"] != "" {
+			maxWait, err = fs.ParseDuration(opt["max-wait"])
+			if err != nil {
+				return nil, fmt.Errorf("bad max-wait: %w", err)
+			}
+		}
+		return f.restoreAndCopy(ctx, dstFs, req, pollInterval, maxWait)
 	case "restore-status":
 		_, all := opt["all"]
 		return f.restoreStatus(ctx, all)
@@ -5445,6 +5602,124 @@ func (f *Fs) restoreStatus(ctx context.Context, all bool) (out []restoreStatusOu
 	return out, nil
 }
 
+// restoreRequest makes a RestoreObject request from the options
+// passed to the "restore" and "restore-and-copy" commands
+func restoreRequest(opt map[string]string) (req s3.RestoreObjectInput, err error) {
+	req.RestoreRequest = &types.RestoreRequest{}
+	if lifetime := opt["lifetime"]; lifetime != "" {
+		ilifetime, err := strconv.ParseInt(lifetime, 10, 32)
+		if err != nil {
+			return req, fmt.Errorf("bad lifetime: %w", err)
+		}
+		ilifetime32 := int32(ilifetime)
+		req.RestoreRequest.Days = &ilifetime32
+	}
+	if priority := opt["priority"]; priority != "" {
+		req.RestoreRequest.GlacierJobParameters = &types.GlacierJobParameters{
+			Tier: types.Tier(priority),
+		}
+	}
+	if description := opt["description"]; description != "" {
+		req.RestoreRequest.Description = &description
+	}
+	return req, nil
+}
+
+// isArchived returns true if the object is in a storage class which
+// may need restoring before it can be read
+func (o *Object) isArchived() bool {
+	if o.storageClass == nil {
+		return false
+	}
+	switch *o.storageClass {
+	case "GLACIER", "DEEP_ARCHIVE", "INTELLIGENT_TIERING":
+		return true
+	}
+	return false
+}
+
+// restore issues a RestoreObject for the object using req as a
+// template
+func (o *Object) restore(ctx context.Context, req s3.RestoreObjectInput) error {
+	bucket, bucketPath := o.split()
+	restoreRequest := *req.RestoreRequest
+	if deref(o.storageClass) == "INTELLIGENT_TIERING" {
+		restoreRequest.Days = nil
+	}
+	req.RestoreRequest = &restoreRequest
+	req.Bucket = &bucket
+	req.Key = &bucketPath
+	req.VersionId = o.versionID
+	return o.fs.pacer.Call(func() (bool, error) {
+		_, err := o.fs.c.RestoreObject(ctx, &req)
+		return o.fs.shouldRetry(ctx, err)
+	})
+}
+
+// errNotS3Object is returned when restoring an object from another backend
+var errNotS3Object = errors.New("not an S3 object")
+
+// parseRestoreHeader reads the restore state from the x-amz-restore
+// header which looks like
+//
+//	ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"
+func parseRestoreHeader(restoreHeader *string) restore.State {
+	switch {
+	case restoreHeader == nil:
+		return restore.Needed
+	case strings.Contains(*restoreHeader, `ongoing-request="true"`):
+		return restore.Ongoing
+	default:
+		return restore.Done
+	}
+}
+
+// readRestoreState reads whether the object needs restoring before it
+// can be read
+func (o *Object) readRestoreState(ctx context.Context) (restore.State, error) {
+	if !o.isArchived() {
+		return restore.Done, nil
+	}
+	resp, err := o.headObject(ctx)
+	if err != nil {
+		return restore.Needed, err
+	}
+	// INTELLIGENT_TIERING objects only need restoring from the archive tiers
+	if resp.StorageClass == types.StorageClassIntelligentTiering && resp.ArchiveStatus == "" {
+		return restore.Done, nil
+	}
+	return parseRestoreHeader(resp.Restore), nil
+}
+
+// restoreAndCopy restores the archived objects in f, waiting for the
+// restores to finish, then copies them to dstFs.
+//
+// It waits at most maxWait for restores if it is set.
+func (f *Fs) restoreAndCopy(ctx context.Context, dstFs fs.Fs, req s3.RestoreObjectInput, pollInterval, maxWait time.Duration) ([]*restore.Status, error) {
+	return restore.Copy(ctx, f, dstFs, restore.Options{
+		Verb:         "restore",
+		Noun:         "restore",
+		Past:         "restored",
+		Ongoing:      "Restoring",
+		PollInterval: pollInterval,
+		MaxWait:      maxWait,
+		State: func(ctx context.Context, obj fs.Object) (restore.State, error) {
+			o, ok := obj.(*Object)
+			if !ok {
+				return restore.Needed, errNotS3Object
+			}
+			return o.readRestoreState(ctx)
+		},
+		Start: func(ctx context.Context, obj fs.Object) error {
+			err := obj.(*Object).restore(ctx, req)
+			if err == nil {
+				fs.Infof(obj, "Restore started")
+			}
+			return err
+		},
+	})
+}
+
 // listMultipartUploads lists all outstanding multipart uploads for (bucket, key)
 //
 // Note that rather lazily we treat key as a prefix so it matches
@@ -5902,6 +6177,9 @@ func (o *Object) setMetaData(resp *s3.HeadObjectOutput) {
 	o.contentDisposition = resp.ContentDisposition
 	o.contentEncoding = resp.ContentEncoding
 	o.contentLanguage = resp.ContentLanguage
+	o.objectLockMode = resp.ObjectLockMode
+	o.objectLockRetainUntil = resp.ObjectLockRetainUntilDate
+	o.objectLockLegalHold = resp.ObjectLockLegalHoldStatus
 
 	// If decompressing then size and md5sum are unknown
 	if o.fs.opt.Decompress && deref(o.contentEncoding) == "gzip" {
@@ -6022,6 +6300,12 @@ func (o *Object) downloadFromURL(ctx context.Context, bucketPath string, options
 		ContentLanguage:    header("Content-Language"),
 		ContentType:        header("Content-Type"),
 		StorageClass:       types.StorageClass(deref(header("X-Amz-Storage-Class"))),
+
+		ObjectLockMode:            types.ObjectLockMode(deref(header("X-Amz-Object-Lock-Mode"))),
+		ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatus(deref(header("X-Amz-Object-Lock-Legal-Hold"))),
+	}
+	if retainUntil, err := time.Parse(time.RFC3339, resp.Header.Get("X-Amz-Object-Lock-Retain-Until-Date")); err == nil {
+		head.ObjectLockRetainUntilDate = &retainUntil
 	}
 	o.setMetaData(&head)
 	return resp.Body, err
@@ -6566,6 +6850,20 @@ func (o *Object) prepareUpload(ctx context.Context, src fs.ObjectInfo, options [
 			ui.req.Tagging = pv
 		case "tier":
 			// ignore
+		case "object-lock-mode":
+			ui.req.ObjectLockMode = types.ObjectLockMode(strings.ToUpper(v))
+		case "object-lock-retain-until-date":
+			retainUntil, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				fs.Debugf(o, "failed to parse metadata %s: %q: %v", k, v, err)
+			} else if retainUntil.After(time.Now()) {
+				ui.req.ObjectLockRetainUntilDate = &retainUntil
+			} else {
+				// S3 won't accept dates in the past
+				fs.Debugf(o, "Ignoring expired metadata %s: %q", k, v)
+			}
+		case "object-lock-legal-hold-status":
+			ui.req.ObjectLockLegalHoldStatus = types.ObjectLockLegalHoldStatus(strings.ToUpper(v))
 		case "mtime":
 			// mtime in meta overrides source ModTime
 			metaModTime, err := time.Parse(time.RFC3339Nano, v)
@@ -6582,6 +6880,14 @@ func (o *Object) prepareUpload(ctx context.Context, src fs.ObjectInfo, options [
 		}
 	}
 
+	// The retention mode and date must be set together
+	if (ui.req.ObjectLockMode == "") != (ui.req.ObjectLockRetainUntilDate == nil) {
+		fs.Debugf(o, "Ignoring Object Lock retention as mode %q or retain until date missing", ui.req.ObjectLockMode)
+		ui.req.ObjectLockMode = ""
+		ui.req.ObjectLockRetainUntilDate = nil
+	}
+	o.fs.objectLock.set(time.Now(), &ui.req.ObjectLockMode, &ui.req.ObjectLockRetainUntilDate, &ui.req.ObjectLockLegalHoldStatus)
+
 	// Set the mtime in the meta data
 	ui.req.Metadata[metaMtime] = swift.TimeToFloatString(modTime)
 
@@ -6864,6 +7170,17 @@ func (o *Object) Metadata(ctx context.Context) (metadata fs.Metadata, err error)
 	setMetadata("content-encoding", o.contentEncoding)
 	setMetadata("content-language", o.contentLanguage)
 	metadata["tier"] = o.GetTier()
+	if !o.fs.opt.NoSystemMetadata {
+		if o.objectLockMode != "" {
+			metadata["object-lock-mode"] = string(o.objectLockMode)
+		}
+		if o.objectLockRetainUntil != nil {
+			metadata["object-lock-retain-until-date"] = o.objectLockRetainUntil.Format(time.RFC3339Nano)
+		}
+		if o.objectLockLegalHold != "" {
+			metadata["object-lock-legal-hold-status"] = string(o.objectLockLegalHold)
+		}
+	}
 
 	return metadata, nil
 }
@@ -23,6 +23,7 @@ import (
 	"github.com/rclone/rclone/fstest/fstests"
 	"github.com/rclone/rclone/lib/bucket"
 	"github.com/rclone/rclone/lib/random"
+	"github.com/rclone/rclone/lib/restore"
 	"github.com/rclone/rclone/lib/version"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -163,6 +164,104 @@ func TestVersionLess(t *testing.T) {
 	}
 }
 
+func TestParseRestoreHeader(t *testing.T) {
+	for _, test := range []struct {
+		in   *string
+		want restore.State
+	}{
+		{in: nil, want: restore.Needed},
+		{in: aws.String(`ongoing-request="true"`), want: restore.Ongoing},
+		{in: aws.String(`ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`), want: restore.Done},
+	} {
+		assert.Equal(t, test.want, parseRestoreHeader(test.in), deref(test.in))
+	}
+}
+
+func TestRestoreRequest(t *testing.T) {
+	req, err := restoreRequest(map[string]string{
+		"lifetime":    "3",
+		"priority":    "Bulk",
+		"description": "potato",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), *req.RestoreRequest.Days)
+	assert.Equal(t, types.TierBulk, req.RestoreRequest.GlacierJobParameters.Tier)
+	assert.Equal(t, "potato", *req.RestoreRequest.Description)
+
+	_, err = restoreRequest(map[string]string{"lifetime": "forever"})
+	assert.ErrorContains(t, err, "bad lifetime")
+
+	assert.True(t, (&Object{storageClass: aws.String("INTELLIGENT_TIERING")}).isArchived())
+	assert.False(t, (&Object{storageClass: aws.String("STANDARD")}).isArchived())
+	assert.False(t, (&Object{}).isArchived())
+}
+
+func TestParseObjectLock(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    objectLock
+		wantErr string
+	}{
+		{in: "", want: objectLock{}},
+		{in: "GOVERNANCE:30d", want: objectLock{mode: types.ObjectLockModeGovernance, retain: 30 * 24 * time.Hour}},
+		{in: "compliance:1h,legal-hold", want: objectLock{mode: types.ObjectLockModeCompliance, retain: time.Hour, legalHold: true}},
+		{in: "COMPLIANCE:2030-01-02T03:04:05Z", want: objectLock{mode: types.ObjectLockModeCompliance, retainUntil: fstest.Time("2030-01-02T03:04:05Z")}},
+		{in: "Legal-Hold", want: objectLock{legalHold: true}},
+		{in: "GOVERNANCE", wantErr: `expecting MODE:DURATION or legal-hold but got "GOVERNANCE"`},
+		{in: "POTATO:1d", wantErr: `unknown Object Lock mode "POTATO" - must be GOVERNANCE or COMPLIANCE`},
+		{in: "GOVERNANCE:soon", wantErr: `bad retention "soon"`},
+		{in: "GOVERNANCE:0s", wantErr: `retention "0s" must be positive`},
+	} {
+		var settings fs.CommaSepList
+		require.NoError(t, settings.Set(test.in))
+		got, err := parseObjectLock(settings)
+		if test.wantErr != "" {
+			assert.ErrorContains(t, err, test.wantErr, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestObjectLockSet(t *testing.T) {
+	now := fstest.Time("2025-01-02T03:04:05Z")
+	until := fstest.Time("2030-01-02T03:04:05Z")
+
+	// No settings leaves the request alone
+	var req s3.PutObjectInput
+	lock := objectLock{}
+	lock.set(now, &req.ObjectLockMode, &req.ObjectLockRetainUntilDate, &req.ObjectLockLegalHoldStatus)
+	assert.Equal(t, s3.PutObjectInput{}, req)
+
+	// Duration is relative to now
+	lock = objectLock{mode: types.ObjectLockModeGovernance, retain: time.Hour, legalHold: true}
+	lock.set(now, &req.ObjectLockMode, &req.ObjectLockRetainUntilDate, &req.ObjectLockLegalHoldStatus)
+	assert.Equal(t, types.ObjectLockModeGovernance, req.ObjectLockMode)
+	assert.Equal(t, now.Add(time.Hour), *req.ObjectLockRetainUntilDate)
+	assert.Equal(t, types.ObjectLockLegalHoldStatusOn, req.ObjectLockLegalHoldStatus)
+
+	// Fixed date
+	req = s3.PutObjectInput{}
+	lock = objectLock{mode: types.ObjectLockModeCompliance, retainUntil: until}
+	lock.set(now, &req.ObjectLockMode, &req.ObjectLockRetainUntilDate, &req.ObjectLockLegalHoldStatus)
+	assert.Equal(t, types.ObjectLockModeCompliance, req.ObjectLockMode)
+	assert.Equal(t, until, *req.ObjectLockRetainUntilDate)
+	assert.Equal(t, types.ObjectLockLegalHoldStatus(""), req.ObjectLockLegalHoldStatus)
+
+	// Settings from the metadata aren't overridden
+	req = s3.PutObjectInput{
+		ObjectLockMode:            types.ObjectLockModeGovernance,
+		ObjectLockRetainUntilDate: &until,
+		ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatusOff,
+	}
+	lock = objectLock{mode: types.ObjectLockModeCompliance, retain: time.Hour, legalHold: true}
+	lock.set(now, &req.ObjectLockMode, &req.ObjectLockRetainUntilDate, &req.ObjectLockLegalHoldStatus)
+	assert.Equal(t, types.ObjectLockModeGovernance, req.ObjectLockMode)
+	assert.Equal(t, until, *req.ObjectLockRetainUntilDate)
+	assert.Equal(t, types.ObjectLockLegalHoldStatusOff, req.ObjectLockLegalHoldStatus)
+}
+
 func TestMergeDeleteMarkers(t *testing.T) {
 	key1 := "key1"
 	key2 := "key2"
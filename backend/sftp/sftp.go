@@ -482,6 +482,64 @@ Example:
 	myUser:myPass@localhost:9005
 	`,
 			Advanced: true,
+		}, {
+			Name:    "jump_hosts",
+			Default: fs.CommaSepList{},
+			Help: strings.ReplaceAll(`Comma separated list of jump hosts to connect through.
+
+This works like |ssh -J| or |ProxyJump| in the ssh config. Each jump
+host is given as |[user@]host[:port]| and rclone connects to each in
+turn, tunnelling through the previous ones, before connecting to the
+SFTP server.
+
+The same authentication and known hosts checking is used for the jump
+hosts as for the SFTP server. If the user is not given then the user
+for the SFTP server is used.
+
+Example:
+
+    bastion.example.com,admin@inner-bastion:2222
+`, "|", "`"),
+			Advanced: true,
+		}, {
+			Name:    "ssh_config_file",
+			Default: "",
+			Help: strings.ReplaceAll(`Path to an OpenSSH config file to read ProxyJump from.
+
+If set, and jump_hosts is not set, then rclone will look up the
+ProxyJump setting for the host in this file and connect through those
+jump hosts. The HostName, User and Port settings for the jump hosts
+are read from the file too.
+
+Only ProxyJump is read, not other settings, and Match and Include are
+not supported.
+
+Leading |~| will be expanded in the file name as will environment
+variables such as |${RCLONE_CONFIG_DIR}|.
+`, "|", "`"),
+			Advanced: true,
+			Examples: []fs.OptionExample{{
+				Value: "~/.ssh/config",
+				Help:  "Use the user's ssh config file",
+			}},
+		}, {
+			Name:    "sessions_per_connection",
+			Default: 1,
+			Help: strings.ReplaceAll(`Number of SFTP sessions to run over each ssh connection.
+
+Normally rclone makes a new ssh connection for each SFTP session it
+needs. Setting this higher than 1 lets rclone open several SFTP
+sessions over one ssh connection which reduces the number of ssh
+handshakes needed, which is useful if they are slow, for example when
+connecting through jump hosts.
+
+Note that servers often limit the number of sessions per connection,
+for example OpenSSH defaults to |MaxSessions 10|, and rclone may run
+extra sessions on the connection to calculate hashes.
+
+This is not used with the ssh option.
+`, "|", "`"),
+			Advanced: true,
 		}, {
 			Name:    "copy_is_hardlink",
 			Default: false,
@@ -545,6 +603,9 @@ type Options struct {
 	HostKeyAlgorithms       fs.SpaceSepList `config:"host_key_algorithms"`
 	SSH                     fs.SpaceSepList `config:"ssh"`
 	SocksProxy              string          `config:"socks_proxy"`
+	JumpHosts               fs.CommaSepList `config:"jump_hosts"`
+	SSHConfigFile           string          `config:"ssh_config_file"`
+	SessionsPerConnection   int             `config:"sessions_per_connection"`
 	CopyIsHardlink          bool            `config:"copy_is_hardlink"`
 }
 
@@ -570,6 +631,9 @@ type Fs struct {
 	savedpswd    string
 	sessions     atomic.Int32 // count in use sessions
 	tokens       *pacer.TokenDispenser
+	jumps        []jumpHost         // jump hosts to connect through
+	sharedMu     sync.Mutex         // protects shared
+	shared       []*sharedSSHClient // ssh connections with sessions_per_connection > 1
 }
 
 // Object is a remote SFTP file that has been stat'd (so it exists, but is not necessarily open for reading)
@@ -656,7 +720,11 @@ func (f *Fs) sftpConnection(ctx context.Context) (c *conn, err error) {
 	c = &conn{
 		err: make(chan error, 1),
 	}
-	if len(f.opt.SSH) == 0 {
+	if len(f.opt.SSH) == 0 && f.opt.SessionsPerConnection > 1 {
+		c.sshClient, err = f.getSharedSSHClient(func() (sshClient, error) {
+			return f.newSSHClientInternal(ctx, "tcp", f.opt.Host+":"+f.opt.Port, f.config)
+		})
+	} else if len(f.opt.SSH) == 0 {
 		c.sshClient, err = f.newSSHClientInternal(ctx, "tcp", f.opt.Host+":"+f.opt.Port, f.config)
 	} else {
 		c.sshClient, err = f.newSSHClientExternal()
@@ -867,6 +935,18 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		opt.Port = "22"
 	}
 
+	if len(opt.SSH) != 0 && (len(opt.JumpHosts) != 0 || opt.SSHConfigFile != "") {
+		fs.Logf(name, "--sftp-ssh is in use - ignoring jump_hosts and ssh_config_file - configure them in the ssh binary instead")
+	} else {
+		f.jumps, err = getJumpHosts(opt)
+		if err != nil {
+			return nil, err
+		}
+		if len(f.jumps) > 0 {
+			fs.Debugf(name, "Connecting via jump hosts %v", f.jumps)
+		}
+	}
+
 	sshConfig := &ssh.ClientConfig{
 		User:            opt.User,
 		Auth:            []ssh.AuthMethod{},
@@ -2230,6 +2310,57 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	return nil
 }
 
+// partialUpdate is a remote sftp file open for random access writes
+// by OpenPartialUpdate
+type partialUpdate struct {
+	ctx  context.Context
+	o    *Object
+	c    *conn
+	file *sftp.File
+}
+
+// WriteAt writes len(p) bytes from p to the file at offset off
+func (pu *partialUpdate) WriteAt(p []byte, off int64) (n int, err error) {
+	return pu.file.WriteAt(p, off)
+}
+
+// Close the file and read the new size and modification time
+func (pu *partialUpdate) Close() error {
+	err := pu.file.Close()
+	pu.o.fs.putSftpConnection(&pu.c, err)
+	pu.o.fs.removeSession()
+	if err != nil {
+		return fmt.Errorf("OpenPartialUpdate Close failed: %w", err)
+	}
+	return pu.o.stat(pu.ctx)
+}
+
+// OpenPartialUpdate opens the existing remote sftp file for random
+// access writes without truncating it and sets its size to size.
+func (o *Object) OpenPartialUpdate(ctx context.Context, size int64) (fs.WriterAtCloser, error) {
+	// Clear the hash cache since we are about to update the object
+	o.md5sum = nil
+	o.sha1sum = nil
+	c, err := o.fs.getSftpConnection(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("OpenPartialUpdate: %w", err)
+	}
+	// Hang on to the connection until the file is closed
+	file, err := c.sftpClient.OpenFile(o.path(), os.O_WRONLY)
+	if err != nil {
+		o.fs.putSftpConnection(&c, err)
+		return nil, fmt.Errorf("OpenPartialUpdate open failed: %w", err)
+	}
+	err = file.Truncate(size)
+	if err != nil {
+		_ = file.Close()
+		o.fs.putSftpConnection(&c, err)
+		return nil, fmt.Errorf("OpenPartialUpdate truncate failed: %w", err)
+	}
+	o.fs.addSession() // Show session in use
+	return &partialUpdate{ctx: ctx, o: o, c: c, file: file}, nil
+}
+
 // Remove a remote sftp file object
 func (o *Object) Remove(ctx context.Context) error {
 	c, err := o.fs.getSftpConnection(ctx)
@@ -2243,13 +2374,14 @@ func (o *Object) Remove(ctx context.Context) error {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs             = &Fs{}
-	_ fs.PutStreamer    = &Fs{}
-	_ fs.Mover          = &Fs{}
-	_ fs.Copier         = &Fs{}
-	_ fs.DirMover       = &Fs{}
-	_ fs.DirSetModTimer = &Fs{}
-	_ fs.Abouter        = &Fs{}
-	_ fs.Shutdowner     = &Fs{}
-	_ fs.Object         = &Object{}
+	_ fs.Fs                 = &Fs{}
+	_ fs.PutStreamer        = &Fs{}
+	_ fs.Mover              = &Fs{}
+	_ fs.Copier             = &Fs{}
+	_ fs.DirMover           = &Fs{}
+	_ fs.DirSetModTimer     = &Fs{}
+	_ fs.Abouter            = &Fs{}
+	_ fs.Shutdowner         = &Fs{}
+	_ fs.Object             = &Object{}
+	_ fs.OpenPartialUpdater = &Object{}
 )
@@ -4,9 +4,11 @@ package sftp
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestShellEscapeUnix(t *testing.T) {
@@ -86,3 +88,64 @@ func TestParseUsage(t *testing.T) {
 		assert.Equal(t, test.usage, [3]int64{gotSpaceTotal, gotSpaceUsed, gotSpaceAvail}, fmt.Sprintf("Test %d sshOutput = %q", i, test.sshOutput))
 	}
 }
+
+func TestParseJumpHosts(t *testing.T) {
+	jumps, err := parseJumpHosts([]string{"bastion", "admin@inner:2222", "", "[::1]", "me@[::1]:23"}, "user")
+	require.NoError(t, err)
+	assert.Equal(t, []jumpHost{
+		{user: "user", addr: "bastion:22"},
+		{user: "admin", addr: "inner:2222"},
+		{user: "user", addr: "[::1]:22"},
+		{user: "me", addr: "[::1]:23"},
+	}, jumps)
+
+	_, err = parseJumpHosts([]string{"user@"}, "user")
+	assert.Error(t, err)
+}
+
+func TestSSHConfigJumpHosts(t *testing.T) {
+	const config = `# comment
+Host bastion
+    HostName bastion.example.com
+    Port 2222
+
+Host inner
+	HostName=10.0.0.1
+	User = admin
+
+Host *.internal !nojump.internal
+    ProxyJump bastion,root@inner
+
+Host direct.internal
+    ProxyJump none
+
+Match host foo
+    ProxyJump nope
+
+Host *
+    User globaluser
+`
+	c, err := parseSSHConfig(strings.NewReader(config))
+	require.NoError(t, err)
+
+	jumps, err := c.jumpHosts("server.internal", "user")
+	require.NoError(t, err)
+	assert.Equal(t, []jumpHost{
+		{user: "globaluser", addr: "bastion.example.com:2222"},
+		{user: "root", addr: "10.0.0.1:22"},
+	}, jumps)
+
+	for _, host := range []string{"nojump.internal", "example.com", "foo"} {
+		jumps, err = c.jumpHosts(host, "user")
+		require.NoError(t, err)
+		assert.Nil(t, jumps, host)
+	}
+
+	// The first value found wins
+	jumps, err = c.jumpHosts("direct.internal", "user")
+	require.NoError(t, err)
+	assert.Len(t, jumps, 2)
+
+	assert.Equal(t, "admin", c.get("inner", "user"))
+	assert.Equal(t, "globaluser", c.get("bastion", "User"))
+}
@@ -4,6 +4,7 @@ package sftp
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net"
 
@@ -16,7 +17,8 @@ import (
 // Internal ssh connections with "golang.org/x/crypto/ssh"
 
 type sshClientInternal struct {
-	srv *ssh.Client
+	srv   *ssh.Client
+	jumps []*ssh.Client // jump hosts srv is connected through, if any
 }
 
 // newSSHClientInternal starts a client connection to the given SSH server. It is a
@@ -29,21 +31,39 @@ func (f *Fs) newSSHClientInternal(ctx context.Context, network, addr string, ssh
 		conn net.Conn
 		err  error
 	)
+	// If using jump hosts connect to the first one
+	dialAddr := addr
+	if len(f.jumps) > 0 {
+		dialAddr = f.jumps[0].addr
+	}
 	if f.opt.SocksProxy != "" {
-		conn, err = proxy.SOCKS5Dial(network, addr, f.opt.SocksProxy, baseDialer)
+		conn, err = proxy.SOCKS5Dial(network, dialAddr, f.opt.SocksProxy, baseDialer)
 	} else {
-		conn, err = baseDialer.Dial(network, addr)
+		conn, err = baseDialer.Dial(network, dialAddr)
 	}
 	if err != nil {
 		return nil, err
 	}
+	var jumps []*ssh.Client
+	if len(f.jumps) > 0 {
+		jumps, err = f.dialJumpHosts(conn, sshConfig)
+		if err != nil {
+			return nil, err
+		}
+		conn, err = jumps[len(jumps)-1].Dial(network, addr)
+		if err != nil {
+			closeJumpHosts(jumps)
+			return nil, fmt.Errorf("couldn't connect to %s through jump hosts: %w", addr, err)
+		}
+	}
 	c, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
 	if err != nil {
+		closeJumpHosts(jumps)
 		return nil, err
 	}
 	fs.Debugf(f, "New connection %s->%s to %q", c.LocalAddr(), c.RemoteAddr(), c.ServerVersion())
 	srv := ssh.NewClient(c, chans, reqs)
-	return sshClientInternal{srv}, nil
+	return sshClientInternal{srv: srv, jumps: jumps}, nil
 }
 
 // Wait for connection to close
@@ -59,9 +79,11 @@ func (s sshClientInternal) SendKeepAlive() {
 	}
 }
 
-// Close the connection
+// Close the connection and any jump hosts it goes through
 func (s sshClientInternal) Close() error {
-	return s.srv.Close()
+	err := s.srv.Close()
+	closeJumpHosts(s.jumps)
+	return err
 }
 
 // CanReuse indicates if this client can be reused
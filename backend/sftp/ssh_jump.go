@@ -0,0 +1,256 @@
+//go:build !plan9
+
+package sftp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/env"
+	"golang.org/x/crypto/ssh"
+)
+
+// Jump hosts, like ssh -J or ProxyJump in ~/.ssh/config
+
+// jumpHost is a host we tunnel through to reach the SFTP server
+type jumpHost struct {
+	user string
+	addr string // host:port
+}
+
+// String returns the jump host in ssh -J format
+func (j jumpHost) String() string {
+	return j.user + "@" + j.addr
+}
+
+// splitJumpHost splits a jump host of the form [user@]host[:port]
+// into its parts. user and port are returned empty if not present.
+func splitJumpHost(s string) (user, host, port string, err error) {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		user, s = s[:i], s[i+1:]
+	}
+	host = s
+	if h, p, err := net.SplitHostPort(s); err == nil {
+		host, port = h, p
+	} else if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		host = s[1 : len(s)-1]
+	}
+	if host == "" {
+		return "", "", "", fmt.Errorf("no host in jump host %q", s)
+	}
+	return user, host, port, nil
+}
+
+// parseJumpHosts parses a list of jump hosts of the form
+// [user@]host[:port] filling in defaultUser and port 22 where not
+// specified.
+func parseJumpHosts(hosts []string, defaultUser string) (jumps []jumpHost, err error) {
+	for _, s := range hosts {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		user, host, port, err := splitJumpHost(s)
+		if err != nil {
+			return nil, err
+		}
+		if user == "" {
+			user = defaultUser
+		}
+		if port == "" {
+			port = "22"
+		}
+		jumps = append(jumps, jumpHost{user: user, addr: net.JoinHostPort(host, port)})
+	}
+	return jumps, nil
+}
+
+// sshConfigBlock is a Host section of an OpenSSH config file
+type sshConfigBlock struct {
+	patterns []string
+	options  map[string]string // lower case keys
+}
+
+// matches returns true if host is matched by the patterns in the block
+func (b *sshConfigBlock) matches(host string) bool {
+	matched := false
+	for _, pattern := range b.patterns {
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(host))
+		if err != nil || !ok {
+			continue
+		}
+		if negate {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// sshConfigFile is a minimal parser for OpenSSH client config files.
+//
+// It only understands enough to read ProxyJump and the HostName,
+// User and Port of the hosts it refers to. Match and Include are
+// not supported.
+type sshConfigFile struct {
+	blocks []*sshConfigBlock
+}
+
+// parseSSHConfig parses an OpenSSH client config file
+func parseSSHConfig(in io.Reader) (*sshConfigFile, error) {
+	// Options before the first Host apply to all hosts
+	block := &sshConfigBlock{patterns: []string{"*"}, options: map[string]string{}}
+	c := &sshConfigFile{blocks: []*sshConfigBlock{block}}
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// Keyword and arguments are separated by whitespace or
+		// optional whitespace and exactly one =
+		i := strings.IndexAny(line, " \t=")
+		if i < 0 {
+			continue
+		}
+		key, value := line[:i], strings.TrimSpace(line[i:])
+		value = strings.TrimPrefix(value, "=")
+		key = strings.ToLower(key)
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch key {
+		case "host":
+			block = &sshConfigBlock{patterns: strings.Fields(value), options: map[string]string{}}
+			c.blocks = append(c.blocks, block)
+		case "match":
+			// Not supported so never matches
+			block = &sshConfigBlock{options: map[string]string{}}
+			c.blocks = append(c.blocks, block)
+		case "include":
+			fs.Debugf(nil, "sftp: ignoring Include %q in ssh config", value)
+		default:
+			// The first value obtained for an option is used
+			if _, found := block.options[key]; !found {
+				block.options[key] = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// get returns the value of option key for host or "" if not set
+func (c *sshConfigFile) get(host, key string) string {
+	key = strings.ToLower(key)
+	for _, block := range c.blocks {
+		if !block.matches(host) {
+			continue
+		}
+		if value, found := block.options[key]; found {
+			return value
+		}
+	}
+	return ""
+}
+
+// jumpHosts returns the ProxyJump hosts for host, resolving each of
+// them using the HostName, User and Port for that host in the
+// config.
+func (c *sshConfigFile) jumpHosts(host, defaultUser string) (jumps []jumpHost, err error) {
+	proxyJump := c.get(host, "ProxyJump")
+	if proxyJump == "" || strings.EqualFold(proxyJump, "none") {
+		return nil, nil
+	}
+	for _, s := range strings.Split(proxyJump, ",") {
+		user, alias, port, err := splitJumpHost(strings.TrimPrefix(s, "ssh://"))
+		if err != nil {
+			return nil, err
+		}
+		hostName := c.get(alias, "HostName")
+		if hostName == "" {
+			hostName = alias
+		}
+		if user == "" {
+			user = c.get(alias, "User")
+		}
+		if user == "" {
+			user = defaultUser
+		}
+		if port == "" {
+			port = c.get(alias, "Port")
+		}
+		if port == "" {
+			port = "22"
+		}
+		jumps = append(jumps, jumpHost{user: user, addr: net.JoinHostPort(hostName, port)})
+	}
+	return jumps, nil
+}
+
+// getJumpHosts works out the jump hosts from the options, reading
+// them from the ssh config file if jump_hosts isn't set.
+func getJumpHosts(opt *Options) (jumps []jumpHost, err error) {
+	if len(opt.JumpHosts) > 0 {
+		return parseJumpHosts(opt.JumpHosts, opt.User)
+	}
+	if opt.SSHConfigFile == "" {
+		return nil, nil
+	}
+	fd, err := os.Open(env.ShellExpand(opt.SSHConfigFile))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open ssh_config_file: %w", err)
+	}
+	defer fs.CheckClose(fd, &err)
+	c, err := parseSSHConfig(fd)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse ssh_config_file: %w", err)
+	}
+	return c.jumpHosts(opt.Host, opt.User)
+}
+
+// dialJumpHosts connects to each of the jump hosts in turn over conn
+// which must be connected to the first one. It returns the clients
+// for each hop so the last can be used to dial the destination.
+//
+// The auth and host key checking from sshConfig are used for each hop.
+func (f *Fs) dialJumpHosts(conn net.Conn, sshConfig *ssh.ClientConfig) (clients []*ssh.Client, err error) {
+	defer func() {
+		if err != nil {
+			closeJumpHosts(clients)
+			_ = conn.Close()
+		}
+	}()
+	for i, jump := range f.jumps {
+		if i > 0 {
+			conn, err = clients[i-1].Dial("tcp", jump.addr)
+			if err != nil {
+				return clients, fmt.Errorf("couldn't connect to jump host %v: %w", jump, err)
+			}
+		}
+		hopConfig := *sshConfig
+		hopConfig.User = jump.user
+		c, chans, reqs, err := ssh.NewClientConn(conn, jump.addr, &hopConfig)
+		if err != nil {
+			return clients, fmt.Errorf("couldn't log in to jump host %v: %w", jump, err)
+		}
+		fs.Debugf(f, "Connected to jump host %v", jump)
+		clients = append(clients, ssh.NewClient(c, chans, reqs))
+	}
+	return clients, nil
+}
+
+// closeJumpHosts closes the jump host connections, last hop first
+func closeJumpHosts(clients []*ssh.Client) {
+	for i := len(clients) - 1; i >= 0; i-- {
+		_ = clients[i].Close()
+	}
+}
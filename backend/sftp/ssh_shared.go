@@ -0,0 +1,70 @@
+//go:build !plan9
+
+package sftp
+
+import "slices"
+
+// Sharing ssh connections between several SFTP sessions
+
+// sharedSSHClient is an sshClient which is used by up to
+// --sftp-sessions-per-connection SFTP sessions.
+//
+// The underlying ssh connection is closed when the last user
+// closes it.
+type sharedSSHClient struct {
+	sshClient
+	f     *Fs
+	users int // number of users - protected by f.sharedMu
+}
+
+// Close the connection if this is the last user
+func (s *sharedSSHClient) Close() error {
+	s.f.sharedMu.Lock()
+	s.users--
+	last := s.users <= 0
+	if last {
+		s.f.shared = slices.DeleteFunc(s.f.shared, func(c *sharedSSHClient) bool { return c == s })
+	}
+	s.f.sharedMu.Unlock()
+	if !last {
+		return nil
+	}
+	return s.sshClient.Close()
+}
+
+// getSharedSSHClient returns an ssh connection which has room for
+// another session, making a new one with newClient if necessary.
+func (f *Fs) getSharedSSHClient(newClient func() (sshClient, error)) (sshClient, error) {
+	f.sharedMu.Lock()
+	for _, s := range f.shared {
+		if s.users < f.opt.SessionsPerConnection {
+			s.users++
+			f.sharedMu.Unlock()
+			return s, nil
+		}
+	}
+	f.sharedMu.Unlock()
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	s := &sharedSSHClient{
+		sshClient: client,
+		f:         f,
+		users:     1,
+	}
+	f.sharedMu.Lock()
+	f.shared = append(f.shared, s)
+	f.sharedMu.Unlock()
+	// Stop handing out the connection if it dies
+	go func() {
+		_ = client.Wait()
+		f.sharedMu.Lock()
+		f.shared = slices.DeleteFunc(f.shared, func(c *sharedSSHClient) bool { return c == s })
+		f.sharedMu.Unlock()
+	}()
+	return s, nil
+}
+
+// Check interfaces
+var _ sshClient = (*sharedSSHClient)(nil)
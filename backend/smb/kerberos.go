@@ -1,50 +1,94 @@
 package smb
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/jcmturner/gokrb5/v8/client"
 	"github.com/jcmturner/gokrb5/v8/config"
 	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
 )
 
+// kerberosEntry is a cached Kerberos client
+type kerberosEntry struct {
+	client  *client.Client
+	modTime time.Time // modification time of the ccache when loaded
+}
+
 var (
-	kerberosClient *client.Client
-	kerberosErr    error
-	kerberosOnce   sync.Once
+	kerberosMu      sync.Mutex
+	kerberosClients = map[string]*kerberosEntry{} // keyed by ccache or keytab and principal
 )
 
-// getKerberosClient returns a Kerberos client that can be used to authenticate.
-func getKerberosClient() (*client.Client, error) {
-	if kerberosClient == nil || kerberosErr == nil {
-		kerberosOnce.Do(func() {
-			kerberosClient, kerberosErr = createKerberosClient()
-		})
+// getKerberosClient returns a Kerberos client that can be used to
+// authenticate.
+//
+// Clients are shared between remotes using the same credentials. A
+// client made from a ccache is made again if the ccache has changed,
+// for example when kinit has been run to renew the tickets.
+func (f *Fs) getKerberosClient() (*client.Client, error) {
+	kerberosMu.Lock()
+	defer kerberosMu.Unlock()
+
+	if f.opt.KerberosKeytab != "" {
+		key := "keytab:" + f.opt.KerberosKeytab + ":" + f.opt.User
+		if entry, ok := kerberosClients[key]; ok {
+			return entry.client, nil
+		}
+		cl, err := newKerberosKeytabClient(f.opt.KerberosKeytab, f.opt.User)
+		if err != nil {
+			return nil, err
+		}
+		kerberosClients[key] = &kerberosEntry{client: cl}
+		return cl, nil
 	}
 
-	return kerberosClient, kerberosErr
+	ccachePath, err := resolveCCachePath(f.opt.KerberosCCache)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(ccachePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Kerberos credentials cache: %w", err)
+	}
+	key := "ccache:" + ccachePath
+	if entry, ok := kerberosClients[key]; ok && entry.modTime.Equal(fi.ModTime()) {
+		return entry.client, nil
+	}
+	cl, err := newKerberosCCacheClient(ccachePath)
+	if err != nil {
+		return nil, err
+	}
+	kerberosClients[key] = &kerberosEntry{client: cl, modTime: fi.ModTime()}
+	return cl, nil
 }
 
-// createKerberosClient creates a new Kerberos client.
-func createKerberosClient() (*client.Client, error) {
+// loadKerberosConfig loads the Kerberos configuration from
+// KRB5_CONFIG or the default location.
+func loadKerberosConfig() (*config.Config, error) {
 	cfgPath := os.Getenv("KRB5_CONFIG")
 	if cfgPath == "" {
 		cfgPath = "/etc/krb5.conf"
 	}
+	return config.Load(cfgPath)
+}
 
-	cfg, err := config.Load(cfgPath)
-	if err != nil {
-		return nil, err
+// resolveCCachePath works out the path of the ccache file from
+// ccachePath, or KRB5CCNAME if that is empty, falling back to the
+// default location.
+//
+// FILE: and DIR: ccache names are supported.
+func resolveCCachePath(ccachePath string) (string, error) {
+	if ccachePath == "" {
+		ccachePath = os.Getenv("KRB5CCNAME")
 	}
-
-	// Determine the ccache location from the environment, falling back to the
-	// default location.
-	ccachePath := os.Getenv("KRB5CCNAME")
 	switch {
 	case strings.Contains(ccachePath, ":"):
 		parts := strings.SplitN(ccachePath, ":", 2)
@@ -54,20 +98,30 @@ func createKerberosClient() (*client.Client, error) {
 		case "DIR":
 			primary, err := os.ReadFile(filepath.Join(parts[1], "primary"))
 			if err != nil {
-				return nil, err
+				return "", err
 			}
 			ccachePath = filepath.Join(parts[1], strings.TrimSpace(string(primary)))
 		default:
-			return nil, fmt.Errorf("unsupported KRB5CCNAME: %s", ccachePath)
+			return "", fmt.Errorf("unsupported KRB5CCNAME: %s", ccachePath)
 		}
 	case ccachePath == "":
 		u, err := user.Current()
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 
 		ccachePath = "/tmp/krb5cc_" + u.Uid
 	}
+	return ccachePath, nil
+}
+
+// newKerberosCCacheClient creates a new Kerberos client from the
+// ccache at ccachePath.
+func newKerberosCCacheClient(ccachePath string) (*client.Client, error) {
+	cfg, err := loadKerberosConfig()
+	if err != nil {
+		return nil, err
+	}
 
 	ccache, err := credentials.LoadCCache(ccachePath)
 	if err != nil {
@@ -76,3 +130,48 @@ func createKerberosClient() (*client.Client, error) {
 
 	return client.NewFromCCache(ccache, cfg)
 }
+
+// splitPrincipal splits principal into user name and realm, using
+// defaultRealm if principal doesn't have one.
+func splitPrincipal(principal, defaultRealm string) (username, realm string, err error) {
+	username, realm, found := strings.Cut(principal, "@")
+	if !found || realm == "" {
+		realm = defaultRealm
+	}
+	if username == "" {
+		return "", "", errors.New("user must be set to use a Kerberos keytab")
+	}
+	if realm == "" {
+		return "", "", fmt.Errorf("no realm in user %q and no default_realm in the Kerberos configuration", principal)
+	}
+	return username, realm, nil
+}
+
+// newKerberosKeytabClient creates a new Kerberos client which logs
+// in as principal with the keys in the keytab at keytabPath.
+//
+// Unlike a client from a ccache, this renews its own tickets.
+func newKerberosKeytabClient(keytabPath, principal string) (*client.Client, error) {
+	cfg, err := loadKerberosConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	username, realm, err := splitPrincipal(principal, cfg.LibDefaults.DefaultRealm)
+	if err != nil {
+		return nil, err
+	}
+
+	kt, err := keytab.Load(keytabPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Kerberos keytab: %w", err)
+	}
+
+	// Active Directory doesn't support PA-FX-FAST
+	cl := client.NewWithKeytab(username, realm, kt, cfg, client.DisablePAFXFAST(true))
+	err = cl.Login()
+	if err != nil {
+		return nil, fmt.Errorf("failed to log in to Kerberos as %s@%s: %w", username, realm, err)
+	}
+	return cl, nil
+}
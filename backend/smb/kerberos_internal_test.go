@@ -0,0 +1,62 @@
+package smb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCCachePath(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "primary"), []byte("tkt123\n"), 0600))
+
+	t.Setenv("KRB5CCNAME", "/env/ccache")
+	for _, test := range []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: "/env/ccache"},
+		{in: "/tmp/cc", want: "/tmp/cc"},
+		{in: "FILE:/tmp/cc", want: "/tmp/cc"},
+		{in: "DIR:" + dir, want: filepath.Join(dir, "tkt123")},
+		{in: "KEYRING:persistent:1000", wantErr: true},
+	} {
+		got, err := resolveCCachePath(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestSplitPrincipal(t *testing.T) {
+	for _, test := range []struct {
+		principal    string
+		defaultRealm string
+		wantUser     string
+		wantRealm    string
+		wantErr      bool
+	}{
+		{principal: "alice@EXAMPLE.COM", wantUser: "alice", wantRealm: "EXAMPLE.COM"},
+		{principal: "alice@EXAMPLE.COM", defaultRealm: "OTHER.COM", wantUser: "alice", wantRealm: "EXAMPLE.COM"},
+		{principal: "alice", defaultRealm: "OTHER.COM", wantUser: "alice", wantRealm: "OTHER.COM"},
+		{principal: "alice@", defaultRealm: "OTHER.COM", wantUser: "alice", wantRealm: "OTHER.COM"},
+		{principal: "alice", wantErr: true},
+		{principal: "", defaultRealm: "OTHER.COM", wantErr: true},
+	} {
+		gotUser, gotRealm, err := splitPrincipal(test.principal, test.defaultRealm)
+		if test.wantErr {
+			assert.Error(t, err, test.principal)
+			continue
+		}
+		require.NoError(t, err, test.principal)
+		assert.Equal(t, test.wantUser, gotUser, test.principal)
+		assert.Equal(t, test.wantRealm, gotRealm, test.principal)
+	}
+}
@@ -3,6 +3,7 @@ package smb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	smb2 "github.com/cloudsoda/go-smb2"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config"
 	"github.com/rclone/rclone/fs/config/configmap"
@@ -30,8 +32,13 @@ const (
 	decayConstant = 2 // bigger for slower decay, exponential
 )
 
+// statusPathNotCovered is the NTSTATUS servers return for paths
+// inside a DFS link, which need a DFS referral to reach
+const statusPathNotCovered = 0xC0000257
+
 var (
 	currentUser = env.CurrentUser()
+	errDFSLink  = errors.New("path is inside a DFS link which rclone can't follow - use the server and share the link points to instead")
 )
 
 // Register with Fs
@@ -86,6 +93,35 @@ available, either in the default locations or as specified by the
 KRB5_CONFIG and KRB5CCNAME environment variables.
 `,
 			Default: false,
+		}, {
+			Name: "kerberos_ccache",
+			Help: `Path to the Kerberos credentials cache.
+
+Use this to set the credentials cache for this remote instead of using
+KRB5CCNAME. It can be a file name, or FILE:path or DIR:path as used
+in KRB5CCNAME.
+
+Rclone reloads the credentials cache if it changes, so tickets
+renewed with kinit are used without restarting rclone.
+
+Leave blank to use KRB5CCNAME or the default location.
+`,
+			Advanced: true,
+		}, {
+			Name: "kerberos_keytab",
+			Help: `Path to a Kerberos keytab.
+
+If set, rclone logs in to Kerberos with the keys for the user in this
+keytab instead of using a credentials cache, and renews its tickets
+itself. This is useful for services with no one to run kinit.
+
+The user must be set to the principal in the keytab, e.g.
+"svc-rclone@EXAMPLE.COM". If no realm is given the default_realm
+from the Kerberos configuration is used.
+
+Only used if use_kerberos is set.
+`,
+			Advanced: true,
 		}, {
 			Name:    "idle_timeout",
 			Default: fs.Duration(60 * time.Second),
@@ -137,6 +173,8 @@ type Options struct {
 	Domain          string      `config:"domain"`
 	SPN             string      `config:"spn"`
 	UseKerberos     bool        `config:"use_kerberos"`
+	KerberosCCache  string      `config:"kerberos_ccache"`
+	KerberosKeytab  string      `config:"kerberos_keytab"`
 	HideSpecial     bool        `config:"hide_special_share"`
 	CaseInsensitive bool        `config:"case_insensitive"`
 	IdleTimeout     fs.Duration `config:"idle_timeout"`
@@ -847,6 +885,10 @@ func translateError(e error, dir bool) error {
 		}
 		return fs.ErrorObjectNotFound
 	}
+	var respErr *smb2.ResponseError
+	if errors.As(e, &respErr) && respErr.Code == statusPathNotCovered {
+		return fmt.Errorf("%w: %w", errDFSLink, e)
+	}
 
 	return e
 }
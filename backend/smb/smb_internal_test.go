@@ -0,0 +1,24 @@
+package smb
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	smb2 "github.com/cloudsoda/go-smb2"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslateError(t *testing.T) {
+	assert.Equal(t, fs.ErrorDirNotFound, translateError(os.ErrNotExist, true))
+	assert.Equal(t, fs.ErrorObjectNotFound, translateError(os.ErrNotExist, false))
+
+	dfsErr := &os.PathError{Op: "open", Path: "link", Err: &smb2.ResponseError{Code: statusPathNotCovered}}
+	err := translateError(dfsErr, false)
+	assert.True(t, errors.Is(err, errDFSLink))
+	assert.True(t, errors.Is(err, dfsErr))
+
+	otherErr := &smb2.ResponseError{Code: 0xC0000022} // STATUS_ACCESS_DENIED
+	assert.Equal(t, error(otherErr), translateError(otherErr, false))
+}
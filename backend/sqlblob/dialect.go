@@ -0,0 +1,67 @@
+package sqlblob
+
+// Differences between the supported databases
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // register the pgx database/sql driver
+)
+
+// dialect describes how to talk to one kind of database
+type dialect struct {
+	driverName string                  // name of the database/sql driver
+	blobType   string                  // column type for the chunk data
+	numbered   bool                    // use $1, $2, ... placeholders instead of ?
+	dsn        func(dsn string) string // adjust the user's DSN before opening - may be nil
+	setup      func(db *sql.DB)        // configure the connection pool - may be nil
+}
+
+// dialects holds the supported databases indexed by the driver option
+//
+// sqlite is added in sqlite.go on the platforms it supports.
+var dialects = map[string]*dialect{
+	"postgres": {
+		driverName: "pgx",
+		blobType:   "BYTEA",
+		numbered:   true,
+	},
+}
+
+// findDialect looks up the dialect for driver
+func findDialect(driver string) (*dialect, error) {
+	d, ok := dialects[driver]
+	if ok {
+		return d, nil
+	}
+	if driver == "sqlite" {
+		return nil, fmt.Errorf("driver %q is not supported on this platform", driver)
+	}
+	return nil, fmt.Errorf("unknown driver %q - must be postgres or sqlite", driver)
+}
+
+// rebind converts the ? placeholders in query into the style the
+// database needs
+//
+// None of the queries contain ? in string literals so this can be
+// done with a simple scan.
+func (d *dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var out strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			out.WriteByte('$')
+			out.WriteString(strconv.Itoa(n))
+		} else {
+			out.WriteRune(c)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,79 @@
+package sqlblob
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// skipUnlessSQLite skips the test if sqlite isn't available
+func skipUnlessSQLite(t *testing.T) {
+	if _, ok := dialects["sqlite"]; !ok {
+		t.Skip("sqlite not supported on this platform")
+	}
+}
+
+// TestSQLite runs the integration tests against a temporary sqlite
+// database
+func TestSQLite(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	skipUnlessSQLite(t)
+	name := "TestSQLBlobSQLite"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "sqlblob"},
+			{Name: name, Key: "driver", Value: "sqlite"},
+			{Name: name, Key: "dsn", Value: filepath.Join(t.TempDir(), "rclone.db")},
+			// small chunks so files are split into several
+			{Name: name, Key: "chunk_size", Value: "1Ki"},
+		},
+		QuickTestOK: true,
+	})
+}
+
+func TestRebind(t *testing.T) {
+	d := &dialect{numbered: true}
+	assert.Equal(t, "SELECT a FROM b WHERE c = $1 AND d > $2", d.rebind("SELECT a FROM b WHERE c = ? AND d > ?"))
+	d.numbered = false
+	assert.Equal(t, "SELECT a FROM b WHERE c = ?", d.rebind("SELECT a FROM b WHERE c = ?"))
+}
+
+// TestListPaging checks listings longer than a page are read completely
+func TestListPaging(t *testing.T) {
+	skipUnlessSQLite(t)
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "rclone.db")
+	f, err := fs.NewFs(ctx, fmt.Sprintf(":sqlblob,driver=sqlite,dsn='%s':", dsn))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, f.(*Fs).Shutdown(ctx))
+	}()
+	const n = 2*listLimit + 10
+	for i := range n {
+		require.NoError(t, f.Mkdir(ctx, fmt.Sprintf("dir/%05d", i)))
+	}
+	entries, err := f.List(ctx, "dir")
+	require.NoError(t, err)
+	assert.Equal(t, n, len(entries))
+	assert.Equal(t, "dir/00000", entries[0].Remote())
+	assert.Equal(t, fmt.Sprintf("dir/%05d", n-1), entries[n-1].Remote())
+
+	count := 0
+	err = f.(*Fs).ListR(ctx, "", func(entries fs.DirEntries) error {
+		count += len(entries)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, n+1, count)
+}
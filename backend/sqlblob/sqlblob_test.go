@@ -0,0 +1,16 @@
+// Test sqlblob filesystem interface
+package sqlblob
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestSQLBlob:",
+		NilObject:  (*Object)(nil),
+	})
+}
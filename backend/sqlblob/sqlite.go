@@ -0,0 +1,47 @@
+// SQLite support is pure Go but only available on the platforms
+// modernc.org/sqlite has been translated for.
+
+//go:build (linux && (386 || amd64 || arm || arm64 || loong64 || ppc64le || riscv64 || s390x)) || (darwin && (amd64 || arm64)) || (freebsd && (386 || amd64 || arm || arm64)) || (windows && (386 || amd64 || arm64)) || (netbsd && amd64) || (openbsd && (amd64 || arm64))
+
+package sqlblob
+
+import (
+	"database/sql"
+	"strings"
+
+	_ "modernc.org/sqlite" // register the sqlite database/sql driver
+)
+
+func init() {
+	dialects["sqlite"] = &dialect{
+		driverName: "sqlite",
+		blobType:   "BLOB",
+		dsn:        sqliteDSN,
+		setup: func(db *sql.DB) {
+			// SQLite only allows one writer at once and
+			// each connection to an in memory database
+			// would be a separate database.
+			db.SetMaxOpenConns(1)
+		},
+	}
+}
+
+// sqliteDSN adds the pragmas we need to dsn unless the user has
+// set them already
+func sqliteDSN(dsn string) string {
+	var params []string
+	if !strings.Contains(dsn, "busy_timeout") {
+		params = append(params, "_pragma=busy_timeout(10000)")
+	}
+	if !strings.Contains(dsn, "_txlock") {
+		params = append(params, "_txlock=immediate")
+	}
+	if len(params) == 0 {
+		return dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + strings.Join(params, "&")
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/lib/bucket"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/text/unicode/norm"
 
 	"storj.io/uplink"
@@ -163,6 +164,7 @@ var (
 	_ fs.ListRer      = &Fs{}
 	_ fs.PutStreamer  = &Fs{}
 	_ fs.Mover        = &Fs{}
+	_ fs.DirMover     = &Fs{}
 	_ fs.Copier       = &Fs{}
 	_ fs.Purger       = &Fs{}
 	_ fs.PublicLinker = &Fs{}
@@ -709,6 +711,26 @@ func newPrefix(prefix string) string {
 	return prefix + "/"
 }
 
+// moveObject moves srcKey in srcBucket to dstKey in dstBucket
+// server-side, creating dstBucket if necessary.
+func (f *Fs) moveObject(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string) error {
+	options := uplink.MoveObjectOptions{}
+	err := f.project.MoveObject(ctx, srcBucket, srcKey, dstBucket, dstKey, &options)
+	if errors.Is(err, uplink.ErrBucketNotFound) {
+		// Make sure destination bucket exists
+		_, err = f.project.EnsureBucket(ctx, dstBucket)
+		if err != nil {
+			return fmt.Errorf("rename object failed to create destination bucket: %w", err)
+		}
+		// And try again
+		err = f.project.MoveObject(ctx, srcBucket, srcKey, dstBucket, dstKey, &options)
+	}
+	if err != nil {
+		return fmt.Errorf("rename object failed: %w", err)
+	}
+	return nil
+}
+
 // Move src to this remote using server-side move operations.
 //
 // This is stored with the remote path given.
@@ -728,27 +750,129 @@ func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	// Move parameters
 	srcBucket, srcKey := bucket.Split(srcObj.absolute)
 	dstBucket, dstKey := f.absolute(remote)
-	options := uplink.MoveObjectOptions{}
 
 	// Do the move
-	err := f.project.MoveObject(ctx, srcBucket, srcKey, dstBucket, dstKey, &options)
+	err := f.moveObject(ctx, srcBucket, srcKey, dstBucket, dstKey)
 	if err != nil {
-		// Make sure destination bucket exists
-		_, err := f.project.EnsureBucket(ctx, dstBucket)
-		if err != nil {
-			return nil, fmt.Errorf("rename object failed to create destination bucket: %w", err)
-		}
-		// And try again
-		err = f.project.MoveObject(ctx, srcBucket, srcKey, dstBucket, dstKey, &options)
-		if err != nil {
-			return nil, fmt.Errorf("rename object failed: %w", err)
-		}
+		return nil, err
 	}
 
 	// Read the new object
 	return f.NewObject(ctx, remote)
 }
 
+// prefixExists returns true if there are any objects under bucketPath
+// in bucketName, or in the bucket if bucketPath is empty.
+func (f *Fs) prefixExists(ctx context.Context, bucketName, bucketPath string) (bool, error) {
+	objects := f.project.ListObjects(ctx, bucketName, &uplink.ListObjectsOptions{
+		Prefix: newPrefix(bucketPath),
+	})
+	if objects.Next() {
+		return true, nil
+	}
+	err := objects.Err()
+	if errors.Is(err, uplink.ErrBucketNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Storj has no directories, so each object under the prefix is moved
+// in turn. This is done by the satellite without downloading or
+// re-encoding any data.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(src, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	srcBucket, srcPath := srcFs.absolute(srcRemote)
+	dstBucket, dstPath := f.absolute(dstRemote)
+	if srcBucket == "" || dstBucket == "" {
+		fs.Debugf(src, "Can't move directory - can't move the root")
+		return fs.ErrorCantDirMove
+	}
+
+	// Check the destination doesn't exist
+	exists, err := f.prefixExists(ctx, dstBucket, dstPath)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fs.ErrorDirExists
+	}
+
+	// Refuse to move a directory into itself
+	if srcBucket == dstBucket && (srcPath == "" || strings.HasPrefix(dstPath+"/", srcPath+"/")) {
+		fs.Debugf(src, "Can't move directory - destination is inside source")
+		return fs.ErrorCantDirMove
+	}
+
+	// Read the keys to move first so the listing isn't changed
+	// under our feet
+	srcPrefix := newPrefix(srcPath)
+	objects := f.project.ListObjects(ctx, srcBucket, &uplink.ListObjectsOptions{
+		Prefix:    srcPrefix,
+		Recursive: true,
+	})
+	var keys []string
+	for objects.Next() {
+		keys = append(keys, objects.Item().Key)
+	}
+	err = objects.Err()
+	if errors.Is(err, uplink.ErrBucketNotFound) {
+		return fs.ErrorDirNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fs.ErrorDirNotFound
+	}
+
+	_, err = f.project.EnsureBucket(ctx, dstBucket)
+	if err != nil {
+		return fmt.Errorf("rename directory failed to create destination bucket: %w", err)
+	}
+
+	dstPrefix := newPrefix(dstPath)
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(fs.GetConfig(ctx).Transfers)
+	for _, key := range keys {
+		dstKey := dstPrefix + strings.TrimPrefix(key, srcPrefix)
+		g.Go(func() error {
+			err := f.moveObject(gCtx, srcBucket, key, dstBucket, dstKey)
+			if err != nil {
+				return fmt.Errorf("failed to move %q: %w", key, err)
+			}
+			fs.Debugf(src, "Moved %q to %q", key, dstKey)
+			return nil
+		})
+	}
+	err = g.Wait()
+	if err != nil {
+		return err
+	}
+
+	// The source bucket is now empty so remove it
+	if srcPath == "" {
+		_, err = f.project.DeleteBucket(ctx, srcBucket)
+		if err != nil {
+			return fmt.Errorf("rename directory failed to remove source bucket: %w", err)
+		}
+	}
+	return nil
+}
+
 // Copy src to this remote using server-side copy operations.
 //
 // This is stored with the remote path given.
@@ -772,17 +896,17 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object,
 
 	// Do the copy
 	newObject, err := f.project.CopyObject(ctx, srcBucket, srcKey, dstBucket, dstKey, &options)
-	if err != nil {
+	if errors.Is(err, uplink.ErrBucketNotFound) {
 		// Make sure destination bucket exists
-		_, err := f.project.EnsureBucket(ctx, dstBucket)
+		_, err = f.project.EnsureBucket(ctx, dstBucket)
 		if err != nil {
 			return nil, fmt.Errorf("copy object failed to create destination bucket: %w", err)
 		}
 		// And try again
 		newObject, err = f.project.CopyObject(ctx, srcBucket, srcKey, dstBucket, dstKey, &options)
-		if err != nil {
-			return nil, fmt.Errorf("copy object failed: %w", err)
-		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("copy object failed: %w", err)
 	}
 
 	// Return the new object
@@ -0,0 +1,147 @@
+package torrent
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// maxDepth is the deepest nesting of lists and dictionaries allowed
+const maxDepth = 64
+
+var errBencodeEOF = errors.New("bencode: unexpected end of data")
+
+// bdecode decodes the bencoded data in b which must hold exactly one
+// value.
+//
+// Integers are returned as int64, strings as string, lists as []any
+// and dictionaries as map[string]any.
+func bdecode(b []byte) (any, error) {
+	v, next, err := bdecodeValue(b, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if next != len(b) {
+		return nil, fmt.Errorf("bencode: %d bytes of trailing data", len(b)-next)
+	}
+	return v, nil
+}
+
+// bdecodeValue decodes the value starting at b[pos] returning it and
+// the position after it.
+func bdecodeValue(b []byte, pos int, depth int) (v any, next int, err error) {
+	if pos >= len(b) {
+		return nil, pos, errBencodeEOF
+	}
+	if depth > maxDepth {
+		return nil, pos, errors.New("bencode: nested too deeply")
+	}
+	switch c := b[pos]; {
+	case c == 'i':
+		end := indexByte(b, pos+1, 'e')
+		if end < 0 {
+			return nil, pos, errBencodeEOF
+		}
+		i, err := strconv.ParseInt(string(b[pos+1:end]), 10, 64)
+		if err != nil {
+			return nil, pos, fmt.Errorf("bencode: bad integer at %d: %w", pos, err)
+		}
+		return i, end + 1, nil
+	case c >= '0' && c <= '9':
+		s, next, err := bdecodeString(b, pos)
+		return s, next, err
+	case c == 'l':
+		list := []any{}
+		pos++
+		for {
+			if pos >= len(b) {
+				return nil, pos, errBencodeEOF
+			}
+			if b[pos] == 'e' {
+				return list, pos + 1, nil
+			}
+			v, pos, err = bdecodeValue(b, pos, depth+1)
+			if err != nil {
+				return nil, pos, err
+			}
+			list = append(list, v)
+		}
+	case c == 'd':
+		dict := map[string]any{}
+		pos++
+		for {
+			if pos >= len(b) {
+				return nil, pos, errBencodeEOF
+			}
+			if b[pos] == 'e' {
+				return dict, pos + 1, nil
+			}
+			var key string
+			key, pos, err = bdecodeString(b, pos)
+			if err != nil {
+				return nil, pos, err
+			}
+			v, pos, err = bdecodeValue(b, pos, depth+1)
+			if err != nil {
+				return nil, pos, err
+			}
+			dict[key] = v
+		}
+	default:
+		return nil, pos, fmt.Errorf("bencode: unexpected %q at %d", c, pos)
+	}
+}
+
+// bdecodeString decodes the string starting at b[pos]
+func bdecodeString(b []byte, pos int) (s string, next int, err error) {
+	colon := indexByte(b, pos, ':')
+	if colon < 0 {
+		return "", pos, errBencodeEOF
+	}
+	n, err := strconv.Atoi(string(b[pos:colon]))
+	if err != nil || n < 0 {
+		return "", pos, fmt.Errorf("bencode: bad string length at %d", pos)
+	}
+	start := colon + 1
+	if n > len(b)-start {
+		return "", pos, errBencodeEOF
+	}
+	return string(b[start : start+n]), start + n, nil
+}
+
+// indexByte returns the index of c in b at or after pos or -1
+func indexByte(b []byte, pos int, c byte) int {
+	for i := pos; i < len(b); i++ {
+		if b[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// bencodedValue returns the raw bencoded bytes of key in the
+// dictionary in b, or nil if it isn't found.
+//
+// This is used to find the info dictionary of a torrent, whose SHA1
+// hash is the info hash.
+func bencodedValue(b []byte, key string) ([]byte, error) {
+	if len(b) == 0 || b[0] != 'd' {
+		return nil, errors.New("bencode: not a dictionary")
+	}
+	pos := 1
+	for pos < len(b) && b[pos] != 'e' {
+		k, next, err := bdecodeString(b, pos)
+		if err != nil {
+			return nil, err
+		}
+		_, end, err := bdecodeValue(b, next, 1)
+		if err != nil {
+			return nil, err
+		}
+		if k == key {
+			return b[next:end], nil
+		}
+		pos = end
+	}
+	return nil, nil
+}
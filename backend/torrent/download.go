@@ -0,0 +1,249 @@
+package torrent
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+)
+
+const (
+	maxPieceTries = 5 // number of times to try downloading a piece
+	piecesAhead   = 2 // number of pieces each peer downloads ahead of the reader
+)
+
+var errNoPeers = errors.New("no peers left to download from")
+
+// pieceResult is a downloaded piece or the error from trying
+type pieceResult struct {
+	data []byte
+	err  error
+}
+
+// peerReader reads a range of the data of a torrent by downloading
+// its pieces from several peers at once.
+//
+// If that fails part way through, the rest is read with fallback if
+// set.
+type peerReader struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	f        *Fs
+	pos      int64              // offset in the torrent of the next byte to read
+	end      int64              // offset in the torrent to stop reading at
+	first    int                // index of the first piece
+	results  []chan pieceResult // results for each piece from first
+	next     int                // index into results of the next piece to read
+	buf      []byte             // unread data from the current piece
+	tokens   chan struct{}      // limits the pieces downloaded ahead
+	jobs     chan int           // pieces to download
+	done     chan struct{}      // closed when all the downloaders have finished
+	fallback func(pos int64) (io.ReadCloser, error)
+	in       io.ReadCloser // set if reading with fallback
+
+	mu    sync.Mutex
+	addrs []string    // peers not yet tried
+	tries map[int]int // number of tries of each piece
+}
+
+// newPeerReader starts downloading the data of the torrent from start
+// to end from up to f.opt.Peers of the peers in addrs at once.
+func newPeerReader(ctx context.Context, f *Fs, addrs []string, start, end int64, fallback func(pos int64) (io.ReadCloser, error)) *peerReader {
+	ctx, cancel := context.WithCancel(ctx)
+	first := int(start / f.info.pieceLength)
+	last := int((end - 1) / f.info.pieceLength)
+	workers := min(f.opt.Peers, len(addrs))
+	r := &peerReader{
+		ctx:      ctx,
+		cancel:   cancel,
+		f:        f,
+		pos:      start,
+		end:      end,
+		first:    first,
+		results:  make([]chan pieceResult, last-first+1),
+		tokens:   make(chan struct{}, workers*piecesAhead),
+		jobs:     make(chan int, last-first+1),
+		done:     make(chan struct{}),
+		fallback: fallback,
+		addrs:    append([]string(nil), addrs...),
+		tries:    map[int]int{},
+	}
+	for i := range r.results {
+		r.results[i] = make(chan pieceResult, 1)
+	}
+
+	// Queue the pieces, only letting the downloaders get so far ahead
+	go func() {
+		for i := first; i <= last; i++ {
+			select {
+			case r.tokens <- struct{}{}:
+				r.jobs <- i
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.download()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(r.done)
+	}()
+	return r
+}
+
+// nextPeer connects to the next peer which hasn't been tried
+func (r *peerReader) nextPeer() (*peer, error) {
+	infoHash, err := hex.DecodeString(r.f.info.infoHash)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		r.mu.Lock()
+		if len(r.addrs) == 0 {
+			r.mu.Unlock()
+			return nil, errNoPeers
+		}
+		addr := r.addrs[0]
+		r.addrs = r.addrs[1:]
+		r.mu.Unlock()
+		p, err := dialPeer(r.ctx, addr, r.f.info, infoHash, r.f.peerID[:])
+		if err == nil {
+			return p, nil
+		}
+		if r.ctx.Err() != nil {
+			return nil, r.ctx.Err()
+		}
+		fs.Debugf(r.f, "Failed to connect to peer %s: %v", addr, err)
+	}
+}
+
+// download pieces from peers until there are no more pieces or peers
+func (r *peerReader) download() {
+	var p *peer
+	defer func() {
+		if p != nil {
+			_ = p.close()
+		}
+	}()
+	for {
+		var i int
+		select {
+		case i = <-r.jobs:
+		case <-r.ctx.Done():
+			return
+		}
+		if p == nil {
+			var err error
+			p, err = r.nextPeer()
+			if err != nil {
+				// Put the piece back for the other downloaders
+				r.jobs <- i
+				return
+			}
+		}
+		data, err := p.downloadPiece(r.ctx, i)
+		if err == nil {
+			r.results[i-r.first] <- pieceResult{data: data}
+			continue
+		}
+		if r.ctx.Err() != nil {
+			return
+		}
+		fs.Debugf(r.f, "Failed to download piece %d from peer %s: %v", i, p.addr, err)
+		if err != errMissingPiece {
+			// Don't use this peer again
+			_ = p.close()
+			p = nil
+		}
+		r.mu.Lock()
+		r.tries[i]++
+		tries := r.tries[i]
+		r.mu.Unlock()
+		if tries >= maxPieceTries {
+			r.results[i-r.first] <- pieceResult{err: fmt.Errorf("failed to download piece %d: %w", i, err)}
+			continue
+		}
+		r.jobs <- i
+	}
+}
+
+// nextPiece waits for the next piece to be downloaded
+func (r *peerReader) nextPiece() ([]byte, error) {
+	results := r.results[r.next]
+	var result pieceResult
+	select {
+	case result = <-results:
+	case <-r.done:
+		// The last downloader may have sent the piece before finishing
+		select {
+		case result = <-results:
+		default:
+			return nil, errNoPeers
+		}
+	case <-r.ctx.Done():
+		return nil, r.ctx.Err()
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+	// Let another piece be downloaded
+	<-r.tokens
+	i := r.first + r.next
+	r.next++
+	start := int64(i) * r.f.info.pieceLength
+	data := result.data[r.pos-start:]
+	if start+int64(len(result.data)) > r.end {
+		data = data[:r.end-r.pos]
+	}
+	return data, nil
+}
+
+// Read bytes from the torrent
+func (r *peerReader) Read(p []byte) (n int, err error) {
+	if r.in != nil {
+		return r.in.Read(p)
+	}
+	if r.pos >= r.end {
+		return 0, io.EOF
+	}
+	if len(r.buf) == 0 {
+		r.buf, err = r.nextPiece()
+		if err != nil {
+			if r.fallback == nil || r.ctx.Err() != nil {
+				return 0, err
+			}
+			fs.Debugf(r.f, "Reading the rest from webseeds after failing to read from peers: %v", err)
+			r.cancel()
+			r.in, err = r.fallback(r.pos)
+			if err != nil {
+				return 0, err
+			}
+			return r.in.Read(p)
+		}
+	}
+	n = copy(p, r.buf)
+	r.buf = r.buf[n:]
+	r.pos += int64(n)
+	return n, nil
+}
+
+// Close the reader stopping the downloads
+func (r *peerReader) Close() error {
+	r.cancel()
+	if r.in != nil {
+		return r.in.Close()
+	}
+	return nil
+}
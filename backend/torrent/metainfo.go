@@ -0,0 +1,296 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metaInfo is the parsed contents of a torrent file
+type metaInfo struct {
+	infoHash    string         // hex SHA1 of the info dictionary
+	name        string         // suggested name of the file or directory
+	multiFile   bool           // set if the torrent holds a directory of files
+	files       []*torrentFile // the files in the torrent
+	webseeds    []string       // BEP 19 webseed URLs
+	trackers    []string       // tracker URLs from announce and announce-list
+	created     time.Time      // creation date of the torrent if known
+	pieceLength int64          // size of each piece apart from the last
+	pieces      []byte         // SHA1 hashes of the pieces
+	size        int64          // total size of the data including padding
+}
+
+// torrentFile is a file in a torrent
+type torrentFile struct {
+	path    []string  // path of the file inside the torrent directory
+	offset  int64     // offset of the file in the data of the torrent
+	size    int64     // size of the file
+	modTime time.Time // modification time if known
+	md5     string    // hex MD5 if known
+	sha1    string    // hex SHA1 if known
+	crc32   string    // hex CRC32 if known
+}
+
+// parseMetaInfo parses the torrent file in b
+func parseMetaInfo(b []byte) (*metaInfo, error) {
+	v, err := bdecode(b)
+	if err != nil {
+		return nil, err
+	}
+	top, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("torrent file is not a dictionary")
+	}
+	info, ok := top["info"].(map[string]any)
+	if !ok {
+		return nil, errors.New("torrent file has no info dictionary")
+	}
+	rawInfo, err := bencodedValue(b, "info")
+	if err != nil {
+		return nil, err
+	}
+	infoHash := sha1.Sum(rawInfo)
+	mi := &metaInfo{
+		infoHash: hex.EncodeToString(infoHash[:]),
+		name:     utf8String(info, "name"),
+	}
+	if created, ok := top["creation date"].(int64); ok && created > 0 {
+		mi.created = time.Unix(created, 0)
+	}
+	if pieceLength, ok := info["piece length"].(int64); ok && pieceLength > 0 {
+		mi.pieceLength = pieceLength
+	}
+	if pieces, ok := info["pieces"].(string); ok && len(pieces)%sha1.Size == 0 {
+		mi.pieces = []byte(pieces)
+	}
+	mi.addTracker(top["announce"])
+	if tiers, ok := top["announce-list"].([]any); ok {
+		for _, tier := range tiers {
+			if trackers, ok := tier.([]any); ok {
+				for _, tracker := range trackers {
+					mi.addTracker(tracker)
+				}
+			}
+		}
+	}
+	switch urls := top["url-list"].(type) {
+	case string:
+		if urls != "" {
+			mi.webseeds = append(mi.webseeds, urls)
+		}
+	case []any:
+		for _, u := range urls {
+			if s, ok := u.(string); ok && s != "" {
+				mi.webseeds = append(mi.webseeds, s)
+			}
+		}
+	}
+	if err := checkPathElement(mi.name); err != nil {
+		return nil, fmt.Errorf("bad torrent name: %w", err)
+	}
+
+	if length, ok := info["length"].(int64); ok {
+		// single file torrent
+		f, err := parseFile(info, length)
+		if err != nil {
+			return nil, err
+		}
+		f.path = []string{mi.name}
+		mi.files = append(mi.files, f)
+		mi.size = length
+		return mi, nil
+	}
+	files, ok := info["files"].([]any)
+	if !ok {
+		if _, ok := info["file tree"]; ok {
+			return nil, errors.New("BitTorrent v2 only torrents aren't supported")
+		}
+		return nil, errors.New("torrent has no length or files")
+	}
+	mi.multiFile = true
+	for _, item := range files {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			return nil, errors.New("bad entry in torrent files list")
+		}
+		length, ok := entry["length"].(int64)
+		if !ok || length < 0 {
+			return nil, errors.New("bad length in torrent files list")
+		}
+		offset := mi.size
+		mi.size += length
+		// Skip BEP 47 padding files
+		if attr, _ := entry["attr"].(string); strings.Contains(attr, "p") {
+			continue
+		}
+		f, err := parseFile(entry, length)
+		if err != nil {
+			return nil, err
+		}
+		f.offset = offset
+		elements, ok := entry["path.utf-8"].([]any)
+		if !ok {
+			elements, _ = entry["path"].([]any)
+		}
+		if len(elements) == 0 {
+			return nil, errors.New("missing path in torrent files list")
+		}
+		for _, element := range elements {
+			s, _ := element.(string)
+			if err := checkPathElement(s); err != nil {
+				return nil, fmt.Errorf("bad path in torrent files list: %w", err)
+			}
+			f.path = append(f.path, s)
+		}
+		mi.files = append(mi.files, f)
+	}
+	return mi, nil
+}
+
+// addTracker adds tracker to the trackers if it is a new URL
+func (mi *metaInfo) addTracker(tracker any) {
+	s, _ := tracker.(string)
+	if s == "" || slices.Contains(mi.trackers, s) {
+		return
+	}
+	mi.trackers = append(mi.trackers, s)
+}
+
+// numPieces returns the number of pieces in the torrent
+func (mi *metaInfo) numPieces() int {
+	return len(mi.pieces) / sha1.Size
+}
+
+// pieceSize returns the size of piece i which is smaller than the
+// piece length for the last piece
+func (mi *metaInfo) pieceSize(i int) int64 {
+	return min(mi.pieceLength, mi.size-int64(i)*mi.pieceLength)
+}
+
+// pieceHash returns the SHA1 hash of piece i
+func (mi *metaInfo) pieceHash(i int) []byte {
+	return mi.pieces[i*sha1.Size : (i+1)*sha1.Size]
+}
+
+// parseFile reads the optional attributes of a file from its
+// dictionary.
+//
+// As well as the md5sum from BEP 3 this reads the md5, sha1, crc32
+// and mtime keys which the Internet Archive puts in its torrents.
+func parseFile(entry map[string]any, length int64) (*torrentFile, error) {
+	if length < 0 {
+		return nil, errors.New("negative file length in torrent")
+	}
+	f := &torrentFile{
+		size:  length,
+		md5:   hexHash(entry, 16, "md5", "md5sum"),
+		sha1:  hexHash(entry, 20, "sha1"),
+		crc32: hexHash(entry, 4, "crc32"),
+	}
+	switch mtime := entry["mtime"].(type) {
+	case int64:
+		f.modTime = time.Unix(mtime, 0)
+	case string:
+		if secs, err := strconv.ParseInt(mtime, 10, 64); err == nil {
+			f.modTime = time.Unix(secs, 0)
+		}
+	}
+	return f, nil
+}
+
+// hexHash returns the first of keys in entry holding a hash of size
+// bytes, either as hex or as raw bytes, in lower case hex.
+func hexHash(entry map[string]any, size int, keys ...string) string {
+	for _, key := range keys {
+		s, _ := entry[key].(string)
+		switch len(s) {
+		case size:
+			return hex.EncodeToString([]byte(s))
+		case 2 * size:
+			if _, err := hex.DecodeString(s); err == nil {
+				return strings.ToLower(s)
+			}
+		}
+	}
+	return ""
+}
+
+// utf8String returns key.utf-8 from dict if set, otherwise key
+func utf8String(dict map[string]any, key string) string {
+	if s, ok := dict[key+".utf-8"].(string); ok {
+		return s
+	}
+	s, _ := dict[key].(string)
+	return s
+}
+
+// checkPathElement checks that s is safe to use as a file name
+func checkPathElement(s string) error {
+	switch {
+	case s == "":
+		return errors.New("empty name")
+	case s == "." || s == "..":
+		return fmt.Errorf("illegal name %q", s)
+	case strings.ContainsRune(s, 0):
+		return fmt.Errorf("illegal name %q", s)
+	}
+	return nil
+}
+
+// magnetLink is the parsed contents of a magnet link
+type magnetLink struct {
+	infoHash string   // hex info hash
+	name     string   // display name
+	sources  []string // URLs of the torrent file from xs and as
+	webseeds []string // webseeds from ws
+}
+
+// parseMagnet parses a magnet link
+func parseMagnet(link string) (*magnetLink, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "magnet" {
+		return nil, fmt.Errorf("%q is not a magnet link", link)
+	}
+	values := u.Query()
+	m := &magnetLink{
+		name:     values.Get("dn"),
+		webseeds: values["ws"],
+	}
+	m.sources = append(m.sources, values["xs"]...)
+	m.sources = append(m.sources, values["as"]...)
+	for _, xt := range values["xt"] {
+		hash, ok := strings.CutPrefix(xt, "urn:btih:")
+		if !ok {
+			continue
+		}
+		switch len(hash) {
+		case 40:
+			if _, err := hex.DecodeString(hash); err != nil {
+				return nil, fmt.Errorf("bad info hash in magnet link: %w", err)
+			}
+			m.infoHash = strings.ToLower(hash)
+		case 32:
+			b, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+			if err != nil {
+				return nil, fmt.Errorf("bad info hash in magnet link: %w", err)
+			}
+			m.infoHash = hex.EncodeToString(b)
+		default:
+			return nil, fmt.Errorf("bad info hash length in magnet link: %q", hash)
+		}
+	}
+	if m.infoHash == "" {
+		return nil, errors.New("magnet link has no BitTorrent v1 info hash")
+	}
+	return m, nil
+}
@@ -0,0 +1,253 @@
+package torrent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Message IDs of the peer wire protocol from BEP 3
+const (
+	msgChoke         = 0
+	msgUnchoke       = 1
+	msgInterested    = 2
+	msgNotInterested = 3
+	msgHave          = 4
+	msgBitfield      = 5
+	msgRequest       = 6
+	msgPiece         = 7
+	msgCancel        = 8
+	msgKeepAlive     = -1 // not a real ID, used for zero length messages
+)
+
+const (
+	protocolName = "BitTorrent protocol"
+	blockSize    = 16 * 1024        // size of the blocks pieces are requested in
+	maxPipeline  = 16               // number of blocks requested from a peer at once
+	peerTimeout  = 30 * time.Second // timeout for connecting to and reading from peers
+)
+
+var (
+	errMissingPiece = errors.New("peer doesn't have the piece")
+	errChoked       = errors.New("peer choked us")
+	errBadPiece     = errors.New("piece from peer has the wrong hash")
+)
+
+// peer is a connection to a peer of a torrent
+type peer struct {
+	addr       string        // address of the peer
+	conn       net.Conn      // the connection
+	r          *bufio.Reader // buffered reader of conn
+	info       *metaInfo     // the torrent being downloaded
+	choked     bool          // set if the peer is choking us
+	have       []byte        // bitfield of the pieces the peer has
+	maxMessage int           // biggest message we will read
+}
+
+// dialPeer connects to the peer at addr and does the handshake for
+// the torrent, saying we are interested in its pieces.
+func dialPeer(ctx context.Context, addr string, info *metaInfo, infoHash []byte, peerID []byte) (p *peer, err error) {
+	dialer := net.Dialer{Timeout: peerTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	p = &peer{
+		addr:       addr,
+		conn:       conn,
+		r:          bufio.NewReaderSize(conn, 2*blockSize),
+		info:       info,
+		choked:     true,
+		have:       make([]byte, (info.numPieces()+7)/8),
+		maxMessage: max(blockSize+9, (info.numPieces()+7)/8+1),
+	}
+	defer func() {
+		if err != nil {
+			_ = conn.Close()
+		}
+	}()
+
+	// Send and check the handshake
+	_ = conn.SetDeadline(time.Now().Add(peerTimeout))
+	var handshake bytes.Buffer
+	handshake.WriteByte(byte(len(protocolName)))
+	handshake.WriteString(protocolName)
+	handshake.Write(make([]byte, 8)) // no extensions
+	handshake.Write(infoHash)
+	handshake.Write(peerID)
+	if _, err = conn.Write(handshake.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to send handshake: %w", err)
+	}
+	reply := make([]byte, handshake.Len())
+	if _, err = io.ReadFull(p.r, reply); err != nil {
+		return nil, fmt.Errorf("failed to read handshake: %w", err)
+	}
+	if reply[0] != byte(len(protocolName)) || string(reply[1:1+len(protocolName)]) != protocolName {
+		return nil, errors.New("peer doesn't speak the BitTorrent protocol")
+	}
+	if !bytes.Equal(reply[28:48], infoHash) {
+		return nil, errors.New("peer sent the wrong info hash")
+	}
+	if err = p.writeMessage(msgInterested, nil); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// close the connection to the peer
+func (p *peer) close() error {
+	return p.conn.Close()
+}
+
+// writeMessage sends the message id with payload to the peer
+func (p *peer) writeMessage(id byte, payload []byte) error {
+	msg := make([]byte, 5+len(payload))
+	binary.BigEndian.PutUint32(msg, uint32(1+len(payload)))
+	msg[4] = id
+	copy(msg[5:], payload)
+	_ = p.conn.SetWriteDeadline(time.Now().Add(peerTimeout))
+	_, err := p.conn.Write(msg)
+	return err
+}
+
+// readMessage reads the next message from the peer
+//
+// Keep alive messages are returned with the ID msgKeepAlive.
+func (p *peer) readMessage() (id int, payload []byte, err error) {
+	_ = p.conn.SetReadDeadline(time.Now().Add(peerTimeout))
+	var length [4]byte
+	if _, err = io.ReadFull(p.r, length[:]); err != nil {
+		return 0, nil, err
+	}
+	n := int(binary.BigEndian.Uint32(length[:]))
+	if n == 0 {
+		return msgKeepAlive, nil, nil
+	}
+	if n > p.maxMessage {
+		return 0, nil, fmt.Errorf("message from peer too big: %d bytes", n)
+	}
+	msg := make([]byte, n)
+	if _, err = io.ReadFull(p.r, msg); err != nil {
+		return 0, nil, err
+	}
+	return int(msg[0]), msg[1:], nil
+}
+
+// handle updates the state of the peer from a message which isn't a
+// piece
+func (p *peer) handle(id int, payload []byte) error {
+	switch id {
+	case msgChoke:
+		p.choked = true
+	case msgUnchoke:
+		p.choked = false
+	case msgHave:
+		if len(payload) != 4 {
+			return errors.New("bad have message from peer")
+		}
+		i := int(binary.BigEndian.Uint32(payload))
+		if i < p.info.numPieces() {
+			p.have[i/8] |= 0x80 >> (i % 8)
+		}
+	case msgBitfield:
+		if len(payload) != len(p.have) {
+			return errors.New("bad bitfield message from peer")
+		}
+		copy(p.have, payload)
+	}
+	return nil
+}
+
+// hasPiece returns true if the peer has said it has piece i
+func (p *peer) hasPiece(i int) bool {
+	return p.have[i/8]&(0x80>>(i%8)) != 0
+}
+
+// waitUnchoke reads messages from the peer until it unchokes us
+func (p *peer) waitUnchoke(ctx context.Context) error {
+	for p.choked {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		id, payload, err := p.readMessage()
+		if err != nil {
+			return err
+		}
+		if err = p.handle(id, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// downloadPiece downloads piece i from the peer checking its hash
+func (p *peer) downloadPiece(ctx context.Context, i int) ([]byte, error) {
+	if err := p.waitUnchoke(ctx); err != nil {
+		return nil, err
+	}
+	if !p.hasPiece(i) {
+		return nil, errMissingPiece
+	}
+	size := p.info.pieceSize(i)
+	piece := make([]byte, size)
+	blocks := int((size + blockSize - 1) / blockSize)
+	got := make([]bool, blocks)
+	requested, received := 0, 0
+	var request [12]byte
+	binary.BigEndian.PutUint32(request[0:], uint32(i))
+	for received < blocks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for requested < blocks && requested-received < maxPipeline {
+			begin := int64(requested) * blockSize
+			binary.BigEndian.PutUint32(request[4:], uint32(begin))
+			binary.BigEndian.PutUint32(request[8:], uint32(min(blockSize, size-begin)))
+			if err := p.writeMessage(msgRequest, request[:]); err != nil {
+				return nil, err
+			}
+			requested++
+		}
+		id, payload, err := p.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if id != msgPiece {
+			if err = p.handle(id, payload); err != nil {
+				return nil, err
+			}
+			if p.choked {
+				// The outstanding requests are dropped
+				return nil, errChoked
+			}
+			continue
+		}
+		if len(payload) < 8 || int(binary.BigEndian.Uint32(payload)) != i {
+			// Ignore blocks of other pieces we may have cancelled
+			continue
+		}
+		begin := int64(binary.BigEndian.Uint32(payload[4:]))
+		block := payload[8:]
+		n := int(begin / blockSize)
+		if begin%blockSize != 0 || n >= blocks || int64(len(block)) != min(blockSize, size-begin) {
+			return nil, errors.New("bad piece message from peer")
+		}
+		if !got[n] {
+			copy(piece[begin:], block)
+			got[n] = true
+			received++
+		}
+	}
+	hash := sha1.Sum(piece)
+	if !bytes.Equal(hash[:], p.info.pieceHash(i)) {
+		return nil, errBadPiece
+	}
+	return piece, nil
+}
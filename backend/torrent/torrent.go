@@ -0,0 +1,670 @@
+// Package torrent provides a read only interface to the files in a
+// torrent, downloaded from its webseeds
+package torrent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/env"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep       = 10 * time.Millisecond
+	maxSleep       = 2 * time.Second
+	decayConstant  = 2                // bigger for slower decay, exponential
+	maxTorrentSize = 64 * 1024 * 1024 // biggest torrent file we will read
+)
+
+var errorReadOnly = errors.New("torrent remotes are read only")
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "torrent",
+		Description: "BitTorrent webseeds (read only)",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		Options: []fs.Option{{
+			Name: "torrent",
+			Help: `Torrent to read.
+
+This can be the path of a torrent file, the URL of one, or a magnet
+link with an xs or as parameter giving the URL of the torrent file.`,
+			Required: true,
+		}, {
+			Name: "webseeds",
+			Help: `Extra webseed URLs to download from.
+
+These are tried after the webseeds in the torrent file and in the ws
+parameters of a magnet link. They are in the BEP 19 format, so the
+name of the torrent and the path of the file are added to URLs which
+end in "/".`,
+			Default:  fs.CommaSepList{},
+			Advanced: true,
+		}, {
+			Name: "peers",
+			Help: `Number of BitTorrent peers to download from at once.
+
+If this is set, files are downloaded from the peers found with the
+HTTP trackers of the torrent, checking each piece against its hash,
+and the webseeds are only used if that fails. This spreads the load
+when webseeds are slow or throttled, for example for large public
+Internet Archive items.
+
+Set to 0 to only download from the webseeds.`,
+			Default:  0,
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			Default: (encoder.Base |
+				encoder.EncodeInvalidUtf8),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Torrent  string               `config:"torrent"`
+	Webseeds fs.CommaSepList      `config:"webseeds"`
+	Peers    int                  `config:"peers"`
+	Enc      encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents the files in a torrent
+type Fs struct {
+	name        string                     // name of this remote
+	root        string                     // the path we are working on
+	opt         Options                    // parsed options
+	features    *fs.Features               // optional features
+	srv         *rest.Client               // the connection to the webseeds
+	pacer       *fs.Pacer                  // pacer for API calls
+	info        *metaInfo                  // the parsed torrent
+	webseeds    []string                   // webseeds to try in order
+	files       map[string]*torrentFile    // files by their path in the torrent
+	dirs        map[string]map[string]bool // names of the entries in each directory, true if a directory
+	hashes      hash.Set                   // hashes found in the torrent
+	hasModTimes bool                       // set if the torrent has modification times
+	peerID      [20]byte                   // our ID when talking to trackers and peers
+	peersMu     sync.Mutex                 // protects the fields below
+	peers       []string                   // addresses of the peers from the trackers
+	peersFound  time.Time                  // when peers was found
+}
+
+// Object describes a file in a torrent
+type Object struct {
+	fs     *Fs          // what this object is part of
+	remote string       // the remote path
+	file   *torrentFile // the file in the torrent
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	if f.root == "" {
+		return fmt.Sprintf("torrent %s", f.info.name)
+	}
+	return fmt.Sprintf("torrent %s path %s", f.info.name, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the modification times in the torrent
+func (f *Fs) Precision() time.Duration {
+	if f.hasModTimes {
+		return time.Second
+	}
+	return fs.ModTimeNotSupported
+}
+
+// Hashes returns the hashes found in the torrent
+func (f *Fs) Hashes() hash.Set {
+	return f.hashes
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// NewFs constructs an Fs from the path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.Torrent == "" {
+		return nil, errors.New("torrent not set")
+	}
+	f := &Fs{
+		name:  name,
+		root:  strings.Trim(root, "/"),
+		opt:   *opt,
+		srv:   rest.NewClient(fshttp.NewClient(ctx)),
+		pacer: fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+	}
+	copy(f.peerID[:], "-RC0001-"+random.String(12))
+	f.features = (&fs.Features{
+		ReadMimeType: false,
+	}).Fill(ctx, f)
+
+	var magnetWebseeds []string
+	if strings.HasPrefix(opt.Torrent, "magnet:") {
+		f.info, magnetWebseeds, err = f.loadMagnet(ctx, opt.Torrent)
+	} else {
+		f.info, err = f.loadTorrent(ctx, opt.Torrent)
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.addWebseeds(magnetWebseeds)
+	f.addWebseeds(f.info.webseeds)
+	f.addWebseeds(opt.Webseeds)
+	f.makeTree()
+
+	// Check to see if the root is a file
+	if _, ok := f.files[f.root]; ok {
+		newRoot := path.Dir(f.root)
+		if newRoot == "." {
+			newRoot = ""
+		}
+		f.root = newRoot
+		// return an error with an fs which points to the parent
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// fetch reads the torrent file at rawURL
+func (f *Fs) fetch(ctx context.Context, rawURL string) (data []byte, err error) {
+	var resp *http.Response
+	opts := rest.Opts{
+		Method:  "GET",
+		RootURL: rawURL,
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.Call(ctx, &opts)
+		if err != nil {
+			return shouldRetry(ctx, resp, err)
+		}
+		defer fs.CheckClose(resp.Body, &err)
+		data, err = io.ReadAll(io.LimitReader(resp.Body, maxTorrentSize+1))
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read torrent from %q: %w", rawURL, err)
+	}
+	if len(data) > maxTorrentSize {
+		return nil, fmt.Errorf("torrent from %q is too big", rawURL)
+	}
+	return data, nil
+}
+
+// loadTorrent reads and parses the torrent file at source which may
+// be a URL or a file name
+func (f *Fs) loadTorrent(ctx context.Context, source string) (info *metaInfo, err error) {
+	var data []byte
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		data, err = f.fetch(ctx, source)
+	} else {
+		data, err = os.ReadFile(env.ShellExpand(source))
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err = parseMetaInfo(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse torrent %q: %w", source, err)
+	}
+	return info, nil
+}
+
+// loadMagnet reads the torrent file for a magnet link from one of
+// the sources in it, checking its info hash.
+//
+// It returns the parsed torrent and the webseeds in the magnet link.
+func (f *Fs) loadMagnet(ctx context.Context, link string) (info *metaInfo, webseeds []string, err error) {
+	magnet, err := parseMagnet(link)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(magnet.sources) == 0 {
+		return nil, nil, errors.New("magnet link has no xs or as parameter with the URL of the torrent file - rclone can't fetch torrents from peers")
+	}
+	for _, source := range magnet.sources {
+		info, err = f.loadTorrent(ctx, source)
+		if err == nil && info.infoHash != magnet.infoHash {
+			err = fmt.Errorf("torrent from %q has info hash %s but magnet link wants %s", source, info.infoHash, magnet.infoHash)
+		}
+		if err == nil {
+			return info, magnet.webseeds, nil
+		}
+		fs.Debugf(f, "Failed to load torrent for magnet link: %v", err)
+	}
+	return nil, nil, err
+}
+
+// addWebseeds adds webseeds to the list to try, ignoring duplicates
+func (f *Fs) addWebseeds(webseeds []string) {
+outer:
+	for _, webseed := range webseeds {
+		for _, existing := range f.webseeds {
+			if webseed == existing {
+				continue outer
+			}
+		}
+		f.webseeds = append(f.webseeds, webseed)
+	}
+}
+
+// makeTree makes the directory tree from the files in the torrent
+//
+// The files of a multi file torrent are at the root, as they would
+// be inside the directory a client downloads them into.
+func (f *Fs) makeTree() {
+	f.files = make(map[string]*torrentFile, len(f.info.files))
+	f.dirs = map[string]map[string]bool{"": {}}
+	hashes := hash.NewHashSet()
+	for _, file := range f.info.files {
+		elements := make([]string, len(file.path))
+		for i, element := range file.path {
+			elements[i] = f.opt.Enc.ToStandardName(element)
+		}
+		p := path.Join(elements...)
+		if _, ok := f.files[p]; ok {
+			fs.Logf(f, "Ignoring duplicate file %q in torrent", p)
+			continue
+		}
+		if _, ok := f.dirs[p]; ok {
+			fs.Logf(f, "Ignoring file %q in torrent with the same name as a directory", p)
+			continue
+		}
+		// Make the parent directories
+		dir := ""
+		ok := true
+		for _, element := range elements[:len(elements)-1] {
+			child := path.Join(dir, element)
+			if _, isFile := f.files[child]; isFile {
+				fs.Logf(f, "Ignoring file %q in torrent inside a file", p)
+				ok = false
+				break
+			}
+			f.dirs[dir][element] = true
+			if f.dirs[child] == nil {
+				f.dirs[child] = map[string]bool{}
+			}
+			dir = child
+		}
+		if !ok {
+			continue
+		}
+		f.dirs[dir][elements[len(elements)-1]] = false
+		f.files[p] = file
+		if file.md5 != "" {
+			hashes.Add(hash.MD5)
+		}
+		if file.sha1 != "" {
+			hashes.Add(hash.SHA1)
+		}
+		if file.crc32 != "" {
+			hashes.Add(hash.CRC32)
+		}
+		if !file.modTime.IsZero() {
+			f.hasModTimes = true
+		}
+	}
+	f.hashes = hashes
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	fullDir := path.Join(f.root, dir)
+	children, ok := f.dirs[fullDir]
+	if !ok {
+		return nil, fs.ErrorDirNotFound
+	}
+	for name, isDir := range children {
+		remote := path.Join(dir, name)
+		if isDir {
+			entries = append(entries, fs.NewDir(remote, f.info.created))
+		} else {
+			entries = append(entries, &Object{
+				fs:     f,
+				remote: remote,
+				file:   f.files[path.Join(fullDir, name)],
+			})
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	p := path.Join(f.root, remote)
+	file, ok := f.files[p]
+	if !ok {
+		if _, isDir := f.dirs[p]; isDir {
+			return nil, fs.ErrorIsDir
+		}
+		return nil, fs.ErrorObjectNotFound
+	}
+	return &Object{
+		fs:     f,
+		remote: remote,
+		file:   file,
+	}, nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return nil, errorReadOnly
+}
+
+// Mkdir makes the root directory of the Fs object
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return errorReadOnly
+}
+
+// Rmdir removes the root directory of the Fs object
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return errorReadOnly
+}
+
+// webseedURL returns the URL of file on webseed as described in
+// BEP 19
+func (f *Fs) webseedURL(webseed string, file *torrentFile) string {
+	if !f.info.multiFile {
+		if strings.HasSuffix(webseed, "/") {
+			return webseed + url.PathEscape(f.info.name)
+		}
+		return webseed
+	}
+	if !strings.HasSuffix(webseed, "/") {
+		webseed += "/"
+	}
+	var u strings.Builder
+	u.WriteString(webseed)
+	u.WriteString(url.PathEscape(f.info.name))
+	for _, element := range file.path {
+		u.WriteString("/")
+		u.WriteString(url.PathEscape(element))
+	}
+	return u.String()
+}
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "info",
+	Short: "Show information about the torrent.",
+	Long: `This shows the name and info hash of the torrent, the number of
+files in it and their total size and the webseeds and trackers
+rclone uses.
+
+    rclone backend info remote:
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "info":
+		var size int64
+		for _, file := range f.info.files {
+			size += file.size
+		}
+		return map[string]any{
+			"name":      f.info.name,
+			"info_hash": f.info.infoHash,
+			"files":     len(f.info.files),
+			"size":      size,
+			"webseeds":  f.webseeds,
+			"trackers":  f.info.trackers,
+		}, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the requested hash of the file if it is in the torrent
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	switch t {
+	case hash.MD5:
+		return o.file.md5, nil
+	case hash.SHA1:
+		return o.file.sha1, nil
+	case hash.CRC32:
+		return o.file.crc32, nil
+	}
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of the file
+func (o *Object) Size() int64 {
+	return o.file.size
+}
+
+// ModTime returns the modification time of the file, or the
+// creation time of the torrent if it isn't known
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if o.file.modTime.IsZero() {
+		return o.fs.info.created
+	}
+	return o.file.modTime
+}
+
+// SetModTime sets the modification time of the local fs object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return errorReadOnly
+}
+
+// Storable returns a boolean as to whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+//
+// If the peers option is set it is read from peers, otherwise or if
+// that fails, it is read from the webseeds.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	if o.file.size == 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	if o.fs.opt.Peers > 0 {
+		in, err = o.openPeers(ctx, options...)
+		if err == nil {
+			return in, nil
+		}
+		fs.Debugf(o, "Reading from webseeds as can't read from peers: %v", err)
+	}
+	return o.openWebseeds(ctx, options...)
+}
+
+// openPeers opens the object for read from the peers of the torrent
+func (o *Object) openPeers(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	info := o.fs.info
+	if info.pieceLength == 0 || int64(info.numPieces()) != (info.size+info.pieceLength-1)/info.pieceLength {
+		return nil, errors.New("torrent has missing or bad piece hashes")
+	}
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.file.size)
+		}
+	}
+	end := o.file.size
+	if limit >= 0 {
+		end = min(offset+limit, end)
+	}
+	if offset >= end {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	addrs := o.fs.findPeers(ctx)
+	if len(addrs) == 0 {
+		return nil, errors.New("no peers found")
+	}
+	var fallback func(pos int64) (io.ReadCloser, error)
+	if len(o.fs.webseeds) > 0 {
+		fallback = func(pos int64) (io.ReadCloser, error) {
+			return o.openWebseeds(ctx, &fs.RangeOption{Start: pos - o.file.offset, End: end - 1})
+		}
+	}
+	return newPeerReader(ctx, o.fs, addrs, o.file.offset+offset, o.file.offset+end, fallback), nil
+}
+
+// openWebseeds opens the object for read from the webseeds
+//
+// Each attempt uses the next webseed so a webseed which is missing
+// the file or is down is skipped.
+func (o *Object) openWebseeds(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	webseeds := o.fs.webseeds
+	if len(webseeds) == 0 {
+		return nil, errors.New("torrent has no webseeds - add some with the webseeds option")
+	}
+	fs.FixRangeOption(options, o.file.size)
+	isRange := false
+	for _, option := range options {
+		switch option.(type) {
+		case *fs.RangeOption, *fs.SeekOption:
+			isRange = true
+		}
+	}
+	var resp *http.Response
+	try := 0
+	err = o.fs.pacer.Call(func() (bool, error) {
+		webseed := webseeds[try%len(webseeds)]
+		try++
+		opts := rest.Opts{
+			Method:  "GET",
+			RootURL: o.fs.webseedURL(webseed, o.file),
+			Options: options,
+		}
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		if err == nil && isRange && resp.StatusCode != http.StatusPartialContent {
+			_ = resp.Body.Close()
+			err = fmt.Errorf("webseed %q doesn't support range requests", webseed)
+		}
+		retry, err := shouldRetry(ctx, resp, err)
+		if err != nil && !retry && try < len(webseeds) && ctx.Err() == nil {
+			fs.Debugf(o, "Trying next webseed after error from %q: %v", webseed, err)
+			return true, err
+		}
+		return retry, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from webseeds: %w", err)
+	}
+	return resp.Body, nil
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return errorReadOnly
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	return errorReadOnly
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs        = &Fs{}
+	_ fs.Commander = &Fs{}
+	_ fs.Object    = &Object{}
+)
@@ -0,0 +1,583 @@
+package torrent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bencode encodes v for making test torrents
+func bencode(v any) []byte {
+	var buf bytes.Buffer
+	switch v := v.(type) {
+	case int:
+		fmt.Fprintf(&buf, "i%de", v)
+	case int64:
+		fmt.Fprintf(&buf, "i%de", v)
+	case string:
+		fmt.Fprintf(&buf, "%d:%s", len(v), v)
+	case []any:
+		buf.WriteByte('l')
+		for _, item := range v {
+			buf.Write(bencode(item))
+		}
+		buf.WriteByte('e')
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('d')
+		for _, key := range keys {
+			buf.Write(bencode(key))
+			buf.Write(bencode(v[key]))
+		}
+		buf.WriteByte('e')
+	default:
+		panic(fmt.Sprintf("can't bencode %T", v))
+	}
+	return buf.Bytes()
+}
+
+var (
+	testFiles = map[string]string{
+		"one.txt":         "hello world",
+		"dir/two.txt":     "potato",
+		"dir/sub/3.txt":   "sausage and mash",
+		"dir/empty.txt":   "",
+		"with space.txt":  "spaced out",
+		"dir/sub/4%.json": `{"a":1}`,
+	}
+	testMtime   = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	testCreated = time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+)
+
+// makeTorrent makes a multi file torrent of testFiles returning it
+// and its info hash
+func makeTorrent(webseeds []any) ([]byte, string) {
+	names := make([]string, 0, len(testFiles))
+	for name := range testFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var files []any
+	for i, name := range names {
+		contents := testFiles[name]
+		var elements []any
+		for _, element := range strings.Split(name, "/") {
+			elements = append(elements, element)
+		}
+		entry := map[string]any{
+			"length": len(contents),
+			"path":   elements,
+			"mtime":  fmt.Sprint(testMtime.Unix()),
+			"sha1":   fmt.Sprintf("%x", sha1.Sum([]byte(contents))),
+		}
+		files = append(files, entry)
+		if i == 0 {
+			// add a padding file which should be ignored
+			files = append(files, map[string]any{
+				"length": 100,
+				"path":   []any{".pad", "100"},
+				"attr":   "p",
+			})
+		}
+	}
+	info := map[string]any{
+		"name":         "test torrent",
+		"piece length": 16384,
+		"pieces":       "",
+		"files":        files,
+	}
+	infoHash := sha1.Sum(bencode(info))
+	torrent := map[string]any{
+		"info":          info,
+		"creation date": testCreated.Unix(),
+	}
+	if webseeds != nil {
+		torrent["url-list"] = webseeds
+	}
+	return bencode(torrent), hex.EncodeToString(infoHash[:])
+}
+
+// webseedHandler serves testFiles under "/test torrent/"
+func webseedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := strings.CutPrefix(r.URL.Path, "/test torrent/")
+		contents, found := testFiles[name]
+		if !ok || !found {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, name, testMtime, strings.NewReader(contents))
+	})
+}
+
+func TestBdecode(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    any
+		wantErr bool
+	}{
+		{in: "i42e", want: int64(42)},
+		{in: "i-3e", want: int64(-3)},
+		{in: "4:spam", want: "spam"},
+		{in: "0:", want: ""},
+		{in: "l4:spami1ee", want: []any{"spam", int64(1)}},
+		{in: "d3:cow3:moo4:spaml1:a1:bee", want: map[string]any{"cow": "moo", "spam": []any{"a", "b"}}},
+		{in: "", wantErr: true},
+		{in: "i42", wantErr: true},
+		{in: "ixe", wantErr: true},
+		{in: "5:spam", wantErr: true},
+		{in: "l4:spam", wantErr: true},
+		{in: "d3:cowe", wantErr: true},
+		{in: "4:spamx", wantErr: true},
+		{in: strings.Repeat("l", 100) + strings.Repeat("e", 100), wantErr: true},
+	} {
+		got, err := bdecode([]byte(test.in))
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestParseMetaInfo(t *testing.T) {
+	data, infoHash := makeTorrent([]any{"http://example.com/"})
+	mi, err := parseMetaInfo(data)
+	require.NoError(t, err)
+	assert.Equal(t, infoHash, mi.infoHash)
+	assert.Equal(t, "test torrent", mi.name)
+	assert.True(t, mi.multiFile)
+	assert.Equal(t, []string{"http://example.com/"}, mi.webseeds)
+	assert.True(t, testCreated.Equal(mi.created))
+	require.Equal(t, len(testFiles), len(mi.files))
+	for _, file := range mi.files {
+		name := strings.Join(file.path, "/")
+		contents, ok := testFiles[name]
+		require.True(t, ok, name)
+		assert.Equal(t, int64(len(contents)), file.size, name)
+		assert.Equal(t, fmt.Sprintf("%x", sha1.Sum([]byte(contents))), file.sha1, name)
+		assert.True(t, testMtime.Equal(file.modTime), name)
+	}
+
+	// single file torrent with raw md5sum
+	single := bencode(map[string]any{
+		"url-list": "http://example.com/file.bin",
+		"info": map[string]any{
+			"name":   "file.bin",
+			"length": 5,
+			"md5sum": "0123456789abcdef",
+		},
+	})
+	mi, err = parseMetaInfo(single)
+	require.NoError(t, err)
+	assert.False(t, mi.multiFile)
+	assert.Equal(t, []string{"http://example.com/file.bin"}, mi.webseeds)
+	require.Equal(t, 1, len(mi.files))
+	assert.Equal(t, []string{"file.bin"}, mi.files[0].path)
+	assert.Equal(t, "30313233343536373839616263646566", mi.files[0].md5)
+
+	// bad torrents
+	for _, bad := range []map[string]any{
+		{"announce": "x"},
+		{"info": map[string]any{"name": "x"}},
+		{"info": map[string]any{"name": "x", "file tree": map[string]any{}}},
+		{"info": map[string]any{"name": "..", "length": 1}},
+		{"info": map[string]any{"name": "x", "files": []any{map[string]any{"length": 1, "path": []any{"a", ".."}}}}},
+		{"info": map[string]any{"name": "x", "files": []any{map[string]any{"length": -1, "path": []any{"a"}}}}},
+	} {
+		_, err = parseMetaInfo(bencode(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseMagnet(t *testing.T) {
+	m, err := parseMagnet("magnet:?xt=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&dn=test&xs=http%3A%2F%2Fexample.com%2Fa.torrent&ws=http%3A%2F%2Fexample.com%2F")
+	require.NoError(t, err)
+	assert.Equal(t, "c12fe1c06bba254a9dc9f519b335aa7c1367a88a", m.infoHash)
+	assert.Equal(t, "test", m.name)
+	assert.Equal(t, []string{"http://example.com/a.torrent"}, m.sources)
+	assert.Equal(t, []string{"http://example.com/"}, m.webseeds)
+
+	m, err = parseMagnet("magnet:?xt=urn:btih:YEX6DQDLXISUVHOJ6UM3GNNKPQJWPKEK")
+	require.NoError(t, err)
+	assert.Equal(t, "c12fe1c06bba254a9dc9f519b335aa7c1367a88a", m.infoHash)
+
+	for _, bad := range []string{
+		"http://example.com/",
+		"magnet:?dn=test",
+		"magnet:?xt=urn:btih:1234",
+		"magnet:?xt=urn:btih:Z12FE1C06BBA254A9DC9F519B335AA7C1367A88A",
+	} {
+		_, err = parseMagnet(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestWebseedURL(t *testing.T) {
+	f := &Fs{info: &metaInfo{name: "a b", multiFile: true}}
+	file := &torrentFile{path: []string{"dir", "c?.txt"}}
+	assert.Equal(t, "http://example.com/a%20b/dir/c%3F.txt", f.webseedURL("http://example.com", file))
+	assert.Equal(t, "http://example.com/a%20b/dir/c%3F.txt", f.webseedURL("http://example.com/", file))
+
+	f.info.multiFile = false
+	file = &torrentFile{path: []string{"a b"}}
+	assert.Equal(t, "http://example.com/a%20b", f.webseedURL("http://example.com/", file))
+	assert.Equal(t, "http://example.com/file", f.webseedURL("http://example.com/file", file))
+}
+
+// prepare makes a torrent with a webseed serving it and returns the
+// config for it
+func prepare(t *testing.T) (configmap.Simple, *httptest.Server) {
+	ts := httptest.NewServer(webseedHandler())
+	t.Cleanup(ts.Close)
+	data, _ := makeTorrent([]any{ts.URL + "/"})
+	torrentPath := filepath.Join(t.TempDir(), "test.torrent")
+	require.NoError(t, os.WriteFile(torrentPath, data, 0666))
+	return configmap.Simple{
+		"type":    "torrent",
+		"torrent": torrentPath,
+	}, ts
+}
+
+func TestListAndRead(t *testing.T) {
+	ctx := context.Background()
+	m, _ := prepare(t)
+	f, err := NewFs(ctx, "TestTorrent", "", m)
+	require.NoError(t, err)
+
+	assert.Equal(t, hash.NewHashSet(hash.SHA1), f.Hashes())
+	assert.Equal(t, time.Second, f.Precision())
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	sort.Sort(entries)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+	}
+	assert.Equal(t, []string{"dir", "one.txt", "with space.txt"}, names)
+
+	entries, err = f.List(ctx, "dir/sub")
+	require.NoError(t, err)
+	sort.Sort(entries)
+	require.Equal(t, 2, len(entries))
+	assert.Equal(t, "dir/sub/3.txt", entries[0].Remote())
+	assert.Equal(t, "dir/sub/4%.json", entries[1].Remote())
+
+	_, err = f.List(ctx, "notfound")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	for name, contents := range testFiles {
+		o, err := f.NewObject(ctx, name)
+		require.NoError(t, err, name)
+		assert.Equal(t, int64(len(contents)), o.Size(), name)
+		assert.True(t, testMtime.Equal(o.ModTime(ctx)), name)
+		sha, err := o.Hash(ctx, hash.SHA1)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%x", sha1.Sum([]byte(contents))), sha, name)
+		in, err := o.Open(ctx)
+		require.NoError(t, err, name)
+		got, err := io.ReadAll(in)
+		require.NoError(t, err, name)
+		require.NoError(t, in.Close())
+		assert.Equal(t, contents, string(got), name)
+	}
+
+	o, err := f.NewObject(ctx, "dir/sub/3.txt")
+	require.NoError(t, err)
+	in, err := o.Open(ctx, &fs.RangeOption{Start: 8, End: 10})
+	require.NoError(t, err)
+	got, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "and", string(got))
+
+	_, err = f.NewObject(ctx, "dir")
+	assert.Equal(t, fs.ErrorIsDir, err)
+	_, err = f.NewObject(ctx, "notfound")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	assert.Equal(t, errorReadOnly, f.Mkdir(ctx, "new"))
+	assert.Equal(t, errorReadOnly, o.Remove(ctx))
+}
+
+func TestRootIsFile(t *testing.T) {
+	ctx := context.Background()
+	m, _ := prepare(t)
+	f, err := NewFs(ctx, "TestTorrent", "dir/two.txt", m)
+	assert.Equal(t, fs.ErrorIsFile, err)
+	assert.Equal(t, "dir", f.Root())
+	o, err := f.NewObject(ctx, "two.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "two.txt", o.Remote())
+}
+
+func TestWebseedFailover(t *testing.T) {
+	ctx := context.Background()
+	m, ts := prepare(t)
+	var badCalls atomic.Int32
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		badCalls.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer bad.Close()
+
+	// Use a magnet link fetching the torrent from the good server
+	// with the bad webseed first
+	data, infoHash := makeTorrent([]any{ts.URL + "/"})
+	torrentServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer torrentServer.Close()
+	m["torrent"] = "magnet:?xt=urn:btih:" + infoHash + "&xs=" + torrentServer.URL + "/test.torrent&ws=" + bad.URL + "/"
+	f, err := NewFs(ctx, "TestTorrent", "", m)
+	require.NoError(t, err)
+
+	o, err := f.NewObject(ctx, "one.txt")
+	require.NoError(t, err)
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	got, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "hello world", string(got))
+	assert.Equal(t, int32(1), badCalls.Load())
+
+	// A magnet link with the wrong info hash should fail
+	m["torrent"] = "magnet:?xt=urn:btih:" + strings.Repeat("0", 40) + "&xs=" + torrentServer.URL + "/test.torrent"
+	_, err = NewFs(ctx, "TestTorrent", "", m)
+	assert.ErrorContains(t, err, "info hash")
+
+	// A magnet link with no source should fail
+	m["torrent"] = "magnet:?xt=urn:btih:" + infoHash
+	_, err = NewFs(ctx, "TestTorrent", "", m)
+	assert.ErrorContains(t, err, "peers")
+}
+
+func TestParseAnnounce(t *testing.T) {
+	peers, err := parseAnnounce(bencode(map[string]any{
+		"interval": 1800,
+		"peers":    "\x7f\x00\x00\x01\x1a\xe1\x0a\x00\x00\x02\x00\x50",
+		"peers6":   "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x1a\xe1",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"127.0.0.1:6881", "10.0.0.2:80", "[::1]:6881"}, peers)
+
+	peers, err = parseAnnounce(bencode(map[string]any{
+		"peers": []any{
+			map[string]any{"ip": "example.com", "port": 6881},
+			map[string]any{"ip": "no port"},
+		},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com:6881"}, peers)
+
+	_, err = parseAnnounce(bencode(map[string]any{"failure reason": "unregistered torrent"}))
+	assert.ErrorContains(t, err, "unregistered torrent")
+
+	_, err = parseAnnounce([]byte("not bencoded"))
+	assert.Error(t, err)
+}
+
+// fakePeer runs a BitTorrent peer on localhost serving the pieces of
+// data, corrupting them if corrupt is set, and returns its address
+func fakePeer(t *testing.T, infoHash string, data []byte, pieceLength int, corrupt bool) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	hashBytes, err := hex.DecodeString(infoHash)
+	require.NoError(t, err)
+	numPieces := (len(data) + pieceLength - 1) / pieceLength
+	serve := func(conn net.Conn) {
+		defer func() { _ = conn.Close() }()
+		handshake := make([]byte, 68)
+		if _, err := io.ReadFull(conn, handshake); err != nil || !bytes.Equal(handshake[28:48], hashBytes) {
+			return
+		}
+		copy(handshake[48:], "-FAKE00-000000000000")
+		if _, err := conn.Write(handshake); err != nil {
+			return
+		}
+		send := func(id byte, payload []byte) error {
+			msg := binary.BigEndian.AppendUint32(nil, uint32(1+len(payload)))
+			msg = append(msg, id)
+			_, err := conn.Write(append(msg, payload...))
+			return err
+		}
+		bitfield := make([]byte, (numPieces+7)/8)
+		for i := range numPieces {
+			bitfield[i/8] |= 0x80 >> (i % 8)
+		}
+		if send(msgBitfield, bitfield) != nil || send(msgUnchoke, nil) != nil {
+			return
+		}
+		for {
+			var length [4]byte
+			if _, err := io.ReadFull(conn, length[:]); err != nil {
+				return
+			}
+			msg := make([]byte, binary.BigEndian.Uint32(length[:]))
+			if _, err := io.ReadFull(conn, msg); err != nil {
+				return
+			}
+			if len(msg) != 13 || msg[0] != msgRequest {
+				continue
+			}
+			index := binary.BigEndian.Uint32(msg[1:])
+			begin := binary.BigEndian.Uint32(msg[5:])
+			size := binary.BigEndian.Uint32(msg[9:])
+			start := int(index)*pieceLength + int(begin)
+			block := append([]byte(nil), data[start:start+int(size)]...)
+			if corrupt {
+				block[0] ^= 0xFF
+			}
+			if send(msgPiece, append(msg[1:9:9], block...)) != nil {
+				return
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+// fakeTracker runs an HTTP tracker returning addrs as the peers
+func fakeTracker(t *testing.T, infoHash string, addrs []string) string {
+	hashBytes, err := hex.DecodeString(infoHash)
+	require.NoError(t, err)
+	var compact []byte
+	for _, addr := range addrs {
+		host, port, err := net.SplitHostPort(addr)
+		require.NoError(t, err)
+		portNumber, err := strconv.Atoi(port)
+		require.NoError(t, err)
+		compact = append(compact, net.ParseIP(host).To4()...)
+		compact = binary.BigEndian.AppendUint16(compact, uint16(portNumber))
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("info_hash") != string(hashBytes) {
+			_, _ = w.Write(bencode(map[string]any{"failure reason": "unknown torrent"}))
+			return
+		}
+		_, _ = w.Write(bencode(map[string]any{"interval": 1800, "peers": string(compact)}))
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL + "/announce"
+}
+
+func TestPeerDownload(t *testing.T) {
+	ctx := context.Background()
+	const pieceLength = 2 * blockSize
+	data := []byte(strings.Repeat("0123456789abcdef", 7000)[:3*pieceLength+1000])
+	var webseedCalls atomic.Int32
+	webseed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webseedCalls.Add(1)
+		http.ServeContent(w, r, "big.bin", testMtime, bytes.NewReader(data))
+	}))
+	defer webseed.Close()
+	var pieces []byte
+	for start := 0; start < len(data); start += pieceLength {
+		hash := sha1.Sum(data[start:min(start+pieceLength, len(data))])
+		pieces = append(pieces, hash[:]...)
+	}
+	info := map[string]any{
+		"name":         "big.bin",
+		"piece length": pieceLength,
+		"pieces":       string(pieces),
+		"length":       len(data),
+	}
+	sum := sha1.Sum(bencode(info))
+	infoHash := hex.EncodeToString(sum[:])
+
+	good := fakePeer(t, infoHash, data, pieceLength, false)
+	bad := fakePeer(t, infoHash, data, pieceLength, true)
+
+	// newFs makes a torrent Fs which finds the peers given
+	newFs := func(addrs ...string) fs.Fs {
+		torrent := bencode(map[string]any{
+			"info":     info,
+			"announce": fakeTracker(t, infoHash, addrs),
+			"url-list": webseed.URL + "/",
+		})
+		torrentPath := filepath.Join(t.TempDir(), "test.torrent")
+		require.NoError(t, os.WriteFile(torrentPath, torrent, 0666))
+		f, err := NewFs(ctx, "TestTorrent", "", configmap.Simple{
+			"type":    "torrent",
+			"torrent": torrentPath,
+			"peers":   "2",
+		})
+		require.NoError(t, err)
+		return f
+	}
+
+	// read reads the range of big.bin given
+	read := func(f fs.Fs, options ...fs.OpenOption) []byte {
+		o, err := f.NewObject(ctx, "big.bin")
+		require.NoError(t, err)
+		in, err := o.Open(ctx, options...)
+		require.NoError(t, err)
+		got, err := io.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		return got
+	}
+
+	t.Run("Good", func(t *testing.T) {
+		f := newFs(good)
+		assert.Equal(t, data, read(f))
+		assert.Equal(t, data[40000:70001], read(f, &fs.RangeOption{Start: 40000, End: 70000}))
+		assert.Equal(t, data[len(data)-10:], read(f, &fs.SeekOption{Offset: int64(len(data) - 10)}))
+		assert.Equal(t, int32(0), webseedCalls.Load())
+	})
+
+	t.Run("SkipBadPeer", func(t *testing.T) {
+		f := newFs(bad, good)
+		assert.Equal(t, data, read(f))
+		assert.Equal(t, int32(0), webseedCalls.Load())
+	})
+
+	t.Run("FallbackToWebseed", func(t *testing.T) {
+		f := newFs(bad)
+		assert.Equal(t, data[1000:], read(f, &fs.SeekOption{Offset: 1000}))
+		assert.Equal(t, int32(1), webseedCalls.Load())
+	})
+
+	t.Run("NoPeers", func(t *testing.T) {
+		f := newFs()
+		assert.Equal(t, data, read(f))
+		assert.Equal(t, int32(2), webseedCalls.Load())
+	})
+}
@@ -0,0 +1,158 @@
+package torrent
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	peerListTTL  = 5 * time.Minute // how long to use the peers from the trackers for
+	maxAnnounce  = 1024 * 1024     // biggest tracker response we will read
+	announcePort = 6881            // port we say we are listening on
+	numWant      = 50              // number of peers to ask the trackers for
+)
+
+// escapeBytes percent encodes b for use in a query as trackers
+// expect, which url.QueryEscape doesn't do for spaces.
+func escapeBytes(b []byte) string {
+	var out strings.Builder
+	for _, c := range b {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			out.WriteByte(c)
+		} else {
+			fmt.Fprintf(&out, "%%%02X", c)
+		}
+	}
+	return out.String()
+}
+
+// announce asks the tracker at trackerURL for the addresses of the
+// peers of the torrent.
+//
+// Only HTTP trackers are supported.
+func (f *Fs) announce(ctx context.Context, trackerURL string) (peers []string, err error) {
+	u, err := url.Parse(trackerURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported tracker %q - only http trackers are supported", trackerURL)
+	}
+	infoHash, err := hex.DecodeString(f.info.infoHash)
+	if err != nil {
+		return nil, err
+	}
+	query := u.RawQuery
+	if query != "" {
+		query += "&"
+	}
+	query += "info_hash=" + escapeBytes(infoHash) +
+		"&peer_id=" + escapeBytes(f.peerID[:]) +
+		"&port=" + strconv.Itoa(announcePort) +
+		"&uploaded=0&downloaded=0" +
+		"&left=" + strconv.FormatInt(f.info.size, 10) +
+		"&compact=1&numwant=" + strconv.Itoa(numWant)
+	u.RawQuery = query
+
+	var data []byte
+	opts := rest.Opts{
+		Method:  "GET",
+		RootURL: u.String(),
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.Call(ctx, &opts)
+		if err != nil {
+			return shouldRetry(ctx, resp, err)
+		}
+		defer fs.CheckClose(resp.Body, &err)
+		data, err = io.ReadAll(io.LimitReader(resp.Body, maxAnnounce))
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseAnnounce(data)
+}
+
+// parseAnnounce parses the response from a tracker returning the
+// addresses of the peers in it.
+func parseAnnounce(data []byte) (peers []string, err error) {
+	v, err := bdecode(data)
+	if err != nil {
+		return nil, fmt.Errorf("bad tracker response: %w", err)
+	}
+	resp, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("bad tracker response: not a dictionary")
+	}
+	if reason, ok := resp["failure reason"].(string); ok {
+		return nil, fmt.Errorf("tracker failed: %s", reason)
+	}
+	switch list := resp["peers"].(type) {
+	case string:
+		// BEP 23 compact list of IPv4 addresses and ports
+		for i := 0; i+6 <= len(list); i += 6 {
+			ip := net.IP([]byte(list[i : i+4]))
+			port := binary.BigEndian.Uint16([]byte(list[i+4 : i+6]))
+			peers = append(peers, net.JoinHostPort(ip.String(), strconv.Itoa(int(port))))
+		}
+	case []any:
+		for _, item := range list {
+			peer, _ := item.(map[string]any)
+			ip, _ := peer["ip"].(string)
+			port, _ := peer["port"].(int64)
+			if ip != "" && port > 0 {
+				peers = append(peers, net.JoinHostPort(ip, strconv.FormatInt(port, 10)))
+			}
+		}
+	}
+	if list, ok := resp["peers6"].(string); ok {
+		// BEP 7 compact list of IPv6 addresses and ports
+		for i := 0; i+18 <= len(list); i += 18 {
+			ip := net.IP([]byte(list[i : i+16]))
+			port := binary.BigEndian.Uint16([]byte(list[i+16 : i+18]))
+			peers = append(peers, net.JoinHostPort(ip.String(), strconv.Itoa(int(port))))
+		}
+	}
+	return peers, nil
+}
+
+// findPeers returns the addresses of the peers of the torrent from
+// all its trackers, asking them again if the last answer is too old.
+func (f *Fs) findPeers(ctx context.Context) []string {
+	f.peersMu.Lock()
+	defer f.peersMu.Unlock()
+	if time.Since(f.peersFound) < peerListTTL {
+		return f.peers
+	}
+	seen := map[string]bool{}
+	var peers []string
+	for _, tracker := range f.info.trackers {
+		found, err := f.announce(ctx, tracker)
+		if err != nil {
+			fs.Debugf(f, "Failed to find peers with tracker %q: %v", tracker, err)
+			continue
+		}
+		fs.Debugf(f, "Found %d peers with tracker %q", len(found), tracker)
+		for _, peer := range found {
+			if !seen[peer] {
+				seen[peer] = true
+				peers = append(peers, peer)
+			}
+		}
+	}
+	f.peers, f.peersFound = peers, time.Now()
+	return peers
+}
@@ -0,0 +1,93 @@
+package union
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rclone/rclone/backend/union/upstream"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// dirExists returns true if dir is on u
+func dirExists(ctx context.Context, u *upstream.Fs, dir string) (bool, error) {
+	if dir == "" {
+		return true, nil
+	}
+	_, err := u.List(ctx, dir)
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// findDir returns dir from one of the upstreams other than u or nil
+// if it isn't on any of them
+func (f *Fs) findDir(ctx context.Context, u *upstream.Fs, dir string) fs.Directory {
+	for _, other := range f.healthyUpstreams() {
+		if other == u {
+			continue
+		}
+		entries, err := other.List(ctx, parentDir(dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if d, ok := entry.(fs.Directory); ok && d.Remote() == dir {
+				return d
+			}
+		}
+	}
+	return nil
+}
+
+// cloneParents makes the directories of dir which are missing on u,
+// copying their modification times and metadata from the upstreams
+// which have them.
+func (f *Fs) cloneParents(ctx context.Context, u *upstream.Fs, dir string) error {
+	// Find the missing directories, deepest first
+	var missing []string
+	for ; dir != ""; dir = parentDir(dir) {
+		ok, err := dirExists(ctx, u, dir)
+		if err != nil {
+			return err
+		}
+		if ok {
+			break
+		}
+		missing = append(missing, dir)
+	}
+	srcs := make([]fs.Directory, len(missing))
+	for i := len(missing) - 1; i >= 0; i-- {
+		dir := missing[i]
+		var err error
+		srcs[i] = f.findDir(ctx, u, dir)
+		switch src := srcs[i]; {
+		case src == nil:
+			err = operations.Mkdir(ctx, u, dir)
+		case u.Features().MkdirMetadata != nil:
+			_, err = operations.CopyDirMetadata(ctx, u, nil, dir, src)
+		default:
+			_, err = operations.MkdirModTime(ctx, u, dir, src.ModTime(ctx))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to make parent directory %q on %s: %w", dir, u.Name(), err)
+		}
+	}
+	// Making the directories below may have changed the modification
+	// times so set them again, deepest first
+	if u.Features().DirSetModTime == nil {
+		return nil
+	}
+	for i := 1; i < len(missing); i++ {
+		if srcs[i] == nil {
+			continue
+		}
+		_, err := operations.SetDirModTime(ctx, u, nil, missing[i], srcs[i].ModTime(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to set modification time of parent directory %q on %s: %w", missing[i], u.Name(), err)
+		}
+	}
+	return nil
+}
@@ -7,11 +7,17 @@ import "github.com/rclone/rclone/fs"
 
 // Options defines the configuration for this backend
 type Options struct {
-	Upstreams    fs.SpaceSepList `config:"upstreams"`
-	Remotes      fs.SpaceSepList `config:"remotes"` // Deprecated
-	ActionPolicy string          `config:"action_policy"`
-	CreatePolicy string          `config:"create_policy"`
-	SearchPolicy string          `config:"search_policy"`
-	CacheTime    int             `config:"cache_time"`
-	MinFreeSpace fs.SizeSuffix   `config:"min_free_space"`
+	Upstreams           fs.SpaceSepList `config:"upstreams"`
+	Remotes             fs.SpaceSepList `config:"remotes"` // Deprecated
+	ActionPolicy        string          `config:"action_policy"`
+	CreatePolicy        string          `config:"create_policy"`
+	SearchPolicy        string          `config:"search_policy"`
+	CacheTime           int             `config:"cache_time"`
+	MinFreeSpace        fs.SizeSuffix   `config:"min_free_space"`
+	ActionMinFreeSpace  fs.SizeSuffix   `config:"action_min_free_space"`
+	PolicyOverrides     fs.CommaSepList `config:"policy_overrides"`
+	OnENOSPC            string          `config:"on_enospc"`
+	MirrorCopies        int             `config:"mirror_copies"`
+	HealthCheckInterval fs.Duration     `config:"health_check_interval"`
+	CreateParent        bool            `config:"create_parent"`
 }
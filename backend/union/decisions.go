@@ -0,0 +1,148 @@
+package union
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/union/policy"
+	"github.com/rclone/rclone/backend/union/upstream"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/rc"
+)
+
+const maxDecisions = 100 // number of policy decisions kept for union/decisions
+
+// decisionUpstream is an upstream a policy could choose, with its
+// usage as last read when the policy was used
+type decisionUpstream struct {
+	Name    string `json:"name"`
+	Free    *int64 `json:"free,omitempty"`
+	Used    *int64 `json:"used,omitempty"`
+	Objects *int64 `json:"objects,omitempty"`
+}
+
+// decision is a record of the upstreams a policy chose
+type decision struct {
+	Time       time.Time          `json:"time"`
+	Union      string             `json:"union"`
+	Category   string             `json:"category"`
+	Policy     string             `json:"policy"`
+	Remote     string             `json:"remote"`
+	Candidates []decisionUpstream `json:"candidates"`
+	Chosen     []string           `json:"chosen"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// decisions are the last maxDecisions policy decisions of all the unions
+var decisions struct {
+	mu   sync.Mutex
+	list []decision // oldest first
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "union/decisions",
+		Fn:    rcDecisions,
+		Title: "Show the last upstreams chosen by the union policies",
+		Help: `
+This shows the last 100 decisions made by the union policies when
+there was more than one upstream to choose from, oldest first. Each
+one has the union, the category of the policy and its name, the path,
+the upstreams which could be chosen with their free space, used space
+and number of objects if known, and the upstreams chosen.
+
+This is useful to find out why files were put on an upstream.
+
+Parameters:
+
+- n - the number of decisions to show (optional, default all)
+- remote - only show decisions for paths starting with this (optional)
+
+Eg
+
+    rclone rc union/decisions n=10
+`,
+	})
+}
+
+// rcDecisions returns the last policy decisions
+func rcDecisions(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	n, err := in.GetInt64("n")
+	if rc.IsErrParamNotFound(err) {
+		n = maxDecisions
+	} else if err != nil {
+		return nil, err
+	}
+	remote, err := in.GetString("remote")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	decisions.mu.Lock()
+	list := make([]decision, 0, len(decisions.list))
+	for _, d := range decisions.list {
+		if strings.HasPrefix(d.Remote, remote) {
+			list = append(list, d)
+		}
+	}
+	decisions.mu.Unlock()
+	if n >= 0 && int64(len(list)) > n {
+		list = list[int64(len(list))-n:]
+	}
+	return rc.Params{"decisions": list}, nil
+}
+
+// upstreamNames returns the names of the upstreams
+func upstreamNames(upstreams []*upstream.Fs) []string {
+	names := make([]string, 0, len(upstreams))
+	for _, u := range upstreams {
+		if u != nil {
+			names = append(names, fs.ConfigString(u.Fs))
+		}
+	}
+	return names
+}
+
+// recordDecision records that policy p chose the chosen upstreams from
+// the candidates for remote, logging it at debug level and counting
+// the selections
+//
+// Decisions with only one candidate aren't recorded as there was
+// nothing to decide.
+func (f *Fs) recordDecision(category string, p policy.Policy, remote string, candidates, chosen []*upstream.Fs, err error) {
+	f.countSelections(category, chosen)
+	if len(candidates) < 2 {
+		return
+	}
+	d := decision{
+		Time:       time.Now(),
+		Union:      fs.ConfigString(f),
+		Category:   category,
+		Policy:     policy.Name(p),
+		Remote:     remote,
+		Candidates: make([]decisionUpstream, len(candidates)),
+		Chosen:     upstreamNames(chosen),
+	}
+	for i, u := range candidates {
+		usage := u.CachedUsage()
+		d.Candidates[i] = decisionUpstream{
+			Name:    fs.ConfigString(u.Fs),
+			Free:    usage.Free,
+			Used:    usage.Used,
+			Objects: usage.Objects,
+		}
+	}
+	if err != nil {
+		d.Error = err.Error()
+		fs.Debugf(f, "%s policy %s failed for %q: %v", category, d.Policy, remote, err)
+	} else {
+		fs.Debugf(f, "%s policy %s chose %v for %q from %v", category, d.Policy, d.Chosen, remote, upstreamNames(candidates))
+	}
+	decisions.mu.Lock()
+	defer decisions.mu.Unlock()
+	if len(decisions.list) >= maxDecisions {
+		decisions.list = append(decisions.list[:0], decisions.list[len(decisions.list)-maxDecisions+1:]...)
+	}
+	decisions.list = append(decisions.list, d)
+}
@@ -0,0 +1,65 @@
+package union
+
+import (
+	"context"
+	"time"
+
+	"github.com/rclone/rclone/backend/union/upstream"
+	"github.com/rclone/rclone/fs"
+)
+
+const healthCheckTimeout = 30 * time.Second // time an upstream has to reply to a health check
+
+// healthyUpstreams returns the upstreams which passed their last
+// health check
+//
+// If none of them did it returns all of them so the errors are seen
+// rather than the union looking empty.
+func (f *Fs) healthyUpstreams() []*upstream.Fs {
+	var healthy []*upstream.Fs
+	for _, u := range f.upstreams {
+		if u.IsHealthy() {
+			healthy = append(healthy, u)
+		}
+	}
+	if len(healthy) == 0 || len(healthy) == len(f.upstreams) {
+		return f.upstreams
+	}
+	return healthy
+}
+
+// checkHealth checks the health of all the upstreams at once
+func (f *Fs) checkHealth(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	multithread(len(f.upstreams), func(i int) {
+		u := f.upstreams[i]
+		changed, err := u.CheckHealth(ctx)
+		switch {
+		case !changed:
+		case err != nil:
+			fs.Errorf(f, "Not using upstream %s as its health check failed: %v", u.Name(), err)
+		default:
+			fs.Logf(f, "Using upstream %s again as it is healthy", u.Name())
+		}
+	})
+}
+
+// startHealthCheck checks the health of the upstreams every interval
+// until Shutdown is called
+func (f *Fs) startHealthCheck(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	f.healthCancel = cancel
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				f.checkHealth(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
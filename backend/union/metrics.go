@@ -0,0 +1,177 @@
+package union
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rclone/rclone/backend/union/upstream"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+)
+
+// selections counts the times an upstream was chosen by the policies
+type selections struct {
+	action atomic.Int64
+	create atomic.Int64
+	search atomic.Int64
+}
+
+// counter returns the counter for the policy category
+func (s *selections) counter(category string) *atomic.Int64 {
+	switch category {
+	case "action":
+		return &s.action
+	case "create":
+		return &s.create
+	default:
+		return &s.search
+	}
+}
+
+// upstreamStats are the stats of an upstream of a union
+type upstreamStats struct {
+	Union        string           `json:"union"`
+	Upstream     string           `json:"upstream"`
+	BytesWritten int64            `json:"bytesWritten"`
+	FilesCreated int64            `json:"filesCreated"`
+	Free         *int64           `json:"free,omitempty"`
+	Selections   map[string]int64 `json:"selections"`
+}
+
+// unions are the unions stats are returned for by name
+var unions = struct {
+	mu sync.Mutex
+	fs map[string]*Fs
+}{
+	fs: map[string]*Fs{},
+}
+
+func init() {
+	accounting.AddRemoteStats("union", func() any {
+		stats := unionStats()
+		if len(stats) == 0 {
+			return nil
+		}
+		return stats
+	})
+	prometheus.MustRegister(newUnionCollector())
+}
+
+// addMetrics sets up the counters of f and adds it to the unions
+// stats are returned for
+func (f *Fs) addMetrics() {
+	f.selections = make(map[*upstream.Fs]*selections, len(f.upstreams))
+	for _, u := range f.upstreams {
+		f.selections[u] = &selections{}
+	}
+	unions.mu.Lock()
+	defer unions.mu.Unlock()
+	unions.fs[fs.ConfigString(f)] = f
+}
+
+// removeMetrics removes f from the unions stats are returned for
+func (f *Fs) removeMetrics() {
+	name := fs.ConfigString(f)
+	unions.mu.Lock()
+	defer unions.mu.Unlock()
+	if unions.fs[name] == f {
+		delete(unions.fs, name)
+	}
+}
+
+// countSelections counts the upstreams chosen by a policy
+func (f *Fs) countSelections(category string, chosen []*upstream.Fs) {
+	for _, u := range chosen {
+		if s := f.selections[u]; s != nil {
+			s.counter(category).Add(1)
+		}
+	}
+}
+
+// unionStats returns the stats of the upstreams of all the unions
+func unionStats() (stats []upstreamStats) {
+	unions.mu.Lock()
+	names := make([]string, 0, len(unions.fs))
+	for name := range unions.fs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fses := make([]*Fs, len(names))
+	for i, name := range names {
+		fses[i] = unions.fs[name]
+	}
+	unions.mu.Unlock()
+	for i, f := range fses {
+		for _, u := range f.upstreams {
+			s := f.selections[u]
+			stats = append(stats, upstreamStats{
+				Union:        names[i],
+				Upstream:     fs.ConfigString(u.Fs),
+				BytesWritten: u.BytesWritten(),
+				FilesCreated: u.FilesCreated(),
+				Free:         u.CachedUsage().Free,
+				Selections: map[string]int64{
+					"action": s.action.Load(),
+					"create": s.create.Load(),
+					"search": s.search.Load(),
+				},
+			})
+		}
+	}
+	return stats
+}
+
+// unionCollector is a Prometheus collector for the union upstreams
+type unionCollector struct {
+	bytesWritten *prometheus.Desc
+	filesCreated *prometheus.Desc
+	free         *prometheus.Desc
+	selections   *prometheus.Desc
+}
+
+// newUnionCollector makes a new unionCollector
+func newUnionCollector() *unionCollector {
+	labels := []string{"union", "upstream"}
+	return &unionCollector{
+		bytesWritten: prometheus.NewDesc("rclone_union_bytes_written_total",
+			"Total bytes written to the upstream of a union",
+			labels, nil,
+		),
+		filesCreated: prometheus.NewDesc("rclone_union_files_created_total",
+			"Total number of files created on the upstream of a union",
+			labels, nil,
+		),
+		free: prometheus.NewDesc("rclone_union_free_bytes",
+			"Free space of the upstream of a union when it was last read",
+			labels, nil,
+		),
+		selections: prometheus.NewDesc("rclone_union_selections_total",
+			"Total number of times the upstream of a union was chosen by a policy",
+			append(labels, "category"), nil,
+		),
+	}
+}
+
+// Describe is part of the Collector interface: https://godoc.org/github.com/prometheus/client_golang/prometheus#Collector
+func (c *unionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.bytesWritten
+	ch <- c.filesCreated
+	ch <- c.free
+	ch <- c.selections
+}
+
+// Collect is part of the Collector interface: https://godoc.org/github.com/prometheus/client_golang/prometheus#Collector
+func (c *unionCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range unionStats() {
+		ch <- prometheus.MustNewConstMetric(c.bytesWritten, prometheus.CounterValue, float64(s.BytesWritten), s.Union, s.Upstream)
+		ch <- prometheus.MustNewConstMetric(c.filesCreated, prometheus.CounterValue, float64(s.FilesCreated), s.Union, s.Upstream)
+		if s.Free != nil {
+			ch <- prometheus.MustNewConstMetric(c.free, prometheus.GaugeValue, float64(*s.Free), s.Union, s.Upstream)
+		}
+		for category, n := range s.Selections {
+			ch <- prometheus.MustNewConstMetric(c.selections, prometheus.CounterValue, float64(n), s.Union, s.Upstream, category)
+		}
+	}
+}
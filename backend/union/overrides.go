@@ -0,0 +1,99 @@
+package union
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/rclone/rclone/backend/union/policy"
+)
+
+// policyOverride is the policies to use for a directory tree instead
+// of the default ones
+type policyOverride struct {
+	dir          string        // directory relative to the root of the remote
+	actionPolicy policy.Policy // policy for ACTION or nil for the default
+	createPolicy policy.Policy // policy for CREATE or nil for the default
+	searchPolicy policy.Policy // policy for SEARCH or nil for the default
+}
+
+// parsePolicyOverrides parses the policy_overrides option
+//
+// Each entry is dir:policy to use policy for all the categories in
+// dir or dir:category=policy to use it for just one. The overrides are
+// returned with the deepest directories first.
+func parsePolicyOverrides(entries []string) ([]*policyOverride, error) {
+	byDir := map[string]*policyOverride{}
+	var overrides []*policyOverride
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("policy override %q should be dir:policy or dir:category=policy", entry)
+		}
+		dir := strings.Trim(path.Clean("/"+entry[:i]), "/")
+		category, name, hasCategory := strings.Cut(entry[i+1:], "=")
+		if !hasCategory {
+			category, name = "", category
+		}
+		p, err := policy.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("policy override %q: %w", entry, err)
+		}
+		o := byDir[dir]
+		if o == nil {
+			o = &policyOverride{dir: dir}
+			byDir[dir] = o
+			overrides = append(overrides, o)
+		}
+		switch strings.ToLower(category) {
+		case "":
+			o.actionPolicy, o.createPolicy, o.searchPolicy = p, p, p
+		case "action":
+			o.actionPolicy = p
+		case "create":
+			o.createPolicy = p
+		case "search":
+			o.searchPolicy = p
+		default:
+			return nil, fmt.Errorf("policy override %q: unknown category %q - should be action, create or search", entry, category)
+		}
+	}
+	sort.SliceStable(overrides, func(i, j int) bool {
+		return overrides[i].depth() > overrides[j].depth()
+	})
+	return overrides, nil
+}
+
+// depth returns the number of directories deep the override is
+func (o *policyOverride) depth() int {
+	if o.dir == "" {
+		return 0
+	}
+	return strings.Count(o.dir, "/") + 1
+}
+
+// contains returns true if remote, relative to the root of the
+// remote, is in the directory tree of the override
+func (o *policyOverride) contains(remote string) bool {
+	return o.dir == "" || remote == o.dir || strings.HasPrefix(remote, o.dir+"/")
+}
+
+// policyFor returns the policy got from the override of the deepest
+// directory containing remote, or def if none of them have one
+func (f *Fs) policyFor(remote string, get func(o *policyOverride) policy.Policy, def policy.Policy) policy.Policy {
+	if len(f.overrides) == 0 {
+		return def
+	}
+	remote = path.Join(f.root, remote)
+	for _, o := range f.overrides {
+		if p := get(o); p != nil && o.contains(remote) {
+			return p
+		}
+	}
+	return def
+}
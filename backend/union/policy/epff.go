@@ -99,7 +99,9 @@ func (p *EpFF) Search(ctx context.Context, upstreams []*upstream.Fs, path string
 	if len(upstreams) == 0 {
 		return nil, fs.ErrorObjectNotFound
 	}
-	return p.epff(ctx, upstreams, path)
+	return searchRA(upstreams, func(upstreams []*upstream.Fs) (*upstream.Fs, error) {
+		return p.epff(ctx, upstreams, path)
+	})
 }
 
 // SearchEntries is SEARCH category policy but receiving a set of candidate entries
@@ -107,5 +109,6 @@ func (p *EpFF) SearchEntries(entries ...upstream.Entry) (upstream.Entry, error)
 	if len(entries) == 0 {
 		return nil, fs.ErrorObjectNotFound
 	}
+	entries = filterRAEntries(entries)
 	return entries[0], nil
 }
@@ -21,7 +21,7 @@ type EpLfs struct {
 	EpAll
 }
 
-var errNoUpstreamsFound = errors.New("no upstreams found with more than min_free_space space spare")
+var errNoUpstreamsFound = errors.New("no upstreams found")
 
 func (p *EpLfs) lfs(upstreams []*upstream.Fs) (*upstream.Fs, error) {
 	// First shuffle the list to randomize the selection order
@@ -38,7 +38,7 @@ func (p *EpLfs) lfs(upstreams []*upstream.Fs) (*upstream.Fs, error) {
 			fs.LogPrintf(fs.LogLevelNotice, nil,
 				"Free Space is not supported for upstream %s, treating as infinite", u.Name())
 		}
-		if space < minFreeSpace && space > int64(u.Opt.MinFreeSpace) {
+		if space < minFreeSpace {
 			minFreeSpace = space
 			lfsupstream = u
 		}
@@ -65,7 +65,7 @@ func (p *EpLfs) lfsEntries(entries []upstream.Entry) (upstream.Entry, error) {
 			fs.LogPrintf(fs.LogLevelNotice, nil,
 				"Free Space is not supported for upstream %s, treating as infinite", u.Name())
 		}
-		if space < minFreeSpace && space > int64(u.Opt.MinFreeSpace) {
+		if space < minFreeSpace {
 			minFreeSpace = space
 			lfsEntry = e
 		}
@@ -121,11 +121,13 @@ func (p *EpLfs) Search(ctx context.Context, upstreams []*upstream.Fs, path strin
 	if len(upstreams) == 0 {
 		return nil, fs.ErrorObjectNotFound
 	}
-	upstreams, err := p.epall(ctx, upstreams, path)
-	if err != nil {
-		return nil, err
-	}
-	return p.lfs(upstreams)
+	return searchRA(upstreams, func(upstreams []*upstream.Fs) (*upstream.Fs, error) {
+		upstreams, err := p.epall(ctx, upstreams, path)
+		if err != nil {
+			return nil, err
+		}
+		return p.lfs(upstreams)
+	})
 }
 
 // SearchEntries is SEARCH category policy but receiving a set of candidate entries
@@ -133,5 +135,6 @@ func (p *EpLfs) SearchEntries(entries ...upstream.Entry) (upstream.Entry, error)
 	if len(entries) == 0 {
 		return nil, fs.ErrorObjectNotFound
 	}
+	entries = filterRAEntries(entries)
 	return p.lfsEntries(entries)
 }
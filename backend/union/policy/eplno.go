@@ -114,11 +114,13 @@ func (p *EpLno) Search(ctx context.Context, upstreams []*upstream.Fs, path strin
 	if len(upstreams) == 0 {
 		return nil, fs.ErrorObjectNotFound
 	}
-	upstreams, err := p.epall(ctx, upstreams, path)
-	if err != nil {
-		return nil, err
-	}
-	return p.lno(upstreams)
+	return searchRA(upstreams, func(upstreams []*upstream.Fs) (*upstream.Fs, error) {
+		upstreams, err := p.epall(ctx, upstreams, path)
+		if err != nil {
+			return nil, err
+		}
+		return p.lno(upstreams)
+	})
 }
 
 // SearchEntries is SEARCH category policy but receiving a set of candidate entries
@@ -126,5 +128,6 @@ func (p *EpLno) SearchEntries(entries ...upstream.Entry) (upstream.Entry, error)
 	if len(entries) == 0 {
 		return nil, fs.ErrorObjectNotFound
 	}
+	entries = filterRAEntries(entries)
 	return p.lnoEntries(entries)
 }
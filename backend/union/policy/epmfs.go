@@ -113,11 +113,13 @@ func (p *EpMfs) Search(ctx context.Context, upstreams []*upstream.Fs, path strin
 	if len(upstreams) == 0 {
 		return nil, fs.ErrorObjectNotFound
 	}
-	upstreams, err := p.epall(ctx, upstreams, path)
-	if err != nil {
-		return nil, err
-	}
-	return p.mfs(upstreams)
+	return searchRA(upstreams, func(upstreams []*upstream.Fs) (*upstream.Fs, error) {
+		upstreams, err := p.epall(ctx, upstreams, path)
+		if err != nil {
+			return nil, err
+		}
+		return p.mfs(upstreams)
+	})
 }
 
 // SearchEntries is SEARCH category policy but receiving a set of candidate entries
@@ -125,5 +127,6 @@ func (p *EpMfs) SearchEntries(entries ...upstream.Entry) (upstream.Entry, error)
 	if len(entries) == 0 {
 		return nil, fs.ErrorObjectNotFound
 	}
+	entries = filterRAEntries(entries)
 	return p.mfsEntries(entries)
 }
@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/rclone/rclone/backend/union/upstream"
+	"github.com/rclone/rclone/fs"
+)
+
+func init() {
+	registerPolicy("epmfsp", &EpMfsp{})
+}
+
+// EpMfsp stands for existing path, most free space percentage
+// Of all the candidates on which the path exists choose the one with the most free space
+// as a percentage of its total space.
+// If multiple candidates have the same percentage of free space, one is chosen randomly.
+type EpMfsp struct {
+	EpAll
+}
+
+func (p *EpMfsp) mfsp(upstreams []*upstream.Fs) (*upstream.Fs, error) {
+	// First shuffle the list to randomize the selection order
+	// This ensures that among backends with equal free space, one is chosen randomly
+	rand.Shuffle(len(upstreams), func(i, j int) {
+		upstreams[i], upstreams[j] = upstreams[j], upstreams[i]
+	})
+
+	maxFreePercent := -1.0
+	var mfspupstream *upstream.Fs
+	for _, u := range upstreams {
+		percent, err := u.GetFreeSpacePercent()
+		if err != nil {
+			fs.LogPrintf(fs.LogLevelNotice, nil,
+				"Free Space percentage is not supported for upstream %s, treating as 100%%", u.Name())
+		}
+		if maxFreePercent < percent {
+			maxFreePercent = percent
+			mfspupstream = u
+		}
+	}
+	if mfspupstream == nil {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return mfspupstream, nil
+}
+
+func (p *EpMfsp) mfspEntries(entries []upstream.Entry) (upstream.Entry, error) {
+	// First shuffle the list to randomize the selection order
+	// This ensures that among entries with equal free space, one is chosen randomly
+	rand.Shuffle(len(entries), func(i, j int) {
+		entries[i], entries[j] = entries[j], entries[i]
+	})
+
+	maxFreePercent := -1.0
+	var mfspEntry upstream.Entry
+	for _, e := range entries {
+		percent, err := e.UpstreamFs().GetFreeSpacePercent()
+		if err != nil {
+			fs.LogPrintf(fs.LogLevelNotice, nil,
+				"Free Space percentage is not supported for upstream %s, treating as 100%%", e.UpstreamFs().Name())
+		}
+		if maxFreePercent < percent {
+			maxFreePercent = percent
+			mfspEntry = e
+		}
+	}
+	return mfspEntry, nil
+}
+
+// Action category policy, governing the modification of files and directories
+func (p *EpMfsp) Action(ctx context.Context, upstreams []*upstream.Fs, path string) ([]*upstream.Fs, error) {
+	upstreams, err := p.EpAll.Action(ctx, upstreams, path)
+	if err != nil {
+		return nil, err
+	}
+	u, err := p.mfsp(upstreams)
+	return []*upstream.Fs{u}, err
+}
+
+// ActionEntries is ACTION category policy but receiving a set of candidate entries
+func (p *EpMfsp) ActionEntries(entries ...upstream.Entry) ([]upstream.Entry, error) {
+	entries, err := p.EpAll.ActionEntries(entries...)
+	if err != nil {
+		return nil, err
+	}
+	e, err := p.mfspEntries(entries)
+	return []upstream.Entry{e}, err
+}
+
+// Create category policy, governing the creation of files and directories
+func (p *EpMfsp) Create(ctx context.Context, upstreams []*upstream.Fs, path string) ([]*upstream.Fs, error) {
+	upstreams, err := p.EpAll.Create(ctx, upstreams, path)
+	if err != nil {
+		return nil, err
+	}
+	u, err := p.mfsp(upstreams)
+	return []*upstream.Fs{u}, err
+}
+
+// CreateEntries is CREATE category policy but receiving a set of candidate entries
+func (p *EpMfsp) CreateEntries(entries ...upstream.Entry) ([]upstream.Entry, error) {
+	entries, err := p.EpAll.CreateEntries(entries...)
+	if err != nil {
+		return nil, err
+	}
+	e, err := p.mfspEntries(entries)
+	return []upstream.Entry{e}, err
+}
+
+// Search category policy, governing the access to files and directories
+func (p *EpMfsp) Search(ctx context.Context, upstreams []*upstream.Fs, path string) (*upstream.Fs, error) {
+	if len(upstreams) == 0 {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return searchRA(upstreams, func(upstreams []*upstream.Fs) (*upstream.Fs, error) {
+		upstreams, err := p.epall(ctx, upstreams, path)
+		if err != nil {
+			return nil, err
+		}
+		return p.mfsp(upstreams)
+	})
+}
+
+// SearchEntries is SEARCH category policy but receiving a set of candidate entries
+func (p *EpMfsp) SearchEntries(entries ...upstream.Entry) (upstream.Entry, error) {
+	if len(entries) == 0 {
+		return nil, fs.ErrorObjectNotFound
+	}
+	entries = filterRAEntries(entries)
+	return p.mfspEntries(entries)
+}
@@ -0,0 +1,102 @@
+package policy
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+
+	"github.com/rclone/rclone/backend/union/upstream"
+	"github.com/rclone/rclone/fs"
+)
+
+func init() {
+	registerPolicy("epmirror", &EpMirror{})
+}
+
+// EpMirror stands for existing path, mirror
+// Action category: same as epall.
+// Create category: Of all the candidates on which the path exists choose the
+// mirror_copies ones with the most free space.
+// Search category: same as epall.
+type EpMirror struct {
+	EpAll
+}
+
+func (p *EpMirror) mirror(upstreams []*upstream.Fs) ([]*upstream.Fs, error) {
+	if len(upstreams) == 0 {
+		return nil, fs.ErrorObjectNotFound
+	}
+	// First shuffle the list to randomize the selection order
+	// This ensures that among backends with equal free space, they are chosen randomly
+	rand.Shuffle(len(upstreams), func(i, j int) {
+		upstreams[i], upstreams[j] = upstreams[j], upstreams[i]
+	})
+	spaces := make(map[*upstream.Fs]int64, len(upstreams))
+	for _, u := range upstreams {
+		space, err := u.GetFreeSpace()
+		if err != nil {
+			fs.LogPrintf(fs.LogLevelNotice, nil,
+				"Free Space is not supported for upstream %s, treating as infinite", u.Name())
+		}
+		spaces[u] = space
+	}
+	sort.SliceStable(upstreams, func(i, j int) bool {
+		return spaces[upstreams[i]] > spaces[upstreams[j]]
+	})
+	n := upstreams[0].Opt.MirrorCopies
+	return upstreams[:min(n, len(upstreams))], nil
+}
+
+func (p *EpMirror) mirrorEntries(entries []upstream.Entry) ([]upstream.Entry, error) {
+	if len(entries) == 0 {
+		return nil, fs.ErrorObjectNotFound
+	}
+	// First shuffle the list to randomize the selection order
+	// This ensures that among entries with equal free space, they are chosen randomly
+	rand.Shuffle(len(entries), func(i, j int) {
+		entries[i], entries[j] = entries[j], entries[i]
+	})
+	spaces := make([]int64, len(entries))
+	for i, e := range entries {
+		space, err := e.UpstreamFs().GetFreeSpace()
+		if err != nil {
+			fs.LogPrintf(fs.LogLevelNotice, nil,
+				"Free Space is not supported for upstream %s, treating as infinite", e.UpstreamFs().Name())
+		}
+		spaces[i] = space
+	}
+	sort.Stable(entriesBySpace{entries: entries, spaces: spaces})
+	n := entries[0].UpstreamFs().Opt.MirrorCopies
+	return entries[:min(n, len(entries))], nil
+}
+
+// entriesBySpace sorts entries by their free space, most first
+type entriesBySpace struct {
+	entries []upstream.Entry
+	spaces  []int64
+}
+
+func (s entriesBySpace) Len() int           { return len(s.entries) }
+func (s entriesBySpace) Less(i, j int) bool { return s.spaces[i] > s.spaces[j] }
+func (s entriesBySpace) Swap(i, j int) {
+	s.entries[i], s.entries[j] = s.entries[j], s.entries[i]
+	s.spaces[i], s.spaces[j] = s.spaces[j], s.spaces[i]
+}
+
+// Create category policy, governing the creation of files and directories
+func (p *EpMirror) Create(ctx context.Context, upstreams []*upstream.Fs, path string) ([]*upstream.Fs, error) {
+	upstreams, err := p.EpAll.Create(ctx, upstreams, path)
+	if err != nil {
+		return nil, err
+	}
+	return p.mirror(upstreams)
+}
+
+// CreateEntries is CREATE category policy but receiving a set of candidate entries
+func (p *EpMirror) CreateEntries(entries ...upstream.Entry) ([]upstream.Entry, error) {
+	entries, err := p.EpAll.CreateEntries(entries...)
+	if err != nil {
+		return nil, err
+	}
+	return p.mirrorEntries(entries)
+}
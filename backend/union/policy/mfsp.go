@@ -0,0 +1,33 @@
+package policy
+
+import (
+	"context"
+
+	"github.com/rclone/rclone/backend/union/upstream"
+	"github.com/rclone/rclone/fs"
+)
+
+func init() {
+	registerPolicy("mfsp", &Mfsp{})
+}
+
+// Mfsp stands for most free space percentage
+// Search category: same as epmfsp.
+// Action category: same as epmfsp.
+// Create category: Pick the drive with the most free space as a percentage of its total space.
+type Mfsp struct {
+	EpMfsp
+}
+
+// Create category policy, governing the creation of files and directories
+func (p *Mfsp) Create(ctx context.Context, upstreams []*upstream.Fs, path string) ([]*upstream.Fs, error) {
+	if len(upstreams) == 0 {
+		return nil, fs.ErrorObjectNotFound
+	}
+	upstreams = filterNC(upstreams)
+	if len(upstreams) == 0 {
+		return nil, fs.ErrorPermissionDenied
+	}
+	u, err := p.mfsp(upstreams)
+	return []*upstream.Fs{u}, err
+}
@@ -0,0 +1,32 @@
+package policy
+
+import (
+	"context"
+
+	"github.com/rclone/rclone/backend/union/upstream"
+	"github.com/rclone/rclone/fs"
+)
+
+func init() {
+	registerPolicy("mirror", &Mirror{})
+}
+
+// Mirror writes new files to several upstreams
+// Search category: same as epmirror.
+// Action category: same as epmirror.
+// Create category: Pick the mirror_copies drives with the most free space.
+type Mirror struct {
+	EpMirror
+}
+
+// Create category policy, governing the creation of files and directories
+func (p *Mirror) Create(ctx context.Context, upstreams []*upstream.Fs, path string) ([]*upstream.Fs, error) {
+	if len(upstreams) == 0 {
+		return nil, fs.ErrorObjectNotFound
+	}
+	upstreams = filterNC(upstreams)
+	if len(upstreams) == 0 {
+		return nil, fs.ErrorPermissionDenied
+	}
+	return p.mirror(upstreams)
+}
@@ -137,7 +137,9 @@ func (p *Newest) Search(ctx context.Context, upstreams []*upstream.Fs, path stri
 	if len(upstreams) == 0 {
 		return nil, fs.ErrorObjectNotFound
 	}
-	return p.newest(ctx, upstreams, path)
+	return searchRA(upstreams, func(upstreams []*upstream.Fs) (*upstream.Fs, error) {
+		return p.newest(ctx, upstreams, path)
+	})
 }
 
 // SearchEntries is SEARCH category policy but receiving a set of candidate entries
@@ -145,5 +147,6 @@ func (p *Newest) SearchEntries(entries ...upstream.Entry) (upstream.Entry, error
 	if len(entries) == 0 {
 		return nil, fs.ErrorObjectNotFound
 	}
+	entries = filterRAEntries(entries)
 	return p.newestEntries(entries)
 }
@@ -39,6 +39,33 @@ func registerPolicy(name string, p Policy) {
 	policies[strings.ToLower(name)] = p
 }
 
+// Name returns the name p was registered with or "" if not found
+func Name(p Policy) string {
+	for name, registered := range policies {
+		if registered == p {
+			return name
+		}
+	}
+	return ""
+}
+
+// PathPreserving returns true if p only creates on the upstreams
+// which already have the parent directory
+func PathPreserving(p Policy) bool {
+	name := Name(p)
+	return strings.HasPrefix(name, "ep") || name == "newest"
+}
+
+// NotPathPreserving returns the policy which is the same as p but
+// doesn't preserve paths, or nil if there isn't one
+func NotPathPreserving(p Policy) Policy {
+	name, ok := strings.CutPrefix(Name(p), "ep")
+	if !ok {
+		return nil
+	}
+	return policies[name]
+}
+
 // Get a Policy from the list
 func Get(name string) (Policy, error) {
 	p, ok := policies[strings.ToLower(name)]
@@ -84,6 +111,41 @@ func filterNCEntries(ue []upstream.Entry) (wue []upstream.Entry) {
 	return wue
 }
 
+// searchRA runs search on the upstreams which aren't read after,
+// only running it on the read after upstreams if it doesn't find the
+// path on any of the others
+func searchRA(upstreams []*upstream.Fs, search func(upstreams []*upstream.Fs) (*upstream.Fs, error)) (*upstream.Fs, error) {
+	var rest, ra []*upstream.Fs
+	for _, u := range upstreams {
+		if u.IsReadAfter() {
+			ra = append(ra, u)
+		} else {
+			rest = append(rest, u)
+		}
+	}
+	if len(rest) > 0 {
+		u, err := search(rest)
+		if err != fs.ErrorObjectNotFound || len(ra) == 0 {
+			return u, err
+		}
+	}
+	return search(ra)
+}
+
+// filterRAEntries removes the entries on read after upstreams unless
+// they are all on read after upstreams
+func filterRAEntries(ue []upstream.Entry) (wue []upstream.Entry) {
+	for _, e := range ue {
+		if !e.UpstreamFs().IsReadAfter() {
+			wue = append(wue, e)
+		}
+	}
+	if len(wue) == 0 {
+		return ue
+	}
+	return wue
+}
+
 func parentDir(absPath string) string {
 	parent := path.Dir(strings.TrimRight(absPath, "/"))
 	if parent == "." {
@@ -67,11 +67,13 @@ func (p *Rand) Search(ctx context.Context, upstreams []*upstream.Fs, path string
 	if len(upstreams) == 0 {
 		return nil, fs.ErrorObjectNotFound
 	}
-	upstreams, err := p.epall(ctx, upstreams, path)
-	if err != nil {
-		return nil, err
-	}
-	return p.rand(upstreams), nil
+	return searchRA(upstreams, func(upstreams []*upstream.Fs) (*upstream.Fs, error) {
+		upstreams, err := p.epall(ctx, upstreams, path)
+		if err != nil {
+			return nil, err
+		}
+		return p.rand(upstreams), nil
+	})
 }
 
 // SearchEntries is SEARCH category policy but receiving a set of candidate entries
@@ -79,5 +81,6 @@ func (p *Rand) SearchEntries(entries ...upstream.Entry) (upstream.Entry, error)
 	if len(entries) == 0 {
 		return nil, fs.ErrorObjectNotFound
 	}
+	entries = filterRAEntries(entries)
 	return p.randEntries(entries), nil
 }
@@ -0,0 +1,251 @@
+package union
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rclone/rclone/backend/union/upstream"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/walk"
+)
+
+// Ways of measuring how full upstreams are for rebalance
+const (
+	rebalanceByFree    = "free"    // free space
+	rebalanceByPercent = "percent" // free space as a percentage of the total space
+	rebalanceByUsed    = "used"    // used space
+	rebalanceByObjects = "objects" // number of files in the union
+)
+
+// rebalanceMove is a file moved by rebalance
+type rebalanceMove struct {
+	Remote string `json:"remote"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Size   int64  `json:"size"`
+}
+
+// rebalanceOutput is the output of the rebalance command
+type rebalanceOutput struct {
+	By     string          `json:"by"`
+	DryRun bool            `json:"dryRun"`
+	Moves  []rebalanceMove `json:"moves"`
+	Bytes  int64           `json:"bytes"`
+}
+
+// rebalanceUpstream is an upstream being rebalanced
+type rebalanceUpstream struct {
+	u       *upstream.Fs
+	name    string
+	objects []fs.Object // files which are only on this upstream, biggest first
+	count   int64       // number of files on this upstream
+	free    int64       // free space
+	used    int64       // used space
+	total   int64       // total space
+}
+
+// rebalanceBy returns how to measure the upstreams for the create
+// policy if by isn't set
+func (f *Fs) rebalanceBy(by string) (string, error) {
+	if by == "" {
+		switch strings.ToLower(f.opt.CreatePolicy) {
+		case "lno", "eplno":
+			by = rebalanceByObjects
+		case "lus", "eplus":
+			by = rebalanceByUsed
+		case "mfsp", "epmfsp":
+			by = rebalanceByPercent
+		default:
+			by = rebalanceByFree
+		}
+	}
+	switch by {
+	case rebalanceByFree, rebalanceByPercent, rebalanceByUsed, rebalanceByObjects:
+		return by, nil
+	}
+	return "", fmt.Errorf("unknown rebalance by %q - should be free, percent, used or objects", by)
+}
+
+// level returns how full r would be with size bytes and count files
+// more on it
+//
+// Rebalancing moves files from the upstreams with the highest levels
+// to those with the lowest.
+func (r *rebalanceUpstream) level(by string, size, count int64) float64 {
+	switch by {
+	case rebalanceByPercent:
+		return 1 - float64(r.free-size)/float64(r.total)
+	case rebalanceByUsed:
+		return float64(r.used + size)
+	case rebalanceByObjects:
+		return float64(r.count + count)
+	default:
+		return -float64(r.free - size)
+	}
+}
+
+// newRebalanceUpstream reads the usage of u and lists its files
+// which aren't on any of the other upstreams
+func (f *Fs) newRebalanceUpstream(ctx context.Context, u *upstream.Fs, by string, upstreams map[string]int) (r *rebalanceUpstream, err error) {
+	r = &rebalanceUpstream{
+		u:    u,
+		name: fs.ConfigString(u.Fs),
+	}
+	if by != rebalanceByObjects {
+		usage, err := u.About(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("can't rebalance by %s space as %s doesn't support about: %w", by, r.name, err)
+		}
+		switch {
+		case usage.Free == nil && by != rebalanceByUsed:
+			return nil, fmt.Errorf("can't rebalance by %s space as %s doesn't report its free space", by, r.name)
+		case usage.Used == nil && by == rebalanceByUsed:
+			return nil, fmt.Errorf("can't rebalance by used space as %s doesn't report it", r.name)
+		}
+		if usage.Free != nil {
+			r.free = *usage.Free
+		}
+		if usage.Used != nil {
+			r.used = *usage.Used
+		}
+		switch {
+		case usage.Total != nil:
+			r.total = *usage.Total
+		case usage.Used != nil:
+			r.total = r.free + r.used
+		}
+		if by == rebalanceByPercent && r.total <= 0 {
+			return nil, fmt.Errorf("can't rebalance by percent as %s doesn't report its total space", r.name)
+		}
+	}
+	err = walk.ListR(ctx, u.Fs, "", true, -1, walk.ListObjects, func(entries fs.DirEntries) error {
+		entries.ForObject(func(o fs.Object) {
+			r.objects = append(r.objects, o)
+			upstreams[o.Remote()]++
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", r.name, err)
+	}
+	r.count = int64(len(r.objects))
+	return r, nil
+}
+
+// pick returns the index of the file to move from r to dst to even
+// them out, or -1 if moving any of them would make it worse.
+func (r *rebalanceUpstream) pick(by string, dst *rebalanceUpstream) int {
+	if by == rebalanceByObjects {
+		// Move the smallest files as the size doesn't matter
+		if len(r.objects) > 0 && r.level(by, 0, -1) >= dst.level(by, 0, 1) {
+			return len(r.objects) - 1
+		}
+		return -1
+	}
+	// Move the biggest file which doesn't overshoot
+	for i, o := range r.objects {
+		size := o.Size()
+		if size > 0 && r.level(by, -size, -1) >= dst.level(by, size, 1) {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextRebalanceMove finds the next file to move, returning the index
+// of it in the objects of src, or -1 if there are no more to move.
+//
+// This moves from the fullest upstream it can to the emptiest one
+// which can be created on.
+func nextRebalanceMove(rs []*rebalanceUpstream, by string) (src, dst *rebalanceUpstream, i int) {
+	sort.SliceStable(rs, func(i, j int) bool {
+		return rs[i].level(by, 0, 0) > rs[j].level(by, 0, 0)
+	})
+	for s, src := range rs {
+		for d := len(rs) - 1; d > s; d-- {
+			dst := rs[d]
+			if !dst.u.IsCreatable() {
+				continue
+			}
+			if i := src.pick(by, dst); i >= 0 {
+				return src, dst, i
+			}
+		}
+	}
+	return nil, nil, -1
+}
+
+// rebalance moves files between the upstreams to even out how full
+// they are, measured by by
+func (f *Fs) rebalance(ctx context.Context, by string) (out *rebalanceOutput, err error) {
+	by, err = f.rebalanceBy(by)
+	if err != nil {
+		return nil, err
+	}
+	out = &rebalanceOutput{
+		By:     by,
+		DryRun: fs.GetConfig(ctx).DryRun,
+		Moves:  []rebalanceMove{},
+	}
+
+	// Find the upstreams files can be moved between
+	var rs []*rebalanceUpstream
+	upstreams := map[string]int{} // number of upstreams each file is on
+	for _, u := range f.upstreams {
+		if !u.IsWritable() {
+			continue
+		}
+		r, err := f.newRebalanceUpstream(ctx, u, by, upstreams)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	if len(rs) < 2 {
+		return nil, errors.New("need at least 2 writable upstreams to rebalance")
+	}
+
+	// Only move files which are on one upstream
+	for _, r := range rs {
+		objects := r.objects[:0]
+		for _, o := range r.objects {
+			if upstreams[o.Remote()] == 1 {
+				objects = append(objects, o)
+			}
+		}
+		r.objects = objects
+		sort.SliceStable(r.objects, func(i, j int) bool {
+			return r.objects[i].Size() > r.objects[j].Size()
+		})
+	}
+
+	for {
+		src, dst, i := nextRebalanceMove(rs, by)
+		if i < 0 {
+			break
+		}
+		o := src.objects[i]
+		src.objects = append(src.objects[:i], src.objects[i+1:]...)
+		size := o.Size()
+		fs.Infof(o, "Rebalancing from %s to %s", src.name, dst.name)
+		_, err = operations.Move(ctx, dst.u.Fs, nil, o.Remote(), o)
+		if err != nil {
+			return out, fmt.Errorf("failed to move %q from %s to %s: %w", o.Remote(), src.name, dst.name, err)
+		}
+		src.free, dst.free = src.free+size, dst.free-size
+		src.used, dst.used = src.used-size, dst.used+size
+		src.count, dst.count = src.count-1, dst.count+1
+		out.Moves = append(out.Moves, rebalanceMove{
+			Remote: o.Remote(),
+			From:   src.name,
+			To:     dst.name,
+			Size:   size,
+		})
+		out.Bytes += size
+	}
+	return out, nil
+}
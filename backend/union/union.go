@@ -17,10 +17,13 @@ import (
 	"github.com/rclone/rclone/backend/union/policy"
 	"github.com/rclone/rclone/backend/union/upstream"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fs/walk"
 )
 
@@ -30,6 +33,7 @@ func init() {
 		Name:        "union",
 		Description: "Union merges the contents of several upstream fs",
 		NewFs:       NewFs,
+		CommandHelp: commandHelp,
 		MetadataInfo: &fs.MetadataInfo{
 			Help: `Any metadata supported by the underlying remote is read and written.`,
 		},
@@ -55,28 +59,148 @@ func init() {
 			Default: 120,
 		}, {
 			Name: "min_free_space",
-			Help: `Minimum viable free space for lfs/eplfs policies.
+			Help: `Minimum viable free space for create policies.
 
 If a remote has less than this much free space then it won't be
-considered for use in lfs or eplfs policies.`,
+considered for creating files and directories on by any of the create
+policies. Remotes which don't report their free space are always
+considered.`,
 			Advanced: true,
 			Default:  fs.Gibi,
+		}, {
+			Name: "action_min_free_space",
+			Help: `Minimum viable free space for action policies.
+
+If a remote has less than this much free space then it won't be
+considered for changing files and directories on by any of the action
+policies. This is separate from min_free_space so files on remotes
+too full to create new ones on can still be renamed and updated.
+
+The default of 0 considers all remotes.`,
+			Advanced: true,
+			Default:  fs.SizeSuffix(0),
+		}, {
+			Name: "mirror_copies",
+			Help: `Number of upstreams mirror/epmirror policies create files on.
+
+New files are written to this many of the upstreams with the most
+free space at once, so each file is kept on more than one of them.`,
+			Advanced: true,
+			Default:  2,
+		}, {
+			Name: "policy_overrides",
+			Help: `Policies to use for some directory trees instead of the default ones.
+
+This is a comma separated list of dir:policy, to use policy for every
+category in dir, or dir:category=policy, to use it for just one of
+action, create or search. The directories are relative to the root of
+the union and the deepest one a path is in is used, for example
+
+    /media:epmfs,/backups:create=all,/backups/logs:create=ff`,
+			Advanced: true,
+			Default:  fs.CommaSepList{},
+		}, {
+			Name: "create_parent",
+			Help: `Make the parent directories on the upstream files are created on.
+
+If this is set, path preserving create policies which can't find the
+parent directory of a new file on any upstream choose one like the
+policy without "ep" does, e.g. mfs for epmfs, rather than using the
+upstream the nearest existing directory above it is on.
+
+The parent directories missing on the upstream chosen by a policy
+which doesn't preserve paths are then made with the modification time
+and metadata of the ones on the other upstreams, so the directory
+structure is the same on each upstream.`,
+			Default:  false,
+			Advanced: true,
+		}, {
+			Name: "health_check_interval",
+			Help: `How often to check the upstreams can be reached.
+
+If this is set then each upstream is checked this often by reading
+its usage, or if it can't do that by looking for a file. Upstreams
+which fail are left out of listings and aren't chosen by the policies
+until they pass again, so one unreachable upstream doesn't stop the
+whole union working. If all the upstreams fail they are all used.
+
+The default of 0 doesn't check the upstreams.`,
+			Default:  fs.Duration(0),
+			Advanced: true,
+		}, {
+			Name: "on_enospc",
+			Help: `What to do when an upstream is full while creating a file.
+
+If this is retry and the upstream chosen to create a file on runs out
+of space or quota, the file is written to the upstream the create
+policy chooses from the others instead. If the policy is path
+preserving and the path isn't on any of the others, the one with the
+most free space is used.
+
+This only works if the file being written can be read again, like
+when copying it from another remote. Files streamed with rclone rcat
+aren't retried.`,
+			Default:  onENOSPCFail,
+			Advanced: true,
+			Examples: []fs.OptionExample{{
+				Value: onENOSPCFail,
+				Help:  "Fail the write.",
+			}, {
+				Value: onENOSPCRetry,
+				Help:  "Write the file to another upstream.",
+			}},
 		}},
 	}
 	fs.Register(fsi)
+
+	rc.Add(rc.Call{
+		Path:  "union/refresh",
+		Fn:    rcRefresh,
+		Title: "Refresh the usage of the union upstreams",
+		Help: `
+The usage of the upstreams used by the path preserving policies is
+cached for cache_time seconds and kept up to date with the files
+written and deleted by rclone. This reads it again from all the
+upstreams now, which is useful if they have been changed outside
+rclone.
+
+It returns the usage of each upstream remote, e.g.
+
+    rclone rc union/refresh
+`,
+	})
+}
+
+// rcRefresh reads the usage of the upstreams again
+func rcRefresh(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	usages, err := upstream.RefreshUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return rc.Params{"usage": usages}, nil
 }
 
+// Values of the on_enospc option
+const (
+	onENOSPCFail  = "fail"
+	onENOSPCRetry = "retry"
+)
+
 // Fs represents a union of upstreams
 type Fs struct {
-	name         string         // name of this remote
-	features     *fs.Features   // optional features
-	opt          common.Options // options for this Fs
-	root         string         // the path we are working on
-	upstreams    []*upstream.Fs // slice of upstreams
-	hashSet      hash.Set       // intersection of hash types
-	actionPolicy policy.Policy  // policy for ACTION
-	createPolicy policy.Policy  // policy for CREATE
-	searchPolicy policy.Policy  // policy for SEARCH
+	name           string                       // name of this remote
+	features       *fs.Features                 // optional features
+	opt            common.Options               // options for this Fs
+	root           string                       // the path we are working on
+	upstreams      []*upstream.Fs               // slice of upstreams
+	hashSet        hash.Set                     // intersection of hash types
+	actionPolicy   policy.Policy                // policy for ACTION
+	createPolicy   policy.Policy                // policy for CREATE
+	searchPolicy   policy.Policy                // policy for SEARCH
+	overflowPolicy policy.Policy                // policy for CREATE when on_enospc retries and the path doesn't exist
+	overrides      []*policyOverride            // policies for directory trees, deepest first
+	healthCancel   func()                       // stops the health checks if set
+	selections     map[*upstream.Fs]*selections // times each upstream was chosen by the policies
 }
 
 // Wrap candidate objects in to a union Object
@@ -158,7 +282,7 @@ func (f *Fs) mkdir(ctx context.Context, dir string) ([]*upstream.Fs, error) {
 			upstreams, err = f.mkdir(ctx, parent)
 		} else if dir == "" {
 			// If root dirs not created then create them
-			upstreams, err = f.upstreams, nil
+			upstreams, err = f.healthyUpstreams(), nil
 		}
 	}
 	if err != nil {
@@ -548,6 +672,9 @@ func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, stream bo
 		} else {
 			o, err = u.Put(ctx, in, src, options...)
 		}
+		if err != nil {
+			u, o, err = f.putOverflow(ctx, in, src, stream, u, err, options...)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -603,6 +730,67 @@ func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options .
 	}
 }
 
+// putOverflow retries a put to u which failed with err on the next
+// upstream chosen by the create policy if on_enospc is retry and err
+// is because u is full.
+//
+// It returns the upstream the object was put on or the error.
+func (f *Fs) putOverflow(ctx context.Context, in io.Reader, src fs.ObjectInfo, stream bool, u *upstream.Fs, err error, options ...fs.OpenOption) (*upstream.Fs, fs.Object, error) {
+	if f.opt.OnENOSPC != onENOSPCRetry {
+		return u, nil, err
+	}
+	// The data already read can't be read again so read it from the source
+	srcObj := fs.UnWrapObjectInfo(src)
+	if unwrapper, ok := src.(fs.ObjectUnWrapper); ok && srcObj == nil {
+		// like fs.OverrideRemote which isn't an Object
+		srcObj = unwrapper.UnWrap()
+	}
+	if srcObj == nil {
+		return u, nil, err
+	}
+	_, wrap := accounting.UnWrap(in)
+	full := map[*upstream.Fs]bool{}
+	for fserrors.GetCategory(err) == fserrors.CategoryQuota {
+		full[u] = true
+		var others []*upstream.Fs
+		for _, other := range f.upstreams {
+			if !full[other] {
+				others = append(others, other)
+			}
+		}
+		others, filterErr := filterFreeSpace(others, f.opt.MinFreeSpace)
+		if filterErr != nil || len(others) == 0 {
+			break
+		}
+		remote := src.Remote()
+		upstreams, createErr := f.policyFor(remote, getCreatePolicy, f.createPolicy).Create(ctx, others, remote)
+		if createErr == fs.ErrorObjectNotFound {
+			// The path isn't on any of the others
+			upstreams, createErr = f.overflowPolicy.Create(ctx, others, remote)
+		}
+		if createErr != nil || len(upstreams) != 1 {
+			break
+		}
+		fs.Infof(src, "Upstream %s is full so writing to %s instead: %v", u.Name(), upstreams[0].Name(), err)
+		u = upstreams[0]
+		srcIn, openErr := srcObj.Open(ctx)
+		if openErr != nil {
+			return u, nil, fmt.Errorf("failed to open source to write to another upstream: %w", openErr)
+		}
+		var o fs.Object
+		if stream {
+			o, err = u.Features().PutStream(ctx, wrap(srcIn), src, options...)
+		} else {
+			o, err = u.Put(ctx, wrap(srcIn), src, options...)
+		}
+		fs.CheckClose(srcIn, &err)
+		if err == nil {
+			return u, o, nil
+		}
+	}
+	return u, nil, err
+}
+
 // PutStream uploads to the remote path with the modTime given of indeterminate size
 //
 // May create the object even if it returns an error - if so
@@ -682,10 +870,11 @@ func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
 // This should return ErrDirNotFound if the directory isn't
 // found.
 func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
-	entriesList := make([][]upstream.Entry, len(f.upstreams))
-	errs := Errors(make([]error, len(f.upstreams)))
-	multithread(len(f.upstreams), func(i int) {
-		u := f.upstreams[i]
+	upstreams := f.healthyUpstreams()
+	entriesList := make([][]upstream.Entry, len(upstreams))
+	errs := Errors(make([]error, len(upstreams)))
+	multithread(len(upstreams), func(i int) {
+		u := upstreams[i]
 		entries, err := u.List(ctx, dir)
 		if err != nil {
 			errs[i] = fmt.Errorf("%s: %w", u.Name(), err)
@@ -730,10 +919,11 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 // of listing recursively that doing a directory traversal.
 func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
 	var entriesList [][]upstream.Entry
-	errs := Errors(make([]error, len(f.upstreams)))
+	upstreams := f.healthyUpstreams()
+	errs := Errors(make([]error, len(upstreams)))
 	var mutex sync.Mutex
-	multithread(len(f.upstreams), func(i int) {
-		u := f.upstreams[i]
+	multithread(len(upstreams), func(i int) {
+		u := upstreams[i]
 		var err error
 		callback := func(entries fs.DirEntries) error {
 			uEntries := make([]upstream.Entry, len(entries))
@@ -777,10 +967,11 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 
 // NewObject creates a new remote union file object
 func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
-	objs := make([]*upstream.Object, len(f.upstreams))
-	errs := Errors(make([]error, len(f.upstreams)))
-	multithread(len(f.upstreams), func(i int) {
-		u := f.upstreams[i]
+	upstreams := f.healthyUpstreams()
+	objs := make([]*upstream.Object, len(upstreams))
+	errs := Errors(make([]error, len(upstreams)))
+	multithread(len(upstreams), func(i int) {
+		u := upstreams[i]
 		o, err := u.NewObject(ctx, remote)
 		if err != nil && err != fs.ErrorObjectNotFound {
 			errs[i] = fmt.Errorf("%s: %w", u.Name(), err)
@@ -816,19 +1007,129 @@ func (f *Fs) Precision() time.Duration {
 }
 
 func (f *Fs) action(ctx context.Context, path string) ([]*upstream.Fs, error) {
-	return f.actionPolicy.Action(ctx, f.upstreams, path)
+	p := f.policyFor(path, getActionPolicy, f.actionPolicy)
+	candidates, err := filterFreeSpace(f.healthyUpstreams(), f.opt.ActionMinFreeSpace)
+	if err != nil {
+		return nil, err
+	}
+	upstreams, err := p.Action(ctx, candidates, path)
+	f.recordDecision("action", p, path, candidates, upstreams, err)
+	return upstreams, err
 }
 
 func (f *Fs) actionEntries(entries ...upstream.Entry) ([]upstream.Entry, error) {
-	return f.actionPolicy.ActionEntries(entries...)
+	remote := entriesRemote(entries)
+	p := f.policyFor(remote, getActionPolicy, f.actionPolicy)
+	entries, err := filterFreeSpaceEntries(entries, f.opt.ActionMinFreeSpace)
+	if err != nil {
+		return nil, err
+	}
+	candidates := entriesUpstreams(entries)
+	entries, err = p.ActionEntries(entries...)
+	f.recordDecision("action", p, remote, candidates, entriesUpstreams(entries), err)
+	return entries, err
 }
 
 func (f *Fs) create(ctx context.Context, path string) ([]*upstream.Fs, error) {
-	return f.createPolicy.Create(ctx, f.upstreams, path)
+	p := f.policyFor(path, getCreatePolicy, f.createPolicy)
+	candidates, err := filterFreeSpace(f.healthyUpstreams(), f.opt.MinFreeSpace)
+	if err != nil {
+		return nil, err
+	}
+	upstreams, err := p.Create(ctx, candidates, path)
+	f.recordDecision("create", p, path, candidates, upstreams, err)
+	if !f.opt.CreateParent {
+		return upstreams, err
+	}
+	if err == fs.ErrorObjectNotFound {
+		// The parent isn't on any upstream so choose as if the
+		// policy didn't preserve paths
+		if np := policy.NotPathPreserving(p); np != nil {
+			p = np
+			upstreams, err = p.Create(ctx, candidates, path)
+			f.recordDecision("create", p, path, candidates, upstreams, err)
+		}
+	}
+	if err != nil || policy.PathPreserving(p) {
+		return upstreams, err
+	}
+	errs := Errors(make([]error, len(upstreams)))
+	multithread(len(upstreams), func(i int) {
+		errs[i] = f.cloneParents(ctx, upstreams[i], parentDir(path))
+	})
+	return upstreams, errs.Err()
 }
 
 func (f *Fs) searchEntries(entries ...upstream.Entry) (upstream.Entry, error) {
-	return f.searchPolicy.SearchEntries(entries...)
+	remote := entriesRemote(entries)
+	p := f.policyFor(remote, getSearchPolicy, f.searchPolicy)
+	candidates := entriesUpstreams(entries)
+	e, err := p.SearchEntries(entries...)
+	var chosen []*upstream.Fs
+	if e != nil {
+		chosen = []*upstream.Fs{e.UpstreamFs()}
+	}
+	f.recordDecision("search", p, remote, candidates, chosen, err)
+	return e, err
+}
+
+func getActionPolicy(o *policyOverride) policy.Policy { return o.actionPolicy }
+func getCreatePolicy(o *policyOverride) policy.Policy { return o.createPolicy }
+func getSearchPolicy(o *policyOverride) policy.Policy { return o.searchPolicy }
+
+// filterFreeSpace returns the upstreams which have at least minFree
+// free space or don't report it
+func filterFreeSpace(upstreams []*upstream.Fs, minFree fs.SizeSuffix) ([]*upstream.Fs, error) {
+	if minFree <= 0 || len(upstreams) == 0 {
+		return upstreams, nil
+	}
+	var free []*upstream.Fs
+	for _, u := range upstreams {
+		space, err := u.GetFreeSpace()
+		if err != nil || space >= int64(minFree) {
+			free = append(free, u)
+		}
+	}
+	if len(free) == 0 {
+		return nil, fserrors.NoRetryError(fmt.Errorf("no upstreams have at least %v free", minFree))
+	}
+	return free, nil
+}
+
+// filterFreeSpaceEntries returns the entries on upstreams which have
+// at least minFree free space or don't report it
+func filterFreeSpaceEntries(entries []upstream.Entry, minFree fs.SizeSuffix) ([]upstream.Entry, error) {
+	if minFree <= 0 || len(entries) == 0 {
+		return entries, nil
+	}
+	var free []upstream.Entry
+	for _, e := range entries {
+		space, err := e.UpstreamFs().GetFreeSpace()
+		if err != nil || space >= int64(minFree) {
+			free = append(free, e)
+		}
+	}
+	if len(free) == 0 {
+		return nil, fserrors.NoRetryError(fmt.Errorf("no upstreams have at least %v free", minFree))
+	}
+	return free, nil
+}
+
+// entriesUpstreams returns the upstreams of the entries
+func entriesUpstreams(entries []upstream.Entry) []*upstream.Fs {
+	upstreams := make([]*upstream.Fs, len(entries))
+	for i, e := range entries {
+		upstreams[i] = e.UpstreamFs()
+	}
+	return upstreams
+}
+
+// entriesRemote returns the remote of the candidate entries
+func entriesRemote(entries []upstream.Entry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+	return entries[0].Remote()
 }
 
 func (f *Fs) mergeDirEntries(entriesList [][]upstream.Entry) (fs.DirEntries, error) {
@@ -859,6 +1160,10 @@ func (f *Fs) mergeDirEntries(entriesList [][]upstream.Entry) (fs.DirEntries, err
 // Shutdown the backend, closing any background tasks and any
 // cached connections.
 func (f *Fs) Shutdown(ctx context.Context) error {
+	if f.healthCancel != nil {
+		f.healthCancel()
+	}
+	f.removeMetrics()
 	errs := Errors(make([]error, len(f.upstreams)))
 	multithread(len(f.upstreams), func(i int) {
 		u := f.upstreams[i]
@@ -972,6 +1277,26 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		return nil, err
 	}
 	fs.Debugf(f, "actionPolicy = %T, createPolicy = %T, searchPolicy = %T", f.actionPolicy, f.createPolicy, f.searchPolicy)
+	f.overrides, err = parsePolicyOverrides(opt.PolicyOverrides)
+	if err != nil {
+		return nil, err
+	}
+	switch opt.OnENOSPC {
+	case onENOSPCFail, onENOSPCRetry:
+	default:
+		return nil, fmt.Errorf("on_enospc must be %q or %q not %q", onENOSPCFail, onENOSPCRetry, opt.OnENOSPC)
+	}
+	if opt.MirrorCopies < 1 {
+		return nil, fmt.Errorf("mirror_copies must be at least 1 not %d", opt.MirrorCopies)
+	}
+	f.overflowPolicy, err = policy.Get("mfs")
+	if err != nil {
+		return nil, err
+	}
+	f.addMetrics()
+	if opt.HealthCheckInterval > 0 {
+		f.startHealthCheck(time.Duration(opt.HealthCheckInterval))
+	}
 	var features = (&fs.Features{
 		CaseInsensitive:          true,
 		DuplicateFiles:           false,
@@ -1007,6 +1332,10 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	// If any of upstreams are SlowHash, propagate it
 	features.SlowHash = slowHash
 
+	// Always Shutdown as the union has its own health check and
+	// stats to stop even if the upstreams don't need it
+	features.Shutdown = f.Shutdown
+
 	// Enable ListR when upstreams either support ListR or is local
 	// But not when all upstreams are local
 	if features.ListR == nil {
@@ -1038,6 +1367,59 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	return f, fserr
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "rebalance",
+	Short: "Move files between the upstreams to even them out.",
+	Long: `This moves files from the fullest upstreams to the emptiest ones
+until moving any more wouldn't make them more even.
+
+    rclone backend rebalance union:
+
+How full the upstreams are is measured to suit the create policy, by
+the number of files for lno and eplno, by the used space for lus and
+eplus, by the percentage of free space for mfsp and epmfsp and by the
+free space for the others. Use "-o by=" with free, percent, used or
+objects to choose another.
+
+Only files in the union which are on just one upstream are moved, to
+upstreams which can be written to and created on. Filters can be used
+to choose which files are moved.
+
+Use --dry-run or "-o dry-run" to see which files would be moved
+without moving them. The files are moved like "rclone move" so
+--bwlimit and --progress can be used.
+
+This returns the files moved and their total size.
+`,
+	Opts: map[string]string{
+		"by":      "How to measure how full the upstreams are: free, percent, used or objects.",
+		"dry-run": "Show which files would be moved without moving them.",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "rebalance":
+		if _, ok := opt["dry-run"]; ok {
+			var ci *fs.ConfigInfo
+			ctx, ci = fs.AddConfig(ctx)
+			ci.DryRun = true
+		}
+		return f.rebalance(ctx, opt["by"])
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
 func parentDir(absPath string) string {
 	parent := path.Dir(strings.TrimRight(filepath.ToSlash(absPath), "/"))
 	if parent == "." {
@@ -1075,4 +1457,5 @@ var (
 	_ fs.ListRer         = (*Fs)(nil)
 	_ fs.Shutdowner      = (*Fs)(nil)
 	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
 )
@@ -3,14 +3,24 @@ package union
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"runtime"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rclone/rclone/backend/union/policy"
+	"github.com/rclone/rclone/backend/union/upstream"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/object"
 	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/fstests"
 	"github.com/rclone/rclone/lib/random"
@@ -163,3 +173,542 @@ func TestMoveCopy(t *testing.T) {
 		})
 	})
 }
+
+func TestParsePolicyOverrides(t *testing.T) {
+	overrides, err := parsePolicyOverrides([]string{"/media:epmfs", "/backups:create=all", "backups/logs/:create=ff", "backups:search=epff", "/:action=epall"})
+	require.NoError(t, err)
+	var dirs []string
+	for _, o := range overrides {
+		dirs = append(dirs, o.dir)
+	}
+	assert.Equal(t, []string{"backups/logs", "media", "backups", ""}, dirs)
+	assert.IsType(t, &policy.EpMfs{}, overrides[1].actionPolicy)
+	assert.IsType(t, &policy.EpMfs{}, overrides[1].createPolicy)
+	assert.IsType(t, &policy.EpMfs{}, overrides[1].searchPolicy)
+	assert.IsType(t, &policy.All{}, overrides[2].createPolicy)
+	assert.IsType(t, &policy.EpFF{}, overrides[2].searchPolicy)
+	assert.Nil(t, overrides[2].actionPolicy)
+
+	f := &Fs{root: "backups", overrides: overrides}
+	def := &policy.Rand{}
+	assert.IsType(t, &policy.FF{}, f.policyFor("logs/today", getCreatePolicy, def))
+	assert.IsType(t, &policy.All{}, f.policyFor("logsfile", getCreatePolicy, def))
+	assert.IsType(t, &policy.EpFF{}, f.policyFor("logs/today", getSearchPolicy, def))
+	assert.IsType(t, &policy.EpAll{}, f.policyFor("logs/today", getActionPolicy, def))
+	f.root = ""
+	assert.IsType(t, &policy.Rand{}, f.policyFor("other", getCreatePolicy, def))
+
+	for _, bad := range []string{"media", "/media:potato", "/media:delete=ff"} {
+		_, err = parsePolicyOverrides([]string{bad})
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestPolicyOverrides(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	dirs := MakeTestDirs(t, 2)
+	fsString := fmt.Sprintf(":union,upstreams='%s %s',create_policy=ff,policy_overrides='/both:create=all':", dirs[0], dirs[1])
+	f, err := fs.NewFs(ctx, fsString)
+	require.NoError(t, err)
+
+	for _, remote := range []string{"one/file.txt", "both/file.txt"} {
+		contents := random.String(10)
+		item := fstest.NewItem(remote, contents, time.Now())
+		_ = fstests.PutTestContents(ctx, t, f, &item, contents, true)
+	}
+	exists := func(dir, remote string) bool {
+		_, err := os.Stat(filepath.Join(dir, remote))
+		return err == nil
+	}
+	assert.True(t, exists(dirs[0], "one/file.txt"))
+	assert.False(t, exists(dirs[1], "one/file.txt"))
+	assert.True(t, exists(dirs[0], "both/file.txt"))
+	assert.True(t, exists(dirs[1], "both/file.txt"))
+}
+
+func TestReadAfter(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	dirs := MakeTestDirs(t, 2)
+	for dir, files := range map[string]map[string]string{
+		dirs[0]: {"both.txt": "archive", "archive.txt": "archive"},
+		dirs[1]: {"both.txt": "main"},
+	} {
+		for name, contents := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0666))
+		}
+	}
+	fsString := fmt.Sprintf(":union,upstreams='%s:ra %s':", dirs[0], dirs[1])
+	f, err := fs.NewFs(ctx, fsString)
+	require.NoError(t, err)
+
+	read := func(remote string) string {
+		o, err := f.NewObject(ctx, remote)
+		require.NoError(t, err)
+		return fstests.ReadObject(ctx, t, o, -1)
+	}
+	assert.Equal(t, "main", read("both.txt"))
+	assert.Equal(t, "archive", read("archive.txt"))
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	// New files are never created on the read after upstream
+	contents := random.String(10)
+	item := fstest.NewItem("new.txt", contents, time.Now())
+	_ = fstests.PutTestContents(ctx, t, f, &item, contents, true)
+	_, err = os.Stat(filepath.Join(dirs[0], "new.txt"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dirs[1], "new.txt"))
+	assert.NoError(t, err)
+}
+
+func TestUsageCache(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping as free space on windows is rounded")
+	}
+	ctx := context.Background()
+	dirs := MakeTestDirs(t, 2)
+	fsString := fmt.Sprintf(":union,upstreams='%s %s',create_policy=ff:", dirs[0], dirs[1])
+	f1, err := fs.NewFs(ctx, fsString)
+	require.NoError(t, err)
+	f2, err := fs.NewFs(ctx, fsString+"dir")
+	require.NoError(t, err)
+	u1 := f1.(*Fs).upstreams[0]
+	u2 := f2.(*Fs).upstreams[0]
+	free, err := u1.GetFreeSpace()
+	require.NoError(t, err)
+
+	// Writes and deletes update the cache of both unions
+	contents := random.String(1000)
+	item := fstest.NewItem("file.txt", contents, time.Now())
+	o := fstests.PutTestContents(ctx, t, f1, &item, contents, true)
+	for _, u := range []*upstream.Fs{u1, u2} {
+		got, err := u.GetFreeSpace()
+		require.NoError(t, err)
+		assert.Equal(t, free-1000, got)
+	}
+	require.NoError(t, o.Remove(ctx))
+	got, err := u2.GetFreeSpace()
+	require.NoError(t, err)
+	assert.Equal(t, free, got)
+
+	// Refreshing reads the usage of all the upstreams
+	usages, err := upstream.RefreshUsage(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, usages, fs.ConfigString(u1.RootFs))
+	assert.Contains(t, usages, fs.ConfigString(f1.(*Fs).upstreams[1].RootFs))
+}
+
+func TestMirror(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	dirs := MakeTestDirs(t, 3)
+	fsString := fmt.Sprintf(":union,upstreams='%s %s %s',create_policy=mirror,mirror_copies=2:", dirs[0], dirs[1], dirs[2])
+	f, err := fs.NewFs(ctx, fsString)
+	require.NoError(t, err)
+
+	contents := random.String(10)
+	item := fstest.NewItem("dir/file.txt", contents, time.Now())
+	o := fstests.PutTestContents(ctx, t, f, &item, contents, true)
+	copies := func() (n int) {
+		for _, dir := range dirs {
+			if _, err := os.Stat(filepath.Join(dir, "dir", "file.txt")); err == nil {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Equal(t, 2, copies())
+
+	// Removing the file removes all the copies
+	require.NoError(t, o.Remove(ctx))
+	assert.Equal(t, 0, copies())
+
+	_, err = fs.NewFs(ctx, fmt.Sprintf(":union,upstreams='%s %s',mirror_copies=0:", dirs[0], dirs[1]))
+	assert.Error(t, err)
+}
+
+func TestDecisions(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	dirs := MakeTestDirs(t, 2)
+	fsString := fmt.Sprintf(":union,upstreams='%s %s',create_policy=mfs:", dirs[0], dirs[1])
+	f, err := fs.NewFs(ctx, fsString)
+	require.NoError(t, err)
+
+	contents := random.String(10)
+	item := fstest.NewItem("decisions/file.txt", contents, time.Now())
+	_ = fstests.PutTestContents(ctx, t, f, &item, contents, true)
+
+	call := rc.Calls.Get("union/decisions")
+	require.NotNil(t, call)
+	out, err := call.Fn(ctx, rc.Params{"remote": "decisions/"})
+	require.NoError(t, err)
+	list := out["decisions"].([]decision)
+	require.NotEmpty(t, list)
+	var d *decision
+	for i := range list {
+		if list[i].Category == "create" && list[i].Union == fs.ConfigString(f) {
+			d = &list[i]
+		}
+	}
+	require.NotNil(t, d)
+	assert.Equal(t, "mfs", d.Policy)
+	assert.Equal(t, "decisions/file.txt", d.Remote)
+	assert.Len(t, d.Candidates, 2)
+	assert.Len(t, d.Chosen, 1)
+	assert.NotNil(t, d.Candidates[0].Free)
+
+	out, err = call.Fn(ctx, rc.Params{"n": 1})
+	require.NoError(t, err)
+	assert.Len(t, out["decisions"], 1)
+}
+
+// deadFs is an fs.Fs which fails to list or read its usage while dead
+// is set
+type deadFs struct {
+	fs.Fs
+	dead *atomic.Bool
+}
+
+var errDead = errors.New("upstream is dead")
+
+func (f *deadFs) Features() *fs.Features {
+	ft := *f.Fs.Features()
+	ft.About = f.About
+	return &ft
+}
+
+func (f *deadFs) About(ctx context.Context) (*fs.Usage, error) {
+	if f.dead.Load() {
+		return nil, errDead
+	}
+	return f.Fs.Features().About(ctx)
+}
+
+func (f *deadFs) List(ctx context.Context, dir string) (fs.DirEntries, error) {
+	if f.dead.Load() {
+		return nil, errDead
+	}
+	return f.Fs.List(ctx, dir)
+}
+
+func TestHealthCheck(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	dirs := MakeTestDirs(t, 2)
+	for i, dir := range dirs {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.txt", i)), []byte("hello"), 0666))
+	}
+	fsString := fmt.Sprintf(":union,upstreams='%s %s',create_policy=all,health_check_interval=1h:", dirs[0], dirs[1])
+	fsys, err := fs.NewFs(ctx, fsString)
+	require.NoError(t, err)
+	f := fsys.(*Fs)
+	defer func() {
+		require.NoError(t, f.Shutdown(ctx))
+	}()
+	var dead atomic.Bool
+	u := f.upstreams[1]
+	u.Fs = &deadFs{Fs: u.Fs, dead: &dead}
+	u.RootFs = &deadFs{Fs: u.RootFs, dead: &dead}
+
+	list := func() (names []string) {
+		entries, err := f.List(ctx, "")
+		require.NoError(t, err)
+		for _, e := range entries {
+			names = append(names, e.Remote())
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"file0.txt", "file1.txt"}, list())
+
+	// A dead upstream is left out once it has been checked
+	dead.Store(true)
+	f.checkHealth(ctx)
+	assert.False(t, u.IsHealthy())
+	assert.Equal(t, []string{"file0.txt"}, list())
+	contents := random.String(10)
+	item := fstest.NewItem("new.txt", contents, time.Now())
+	_ = fstests.PutTestContents(ctx, t, f, &item, contents, true)
+	_, err = os.Stat(filepath.Join(dirs[1], "new.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// It is used again once it recovers
+	dead.Store(false)
+	f.checkHealth(ctx)
+	assert.True(t, u.IsHealthy())
+	assert.ElementsMatch(t, []string{"file0.txt", "file1.txt", "new.txt"}, list())
+}
+
+func TestCreateParent(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	dirs := MakeTestDirs(t, 2)
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	require.NoError(t, os.MkdirAll(filepath.Join(dirs[0], "a", "b"), 0777))
+	for _, dir := range []string{"a/b", "a"} {
+		require.NoError(t, os.Chtimes(filepath.Join(dirs[0], dir), modTime, modTime))
+	}
+	fsString := fmt.Sprintf(":union,upstreams='%s %s',create_policy=all,create_parent=true:", dirs[0], dirs[1])
+	f, err := fs.NewFs(ctx, fsString)
+	require.NoError(t, err)
+
+	// The parent directories are cloned onto the other upstream
+	contents := random.String(10)
+	item := fstest.NewItem("a/b/file.txt", contents, time.Now())
+	_ = fstests.PutTestContents(ctx, t, f, &item, contents, true)
+	fi, err := os.Stat(filepath.Join(dirs[1], "a"))
+	require.NoError(t, err)
+	assert.True(t, modTime.Equal(fi.ModTime()), "modtime %v", fi.ModTime())
+	_, err = os.Stat(filepath.Join(dirs[1], "a", "b", "file.txt"))
+	assert.NoError(t, err)
+
+	// Path preserving policies choose an upstream if the parent isn't on any
+	fsString = fmt.Sprintf(":union,upstreams='%s %s',create_policy=epff,create_parent=true:", dirs[0], dirs[1])
+	f, err = fs.NewFs(ctx, fsString)
+	require.NoError(t, err)
+	item = fstest.NewItem("new/dir/file.txt", contents, time.Now())
+	_ = fstests.PutTestContents(ctx, t, f, &item, contents, true)
+	found := 0
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, "new", "dir", "file.txt")); err == nil {
+			found++
+		}
+	}
+	assert.Equal(t, 1, found)
+}
+
+func TestMetrics(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	dirs := MakeTestDirs(t, 2)
+	fsString := fmt.Sprintf(":union,upstreams='%s %s',create_policy=all:", dirs[0], dirs[1])
+	f, err := fs.NewFs(ctx, fsString)
+	require.NoError(t, err)
+	name := fs.ConfigString(f)
+
+	contents := random.String(10)
+	item := fstest.NewItem("file.txt", contents, time.Now())
+	_ = fstests.PutTestContents(ctx, t, f, &item, contents, true)
+
+	// Stats are added to core/stats
+	call := rc.Calls.Get("core/stats")
+	require.NotNil(t, call)
+	out, err := call.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	var stats []upstreamStats
+	for _, s := range out["union"].([]upstreamStats) {
+		if s.Union == name {
+			stats = append(stats, s)
+		}
+	}
+	require.Len(t, stats, 2)
+	for _, s := range stats {
+		assert.Equal(t, int64(10), s.BytesWritten)
+		assert.Equal(t, int64(1), s.FilesCreated)
+		assert.Equal(t, int64(1), s.Selections["create"])
+	}
+
+	// And can be gathered by Prometheus
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(newUnionCollector())
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	found := 0
+	for _, family := range families {
+		if family.GetName() != "rclone_union_files_created_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "union" && label.GetValue() == name {
+					assert.Equal(t, 1.0, metric.GetCounter().GetValue())
+					found++
+				}
+			}
+		}
+	}
+	assert.Equal(t, 2, found)
+
+	// Shutdown removes the stats
+	require.NoError(t, f.Features().Shutdown(ctx))
+	for _, s := range unionStats() {
+		assert.NotEqual(t, name, s.Union)
+	}
+}
+
+func TestMinFreeSpace(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	dirs := MakeTestDirs(t, 2)
+	newFs := func(opts string) fs.Fs {
+		f, err := fs.NewFs(ctx, fmt.Sprintf(":union,upstreams='%s %s',create_policy=ff,%s:", dirs[0], dirs[1], opts))
+		require.NoError(t, err)
+		return f
+	}
+	contents := random.String(10)
+	put := func(f fs.Fs, remote string) (fs.Object, error) {
+		src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, nil, nil)
+		return f.Put(ctx, bytes.NewBufferString(contents), src)
+	}
+
+	// Files are created when the upstreams have enough free space
+	o, err := put(newFs("min_free_space=0"), "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "file.txt", o.Remote())
+
+	// Creating fails with any create policy if no upstream has
+	f := newFs("min_free_space=1P")
+	_, err = put(f, "other.txt")
+	assert.ErrorContains(t, err, "no upstreams have at least 1Pi free")
+
+	// but changing existing files doesn't
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	src := object.NewStaticObjectInfo("file.txt", time.Now(), int64(len(contents)), true, nil, nil)
+	require.NoError(t, o.Update(ctx, bytes.NewBufferString(contents), src))
+
+	// unless action_min_free_space is set too
+	f = newFs("min_free_space=1P,action_min_free_space=1P")
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	err = o.Update(ctx, bytes.NewBufferString(contents), src)
+	assert.ErrorContains(t, err, "no upstreams have at least 1Pi free")
+}
+
+func TestRebalance(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	dirs := MakeTestDirs(t, 2)
+	require.NoError(t, os.MkdirAll(filepath.Join(dirs[0], "dir"), 0777))
+	for _, name := range []string{"a.txt", "dir/b.txt", "c.txt", "d.txt", "shared.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dirs[0], name), []byte(name), 0666))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dirs[1], "shared.txt"), []byte("shared"), 0666))
+	countFiles := func(dir string) (n int) {
+		require.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				n++
+			}
+			return err
+		}))
+		return n
+	}
+
+	fsString := fmt.Sprintf(":union,upstreams='%s %s',create_policy=lno:", dirs[0], dirs[1])
+	f, err := fs.NewFs(ctx, fsString)
+	require.NoError(t, err)
+	do := f.Features().Command
+
+	_, err = do(ctx, "rebalance", nil, map[string]string{"by": "potato"})
+	assert.ErrorContains(t, err, "unknown rebalance by")
+
+	out, err := do(ctx, "rebalance", nil, map[string]string{"dry-run": ""})
+	require.NoError(t, err)
+	result := out.(*rebalanceOutput)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, "objects", result.By)
+	assert.Len(t, result.Moves, 2)
+	assert.Equal(t, 5, countFiles(dirs[0]))
+	assert.Equal(t, 1, countFiles(dirs[1]))
+
+	out, err = do(ctx, "rebalance", nil, nil)
+	require.NoError(t, err)
+	result = out.(*rebalanceOutput)
+	assert.False(t, result.DryRun)
+	require.Len(t, result.Moves, 2)
+	assert.NotEqual(t, "shared.txt", result.Moves[0].Remote)
+	assert.NotEqual(t, "shared.txt", result.Moves[1].Remote)
+	assert.Equal(t, 3, countFiles(dirs[0]))
+	assert.Equal(t, 3, countFiles(dirs[1]))
+
+	// Already balanced
+	out, err = do(ctx, "rebalance", nil, nil)
+	require.NoError(t, err)
+	assert.Len(t, out.(*rebalanceOutput).Moves, 0)
+}
+
+// fullFs is an fs.Fs which is full for the first put to any of them
+type fullFs struct {
+	fs.Fs
+	puts *atomic.Int32
+}
+
+func (f *fullFs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	if f.puts.Add(1) == 1 {
+		_, _ = io.CopyN(io.Discard, in, 3)
+		return nil, fmt.Errorf("write: %w", syscall.ENOSPC)
+	}
+	return f.Fs.Put(ctx, in, src, options...)
+}
+
+func TestOnENOSPC(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	dirs := MakeTestDirs(t, 3)
+	fSrc, err := fs.NewFs(ctx, dirs[2])
+	require.NoError(t, err)
+	contents := random.String(50)
+	item := fstest.NewItem("file.txt", contents, time.Now())
+	src := fstests.PutTestContents(ctx, t, fSrc, &item, contents, true)
+
+	newUnion := func(onENOSPC string) (*Fs, *atomic.Int32) {
+		fsString := fmt.Sprintf(":union,upstreams='%s %s',create_policy=mfs,on_enospc=%s:", dirs[0], dirs[1], onENOSPC)
+		f, err := fs.NewFs(ctx, fsString)
+		require.NoError(t, err)
+		unionFs := f.(*Fs)
+		var puts atomic.Int32
+		for _, u := range unionFs.upstreams {
+			u.Fs = &fullFs{Fs: u.Fs, puts: &puts}
+		}
+		return unionFs, &puts
+	}
+
+	_, err = fs.NewFs(ctx, fmt.Sprintf(":union,upstreams='%s %s',on_enospc=potato:", dirs[0], dirs[1]))
+	assert.ErrorContains(t, err, "on_enospc must be")
+
+	f, _ := newUnion("fail")
+	_, err = operations.Copy(ctx, f, nil, "file.txt", src)
+	assert.ErrorIs(t, err, syscall.ENOSPC)
+
+	f, puts := newUnion("retry")
+	o, err := operations.Copy(ctx, f, nil, "file.txt", src)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), puts.Load())
+	assert.Equal(t, int64(50), o.Size())
+	var found int
+	for _, dir := range dirs[:2] {
+		data, err := os.ReadFile(filepath.Join(dir, "file.txt"))
+		if err == nil {
+			found++
+			assert.Equal(t, contents, string(data))
+		}
+	}
+	assert.Equal(t, 1, found)
+}
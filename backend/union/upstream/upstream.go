@@ -8,7 +8,6 @@ import (
 	"io"
 	"math"
 	"strings"
-	"sync"
 	"sync/atomic"
 	"time"
 
@@ -19,6 +18,10 @@ import (
 	"github.com/rclone/rclone/fs/operations"
 )
 
+// healthCheckProbe is the object looked for to check the health of
+// upstreams which can't read their usage
+const healthCheckProbe = ".rclone-union-health-check"
+
 var (
 	// ErrUsageFieldNotSupported stats the usage field is not supported by the backend
 	ErrUsageFieldNotSupported = errors.New("this usage field is not supported")
@@ -32,14 +35,14 @@ type Fs struct {
 	Opt         *common.Options
 	writable    bool
 	creatable   bool
-	usage       *fs.Usage     // Cache the usage
+	readAfter   bool          // only read from if no other upstream has the file
+	cache       *usageCache   // cache of the usage of RootFs
 	cacheTime   time.Duration // cache duration
-	cacheExpiry atomic.Int64  // usage cache expiry time
-	cacheMutex  sync.RWMutex
-	cacheOnce   sync.Once
-	cacheUpdate bool // if the cache is updating
-	writeback   bool // writeback to this upstream
-	writebackFs *Fs  // if non zero, writeback to this upstream
+	unhealthy   atomic.Bool   // set if the last health check failed
+	written     atomic.Int64  // bytes written to the fs
+	created     atomic.Int64  // files created on the fs
+	writeback   bool          // writeback to this upstream
+	writebackFs *Fs           // if non zero, writeback to this upstream
 }
 
 // Directory describes a wrapped Directory
@@ -66,7 +69,7 @@ type Entry interface {
 }
 
 // New creates a new Fs based on the
-// string formatted `type:root_path(:ro/:nc)`
+// string formatted `type:root_path(:ro/:nc/:ra)`
 func New(ctx context.Context, remote, root string, opt *common.Options) (*Fs, error) {
 	configName, fsPath, err := fspath.SplitFs(remote)
 	if err != nil {
@@ -78,9 +81,7 @@ func New(ctx context.Context, remote, root string, opt *common.Options) (*Fs, er
 		writable:  true,
 		creatable: true,
 		cacheTime: time.Duration(opt.CacheTime) * time.Second,
-		usage:     &fs.Usage{},
 	}
-	f.cacheExpiry.Store(time.Now().Unix())
 	if strings.HasSuffix(fsPath, ":ro") {
 		f.writable = false
 		f.creatable = false
@@ -89,6 +90,11 @@ func New(ctx context.Context, remote, root string, opt *common.Options) (*Fs, er
 		f.writable = true
 		f.creatable = false
 		fsPath = fsPath[0 : len(fsPath)-3]
+	} else if strings.HasSuffix(fsPath, ":ra") {
+		f.writable = false
+		f.creatable = false
+		f.readAfter = true
+		fsPath = fsPath[0 : len(fsPath)-3]
 	} else if strings.HasSuffix(fsPath, ":writeback") {
 		f.writeback = true
 		fsPath = fsPath[0 : len(fsPath)-len(":writeback")]
@@ -99,6 +105,7 @@ func New(ctx context.Context, remote, root string, opt *common.Options) (*Fs, er
 		return nil, err
 	}
 	f.RootFs = rFs
+	f.cache = getUsageCache(rFs)
 	rootString := fspath.JoinRootPath(remote, root)
 	myFs, err := cache.Get(ctx, rootString)
 	if err != nil && err != fs.ErrorIsFile {
@@ -190,6 +197,50 @@ func (f *Fs) IsCreatable() bool {
 	return f.creatable
 }
 
+// IsReadAfter return if the fs is only read from when no other
+// upstream has the file
+func (f *Fs) IsReadAfter() bool {
+	return f.readAfter
+}
+
+// IsHealthy return if the last health check of the fs succeeded
+//
+// It is true if the fs hasn't been checked.
+func (f *Fs) IsHealthy() bool {
+	return !f.unhealthy.Load()
+}
+
+// CheckHealth checks the fs can be reached, by reading its usage or
+// if that isn't supported by looking for an object which doesn't
+// exist. It returns whether IsHealthy changed and the error if the
+// check failed.
+func (f *Fs) CheckHealth(ctx context.Context) (changed bool, err error) {
+	if do := f.RootFs.Features().About; do != nil {
+		_, err = do(ctx)
+	} else {
+		_, err = f.RootFs.NewObject(ctx, healthCheckProbe)
+		if errors.Is(err, fs.ErrorObjectNotFound) || errors.Is(err, fs.ErrorIsDir) {
+			err = nil
+		}
+	}
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		err = nil
+	}
+	return f.unhealthy.Swap(err != nil) != (err != nil), err
+}
+
+// BytesWritten returns the number of bytes written to the fs by
+// Put, PutStream and Update
+func (f *Fs) BytesWritten() int64 {
+	return f.written.Load()
+}
+
+// FilesCreated returns the number of files created on the fs by Put
+// and PutStream
+func (f *Fs) FilesCreated() int64 {
+	return f.created.Load()
+}
+
 // IsWritable return if the fs is allowed to write
 func (f *Fs) IsWritable() bool {
 	return f.writable
@@ -205,18 +256,9 @@ func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options .
 	if err != nil {
 		return o, err
 	}
-	f.cacheMutex.Lock()
-	defer f.cacheMutex.Unlock()
-	size := src.Size()
-	if f.usage.Used != nil {
-		*f.usage.Used += size
-	}
-	if f.usage.Free != nil {
-		*f.usage.Free -= size
-	}
-	if f.usage.Objects != nil {
-		*f.usage.Objects++
-	}
+	f.cache.add(src.Size(), 1)
+	f.written.Add(o.Size())
+	f.created.Add(1)
 	return o, nil
 }
 
@@ -234,18 +276,9 @@ func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, opt
 	if err != nil {
 		return o, err
 	}
-	f.cacheMutex.Lock()
-	defer f.cacheMutex.Unlock()
-	size := o.Size()
-	if f.usage.Used != nil {
-		*f.usage.Used += size
-	}
-	if f.usage.Free != nil {
-		*f.usage.Free -= size
-	}
-	if f.usage.Objects != nil {
-		*f.usage.Objects++
-	}
+	f.cache.add(o.Size(), 1)
+	f.written.Add(o.Size())
+	f.created.Add(1)
 	return o, nil
 }
 
@@ -260,18 +293,19 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	if err != nil {
 		return err
 	}
-	o.f.cacheMutex.Lock()
-	defer o.f.cacheMutex.Unlock()
-	delta := o.Size() - size
-	if delta <= 0 {
-		return nil
-	}
-	if o.f.usage.Used != nil {
-		*o.f.usage.Used += size
-	}
-	if o.f.usage.Free != nil {
-		*o.f.usage.Free -= size
+	o.f.cache.add(o.Size()-size, 0)
+	o.f.written.Add(o.Size())
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	size := o.Size()
+	err := o.Object.Remove(ctx)
+	if err != nil {
+		return err
 	}
+	o.f.cache.add(-size, -1)
 	return nil
 }
 
@@ -390,113 +424,73 @@ func (o *Object) Writeback(ctx context.Context) (*Object, error) {
 
 // About gets quota information from the Fs
 func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
-	if f.cacheExpiry.Load() <= time.Now().Unix() {
-		err := f.updateUsage()
-		if err != nil {
-			return nil, ErrUsageFieldNotSupported
-		}
+	usage, err := f.cache.get(f.cacheTime)
+	if err != nil {
+		return nil, ErrUsageFieldNotSupported
 	}
-	f.cacheMutex.RLock()
-	defer f.cacheMutex.RUnlock()
-	return f.usage, nil
+	return usage, nil
+}
+
+// CachedUsage returns the usage of the fs as last read, without
+// reading it from the remote
+func (f *Fs) CachedUsage() *fs.Usage {
+	return f.cache.peek()
 }
 
 // GetFreeSpace get the free space of the fs
 //
 // This is returned as 0..math.MaxInt64-1 leaving math.MaxInt64 as a sentinel
 func (f *Fs) GetFreeSpace() (int64, error) {
-	if f.cacheExpiry.Load() <= time.Now().Unix() {
-		err := f.updateUsage()
-		if err != nil {
-			return math.MaxInt64 - 1, ErrUsageFieldNotSupported
-		}
-	}
-	f.cacheMutex.RLock()
-	defer f.cacheMutex.RUnlock()
-	if f.usage.Free == nil {
+	usage, err := f.cache.get(f.cacheTime)
+	if err != nil || usage.Free == nil {
 		return math.MaxInt64 - 1, ErrUsageFieldNotSupported
 	}
-	return *f.usage.Free, nil
+	return *usage.Free, nil
+}
+
+// GetFreeSpacePercent get the free space of the fs as a percentage
+// of its total space
+//
+// If the total space isn't known it is worked out from the free and
+// used space. This is returned as 0..100, with 100 if it isn't known.
+func (f *Fs) GetFreeSpacePercent() (float64, error) {
+	usage, err := f.cache.get(f.cacheTime)
+	if err != nil || usage.Free == nil {
+		return 100, ErrUsageFieldNotSupported
+	}
+	var total int64
+	switch {
+	case usage.Total != nil:
+		total = *usage.Total
+	case usage.Used != nil:
+		total = *usage.Free + *usage.Used
+	default:
+		return 100, ErrUsageFieldNotSupported
+	}
+	if total <= 0 {
+		return 100, ErrUsageFieldNotSupported
+	}
+	return 100 * float64(max(*usage.Free, 0)) / float64(total), nil
 }
 
 // GetUsedSpace get the used space of the fs
 //
 // This is returned as 0..math.MaxInt64-1 leaving math.MaxInt64 as a sentinel
 func (f *Fs) GetUsedSpace() (int64, error) {
-	if f.cacheExpiry.Load() <= time.Now().Unix() {
-		err := f.updateUsage()
-		if err != nil {
-			return 0, ErrUsageFieldNotSupported
-		}
-	}
-	f.cacheMutex.RLock()
-	defer f.cacheMutex.RUnlock()
-	if f.usage.Used == nil {
+	usage, err := f.cache.get(f.cacheTime)
+	if err != nil || usage.Used == nil {
 		return 0, ErrUsageFieldNotSupported
 	}
-	return *f.usage.Used, nil
+	return *usage.Used, nil
 }
 
 // GetNumObjects get the number of objects of the fs
 func (f *Fs) GetNumObjects() (int64, error) {
-	if f.cacheExpiry.Load() <= time.Now().Unix() {
-		err := f.updateUsage()
-		if err != nil {
-			return 0, ErrUsageFieldNotSupported
-		}
-	}
-	f.cacheMutex.RLock()
-	defer f.cacheMutex.RUnlock()
-	if f.usage.Objects == nil {
+	usage, err := f.cache.get(f.cacheTime)
+	if err != nil || usage.Objects == nil {
 		return 0, ErrUsageFieldNotSupported
 	}
-	return *f.usage.Objects, nil
-}
-
-func (f *Fs) updateUsage() (err error) {
-	if do := f.RootFs.Features().About; do == nil {
-		return ErrUsageFieldNotSupported
-	}
-	done := false
-	f.cacheOnce.Do(func() {
-		f.cacheMutex.Lock()
-		err = f.updateUsageCore(false)
-		f.cacheMutex.Unlock()
-		done = true
-	})
-	if done {
-		return err
-	}
-	if !f.cacheUpdate {
-		f.cacheUpdate = true
-		go func() {
-			_ = f.updateUsageCore(true)
-			f.cacheUpdate = false
-		}()
-	}
-	return nil
-}
-
-func (f *Fs) updateUsageCore(lock bool) error {
-	// Run in background, should not be cancelled by user
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
-	usage, err := f.RootFs.Features().About(ctx)
-	if err != nil {
-		f.cacheUpdate = false
-		if errors.Is(err, fs.ErrorDirNotFound) {
-			err = nil
-		}
-		return err
-	}
-	if lock {
-		f.cacheMutex.Lock()
-		defer f.cacheMutex.Unlock()
-	}
-	// Store usage
-	f.cacheExpiry.Store(time.Now().Add(f.cacheTime).Unix())
-	f.usage = usage
-	return nil
+	return *usage.Objects, nil
 }
 
 // Check the interfaces are satisfied
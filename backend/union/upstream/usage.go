@@ -0,0 +1,168 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// usageCache is the cached usage of a remote
+//
+// It is shared by all the upstreams with the same root, whichever
+// union they are in, so the remote isn't asked for its usage more
+// often than needed.
+type usageCache struct {
+	f        fs.Fs // the remote to read the usage of
+	mu       sync.RWMutex
+	usage    *fs.Usage // the cached usage
+	fetched  time.Time // when the usage was read, zero if it hasn't been
+	updating bool      // set if the usage is being read in the background
+}
+
+var (
+	usageCachesMu sync.Mutex
+	usageCaches   = map[string]*usageCache{} // usage caches by fs.ConfigString of the remote
+)
+
+// getUsageCache returns the usage cache for f, making it if needed
+func getUsageCache(f fs.Fs) *usageCache {
+	key := fs.ConfigString(f)
+	usageCachesMu.Lock()
+	defer usageCachesMu.Unlock()
+	c, ok := usageCaches[key]
+	if !ok {
+		c = &usageCache{
+			f:     f,
+			usage: &fs.Usage{},
+		}
+		usageCaches[key] = c
+	}
+	return c
+}
+
+// copyUsage returns a deep copy of usage
+func copyUsage(usage *fs.Usage) *fs.Usage {
+	c := *usage
+	for _, p := range []**int64{&c.Total, &c.Used, &c.Trashed, &c.Other, &c.Free, &c.Objects} {
+		if *p != nil {
+			v := **p
+			*p = &v
+		}
+	}
+	return &c
+}
+
+// read the usage from the remote and store it
+//
+// This must be called with c.mu held for writing.
+func (c *usageCache) read() error {
+	do := c.f.Features().About
+	if do == nil {
+		return ErrUsageFieldNotSupported
+	}
+	// Run in background, should not be cancelled by user
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	usage, err := do(ctx)
+	if err != nil {
+		if errors.Is(err, fs.ErrorDirNotFound) {
+			err = nil
+		}
+		return err
+	}
+	c.usage = usage
+	c.fetched = time.Now()
+	return nil
+}
+
+// get returns a copy of the cached usage
+//
+// The first time it is called the usage is read from the remote. If
+// it is older than ttl after that it is read again in the background
+// and the old usage returned until that is done.
+func (c *usageCache) get(ttl time.Duration) (*fs.Usage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.f.Features().About == nil {
+		return nil, ErrUsageFieldNotSupported
+	}
+	if c.fetched.IsZero() {
+		if err := c.read(); err != nil {
+			return nil, err
+		}
+		if c.fetched.IsZero() {
+			// Don't read the usage of a missing root on every call
+			c.fetched = time.Now()
+		}
+	} else if time.Since(c.fetched) >= ttl && !c.updating {
+		c.updating = true
+		go func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			err := c.read()
+			if err != nil {
+				fs.Debugf(c.f, "Failed to read usage: %v", err)
+			}
+			c.updating = false
+		}()
+	}
+	return copyUsage(c.usage), nil
+}
+
+// peek returns a copy of the cached usage without reading it
+func (c *usageCache) peek() *fs.Usage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return copyUsage(c.usage)
+}
+
+// add size bytes and objects objects to the cached usage
+//
+// This keeps the usage up to date with the writes and deletes done
+// through rclone between reads of it.
+func (c *usageCache) add(size, objects int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.usage.Used != nil {
+		*c.usage.Used += size
+	}
+	if c.usage.Free != nil {
+		*c.usage.Free -= size
+	}
+	if c.usage.Objects != nil {
+		*c.usage.Objects += objects
+	}
+}
+
+// RefreshUsage reads the usage of all the remotes used as upstreams
+// again, returning it by the name of the remote
+func RefreshUsage(ctx context.Context) (map[string]*fs.Usage, error) {
+	usageCachesMu.Lock()
+	caches := make(map[string]*usageCache, len(usageCaches))
+	for key, c := range usageCaches {
+		caches[key] = c
+	}
+	usageCachesMu.Unlock()
+	usages := make(map[string]*fs.Usage, len(caches))
+	for key, c := range caches {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if c.f.Features().About == nil {
+			continue
+		}
+		c.mu.Lock()
+		err := c.read()
+		usage := copyUsage(c.usage)
+		c.mu.Unlock()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read usage of %s: %w", key, err)
+		}
+		usages[key] = usage
+	}
+	return usages, nil
+}
@@ -0,0 +1,157 @@
+// Package api has type definitions for the web3.storage upload service
+// as used through its HTTP bridge.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Store statuses returned by store/add
+const (
+	StoreStatusUpload = "upload" // the shard must be PUT to the URL returned
+	StoreStatusDone   = "done"   // the shard is already stored in the space
+)
+
+// Error names returned in receipts for missing items
+const (
+	ErrorUploadNotFound    = "UploadNotFound"
+	ErrorStoreItemNotFound = "StoreItemNotFound"
+)
+
+// Link is a CID encoded as DAG-JSON
+type Link struct {
+	CID string `json:"/"`
+}
+
+// NewLink makes a Link from a CID string
+func NewLink(cid string) Link {
+	return Link{CID: cid}
+}
+
+// Request is the body sent to the bridge.
+//
+// Each task is a tuple of ability, resource (the space DID) and
+// caveats.
+type Request struct {
+	Tasks [][]any `json:"tasks"`
+}
+
+// Receipt is returned by the bridge for each task invoked
+type Receipt struct {
+	P struct {
+		Out Result `json:"out"`
+	} `json:"p"`
+}
+
+// Result is the outcome of a task, exactly one of Ok and Error is set
+type Result struct {
+	Ok    json.RawMessage `json:"ok"`
+	Error *Error          `json:"error"`
+}
+
+// Error is returned by a failed task, or by the bridge itself
+type Error struct {
+	Name       string `json:"name"`
+	Message    string `json:"message"`
+	StatusCode int    `json:"-"`
+}
+
+// Error satisfies the error interface
+func (e *Error) Error() string {
+	out := e.Message
+	if e.Name != "" {
+		out = e.Name + ": " + out
+	}
+	if e.StatusCode != 0 {
+		out += fmt.Sprintf(" (HTTP %d)", e.StatusCode)
+	}
+	return out
+}
+
+// StoreAdd is the caveats of store/add
+type StoreAdd struct {
+	Link Link  `json:"link"`
+	Size int64 `json:"size"`
+}
+
+// StoreAddResult is returned by store/add
+type StoreAddResult struct {
+	Status    string            `json:"status"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	Allocated int64             `json:"allocated"`
+}
+
+// StoreRemove is the caveats of store/remove
+type StoreRemove struct {
+	Link Link `json:"link"`
+}
+
+// UploadAdd is the caveats of upload/add
+type UploadAdd struct {
+	Root   Link   `json:"root"`
+	Shards []Link `json:"shards"`
+}
+
+// UploadRemove is the caveats of upload/remove
+type UploadRemove struct {
+	Root Link `json:"root"`
+}
+
+// UploadList is the caveats of upload/list
+type UploadList struct {
+	Cursor string `json:"cursor,omitempty"`
+	Size   int    `json:"size"`
+}
+
+// UploadListResult is a page of results returned by upload/list
+type UploadListResult struct {
+	Results []Upload `json:"results"`
+	Cursor  string   `json:"cursor"`
+	Size    int      `json:"size"`
+}
+
+// Upload is an entry in the upload index of a space
+type Upload struct {
+	Root       Link      `json:"root"`
+	Shards     []Link    `json:"shards"`
+	InsertedAt time.Time `json:"insertedAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// FilecoinOffer is the caveats of filecoin/offer
+type FilecoinOffer struct {
+	Content Link `json:"content"`
+	Piece   Link `json:"piece"`
+}
+
+// FilecoinInfo is the caveats of filecoin/info
+type FilecoinInfo struct {
+	Piece Link `json:"piece"`
+}
+
+// FilecoinInfoResult is returned by filecoin/info
+type FilecoinInfoResult struct {
+	Piece      Link        `json:"piece"`
+	Aggregates []Aggregate `json:"aggregates"`
+	Deals      []Deal      `json:"deals"`
+}
+
+// Aggregate is an aggregate piece the piece was included in
+type Aggregate struct {
+	Aggregate Link `json:"aggregate"`
+}
+
+// Deal is a Filecoin deal made for an aggregate containing the piece
+type Deal struct {
+	Aggregate Link   `json:"aggregate"`
+	Provider  string `json:"provider"`
+	Aux       struct {
+		DataType   int `json:"dataType"`
+		DataSource struct {
+			DealID uint64 `json:"dealID"`
+		} `json:"dataSource"`
+	} `json:"aux"`
+}
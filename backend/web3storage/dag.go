@@ -0,0 +1,542 @@
+package web3storage
+
+// This file contains the minimal subset of CIDs, DAG-PB, UnixFS and
+// CARv1 needed to turn a file into a shard for the upload service
+// and to read back the directory nodes written.
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Multicodec and multihash codes
+const (
+	codecRaw    = 0x55
+	codecDagPB  = 0x70
+	codecCAR    = 0x0202
+	mhSHA256    = 0x12
+	mhPieceTree = 0x1011 // fr32-sha2-256-trunc254-padded-binary-tree
+	cidVersion1 = 1
+)
+
+// UnixFS data types
+const (
+	unixfsDirectory = 1
+	unixfsFile      = 2
+)
+
+const (
+	chunkSize     = 1024 * 1024 // size of the raw leaves files are split into
+	maxLinks      = 1024        // maximum number of links in a file node
+	maxBlockSize  = 2 * 1024 * 1024
+	maxPathLength = 256 // maximum number of directories above a file
+)
+
+var cidEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// cid is the binary form of a CIDv1
+type cid string
+
+// newCID makes a CIDv1 from its parts
+func newCID(codec, mhCode uint64, digest []byte) cid {
+	b := binary.AppendUvarint(nil, cidVersion1)
+	b = binary.AppendUvarint(b, codec)
+	b = binary.AppendUvarint(b, mhCode)
+	b = binary.AppendUvarint(b, uint64(len(digest)))
+	return cid(append(b, digest...))
+}
+
+// sumCID makes the sha2-256 CIDv1 of data
+func sumCID(codec uint64, data []byte) cid {
+	digest := sha256.Sum256(data)
+	return newCID(codec, mhSHA256, digest[:])
+}
+
+// String returns the CID in the default base32 multibase encoding
+func (c cid) String() string {
+	return "b" + strings.ToLower(cidEncoding.EncodeToString([]byte(c)))
+}
+
+// parseCID decodes a base32 CIDv1
+func parseCID(s string) (cid, error) {
+	if len(s) < 2 || s[0] != 'b' {
+		return "", fmt.Errorf("unsupported CID %q: only base32 CIDv1 is supported", s)
+	}
+	b, err := cidEncoding.DecodeString(strings.ToUpper(s[1:]))
+	if err != nil {
+		return "", fmt.Errorf("bad CID %q: %w", s, err)
+	}
+	c := cid(b)
+	if _, _, _, err := c.parts(); err != nil {
+		return "", fmt.Errorf("bad CID %q: %w", s, err)
+	}
+	return c, nil
+}
+
+// parts returns the codec and the multihash code and digest of the CID
+func (c cid) parts() (codec, mhCode uint64, digest []byte, err error) {
+	b := []byte(c)
+	var fields [4]uint64
+	for i := range fields {
+		var n int
+		fields[i], n = binary.Uvarint(b)
+		if n <= 0 {
+			return 0, 0, nil, errors.New("truncated CID")
+		}
+		b = b[n:]
+	}
+	if fields[0] != cidVersion1 {
+		return 0, 0, nil, fmt.Errorf("unsupported CID version %d", fields[0])
+	}
+	if fields[3] != uint64(len(b)) {
+		return 0, 0, nil, errors.New("bad multihash length")
+	}
+	return fields[1], fields[2], b, nil
+}
+
+// verify checks that data hashes to the CID
+func (c cid) verify(data []byte) error {
+	codec, mhCode, _, err := c.parts()
+	if err != nil {
+		return err
+	}
+	if mhCode != mhSHA256 {
+		return fmt.Errorf("unsupported multihash 0x%x in %v", mhCode, c)
+	}
+	if sumCID(codec, data) != c {
+		return fmt.Errorf("block %v is corrupted", c)
+	}
+	return nil
+}
+
+// Protocol buffer wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+func appendTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	return binary.AppendUvarint(appendTag(b, field, wireVarint), v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(appendTag(b, field, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+// pbField is a decoded protocol buffer field
+type pbField struct {
+	num      int
+	wireType int
+	varint   uint64
+	bytes    []byte
+}
+
+// decodeFields decodes the fields of a protocol buffer message
+func decodeFields(b []byte) (fields []pbField, err error) {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errors.New("truncated protobuf tag")
+		}
+		b = b[n:]
+		field := pbField{num: int(tag >> 3), wireType: int(tag & 7)}
+		switch field.wireType {
+		case wireVarint:
+			field.varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, errors.New("truncated protobuf varint")
+			}
+			b = b[n:]
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || length > uint64(len(b)-n) {
+				return nil, errors.New("truncated protobuf bytes")
+			}
+			field.bytes = b[n : n+int(length)]
+			b = b[n+int(length):]
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, errors.New("truncated protobuf fixed32")
+			}
+			field.varint = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, errors.New("truncated protobuf fixed64")
+			}
+			field.varint = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d", field.wireType)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// pbLink is a link in a DAG-PB node
+type pbLink struct {
+	hash  cid
+	name  string
+	tsize uint64 // size of the linked node and everything below it
+}
+
+// encodePBNode encodes a DAG-PB node in canonical form
+func encodePBNode(links []pbLink, data []byte) []byte {
+	var b []byte
+	for _, link := range links {
+		l := appendBytesField(nil, 1, []byte(link.hash))
+		if link.name != "" {
+			l = appendBytesField(l, 2, []byte(link.name))
+		}
+		l = appendVarintField(l, 3, link.tsize)
+		b = appendBytesField(b, 2, l)
+	}
+	return appendBytesField(b, 1, data)
+}
+
+// decodePBNode decodes a DAG-PB node
+func decodePBNode(b []byte) (links []pbLink, data []byte, err error) {
+	fields, err := decodeFields(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, field := range fields {
+		switch {
+		case field.num == 1 && field.wireType == wireBytes:
+			data = field.bytes
+		case field.num == 2 && field.wireType == wireBytes:
+			linkFields, err := decodeFields(field.bytes)
+			if err != nil {
+				return nil, nil, err
+			}
+			var link pbLink
+			for _, lf := range linkFields {
+				switch lf.num {
+				case 1:
+					link.hash = cid(lf.bytes)
+				case 2:
+					link.name = string(lf.bytes)
+				case 3:
+					link.tsize = lf.varint
+				}
+			}
+			links = append(links, link)
+		default:
+			return nil, nil, fmt.Errorf("unexpected field %d in DAG-PB node", field.num)
+		}
+	}
+	return links, data, nil
+}
+
+// unixfsData is the Data of a UnixFS node
+type unixfsData struct {
+	typ        uint64
+	fileSize   uint64
+	blockSizes []uint64
+	modTime    time.Time // zero if not set
+}
+
+// encode the UnixFS data
+func (u *unixfsData) encode() []byte {
+	b := appendVarintField(nil, 1, u.typ)
+	if u.typ == unixfsFile {
+		b = appendVarintField(b, 3, u.fileSize)
+		for _, size := range u.blockSizes {
+			b = appendVarintField(b, 4, size)
+		}
+	}
+	if !u.modTime.IsZero() {
+		t := appendVarintField(nil, 1, uint64(u.modTime.Unix()))
+		if nsec := u.modTime.Nanosecond(); nsec != 0 {
+			t = appendTag(t, 2, wireFixed32)
+			t = binary.LittleEndian.AppendUint32(t, uint32(nsec))
+		}
+		b = appendBytesField(b, 8, t)
+	}
+	return b
+}
+
+// decodeUnixFS decodes the Data of a UnixFS node
+func decodeUnixFS(b []byte) (u unixfsData, err error) {
+	fields, err := decodeFields(b)
+	if err != nil {
+		return u, err
+	}
+	for _, field := range fields {
+		switch field.num {
+		case 1:
+			u.typ = field.varint
+		case 3:
+			u.fileSize = field.varint
+		case 4:
+			u.blockSizes = append(u.blockSizes, field.varint)
+		case 8:
+			timeFields, err := decodeFields(field.bytes)
+			if err != nil {
+				return u, err
+			}
+			var sec int64
+			var nsec int64
+			for _, tf := range timeFields {
+				switch tf.num {
+				case 1:
+					sec = int64(tf.varint)
+				case 2:
+					nsec = int64(tf.varint)
+				}
+			}
+			u.modTime = time.Unix(sec, nsec)
+		}
+	}
+	return u, nil
+}
+
+// block is a node of the DAG held in memory
+type block struct {
+	cid  cid
+	data []byte
+}
+
+// leaf is a raw leaf of the DAG read from the file data
+type leaf struct {
+	cid    cid
+	offset int64
+	size   int64
+}
+
+// child describes a node when linking it from its parent
+type child struct {
+	cid      cid
+	tsize    uint64 // size of the encoded node and everything below it
+	fileSize uint64 // bytes of file data below the node
+}
+
+// fileDAG is the DAG made from a file and the directories above it
+//
+// The root of the DAG is a chain of directories with a single entry
+// each, one for every path segment, ending in a UnixFS file node
+// carrying the modification time.
+type fileDAG struct {
+	root   cid         // the outermost directory
+	file   cid         // the file node
+	nodes  []block     // directory and file nodes, from the root down
+	leaves []leaf      // the raw leaves with the file data
+	in     io.ReaderAt // where to read the leaves from
+}
+
+// newFileDAG makes the DAG for size bytes of in stored at the path
+// given by segments, which must already be encoded.
+func newFileDAG(in io.ReaderAt, size int64, segments []string, modTime time.Time) (*fileDAG, error) {
+	if len(segments) == 0 {
+		return nil, errors.New("can't make a DAG for a file without a name")
+	}
+	d := &fileDAG{in: in}
+	buf := make([]byte, chunkSize)
+	var level []child
+	for offset := int64(0); offset < size; offset += chunkSize {
+		n := min(size-offset, chunkSize)
+		_, err := in.ReadAt(buf[:n], offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chunk: %w", err)
+		}
+		c := sumCID(codecRaw, buf[:n])
+		d.leaves = append(d.leaves, leaf{cid: c, offset: offset, size: n})
+		level = append(level, child{cid: c, tsize: uint64(n), fileSize: uint64(n)})
+	}
+
+	// Build the file nodes bottom up, the file nodes are emitted
+	// top down so the CAR can be read in order
+	var fileNodes [][]block
+	for len(level) > maxLinks {
+		var next []child
+		var nodes []block
+		for i := 0; i < len(level); i += maxLinks {
+			blk, c := fileNode(level[i:min(i+maxLinks, len(level))], time.Time{})
+			nodes = append(nodes, blk)
+			next = append(next, c)
+		}
+		fileNodes = append(fileNodes, nodes)
+		level = next
+	}
+	blk, cur := fileNode(level, modTime)
+	d.file = cur.cid
+	fileNodes = append(fileNodes, []block{blk})
+
+	// Wrap the file in its directories
+	dirs := make([]block, len(segments))
+	for i := len(segments) - 1; i >= 0; i-- {
+		data := (&unixfsData{typ: unixfsDirectory}).encode()
+		node := encodePBNode([]pbLink{{hash: cur.cid, name: segments[i], tsize: cur.tsize}}, data)
+		c := sumCID(codecDagPB, node)
+		dirs[i] = block{cid: c, data: node}
+		cur = child{cid: c, tsize: uint64(len(node)) + cur.tsize}
+	}
+	d.root = cur.cid
+	d.nodes = dirs
+	for i := len(fileNodes) - 1; i >= 0; i-- {
+		d.nodes = append(d.nodes, fileNodes[i]...)
+	}
+	return d, nil
+}
+
+// fileNode makes a UnixFS file node linking to children
+func fileNode(children []child, modTime time.Time) (block, child) {
+	u := unixfsData{typ: unixfsFile, modTime: modTime}
+	links := make([]pbLink, len(children))
+	var tsize uint64
+	for i, c := range children {
+		links[i] = pbLink{hash: c.cid, tsize: c.tsize}
+		u.fileSize += c.fileSize
+		u.blockSizes = append(u.blockSizes, c.fileSize)
+		tsize += c.tsize
+	}
+	node := encodePBNode(links, u.encode())
+	c := sumCID(codecDagPB, node)
+	return block{cid: c, data: node}, child{cid: c, tsize: tsize + uint64(len(node)), fileSize: u.fileSize}
+}
+
+// appendCBORHead appends a CBOR major type and argument
+func appendCBORHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n < 1<<8:
+		return append(b, major|24, byte(n))
+	case n < 1<<16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n < 1<<32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), n)
+}
+
+// carHeader returns the CARv1 header naming root, which is the
+// DAG-CBOR map {"roots": [root], "version": 1}
+func carHeader(root cid) []byte {
+	var h []byte
+	h = appendCBORHead(h, 5, 2) // map of 2
+	h = appendCBORHead(h, 3, 5) // text "roots"
+	h = append(h, "roots"...)
+	h = appendCBORHead(h, 4, 1)  // array of 1
+	h = appendCBORHead(h, 6, 42) // tag 42 (CID)
+	h = appendCBORHead(h, 2, uint64(len(root)+1))
+	h = append(h, 0) // identity multibase prefix
+	h = append(h, root...)
+	h = appendCBORHead(h, 3, 7) // text "version"
+	h = append(h, "version"...)
+	h = appendCBORHead(h, 0, 1)
+	return append(binary.AppendUvarint(nil, uint64(len(h))), h...)
+}
+
+// writeSection writes a block to a CAR
+func writeSection(w io.Writer, c cid, data []byte) error {
+	b := binary.AppendUvarint(nil, uint64(len(c)+len(data)))
+	b = append(b, c...)
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// writeCAR writes the DAG as a CARv1 to w
+func (d *fileDAG) writeCAR(w io.Writer) error {
+	if _, err := w.Write(carHeader(d.root)); err != nil {
+		return err
+	}
+	for _, blk := range d.nodes {
+		if err := writeSection(w, blk.cid, blk.data); err != nil {
+			return err
+		}
+	}
+	buf := make([]byte, chunkSize)
+	for _, l := range d.leaves {
+		_, err := d.in.ReadAt(buf[:l.size], l.offset)
+		if err != nil {
+			return fmt.Errorf("failed to read chunk: %w", err)
+		}
+		if err := writeSection(w, l.cid, buf[:l.size]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shard describes the CAR made from a DAG
+type shard struct {
+	car   cid   // CID of the CAR
+	piece cid   // Filecoin piece CID of the CAR
+	size  int64 // size of the CAR
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter int64
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
+
+// shard hashes the CAR made from the DAG
+func (d *fileDAG) shard() (s shard, err error) {
+	carHash := sha256.New()
+	pieceHash := newPieceHasher()
+	var size countingWriter
+	err = d.writeCAR(io.MultiWriter(carHash, pieceHash, &size))
+	if err != nil {
+		return s, err
+	}
+	s.piece, err = pieceHash.cid()
+	if err != nil {
+		return s, err
+	}
+	s.car = newCID(codecCAR, mhSHA256, carHash.Sum(nil))
+	s.size = int64(size)
+	return s, nil
+}
+
+// openCAR returns a reader streaming the CAR made from the DAG
+func (d *fileDAG) openCAR() io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(d.writeCAR(pw))
+	}()
+	return pr
+}
+
+// readNode decodes a DAG-PB node checking it is UnixFS
+func readNode(c cid, data []byte) (links []pbLink, u unixfsData, err error) {
+	if err = c.verify(data); err != nil {
+		return nil, u, err
+	}
+	links, raw, err := decodePBNode(data)
+	if err != nil {
+		return nil, u, fmt.Errorf("failed to decode %v: %w", c, err)
+	}
+	u, err = decodeUnixFS(raw)
+	if err != nil {
+		return nil, u, fmt.Errorf("failed to decode UnixFS data of %v: %w", c, err)
+	}
+	return links, u, nil
+}
+
+// isDagPB returns true if the CID is of a DAG-PB node
+func (c cid) isDagPB() bool {
+	codec, _, _, err := c.parts()
+	return err == nil && codec == codecDagPB
+}
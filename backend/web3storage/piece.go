@@ -0,0 +1,141 @@
+package web3storage
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// The Filecoin piece CID (CommP) of the data is the root of a binary
+// merkle tree of sha2-256 hashes truncated to 254 bits. The leaves of
+// the tree are the data expanded with 2 zero bits for every 254 bits
+// (fr32 padding) and padded with zeros to a power of 2 in size.
+//
+// The piece CID is the v2 form described in FRC-0069 which also
+// encodes the height of the tree and the amount of padding.
+
+const (
+	nodeSize          = 32
+	unpaddedChunkSize = 127
+	paddedChunkSize   = 128
+	minPieceDataSize  = 65 // smallest payload a piece can be made from
+)
+
+// zeroNodes[i] is the root of a tree of height i with all zero leaves
+var zeroNodes = func() (nodes [64][nodeSize]byte) {
+	for i := 1; i < len(nodes); i++ {
+		nodes[i] = hashPair(nodes[i-1][:], nodes[i-1][:])
+	}
+	return nodes
+}()
+
+// hashPair returns the parent node of a and b
+func hashPair(a, b []byte) (out [nodeSize]byte) {
+	h := sha256.New()
+	_, _ = h.Write(a)
+	_, _ = h.Write(b)
+	h.Sum(out[:0])
+	out[nodeSize-1] &= 0x3f
+	return out
+}
+
+// fr32Expand expands 127 bytes into 128 by inserting 2 zero bits
+// after every 254 bits
+func fr32Expand(in *[unpaddedChunkSize]byte, out *[paddedChunkSize]byte) {
+	copy(out[:32], in[:32])
+	out[31] &= 0x3f
+	for i := 32; i < 64; i++ {
+		out[i] = in[i]<<2 | in[i-1]>>6
+	}
+	out[63] &= 0x3f
+	for i := 64; i < 96; i++ {
+		out[i] = in[i]<<4 | in[i-1]>>4
+	}
+	out[95] &= 0x3f
+	for i := 96; i < 127; i++ {
+		out[i] = in[i]<<6 | in[i-1]>>2
+	}
+	out[127] = in[126] >> 2
+}
+
+// pieceHasher computes the piece CID of the data written to it
+type pieceHasher struct {
+	size   uint64                  // bytes written
+	buf    [unpaddedChunkSize]byte // partial chunk
+	n      int                     // bytes in buf
+	leaves uint64                  // leaves added to the tree
+	stack  [64]*[nodeSize]byte     // pending left node at each level
+	padded [paddedChunkSize]byte   // scratch space for fr32Expand
+}
+
+func newPieceHasher() *pieceHasher {
+	return &pieceHasher{}
+}
+
+// Write data to the hasher
+func (p *pieceHasher) Write(data []byte) (int, error) {
+	total := len(data)
+	p.size += uint64(total)
+	for len(data) > 0 {
+		n := copy(p.buf[p.n:], data)
+		p.n += n
+		data = data[n:]
+		if p.n == unpaddedChunkSize {
+			p.addChunk()
+		}
+	}
+	return total, nil
+}
+
+// addChunk expands the chunk in buf into the tree
+func (p *pieceHasher) addChunk() {
+	fr32Expand(&p.buf, &p.padded)
+	for i := 0; i < paddedChunkSize; i += nodeSize {
+		var node [nodeSize]byte
+		copy(node[:], p.padded[i:i+nodeSize])
+		p.addNode(0, node)
+	}
+	p.leaves += paddedChunkSize / nodeSize
+	p.n = 0
+}
+
+// addNode adds a node at level, combining it with its left sibling
+func (p *pieceHasher) addNode(level int, node [nodeSize]byte) {
+	for p.stack[level] != nil {
+		node = hashPair(p.stack[level][:], node[:])
+		p.stack[level] = nil
+		level++
+	}
+	p.stack[level] = &node
+}
+
+// cid returns the piece CID of the data written so far.
+//
+// The hasher can't be used after this.
+func (p *pieceHasher) cid() (cid, error) {
+	if p.size < minPieceDataSize {
+		return "", errors.New("too little data to make a piece")
+	}
+	size := p.size
+	if p.n > 0 {
+		clear(p.buf[p.n:])
+		p.addChunk()
+	}
+	height := bits.Len64(p.leaves - 1)
+	for level := 0; level < height; level++ {
+		if p.stack[level] != nil {
+			node := hashPair(p.stack[level][:], zeroNodes[level][:])
+			p.stack[level] = nil
+			p.addNode(level+1, node)
+		}
+	}
+	root := p.stack[height]
+	paddedSize := uint64(nodeSize) << height
+	padding := paddedSize/paddedChunkSize*unpaddedChunkSize - size
+
+	digest := binary.AppendUvarint(nil, padding)
+	digest = append(digest, byte(height))
+	digest = append(digest, root[:]...)
+	return newCID(codecRaw, mhPieceTree, digest), nil
+}
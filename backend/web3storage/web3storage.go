@@ -0,0 +1,1169 @@
+// Package web3storage provides an interface to web3.storage, the hot
+// storage layer of Filecoin run by Storacha.
+package web3storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/web3storage/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	minSleep        = 10 * time.Millisecond
+	maxSleep        = 2 * time.Second
+	decayConstant   = 2 // bigger for slower decay, exponential
+	maxShardSize    = 4261412864
+	memoryThreshold = 4 * chunkSize // files bigger than this are spooled to disk
+	tempFilePrefix  = "rclone-web3storage-"
+)
+
+// Deal statuses returned as metadata
+const (
+	dealStatusQueued     = "queued"
+	dealStatusAggregated = "aggregated"
+	dealStatusDealt      = "dealt"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "web3storage",
+		Description: "web3.storage (Storacha) with Filecoin deals",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		MetadataInfo: &fs.MetadataInfo{
+			System: map[string]fs.MetadataHelp{
+				"root-cid": {
+					Help:     "CID of the upload, the directory wrapping the file",
+					Type:     "string",
+					Example:  "bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku",
+					ReadOnly: true,
+				},
+				"file-cid": {
+					Help:     "CID of the file itself",
+					Type:     "string",
+					Example:  "bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi",
+					ReadOnly: true,
+				},
+				"car-cid": {
+					Help:     "CID of the CAR shard holding the file",
+					Type:     "string",
+					Example:  "bagbaieratsdsywgxwu6lbs4ncsyl5xywnjgmpwvvkdm7rjddmh24ahc5ol3q",
+					ReadOnly: true,
+				},
+				"piece-cid": {
+					Help:     "Filecoin piece CID of the CAR shard",
+					Type:     "string",
+					Example:  "bafkzcibcaapao7zcn2wiw6ayqdcgrjmnnkysiqffzuyhnhqvvlg2zkxbcsbsuei",
+					ReadOnly: true,
+				},
+				"deal-status": {
+					Help:     "Progress of the piece towards Filecoin deals: queued, aggregated or dealt",
+					Type:     "string",
+					Example:  "dealt",
+					ReadOnly: true,
+				},
+				"aggregate-cid": {
+					Help:     "Piece CID of the aggregate the piece was included in",
+					Type:     "string",
+					Example:  "bafkzcibcaapdc4lmbfmg7xqylcqs7sgw4dz5z4ktfnqhrbqmgcwi2d6xk4g6wgq",
+					ReadOnly: true,
+				},
+				"deals": {
+					Help:     "Comma separated list of Filecoin deals as provider:deal-id",
+					Type:     "string",
+					Example:  "f02620:69011214,f01518369:69011347",
+					ReadOnly: true,
+				},
+			},
+			Help: `The CIDs of every file are returned as metadata, along with the
+Filecoin piece CID and deal status of the shard it is stored in, so
+they can be seen with "rclone lsjson -M".`,
+		},
+		Options: []fs.Option{{
+			Name: "space",
+			Help: `DID of the space to use, like did:key:z6Mk...
+
+Create a space with "w3 space create" and list the spaces you can
+access with "w3 space ls".`,
+			Required:  true,
+			Sensitive: true,
+		}, {
+			Name: "auth_secret",
+			Help: `Secret for the HTTP bridge of the upload service.
+
+This is the X-Auth-Secret printed by "w3 bridge generate-tokens".`,
+			Required:   true,
+			IsPassword: true,
+		}, {
+			Name: "authorization",
+			Help: `Authorization for the HTTP bridge of the upload service.
+
+This is the Authorization printed by "w3 bridge generate-tokens". It
+must delegate store/*, upload/* and filecoin/* on the space.`,
+			Required:   true,
+			IsPassword: true,
+		}, {
+			Name:     "endpoint",
+			Help:     "URL of the upload service.",
+			Default:  "https://up.web3.storage",
+			Advanced: true,
+		}, {
+			Name: "gateway",
+			Help: `URL of the IPFS gateway used to read files.
+
+The gateway must support trustless block requests.`,
+			Default:  "https://w3s.link",
+			Advanced: true,
+		}, {
+			Name: "filecoin_offer",
+			Help: `Offer uploaded shards for Filecoin deals.
+
+If set, the piece CID of each shard uploaded is submitted to the
+Filecoin pipeline of the upload service so it gets aggregated and
+stored with Filecoin storage providers.`,
+			Default:  true,
+			Advanced: true,
+		}, {
+			Name: "compute_pieces",
+			Help: `Compute piece CIDs of files by downloading them.
+
+The upload service doesn't index piece CIDs by upload, so to return
+the piece CID and deal status of files not uploaded by this rclone
+process as metadata they are downloaded and the shard they were
+uploaded as is rebuilt.
+
+Set this to false to only return the piece CIDs of files uploaded by
+this process.`,
+			Default:  true,
+			Advanced: true,
+		}, {
+			Name:     "list_chunk",
+			Help:     "Size of listing chunk, the number of uploads read from the space index per request.",
+			Default:  1000,
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			Default: encoder.Base |
+				encoder.EncodeInvalidUtf8,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Space         string               `config:"space"`
+	AuthSecret    string               `config:"auth_secret"`
+	Authorization string               `config:"authorization"`
+	Endpoint      string               `config:"endpoint"`
+	Gateway       string               `config:"gateway"`
+	FilecoinOffer bool                 `config:"filecoin_offer"`
+	ComputePieces bool                 `config:"compute_pieces"`
+	ListChunk     int                  `config:"list_chunk"`
+	Enc           encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents a path in a web3.storage space
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on if any
+	opt      Options      // parsed config options
+	features *fs.Features // optional features
+	srv      *rest.Client // the connection to the upload service bridge
+	gw       *rest.Client // the connection to the IPFS gateway
+	shardSrv *rest.Client // client for PUTting shards, without credentials
+	pacer    *fs.Pacer    // pacer for API calls
+
+	mu        sync.Mutex
+	resolved  map[cid]*upload     // uploads by root CID, nil if not made by rclone
+	byPath    map[string]*upload  // uploads in the space index by path
+	shardRefs map[cid]int         // number of uploads using each shard
+	dirs      map[string]struct{} // directories made with Mkdir by path
+	indexed   bool                // set once the index has been read
+}
+
+// upload describes a file stored in the space
+type upload struct {
+	root     cid       // root of the upload
+	file     cid       // the UnixFS file node
+	segments []string  // encoded path of the file in the space
+	path     string    // path of the file in the space
+	size     int64     // size of the file
+	modTime  time.Time // modification time, zero if not known
+	shards   []cid     // CARs the upload is stored in
+	updated  time.Time // when the upload was last added to the space
+	piece    cid       // piece CID of the shard, empty if not known yet
+}
+
+// Object describes a file in a web3.storage space
+type Object struct {
+	fs     *Fs
+	remote string
+	upload *upload
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	if f.root == "" {
+		return fmt.Sprintf("web3.storage space %s", f.opt.Space)
+	}
+	return fmt.Sprintf("web3.storage space %s path %s", f.opt.Space, f.root)
+}
+
+// Precision of the modification times stored in the UnixFS file nodes
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes are not supported, CIDs are returned as metadata instead
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// Features for this fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// errorHandler parses a non 2xx error response from the bridge
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("error when trying to read error body: %w", err)
+	}
+	errResponse := new(api.Error)
+	err = json.Unmarshal(body, &errResponse)
+	if err != nil || errResponse.Message == "" {
+		// Set the Message to be the body if we can't parse the JSON
+		errResponse.Message = strings.TrimSpace(string(body))
+	}
+	errResponse.StatusCode = resp.StatusCode
+	return errResponse
+}
+
+// gatewayErrorHandler parses a non 2xx error response from the gateway
+func gatewayErrorHandler(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		_ = resp.Body.Close()
+		return fs.ErrorObjectNotFound
+	}
+	return errorHandler(resp)
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// invoke runs a task on the space through the bridge, decoding the
+// result into result if it isn't nil.
+//
+// All the tasks used are idempotent so can be retried.
+func (f *Fs) invoke(ctx context.Context, ability string, caveats, result any) error {
+	request := api.Request{
+		Tasks: [][]any{{ability, f.opt.Space, caveats}},
+	}
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/bridge",
+	}
+	var receipts []api.Receipt
+	err := f.pacer.Call(func() (bool, error) {
+		receipts = nil
+		resp, err := f.srv.CallJSON(ctx, &opts, &request, &receipts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", ability, err)
+	}
+	if len(receipts) != 1 {
+		return fmt.Errorf("%s failed: expecting 1 receipt but got %d", ability, len(receipts))
+	}
+	out := receipts[0].P.Out
+	if out.Error != nil {
+		switch out.Error.Name {
+		case api.ErrorUploadNotFound, api.ErrorStoreItemNotFound:
+			return fs.ErrorObjectNotFound
+		}
+		return fmt.Errorf("%s failed: %w", ability, out.Error)
+	}
+	if result != nil {
+		err = json.Unmarshal(out.Ok, result)
+		if err != nil {
+			return fmt.Errorf("%s failed: couldn't decode result: %w", ability, err)
+		}
+	}
+	return nil
+}
+
+// fetchBlock reads a block from the gateway and checks it matches c
+func (f *Fs) fetchBlock(ctx context.Context, c cid) (data []byte, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       "/ipfs/" + c.String(),
+		Parameters: map[string][]string{"format": {"raw"}},
+		ExtraHeaders: map[string]string{
+			"Accept": "application/vnd.ipld.raw",
+		},
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err := f.gw.Call(ctx, &opts)
+		if err == nil {
+			data, err = io.ReadAll(io.LimitReader(resp.Body, maxBlockSize+1))
+			_ = resp.Body.Close()
+		}
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBlockSize {
+		return nil, fmt.Errorf("block %v is too big", c)
+	}
+	return data, c.verify(data)
+}
+
+// resolve walks the directories at the top of an upload to find the
+// file in it.
+//
+// It returns nil if the upload wasn't made by rclone.
+func (f *Fs) resolve(ctx context.Context, root cid) (*upload, error) {
+	u := &upload{root: root}
+	c := root
+	for range maxPathLength {
+		if !c.isDagPB() {
+			return nil, nil
+		}
+		data, err := f.fetchBlock(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		links, unixfs, err := readNode(c, data)
+		if err != nil {
+			return nil, err
+		}
+		switch unixfs.typ {
+		case unixfsDirectory:
+			if len(links) != 1 || links[0].name == "" {
+				return nil, nil
+			}
+			u.segments = append(u.segments, links[0].name)
+			c = links[0].hash
+		case unixfsFile:
+			if len(u.segments) == 0 {
+				return nil, nil
+			}
+			names := make([]string, len(u.segments))
+			for i, segment := range u.segments {
+				names[i] = f.opt.Enc.ToStandardName(segment)
+			}
+			u.file = c
+			u.path = path.Join(names...)
+			u.size = int64(unixfs.fileSize)
+			u.modTime = unixfs.modTime
+			return u, nil
+		default:
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// listUploads reads the whole upload index of the space
+func (f *Fs) listUploads(ctx context.Context) (uploads []api.Upload, err error) {
+	list := api.UploadList{Size: f.opt.ListChunk}
+	for {
+		var result api.UploadListResult
+		err = f.invoke(ctx, "upload/list", &list, &result)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, result.Results...)
+		if result.Cursor == "" || result.Cursor == list.Cursor || len(result.Results) < list.Size {
+			return uploads, nil
+		}
+		list.Cursor = result.Cursor
+	}
+}
+
+// refresh reads the upload index of the space, resolving the paths of
+// any uploads not seen before
+func (f *Fs) refresh(ctx context.Context) error {
+	items, err := f.listUploads(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Resolve the new uploads in parallel
+	f.mu.Lock()
+	var todo []cid
+	roots := make([]cid, len(items))
+	for i, item := range items {
+		roots[i], err = parseCID(item.Root.CID)
+		if err != nil {
+			fs.Debugf(f, "Ignoring upload: %v", err)
+			continue
+		}
+		if _, found := f.resolved[roots[i]]; !found {
+			todo = append(todo, roots[i])
+		}
+	}
+	f.mu.Unlock()
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(fs.GetConfig(ctx).Checkers)
+	for _, root := range todo {
+		g.Go(func() error {
+			u, err := f.resolve(gCtx, root)
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					return err
+				}
+				fs.Errorf(f, "Failed to read upload %v, ignoring it: %v", root, err)
+				return nil
+			}
+			if u == nil {
+				fs.Debugf(f, "Ignoring upload %v not made by rclone", root)
+			}
+			f.mu.Lock()
+			f.resolved[root] = u
+			f.mu.Unlock()
+			return nil
+		})
+	}
+	err = g.Wait()
+	if err != nil {
+		return err
+	}
+
+	// Rebuild the index
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.byPath = make(map[string]*upload, len(items))
+	f.shardRefs = make(map[cid]int)
+	for i, item := range items {
+		var shards []cid
+		for _, link := range item.Shards {
+			shard, err := parseCID(link.CID)
+			if err != nil {
+				fs.Debugf(f, "Ignoring shard: %v", err)
+				continue
+			}
+			shards = append(shards, shard)
+			f.shardRefs[shard]++
+		}
+		u := f.resolved[roots[i]]
+		if u == nil {
+			continue
+		}
+		u.shards = shards
+		u.updated = item.UpdatedAt
+		if old := f.byPath[u.path]; old != nil {
+			if old.updated.After(u.updated) {
+				fs.Debugf(f, "Ignoring older upload %v of %q", u.root, u.path)
+				continue
+			}
+			fs.Debugf(f, "Ignoring older upload %v of %q", old.root, old.path)
+		}
+		f.byPath[u.path] = u
+	}
+	f.indexed = true
+	return nil
+}
+
+// lookup finds the upload of the file at remote, reading the index if
+// it hasn't been read yet
+func (f *Fs) lookup(ctx context.Context, remote string) (*upload, error) {
+	f.mu.Lock()
+	indexed := f.indexed
+	f.mu.Unlock()
+	if !indexed {
+		err := f.refresh(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u := f.byPath[path.Join(f.root, remote)]
+	if u == nil {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return u, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	err = f.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := path.Join(f.root, dir)
+	if prefix != "" {
+		prefix += "/"
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dirs := make(map[string]struct{})
+	for filePath, u := range f.byPath {
+		if !strings.HasPrefix(filePath, prefix) {
+			continue
+		}
+		name, _, isDir := strings.Cut(filePath[len(prefix):], "/")
+		remote := path.Join(dir, name)
+		if isDir {
+			if _, found := dirs[remote]; !found {
+				dirs[remote] = struct{}{}
+				entries = append(entries, fs.NewDir(remote, time.Time{}))
+			}
+			continue
+		}
+		entries = append(entries, &Object{
+			fs:     f,
+			remote: remote,
+			upload: u,
+		})
+	}
+	if _, found := f.dirs[path.Join(f.root, dir)]; len(entries) == 0 && prefix != "" && !found {
+		return nil, fs.ErrorDirNotFound
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	u, err := f.lookup(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{
+		fs:     f,
+		remote: remote,
+		upload: u,
+	}, nil
+}
+
+// Put the object into the space
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	err := o.Update(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// PutStream uploads to the space with an unknown size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory
+//
+// Directories only exist as paths of files in the space, so it is
+// just remembered until a file is put in it.
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for dirPath := path.Join(f.root, dir); dirPath != "." && dirPath != ""; dirPath = path.Dir(dirPath) {
+		f.dirs[dirPath] = struct{}{}
+	}
+	return nil
+}
+
+// Rmdir deletes the directory
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	entries, err := f.List(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	f.mu.Lock()
+	delete(f.dirs, path.Join(f.root, dir))
+	f.mu.Unlock()
+	return nil
+}
+
+// segments returns the encoded path segments of remote in the space
+func (f *Fs) segments(remote string) []string {
+	segments := strings.Split(path.Join(f.root, remote), "/")
+	for i, segment := range segments {
+		segments[i] = f.opt.Enc.FromStandardName(segment)
+	}
+	return segments
+}
+
+// spool reads in into memory or a temporary file so the DAG can be
+// built from it and read again to make the shard.
+//
+// The cleanup function should be called when out is finished with
+// regardless of whether this function returned an error or not.
+func spool(in io.Reader, size int64) (out io.ReaderAt, n int64, cleanup func(), err error) {
+	// nothing to clean up by default
+	cleanup = func() {}
+
+	// don't spool small files to disk
+	if size >= 0 && size <= memoryThreshold {
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return nil, 0, cleanup, err
+		}
+		return bytes.NewReader(data), int64(len(data)), cleanup, nil
+	}
+	tempFile, err := os.CreateTemp("", tempFilePrefix)
+	if err != nil {
+		return nil, 0, cleanup, err
+	}
+	_ = os.Remove(tempFile.Name()) // Delete the file - may not work on Windows
+	cleanup = func() {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name()) // may be deleted already
+	}
+	n, err = io.Copy(tempFile, io.LimitReader(in, maxShardSize+1))
+	if err != nil {
+		return nil, 0, cleanup, err
+	}
+	if n > maxShardSize {
+		return nil, 0, cleanup, fmt.Errorf("file too big for a single shard, the maximum is %v", fs.SizeSuffix(maxShardSize))
+	}
+	return tempFile, n, cleanup, nil
+}
+
+// putShard stores the CAR made from dag in the space
+func (f *Fs) putShard(ctx context.Context, dag *fileDAG, s shard) error {
+	var result api.StoreAddResult
+	err := f.invoke(ctx, "store/add", api.StoreAdd{Link: api.NewLink(s.car.String()), Size: s.size}, &result)
+	if err != nil {
+		return err
+	}
+	if result.Status != api.StoreStatusUpload {
+		fs.Debugf(f, "Shard %v already stored", s.car)
+		return nil
+	}
+	opts := rest.Opts{
+		Method:        "PUT",
+		RootURL:       result.URL,
+		ContentLength: &s.size,
+		ExtraHeaders:  result.Headers,
+		NoResponse:    true,
+	}
+	return f.pacer.Call(func() (bool, error) {
+		// The CAR is made again for every attempt
+		car := dag.openCAR()
+		defer func() { _ = car.Close() }()
+		opts.Body = car
+		resp, err := f.shardSrv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+}
+
+// put stores the file read from in at remote returning its upload and
+// the upload it replaced if any
+func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, remote string) (u, old *upload, err error) {
+	size := src.Size()
+	if size > maxShardSize {
+		return nil, nil, fmt.Errorf("file too big for a single shard, the maximum is %v", fs.SizeSuffix(maxShardSize))
+	}
+	// Read the index to find the upload being replaced
+	old, err = f.lookup(ctx, remote)
+	if err != nil && !errors.Is(err, fs.ErrorObjectNotFound) {
+		return nil, nil, err
+	}
+	data, size, cleanup, err := spool(in, size)
+	defer cleanup()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	segments := f.segments(remote)
+	modTime := src.ModTime(ctx)
+	dag, err := newFileDAG(data, size, segments, modTime)
+	if err != nil {
+		return nil, nil, err
+	}
+	s, err := dag.shard()
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.size > maxShardSize {
+		return nil, nil, fmt.Errorf("file too big for a single shard, the maximum is %v", fs.SizeSuffix(maxShardSize))
+	}
+	err = f.putShard(ctx, dag, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = f.invoke(ctx, "upload/add", api.UploadAdd{
+		Root:   api.NewLink(dag.root.String()),
+		Shards: []api.Link{api.NewLink(s.car.String())},
+	}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f.opt.FilecoinOffer {
+		err = f.offer(ctx, s)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	u = &upload{
+		root:     dag.root,
+		file:     dag.file,
+		segments: segments,
+		path:     path.Join(f.root, remote),
+		size:     size,
+		modTime:  modTime,
+		shards:   []cid{s.car},
+		updated:  time.Now(),
+		piece:    s.piece,
+	}
+	f.mu.Lock()
+	if old := f.resolved[u.root]; old != nil && f.byPath[old.path] == old {
+		// Uploaded the same file again
+		u = old
+		u.updated = time.Now()
+	} else {
+		f.resolved[u.root] = u
+		f.byPath[u.path] = u
+		f.shardRefs[s.car]++
+	}
+	f.mu.Unlock()
+	return u, old, nil
+}
+
+// offer submits the piece of a shard for Filecoin deals
+func (f *Fs) offer(ctx context.Context, s shard) error {
+	return f.invoke(ctx, "filecoin/offer", api.FilecoinOffer{
+		Content: api.NewLink(s.car.String()),
+		Piece:   api.NewLink(s.piece.String()),
+	}, nil)
+}
+
+// remove removes the upload from the space along with any shards no
+// other upload uses
+func (f *Fs) remove(ctx context.Context, u *upload) error {
+	err := f.invoke(ctx, "upload/remove", api.UploadRemove{Root: api.NewLink(u.root.String())}, nil)
+	if err != nil && !errors.Is(err, fs.ErrorObjectNotFound) {
+		return err
+	}
+	f.mu.Lock()
+	if f.byPath[u.path] == u {
+		delete(f.byPath, u.path)
+	}
+	// Keep the directories the file was in until they are removed
+	for dirPath := path.Dir(u.path); dirPath != "."; dirPath = path.Dir(dirPath) {
+		f.dirs[dirPath] = struct{}{}
+	}
+	var unused []cid
+	for _, shard := range u.shards {
+		f.shardRefs[shard]--
+		if f.shardRefs[shard] <= 0 {
+			delete(f.shardRefs, shard)
+			unused = append(unused, shard)
+		}
+	}
+	f.mu.Unlock()
+	for _, shard := range unused {
+		err = f.invoke(ctx, "store/remove", api.StoreRemove{Link: api.NewLink(shard.String())}, nil)
+		if err != nil && !errors.Is(err, fs.ErrorObjectNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// piece returns the piece CID of the shard the upload is stored in,
+// rebuilding the shard from the file contents if necessary.
+//
+// It returns an empty CID if the piece can't be found.
+func (f *Fs) piece(ctx context.Context, o *Object, compute bool) (cid, error) {
+	u := o.upload
+	f.mu.Lock()
+	piece, shards := u.piece, u.shards
+	f.mu.Unlock()
+	if piece != "" || !compute {
+		return piece, nil
+	}
+	if len(shards) != 1 {
+		fs.Debugf(o, "Can't compute the piece CID of an upload with %d shards", len(shards))
+		return "", nil
+	}
+	in, err := o.Open(ctx)
+	if err != nil {
+		return "", err
+	}
+	data, size, cleanup, err := spool(in, u.size)
+	_ = in.Close()
+	defer cleanup()
+	if err != nil {
+		return "", err
+	}
+	dag, err := newFileDAG(data, size, u.segments, u.modTime)
+	if err != nil {
+		return "", err
+	}
+	s, err := dag.shard()
+	if err != nil {
+		return "", err
+	}
+	if s.car != shards[0] {
+		fs.Debugf(o, "Can't compute the piece CID as the shard wasn't made by rclone")
+		return "", nil
+	}
+	f.mu.Lock()
+	u.piece = s.piece
+	f.mu.Unlock()
+	return s.piece, nil
+}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "offer":
+		if len(arg) == 0 {
+			return nil, errors.New("need at least one path to offer")
+		}
+		pieces := make(map[string]string, len(arg))
+		for _, remote := range arg {
+			obj, err := f.NewObject(ctx, remote)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", remote, err)
+			}
+			o := obj.(*Object)
+			piece, err := f.piece(ctx, o, true)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", remote, err)
+			}
+			if piece == "" {
+				return nil, fmt.Errorf("%s: can't find the piece CID of the file", remote)
+			}
+			err = f.offer(ctx, shard{car: o.upload.shards[0], piece: piece})
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", remote, err)
+			}
+			pieces[remote] = piece.String()
+		}
+		return pieces, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "offer",
+	Short: "Offer files for Filecoin deals.",
+	Long: `This command submits the piece CIDs of the shards the files given are
+stored in to the Filecoin pipeline of the upload service, returning
+a map of path to piece CID. Use it for files uploaded with
+--web3storage-filecoin-offer=false.
+
+    rclone backend offer remote: path/to/file [path/to/file...]
+
+The piece CIDs of files not uploaded by this process are computed by
+downloading them.
+`,
+}}
+
+// NewFs constructs an Fs from the path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(opt.Space, "did:") {
+		return nil, fmt.Errorf("space %q must be a DID like did:key:z6Mk", opt.Space)
+	}
+	if opt.ListChunk <= 0 {
+		return nil, fmt.Errorf("list_chunk must be positive: %d", opt.ListChunk)
+	}
+	authSecret, err := obscure.Reveal(opt.AuthSecret)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decrypt auth_secret: %w", err)
+	}
+	authorization, err := obscure.Reveal(opt.Authorization)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decrypt authorization: %w", err)
+	}
+
+	root = strings.Trim(root, "/")
+	f := &Fs{
+		name:      name,
+		opt:       *opt,
+		root:      root,
+		resolved:  make(map[cid]*upload),
+		byPath:    make(map[string]*upload),
+		shardRefs: make(map[cid]int),
+		dirs:      make(map[string]struct{}),
+	}
+	f.pacer = fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant)))
+	f.features = (&fs.Features{
+		ReadMetadata: true,
+	}).Fill(ctx, f)
+
+	client := fshttp.NewClient(ctx)
+	f.srv = rest.NewClient(client).SetRoot(strings.TrimSuffix(opt.Endpoint, "/")).SetErrorHandler(errorHandler)
+	f.srv.SetHeader("X-Auth-Secret", authSecret)
+	f.srv.SetHeader("Authorization", authorization)
+	f.gw = rest.NewClient(client).SetRoot(strings.TrimSuffix(opt.Gateway, "/")).SetErrorHandler(gatewayErrorHandler)
+	f.shardSrv = rest.NewClient(client)
+
+	// Check to see if the root is actually an existing file
+	if root != "" {
+		remote := path.Base(root)
+		f.root = path.Dir(root)
+		if f.root == "." {
+			f.root = ""
+		}
+		_, err := f.NewObject(ctx, remote)
+		if err != nil {
+			if errors.Is(err, fs.ErrorObjectNotFound) {
+				// File doesn't exist so return old f
+				f.root = root
+				return f, nil
+			}
+			return nil, err
+		}
+		// return an error with an fs which points to the parent
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash is not supported
+func (o *Object) Hash(ctx context.Context, ty hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.upload.size
+}
+
+// ModTime returns the modification time stored in the file node, or
+// the default time if there isn't one
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if o.upload.modTime.IsZero() {
+		return time.Time(fs.GetConfig(ctx).DefaultTime)
+	}
+	return o.upload.modTime
+}
+
+// SetModTime is not supported as uploads are immutable
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTimeWithoutDelete
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// ID returns the root CID of the upload of the Object
+func (o *Object) ID() string {
+	return o.upload.root.String()
+}
+
+// Metadata returns the CIDs of the Object and the Filecoin deal
+// status of its piece
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	u := o.upload
+	m := fs.Metadata{
+		"root-cid": u.root.String(),
+		"file-cid": u.file.String(),
+	}
+	o.fs.mu.Lock()
+	shards := make([]string, len(u.shards))
+	for i, shard := range u.shards {
+		shards[i] = shard.String()
+	}
+	o.fs.mu.Unlock()
+	if len(shards) > 0 {
+		m["car-cid"] = strings.Join(shards, ",")
+	}
+	piece, err := o.fs.piece(ctx, o, o.fs.opt.ComputePieces)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find piece CID: %w", err)
+	}
+	if piece == "" {
+		return m, nil
+	}
+	m["piece-cid"] = piece.String()
+	var info api.FilecoinInfoResult
+	err = o.fs.invoke(ctx, "filecoin/info", api.FilecoinInfo{Piece: api.NewLink(piece.String())}, &info)
+	if err != nil {
+		fs.Debugf(o, "Failed to read deal status: %v", err)
+		return m, nil
+	}
+	switch {
+	case len(info.Deals) > 0:
+		m["deal-status"] = dealStatusDealt
+		deals := make([]string, len(info.Deals))
+		for i, deal := range info.Deals {
+			deals[i] = deal.Provider + ":" + strconv.FormatUint(deal.Aux.DataSource.DealID, 10)
+		}
+		m["deals"] = strings.Join(deals, ",")
+	case len(info.Aggregates) > 0:
+		m["deal-status"] = dealStatusAggregated
+	default:
+		m["deal-status"] = dealStatusQueued
+	}
+	if len(info.Aggregates) > 0 {
+		m["aggregate-cid"] = info.Aggregates[0].Aggregate.CID
+	}
+	return m, nil
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	if o.upload.size == 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	fs.FixRangeOption(options, o.upload.size)
+	opts := rest.Opts{
+		Method:  "GET",
+		Path:    "/ipfs/" + o.upload.file.String(),
+		Options: options,
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.gw.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new upload replaces the old one which is removed.
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	u, old, err := o.fs.put(ctx, in, src, o.remote)
+	if err != nil {
+		return err
+	}
+	o.upload = u
+	if old != nil && old.root != u.root {
+		err = o.fs.remove(ctx, old)
+		if err != nil {
+			return fmt.Errorf("failed to remove old version: %w", err)
+		}
+	}
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	return o.fs.remove(ctx, o.upload)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.Commander   = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+	_ fs.IDer        = (*Object)(nil)
+	_ fs.Metadataer  = (*Object)(nil)
+)
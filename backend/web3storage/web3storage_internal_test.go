@@ -0,0 +1,604 @@
+package web3storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/web3storage/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSpace         = "did:key:z6MkwDK3M4PxU1FqcSt6quBH1xRBSGnPRdQYP9B13h3Wq5X1"
+	testAuthSecret    = "test-secret"
+	testAuthorization = "test-authorization"
+)
+
+// fakeUpload is an entry in the upload index of fakeW3up
+type fakeUpload struct {
+	root      string
+	shards    []string
+	updatedAt time.Time
+}
+
+// fakeW3up implements the bridge of the upload service and a
+// gateway serving the blocks of the shards stored
+type fakeW3up struct {
+	t       *testing.T
+	url     string
+	mu      sync.Mutex
+	uploads map[string]*fakeUpload // by root CID
+	allowed map[string]int64       // shards allocated with store/add but not PUT yet
+	shards  map[string][]cid       // blocks of each shard stored by CAR CID
+	blocks  map[cid][]byte
+	offers  map[string]string // piece CID to CAR CID
+	dealt   map[string]bool   // pieces which have made it into deals
+}
+
+func newFakeW3up(t *testing.T) *fakeW3up {
+	f := &fakeW3up{
+		t:       t,
+		uploads: map[string]*fakeUpload{},
+		allowed: map[string]int64{},
+		shards:  map[string][]cid{},
+		blocks:  map[cid][]byte{},
+		offers:  map[string]string{},
+		dealt:   map[string]bool{},
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	f.url = srv.URL
+	return f
+}
+
+// remote returns a connection string for the fake
+func (f *fakeW3up) remote() string {
+	return fmt.Sprintf(":web3storage,space='%s',auth_secret='%s',authorization='%s',endpoint='%s',gateway='%s':",
+		testSpace, obscure.MustObscure(testAuthSecret), obscure.MustObscure(testAuthorization), f.url, f.url)
+}
+
+func (f *fakeW3up) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/bridge":
+		f.serveBridge(w, r)
+	case r.Method == "PUT" && strings.HasPrefix(r.URL.Path, "/shard/"):
+		f.serveShard(w, r)
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/ipfs/"):
+		f.serveGateway(w, r)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (f *fakeW3up) serveBridge(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Auth-Secret") != testAuthSecret || r.Header.Get("Authorization") != testAuthorization {
+		http.Error(w, `{"message":"bad credentials"}`, http.StatusUnauthorized)
+		return
+	}
+	var request struct {
+		Tasks [][]json.RawMessage `json:"tasks"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Tasks) != 1 || len(request.Tasks[0]) != 3 {
+		http.Error(w, `{"message":"bad request"}`, http.StatusBadRequest)
+		return
+	}
+	var ability, resource string
+	require.NoError(f.t, json.Unmarshal(request.Tasks[0][0], &ability))
+	require.NoError(f.t, json.Unmarshal(request.Tasks[0][1], &resource))
+	caveats := request.Tasks[0][2]
+	var ok any
+	var taskErr *api.Error
+	if resource != testSpace {
+		taskErr = &api.Error{Name: "Unauthorized", Message: "wrong space"}
+	} else {
+		ok, taskErr = f.runTask(ability, caveats)
+	}
+	var receipt struct {
+		P struct {
+			Out struct {
+				Ok    any        `json:"ok,omitempty"`
+				Error *api.Error `json:"error,omitempty"`
+			} `json:"out"`
+		} `json:"p"`
+	}
+	receipt.P.Out.Ok = ok
+	receipt.P.Out.Error = taskErr
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode([]any{receipt})
+}
+
+func (f *fakeW3up) runTask(ability string, caveats json.RawMessage) (any, *api.Error) {
+	switch ability {
+	case "store/add":
+		var in api.StoreAdd
+		require.NoError(f.t, json.Unmarshal(caveats, &in))
+		if _, found := f.shards[in.Link.CID]; found {
+			return api.StoreAddResult{Status: api.StoreStatusDone}, nil
+		}
+		f.allowed[in.Link.CID] = in.Size
+		return api.StoreAddResult{
+			Status:    api.StoreStatusUpload,
+			URL:       f.url + "/shard/" + in.Link.CID,
+			Headers:   map[string]string{"X-Fake-Size": fmt.Sprint(in.Size)},
+			Allocated: in.Size,
+		}, nil
+	case "store/remove":
+		var in api.StoreRemove
+		require.NoError(f.t, json.Unmarshal(caveats, &in))
+		if _, found := f.shards[in.Link.CID]; !found {
+			return nil, &api.Error{Name: api.ErrorStoreItemNotFound, Message: "not found"}
+		}
+		for _, upload := range f.uploads {
+			for _, shard := range upload.shards {
+				assert.NotEqual(f.t, in.Link.CID, shard, "removing shard still in use")
+			}
+		}
+		delete(f.shards, in.Link.CID)
+		return struct{}{}, nil
+	case "upload/add":
+		var in api.UploadAdd
+		require.NoError(f.t, json.Unmarshal(caveats, &in))
+		u := &fakeUpload{root: in.Root.CID, updatedAt: time.Now()}
+		for _, link := range in.Shards {
+			if _, found := f.shards[link.CID]; !found {
+				return nil, &api.Error{Name: "ShardNotFound", Message: link.CID}
+			}
+			u.shards = append(u.shards, link.CID)
+		}
+		f.uploads[in.Root.CID] = u
+		return struct{}{}, nil
+	case "upload/remove":
+		var in api.UploadRemove
+		require.NoError(f.t, json.Unmarshal(caveats, &in))
+		if _, found := f.uploads[in.Root.CID]; !found {
+			return nil, &api.Error{Name: api.ErrorUploadNotFound, Message: "not found"}
+		}
+		delete(f.uploads, in.Root.CID)
+		return struct{}{}, nil
+	case "upload/list":
+		var in api.UploadList
+		require.NoError(f.t, json.Unmarshal(caveats, &in))
+		roots := make([]string, 0, len(f.uploads))
+		for root := range f.uploads {
+			roots = append(roots, root)
+		}
+		sort.Strings(roots)
+		var result api.UploadListResult
+		for _, root := range roots {
+			if root <= in.Cursor || len(result.Results) >= in.Size {
+				continue
+			}
+			u := f.uploads[root]
+			item := api.Upload{Root: api.NewLink(root), UpdatedAt: u.updatedAt, InsertedAt: u.updatedAt}
+			for _, shard := range u.shards {
+				item.Shards = append(item.Shards, api.NewLink(shard))
+			}
+			result.Results = append(result.Results, item)
+			result.Cursor = root
+		}
+		result.Size = len(result.Results)
+		return result, nil
+	case "filecoin/offer":
+		var in api.FilecoinOffer
+		require.NoError(f.t, json.Unmarshal(caveats, &in))
+		if _, found := f.shards[in.Content.CID]; !found {
+			return nil, &api.Error{Name: "ContentNotFound", Message: in.Content.CID}
+		}
+		f.offers[in.Piece.CID] = in.Content.CID
+		return struct{}{}, nil
+	case "filecoin/info":
+		var in api.FilecoinInfo
+		require.NoError(f.t, json.Unmarshal(caveats, &in))
+		if _, found := f.offers[in.Piece.CID]; !found {
+			return nil, &api.Error{Name: "PieceNotFound", Message: in.Piece.CID}
+		}
+		result := api.FilecoinInfoResult{Piece: in.Piece}
+		if f.dealt[in.Piece.CID] {
+			var deal api.Deal
+			deal.Provider = "f01234"
+			deal.Aux.DataSource.DealID = 42
+			result.Aggregates = []api.Aggregate{{Aggregate: api.NewLink("bafkzcibaggregate")}}
+			result.Deals = []api.Deal{deal}
+		}
+		return result, nil
+	}
+	return nil, &api.Error{Name: "UnknownAbility", Message: ability}
+}
+
+// serveShard accepts a CAR, checking its CID and the CIDs of its blocks
+func (f *fakeW3up) serveShard(w http.ResponseWriter, r *http.Request) {
+	car := strings.TrimPrefix(r.URL.Path, "/shard/")
+	size, found := f.allowed[car]
+	if !found || r.Header.Get("X-Fake-Size") != fmt.Sprint(size) || r.ContentLength != size {
+		http.Error(w, "not allowed", http.StatusForbidden)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	require.NoError(f.t, err)
+	digest := sha256.Sum256(data)
+	require.Equal(f.t, car, newCID(codecCAR, mhSHA256, digest[:]).String(), "bad CAR CID")
+	blocks, err := parseCAR(data)
+	require.NoError(f.t, err)
+	var cids []cid
+	for _, blk := range blocks {
+		require.NoError(f.t, blk.cid.verify(blk.data))
+		f.blocks[blk.cid] = blk.data
+		cids = append(cids, blk.cid)
+	}
+	f.shards[car] = cids
+	delete(f.allowed, car)
+}
+
+// parseCAR reads the blocks of a CARv1 checking the header
+func parseCAR(data []byte) (blocks []block, err error) {
+	readSection := func() ([]byte, error) {
+		length, n := binary.Uvarint(data)
+		if n <= 0 || length > uint64(len(data)-n) {
+			return nil, errors.New("truncated CAR")
+		}
+		section := data[n : n+int(length)]
+		data = data[n+int(length):]
+		return section, nil
+	}
+	header, err := readSection()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(header, []byte("\xa2\x65roots\x81\xd8\x2a")) || !bytes.HasSuffix(header, []byte("\x67version\x01")) {
+		return nil, fmt.Errorf("bad CAR header %q", header)
+	}
+	for len(data) > 0 {
+		section, err := readSection()
+		if err != nil {
+			return nil, err
+		}
+		// find the end of the CID
+		b := section
+		for i := range 4 {
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return nil, errors.New("truncated CID")
+			}
+			b = b[n:]
+			if i == 3 {
+				b = b[v:]
+			}
+		}
+		cidLength := len(section) - len(b)
+		blocks = append(blocks, block{cid: cid(section[:cidLength]), data: section[cidLength:]})
+	}
+	return blocks, nil
+}
+
+// serveGateway serves blocks and files
+func (f *fakeW3up) serveGateway(w http.ResponseWriter, r *http.Request) {
+	c, err := parseCID(strings.TrimPrefix(r.URL.Path, "/ipfs/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, found := f.blocks[c]
+	if !found {
+		http.Error(w, "block not found", http.StatusNotFound)
+		return
+	}
+	if r.URL.Query().Get("format") == "raw" {
+		_, _ = w.Write(data)
+		return
+	}
+	var file bytes.Buffer
+	require.NoError(f.t, f.readFile(c, &file))
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(file.Bytes()))
+}
+
+// readFile writes the contents of the file at c to out
+func (f *fakeW3up) readFile(c cid, out io.Writer) error {
+	data, found := f.blocks[c]
+	if !found {
+		return fmt.Errorf("block %v not found", c)
+	}
+	if !c.isDagPB() {
+		_, err := out.Write(data)
+		return err
+	}
+	links, u, err := readNode(c, data)
+	if err != nil {
+		return err
+	}
+	if u.typ != unixfsFile {
+		return fmt.Errorf("%v is not a file", c)
+	}
+	for _, link := range links {
+		if err := f.readFile(link.hash, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TestFakeW3up runs the integration tests against a fake upload service
+func TestFakeW3up(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	fake := newFakeW3up(t)
+	name := "TestWeb3StorageFake"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "web3storage"},
+			{Name: name, Key: "space", Value: testSpace},
+			{Name: name, Key: "auth_secret", Value: obscure.MustObscure(testAuthSecret)},
+			{Name: name, Key: "authorization", Value: obscure.MustObscure(testAuthorization)},
+			{Name: name, Key: "endpoint", Value: fake.url},
+			{Name: name, Key: "gateway", Value: fake.url},
+		},
+		QuickTestOK: true,
+	})
+}
+
+// TestCID checks CIDs and nodes against well known values
+func TestCID(t *testing.T) {
+	c := sumCID(codecRaw, []byte("hello world"))
+	assert.Equal(t, "bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e", c.String())
+	parsed, err := parseCID(c.String())
+	require.NoError(t, err)
+	assert.Equal(t, c, parsed)
+
+	emptyDir := encodePBNode(nil, (&unixfsData{typ: unixfsDirectory}).encode())
+	assert.Equal(t, "bafybeiczsscdsbs7ffqz55asqdf3smv6klcw3gofszvwlyarci47bgf354", sumCID(codecDagPB, emptyDir).String())
+
+	_, err = parseCID("QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn")
+	assert.Error(t, err)
+	_, err = parseCID("bafybeiczsscdsbs7ffqz55asqdf3smv6klcw3gofszvwlyarci47bgf3")
+	assert.Error(t, err)
+}
+
+// TestUnixFS checks UnixFS nodes can be read back
+func TestUnixFS(t *testing.T) {
+	modTime := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	in := unixfsData{typ: unixfsFile, fileSize: 3, blockSizes: []uint64{1, 2}, modTime: modTime}
+	child := sumCID(codecRaw, []byte("x"))
+	node := encodePBNode([]pbLink{{hash: child, name: "x", tsize: 1}}, in.encode())
+	links, out, err := readNode(sumCID(codecDagPB, node), node)
+	require.NoError(t, err)
+	assert.Equal(t, []pbLink{{hash: child, name: "x", tsize: 1}}, links)
+	assert.Equal(t, in.typ, out.typ)
+	assert.Equal(t, in.fileSize, out.fileSize)
+	assert.Equal(t, in.blockSizes, out.blockSizes)
+	assert.True(t, modTime.Equal(out.modTime))
+
+	_, _, err = readNode(sumCID(codecDagPB, []byte("x")), node)
+	assert.ErrorContains(t, err, "corrupted")
+}
+
+// referencePiece computes the root and height of the piece tree of
+// data the simple way
+func referencePiece(data []byte) (root []byte, height int, padding uint64) {
+	chunks := (len(data) + unpaddedChunkSize - 1) / unpaddedChunkSize
+	paddedSize := uint64(paddedChunkSize) << bits.Len(uint(chunks-1))
+	padded := make([]byte, paddedSize)
+	unpadded := make([]byte, paddedSize/paddedChunkSize*unpaddedChunkSize)
+	copy(unpadded, data)
+	for i := 0; i < len(unpadded)/unpaddedChunkSize; i++ {
+		fr32Expand((*[unpaddedChunkSize]byte)(unpadded[i*unpaddedChunkSize:]), (*[paddedChunkSize]byte)(padded[i*paddedChunkSize:]))
+	}
+	var level [][]byte
+	for i := 0; i < len(padded); i += nodeSize {
+		level = append(level, padded[i:i+nodeSize])
+	}
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			node := hashPair(level[i], level[i+1])
+			next = append(next, node[:])
+		}
+		level = next
+		height++
+	}
+	return level[0], height, uint64(len(unpadded) - len(data))
+}
+
+// TestPiece checks the streaming piece hasher against the simple way
+func TestPiece(t *testing.T) {
+	// fr32 expansion keeps all the bits
+	var in [unpaddedChunkSize]byte
+	var out [paddedChunkSize]byte
+	for i := range in {
+		in[i] = 0xff
+	}
+	fr32Expand(&in, &out)
+	ones := 0
+	for _, b := range out {
+		ones += bits.OnesCount8(b)
+	}
+	assert.Equal(t, unpaddedChunkSize*8, ones)
+
+	_, err := newPieceHasher().cid()
+	assert.Error(t, err)
+
+	rng := rand.New(rand.NewSource(1))
+	for _, size := range []int{65, 126, 127, 128, 254, 508, 509, 1000, 127 * 1024, 100000} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			data := make([]byte, size)
+			_, _ = rng.Read(data)
+			p := newPieceHasher()
+			// write in odd sized pieces
+			for rest := data; len(rest) > 0; {
+				n := min(len(rest), 1+rng.Intn(300))
+				_, _ = p.Write(rest[:n])
+				rest = rest[n:]
+			}
+			piece, err := p.cid()
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(piece.String(), "bafkzcib"), piece.String())
+
+			root, height, padding := referencePiece(data)
+			want := binary.AppendUvarint(nil, padding)
+			want = append(want, byte(height))
+			want = append(want, root...)
+			assert.Equal(t, newCID(codecRaw, mhPieceTree, want), piece)
+		})
+	}
+}
+
+// TestDAG checks the DAG made from a file is read back correctly
+func TestDAG(t *testing.T) {
+	fake := newFakeW3up(t)
+	modTime := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	for _, size := range []int64{0, 1, chunkSize, 3*chunkSize + 1} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			data := bytes.Repeat([]byte{'x'}, int(size))
+			dag, err := newFileDAG(bytes.NewReader(data), size, []string{"dir", "file.txt"}, modTime)
+			require.NoError(t, err)
+			var car bytes.Buffer
+			require.NoError(t, dag.writeCAR(&car))
+			blocks, err := parseCAR(car.Bytes())
+			require.NoError(t, err)
+			for _, blk := range blocks {
+				require.NoError(t, blk.cid.verify(blk.data))
+				fake.blocks[blk.cid] = blk.data
+			}
+			assert.Equal(t, dag.root, blocks[0].cid, "root must be first")
+
+			s, err := dag.shard()
+			require.NoError(t, err)
+			assert.Equal(t, int64(car.Len()), s.size)
+			digest := sha256.Sum256(car.Bytes())
+			assert.Equal(t, newCID(codecCAR, mhSHA256, digest[:]), s.car)
+
+			var out bytes.Buffer
+			require.NoError(t, fake.readFile(dag.file, &out))
+			assert.Equal(t, string(data), out.String())
+		})
+	}
+}
+
+// TestMetadata checks the CIDs and deal status returned as metadata
+func TestMetadata(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	fake := newFakeW3up(t)
+	f, err := fs.NewFs(ctx, fake.remote()+"meta")
+	require.NoError(t, err)
+
+	contents := "hello filecoin, this is more than sixty five bytes long to make a piece"
+	modTime := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	src := object.NewStaticObjectInfo("file.txt", modTime, int64(len(contents)), true, nil, nil)
+	o, err := f.Put(ctx, strings.NewReader(contents), src)
+	require.NoError(t, err)
+
+	m, err := o.(fs.Metadataer).Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, o.(fs.IDer).ID(), m["root-cid"])
+	for _, key := range []string{"file-cid", "car-cid", "piece-cid"} {
+		assert.NotEmpty(t, m[key], key)
+	}
+	assert.Contains(t, fake.offers, m["piece-cid"])
+	assert.Equal(t, fake.offers[m["piece-cid"]], m["car-cid"])
+	assert.Equal(t, dealStatusQueued, m["deal-status"])
+	assert.NotContains(t, m, "deals")
+
+	// A new Fs has to compute the piece from the contents
+	fake.dealt[m["piece-cid"]] = true
+	m["deal-status"] = dealStatusDealt
+	m["deals"] = "f01234:42"
+	m["aggregate-cid"] = "bafkzcibaggregate"
+	f2, err := fs.NewFs(ctx, fake.remote()+"meta")
+	require.NoError(t, err)
+	o2, err := f2.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.True(t, modTime.Equal(o2.ModTime(ctx)))
+	m2, err := o2.(fs.Metadataer).Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, m, m2)
+
+	// Unless that is disabled
+	f3, err := fs.NewFs(ctx, strings.Replace(fake.remote(), ":web3storage,", ":web3storage,compute_pieces=false,", 1)+"meta")
+	require.NoError(t, err)
+	o3, err := f3.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	m3, err := o3.(fs.Metadataer).Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, m["root-cid"], m3["root-cid"])
+	assert.NotContains(t, m3, "piece-cid")
+
+	// The offer command offers the piece again
+	fake.offers = map[string]string{}
+	out, err := f3.(fs.Commander).Command(ctx, "offer", []string{"file.txt"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"file.txt": m["piece-cid"]}, out)
+	assert.Contains(t, fake.offers, m["piece-cid"])
+}
+
+// TestForeignUploads checks uploads not made by rclone are ignored
+// and shared shards aren't removed
+func TestForeignUploads(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	fake := newFakeW3up(t)
+	f, err := fs.NewFs(ctx, fake.remote())
+	require.NoError(t, err)
+
+	src := object.NewStaticObjectInfo("dir/file.txt", time.Now(), 5, true, nil, nil)
+	o, err := f.Put(ctx, strings.NewReader("hello"), src)
+	require.NoError(t, err)
+	u := o.(*Object).upload
+
+	// An upload of the bare file sharing the shard
+	fake.uploads[u.file.String()] = &fakeUpload{root: u.file.String(), shards: []string{u.shards[0].String()}}
+	// An upload of a raw block
+	raw := sumCID(codecRaw, []byte("raw"))
+	fake.blocks[raw] = []byte("raw")
+	fake.uploads[raw.String()] = &fakeUpload{root: raw.String()}
+
+	f2, err := fs.NewFs(ctx, fake.remote())
+	require.NoError(t, err)
+	entries, err := f2.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "dir", entries[0].Remote())
+	entries, err = f2.List(ctx, "dir")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// Removing the file keeps the shared shard
+	require.NoError(t, entries[0].(fs.Object).Remove(ctx))
+	assert.Contains(t, fake.shards, u.shards[0].String())
+	_, err = f2.NewObject(ctx, "dir/file.txt")
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+
+	// The root can be a file
+	_, err = f.Put(ctx, strings.NewReader("hello"), src)
+	require.NoError(t, err)
+	_, err = fs.NewFs(ctx, fake.remote()+"dir/file.txt")
+	assert.ErrorIs(t, err, fs.ErrorIsFile)
+}
@@ -0,0 +1,17 @@
+// Test web3.storage filesystem interface
+package web3storage_test
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/backend/web3storage"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestWeb3Storage:",
+		NilObject:  (*web3storage.Object)(nil),
+	})
+}
@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/pool"
 	"github.com/rclone/rclone/lib/readers"
 	"github.com/rclone/rclone/lib/rest"
 )
@@ -105,6 +106,8 @@ func (o *Object) updateChunked(ctx context.Context, in0 io.Reader, src fs.Object
 
 func (o *Object) uploadChunks(ctx context.Context, in0 io.Reader, size int64, partObj *Object, uploadDir string, options []fs.OpenOption) error {
 	chunkSize := int64(partObj.fs.opt.ChunkSize)
+	buf := pool.GetBuffer(int(chunkSize))
+	defer pool.PutBuffer(buf)
 
 	// TODO: upload chunks in parallel for faster transfer speeds
 	for offset := int64(0); offset < size; offset += chunkSize {
@@ -121,7 +124,6 @@ func (o *Object) uploadChunks(ctx context.Context, in0 io.Reader, size int64, pa
 		// Enable low-level HTTP 2 retries.
 		// 2022-04-28 15:59:06 ERROR : stuff/video.avi: Failed to copy: uploading chunk failed: Put "https://censored.com/remote.php/dav/uploads/Admin/rclone-chunked-upload-censored/000006113198080-000006123683840": http2: Transport: cannot retry err [http2: Transport received Server's graceful shutdown GOAWAY] after Request.Body was written; define Request.GetBody to avoid this error
 
-		buf := make([]byte, chunkSize)
 		in := readers.NewRepeatableLimitReaderBuffer(in0, buf, chunkSize)
 
 		getBody := func() (io.ReadCloser, error) {
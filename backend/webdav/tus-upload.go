@@ -1,8 +1,8 @@
 package webdav
 
 import (
-	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -13,9 +13,11 @@ type Metadata map[string]string
 
 // Upload is a struct containing the file status during upload
 type Upload struct {
-	stream io.ReadSeeker
-	size   int64
-	offset int64
+	stream    io.Reader
+	size      int64
+	offset    int64
+	buf       []byte // the chunk most recently read from stream
+	bufOffset int64  // the offset of buf in the upload
 
 	Fingerprint string
 	Metadata    Metadata
@@ -62,16 +64,12 @@ func b64encode(s string) string {
 }
 
 // NewUpload creates a new upload from an io.Reader.
-func NewUpload(reader io.Reader, size int64, metadata Metadata, fingerprint string) *Upload {
-	stream, ok := reader.(io.ReadSeeker)
-
-	if !ok {
-		buf := new(bytes.Buffer)
-		_, err := buf.ReadFrom(reader)
-		if err != nil {
-			return nil
-		}
-		stream = bytes.NewReader(buf.Bytes())
+//
+// The reader is read a chunk at a time as the upload progresses so
+// only one chunk is held in memory.
+func NewUpload(reader io.Reader, size int64, metadata Metadata, fingerprint string) (*Upload, error) {
+	if size < 0 {
+		return nil, errors.New("tus upload needs the size of the file")
 	}
 
 	if metadata == nil {
@@ -79,10 +77,50 @@ func NewUpload(reader io.Reader, size int64, metadata Metadata, fingerprint stri
 	}
 
 	return &Upload{
-		stream: stream,
+		stream: reader,
 		size:   size,
 
 		Fingerprint: fingerprint,
 		Metadata:    metadata,
+	}, nil
+}
+
+// chunk returns the data to upload from offset, at most chunkSize
+// bytes.
+//
+// This reads the next chunk from the stream unless offset is in the
+// chunk already read, which happens when an upload is resumed part way
+// through a chunk. If the stream can't seek then offset must be in the
+// chunk already read or at its end.
+func (u *Upload) chunk(offset int64, chunkSize int) ([]byte, error) {
+	bufEnd := u.bufOffset + int64(len(u.buf))
+	if offset >= u.bufOffset && offset < bufEnd {
+		data := u.buf[offset-u.bufOffset:]
+		return data[:min(len(data), chunkSize)], nil
+	}
+	if offset != bufEnd {
+		seeker, ok := u.stream.(io.Seeker)
+		if !ok {
+			return nil, fmt.Errorf("can't resume tus upload at offset %d as the source can't seek", offset)
+		}
+		_, err := seeker.Seek(offset, io.SeekStart)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seek tus upload to offset %d: %w", offset, err)
+		}
+	}
+	chunkSize = int(min(int64(chunkSize), u.size-offset))
+	if cap(u.buf) < chunkSize {
+		u.buf = make([]byte, chunkSize)
+	}
+	u.buf = u.buf[:chunkSize]
+	u.bufOffset = offset
+	n, err := io.ReadFull(u.stream, u.buf)
+	u.buf = u.buf[:n]
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
 	}
+	return u.buf, nil
 }
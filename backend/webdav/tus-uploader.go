@@ -3,6 +3,7 @@ package webdav
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"strconv"
 
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/lib/rest"
 )
 
@@ -65,7 +67,7 @@ func (u *Uploader) uploadChunk(ctx context.Context, body io.Reader, size int64,
 
 	extraHeaders := map[string]string{} // FIXME: Use extraHeaders(ctx, src) from Object maybe?
 	extraHeaders["Upload-Offset"] = strconv.FormatInt(offset, 10)
-	extraHeaders["Tus-Resumable"] = "1.0.0"
+	extraHeaders["Tus-Resumable"] = tusVersion
 	extraHeaders["filetype"] = u.upload.Metadata["filetype"]
 	if u.overridePatchMethod {
 		extraHeaders["X-HTTP-Method-Override"] = "PATCH"
@@ -104,17 +106,75 @@ func (u *Uploader) uploadChunk(ctx context.Context, body io.Reader, size int64,
 	return newOffset, nil
 }
 
+// getOffset asks the server how much of the upload it has received
+func (u *Uploader) getOffset(ctx context.Context) (int64, error) {
+	opts := rest.Opts{
+		Method:       "HEAD",
+		RootURL:      u.url,
+		NoResponse:   true,
+		ExtraHeaders: map[string]string{"Tus-Resumable": tusVersion},
+	}
+	var offset int64
+	err := u.fs.pacer.Call(func() (bool, error) {
+		resp, err := u.fs.srv.Call(ctx, &opts)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, ErrUploadNotFound
+		}
+		if err == nil {
+			offset, err = strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+			if err != nil {
+				return false, fmt.Errorf("bad Upload-Offset: %w", err)
+			}
+		}
+		return u.fs.shouldRetry(ctx, resp, err)
+	})
+	return offset, err
+}
+
+// resume finds out where the server got to after a chunk failed
+// with err so the upload can carry on from there.
+//
+// It returns err if the upload can't be resumed.
+func (u *Uploader) resume(ctx context.Context, err error) error {
+	if errors.Is(err, ErrVersionMismatch) || errors.Is(err, ErrLargeUpload) || fserrors.ContextError(ctx, &err) {
+		return err
+	}
+	offset, offsetErr := u.getOffset(ctx)
+	if offsetErr != nil {
+		fs.Debugf(u.fs, "Failed to read tus upload offset: %v", offsetErr)
+		return err
+	}
+	fs.Debugf(u.fs, "Resuming tus upload at offset %d after error: %v", offset, err)
+	u.offset = offset
+	u.upload.updateProgress(offset)
+	return nil
+}
+
 // Upload uploads the entire body to the server.
+//
+// If a chunk fails the upload is resumed from the offset the server
+// has, up to --low-level-retries times in a row.
 func (u *Uploader) Upload(ctx context.Context, options ...fs.OpenOption) error {
 	cnt := 1
+	retries := 0
+	maxRetries := fs.GetConfig(ctx).LowLevelRetries
 
 	fs.Debug(u.fs, "Uploaded starts")
 	for u.offset < u.upload.size && !u.aborted {
 		err := u.UploadChunk(ctx, cnt, options...)
 		cnt++
 		if err != nil {
-			return err
+			retries++
+			if retries >= maxRetries {
+				return err
+			}
+			err = u.resume(ctx, err)
+			if err != nil {
+				return err
+			}
+			continue
 		}
+		retries = 0
 	}
 	fs.Debug(u.fs, "-- Uploaded finished")
 
@@ -123,26 +183,15 @@ func (u *Uploader) Upload(ctx context.Context, options ...fs.OpenOption) error {
 
 // UploadChunk uploads a single chunk.
 func (u *Uploader) UploadChunk(ctx context.Context, cnt int, options ...fs.OpenOption) error {
-	chunkSize := u.fs.opt.ChunkSize
-	data := make([]byte, chunkSize)
-
-	_, err := u.upload.stream.Seek(u.offset, 0)
-
-	if err != nil {
-		fs.Errorf(u.fs, "Chunk %d: Error seek in stream failed: %v", cnt, err)
-		return err
-	}
-
-	size, err := u.upload.stream.Read(data)
-
+	data, err := u.upload.chunk(u.offset, int(u.fs.opt.TusChunkSize))
 	if err != nil {
-		fs.Errorf(u.fs, "Chunk %d: Error: Can not read from data strem: %v", cnt, err)
+		fs.Errorf(u.fs, "Chunk %d: Error: Can not read from data stream: %v", cnt, err)
 		return err
 	}
 
-	body := bytes.NewBuffer(data[:size])
+	body := bytes.NewReader(data)
 
-	newOffset, err := u.uploadChunk(ctx, body, int64(size), u.offset, options...)
+	newOffset, err := u.uploadChunk(ctx, body, int64(len(data)), u.offset, options...)
 
 	if err == nil {
 		fs.Debugf(u.fs, "Uploaded chunk no %d ok, range %d -> %d", cnt, u.offset, newOffset)
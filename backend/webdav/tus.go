@@ -12,11 +12,77 @@ import (
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/lib/rest"
 )
 
+const tusVersion = "1.0.0"
+
+// parseTusHeaders checks the Tus-* headers from an OPTIONS response
+// to see whether the server supports creating uploads with the
+// version of the protocol we speak. It returns the maximum upload
+// size, or 0 if there isn't one.
+func parseTusHeaders(header http.Header) (ok bool, maxSize int64) {
+	hasToken := func(name, want string) bool {
+		for _, value := range header.Values(name) {
+			for _, token := range strings.Split(value, ",") {
+				if strings.TrimSpace(token) == want {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if !hasToken("Tus-Version", tusVersion) || !hasToken("Tus-Extension", "creation") {
+		return false, 0
+	}
+	maxSize, err := strconv.ParseInt(header.Get("Tus-Max-Size"), 10, 64)
+	if err != nil || maxSize < 0 {
+		maxSize = 0
+	}
+	return true, maxSize
+}
+
+// detectTus sends an OPTIONS request to the server to see if it
+// supports the tus protocol.
+//
+// Any errors are logged and treated as no support.
+func (f *Fs) detectTus(ctx context.Context) bool {
+	opts := rest.Opts{
+		Method:       "OPTIONS",
+		Path:         f.filePath(""),
+		NoResponse:   true,
+		ExtraHeaders: map[string]string{"Tus-Resumable": tusVersion},
+	}
+	var resp *http.Response
+	err := f.pacer.Call(func() (bool, error) {
+		var err error
+		resp, err = f.srv.Call(ctx, &opts)
+		return f.shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		fs.Debugf(f, "Failed to detect tus support: %v", err)
+		return false
+	}
+	ok, maxSize := parseTusHeaders(resp.Header)
+	if ok {
+		fs.Debugf(f, "Server supports tus uploads (max size %d)", maxSize)
+		f.tusMaxSize = maxSize
+	}
+	return ok
+}
+
+// shouldUseTus returns true if src should be uploaded with tus
+func (o *Object) shouldUseTus(src fs.ObjectInfo) bool {
+	size := src.Size()
+	if !o.fs.canTus || size < 0 {
+		return false
+	}
+	return o.fs.tusMaxSize == 0 || size <= o.fs.tusMaxSize
+}
+
 func (o *Object) updateViaTus(ctx context.Context, in io.Reader, contentType string, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
 
 	fn := filepath.Base(src.Remote())
@@ -30,7 +96,10 @@ func (o *Object) updateViaTus(ctx context.Context, in io.Reader, contentType str
 	fingerprint := ""
 
 	// create an upload from a file.
-	upload := NewUpload(in, src.Size(), metadata, fingerprint)
+	upload, err := NewUpload(in, src.Size(), metadata, fingerprint)
+	if err != nil {
+		return err
+	}
 
 	// create the uploader.
 	uploader, err := o.CreateUploader(ctx, upload, options...)
@@ -43,6 +112,10 @@ func (o *Object) updateViaTus(ctx context.Context, in io.Reader, contentType str
 }
 
 func (f *Fs) getTusLocationOrRetry(ctx context.Context, resp *http.Response, err error) (bool, string, error) {
+	if resp == nil {
+		retry, err := f.shouldRetry(ctx, resp, err)
+		return retry, "", err
+	}
 
 	switch resp.StatusCode {
 	case 201:
@@ -87,7 +160,7 @@ func (o *Object) CreateUploader(ctx context.Context, u *Upload, options ...fs.Op
 	}
 	opts.ExtraHeaders["Upload-Length"] = strconv.FormatInt(u.size, 10)
 	opts.ExtraHeaders["Upload-Metadata"] = u.EncodedMetadata()
-	opts.ExtraHeaders["Tus-Resumable"] = "1.0.0"
+	opts.ExtraHeaders["Tus-Resumable"] = tusVersion
 	// opts.ExtraHeaders["mtime"] = strconv.FormatInt(src.ModTime(ctx).Unix(), 10)
 
 	var tusLocation string
@@ -102,6 +175,13 @@ func (o *Object) CreateUploader(ctx context.Context, u *Upload, options ...fs.Op
 		return nil, fmt.Errorf("making upload directory failed: %w", err)
 	}
 
+	// The Location may be relative to the endpoint
+	location, err := o.fs.endpoint.Parse(tusLocation)
+	if err != nil {
+		return nil, fmt.Errorf("bad tus upload location %q: %w", tusLocation, err)
+	}
+	tusLocation = location.String()
+
 	uploader := NewUploader(o.fs, tusLocation, u, 0)
 
 	return uploader, nil
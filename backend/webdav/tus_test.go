@@ -0,0 +1,121 @@
+package webdav
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTusHeaders(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		header      http.Header
+		wantOK      bool
+		wantMaxSize int64
+	}{{
+		name:   "none",
+		header: http.Header{},
+	}, {
+		name: "no creation",
+		header: http.Header{
+			"Tus-Version":   {"1.0.0"},
+			"Tus-Extension": {"termination"},
+		},
+	}, {
+		name: "wrong version",
+		header: http.Header{
+			"Tus-Version":   {"0.2.2"},
+			"Tus-Extension": {"creation"},
+		},
+	}, {
+		name: "ok",
+		header: http.Header{
+			"Tus-Version":   {"1.0.0,0.2.2"},
+			"Tus-Extension": {"creation, creation-with-upload,termination"},
+		},
+		wantOK: true,
+	}, {
+		name: "max size",
+		header: http.Header{
+			"Tus-Version":   {"1.0.0"},
+			"Tus-Extension": {"creation"},
+			"Tus-Max-Size":  {"1073741824"},
+		},
+		wantOK:      true,
+		wantMaxSize: 1 << 30,
+	}, {
+		name: "bad max size",
+		header: http.Header{
+			"Tus-Version":   {"1.0.0"},
+			"Tus-Extension": {"creation"},
+			"Tus-Max-Size":  {"potato"},
+		},
+		wantOK: true,
+	}} {
+		t.Run(test.name, func(t *testing.T) {
+			ok, maxSize := parseTusHeaders(test.header)
+			assert.Equal(t, test.wantOK, ok)
+			assert.Equal(t, test.wantMaxSize, maxSize)
+		})
+	}
+}
+
+func TestUploadChunk(t *testing.T) {
+	const contents = "0123456789"
+
+	_, err := NewUpload(strings.NewReader(contents), -1, nil, "")
+	assert.Error(t, err)
+
+	t.Run("Stream", func(t *testing.T) {
+		// hide the Seek method
+		u, err := NewUpload(io.MultiReader(strings.NewReader(contents)), int64(len(contents)), nil, "")
+		require.NoError(t, err)
+
+		data, err := u.chunk(0, 4)
+		require.NoError(t, err)
+		assert.Equal(t, "0123", string(data))
+
+		// resume in the current chunk
+		data, err = u.chunk(2, 4)
+		require.NoError(t, err)
+		assert.Equal(t, "23", string(data))
+
+		data, err = u.chunk(4, 4)
+		require.NoError(t, err)
+		assert.Equal(t, "4567", string(data))
+
+		// can't go back to a previous chunk
+		_, err = u.chunk(1, 4)
+		assert.ErrorContains(t, err, "can't seek")
+
+		data, err = u.chunk(8, 4)
+		require.NoError(t, err)
+		assert.Equal(t, "89", string(data))
+	})
+
+	t.Run("Seek", func(t *testing.T) {
+		u, err := NewUpload(bytes.NewReader([]byte(contents)), int64(len(contents)), nil, "")
+		require.NoError(t, err)
+
+		data, err := u.chunk(4, 4)
+		require.NoError(t, err)
+		assert.Equal(t, "4567", string(data))
+
+		data, err = u.chunk(1, 4)
+		require.NoError(t, err)
+		assert.Equal(t, "1234", string(data))
+	})
+
+	t.Run("Short", func(t *testing.T) {
+		u, err := NewUpload(strings.NewReader(contents), 20, nil, "")
+		require.NoError(t, err)
+
+		_, err = u.chunk(0, 16)
+		assert.Equal(t, io.ErrUnexpectedEOF, err)
+	})
+}
@@ -152,6 +152,32 @@ Set to 0 to disable chunked uploading.
 `,
 			Advanced: true,
 			Default:  10 * fs.Mebi, // Default NextCloud `max_chunk_size` is `10 MiB`. See https://github.com/nextcloud/server/blob/0447b53bda9fe95ea0cbed765aa332584605d652/apps/files/lib/App.php#L57
+		}, {
+			Name: "tus",
+			Help: `Use the TUS protocol for resumable uploads.
+
+TUS uploads files in chunks which are resumed from where the server
+got to if one fails. This avoids request body limits in reverse
+proxies which would otherwise make large uploads fail.
+
+If this is unset (the default) then rclone will always use TUS for
+ownCloud Infinite Scale, and for the owncloud, nextcloud and other
+vendors will send an OPTIONS request to the server to detect whether
+it supports TUS. Set this to true or false to override the detection.
+
+See https://tus.io/protocols/resumable-upload
+`,
+			Default:  fs.Tristate{},
+			Advanced: true,
+		}, {
+			Name: "tus_chunk_size",
+			Help: `Chunk size for TUS uploads.
+
+Each chunk is buffered in memory so larger chunks use more memory but
+will upload faster. Make sure this is below any request body limit of
+the server or reverse proxy.`,
+			Default:  10 * fs.Mebi,
+			Advanced: true,
 		}, {
 			Name:     "owncloud_exclude_shares",
 			Help:     "Exclude ownCloud shares",
@@ -197,6 +223,8 @@ type Options struct {
 	Headers            fs.CommaSepList      `config:"headers"`
 	PacerMinSleep      fs.Duration          `config:"pacer_min_sleep"`
 	ChunkSize          fs.SizeSuffix        `config:"nextcloud_chunk_size"`
+	Tus                fs.Tristate          `config:"tus"`
+	TusChunkSize       fs.SizeSuffix        `config:"tus_chunk_size"`
 	ExcludeShares      bool                 `config:"owncloud_exclude_shares"`
 	ExcludeMounts      bool                 `config:"owncloud_exclude_mounts"`
 	UnixSocket         string               `config:"unix_socket"`
@@ -216,6 +244,7 @@ type Fs struct {
 	precision          time.Duration // mod time precision
 	canStream          bool          // set if can stream
 	canTus             bool          // supports the TUS upload protocol
+	tusMaxSize         int64         // max size of a TUS upload as returned by the server or 0 for no limit
 	useOCMtime         bool          // set if can use X-OC-Mtime
 	propsetMtime       bool          // set if can use propset
 	retryWithZeroDepth bool          // some vendors (sharepoint) won't list files when Depth is 1 (our default)
@@ -644,7 +673,6 @@ func (f *Fs) setQuirks(ctx context.Context, vendor string) error {
 		f.hasOCSHA1 = true
 		f.canChunk = false
 		f.canTus = true
-		f.opt.ChunkSize = 10 * fs.Mebi
 	case "nextcloud":
 		f.precision = time.Second
 		f.useOCMtime = true
@@ -709,6 +737,19 @@ func (f *Fs) setQuirks(ctx context.Context, vendor string) error {
 		fs.Debugf(f, "Unknown vendor %q", vendor)
 	}
 
+	switch vendor {
+	case "owncloud", "nextcloud", "other":
+		if !f.opt.Tus.Valid {
+			f.canTus = f.detectTus(ctx)
+		}
+	}
+	if f.opt.Tus.Valid {
+		f.canTus = f.opt.Tus.Value
+	}
+	if f.canTus && f.opt.TusChunkSize <= 0 {
+		return ErrChunkSize
+	}
+
 	// Remove PutStream from optional features
 	if !f.canStream {
 		f.features.PutStream = nil
@@ -1512,7 +1553,7 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		return fmt.Errorf("Update mkParentDir failed: %w", err)
 	}
 
-	if o.fs.canTus { // supports the tus upload protocol, ie. InfiniteScale
+	if o.shouldUseTus(src) { // supports the tus upload protocol, ie. InfiniteScale
 		fs.Debugf(src, "Update will use the tus protocol to upload")
 		contentType := fs.MimeType(ctx, src)
 		err = o.updateViaTus(ctx, in, contentType, src, options...)
@@ -32,6 +32,7 @@ docs = [
     "fichier.md",
     "alias.md",
     "s3.md",
+    "arweave.md",
     "b2.md",
     "box.md",
     "cache.md",
@@ -45,6 +46,7 @@ docs = [
     "filefabric.md",
     "filescom.md",
     "ftp.md",
+    "gitlfs.md",
     "gofile.md",
     "googlecloudstorage.md",
     "drive.md",
@@ -56,6 +58,7 @@ docs = [
     "imagekit.md",
     "iclouddrive.md",
     "internetarchive.md",
+    "ipfs.md",
     "jottacloud.md",
     "koofr.md",
     "linkbox.md",
@@ -63,6 +66,7 @@ docs = [
     "mega.md",
     "memory.md",
     "netstorage.md",
+    "nfs.md",
     "azureblob.md",
     "azurefiles.md",
     "onedrive.md",
@@ -70,6 +74,8 @@ docs = [
     "oracleobjectstorage/_index.md",
     "qingstor.md",
     "quatrix.md",
+    "renterd.md",
+    "rsyncd.md",
     "sia.md",
     "swift.md",
     "pcloud.md",
@@ -82,11 +88,14 @@ docs = [
     "seafile.md",
     "sftp.md",
     "smb.md",
+    "sqlblob.md",
     "storj.md",
     "sugarsync.md",
+    "torrent.md",
     "ulozto.md",
     "uptobox.md",
     "union.md",
+    "web3storage.md",
     "webdav.md",
     "yandex.md",
     "zoho.md",
@@ -1132,6 +1132,10 @@ func (b *bisyncTest) runBisync(ctx context.Context, args []string) (err error) {
 			opt.ConflictSuffixFlag = val
 		case "resync-mode":
 			_ = opt.ResyncMode.Set(val)
+		case "conflict-merge":
+			_ = opt.ConflictMerge.Set(val)
+		case "detect-renames":
+			opt.DetectRenames = true
 		default:
 			return fmt.Errorf("invalid bisync option %q", arg)
 		}
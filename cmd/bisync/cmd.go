@@ -61,6 +61,10 @@ type Options struct {
 	ConflictSuffixFlag    string
 	ConflictSuffix1       string
 	ConflictSuffix2       string
+	ConflictMerge         fs.SpaceSepList
+	DetectRenames         bool
+	Watch                 bool
+	WatchInterval         time.Duration
 }
 
 // Default values
@@ -119,6 +123,7 @@ var Opt Options
 
 func init() {
 	Opt.MaxLock = 0
+	Opt.WatchInterval = DefaultWatchInterval
 	cmd.Root.AddCommand(commandDefinition)
 	cmdFlags := commandDefinition.Flags()
 	// when adding new flags, remember to also update the rc params:
@@ -150,6 +155,10 @@ func init() {
 	flags.FVarP(cmdFlags, &Opt.ConflictResolve, "conflict-resolve", "", "Automatically resolve conflicts by preferring the version that is: "+ConflictResolveList+" (default: none)", "")
 	flags.FVarP(cmdFlags, &Opt.ConflictLoser, "conflict-loser", "", "Action to take on the loser of a sync conflict (when there is a winner) or on both files (when there is no winner): "+ConflictLoserList+" (default: num)", "")
 	flags.StringVarP(cmdFlags, &Opt.ConflictSuffixFlag, "conflict-suffix", "", Opt.ConflictSuffixFlag, "Suffix to use when renaming a --conflict-loser. Can be either one string or two comma-separated strings to assign different suffixes to Path1/Path2. (default: 'conflict')", "")
+	flags.FVarP(cmdFlags, &Opt.ConflictMerge, "conflict-merge", "", "Merge files changed on both sides with this command, or '"+MergeUnion+"' for a built-in merge of text files. (default: off)", "")
+	flags.BoolVarP(cmdFlags, &Opt.DetectRenames, "detect-renames", "", Opt.DetectRenames, "Detect renamed files from the listings and rename them on the other side with a server-side move.", "")
+	flags.BoolVarP(cmdFlags, &Opt.Watch, "watch", "", Opt.Watch, "Keep running and bisync again whenever Path1 or Path2 changes.", "")
+	flags.DurationVarP(cmdFlags, &Opt.WatchInterval, "watch-interval", "", Opt.WatchInterval, "How often to check for changes with --watch", "")
 	_ = cmdFlags.MarkHidden("debugname")
 	_ = cmdFlags.MarkHidden("localtime")
 }
@@ -192,7 +201,12 @@ var commandDefinition = &cobra.Command{
 
 		fs.Logf(nil, "bisync is IN BETA. Don't use in production!")
 		cmd.Run(false, true, command, func() error {
-			err := Bisync(ctx, fs1, fs2, &opt)
+			var err error
+			if opt.Watch {
+				err = Watch(ctx, fs1, fs2, &opt)
+			} else {
+				err = Bisync(ctx, fs1, fs2, &opt)
+			}
 			if err == ErrBisyncAborted {
 				return fserrors.FatalError(err)
 			}
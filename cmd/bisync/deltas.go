@@ -55,6 +55,7 @@ type deltaSet struct {
 	deleted    int    // number of deleted files (for "excess deletes" check)
 	foundSame  bool   // true if found at least one unchanged file
 	checkFiles bilib.Names
+	renamed    map[string]string // [newName]oldName for files renamed (with --detect-renames)
 }
 
 func (ds *deltaSet) empty() bool {
@@ -176,6 +177,7 @@ func (b *bisyncRun) findDeltas(fctx context.Context, f fs.Fs, oldListing string,
 		oldCount:   len(old.list),
 		opt:        b.opt,
 		checkFiles: bilib.Names{},
+		renamed:    map[string]string{},
 	}
 
 	for _, file := range old.list {
@@ -270,6 +272,10 @@ func (b *bisyncRun) findDeltas(fctx context.Context, f fs.Fs, oldListing string,
 		}
 	}
 
+	if b.opt.DetectRenames {
+		b.findRenames(ds, old, now)
+	}
+
 	if b.opt.CheckAccess {
 		// checkFiles is a small structure compared with the `now`, so we
 		// return it alone and let the full delta map be garbage collected.
@@ -304,6 +310,12 @@ func (b *bisyncRun) applyDeltas(ctx context.Context, ds1, ds2 *deltaSet) (result
 	// update AliasMap for deleted files, as march does not know about them
 	b.updateAliases(ctx, ds1, ds2)
 
+	// do renames as server-side moves instead of deleting and copying
+	if b.opt.DetectRenames {
+		b.moveRenames(ctxMove, ds1, ds2, 1)
+		b.moveRenames(ctxMove, ds2, ds1, 2)
+	}
+
 	// efficient isDir check
 	// we load the listing just once and store only the dirs
 	dirs1, dirs1Err := b.listDirsOnly(1)
@@ -427,10 +439,17 @@ func (b *bisyncRun) applyDeltas(ctx context.Context, ds1, ds2 *deltaSet) (result
 						}
 					} else {
 						fs.Debugf(nil, "Files are NOT equal: %s", file)
-						err = b.resolve(ctxMove, path1, path2, file, alias, &renameSkipped, &copy1to2, &copy2to1, ds1, ds2)
+						var merged bool
+						merged, err = b.mergeConflict(ctxMove, path1, file, alias, &renameSkipped, &copy1to2)
 						if err != nil {
 							return
 						}
+						if !merged {
+							err = b.resolve(ctxMove, path1, path2, file, alias, &renameSkipped, &copy1to2, &copy2to1, ds1, ds2)
+							if err != nil {
+								return
+							}
+						}
 					}
 				}
 				handled.Add(file)
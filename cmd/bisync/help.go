@@ -41,6 +41,13 @@ var rcHelp = makeHelp(`This takes the following parameters
 - backupdir1 - --backup-dir for Path1. Must be a non-overlapping path on the same remote.
 - backupdir2 - --backup-dir for Path2. Must be a non-overlapping path on the same remote.
 - noCleanup - retain working files
+- conflictMerge - merge files changed on both paths with this command,
+              or |union| for a built-in merge of text files
+- detectRenames - detect renamed files and rename them on the other side with a server-side move
+- watch - keep running and bisync again whenever path1 or path2 changes.
+          This never finishes so it must be used with |_async=true|,
+          otherwise the call fails. Stop it with |job/stop|.
+- watchInterval - how often to check for changes with watch (default: 1m)
 
 See [bisync command help](https://rclone.org/commands/rclone_bisync/)
 and [full bisync description](https://rclone.org/bisync/)
@@ -643,6 +643,10 @@ func (b *bisyncRun) modifyListing(ctx context.Context, src fs.Fs, dst fs.Fs, res
 	if !queues.skippedDirs2.empty() {
 		queues.skippedDirs2.getPutAll(path2List)
 	}
+	// files renamed on one side and moved to match on the other
+	if !b.opt.DryRun {
+		b.updateMoves(path1List, path2List)
+	}
 
 	if filterRecheck.HaveFilesFrom() {
 		// also include any aliases
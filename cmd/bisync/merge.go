@@ -0,0 +1,195 @@
+package bisync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/rclone/rclone/cmd/bisync/bilib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/terminal"
+)
+
+// MergeUnion is the --conflict-merge value for the built-in line based merge
+const MergeUnion = "union"
+
+// mergeMaxSize is the largest file --conflict-merge will try to merge
+const mergeMaxSize = 16 * 1024 * 1024
+
+// mergeConflict tries to merge file, which was changed on both sides,
+// with --conflict-merge.
+//
+// If the merge works then the merged file is written to Path1 and
+// queued to be copied to Path2 and it returns true. If it returns
+// false with no error then the conflict should be resolved as usual.
+func (b *bisyncRun) mergeConflict(ctx context.Context, path1, file, alias string, renameSkipped, copy1to2 *bilib.Names) (merged bool, err error) {
+	if len(b.opt.ConflictMerge) == 0 {
+		return false, nil
+	}
+	obj1, err := b.fs1.NewObject(ctx, file)
+	if err != nil {
+		fs.Errorf(file, "Can't merge: %v", err)
+		return false, nil
+	}
+	obj2, err := b.fs2.NewObject(ctx, alias)
+	if err != nil {
+		fs.Errorf(alias, "Can't merge: %v", err)
+		return false, nil
+	}
+	for _, o := range []fs.Object{obj1, obj2} {
+		if o.Size() < 0 || o.Size() > mergeMaxSize {
+			fs.Infof(file, "Not merging as %s is too big or of unknown size", bilib.FsPath(o.Fs())+o.Remote())
+			return false, nil
+		}
+	}
+	data1, err := readObject(ctx, obj1)
+	if err != nil {
+		fs.Errorf(file, "Can't merge: %v", err)
+		return false, nil
+	}
+	data2, err := readObject(ctx, obj2)
+	if err != nil {
+		fs.Errorf(file, "Can't merge: %v", err)
+		return false, nil
+	}
+	var data []byte
+	if len(b.opt.ConflictMerge) == 1 && b.opt.ConflictMerge[0] == MergeUnion {
+		data, err = mergeLines(data1, data2)
+	} else {
+		data, err = mergeCommand(ctx, b.opt.ConflictMerge, file, data1, data2)
+	}
+	if err != nil {
+		fs.Infof(file, Color(terminal.YellowFg, "Could not merge: %v"), err)
+		return false, nil
+	}
+
+	b.indent("!Path1", path1+file, "Writing merged Path1 and Path2 versions")
+	if operations.SkipDestructive(ctx, file, "write merged file") {
+		renameSkipped.Add(file) // (due to dry-run, not equality)
+		return true, nil
+	}
+	ctx = b.setBackupDir(ctx, 1)
+	ci := fs.GetConfig(ctx)
+	if ci.BackupDir != "" || ci.Suffix != "" {
+		backupDir, err := operations.BackupDir(ctx, b.fs1, b.fs1, file)
+		if err != nil {
+			b.critical = true
+			return false, err
+		}
+		if err = operations.MoveBackupDir(ctx, backupDir, obj1); err != nil {
+			b.critical = true
+			return false, fmt.Errorf("%s backup failed for %s: %w", path1, path1+file, err)
+		}
+	}
+	// give the merged file the modtime of the newest version
+	modTime := obj1.ModTime(ctx)
+	if t := obj2.ModTime(ctx); t.After(modTime) {
+		modTime = t
+	}
+	_, err = operations.Rcat(ctx, b.fs1, file, io.NopCloser(bytes.NewReader(data)), modTime, nil)
+	if err != nil {
+		b.critical = true
+		return false, fmt.Errorf("%s failed to write merged file %s: %w", path1, path1+file, err)
+	}
+	b.indent("Path1", file, "Queue copy to Path2")
+	copy1to2.Add(file)
+	return true, nil
+}
+
+// readObject reads all of o into memory
+func readObject(ctx context.Context, o fs.Object) ([]byte, error) {
+	in, err := operations.Open(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(in)
+	closeErr := in.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return data, err
+}
+
+// mergeLines does a line based merge of the text files a and b.
+//
+// Lines which are the same in both are kept once and where they
+// differ the lines from a are followed by the lines from b, so
+// nothing is lost from either file.
+func mergeLines(a, b []byte) ([]byte, error) {
+	if !isText(a) || !isText(b) {
+		return nil, errors.New("can't merge as not a text file")
+	}
+	linesA, linesB := splitLines(a), splitLines(b)
+	var out []string
+	m := difflib.NewMatcherWithJunk(linesA, linesB, false, nil)
+	for _, op := range m.GetOpCodes() {
+		out = append(out, linesA[op.I1:op.I2]...)
+		if op.Tag != 'e' {
+			out = append(out, linesB[op.J1:op.J2]...)
+		}
+	}
+	merged := strings.Join(out, "\n")
+	if len(out) > 0 && (bytes.HasSuffix(a, []byte("\n")) || bytes.HasSuffix(b, []byte("\n"))) {
+		merged += "\n"
+	}
+	return []byte(merged), nil
+}
+
+// isText returns true if data looks like a text file
+func isText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+// splitLines splits data into lines without the line endings
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// mergeCommand merges a and b by running command with the paths of
+// temporary files containing a and b and the path to write the
+// merged file to added to the end of it.
+func mergeCommand(ctx context.Context, command fs.SpaceSepList, file string, a, b []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "rclone-bisync-merge")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	// keep the file name so the command can see the extension
+	name := path.Base(file)
+	var paths []string
+	for _, subdir := range []string{"path1", "path2", "merged"} {
+		if err := os.Mkdir(filepath.Join(dir, subdir), 0700); err != nil {
+			return nil, err
+		}
+		paths = append(paths, filepath.Join(dir, subdir, name))
+	}
+	if err := os.WriteFile(paths[0], a, 0600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(paths[1], b, 0600); err != nil {
+		return nil, err
+	}
+	args := append(append([]string{}, command[1:]...), paths...)
+	cmd := exec.CommandContext(ctx, command[0], args...)
+	fs.Debugf(file, "Running merge command: %v", cmd.Args)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("merge command failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return os.ReadFile(paths[2])
+}
@@ -0,0 +1,159 @@
+package bisync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/rclone/rclone/cmd/bisync/bilib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/terminal"
+)
+
+// movedFile is a file renamed on one side which was moved to match on the other side
+type movedFile struct {
+	fromPath int      // the side the file was renamed on (1 or 2)
+	oldName  string   // name before the rename
+	newName  string   // name after the rename
+	info     fileInfo // info for the moved file on the other side
+}
+
+// renameKey returns a key which identifies the contents of file in ls,
+// or "" if it can't be identified well enough to detect renames.
+//
+// The hash is used if there is one, otherwise the modtime.
+func (b *bisyncRun) renameKey(ls *fileList, file string) string {
+	if ls.isDir(file) {
+		return ""
+	}
+	size := ls.getSize(file)
+	if size <= 0 {
+		// empty files and files of unknown size are too ambiguous
+		return ""
+	}
+	if h := ls.getHash(file); h != "" && !b.opt.IgnoreListingChecksum {
+		return fmt.Sprintf("%d hash:%s", size, h)
+	}
+	if t := ls.getTime(file); !t.IsZero() {
+		return fmt.Sprintf("%d time:%d", size, t.UnixNano())
+	}
+	return ""
+}
+
+// findRenames looks for deleted files in ds with the same contents as
+// a new file and records them as renamed.
+//
+// Only unambiguous matches are used - if more than one file was
+// deleted or made with the same contents then none of them are treated
+// as renames. Renames aren't counted as deletes for --max-delete.
+func (b *bisyncRun) findRenames(ds *deltaSet, old, now *fileList) {
+	deleted := map[string][]string{} // [key]oldNames
+	created := map[string][]string{} // [key]newNames
+	for _, file := range ds.sort() {
+		d := ds.deltas[file]
+		if d.is(deltaDeleted) {
+			if key := b.renameKey(old, file); key != "" {
+				deleted[key] = append(deleted[key], file)
+			}
+		} else if d.is(deltaNew) {
+			if key := b.renameKey(now, file); key != "" {
+				created[key] = append(created[key], file)
+			}
+		}
+	}
+	for key, oldNames := range deleted {
+		newNames := created[key]
+		if len(oldNames) != 1 || len(newNames) != 1 {
+			continue
+		}
+		oldName, newName := oldNames[0], newNames[0]
+		b.indent(ds.msg, newName, Color(terminal.CyanFg, "File was renamed from "+oldName))
+		ds.renamed[newName] = oldName
+		ds.deleted--
+	}
+}
+
+// moveRenames does the renames found on one side as server-side moves
+// on the other side so the files don't have to be deleted and copied
+// again.
+//
+// The renames it does are removed from the deltas. Any which can't be
+// done, for example because the file was changed on the other side,
+// are left in the deltas to be deleted and copied as usual.
+func (b *bisyncRun) moveRenames(ctx context.Context, dsFrom, dsTo *deltaSet, fromPath int) {
+	if len(dsFrom.renamed) == 0 {
+		return
+	}
+	toPath, toFs, lsTo := 2, b.fs2, ls2
+	if fromPath == 2 {
+		toPath, toFs, lsTo = 1, b.fs1, ls1
+	}
+	if !operations.CanServerSideMove(toFs) {
+		fs.Infof(toFs, "Can't do renames as server-side moves, so will copy renamed files")
+		return
+	}
+	toFsPath := bilib.FsPath(toFs)
+
+	newNames := make([]string, 0, len(dsFrom.renamed))
+	for newName := range dsFrom.renamed {
+		newNames = append(newNames, newName)
+	}
+	sort.Strings(newNames)
+
+	for _, newName := range newNames {
+		oldName := dsFrom.renamed[newName]
+		if b.aliases.Alias(oldName) != oldName || b.aliases.Alias(newName) != newName {
+			fs.Debugf(newName, "not moving renamed file with an alias")
+			continue
+		}
+		_, oldChanged := dsTo.deltas[oldName]
+		_, newChanged := dsTo.deltas[newName]
+		if oldChanged || newChanged || !lsTo.has(oldName) || lsTo.has(newName) {
+			fs.Debugf(newName, "not moving renamed file as it was changed on Path%d", toPath)
+			continue
+		}
+		b.indentf(fmt.Sprintf("Path%d", fromPath), toFsPath+newName, "Renaming Path%d copy from %s", toPath, oldName)
+		if !operations.SkipDestructive(ctx, oldName, "rename") {
+			obj, err := toFs.NewObject(ctx, oldName)
+			if err == nil {
+				obj, err = operations.Move(ctx, toFs, nil, newName, obj)
+			}
+			if err != nil {
+				fs.Errorf(toFsPath+oldName, Color(terminal.YellowFg, "Rename failed, will delete and copy instead: %v"), err)
+				continue
+			}
+			info := *lsTo.get(oldName)
+			if b.opt.Compare.Modtime {
+				info.time = obj.ModTime(ctx).In(TZ)
+			}
+			b.moves = append(b.moves, movedFile{
+				fromPath: fromPath,
+				oldName:  oldName,
+				newName:  newName,
+				info:     info,
+			})
+		}
+		delete(dsFrom.deltas, oldName)
+		delete(dsFrom.deltas, newName)
+	}
+}
+
+// updateMoves renames the files moved by moveRenames in the listings
+func (b *bisyncRun) updateMoves(path1List, path2List *fileList) {
+	for _, m := range b.moves {
+		fromList, toList, lsFrom := path1List, path2List, ls1
+		if m.fromPath == 2 {
+			fromList, toList, lsFrom = path2List, path1List, ls2
+		}
+		from := lsFrom.get(m.newName)
+		if from == nil {
+			continue
+		}
+		fromList.remove(m.oldName)
+		fromList.put(m.newName, from.size, from.time, from.hash, from.id, from.flags)
+		toList.remove(m.oldName)
+		toList.put(m.newName, m.info.size, m.info.time, m.info.hash, m.info.id, m.info.flags)
+		fs.Debugf(nil, "decision: renamed %v to %v", m.oldName, m.newName)
+	}
+}
@@ -50,6 +50,7 @@ type bisyncRun struct {
 	DebugName          string
 	lockFile           string
 	renames            renames
+	moves              []movedFile
 	resyncIs1to2       bool
 }
 
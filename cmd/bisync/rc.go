@@ -8,6 +8,7 @@ import (
 	"github.com/rclone/rclone/cmd/bisync/bilib"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fs/rc/jobs"
 )
 
 func init() {
@@ -64,6 +65,19 @@ func rcBisync(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	if opt.Resilient, err = in.GetBool("resilient"); rc.NotErrParamNotFound(err) {
 		return
 	}
+	if opt.DetectRenames, err = in.GetBool("detectRenames"); rc.NotErrParamNotFound(err) {
+		return
+	}
+	if opt.Watch, err = in.GetBool("watch"); rc.NotErrParamNotFound(err) {
+		return
+	}
+	if opt.Watch && !jobs.IsAsync(ctx) {
+		// The call would never return
+		return nil, rc.NewErrParamInvalid(errors.New("watch must be used with _async=true"))
+	}
+	if opt.WatchInterval, err = in.GetDuration("watchInterval"); rc.NotErrParamNotFound(err) {
+		return
+	}
 
 	if opt.CheckFilename, err = in.GetString("checkFilename"); rc.NotErrParamNotFound(err) {
 		return
@@ -81,6 +95,14 @@ func rcBisync(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 		return
 	}
 
+	conflictMerge, err := in.GetString("conflictMerge")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	if err := opt.ConflictMerge.Set(conflictMerge); err != nil {
+		return nil, err
+	}
+
 	checkSync, err := in.GetString("checkSync")
 	if rc.NotErrParamNotFound(err) {
 		return nil, err
@@ -102,6 +124,11 @@ func rcBisync(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 		return nil, err
 	}
 
+	if opt.Watch {
+		// Runs until the job is stopped so don't capture the output
+		return nil, Watch(octx, fs1, fs2, opt)
+	}
+
 	output := bilib.CaptureOutput(func() {
 		err = Bisync(octx, fs1, fs2, opt)
 	})
@@ -0,0 +1,115 @@
+package bisync
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/lib/terminal"
+)
+
+// DefaultWatchInterval is how often to look for changes in --watch mode
+const DefaultWatchInterval = time.Minute
+
+// watchSettle is how long to wait for further changes once a change
+// has been noticed before starting the next run, so that a burst of
+// changes is picked up by a single run.
+var watchSettle = 5 * time.Second
+
+// watchRun runs bisync for Watch, replaced in tests
+var watchRun = Bisync
+
+// Watch runs bisync and then keeps running it each time Path1 or Path2
+// changes until ctx is cancelled.
+//
+// Remotes which support ChangeNotify are asked to report changes
+// every opt.WatchInterval. If either remote doesn't support it then
+// bisync is run every opt.WatchInterval instead.
+//
+// Only the first run does a --resync if requested. Watching stops if
+// a run aborts with a critical error, but other errors are logged and
+// retried on the next change.
+func Watch(ctx context.Context, fs1, fs2 fs.Fs, optArg *Options) error {
+	opt := *optArg // ensure that input is never changed
+	if opt.CheckSync == CheckSyncOnly {
+		return errors.New("--watch can't be used with --check-sync=only")
+	}
+	interval := opt.WatchInterval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changed := make(chan struct{}, 1)
+	notify := func(path string, entryType fs.EntryType) {
+		fs.Debugf(nil, "bisync: change notified: %q", path)
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	poll := false
+	for _, f := range []fs.Fs{fs1, fs2} {
+		do := f.Features().ChangeNotify
+		if do == nil {
+			fs.Infof(f, "Remote doesn't support ChangeNotify - running bisync every %v", interval)
+			poll = true
+			continue
+		}
+		pollChan := make(chan time.Duration)
+		do(ctx, notify, pollChan)
+		pollChan <- interval
+		defer close(pollChan)
+	}
+	var tick <-chan time.Time
+	if poll {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		err := watchRun(ctx, fs1, fs2, &opt)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == ErrBisyncAborted {
+			return err
+		} else if err != nil {
+			fs.Errorf(nil, Color(terminal.RedFg, "Bisync failed, will retry on the next change: %v"), err)
+		}
+
+		// Only resync on the first run
+		opt.Resync = false
+		opt.ResyncMode = PreferNone
+
+		fs.Infoc(nil, Color(terminal.Dim, "Watching for changes..."))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick:
+		case <-changed:
+			// Wait for the changes to settle
+			timer := time.NewTimer(watchSettle)
+		settle:
+			for {
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil
+				case <-changed:
+					timer.Reset(watchSettle)
+				case <-timer.C:
+					break settle
+				}
+			}
+		}
+
+		// Errors from the last run would abort this one
+		accounting.Stats(ctx).ResetErrors()
+	}
+}
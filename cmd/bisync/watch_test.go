@@ -0,0 +1,143 @@
+package bisync
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/memory"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// notifyFs is an fs.Fs which supports ChangeNotify, passing the
+// notify function it is given to the test
+type notifyFs struct {
+	fs.Fs
+	features *fs.Features
+	notify   chan func(string, fs.EntryType)
+}
+
+func newNotifyFs(ctx context.Context, t *testing.T) *notifyFs {
+	f, err := fs.NewFs(ctx, ":memory:")
+	require.NoError(t, err)
+	nf := &notifyFs{Fs: f, notify: make(chan func(string, fs.EntryType), 1)}
+	nf.features = (&fs.Features{}).Fill(ctx, nf)
+	return nf
+}
+
+func (f *notifyFs) Features() *fs.Features {
+	return f.features
+}
+
+func (f *notifyFs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollInterval <-chan time.Duration) {
+	f.notify <- notifyFunc
+	go func() {
+		for range pollInterval {
+		}
+	}()
+}
+
+func TestWatch(t *testing.T) {
+	ctx := context.Background()
+	oldSettle, oldRun := watchSettle, watchRun
+	defer func() {
+		watchSettle, watchRun = oldSettle, oldRun
+	}()
+	watchSettle = 200 * time.Millisecond
+
+	// Record the options of each run, aborting if requested
+	runs := make(chan Options, 10)
+	var abort atomic.Bool
+	watchRun = func(ctx context.Context, fs1, fs2 fs.Fs, opt *Options) error {
+		runs <- *opt
+		if abort.Load() {
+			return ErrBisyncAborted
+		}
+		return nil
+	}
+	noRun := func(t *testing.T, wait time.Duration) {
+		select {
+		case <-runs:
+			t.Fatal("unexpected run")
+		case <-time.After(wait):
+		}
+	}
+	waitRun := func(t *testing.T) Options {
+		select {
+		case opt := <-runs:
+			return opt
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for run")
+		}
+		return Options{}
+	}
+
+	f1, f2 := newNotifyFs(ctx, t), newNotifyFs(ctx, t)
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, f1, f2, &Options{Resync: true, ResyncMode: PreferPath1, WatchInterval: time.Hour})
+	}()
+	notify1, notify2 := <-f1.notify, <-f2.notify
+
+	// Only the first run resyncs
+	opt := waitRun(t)
+	assert.True(t, opt.Resync)
+	assert.Equal(t, PreferPath1, opt.ResyncMode)
+	noRun(t, 2*watchSettle)
+
+	t.Run("Change", func(t *testing.T) {
+		start := time.Now()
+		notify1("file1", fs.EntryObject)
+		opt := waitRun(t)
+		assert.GreaterOrEqual(t, time.Since(start), watchSettle)
+		assert.False(t, opt.Resync)
+		assert.Equal(t, PreferNone, opt.ResyncMode)
+		noRun(t, 2*watchSettle)
+	})
+
+	t.Run("Burst", func(t *testing.T) {
+		// The changes take longer than watchSettle but each comes
+		// before the last has settled
+		var last time.Time
+		for i := range 6 {
+			if i > 0 {
+				time.Sleep(watchSettle / 4)
+			}
+			last = time.Now()
+			if i%2 == 0 {
+				notify1("file1", fs.EntryObject)
+			} else {
+				notify2("dir", fs.EntryDirectory)
+			}
+		}
+		waitRun(t)
+		assert.GreaterOrEqual(t, time.Since(last), watchSettle)
+		noRun(t, 2*watchSettle)
+	})
+
+	t.Run("Aborted", func(t *testing.T) {
+		abort.Store(true)
+		notify2("file2", fs.EntryObject)
+		waitRun(t)
+		select {
+		case err := <-done:
+			assert.Equal(t, ErrBisyncAborted, err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("watch didn't stop")
+		}
+	})
+}
+
+func TestRcWatchNeedsAsync(t *testing.T) {
+	ctx := context.Background()
+	call := rc.Calls.Get("sync/bisync")
+	require.NotNil(t, call)
+	_, err := call.Fn(ctx, rc.Params{"path1": ":memory:a", "path2": ":memory:b", "watch": true})
+	require.Error(t, err)
+	assert.True(t, rc.IsErrParamInvalid(err))
+	assert.Contains(t, err.Error(), "_async")
+}
@@ -0,0 +1,711 @@
+//go:build !plan9 && !js
+
+// Package browse implements a two pane text based file manager for remotes
+package browse
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	runewidth "github.com/mattn/go-runewidth"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/log"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rivo/uniseg"
+	"github.com/spf13/cobra"
+
+	// Register the rc calls used for the transfers
+	_ "github.com/rclone/rclone/fs/operations"
+	_ "github.com/rclone/rclone/fs/sync"
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "browse [remote:path [remote:path]]",
+	Short: `Copy and move files between remotes with a two pane text based user interface.`,
+	Long: strings.ReplaceAll(`This displays a text based file manager with two panes, each showing
+a directory on any of the configured remotes or the local disk. Files
+and directories can be copied or moved from one pane to the other,
+deleted, renamed and new directories made.
+
+The panes start in the remote:paths given, or show the list of
+configured remotes if they aren't. Going up from the top of a remote
+returns to the list of remotes.
+
+The operations are queued and run one after another in the background
+while you carry on browsing. The progress of the one running is shown
+at the bottom of the screen, and the |t| key shows the whole queue
+where transfers can be cancelled. Each transfer is run as an rc job,
+so if the remote control is enabled with |--rc| they can be seen with
+|rclone rc job/list| and their stats with |rclone rc core/stats|
+using the job's group.
+
+Files are transferred with the same calls as |rclone rc
+operations/copyfile| and |operations/movefile|, and directories as
+|sync/copy| and |sync/move|, so the usual flags like |--transfers|,
+|--checksum| and the filters apply to them.
+
+You can interact with the user interface using key presses,
+press '?' to toggle the help on and off. The supported keys are:
+
+    `+strings.Join(helpText()[1:], "\n    ")+`
+
+If there are transfers still queued or running when you quit you will
+be asked whether to stop them.
+
+For a text based user interface for exploring the disk usage of a
+remote, see the [ncdu](/commands/rclone_ncdu/) command.
+`, "|", "`"),
+	Annotations: map[string]string{
+		"versionIntroduced": "v1.70",
+		"groups":            "Copy,Important",
+	},
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 2, command, args)
+		var panes [2]*pane
+		for i := range panes {
+			var remotePath string
+			if i < len(args) {
+				remotePath = args[i]
+			}
+			var err error
+			panes[i], err = newPane(remotePath)
+			if err != nil {
+				fs.Fatalf(nil, "Invalid remote:path %q: %v", remotePath, err)
+			}
+		}
+		cmd.Run(false, false, command, func() error {
+			return newUI(context.Background(), panes).Run()
+		})
+	},
+}
+
+// helpText returns help text for browse
+func helpText() (tr []string) {
+	return []string{
+		"rclone browse",
+		" ↑,↓ or k,j to move",
+		" PgUp,PgDn,Home,End to move a page or to the ends",
+		" →,l,Enter to enter a directory",
+		" ←,h,Backspace to go up",
+		" Tab to switch pane",
+		" Space,Insert to select file/directory",
+		" c,F5 copy selected or current to the other pane",
+		" m,F6 move selected or current to the other pane",
+		" d,F8 delete selected or current",
+		" r rename current",
+		" n,F7 make a new directory",
+		" t toggle the transfer queue",
+		"   x cancel transfer, C clear ended transfers",
+		" ^R refresh the panes",
+		" ^L refresh screen (fix screen corruption)",
+		" ? to toggle help on and off",
+		" q,F10 to quit",
+	}
+}
+
+// prompt is a question shown on the status line
+type prompt struct {
+	text   string       // question to show
+	input  []rune       // text typed so far if reading a line
+	isLine bool         // set if reading a line rather than a y/n answer
+	action func(string) // called with the line or "y" if answered yes
+}
+
+// UI contains the state of the user interface
+type UI struct {
+	ctx           context.Context
+	s             tcell.Screen
+	panes         [2]*pane        // the left and right panes
+	active        int             // index of the pane with the cursor
+	results       chan listResult // listings of panes arrive here
+	q             *queue          // queue of transfers
+	showHelp      bool            // set to show the help
+	showQueue     bool            // set to show the transfer queue
+	queueCursor   int             // transfer under the cursor in the queue
+	prompt        *prompt         // question being asked if set
+	message       string          // message shown on the status line
+	dirListHeight int             // height of listing
+	quit          bool            // set to exit the main loop
+}
+
+// newUI makes a new UI showing panes
+func newUI(ctx context.Context, panes [2]*pane) *UI {
+	return &UI{
+		ctx:           ctx,
+		panes:         panes,
+		results:       make(chan listResult),
+		q:             newQueue(ctx),
+		dirListHeight: 20, // updated in Draw
+	}
+}
+
+// Line prints a string to given xmax, with given space
+func (u *UI) Line(x, y, xmax int, style tcell.Style, spacer rune, msg string) {
+	g := uniseg.NewGraphemes(msg)
+	for g.Next() {
+		if x >= xmax {
+			return
+		}
+		rs := g.Runes()
+		u.s.SetContent(x, y, rs[0], rs[1:], style)
+		x += runewidth.StringWidth(string(rs))
+	}
+	for ; x < xmax; x++ {
+		u.s.SetContent(x, y, spacer, nil, style)
+	}
+}
+
+// Linef a string
+func (u *UI) Linef(x, y, xmax int, style tcell.Style, spacer rune, format string, args ...any) {
+	s := fmt.Sprintf(format, args...)
+	u.Line(x, y, xmax, style, spacer, s)
+}
+
+// Draw the user interface
+func (u *UI) Draw() {
+	w, h := u.s.Size()
+	u.s.Clear()
+	reverse := tcell.StyleDefault.Reverse(true)
+
+	// The header, the panes, the transfer line and the status line
+	u.dirListHeight = max(h-4, 1)
+	u.Line(0, 0, w, reverse, ' ', "rclone browse - use the arrow keys to navigate, press ? for help")
+	if u.showQueue {
+		u.drawQueue(0, 1, w, u.dirListHeight+1)
+	} else {
+		paneWidth := (w - 1) / 2
+		u.drawPane(u.panes[0], u.active == 0, 0, 1, paneWidth)
+		for y := 1; y <= u.dirListHeight+1; y++ {
+			u.s.SetContent(paneWidth, y, tcell.RuneVLine, nil, tcell.StyleDefault)
+		}
+		u.drawPane(u.panes[1], u.active == 1, paneWidth+1, 1, w-paneWidth-1)
+	}
+	u.Line(0, h-2, w, reverse, ' ', u.transferLine())
+
+	// The status line
+	switch {
+	case u.prompt != nil && u.prompt.isLine:
+		u.Line(0, h-1, w, tcell.StyleDefault, ' ', u.prompt.text+string(u.prompt.input))
+		u.s.ShowCursor(runewidth.StringWidth(u.prompt.text+string(u.prompt.input)), h-1)
+	case u.prompt != nil:
+		u.Line(0, h-1, w, tcell.StyleDefault, ' ', u.prompt.text+" (y/n)")
+		u.s.HideCursor()
+	default:
+		u.Line(0, h-1, w, tcell.StyleDefault, ' ', u.message)
+		u.s.HideCursor()
+	}
+
+	if u.showHelp {
+		u.drawHelp(w, h)
+	}
+	u.s.Show()
+}
+
+// drawPane draws p at x, y with width w
+func (u *UI) drawPane(p *pane, active bool, x, y, w int) {
+	titleStyle := tcell.StyleDefault.Bold(true)
+	if active {
+		titleStyle = titleStyle.Reverse(true)
+	}
+	title := p.title()
+	if p.listing {
+		title += " [listing...]"
+	}
+	if n := len(p.selected); n > 0 {
+		title += fmt.Sprintf(" [%d selected]", n)
+	}
+	u.Line(x, y, x+w, titleStyle, ' ', title)
+	y++
+	height := u.dirListHeight
+
+	if p.err != nil {
+		u.Linef(x, y, x+w, tcell.StyleDefault.Foreground(tcell.ColorRed), ' ', "Error: %v", p.err)
+		return
+	}
+
+	// Make sure the cursor is visible
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if p.cursor >= p.offset+height {
+		p.offset = p.cursor - height + 1
+	}
+
+	const sizeWidth = 11
+	for i := p.offset; i < len(p.entries) && i < p.offset+height; i++ {
+		e := p.entries[i]
+		style := tcell.StyleDefault
+		if i == p.cursor {
+			if active {
+				style = style.Reverse(true)
+			} else {
+				style = style.Underline(true)
+			}
+		}
+		marker := " "
+		if p.selected[e.name] {
+			marker = "*"
+			style = style.Foreground(tcell.ColorYellow)
+		}
+		name, size := e.name, ""
+		if e.isDir {
+			if !p.isRemotes() {
+				name += "/"
+			}
+		} else if e.size >= 0 {
+			size = fs.SizeSuffix(e.size).String()
+		} else {
+			size = "?"
+		}
+		nameEnd := max(x+w-sizeWidth, x)
+		u.Line(x, y, nameEnd, style, ' ', marker+name)
+		u.Linef(nameEnd, y, x+w, style, ' ', "%*s ", sizeWidth-1, size)
+		y++
+	}
+}
+
+// drawQueue draws the transfer queue in the box given
+func (u *UI) drawQueue(x, y, w, h int) {
+	u.Line(x, y, x+w, tcell.StyleDefault.Bold(true).Reverse(true), ' ', "Transfers - x to cancel, C to clear ended transfers, t to return")
+	y++
+	lines := u.q.lines()
+	u.queueCursor = max(0, min(u.queueCursor, len(lines)-1))
+	if len(lines) == 0 {
+		u.Line(x, y, x+w, tcell.StyleDefault, ' ', " No transfers")
+		return
+	}
+	offset := max(0, u.queueCursor-(h-2))
+	for i := offset; i < len(lines) && y < h+1; i++ {
+		style := tcell.StyleDefault
+		if i == u.queueCursor {
+			style = style.Reverse(true)
+		}
+		u.Line(x, y, x+w, style, ' ', " "+lines[i])
+		y++
+	}
+}
+
+// drawHelp draws the help in a box in the middle of the screen
+func (u *UI) drawHelp(w, h int) {
+	lines := helpText()
+	boxWidth := 0
+	for _, line := range lines {
+		boxWidth = max(boxWidth, runewidth.StringWidth(line)+1)
+	}
+	x := max((w-boxWidth)/2, 0)
+	y := max((h-len(lines))/2, 0)
+	style := tcell.StyleDefault.Background(tcell.ColorRed).Reverse(true)
+	for i, line := range lines {
+		u.Line(x, y+i, x+boxWidth, style, ' ', line)
+	}
+}
+
+// transferLine returns a one line summary of the transfer queue
+func (u *UI) transferLine() string {
+	active := u.q.active()
+	if active == 0 {
+		return "No transfers running"
+	}
+	running := u.q.running()
+	if running == "" {
+		return fmt.Sprintf("[%d queued]", active)
+	}
+	return fmt.Sprintf("[%d queued] %s", active-1, running)
+}
+
+// other returns the pane which isn't active
+func (u *UI) other() *pane {
+	return u.panes[1-u.active]
+}
+
+// refresh lists both panes again
+func (u *UI) refresh() {
+	for _, p := range u.panes {
+		p.list(u.ctx, u.results)
+	}
+}
+
+// ask a yes/no question calling action if the answer is yes
+func (u *UI) ask(text string, action func()) {
+	u.prompt = &prompt{
+		text:   text,
+		action: func(string) { action() },
+	}
+}
+
+// readLine reads a line of text starting with initial calling action
+// with it if it isn't cancelled
+func (u *UI) readLine(text, initial string, action func(string)) {
+	u.prompt = &prompt{
+		text:   text,
+		input:  []rune(initial),
+		isLine: true,
+		action: action,
+	}
+}
+
+// enqueue adds a transfer to the queue showing any error
+func (u *UI) enqueue(what, path string, in rc.Params) {
+	err := u.q.add(what, path, in)
+	if err != nil {
+		u.message = fmt.Sprintf("Failed to queue %s: %v", what, err)
+		return
+	}
+	u.message = "Queued " + what
+}
+
+// copyOrMove the chosen entries of the active pane to the other pane
+func (u *UI) copyOrMove(move bool) {
+	src, dst := u.panes[u.active], u.other()
+	if src.isRemotes() || dst.isRemotes() {
+		u.message = "Choose a directory on a remote in both panes first"
+		return
+	}
+	entries := src.chosen()
+	if len(entries) == 0 {
+		return
+	}
+	if src.root == dst.root && src.dir == dst.dir {
+		u.message = "Source and destination are the same directory"
+		return
+	}
+	verb := "Copy"
+	if move {
+		verb = "Move"
+	}
+	u.ask(fmt.Sprintf("%s %s to %s?", verb, describe(entries), dst.title()), func() {
+		for _, e := range entries {
+			what := fmt.Sprintf("%s %s to %s", strings.ToLower(verb), src.fsPath(e.name), dst.title())
+			if e.isDir {
+				u.enqueue(what, "sync/"+strings.ToLower(verb), rc.Params{
+					"srcFs":              src.fsPath(e.name),
+					"dstFs":              dst.fsPath(e.name),
+					"createEmptySrcDirs": true,
+					"deleteEmptySrcDirs": move,
+				})
+			} else {
+				u.enqueue(what, "operations/"+strings.ToLower(verb)+"file", rc.Params{
+					"srcFs":     src.root,
+					"srcRemote": src.remote(e.name),
+					"dstFs":     dst.root,
+					"dstRemote": dst.remote(e.name),
+				})
+			}
+		}
+		clear(src.selected)
+	})
+}
+
+// remove the chosen entries of the active pane
+func (u *UI) remove() {
+	p := u.panes[u.active]
+	if p.isRemotes() {
+		return
+	}
+	entries := p.chosen()
+	if len(entries) == 0 {
+		return
+	}
+	u.ask(fmt.Sprintf("Delete %s?", describe(entries)), func() {
+		for _, e := range entries {
+			call := "operations/deletefile"
+			if e.isDir {
+				call = "operations/purge"
+			}
+			u.enqueue("delete "+p.fsPath(e.name), call, rc.Params{
+				"fs":     p.root,
+				"remote": p.remote(e.name),
+			})
+		}
+		clear(p.selected)
+	})
+}
+
+// rename the entry under the cursor in the active pane
+func (u *UI) rename() {
+	p := u.panes[u.active]
+	e := p.current()
+	if p.isRemotes() || e == nil {
+		return
+	}
+	oldName, isDir := e.name, e.isDir
+	u.readLine("Rename to: ", oldName, func(newName string) {
+		if newName == "" || newName == oldName || strings.Contains(newName, "/") {
+			return
+		}
+		what := fmt.Sprintf("rename %s to %s", p.fsPath(oldName), newName)
+		if isDir {
+			u.enqueue(what, "sync/move", rc.Params{
+				"srcFs":              p.fsPath(oldName),
+				"dstFs":              p.fsPath(newName),
+				"createEmptySrcDirs": true,
+				"deleteEmptySrcDirs": true,
+			})
+		} else {
+			u.enqueue(what, "operations/movefile", rc.Params{
+				"srcFs":     p.root,
+				"srcRemote": p.remote(oldName),
+				"dstFs":     p.root,
+				"dstRemote": p.remote(newName),
+			})
+		}
+		p.want = newName
+	})
+}
+
+// mkdir makes a new directory in the active pane
+func (u *UI) mkdir() {
+	p := u.panes[u.active]
+	if p.isRemotes() {
+		return
+	}
+	u.readLine("New directory: ", "", func(name string) {
+		if name == "" {
+			return
+		}
+		u.enqueue("make directory "+p.fsPath(name), "operations/mkdir", rc.Params{
+			"fs":     p.root,
+			"remote": p.remote(name),
+		})
+		p.want = path.Base(name)
+	})
+}
+
+// describe returns a short description of entries
+func describe(entries []entry) string {
+	if len(entries) == 1 {
+		if entries[0].isDir {
+			return fmt.Sprintf("directory %q", entries[0].name)
+		}
+		return fmt.Sprintf("file %q", entries[0].name)
+	}
+	return fmt.Sprintf("%d files/directories", len(entries))
+}
+
+// handlePrompt handles a key press while a prompt is showing
+func (u *UI) handlePrompt(ev *tcell.EventKey) {
+	p := u.prompt
+	if !p.isLine {
+		u.prompt = nil
+		if ev.Key() == tcell.KeyRune && (ev.Rune() == 'y' || ev.Rune() == 'Y') {
+			p.action("y")
+		}
+		return
+	}
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		u.prompt = nil
+		p.action(strings.TrimSpace(string(p.input)))
+	case tcell.KeyEsc, tcell.KeyCtrlC:
+		u.prompt = nil
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(p.input) > 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+	case tcell.KeyCtrlU:
+		p.input = p.input[:0]
+	case tcell.KeyRune:
+		p.input = append(p.input, ev.Rune())
+	}
+}
+
+// handleKey handles a key press
+func (u *UI) handleKey(ev *tcell.EventKey) {
+	if u.prompt != nil {
+		u.handlePrompt(ev)
+		return
+	}
+	u.message = ""
+	var c rune
+	if k := ev.Key(); k == tcell.KeyRune {
+		c = ev.Rune()
+	} else {
+		c = key(k)
+	}
+	if u.showHelp {
+		u.showHelp = false
+		if c == '?' || c == key(tcell.KeyEsc) {
+			return
+		}
+	}
+	switch c {
+	case 'q', key(tcell.KeyF10), key(tcell.KeyCtrlC):
+		if u.q.active() == 0 {
+			u.quit = true
+			return
+		}
+		u.ask("Transfers are still running - stop them and quit?", func() {
+			u.q.stopAll()
+			u.quit = true
+		})
+		return
+	case '?':
+		u.showHelp = true
+		return
+	case 't':
+		u.showQueue = !u.showQueue
+		return
+	case key(tcell.KeyCtrlL):
+		u.s.Sync()
+		return
+	case key(tcell.KeyCtrlR):
+		u.refresh()
+		return
+	}
+	if u.showQueue {
+		u.handleQueueKey(c)
+		return
+	}
+	p := u.panes[u.active]
+	switch c {
+	case key(tcell.KeyTab), key(tcell.KeyBacktab):
+		u.active = 1 - u.active
+	case key(tcell.KeyDown), 'j':
+		p.move(1)
+	case key(tcell.KeyUp), 'k':
+		p.move(-1)
+	case key(tcell.KeyPgDn):
+		p.move(u.dirListHeight)
+	case key(tcell.KeyPgUp):
+		p.move(-u.dirListHeight)
+	case key(tcell.KeyHome):
+		p.move(-len(p.entries))
+	case key(tcell.KeyEnd):
+		p.move(len(p.entries))
+	case key(tcell.KeyRight), key(tcell.KeyEnter), 'l':
+		if p.enter() {
+			p.list(u.ctx, u.results)
+		}
+	case key(tcell.KeyLeft), key(tcell.KeyBackspace), key(tcell.KeyBackspace2), 'h':
+		if !p.isRemotes() {
+			p.up()
+			p.list(u.ctx, u.results)
+		}
+	case ' ', key(tcell.KeyInsert):
+		p.toggle()
+	case 'c', key(tcell.KeyF5):
+		u.copyOrMove(false)
+	case 'm', key(tcell.KeyF6):
+		u.copyOrMove(true)
+	case 'd', key(tcell.KeyF8), key(tcell.KeyDelete):
+		u.remove()
+	case 'r':
+		u.rename()
+	case 'n', key(tcell.KeyF7):
+		u.mkdir()
+	}
+}
+
+// handleQueueKey handles a key press when the queue is showing
+func (u *UI) handleQueueKey(c rune) {
+	switch c {
+	case key(tcell.KeyDown), 'j':
+		u.queueCursor++
+	case key(tcell.KeyUp), 'k':
+		u.queueCursor--
+	case 'x', key(tcell.KeyDelete):
+		u.q.cancel(u.queueCursor)
+	case 'C':
+		u.q.clear()
+	case key(tcell.KeyEsc), key(tcell.KeyLeft), 'h':
+		u.showQueue = false
+	}
+}
+
+// Run shows the user interface
+func (u *UI) Run() error {
+	var err error
+	u.s, err = tcell.NewScreen()
+	if err != nil {
+		return fmt.Errorf("screen new: %w", err)
+	}
+	err = u.s.Init()
+	if err != nil {
+		return fmt.Errorf("screen init: %w", err)
+	}
+
+	// Hijack fs.LogOutput so that it doesn't corrupt the screen.
+	if logOutput := fs.LogOutput; !log.Redirected() {
+		type log struct {
+			text  string
+			level fs.LogLevel
+		}
+		var (
+			logsMu sync.Mutex // logs are written by queued rc jobs too
+			logs   []log
+		)
+		fs.LogOutput = func(level fs.LogLevel, text string) {
+			logsMu.Lock()
+			defer logsMu.Unlock()
+			if len(logs) > 100 {
+				logs = logs[len(logs)-100:]
+			}
+			logs = append(logs, log{level: level, text: text})
+		}
+		defer func() {
+			fs.LogOutput = logOutput
+			logsMu.Lock()
+			defer logsMu.Unlock()
+			for i := range logs {
+				logOutput(logs[i].level, logs[i].text)
+			}
+		}()
+	}
+
+	defer u.s.Fini()
+
+	u.refresh()
+
+	// Poll the events into a channel
+	events := make(chan tcell.Event)
+	go u.s.ChannelEvents(events, nil)
+
+	// Update the progress of the transfers regularly
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// Main loop, waiting for events and channels
+	for !u.quit {
+		select {
+		case r := <-u.results:
+			r.p.update(r)
+		case t := <-u.q.finished:
+			switch t.state {
+			case stateFailed:
+				u.message = fmt.Sprintf("Failed to %s: %v", t.what, t.err)
+			case stateDone:
+				u.message = fmt.Sprintf("Finished %s", t.what)
+			}
+			u.refresh()
+		case <-ticker.C:
+			if u.q.active() == 0 {
+				continue // nothing to update
+			}
+		case ev := <-events:
+			switch ev := ev.(type) {
+			case *tcell.EventResize:
+				u.Draw()
+				u.s.Sync()
+				continue // don't draw again
+			case *tcell.EventKey:
+				u.handleKey(ev)
+			}
+		}
+		u.Draw()
+	}
+	return nil
+}
+
+// key returns a rune representing the key k. It is larger than the maximum Unicode code-point.
+func key(k tcell.Key) rune {
+	return rune(-k)
+}
@@ -0,0 +1,7 @@
+// Build for browse for unsupported platforms to stop go complaining
+// about "no buildable Go source files "
+
+//go:build plan9 || js
+
+// Package browse implements a two pane text based file manager for remotes
+package browse
@@ -0,0 +1,296 @@
+//go:build !plan9 && !js
+
+package browse
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/fspath"
+)
+
+// entry is a file or directory shown in a pane
+type entry struct {
+	name    string
+	isDir   bool
+	size    int64
+	modTime time.Time
+}
+
+// pane is one side of the browser showing a directory
+//
+// If root is empty the pane shows the configured remotes instead.
+type pane struct {
+	root     string          // root of the remote e.g. "remote:" or "/"
+	dir      string          // directory within root being shown
+	entries  []entry         // entries of dir
+	cursor   int             // index of the entry under the cursor
+	offset   int             // index of the first entry shown
+	selected map[string]bool // names of the selected entries
+	listing  bool            // set if a listing is in progress
+	err      error           // error from the last listing
+	gen      int             // incremented each time a listing starts
+	want     string          // name to put the cursor on after listing
+}
+
+// listResult is the result of listing a pane
+type listResult struct {
+	p       *pane
+	gen     int
+	entries []entry
+	err     error
+}
+
+// newPane makes a pane showing remotePath or the list of remotes if
+// it is empty
+func newPane(remotePath string) (*pane, error) {
+	p := &pane{
+		selected: map[string]bool{},
+	}
+	if remotePath == "" {
+		return p, nil
+	}
+	parsed, err := fspath.Parse(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Name == "" {
+		// Split local paths into the root of the volume and the
+		// path within it so we can go up to the top
+		abs, err := filepath.Abs(remotePath)
+		if err != nil {
+			return nil, err
+		}
+		abs = filepath.ToSlash(abs)
+		volume := filepath.VolumeName(abs)
+		p.root = volume + "/"
+		p.dir = strings.Trim(abs[len(volume):], "/")
+		return p, nil
+	}
+	p.root = parsed.ConfigString + ":"
+	if strings.HasPrefix(parsed.Path, "/") {
+		// Keep paths absolute for backends like sftp
+		p.root += "/"
+	}
+	p.dir = strings.Trim(parsed.Path, "/")
+	return p, nil
+}
+
+// localRoot returns the root of the local volume we are running in
+func localRoot() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "/"
+	}
+	return filepath.VolumeName(dir) + "/"
+}
+
+// isRemotes returns true if the pane is showing the list of remotes
+func (p *pane) isRemotes() bool {
+	return p.root == ""
+}
+
+// title returns the remote:path being shown
+func (p *pane) title() string {
+	if p.isRemotes() {
+		return "Remotes"
+	}
+	return fspath.JoinRootPath(p.root, p.dir)
+}
+
+// remote returns the path of name relative to the root
+func (p *pane) remote(name string) string {
+	return path.Join(p.dir, name)
+}
+
+// fsPath returns the remote:path of name suitable for making an Fs
+func (p *pane) fsPath(name string) string {
+	return fspath.JoinRootPath(p.root, p.remote(name))
+}
+
+// current returns the entry under the cursor or nil if none
+func (p *pane) current() *entry {
+	if p.cursor < 0 || p.cursor >= len(p.entries) {
+		return nil
+	}
+	return &p.entries[p.cursor]
+}
+
+// chosen returns the selected entries or the one under the cursor if
+// none are selected
+func (p *pane) chosen() (entries []entry) {
+	for _, e := range p.entries {
+		if p.selected[e.name] {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		if e := p.current(); e != nil {
+			entries = append(entries, *e)
+		}
+	}
+	return entries
+}
+
+// move the cursor by n entries
+func (p *pane) move(n int) {
+	p.cursor = max(0, min(p.cursor+n, len(p.entries)-1))
+}
+
+// toggle the selection of the entry under the cursor
+func (p *pane) toggle() {
+	e := p.current()
+	if e == nil || p.isRemotes() {
+		return
+	}
+	if p.selected[e.name] {
+		delete(p.selected, e.name)
+	} else {
+		p.selected[e.name] = true
+	}
+	p.move(1)
+}
+
+// enter the directory under the cursor
+//
+// It returns false if there isn't a directory to enter.
+func (p *pane) enter() bool {
+	e := p.current()
+	if e == nil || !e.isDir {
+		return false
+	}
+	if p.isRemotes() {
+		p.root = e.name
+	} else {
+		p.dir = p.remote(e.name)
+	}
+	p.reset("")
+	return true
+}
+
+// up goes to the parent directory, or to the list of remotes from
+// the top of a remote
+func (p *pane) up() {
+	if p.isRemotes() {
+		return
+	}
+	if p.dir == "" {
+		p.reset(p.root)
+		p.root = ""
+		return
+	}
+	want := path.Base(p.dir)
+	p.dir = path.Dir(p.dir)
+	if p.dir == "." {
+		p.dir = ""
+	}
+	p.reset(want)
+}
+
+// reset the pane for a new directory putting the cursor on want
+func (p *pane) reset(want string) {
+	p.entries = nil
+	p.cursor = 0
+	p.offset = 0
+	p.err = nil
+	p.want = want
+	clear(p.selected)
+}
+
+// list starts listing the directory in the background sending the
+// result to results
+func (p *pane) list(ctx context.Context, results chan<- listResult) {
+	p.gen++
+	p.listing = true
+	gen, root, dir := p.gen, p.root, p.dir
+	if p.entries != nil && p.want == "" {
+		// Keep the cursor where it is when refreshing
+		if e := p.current(); e != nil {
+			p.want = e.name
+		}
+	}
+	go func() {
+		entries, err := listDir(ctx, root, dir)
+		results <- listResult{p: p, gen: gen, entries: entries, err: err}
+	}()
+}
+
+// update the pane with the result of a listing
+func (p *pane) update(r listResult) {
+	if r.gen != p.gen {
+		// Result of a listing which has been superseded
+		return
+	}
+	p.listing = false
+	p.err = r.err
+	p.entries = r.entries
+	for name := range p.selected {
+		if !containsEntry(p.entries, name) {
+			delete(p.selected, name)
+		}
+	}
+	p.cursor = min(p.cursor, max(len(p.entries)-1, 0))
+	if p.want != "" {
+		for i, e := range p.entries {
+			if e.name == p.want {
+				p.cursor = i
+				break
+			}
+		}
+		p.want = ""
+	}
+}
+
+// containsEntry returns true if entries has one called name
+func containsEntry(entries []entry, name string) bool {
+	for _, e := range entries {
+		if e.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// listDir lists dir in root, or the remotes if root is empty, with
+// the directories first
+func listDir(ctx context.Context, root, dir string) (entries []entry, err error) {
+	if root == "" {
+		entries = append(entries, entry{name: localRoot(), isDir: true})
+		for _, name := range config.FileSections() {
+			entries = append(entries, entry{name: name + ":", isDir: true})
+		}
+		return entries, nil
+	}
+	f, err := cache.Get(ctx, root)
+	if err != nil && err != fs.ErrorIsFile {
+		return nil, err
+	}
+	dirEntries, err := f.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, dirEntry := range dirEntries {
+		e := entry{
+			name:    path.Base(dirEntry.Remote()),
+			size:    dirEntry.Size(),
+			modTime: dirEntry.ModTime(ctx),
+		}
+		_, e.isDir = dirEntry.(fs.Directory)
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].isDir != entries[j].isDir {
+			return entries[i].isDir
+		}
+		return entries[i].name < entries[j].name
+	})
+	return entries, nil
+}
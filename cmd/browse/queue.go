@@ -0,0 +1,219 @@
+//go:build !plan9 && !js
+
+package browse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fs/rc/jobs"
+)
+
+// State of a transfer in the queue
+const (
+	statePending = iota
+	stateRunning
+	stateDone
+	stateFailed
+	stateCancelled
+)
+
+// transfer is an operation in the queue, run as an rc job
+type transfer struct {
+	what  string    // description for the user
+	call  *rc.Call  // rc call to run
+	in    rc.Params // parameters for the call
+	state int       // one of the state constants
+	job   *jobs.Job // job running the call once started
+	err   error     // error if failed
+}
+
+// progress returns a description of how the transfer is going
+func (t *transfer) progress(ctx context.Context) string {
+	switch t.state {
+	case statePending:
+		return "queued"
+	case stateCancelled:
+		return "cancelled"
+	case stateFailed:
+		return fmt.Sprintf("failed: %v", t.err)
+	}
+	out, err := accounting.StatsGroup(ctx, t.job.Group).RemoteStats(true)
+	if err != nil {
+		return err.Error()
+	}
+	bytes, _ := out["bytes"].(int64)
+	speed, _ := out["speed"].(float64)
+	transfers, _ := out["transfers"].(int64)
+	errs, _ := out["errors"].(int64)
+	s := fmt.Sprintf("%v, %d files", fs.SizeSuffix(bytes).ByteUnit(), transfers)
+	if t.state == stateRunning {
+		s += fmt.Sprintf(", %v/s", fs.SizeSuffix(int64(speed)).ByteUnit())
+	} else {
+		s = "done: " + s
+	}
+	if errs > 0 {
+		s += fmt.Sprintf(", %d errors", errs)
+	}
+	return s
+}
+
+// queue runs transfers one after another
+//
+// Each transfer is an rc job, so it can also be seen and stopped
+// with the rc if that is enabled, and has its own stats group.
+type queue struct {
+	ctx      context.Context
+	mu       sync.Mutex
+	items    []*transfer
+	finished chan *transfer // transfers are sent here when they end
+}
+
+// newQueue makes a new queue
+func newQueue(ctx context.Context) *queue {
+	return &queue{
+		ctx:      ctx,
+		finished: make(chan *transfer, 16),
+	}
+}
+
+// add a transfer of the rc call path with parameters in to the queue
+func (q *queue) add(what, path string, in rc.Params) error {
+	call := rc.Calls.Get(path)
+	if call == nil {
+		return fmt.Errorf("rc call %q not found", path)
+	}
+	q.mu.Lock()
+	q.items = append(q.items, &transfer{
+		what: what,
+		call: call,
+		in:   in,
+	})
+	q.mu.Unlock()
+	q.startNext()
+	return nil
+}
+
+// startNext starts the next pending transfer if nothing is running
+func (q *queue) startNext() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var next *transfer
+	for _, t := range q.items {
+		switch t.state {
+		case stateRunning:
+			return
+		case statePending:
+			if next == nil {
+				next = t
+			}
+		}
+	}
+	if next == nil {
+		return
+	}
+	in := next.in.Copy()
+	in["_async"] = true
+	job, _, err := jobs.NewJob(q.ctx, next.call.Fn, in)
+	if err != nil {
+		next.state, next.err = stateFailed, err
+		go func() { q.finished <- next }()
+		return
+	}
+	next.state, next.job = stateRunning, job
+	job.OnFinish(func() {
+		q.mu.Lock()
+		if next.state == stateRunning {
+			if job.Error != "" {
+				next.state, next.err = stateFailed, errors.New(job.Error)
+			} else {
+				next.state = stateDone
+			}
+		}
+		q.mu.Unlock()
+		q.finished <- next
+		q.startNext()
+	})
+}
+
+// cancel the transfer i, stopping it if it is running
+func (q *queue) cancel(i int) {
+	q.mu.Lock()
+	if i < 0 || i >= len(q.items) {
+		q.mu.Unlock()
+		return
+	}
+	t := q.items[i]
+	state := t.state
+	if state == statePending || state == stateRunning {
+		t.state = stateCancelled
+	}
+	q.mu.Unlock()
+	if state == stateRunning {
+		t.job.Stop()
+	}
+}
+
+// clear removes the transfers which have ended from the queue
+func (q *queue) clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := q.items[:0]
+	for _, t := range q.items {
+		if t.state == statePending || t.state == stateRunning {
+			items = append(items, t)
+		}
+	}
+	q.items = items
+}
+
+// active returns the number of transfers which haven't ended yet
+func (q *queue) active() (n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, t := range q.items {
+		if t.state == statePending || t.state == stateRunning {
+			n++
+		}
+	}
+	return n
+}
+
+// lines returns a line describing each transfer in the queue
+func (q *queue) lines() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	lines := make([]string, len(q.items))
+	for i, t := range q.items {
+		lines[i] = fmt.Sprintf("%s - %s", t.what, t.progress(q.ctx))
+	}
+	return lines
+}
+
+// running returns a line describing the running transfer or "" if
+// there isn't one
+func (q *queue) running() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, t := range q.items {
+		if t.state == stateRunning {
+			return fmt.Sprintf("%s - %s", t.what, t.progress(q.ctx))
+		}
+	}
+	return ""
+}
+
+// stopAll cancels all the transfers
+func (q *queue) stopAll() {
+	q.mu.Lock()
+	n := len(q.items)
+	q.mu.Unlock()
+	for i := range n {
+		q.cancel(i)
+	}
+}
@@ -27,6 +27,8 @@ var (
 	match             = ""
 	differ            = ""
 	errFile           = ""
+	report            = ""
+	reportFormat      = operations.CheckReportJSON
 	checkFileHashType = ""
 )
 
@@ -47,6 +49,8 @@ func AddFlags(cmdFlags *pflag.FlagSet) {
 	flags.StringVarP(cmdFlags, &match, "match", "", match, "Report all matching files to this file", "")
 	flags.StringVarP(cmdFlags, &differ, "differ", "", differ, "Report all non-matching files to this file", "")
 	flags.StringVarP(cmdFlags, &errFile, "error", "", errFile, "Report all files with errors (hashing or reading) to this file", "")
+	flags.StringVarP(cmdFlags, &report, "report", "", report, "Write a machine readable report of all files to this file", "")
+	flags.StringVarP(cmdFlags, &reportFormat, "report-format", "", reportFormat, "Format of the --report file: json|csv", "")
 }
 
 // FlagsHelp describes the flags for the help
@@ -73,6 +77,23 @@ you what happened to it. These are reminiscent of diff files.
 - |* path| means path was present in source and destination but different.
 - |! path| means there was an error reading or hashing the source or dest.
 
+The |--report| flag writes a machine readable report of every file
+checked to the file name (or stdout if it is |-|) supplied, for use by
+auditing scripts. The format is set with |--report-format| and can be
+|json| (the default), which writes a JSON array of objects, or |csv|,
+which writes a CSV file with a header line. Each file has these fields:
+
+- |Path| / |path| - the path of the file
+- |Status| / |status| - one of |match|, |differ|, |missing_on_src|, |missing_on_dst| or |error|
+- |SrcSize| / |src_size| - the size in the source or -1 if missing
+- |DstSize| / |dst_size| - the size in the destination or -1 if missing
+- |HashType| / |hash_type| - the hash compared, if any
+- |SrcHash| / |src_hash| - the hash of the source, if compared
+- |DstHash| / |dst_hash| - the hash of the destination, if compared
+- |Error| / |error| - the reason for the |error| status
+
+Empty fields are left out of the JSON report.
+
 The default number of parallel checks is 8. See the [--checkers=N](/docs/#checkers-n)
 option for more information.
 `, "|", "`")
@@ -122,6 +143,10 @@ func GetCheckOpt(fsrc, fdst fs.Fs) (opt *operations.CheckOpt, close func(), err
 	if err = open(errFile, &opt.Error); err != nil {
 		return nil, nil, err
 	}
+	if err = open(report, &opt.Report); err != nil {
+		return nil, nil, err
+	}
+	opt.ReportFormat = reportFormat
 
 	close = func() {
 		for _, closer := range closers {
@@ -23,6 +23,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config"
 	"github.com/rclone/rclone/fs/config/configfile"
 	"github.com/rclone/rclone/fs/config/configflags"
 	"github.com/rclone/rclone/fs/config/flags"
@@ -401,6 +402,20 @@ func initConfig() {
 	// Load the config
 	configfile.Install()
 
+	// Apply the profile if set, then reload the global options so
+	// it takes effect under the flags and environment variables
+	if ci.Profile != "" {
+		err = config.SetProfile(ci.Profile)
+		if err != nil {
+			fs.Fatalf(nil, "Failed to use profile: %v", err)
+		}
+		err = fs.GlobalOptionsInit()
+		if err != nil {
+			fs.Fatalf(nil, "Failed to initialise global options from profile: %v", err)
+		}
+		configflags.SetFlags(ci)
+	}
+
 	// Start accounting
 	accounting.Start(ctx)
 
@@ -505,6 +520,9 @@ func resolveExitCode(err error) {
 		os.Exit(exitcode.TransferExceeded)
 	case errors.Is(err, fssync.ErrorMaxDurationReached):
 		os.Exit(exitcode.DurationExceeded)
+	}
+
+	switch {
 	case fserrors.ShouldRetry(err):
 		os.Exit(exitcode.RetryError)
 	case fserrors.IsNoRetryError(err), fserrors.IsNoLowLevelRetryError(err):
@@ -513,6 +531,19 @@ func resolveExitCode(err error) {
 		os.Exit(exitcode.FatalError)
 	case errors.Is(err, errorCommandNotFound), errors.Is(err, errorNotEnoughArguments), errors.Is(err, errorTooManyArguments):
 		os.Exit(exitcode.UsageError)
+	}
+
+	// Only use the category if none of the above applies so the
+	// exit codes don't change for errors which already had one
+	switch fserrors.GetCategory(err) {
+	case fserrors.CategoryAuth:
+		os.Exit(exitcode.AuthError)
+	case fserrors.CategoryQuota:
+		os.Exit(exitcode.QuotaError)
+	case fserrors.CategoryRateLimit:
+		os.Exit(exitcode.RateLimitError)
+	case fserrors.CategoryIntegrity:
+		os.Exit(exitcode.IntegrityError)
 	default:
 		os.Exit(exitcode.UncategorizedError)
 	}
@@ -30,6 +30,7 @@ type FS struct {
 	mu        sync.Mutex // to protect the below
 	handles   []vfs.Handle
 	destroyed atomic.Int32
+	host      *fuse.FileSystemHost
 }
 
 // NewFS makes a new FS
@@ -43,6 +44,37 @@ func NewFS(VFS *vfs.VFS, opt *mountlib.Options) *FS {
 	return fsys
 }
 
+// changeNotify tells the OS about a path which has changed on the
+// remote so file watchers see the change
+//
+// This is only supported by WinFsp. Nothing is sent unless the parent
+// directory is in the directory cache as otherwise nothing can be
+// watching it.
+func (fsys *FS) changeNotify(changePath string, entryType fs.EntryType) {
+	defer log.Trace(changePath, "type=%v", entryType)("")
+	if changePath == "" || fsys.VFS.CachedNode(path.Dir(changePath)) == nil {
+		return
+	}
+	known := fsys.VFS.CachedNode(changePath) != nil
+	var action uint32
+	node, err := fsys.VFS.Stat(changePath)
+	switch {
+	case err != nil && entryType == fs.EntryDirectory:
+		action = fuse.NOTIFY_RMDIR
+	case err != nil:
+		action = fuse.NOTIFY_UNLINK
+	case !known && node.IsDir():
+		action = fuse.NOTIFY_MKDIR
+	case !known:
+		action = fuse.NOTIFY_CREATE
+	default:
+		action = fuse.NOTIFY_UTIME | fuse.NOTIFY_TRUNCATE
+	}
+	if !fsys.host.Notify("/"+changePath, action) {
+		fs.Debugf(changePath, "Failed to notify change")
+	}
+}
+
 // Open a handle returning an integer file handle
 func (fsys *FS) openHandle(handle vfs.Handle) (fh uint64) {
 	fsys.mu.Lock()
@@ -530,27 +562,58 @@ func (fsys *FS) Fsyncdir(path string, datasync bool, fh uint64) (errc int) {
 }
 
 // Setxattr sets extended attributes.
+//
+// Extended attributes in the user namespace are mapped to the
+// metadata of the objects and directories by the vfs.
 func (fsys *FS) Setxattr(path string, name string, value []byte, flags int) (errc int) {
 	defer log.Trace(path, "name=%q, value=%q, flags=%d", name, value, flags)("errc=%d", &errc)
-	return -fuse.ENOSYS
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return errc
+	}
+	create := flags&fuse.XATTR_CREATE != 0
+	replace := flags&fuse.XATTR_REPLACE != 0
+	return translateError(vfs.Setxattr(node, name, value, create, replace))
 }
 
 // Getxattr gets extended attributes.
 func (fsys *FS) Getxattr(path string, name string) (errc int, value []byte) {
 	defer log.Trace(path, "name=%q", name)("errc=%d, value=%q", &errc, &value)
-	return -fuse.ENOSYS, nil
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return errc, nil
+	}
+	value, err := vfs.Getxattr(node, name)
+	return translateError(err), value
 }
 
 // Removexattr removes extended attributes.
 func (fsys *FS) Removexattr(path string, name string) (errc int) {
 	defer log.Trace(path, "name=%q", name)("errc=%d", &errc)
-	return -fuse.ENOSYS
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return errc
+	}
+	return translateError(vfs.Removexattr(node, name))
 }
 
 // Listxattr lists extended attributes.
 func (fsys *FS) Listxattr(path string, fill func(name string) bool) (errc int) {
 	defer log.Trace(path, "fill=%p", fill)("errc=%d", &errc)
-	return -fuse.ENOSYS
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return errc
+	}
+	names, err := vfs.Listxattr(node)
+	if err != nil {
+		return translateError(err)
+	}
+	for _, name := range names {
+		if !fill(name) {
+			return -fuse.ERANGE
+		}
+	}
+	return 0
 }
 
 // Getpath allows a case-insensitive file system to report the correct case of
@@ -599,6 +662,8 @@ func translateError(err error) (errc int) {
 		return -fuse.EINVAL
 	case vfs.ELOOP:
 		return -fuse.ELOOP
+	case vfs.ENOATTR:
+		return -fuse.ENOATTR
 	}
 	fs.Errorf(nil, "IO error: %v", err)
 	return -fuse.EIO
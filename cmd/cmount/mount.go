@@ -133,6 +133,7 @@ func mount(VFS *vfs.VFS, mountPath string, opt *mountlib.Options) (<-chan error,
 	// Create underlying FS
 	fsys := NewFS(VFS, opt)
 	host := fuse.NewFileSystemHost(fsys)
+	fsys.host = host
 	host.SetCapReaddirPlus(true) // only works on Windows
 	if opt.CaseInsensitive.Valid {
 		host.SetCapCaseInsensitive(opt.CaseInsensitive.Value)
@@ -161,8 +162,12 @@ func mount(VFS *vfs.VFS, mountPath string, opt *mountlib.Options) (<-chan error,
 		errChan <- err
 	}()
 
+	// set once mounted
+	removeChangeNotify := func() {}
+
 	// unmount
 	unmount := func() error {
+		removeChangeNotify()
 		// Shutdown the VFS
 		fsys.VFS.Shutdown()
 		var umountOK bool
@@ -213,5 +218,10 @@ func mount(VFS *vfs.VFS, mountPath string, opt *mountlib.Options) (<-chan error,
 		}
 	}
 
+	// Tell WinFsp about changes on the remote
+	if runtime.GOOS == "windows" {
+		removeChangeNotify = VFS.AddChangeNotify(fsys.changeNotify)
+	}
+
 	return errChan, unmount, nil
 }
@@ -1,16 +1,26 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/fshttp"
 	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/lib/file"
+	"github.com/rclone/rclone/lib/random"
 	"github.com/spf13/cobra"
 )
 
@@ -70,6 +80,164 @@ func addLocalFiles(toComplete string, result cobra.ShellCompDirective, completio
 	return result, completions
 }
 
+// How long remote listings made for completion are cached for
+const completionCacheTime = time.Minute
+
+// How long to wait for a remote listing while completing
+const completionTimeout = 10 * time.Second
+
+// completionEntry is an entry in a remote directory offered for
+// completion
+type completionEntry struct {
+	Name  string `json:"name"`
+	IsDir bool   `json:"isDir"`
+}
+
+// completionCache is a remote directory listing cached on disk
+type completionCache struct {
+	Time    time.Time         `json:"time"`
+	Entries []completionEntry `json:"entries"`
+}
+
+// completionCachePath returns the file the listing of parent is cached in
+func completionCachePath(parent string) string {
+	return filepath.Join(config.GetCacheDir(), "completion", fmt.Sprintf("%x.json", md5.Sum([]byte(parent))))
+}
+
+// readCompletionCache reads the listing of parent from the cache if
+// it is there and fresh enough
+func readCompletionCache(parent string) ([]completionEntry, bool) {
+	data, err := os.ReadFile(completionCachePath(parent))
+	if err != nil {
+		return nil, false
+	}
+	var cached completionCache
+	if err := json.Unmarshal(data, &cached); err != nil || time.Since(cached.Time) > completionCacheTime {
+		return nil, false
+	}
+	compLogf("Using cached listing of %q", parent)
+	return cached.Entries, true
+}
+
+// writeCompletionCache caches the listing of parent so that
+// completing the same directory again doesn't list it again
+func writeCompletionCache(parent string, entries []completionEntry) {
+	path := completionCachePath(parent)
+	data, err := json.Marshal(completionCache{Time: time.Now(), Entries: entries})
+	if err == nil {
+		err = file.MkdirAll(filepath.Dir(path), 0700)
+	}
+	if err == nil {
+		tmp := path + ".tmp" + random.String(8)
+		err = os.WriteFile(tmp, data, 0600)
+		if err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		compLogf("Failed to cache listing of %q: %v", parent, err)
+	}
+}
+
+// listRemoteDirRC lists parent using the rc of a running rclone
+// rcd at rcURL, which may contain a user and password.
+func listRemoteDirRC(ctx context.Context, rcURL, parent string) (entries []completionEntry, err error) {
+	u, err := url.Parse(rcURL)
+	if err != nil {
+		return nil, err
+	}
+	user := u.User
+	u.User = nil
+	u.Path = strings.TrimRight(u.Path, "/") + "/operations/list"
+	in, err := json.Marshal(rc.Params{
+		"fs":     parent,
+		"remote": "",
+		"opt": rc.Params{
+			"noModTime":  true,
+			"noMimeType": true,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), bytes.NewReader(in))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if user != nil {
+		pass, _ := user.Password()
+		req.SetBasicAuth(user.Username(), pass)
+	}
+	resp, err := fshttp.NewClient(ctx).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rc returned %s", resp.Status)
+	}
+	var out struct {
+		List []struct {
+			Name  string
+			IsDir bool
+		} `json:"list"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	for _, item := range out.List {
+		entries = append(entries, completionEntry{Name: item.Name, IsDir: item.IsDir})
+	}
+	return entries, nil
+}
+
+// listRemoteDirDirect lists parent by making the remote
+func listRemoteDirDirect(ctx context.Context, parent string) ([]completionEntry, error) {
+	f, err := cache.Get(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	fis, err := f.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]completionEntry, 0, len(fis))
+	for _, fi := range fis {
+		_, isDir := fi.(fs.Directory)
+		entries = append(entries, completionEntry{Name: fi.Remote(), IsDir: isDir})
+	}
+	return entries, nil
+}
+
+// listRemoteDir lists parent for completion.
+//
+// If RCLONE_COMPLETION_RC is set to the URL of a running rclone rcd
+// the listing is done by that, which is quick as it already has the
+// remote set up and maybe the listing cached. Otherwise the remote is
+// listed here and the listing cached for completionCacheTime so
+// completing more of the same path doesn't need to list it again.
+func listRemoteDir(ctx context.Context, parent string) ([]completionEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+	if rcURL := os.Getenv("RCLONE_COMPLETION_RC"); rcURL != "" {
+		entries, err := listRemoteDirRC(ctx, rcURL, parent)
+		if err == nil {
+			return entries, nil
+		}
+		compLogf("Failed to list %q with the rc: %v", parent, err)
+	}
+	if entries, ok := readCompletionCache(parent); ok {
+		return entries, nil
+	}
+	entries, err := listRemoteDirDirect(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	writeCompletionCache(parent, entries)
+	return entries, nil
+}
+
 // Add remote files to the completions being built up
 func addRemoteFiles(toComplete string, result cobra.ShellCompDirective, completions []string) (cobra.ShellCompDirective, []string) {
 	ctx := context.Background()
@@ -78,24 +246,18 @@ func addRemoteFiles(toComplete string, result cobra.ShellCompDirective, completi
 		compLogf("Failed to split path %q: %v", toComplete, err)
 		return result, completions
 	}
-	f, err := cache.Get(ctx, parent)
+	entries, err := listRemoteDir(ctx, parent)
 	if err == fs.ErrorIsFile {
 		completions = append(completions, toComplete)
 		return result, completions
 	} else if err != nil {
-		compLogf("Failed to make Fs %q: %v", parent, err)
+		compLogf("Failed to list %q: %v", parent, err)
 		return result, completions
 	}
-	fis, err := f.List(ctx, "")
-	if err != nil {
-		compLogf("Failed to list Fs %q: %v", parent, err)
-		return result, completions
-	}
-	for _, fi := range fis {
-		remote := fi.Remote()
-		path := parent + remote
+	for _, entry := range entries {
+		path := parent + entry.Name
 		if strings.HasPrefix(path, toComplete) {
-			if _, ok := fi.(fs.Directory); ok {
+			if entry.IsDir {
 				path += "/"
 				result |= cobra.ShellCompDirectiveNoSpace
 			}
@@ -0,0 +1,308 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkWrite bool
+)
+
+func init() {
+	configCommand.AddCommand(configCheckCommand)
+	cmdFlags := configCheckCommand.Flags()
+	flags.BoolVarP(cmdFlags, &checkWrite, "write", "", false, "Write, read back and delete a small test file", "")
+	flags.BoolVarP(cmdFlags, &jsonOutput, "json", "", false, "Format output as JSON", "")
+}
+
+var configCheckCommand = &cobra.Command{
+	Use:   "check [remote:path]*",
+	Short: `Check the remotes passed in are configured and working.`,
+	Long: strings.ReplaceAll(`This checks each remote passed in, or every remote in the config
+file if none are, to see whether it can be used. It can be run before
+scheduled jobs to make sure that credentials haven't expired and the
+remotes can be reached.
+
+For each remote it
+
+- creates the remote, which checks the config and usually logs in
+- lists the top level of the remote:path
+- writes, reads back and deletes a small test file if |--write| is set
+- reports the hashes and optional features the remote supports
+
+If any of the checks fail for any remote then rclone exits with a
+non-zero exit code.
+
+The test file is called |rclone-config-check-XXXXXXXX.txt| and is
+written to the root of the remote:path. It isn't written if
+|--dry-run| is set.
+
+Use |--json| to output the results as JSON, for example
+
+|||json
+[
+	{
+		"remote": "remote:",
+		"ok": true,
+		"checks": [
+			{ "name": "create", "ok": true, "duration": 0.21 },
+			{ "name": "list", "ok": true, "duration": 0.13 }
+		],
+		"hashes": [ "md5" ],
+		"features": [ "About", "Copy", "Move", "Purge" ]
+	}
+]
+|||
+`, "|", "`"),
+	Annotations: map[string]string{
+		"versionIntroduced": "v1.70",
+	},
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 1e6, command, args)
+		cmd.Run(false, false, command, func() error {
+			return runCheck(context.Background(), args)
+		})
+	},
+}
+
+// runCheck checks the remotes in args or all the remotes if empty
+func runCheck(ctx context.Context, args []string) error {
+	remotes := args
+	if len(remotes) == 0 {
+		for _, remote := range config.FileSections() {
+			remotes = append(remotes, remote+":")
+		}
+		if len(remotes) == 0 {
+			return errors.New("no remotes found in the config file")
+		}
+	}
+	var results []*checkResult
+	failed := 0
+	for _, remote := range remotes {
+		result := checkRemote(ctx, remote, checkWrite)
+		if !result.OK {
+			failed++
+		}
+		results = append(results, result)
+		if !jsonOutput {
+			result.print(os.Stdout)
+		}
+	}
+	if jsonOutput {
+		out := json.NewEncoder(os.Stdout)
+		out.SetIndent("", "\t")
+		err := out.Encode(results)
+		if err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d remotes failed checks", failed, len(results))
+	}
+	return nil
+}
+
+// checkStep is the result of one check on a remote
+type checkStep struct {
+	Name     string  `json:"name"`
+	OK       bool    `json:"ok"`
+	Error    string  `json:"error,omitempty"`
+	Info     string  `json:"info,omitempty"`
+	Duration float64 `json:"duration"` // seconds
+}
+
+// checkResult is the result of checking a remote
+type checkResult struct {
+	Remote   string       `json:"remote"`
+	OK       bool         `json:"ok"`
+	Checks   []*checkStep `json:"checks"`
+	Hashes   []string     `json:"hashes,omitempty"`
+	Features []string     `json:"features,omitempty"`
+}
+
+// run runs fn as the check called name, recording the result. It
+// returns false if the check failed.
+func (r *checkResult) run(name string, fn func() (info string, err error)) bool {
+	start := time.Now()
+	info, err := fn()
+	step := &checkStep{
+		Name:     name,
+		OK:       err == nil,
+		Info:     info,
+		Duration: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		step.Error = err.Error()
+		r.OK = false
+		fs.Errorf(r.Remote, "Check %s failed: %v", name, err)
+	} else {
+		fs.Debugf(r.Remote, "Check %s passed", name)
+	}
+	r.Checks = append(r.Checks, step)
+	return err == nil
+}
+
+// print the result in human readable form to out
+func (r *checkResult) print(out io.Writer) {
+	status := "OK"
+	if !r.OK {
+		status = "FAILED"
+	}
+	_, _ = fmt.Fprintf(out, "%s %s\n", r.Remote, status)
+	for _, step := range r.Checks {
+		detail := step.Info
+		status := "OK"
+		if !step.OK {
+			status = "FAILED"
+			detail = step.Error
+		}
+		if detail != "" {
+			detail = " - " + detail
+		}
+		_, _ = fmt.Fprintf(out, "  %-10s %-6s %8.3fs%s\n", step.Name, status, step.Duration, detail)
+	}
+	if r.Hashes != nil {
+		_, _ = fmt.Fprintf(out, "  %-10s %s\n", "hashes", strings.Join(r.Hashes, ", "))
+	}
+	if r.Features != nil {
+		_, _ = fmt.Fprintf(out, "  %-10s %s\n", "features", strings.Join(r.Features, ", "))
+	}
+}
+
+// checkRemote runs the checks on remote and returns the result
+func checkRemote(ctx context.Context, remote string, write bool) *checkResult {
+	// Allow "remote" as a short form of "remote:"
+	if !strings.ContainsAny(remote, `:/\`) {
+		remote += ":"
+	}
+	r := &checkResult{
+		Remote: remote,
+		OK:     true,
+	}
+
+	var f fs.Fs
+	ok := r.run("create", func() (_ string, err error) {
+		f, err = fs.NewFs(ctx, remote)
+		if errors.Is(err, fs.ErrorIsFile) {
+			return "", fmt.Errorf("%q is a file not a directory", remote)
+		}
+		return "", err
+	})
+	if !ok {
+		return r
+	}
+
+	for _, ht := range f.Hashes().Array() {
+		r.Hashes = append(r.Hashes, ht.String())
+	}
+	if r.Hashes == nil {
+		r.Hashes = []string{}
+	}
+	r.Features = []string{}
+	for name, enabled := range f.Features().Enabled() {
+		if enabled {
+			r.Features = append(r.Features, name)
+		}
+	}
+	sort.Strings(r.Features)
+
+	r.run("list", func() (string, error) {
+		entries, err := f.List(ctx, "")
+		if errors.Is(err, fs.ErrorDirNotFound) {
+			return "directory not found", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d entries", len(entries)), nil
+	})
+
+	if write {
+		checkReadWrite(ctx, r, f)
+	}
+	return r
+}
+
+// checkReadWrite writes a test file to f, reads it back and deletes it
+func checkReadWrite(ctx context.Context, r *checkResult, f fs.Fs) {
+	name := "rclone-config-check-" + random.String(8) + ".txt"
+	if operations.SkipDestructive(ctx, name, "write test file") {
+		return
+	}
+	data := []byte("rclone config check " + random.String(32) + "\n")
+
+	var o fs.Object
+	ok := r.run("write", func() (_ string, err error) {
+		src := object.NewStaticObjectInfo(name, time.Now(), int64(len(data)), true, nil, f)
+		o, err = f.Put(ctx, bytes.NewReader(data), src)
+		return "", err
+	})
+	if !ok {
+		return
+	}
+
+	r.run("read", func() (string, error) {
+		o, err := f.NewObject(ctx, name)
+		if err != nil {
+			return "", fmt.Errorf("failed to find test file: %w", err)
+		}
+		in, err := o.Open(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to open test file: %w", err)
+		}
+		got, err := io.ReadAll(in)
+		closeErr := in.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read test file: %w", err)
+		}
+		if !bytes.Equal(got, data) {
+			return "", errors.New("test file contents read back differ from those written")
+		}
+		ht := f.Hashes().GetOne()
+		if ht == hash.None {
+			return "", nil
+		}
+		want, err := hash.NewMultiHasherTypes(hash.NewHashSet(ht))
+		if err != nil {
+			return "", err
+		}
+		_, _ = want.Write(data)
+		sum, err := o.Hash(ctx, ht)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %v of test file: %w", ht, err)
+		}
+		if sum == "" {
+			return fmt.Sprintf("%v not available", ht), nil
+		}
+		if wantSum := want.Sums()[ht]; sum != wantSum {
+			return "", fmt.Errorf("%v of test file is %q but expected %q", ht, sum, wantSum)
+		}
+		return fmt.Sprintf("%v matches", ht), nil
+	})
+
+	r.run("delete", func() (string, error) {
+		return "", o.Remove(ctx)
+	})
+}
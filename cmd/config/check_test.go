@@ -0,0 +1,53 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRemote(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello"), 0666))
+
+	r := checkRemote(ctx, dir, true)
+	assert.True(t, r.OK)
+	var names []string
+	for _, step := range r.Checks {
+		assert.True(t, step.OK, step.Name)
+		names = append(names, step.Name)
+	}
+	assert.Equal(t, []string{"create", "list", "write", "read", "delete"}, names)
+	assert.Equal(t, "1 entries", r.Checks[1].Info)
+	assert.Equal(t, "md5 matches", r.Checks[3].Info)
+	assert.Contains(t, r.Hashes, "md5")
+	assert.Contains(t, r.Features, "Move")
+
+	// The test file should have been removed
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	var out bytes.Buffer
+	r.print(&out)
+	assert.Contains(t, out.String(), dir+" OK\n")
+	assert.Contains(t, out.String(), "  delete     OK")
+}
+
+func TestCheckRemoteFailed(t *testing.T) {
+	ctx := context.Background()
+	r := checkRemote(ctx, "config-check-does-not-exist", false)
+	assert.False(t, r.OK)
+	require.Len(t, r.Checks, 1)
+	assert.Equal(t, "create", r.Checks[0].Name)
+	assert.False(t, r.Checks[0].OK)
+	assert.NotEqual(t, "", r.Checks[0].Error)
+	assert.Equal(t, "config-check-does-not-exist:", r.Remote)
+}
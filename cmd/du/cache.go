@@ -0,0 +1,93 @@
+package du
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+)
+
+// scanCache is the file the scan cache is stored in
+type scanCache struct {
+	Remote string               // the remote:path scanned
+	Dirs   map[string]*dirEntry // usage of each directory
+}
+
+// cachePath returns the path of the scan cache file for f
+func cachePath(f fs.Fs) string {
+	remote := fs.ConfigString(f)
+	sum := md5.Sum([]byte(remote))
+	return filepath.Join(config.GetCacheDir(), "du", hex.EncodeToString(sum[:])+".json")
+}
+
+// loadCache reads the scan cache for f, returning nil if there isn't
+// a usable one
+func loadCache(f fs.Fs) map[string]*dirEntry {
+	cacheFile := cachePath(f)
+	data, err := os.ReadFile(cacheFile)
+	if os.IsNotExist(err) {
+		fs.Debugf(f, "No scan cache found")
+		return nil
+	} else if err != nil {
+		fs.Errorf(f, "Failed to read scan cache: %v", err)
+		return nil
+	}
+	var cache scanCache
+	err = json.Unmarshal(data, &cache)
+	if err != nil {
+		fs.Errorf(f, "Ignoring corrupted scan cache %q: %v", cacheFile, err)
+		return nil
+	}
+	if cache.Remote != fs.ConfigString(f) {
+		fs.Debugf(f, "Ignoring scan cache for %q", cache.Remote)
+		return nil
+	}
+	fs.Debugf(f, "Loaded scan cache from %q", cacheFile)
+	return cache.Dirs
+}
+
+// saveCache writes dirs as the scan cache for f
+func saveCache(f fs.Fs, dirs map[string]*dirEntry) {
+	cacheFile := cachePath(f)
+	err := writeCache(cacheFile, &scanCache{
+		Remote: fs.ConfigString(f),
+		Dirs:   dirs,
+	})
+	if err != nil {
+		fs.Errorf(f, "Failed to write scan cache: %v", err)
+		return
+	}
+	fs.Debugf(f, "Saved scan cache to %q", cacheFile)
+}
+
+// writeCache writes cache to cacheFile atomically
+func writeCache(cacheFile string, cache *scanCache) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(cacheFile), 0700)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(cacheFile), filepath.Base(cacheFile)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), cacheFile)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,176 @@
+// Package du provides the du command.
+package du
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	level       int
+	jsonOutput  bool
+	noCache     bool
+	refresh     bool
+	maxCacheAge = fs.Duration(24 * time.Hour)
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.IntVarP(cmdFlags, &level, "level", "", 0, "Only show directories this many levels deep (0 for all)", "")
+	flags.BoolVarP(cmdFlags, &jsonOutput, "json", "", false, "Format output as JSON", "")
+	flags.BoolVarP(cmdFlags, &noCache, "no-cache", "", false, "Don't read or write the scan cache", "")
+	flags.BoolVarP(cmdFlags, &refresh, "refresh", "", false, "Scan everything again, replacing the scan cache", "")
+	flags.FVarP(cmdFlags, &maxCacheAge, "max-cache-age", "", "Rescan cached directories older than this, 0 for no limit", "")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "du remote:path",
+	Short: `Prints the size and number of objects in each directory of remote:path.`,
+	Long: strings.ReplaceAll(`Prints the total size and number of objects in each directory in
+remote:path, including all of its subdirectories, in a similar way to
+the unix du command. The directories are printed after their
+subdirectories with the total for remote:path last.
+
+    $ rclone du remote:path
+         1.234 GiB      1203 photos/2023
+         2.468 GiB      2422 photos
+           512 KiB         3 docs
+         2.469 GiB      2425 .
+
+Use |--level| to only show the directories down to that depth, for
+example |--level 1| shows just the top level directories. The totals
+still include everything below them. Use |--human-readable| to show
+the sizes in human-readable format and |--json| to output a list of
+objects with |Path|, |Size| and |Count| instead.
+
+### Scan cache
+
+Listing a large remote can take a long time, so the results of the
+scan are kept in a cache in the directory given by |--cache-dir|.
+When |rclone du| is run again on the same remote:path, a directory
+whose modification time is the same as when it was scanned has its
+totals, and the totals of all the directories below it, read from the
+cache instead of being listed again. This makes repeated runs on
+slowly changing remotes very quick.
+
+This relies on the remote updating the modification time of a
+directory when its contents change. Most remotes which store
+directories, like the local disk and SFTP, do this when a file or
+directory is added, removed or renamed in it. However the
+modification time of a directory usually isn't changed when a file
+in it is modified, nor when anything changes deeper down, so the
+totals can be out of date. Directories whose modification times
+aren't known, for example on bucket based remotes, are always listed.
+
+To limit how stale the results can be, cached directories are scanned
+again when they are older than |--max-cache-age| (default 24h). Use
+|--refresh| to scan everything again, or |--no-cache| to not use the
+cache at all. The cache isn't used when any filters are in use.
+`, "|", "`"),
+	Annotations: map[string]string{
+		"versionIntroduced": "v1.70",
+		"groups":            "Filter,Listing",
+	},
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		fsrc := cmd.NewFsDir(args)
+		cmd.Run(false, false, command, func() error {
+			return du(context.Background(), fsrc)
+		})
+	},
+}
+
+// Usage is the usage of a directory as output with --json
+type Usage struct {
+	Path  string
+	Size  int64
+	Count int64
+}
+
+// scanUsage works out the usage of fsrc, reading and updating the
+// scan cache if useCache is set
+func scanUsage(ctx context.Context, fsrc fs.Fs, useCache bool) (s *scanner, root *dirEntry, err error) {
+	s = newScanner(ctx, fsrc, time.Duration(maxCacheAge))
+	if useCache && !refresh {
+		s.old = loadCache(fsrc)
+	}
+	root, err = s.scan()
+	if err != nil {
+		return nil, nil, err
+	}
+	fs.Infof(fsrc, "Listed %d directories, read %d from the scan cache", s.listed, s.cached)
+	if useCache && s.complete {
+		saveCache(fsrc, s.dirs)
+	}
+	return s, root, nil
+}
+
+// du prints the usage of fsrc
+func du(ctx context.Context, fsrc fs.Fs) error {
+	useCache := !noCache
+	if useCache && !filter.GetConfig(ctx).InActive() {
+		fs.Infof(fsrc, "Not using the scan cache as filters are in use")
+		useCache = false
+	}
+	s, root, err := scanUsage(ctx, fsrc, useCache)
+	if err != nil {
+		return err
+	}
+
+	var usages []Usage
+	addUsage(s.dirs, "", root, 0, &usages)
+	if jsonOutput {
+		out := json.NewEncoder(os.Stdout)
+		out.SetIndent("", "\t")
+		return out.Encode(usages)
+	}
+	ci := fs.GetConfig(ctx)
+	for _, u := range usages {
+		p := u.Path
+		if p == "" {
+			p = "."
+		}
+		operations.SyncPrintf("%s %s %s\n",
+			operations.SizeStringField(u.Size, ci.HumanReadable, 12),
+			operations.CountStringField(u.Count, ci.HumanReadable, 9),
+			p)
+	}
+	if !s.complete {
+		return fmt.Errorf("failed to read some directories - totals may be underestimated")
+	}
+	return nil
+}
+
+// addUsage adds the usage of dir at depth and the directories below
+// it down to --level to usages with the subdirectories first
+func addUsage(dirs map[string]*dirEntry, dir string, d *dirEntry, depth int, usages *[]Usage) {
+	if level <= 0 || depth < level {
+		subDirs := append([]string(nil), d.Dirs...)
+		sort.Strings(subDirs)
+		for _, leaf := range subDirs {
+			subDir := path.Join(dir, leaf)
+			if sub := dirs[subDir]; sub != nil {
+				addUsage(dirs, subDir, sub, depth+1, usages)
+			}
+		}
+	}
+	*usages = append(*usages, Usage{
+		Path:  dir,
+		Size:  d.Size,
+		Count: d.Count,
+	})
+}
@@ -0,0 +1,108 @@
+package du
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes size bytes to name in dir
+func writeFile(t *testing.T, dir, name string, size int) {
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0777))
+	require.NoError(t, os.WriteFile(p, make([]byte, size), 0666))
+}
+
+func TestScanUsage(t *testing.T) {
+	ctx := context.Background()
+	oldCacheDir := config.GetCacheDir()
+	require.NoError(t, config.SetCacheDir(t.TempDir()))
+	defer func() {
+		require.NoError(t, config.SetCacheDir(oldCacheDir))
+	}()
+
+	dir := t.TempDir()
+	writeFile(t, dir, "top", 1)
+	writeFile(t, dir, "a/1", 10)
+	writeFile(t, dir, "a/b/2", 20)
+	writeFile(t, dir, "c/3", 5)
+	f, err := fs.NewFs(ctx, dir)
+	require.NoError(t, err)
+
+	// First scan lists everything
+	s, root, err := scanUsage(ctx, f, true)
+	require.NoError(t, err)
+	assert.Equal(t, 4, s.listed)
+	assert.Equal(t, 0, s.cached)
+	assert.Equal(t, int64(36), root.Size)
+	assert.Equal(t, int64(4), root.Count)
+	assert.Equal(t, int64(30), s.dirs["a"].Size)
+	assert.Equal(t, int64(20), s.dirs["a/b"].Size)
+
+	// Second scan only lists the root
+	s, root, err = scanUsage(ctx, f, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.listed)
+	assert.Equal(t, 3, s.cached)
+	assert.Equal(t, int64(36), root.Size)
+	assert.Equal(t, int64(30), s.dirs["a"].Size)
+
+	// Adding a file to c changes its modification time so it is
+	// listed again
+	writeFile(t, dir, "c/4", 100)
+	s, root, err = scanUsage(ctx, f, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.listed)
+	assert.Equal(t, 2, s.cached)
+	assert.Equal(t, int64(136), root.Size)
+	assert.Equal(t, int64(105), s.dirs["c"].Size)
+	assert.Equal(t, int64(2), s.dirs["c"].Count)
+
+	// Everything is listed if the cache is too old
+	oldMaxCacheAge := maxCacheAge
+	maxCacheAge = fs.Duration(time.Nanosecond)
+	defer func() {
+		maxCacheAge = oldMaxCacheAge
+	}()
+	s, _, err = scanUsage(ctx, f, true)
+	require.NoError(t, err)
+	assert.Equal(t, 4, s.listed)
+	assert.Equal(t, 0, s.cached)
+	maxCacheAge = oldMaxCacheAge
+
+	// Or the cache isn't used
+	s, _, err = scanUsage(ctx, f, false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, s.listed)
+}
+
+func TestAddUsage(t *testing.T) {
+	dirs := map[string]*dirEntry{
+		"":      {Size: 6, Count: 3, Dirs: []string{"b", "a"}},
+		"a":     {Size: 3, Count: 2, Dirs: []string{"sub"}},
+		"a/sub": {Size: 1, Count: 1},
+		"b":     {Size: 2, Count: 1},
+	}
+	for _, test := range []struct {
+		level int
+		want  []Usage
+	}{
+		{0, []Usage{{"a/sub", 1, 1}, {"a", 3, 2}, {"b", 2, 1}, {"", 6, 3}}},
+		{1, []Usage{{"a", 3, 2}, {"b", 2, 1}, {"", 6, 3}}},
+	} {
+		oldLevel := level
+		level = test.level
+		var got []Usage
+		addUsage(dirs, "", dirs[""], 0, &got)
+		level = oldLevel
+		assert.Equal(t, test.want, got, test.level)
+	}
+}
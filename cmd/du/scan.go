@@ -0,0 +1,169 @@
+package du
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/list"
+)
+
+// dirEntry is the usage of a directory as stored in the scan cache
+type dirEntry struct {
+	ModTime time.Time // modification time of the directory from its parent's listing
+	Scanned time.Time // when the directory was listed
+	Size    int64     // total size of the objects including subdirectories
+	Count   int64     // total number of objects including subdirectories
+	Dirs    []string  `json:",omitempty"` // leaf names of the subdirectories
+}
+
+// scanner works out the usage of each directory in f
+type scanner struct {
+	ctx    context.Context
+	f      fs.Fs
+	maxAge time.Duration
+	old    map[string]*dirEntry // scan cache from the last run if any
+	tokens chan struct{}        // limits the number of concurrent listings
+
+	mu       sync.Mutex
+	dirs     map[string]*dirEntry // usage found
+	listed   int                  // number of directories listed
+	cached   int                  // number of directories read from the cache
+	complete bool                 // set if all the directories could be read
+}
+
+// newScanner makes a scanner for f
+func newScanner(ctx context.Context, f fs.Fs, maxAge time.Duration) *scanner {
+	return &scanner{
+		ctx:      ctx,
+		f:        f,
+		maxAge:   maxAge,
+		tokens:   make(chan struct{}, fs.GetConfig(ctx).Checkers),
+		dirs:     make(map[string]*dirEntry),
+		complete: true,
+	}
+}
+
+// scan works out the usage of the root and everything below it
+func (s *scanner) scan() (*dirEntry, error) {
+	// The root has no modification time as it has no parent
+	// listing so it is always listed
+	return s.scanDir("", time.Time{})
+}
+
+// validCache checks dir and all the directories below it are in the
+// old cache and not too old, returning false if not
+//
+// call with s.mu held
+func (s *scanner) validCache(dir string) bool {
+	d := s.old[dir]
+	if d == nil {
+		return false
+	}
+	if s.maxAge > 0 && time.Since(d.Scanned) > s.maxAge {
+		return false
+	}
+	for _, leaf := range d.Dirs {
+		if !s.validCache(path.Join(dir, leaf)) {
+			return false
+		}
+	}
+	return true
+}
+
+// copyCache copies dir and all the directories below it from the old
+// cache
+//
+// call with s.mu held
+func (s *scanner) copyCache(dir string) {
+	d := s.old[dir]
+	s.dirs[dir] = d
+	s.cached++
+	for _, leaf := range d.Dirs {
+		s.copyCache(path.Join(dir, leaf))
+	}
+}
+
+// fromCache returns the usage of dir from the old cache if its
+// modification time shows it hasn't changed, or nil if it needs
+// listing
+func (s *scanner) fromCache(dir string, modTime time.Time) *dirEntry {
+	if s.old == nil || modTime.IsZero() {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.old[dir]
+	if d == nil || !d.ModTime.Equal(modTime) || !s.validCache(dir) {
+		return nil
+	}
+	s.copyCache(dir)
+	return d
+}
+
+// scanDir works out the usage of dir, whose modification time from
+// its parent's listing is modTime, and the directories below it
+func (s *scanner) scanDir(dir string, modTime time.Time) (*dirEntry, error) {
+	if d := s.fromCache(dir, modTime); d != nil {
+		fs.Debugf(dir, "Read usage from the scan cache")
+		return d, nil
+	}
+	s.tokens <- struct{}{}
+	entries, err := list.DirSorted(s.ctx, s.f, false, dir)
+	<-s.tokens
+	if err != nil {
+		return nil, err
+	}
+	d := &dirEntry{
+		ModTime: modTime,
+		Scanned: time.Now(),
+	}
+	var subDirs []fs.Directory
+	for _, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Object:
+			if size := x.Size(); size > 0 {
+				d.Size += size
+			}
+			d.Count++
+		case fs.Directory:
+			subDirs = append(subDirs, x)
+			d.Dirs = append(d.Dirs, path.Base(x.Remote()))
+		}
+	}
+
+	// Scan the subdirectories concurrently
+	var wg sync.WaitGroup
+	results := make([]*dirEntry, len(subDirs))
+	for i, subDir := range subDirs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := s.scanDir(subDir.Remote(), subDir.ModTime(s.ctx))
+			if err != nil {
+				err = fs.CountError(s.ctx, err)
+				fs.Errorf(subDir, "Failed to list: %v", err)
+				s.mu.Lock()
+				s.complete = false
+				s.mu.Unlock()
+				return
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+	for _, result := range results {
+		if result != nil {
+			d.Size += result.Size
+			d.Count += result.Count
+		}
+	}
+
+	s.mu.Lock()
+	s.dirs[dir] = d
+	s.listed++
+	s.mu.Unlock()
+	return d, nil
+}
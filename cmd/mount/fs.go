@@ -6,6 +6,7 @@ package mount
 
 import (
 	"context"
+	"path"
 	"syscall"
 
 	"bazil.org/fuse"
@@ -45,7 +46,41 @@ func (f *FS) Root() (node fusefs.Node, err error) {
 	if err != nil {
 		return nil, translateError(err)
 	}
-	return &Dir{root, f}, nil
+	if node, ok := root.Sys().(fusefs.Node); ok {
+		return node, nil
+	}
+	node = &Dir{root, f}
+	root.SetSys(node) // cache the FUSE node for changeNotify
+	return node, nil
+}
+
+// changeNotify invalidates the kernel's caches of a path which has
+// changed on the remote
+//
+// Only nodes the kernel has looked up have a FUSE node cached in them
+// and only those can be cached by the kernel.
+func (f *FS) changeNotify(changePath string, entryType fs.EntryType) {
+	defer log.Trace(changePath, "type=%v", entryType)("")
+	if changePath != "" {
+		parent, leaf := path.Split(changePath)
+		if dir := f.VFS.CachedNode(parent); dir != nil {
+			if dirNode, ok := dir.Sys().(fusefs.Node); ok {
+				f.invalidate(f.server.InvalidateEntry(dirNode, leaf), changePath)
+			}
+		}
+	}
+	if node := f.VFS.CachedNode(changePath); node != nil {
+		if fuseNode, ok := node.Sys().(fusefs.Node); ok {
+			f.invalidate(f.server.InvalidateNodeData(fuseNode), changePath)
+		}
+	}
+}
+
+// invalidate logs the result of invalidating changePath
+func (f *FS) invalidate(err error, changePath string) {
+	if err != nil && err != fuse.ErrNotCached {
+		fs.Debugf(changePath, "Failed to invalidate: %v", err)
+	}
 }
 
 // Check interface satisfied
@@ -102,6 +137,8 @@ func translateError(err error) error {
 		return fuse.Errno(syscall.EINVAL)
 	case vfs.ELOOP:
 		return fuse.Errno(syscall.ELOOP)
+	case vfs.ENOATTR:
+		return fuse.ErrNoXattr
 	}
 	fs.Errorf(nil, "IO error: %v", err)
 	return err
@@ -90,6 +90,7 @@ func mount(VFS *vfs.VFS, mountpoint string, opt *mountlib.Options) (<-chan error
 
 	filesys := NewFS(VFS, opt)
 	filesys.server = fusefs.New(c, nil)
+	removeChangeNotify := VFS.AddChangeNotify(filesys.changeNotify)
 
 	// Serve the mount point in the background returning error to errChan
 	errChan := make(chan error, 1)
@@ -103,6 +104,7 @@ func mount(VFS *vfs.VFS, mountpoint string, opt *mountlib.Options) (<-chan error
 	}()
 
 	unmount := func() error {
+		removeChangeNotify()
 		// Shutdown the VFS
 		filesys.VFS.Shutdown()
 		return fuse.Unmount(mountpoint)
@@ -0,0 +1,112 @@
+//go:build linux
+
+package mount
+
+import (
+	"context"
+
+	"bazil.org/fuse"
+	fusefs "bazil.org/fuse/fs"
+	"github.com/rclone/rclone/fs/log"
+	"github.com/rclone/rclone/vfs"
+	"golang.org/x/sys/unix"
+)
+
+// Extended attributes in the user namespace are mapped to the
+// metadata of the objects and directories by the vfs.
+
+// getxattr gets the extended attribute req.Name of node
+func getxattr(node vfs.Node, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) (err error) {
+	defer log.Trace(node, "name=%q", req.Name)("value=%q, err=%v", &resp.Xattr, &err)
+	resp.Xattr, err = vfs.Getxattr(node, req.Name)
+	return translateError(err)
+}
+
+// listxattr lists the extended attributes of node
+func listxattr(node vfs.Node, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) (err error) {
+	defer log.Trace(node, "")("err=%v", &err)
+	names, err := vfs.Listxattr(node)
+	if err != nil {
+		return translateError(err)
+	}
+	resp.Append(names...)
+	return nil
+}
+
+// setxattr sets the extended attribute req.Name of node
+func setxattr(node vfs.Node, req *fuse.SetxattrRequest) (err error) {
+	defer log.Trace(node, "name=%q, flags=0x%X", req.Name, req.Flags)("err=%v", &err)
+	create := req.Flags&unix.XATTR_CREATE != 0
+	replace := req.Flags&unix.XATTR_REPLACE != 0
+	return translateError(vfs.Setxattr(node, req.Name, req.Xattr, create, replace))
+}
+
+// removexattr removes the extended attribute req.Name of node
+func removexattr(node vfs.Node, req *fuse.RemovexattrRequest) (err error) {
+	defer log.Trace(node, "name=%q", req.Name)("err=%v", &err)
+	return translateError(vfs.Removexattr(node, req.Name))
+}
+
+// Getxattr gets an extended attribute by the given name from the
+// node.
+//
+// If there is no xattr by that name, returns fuse.ErrNoXattr.
+func (f *File) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) error {
+	return getxattr(f.File, req, resp)
+}
+
+// Listxattr lists the extended attributes recorded for the node.
+func (f *File) Listxattr(ctx context.Context, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) error {
+	return listxattr(f.File, req, resp)
+}
+
+// Setxattr sets an extended attribute with the given name and
+// value for the node.
+func (f *File) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
+	return setxattr(f.File, req)
+}
+
+// Removexattr removes an extended attribute for the name.
+//
+// If there is no xattr by that name, returns fuse.ErrNoXattr.
+func (f *File) Removexattr(ctx context.Context, req *fuse.RemovexattrRequest) error {
+	return removexattr(f.File, req)
+}
+
+// Getxattr gets an extended attribute by the given name from the
+// node.
+//
+// If there is no xattr by that name, returns fuse.ErrNoXattr.
+func (d *Dir) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) error {
+	return getxattr(d.Dir, req, resp)
+}
+
+// Listxattr lists the extended attributes recorded for the node.
+func (d *Dir) Listxattr(ctx context.Context, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) error {
+	return listxattr(d.Dir, req, resp)
+}
+
+// Setxattr sets an extended attribute with the given name and
+// value for the node.
+func (d *Dir) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
+	return setxattr(d.Dir, req)
+}
+
+// Removexattr removes an extended attribute for the name.
+//
+// If there is no xattr by that name, returns fuse.ErrNoXattr.
+func (d *Dir) Removexattr(ctx context.Context, req *fuse.RemovexattrRequest) error {
+	return removexattr(d.Dir, req)
+}
+
+// Check interfaces satisfied
+var (
+	_ fusefs.NodeGetxattrer    = (*File)(nil)
+	_ fusefs.NodeListxattrer   = (*File)(nil)
+	_ fusefs.NodeSetxattrer    = (*File)(nil)
+	_ fusefs.NodeRemovexattrer = (*File)(nil)
+	_ fusefs.NodeGetxattrer    = (*Dir)(nil)
+	_ fusefs.NodeListxattrer   = (*Dir)(nil)
+	_ fusefs.NodeSetxattrer    = (*Dir)(nil)
+	_ fusefs.NodeRemovexattrer = (*Dir)(nil)
+)
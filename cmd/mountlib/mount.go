@@ -416,12 +416,7 @@ func (m *MountPoint) Wait() error {
 			waiting = false
 		// user sent SIGHUP to clear the cache
 		case <-sigHup:
-			root, err := m.VFS.Root()
-			if err != nil {
-				fs.Errorf(m.VFS.Fs(), "Error reading root: %v", err)
-			} else {
-				root.ForgetAll()
-			}
+			m.VFS.FlushDirCache()
 		}
 	}
 
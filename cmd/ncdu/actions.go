@@ -0,0 +1,221 @@
+//go:build !plan9 && !js
+
+package ncdu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/sync"
+)
+
+// Options for the actions
+var (
+	trashDir        string
+	moveTo          string
+	exportFilesFrom string
+	exportJSON      string
+)
+
+// actionPositions returns the positions in u.entries of the selected
+// entries or the entry under the cursor if none are selected
+func (u *UI) actionPositions() (positions []int) {
+	if len(u.selectedEntries) == 0 {
+		cursorPos := u.dirPosMap[u.path]
+		return []int{u.sortPerm[cursorPos.entry]}
+	}
+	for _, cursorPos := range u.selectedEntries {
+		positions = append(positions, u.sortPerm[cursorPos.entry])
+	}
+	sort.Ints(positions)
+	return positions
+}
+
+// removePositions removes the entries at positions from the current
+// directory after they have been deleted or moved
+func (u *UI) removePositions(positions []int) {
+	// remove from the end so the positions stay valid
+	positions = append([]int(nil), positions...)
+	sort.Sort(sort.Reverse(sort.IntSlice(positions)))
+	for _, pos := range positions {
+		u.removeEntry(pos)
+	}
+	// move cursor back onto a valid entry if needed
+	cursorPos := u.dirPosMap[u.path]
+	if cursorPos.entry >= len(u.entries) {
+		u.move(-1)
+	}
+}
+
+// moveEntry moves entry, which is relative to the root of f, to the
+// same relative path under dst
+func moveEntry(ctx context.Context, f fs.Fs, dst string, entry fs.DirEntry) error {
+	if obj, isFile := entry.(fs.Object); isFile {
+		fdst, err := cache.Get(ctx, dst)
+		if err != nil {
+			return err
+		}
+		_, err = operations.Move(ctx, fdst, nil, obj.Remote(), obj)
+		return err
+	}
+	fsrc, err := cache.Get(ctx, fspath.JoinRootPath(fs.ConfigString(f), entry.Remote()))
+	if err != nil {
+		return err
+	}
+	fdst, err := cache.Get(ctx, fspath.JoinRootPath(dst, entry.Remote()))
+	if err != nil {
+		return err
+	}
+	return sync.MoveDir(ctx, fdst, fsrc, true, true)
+}
+
+// deleteEntry deletes entry or moves it to the trash if --trash is
+// set
+func deleteEntry(ctx context.Context, f fs.Fs, entry fs.DirEntry) error {
+	if trashDir != "" {
+		return moveEntry(ctx, f, trashDir, entry)
+	}
+	if obj, isFile := entry.(fs.Object); isFile {
+		return operations.DeleteFile(ctx, obj)
+	}
+	return operations.Purge(ctx, f, entry.String())
+}
+
+// moveSelected moves the selected entries, or the entry under the
+// cursor, to --move-to
+func (u *UI) moveSelected() {
+	if u.d == nil || len(u.entries) == 0 {
+		return
+	}
+	if moveTo == "" {
+		u.popupBox([]string{"error:", "use --move-to to set where to move to"})
+		return
+	}
+	ctx := context.Background()
+	positions := u.actionPositions()
+	u.boxMenu = []string{"cancel", "confirm"}
+	u.boxMenuHandler = func(f fs.Fs, p string, o int) (string, error) {
+		if o != 1 {
+			return "Aborted!", nil
+		}
+		var moved []int
+		defer func() {
+			u.removePositions(moved)
+		}()
+		for _, pos := range positions {
+			err := moveEntry(ctx, f, moveTo, u.entries[pos])
+			if err != nil {
+				return "", err
+			}
+			moved = append(moved, pos)
+		}
+		return fmt.Sprintf("Successfully moved %d items!", len(moved)), nil
+	}
+	what := fspath.JoinRootPath(u.fsName, u.entries[positions[0]].String())
+	if len(positions) > 1 {
+		what = fmt.Sprintf("%d selected items", len(positions))
+	}
+	u.popupBox([]string{
+		"Move to " + moveTo + "?",
+		what})
+}
+
+// writeFile writes a file with fn, reporting what was written
+func writeFile(name string, fn func(out *os.File) (string, error)) (msg string, err error) {
+	out, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	msg, err = fn(out)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return msg, err
+}
+
+// exportSelected writes the paths of the files in the selected entries,
+// or the entry under the cursor, to --export-files-from
+func (u *UI) exportSelected() {
+	if u.d == nil || len(u.entries) == 0 {
+		return
+	}
+	if exportFilesFrom == "" {
+		u.popupBox([]string{"error:", "use --export-files-from to set the file to export to"})
+		return
+	}
+	msg, err := writeFile(exportFilesFrom, func(out *os.File) (string, error) {
+		n := 0
+		for _, pos := range u.actionPositions() {
+			for _, file := range u.d.FilesI(pos) {
+				if strings.ContainsAny(file, "\r\n") {
+					fs.Errorf(file, "Can't export file name with a newline in to --files-from")
+					continue
+				}
+				if _, err := fmt.Fprintln(out, file); err != nil {
+					return "", err
+				}
+				n++
+			}
+		}
+		return fmt.Sprintf("Exported %d file names to %s", n, exportFilesFrom), nil
+	})
+	u.showResult(msg, err)
+}
+
+// exportTree writes the tree scanned so far as JSON to --export-json
+func (u *UI) exportTree() {
+	if u.root == nil {
+		return
+	}
+	if exportJSON == "" {
+		u.popupBox([]string{"error:", "use --export-json to set the file to export to"})
+		return
+	}
+	msg, err := writeFile(exportJSON, func(out *os.File) (string, error) {
+		err := u.root.WriteJSON(out)
+		if err != nil {
+			return "", err
+		}
+		msg := "Exported tree to " + exportJSON
+		if u.listing {
+			msg += " (listing in progress so it is incomplete)"
+		}
+		return msg, nil
+	})
+	u.showResult(msg, err)
+}
+
+// showResult shows the result of an action in a box
+func (u *UI) showResult(msg string, err error) {
+	if err != nil {
+		u.popupBox([]string{"error:", err.Error()})
+		return
+	}
+	u.popupBox([]string{"Finished:", msg})
+}
+
+// checkActionFlags checks the destinations of the actions are
+// directories which can be used
+func checkActionFlags(ctx context.Context) error {
+	for _, dst := range []string{trashDir, moveTo} {
+		if dst == "" {
+			continue
+		}
+		_, err := cache.Get(ctx, dst)
+		if errors.Is(err, fs.ErrorIsFile) {
+			return fmt.Errorf("%q must be a directory", dst)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/cmd/ncdu/scan"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
 	"github.com/rclone/rclone/fs/fspath"
 	"github.com/rclone/rclone/fs/log"
 	"github.com/rclone/rclone/fs/operations"
@@ -26,12 +27,17 @@ import (
 
 func init() {
 	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.StringVarP(cmdFlags, &trashDir, "trash", "", "", "Move deleted files and directories to this remote:path instead of deleting them", "")
+	flags.StringVarP(cmdFlags, &moveTo, "move-to", "", "", "Move files and directories to this remote:path with the x key", "")
+	flags.StringVarP(cmdFlags, &exportFilesFrom, "export-files-from", "", "", "Write the paths of files to this file with the e key for use with --files-from", "")
+	flags.StringVarP(cmdFlags, &exportJSON, "export-json", "", "", "Write the scanned tree to this file as JSON with the J key", "")
 }
 
 var commandDefinition = &cobra.Command{
 	Use:   "ncdu remote:path",
 	Short: `Explore a remote with a text based user interface.`,
-	Long: `This displays a text based user interface allowing the navigation of a
+	Long: strings.ReplaceAll(`This displays a text based user interface allowing the navigation of a
 remote. It is most useful for answering the question - "What is using
 all my disk space?".
 
@@ -45,7 +51,7 @@ structure as it goes along.
 You can interact with the user interface using key presses,
 press '?' to toggle the help on and off. The supported keys are:
 
-    ` + strings.Join(helpText()[1:], "\n    ") + `
+    `+strings.Join(helpText()[1:], "\n    ")+`
 
 Listed files/directories may be prefixed by a one-character flag,
 some of them combined with a description in brackets at end of line.
@@ -62,6 +68,22 @@ These flags have the following meaning:
       size inaccurate)
     ! means an error occurred while reading this directory
 
+Files and directories can be deleted with the d and D keys. If
+|--trash remote:path| is set then they are moved there instead,
+keeping their paths relative to the remote being explored, so they
+can be recovered later.
+
+The x key moves the selected files and directories, or the one under
+the cursor, to the remote:path set with |--move-to|, again keeping
+their relative paths.
+
+The e key writes the paths of the selected files, or all the files in
+the selected directories, to the file set with |--export-files-from|.
+This can be used with |--files-from| in other rclone commands, for
+example to copy or archive just those files. The J key writes the
+whole tree scanned so far with sizes and counts as JSON to the file
+set with |--export-json|.
+
 This an homage to the [ncdu tool](https://dev.yorhel.nl/ncdu) but for
 rclone remotes.  It is missing lots of features at the moment
 but is useful as it stands. Unlike ncdu it does not show excluded files.
@@ -72,7 +94,7 @@ UI won't respond in the meantime since the deletion is done synchronously.
 For a non-interactive listing of the remote, see the
 [tree](/commands/rclone_tree/) command. To just get the total size of
 the remote you can also use the [size](/commands/rclone_size/) command.
-`,
+`, "|", "`"),
 	Annotations: map[string]string{
 		"versionIntroduced": "v1.37",
 		"groups":            "Filter,Listing",
@@ -81,6 +103,10 @@ the remote you can also use the [size](/commands/rclone_size/) command.
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc := cmd.NewFsSrc(args)
 		cmd.Run(false, false, command, func() error {
+			err := checkActionFlags(context.Background())
+			if err != nil {
+				return err
+			}
 			return NewUI(fsrc).Run()
 		})
 	},
@@ -103,6 +129,9 @@ func helpText() (tr []string) {
 		" v select file/directory",
 		" V enter visual select mode",
 		" D delete selected files/directories",
+		" x move selected or current file/directory to --move-to",
+		" e export selected or current files to --export-files-from",
+		" J export scanned tree as JSON to --export-json",
 	}
 	if !clipboard.Unsupported {
 		tr = append(tr, " y copy current path to clipboard")
@@ -573,7 +602,7 @@ func (u *UI) deleteSingle() {
 			if o != 1 {
 				return "Aborted!", nil
 			}
-			err := operations.DeleteFile(ctx, obj)
+			err := deleteEntry(ctx, f, obj)
 			if err != nil {
 				return "", err
 			}
@@ -581,8 +610,17 @@ func (u *UI) deleteSingle() {
 			if cursorPos.entry >= len(u.entries) {
 				u.move(-1) // move back onto a valid entry
 			}
+			if trashDir != "" {
+				return "Successfully moved file to the trash!", nil
+			}
 			return "Successfully deleted file!", nil
 		}
+		if trashDir != "" {
+			u.popupBox([]string{
+				"Move this file to the trash?",
+				fspath.JoinRootPath(u.fsName, dirEntry.String())})
+			return
+		}
 		u.popupBox([]string{
 			"Delete this file?",
 			fspath.JoinRootPath(u.fsName, dirEntry.String())})
@@ -591,7 +629,7 @@ func (u *UI) deleteSingle() {
 			if o != 1 {
 				return "Aborted!", nil
 			}
-			err := operations.Purge(ctx, f, dirEntry.String())
+			err := deleteEntry(ctx, f, dirEntry)
 			if err != nil {
 				return "", err
 			}
@@ -599,8 +637,17 @@ func (u *UI) deleteSingle() {
 			if cursorPos.entry >= len(u.entries) {
 				u.move(-1) // move back onto a valid entry
 			}
+			if trashDir != "" {
+				return "Successfully moved folder to the trash!", nil
+			}
 			return "Successfully purged folder!", nil
 		}
+		if trashDir != "" {
+			u.popupBox([]string{
+				"Move this directory to the trash?",
+				fspath.JoinRootPath(u.fsName, dirEntry.String())})
+			return
+		}
 		u.popupBox([]string{
 			"Purge this directory?",
 			"ALL files in it will be deleted",
@@ -625,14 +672,8 @@ func (u *UI) deleteSelected() {
 
 			dirPos := u.sortPerm[cursorPos.entry]
 			dirEntry := u.entries[dirPos]
-			var err error
-
-			if obj, isFile := dirEntry.(fs.Object); isFile {
-				err = operations.DeleteFile(ctx, obj)
-			} else {
-				err = operations.Purge(ctx, f, dirEntry.String())
-			}
 
+			err := deleteEntry(ctx, f, dirEntry)
 			if err != nil {
 				return "", err
 			}
@@ -657,8 +698,17 @@ func (u *UI) deleteSelected() {
 			u.move(-1)
 		}
 
+		if trashDir != "" {
+			return "Successfully moved all items to the trash!", nil
+		}
 		return "Successfully deleted all items!", nil
 	}
+	if trashDir != "" {
+		u.popupBox([]string{
+			"Move selected items to the trash?",
+			fmt.Sprintf("ALL %d items will be moved to %s", len(u.selectedEntries), trashDir)})
+		return
+	}
 	u.popupBox([]string{
 		"Delete selected items?",
 		fmt.Sprintf("ALL %d items will be deleted", len(u.selectedEntries))})
@@ -1048,6 +1098,12 @@ outer:
 					u.humanReadable = !u.humanReadable
 				case 'D':
 					u.deleteSelected()
+				case 'x':
+					u.moveSelected()
+				case 'e':
+					u.exportSelected()
+				case 'J':
+					u.exportTree()
 				case '?':
 					u.togglePopupBox(helpText())
 				case 'r':
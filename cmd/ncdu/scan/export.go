@@ -0,0 +1,114 @@
+package scan
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+)
+
+// FilesI returns the paths of all the files in the i-th entry, which
+// is the path of the entry itself if it is a file, or all the files
+// scanned so far below it if it is a directory.
+func (d *Dir) FilesI(i int) (files []string) {
+	d.mu.Lock()
+	subDir, isDir := d.getDir(i)
+	entry := d.entries[i]
+	d.mu.Unlock()
+	if !isDir {
+		return []string{entry.Remote()}
+	}
+	if subDir != nil {
+		files = subDir.appendFiles(files)
+	}
+	return files
+}
+
+// appendFiles appends the paths of all the files in d and its
+// subdirectories to files
+func (d *Dir) appendFiles(files []string) []string {
+	d.mu.Lock()
+	entries := d.entries
+	var dirs []*Dir
+	for i := range entries {
+		subDir, isDir := d.getDir(i)
+		if !isDir {
+			files = append(files, entries[i].Remote())
+		} else if subDir != nil {
+			dirs = append(dirs, subDir)
+		}
+	}
+	d.mu.Unlock()
+	for _, subDir := range dirs {
+		files = subDir.appendFiles(files)
+	}
+	return files
+}
+
+// JSONEntry is a directory entry as written by WriteJSON
+type JSONEntry struct {
+	Path             string
+	Name             string
+	Size             int64
+	IsDir            bool
+	Count            int64       `json:",omitempty"`
+	CountUnknownSize int64       `json:",omitempty"`
+	Error            string      `json:",omitempty"`
+	Unread           bool        `json:",omitempty"`
+	Entries          []JSONEntry `json:",omitempty"`
+}
+
+// jsonEntry returns d and the entries below it as a JSONEntry
+func (d *Dir) jsonEntry() JSONEntry {
+	d.mu.Lock()
+	je := JSONEntry{
+		Path:             d.path,
+		Name:             path.Base(d.path),
+		Size:             d.size,
+		IsDir:            true,
+		Count:            d.count,
+		CountUnknownSize: d.countUnknownSize,
+		Entries:          make([]JSONEntry, 0, len(d.entries)),
+	}
+	if d.path == "" {
+		je.Name = ""
+	}
+	if d.readError != nil {
+		je.Error = d.readError.Error()
+	}
+	dirs := map[int]*Dir{}
+	for i, entry := range d.entries {
+		subDir, isDir := d.getDir(i)
+		switch {
+		case !isDir:
+			je.Entries = append(je.Entries, JSONEntry{
+				Path: entry.Remote(),
+				Name: path.Base(entry.Remote()),
+				Size: entry.Size(),
+			})
+		case subDir == nil:
+			je.Entries = append(je.Entries, JSONEntry{
+				Path:   entry.Remote(),
+				Name:   path.Base(entry.Remote()),
+				IsDir:  true,
+				Unread: true,
+			})
+		default:
+			// Filled in below without holding the lock
+			dirs[len(je.Entries)] = subDir
+			je.Entries = append(je.Entries, JSONEntry{})
+		}
+	}
+	d.mu.Unlock()
+	for i, subDir := range dirs {
+		je.Entries[i] = subDir.jsonEntry()
+	}
+	return je
+}
+
+// WriteJSON writes the tree of directories scanned so far starting
+// at d to out as JSON.
+func (d *Dir) WriteJSON(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "\t")
+	return enc.Encode(d.jsonEntry())
+}
@@ -0,0 +1,66 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/memory"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scanTree makes a tree on a memory remote and scans it
+func scanTree(t *testing.T) *Dir {
+	ctx := context.Background()
+	f, err := fs.NewFs(ctx, ":memory:scan-test")
+	require.NoError(t, err)
+	for _, remote := range []string{"a.txt", "dir/b.txt", "dir/sub/c.txt"} {
+		data := []byte(remote)
+		src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(data)), true, nil, f)
+		_, err := f.Put(ctx, bytes.NewReader(data), src)
+		require.NoError(t, err)
+	}
+	rootChan, errChan, _ := Scan(ctx, f)
+	root := <-rootChan
+	require.NoError(t, <-errChan)
+	return root
+}
+
+func TestFilesI(t *testing.T) {
+	root := scanTree(t)
+	var files []string
+	for i := range root.Entries() {
+		files = append(files, root.FilesI(i)...)
+	}
+	assert.ElementsMatch(t, []string{"a.txt", "dir/b.txt", "dir/sub/c.txt"}, files)
+}
+
+func TestWriteJSON(t *testing.T) {
+	root := scanTree(t)
+	var buf bytes.Buffer
+	require.NoError(t, root.WriteJSON(&buf))
+
+	var got JSONEntry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.True(t, got.IsDir)
+	assert.Equal(t, "", got.Path)
+	assert.Equal(t, int64(3), got.Count)
+	assert.Equal(t, int64(len("a.txt")+len("dir/b.txt")+len("dir/sub/c.txt")), got.Size)
+	require.Len(t, got.Entries, 2)
+
+	byName := map[string]JSONEntry{}
+	for _, entry := range got.Entries {
+		byName[entry.Name] = entry
+	}
+	assert.Equal(t, JSONEntry{Path: "a.txt", Name: "a.txt", Size: 5}, byName["a.txt"])
+	dir := byName["dir"]
+	assert.True(t, dir.IsDir)
+	assert.Equal(t, "dir", dir.Path)
+	assert.Equal(t, int64(2), dir.Count)
+	require.Len(t, dir.Entries, 2)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fs/rc/rcflags"
 	"github.com/rclone/rclone/fs/rc/rcserver"
+	"github.com/rclone/rclone/fs/rc/schedule"
 	libhttp "github.com/rclone/rclone/lib/http"
 	"github.com/rclone/rclone/lib/systemd"
 	"github.com/spf13/cobra"
@@ -29,6 +30,11 @@ If you pass in a path to a directory, rclone will serve that directory
 for GET requests on the URL passed in.  It will also open the URL in
 the browser when rclone is run.
 
+rc commands can be run on a cron schedule with the schedule/create rc
+command. The schedules are saved in the file set with
+--rc-schedule-file (default schedules.json next to the config file)
+and are run again when rcd restarts.
+
 See the [rc documentation](/rc/) for more info on the rc flags.
 
 ` + libhttp.Help(rcflags.FlagPrefix) + libhttp.TemplateHelp(rcflags.FlagPrefix) + libhttp.AuthHelp(rcflags.FlagPrefix),
@@ -56,6 +62,15 @@ See the [rc documentation](/rc/) for more info on the rc flags.
 			fs.Fatal(nil, "rc server not configured")
 		}
 
+		// Start running the scheduled jobs
+		schedulePath := rc.Opt.ScheduleFile
+		if schedulePath == "" {
+			schedulePath = schedule.DefaultPath()
+		}
+		if err := schedule.Start(context.Background(), schedulePath); err != nil {
+			fs.Fatalf(nil, "Failed to start scheduler: %v", err)
+		}
+
 		// Notify stopping on exit
 		defer systemd.Notify()()
 
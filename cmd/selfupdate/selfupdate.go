@@ -43,8 +43,10 @@ type Options struct {
 	Output  string // output path
 	Beta    bool   // mutually exclusive with Stable (false means "stable")
 	Stable  bool   // mutually exclusive with Beta
-	Version string
+	Version string // version or range of versions
 	Package string // package format: zip, deb, rpm (empty string means "zip")
+
+	AllowUnsigned bool // install releases which can't be verified
 }
 
 // Opt is options set via command line
@@ -56,9 +58,10 @@ func init() {
 	flags.BoolVarP(cmdFlags, &Opt.Check, "check", "", Opt.Check, "Check for latest release, do not download", "")
 	flags.StringVarP(cmdFlags, &Opt.Output, "output", "", Opt.Output, "Save the downloaded binary at a given path (default: replace running binary)", "")
 	flags.BoolVarP(cmdFlags, &Opt.Stable, "stable", "", Opt.Stable, "Install stable release (this is the default)", "")
-	flags.BoolVarP(cmdFlags, &Opt.Beta, "beta", "", Opt.Beta, "Install beta release", "")
-	flags.StringVarP(cmdFlags, &Opt.Version, "version", "", Opt.Version, "Install the given rclone version (default: latest)", "")
+	flags.BoolVarP(cmdFlags, &Opt.Beta, "beta", "", Opt.Beta, "Install beta release (not verified as betas aren't signed)", "")
+	flags.StringVarP(cmdFlags, &Opt.Version, "version", "", Opt.Version, "Install the given rclone version or the latest in a range like \">=1.66 <1.69\" (default: latest)", "")
 	flags.StringVarP(cmdFlags, &Opt.Package, "package", "", Opt.Package, "Package format: zip|deb|rpm (default: zip)", "")
+	flags.BoolVarP(cmdFlags, &Opt.AllowUnsigned, "allow-unsigned", "", Opt.AllowUnsigned, "Install releases whose signature can't be verified (insecure)", "")
 }
 
 var cmdSelfUpdate = &cobra.Command{
@@ -101,7 +104,8 @@ var cmdSelfUpdate = &cobra.Command{
 
 // GetVersion can get the latest release number from the download site
 // or massage a stable release number - prepend semantic "v" prefix
-// or find the latest micro release for a given major.minor release.
+// or find the latest micro release for a given major.minor release
+// or the latest release in a range of versions like ">=1.66 <1.69".
 // Note: this will not be applied to beta releases.
 func GetVersion(ctx context.Context, beta bool, version string) (newVersion, siteURL string, err error) {
 	siteURL = "https://downloads.rclone.org"
@@ -115,6 +119,14 @@ func GetVersion(ctx context.Context, beta bool, version string) (newVersion, sit
 		return
 	}
 
+	if isVersionRange(version) {
+		if beta {
+			return "", siteURL, errors.New("version ranges can only be used with stable releases")
+		}
+		newVersion, err = findVersionInRange(ctx, siteURL, version)
+		return newVersion, siteURL, err
+	}
+
 	newVersion = version
 	if version[0] != 'v' {
 		newVersion = "v" + version
@@ -174,7 +186,7 @@ func InstallUpdate(ctx context.Context, opt *Options) error {
 		if opt.Check {
 			fmt.Println("Warning: --package flag is ignored in --check mode")
 		} else {
-			err := installPackage(ctx, opt.Beta, newVersion, siteURL, opt.Package)
+			err := installPackage(ctx, opt, newVersion, siteURL, opt.Package)
 			if err == nil {
 				fs.Logf(nil, "Successfully updated rclone package from version %s to version %s", oldVersion, newVersion)
 			}
@@ -219,7 +231,7 @@ func InstallUpdate(ctx context.Context, opt *Options) error {
 	}
 
 	// Download the update as a temporary file
-	err = downloadUpdate(ctx, opt.Beta, newVersion, siteURL, newFile, "zip")
+	err = downloadUpdate(ctx, opt, newVersion, siteURL, newFile, "zip")
 	if err != nil {
 		return fmt.Errorf("failed to update rclone: %w", err)
 	}
@@ -231,7 +243,7 @@ func InstallUpdate(ctx context.Context, opt *Options) error {
 	return err
 }
 
-func installPackage(ctx context.Context, beta bool, version, siteURL, packageFormat string) error {
+func installPackage(ctx context.Context, opt *Options, version, siteURL, packageFormat string) error {
 	tempFile, err := os.CreateTemp("", "rclone.*."+packageFormat)
 	if err != nil {
 		return fmt.Errorf("unable to write temporary package: %w", err)
@@ -243,7 +255,7 @@ func installPackage(ctx context.Context, beta bool, version, siteURL, packageFor
 			fs.Errorf(nil, "%s: could not remove temporary package: %v", packageFile, rmErr)
 		}
 	}()
-	if err := downloadUpdate(ctx, beta, version, siteURL, packageFile, packageFormat); err != nil {
+	if err := downloadUpdate(ctx, opt, version, siteURL, packageFile, packageFormat); err != nil {
 		return err
 	}
 
@@ -337,12 +349,14 @@ func makeRandomExeName(baseName, extension string) (string, error) {
 	return "", fmt.Errorf("cannot find a file name like %s.xxxx.%s", baseName, extension)
 }
 
-func downloadUpdate(ctx context.Context, beta bool, version, siteURL, newFile, packageFormat string) error {
-	osName := runtime.GOOS
+// releasePlatform returns the OS and architecture names used in the
+// names of the release archives for this system
+func releasePlatform() (osName, arch string) {
+	osName = runtime.GOOS
 	if osName == "darwin" {
 		osName = "osx"
 	}
-	arch := runtime.GOARCH
+	arch = runtime.GOARCH
 	if arch == "arm" {
 		// Check the ARM compatibility level of the current CPU.
 		// We don't know if this matches the rclone binary currently running, it
@@ -364,7 +378,36 @@ func downloadUpdate(ctx context.Context, beta bool, version, siteURL, newFile, p
 			arch = "arm"
 		}
 	}
-	archiveFilename := fmt.Sprintf("rclone-%s-%s-%s.%s", version, osName, arch, packageFormat)
+	return osName, arch
+}
+
+func downloadUpdate(ctx context.Context, opt *Options, version, siteURL, newFile, packageFormat string) error {
+	osName, arch := releasePlatform()
+	platform := osName + "-" + arch
+	archiveFilename := fmt.Sprintf("rclone-%s-%s.%s", version, platform, packageFormat)
+	entryName := fmt.Sprintf("rclone-%s-%s/rclone", version, platform)
+	if runtime.GOOS == "windows" {
+		entryName += ".exe"
+	}
+
+	// Everything downloaded is checked against the signed hashsums
+	// of the release unless the user insists. CI/CD does not
+	// provide hashsums for beta releases so these can't be.
+	var sums []byte
+	if opt.Beta {
+		fs.Logf(nil, "Installing beta release %s without verifying it as beta releases aren't signed", version)
+	} else {
+		var err error
+		sums, err = getHashsums(ctx, siteURL, version)
+		if err != nil {
+			if !opt.AllowUnsigned {
+				return fmt.Errorf("unable to verify release %s, use --allow-unsigned to install it anyway: %w", version, err)
+			}
+			fs.Logf(nil, "Installing release %s without verifying it: %v", version, err)
+			sums = nil
+		}
+	}
+
 	archiveURL := fmt.Sprintf("%s/%s/%s", siteURL, version, archiveFilename)
 	archiveBuf, err := downloadFile(ctx, archiveURL)
 	if err != nil {
@@ -374,9 +417,8 @@ func downloadUpdate(ctx context.Context, beta bool, version, siteURL, newFile, p
 	strHash := hex.EncodeToString(gotHash[:])
 	fs.Debugf(nil, "downloaded release archive with hashsum %s from %s", strHash, archiveURL)
 
-	// CI/CD does not provide hashsums for beta releases
-	if !beta {
-		if err := verifyHashsum(ctx, siteURL, version, archiveFilename, gotHash[:]); err != nil {
+	if sums != nil {
+		if err := checkHash(sums, archiveFilename, "archive", gotHash[:]); err != nil {
 			return err
 		}
 	}
@@ -388,17 +430,25 @@ func downloadUpdate(ctx context.Context, beta bool, version, siteURL, newFile, p
 		return nil
 	}
 
-	entryName := fmt.Sprintf("rclone-%s-%s-%s/rclone", version, osName, arch)
-	if runtime.GOOS == "windows" {
-		entryName += ".exe"
-	}
-
 	// Extract executable to a temporary file, then replace it by an instant rename
 	err = extractZipToFile(archiveBuf, entryName, newFile)
 	if err != nil {
 		return err
 	}
 	fs.Debugf(nil, "extracted %s to %s", entryName, newFile)
+
+	// Check the executable itself too if its hash is published
+	if sums != nil {
+		if _, err := findFileHash(sums, entryName); err == nil {
+			err = checkFileHash(sums, entryName, "executable", newFile)
+			if err != nil {
+				if rmErr := os.Remove(newFile); rmErr != nil {
+					fs.Errorf(nil, "%s: could not remove temporary file: %v", newFile, rmErr)
+				}
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -5,8 +5,11 @@ package selfupdate
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
@@ -57,41 +60,73 @@ EtC4nj5EImssVk/xmU3axw==
 -----END PGP PUBLIC KEY BLOCK-----
 `
 
-func verifyHashsum(ctx context.Context, siteURL, version, archive string, hash []byte) error {
+// getHashsums downloads the SHA256SUMS of a release and checks its
+// signature, returning the signed list of hashes.
+func getHashsums(ctx context.Context, siteURL, version string) ([]byte, error) {
 	sumsURL := fmt.Sprintf("%s/%s/SHA256SUMS", siteURL, version)
 	sumsBuf, err := downloadFile(ctx, sumsURL)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	fs.Debugf(nil, "downloaded hashsum list: %s", sumsURL)
-	return verifyHashsumDownloaded(ctx, sumsBuf, archive, hash)
+	return verifySignature(sumsBuf)
 }
 
-func verifyHashsumDownloaded(ctx context.Context, sumsBuf []byte, archive string, hash []byte) error {
+// verifySignature checks the signature of a signed hashsum list and
+// returns the part which is signed.
+func verifySignature(sumsBuf []byte) ([]byte, error) {
 	keyRing, err := openpgp.ReadArmoredKeyRing(strings.NewReader(ncwPublicKeyPGP))
 	if err != nil {
-		return fmt.Errorf("unsupported signing key: %w", err)
+		return nil, fmt.Errorf("unsupported signing key: %w", err)
 	}
 
 	block, rest := clearsign.Decode(sumsBuf)
 	if block == nil {
-		return errors.New("invalid hashsum signature: couldn't find detached signature")
+		return nil, errors.New("invalid hashsum signature: couldn't find detached signature")
 	}
 	if len(rest) > 0 {
-		return fmt.Errorf("invalid hashsum signature: %d bytes of unsigned data", len(rest))
+		return nil, fmt.Errorf("invalid hashsum signature: %d bytes of unsigned data", len(rest))
 	}
 
 	_, err = openpgp.CheckDetachedSignature(keyRing, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body, nil)
 	if err != nil {
-		return fmt.Errorf("invalid hashsum signature: %w", err)
+		return nil, fmt.Errorf("invalid hashsum signature: %w", err)
+	}
+	return block.Plaintext, nil
+}
+
+func verifyHashsumDownloaded(ctx context.Context, sumsBuf []byte, archive string, hash []byte) error {
+	sums, err := verifySignature(sumsBuf)
+	if err != nil {
+		return err
 	}
+	return checkHash(sums, archive, "archive", hash)
+}
 
-	wantHash, err := findFileHash(sumsBuf, archive)
+// checkHash checks that hash is the hash of the file name in the
+// list of hashes. what describes the file for errors.
+func checkHash(sums []byte, name, what string, hash []byte) error {
+	wantHash, err := findFileHash(sums, name)
 	if err != nil {
 		return err
 	}
 	if !bytes.Equal(hash, wantHash) {
-		return fmt.Errorf("archive hash mismatch: want %02x vs got %02x", wantHash, hash)
+		return fmt.Errorf("%s hash mismatch: want %02x vs got %02x", what, wantHash, hash)
 	}
 	return nil
 }
+
+// checkFileHash checks that the file at path has the hash of the file
+// name in the list of hashes.
+func checkFileHash(sums []byte, name, what, path string) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(f, &err)
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return err
+	}
+	return checkHash(sums, name, what, h.Sum(nil))
+}
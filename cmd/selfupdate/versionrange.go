@@ -0,0 +1,160 @@
+//go:build !noselfupdate
+
+package selfupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+)
+
+// versionConstraint is one comparison in a version range, e.g. >=1.66.0
+type versionConstraint struct {
+	op      string
+	version semver.Version
+}
+
+// match returns true if v satisfies the constraint
+func (c versionConstraint) match(v *semver.Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	case "<":
+		return cmp < 0
+	default:
+		return cmp == 0
+	}
+}
+
+// versionRange is a list of constraints which must all be satisfied
+type versionRange []versionConstraint
+
+// match returns true if v is in the range
+func (r versionRange) match(v *semver.Version) bool {
+	for _, c := range r {
+		if !c.match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+var versionConstraintRe = regexp.MustCompile(`^(>=|<=|>|<|=)?v?(\d+)(?:\.(\d+))?(?:\.(\d+))?$`)
+
+// isVersionRange returns true if version is a range rather than a
+// single version
+func isVersionRange(version string) bool {
+	return strings.ContainsAny(version, "<>=, ")
+}
+
+// parseVersionRange parses a range of versions like ">=1.66 <1.69".
+//
+// The constraints are separated by spaces or commas and all must be
+// met. Versions may leave out the micro or minor version in which case
+// they stand for all the releases they are a prefix of, so "<=1.68"
+// includes v1.68.2 and "=1.68" is the same as ">=1.68 <1.69".
+func parseVersionRange(s string) (versionRange, error) {
+	var r versionRange
+	for _, term := range strings.FieldsFunc(s, func(c rune) bool { return c == ' ' || c == ',' }) {
+		match := versionConstraintRe.FindStringSubmatch(term)
+		if match == nil {
+			return nil, fmt.Errorf("invalid version constraint %q", term)
+		}
+		op := match[1]
+		var parts []int64
+		for _, part := range match[2:] {
+			if part == "" {
+				break
+			}
+			n, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint %q: %w", term, err)
+			}
+			parts = append(parts, n)
+		}
+		lower := semver.Version{Major: parts[0]}
+		if len(parts) > 1 {
+			lower.Minor = parts[1]
+		}
+		if len(parts) > 2 {
+			lower.Patch = parts[2]
+		}
+		if len(parts) == 3 {
+			if op == "" {
+				op = "="
+			}
+			r = append(r, versionConstraint{op: op, version: lower})
+			continue
+		}
+		// The first version after the ones lower is a prefix of
+		upper := lower
+		if len(parts) == 1 {
+			upper.Major++
+		} else {
+			upper.Minor++
+		}
+		switch op {
+		case "", "=":
+			r = append(r, versionConstraint{op: ">=", version: lower}, versionConstraint{op: "<", version: upper})
+		case "<=":
+			r = append(r, versionConstraint{op: "<", version: upper})
+		case ">":
+			r = append(r, versionConstraint{op: ">=", version: upper})
+		default:
+			r = append(r, versionConstraint{op: op, version: lower})
+		}
+	}
+	if len(r) == 0 {
+		return nil, errors.New("empty version range")
+	}
+	return r, nil
+}
+
+var releaseRe = regexp.MustCompile(`href="\./(v\d+\.\d+\.\d+)/"`)
+
+// latestInRange returns the latest release listed in the html index
+// of the download site which is in the range
+func latestInRange(html []byte, r versionRange) (string, error) {
+	var (
+		best    *semver.Version
+		bestStr string
+	)
+	for _, match := range releaseRe.FindAllSubmatch(html, -1) {
+		release := string(match[1])
+		v, err := semver.NewVersion(release[1:])
+		if err != nil || !r.match(v) {
+			continue
+		}
+		if best == nil || best.LessThan(*v) {
+			best, bestStr = v, release
+		}
+	}
+	if best == nil {
+		return "", errors.New("no release found in the version range")
+	}
+	return bestStr, nil
+}
+
+// findVersionInRange returns the latest stable release in the range
+// of versions described by version
+func findVersionInRange(ctx context.Context, siteURL, version string) (string, error) {
+	r, err := parseVersionRange(version)
+	if err != nil {
+		return "", err
+	}
+	html, err := downloadFile(ctx, siteURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to get list of releases: %w", err)
+	}
+	return latestInRange(html, r)
+}
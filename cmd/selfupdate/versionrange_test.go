@@ -0,0 +1,81 @@
+//go:build !noselfupdate
+
+package selfupdate
+
+import (
+	"testing"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsVersionRange(t *testing.T) {
+	assert.False(t, isVersionRange("1.68"))
+	assert.False(t, isVersionRange("v1.68.2"))
+	assert.False(t, isVersionRange("v1.69.0-beta.8500.abcdef123"))
+	assert.True(t, isVersionRange(">=1.66"))
+	assert.True(t, isVersionRange("=1.68"))
+	assert.True(t, isVersionRange(">=1.66 <1.69"))
+	assert.True(t, isVersionRange(">=1.66,<1.69"))
+}
+
+func TestParseVersionRange(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		yes  []string
+		no   []string
+		fail bool
+	}{
+		{in: ">=1.66 <1.69", yes: []string{"1.66.0", "1.68.9"}, no: []string{"1.65.2", "1.69.0"}},
+		{in: ">=v1.66.1,<1.68.0", yes: []string{"1.66.1", "1.67.0"}, no: []string{"1.66.0", "1.68.0"}},
+		{in: "=1.68", yes: []string{"1.68.0", "1.68.2"}, no: []string{"1.67.0", "1.69.0"}},
+		{in: "<=1.68", yes: []string{"1.60.0", "1.68.2"}, no: []string{"1.69.0"}},
+		{in: ">1.68", yes: []string{"1.69.0", "2.0.0"}, no: []string{"1.68.2"}},
+		{in: "<2", yes: []string{"1.99.0"}, no: []string{"2.0.0"}},
+		{in: "1", yes: []string{"1.0.0", "1.99.1"}, no: []string{"0.9.0", "2.0.0"}},
+		{in: "=1.68.1", yes: []string{"1.68.1"}, no: []string{"1.68.0", "1.68.2"}},
+		{in: "", fail: true},
+		{in: "~1.68", fail: true},
+		{in: ">=1.68.1.2", fail: true},
+	} {
+		r, err := parseVersionRange(test.in)
+		if test.fail {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		for _, v := range test.yes {
+			assert.True(t, r.match(semver.New(v)), "%s should match %s", test.in, v)
+		}
+		for _, v := range test.no {
+			assert.False(t, r.match(semver.New(v)), "%s shouldn't match %s", test.in, v)
+		}
+	}
+}
+
+func TestLatestInRange(t *testing.T) {
+	html := []byte(`<a href="./v1.66.0/">v1.66.0/</a>
+<a href="./v1.68.2/">v1.68.2/</a>
+<a href="./v1.68.10/">v1.68.10/</a>
+<a href="./v1.67.0/">v1.67.0/</a>
+<a href="./v1.69.0/">v1.69.0/</a>
+<a href="./version.txt">version.txt</a>`)
+
+	r, err := parseVersionRange(">=1.66 <1.69")
+	require.NoError(t, err)
+	got, err := latestInRange(html, r)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.68.10", got)
+
+	r, err = parseVersionRange("<1.68")
+	require.NoError(t, err)
+	got, err = latestInRange(html, r)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.67.0", got)
+
+	r, err = parseVersionRange(">=1.70")
+	require.NoError(t, err)
+	_, err = latestInRange(html, r)
+	assert.Error(t, err)
+}
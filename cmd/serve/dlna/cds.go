@@ -80,7 +80,20 @@ func (cds *contentDirectoryService) cdsObjectToUpnpavObject(cdsObject object, fi
 
 	item := upnpav.Item{
 		Object: obj,
-		Res:    make([]upnpav.Resource, 0, 1),
+		Res:    make([]upnpav.Resource, 0, 2),
+	}
+
+	// Offer the transcoded media first so players which can play
+	// it prefer it
+	if cds.transcoder.wants(fileInfo.Name(), mimeType) {
+		item.Res = append(item.Res, upnpav.Resource{
+			URL: (&url.URL{
+				Scheme: "http",
+				Host:   host,
+				Path:   path.Join(transcodePath, cdsObject.Path),
+			}).String(),
+			ProtocolInfo: fmt.Sprintf("http-get:*:%s:%s", cds.transcoder.mimeType, cds.transcoder.contentFeatures()),
+		})
 	}
 
 	item.Res = append(item.Res, upnpav.Resource{
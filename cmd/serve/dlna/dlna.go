@@ -54,15 +54,30 @@ var OptionsInfo = fs.Options{{
 	Name:    "announce_interval",
 	Default: fs.Duration(12 * time.Minute),
 	Help:    "The interval between SSDP announcements",
+}, {
+	Name:    "transcode_command",
+	Default: fs.SpaceSepList{},
+	Help:    "Command to transcode media with, {input} is replaced with the media URL",
+}, {
+	Name:    "transcode_ext",
+	Default: fs.CommaSepList{},
+	Help:    "Comma separated list of file extensions to transcode, blank for all videos",
+}, {
+	Name:    "transcode_mime_type",
+	Default: "video/mpeg",
+	Help:    "Mime type of the output of the transcode command",
 }}
 
 // Options is the type for DLNA serving options.
 type Options struct {
-	ListenAddr       string      `config:"addr"`
-	FriendlyName     string      `config:"name"`
-	LogTrace         bool        `config:"log_trace"`
-	InterfaceNames   []string    `config:"interface"`
-	AnnounceInterval fs.Duration `config:"announce_interval"`
+	ListenAddr        string          `config:"addr"`
+	FriendlyName      string          `config:"name"`
+	LogTrace          bool            `config:"log_trace"`
+	InterfaceNames    []string        `config:"interface"`
+	AnnounceInterval  fs.Duration     `config:"announce_interval"`
+	TranscodeCommand  fs.SpaceSepList `config:"transcode_command"`
+	TranscodeExt      fs.CommaSepList `config:"transcode_ext"`
+	TranscodeMimeType string          `config:"transcode_mime_type"`
 }
 
 // Opt contains the options for DLNA serving.
@@ -103,9 +118,9 @@ supported. Service discovery uses UDP multicast packets (SSDP) and
 will thus only work on LANs.
 
 Rclone will list all files present in the remote, without filtering
-based on media formats or file extensions. Additionally, there is no
-media transcoding support. This means that some players might show
-files that they are not able to play back correctly.
+based on media formats or file extensions. This means that some
+players might show files that they are not able to play back
+correctly.
 
 Rclone will add external subtitle files (.srt) to videos if they have the same
 filename as the video file itself (except the extension), either in the same
@@ -123,6 +138,28 @@ default "rclone (hostname)".
 Use ` + "`--log-trace` in conjunction with `-vv`" + ` to enable additional debug
 logging of all UPNP traffic.
 
+### Transcoding
+
+Rclone doesn't transcode media itself, but it can pipe media through
+an external command such as ffmpeg for players which can't play it.
+Set ` + "`--transcode-command`" + ` to the command to run. ` + "`{input}`" + ` in
+the command is replaced with the URL of the original media and the
+command should write the transcoded media to its standard output.
+For example
+
+    rclone serve dlna remote:media \
+        --transcode-command "ffmpeg -loglevel error -i {input} -c:v mpeg2video -q:v 2 -c:a mp2 -f mpegts -" \
+        --transcode-ext .mkv,.webm
+
+Each file matching one of the ` + "`--transcode-ext`" + ` extensions (all
+videos if not set) is then offered to players in two formats - the
+transcoded stream, advertised with the ` + "`--transcode-mime-type`" + `
+(default ` + "`video/mpeg`" + `), followed by the original file. Players
+usually pick the first format they can play.
+
+The transcoded stream is made while it is played so it can't be
+seeked, and a new transcode command is run each time it is played.
+
 ` + vfs.Help(),
 	Annotations: map[string]string{
 		"versionIntroduced": "v1.46",
@@ -147,6 +184,7 @@ const (
 	serverField       = "Linux/3.4 DLNADOC/1.50 UPnP/1.0 DMS/1.0"
 	rootDescPath      = "/rootDesc.xml"
 	resPath           = "/r/"
+	transcodePath     = "/t/"
 	serviceControlURL = "/ctl"
 )
 
@@ -170,8 +208,9 @@ type server struct {
 	// Time interval between SSPD announces
 	AnnounceInterval time.Duration
 
-	f   fs.Fs
-	vfs *vfs.VFS
+	f          fs.Fs
+	vfs        *vfs.VFS
+	transcoder *transcoder // nil if not transcoding
 }
 
 func newServer(ctx context.Context, f fs.Fs, opt *Options, vfsOpt *vfscommon.Options) (*server, error) {
@@ -195,6 +234,11 @@ func newServer(ctx context.Context, f fs.Fs, opt *Options, vfsOpt *vfscommon.Opt
 		interfaces = listInterfaces()
 	}
 
+	transcoder, err := newTranscoder(opt)
+	if err != nil {
+		return nil, err
+	}
+
 	s := &server{
 		AnnounceInterval: time.Duration(opt.AnnounceInterval),
 		FriendlyName:     friendlyName,
@@ -204,6 +248,7 @@ func newServer(ctx context.Context, f fs.Fs, opt *Options, vfsOpt *vfscommon.Opt
 		httpListenAddr:   opt.ListenAddr,
 		f:                f,
 		vfs:              vfs.New(f, vfsOpt),
+		transcoder:       transcoder,
 	}
 
 	s.services = map[string]UPnPService{
@@ -222,6 +267,10 @@ func newServer(ctx context.Context, f fs.Fs, opt *Options, vfsOpt *vfscommon.Opt
 	r := http.NewServeMux()
 	r.Handle(resPath, http.StripPrefix(resPath,
 		http.HandlerFunc(s.resourceHandler)))
+	if s.transcoder != nil {
+		r.Handle(transcodePath, http.StripPrefix(transcodePath,
+			http.HandlerFunc(s.transcodeHandler)))
+	}
 	if opt.LogTrace {
 		r.Handle(rootDescPath, traceLogging(http.HandlerFunc(s.rootDescHandler)))
 		r.Handle(serviceControlURL, traceLogging(http.HandlerFunc(s.serviceControlHandler)))
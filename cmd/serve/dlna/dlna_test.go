@@ -276,6 +276,78 @@ func TestContentDirectoryBrowseDirectChildren(t *testing.T) {
 	}
 }
 
+// Check that media is offered and served transcoded.
+func TestTranscode(t *testing.T) {
+	f, err := fs.NewFs(context.Background(), "testdata/files")
+	require.NoError(t, err)
+	opt := Opt
+	opt.ListenAddr = testBindAddress
+	opt.TranscodeCommand = fs.SpaceSepList{"sh", "-c", "printf 'transcoded %s' {input}"}
+	opt.TranscodeExt = fs.CommaSepList{"MP4"}
+	opt.TranscodeMimeType = "video/mpeg"
+	s, err := newServer(context.Background(), f, &opt, &vfscommon.Opt)
+	require.NoError(t, err)
+	go func() {
+		assert.NoError(t, s.Serve())
+	}()
+	defer func() {
+		assert.NoError(t, s.Shutdown())
+	}()
+	transcodeURL := "http://" + s.HTTPConn.Addr().String()
+
+	req, err := http.NewRequest("POST", transcodeURL+serviceControlURL, strings.NewReader(`
+<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"
+            s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+    <s:Body>
+        <u:Browse xmlns:u="urn:schemas-upnp-org:service:ContentDirectory:1">
+            <ObjectID>%2Fsubdir</ObjectID>
+            <BrowseFlag>BrowseDirectChildren</BrowseFlag>
+            <Filter>*</Filter>
+            <StartingIndex>0</StartingIndex>
+            <RequestedCount>0</RequestedCount>
+            <SortCriteria></SortCriteria>
+        </u:Browse>
+    </s:Body>
+</s:Envelope>`))
+	require.NoError(t, err)
+	req.Header.Set("SOAPACTION", `"urn:schemas-upnp-org:service:ContentDirectory:1#Browse"`)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	didl := html.UnescapeString(string(body))
+	// the transcoded resource should come before the original
+	transcoded := strings.Index(didl, "/t/subdir/video.mp4")
+	original := strings.Index(didl, "/r/subdir/video.mp4")
+	require.NotEqual(t, -1, transcoded)
+	require.NotEqual(t, -1, original)
+	assert.Less(t, transcoded, original)
+	assert.Contains(t, didl, `protocolInfo="http-get:*:video/mpeg:DLNA.ORG_OP=00;DLNA.ORG_CI=1;`)
+	// but not for the subtitles
+	assert.NotContains(t, didl, "/t/subdir/video.srt")
+
+	// the input is always read from the server whatever the Host
+	req, err = http.NewRequest("GET", transcodeURL+"/t/subdir/video.mp4", nil)
+	require.NoError(t, err)
+	req.Host = "attacker.example.com"
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "video/mpeg", resp.Header.Get("Content-Type"))
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "transcoded "+transcodeURL+"/r/subdir/video.mp4", string(body))
+
+	// the command must read the input
+	opt.TranscodeCommand = fs.SpaceSepList{"cat"}
+	_, err = newServer(context.Background(), f, &opt, &vfscommon.Opt)
+	assert.ErrorContains(t, err, "{input}")
+}
+
 func TestRc(t *testing.T) {
 	servetest.TestRc(t, rc.Params{
 		"type":           "dlna",
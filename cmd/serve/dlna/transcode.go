@@ -0,0 +1,156 @@
+package dlna
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+
+	dms_dlna "github.com/anacrolix/dms/dlna"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+)
+
+// transcodeInput is replaced in the transcode command with the URL of
+// the media being transcoded
+const transcodeInput = "{input}"
+
+// transcoder pipes media through an external command
+type transcoder struct {
+	command  []string            // command with transcodeInput to be replaced
+	exts     map[string]struct{} // lower case extensions to transcode, empty for all video
+	mimeType string              // mime type of the output of command
+}
+
+// newTranscoder makes a transcoder from opt or returns nil if
+// transcoding isn't configured
+func newTranscoder(opt *Options) (*transcoder, error) {
+	if len(opt.TranscodeCommand) == 0 {
+		return nil, nil
+	}
+	found := false
+	for _, arg := range opt.TranscodeCommand {
+		if strings.Contains(arg, transcodeInput) {
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("--transcode-command must contain %s to be replaced with the media URL", transcodeInput)
+	}
+	if _, err := exec.LookPath(opt.TranscodeCommand[0]); err != nil {
+		return nil, fmt.Errorf("transcode command not found: %w", err)
+	}
+	t := &transcoder{
+		command:  opt.TranscodeCommand,
+		exts:     make(map[string]struct{}, len(opt.TranscodeExt)),
+		mimeType: opt.TranscodeMimeType,
+	}
+	for _, ext := range opt.TranscodeExt {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		t.exts[ext] = struct{}{}
+	}
+	return t, nil
+}
+
+// wants returns true if a file called name with mimeType should be
+// offered transcoded
+func (t *transcoder) wants(name, mimeType string) bool {
+	if t == nil {
+		return false
+	}
+	if len(t.exts) == 0 {
+		return strings.HasPrefix(mimeType, "video/")
+	}
+	_, ok := t.exts[strings.ToLower(path.Ext(name))]
+	return ok
+}
+
+// contentFeatures returns the DLNA content features of the transcoded
+// output which is a stream which can't be seeked
+func (t *transcoder) contentFeatures() string {
+	return dms_dlna.ContentFeatures{
+		Transcoded: true,
+	}.String()
+}
+
+// args returns the command line to transcode the media at input
+func (t *transcoder) args(input string) []string {
+	args := make([]string, len(t.command))
+	for i, arg := range t.command {
+		args[i] = strings.ReplaceAll(arg, transcodeInput, input)
+	}
+	return args
+}
+
+// Serves media transcoded by the transcode command.
+func (s *server) transcodeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	remotePath := r.URL.Path
+	node, err := s.vfs.Stat(remotePath)
+	if err != nil || !node.IsFile() {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", s.transcoder.mimeType)
+	if r.Header.Get("getContentFeatures.dlna.org") != "" {
+		w.Header().Set("contentFeatures.dlna.org", s.transcoder.contentFeatures())
+	}
+	w.Header().Set("transferMode.dlna.org", "Streaming")
+	if r.Method == "HEAD" {
+		return
+	}
+
+	err = s.transcode(ctx, w, node, s.transcodeInput(remotePath))
+	if err != nil && ctx.Err() == nil {
+		fs.Errorf(node, "Transcoding failed: %v", err)
+	}
+}
+
+// transcodeInput returns the URL of the resource handler for
+// remotePath for the transcode command to read the original media
+// from.
+//
+// This uses the address the server is listening on, or loopback if it
+// is listening on all addresses, rather than the Host of the request
+// so clients can't make the command fetch from anywhere else.
+func (s *server) transcodeInput(remotePath string) string {
+	host := s.HTTPConn.Addr().String()
+	if addr, ok := s.HTTPConn.Addr().(*net.TCPAddr); ok && addr.IP.IsUnspecified() {
+		ip := net.IPv6loopback
+		if addr.IP.To4() != nil {
+			ip = net.IPv4(127, 0, 0, 1)
+		}
+		host = net.JoinHostPort(ip.String(), strconv.Itoa(addr.Port))
+	}
+	return (&url.URL{
+		Scheme: "http",
+		Host:   host,
+		Path:   path.Join(resPath, remotePath),
+	}).String()
+}
+
+// transcode runs the transcode command on input writing the output to out
+func (s *server) transcode(ctx context.Context, out io.Writer, node vfs.Node, input string) error {
+	args := s.transcoder.args(input)
+	fs.Debugf(node, "Transcoding with %q", args)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stdout = out
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
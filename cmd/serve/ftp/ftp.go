@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	iofs "io/fs"
+	"maps"
 	"net"
 	"os"
 	"os/user"
@@ -44,11 +45,15 @@ var OptionsInfo = fs.Options{{
 }, {
 	Name:    "public_ip",
 	Default: "",
-	Help:    "Public IP address to advertise for passive connections",
+	Help:    "Public IP address or host name to advertise for passive connections",
 }, {
 	Name:    "passive_port",
 	Default: "30000-32000",
 	Help:    "Passive port range to use",
+}, {
+	Name:    "disable_epsv",
+	Default: false,
+	Help:    "Disable EPSV and EPRT so clients use PASV and PORT",
 }, {
 	Name:    "user",
 	Default: "anonymous",
@@ -73,6 +78,7 @@ type Options struct {
 	ListenAddr   string `config:"addr"`         // Port to listen on
 	PublicIP     string `config:"public_ip"`    // Passive ports range
 	PassivePorts string `config:"passive_port"` // Passive ports range
+	DisableEPSV  bool   `config:"disable_epsv"` // Don't offer the extended passive and active commands
 	User         string `config:"user"`         // single username for basic auth if not using Htpasswd
 	Pass         string `config:"pass"`         // password for User
 	TLSCert      string `config:"cert"`         // TLS PEM key (concatenation of certificate and CA certificate)
@@ -140,6 +146,28 @@ By default this will serve files without needing a login.
 
 You can set a single username and password with the --user and --pass flags.
 
+#### NAT and firewalls
+
+In passive mode the client opens a new connection to the server for
+each transfer, to a port chosen from the --passive-port range (default
+30000-32000). If the server is behind NAT then forward the whole range
+to it and set --public-ip to the address clients should connect to.
+This can be a host name, e.g. one kept up to date by a dynamic DNS
+service, which is looked up when the server starts.
+
+Some NAT routers and firewalls only understand the original PASV and
+PORT commands, and some clients have trouble with the extended EPSV
+and EPRT commands. Use --disable-epsv to stop offering the extended
+commands so clients fall back to PASV and PORT.
+
+#### TLS
+
+Use --cert and --key to serve FTPS with implicit TLS. Data connections
+use the same TLS configuration as the control connection so clients
+which insist on TLS session resumption for data connections (as
+required by servers such as vsftpd) can resume the control
+connection's session.
+
 ` + vfs.Help() + proxy.Help,
 	Annotations: map[string]string{
 		"versionIntroduced": "v1.44",
@@ -180,7 +208,58 @@ func init() {
 	fs.RegisterGlobalOptions(fs.OptionsInfo{Name: "ftp", Opt: &Opt, Options: OptionsInfo})
 }
 
-var passivePortsRe = regexp.MustCompile(`^\s*\d+\s*-\s*\d+\s*$`)
+var passivePortsRe = regexp.MustCompile(`^\s*(\d+)\s*-\s*(\d+)\s*$`)
+
+// checkPassivePorts checks the format of the passive port range
+// since the server library doesn't!
+func checkPassivePorts(passivePorts string) error {
+	match := passivePortsRe.FindStringSubmatch(passivePorts)
+	if match == nil {
+		return fmt.Errorf("invalid format for passive ports %q", passivePorts)
+	}
+	minPort, _ := strconv.Atoi(match[1])
+	maxPort, _ := strconv.Atoi(match[2])
+	if minPort < 1 || maxPort > 65535 || minPort >= maxPort {
+		return fmt.Errorf("invalid passive port range %q: need 1 <= start < end <= 65535", passivePorts)
+	}
+	return nil
+}
+
+// resolvePublicIP returns the IPv4 address for publicIP which may be
+// an IP address or a host name
+//
+// PASV replies can only carry IPv4 addresses.
+func resolvePublicIP(ctx context.Context, publicIP string) (string, error) {
+	if publicIP == "" {
+		return "", nil
+	}
+	if ip := net.ParseIP(publicIP); ip != nil {
+		if ip.To4() == nil {
+			return "", fmt.Errorf("public IP %q must be an IPv4 address", publicIP)
+		}
+		return ip.String(), nil
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", publicIP)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up public IP: %w", err)
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("no IPv4 address found for public IP %q", publicIP)
+	}
+	fs.Infof(nil, "Advertising %v for %q for passive connections", ips[0], publicIP)
+	return ips[0].String(), nil
+}
+
+// makeCommands returns the FTP commands the server should offer
+func makeCommands(opt *Options) map[string]ftp.Command {
+	commands := maps.Clone(ftp.DefaultCommands())
+	if opt.DisableEPSV {
+		delete(commands, "EPSV")
+		delete(commands, "EPRT")
+		delete(commands, "LPRT")
+	}
+	return commands
+}
 
 // Make a new FTP to serve the remote
 func newServer(ctx context.Context, f fs.Fs, opt *Options, vfsOpt *vfscommon.Options, proxyOpt *proxy.Options) (*driver, error) {
@@ -206,9 +285,12 @@ func newServer(ctx context.Context, f fs.Fs, opt *Options, vfsOpt *vfscommon.Opt
 	}
 	d.useTLS = d.opt.TLSKey != ""
 
-	// Check PassivePorts format since the server library doesn't!
-	if !passivePortsRe.MatchString(opt.PassivePorts) {
-		return nil, fmt.Errorf("invalid format for passive ports %q", opt.PassivePorts)
+	if err := checkPassivePorts(opt.PassivePorts); err != nil {
+		return nil, err
+	}
+	publicIP, err := resolvePublicIP(ctx, opt.PublicIP)
+	if err != nil {
+		return nil, err
 	}
 
 	ftpopt := &ftp.Options{
@@ -217,8 +299,9 @@ func newServer(ctx context.Context, f fs.Fs, opt *Options, vfsOpt *vfscommon.Opt
 		Driver:         d,
 		Hostname:       host,
 		Port:           portNum,
-		PublicIP:       opt.PublicIP,
+		PublicIP:       publicIP,
 		PassivePorts:   opt.PassivePorts,
+		Commands:       makeCommands(opt),
 		Auth:           d,
 		Perm:           ftp.NewSimplePerm("ftp", "ftp"), // fake user and group
 		Logger:         &Logger{},
@@ -21,6 +21,7 @@ import (
 	"github.com/rclone/rclone/lib/israce"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -79,3 +80,53 @@ func TestRc(t *testing.T) {
 		"vfs_cache_mode": "off",
 	})
 }
+
+func TestCheckPassivePorts(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		wantErr bool
+	}{
+		{"30000-32000", false},
+		{" 1 - 65535 ", false},
+		{"30000", true},
+		{"32000-30000", true},
+		{"0-100", true},
+		{"100-70000", true},
+		{"a-b", true},
+	} {
+		err := checkPassivePorts(test.in)
+		assert.Equal(t, test.wantErr, err != nil, test.in)
+	}
+}
+
+func TestResolvePublicIP(t *testing.T) {
+	ctx := context.Background()
+	ip, err := resolvePublicIP(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "", ip)
+
+	ip, err = resolvePublicIP(ctx, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, "192.0.2.1", ip)
+
+	_, err = resolvePublicIP(ctx, "2001:db8::1")
+	assert.Error(t, err)
+
+	ip, err = resolvePublicIP(ctx, "localhost")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", ip)
+}
+
+func TestMakeCommands(t *testing.T) {
+	commands := makeCommands(&Options{})
+	assert.Contains(t, commands, "EPSV")
+
+	commands = makeCommands(&Options{DisableEPSV: true})
+	assert.NotContains(t, commands, "EPSV")
+	assert.NotContains(t, commands, "EPRT")
+	assert.Contains(t, commands, "PASV")
+	assert.Contains(t, commands, "PORT")
+
+	// The library's defaults must not be changed
+	assert.Contains(t, makeCommands(&Options{}), "EPSV")
+}
@@ -34,7 +34,11 @@ import (
 )
 
 // OptionsInfo describes the Options in use
-var OptionsInfo = fs.Options{}.
+var OptionsInfo = fs.Options{{
+	Name:    "zip",
+	Default: false,
+	Help:    "Offer a link to download each directory as a zip file",
+}}.
 	Add(libhttp.ConfigInfo).
 	Add(libhttp.AuthConfigInfo).
 	Add(libhttp.TemplateConfigInfo)
@@ -44,6 +48,7 @@ type Options struct {
 	Auth     libhttp.AuthConfig
 	HTTP     libhttp.Config
 	Template libhttp.TemplateConfig
+	Zip      bool `config:"zip"`
 }
 
 // DefaultOpt is the default values used for Options
@@ -110,6 +115,13 @@ The server will log errors.  Use ` + "`-v`" + ` to see access logs.
 ` + "`--bwlimit`" + ` will be respected for file transfers.  Use ` + "`--stats`" + ` to
 control the stats printing.
 
+If ` + "`--zip`" + ` is set then each directory listing will have a "Download as
+zip" link. This streams a zip file of the directory and everything in
+it, which is assembled as it is sent, so the size isn't known in
+advance and downloads can't be resumed. If a file can't be read part
+way through then the error is logged and the download will be cut
+short.
+
 ` + libhttp.Help(flagPrefix) + libhttp.TemplateHelp(flagPrefix) + libhttp.AuthHelp(flagPrefix) + vfs.Help() + proxy.Help,
 	Annotations: map[string]string{
 		"versionIntroduced": "v1.39",
@@ -256,6 +268,10 @@ func (s *HTTP) serveDir(w http.ResponseWriter, r *http.Request, dirRemote string
 		return
 	}
 	dir := node.(*vfs.Dir)
+	if s.opt.Zip && r.URL.RawQuery == zipQuery {
+		s.serveZip(w, r, dir)
+		return
+	}
 	dirEntries, err := dir.ReadDirAll()
 	if err != nil {
 		serve.Error(ctx, dirRemote, w, "Failed to list directory", err)
@@ -264,6 +280,9 @@ func (s *HTTP) serveDir(w http.ResponseWriter, r *http.Request, dirRemote string
 
 	// Make the entries for display
 	directory := serve.NewDirectory(dirRemote, s.server.HTMLTemplate())
+	if s.opt.Zip {
+		directory.ZipURL = "?" + zipQuery
+	}
 	for _, node := range dirEntries {
 		if vfscommon.Opt.NoModTime {
 			directory.AddHTMLEntry(node.Path(), node.IsDir(), node.Size(), time.Time{})
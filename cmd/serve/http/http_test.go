@@ -1,6 +1,8 @@
 package http
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"flag"
 	"io"
@@ -274,6 +276,80 @@ func TestAuthProxy(t *testing.T) {
 	testGET(t, true)
 }
 
+func TestZip(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "photos")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub", "empty"), 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0666))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("potato"), 0666))
+	f, err := fs.NewFs(ctx, dir)
+	require.NoError(t, err)
+
+	opts := Options{
+		HTTP: libhttp.DefaultCfg(),
+		Zip:  true,
+	}
+	opts.HTTP.ListenAddr = []string{testBindAddress}
+	s, err := newServer(ctx, f, &opts, &vfscommon.Opt, &proxy.Opt)
+	require.NoError(t, err)
+	go func() {
+		require.NoError(t, s.Serve())
+	}()
+	defer func() {
+		assert.NoError(t, s.Shutdown())
+	}()
+	testURL := s.server.URLs()[0]
+
+	get := func(url string) (*http.Response, []byte) {
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, resp.Body.Close())
+		}()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp, body
+	}
+
+	// The listing links to the zip
+	_, body := get(testURL)
+	assert.Contains(t, string(body), `<a href="?download=zip">Download as zip</a>`)
+
+	// Download the root
+	resp, body := get(testURL + "?download=zip")
+	assert.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=photos.zip`, resp.Header.Get("Content-Disposition"))
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	got := map[string]string{}
+	for _, file := range zr.File {
+		in, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		got[file.Name] = string(data)
+	}
+	assert.Equal(t, map[string]string{
+		"a.txt":      "hello",
+		"sub/":       "",
+		"sub/b.txt":  "potato",
+		"sub/empty/": "",
+	}, got)
+
+	// Download a subdirectory
+	resp, body = get(testURL + "sub/?download=zip")
+	assert.Equal(t, `attachment; filename=sub.zip`, resp.Header.Get("Content-Disposition"))
+	zr, err = zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	var names []string
+	for _, file := range zr.File {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{"b.txt", "empty/"}, names)
+}
+
 func TestRc(t *testing.T) {
 	servetest.TestRc(t, rc.Params{
 		"type":           "http",
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/lib/http/serve"
+	"github.com/rclone/rclone/vfs"
+)
+
+// zipQuery is the query string which asks for a directory as a zip
+const zipQuery = "download=zip"
+
+// serveZip streams the directory dir as a zip file
+//
+// The zip is assembled as it is sent so errors part way through can
+// only be logged and the client will see a truncated download.
+func (s *HTTP) serveZip(w http.ResponseWriter, r *http.Request, dir *vfs.Dir) {
+	ctx := r.Context()
+	name := path.Base("/" + dir.Path())
+	if name == "/" && s.f != nil {
+		name = path.Base(s.f.Root())
+	}
+	if name == "/" || name == "." || name == "" {
+		name = "download"
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".zip"}))
+	w.Header().Set("Last-Modified", dir.ModTime().UTC().Format(http.TimeFormat))
+	if r.Method == "HEAD" {
+		return
+	}
+
+	fs.Infof(dir.Path(), "%s: Serving directory as zip", r.RemoteAddr)
+	zw := zip.NewWriter(w)
+	err := s.zipDir(ctx, zw, dir, "")
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		serve.Error(ctx, dir.Path(), nil, "Didn't finish writing zip", err)
+	}
+}
+
+// zipDir adds the contents of dir to zw with names prefixed by prefix
+func (s *HTTP) zipDir(ctx context.Context, zw *zip.Writer, dir *vfs.Dir, prefix string) error {
+	nodes, err := dir.ReadDirAll()
+	if err != nil {
+		return fmt.Errorf("failed to list %q: %w", dir.Path(), err)
+	}
+	for _, node := range nodes {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		name := prefix + node.Name()
+		switch x := node.(type) {
+		case *vfs.Dir:
+			_, err = zw.CreateHeader(&zip.FileHeader{
+				Name:     name + "/",
+				Method:   zip.Store,
+				Modified: x.ModTime(),
+			})
+			if err != nil {
+				return err
+			}
+			err = s.zipDir(ctx, zw, x, name+"/")
+		case *vfs.File:
+			err = s.zipFile(ctx, zw, x, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zipFile adds file to zw as name
+func (s *HTTP) zipFile(ctx context.Context, zw *zip.Writer, file *vfs.File, name string) error {
+	out, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: file.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+	in, err := file.Open(os.O_RDONLY)
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %w", file.Path(), err)
+	}
+	defer func() {
+		err := in.Close()
+		if err != nil {
+			fs.Errorf(file.Path(), "Failed to close file: %v", err)
+		}
+	}()
+
+	// Account the transfer
+	if obj, ok := file.DirEntry().(fs.Object); ok {
+		tr := accounting.Stats(ctx).NewTransfer(obj, nil)
+		defer tr.Done(ctx, nil)
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		return fmt.Errorf("failed to read %q: %w", file.Path(), err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 //go:build unix
 
-// Package nfs implements a server to serve a VFS remote over the NFSv3 protocol
+// Package nfs implements a server to serve a VFS remote over the NFSv3
+// and NFSv4 protocols
 //
 // There is no authentication available on this server and it is
 // served on the loopback interface by default.
@@ -126,7 +127,9 @@ var Command = &cobra.Command{
 	Short: `Serve the remote as an NFS mount`,
 	Long: strings.ReplaceAll(`Create an NFS server that serves the given remote over the network.
 	
-This implements an NFSv3 server to serve any rclone remote via NFS.
+This implements an NFSv3 and NFSv4.0 server to serve any rclone remote
+via NFS. Both are served on the same port, the version is picked by
+the client.
 
 The primary purpose for this command is to enable the [mount
 command](/commands/rclone_mount/) on recent macOS versions where
@@ -190,6 +193,20 @@ Where |$PORT| is the same port number used in the |serve nfs| command
 and |$HOSTNAME| is the network address of the machine that |serve nfs|
 was run on.
 
+To mount using NFSv4 use this instead:
+
+    mount -t nfs -o vers=4.0,port=$PORT $HOSTNAME:/ path/to/mountpoint
+
+NFSv4 doesn't need the separate mount protocol and supports byte range
+locking with |fcntl| and |flock| (on Linux), which NFSv3 mounts
+of this server don't. The locks are held by the server in memory, so
+they are only seen by clients of this server and not by anything else
+using the remote. Files opened over NFSv4 are kept open in the VFS
+until the client closes them, so they are uploaded on close rather
+than after each write. Clients which don't renew their lease for
+twice the 90 second lease time lose their locks and open files. NFSv4
+minor versions 4.1 and 4.2 are not supported.
+
 If |--vfs-metadata-extension| is in use then for the |--nfs-cache-type disk|
 and |--nfs-cache-type cache| the metadata files will have the file
 handle of their parent file suffixed with |0x00, 0x00, 0x00, 0x01|.
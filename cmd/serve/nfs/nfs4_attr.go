@@ -0,0 +1,304 @@
+//go:build unix
+
+package nfs
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rclone/rclone/vfs"
+)
+
+// NFSv4 file attributes (RFC 7530 section 5)
+const (
+	attrSupportedAttrs  = 0
+	attrType            = 1
+	attrFHExpireType    = 2
+	attrChange          = 3
+	attrSize            = 4
+	attrLinkSupport     = 5
+	attrSymlinkSupport  = 6
+	attrNamedAttr       = 7
+	attrFsid            = 8
+	attrUniqueHandles   = 9
+	attrLeaseTime       = 10
+	attrRdattrError     = 11
+	attrCanSetTime      = 15
+	attrCaseInsensitive = 16
+	attrCasePreserving  = 17
+	attrChownRestricted = 18
+	attrFilehandle      = 19
+	attrFileid          = 20
+	attrFilesAvail      = 21
+	attrFilesFree       = 22
+	attrFilesTotal      = 23
+	attrHomogeneous     = 26
+	attrMaxFileSize     = 27
+	attrMaxLink         = 28
+	attrMaxName         = 29
+	attrMaxRead         = 30
+	attrMaxWrite        = 31
+	attrMode            = 33
+	attrNoTrunc         = 34
+	attrNumLinks        = 35
+	attrOwner           = 36
+	attrOwnerGroup      = 37
+	attrSpaceAvail      = 42
+	attrSpaceFree       = 43
+	attrSpaceTotal      = 44
+	attrSpaceUsed       = 45
+	attrTimeAccess      = 47
+	attrTimeAccessSet   = 48
+	attrTimeMetadata    = 52
+	attrTimeModify      = 53
+	attrTimeModifySet   = 54
+	attrMountedOnFileid = 55
+	attrMax             = 56
+)
+
+// Values used in the attributes
+const (
+	nfs4FileTypeRegular   = 1
+	nfs4FileTypeDirectory = 2
+	nfs4FileTypeSymlink   = 5
+	setToClientTime       = 1       // time_*_set from the client rather than the server
+	nfs4Files             = 1 << 30 // number of files reported as free
+)
+
+// supportedAttrs is the bitmap of the attributes the server supports
+var supportedAttrs = makeBitmap(
+	attrSupportedAttrs, attrType, attrFHExpireType, attrChange, attrSize,
+	attrLinkSupport, attrSymlinkSupport, attrNamedAttr, attrFsid,
+	attrUniqueHandles, attrLeaseTime, attrRdattrError, attrCanSetTime,
+	attrCaseInsensitive, attrCasePreserving, attrChownRestricted,
+	attrFilehandle, attrFileid, attrFilesAvail, attrFilesFree,
+	attrFilesTotal, attrHomogeneous, attrMaxFileSize, attrMaxLink,
+	attrMaxName, attrMaxRead, attrMaxWrite, attrMode, attrNoTrunc,
+	attrNumLinks, attrOwner, attrOwnerGroup, attrSpaceAvail,
+	attrSpaceFree, attrSpaceTotal, attrSpaceUsed, attrTimeAccess,
+	attrTimeAccessSet, attrTimeMetadata, attrTimeModify,
+	attrTimeModifySet, attrMountedOnFileid,
+)
+
+// makeBitmap makes a bitmap4 with the attrs set
+func makeBitmap(attrs ...int) []uint32 {
+	bitmap := make([]uint32, 2)
+	for _, attr := range attrs {
+		bitmap[attr/32] |= 1 << (attr % 32)
+	}
+	return bitmap
+}
+
+// isSet returns true if attr is set in bitmap
+func isSet(bitmap []uint32, attr int) bool {
+	return attr/32 < len(bitmap) && bitmap[attr/32]&(1<<(attr%32)) != 0
+}
+
+// writeTime writes an nfstime4
+func writeTime(w *xdrWriter, t time.Time) {
+	w.uint64(uint64(t.Unix()))
+	w.uint32(uint32(t.Nanosecond()))
+}
+
+// changeID returns the change attribute for node
+func changeID(node vfs.Node) uint64 {
+	return uint64(node.ModTime().UnixNano())
+}
+
+// fileType returns the nfs_ftype4 of node
+func fileType(node vfs.Node) uint32 {
+	switch {
+	case node.IsDir():
+		return nfs4FileTypeDirectory
+	case node.Mode()&os.ModeSymlink != 0:
+		return nfs4FileTypeSymlink
+	}
+	return nfs4FileTypeRegular
+}
+
+// encodeAttrs encodes the attributes in request of node which has
+// handle fh as an fattr4
+func (s *nfs4Server) encodeAttrs(w *xdrWriter, node vfs.Node, fh []byte, request []uint32) {
+	var values xdrWriter
+	bitmap := make([]uint32, 2)
+	opt := &s.vfs.Opt
+	var total, free int64
+	statfsDone := false
+	statfs := func() {
+		if !statfsDone {
+			total, _, free = s.vfs.Statfs()
+			total, free = max(total, 0), max(free, 0)
+			statfsDone = true
+		}
+	}
+	for attr := range attrMax {
+		if !isSet(request, attr) || !isSet(supportedAttrs, attr) {
+			continue
+		}
+		switch attr {
+		case attrSupportedAttrs:
+			values.bitmap(supportedAttrs)
+		case attrType:
+			values.uint32(fileType(node))
+		case attrFHExpireType:
+			values.uint32(s.fhExpireType())
+		case attrChange:
+			values.uint64(changeID(node))
+		case attrSize:
+			values.uint64(uint64(node.Size()))
+		case attrLinkSupport, attrNamedAttr, attrCaseInsensitive:
+			values.bool(attr == attrCaseInsensitive && opt.CaseInsensitive)
+		case attrSymlinkSupport:
+			values.bool(opt.Links)
+		case attrFsid:
+			values.uint64(0)
+			values.uint64(0)
+		case attrUniqueHandles, attrCanSetTime, attrCasePreserving,
+			attrChownRestricted, attrHomogeneous, attrNoTrunc:
+			values.bool(true)
+		case attrLeaseTime:
+			values.uint32(uint32(nfs4LeaseTime / time.Second))
+		case attrRdattrError:
+			values.uint32(nfs4OK)
+		case attrFilehandle:
+			values.opaque(fh)
+		case attrFileid, attrMountedOnFileid:
+			values.uint64(node.Inode())
+		case attrFilesAvail, attrFilesFree, attrFilesTotal:
+			values.uint64(nfs4Files)
+		case attrMaxFileSize:
+			values.uint64(math.MaxInt64)
+		case attrMaxLink:
+			values.uint32(1)
+		case attrMaxName:
+			values.uint32(nfs4MaxName)
+		case attrMaxRead, attrMaxWrite:
+			values.uint64(nfs4MaxIO)
+		case attrMode:
+			values.uint32(uint32(node.Mode().Perm()))
+		case attrNumLinks:
+			values.uint32(1)
+		case attrOwner:
+			values.string(strconv.FormatUint(uint64(opt.UID), 10))
+		case attrOwnerGroup:
+			values.string(strconv.FormatUint(uint64(opt.GID), 10))
+		case attrSpaceAvail, attrSpaceFree:
+			statfs()
+			values.uint64(uint64(free))
+		case attrSpaceTotal:
+			statfs()
+			values.uint64(uint64(total))
+		case attrSpaceUsed:
+			values.uint64(uint64(node.Size()))
+		case attrTimeAccess, attrTimeMetadata, attrTimeModify:
+			writeTime(&values, node.ModTime())
+		default:
+			// write only attributes
+			continue
+		}
+		bitmap[attr/32] |= 1 << (attr % 32)
+	}
+	w.bitmap(bitmap)
+	w.opaque(values.b)
+}
+
+// setAttrs are the attributes decoded from an fattr4 to be set
+type setAttrs struct {
+	bitmap  []uint32   // the attributes present
+	size    *uint64    // new size if set
+	modTime *time.Time // new modification time if set
+}
+
+// decodeAttrs decodes an fattr4 of attributes to set
+func decodeAttrs(r *xdrReader) (*setAttrs, uint32) {
+	a := &setAttrs{bitmap: r.bitmap()}
+	vr := &xdrReader{b: r.opaque()}
+	if r.err != nil {
+		return nil, nfs4ErrBadXDR
+	}
+	for attr := range 32 * len(a.bitmap) {
+		if !isSet(a.bitmap, attr) {
+			continue
+		}
+		switch attr {
+		case attrSize:
+			size := vr.uint64()
+			a.size = &size
+		case attrMode:
+			// Permissions are set by --dir-perms and --file-perms
+			vr.uint32()
+		case attrOwner, attrOwnerGroup:
+			// Ownership is set by --uid and --gid
+			vr.string()
+		case attrTimeAccessSet, attrTimeModifySet:
+			t := time.Now()
+			if vr.uint32() == setToClientTime {
+				secs := vr.uint64()
+				nsecs := vr.uint32()
+				t = time.Unix(int64(secs), int64(nsecs))
+			}
+			if attr == attrTimeModifySet {
+				a.modTime = &t
+			}
+		default:
+			if isSet(supportedAttrs, attr) {
+				return nil, nfs4ErrInval
+			}
+			return nil, nfs4ErrAttrNotSupp
+		}
+	}
+	if vr.err != nil || len(vr.b) != 0 {
+		return nil, nfs4ErrBadXDR
+	}
+	return a, nfs4OK
+}
+
+// apply sets the attributes on node
+func (a *setAttrs) apply(node vfs.Node, handle vfs.Handle) error {
+	if a.size != nil {
+		var err error
+		if handle != nil {
+			err = handle.Truncate(int64(*a.size))
+		} else {
+			err = node.Truncate(int64(*a.size))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if a.modTime != nil {
+		if err := node.SetModTime(*a.modTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyAttrs returns true if the attributes in the fattr4 read from
+// r match node
+func (s *nfs4Server) verifyAttrs(r *xdrReader, node vfs.Node, fh []byte) (bool, uint32) {
+	request := r.bitmap()
+	values := r.opaque()
+	if r.err != nil {
+		return false, nfs4ErrBadXDR
+	}
+	for attr := range 32 * len(request) {
+		if !isSet(request, attr) {
+			continue
+		}
+		switch {
+		case !isSet(supportedAttrs, attr):
+			return false, nfs4ErrAttrNotSupp
+		case attr == attrRdattrError, attr == attrTimeAccessSet, attr == attrTimeModifySet:
+			return false, nfs4ErrInval
+		}
+	}
+	var w xdrWriter
+	s.encodeAttrs(&w, node, fh, request)
+	got := &xdrReader{b: w.b}
+	got.bitmap()
+	return bytes.Equal(got.opaque(), values), nfs4OK
+}
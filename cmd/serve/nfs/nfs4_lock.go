@@ -0,0 +1,152 @@
+//go:build unix
+
+package nfs
+
+import "math"
+
+// byteLock is a byte range lock held by a lock owner
+//
+// The range is [start, end) with end == math.MaxUint64 meaning the
+// lock extends to the end of the file whatever its size.
+type byteLock struct {
+	owner *nfs4Owner
+	write bool
+	start uint64
+	end   uint64
+}
+
+// overlaps returns true if l overlaps [start, end)
+func (l *byteLock) overlaps(start, end uint64) bool {
+	return l.start < end && start < l.end
+}
+
+// lockManager keeps track of the byte range locks on the files of
+// the VFS by path
+//
+// It implements POSIX semantics - locks held by the same owner never
+// conflict and a new lock replaces the owner's locks on the range it
+// covers. It isn't safe for concurrent use, the caller must lock it.
+type lockManager struct {
+	files map[string][]*byteLock
+}
+
+// newLockManager makes a new lockManager
+func newLockManager() *lockManager {
+	return &lockManager{
+		files: make(map[string][]*byteLock),
+	}
+}
+
+// lockRange converts an NFSv4 offset and length into [start, end)
+//
+// It returns false if the range is invalid.
+func lockRange(offset, length uint64) (start, end uint64, ok bool) {
+	switch {
+	case length == 0:
+		return 0, 0, false
+	case length == math.MaxUint64:
+		return offset, math.MaxUint64, true
+	case offset > math.MaxUint64-length:
+		return 0, 0, false
+	}
+	return offset, offset + length, true
+}
+
+// test returns the first lock on path conflicting with a lock by
+// owner on [start, end) or nil if there is none
+func (m *lockManager) test(path string, owner *nfs4Owner, write bool, start, end uint64) *byteLock {
+	for _, l := range m.files[path] {
+		if l.owner != owner && (write || l.write) && l.overlaps(start, end) {
+			return l
+		}
+	}
+	return nil
+}
+
+// lock locks [start, end) of path for owner
+//
+// It returns the conflicting lock if it couldn't.
+func (m *lockManager) lock(path string, owner *nfs4Owner, write bool, start, end uint64) *byteLock {
+	if conflict := m.test(path, owner, write, start, end); conflict != nil {
+		return conflict
+	}
+	m.unlock(path, owner, start, end)
+	m.files[path] = append(m.files[path], &byteLock{
+		owner: owner,
+		write: write,
+		start: start,
+		end:   end,
+	})
+	return nil
+}
+
+// unlock removes the locks of owner on [start, end) of path, splitting
+// any which only partly overlap it
+func (m *lockManager) unlock(path string, owner *nfs4Owner, start, end uint64) {
+	var locks []*byteLock
+	for _, l := range m.files[path] {
+		if l.owner != owner || !l.overlaps(start, end) {
+			locks = append(locks, l)
+			continue
+		}
+		if l.start < start {
+			locks = append(locks, &byteLock{owner: owner, write: l.write, start: l.start, end: start})
+		}
+		if l.end > end {
+			locks = append(locks, &byteLock{owner: owner, write: l.write, start: end, end: l.end})
+		}
+	}
+	m.set(path, locks)
+}
+
+// set sets the locks on path removing it if there are none
+func (m *lockManager) set(path string, locks []*byteLock) {
+	if len(locks) == 0 {
+		delete(m.files, path)
+	} else {
+		m.files[path] = locks
+	}
+}
+
+// held returns true if owner holds any locks on path or on any file
+// if path is ""
+func (m *lockManager) held(path string, owner *nfs4Owner) bool {
+	for p, locks := range m.files {
+		if path != "" && p != path {
+			continue
+		}
+		for _, l := range locks {
+			if l.owner == owner {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// release removes all the locks held by owner
+func (m *lockManager) release(owner *nfs4Owner) {
+	for path, locks := range m.files {
+		var kept []*byteLock
+		for _, l := range locks {
+			if l.owner != owner {
+				kept = append(kept, l)
+			}
+		}
+		m.set(path, kept)
+	}
+}
+
+// rename moves the locks on oldPath and the files below it to newPath
+func (m *lockManager) rename(oldPath, newPath string) {
+	renamed := make(map[string][]*byteLock)
+	for path, locks := range m.files {
+		if newName, ok := renamePath(path, oldPath, newPath); ok {
+			delete(m.files, path)
+			renamed[newName] = locks
+		}
+	}
+	for path, locks := range renamed {
+		m.files[path] = locks
+	}
+}
@@ -0,0 +1,1395 @@
+//go:build unix
+
+package nfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// NFSv4 operations (RFC 7530 section 16)
+const (
+	opAccess             = 3
+	opClose              = 4
+	opCommit             = 5
+	opCreate             = 6
+	opDelegPurge         = 7
+	opDelegReturn        = 8
+	opGetattr            = 9
+	opGetFH              = 10
+	opLink               = 11
+	opLock               = 12
+	opLockT              = 13
+	opLockU              = 14
+	opLookup             = 15
+	opLookupP            = 16
+	opNVerify            = 17
+	opOpen               = 18
+	opOpenAttr           = 19
+	opOpenConfirm        = 20
+	opOpenDowngrade      = 21
+	opPutFH              = 22
+	opPutPubFH           = 23
+	opPutRootFH          = 24
+	opRead               = 25
+	opReadDir            = 26
+	opReadLink           = 27
+	opRemove             = 28
+	opRename             = 29
+	opRenew              = 30
+	opRestoreFH          = 31
+	opSaveFH             = 32
+	opSecInfo            = 33
+	opSetattr            = 34
+	opSetClientID        = 35
+	opSetClientIDConfirm = 36
+	opVerify             = 37
+	opWrite              = 38
+	opReleaseLockOwner   = 39
+	opIllegal            = 10044
+)
+
+// Values used in the operations
+const (
+	accessRead    = 0x01
+	accessLookup  = 0x02
+	accessModify  = 0x04
+	accessExtend  = 0x08
+	accessDelete  = 0x10
+	accessExecute = 0x20
+
+	createDir     = 2
+	createSymlink = 5
+
+	openCreate      = 1
+	createUnchecked = 0
+	createGuarded   = 1
+	createExclusive = 2
+	claimNull       = 0
+	claimPrevious   = 1
+	claimDelegCur   = 2
+	claimDelegPrev  = 3
+
+	openResultLockTypePosix = 0x04
+	openDelegateNone        = 0
+
+	lockRead   = 1
+	lockWrite  = 2
+	lockReadW  = 3
+	lockWriteW = 4
+
+	fileSync = 2
+
+	authNone = 0
+	authSys  = 1
+
+	fh4VolatileAny = 0x02
+	fh4VolRename   = 0x08
+
+	maxFHSize = 128
+)
+
+// nfs4FH is a file handle and the path it refers to
+type nfs4FH struct {
+	fh   []byte
+	path string
+}
+
+// compound is the state of a COMPOUND request while it is being run
+type compound struct {
+	s     *nfs4Server
+	cur   *nfs4FH // current filehandle
+	saved *nfs4FH // saved filehandle
+}
+
+// opFunc runs an operation reading its arguments from r
+//
+// It returns the status and writes the result which follows the
+// status to w, normally only when the status is nfs4OK.
+type opFunc func(c *compound, r *xdrReader, w *xdrWriter) uint32
+
+// nfs4Ops are the operations of the NFSv4 server
+var nfs4Ops map[uint32]opFunc
+
+func init() {
+	nfs4Ops = map[uint32]opFunc{
+		opAccess:             (*compound).access,
+		opClose:              (*compound).close,
+		opCommit:             (*compound).commit,
+		opCreate:             (*compound).create,
+		opDelegPurge:         (*compound).delegPurge,
+		opDelegReturn:        (*compound).delegReturn,
+		opGetattr:            (*compound).getattr,
+		opGetFH:              (*compound).getFH,
+		opLink:               (*compound).link,
+		opLock:               (*compound).lock,
+		opLockT:              (*compound).lockT,
+		opLockU:              (*compound).lockU,
+		opLookup:             (*compound).lookup,
+		opLookupP:            (*compound).lookupP,
+		opNVerify:            (*compound).nverify,
+		opOpen:               (*compound).open,
+		opOpenAttr:           (*compound).openAttr,
+		opOpenConfirm:        (*compound).openConfirm,
+		opOpenDowngrade:      (*compound).openDowngrade,
+		opPutFH:              (*compound).putFH,
+		opPutPubFH:           (*compound).putRootFH,
+		opPutRootFH:          (*compound).putRootFH,
+		opRead:               (*compound).read,
+		opReadDir:            (*compound).readDir,
+		opReadLink:           (*compound).readLink,
+		opRemove:             (*compound).remove,
+		opRename:             (*compound).rename,
+		opRenew:              (*compound).renew,
+		opRestoreFH:          (*compound).restoreFH,
+		opSaveFH:             (*compound).saveFH,
+		opSecInfo:            (*compound).secInfo,
+		opSetattr:            (*compound).setattr,
+		opSetClientID:        (*compound).setClientID,
+		opSetClientIDConfirm: (*compound).setClientIDConfirm,
+		opVerify:             (*compound).verify,
+		opWrite:              (*compound).write,
+		opReleaseLockOwner:   (*compound).releaseLockOwner,
+	}
+}
+
+// compound runs the COMPOUND procedure reading the arguments from r
+// and writing the results to w
+//
+// The operations are run in order until one fails. It returns false
+// if the arguments couldn't be decoded at all.
+func (s *nfs4Server) compound(r *xdrReader, w *xdrWriter) bool {
+	tag := r.opaque()
+	minorVersion := r.uint32()
+	n := r.uint32()
+	if r.err != nil {
+		return false
+	}
+	statusPos := len(w.b)
+	w.uint32(nfs4OK)
+	w.opaque(tag)
+	countPos := len(w.b)
+	w.uint32(0)
+	var status uint32 = nfs4OK
+	var count uint32
+	if minorVersion != 0 {
+		status = nfs4ErrMinorVers
+		n = 0
+	}
+	c := &compound{s: s}
+	for range n {
+		op := r.uint32()
+		var result xdrWriter
+		fn := nfs4Ops[op]
+		switch {
+		case r.err != nil:
+			status = nfs4ErrBadXDR
+		case fn == nil:
+			op = opIllegal
+			status = nfs4ErrOpIllegal
+		default:
+			status = fn(c, r, &result)
+			if r.err != nil {
+				status = nfs4ErrBadXDR
+				result.b = nil
+			}
+		}
+		w.uint32(op)
+		w.uint32(status)
+		w.fixed(result.b)
+		count++
+		if status != nfs4OK {
+			break
+		}
+	}
+	binary.BigEndian.PutUint32(w.b[statusPos:], status)
+	binary.BigEndian.PutUint32(w.b[countPos:], count)
+	return true
+}
+
+// nfs4Status converts a VFS error into an nfsstat4
+func nfs4Status(err error) uint32 {
+	switch {
+	case err == nil:
+		return nfs4OK
+	case errors.Is(err, vfs.ENOENT):
+		return nfs4ErrNoEnt
+	case errors.Is(err, vfs.EEXIST):
+		return nfs4ErrExist
+	case errors.Is(err, vfs.EPERM):
+		return nfs4ErrPerm
+	case errors.Is(err, vfs.EINVAL), errors.Is(err, vfs.ESPIPE):
+		return nfs4ErrInval
+	case errors.Is(err, vfs.ENOTEMPTY):
+		return nfs4ErrNotEmpty
+	case errors.Is(err, vfs.EROFS):
+		return nfs4ErrROFS
+	case errors.Is(err, vfs.ENOSYS):
+		return nfs4ErrNotSupp
+	case errors.Is(err, vfs.EBADF):
+		return nfs4ErrOpenMode
+	}
+	fs.Debugf("nfs", "NFSv4 returning I/O error for: %v", err)
+	return nfs4ErrIO
+}
+
+// readOnly returns true if the VFS can't be written to
+func (s *nfs4Server) readOnly() bool {
+	return s.vfs.Opt.ReadOnly || s.vfs.Opt.CacheMode == vfscommon.CacheModeOff
+}
+
+// fhExpireType returns the fh_expire_type attribute
+//
+// The handles are looked up by path so they don't survive a rename
+// and the memory cache forgets them when the server is restarted.
+func (s *nfs4Server) fhExpireType() uint32 {
+	if s.h.opt.HandleCache == cacheMemory {
+		return fh4VolatileAny | fh4VolRename
+	}
+	return fh4VolRename
+}
+
+// splitPath splits p into the elements the handle cache uses
+func splitPath(p string) []string {
+	if p == "" {
+		return []string{}
+	}
+	return strings.Split(p, "/")
+}
+
+// makeFH returns the file handle for p
+func (s *nfs4Server) makeFH(p string) *nfs4FH {
+	return &nfs4FH{
+		fh:   s.h.ToHandle(s.h.billyFS, splitPath(p)),
+		path: p,
+	}
+}
+
+// invalidate forgets the file handle for p
+func (s *nfs4Server) invalidate(p string) {
+	fh := s.h.ToHandle(s.h.billyFS, splitPath(p))
+	if err := s.h.InvalidateHandle(s.h.billyFS, fh); err != nil {
+		fs.Debugf("nfs", "NFSv4 failed to invalidate handle for %q: %v", p, err)
+	}
+}
+
+// checkName checks a file name from the client
+func checkName(name string) uint32 {
+	switch {
+	case name == "":
+		return nfs4ErrInval
+	case len(name) > nfs4MaxName:
+		return nfs4ErrNameTooLong
+	case !utf8.ValidString(name):
+		return nfs4ErrInval
+	case name == ".", name == "..", strings.ContainsAny(name, "/\x00"):
+		return nfs4ErrBadName
+	}
+	return nfs4OK
+}
+
+// readStateid reads a stateid4
+func readStateid(r *xdrReader) (sid stateid) {
+	sid.seqid = r.uint32()
+	copy(sid.other[:], r.fixed(12))
+	return sid
+}
+
+// writeStateid writes a stateid4
+func writeStateid(w *xdrWriter, sid stateid) {
+	w.uint32(sid.seqid)
+	w.fixed(sid.other[:])
+}
+
+// writeChangeInfo writes a change_info4
+func writeChangeInfo(w *xdrWriter, before, after uint64) {
+	w.bool(false)
+	w.uint64(before)
+	w.uint64(after)
+}
+
+// stat returns the node of the current filehandle
+func (c *compound) stat() (vfs.Node, uint32) {
+	if c.cur == nil {
+		return nil, nfs4ErrNoFileHandle
+	}
+	node, err := c.s.vfs.Stat(c.cur.path)
+	if errors.Is(err, vfs.ENOENT) {
+		return nil, nfs4ErrStale
+	}
+	return node, nfs4Status(err)
+}
+
+// statDir returns the node of the current filehandle which must be a
+// directory
+func (c *compound) statDir() (vfs.Node, uint32) {
+	node, status := c.stat()
+	if status == nfs4OK && !node.IsDir() {
+		return nil, nfs4ErrNotDir
+	}
+	return node, status
+}
+
+// statFile returns the node of the current filehandle which must be
+// a regular file
+func (c *compound) statFile() (vfs.Node, uint32) {
+	node, status := c.stat()
+	if status != nfs4OK {
+		return nil, status
+	}
+	switch fileType(node) {
+	case nfs4FileTypeDirectory:
+		return nil, nfs4ErrIsDir
+	case nfs4FileTypeSymlink:
+		return nil, nfs4ErrInval
+	}
+	return node, nfs4OK
+}
+
+// dirChange returns the change attribute of the directory p
+func (c *compound) dirChange(p string) uint64 {
+	node, err := c.s.vfs.Stat(p)
+	if err != nil {
+		return 0
+	}
+	return changeID(node)
+}
+
+// access implements ACCESS
+func (c *compound) access(r *xdrReader, w *xdrWriter) uint32 {
+	want := r.uint32()
+	if _, status := c.stat(); status != nfs4OK {
+		return status
+	}
+	supported := want & (accessRead | accessLookup | accessModify | accessExtend | accessDelete | accessExecute)
+	granted := supported
+	if c.s.readOnly() {
+		granted &^= accessModify | accessExtend | accessDelete
+	}
+	w.uint32(supported)
+	w.uint32(granted)
+	return nfs4OK
+}
+
+// close implements CLOSE
+func (c *compound) close(r *xdrReader, w *xdrWriter) (status uint32) {
+	seqid := r.uint32()
+	sid := readStateid(r)
+	if c.cur == nil {
+		return nfs4ErrNoFileHandle
+	}
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, status := s.findState(sid)
+	if status != nfs4OK {
+		return status
+	}
+	if st == nil || st.owner.lock {
+		return nfs4ErrBadStateID
+	}
+	if status = st.owner.checkSeqid(seqid); status != nfs4OK {
+		return status
+	}
+	defer func() { st.owner.useSeqid(seqid, status) }()
+	var lockStates []*nfs4State
+	for _, lst := range s.states {
+		if lst.open == st {
+			if s.locks.held(lst.path, lst.owner) {
+				return nfs4ErrLocksHeld
+			}
+			lockStates = append(lockStates, lst)
+		}
+	}
+	for _, lst := range lockStates {
+		s.dropState(lst)
+	}
+	s.dropState(st)
+	st.seqid++
+	writeStateid(w, st.stateid())
+	return nfs4OK
+}
+
+// commit implements COMMIT
+//
+// Writes go straight into the VFS so there is nothing to do.
+func (c *compound) commit(r *xdrReader, w *xdrWriter) uint32 {
+	r.uint64() // offset
+	r.uint32() // count
+	if _, status := c.statFile(); status != nfs4OK {
+		return status
+	}
+	w.fixed(c.s.boot[:])
+	return nfs4OK
+}
+
+// create implements CREATE for directories and symlinks
+func (c *compound) create(r *xdrReader, w *xdrWriter) uint32 {
+	objType := r.uint32()
+	var target string
+	switch objType {
+	case createSymlink:
+		target = r.string()
+	case 3, 4: // block and character devices
+		r.uint32()
+		r.uint32()
+	}
+	name := r.string()
+	attrs, attrStatus := decodeAttrs(r)
+	if _, status := c.statDir(); status != nfs4OK {
+		return status
+	}
+	if status := checkName(name); status != nfs4OK {
+		return status
+	}
+	if attrStatus != nfs4OK {
+		return attrStatus
+	}
+	if c.s.readOnly() {
+		return nfs4ErrROFS
+	}
+	dir := c.cur.path
+	p := path.Join(dir, name)
+	before := c.dirChange(dir)
+	var err error
+	switch objType {
+	case createDir:
+		err = c.s.vfs.Mkdir(p, 0777)
+	case createSymlink:
+		err = c.s.vfs.Symlink(target, p)
+	default:
+		return nfs4ErrBadType
+	}
+	if err != nil {
+		return nfs4Status(err)
+	}
+	node, err := c.s.vfs.Stat(p)
+	if err == nil {
+		err = attrs.apply(node, nil)
+	}
+	if err != nil {
+		return nfs4Status(err)
+	}
+	writeChangeInfo(w, before, c.dirChange(dir))
+	w.bitmap(attrs.bitmap)
+	c.cur = c.s.makeFH(p)
+	return nfs4OK
+}
+
+// delegPurge implements DELEGPURGE - delegations aren't supported
+func (c *compound) delegPurge(r *xdrReader, w *xdrWriter) uint32 {
+	r.uint64() // clientid
+	return nfs4ErrNotSupp
+}
+
+// delegReturn implements DELEGRETURN - no delegations are handed out
+func (c *compound) delegReturn(r *xdrReader, w *xdrWriter) uint32 {
+	readStateid(r)
+	return nfs4ErrBadStateID
+}
+
+// getattr implements GETATTR
+func (c *compound) getattr(r *xdrReader, w *xdrWriter) uint32 {
+	request := r.bitmap()
+	node, status := c.stat()
+	if status != nfs4OK {
+		return status
+	}
+	c.s.encodeAttrs(w, node, c.cur.fh, request)
+	return nfs4OK
+}
+
+// getFH implements GETFH
+func (c *compound) getFH(r *xdrReader, w *xdrWriter) uint32 {
+	if c.cur == nil {
+		return nfs4ErrNoFileHandle
+	}
+	w.opaque(c.cur.fh)
+	return nfs4OK
+}
+
+// link implements LINK - hard links aren't supported
+func (c *compound) link(r *xdrReader, w *xdrWriter) uint32 {
+	r.string() // newname
+	return nfs4ErrNotSupp
+}
+
+// writeDenied writes a LOCK4denied for l
+func writeDenied(w *xdrWriter, l *byteLock) {
+	w.uint64(l.start)
+	if l.end == math.MaxUint64 {
+		w.uint64(math.MaxUint64)
+	} else {
+		w.uint64(l.end - l.start)
+	}
+	if l.write {
+		w.uint32(lockWrite)
+	} else {
+		w.uint32(lockRead)
+	}
+	w.uint64(l.owner.client.id)
+	w.string(l.owner.name)
+}
+
+// readLockType reads an nfs_lock_type4 returning whether it is a
+// write lock
+func readLockType(r *xdrReader) (write bool, status uint32) {
+	switch r.uint32() {
+	case lockRead, lockReadW:
+		return false, nfs4OK
+	case lockWrite, lockWriteW:
+		return true, nfs4OK
+	}
+	return false, nfs4ErrInval
+}
+
+// lock implements LOCK
+func (c *compound) lock(r *xdrReader, w *xdrWriter) (status uint32) {
+	write, typeStatus := readLockType(r)
+	reclaim := r.bool()
+	start, end, rangeOK := lockRange(r.uint64(), r.uint64())
+	newOwner := r.bool()
+	var openSeqid, lockSeqid uint32
+	var openSid, lockSid stateid
+	var ownerName string
+	if newOwner {
+		openSeqid = r.uint32()
+		openSid = readStateid(r)
+		lockSeqid = r.uint32()
+		r.uint64() // clientid - taken from the open
+		ownerName = r.string()
+	} else {
+		lockSid = readStateid(r)
+		lockSeqid = r.uint32()
+	}
+	if _, status := c.statFile(); status != nfs4OK {
+		return status
+	}
+	switch {
+	case typeStatus != nfs4OK:
+		return typeStatus
+	case !rangeOK:
+		return nfs4ErrInval
+	case reclaim:
+		return nfs4ErrNoGrace
+	}
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var st *nfs4State
+	if newOwner {
+		var open *nfs4State
+		open, status = s.findState(openSid)
+		if status != nfs4OK {
+			return status
+		}
+		if open == nil || open.owner.lock {
+			return nfs4ErrBadStateID
+		}
+		if status = open.owner.checkSeqid(openSeqid); status != nfs4OK {
+			return status
+		}
+		defer func() { open.owner.useSeqid(openSeqid, status) }()
+		owner := s.owner(open.owner.client, ownerName, true)
+		st = s.ownerState(owner, open.path)
+		if st == nil {
+			st = &nfs4State{
+				other: s.newOther(),
+				owner: owner,
+				path:  open.path,
+				open:  open,
+			}
+			s.states[st.other] = st
+		}
+	} else {
+		st, status = s.findState(lockSid)
+		if status != nfs4OK {
+			return status
+		}
+		if st == nil || !st.owner.lock {
+			return nfs4ErrBadStateID
+		}
+		if status = st.owner.checkSeqid(lockSeqid); status != nfs4OK {
+			return status
+		}
+	}
+	defer func() { st.owner.useSeqid(lockSeqid, status) }()
+	if st.path != c.cur.path {
+		return nfs4ErrBadStateID
+	}
+	if write && st.open.access&shareAccessWrite == 0 || !write && st.open.access&shareAccessRead == 0 {
+		return nfs4ErrOpenMode
+	}
+	s.expireClients()
+	if conflict := s.locks.lock(st.path, st.owner, write, start, end); conflict != nil {
+		writeDenied(w, conflict)
+		return nfs4ErrDenied
+	}
+	st.seqid++
+	writeStateid(w, st.stateid())
+	return nfs4OK
+}
+
+// lockT implements LOCKT
+func (c *compound) lockT(r *xdrReader, w *xdrWriter) uint32 {
+	write, typeStatus := readLockType(r)
+	start, end, rangeOK := lockRange(r.uint64(), r.uint64())
+	clientID := r.uint64()
+	ownerName := r.string()
+	if _, status := c.statFile(); status != nfs4OK {
+		return status
+	}
+	switch {
+	case typeStatus != nfs4OK:
+		return typeStatus
+	case !rangeOK:
+		return nfs4ErrInval
+	}
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, status := s.renew(clientID); status != nfs4OK {
+		return status
+	}
+	s.expireClients()
+	// a nil owner conflicts with everyone
+	owner := s.owners[ownerKey(clientID, ownerName, true)]
+	if conflict := s.locks.test(c.cur.path, owner, write, start, end); conflict != nil {
+		writeDenied(w, conflict)
+		return nfs4ErrDenied
+	}
+	return nfs4OK
+}
+
+// lockU implements LOCKU
+func (c *compound) lockU(r *xdrReader, w *xdrWriter) (status uint32) {
+	r.uint32() // locktype
+	seqid := r.uint32()
+	sid := readStateid(r)
+	start, end, rangeOK := lockRange(r.uint64(), r.uint64())
+	if c.cur == nil {
+		return nfs4ErrNoFileHandle
+	}
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, status := s.findState(sid)
+	if status != nfs4OK {
+		return status
+	}
+	if st == nil || !st.owner.lock || st.path != c.cur.path {
+		return nfs4ErrBadStateID
+	}
+	if status = st.owner.checkSeqid(seqid); status != nfs4OK {
+		return status
+	}
+	defer func() { st.owner.useSeqid(seqid, status) }()
+	if !rangeOK {
+		return nfs4ErrInval
+	}
+	s.locks.unlock(st.path, st.owner, start, end)
+	st.seqid++
+	writeStateid(w, st.stateid())
+	return nfs4OK
+}
+
+// lookup implements LOOKUP
+func (c *compound) lookup(r *xdrReader, w *xdrWriter) uint32 {
+	name := r.string()
+	if _, status := c.statDir(); status != nfs4OK {
+		return status
+	}
+	if status := checkName(name); status != nfs4OK {
+		return status
+	}
+	p := path.Join(c.cur.path, name)
+	if _, err := c.s.vfs.Stat(p); err != nil {
+		return nfs4Status(err)
+	}
+	c.cur = c.s.makeFH(p)
+	return nfs4OK
+}
+
+// lookupP implements LOOKUPP
+func (c *compound) lookupP(r *xdrReader, w *xdrWriter) uint32 {
+	if _, status := c.statDir(); status != nfs4OK {
+		return status
+	}
+	if c.cur.path == "" {
+		return nfs4ErrNoEnt
+	}
+	parent := path.Dir(c.cur.path)
+	if parent == "." {
+		parent = ""
+	}
+	c.cur = c.s.makeFH(parent)
+	return nfs4OK
+}
+
+// verify implements VERIFY
+func (c *compound) verify(r *xdrReader, w *xdrWriter) uint32 {
+	return c.doVerify(r, nfs4OK, nfs4ErrNotSame)
+}
+
+// nverify implements NVERIFY
+func (c *compound) nverify(r *xdrReader, w *xdrWriter) uint32 {
+	return c.doVerify(r, nfs4ErrSame, nfs4OK)
+}
+
+// doVerify compares the attributes in r with the current filehandle
+// returning same or notSame
+func (c *compound) doVerify(r *xdrReader, same, notSame uint32) uint32 {
+	node, status := c.stat()
+	if status != nfs4OK {
+		// consume the arguments
+		r.bitmap()
+		r.opaque()
+		return status
+	}
+	equal, status := c.s.verifyAttrs(r, node, c.cur.fh)
+	switch {
+	case status != nfs4OK:
+		return status
+	case equal:
+		return same
+	}
+	return notSame
+}
+
+// open implements OPEN
+//
+// Files are opened in the VFS for the lifetime of the open so
+// writes are uploaded when the client closes the file. No
+// delegations are handed out and opens don't need confirming.
+func (c *compound) open(r *xdrReader, w *xdrWriter) (status uint32) {
+	seqid := r.uint32()
+	access := r.uint32() & shareAccessBoth
+	deny := r.uint32()
+	clientID := r.uint64()
+	ownerName := r.string()
+	create := r.uint32() == openCreate
+	var attrs *setAttrs
+	attrStatus := uint32(nfs4OK)
+	how := uint32(createUnchecked)
+	if create {
+		how = r.uint32()
+		switch how {
+		case createUnchecked, createGuarded:
+			attrs, attrStatus = decodeAttrs(r)
+		case createExclusive:
+			r.fixed(8) // verifier
+		}
+	}
+	var name string
+	claim := r.uint32()
+	switch claim {
+	case claimNull, claimDelegPrev:
+		name = r.string()
+	case claimPrevious:
+		r.uint32() // delegate_type
+	case claimDelegCur:
+		readStateid(r)
+		name = r.string()
+	}
+	if c.cur == nil {
+		return nfs4ErrNoFileHandle
+	}
+	switch claim {
+	case claimNull:
+	case claimPrevious:
+		return nfs4ErrNoGrace
+	default:
+		return nfs4ErrNotSupp
+	}
+	if _, status := c.statDir(); status != nfs4OK {
+		return status
+	}
+	if status := checkName(name); status != nfs4OK {
+		return status
+	}
+	switch {
+	case attrStatus != nfs4OK:
+		return attrStatus
+	case access == 0 || deny > shareAccessBoth || how > createExclusive:
+		return nfs4ErrInval
+	case c.s.readOnly() && (create || access&shareAccessWrite != 0):
+		return nfs4ErrROFS
+	}
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, status := s.renew(clientID)
+	if status != nfs4OK {
+		return status
+	}
+	owner := s.owner(client, ownerName, false)
+	if status = owner.checkSeqid(seqid); status != nfs4OK {
+		return status
+	}
+	defer func() { owner.useSeqid(seqid, status) }()
+
+	dir := c.cur.path
+	p := path.Join(dir, name)
+	node, err := s.vfs.Stat(p)
+	exists := err == nil
+	switch {
+	case err != nil && !errors.Is(err, vfs.ENOENT):
+		return nfs4Status(err)
+	case !exists && !create:
+		return nfs4ErrNoEnt
+	case exists && node.IsDir():
+		return nfs4ErrIsDir
+	case exists && node.Mode()&os.ModeSymlink != 0:
+		return nfs4ErrSymlink
+	case exists && create && how != createUnchecked:
+		return nfs4ErrExist
+	}
+	s.expireClients()
+	if s.shareConflict(p, owner, access, deny) {
+		return nfs4ErrShareDenied
+	}
+	before := c.dirChange(dir)
+	st := s.ownerState(owner, p)
+	if st == nil {
+		handleAccess, flags := access, 0
+		if !exists {
+			// the file must be opened for write to create it
+			handleAccess |= shareAccessWrite
+			flags = os.O_CREATE | os.O_EXCL
+		}
+		handle, err := s.openHandle(p, handleAccess, flags)
+		if err != nil {
+			return nfs4Status(err)
+		}
+		st = &nfs4State{
+			other:    s.newOther(),
+			owner:    owner,
+			path:     p,
+			handle:   handle,
+			writable: handleAccess&shareAccessWrite != 0,
+		}
+		s.states[st.other] = st
+	} else if access&shareAccessWrite != 0 && !st.writable {
+		// Upgrading a read only open to read/write
+		handle, err := s.openHandle(p, st.access|access, 0)
+		if err != nil {
+			return nfs4Status(err)
+		}
+		if err := st.handle.Close(); err != nil {
+			fs.Errorf(p, "NFSv4 failed to close file: %v", err)
+		}
+		st.handle = handle
+		st.writable = true
+	}
+	st.access |= access
+	st.deny |= deny
+	st.seqid++
+	if attrs != nil {
+		if err := attrs.apply(st.handle.Node(), st.handle); err != nil {
+			return nfs4Status(err)
+		}
+	}
+	writeStateid(w, st.stateid())
+	writeChangeInfo(w, before, c.dirChange(dir))
+	w.uint32(openResultLockTypePosix)
+	if attrs != nil {
+		w.bitmap(attrs.bitmap)
+	} else {
+		w.bitmap(nil)
+	}
+	w.uint32(openDelegateNone)
+	c.cur = s.makeFH(p)
+	return nfs4OK
+}
+
+// openAttr implements OPENATTR - named attributes aren't supported
+func (c *compound) openAttr(r *xdrReader, w *xdrWriter) uint32 {
+	r.bool() // createdir
+	return nfs4ErrNotSupp
+}
+
+// openConfirm implements OPEN_CONFIRM
+//
+// Opens are never marked as needing confirmation but clients may
+// still confirm them.
+func (c *compound) openConfirm(r *xdrReader, w *xdrWriter) (status uint32) {
+	sid := readStateid(r)
+	seqid := r.uint32()
+	if c.cur == nil {
+		return nfs4ErrNoFileHandle
+	}
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, status := s.findState(sid)
+	if status != nfs4OK {
+		return status
+	}
+	if st == nil || st.owner.lock {
+		return nfs4ErrBadStateID
+	}
+	if status = st.owner.checkSeqid(seqid); status != nfs4OK {
+		return status
+	}
+	defer func() { st.owner.useSeqid(seqid, status) }()
+	st.seqid++
+	writeStateid(w, st.stateid())
+	return nfs4OK
+}
+
+// openDowngrade implements OPEN_DOWNGRADE
+func (c *compound) openDowngrade(r *xdrReader, w *xdrWriter) (status uint32) {
+	sid := readStateid(r)
+	seqid := r.uint32()
+	access := r.uint32() & shareAccessBoth
+	deny := r.uint32()
+	if c.cur == nil {
+		return nfs4ErrNoFileHandle
+	}
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, status := s.findState(sid)
+	if status != nfs4OK {
+		return status
+	}
+	if st == nil || st.owner.lock {
+		return nfs4ErrBadStateID
+	}
+	if status = st.owner.checkSeqid(seqid); status != nfs4OK {
+		return status
+	}
+	defer func() { st.owner.useSeqid(seqid, status) }()
+	if access == 0 || access&^st.access != 0 || deny&^st.deny != 0 {
+		return nfs4ErrInval
+	}
+	st.access, st.deny = access, deny
+	st.seqid++
+	writeStateid(w, st.stateid())
+	return nfs4OK
+}
+
+// putFH implements PUTFH
+func (c *compound) putFH(r *xdrReader, w *xdrWriter) uint32 {
+	fh := r.opaque()
+	if r.err != nil {
+		return nfs4ErrBadXDR
+	}
+	if len(fh) == 0 || len(fh) > maxFHSize {
+		return nfs4ErrBadHandle
+	}
+	_, elements, err := c.s.h.FromHandle(fh)
+	if err != nil {
+		return nfs4ErrStale
+	}
+	c.cur = &nfs4FH{
+		fh:   bytes.Clone(fh),
+		path: path.Join(elements...),
+	}
+	return nfs4OK
+}
+
+// putRootFH implements PUTROOTFH and PUTPUBFH
+func (c *compound) putRootFH(r *xdrReader, w *xdrWriter) uint32 {
+	c.cur = c.s.makeFH("")
+	return nfs4OK
+}
+
+// openHandle returns the handle to use for I/O with sid on the
+// current filehandle and whether it should be closed after use
+func (c *compound) openHandle(sid stateid, write bool) (handle vfs.Handle, temporary bool, status uint32) {
+	s := c.s
+	s.mu.Lock()
+	st, status := s.findState(sid)
+	if st != nil && st.owner.lock {
+		st = st.open
+	}
+	switch {
+	case status != nfs4OK:
+	case st == nil:
+		// Special stateid so do I/O without an open
+		temporary = true
+	case st.path != c.cur.path:
+		status = nfs4ErrBadStateID
+	case write && st.access&shareAccessWrite == 0:
+		status = nfs4ErrOpenMode
+	default:
+		handle = st.handle
+	}
+	s.mu.Unlock()
+	if !temporary {
+		return handle, false, status
+	}
+	var access uint32 = shareAccessRead
+	if write {
+		access = shareAccessWrite
+	}
+	handle, err := s.openHandle(c.cur.path, access, 0)
+	if err != nil {
+		return nil, false, nfs4Status(err)
+	}
+	return handle, true, nfs4OK
+}
+
+// closeHandle closes handle if it was opened for a single I/O
+func closeHandle(handle vfs.Handle, temporary bool, status *uint32) {
+	if !temporary {
+		return
+	}
+	if err := handle.Close(); err != nil && *status == nfs4OK {
+		*status = nfs4Status(err)
+	}
+}
+
+// read implements READ
+func (c *compound) read(r *xdrReader, w *xdrWriter) (status uint32) {
+	sid := readStateid(r)
+	offset := r.uint64()
+	count := r.uint32()
+	node, status := c.statFile()
+	if status != nfs4OK {
+		return status
+	}
+	handle, temporary, status := c.openHandle(sid, false)
+	if status != nfs4OK {
+		return status
+	}
+	defer closeHandle(handle, temporary, &status)
+	buf := make([]byte, min(count, nfs4MaxIO))
+	n, err := handle.ReadAt(buf, int64(offset))
+	eof := errors.Is(err, io.EOF)
+	if err != nil && !eof {
+		return nfs4Status(err)
+	}
+	eof = eof || int64(offset)+int64(n) >= node.Size()
+	w.bool(eof)
+	w.opaque(buf[:n])
+	return nfs4OK
+}
+
+// readDir implements READDIR
+//
+// Cookies are the index of the entry plus 3 as 1 and 2 are reserved.
+func (c *compound) readDir(r *xdrReader, w *xdrWriter) uint32 {
+	cookie := r.uint64()
+	r.fixed(8) // cookieverf
+	r.uint32() // dircount
+	maxCount := r.uint32()
+	request := r.bitmap()
+	if _, status := c.statDir(); status != nfs4OK {
+		return status
+	}
+	dir := c.cur.path
+	items, err := c.s.vfs.ReadDir(dir)
+	if err != nil {
+		return nfs4Status(err)
+	}
+	start := 0
+	if cookie != 0 {
+		if cookie < 3 || cookie-2 > uint64(len(items)) {
+			return nfs4ErrBadCookie
+		}
+		start = int(cookie - 2)
+	}
+	var verifier [8]byte
+	w.fixed(verifier[:])
+	// verifier, end of entries and eof
+	size := len(w.b) + 16
+	written := 0
+	eof := true
+	for i := start; i < len(items); i++ {
+		node, ok := items[i].(vfs.Node)
+		if !ok {
+			continue
+		}
+		var entry xdrWriter
+		entry.bool(true)
+		entry.uint64(uint64(i) + 3)
+		entry.string(node.Name())
+		var fh []byte
+		p := path.Join(dir, node.Name())
+		if isSet(request, attrFilehandle) {
+			fh = c.s.makeFH(p).fh
+		}
+		c.s.encodeAttrs(&entry, node, fh, request)
+		if size+len(entry.b) > int(maxCount) {
+			eof = false
+			break
+		}
+		size += len(entry.b)
+		w.fixed(entry.b)
+		written++
+	}
+	if written == 0 && !eof {
+		w.b = w.b[:0]
+		return nfs4ErrTooSmall
+	}
+	w.bool(false)
+	w.bool(eof)
+	return nfs4OK
+}
+
+// readLink implements READLINK
+func (c *compound) readLink(r *xdrReader, w *xdrWriter) uint32 {
+	node, status := c.stat()
+	if status != nfs4OK {
+		return status
+	}
+	if fileType(node) != nfs4FileTypeSymlink {
+		return nfs4ErrInval
+	}
+	target, err := c.s.vfs.Readlink(c.cur.path)
+	if err != nil {
+		return nfs4Status(err)
+	}
+	w.string(target)
+	return nfs4OK
+}
+
+// remove implements REMOVE
+func (c *compound) remove(r *xdrReader, w *xdrWriter) uint32 {
+	name := r.string()
+	if _, status := c.statDir(); status != nfs4OK {
+		return status
+	}
+	if status := checkName(name); status != nfs4OK {
+		return status
+	}
+	if c.s.readOnly() {
+		return nfs4ErrROFS
+	}
+	dir := c.cur.path
+	p := path.Join(dir, name)
+	before := c.dirChange(dir)
+	if err := c.s.vfs.Remove(p); err != nil {
+		return nfs4Status(err)
+	}
+	c.s.invalidate(p)
+	writeChangeInfo(w, before, c.dirChange(dir))
+	return nfs4OK
+}
+
+// rename implements RENAME from the saved to the current filehandle
+func (c *compound) rename(r *xdrReader, w *xdrWriter) uint32 {
+	oldName := r.string()
+	newName := r.string()
+	if c.saved == nil {
+		return nfs4ErrNoFileHandle
+	}
+	if _, status := c.statDir(); status != nfs4OK {
+		return status
+	}
+	srcNode, err := c.s.vfs.Stat(c.saved.path)
+	switch {
+	case err != nil:
+		return nfs4ErrStale
+	case !srcNode.IsDir():
+		return nfs4ErrNotDir
+	}
+	for _, name := range []string{oldName, newName} {
+		if status := checkName(name); status != nfs4OK {
+			return status
+		}
+	}
+	if c.s.readOnly() {
+		return nfs4ErrROFS
+	}
+	srcDir, dstDir := c.saved.path, c.cur.path
+	src, dst := path.Join(srcDir, oldName), path.Join(dstDir, newName)
+	srcBefore, dstBefore := c.dirChange(srcDir), c.dirChange(dstDir)
+	if err := c.s.vfs.Rename(src, dst); err != nil {
+		return nfs4Status(err)
+	}
+	c.s.invalidate(src)
+	c.s.renamed(src, dst)
+	writeChangeInfo(w, srcBefore, c.dirChange(srcDir))
+	writeChangeInfo(w, dstBefore, c.dirChange(dstDir))
+	return nfs4OK
+}
+
+// renew implements RENEW
+func (c *compound) renew(r *xdrReader, w *xdrWriter) uint32 {
+	clientID := r.uint64()
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	_, status := c.s.renew(clientID)
+	return status
+}
+
+// restoreFH implements RESTOREFH
+func (c *compound) restoreFH(r *xdrReader, w *xdrWriter) uint32 {
+	if c.saved == nil {
+		return nfs4ErrRestoreFH
+	}
+	c.cur = c.saved
+	return nfs4OK
+}
+
+// saveFH implements SAVEFH
+func (c *compound) saveFH(r *xdrReader, w *xdrWriter) uint32 {
+	if c.cur == nil {
+		return nfs4ErrNoFileHandle
+	}
+	c.saved = c.cur
+	return nfs4OK
+}
+
+// secInfo implements SECINFO
+//
+// There is no authentication so AUTH_SYS and AUTH_NONE are offered.
+func (c *compound) secInfo(r *xdrReader, w *xdrWriter) uint32 {
+	name := r.string()
+	if _, status := c.statDir(); status != nfs4OK {
+		return status
+	}
+	if status := checkName(name); status != nfs4OK {
+		return status
+	}
+	if _, err := c.s.vfs.Stat(path.Join(c.cur.path, name)); err != nil {
+		return nfs4Status(err)
+	}
+	w.uint32(2)
+	w.uint32(authSys)
+	w.uint32(authNone)
+	c.cur = nil
+	return nfs4OK
+}
+
+// setattr implements SETATTR
+//
+// The attributes set are returned whether it succeeds or not.
+func (c *compound) setattr(r *xdrReader, w *xdrWriter) uint32 {
+	sid := readStateid(r)
+	attrs, status := decodeAttrs(r)
+	if status == nfs4OK {
+		status = c.doSetattr(sid, attrs)
+	}
+	if status == nfs4OK {
+		w.bitmap(attrs.bitmap)
+	} else {
+		w.bitmap(nil)
+	}
+	return status
+}
+
+// doSetattr sets attrs on the current filehandle
+func (c *compound) doSetattr(sid stateid, attrs *setAttrs) (status uint32) {
+	node, status := c.stat()
+	if status != nfs4OK {
+		return status
+	}
+	if c.s.readOnly() {
+		return nfs4ErrROFS
+	}
+	var handle vfs.Handle
+	if attrs.size != nil {
+		if fileType(node) != nfs4FileTypeRegular {
+			return nfs4ErrInval
+		}
+		var temporary bool
+		handle, temporary, status = c.openHandle(sid, true)
+		if status != nfs4OK {
+			return status
+		}
+		defer closeHandle(handle, temporary, &status)
+	}
+	return nfs4Status(attrs.apply(node, handle))
+}
+
+// setClientID implements SETCLIENTID
+func (c *compound) setClientID(r *xdrReader, w *xdrWriter) uint32 {
+	var verifier [8]byte
+	copy(verifier[:], r.fixed(8))
+	name := r.string()
+	// callback - no delegations are handed out so it isn't used
+	r.uint32() // cb_program
+	r.string() // r_netid
+	r.string() // r_addr
+	r.uint32() // callback_ident
+	if r.err != nil {
+		return nfs4ErrBadXDR
+	}
+	id, confirm := c.s.setClientID(name, verifier)
+	w.uint64(id)
+	w.fixed(confirm[:])
+	return nfs4OK
+}
+
+// setClientIDConfirm implements SETCLIENTID_CONFIRM
+func (c *compound) setClientIDConfirm(r *xdrReader, w *xdrWriter) uint32 {
+	clientID := r.uint64()
+	var confirm [8]byte
+	copy(confirm[:], r.fixed(8))
+	if r.err != nil {
+		return nfs4ErrBadXDR
+	}
+	return c.s.confirmClientID(clientID, confirm)
+}
+
+// write implements WRITE
+//
+// The data is written to the VFS which uploads it when the file is
+// closed, so it is reported as stable.
+func (c *compound) write(r *xdrReader, w *xdrWriter) (status uint32) {
+	sid := readStateid(r)
+	offset := r.uint64()
+	r.uint32() // stable
+	data := r.opaque()
+	if _, status := c.statFile(); status != nfs4OK {
+		return status
+	}
+	if c.s.readOnly() {
+		return nfs4ErrROFS
+	}
+	handle, temporary, status := c.openHandle(sid, true)
+	if status != nfs4OK {
+		return status
+	}
+	defer closeHandle(handle, temporary, &status)
+	n, err := handle.WriteAt(data, int64(offset))
+	if err != nil {
+		return nfs4Status(err)
+	}
+	w.uint32(uint32(n))
+	w.uint32(fileSync)
+	w.fixed(c.s.boot[:])
+	return nfs4OK
+}
+
+// releaseLockOwner implements RELEASE_LOCKOWNER
+func (c *compound) releaseLockOwner(r *xdrReader, w *xdrWriter) uint32 {
+	clientID := r.uint64()
+	ownerName := r.string()
+	if r.err != nil {
+		return nfs4ErrBadXDR
+	}
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := ownerKey(clientID, ownerName, true)
+	owner := s.owners[key]
+	if owner == nil {
+		return nfs4OK
+	}
+	if s.locks.held("", owner) {
+		return nfs4ErrLocksHeld
+	}
+	for _, st := range s.states {
+		if st.owner == owner {
+			s.dropState(st)
+		}
+	}
+	delete(s.owners, key)
+	return nfs4OK
+}
@@ -0,0 +1,248 @@
+//go:build unix
+
+package nfs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// ONC RPC (RFC 5531) constants used by the NFSv4 server
+const (
+	rpcVersion     = 2
+	rpcCall        = 0
+	rpcReply       = 1
+	rpcMsgAccepted = 0
+	rpcMsgDenied   = 1
+
+	rpcSuccess      = 0
+	rpcProgUnavail  = 1
+	rpcProgMismatch = 2
+	rpcProcUnavail  = 3
+	rpcGarbageArgs  = 4
+	rpcMismatch     = 0
+
+	nfsProgram = 100003
+	nfsV4      = 4
+
+	nfsProcNull     = 0
+	nfsProcCompound = 1
+
+	// largest RPC record accepted
+	rpcMaxRecord = nfs4MaxIO + 64*1024
+)
+
+// versionListener is a net.Listener which hands out the connections
+// which aren't NFSv4 so they can be served by go-nfs
+//
+// The connections from the underlying listener are sorted by looking
+// at the program and version of the first RPC call on them.
+type versionListener struct {
+	net.Listener
+	serveV4 func(net.Conn)
+	conns   chan net.Conn
+	done    chan struct{}
+	err     error
+}
+
+// newVersionListener starts sorting the connections of l, passing
+// the NFSv4 ones to serveV4
+func newVersionListener(l net.Listener, serveV4 func(net.Conn)) *versionListener {
+	vl := &versionListener{
+		Listener: l,
+		serveV4:  serveV4,
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+	}
+	go vl.acceptLoop()
+	return vl
+}
+
+// acceptLoop accepts connections from the underlying listener
+func (vl *versionListener) acceptLoop() {
+	defer close(vl.done)
+	for {
+		conn, err := vl.Listener.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(5 * time.Millisecond)
+				continue
+			}
+			vl.err = err
+			return
+		}
+		go vl.sort(conn)
+	}
+}
+
+// sort peeks at the first RPC call on conn and dispatches it
+func (vl *versionListener) sort(conn net.Conn) {
+	br := bufio.NewReader(conn)
+	// record mark, xid, msg_type, rpcvers, prog, vers
+	_ = conn.SetReadDeadline(time.Now().Add(time.Minute))
+	hdr, err := br.Peek(24)
+	_ = conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		fs.Debugf("nfs", "Closing connection from %v: %v", conn.RemoteAddr(), err)
+		_ = conn.Close()
+		return
+	}
+	conn = &bufferedConn{Conn: conn, r: br}
+	prog := binary.BigEndian.Uint32(hdr[16:])
+	vers := binary.BigEndian.Uint32(hdr[20:])
+	if prog == nfsProgram && vers == nfsV4 {
+		vl.serveV4(conn)
+		return
+	}
+	select {
+	case vl.conns <- conn:
+	case <-vl.done:
+		_ = conn.Close()
+	}
+}
+
+// Accept returns the next connection which isn't NFSv4
+func (vl *versionListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-vl.conns:
+		return conn, nil
+	case <-vl.done:
+		return nil, vl.err
+	}
+}
+
+// bufferedConn is a net.Conn which reads through a bufio.Reader
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// Read reads from the buffer first
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// rpcConn is an NFSv4 connection
+type rpcConn struct {
+	s    *nfs4Server
+	conn net.Conn
+	wmu  sync.Mutex // held while writing replies
+}
+
+// readRecord reads an RPC record made of one or more fragments
+func readRecord(r io.Reader) ([]byte, error) {
+	var record []byte
+	var hdr [4]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, err
+		}
+		mark := binary.BigEndian.Uint32(hdr[:])
+		size := int(mark & 0x7fffffff)
+		if len(record)+size > rpcMaxRecord {
+			return nil, fmt.Errorf("RPC record too big: %d bytes", len(record)+size)
+		}
+		start := len(record)
+		record = append(record, make([]byte, size)...)
+		if _, err := io.ReadFull(r, record[start:]); err != nil {
+			return nil, err
+		}
+		if mark&0x80000000 != 0 {
+			return record, nil
+		}
+	}
+}
+
+// serve reads calls from the connection until it is closed
+//
+// The calls are run concurrently and the replies written as they
+// complete as the clients match them up by xid.
+func (c *rpcConn) serve() {
+	defer func() {
+		_ = c.conn.Close()
+	}()
+	for {
+		record, err := readRecord(c.conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				fs.Debugf("nfs", "NFSv4 connection from %v: %v", c.conn.RemoteAddr(), err)
+			}
+			return
+		}
+		go c.call(record)
+	}
+}
+
+// call decodes and runs an RPC call and writes the reply
+func (c *rpcConn) call(record []byte) {
+	r := &xdrReader{b: record}
+	xid := r.uint32()
+	msgType := r.uint32()
+	if r.err != nil || msgType != rpcCall {
+		return
+	}
+	w := &xdrWriter{}
+	w.uint32(0) // record mark, filled in later
+	w.uint32(xid)
+	w.uint32(rpcReply)
+	rpcvers := r.uint32()
+	prog := r.uint32()
+	vers := r.uint32()
+	proc := r.uint32()
+	// credentials and verifier - there is no authentication
+	r.uint32()
+	r.opaque()
+	r.uint32()
+	r.opaque()
+	switch {
+	case r.err != nil:
+		return
+	case rpcvers != rpcVersion:
+		w.uint32(rpcMsgDenied)
+		w.uint32(rpcMismatch)
+		w.uint32(rpcVersion)
+		w.uint32(rpcVersion)
+	case prog != nfsProgram:
+		acceptedReply(w, rpcProgUnavail)
+	case vers != nfsV4:
+		acceptedReply(w, rpcProgMismatch)
+		w.uint32(nfsV4)
+		w.uint32(nfsV4)
+	case proc == nfsProcNull:
+		acceptedReply(w, rpcSuccess)
+	case proc == nfsProcCompound:
+		mark := len(w.b)
+		acceptedReply(w, rpcSuccess)
+		if !c.s.compound(r, w) {
+			w.b = w.b[:mark]
+			acceptedReply(w, rpcGarbageArgs)
+		}
+	default:
+		acceptedReply(w, rpcProcUnavail)
+	}
+	binary.BigEndian.PutUint32(w.b, 0x80000000|uint32(len(w.b)-4))
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if _, err := c.conn.Write(w.b); err != nil {
+		fs.Debugf("nfs", "Failed to write NFSv4 reply to %v: %v", c.conn.RemoteAddr(), err)
+		_ = c.conn.Close()
+	}
+}
+
+// acceptedReply writes the header of an accepted reply
+func acceptedReply(w *xdrWriter, stat uint32) {
+	w.uint32(rpcMsgAccepted)
+	// AUTH_NONE verifier
+	w.uint32(0)
+	w.uint32(0)
+	w.uint32(stat)
+}
@@ -0,0 +1,419 @@
+//go:build unix
+
+package nfs
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+)
+
+// NFSv4 status codes (RFC 7530)
+const (
+	nfs4OK               = 0
+	nfs4ErrPerm          = 1
+	nfs4ErrNoEnt         = 2
+	nfs4ErrIO            = 5
+	nfs4ErrExist         = 17
+	nfs4ErrNotDir        = 20
+	nfs4ErrIsDir         = 21
+	nfs4ErrInval         = 22
+	nfs4ErrROFS          = 30
+	nfs4ErrNameTooLong   = 63
+	nfs4ErrNotEmpty      = 66
+	nfs4ErrStale         = 70
+	nfs4ErrBadHandle     = 10001
+	nfs4ErrBadCookie     = 10003
+	nfs4ErrNotSupp       = 10004
+	nfs4ErrTooSmall      = 10005
+	nfs4ErrBadType       = 10007
+	nfs4ErrSame          = 10009
+	nfs4ErrDenied        = 10010
+	nfs4ErrShareDenied   = 10015
+	nfs4ErrResource      = 10018
+	nfs4ErrMoved         = 10019
+	nfs4ErrNoFileHandle  = 10020
+	nfs4ErrMinorVers     = 10021
+	nfs4ErrStaleClientID = 10022
+	nfs4ErrStaleStateID  = 10023
+	nfs4ErrOldStateID    = 10024
+	nfs4ErrBadStateID    = 10025
+	nfs4ErrBadSeqID      = 10026
+	nfs4ErrNotSame       = 10027
+	nfs4ErrSymlink       = 10029
+	nfs4ErrRestoreFH     = 10030
+	nfs4ErrAttrNotSupp   = 10032
+	nfs4ErrNoGrace       = 10033
+	nfs4ErrBadXDR        = 10036
+	nfs4ErrLocksHeld     = 10037
+	nfs4ErrOpenMode      = 10038
+	nfs4ErrBadName       = 10041
+	nfs4ErrOpIllegal     = 10044
+)
+
+// Limits of the NFSv4 server
+const (
+	nfs4LeaseTime = 90 * time.Second // how long clients have to renew their state
+	nfs4MaxIO     = 1024 * 1024      // largest READ or WRITE
+	nfs4MaxName   = 255              // longest file name
+)
+
+// Share reservations
+const (
+	shareAccessRead  = 1
+	shareAccessWrite = 2
+	shareAccessBoth  = 3
+)
+
+// nfs4Client is a client which has called SETCLIENTID
+type nfs4Client struct {
+	id        uint64
+	name      string  // the client's id string
+	verifier  [8]byte // changes when the client reboots
+	confirm   [8]byte // to pass to SETCLIENTID_CONFIRM
+	confirmed bool
+	renewed   time.Time
+}
+
+// nfs4Owner is an open owner or a lock owner
+type nfs4Owner struct {
+	client *nfs4Client
+	name   string
+	seqid  uint32 // last seqid used
+	used   bool   // set once a seqid has been used
+	lock   bool   // set if this is a lock owner
+}
+
+// nfs4State is the state of an open file or the locks of a lock
+// owner on a file, identified to the client by a stateid
+type nfs4State struct {
+	other    [12]byte
+	seqid    uint32
+	owner    *nfs4Owner
+	path     string
+	access   uint32     // share access of an open
+	deny     uint32     // share deny of an open
+	handle   vfs.Handle // open file for an open
+	writable bool       // set if handle is open for writing
+	open     *nfs4State // the open a lock state came from
+}
+
+// stateid returns the current stateid4
+func (st *nfs4State) stateid() stateid {
+	return stateid{seqid: st.seqid, other: st.other}
+}
+
+// stateid is an NFSv4 stateid4
+type stateid struct {
+	seqid uint32
+	other [12]byte
+}
+
+// special returns true if this is the anonymous or the READ bypass
+// stateid which can be used without opening the file
+func (sid stateid) special() bool {
+	var zero, ones [12]byte
+	for i := range ones {
+		ones[i] = 0xff
+	}
+	return (sid.seqid == 0 && sid.other == zero) || (sid.seqid == 0xffffffff && sid.other == ones)
+}
+
+// nfs4Server implements NFSv4.0 on top of the Handler
+type nfs4Server struct {
+	h       *Handler
+	vfs     *vfs.VFS
+	boot    [8]byte // changes each time the server is started
+	mu      sync.Mutex
+	nextID  uint64                  // last clientid or state counter used
+	clients map[uint64]*nfs4Client  // clients by clientid
+	owners  map[string]*nfs4Owner   // open and lock owners by key
+	states  map[[12]byte]*nfs4State // states by stateid other
+	locks   *lockManager            // byte range locks
+}
+
+// newNFS4Server makes an NFSv4 server for h
+func newNFS4Server(h *Handler) *nfs4Server {
+	s := &nfs4Server{
+		h:       h,
+		vfs:     h.vfs,
+		clients: make(map[uint64]*nfs4Client),
+		owners:  make(map[string]*nfs4Owner),
+		states:  make(map[[12]byte]*nfs4State),
+		locks:   newLockManager(),
+	}
+	_, _ = rand.Read(s.boot[:])
+	s.nextID = uint64(time.Now().Unix()) << 32
+	return s
+}
+
+// serveConn serves the NFSv4 connection conn
+func (s *nfs4Server) serveConn(conn net.Conn) {
+	fs.Debugf("nfs", "NFSv4 connection from %v", conn.RemoteAddr())
+	c := &rpcConn{s: s, conn: conn}
+	c.serve()
+}
+
+// newID returns a new unique id - call with the lock held
+func (s *nfs4Server) newID() uint64 {
+	s.nextID++
+	return s.nextID
+}
+
+// newOther returns a new stateid other - call with the lock held
+//
+// The first 4 bytes identify this server instance so stateids from
+// before a restart can be told apart from bad ones.
+func (s *nfs4Server) newOther() (other [12]byte) {
+	copy(other[:4], s.boot[:4])
+	binary.BigEndian.PutUint64(other[4:], s.newID())
+	return other
+}
+
+// setClientID implements SETCLIENTID
+func (s *nfs4Server) setClientID(name string, verifier [8]byte) (id uint64, confirm [8]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireClients()
+	_, _ = rand.Read(confirm[:])
+	for _, c := range s.clients {
+		if c.name != name {
+			continue
+		}
+		if c.confirmed && c.verifier == verifier {
+			// Same client instance updating its callback
+			c.confirm = confirm
+			return c.id, confirm
+		}
+		if !c.confirmed {
+			delete(s.clients, c.id)
+		}
+	}
+	c := &nfs4Client{
+		id:       s.newID(),
+		name:     name,
+		verifier: verifier,
+		confirm:  confirm,
+		renewed:  time.Now(),
+	}
+	s.clients[c.id] = c
+	return c.id, confirm
+}
+
+// confirmClientID implements SETCLIENTID_CONFIRM
+func (s *nfs4Server) confirmClientID(id uint64, confirm [8]byte) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.clients[id]
+	if c == nil || c.confirm != confirm {
+		return nfs4ErrStaleClientID
+	}
+	if !c.confirmed {
+		// The client has rebooted so drop the state of its old instance
+		for _, old := range s.clients {
+			if old != c && old.name == c.name {
+				s.dropClient(old)
+			}
+		}
+		c.confirmed = true
+		fs.Debugf("nfs", "NFSv4 client %q confirmed as %016x", c.name, c.id)
+	}
+	c.renewed = time.Now()
+	return nfs4OK
+}
+
+// renew implements RENEW - call with the lock held
+func (s *nfs4Server) renew(id uint64) (*nfs4Client, uint32) {
+	c := s.clients[id]
+	if c == nil || !c.confirmed {
+		return nil, nfs4ErrStaleClientID
+	}
+	c.renewed = time.Now()
+	return c, nfs4OK
+}
+
+// expireClients drops the clients which haven't renewed their lease
+// so their locks and opens don't block others - call with the lock held
+func (s *nfs4Server) expireClients() {
+	cutoff := time.Now().Add(-2 * nfs4LeaseTime)
+	for _, c := range s.clients {
+		if c.renewed.Before(cutoff) {
+			fs.Debugf("nfs", "NFSv4 client %q lease expired", c.name)
+			s.dropClient(c)
+		}
+	}
+}
+
+// dropClient removes a client and all its state - call with the lock held
+func (s *nfs4Server) dropClient(c *nfs4Client) {
+	for _, st := range s.states {
+		if st.owner.client == c {
+			s.dropState(st)
+		}
+	}
+	for key, o := range s.owners {
+		if o.client == c {
+			s.locks.release(o)
+			delete(s.owners, key)
+		}
+	}
+	delete(s.clients, c.id)
+}
+
+// dropState removes a state closing its file - call with the lock held
+func (s *nfs4Server) dropState(st *nfs4State) {
+	delete(s.states, st.other)
+	if st.owner.lock {
+		s.locks.unlock(st.path, st.owner, 0, math.MaxUint64)
+	}
+	if st.handle != nil {
+		if err := st.handle.Close(); err != nil {
+			fs.Errorf(st.path, "NFSv4 failed to close file: %v", err)
+		}
+		st.handle = nil
+	}
+}
+
+// ownerKey returns the key of an open or lock owner in the owners map
+func ownerKey(clientID uint64, name string, lock bool) string {
+	kind := "O"
+	if lock {
+		kind = "L"
+	}
+	return kind + string(binary.BigEndian.AppendUint64(nil, clientID)) + name
+}
+
+// owner finds or makes the owner for clientid and name - call with
+// the lock held
+func (s *nfs4Server) owner(c *nfs4Client, name string, lock bool) *nfs4Owner {
+	key := ownerKey(c.id, name, lock)
+	o := s.owners[key]
+	if o == nil {
+		o = &nfs4Owner{client: c, name: name, lock: lock}
+		s.owners[key] = o
+	}
+	return o
+}
+
+// checkSeqid checks the seqid of a request by owner
+//
+// New owners accept any seqid, after that it must go up by one.
+func (o *nfs4Owner) checkSeqid(seqid uint32) uint32 {
+	if o.used && seqid != o.seqid+1 {
+		return nfs4ErrBadSeqID
+	}
+	return nfs4OK
+}
+
+// useSeqid records seqid as used unless status says the request
+// didn't count
+func (o *nfs4Owner) useSeqid(seqid uint32, status uint32) {
+	switch status {
+	case nfs4ErrStaleClientID, nfs4ErrStaleStateID, nfs4ErrBadStateID,
+		nfs4ErrBadSeqID, nfs4ErrBadXDR, nfs4ErrResource,
+		nfs4ErrNoFileHandle, nfs4ErrMoved:
+		return
+	}
+	o.seqid = seqid
+	o.used = true
+}
+
+// findState looks up sid - call with the lock held
+//
+// It returns nil and nfs4OK for the special stateids.
+func (s *nfs4Server) findState(sid stateid) (*nfs4State, uint32) {
+	if sid.special() {
+		return nil, nfs4OK
+	}
+	st := s.states[sid.other]
+	if st == nil {
+		if [4]byte(sid.other[:4]) != [4]byte(s.boot[:4]) {
+			return nil, nfs4ErrStaleStateID
+		}
+		return nil, nfs4ErrBadStateID
+	}
+	switch {
+	case sid.seqid > st.seqid:
+		return nil, nfs4ErrBadStateID
+	case sid.seqid < st.seqid:
+		return nil, nfs4ErrOldStateID
+	}
+	st.owner.client.renewed = time.Now()
+	return st, nfs4OK
+}
+
+// openFlags returns the flags to open a file with for share access
+func (s *nfs4Server) openFlags(access uint32) int {
+	if access&shareAccessWrite == 0 {
+		return os.O_RDONLY
+	}
+	return os.O_RDWR
+}
+
+// openHandle opens path for share access
+//
+// Some cache modes can't open files read/write so fall back to
+// write only.
+func (s *nfs4Server) openHandle(path string, access uint32, flags int) (vfs.Handle, error) {
+	h, err := s.vfs.OpenFile(path, s.openFlags(access)|flags, 0666)
+	if err != nil && access&shareAccessWrite != 0 && err == vfs.EPERM {
+		h, err = s.vfs.OpenFile(path, os.O_WRONLY|flags, 0666)
+	}
+	return h, err
+}
+
+// shareConflict returns true if an open of path with access and deny
+// by owner conflicts with another open - call with the lock held
+func (s *nfs4Server) shareConflict(path string, owner *nfs4Owner, access, deny uint32) bool {
+	for _, st := range s.states {
+		if st.owner.lock || st.owner == owner || st.path != path {
+			continue
+		}
+		if access&st.deny != 0 || deny&st.access != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ownerState returns the state of owner on path if any - call with
+// the lock held
+func (s *nfs4Server) ownerState(owner *nfs4Owner, path string) *nfs4State {
+	for _, st := range s.states {
+		if st.owner == owner && st.path == path {
+			return st
+		}
+	}
+	return nil
+}
+
+// renamed updates the state after oldPath was renamed to newPath
+func (s *nfs4Server) renamed(oldPath, newPath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range s.states {
+		if p, ok := renamePath(st.path, oldPath, newPath); ok {
+			st.path = p
+		}
+	}
+	s.locks.rename(oldPath, newPath)
+}
+
+// renamePath returns the new name of p if it is oldPath or below it
+func renamePath(p, oldPath, newPath string) (string, bool) {
+	if p == oldPath {
+		return newPath, true
+	}
+	if rest, ok := strings.CutPrefix(p, oldPath+"/"); ok && oldPath != "" {
+		return newPath + "/" + rest, true
+	}
+	return "", false
+}
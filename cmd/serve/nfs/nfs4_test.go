@@ -0,0 +1,507 @@
+//go:build unix
+
+package nfs
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"net"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClient is a minimal NFSv4 client
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	xid  uint32
+	id   uint64
+}
+
+// newTestNFS4Server starts a server on a local directory returning
+// its address
+func newTestNFS4Server(t *testing.T) string {
+	ctx := context.Background()
+	config.SetCacheDir(t.TempDir())
+	f, err := fs.NewFs(ctx, t.TempDir())
+	require.NoError(t, err)
+	vfsOpt := vfscommon.Opt
+	vfsOpt.CacheMode = vfscommon.CacheModeWrites
+	VFS := vfs.New(f, &vfsOpt)
+	t.Cleanup(VFS.Shutdown)
+	opt := Opt
+	opt.ListenAddr = "localhost:0"
+	s, err := NewServer(ctx, VFS, &opt)
+	require.NoError(t, err)
+	go func() {
+		_ = s.Serve()
+	}()
+	t.Cleanup(func() {
+		_ = s.Shutdown()
+	})
+	return s.Addr().String()
+}
+
+// newTestClient connects to the server at addr
+func newTestClient(t *testing.T, addr string) *testClient {
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return &testClient{t: t, conn: conn}
+}
+
+// call makes an RPC call returning the accept_stat and the results
+func (c *testClient) call(prog, vers, proc uint32, args []byte) (uint32, *xdrReader) {
+	c.xid++
+	w := &xdrWriter{}
+	w.uint32(0)
+	w.uint32(c.xid)
+	w.uint32(rpcCall)
+	w.uint32(rpcVersion)
+	w.uint32(prog)
+	w.uint32(vers)
+	w.uint32(proc)
+	w.uint32(authSys)
+	var cred xdrWriter
+	cred.uint32(0)             // stamp
+	cred.string("test")        // machine name
+	cred.uint32(0)             // uid
+	cred.uint32(0)             // gid
+	cred.uint32(0)             // gids
+	w.opaque(cred.b)           // credentials
+	w.uint32(0)                // verifier
+	w.uint32(0)                //
+	w.b = append(w.b, args...) // arguments
+	binary.BigEndian.PutUint32(w.b, 0x80000000|uint32(len(w.b)-4))
+	_, err := c.conn.Write(w.b)
+	require.NoError(c.t, err)
+	record, err := readRecord(c.conn)
+	require.NoError(c.t, err)
+	r := &xdrReader{b: record}
+	assert.Equal(c.t, c.xid, r.uint32())
+	assert.Equal(c.t, uint32(rpcReply), r.uint32())
+	require.Equal(c.t, uint32(rpcMsgAccepted), r.uint32())
+	r.uint32() // verifier
+	r.opaque()
+	return r.uint32(), r
+}
+
+// compound runs a COMPOUND made from the ops returning its status and
+// the results positioned at the first op
+func (c *testClient) compound(minorVersion uint32, ops ...func(w *xdrWriter)) (uint32, *xdrReader) {
+	w := &xdrWriter{}
+	w.string("test")
+	w.uint32(minorVersion)
+	w.uint32(uint32(len(ops)))
+	for _, op := range ops {
+		op(w)
+	}
+	stat, r := c.call(nfsProgram, nfsV4, nfsProcCompound, w.b)
+	require.Equal(c.t, uint32(rpcSuccess), stat)
+	status := r.uint32()
+	assert.Equal(c.t, "test", r.string())
+	r.uint32() // number of results
+	require.NoError(c.t, r.err)
+	return status, r
+}
+
+// result reads the op and status of the next result
+func (c *testClient) result(r *xdrReader, op uint32) uint32 {
+	assert.Equal(c.t, op, r.uint32())
+	return r.uint32()
+}
+
+// setClientID sets up the client id
+func (c *testClient) setClientID(name string) {
+	status, r := c.compound(0, func(w *xdrWriter) {
+		w.uint32(opSetClientID)
+		w.fixed([]byte("verifier"))
+		w.string(name)
+		w.uint32(0x40000000)
+		w.string("tcp")
+		w.string("127.0.0.1.0.0")
+		w.uint32(1)
+	})
+	require.Equal(c.t, uint32(nfs4OK), status)
+	require.Equal(c.t, uint32(nfs4OK), c.result(r, opSetClientID))
+	c.id = r.uint64()
+	confirm := r.fixed(8)
+	status, _ = c.compound(0, func(w *xdrWriter) {
+		w.uint32(opSetClientIDConfirm)
+		w.uint64(c.id)
+		w.fixed(confirm)
+	})
+	require.Equal(c.t, uint32(nfs4OK), status)
+}
+
+func putRootFH(w *xdrWriter) {
+	w.uint32(opPutRootFH)
+}
+
+func getFH(w *xdrWriter) {
+	w.uint32(opGetFH)
+}
+
+func putFH(fh []byte) func(w *xdrWriter) {
+	return func(w *xdrWriter) {
+		w.uint32(opPutFH)
+		w.opaque(fh)
+	}
+}
+
+func lookup(name string) func(w *xdrWriter) {
+	return func(w *xdrWriter) {
+		w.uint32(opLookup)
+		w.string(name)
+	}
+}
+
+func getattr(attrs ...int) func(w *xdrWriter) {
+	return func(w *xdrWriter) {
+		w.uint32(opGetattr)
+		w.bitmap(makeBitmap(attrs...))
+	}
+}
+
+// open opens name for the open owner with access creating it if
+// create is set
+func (c *testClient) open(seqid uint32, owner string, access uint32, create bool, name string) func(w *xdrWriter) {
+	return func(w *xdrWriter) {
+		w.uint32(opOpen)
+		w.uint32(seqid)
+		w.uint32(access)
+		w.uint32(0) // deny
+		w.uint64(c.id)
+		w.string(owner)
+		if create {
+			w.uint32(openCreate)
+			w.uint32(createUnchecked)
+			w.bitmap(nil)
+			w.opaque(nil)
+		} else {
+			w.uint32(0)
+		}
+		w.uint32(claimNull)
+		w.string(name)
+	}
+}
+
+// openFile opens name returning its file handle and stateid
+func (c *testClient) openFile(seqid uint32, owner string, access uint32, create bool, name string) ([]byte, stateid) {
+	status, r := c.compound(0, putRootFH, c.open(seqid, owner, access, create, name), getFH)
+	require.Equal(c.t, uint32(nfs4OK), status)
+	c.result(r, opPutRootFH)
+	require.Equal(c.t, uint32(nfs4OK), c.result(r, opOpen))
+	sid := readStateid(r)
+	r.fixed(20) // change_info
+	assert.Equal(c.t, uint32(openResultLockTypePosix), r.uint32())
+	r.bitmap()
+	assert.Equal(c.t, uint32(openDelegateNone), r.uint32())
+	require.Equal(c.t, uint32(nfs4OK), c.result(r, opGetFH))
+	return r.opaque(), sid
+}
+
+// lock locks a range of the file fh with a new lock owner
+func (c *testClient) lock(fh []byte, write bool, offset, length uint64, openSeqid uint32, openSid stateid, owner string) (uint32, *xdrReader) {
+	lockType := uint32(lockRead)
+	if write {
+		lockType = lockWrite
+	}
+	status, r := c.compound(0, putFH(fh), func(w *xdrWriter) {
+		w.uint32(opLock)
+		w.uint32(lockType)
+		w.bool(false) // reclaim
+		w.uint64(offset)
+		w.uint64(length)
+		w.bool(true) // new lock owner
+		w.uint32(openSeqid)
+		writeStateid(w, openSid)
+		w.uint32(0) // lock seqid
+		w.uint64(c.id)
+		w.string(owner)
+	})
+	c.result(r, opPutFH)
+	assert.Equal(c.t, status, c.result(r, opLock))
+	return status, r
+}
+
+func TestNFS4Null(t *testing.T) {
+	addr := newTestNFS4Server(t)
+
+	// NFSv4 NULL is served by the NFSv4 server
+	c := newTestClient(t, addr)
+	stat, _ := c.call(nfsProgram, nfsV4, nfsProcNull, nil)
+	assert.Equal(t, uint32(rpcSuccess), stat)
+
+	// The connection only serves NFSv4
+	stat, r := c.call(nfsProgram, 3, nfsProcNull, nil)
+	assert.Equal(t, uint32(rpcProgMismatch), stat)
+	assert.Equal(t, uint32(nfsV4), r.uint32())
+
+	// NFSv3 connections are served by go-nfs
+	c3 := newTestClient(t, addr)
+	stat, _ = c3.call(nfsProgram, 3, nfsProcNull, nil)
+	assert.Equal(t, uint32(rpcSuccess), stat)
+
+	// Only minor version 0 is supported
+	status, _ := c.compound(1, putRootFH)
+	assert.Equal(t, uint32(nfs4ErrMinorVers), status)
+
+	// Unknown operations are illegal
+	status, r = c.compound(0, func(w *xdrWriter) {
+		w.uint32(9999)
+	})
+	assert.Equal(t, uint32(nfs4ErrOpIllegal), status)
+	assert.Equal(t, uint32(opIllegal), r.uint32())
+}
+
+func TestNFS4Files(t *testing.T) {
+	c := newTestClient(t, newTestNFS4Server(t))
+
+	// Operations need a current filehandle
+	status, _ := c.compound(0, getFH)
+	assert.Equal(t, uint32(nfs4ErrNoFileHandle), status)
+
+	// Opens need a confirmed client
+	status, _ = c.compound(0, putRootFH, c.open(1, "owner", shareAccessBoth, true, "file.txt"))
+	assert.Equal(t, uint32(nfs4ErrStaleClientID), status)
+	c.setClientID("files")
+
+	// Write a file
+	fh, sid := c.openFile(1, "owner", shareAccessBoth, true, "file.txt")
+	status, r := c.compound(0, putFH(fh), func(w *xdrWriter) {
+		w.uint32(opWrite)
+		writeStateid(w, sid)
+		w.uint64(0)
+		w.uint32(fileSync)
+		w.opaque([]byte("hello world"))
+	}, func(w *xdrWriter) {
+		w.uint32(opClose)
+		w.uint32(2)
+		writeStateid(w, sid)
+	})
+	require.Equal(t, uint32(nfs4OK), status)
+	c.result(r, opPutFH)
+	require.Equal(t, uint32(nfs4OK), c.result(r, opWrite))
+	assert.Equal(t, uint32(11), r.uint32())
+
+	// The stateid can't be used after the close
+	status, _ = c.compound(0, putFH(fh), func(w *xdrWriter) {
+		w.uint32(opRead)
+		writeStateid(w, sid)
+		w.uint64(0)
+		w.uint32(100)
+	})
+	assert.Equal(t, uint32(nfs4ErrBadStateID), status)
+
+	// Read it back with the anonymous stateid
+	status, r = c.compound(0, putRootFH, lookup("file.txt"), func(w *xdrWriter) {
+		w.uint32(opRead)
+		writeStateid(w, stateid{})
+		w.uint64(6)
+		w.uint32(100)
+	}, getattr(attrType, attrSize))
+	require.Equal(t, uint32(nfs4OK), status)
+	c.result(r, opPutRootFH)
+	c.result(r, opLookup)
+	require.Equal(t, uint32(nfs4OK), c.result(r, opRead))
+	assert.True(t, r.bool())
+	assert.Equal(t, "world", string(r.opaque()))
+	require.Equal(t, uint32(nfs4OK), c.result(r, opGetattr))
+	assert.Equal(t, makeBitmap(attrType, attrSize)[:1], r.bitmap())
+	attrs := &xdrReader{b: r.opaque()}
+	assert.Equal(t, uint32(nfs4FileTypeRegular), attrs.uint32())
+	assert.Equal(t, uint64(11), attrs.uint64())
+
+	// Make a directory and list the root
+	status, _ = c.compound(0, putRootFH, func(w *xdrWriter) {
+		w.uint32(opCreate)
+		w.uint32(createDir)
+		w.string("dir")
+		w.bitmap(nil)
+		w.opaque(nil)
+	})
+	require.Equal(t, uint32(nfs4OK), status)
+	status, r = c.compound(0, putRootFH, func(w *xdrWriter) {
+		w.uint32(opReadDir)
+		w.uint64(0)
+		w.fixed(make([]byte, 8))
+		w.uint32(4096)
+		w.uint32(4096)
+		w.bitmap(makeBitmap(attrType))
+	})
+	require.Equal(t, uint32(nfs4OK), status)
+	c.result(r, opPutRootFH)
+	c.result(r, opReadDir)
+	r.fixed(8)
+	var names []string
+	for r.bool() {
+		r.uint64()
+		names = append(names, r.string())
+		r.bitmap()
+		r.opaque()
+	}
+	assert.True(t, r.bool())
+	assert.Equal(t, []string{"dir", "file.txt"}, names)
+
+	// Rename the file into the directory and remove it
+	status, _ = c.compound(0, putRootFH, func(w *xdrWriter) {
+		w.uint32(opSaveFH)
+	}, lookup("dir"), func(w *xdrWriter) {
+		w.uint32(opRename)
+		w.string("file.txt")
+		w.string("renamed.txt")
+	}, func(w *xdrWriter) {
+		w.uint32(opRemove)
+		w.string("renamed.txt")
+	})
+	require.Equal(t, uint32(nfs4OK), status)
+	status, _ = c.compound(0, putRootFH, lookup("dir"), lookup("renamed.txt"))
+	assert.Equal(t, uint32(nfs4ErrNoEnt), status)
+}
+
+func TestNFS4Locks(t *testing.T) {
+	addr := newTestNFS4Server(t)
+	a := newTestClient(t, addr)
+	a.setClientID("a")
+	b := newTestClient(t, addr)
+	b.setClientID("b")
+
+	fhA, sidA := a.openFile(1, "owner", shareAccessBoth, true, "file")
+	fhB, sidB := b.openFile(1, "owner", shareAccessBoth, false, "file")
+	assert.Equal(t, fhA, fhB)
+
+	// a write locks the start of the file
+	status, r := a.lock(fhA, true, 0, 100, 2, sidA, "lock")
+	require.Equal(t, uint32(nfs4OK), status)
+	lockSidA := readStateid(r)
+
+	// b can't lock an overlapping range
+	status, r = b.lock(fhB, false, 50, 10, 2, sidB, "lock")
+	require.Equal(t, uint32(nfs4ErrDenied), status)
+	assert.Equal(t, uint64(0), r.uint64())
+	assert.Equal(t, uint64(100), r.uint64())
+	assert.Equal(t, uint32(lockWrite), r.uint32())
+	assert.Equal(t, a.id, r.uint64())
+	assert.Equal(t, "lock", r.string())
+
+	// but can lock the rest of the file
+	status, r = b.lock(fhB, false, 100, math.MaxUint64, 3, sidB, "lock")
+	require.Equal(t, uint32(nfs4OK), status)
+	lockSidB := readStateid(r)
+
+	// LOCKT sees the locks
+	lockT := func(c *testClient, offset uint64) uint32 {
+		status, _ := c.compound(0, putFH(fhA), func(w *xdrWriter) {
+			w.uint32(opLockT)
+			w.uint32(lockWrite)
+			w.uint64(offset)
+			w.uint64(1)
+			w.uint64(c.id)
+			w.string("lock")
+		})
+		return status
+	}
+	assert.Equal(t, uint32(nfs4ErrDenied), lockT(b, 99))
+	assert.Equal(t, uint32(nfs4OK), lockT(a, 99))
+	assert.Equal(t, uint32(nfs4ErrDenied), lockT(a, 1000))
+
+	// a can't close the file while holding locks
+	closeFile := func(c *testClient, fh []byte, seqid uint32, sid stateid) uint32 {
+		status, _ := c.compound(0, putFH(fh), func(w *xdrWriter) {
+			w.uint32(opClose)
+			w.uint32(seqid)
+			writeStateid(w, sid)
+		})
+		return status
+	}
+	assert.Equal(t, uint32(nfs4ErrLocksHeld), closeFile(a, fhA, 3, sidA))
+
+	// a unlocks and b can then lock
+	status, _ = a.compound(0, putFH(fhA), func(w *xdrWriter) {
+		w.uint32(opLockU)
+		w.uint32(lockWrite)
+		w.uint32(1)
+		writeStateid(w, lockSidA)
+		w.uint64(0)
+		w.uint64(math.MaxUint64)
+	})
+	require.Equal(t, uint32(nfs4OK), status)
+	status, r = b.compound(0, putFH(fhB), func(w *xdrWriter) {
+		w.uint32(opLock)
+		w.uint32(lockWrite)
+		w.bool(false)
+		w.uint64(0)
+		w.uint64(100)
+		w.bool(false) // existing lock owner
+		writeStateid(w, lockSidB)
+		w.uint32(1)
+	})
+	require.Equal(t, uint32(nfs4OK), status)
+	b.result(r, opPutFH)
+	assert.Equal(t, uint32(nfs4OK), b.result(r, opLock))
+
+	// Bad seqids are rejected
+	assert.Equal(t, uint32(nfs4ErrBadSeqID), closeFile(a, fhA, 10, sidA))
+
+	// Releasing the lock owner drops the lock state
+	assert.Equal(t, uint32(nfs4OK), closeFile(a, fhA, 4, sidA))
+	status, _ = a.compound(0, func(w *xdrWriter) {
+		w.uint32(opReleaseLockOwner)
+		w.uint64(a.id)
+		w.string("lock")
+	})
+	assert.Equal(t, uint32(nfs4OK), status)
+}
+
+func TestLockManager(t *testing.T) {
+	m := newLockManager()
+	a, b := &nfs4Owner{name: "a"}, &nfs4Owner{name: "b"}
+
+	// Read locks don't conflict with each other
+	assert.Nil(t, m.lock("file", a, false, 0, 100))
+	assert.Nil(t, m.lock("file", b, false, 50, 150))
+	assert.NotNil(t, m.test("file", b, true, 0, 10))
+
+	// Upgrading part of a lock splits it
+	assert.Equal(t, b, m.lock("file", a, true, 40, 60).owner)
+	assert.Nil(t, m.lock("file", a, true, 10, 20))
+	assert.Len(t, m.files["file"], 4)
+	assert.NotNil(t, m.test("file", b, false, 15, 16))
+	assert.Nil(t, m.test("file", b, false, 20, 21))
+
+	// Unlocking the middle of a lock leaves both ends
+	m.unlock("file", b, 60, 70)
+	assert.Nil(t, m.test("file", a, true, 60, 70))
+	assert.NotNil(t, m.test("file", a, true, 70, 71))
+
+	// Locks follow renames
+	m.rename("file", "dir/file")
+	assert.True(t, m.held("dir/file", a))
+	assert.False(t, m.held("file", a))
+
+	// Releasing an owner removes all its locks
+	m.release(a)
+	m.release(b)
+	assert.Empty(t, m.files)
+
+	// Check ranges
+	_, _, ok := lockRange(0, 0)
+	assert.False(t, ok)
+	_, _, ok = lockRange(math.MaxUint64-1, 2)
+	assert.False(t, ok)
+	start, end, ok := lockRange(10, math.MaxUint64)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(10), start)
+	assert.Equal(t, uint64(math.MaxUint64), end)
+}
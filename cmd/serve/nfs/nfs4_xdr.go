@@ -0,0 +1,150 @@
+//go:build unix
+
+package nfs
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// errBadXDR is returned when an XDR encoded message can't be decoded
+var errBadXDR = errors.New("bad XDR encoding")
+
+// maxXDROpaque is the largest variable length item accepted
+const maxXDROpaque = 4 * 1024 * 1024
+
+// xdrReader decodes XDR (RFC 4506) from a buffer
+//
+// Errors are sticky so a whole structure can be decoded before
+// checking err.
+type xdrReader struct {
+	b   []byte
+	err error
+}
+
+// fail records the first error
+func (r *xdrReader) fail() {
+	if r.err == nil {
+		r.err = errBadXDR
+	}
+	r.b = nil
+}
+
+// fixed reads n bytes plus their padding
+func (r *xdrReader) fixed(n int) []byte {
+	padded := (n + 3) &^ 3
+	if r.err != nil || n < 0 || padded > len(r.b) {
+		r.fail()
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[padded:]
+	return b
+}
+
+// uint32 reads an unsigned int
+func (r *xdrReader) uint32() uint32 {
+	b := r.fixed(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+// uint64 reads an unsigned hyper
+func (r *xdrReader) uint64() uint64 {
+	b := r.fixed(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// bool reads a boolean
+func (r *xdrReader) bool() bool {
+	return r.uint32() != 0
+}
+
+// opaque reads variable length opaque data
+func (r *xdrReader) opaque() []byte {
+	n := r.uint32()
+	if n > maxXDROpaque {
+		r.fail()
+		return nil
+	}
+	return r.fixed(int(n))
+}
+
+// string reads a string
+func (r *xdrReader) string() string {
+	return string(r.opaque())
+}
+
+// bitmap reads a bitmap4
+func (r *xdrReader) bitmap() []uint32 {
+	n := r.uint32()
+	if n > 8 {
+		r.fail()
+		return nil
+	}
+	bitmap := make([]uint32, 0, n)
+	for range n {
+		bitmap = append(bitmap, r.uint32())
+	}
+	return bitmap
+}
+
+// xdrWriter encodes XDR into a buffer
+type xdrWriter struct {
+	b []byte
+}
+
+// fixed writes b plus its padding
+func (w *xdrWriter) fixed(b []byte) {
+	w.b = append(w.b, b...)
+	for len(w.b)&3 != 0 {
+		w.b = append(w.b, 0)
+	}
+}
+
+// uint32 writes an unsigned int
+func (w *xdrWriter) uint32(x uint32) {
+	w.b = binary.BigEndian.AppendUint32(w.b, x)
+}
+
+// uint64 writes an unsigned hyper
+func (w *xdrWriter) uint64(x uint64) {
+	w.b = binary.BigEndian.AppendUint64(w.b, x)
+}
+
+// bool writes a boolean
+func (w *xdrWriter) bool(x bool) {
+	if x {
+		w.uint32(1)
+	} else {
+		w.uint32(0)
+	}
+}
+
+// opaque writes variable length opaque data
+func (w *xdrWriter) opaque(b []byte) {
+	w.uint32(uint32(len(b)))
+	w.fixed(b)
+}
+
+// string writes a string
+func (w *xdrWriter) string(s string) {
+	w.opaque([]byte(s))
+}
+
+// bitmap writes a bitmap4 leaving off any trailing empty words
+func (w *xdrWriter) bitmap(bitmap []uint32) {
+	n := len(bitmap)
+	for n > 0 && bitmap[n-1] == 0 {
+		n--
+	}
+	w.uint32(uint32(n))
+	for _, word := range bitmap[:n] {
+		w.uint32(word)
+	}
+}
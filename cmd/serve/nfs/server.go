@@ -18,6 +18,7 @@ import (
 type Server struct {
 	opt                 Options
 	handler             nfs.Handler
+	v4                  *nfs4Server
 	ctx                 context.Context // for global config
 	listener            net.Listener
 	UnmountedExternally bool
@@ -41,6 +42,7 @@ func NewServer(ctx context.Context, vfs *vfs.VFS, opt *Options) (s *Server, err
 	if err != nil {
 		return nil, fmt.Errorf("failed to make NFS handler: %w", err)
 	}
+	s.v4 = newNFS4Server(s.handler.(*Handler))
 	s.listener, err = net.Listen("tcp", s.opt.ListenAddr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open listening socket: %w", err)
@@ -61,5 +63,6 @@ func (s *Server) Shutdown() error {
 // Serve starts the server
 func (s *Server) Serve() (err error) {
 	fs.Logf(nil, "NFS Server running at %s\n", s.listener.Addr())
-	return nfs.Serve(s.listener, s.handler)
+	// NFSv4 connections are served by us, the rest by go-nfs
+	return nfs.Serve(newVersionListener(s.listener, s.v4.serveConn), s.handler)
 }
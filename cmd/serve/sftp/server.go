@@ -43,6 +43,7 @@ type server struct {
 	listener net.Listener
 	stopped  chan struct{} // for waiting on the listener to stop
 	proxy    *proxy.Proxy
+	users    *userVFSes // set if --users-file is in use
 }
 
 func newServer(ctx context.Context, f fs.Fs, opt *Options, vfsOpt *vfscommon.Options, proxyOpt *proxy.Options) (*server, error) {
@@ -54,6 +55,12 @@ func newServer(ctx context.Context, f fs.Fs, opt *Options, vfsOpt *vfscommon.Opt
 	}
 	if proxy.Opt.AuthProxy != "" {
 		s.proxy = proxy.New(ctx, proxyOpt, vfsOpt)
+	} else if opt.UsersFile != "" {
+		var err error
+		s.users, err = newUserVFSes(ctx, f, env.ShellExpand(opt.UsersFile), vfsOpt)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		s.vfs = vfs.New(f, vfsOpt)
 	}
@@ -64,15 +71,23 @@ func newServer(ctx context.Context, f fs.Fs, opt *Options, vfsOpt *vfscommon.Opt
 	return s, nil
 }
 
-// getVFS gets the vfs from s or the proxy
+// getVFS gets the vfs from s, the users file or the proxy
 func (s *server) getVFS(what string, sshConn *ssh.ServerConn) (VFS *vfs.VFS) {
-	if s.proxy == nil {
+	if s.proxy == nil && s.users == nil {
 		return s.vfs
 	}
 	if sshConn.Permissions == nil || sshConn.Permissions.Extensions == nil {
 		fs.Infof(what, "SSH Permissions Extensions not found")
 		return nil
 	}
+	if s.users != nil {
+		VFS, err := s.users.getVFS(sshConn.Permissions.Extensions["_user"])
+		if err != nil {
+			fs.Errorf(what, "Failed to make VFS: %v", err)
+			return nil
+		}
+		return VFS
+	}
 	key := sshConn.Permissions.Extensions["_vfsKey"]
 	if key == "" {
 		fs.Infof(what, "VFS key not found")
@@ -143,9 +158,21 @@ func (s *server) configure() (err error) {
 	if proxy.Opt.AuthProxy != "" && s.opt.AuthorizedKeys != "" && s.opt.AuthorizedKeys != Opt.AuthorizedKeys {
 		return errors.New("--auth-proxy and --authorized-keys cannot be used at the same time")
 	}
+	if s.opt.UsersFile != "" {
+		switch {
+		case proxy.Opt.AuthProxy != "":
+			return errors.New("--auth-proxy and --users-file cannot be used at the same time")
+		case s.opt.AuthorizedKeys != Opt.AuthorizedKeys:
+			return errors.New("--authorized-keys and --users-file cannot be used at the same time")
+		case s.opt.User != "" || s.opt.Pass != "":
+			return errors.New("--user/--pass and --users-file cannot be used at the same time")
+		case s.opt.NoAuth:
+			return errors.New("--no-auth and --users-file cannot be used at the same time")
+		}
+	}
 
 	// Load the authorized keys
-	if s.opt.AuthorizedKeys != "" && proxy.Opt.AuthProxy == "" {
+	if s.opt.AuthorizedKeys != "" && proxy.Opt.AuthProxy == "" && s.users == nil {
 		authKeysFile := env.ShellExpand(s.opt.AuthorizedKeys)
 		authorizedKeysMap, err = loadAuthorizedKeys(authKeysFile)
 		// If user set the flag away from the default then report an error
@@ -160,8 +187,8 @@ func (s *server) configure() (err error) {
 		fs.Logf(nil, "Loaded %d authorized keys from %q", len(authorizedKeysMap), authKeysFile)
 	}
 
-	if !s.opt.NoAuth && len(authorizedKeysMap) == 0 && s.opt.User == "" && s.opt.Pass == "" && s.proxy == nil {
-		return errors.New("no authorization found, use --user/--pass or --authorized-keys or --no-auth or --auth-proxy")
+	if !s.opt.NoAuth && len(authorizedKeysMap) == 0 && s.opt.User == "" && s.opt.Pass == "" && s.proxy == nil && s.users == nil {
+		return errors.New("no authorization found, use --user/--pass or --authorized-keys or --users-file or --no-auth or --auth-proxy")
 	}
 
 	// An SSH server is represented by a ServerConfig, which holds
@@ -182,6 +209,14 @@ func (s *server) configure() (err error) {
 						"_vfsKey": vfsKey,
 					},
 				}, nil
+			} else if s.users != nil {
+				if u := s.users.get(c.User()); u != nil && u.checkPassword(pass) {
+					return &ssh.Permissions{
+						Extensions: map[string]string{
+							"_user": u.name,
+						},
+					}, nil
+				}
 			} else if s.opt.User != "" && s.opt.Pass != "" {
 				userOK := subtle.ConstantTimeCompare([]byte(c.User()), []byte(s.opt.User))
 				passOK := subtle.ConstantTimeCompare(pass, []byte(s.opt.Pass))
@@ -210,6 +245,17 @@ func (s *server) configure() (err error) {
 					},
 				}, nil
 			}
+			if s.users != nil {
+				if u := s.users.get(c.User()); u != nil && u.checkKey(pubKey) {
+					return &ssh.Permissions{
+						Extensions: map[string]string{
+							"_user":     u.name,
+							"pubkey-fp": ssh.FingerprintSHA256(pubKey),
+						},
+					}, nil
+				}
+				return nil, fmt.Errorf("unknown public key for %q", c.User())
+			}
 			if _, ok := authorizedKeysMap[string(pubKey.Marshal())]; ok {
 				return &ssh.Permissions{
 					// Record the public key used for authentication.
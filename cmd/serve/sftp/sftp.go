@@ -48,6 +48,10 @@ var OptionsInfo = fs.Options{{
 	Name:    "no_auth",
 	Default: false,
 	Help:    "Allow connections with no authentication if set",
+}, {
+	Name:    "users_file",
+	Default: "",
+	Help:    "File of users with their own root, permissions and keys",
 }, {
 	Name:    "stdio",
 	Default: false,
@@ -62,6 +66,7 @@ type Options struct {
 	User           string   `config:"user"`            // single username
 	Pass           string   `config:"pass"`            // password for user
 	NoAuth         bool     `config:"no_auth"`         // allow no authentication on connections
+	UsersFile      string   `config:"users_file"`      // path to file of users
 	Stdio          bool     `config:"stdio"`           // serve on stdio
 }
 
@@ -132,8 +137,34 @@ Use ` + "`--stats`" + ` to control the stats printing.
 You must provide some means of authentication, either with
 ` + "`--user`/`--pass`" + `, an authorized keys file (specify location with
 ` + "`--authorized-keys`" + ` - the default is the same as ssh), an
-` + "`--auth-proxy`" + `, or set the ` + "`--no-auth`" + ` flag for no
-authentication when logging in.
+` + "`--auth-proxy`" + `, a ` + "`--users-file`" + `, or set the ` + "`--no-auth`" + `
+flag for no authentication when logging in.
+
+To serve several isolated users from one server use ` + "`--users-file`" + `.
+Each line of the file describes one user like this:
+
+    name:password-hash:root:permissions:key-fingerprints
+
+- ` + "`password-hash`" + ` is an htpasswd style hash (bcrypt, SHA1 or MD5) as
+  made by ` + "`htpasswd -B`" + `. Leave it empty to stop password logins.
+- ` + "`root`" + ` is the directory of the remote the user sees as their root.
+  Leave it empty to serve the whole remote.
+- ` + "`permissions`" + ` is ` + "`rw`" + ` (the default) or ` + "`ro`" + ` for read only access.
+- ` + "`key-fingerprints`" + ` is a comma separated list of SHA256 fingerprints of
+  the public keys the user may log in with, as shown by
+  ` + "`ssh-keygen -lf key.pub`" + `.
+
+Trailing fields may be left off and lines starting with ` + "`#`" + ` are
+ignored. For example:
+
+    # alice can log in with a password or a key
+    alice:$2y$05$...:home/alice:rw:SHA256:2Kf...
+    # bob can only read the shared files
+    bob:$apr1$...:shared:ro
+
+Users with the same root and permissions share a VFS. The users file
+can't be combined with ` + "`--user`/`--pass`" + `, ` + "`--authorized-keys`" + `,
+` + "`--no-auth`" + ` or ` + "`--auth-proxy`" + `.
 
 If you don't supply a host ` + "`--key`" + ` then rclone will generate rsa, ecdsa
 and ed25519 variants, and cache them for later use in rclone's cache
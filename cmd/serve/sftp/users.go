@@ -0,0 +1,196 @@
+//go:build !plan9
+
+package sftp
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	goauth "github.com/abbot/go-http-auth"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/ssh"
+)
+
+// user is a user read from the --users-file
+type user struct {
+	name     string
+	hash     string              // htpasswd style password hash, "" for no password logins
+	root     string              // directory of the remote the user sees
+	readOnly bool                // set if the user can't modify anything
+	keys     map[string]struct{} // SHA256 fingerprints of the user's public keys
+}
+
+// checkPassword returns true if pass is the user's password
+func (u *user) checkPassword(pass []byte) bool {
+	hash := []byte(u.hash)
+	switch {
+	case u.hash == "":
+		return false
+	case strings.HasPrefix(u.hash, "$2"):
+		return bcrypt.CompareHashAndPassword(hash, pass) == nil
+	case strings.HasPrefix(u.hash, "{SHA}"):
+		sum := sha1.Sum(pass)
+		return subtle.ConstantTimeCompare(hash[5:], []byte(base64.StdEncoding.EncodeToString(sum[:]))) == 1
+	case strings.HasPrefix(u.hash, "$1$"), strings.HasPrefix(u.hash, "$apr1$"):
+		parts := strings.SplitN(u.hash, "$", 4)
+		if len(parts) != 4 {
+			return false
+		}
+		magic := []byte("$" + parts[1] + "$")
+		return subtle.ConstantTimeCompare(hash, goauth.MD5Crypt(pass, []byte(parts[2]), magic)) == 1
+	}
+	return false
+}
+
+// checkKey returns true if key is one of the user's public keys
+func (u *user) checkKey(key ssh.PublicKey) bool {
+	_, ok := u.keys[ssh.FingerprintSHA256(key)]
+	return ok
+}
+
+// parseUsers parses a users file
+//
+// Each line is "name:password-hash:root:permissions:keys" where the
+// fields after the name may be left off.
+func parseUsers(in io.Reader) (map[string]*user, error) {
+	users := make(map[string]*user)
+	scanner := bufio.NewScanner(in)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, ":", 5)
+		for len(fields) < 5 {
+			fields = append(fields, "")
+		}
+		u := &user{
+			name: fields[0],
+			hash: fields[1],
+			root: strings.Trim(path.Clean("/"+fields[2]), "/"),
+			keys: make(map[string]struct{}),
+		}
+		if u.name == "" {
+			return nil, fmt.Errorf("line %d: empty user name", lineNumber)
+		}
+		if _, found := users[u.name]; found {
+			return nil, fmt.Errorf("line %d: duplicate user %q", lineNumber, u.name)
+		}
+		switch fields[3] {
+		case "", "rw":
+		case "ro":
+			u.readOnly = true
+		default:
+			return nil, fmt.Errorf("line %d: permissions must be \"ro\" or \"rw\" not %q", lineNumber, fields[3])
+		}
+		for _, key := range strings.Split(fields[4], ",") {
+			key = strings.TrimSpace(key)
+			if key == "" {
+				continue
+			}
+			if !strings.HasPrefix(key, "SHA256:") {
+				return nil, fmt.Errorf("line %d: key fingerprint %q must start with \"SHA256:\"", lineNumber, key)
+			}
+			u.keys[key] = struct{}{}
+		}
+		if u.hash == "" && len(u.keys) == 0 {
+			return nil, fmt.Errorf("line %d: user %q has no password or keys", lineNumber, u.name)
+		}
+		users[u.name] = u
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, errors.New("no users found")
+	}
+	return users, nil
+}
+
+// userVFSes serves each user in the --users-file a VFS of their
+// directory of the remote
+type userVFSes struct {
+	ctx    context.Context
+	f      fs.Fs
+	vfsOpt vfscommon.Options
+	users  map[string]*user
+	mu     sync.Mutex
+	vfses  map[string]*vfs.VFS // by root and permissions
+}
+
+// newUserVFSes reads the users file at usersPath
+func newUserVFSes(ctx context.Context, f fs.Fs, usersPath string, vfsOpt *vfscommon.Options) (*userVFSes, error) {
+	in, err := os.Open(usersPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open users file: %w", err)
+	}
+	defer fs.CheckClose(in, &err)
+	users, err := parseUsers(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file %q: %w", usersPath, err)
+	}
+	fs.Logf(nil, "Loaded %d users from %q", len(users), usersPath)
+	return &userVFSes{
+		ctx:    ctx,
+		f:      f,
+		vfsOpt: *vfsOpt,
+		users:  users,
+		vfses:  make(map[string]*vfs.VFS),
+	}, nil
+}
+
+// get returns the user called name or nil if not found
+func (uv *userVFSes) get(name string) *user {
+	return uv.users[name]
+}
+
+// getVFS returns the VFS for the user called name, making it if
+// necessary
+func (uv *userVFSes) getVFS(name string) (*vfs.VFS, error) {
+	u := uv.get(name)
+	if u == nil {
+		return nil, fmt.Errorf("unknown user %q", name)
+	}
+	key := u.root
+	if u.readOnly {
+		key += "\x00ro"
+	}
+	uv.mu.Lock()
+	defer uv.mu.Unlock()
+	if VFS := uv.vfses[key]; VFS != nil {
+		return VFS, nil
+	}
+	f := uv.f
+	if u.root != "" {
+		var err error
+		f, err = cache.Get(uv.ctx, fspath.JoinRootPath(fs.ConfigString(uv.f), u.root))
+		if err != nil && !errors.Is(err, fs.ErrorIsFile) {
+			return nil, fmt.Errorf("failed to make remote for %q: %w", u.root, err)
+		}
+		if errors.Is(err, fs.ErrorIsFile) {
+			return nil, fmt.Errorf("root %q of user %q is a file", u.root, u.name)
+		}
+	}
+	opt := uv.vfsOpt
+	opt.ReadOnly = opt.ReadOnly || u.readOnly
+	VFS := vfs.New(f, &opt)
+	uv.vfses[key] = VFS
+	return VFS, nil
+}
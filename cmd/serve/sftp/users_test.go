@@ -0,0 +1,170 @@
+//go:build !windows && !darwin && !plan9
+
+package sftp
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/cmd/serve/proxy"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseUsers(t *testing.T) {
+	users, err := parseUsers(strings.NewReader(`
+# comment
+alice:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=:home/alice:rw:SHA256:abc, SHA256:def
+bob:$1$abcdefgh$cHJi5PXp/ki/ktXzqlk6I1:../../shared/:ro
+carol:$apr1$abcdefgh$h9FWgUz3n9YxylKLlR5SQ/
+`))
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+
+	alice := users["alice"]
+	assert.Equal(t, "home/alice", alice.root)
+	assert.False(t, alice.readOnly)
+	assert.Equal(t, map[string]struct{}{"SHA256:abc": {}, "SHA256:def": {}}, alice.keys)
+
+	bob := users["bob"]
+	assert.Equal(t, "shared", bob.root)
+	assert.True(t, bob.readOnly)
+	assert.Empty(t, bob.keys)
+
+	carol := users["carol"]
+	assert.Equal(t, "", carol.root)
+	assert.False(t, carol.readOnly)
+
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"", "no users found"},
+		{":pass", "line 1: empty user name"},
+		{"a:pass\na:pass", `line 2: duplicate user "a"`},
+		{"a:pass::rwx", `line 1: permissions must be "ro" or "rw" not "rwx"`},
+		{"a:pass:::MD5:ab", `line 1: key fingerprint "MD5:ab" must start with "SHA256:"`},
+		{"a", `line 1: user "a" has no password or keys`},
+	} {
+		_, err := parseUsers(strings.NewReader(test.in))
+		assert.EqualError(t, err, test.want, test.in)
+	}
+}
+
+func TestUserCheckPassword(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	for _, hash := range []string{
+		string(bcryptHash),
+		"{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=",
+		"$1$abcdefgh$cHJi5PXp/ki/ktXzqlk6I1",
+		"$apr1$abcdefgh$h9FWgUz3n9YxylKLlR5SQ/",
+	} {
+		u := &user{hash: hash}
+		assert.True(t, u.checkPassword([]byte("secret")), hash)
+		assert.False(t, u.checkPassword([]byte("wrong")), hash)
+	}
+	for _, hash := range []string{"", "secret", "$apr1$broken"} {
+		u := &user{hash: hash}
+		assert.False(t, u.checkPassword([]byte("secret")), hash)
+	}
+}
+
+func TestUsersFile(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, config.SetCacheDir(t.TempDir()))
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "alice"), 0777))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "shared"), 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shared", "file.txt"), []byte("hello"), 0666))
+	f, err := fs.NewFs(ctx, dir)
+	require.NoError(t, err)
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(private)
+	require.NoError(t, err)
+
+	usersFile := filepath.Join(t.TempDir(), "users")
+	require.NoError(t, os.WriteFile(usersFile, []byte(fmt.Sprintf(
+		"alice::alice:rw:%s\nbob:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=:shared:ro\n",
+		ssh.FingerprintSHA256(signer.PublicKey()),
+	)), 0666))
+
+	opt := Opt
+	opt.ListenAddr = testBindAddress
+	opt.UsersFile = usersFile
+	s, err := newServer(ctx, f, &opt, &vfscommon.Opt, &proxy.Opt)
+	require.NoError(t, err)
+	go func() {
+		require.NoError(t, s.Serve())
+	}()
+	defer func() {
+		assert.NoError(t, s.Shutdown())
+	}()
+
+	login := func(name string, auth ssh.AuthMethod) (*sftp.Client, error) {
+		conn, err := ssh.Dial("tcp", s.Addr().String(), &ssh.ClientConfig{
+			User:            name,
+			Auth:            []ssh.AuthMethod{auth},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		return sftp.NewClient(conn)
+	}
+
+	// alice can only log in with a key and writes to their own root
+	_, err = login("alice", ssh.Password("secret"))
+	require.Error(t, err)
+	client, err := login("alice", ssh.PublicKeys(signer))
+	require.NoError(t, err)
+	out, err := client.Create("/new.txt")
+	require.NoError(t, err)
+	_, err = out.Write([]byte("potato"))
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+	data, err := os.ReadFile(filepath.Join(dir, "alice", "new.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "potato", string(data))
+	_, err = client.Stat("/file.txt")
+	assert.Error(t, err)
+
+	// bob can read the shared directory but not write to it
+	_, err = login("bob", ssh.Password("wrong"))
+	require.Error(t, err)
+	_, err = login("bob", ssh.PublicKeys(signer))
+	require.Error(t, err)
+	client, err = login("bob", ssh.Password("secret"))
+	require.NoError(t, err)
+	in, err := client.Open("/file.txt")
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = in.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+	require.NoError(t, in.Close())
+	_, err = client.Create("/new.txt")
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "shared", "new.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// unknown users can't log in
+	_, err = login("carol", ssh.Password("secret"))
+	require.Error(t, err)
+}
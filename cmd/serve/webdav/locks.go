@@ -0,0 +1,290 @@
+package webdav
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/net/webdav"
+)
+
+// lock is a single WebDAV lock
+type lock struct {
+	Token   string
+	Details webdav.LockDetails
+	Expiry  time.Time `json:",omitempty"` // zero for locks which never expire
+	held    bool      // set while a request is using the lock
+}
+
+// covers returns true if the lock applies to name
+func (l *lock) covers(name string) bool {
+	return name == l.Details.Root || (!l.Details.ZeroDepth && isAncestor(l.Details.Root, name))
+}
+
+// persistent returns true if the lock should be saved in the lock file
+//
+// The webdav handler takes out ownerless zero depth locks which never
+// expire for the duration of requests which don't supply locks. These
+// are never saved.
+func (l *lock) persistent() bool {
+	return l.Details.OwnerXML != "" || l.Details.Duration >= 0 || !l.Details.ZeroDepth
+}
+
+// setExpiry sets the expiry of l from its duration
+func (l *lock) setExpiry(now time.Time) {
+	if l.Details.Duration >= 0 {
+		l.Expiry = now.Add(l.Details.Duration)
+	} else {
+		l.Expiry = time.Time{}
+	}
+}
+
+// isAncestor returns true if dir is a parent directory of name
+func isAncestor(dir, name string) bool {
+	if dir == "/" {
+		return name != "/"
+	}
+	return strings.HasPrefix(name, dir+"/")
+}
+
+// lockName cleans name into the form used for locks
+func lockName(name string) string {
+	return path.Clean("/" + name)
+}
+
+// lockSystem implements webdav.LockSystem
+//
+// It has the same semantics as webdav.NewMemLS but uses
+// opaquelocktoken URIs for the tokens, which some clients insist on,
+// and can save the locks to a file so they survive a restart.
+type lockSystem struct {
+	mu    sync.Mutex
+	path  string           // file to save the locks in, "" for memory only
+	locks map[string]*lock // by token
+}
+
+// check interface
+var _ webdav.LockSystem = (*lockSystem)(nil)
+
+// newLockSystem makes a lock system, loading the locks from lockFile
+// if set
+func newLockSystem(lockFile string) (*lockSystem, error) {
+	ls := &lockSystem{
+		path:  lockFile,
+		locks: make(map[string]*lock),
+	}
+	if lockFile == "" {
+		return ls, nil
+	}
+	data, err := os.ReadFile(lockFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return ls, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	var locks []*lock
+	if err := json.Unmarshal(data, &locks); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file %q: %w", lockFile, err)
+	}
+	for _, l := range locks {
+		ls.locks[l.Token] = l
+	}
+	ls.expire(time.Now())
+	fs.Debugf(nil, "Loaded %d WebDAV locks from %q", len(ls.locks), lockFile)
+	return ls, nil
+}
+
+// save writes the persistent locks to the lock file
+//
+// Call with the mutex held
+func (ls *lockSystem) save() error {
+	if ls.path == "" {
+		return nil
+	}
+	locks := make([]*lock, 0, len(ls.locks))
+	for _, l := range ls.locks {
+		if l.persistent() {
+			locks = append(locks, l)
+		}
+	}
+	data, err := json.MarshalIndent(locks, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp := ls.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(ls.path), 0700); err != nil {
+		return fmt.Errorf("failed to make lock file directory: %w", err)
+	}
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if err := os.Rename(tmp, ls.path); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// saveIf saves the locks if l should be persisted
+//
+// Call with the mutex held
+func (ls *lockSystem) saveIf(l *lock) error {
+	if !l.persistent() {
+		return nil
+	}
+	return ls.save()
+}
+
+// expire removes the locks which have expired at now
+//
+// Call with the mutex held
+func (ls *lockSystem) expire(now time.Time) {
+	changed := false
+	for token, l := range ls.locks {
+		if !l.held && !l.Expiry.IsZero() && !now.Before(l.Expiry) {
+			delete(ls.locks, token)
+			changed = changed || l.persistent()
+		}
+	}
+	if changed {
+		if err := ls.save(); err != nil {
+			fs.Errorf(nil, "WebDAV locks: %v", err)
+		}
+	}
+}
+
+// lookup returns the lock named by one of the conditions which covers
+// name and isn't held, or nil if not found
+//
+// Call with the mutex held
+func (ls *lockSystem) lookup(name string, conditions ...webdav.Condition) *lock {
+	for _, c := range conditions {
+		l := ls.locks[c.Token]
+		if l != nil && !l.held && l.covers(name) {
+			return l
+		}
+	}
+	return nil
+}
+
+// Confirm confirms that the caller can claim all of the locks
+// specified by the conditions for name0 and name1
+func (ls *lockSystem) Confirm(now time.Time, name0, name1 string, conditions ...webdav.Condition) (release func(), err error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.expire(now)
+	var l0, l1 *lock
+	if name0 != "" {
+		if l0 = ls.lookup(lockName(name0), conditions...); l0 == nil {
+			return nil, webdav.ErrConfirmationFailed
+		}
+	}
+	if name1 != "" {
+		if l1 = ls.lookup(lockName(name1), conditions...); l1 == nil {
+			return nil, webdav.ErrConfirmationFailed
+		}
+	}
+	// Don't hold the same lock twice
+	if l1 == l0 {
+		l1 = nil
+	}
+	for _, l := range []*lock{l0, l1} {
+		if l != nil {
+			l.held = true
+		}
+	}
+	return func() {
+		ls.mu.Lock()
+		defer ls.mu.Unlock()
+		for _, l := range []*lock{l0, l1} {
+			if l != nil {
+				l.held = false
+			}
+		}
+	}, nil
+}
+
+// canCreate returns true if a lock can be created on name
+//
+// Call with the mutex held
+func (ls *lockSystem) canCreate(name string, zeroDepth bool) bool {
+	for _, l := range ls.locks {
+		switch {
+		case l.Details.Root == name:
+			// name is already locked
+			return false
+		case !l.Details.ZeroDepth && isAncestor(l.Details.Root, name):
+			// a parent of name is locked with infinite depth
+			return false
+		case !zeroDepth && isAncestor(name, l.Details.Root):
+			// an infinite depth lock would cover an existing lock
+			return false
+		}
+	}
+	return true
+}
+
+// Create creates a lock with the given details
+func (ls *lockSystem) Create(now time.Time, details webdav.LockDetails) (token string, err error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.expire(now)
+	details.Root = lockName(details.Root)
+	if !ls.canCreate(details.Root, details.ZeroDepth) {
+		return "", webdav.ErrLocked
+	}
+	l := &lock{
+		Token:   "opaquelocktoken:" + uuid.New().String(),
+		Details: details,
+	}
+	l.setExpiry(now)
+	ls.locks[l.Token] = l
+	if err := ls.saveIf(l); err != nil {
+		delete(ls.locks, l.Token)
+		return "", err
+	}
+	return l.Token, nil
+}
+
+// Refresh refreshes the lock with the given token
+func (ls *lockSystem) Refresh(now time.Time, token string, duration time.Duration) (webdav.LockDetails, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.expire(now)
+	l := ls.locks[token]
+	if l == nil {
+		return webdav.LockDetails{}, webdav.ErrNoSuchLock
+	}
+	if l.held {
+		return webdav.LockDetails{}, webdav.ErrLocked
+	}
+	l.Details.Duration = duration
+	l.setExpiry(now)
+	if err := ls.saveIf(l); err != nil {
+		return webdav.LockDetails{}, err
+	}
+	return l.Details, nil
+}
+
+// Unlock unlocks the lock with the given token
+func (ls *lockSystem) Unlock(now time.Time, token string) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.expire(now)
+	l := ls.locks[token]
+	if l == nil {
+		return webdav.ErrNoSuchLock
+	}
+	if l.held {
+		return webdav.ErrLocked
+	}
+	delete(ls.locks, token)
+	return ls.saveIf(l)
+}
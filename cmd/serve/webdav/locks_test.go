@@ -0,0 +1,103 @@
+package webdav
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/webdav"
+)
+
+func TestLockSystem(t *testing.T) {
+	now := time.Now()
+	ls, err := newLockSystem("")
+	require.NoError(t, err)
+
+	// Infinite depth lock on a directory
+	dirToken, err := ls.Create(now, webdav.LockDetails{Root: "dir/", Duration: time.Minute})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(dirToken, "opaquelocktoken:"))
+
+	// Can't lock the directory, anything in it or its parent with infinite depth
+	for _, details := range []webdav.LockDetails{
+		{Root: "/dir", ZeroDepth: true, Duration: -1},
+		{Root: "/dir/file", ZeroDepth: true, Duration: -1},
+		{Root: "/", Duration: -1},
+	} {
+		_, err = ls.Create(now, details)
+		assert.Equal(t, webdav.ErrLocked, err, details.Root)
+	}
+
+	// Can lock elsewhere
+	fileToken, err := ls.Create(now, webdav.LockDetails{Root: "/other", ZeroDepth: true, Duration: -1})
+	require.NoError(t, err)
+
+	// Confirm needs the right token
+	_, err = ls.Confirm(now, "/dir/file", "", webdav.Condition{Token: fileToken})
+	assert.Equal(t, webdav.ErrConfirmationFailed, err)
+	release, err := ls.Confirm(now, "/dir/file", "/dir/file2", webdav.Condition{Token: dirToken})
+	require.NoError(t, err)
+
+	// Held locks can't be confirmed, refreshed or unlocked
+	_, err = ls.Confirm(now, "/dir/file", "", webdav.Condition{Token: dirToken})
+	assert.Equal(t, webdav.ErrConfirmationFailed, err)
+	_, err = ls.Refresh(now, dirToken, time.Minute)
+	assert.Equal(t, webdav.ErrLocked, err)
+	assert.Equal(t, webdav.ErrLocked, ls.Unlock(now, dirToken))
+	release()
+
+	// Refresh extends the lock
+	details, err := ls.Refresh(now, dirToken, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "/dir", details.Root)
+	assert.Equal(t, time.Hour, details.Duration)
+	_, err = ls.Create(now.Add(30*time.Minute), webdav.LockDetails{Root: "/dir/file", ZeroDepth: true})
+	assert.Equal(t, webdav.ErrLocked, err)
+
+	// Locks expire
+	_, err = ls.Create(now.Add(2*time.Hour), webdav.LockDetails{Root: "/dir/file", ZeroDepth: true, Duration: -1})
+	require.NoError(t, err)
+	_, err = ls.Refresh(now.Add(2*time.Hour), dirToken, time.Hour)
+	assert.Equal(t, webdav.ErrNoSuchLock, err)
+
+	// Unlock
+	require.NoError(t, ls.Unlock(now, fileToken))
+	assert.Equal(t, webdav.ErrNoSuchLock, ls.Unlock(now, fileToken))
+}
+
+func TestLockSystemPersist(t *testing.T) {
+	now := time.Now()
+	lockFile := filepath.Join(t.TempDir(), "locks", "locks.json")
+	ls, err := newLockSystem(lockFile)
+	require.NoError(t, err)
+
+	token, err := ls.Create(now, webdav.LockDetails{Root: "/file", ZeroDepth: true, Duration: time.Hour, OwnerXML: "<href>me</href>"})
+	require.NoError(t, err)
+	// Temporary locks taken by the handler aren't saved
+	_, err = ls.Create(now, webdav.LockDetails{Root: "/other", ZeroDepth: true, Duration: -1})
+	require.NoError(t, err)
+	expiredToken, err := ls.Create(now, webdav.LockDetails{Root: "/expired", ZeroDepth: true, Duration: time.Nanosecond})
+	require.NoError(t, err)
+
+	// Reload the locks
+	ls, err = newLockSystem(lockFile)
+	require.NoError(t, err)
+	assert.Len(t, ls.locks, 1)
+	_, err = ls.Refresh(now, expiredToken, time.Hour)
+	assert.Equal(t, webdav.ErrNoSuchLock, err)
+	details, err := ls.Refresh(now, token, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "<href>me</href>", details.OwnerXML)
+	release, err := ls.Confirm(now, "/file", "", webdav.Condition{Token: token})
+	require.NoError(t, err)
+	release()
+
+	// Unlocking removes the lock from the file
+	require.NoError(t, ls.Unlock(now, token))
+	ls, err = newLockSystem(lockFile)
+	require.NoError(t, err)
+	assert.Len(t, ls.locks, 0)
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/rclone/rclone/fs/config/flags"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/lib/env"
 	libhttp "github.com/rclone/rclone/lib/http"
 	"github.com/rclone/rclone/lib/http/serve"
 	"github.com/rclone/rclone/lib/systemd"
@@ -45,6 +46,10 @@ var OptionsInfo = fs.Options{{
 	Name:    "disable_dir_list",
 	Default: false,
 	Help:    "Disable HTML directory list on GET request for a directory",
+}, {
+	Name:    "lock_file",
+	Default: "",
+	Help:    "File to keep WebDAV locks in so they survive a restart, leave blank to keep them in memory",
 }}.
 	Add(libhttp.ConfigInfo).
 	Add(libhttp.AuthConfigInfo).
@@ -57,6 +62,7 @@ type Options struct {
 	Template       libhttp.TemplateConfig
 	EtagHash       string `config:"etag_hash"`
 	DisableDirList bool   `config:"disable_dir_list"`
+	LockFile       string `config:"lock_file"`
 }
 
 // Opt is options set by command line flags
@@ -117,6 +123,19 @@ supported hash on the backend or you can use a named hash such as
 "MD5" or "SHA-1". Use the [hashsum](/commands/rclone_hashsum/) command
 to see the full list.
 
+#### --lock-file
+
+The server supports WebDAV locking (class 2) which Windows, Office
+and other clients use to stop two people editing the same file at
+once. Locks are normally kept in memory, so are lost when the server
+restarts. Set ` + "`--lock-file`" + ` to a path to save the locks in and
+they will be restored when the server is next started, for example
+
+    rclone serve webdav --lock-file ~/.cache/rclone/webdav-locks.json remote:
+
+Locks only control access through this server - they don't stop the
+files being changed in the remote by other means.
+
 ### Access WebDAV on Windows
 
 WebDAV shared folder can be mapped as a drive on Windows, however the default settings prevent it.
@@ -246,10 +265,15 @@ func newWebDAV(ctx context.Context, f fs.Fs, opt *Options, vfsOpt *vfscommon.Opt
 	// Make sure BaseURL starts with a / and doesn't end with one
 	w.opt.HTTP.BaseURL = "/" + strings.Trim(w.opt.HTTP.BaseURL, "/")
 
+	lockSystem, err := newLockSystem(env.ShellExpand(w.opt.LockFile))
+	if err != nil {
+		return nil, err
+	}
+
 	webdavHandler := &webdav.Handler{
 		Prefix:     w.opt.HTTP.BaseURL,
 		FileSystem: w,
-		LockSystem: webdav.NewMemLS(),
+		LockSystem: lockSystem,
 		Logger:     w.logRequest, // FIXME
 	}
 	w.webdavhandler = webdavHandler
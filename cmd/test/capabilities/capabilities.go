@@ -0,0 +1,600 @@
+// Package capabilities provides the capabilities test command.
+package capabilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/cmd/test"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/spf13/cobra"
+)
+
+var (
+	writeJSON     string
+	keepTestFiles bool
+	fileSize      = fs.SizeSuffix(1024 * 1024)
+)
+
+func init() {
+	test.Command.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.StringVarP(cmdFlags, &writeJSON, "write-json", "", "", "Write the report as JSON to this file (- for stdout)", "")
+	flags.BoolVarP(cmdFlags, &keepTestFiles, "keep-test-files", "", false, "Keep test files after execution", "")
+	flags.FVarP(cmdFlags, &fileSize, "file-size", "", "Size of the test files", "")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "capabilities remote:path",
+	Short: `Check what a remote can do and make a conformance report.`,
+	Long: `Exercises the remote to find out which of rclone's optional features
+work on it and checks them against what the backend says it can do.
+
+It writes test files into a temporary directory in remote:path and
+checks
+
+- the hashes the backend supports are returned and correct
+- modification times are kept to the precision the backend claims
+- modification times can be changed
+- ranged reads return the right part of a file
+- uploads of files of unknown size (streaming)
+- server-side copy, move and directory move
+- reading and writing metadata
+- recursive listing with ListR gives the same result as listing each
+  directory
+
+Each check has one of these results
+
+- ` + "`ok`" + ` - the feature works
+- ` + "`fail`" + ` - the feature is advertised but doesn't work properly
+- ` + "`unsupported`" + ` - the backend doesn't have the feature
+- ` + "`skipped`" + ` - the check couldn't be run
+
+A table of the results is printed, and with ` + "`--write-json`" + ` a
+machine-readable report including the features the backend advertises
+is written too. Use ` + "`--write-json -`" + ` to write it to stdout
+instead of the table.
+
+The command exits with an error if any check fails, so it can be used
+to spot regressions in backends.
+
+**NB** this writes files to the remote and deletes them afterwards
+unless ` + "`--keep-test-files`" + ` is used.
+`,
+	Annotations: map[string]string{
+		"versionIntroduced": "v1.70",
+	},
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		tempDirPath := path.Join(args[0], "rclone-test-capabilities-"+random.String(8))
+		f := cmd.NewFsDir([]string{tempDirPath})
+		cmd.Run(false, false, command, func() error {
+			ctx := context.Background()
+			err := f.Mkdir(ctx, "")
+			if err != nil {
+				return fmt.Errorf("couldn't create temporary directory: %w", err)
+			}
+			fs.Infof(f, "Created temporary directory for test files: %s", tempDirPath)
+			if !keepTestFiles {
+				defer func() {
+					if err := operations.Purge(ctx, f, ""); err != nil {
+						fs.Errorf(f, "Failed to remove test files: %v", err)
+					}
+				}()
+			}
+			report, err := Check(ctx, f, int64(fileSize))
+			if err != nil {
+				return err
+			}
+			if writeJSON != "-" {
+				report.Print(os.Stdout)
+			}
+			if writeJSON != "" {
+				if err := report.WriteJSON(writeJSON); err != nil {
+					return err
+				}
+			}
+			if failed := report.Failed(); failed > 0 {
+				return fmt.Errorf("%d of %d checks failed", failed, len(report.Checks))
+			}
+			return nil
+		})
+	},
+}
+
+// Results of a check
+const (
+	ResultOK          = "ok"
+	ResultFail        = "fail"
+	ResultUnsupported = "unsupported"
+	ResultSkipped     = "skipped"
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the conformance report of a remote
+type Report struct {
+	Remote        string          `json:"remote"`
+	Backend       string          `json:"backend"`
+	RcloneVersion string          `json:"rcloneVersion"`
+	Time          time.Time       `json:"time"`
+	Hashes        []string        `json:"hashes"`
+	Precision     string          `json:"precision"`
+	Features      map[string]bool `json:"features"`
+	Checks        []Result        `json:"checks"`
+}
+
+// Failed returns the number of checks which failed
+func (r *Report) Failed() (n int) {
+	for _, check := range r.Checks {
+		if check.Result == ResultFail {
+			n++
+		}
+	}
+	return n
+}
+
+// Print the report as a table to out
+func (r *Report) Print(out io.Writer) {
+	fmt.Fprintf(out, "Remote:    %s\n", r.Remote)
+	fmt.Fprintf(out, "Backend:   %s\n", r.Backend)
+	fmt.Fprintf(out, "Hashes:    %s\n", strings.Join(r.Hashes, ", "))
+	fmt.Fprintf(out, "Precision: %s\n\n", r.Precision)
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "CHECK\tRESULT\tDETAIL\n")
+	for _, check := range r.Checks {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", check.Name, check.Result, check.Detail)
+	}
+	_ = w.Flush()
+}
+
+// WriteJSON writes the report as JSON to the file name or to stdout
+// if it is "-"
+func (r *Report) WriteJSON(name string) (err error) {
+	out := io.Writer(os.Stdout)
+	if name != "-" {
+		f, err := os.Create(name)
+		if err != nil {
+			return fmt.Errorf("creating JSON file failed: %w", err)
+		}
+		defer fs.CheckClose(f, &err)
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("writing JSON failed: %w", err)
+	}
+	if name != "-" {
+		fs.Infof(nil, "Wrote JSON file: %s", name)
+	}
+	return nil
+}
+
+// checker runs the checks on a remote
+type checker struct {
+	f       fs.Fs
+	report  *Report
+	data    []byte    // contents of the test file
+	modTime time.Time // modification time of the test file
+	obj     fs.Object // the test file
+}
+
+// Check runs all the checks on f, which should be an empty
+// directory, using test files of size bytes.
+//
+// It returns an error only if the checks couldn't be run at all.
+func Check(ctx context.Context, f fs.Fs, size int64) (*Report, error) {
+	c := &checker{
+		f: f,
+		report: &Report{
+			Remote:        fs.ConfigString(f),
+			Backend:       fs.Type(f),
+			RcloneVersion: fs.Version,
+			Time:          time.Now(),
+			Precision:     f.Precision().String(),
+			Features:      f.Features().Enabled(),
+			Checks:        []Result{},
+		},
+		data:    []byte(random.String(int(size))),
+		modTime: time.Date(2001, 2, 3, 4, 5, 6, 123456789, time.UTC),
+	}
+	if f.Precision() == fs.ModTimeNotSupported {
+		c.report.Precision = "none"
+	}
+	for _, ht := range f.Hashes().Array() {
+		c.report.Hashes = append(c.report.Hashes, ht.String())
+	}
+
+	var err error
+	c.obj, err = c.put(ctx, "capabilities.bin", c.data, c.modTime, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload test file: %w", err)
+	}
+
+	c.checkHashes(ctx)
+	c.run(ctx, "modtime", c.checkModTime)
+	c.run(ctx, "set-modtime", c.checkSetModTime)
+	c.run(ctx, "ranged-read", c.checkRangedRead)
+	c.run(ctx, "stream-upload", c.checkStreamUpload)
+	c.run(ctx, "copy", c.checkCopy)
+	c.run(ctx, "move", c.checkMove)
+	c.run(ctx, "dirmove", c.checkDirMove)
+	c.run(ctx, "metadata-read", c.checkMetadataRead)
+	c.run(ctx, "metadata-write", c.checkMetadataWrite)
+	c.run(ctx, "listr", c.checkListR)
+	return c.report, nil
+}
+
+// add a result to the report
+func (c *checker) add(name, result, detail string) {
+	switch result {
+	case ResultFail:
+		fs.Errorf(c.f, "%s: %s: %s", name, result, detail)
+	default:
+		fs.Infof(c.f, "%s: %s %s", name, result, detail)
+	}
+	c.report.Checks = append(c.report.Checks, Result{Name: name, Result: result, Detail: detail})
+}
+
+// run a check adding its result to the report
+func (c *checker) run(ctx context.Context, name string, check func(ctx context.Context) (result, detail string)) {
+	result, detail := check(ctx)
+	c.add(name, result, detail)
+}
+
+// put uploads data to remote
+func (c *checker) put(ctx context.Context, remote string, data []byte, modTime time.Time, meta fs.Metadata) (fs.Object, error) {
+	src := object.NewStaticObjectInfo(remote, modTime, int64(len(data)), true, nil, c.f).WithMetadata(meta)
+	return c.f.Put(ctx, bytes.NewReader(data), src)
+}
+
+// timeDiff returns the absolute difference between a and b
+func timeDiff(a, b time.Time) time.Duration {
+	dt := a.Sub(b)
+	if dt < 0 {
+		dt = -dt
+	}
+	return dt
+}
+
+// checkHashes checks each supported hash is returned and correct
+func (c *checker) checkHashes(ctx context.Context) {
+	set := c.f.Hashes()
+	if set.Count() == 0 {
+		c.add("hashes", ResultUnsupported, "")
+		return
+	}
+	hashes, err := hash.StreamTypes(bytes.NewReader(c.data), set)
+	if err != nil {
+		c.add("hashes", ResultSkipped, fmt.Sprintf("failed to calculate hashes: %v", err))
+		return
+	}
+	for _, ht := range set.Array() {
+		name := "hash-" + strings.ToLower(ht.String())
+		got, err := c.obj.Hash(ctx, ht)
+		switch {
+		case err != nil:
+			c.add(name, ResultFail, err.Error())
+		case got == "":
+			c.add(name, ResultFail, "hash not returned for uploaded file")
+		case got != hashes[ht]:
+			c.add(name, ResultFail, fmt.Sprintf("hash %s doesn't match %s", got, hashes[ht]))
+		default:
+			c.add(name, ResultOK, "")
+		}
+	}
+}
+
+// checkModTime checks the modification time is kept to the precision
+// advertised
+func (c *checker) checkModTime(ctx context.Context) (string, string) {
+	precision := c.f.Precision()
+	if precision == fs.ModTimeNotSupported {
+		return ResultUnsupported, ""
+	}
+	o, err := c.f.NewObject(ctx, c.obj.Remote())
+	if err != nil {
+		return ResultFail, fmt.Sprintf("failed to find test file: %v", err)
+	}
+	dt := timeDiff(o.ModTime(ctx), c.modTime)
+	if dt > precision {
+		return ResultFail, fmt.Sprintf("modtime out by %v which is more than the precision %v", dt, precision)
+	}
+	return ResultOK, fmt.Sprintf("out by %v", dt)
+}
+
+// checkSetModTime checks the modification time can be changed
+func (c *checker) checkSetModTime(ctx context.Context) (string, string) {
+	precision := c.f.Precision()
+	if precision == fs.ModTimeNotSupported {
+		return ResultUnsupported, ""
+	}
+	newModTime := time.Date(2011, 12, 13, 14, 15, 16, 987654321, time.UTC)
+	err := c.obj.SetModTime(ctx, newModTime)
+	if errors.Is(err, fs.ErrorCantSetModTime) || errors.Is(err, fs.ErrorCantSetModTimeWithoutDelete) {
+		return ResultUnsupported, err.Error()
+	} else if err != nil {
+		return ResultFail, err.Error()
+	}
+	o, err := c.f.NewObject(ctx, c.obj.Remote())
+	if err != nil {
+		return ResultFail, fmt.Sprintf("failed to find test file: %v", err)
+	}
+	c.obj = o
+	dt := timeDiff(o.ModTime(ctx), newModTime)
+	if dt > precision {
+		return ResultFail, fmt.Sprintf("modtime out by %v which is more than the precision %v", dt, precision)
+	}
+	return ResultOK, ""
+}
+
+// read the object with the options, returning what was read
+func read(ctx context.Context, o fs.Object, options ...fs.OpenOption) ([]byte, error) {
+	in, err := o.Open(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(in)
+	closeErr := in.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return data, err
+}
+
+// checkRangedRead checks reading parts of a file
+func (c *checker) checkRangedRead(ctx context.Context) (string, string) {
+	size := int64(len(c.data))
+	if size < 4 {
+		return ResultSkipped, "test file too small"
+	}
+	for _, test := range []struct {
+		option     fs.OpenOption
+		start, end int64
+	}{
+		{&fs.RangeOption{Start: size / 4, End: size/2 - 1}, size / 4, size / 2},
+		{&fs.RangeOption{Start: -1, End: size / 4}, size - size/4, size},
+		{&fs.SeekOption{Offset: size / 2}, size / 2, size},
+	} {
+		got, err := read(ctx, c.obj, test.option)
+		if err != nil {
+			return ResultFail, fmt.Sprintf("%v: %v", test.option, err)
+		}
+		if !bytes.Equal(got, c.data[test.start:test.end]) {
+			return ResultFail, fmt.Sprintf("%v: read %d bytes which don't match bytes %d-%d", test.option, len(got), test.start, test.end-1)
+		}
+	}
+	return ResultOK, ""
+}
+
+// checkStreamUpload checks uploading files of unknown size
+func (c *checker) checkStreamUpload(ctx context.Context) (string, string) {
+	putStream := c.f.Features().PutStream
+	if putStream == nil {
+		return ResultUnsupported, ""
+	}
+	src := object.NewStaticObjectInfo("stream.bin", c.modTime, -1, true, nil, c.f)
+	o, err := putStream(ctx, bytes.NewReader(c.data), src)
+	if err != nil {
+		return ResultFail, err.Error()
+	}
+	o, err = c.f.NewObject(ctx, o.Remote())
+	if err != nil {
+		return ResultFail, fmt.Sprintf("failed to find uploaded file: %v", err)
+	}
+	if o.Size() != int64(len(c.data)) {
+		return ResultFail, fmt.Sprintf("size is %d but should be %d", o.Size(), len(c.data))
+	}
+	return ResultOK, ""
+}
+
+// checkSame checks o is the same as the test file
+func (c *checker) checkSame(ctx context.Context, o fs.Object) (string, string) {
+	if o.Size() != int64(len(c.data)) {
+		return ResultFail, fmt.Sprintf("size is %d but should be %d", o.Size(), len(c.data))
+	}
+	equal, ht, err := operations.CheckHashes(ctx, c.obj, o)
+	if err != nil {
+		return ResultFail, fmt.Sprintf("failed to check hashes: %v", err)
+	}
+	if !equal {
+		return ResultFail, fmt.Sprintf("%v hash differs", ht)
+	}
+	return ResultOK, ""
+}
+
+// checkCopy checks server-side copy
+func (c *checker) checkCopy(ctx context.Context) (string, string) {
+	doCopy := c.f.Features().Copy
+	if doCopy == nil {
+		return ResultUnsupported, ""
+	}
+	o, err := doCopy(ctx, c.obj, "copy.bin")
+	if errors.Is(err, fs.ErrorCantCopy) {
+		return ResultFail, fmt.Sprintf("advertised but not possible: %v", err)
+	} else if err != nil {
+		return ResultFail, err.Error()
+	}
+	return c.checkSame(ctx, o)
+}
+
+// checkMove checks server-side move
+func (c *checker) checkMove(ctx context.Context) (string, string) {
+	doMove := c.f.Features().Move
+	if doMove == nil {
+		return ResultUnsupported, ""
+	}
+	src, err := c.put(ctx, "move-src.bin", c.data, c.modTime, nil)
+	if err != nil {
+		return ResultSkipped, fmt.Sprintf("failed to upload file to move: %v", err)
+	}
+	o, err := doMove(ctx, src, "move.bin")
+	if errors.Is(err, fs.ErrorCantMove) {
+		return ResultFail, fmt.Sprintf("advertised but not possible: %v", err)
+	} else if err != nil {
+		return ResultFail, err.Error()
+	}
+	if _, err := c.f.NewObject(ctx, "move-src.bin"); !errors.Is(err, fs.ErrorObjectNotFound) {
+		return ResultFail, "source still exists after move"
+	}
+	return c.checkSame(ctx, o)
+}
+
+// checkDirMove checks server-side directory move
+func (c *checker) checkDirMove(ctx context.Context) (string, string) {
+	dirMove := c.f.Features().DirMove
+	if dirMove == nil {
+		return ResultUnsupported, ""
+	}
+	_, err := c.put(ctx, "dir/file.bin", c.data, c.modTime, nil)
+	if err != nil {
+		return ResultSkipped, fmt.Sprintf("failed to upload file to move: %v", err)
+	}
+	err = dirMove(ctx, c.f, "dir", "dir-moved")
+	if errors.Is(err, fs.ErrorCantDirMove) {
+		return ResultFail, fmt.Sprintf("advertised but not possible: %v", err)
+	} else if err != nil {
+		return ResultFail, err.Error()
+	}
+	o, err := c.f.NewObject(ctx, "dir-moved/file.bin")
+	if err != nil {
+		return ResultFail, fmt.Sprintf("moved file not found: %v", err)
+	}
+	if _, err := c.f.NewObject(ctx, "dir/file.bin"); !errors.Is(err, fs.ErrorObjectNotFound) {
+		return ResultFail, "source still exists after move"
+	}
+	return c.checkSame(ctx, o)
+}
+
+// checkMetadataRead checks metadata can be read
+func (c *checker) checkMetadataRead(ctx context.Context) (string, string) {
+	if !c.f.Features().ReadMetadata {
+		return ResultUnsupported, ""
+	}
+	metadata, err := fs.GetMetadata(ctx, c.obj)
+	if err != nil {
+		return ResultFail, err.Error()
+	}
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return ResultOK, "keys: " + strings.Join(keys, ", ")
+}
+
+// checkMetadataWrite checks metadata can be written by setting the
+// modification time with it
+func (c *checker) checkMetadataWrite(ctx context.Context) (string, string) {
+	if !c.f.Features().WriteMetadata {
+		return ResultUnsupported, ""
+	}
+	ri, err := fs.Find(fs.Type(c.f))
+	if err != nil || ri.MetadataInfo == nil {
+		return ResultSkipped, "backend has no metadata help"
+	}
+	if help, ok := ri.MetadataInfo.System["mtime"]; !ok || help.ReadOnly {
+		return ResultSkipped, "backend has no writable mtime metadata to test with"
+	}
+	ctx, ci := fs.AddConfig(ctx)
+	ci.Metadata = true
+	mtime := time.Date(2005, 6, 7, 8, 9, 10, 0, time.UTC)
+	o, err := c.put(ctx, "metadata.bin", c.data, c.modTime, fs.Metadata{"mtime": mtime.Format(time.RFC3339Nano)})
+	if err != nil {
+		return ResultFail, err.Error()
+	}
+	o, err = c.f.NewObject(ctx, o.Remote())
+	if err != nil {
+		return ResultFail, fmt.Sprintf("failed to find uploaded file: %v", err)
+	}
+	if dt := timeDiff(o.ModTime(ctx), mtime); c.f.Precision() != fs.ModTimeNotSupported && dt > c.f.Precision() {
+		return ResultFail, fmt.Sprintf("mtime from metadata out by %v", dt)
+	}
+	metadata, err := fs.GetMetadata(ctx, o)
+	if err != nil {
+		return ResultFail, fmt.Sprintf("failed to read metadata back: %v", err)
+	}
+	got, err := time.Parse(time.RFC3339Nano, metadata["mtime"])
+	if err != nil {
+		return ResultFail, fmt.Sprintf("failed to read mtime back: %v", err)
+	}
+	if dt := timeDiff(got, mtime); dt > time.Second {
+		return ResultFail, fmt.Sprintf("mtime read back out by %v", dt)
+	}
+	return ResultOK, ""
+}
+
+// listDir lists the objects in dir and its subdirectories one
+// directory at a time
+func (c *checker) listDir(ctx context.Context, dir string) (objects []string, err error) {
+	entries, err := c.f.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		switch entry := entry.(type) {
+		case fs.Object:
+			objects = append(objects, entry.Remote())
+		case fs.Directory:
+			sub, err := c.listDir(ctx, entry.Remote())
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, sub...)
+		}
+	}
+	return objects, nil
+}
+
+// checkListR checks ListR finds the same objects as List
+func (c *checker) checkListR(ctx context.Context) (string, string) {
+	listR := c.f.Features().ListR
+	if listR == nil {
+		return ResultUnsupported, ""
+	}
+	want, err := c.listDir(ctx, "")
+	if err != nil {
+		return ResultSkipped, fmt.Sprintf("failed to list: %v", err)
+	}
+	var got []string
+	err = listR(ctx, "", func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			if o, ok := entry.(fs.Object); ok {
+				got = append(got, o.Remote())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ResultFail, err.Error()
+	}
+	sort.Strings(want)
+	sort.Strings(got)
+	if !slices.Equal(got, want) {
+		return ResultFail, fmt.Sprintf("found %q but List found %q", got, want)
+	}
+	return ResultOK, fmt.Sprintf("%d files", len(got))
+}
@@ -0,0 +1,202 @@
+package tree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/a8m/tree"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/dirtree"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// dirStats holds the total size and number of files in a directory
+// and all its subdirectories
+type dirStats struct {
+	size  int64
+	count int64
+}
+
+// sumDirs works out the dirStats for dir and all the directories
+// below it in dirs, storing them in stats.
+//
+// Hidden entries are left out unless showAll is set, as they are
+// when listing.
+func sumDirs(dirs dirtree.DirTree, dir string, showAll bool, stats map[string]dirStats) (total dirStats) {
+	for _, entry := range dirs[dir] {
+		if !showAll && strings.HasPrefix(path.Base(entry.Remote()), ".") {
+			continue
+		}
+		switch x := entry.(type) {
+		case fs.Object:
+			if size := x.Size(); size > 0 {
+				total.size += size
+			}
+			total.count++
+		case fs.Directory:
+			sub := sumDirs(dirs, x.Remote(), showAll, stats)
+			total.size += sub.size
+			total.count += sub.count
+		}
+	}
+	stats[dir] = total
+	return total
+}
+
+// columns prints the properties of each entry before its name.
+//
+// The tree library can't find the hashes of files, or the sizes of
+// directories below the --level it shows, so rclone prints all the
+// properties itself using the library's Color hook, which is called
+// with each name just before it is printed.
+type columns struct {
+	ctx      context.Context
+	opts     tree.Options // the properties to show
+	hashType hash.Type
+	stats    map[string]dirStats
+	now      time.Time
+}
+
+// newColumns returns the columns to show for opts or nil if none are
+// needed.
+func newColumns(ctx context.Context, dirs dirtree.DirTree, opts *tree.Options, hashType hash.Type) *columns {
+	if !opts.FileMode && !opts.ByteSize && !opts.UnitSize && !opts.LastMod && hashType == hash.None {
+		return nil
+	}
+	c := &columns{
+		ctx:      ctx,
+		opts:     *opts,
+		hashType: hashType,
+		stats:    map[string]dirStats{},
+		now:      opts.Now,
+	}
+	if c.now.IsZero() {
+		c.now = time.Now()
+	}
+	sumDirs(dirs, "", opts.All, c.stats)
+	return c
+}
+
+// install makes opts print the columns instead of the tree library
+func (c *columns) install(opts *tree.Options) {
+	opts.FileMode = false
+	opts.ByteSize = false
+	opts.UnitSize = false
+	opts.LastMod = false
+	opts.Colorize = true
+	opts.Color = c.color
+}
+
+// size formats size as the tree library does
+func (c *columns) size(size int64) string {
+	if c.opts.UnitSize {
+		return fmt.Sprintf("%4s", formatBytes(size))
+	}
+	return fmt.Sprintf("%11d", size)
+}
+
+// hash returns the hash of o for the hash column
+func (c *columns) hash(o fs.Object) string {
+	sum, err := o.Hash(c.ctx, c.hashType)
+	if errors.Is(err, hash.ErrUnsupported) {
+		sum = "UNSUPPORTED"
+	} else if err != nil {
+		fs.Errorf(o, "Failed to read %v: %v", c.hashType, err)
+		sum = "ERROR"
+	}
+	return fmt.Sprintf("%-*s", hash.Width(c.hashType, false), sum)
+}
+
+// color is the tree.Options.Color hook which prefixes name with the
+// properties of node and colors it if required
+func (c *columns) color(node *tree.Node, name string) string {
+	if c.opts.Colorize {
+		if c.opts.Color != nil {
+			name = c.opts.Color(node, name)
+		} else {
+			name = tree.ANSIColor(node, name)
+		}
+	}
+	fi, ok := node.FileInfo.(*FileInfo)
+	if !ok {
+		return name
+	}
+	var props []string
+	if o, isFile := fi.entry.(fs.Object); isFile {
+		if c.opts.FileMode {
+			props = append(props, fi.Mode().String())
+		}
+		if c.opts.ByteSize || c.opts.UnitSize {
+			props = append(props, c.size(o.Size()))
+		}
+		if c.opts.LastMod {
+			modTime := fi.ModTime()
+			format := "Jan 02 15:04"
+			if modTime.Year() != c.now.Year() {
+				format = "Jan 02  2006"
+			}
+			props = append(props, modTime.Format(format))
+		}
+		if c.hashType != hash.None {
+			props = append(props, c.hash(o))
+		}
+	} else if c.opts.ByteSize || c.opts.UnitSize {
+		props = append(props, c.size(c.stats[fi.entry.Remote()].size))
+	}
+	if len(props) == 0 {
+		return name
+	}
+	return "[" + strings.Join(props, " ") + "]  " + name
+}
+
+// Sizes used by formatBytes
+const (
+	_        = iota // ignore first value by assigning to blank identifier
+	kb int64 = 1 << (10 * iota)
+	mb
+	gb
+	tb
+	pb
+	eb
+)
+
+// formatBytes formats i for human readable output
+//
+// This is copied from github.com/a8m/tree so the output matches
+func formatBytes(i int64) (result string) {
+	var n float64
+	sFmt, eFmt := "%.01f", ""
+	switch {
+	case i > eb:
+		eFmt = "E"
+		n = float64(i) / float64(eb)
+	case i > pb:
+		eFmt = "P"
+		n = float64(i) / float64(pb)
+	case i > tb:
+		eFmt = "T"
+		n = float64(i) / float64(tb)
+	case i > gb:
+		eFmt = "G"
+		n = float64(i) / float64(gb)
+	case i > mb:
+		eFmt = "M"
+		n = float64(i) / float64(mb)
+	case i > kb:
+		eFmt = "K"
+		n = float64(i) / float64(kb)
+	default:
+		sFmt = "%.0f"
+		n = float64(i)
+	}
+	if eFmt != "" && n >= 10 {
+		sFmt = "%.0f"
+	}
+	result = fmt.Sprintf(sFmt+eFmt, n)
+	result = strings.Trim(result, " ")
+	return
+}
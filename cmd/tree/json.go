@@ -0,0 +1,87 @@
+package tree
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/a8m/tree"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/dirtree"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// jsonEntry is an entry in the tree as written by --format json
+type jsonEntry struct {
+	Path    string
+	Name    string
+	Size    int64
+	ModTime *time.Time `json:",omitempty"`
+	IsDir   bool
+	Hashes  map[string]string `json:",omitempty"`
+	Count   int64             `json:",omitempty"` // number of files in a directory
+	Entries []*jsonEntry      `json:",omitempty"`
+}
+
+// jsonTree makes the jsonEntry for the directory dir at depth and the
+// entries below it down to --level
+func jsonTree(ctx context.Context, dirs dirtree.DirTree, dir string, depth int, opts *tree.Options, hashType hash.Type, stats map[string]dirStats) (entries []*jsonEntry) {
+	if opts.DeepLevel > 0 && depth >= opts.DeepLevel {
+		return nil
+	}
+	for _, entry := range dirs[dir] {
+		remote := entry.Remote()
+		name := path.Base(remote)
+		if !opts.All && strings.HasPrefix(name, ".") {
+			continue
+		}
+		modTime := entry.ModTime(ctx)
+		item := &jsonEntry{
+			Path:    enc.FromStandardPath(remote),
+			Name:    enc.FromStandardName(name),
+			ModTime: &modTime,
+		}
+		switch x := entry.(type) {
+		case fs.Object:
+			if opts.DirsOnly {
+				continue
+			}
+			item.Size = x.Size()
+			if hashType != hash.None {
+				sum, err := x.Hash(ctx, hashType)
+				if err != nil && !errors.Is(err, hash.ErrUnsupported) {
+					fs.Errorf(x, "Failed to read %v: %v", hashType, err)
+				}
+				if sum != "" {
+					item.Hashes = map[string]string{hashType.String(): sum}
+				}
+			}
+		case fs.Directory:
+			item.IsDir = true
+			item.Size = stats[remote].size
+			item.Count = stats[remote].count
+			item.Entries = jsonTree(ctx, dirs, remote, depth+1, opts, hashType, stats)
+		}
+		entries = append(entries, item)
+	}
+	return entries
+}
+
+// writeJSON writes dirs to w as a JSON tree
+func writeJSON(ctx context.Context, dirs dirtree.DirTree, w io.Writer, opts *tree.Options, hashType hash.Type) error {
+	stats := map[string]dirStats{}
+	total := sumDirs(dirs, "", opts.All, stats)
+	root := &jsonEntry{
+		IsDir:   true,
+		Size:    total.size,
+		Count:   total.count,
+		Entries: jsonTree(ctx, dirs, "", 0, opts, hashType, stats),
+	}
+	out := json.NewEncoder(w)
+	out.SetIndent("", "\t")
+	return out.Encode(root)
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/flags"
 	"github.com/rclone/rclone/fs/dirtree"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/log"
 	"github.com/rclone/rclone/fs/walk"
 	"github.com/rclone/rclone/lib/encoder"
@@ -28,6 +29,8 @@ var (
 	outFileName string
 	noReport    bool
 	sort        string
+	hashType    = hash.None
+	format      = "text"
 	enc         = encoder.OS
 )
 
@@ -52,6 +55,7 @@ func init() {
 	// flags.BoolVarP(cmdFlags, &opts.ShowGid, "gid", "", false, "Displays file group owner or GID number.")
 	flags.BoolVarP(cmdFlags, &opts.Quotes, "quote", "Q", false, "Quote filenames with double quotes.", "")
 	flags.BoolVarP(cmdFlags, &opts.LastMod, "modtime", "D", false, "Print the date of last modification.", "")
+	flags.FVarP(cmdFlags, &hashType, "hash", "", "Print the hash of this type for each file, e.g. MD5", "")
 	// flags.BoolVarP(cmdFlags, &opts.Inodes, "inodes", "", false, "Print inode number of each file.")
 	// flags.BoolVarP(cmdFlags, &opts.Device, "device", "", false, "Print device ID number to which each file belongs.")
 	// Sort
@@ -64,6 +68,8 @@ func init() {
 	flags.StringVarP(cmdFlags, &sort, "sort", "", "", "Select sort: name,version,size,mtime,ctime", "")
 	// Graphics
 	flags.BoolVarP(cmdFlags, &opts.NoIndent, "noindent", "", false, "Don't print indentation lines", "")
+	// Output
+	flags.StringVarP(cmdFlags, &format, "format", "", format, "Output format: text or json", "")
 }
 
 var commandDefinition = &cobra.Command{
@@ -92,6 +98,27 @@ are compatible with the tree command, for example you can include file
 sizes with ` + "`--size`" + `.  Note that not all of them have
 short options as they conflict with rclone's short options.
 
+Use ` + "`--hash MD5`" + ` (or any other hash the remote supports) to
+show the hash of each file next to it.
+
+When ` + "`--size`" + ` is used the size shown for each directory is
+the total size of all the files in it and its subdirectories, even
+those below the ` + "`--level`" + ` shown, so
+
+    rclone tree --size --level 1 remote:path
+
+shows the total size of each top level directory. Note that this
+means the whole remote is listed.
+
+Use ` + "`--format json`" + ` to output the tree as JSON instead. Each
+entry has its ` + "`Path`" + `, ` + "`Name`" + `, ` + "`Size`" + `,
+` + "`ModTime`" + ` and ` + "`IsDir`" + `, with ` + "`Hashes`" + ` if
+` + "`--hash`" + ` is used. Directories have the total ` + "`Size`" + `
+and ` + "`Count`" + ` of the files in them and their subdirectories and
+their ` + "`Entries`" + ` down to the ` + "`--level`" + ` given. The
+` + "`--all`" + `, ` + "`--dirs-only`" + ` and ` + "`--level`" + ` flags
+are obeyed but the other flags controlling the output aren't.
+
 For a more interactive navigation of the remote see the
 [ncdu](/commands/rclone_ncdu/) command.
 `,
@@ -101,6 +128,9 @@ For a more interactive navigation of the remote see the
 	},
 	RunE: func(command *cobra.Command, args []string) error {
 		cmd.CheckArgs(1, 1, command, args)
+		if format != "text" && format != "json" {
+			return fmt.Errorf("unknown --format %q: use text or json", format)
+		}
 		fsrc := cmd.NewFsSrc(args)
 		ci := fs.GetConfig(context.Background())
 		var outFile io.Writer
@@ -111,6 +141,8 @@ For a more interactive navigation of the remote see the
 				return fmt.Errorf("failed to create output file: %w", err)
 			}
 			opts.Colorize = false
+		} else if format == "json" {
+			outFile = os.Stdout
 		} else {
 			terminal.Start()
 			outFile = terminal.Out
@@ -134,10 +166,26 @@ For a more interactive navigation of the remote see the
 
 // Tree lists fsrc to outFile using the Options passed in
 func Tree(fsrc fs.Fs, outFile io.Writer, opts *tree.Options) error {
-	dirs, err := walk.NewDirTree(context.Background(), fsrc, "", false, opts.DeepLevel)
+	ctx := context.Background()
+	// Directory sizes need the whole tree to include the entries
+	// below the --level shown
+	depth := opts.DeepLevel
+	if opts.ByteSize || opts.UnitSize || format == "json" {
+		depth = fs.GetConfig(ctx).MaxDepth
+	}
+	dirs, err := walk.NewDirTree(ctx, fsrc, "", false, depth)
 	if err != nil {
 		return err
 	}
+	if format == "json" {
+		return writeJSON(ctx, dirs, outFile, opts, hashType)
+	}
+	if c := newColumns(ctx, dirs, opts, hashType); c != nil {
+		// Don't change the caller's options
+		newOpts := *opts
+		opts = &newOpts
+		c.install(opts)
+	}
 	opts.Fs = NewFs(dirs)
 	opts.OutFile = outFile
 	inf := tree.New("/")
@@ -3,11 +3,15 @@ package tree
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/a8m/tree"
 	_ "github.com/rclone/rclone/backend/local"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -33,3 +37,85 @@ func TestTree(t *testing.T) {
 1 directories, 5 files
 `, buf.String())
 }
+
+// makeTree makes a directory tree with files of known sizes
+func makeTree(t *testing.T) fs.Fs {
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"a.txt":         "hello",
+		"dir/b.txt":     "abc",
+		"dir/sub/c.txt": "abcd",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0777))
+		require.NoError(t, os.WriteFile(p, []byte(contents), 0666))
+	}
+	f, err := fs.NewFs(context.Background(), dir)
+	require.NoError(t, err)
+	return f
+}
+
+func TestTreeColumns(t *testing.T) {
+	f := makeTree(t)
+	oldHashType := hashType
+	hashType = hash.MD5
+	defer func() {
+		hashType = oldHashType
+	}()
+
+	buf := new(bytes.Buffer)
+	opts := &tree.Options{
+		ByteSize:  true,
+		DeepLevel: 1,
+	}
+	err := Tree(f, buf, opts)
+	require.NoError(t, err)
+	assert.Equal(t, `[         12]  /
+├── [          5 5d41402abc4b2a76b9719d911017c592]  a.txt
+└── [          7]  dir
+
+1 directories, 1 files
+`, buf.String())
+	assert.Nil(t, opts.Color, "caller's options changed")
+}
+
+func TestTreeJSON(t *testing.T) {
+	f := makeTree(t)
+	oldFormat := format
+	format = "json"
+	defer func() {
+		format = oldFormat
+	}()
+
+	buf := new(bytes.Buffer)
+	err := Tree(f, buf, &tree.Options{DeepLevel: 2})
+	require.NoError(t, err)
+
+	var root jsonEntry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &root))
+	assert.True(t, root.IsDir)
+	assert.Equal(t, int64(12), root.Size)
+	assert.Equal(t, int64(3), root.Count)
+	require.Len(t, root.Entries, 2)
+
+	a := root.Entries[0]
+	assert.Equal(t, "a.txt", a.Path)
+	assert.Equal(t, int64(5), a.Size)
+	assert.False(t, a.IsDir)
+	assert.Nil(t, a.Hashes)
+
+	dir := root.Entries[1]
+	assert.Equal(t, "dir", dir.Path)
+	assert.True(t, dir.IsDir)
+	assert.Equal(t, int64(7), dir.Size)
+	assert.Equal(t, int64(2), dir.Count)
+	require.Len(t, dir.Entries, 2)
+
+	// dir/sub is at the --level limit so has a size but no entries
+	sub := dir.Entries[1]
+	assert.Equal(t, "dir/sub", sub.Path)
+	assert.Equal(t, "sub", sub.Name)
+	assert.Equal(t, int64(4), sub.Size)
+	assert.Equal(t, int64(1), sub.Count)
+	assert.Nil(t, sub.Entries)
+}
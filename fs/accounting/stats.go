@@ -782,6 +782,16 @@ func (s *StatsInfo) Error(err error) error {
 	s.lastError = err
 	err = fserrors.FsError(err)
 	fserrors.Count(err)
+	if rc.EventsWanted() {
+		rc.PublishEvent(rc.Event{
+			Type:  rc.EventError,
+			Group: s.group,
+			Data: rc.Params{
+				"error":         err.Error(),
+				"errorCategory": string(fserrors.GetCategory(err)),
+			},
+		})
+	}
 	switch {
 	case fserrors.IsFatalError(err):
 		s.fatalError = true
@@ -50,6 +50,24 @@ Returns the following values:
 	})
 }
 
+// extraStats are the stats added to core/stats by AddRemoteStats
+var extraStats = struct {
+	mu  sync.Mutex
+	fns map[string]func() any
+}{
+	fns: map[string]func() any{},
+}
+
+// AddRemoteStats adds the value fn returns as key to the stats of all
+// the groups returned by core/stats.
+//
+// This is for backends to return stats of their own.
+func AddRemoteStats(key string, fn func() any) {
+	extraStats.mu.Lock()
+	defer extraStats.mu.Unlock()
+	extraStats.fns[key] = fn
+}
+
 func rcRemoteStats(ctx context.Context, in rc.Params) (rc.Params, error) {
 	// Check to see if we should filter by group.
 	group, err := in.GetString("group")
@@ -62,7 +80,18 @@ func rcRemoteStats(ctx context.Context, in rc.Params) (rc.Params, error) {
 		return StatsGroup(ctx, group).RemoteStats(short)
 	}
 
-	return groups.sum(ctx).RemoteStats(short)
+	out, err := groups.sum(ctx).RemoteStats(short)
+	if err != nil {
+		return nil, err
+	}
+	extraStats.mu.Lock()
+	defer extraStats.mu.Unlock()
+	for key, fn := range extraStats.fns {
+		if value := fn(); value != nil {
+			out[key] = value
+		}
+	}
+	return out, nil
 }
 
 func init() {
@@ -126,6 +155,9 @@ Returns the following values:
 ` + "```" + `
 Values for "transferring", "checking" and "lastError" are only assigned if data is available.
 The value for "eta" is null if an eta cannot be determined.
+
+If group is not provided then backends may add stats of their own,
+e.g. "union" for the upstreams of union remotes.
 `,
 	})
 }
@@ -89,6 +89,13 @@ func newTransferRemoteSize(stats *StatsInfo, remote string, size int64, checking
 		dstFs:     dstFs,
 	}
 	stats.AddTransfer(tr)
+	if !checking && rc.EventsWanted() {
+		rc.PublishEvent(rc.Event{
+			Type:  rc.EventTransferStart,
+			Group: stats.group,
+			Data:  tr.rcStats(),
+		})
+	}
 	return tr
 }
 
@@ -127,6 +134,13 @@ func (tr *Transfer) Done(ctx context.Context, err error) {
 		tr.stats.DoneChecking(tr.remote)
 	} else {
 		tr.stats.DoneTransferring(tr.remote, err == nil)
+		if rc.EventsWanted() {
+			rc.PublishEvent(rc.Event{
+				Type:  rc.EventTransferFinish,
+				Group: tr.stats.group,
+				Data:  tr.rcFinishStats(err),
+			})
+		}
 	}
 	tr.stats.PruneTransfers()
 }
@@ -217,3 +231,17 @@ func (tr *Transfer) rcStats() rc.Params {
 	}
 	return out
 }
+
+// rcFinishStats returns stats for the finished transfer suitable for
+// the rc
+func (tr *Transfer) rcFinishStats(err error) rc.Params {
+	out := tr.rcStats()
+	snapshot := tr.Snapshot()
+	out["bytes"] = snapshot.Bytes
+	out["duration"] = snapshot.CompletedAt.Sub(snapshot.StartedAt).Seconds()
+	out["success"] = err == nil
+	if err != nil {
+		out["error"] = err.Error()
+	}
+	return out
+}
@@ -36,6 +36,13 @@ var (
 	// implementation from the fs
 	ConfigFileHasSection = func(section string) bool { return false }
 
+	// Read a value for remote from the profile in use, or a
+	// global option if remote is ""
+	//
+	// This is a function pointer to decouple the config
+	// implementation from the fs
+	ConfigProfileGet = func(remote, key string) (string, bool) { return "", false }
+
 	// CountError counts an error.  If any errors have been
 	// counted then rclone will exit with a non zero error code.
 	//
@@ -141,6 +148,21 @@ var ConfigOptionsInfo = Options{{
 	Default: SpaceSepList{},
 	Help:    "Command for supplying password for encrypted configuration",
 	Groups:  "Config",
+}, {
+	Name:    "profile",
+	Default: "",
+	Help:    "Use the named profile from the config file to override options",
+	Groups:  "Config",
+}, {
+	Name:    "config_keychain",
+	Default: false,
+	Help:    "Keep the config password and remote secrets in the OS keychain",
+	Groups:  "Config",
+}, {
+	Name:    "secret_cache_time",
+	Default: 5 * time.Minute,
+	Help:    "How long to cache secrets read from secret managers",
+	Groups:  "Config",
 }, {
 	Name:    "max_delete",
 	Default: int64(-1),
@@ -262,6 +284,11 @@ var ConfigOptionsInfo = Options{{
 	Default: "",
 	Help:    "Make backups into hierarchy based in DIR",
 	Groups:  "Sync",
+}, {
+	Name:    "snapshot_dir",
+	Default: "",
+	Help:    "Make a timestamped snapshot of the destination in DIR after each sync",
+	Groups:  "Sync",
 }, {
 	Name:    "suffix",
 	Default: "",
@@ -292,6 +319,11 @@ var ConfigOptionsInfo = Options{{
 	Default: 1,
 	Help:    "Max burst of transactions for --tpslimit",
 	Groups:  "Networking",
+}, {
+	Name:    "adaptive_pacer",
+	Default: false,
+	Help:    "Probe for the highest rate of API calls each remote allows rather than using fixed pacing",
+	Groups:  "Networking",
 }, {
 	Name:    "user_agent",
 	Default: "rclone/" + Version,
@@ -423,6 +455,11 @@ var ConfigOptionsInfo = Options{{
 	Default: SizeSuffix(-1),
 	Help:    "If set, don't allocate more than this amount of memory as buffers",
 	Groups:  "Config",
+}, {
+	Name:    "multipart_disk_buffer",
+	Default: false,
+	Help:    "Buffer the chunks of multipart uploads in temporary files instead of memory",
+	Groups:  "Config",
 }, {
 	Name:    "ca_cert",
 	Default: []string{},
@@ -448,6 +485,11 @@ var ConfigOptionsInfo = Options{{
 	Default: 4,
 	Help:    "Number of streams to use for multi-thread downloads",
 	Groups:  "Copy",
+}, {
+	Name:    "multi_thread_streams_max",
+	Default: 0,
+	Help:    "If set, adapt the number of multi-thread streams up to this to suit the throughput",
+	Groups:  "Copy",
 }, {
 	Name:    "multi_thread_write_buffer_size",
 	Default: SizeSuffix(128 * 1024),
@@ -458,6 +500,11 @@ var ConfigOptionsInfo = Options{{
 	Default: SizeSuffix(64 * 1024 * 1024),
 	Help:    "Chunk size for multi-thread downloads / uploads, if not set by filesystem",
 	Groups:  "Copy",
+}, {
+	Name:    "multi_thread_temp_file",
+	Default: false,
+	Help:    "Use a temporary file for multi-thread downloads to backends which can't write in parallel",
+	Groups:  "Copy",
 }, {
 	Name:    "use_json_log",
 	Default: false,
@@ -493,6 +540,26 @@ var ConfigOptionsInfo = Options{{
 	Default: false,
 	Help:    "Disable HTTP/2 in the global transport",
 	Groups:  "Networking",
+}, {
+	Name:    "http3",
+	Default: false,
+	Help:    "Use HTTP/3 (QUIC) with servers which advertise it",
+	Groups:  "Networking",
+}, {
+	Name:    "max_conns_per_host",
+	Default: 0,
+	Help:    "Max number of HTTP connections to each host, 0 for unlimited",
+	Groups:  "Networking",
+}, {
+	Name:    "idle_conn_timeout",
+	Default: 60 * time.Second,
+	Help:    "Close idle HTTP connections after this long",
+	Groups:  "Networking",
+}, {
+	Name:    "happy_eyeballs_delay",
+	Default: 300 * time.Millisecond,
+	Help:    "Delay before trying the other IP version when connecting, negative to disable",
+	Groups:  "Networking",
 }, {
 	Name:    "human_readable",
 	Default: false,
@@ -534,6 +601,11 @@ var ConfigOptionsInfo = Options{{
 	Default: false,
 	Help:    "Download directly to destination file instead of atomic download to temp/rename",
 	Groups:  "Copy",
+}, {
+	Name:    "write_changed_blocks",
+	Default: false,
+	Help:    "Update existing files in place by only writing the blocks which have changed",
+	Groups:  "Copy",
 }, {
 	Name:    "metadata_mapper",
 	Default: SpaceSepList{},
@@ -618,6 +690,7 @@ type ConfigInfo struct {
 	CompareDest                []string          `config:"compare_dest"`
 	CopyDest                   []string          `config:"copy_dest"`
 	BackupDir                  string            `config:"backup_dir"`
+	SnapshotDir                string            `config:"snapshot_dir"`
 	Suffix                     string            `config:"suffix"`
 	SuffixKeepExtension        bool              `config:"suffix_keep_extension"`
 	UseListR                   bool              `config:"fast_list"`
@@ -627,6 +700,7 @@ type ConfigInfo struct {
 	BwLimitFile                BwTimetable       `config:"bwlimit_file"`
 	TPSLimit                   float64           `config:"tpslimit"`
 	TPSLimitBurst              int               `config:"tpslimit_burst"`
+	AdaptivePacer              bool              `config:"adaptive_pacer"`
 	BindAddr                   net.IP            `config:"bind_addr"`
 	DisableFeatures            []string          `config:"disable"`
 	UserAgent                  string            `config:"user_agent"`
@@ -636,6 +710,9 @@ type ConfigInfo struct {
 	StatsFileNameLength        int               `config:"stats_file_name_length"`
 	AskPassword                bool              `config:"ask_password"`
 	PasswordCommand            SpaceSepList      `config:"password_command"`
+	ConfigKeychain             bool              `config:"config_keychain"`
+	SecretCacheTime            time.Duration     `config:"secret_cache_time"`
+	Profile                    string            `config:"profile"`
 	UseServerModTime           bool              `config:"use_server_modtime"`
 	MaxTransfer                SizeSuffix        `config:"max_transfer"`
 	MaxDuration                time.Duration     `config:"max_duration"`
@@ -651,14 +728,17 @@ type ConfigInfo struct {
 	Cookie                     bool              `config:"use_cookies"`
 	UseMmap                    bool              `config:"use_mmap"`
 	MaxBufferMemory            SizeSuffix        `config:"max_buffer_memory"`
+	MultipartDiskBuffer        bool              `config:"multipart_disk_buffer"`
 	CaCert                     []string          `config:"ca_cert"`     // Client Side CA
 	ClientCert                 string            `config:"client_cert"` // Client Side Cert
 	ClientKey                  string            `config:"client_key"`  // Client Side Key
 	MultiThreadCutoff          SizeSuffix        `config:"multi_thread_cutoff"`
 	MultiThreadStreams         int               `config:"multi_thread_streams"`
+	MultiThreadStreamsMax      int               `config:"multi_thread_streams_max"`
 	MultiThreadSet             bool              `config:"multi_thread_set"`        // whether MultiThreadStreams was set (set in fs/config/configflags)
 	MultiThreadChunkSize       SizeSuffix        `config:"multi_thread_chunk_size"` // Chunk size for multi-thread downloads / uploads, if not set by filesystem
 	MultiThreadWriteBufferSize SizeSuffix        `config:"multi_thread_write_buffer_size"`
+	MultiThreadTempFile        bool              `config:"multi_thread_temp_file"`
 	OrderBy                    string            `config:"order_by"` // instructions on how to order the transfer
 	UploadHeaders              []*HTTPOption     `config:"upload_headers"`
 	DownloadHeaders            []*HTTPOption     `config:"download_headers"`
@@ -670,6 +750,10 @@ type ConfigInfo struct {
 	FsCacheExpireDuration      time.Duration     `config:"fs_cache_expire_duration"`
 	FsCacheExpireInterval      time.Duration     `config:"fs_cache_expire_interval"`
 	DisableHTTP2               bool              `config:"disable_http2"`
+	HTTP3                      bool              `config:"http3"`
+	MaxConnsPerHost            int               `config:"max_conns_per_host"`
+	IdleConnTimeout            time.Duration     `config:"idle_conn_timeout"`
+	HappyEyeballsDelay         time.Duration     `config:"happy_eyeballs_delay"`
 	HumanReadable              bool              `config:"human_readable"`
 	KvLockTime                 time.Duration     `config:"kv_lock_time"` // maximum time to keep key-value database locked by process
 	DisableHTTPKeepAlives      bool              `config:"disable_http_keep_alives"`
@@ -678,6 +762,7 @@ type ConfigInfo struct {
 	TerminalColorMode          TerminalColorMode `config:"color"`
 	DefaultTime                Time              `config:"default_time"` // time that directories with no time should display
 	Inplace                    bool              `config:"inplace"`      // Download directly to destination file instead of atomic download to temp/rename
+	WriteChangedBlocks         bool              `config:"write_changed_blocks"`
 	PartialSuffix              string            `config:"partial_suffix"`
 	MetadataMapper             SpaceSepList      `config:"metadata_mapper"`
 	MaxConnections             int               `config:"max_connections"`
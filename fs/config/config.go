@@ -123,7 +123,7 @@ var (
 
 func init() {
 	// Set the function pointers up in fs
-	fs.ConfigFileGet = FileGetValue
+	fs.ConfigFileGet = fileGetSecretValue
 	fs.ConfigFileSet = SetValueAndSave
 	fs.ConfigFileHasSection = func(section string) bool {
 		return LoadedData().HasSection(section)
@@ -396,9 +396,10 @@ func SaveConfig() {
 	fs.Errorf(nil, "Failed to save config after %d tries: %v", ci.LowLevelRetries, err)
 }
 
-// FileSections returns the sections in the config file
+// FileSections returns the sections in the config file which are
+// remotes, leaving out the profiles
 func FileSections() []string {
-	return LoadedData().GetSectionList()
+	return remoteSections()
 }
 
 // FileGetValue gets the config key under section returning the
@@ -407,17 +408,70 @@ func FileGetValue(section, key string) (string, bool) {
 	return LoadedData().GetValue(section, key)
 }
 
+// fileGetKeychainValue gets the config key under section like
+// FileGetValue but reads the value from the keychain if it is stored
+// there.
+func fileGetKeychainValue(section, key string) (string, bool) {
+	value, ok := FileGetValue(section, key)
+	if !ok || !IsKeychainRef(value) {
+		return value, ok
+	}
+	secret, err := ResolveKeychainRef(value)
+	if err != nil {
+		fs.Errorf(nil, "Remote %q: %v", section, err)
+		return "", false
+	}
+	return secret, true
+}
+
+// fileGetSecretValue gets the config key under section like
+// fileGetKeychainValue and replaces any references to secrets in
+// secret managers.
+//
+// This is only used to read the config to create backends, so that
+// showing the config doesn't reveal the secrets or read them.
+func fileGetSecretValue(section, key string) (string, bool) {
+	value, ok := fileGetKeychainValue(section, key)
+	if !ok {
+		return value, ok
+	}
+	if HasSecretRef(value) {
+		secret, err := ResolveSecretRefs(context.Background(), value)
+		if err != nil {
+			fs.Errorf(nil, "Remote %q: %v", section, err)
+			return "", false
+		}
+		// Secret managers hold passwords in plain text, but the
+		// backends expect them obscured as in the config file
+		if opt := findOption(section, key); opt != nil && opt.IsPassword {
+			secret = obscure.MustObscure(secret)
+		}
+		value = secret
+	}
+	return value, true
+}
+
 // FileSetValue sets the key in section to value.
 // It doesn't save the config file.
+//
+// If --config-keychain is in use secrets are stored in the keychain
+// with a reference to them in the config file.
 func FileSetValue(section, key, value string) {
-	LoadedData().SetValue(section, key, value)
+	oldValue, _ := LoadedData().GetValue(section, key)
+	LoadedData().SetValue(section, key, storeSecret(section, key, value))
+	deleteSecret(oldValue)
 }
 
 // FileDeleteKey deletes the config key in the config file.
 // It returns true if the key was deleted,
 // or returns false if the section or key didn't exist.
 func FileDeleteKey(section, key string) bool {
-	return LoadedData().DeleteKey(section, key)
+	oldValue, _ := LoadedData().GetValue(section, key)
+	deleted := LoadedData().DeleteKey(section, key)
+	if deleted {
+		deleteSecret(oldValue)
+	}
+	return deleted
 }
 
 // GetValue gets the value for a config key from environment
@@ -425,13 +479,16 @@ func FileDeleteKey(section, key string) bool {
 //
 // Emulates the preference documented and normally used by rclone via
 // configmap, which means environment variables before config file.
+//
+// References to secrets in secret managers are returned as they are
+// rather than being read.
 func GetValue(remote, key string) string {
 	envKey := fs.ConfigToEnv(remote, key)
 	value, found := os.LookupEnv(envKey)
 	if found {
 		return value
 	}
-	value, _ = LoadedData().GetValue(remote, key)
+	value, _ = fileGetKeychainValue(remote, key)
 	return value
 }
 
@@ -573,8 +630,9 @@ func updateRemote(ctx context.Context, name string, keyValues rc.Params, opt Upd
 		if strings.ContainsAny(k, "\n\r") || strings.ContainsAny(vStr, "\n\r") {
 			return nil, fmt.Errorf("update remote: invalid key or value contains \\n or \\r")
 		}
-		// Obscure parameter if necessary
-		if _, ok := needsObscure[k]; ok {
+		// Obscure parameter if necessary - references to secrets
+		// are obscured when they are read instead
+		if _, ok := needsObscure[k]; ok && !HasSecretRef(vStr) && !IsKeychainRef(vStr) {
 			_, err := obscure.Reveal(vStr)
 			if err != nil || opt.Obscure {
 				// If error => not already obscured, so obscure it
@@ -705,6 +763,29 @@ func DumpRcRemote(name string) (dump rc.Params) {
 	return params
 }
 
+// DumpRcRemoteRedacted dumps the config for a single remote with the
+// passwords and other sensitive values replaced with XXX
+//
+// References to secrets in secret managers and the keychain aren't
+// secret so they are shown but not resolved.
+func DumpRcRemoteRedacted(name string) (dump rc.Params) {
+	fsInfo, _ := findByName(name)
+	params := rc.Params{}
+	for _, key := range LoadedData().GetKeyList(name) {
+		value, _ := FileGetValue(name, key)
+		if value != "" && fsInfo != nil && !HasSecretRef(value) && !IsKeychainRef(value) {
+			for _, option := range fsInfo.Options {
+				if option.Name == key && (option.IsPassword || option.Sensitive) {
+					value = "XXX"
+					break
+				}
+			}
+		}
+		params[key] = value
+	}
+	return params
+}
+
 // DumpRcBlob dumps all the config as an unstructured blob suitable
 // for the rc
 func DumpRcBlob() (dump rc.Params) {
@@ -6,6 +6,7 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -109,6 +110,17 @@ func Decrypt(b io.ReadSeeker) (io.Reader, error) {
 					fs.Debugf(nil, "Using RCLONE_CONFIG_PASS password.")
 				}
 			}
+
+			if len(configKey) == 0 {
+				if keychainpw := getKeychainConfigPassword(); keychainpw != "" {
+					err := SetConfigPassword(keychainpw)
+					if err != nil {
+						fs.Errorf(nil, "Using config password from the keychain returned: %v", err)
+					} else {
+						fs.Debugf(nil, "Using config password from the keychain.")
+					}
+				}
+			}
 		}
 	}
 
@@ -262,17 +274,11 @@ func getConfigPassword(q string) {
 // the password. If the length of the password is
 // zero after trimming+normalization, an error is returned.
 func SetConfigPassword(password string) error {
-	password, err := checkPassword(password)
+	key, err := passwordKey(password)
 	if err != nil {
 		return err
 	}
-	// Create SHA256 has of the password
-	sha := sha256.New()
-	_, err = sha.Write([]byte("[" + password + "][rclone-config]"))
-	if err != nil {
-		return err
-	}
-	configKey = sha.Sum(nil)
+	configKey = key
 	if PassConfigKeyForDaemonization {
 		tempFile, err := os.CreateTemp("", "rclone")
 		if err != nil {
@@ -307,6 +313,38 @@ func SetConfigPassword(password string) error {
 	return nil
 }
 
+// passwordKey returns the key to encrypt the config with made from
+// password
+func passwordKey(password string) ([]byte, error) {
+	password, err := checkPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	// Create SHA256 has of the password
+	sha := sha256.New()
+	_, err = sha.Write([]byte("[" + password + "][rclone-config]"))
+	if err != nil {
+		return nil, err
+	}
+	return sha.Sum(nil), nil
+}
+
+// CheckConfigPassword returns an error if the config isn't encrypted
+// with password
+func CheckConfigPassword(password string) error {
+	if !IsEncrypted() {
+		return errors.New("config file is not encrypted")
+	}
+	key, err := passwordKey(password)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(key, configKey) != 1 {
+		return errors.New("incorrect config password")
+	}
+	return nil
+}
+
 // ClearConfigPassword sets the current the password to empty
 func ClearConfigPassword() {
 	configKey = nil
@@ -338,6 +376,7 @@ func changeConfigPassword() {
 		fmt.Printf("Failed to set config password: %v\n", err)
 		return
 	}
+	setKeychainConfigPassword(pass)
 }
 
 // ChangeConfigPasswordAndSave will query the user twice
@@ -356,5 +395,6 @@ func ChangeConfigPasswordAndSave() {
 // the unencrypted config file.
 func RemoveConfigPasswordAndSave() {
 	configKey = nil
+	setKeychainConfigPassword("")
 	SaveConfig()
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
 )
 
 func hashedKeyCompare(t *testing.T, a, b string, shouldMatch bool) {
@@ -104,3 +105,39 @@ func main() {
 	expect = []string{"type", "nounc"}
 	assert.Equal(t, expect, keys)
 }
+
+func TestKeychainConfigPassword(t *testing.T) {
+	ci := fs.GetConfig(context.Background())
+	keyring.MockInit()
+
+	oldConfigPath := GetConfigPath()
+	oldAskPassword := ci.AskPassword
+	assert.NoError(t, SetConfigPath("./testdata/encrypted.conf"))
+	defer func() {
+		assert.NoError(t, SetConfigPath(oldConfigPath))
+		ClearConfigPassword()
+		ci.ConfigKeychain = false
+		ci.AskPassword = oldAskPassword
+	}()
+	ClearConfigPassword()
+	ci.AskPassword = false
+
+	// Without the keychain the config can't be read
+	require.Error(t, Data().Load())
+
+	// Nothing is stored unless --config-keychain is set
+	setKeychainConfigPassword("asdf")
+	ci.ConfigKeychain = true
+	assert.Equal(t, "", getKeychainConfigPassword())
+
+	// Store the password and check the config can be read
+	setKeychainConfigPassword("asdf")
+	assert.Equal(t, "asdf", getKeychainConfigPassword())
+	ClearConfigPassword()
+	require.NoError(t, Data().Load())
+	assert.Equal(t, []string{"nounc", "unc"}, Data().GetSectionList())
+
+	// Check it can be removed
+	setKeychainConfigPassword("")
+	assert.Equal(t, "", getKeychainConfigPassword())
+}
@@ -0,0 +1,170 @@
+package config
+
+// Storing the config password and remote secrets in the OS keychain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	// keychainService is the service name rclone's secrets are
+	// stored under in the keychain
+	keychainService = "rclone"
+
+	// KeychainPrefix starts a config file value which is stored
+	// in the keychain. The rest of the value is the keychain
+	// account it is stored under.
+	KeychainPrefix = "keychain:"
+)
+
+// useKeychain returns true if --config-keychain is in use
+func useKeychain() bool {
+	return fs.GetConfig(context.Background()).ConfigKeychain
+}
+
+// IsKeychainRef returns true if value refers to a secret stored in the
+// keychain
+func IsKeychainRef(value string) bool {
+	return strings.HasPrefix(value, KeychainPrefix)
+}
+
+// ResolveKeychainRef returns the secret value refers to if it is a
+// keychain reference, otherwise it returns value unchanged.
+func ResolveKeychainRef(value string) (string, error) {
+	if !IsKeychainRef(value) {
+		return value, nil
+	}
+	account := value[len(KeychainPrefix):]
+	secret, err := keyring.Get(keychainService, account)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %q from the keychain: %w", account, err)
+	}
+	return secret, nil
+}
+
+// keychainAccount returns the keychain account key of section is
+// stored under
+func keychainAccount(section, key string) string {
+	return section + "/" + key
+}
+
+// findOption returns the backend option key in section is for or nil
+// if not known
+func findOption(section, key string) *fs.Option {
+	backend, ok := LoadedData().GetValue(section, "type")
+	if !ok {
+		return nil
+	}
+	ri, err := fs.Find(backend)
+	if err != nil {
+		return nil
+	}
+	return ri.Options.Get(key)
+}
+
+// isSecret returns true if key in section should be stored in the
+// keychain
+//
+// These are the passwords and the OAuth client secret and token.
+func isSecret(section, key string) bool {
+	opt := findOption(section, key)
+	if opt == nil {
+		return false
+	}
+	return opt.IsPassword || key == ConfigToken || key == ConfigClientSecret
+}
+
+// storeSecret stores value in the keychain if it is a secret and
+// --config-keychain is in use, returning the value to put in the
+// config file.
+//
+// If the keychain can't be used the value is returned unchanged so
+// it is saved in the config file instead.
+func storeSecret(section, key, value string) string {
+	if value == "" || IsKeychainRef(value) || !useKeychain() || !isSecret(section, key) {
+		return value
+	}
+	account := keychainAccount(section, key)
+	err := keyring.Set(keychainService, account, value)
+	if err != nil {
+		fs.Errorf(nil, "Failed to store %q in the keychain - saving it in the config file: %v", account, err)
+		return value
+	}
+	fs.Debugf(nil, "Stored %q in the keychain", account)
+	return KeychainPrefix + account
+}
+
+// deleteSecret removes the secret value refers to from the keychain
+// unless another remote, e.g. a copy of this one, still uses it.
+func deleteSecret(value string) {
+	if !IsKeychainRef(value) {
+		return
+	}
+	for _, section := range LoadedData().GetSectionList() {
+		for _, key := range LoadedData().GetKeyList(section) {
+			if other, _ := LoadedData().GetValue(section, key); other == value {
+				return
+			}
+		}
+	}
+	account := value[len(KeychainPrefix):]
+	err := keyring.Delete(keychainService, account)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		fs.Errorf(nil, "Failed to remove %q from the keychain: %v", account, err)
+		return
+	}
+	fs.Debugf(nil, "Removed %q from the keychain", account)
+}
+
+// configPasswordAccount returns the keychain account the password
+// of the config file in use is stored under
+func configPasswordAccount() string {
+	return "config-password:" + GetConfigPath()
+}
+
+// getKeychainConfigPassword reads the config password from the
+// keychain if --config-keychain is in use, returning "" if it isn't
+// there.
+func getKeychainConfigPassword() string {
+	if !useKeychain() {
+		return ""
+	}
+	pass, err := keyring.Get(keychainService, configPasswordAccount())
+	if err != nil {
+		if !errors.Is(err, keyring.ErrNotFound) {
+			fs.Errorf(nil, "Failed to read config password from the keychain: %v", err)
+		}
+		return ""
+	}
+	return pass
+}
+
+// setKeychainConfigPassword stores the config password in the
+// keychain if --config-keychain is in use, or removes it if pass is
+// empty.
+func setKeychainConfigPassword(pass string) {
+	if !useKeychain() {
+		return
+	}
+	account := configPasswordAccount()
+	var err error
+	if pass == "" {
+		err = keyring.Delete(keychainService, account)
+		if errors.Is(err, keyring.ErrNotFound) {
+			err = nil
+		}
+	} else {
+		err = keyring.Set(keychainService, account, pass)
+	}
+	if err != nil {
+		fs.Errorf(nil, "Failed to update config password in the keychain: %v", err)
+		return
+	}
+	fs.Infof(nil, "Updated config password in the keychain")
+}
@@ -0,0 +1,76 @@
+package config_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func TestKeychainSecrets(t *testing.T) {
+	ctx := context.Background()
+	defer testConfigFile(t, simpleOptions, "keychain.conf")()
+	keyring.MockInit()
+	ci := fs.GetConfig(ctx)
+	ci.ConfigKeychain = true
+	defer func() {
+		ci.ConfigKeychain = false
+	}()
+
+	_, err := config.CreateRemote(ctx, "test", "config_test_remote", rc.Params{
+		"bool": true,
+		"pass": "potato",
+	}, config.UpdateRemoteOpt{})
+	require.NoError(t, err)
+
+	// The password should be in the keychain and not the config file
+	raw, ok := config.FileGetValue("test", "pass")
+	require.True(t, ok)
+	assert.Equal(t, config.KeychainPrefix+"test/pass", raw)
+	stored, err := keyring.Get("rclone", "test/pass")
+	require.NoError(t, err)
+	assert.Equal(t, "potato", obscure.MustReveal(stored))
+
+	// Other values stay in the config file
+	raw, _ = config.FileGetValue("test", "bool")
+	assert.Equal(t, "true", raw)
+
+	// Reading the config should fetch the password from the keychain
+	assert.Equal(t, "potato", obscure.MustReveal(config.GetValue("test", "pass")))
+	value, ok := fs.ConfigFileGet("test", "pass")
+	require.True(t, ok)
+	assert.Equal(t, stored, value)
+
+	// Updating the password should update the keychain
+	_, err = config.UpdateRemote(ctx, "test", rc.Params{
+		"pass": "potato2",
+	}, config.UpdateRemoteOpt{})
+	require.NoError(t, err)
+	assert.Equal(t, "potato2", obscure.MustReveal(config.GetValue("test", "pass")))
+
+	// Deleting the remote should remove it from the keychain
+	config.DeleteRemote("test")
+	_, err = keyring.Get("rclone", "test/pass")
+	assert.Equal(t, keyring.ErrNotFound, err)
+}
+
+func TestKeychainDisabled(t *testing.T) {
+	ctx := context.Background()
+	defer testConfigFile(t, simpleOptions, "keychain.conf")()
+	keyring.MockInit()
+
+	_, err := config.CreateRemote(ctx, "test", "config_test_remote", rc.Params{
+		"pass": "potato",
+	}, config.UpdateRemoteOpt{})
+	require.NoError(t, err)
+
+	raw, _ := config.FileGetValue("test", "pass")
+	assert.False(t, config.IsKeychainRef(raw))
+	assert.Equal(t, "potato", obscure.MustReveal(raw))
+}
@@ -0,0 +1,117 @@
+package config
+
+// Profiles which override remote options and global flags
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+)
+
+const (
+	// ProfilePrefix starts the names of config file sections
+	// which hold profiles rather than remotes.
+	//
+	// The global options for profile NAME are in [profile:NAME]
+	// and the overrides for REMOTE are in [profile:NAME:REMOTE].
+	// Remote names can't contain ":" so these can't clash with
+	// remotes.
+	ProfilePrefix = "profile:"
+
+	// ConfigInherits is the key in a profile naming the profile it
+	// inherits from
+	ConfigInherits = "inherits"
+)
+
+// profileChain is the profile in use followed by the profiles it
+// inherits from
+var profileChain []string
+
+// IsProfileSection returns true if section holds a profile rather
+// than a remote
+func IsProfileSection(section string) bool {
+	return strings.HasPrefix(section, ProfilePrefix)
+}
+
+// profileSection returns the section holding the overrides for
+// remote in profile, or its global options if remote is ""
+func profileSection(profile, remote string) string {
+	if remote == "" {
+		return ProfilePrefix + profile
+	}
+	return ProfilePrefix + profile + ":" + remote
+}
+
+// profileExists returns true if the config file has any sections for
+// profile
+func profileExists(profile string) bool {
+	section := profileSection(profile, "")
+	for _, s := range LoadedData().GetSectionList() {
+		if s == section || strings.HasPrefix(s, section+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteSections returns the sections in the config file which are
+// remotes
+func remoteSections() (remotes []string) {
+	for _, section := range LoadedData().GetSectionList() {
+		if !IsProfileSection(section) {
+			remotes = append(remotes, section)
+		}
+	}
+	return remotes
+}
+
+// SetProfile makes name the profile in use.
+//
+// Its options override those in the remotes and the defaults of the
+// global options, but are overridden by command line flags and
+// environment variables. Use "" to stop using a profile.
+func SetProfile(name string) error {
+	if name == "" {
+		profileChain = nil
+		fs.ConfigProfileGet = func(remote, key string) (string, bool) { return "", false }
+		return nil
+	}
+	var chain []string
+	seen := map[string]bool{}
+	for profile := name; profile != ""; {
+		if strings.Contains(profile, ":") {
+			return fmt.Errorf("invalid profile name %q", profile)
+		}
+		if seen[profile] {
+			return fmt.Errorf("profile %q inherits from itself", profile)
+		}
+		if !profileExists(profile) {
+			return fmt.Errorf("profile %q not found in config file", profile)
+		}
+		seen[profile] = true
+		chain = append(chain, profile)
+		profile, _ = LoadedData().GetValue(profileSection(profile, ""), ConfigInherits)
+		profile = strings.TrimSpace(profile)
+	}
+	fs.Debugf(nil, "Using profile %q", strings.Join(chain, " -> "))
+	profileChain = chain
+	fs.ConfigProfileGet = getProfileValue
+	return nil
+}
+
+// getProfileValue reads key for remote from the profile in use or the
+// first profile it inherits from which sets it. remote is "" for the
+// global options.
+func getProfileValue(remote, key string) (string, bool) {
+	if remote == "" && key == ConfigInherits {
+		return "", false
+	}
+	for _, profile := range profileChain {
+		value, ok := fileGetSecretValue(profileSection(profile, remote), key)
+		if ok && value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,60 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfile(t *testing.T) {
+	defer testConfigFile(t, simpleOptions, "profile.conf")()
+	defer func() {
+		require.NoError(t, config.SetProfile(""))
+	}()
+
+	config.FileSetValue("test", "type", "config_test_remote")
+	config.FileSetValue("test", "bool", "false")
+	config.FileSetValue("profile:base", "retries", "7")
+	config.FileSetValue("profile:base:test", "bool", "true")
+	config.FileSetValue("profile:prod", "inherits", "base")
+	config.FileSetValue("profile:prod", "retries", "9")
+	config.FileSetValue("profile:loop", "inherits", "loop2")
+	config.FileSetValue("profile:loop2", "inherits", "loop")
+
+	// Profiles aren't remotes
+	assert.Equal(t, []string{"test"}, config.FileSections())
+
+	// Without a profile the remote's options are used
+	m := fs.ConfigMap("config_test_remote", simpleOptions, "test", nil)
+	value, _ := m.Get("bool")
+	assert.Equal(t, "false", value)
+
+	// Errors
+	assert.ErrorContains(t, config.SetProfile("missing"), "not found")
+	assert.ErrorContains(t, config.SetProfile("loop"), "inherits from itself")
+
+	// The profile overrides the remote's options, inheriting
+	// from base
+	require.NoError(t, config.SetProfile("prod"))
+	value, _ = m.Get("bool")
+	assert.Equal(t, "true", value)
+
+	// And the global options, with prod taking precedence
+	value, ok := fs.ConfigProfileGet("", "retries")
+	assert.True(t, ok)
+	assert.Equal(t, "9", value)
+	_, ok = fs.ConfigProfileGet("", "inherits")
+	assert.False(t, ok)
+
+	require.NoError(t, config.SetProfile("base"))
+	value, _ = fs.ConfigProfileGet("", "retries")
+	assert.Equal(t, "7", value)
+
+	// Turning the profile off restores the remote's options
+	require.NoError(t, config.SetProfile(""))
+	value, _ = m.Get("bool")
+	assert.Equal(t, "false", value)
+}
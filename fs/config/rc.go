@@ -3,7 +3,9 @@ package config
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/rc"
@@ -56,6 +58,43 @@ func rcGet(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	return DumpRcRemote(name), nil
 }
 
+func init() {
+	rc.Add(rc.Call{
+		Path:         "config/redacted",
+		Fn:           rcRedacted,
+		Title:        "Dumps the config file with secrets redacted.",
+		AuthRequired: true,
+		Help: `
+Parameters:
+
+- name - name of remote to dump (optional - all remotes if not set)
+
+This returns the same as config/dump (or config/get if name is set)
+but with passwords and other sensitive values replaced with XXX, so it
+is safe to log or share. References to secrets such as
+"${env:NAME}" are shown as they are without being resolved.
+
+See the [config redacted](/commands/rclone_config_redacted/) command for more information on the above.
+`,
+	})
+}
+
+// Return the redacted config file dump
+func rcRedacted(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	name, err := in.GetString("name")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	if name != "" {
+		return DumpRcRemoteRedacted(name), nil
+	}
+	out = rc.Params{}
+	for _, name := range LoadedData().GetSectionList() {
+		out[name] = DumpRcRemoteRedacted(name)
+	}
+	return out, nil
+}
+
 func init() {
 	rc.Add(rc.Call{
 		Path:         "config/listremotes",
@@ -284,3 +323,138 @@ func rcPaths(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 		"temp":   os.TempDir(),
 	}, nil
 }
+
+func init() {
+	rc.Add(rc.Call{
+		Path:         "config/setpassword",
+		Fn:           rcSetPassword,
+		Title:        "Set or change the password used to encrypt the config file.",
+		AuthRequired: true,
+		Help: `
+Parameters:
+
+- password - the new config password
+- oldPassword - the current config password, needed if the config is already encrypted
+
+This encrypts the config file with the new password and saves it.
+
+See the [config encryption](/commands/rclone_config_encryption/) command for more information on the above.
+`,
+	})
+}
+
+// Set or change the config password
+func rcSetPassword(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	password, err := in.GetString("password")
+	if err != nil {
+		return nil, err
+	}
+	if IsEncrypted() {
+		oldPassword, err := in.GetString("oldPassword")
+		if err != nil {
+			return nil, err
+		}
+		if err := CheckConfigPassword(oldPassword); err != nil {
+			return nil, rc.NewErrParamInvalid(err)
+		}
+	}
+	if err := SetConfigPassword(password); err != nil {
+		return nil, rc.NewErrParamInvalid(err)
+	}
+	setKeychainConfigPassword(password)
+	SaveConfig()
+	return nil, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:         "config/removepassword",
+		Fn:           rcRemovePassword,
+		Title:        "Remove the config file encryption.",
+		AuthRequired: true,
+		Help: `
+Parameters:
+
+- password - the current config password
+
+This decrypts the config file and saves it unencrypted.
+
+See the [config encryption](/commands/rclone_config_encryption/) command for more information on the above.
+`,
+	})
+}
+
+// Remove the config password
+func rcRemovePassword(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	password, err := in.GetString("password")
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckConfigPassword(password); err != nil {
+		return nil, rc.NewErrParamInvalid(err)
+	}
+	RemoveConfigPasswordAndSave()
+	return nil, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:         "config/setsecret",
+		Fn:           rcSetSecret,
+		Title:        "Set a secret held only in memory.",
+		AuthRequired: true,
+		Help: `
+Parameters:
+
+- name - name of the secret
+- value - value of the secret, or empty to remove it
+
+The secret can be used in config values as "${session:name}", e.g.
+
+    rclone rc config/setsecret name=s3key value=XXXXXXXX
+    rclone rc config/update name=s3 parameters='{"secret_access_key":"${session:s3key}"}'
+
+Session secrets are never written to disk so they are lost when rclone
+exits. This allows credentials to be supplied to a running rclone
+without writing them to the config file. Use plain values for
+passwords - they will be obscured as needed.
+`,
+	})
+}
+
+// Set a session secret
+func rcSetSecret(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	name, err := in.GetString("name")
+	if err != nil {
+		return nil, err
+	}
+	if name == "" || strings.ContainsAny(name, "{}") {
+		return nil, rc.NewErrParamInvalid(fmt.Errorf("invalid secret name %q", name))
+	}
+	value, err := in.GetString("value")
+	if err != nil {
+		return nil, err
+	}
+	SetSessionSecret(name, value)
+	return nil, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:         "config/listsecrets",
+		Fn:           rcListSecrets,
+		Title:        "List the names of the secrets held in memory.",
+		AuthRequired: true,
+		Help: `
+Returns
+- names - array of the names of the secrets set with config/setsecret
+`,
+	})
+}
+
+// List the session secrets
+func rcListSecrets(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	return rc.Params{
+		"names": SessionSecretNames(),
+	}, nil
+}
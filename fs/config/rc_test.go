@@ -3,6 +3,7 @@ package config_test
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 
 	_ "github.com/rclone/rclone/backend/local"
@@ -188,3 +189,115 @@ func TestRcPaths(t *testing.T) {
 	assert.Equal(t, config.GetCacheDir(), out["cache"])
 	assert.Equal(t, os.TempDir(), out["temp"])
 }
+
+func TestRcSecrets(t *testing.T) {
+	defer testConfigFile(t, simpleOptions, "rcsecrets.conf")()
+	ctx := context.Background()
+	rcCall := func(path string, in rc.Params) (rc.Params, error) {
+		call := rc.Calls.Get(path)
+		require.NotNil(t, call, path)
+		return call.Fn(ctx, in)
+	}
+
+	_, err := rcCall("config/create", rc.Params{
+		"name": "test",
+		"type": "config_test_remote",
+		"parameters": rc.Params{
+			"bool": "true",
+			"pass": "potato",
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("Redacted", func(t *testing.T) {
+		out, err := rcCall("config/redacted", rc.Params{})
+		require.NoError(t, err)
+		assert.Equal(t, rc.Params{"type": "config_test_remote", "bool": "true", "pass": "XXX"}, out["test"])
+
+		out, err = rcCall("config/redacted", rc.Params{"name": "test"})
+		require.NoError(t, err)
+		assert.Equal(t, "XXX", out["pass"])
+	})
+
+	t.Run("SessionSecret", func(t *testing.T) {
+		_, err := rcCall("config/setsecret", rc.Params{"name": "sess", "value": "hunter2"})
+		require.NoError(t, err)
+		_, err = rcCall("config/setsecret", rc.Params{"name": "{bad}", "value": "x"})
+		assert.Error(t, err)
+
+		out, err := rcCall("config/listsecrets", rc.Params{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sess"}, out["names"])
+
+		// The reference is stored, not obscured, and resolved on use
+		_, err = rcCall("config/update", rc.Params{
+			"name":       "test",
+			"parameters": rc.Params{"pass": "${session:sess}"},
+		})
+		require.NoError(t, err)
+		value, _ := config.FileGetValue("test", "pass")
+		assert.Equal(t, "${session:sess}", value)
+		value, _ = fs.ConfigFileGet("test", "pass")
+		assert.Equal(t, "hunter2", obscure.MustReveal(value))
+
+		// But not when the config is read
+		assert.Equal(t, "${session:sess}", config.GetValue("test", "pass"))
+		out, err = rcCall("config/get", rc.Params{"name": "test"})
+		require.NoError(t, err)
+		assert.Equal(t, "${session:sess}", out["pass"])
+		out, err = rcCall("config/dump", rc.Params{})
+		require.NoError(t, err)
+		assert.Equal(t, "${session:sess}", out["test"].(rc.Params)["pass"])
+
+		// References aren't redacted
+		out, err = rcCall("config/redacted", rc.Params{"name": "test"})
+		require.NoError(t, err)
+		assert.Equal(t, "${session:sess}", out["pass"])
+
+		// Changing the secret is picked up
+		_, err = rcCall("config/setsecret", rc.Params{"name": "sess", "value": "hunter3"})
+		require.NoError(t, err)
+		value, _ = fs.ConfigFileGet("test", "pass")
+		assert.Equal(t, "hunter3", obscure.MustReveal(value))
+
+		// Remove the secret
+		_, err = rcCall("config/setsecret", rc.Params{"name": "sess", "value": ""})
+		require.NoError(t, err)
+		out, err = rcCall("config/listsecrets", rc.Params{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{}, out["names"])
+	})
+
+	t.Run("Password", func(t *testing.T) {
+		defer config.ClearConfigPassword()
+		isEncrypted := func() bool {
+			data, err := os.ReadFile(config.GetConfigPath())
+			require.NoError(t, err)
+			return strings.Contains(string(data), "RCLONE_ENCRYPT_V0:")
+		}
+
+		_, err := rcCall("config/removepassword", rc.Params{"password": "pw1"})
+		assert.ErrorContains(t, err, "not encrypted")
+
+		_, err = rcCall("config/setpassword", rc.Params{"password": "pw1"})
+		require.NoError(t, err)
+		assert.True(t, config.IsEncrypted())
+		assert.True(t, isEncrypted())
+
+		// Rotating the password needs the old one
+		_, err = rcCall("config/setpassword", rc.Params{"password": "pw2"})
+		assert.Error(t, err)
+		_, err = rcCall("config/setpassword", rc.Params{"password": "pw2", "oldPassword": "wrong"})
+		assert.ErrorContains(t, err, "incorrect config password")
+		_, err = rcCall("config/setpassword", rc.Params{"password": "pw2", "oldPassword": "pw1"})
+		require.NoError(t, err)
+		require.NoError(t, config.CheckConfigPassword("pw2"))
+
+		_, err = rcCall("config/removepassword", rc.Params{"password": "pw1"})
+		assert.ErrorContains(t, err, "incorrect config password")
+		_, err = rcCall("config/removepassword", rc.Params{"password": "pw2"})
+		require.NoError(t, err)
+		assert.False(t, config.IsEncrypted())
+		assert.False(t, isEncrypted())
+	})
+}
@@ -0,0 +1,223 @@
+package config
+
+// Resolving secrets from secret managers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// SecretResolver returns the secret which ref refers to.
+//
+// ref is the part of a secret reference ${scheme:ref} after the
+// scheme.
+type SecretResolver func(ctx context.Context, ref string) (string, error)
+
+// secretCacheEntry is a secret in the cache
+type secretCacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+var (
+	secretMu        sync.Mutex
+	secretResolvers = map[string]SecretResolver{}
+	secretCache     = map[string]secretCacheEntry{} // indexed by scheme:ref
+	sessionSecrets  = map[string]string{}           // secrets only held in memory
+)
+
+// secretRefRe matches a secret reference ${scheme:ref}
+var secretRefRe = regexp.MustCompile(`\$\{([a-zA-Z0-9_-]+):([^}]*)\}`)
+
+func init() {
+	RegisterSecretResolver("env", resolveEnvSecret)
+	RegisterSecretResolver("file", resolveFileSecret)
+	RegisterSecretResolver("session", resolveSessionSecret)
+}
+
+// RegisterSecretResolver registers resolver to read the secrets
+// referred to as ${scheme:ref} in config values.
+func RegisterSecretResolver(scheme string, resolver SecretResolver) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	secretResolvers[scheme] = resolver
+}
+
+// getSecretResolver returns the resolver for scheme or nil
+func getSecretResolver(scheme string) SecretResolver {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	return secretResolvers[scheme]
+}
+
+// HasSecretRef returns true if value contains a reference to a secret
+// in a secret manager.
+//
+// Only references with a registered scheme count, so values which
+// happen to contain ${...} otherwise are left alone.
+func HasSecretRef(value string) bool {
+	if !strings.Contains(value, "${") {
+		return false
+	}
+	for _, match := range secretRefRe.FindAllStringSubmatch(value, -1) {
+		if getSecretResolver(match[1]) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveSecretRefs returns value with the secret references in it
+// replaced by the secrets they refer to.
+//
+// Secrets are cached for --secret-cache-time so they aren't fetched
+// for every use but changes to them are picked up.
+func ResolveSecretRefs(ctx context.Context, value string) (string, error) {
+	var (
+		out  strings.Builder
+		last = 0
+	)
+	for _, match := range secretRefRe.FindAllStringSubmatchIndex(value, -1) {
+		scheme, ref := value[match[2]:match[3]], value[match[4]:match[5]]
+		resolver := getSecretResolver(scheme)
+		if resolver == nil {
+			continue
+		}
+		secret, err := resolveSecret(ctx, scheme, ref, resolver)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(value[last:match[0]])
+		out.WriteString(secret)
+		last = match[1]
+	}
+	out.WriteString(value[last:])
+	return out.String(), nil
+}
+
+// resolveSecret reads the secret ${scheme:ref} from the cache or
+// with resolver
+func resolveSecret(ctx context.Context, scheme, ref string, resolver SecretResolver) (string, error) {
+	cacheTime := fs.GetConfig(ctx).SecretCacheTime
+	key := scheme + ":" + ref
+	secretMu.Lock()
+	entry, found := secretCache[key]
+	secretMu.Unlock()
+	if found && time.Now().Before(entry.expires) {
+		return entry.value, nil
+	}
+	secret, err := resolver(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret ${%s}: %w", key, err)
+	}
+	fs.Debugf(nil, "Read secret ${%s}", key)
+	if cacheTime > 0 {
+		secretMu.Lock()
+		secretCache[key] = secretCacheEntry{
+			value:   secret,
+			expires: time.Now().Add(cacheTime),
+		}
+		secretMu.Unlock()
+	}
+	return secret, nil
+}
+
+// ClearSecretCache removes all the secrets from the cache so they
+// are read again on next use.
+func ClearSecretCache() {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	secretCache = map[string]secretCacheEntry{}
+}
+
+// resolveEnvSecret reads ${env:NAME} from the environment variable NAME
+func resolveEnvSecret(ctx context.Context, ref string) (string, error) {
+	value, found := os.LookupEnv(ref)
+	if !found {
+		return "", fmt.Errorf("environment variable %q not set", ref)
+	}
+	return value, nil
+}
+
+// resolveFileSecret reads ${file:path} from the file at path,
+// without any trailing newline
+func resolveFileSecret(ctx context.Context, ref string) (string, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// SetSessionSecret sets the secret ${session:name} to value, or removes
+// it if value is empty.
+//
+// Session secrets are only held in memory so they are lost when
+// rclone exits.
+func SetSessionSecret(name, value string) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	if value == "" {
+		delete(sessionSecrets, name)
+	} else {
+		sessionSecrets[name] = value
+	}
+	delete(secretCache, "session:"+name)
+}
+
+// SessionSecretNames returns the sorted names of the session secrets
+func SessionSecretNames() []string {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	names := make([]string, 0, len(sessionSecrets))
+	for name := range sessionSecrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveSessionSecret reads ${session:name} from the secrets set
+// with SetSessionSecret
+func resolveSessionSecret(ctx context.Context, ref string) (string, error) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	value, found := sessionSecrets[ref]
+	if !found {
+		return "", fmt.Errorf("session secret %q not set", ref)
+	}
+	return value, nil
+}
+
+// secretField returns the field key from the fields of a structured
+// secret. If key is empty then the secret must only have one field.
+func secretField(fields map[string]any, key string) (string, error) {
+	if key == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("secret has %d fields - choose one with #key", len(fields))
+		}
+		for k := range fields {
+			key = k
+		}
+	}
+	value, found := fields[key]
+	if !found {
+		return "", fmt.Errorf("secret has no field %q", key)
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+// Reading secrets from AWS Secrets Manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/rclone/rclone/fs/fshttp"
+)
+
+func init() {
+	RegisterSecretResolver("aws-sm", resolveAWSSecret)
+}
+
+// resolveAWSSecret reads ${aws-sm:name#key} from AWS Secrets Manager
+//
+// The credentials and region are found in the usual places for the
+// AWS SDK. name may be the ARN of the secret in which case its region
+// is used. If key is set the secret is read as JSON and the key field
+// returned.
+func resolveAWSSecret(ctx context.Context, ref string) (string, error) {
+	name, key, _ := strings.Cut(ref, "#")
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	cfg.HTTPClient = fshttp.NewClient(ctx)
+	client := secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		// arn:aws:secretsmanager:region:account:secret:name
+		if parts := strings.SplitN(name, ":", 5); len(parts) == 5 && parts[0] == "arn" && parts[3] != "" {
+			o.Region = parts[3]
+		}
+	})
+	out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+	var secret []byte
+	if out.SecretString != nil {
+		secret = []byte(*out.SecretString)
+	} else {
+		secret = out.SecretBinary
+	}
+	if key == "" {
+		return string(secret), nil
+	}
+	var fields map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(secret))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return "", fmt.Errorf("secret isn't a JSON object so can't read #%s from it: %w", key, err)
+	}
+	return secretField(fields, key)
+}
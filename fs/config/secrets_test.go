@@ -0,0 +1,225 @@
+package config_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSecretRefs(t *testing.T) {
+	ctx := context.Background()
+	config.ClearSecretCache()
+	calls := 0
+	config.RegisterSecretResolver("test-secret", func(ctx context.Context, ref string) (string, error) {
+		calls++
+		if ref == "missing" {
+			return "", fmt.Errorf("not found")
+		}
+		return fmt.Sprintf("%s-%d", ref, calls), nil
+	})
+
+	assert.False(t, config.HasSecretRef("potato"))
+	assert.False(t, config.HasSecretRef("${unknown:potato}"))
+	assert.True(t, config.HasSecretRef("${test-secret:potato}"))
+
+	for _, test := range []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "potato", want: "potato"},
+		{in: "${test-secret:a}", want: "a-1"},
+		{in: "${test-secret:a}", want: "a-1"}, // cached
+		{in: "x${test-secret:b}y${test-secret:a}z", want: "xb-2ya-1z"},
+		{in: "${unknown:a}${test-secret:c}", want: "${unknown:a}c-3"},
+		{in: "${test-secret:missing}", wantErr: true},
+	} {
+		got, err := config.ResolveSecretRefs(ctx, test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+
+	// Check the cache expires
+	ci := fs.GetConfig(ctx)
+	oldCacheTime := ci.SecretCacheTime
+	defer func() {
+		ci.SecretCacheTime = oldCacheTime
+	}()
+	ci.SecretCacheTime = 0
+	config.ClearSecretCache()
+	got, err := config.ResolveSecretRefs(ctx, "${test-secret:a}")
+	require.NoError(t, err)
+	assert.Equal(t, "a-5", got)
+	got, err = config.ResolveSecretRefs(ctx, "${test-secret:a}")
+	require.NoError(t, err)
+	assert.Equal(t, "a-6", got)
+}
+
+func TestEnvAndFileSecrets(t *testing.T) {
+	ctx := context.Background()
+	config.ClearSecretCache()
+	t.Setenv("RCLONE_TEST_SECRET", "potato")
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte("sausage\n"), 0600))
+
+	got, err := config.ResolveSecretRefs(ctx, "${env:RCLONE_TEST_SECRET}:${file:"+path+"}")
+	require.NoError(t, err)
+	assert.Equal(t, "potato:sausage", got)
+
+	_, err = config.ResolveSecretRefs(ctx, "${env:RCLONE_TEST_SECRET_NOT_SET}")
+	assert.ErrorContains(t, err, "not set")
+	_, err = config.ResolveSecretRefs(ctx, "${file:"+path+"-not-found}")
+	assert.Error(t, err)
+}
+
+func TestConfigFileSecretRefs(t *testing.T) {
+	ctx := context.Background()
+	defer testConfigFile(t, simpleOptions, "secrets.conf")()
+	config.ClearSecretCache()
+	t.Setenv("RCLONE_TEST_SECRET", "potato")
+
+	_, err := config.CreateRemote(ctx, "test", "config_test_remote", rc.Params{
+		"bool": true,
+	}, config.UpdateRemoteOpt{})
+	require.NoError(t, err)
+	config.FileSetValue("test", "pass", "${env:RCLONE_TEST_SECRET}")
+	config.FileSetValue("test", "string1", "${env:RCLONE_TEST_SECRET}")
+	config.FileSetValue("test", "string2", "${env:RCLONE_TEST_SECRET_NOT_SET}")
+
+	// The reference stays in the config file
+	raw, _ := config.FileGetValue("test", "pass")
+	assert.Equal(t, "${env:RCLONE_TEST_SECRET}", raw)
+
+	// Passwords are obscured as the backend expects
+	value, ok := fs.ConfigFileGet("test", "pass")
+	require.True(t, ok)
+	assert.Equal(t, "potato", obscure.MustReveal(value))
+
+	// Other values are used as they are
+	value, ok = fs.ConfigFileGet("test", "string1")
+	require.True(t, ok)
+	assert.Equal(t, "potato", value)
+
+	// Values which can't be read are missing
+	_, ok = fs.ConfigFileGet("test", "string2")
+	assert.False(t, ok)
+}
+
+func TestVaultSecrets(t *testing.T) {
+	ctx := context.Background()
+	config.ClearSecretCache()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			_, _ = w.Write([]byte(`{"data":{"data":{"user":"bob","pass":"potato"},"metadata":{"version":3}}}`))
+		case "/v1/kv1/app":
+			_, _ = w.Write([]byte(`{"data":{"pass":"sausage"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "token")
+
+	for _, test := range []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{in: "${vault:secret/data/app#pass}", want: "potato"},
+		{in: "${vault:secret/app#user}", want: "bob"},
+		{in: "${vault:kv1/app}", want: "sausage"},
+		{in: "${vault:secret/app}", wantErr: "choose one with #key"},
+		{in: "${vault:secret/app#missing}", wantErr: "no field"},
+		{in: "${vault:secret/missing#pass}", wantErr: "not found"},
+	} {
+		got, err := config.ResolveSecretRefs(ctx, test.in)
+		if test.wantErr != "" {
+			assert.ErrorContains(t, err, test.wantErr, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+
+	config.ClearSecretCache()
+	t.Setenv("VAULT_TOKEN", "wrong")
+	_, err := config.ResolveSecretRefs(ctx, "${vault:kv1/app}")
+	assert.ErrorContains(t, err, "permission denied")
+}
+
+func TestAWSSecrets(t *testing.T) {
+	ctx := context.Background()
+	config.ClearSecretCache()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-2/secretsmanager/")
+		var in struct {
+			SecretID string `json:"SecretId"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch in.SecretID {
+		case "plain":
+			_, _ = fmt.Fprintf(w, `{"Name":"plain","SecretString":"potato","CreatedDate":%d}`, time.Now().Unix())
+		case "structured":
+			_, _ = w.Write([]byte(`{"Name":"structured","SecretString":"{\"user\":\"bob\",\"port\":1234}"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException","Message":"Secrets Manager can't find the specified secret."}`))
+		}
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_SECRETS_MANAGER", server.URL)
+	t.Setenv("AWS_REGION", "eu-west-2")
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_MAX_ATTEMPTS", "1")
+
+	for _, test := range []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{in: "${aws-sm:plain}", want: "potato"},
+		{in: "${aws-sm:structured#user}", want: "bob"},
+		{in: "${aws-sm:structured#port}", want: "1234"},
+		{in: "${aws-sm:plain#user}", wantErr: "isn't a JSON object"},
+		{in: "${aws-sm:missing}", wantErr: "ResourceNotFoundException"},
+	} {
+		got, err := config.ResolveSecretRefs(ctx, test.in)
+		if test.wantErr != "" {
+			assert.ErrorContains(t, err, test.wantErr, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
@@ -0,0 +1,121 @@
+package config
+
+// Reading secrets from HashiCorp Vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/rclone/rclone/fs/fshttp"
+)
+
+func init() {
+	RegisterSecretResolver("vault", resolveVaultSecret)
+}
+
+// errVaultNotFound is returned if there is no secret at the path
+var errVaultNotFound = errors.New("secret not found")
+
+// vaultToken returns the token to use with Vault from VAULT_TOKEN or
+// the file the vault CLI stores it in
+func vaultToken() (string, error) {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", fmt.Errorf("VAULT_TOKEN not set: %w", err)
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".vault-token"))
+	if err != nil {
+		return "", fmt.Errorf("VAULT_TOKEN not set: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// vaultRead reads the data of the secret at path
+//
+// For the KV version 2 secrets engine this is the data of the
+// latest version of the secret.
+func vaultRead(ctx context.Context, addr, token, path string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(addr, "/")+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := fshttp.NewClient(ctx).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	var result struct {
+		Data   map[string]any `json:"data"`
+		Errors []string       `json:"errors"`
+	}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	decodeErr := decoder.Decode(&result)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errVaultNotFound
+	case resp.StatusCode != http.StatusOK:
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("%s: %s", resp.Status, strings.Join(result.Errors, ", "))
+		}
+		return nil, errors.New(resp.Status)
+	case decodeErr != nil:
+		return nil, fmt.Errorf("failed to decode response: %w", decodeErr)
+	}
+	// Unwrap the data of a KV version 2 secret
+	if data, ok := result.Data["data"].(map[string]any); ok {
+		if _, ok := result.Data["metadata"]; ok {
+			return data, nil
+		}
+	}
+	return result.Data, nil
+}
+
+// resolveVaultSecret reads ${vault:path#key} from Vault
+//
+// The Vault server and token are read from VAULT_ADDR and VAULT_TOKEN
+// as the vault CLI does. path may leave out the /data/ of the KV
+// version 2 secrets engine, so secret/myapp will read
+// secret/data/myapp if needed.
+func resolveVaultSecret(ctx context.Context, ref string) (string, error) {
+	path, key, _ := strings.Cut(ref, "#")
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", errors.New("VAULT_ADDR not set")
+	}
+	if _, err := url.Parse(addr); err != nil {
+		return "", fmt.Errorf("bad VAULT_ADDR: %w", err)
+	}
+	token, err := vaultToken()
+	if err != nil {
+		return "", err
+	}
+	data, err := vaultRead(ctx, addr, token, path)
+	if errors.Is(err, errVaultNotFound) {
+		mount, rest, ok := strings.Cut(strings.TrimLeft(path, "/"), "/")
+		if ok && !strings.HasPrefix(rest, "data/") {
+			data, err = vaultRead(ctx, addr, token, mount+"/data/"+rest)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return secretField(data, key)
+}
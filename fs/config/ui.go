@@ -271,7 +271,7 @@ func ChooseNumber(what string, min, max int) int {
 
 // ShowRemotes shows an overview of the config file
 func ShowRemotes() {
-	remotes := LoadedData().GetSectionList()
+	remotes := remoteSections()
 	if len(remotes) == 0 {
 		return
 	}
@@ -285,7 +285,7 @@ func ShowRemotes() {
 
 // ChooseRemote chooses a remote name
 func ChooseRemote() string {
-	remotes := LoadedData().GetSectionList()
+	remotes := remoteSections()
 	sort.Strings(remotes)
 	fmt.Println("Select remote.")
 	return Choose("remote", "value", remotes, nil, "", true, false)
@@ -602,7 +602,15 @@ func EditRemote(ctx context.Context, ri *fs.RegInfo, name string) error {
 
 // DeleteRemote gets the user to delete a remote
 func DeleteRemote(name string) {
+	var values []string
+	for _, key := range LoadedData().GetKeyList(name) {
+		value, _ := LoadedData().GetValue(name, key)
+		values = append(values, value)
+	}
 	LoadedData().DeleteSection(name)
+	for _, value := range values {
+		deleteSecret(value)
+	}
 	SaveConfig()
 }
 
@@ -680,7 +688,7 @@ func ShowRedactedConfig() {
 // EditConfig edits the config file interactively
 func EditConfig(ctx context.Context) (err error) {
 	for {
-		haveRemotes := len(LoadedData().GetSectionList()) != 0
+		haveRemotes := len(remoteSections()) != 0
 		what := []string{"eEdit existing remote", "nNew remote", "dDelete remote", "rRename remote", "cCopy remote", "sSet configuration password", "qQuit config"}
 		if haveRemotes {
 			fmt.Printf("Current remotes:\n\n")
@@ -95,6 +95,14 @@ func (section getConfigFile) Get(key string) (value string, ok bool) {
 	return value, ok
 }
 
+// A configmap.Getter to read from the profile in use
+type getConfigProfile string
+
+// Get a config item for the remote from the profile
+func (remote getConfigProfile) Get(key string) (value string, ok bool) {
+	return ConfigProfileGet(string(remote), key)
+}
+
 // ConfigMap creates a configmap.Map from the Options, prefix and the
 // configName passed in. If connectionStringConfig has any entries (it may be nil),
 // then it will be added to the lookup with the highest priority.
@@ -129,6 +137,11 @@ func ConfigMap(prefix string, options Options, configName string, connectionStri
 		config.AddGetter(optionEnvVars{prefix: prefix, options: options}, configmap.PriorityNormal)
 	}
 
+	// profile overrides of the config file or global options
+	if configName != "" || (prefix == "" && options != nil) {
+		config.AddGetter(getConfigProfile(configName), configmap.PriorityConfig)
+	}
+
 	// config file
 	if configName != "" {
 		config.AddGetter(getConfigFile(configName), configmap.PriorityConfig)
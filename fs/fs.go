@@ -7,6 +7,8 @@ import (
 	"io"
 	"math"
 	"time"
+
+	"github.com/rclone/rclone/fs/fserrors"
 )
 
 // Constants
@@ -52,6 +54,11 @@ var (
 	ErrorFileNameTooLong             = errors.New("file name too long")
 )
 
+func init() {
+	fserrors.RegisterCategory(fserrors.CategoryNotFound, ErrorDirNotFound, ErrorObjectNotFound)
+	fserrors.RegisterCategory(fserrors.CategoryAuth, ErrorPermissionDenied)
+}
+
 // CheckClose is a utility function used to check the return from
 // Close in a defer statement.
 func CheckClose(c io.Closer, err *error) {
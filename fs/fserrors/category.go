@@ -0,0 +1,165 @@
+package fserrors
+
+import (
+	"errors"
+	"net/http"
+
+	liberrors "github.com/rclone/rclone/lib/errors"
+)
+
+// Category is a stable machine readable classification of an error
+//
+// These are shown in JSON logs, rc error responses and determine the
+// exit code so programs driving rclone can react to the kind of error
+// rather than its message. The values must not be changed.
+type Category string
+
+// Categories of error
+const (
+	// CategoryNone is returned for errors not otherwise categorised
+	CategoryNone Category = ""
+	// CategoryAuth is for credentials which are rejected or don't
+	// allow the operation - re-authenticating may fix it
+	CategoryAuth Category = "auth"
+	// CategoryQuota is for running out of storage space or a
+	// transfer quota
+	CategoryQuota Category = "quota"
+	// CategoryRateLimit is for being rate limited by the provider -
+	// trying again later may fix it
+	CategoryRateLimit Category = "rate-limit"
+	// CategoryNotFound is for a file, directory or bucket which
+	// doesn't exist
+	CategoryNotFound Category = "not-found"
+	// CategoryIntegrity is for data which was corrupted in transit
+	// or doesn't match its hash
+	CategoryIntegrity Category = "integrity"
+	// CategoryNetwork is for transient network or server errors
+	// which retrying may fix
+	CategoryNetwork Category = "transient-network"
+)
+
+// Categorizer is an optional interface for error to say which
+// Category it is in.
+type Categorizer interface {
+	error
+	Category() Category
+}
+
+// wrappedCategoryError is an error wrapped so it will satisfy the
+// Categorizer interface
+type wrappedCategoryError struct {
+	error
+	category Category
+}
+
+// Category interface
+func (err wrappedCategoryError) Category() Category {
+	return err.category
+}
+
+// Unwrap returns the underlying error
+func (err wrappedCategoryError) Unwrap() error {
+	return err.error
+}
+
+// Check interfaces
+var _ Categorizer = wrappedCategoryError{error(nil), CategoryNone}
+var _ unwrapper = wrappedCategoryError{}
+
+// CategoryError makes an error which is in the category given.
+//
+// It returns nil if err is nil.
+func CategoryError(err error, category Category) error {
+	if err == nil {
+		return nil
+	}
+	return wrappedCategoryError{error: err, category: category}
+}
+
+// categoryErrors are well known errors and their categories
+//
+// These are added to with RegisterCategory
+var categoryErrors []struct {
+	err      error
+	category Category
+}
+
+// RegisterCategory sets the category of errors matching any of errs
+// with errors.Is.
+//
+// This is for sentinel errors in packages which this one can't import.
+// It should be called from init functions only.
+func RegisterCategory(category Category, errs ...error) {
+	for _, err := range errs {
+		categoryErrors = append(categoryErrors, struct {
+			err      error
+			category Category
+		}{err, category})
+	}
+}
+
+// HTTPStatusCategory returns the category of an HTTP error status code
+// or CategoryNone if it doesn't correspond to one.
+func HTTPStatusCategory(statusCode int) Category {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return CategoryAuth
+	case http.StatusNotFound, http.StatusGone:
+		return CategoryNotFound
+	case http.StatusTooManyRequests:
+		return CategoryRateLimit
+	case http.StatusInsufficientStorage:
+		return CategoryQuota
+	case http.StatusRequestTimeout, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return CategoryNetwork
+	}
+	return CategoryNone
+}
+
+// GetCategory returns the category of err or CategoryNone if it
+// can't be categorised.
+//
+// Errors marked with CategoryError or satisfying the Categorizer
+// interface are used first, then well known errors, running out of
+// disk space, RetryAfter errors, errors with an HTTPStatusCode()
+// method and finally errors which ShouldRetry says are retriable.
+func GetCategory(err error) (category Category) {
+	if err == nil {
+		return CategoryNone
+	}
+	liberrors.Walk(err, func(err error) bool {
+		if c, ok := err.(Categorizer); ok {
+			category = c.Category()
+			return category != CategoryNone
+		}
+		return false
+	})
+	if category != CategoryNone {
+		return category
+	}
+	for _, item := range categoryErrors {
+		if errors.Is(err, item.err) {
+			return item.category
+		}
+	}
+	if IsErrNoSpace(err) {
+		return CategoryQuota
+	}
+	if IsRetryAfterError(err) {
+		return CategoryRateLimit
+	}
+	liberrors.Walk(err, func(err error) bool {
+		if e, ok := err.(interface{ HTTPStatusCode() int }); ok {
+			category = HTTPStatusCategory(e.HTTPStatusCode())
+			return true
+		}
+		return false
+	})
+	if category != CategoryNone {
+		return category
+	}
+	if ShouldRetry(err) {
+		return CategoryNetwork
+	}
+	return CategoryNone
+}
@@ -0,0 +1,72 @@
+package fserrors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// httpStatusError is an error with an HTTPStatusCode method like the
+// ones from the AWS SDK
+type httpStatusError int
+
+func (e httpStatusError) Error() string       { return fmt.Sprintf("HTTP error %d", int(e)) }
+func (e httpStatusError) HTTPStatusCode() int { return int(e) }
+
+func TestGetCategory(t *testing.T) {
+	errSentinel := errors.New("sentinel")
+	RegisterCategory(CategoryNotFound, errSentinel)
+	defer func() {
+		categoryErrors = categoryErrors[:len(categoryErrors)-1]
+	}()
+
+	for i, test := range []struct {
+		err  error
+		want Category
+	}{
+		{nil, CategoryNone},
+		{errors.New("potato"), CategoryNone},
+		{CategoryError(errors.New("bad token"), CategoryAuth), CategoryAuth},
+		{fmt.Errorf("wrapped: %w", CategoryError(errors.New("full"), CategoryQuota)), CategoryQuota},
+		{wrap(CategoryError(errors.New("bad hash"), CategoryIntegrity), "wrapped"), CategoryIntegrity},
+		{FatalError(CategoryError(errors.New("full"), CategoryQuota)), CategoryQuota},
+		{CategoryError(io.EOF, CategoryIntegrity), CategoryIntegrity},
+		{CategoryError(io.EOF, CategoryNone), CategoryNetwork},
+		{errSentinel, CategoryNotFound},
+		{fmt.Errorf("wrapped: %w", errSentinel), CategoryNotFound},
+		{fmt.Errorf("write: %w", syscall.ENOSPC), CategoryQuota},
+		{NewErrorRetryAfter(time.Second), CategoryRateLimit},
+		{httpStatusError(http.StatusForbidden), CategoryAuth},
+		{fmt.Errorf("wrapped: %w", httpStatusError(http.StatusTooManyRequests)), CategoryRateLimit},
+		{httpStatusError(http.StatusBadRequest), CategoryNone},
+		{io.ErrUnexpectedEOF, CategoryNetwork},
+		{errUseOfClosedNetworkConnection, CategoryNetwork},
+	} {
+		got := GetCategory(test.err)
+		assert.Equal(t, test.want, got, fmt.Sprintf("test #%d: %v", i, test.err))
+	}
+}
+
+func TestCategoryError(t *testing.T) {
+	assert.NoError(t, CategoryError(nil, CategoryAuth))
+
+	orig := errors.New("original")
+	err := CategoryError(orig, CategoryAuth)
+	assert.Equal(t, "original", err.Error())
+	assert.True(t, errors.Is(err, orig))
+}
+
+func TestHTTPStatusCategory(t *testing.T) {
+	assert.Equal(t, CategoryAuth, HTTPStatusCategory(http.StatusUnauthorized))
+	assert.Equal(t, CategoryNotFound, HTTPStatusCategory(http.StatusNotFound))
+	assert.Equal(t, CategoryRateLimit, HTTPStatusCategory(http.StatusTooManyRequests))
+	assert.Equal(t, CategoryQuota, HTTPStatusCategory(http.StatusInsufficientStorage))
+	assert.Equal(t, CategoryNetwork, HTTPStatusCategory(http.StatusServiceUnavailable))
+	assert.Equal(t, CategoryNone, HTTPStatusCategory(http.StatusOK))
+}
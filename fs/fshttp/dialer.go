@@ -27,8 +27,9 @@ func NewDialer(ctx context.Context) *Dialer {
 	ci := fs.GetConfig(ctx)
 	dialer := &Dialer{
 		Dialer: net.Dialer{
-			Timeout:   ci.ConnectTimeout,
-			KeepAlive: 30 * time.Second,
+			Timeout:       ci.ConnectTimeout,
+			KeepAlive:     30 * time.Second,
+			FallbackDelay: ci.HappyEyeballsDelay,
 		},
 		timeout: ci.Timeout,
 		tclass:  int(ci.TrafficClass),
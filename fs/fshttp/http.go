@@ -58,6 +58,7 @@ func NewTransportCustom(ctx context.Context, customize func(*http.Transport)) ht
 	t.Proxy = http.ProxyFromEnvironment
 	t.MaxIdleConnsPerHost = 2 * (ci.Checkers + ci.Transfers + 1)
 	t.MaxIdleConns = 2 * t.MaxIdleConnsPerHost
+	t.MaxConnsPerHost = ci.MaxConnsPerHost
 	t.TLSHandshakeTimeout = ci.ConnectTimeout
 	t.ResponseHeaderTimeout = ci.Timeout
 	t.DisableKeepAlives = ci.DisableHTTPKeepAlives
@@ -108,7 +109,7 @@ func NewTransportCustom(ctx context.Context, customize func(*http.Transport)) ht
 	t.DialContext = func(reqCtx context.Context, network, addr string) (net.Conn, error) {
 		return NewDialer(ctx).DialContext(reqCtx, network, addr)
 	}
-	t.IdleConnTimeout = 60 * time.Second
+	t.IdleConnTimeout = ci.IdleConnTimeout
 	t.ExpectContinueTimeout = ci.ExpectContinueTimeout
 
 	if ci.Dump&(fs.DumpHeaders|fs.DumpBodies|fs.DumpAuth|fs.DumpRequests|fs.DumpResponses) != 0 {
@@ -179,6 +180,7 @@ type Transport struct {
 	userAgent     string
 	headers       []*fs.HTTPOption
 	metrics       *Metrics
+	http3         *http3Transport // set if using HTTP/3
 	// Filename of the client cert in case we need to reload it
 	clientCert string
 	clientKey  string
@@ -189,7 +191,7 @@ type Transport struct {
 // newTransport wraps the http.Transport passed in and logs all
 // roundtrips including the body if logBody is set.
 func newTransport(ci *fs.ConfigInfo, transport *http.Transport) *Transport {
-	return &Transport{
+	t := &Transport{
 		Transport:  transport,
 		dump:       ci.Dump,
 		userAgent:  ci.UserAgent,
@@ -198,6 +200,14 @@ func newTransport(ci *fs.ConfigInfo, transport *http.Transport) *Transport {
 		clientCert: ci.ClientCert,
 		clientKey:  ci.ClientKey,
 	}
+	if ci.HTTP3 {
+		if ci.BindAddr != nil {
+			fs.Logf(nil, "Not using HTTP/3 as it isn't supported with --bind")
+		} else {
+			t.http3 = newHTTP3Transport(ci, transport)
+		}
+	}
+	return t
 }
 
 // SetRequestFilter sets a filter to be used on each request
@@ -346,7 +356,7 @@ func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error
 		logMutex.Unlock()
 	}
 	// Do round trip
-	resp, err = t.Transport.RoundTrip(req)
+	resp, err = t.roundTrip(req)
 	// Logf response
 	if t.dump&(fs.DumpHeaders|fs.DumpBodies|fs.DumpAuth|fs.DumpRequests|fs.DumpResponses) != 0 {
 		logMutex.Lock()
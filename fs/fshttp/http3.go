@@ -0,0 +1,230 @@
+// HTTP/3 support
+
+package fshttp
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/rclone/rclone/fs"
+)
+
+const (
+	altSvcMaxAge     = 24 * time.Hour  // how long an Alt-Svc lasts if it doesn't say
+	http3BrokenDelay = 5 * time.Minute // don't try HTTP/3 for this long after it fails
+)
+
+// http3Transport makes requests with HTTP/3 to the servers which
+// have advertised it with an Alt-Svc header.
+//
+// Servers are always contacted first with the normal transport, so
+// servers which don't support HTTP/3 work as before. If an HTTP/3
+// request fails then it is retried with the normal transport if
+// possible and HTTP/3 isn't used for that server for a while.
+//
+// HTTP/3 can't be sent through an HTTP proxy, so requests which the
+// normal transport would send through a proxy always use it.
+type http3Transport struct {
+	rt    *http3.Transport
+	proxy func(*http.Request) (*url.URL, error) // the proxy of the normal transport, may be nil
+	mu    sync.Mutex
+	hosts map[string]*http3Host // indexed by host:port
+}
+
+// http3Host is the HTTP/3 state of a server
+type http3Host struct {
+	expires time.Time // when the Alt-Svc advertisement expires
+	broken  time.Time // don't use HTTP/3 until this time
+}
+
+// newHTTP3Transport makes an http3Transport with the same settings as t
+func newHTTP3Transport(ci *fs.ConfigInfo, t *http.Transport) *http3Transport {
+	quicConfig := &quic.Config{
+		HandshakeIdleTimeout: ci.ConnectTimeout,
+		MaxIdleTimeout:       ci.Timeout,
+	}
+	var tlsConfig = t.TLSClientConfig
+	if tlsConfig != nil {
+		tlsConfig = tlsConfig.Clone()
+	}
+	return &http3Transport{
+		rt: &http3.Transport{
+			TLSClientConfig:    tlsConfig,
+			QUICConfig:         quicConfig,
+			DisableCompression: t.DisableCompression,
+		},
+		proxy: t.Proxy,
+		hosts: make(map[string]*http3Host),
+	}
+}
+
+// hostPort returns the host and port of the server req is for
+func hostPort(req *http.Request) (host, port string) {
+	host, port = req.URL.Hostname(), req.URL.Port()
+	if port == "" {
+		port = "443"
+	}
+	return host, port
+}
+
+// proxied returns true if the normal transport would send req
+// through a proxy
+func (h *http3Transport) proxied(req *http.Request) bool {
+	if h.proxy == nil {
+		return false
+	}
+	proxyURL, err := h.proxy(req)
+	return err != nil || proxyURL != nil
+}
+
+// use returns true if req should be made with HTTP/3
+func (h *http3Transport) use(req *http.Request) bool {
+	if req.URL.Scheme != "https" {
+		return false
+	}
+	key := net.JoinHostPort(hostPort(req))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	host, ok := h.hosts[key]
+	if !ok {
+		return false
+	}
+	now := time.Now()
+	if now.After(host.expires) {
+		delete(h.hosts, key)
+		return false
+	}
+	return now.After(host.broken)
+}
+
+// parseAltSvc parses an Alt-Svc header from the server on host:port and
+// returns how long HTTP/3 is available for.
+//
+// It returns ok false if the header doesn't offer HTTP/3 on the same
+// host and port and cleared true if the header withdraws all the
+// alternative services.
+func parseAltSvc(header, host, port string) (maxAge time.Duration, ok bool, cleared bool) {
+	header = strings.TrimSpace(header)
+	if header == "clear" {
+		return 0, false, true
+	}
+	for _, service := range strings.Split(header, ",") {
+		params := strings.Split(service, ";")
+		protocol, authority, found := strings.Cut(strings.TrimSpace(params[0]), "=")
+		if !found || protocol != "h3" {
+			continue
+		}
+		altHost, altPort, err := net.SplitHostPort(strings.Trim(authority, `"`))
+		if err != nil || (altHost != "" && altHost != host) || altPort != port {
+			continue
+		}
+		maxAge = altSvcMaxAge
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key != "ma" {
+				continue
+			}
+			seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+			if err == nil {
+				maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+		return maxAge, maxAge > 0, false
+	}
+	return 0, false, false
+}
+
+// altSvc records whether the server which made resp offers HTTP/3
+func (h *http3Transport) altSvc(req *http.Request, resp *http.Response) {
+	if req.URL.Scheme != "https" {
+		return
+	}
+	header := resp.Header.Get("Alt-Svc")
+	if header == "" {
+		return
+	}
+	host, port := hostPort(req)
+	maxAge, ok, cleared := parseAltSvc(header, host, port)
+	key := net.JoinHostPort(host, port)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if cleared {
+		delete(h.hosts, key)
+		return
+	}
+	if !ok {
+		return
+	}
+	state, found := h.hosts[key]
+	if !found {
+		fs.Debugf(nil, "Using HTTP/3 for %s", key)
+		state = &http3Host{}
+		h.hosts[key] = state
+	}
+	state.expires = time.Now().Add(maxAge)
+}
+
+// markBroken stops HTTP/3 being used for the server req is for for a while
+func (h *http3Transport) markBroken(req *http.Request, err error) {
+	key := net.JoinHostPort(hostPort(req))
+	fs.Debugf(nil, "HTTP/3 to %s failed, not using it for %v: %v", key, http3BrokenDelay, err)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if host, ok := h.hosts[key]; ok {
+		host.broken = time.Now().Add(http3BrokenDelay)
+	}
+}
+
+// roundTrip makes the request with HTTP/3 if the server offers it,
+// falling back to the normal transport if HTTP/3 fails.
+func (t *Transport) roundTrip(req *http.Request) (*http.Response, error) {
+	h := t.http3
+	if h == nil || h.proxied(req) {
+		return t.Transport.RoundTrip(req)
+	}
+	if !h.use(req) {
+		resp, err := t.Transport.RoundTrip(req)
+		if err == nil {
+			h.altSvc(req, resp)
+		}
+		return resp, err
+	}
+	resp, err := h.rt.RoundTrip(req)
+	if err == nil {
+		h.altSvc(req, resp)
+		return resp, nil
+	}
+	if req.Context().Err() != nil {
+		return nil, err
+	}
+	h.markBroken(req, err)
+	// Retry with the normal transport if the body can be sent again
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, err
+		}
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	return t.Transport.RoundTrip(req)
+}
+
+// CloseIdleConnections closes any connections which are now sitting
+// idle, including HTTP/3 ones.
+func (t *Transport) CloseIdleConnections() {
+	t.Transport.CloseIdleConnections()
+	if t.http3 != nil {
+		t.http3.rt.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,119 @@
+package fshttp
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAltSvc(t *testing.T) {
+	for _, test := range []struct {
+		header  string
+		maxAge  time.Duration
+		ok      bool
+		cleared bool
+	}{
+		{``, 0, false, false},
+		{`clear`, 0, false, true},
+		{`h3=":443"`, altSvcMaxAge, true, false},
+		{`h3=":443"; ma=3600`, time.Hour, true, false},
+		{`h3-29=":443"; ma=3600, h3=":443"; ma=60; persist=1`, time.Minute, true, false},
+		{`h3="example.com:443"`, altSvcMaxAge, true, false},
+		{`h3="other.example.com:443"`, 0, false, false},
+		{`h3=":8443"`, 0, false, false},
+		{`h2=":443"`, 0, false, false},
+		{`h3=":443"; ma=0`, 0, false, false},
+	} {
+		maxAge, ok, cleared := parseAltSvc(test.header, "example.com", "443")
+		assert.Equal(t, test.maxAge, maxAge, test.header)
+		assert.Equal(t, test.ok, ok, test.header)
+		assert.Equal(t, test.cleared, cleared, test.header)
+	}
+}
+
+func TestHTTP3(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, port, _ := net.SplitHostPort(r.Host)
+		w.Header().Set("Alt-Svc", fmt.Sprintf(`h3=":%s"; ma=60`, port))
+		_, _ = fmt.Fprint(w, r.Proto)
+	})
+
+	// Serve HTTP/1.1 over TCP and HTTP/3 over UDP on the same port
+	ts := httptest.NewTLSServer(handler)
+	defer ts.Close()
+	udpConn, err := net.ListenPacket("udp", ts.Listener.Addr().String())
+	require.NoError(t, err)
+	h3Server := &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(ts.TLS.Clone()),
+	}
+	go func() {
+		_ = h3Server.Serve(udpConn)
+	}()
+
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.HTTP3 = true
+	ci.ConnectTimeout = time.Second
+	client := NewClientCustom(ctx, func(t *http.Transport) {
+		roots := x509.NewCertPool()
+		roots.AddCert(ts.Certificate())
+		t.TLSClientConfig.RootCAs = roots
+	})
+	defer client.CloseIdleConnections()
+
+	get := func() string {
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp.Proto
+	}
+
+	// The first request finds out about HTTP/3
+	assert.Equal(t, "HTTP/1.1", get())
+
+	// Which is used for the next
+	assert.Equal(t, "HTTP/3.0", get())
+	assert.Equal(t, "HTTP/3.0", get())
+
+	// If HTTP/3 stops working then it falls back
+	require.NoError(t, h3Server.Close())
+	require.NoError(t, udpConn.Close())
+	assert.Equal(t, "HTTP/1.1", get())
+	assert.Equal(t, "HTTP/1.1", get())
+}
+
+func TestHTTP3Proxied(t *testing.T) {
+	proxyURL, err := url.Parse("http://proxy.example.com:3128")
+	require.NoError(t, err)
+	transport := &http.Transport{
+		Proxy: func(req *http.Request) (*url.URL, error) {
+			if req.URL.Hostname() == "direct.example.com" {
+				return nil, nil
+			}
+			return proxyURL, nil
+		},
+	}
+	h := newHTTP3Transport(fs.GetConfig(context.Background()), transport)
+
+	req, err := http.NewRequest("GET", "https://direct.example.com/", nil)
+	require.NoError(t, err)
+	assert.False(t, h.proxied(req))
+
+	req, err = http.NewRequest("GET", "https://proxied.example.com/", nil)
+	require.NoError(t, err)
+	assert.True(t, h.proxied(req))
+}
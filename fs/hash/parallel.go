@@ -0,0 +1,146 @@
+package hash
+
+import (
+	"context"
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"github.com/rclone/rclone/lib/readers"
+	"golang.org/x/sync/errgroup"
+)
+
+// Chunker is implemented by the hash.Hash of hash types which can be
+// calculated in parallel from chunks of the data.
+//
+// Hashes such as MD5 and SHA-1 must be calculated from start to finish
+// but hashes made by combining the hashes of blocks of the data, such
+// as the Dropbox hash, don't have to be.
+type Chunker interface {
+	// ChunkSize returns the size which the chunks must be a
+	// multiple of
+	ChunkSize() int64
+	// NewChunk returns a hash.Hash to hash a chunk of the data
+	// starting at a multiple of ChunkSize. Its Sum is passed to
+	// Combine.
+	NewChunk() hash.Hash
+	// Combine returns the hash of size bytes of data from the sums
+	// of its chunks in order.
+	Combine(sums [][]byte, size int64) []byte
+}
+
+// Read the chunks in blocks of this size
+const parallelBufferSize = 1024 * 1024
+
+// Don't split the data into chunks smaller than this
+var parallelMinChunk int64 = 16 * 1024 * 1024
+
+// chunkers returns the types in set whose hashes implement Chunker
+func chunkers(set Set) map[Type]Chunker {
+	chunkers := map[Type]Chunker{}
+	for _, t := range set.Array() {
+		if h := type2hash[t]; h != nil {
+			if c, ok := h.newFunc().(Chunker); ok {
+				chunkers[t] = c
+			}
+		}
+	}
+	return chunkers
+}
+
+// Parallel returns true if hashes of type t can be calculated in
+// parallel by ReaderAtTypes.
+func Parallel(t Type) bool {
+	return len(chunkers(NewHashSet(t))) > 0
+}
+
+// gcd returns the greatest common divisor of a and b
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// ReaderAtTypes calculates the hashes of the requested types of size
+// bytes read from in.
+//
+// The hash types which implement Chunker are calculated using up to
+// streams goroutines each hashing a different part of the data at
+// once. The other types are calculated in one pass from start to
+// finish at the same time.
+func ReaderAtTypes(ctx context.Context, in io.ReaderAt, size int64, set Set, streams int) (map[Type]string, error) {
+	chunked := chunkers(set)
+	if streams <= 1 || len(chunked) == 0 || size <= parallelMinChunk {
+		return StreamTypes(readers.NewContextReader(ctx, io.NewSectionReader(in, 0, size)), set)
+	}
+
+	// Work out a chunk size which suits all the hash types and
+	// gives each stream at least one chunk
+	var unit int64 = 1
+	for _, c := range chunked {
+		unit = unit / gcd(unit, c.ChunkSize()) * c.ChunkSize()
+	}
+	chunkSize := max(size/int64(streams), parallelMinChunk)
+	chunkSize = (chunkSize + unit - 1) / unit * unit
+	chunks := max(1, int((size+chunkSize-1)/chunkSize))
+
+	var (
+		ret     = make(map[Type]string)
+		sums    = make(map[Type][][]byte, len(chunked))
+		g, gCtx = errgroup.WithContext(ctx)
+	)
+	for t := range chunked {
+		sums[t] = make([][]byte, chunks)
+	}
+	g.SetLimit(streams + 1)
+
+	// Calculate the other types in one pass
+	var plain Set
+	for _, t := range set.Array() {
+		if _, ok := chunked[t]; !ok {
+			plain.Add(t)
+		}
+	}
+	if plain.Count() > 0 {
+		g.Go(func() error {
+			hashes, err := StreamTypes(readers.NewContextReader(gCtx, io.NewSectionReader(in, 0, size)), plain)
+			if err != nil {
+				return err
+			}
+			for t, sum := range hashes {
+				ret[t] = sum
+			}
+			return nil
+		})
+	}
+
+	// Hash the chunks in parallel
+	for i := range chunks {
+		offset := int64(i) * chunkSize
+		n := min(chunkSize, size-offset)
+		g.Go(func() error {
+			hashers := make(map[Type]hash.Hash, len(chunked))
+			for t, c := range chunked {
+				hashers[t] = c.NewChunk()
+			}
+			buf := make([]byte, min(n, parallelBufferSize))
+			_, err := io.CopyBuffer(toMultiWriter(hashers), readers.NewContextReader(gCtx, io.NewSectionReader(in, offset, n)), buf)
+			if err != nil {
+				return err
+			}
+			for t, h := range hashers {
+				sums[t][i] = h.Sum(nil)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	for t, c := range chunked {
+		ret[t] = hex.EncodeToString(c.Combine(sums[t], size))
+	}
+	return ret, nil
+}
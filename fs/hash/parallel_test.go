@@ -0,0 +1,84 @@
+package hash
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/rclone/rclone/backend/dropbox/dbhash"
+	"github.com/rclone/rclone/backend/onedrive/quickxorhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errorReaderAt returns an error reading after offset
+type errorReaderAt struct {
+	*bytes.Reader
+	offset int64
+}
+
+var errTestRead = errors.New("test read error")
+
+func (r errorReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > r.offset {
+		return 0, errTestRead
+	}
+	return r.Reader.ReadAt(p, off)
+}
+
+func TestReaderAtTypes(t *testing.T) {
+	old := SupportOnly(append([]Type(nil), supported...))
+	defer SupportOnly(old)
+	dropbox := RegisterHash("dropbox", "DropboxHash", 64, dbhash.New)
+	quickXor := RegisterHash("quickxor", "QuickXorHash", 40, quickxorhash.New)
+
+	oldMinChunk := parallelMinChunk
+	parallelMinChunk = 1024 * 1024
+	defer func() {
+		parallelMinChunk = oldMinChunk
+	}()
+
+	data := make([]byte, 21*1024*1024)
+	_, err := rand.New(rand.NewSource(1)).Read(data)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		name    string
+		set     Set
+		size    int
+		streams int
+	}{
+		{"dropbox", NewHashSet(dropbox), len(data), 4},
+		{"dropbox small", NewHashSet(dropbox), 1000, 4},
+		{"dropbox one stream", NewHashSet(dropbox), len(data), 1},
+		{"dropbox and md5", NewHashSet(dropbox, MD5), len(data), 3},
+		{"quickxor", NewHashSet(quickXor), len(data), 8},
+		{"quickxor and md5", NewHashSet(quickXor, MD5), 3*1024*1024 + 17, 2},
+		{"md5", NewHashSet(MD5), len(data), 4},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			in := data[:test.size]
+			want, err := StreamTypes(bytes.NewReader(in), test.set)
+			require.NoError(t, err)
+			got, err := ReaderAtTypes(ctx, bytes.NewReader(in), int64(len(in)), test.set, test.streams)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		in := errorReaderAt{Reader: bytes.NewReader(data), offset: 10 * 1024 * 1024}
+		_, err := ReaderAtTypes(ctx, in, int64(len(data)), NewHashSet(dropbox, MD5), 4)
+		assert.ErrorIs(t, err, errTestRead)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := ReaderAtTypes(ctx, bytes.NewReader(data), int64(len(data)), NewHashSet(dropbox), 4)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
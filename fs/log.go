@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -144,8 +145,16 @@ func logJSONf(level LogLevel, o any, text string, args ...any) {
 	text = fmt.Sprintf(text, args...)
 	fields := logrus.Fields{}
 	for _, arg := range args {
-		if item, ok := arg.(LogValueItem); ok {
+		switch item := arg.(type) {
+		case LogValueItem:
 			fields[item.key] = item.value
+		case error:
+			if _, found := fields["errorCategory"]; found {
+				break
+			}
+			if category := fserrors.GetCategory(item); category != fserrors.CategoryNone {
+				fields["errorCategory"] = string(category)
+			}
 		}
 	}
 	logLogrusWithObject(level, o, text, fields)
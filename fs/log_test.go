@@ -1,11 +1,15 @@
 package fs
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"testing"
 
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -32,6 +36,34 @@ func TestLogValue(t *testing.T) {
 	assert.Equal(t, "", x.String())
 }
 
+func TestLogJSONErrorCategory(t *testing.T) {
+	var buf bytes.Buffer
+	oldOut, oldFormatter := logrus.StandardLogger().Out, logrus.StandardLogger().Formatter
+	logrus.SetOutput(&buf)
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+	defer func() {
+		logrus.SetOutput(oldOut)
+		logrus.SetFormatter(oldFormatter)
+	}()
+
+	read := func() (fields map[string]any) {
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &fields))
+		buf.Reset()
+		return fields
+	}
+
+	logJSONf(LogLevelError, nil, "failed: %v", fserrors.CategoryError(errors.New("bad token"), fserrors.CategoryAuth))
+	fields := read()
+	assert.Equal(t, "failed: bad token", fields["msg"])
+	assert.Equal(t, "auth", fields["errorCategory"])
+
+	logJSONf(LogLevelError, nil, "failed: %v", fmt.Errorf("open: %w", ErrorObjectNotFound))
+	assert.Equal(t, "not-found", read()["errorCategory"])
+
+	logJSONf(LogLevelError, nil, "failed: %v", errors.New("potato"))
+	assert.NotContains(t, read(), "errorCategory")
+}
+
 func TestLogLevelString(t *testing.T) {
 	for _, test := range []struct {
 		in   LogLevel
@@ -0,0 +1,164 @@
+package march
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/dirtree"
+	"github.com/rclone/rclone/fs/list"
+	"github.com/rclone/rclone/fs/walk"
+)
+
+// fastListSplitMax is the maximum number of directories in the root
+// for the --fast-list listing to be split into one ListR for each.
+var fastListSplitMax = 100
+
+// fastList lists a directory tree for --fast-list.
+//
+// Rather than listing the whole tree with ListR before the march can
+// start, the root is listed on its own and then each directory in it
+// is listed with ListR when the march gets to it. This means checks
+// and transfers can start as soon as the first directory has been
+// listed and only the directories being marched are kept in memory.
+//
+// This costs an extra listing of the root and a ListR for each
+// directory in it, so if there are more than fastListSplitMax
+// directories in the root the whole tree is listed with one ListR
+// instead.
+type fastList struct {
+	ctx        context.Context
+	f          fs.Fs
+	root       string
+	includeAll bool
+	maxLevel   int
+
+	mu       sync.Mutex
+	started  bool                // set once the root has been listed
+	err      error               // error listing the root
+	dirs     dirtree.DirTree     // directories listed but not yet marched
+	subTrees map[string]*subTree // directories in the root still to be listed
+}
+
+// subTree is a directory in the root which is listed with ListR
+type subTree struct {
+	once sync.Once
+	err  error
+}
+
+// newFastList makes a fastList for the tree at root in f
+func newFastList(ctx context.Context, f fs.Fs, root string, includeAll bool, maxLevel int) *fastList {
+	return &fastList{
+		ctx:        ctx,
+		f:          f,
+		root:       root,
+		includeAll: includeAll,
+		maxLevel:   maxLevel,
+	}
+}
+
+// listRoot lists the root and works out which directories to list
+// with ListR
+//
+// Call with mu held
+func (fl *fastList) listRoot() error {
+	if fl.maxLevel == 0 {
+		dirs, err := walk.NewDirTree(fl.ctx, fl.f, fl.root, fl.includeAll, fl.maxLevel)
+		fl.dirs = dirs
+		return err
+	}
+	entries, err := list.DirSorted(fl.ctx, fl.f, fl.includeAll, fl.root)
+	if err != nil {
+		return err
+	}
+	dirs := dirtree.New()
+	dirs[fl.root] = entries
+	if fl.maxLevel == 1 {
+		fl.dirs = dirs
+		return nil
+	}
+	subTrees := make(map[string]*subTree)
+	for _, entry := range entries {
+		if _, ok := entry.(fs.Directory); ok {
+			subTrees[entry.Remote()] = &subTree{}
+		}
+	}
+	if len(subTrees) > fastListSplitMax {
+		fs.Debugf(fl.f, "Listing whole tree with ListR as %d directories in the root is too many to split", len(subTrees))
+		dirs, err = walk.NewDirTree(fl.ctx, fl.f, fl.root, fl.includeAll, fl.maxLevel)
+		fl.dirs = dirs
+		return err
+	}
+	fl.dirs = dirs
+	fl.subTrees = subTrees
+	return nil
+}
+
+// subTreeRoot returns the directory in the root which dir is in or ""
+// if dir is the root
+func (fl *fastList) subTreeRoot(dir string) string {
+	if dir == fl.root {
+		return ""
+	}
+	rel := dir
+	if fl.root != "" {
+		rel = strings.TrimPrefix(dir, fl.root+"/")
+	}
+	top, _, _ := strings.Cut(rel, "/")
+	return path.Join(fl.root, top)
+}
+
+// listSubTree lists the directory dir in the root with ListR
+func (fl *fastList) listSubTree(dir string) error {
+	maxLevel := fl.maxLevel
+	if maxLevel > 0 {
+		maxLevel--
+	}
+	dirs, err := walk.NewDirTree(fl.ctx, fl.f, dir, fl.includeAll, maxLevel)
+	if err != nil {
+		return err
+	}
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	for dirPath, entries := range dirs {
+		fl.dirs[dirPath] = entries
+	}
+	return nil
+}
+
+// listDir returns the entries in dir, listing them if necessary
+//
+// The entries are removed from the fastList once returned.
+func (fl *fastList) listDir(dir string) (entries fs.DirEntries, err error) {
+	fl.mu.Lock()
+	if !fl.started {
+		fl.started = true
+		fl.err = fl.listRoot()
+	}
+	if fl.err != nil {
+		fl.mu.Unlock()
+		return nil, fl.err
+	}
+	st := fl.subTrees[fl.subTreeRoot(dir)]
+	fl.mu.Unlock()
+
+	if st != nil {
+		st.once.Do(func() {
+			st.err = fl.listSubTree(fl.subTreeRoot(dir))
+		})
+		if st.err != nil {
+			return nil, st.err
+		}
+	}
+
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	entries, ok := fl.dirs[dir]
+	if !ok {
+		return nil, fs.ErrorDirNotFound
+	}
+	delete(fl.dirs, dir)
+	return entries, nil
+}
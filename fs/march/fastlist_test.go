@@ -0,0 +1,119 @@
+package march
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/memory"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeFastListFs makes a memory Fs with some files in for testing
+func makeFastListFs(t *testing.T) fs.Fs {
+	ctx := context.Background()
+	f, err := fs.NewFs(ctx, ":memory:"+t.Name())
+	require.NoError(t, err)
+	require.NotNil(t, f.Features().ListR)
+	for _, remote := range []string{"file", "a/file1", "a/b/file2", "a/b/c/file3", "d/file4"} {
+		src := object.NewStaticObjectInfo(remote, t1, int64(len(remote)), true, nil, nil)
+		_, err := f.Put(ctx, strings.NewReader(remote), src)
+		require.NoError(t, err)
+	}
+	return f
+}
+
+// names returns the remotes of entries
+func names(entries fs.DirEntries) (remotes []string) {
+	for _, entry := range entries {
+		remotes = append(remotes, entry.Remote())
+	}
+	return remotes
+}
+
+func TestFastList(t *testing.T) {
+	ctx := context.Background()
+	f := makeFastListFs(t)
+	fl := newFastList(ctx, f, "", true, -1)
+
+	entries, err := fl.listDir("")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"file", "a", "d"}, names(entries))
+	assert.Len(t, fl.subTrees, 2)
+
+	// Only the root has been listed so far
+	assert.Empty(t, fl.dirs)
+
+	entries, err = fl.listDir("d")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"d/file4"}, names(entries))
+
+	// Listing a only lists that tree
+	entries, err = fl.listDir("a")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a/file1", "a/b"}, names(entries))
+	assert.Contains(t, fl.dirs, "a/b")
+	assert.Contains(t, fl.dirs, "a/b/c")
+
+	entries, err = fl.listDir("a/b/c")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a/b/c/file3"}, names(entries))
+
+	// Directories are only returned once
+	_, err = fl.listDir("a/b/c")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+	_, err = fl.listDir("a/notfound")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+}
+
+func TestFastListSubDir(t *testing.T) {
+	ctx := context.Background()
+	f := makeFastListFs(t)
+	fl := newFastList(ctx, f, "a", true, -1)
+
+	entries, err := fl.listDir("a")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a/file1", "a/b"}, names(entries))
+	assert.Len(t, fl.subTrees, 1)
+	assert.Equal(t, "a/b", fl.subTreeRoot("a/b/c"))
+
+	entries, err = fl.listDir("a/b/c")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a/b/c/file3"}, names(entries))
+}
+
+func TestFastListMaxLevel(t *testing.T) {
+	ctx := context.Background()
+	f := makeFastListFs(t)
+	fl := newFastList(ctx, f, "", true, 2)
+
+	_, err := fl.listDir("")
+	require.NoError(t, err)
+	entries, err := fl.listDir("a")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a/file1", "a/b"}, names(entries))
+	_, err = fl.listDir("a/b")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+}
+
+func TestFastListTooManyToSplit(t *testing.T) {
+	ctx := context.Background()
+	f := makeFastListFs(t)
+	oldFastListSplitMax := fastListSplitMax
+	fastListSplitMax = 1
+	defer func() {
+		fastListSplitMax = oldFastListSplitMax
+	}()
+	fl := newFastList(ctx, f, "", true, -1)
+
+	entries, err := fl.listDir("")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"file", "a", "d"}, names(entries))
+
+	// The whole tree has been listed
+	assert.Nil(t, fl.subTrees)
+	assert.Contains(t, fl.dirs, "a/b/c")
+}
@@ -106,8 +106,27 @@ func (m *March) makeListDir(ctx context.Context, f fs.Fs, includeAll bool) listD
 		}
 	}
 
-	// This returns a closure for use when --fast-list is active or for when
-	// --files-from and --no-traverse is set
+	// If --fast-list is active split the listing up so the march
+	// can start before the whole tree is listed
+	if ci.UseListR && f.Features().ListR != nil && !fi.HaveFilesFrom() {
+		dirCtx := filter.SetUseFilter(m.Ctx, f.Features().FilterAware && !includeAll) // make filter-aware backends constrain List
+		fl := newFastList(dirCtx, f, m.Dir, includeAll, ci.MaxDepth)
+		return func(dir string, callback fs.ListRCallback) (err error) {
+			entries, err := fl.listDir(dir)
+			if err != nil {
+				return err
+			}
+			// We use a stable sort here just in case there are
+			// duplicates - see below.
+			slices.SortStableFunc(entries, func(a, b fs.DirEntry) int {
+				return cmp.Compare(m.key(a), m.key(b))
+			})
+			return callback(entries)
+		}
+	}
+
+	// This returns a closure for use when --files-from and
+	// --no-traverse is set
 	var (
 		mu      sync.Mutex
 		started bool
@@ -0,0 +1,129 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rclone/rclone/fs"
+)
+
+const (
+	changedBlockSize = 64 << 10
+)
+
+// Return the updater to use if we should only write the changed
+// blocks for this copy or nil if not
+func (c *copy) changedBlocksUpdater() fs.OpenPartialUpdater {
+	// Don't only write the changed blocks if...
+
+	// ...it isn't configured
+	if !c.ci.WriteChangedBlocks {
+		return nil
+	}
+	// ...there is nothing to update
+	if !c.doUpdate || c.dst == nil {
+		return nil
+	}
+	// ...the size of the source is unknown
+	if c.src.Size() < 0 {
+		return nil
+	}
+	// ...metadata is being copied as it wouldn't be updated
+	if c.ci.Metadata {
+		return nil
+	}
+	// ...the destination doesn't support it
+	updater, ok := c.dst.(fs.OpenPartialUpdater)
+	if !ok {
+		return nil
+	}
+	return updater
+}
+
+// Copy c.src over c.dst by only writing the blocks which differ
+//
+// The source and destination are read block by block in step and
+// only the blocks which are different are written to the destination.
+// This means that the whole of the destination is read so it is best
+// suited for large files with small changes where writing is more
+// expensive than reading.
+//
+// Blocks are only compared at the same offset, so unlike rsync data
+// which has moved within the file is written again.
+func (c *copy) changedBlocksCopy(ctx context.Context, updater fs.OpenPartialUpdater, downloadOptions []fs.OpenOption) (actionTaken string, newDst fs.Object, err error) {
+	size := c.src.Size()
+	in, err := Open(ctx, c.src, downloadOptions...)
+	if err != nil {
+		return actionTaken, nil, fmt.Errorf("failed to open source object: %w", err)
+	}
+	inAcc := c.tr.Account(ctx, in)
+	defer fs.CheckClose(inAcc, &err)
+
+	old, err := Open(ctx, c.dst)
+	if err != nil {
+		return actionTaken, nil, fmt.Errorf("failed to open destination object: %w", err)
+	}
+	defer fs.CheckClose(old, &err)
+
+	out, err := updater.OpenPartialUpdate(ctx, size)
+	if err != nil {
+		return actionTaken, nil, fmt.Errorf("failed to open destination object for update: %w", err)
+	}
+	written, err := writeChangedBlocks(out, inAcc, old, size)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return actionTaken, nil, fmt.Errorf("writing changed blocks failed: %w", err)
+	}
+
+	err = c.dst.SetModTime(ctx, c.src.ModTime(ctx))
+	if err != nil && !errors.Is(err, fs.ErrorCantSetModTime) {
+		return actionTaken, nil, fmt.Errorf("writing changed blocks failed to set modification time: %w", err)
+	}
+	actionTaken = fmt.Sprintf("Copied (changed blocks, wrote %v)", fs.SizeSuffix(written))
+	return actionTaken, c.dst, nil
+}
+
+// Read size bytes from in and the same part of old block by block and
+// write any blocks which differ to out.
+//
+// old may be shorter or longer than size. It returns the number of
+// bytes written to out.
+func writeChangedBlocks(out io.WriterAt, in, old io.Reader, size int64) (written int64, err error) {
+	inBuf := make([]byte, changedBlockSize)
+	oldBuf := make([]byte, changedBlockSize)
+	oldEOF := false
+	for offset := int64(0); offset < size; {
+		n := int64(changedBlockSize)
+		if size-offset < n {
+			n = size - offset
+		}
+		_, err = io.ReadFull(in, inBuf[:n])
+		if err != nil {
+			return written, fmt.Errorf("failed to read source: %w", err)
+		}
+		var oldN int
+		if !oldEOF {
+			oldN, err = io.ReadFull(old, oldBuf[:n])
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				oldEOF = true
+			} else if err != nil {
+				return written, fmt.Errorf("failed to read destination: %w", err)
+			}
+		}
+		if oldN != int(n) || !bytes.Equal(inBuf[:n], oldBuf[:n]) {
+			_, err = out.WriteAt(inBuf[:n], offset)
+			if err != nil {
+				return written, fmt.Errorf("failed to write destination: %w", err)
+			}
+			written += n
+		}
+		offset += n
+	}
+	return written, nil
+}
@@ -0,0 +1,52 @@
+package operations
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bufWriterAt is an io.WriterAt which writes into a byte slice
+type bufWriterAt struct {
+	buf []byte
+}
+
+func (w *bufWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
+	for i, c := range p {
+		w.buf[off+int64(i)] = c
+	}
+	return len(p), nil
+}
+
+func TestWriteChangedBlocks(t *testing.T) {
+	block := changedBlockSize
+	base := strings.Repeat("a", block) + strings.Repeat("b", block) + strings.Repeat("c", block/2)
+	for _, test := range []struct {
+		name        string
+		src         string
+		old         string
+		wantWritten int64
+	}{
+		{"Same", base, base, 0},
+		{"Empty", "", base, 0},
+		{"ChangedMiddle", base[:block+10] + "X" + base[block+11:], base, int64(block)},
+		{"ChangedEnd", base[:len(base)-1] + "X", base, int64(block / 2)},
+		{"Longer", base + "more", base, int64(block/2 + 4)},
+		{"LongerInBlock", base[:block+10], base[:block+5], 10},
+		{"Shorter", base[:block], base, 0},
+		{"OldEmpty", base, "", int64(len(base))},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// the destination after it has been opened with the new size
+			old := test.old + strings.Repeat("\x00", max(len(test.src)-len(test.old), 0))
+			out := &bufWriterAt{buf: []byte(old[:len(test.src)])}
+			written, err := writeChangedBlocks(out, strings.NewReader(test.src), strings.NewReader(test.old), int64(len(test.src)))
+			require.NoError(t, err)
+			assert.Equal(t, test.wantWritten, written)
+			assert.True(t, bytes.Equal([]byte(test.src), out.buf), "contents differ")
+		})
+	}
+}
@@ -44,6 +44,9 @@ type CheckOpt struct {
 	Match        io.Writer // matching files
 	Differ       io.Writer // differing files
 	Error        io.Writer // files with errors of some kind
+	Report       io.Writer // machine readable report of all files
+	ReportFormat string    // format of Report, CheckReportJSON (default) or CheckReportCSV
+	hashType     hash.Type // hash type compared by Check for the report
 }
 
 // checkMarch is used to march over two Fses in the same way as
@@ -59,11 +62,108 @@ type checkMarch struct {
 	dstFilesMissing atomic.Int32
 	matches         atomic.Int32
 	opt             CheckOpt
+	reporter        *checkReporter // set if making a report
 }
 
-// report outputs the fileName to out if required and to the combined log
-func (c *checkMarch) report(o fs.DirEntry, out io.Writer, sigil rune) {
+// newCheckMarch makes a checkMarch for opt
+func newCheckMarch(ctx context.Context, opt *CheckOpt) (c *checkMarch, err error) {
+	ci := fs.GetConfig(ctx)
+	c = &checkMarch{
+		ctx:    ctx,
+		tokens: make(chan struct{}, ci.Checkers),
+		opt:    *opt,
+	}
+	if opt.Report != nil {
+		c.reporter, err = newCheckReporter(opt.Report, opt.ReportFormat)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// closeReport finishes the report if there is one, returning err if
+// set or any error writing the report
+func (c *checkMarch) closeReport(err error) error {
+	if c.reporter == nil {
+		return err
+	}
+	reportErr := c.reporter.close()
+	if reportErr != nil {
+		fs.Errorf(nil, "%v", reportErr)
+		if err == nil {
+			err = reportErr
+		}
+	}
+	return err
+}
+
+// report outputs the file name to out if required and to the combined
+// log and adds it to the report.
+//
+// src and dst are the entries found with the name, either of which
+// may be nil, and err is the reason for an error.
+func (c *checkMarch) report(out io.Writer, sigil rune, src, dst fs.DirEntry, err error) {
+	o := src
+	if o == nil {
+		o = dst
+	}
 	c.reportFilename(o.String(), out, sigil)
+	if c.reporter == nil {
+		return
+	}
+	entry := &CheckReportEntry{
+		Path:    o.String(),
+		Status:  checkStatuses[sigil],
+		SrcSize: -1,
+		DstSize: -1,
+	}
+	srcObj, srcOK := src.(fs.Object)
+	if srcOK {
+		entry.SrcSize = srcObj.Size()
+	}
+	dstObj, dstOK := dst.(fs.Object)
+	if dstOK {
+		entry.DstSize = dstObj.Size()
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	// Add the hashes compared by Check - these will usually be cached
+	if srcOK && dstOK && c.opt.hashType != hash.None && (sigil == '=' || sigil == '*') && !sizeDiffers(c.ctx, srcObj, dstObj) && !fs.GetConfig(c.ctx).SizeOnly {
+		entry.HashType = c.opt.hashType.String()
+		entry.SrcHash, _ = srcObj.Hash(c.ctx, c.opt.hashType)
+		entry.DstHash, _ = dstObj.Hash(c.ctx, c.opt.hashType)
+	}
+	c.reporter.write(entry)
+}
+
+// reportSum outputs the file name to out if required and to the
+// combined log and adds it to the report for the checks against a SUM
+// file.
+//
+// obj may be nil if the file is missing.
+func (c *checkMarch) reportSum(out io.Writer, sigil rune, filename string, obj fs.Object, hashType hash.Type, sumHash, objHash string, err error) {
+	c.reportFilename(filename, out, sigil)
+	if c.reporter == nil {
+		return
+	}
+	entry := &CheckReportEntry{
+		Path:     filename,
+		Status:   checkStatuses[sigil],
+		SrcSize:  -1,
+		DstSize:  -1,
+		HashType: hashType.String(),
+		SrcHash:  sumHash,
+		DstHash:  objHash,
+	}
+	if obj != nil {
+		entry.DstSize = obj.Size()
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	c.reporter.write(entry)
 }
 
 func (c *checkMarch) reportFilename(filename string, out io.Writer, sigil rune) {
@@ -87,7 +187,7 @@ func (c *checkMarch) DstOnly(dst fs.DirEntry) (recurse bool) {
 		_ = fs.CountError(c.ctx, err)
 		c.differences.Add(1)
 		c.srcFilesMissing.Add(1)
-		c.report(dst, c.opt.MissingOnSrc, '-')
+		c.report(c.opt.MissingOnSrc, '-', nil, dst, nil)
 	case fs.Directory:
 		// Do the same thing to the entire contents of the directory
 		if c.opt.OneWay {
@@ -109,7 +209,7 @@ func (c *checkMarch) SrcOnly(src fs.DirEntry) (recurse bool) {
 		_ = fs.CountError(c.ctx, err)
 		c.differences.Add(1)
 		c.dstFilesMissing.Add(1)
-		c.report(src, c.opt.MissingOnDst, '+')
+		c.report(c.opt.MissingOnDst, '+', src, nil, nil)
 	case fs.Directory:
 		// Do the same thing to the entire contents of the directory
 		return true
@@ -157,16 +257,16 @@ func (c *checkMarch) Match(ctx context.Context, dst, src fs.DirEntry) (recurse b
 				if err != nil {
 					fs.Errorf(src, "%v", err)
 					_ = fs.CountError(ctx, err)
-					c.report(src, c.opt.Error, '!')
+					c.report(c.opt.Error, '!', srcX, dstX, err)
 				} else if differ {
 					c.differences.Add(1)
 					err := errors.New("files differ")
 					// the checkFn has already logged the reason
 					_ = fs.CountError(ctx, err)
-					c.report(src, c.opt.Differ, '*')
+					c.report(c.opt.Differ, '*', srcX, dstX, nil)
 				} else {
 					c.matches.Add(1)
-					c.report(src, c.opt.Match, '=')
+					c.report(c.opt.Match, '=', srcX, dstX, nil)
 					if noHash {
 						c.noHashes.Add(1)
 						fs.Debugf(dstX, "OK - could not check hash")
@@ -181,7 +281,7 @@ func (c *checkMarch) Match(ctx context.Context, dst, src fs.DirEntry) (recurse b
 			_ = fs.CountError(ctx, err)
 			c.differences.Add(1)
 			c.dstFilesMissing.Add(1)
-			c.report(src, c.opt.MissingOnDst, '+')
+			c.report(c.opt.MissingOnDst, '+', src, dst, err)
 		}
 	case fs.Directory:
 		// Do the same thing to the entire contents of the directory
@@ -194,7 +294,7 @@ func (c *checkMarch) Match(ctx context.Context, dst, src fs.DirEntry) (recurse b
 		_ = fs.CountError(ctx, err)
 		c.differences.Add(1)
 		c.srcFilesMissing.Add(1)
-		c.report(dst, c.opt.MissingOnSrc, '-')
+		c.report(c.opt.MissingOnSrc, '-', src, dst, err)
 
 	default:
 		panic("Bad object in DirEntries")
@@ -214,10 +314,9 @@ func CheckFn(ctx context.Context, opt *CheckOpt) error {
 	if opt.Check == nil {
 		return errors.New("internal error: nil check function")
 	}
-	c := &checkMarch{
-		ctx:    ctx,
-		tokens: make(chan struct{}, ci.Checkers),
-		opt:    *opt,
+	c, err := newCheckMarch(ctx, opt)
+	if err != nil {
+		return err
 	}
 
 	// set up a march over fdst and fsrc
@@ -231,8 +330,9 @@ func CheckFn(ctx context.Context, opt *CheckOpt) error {
 		NoUnicodeNormalization: ci.NoUnicodeNormalization,
 	}
 	fs.Debugf(c.opt.Fdst, "Waiting for checks to finish")
-	err := m.Run(ctx)
+	err = m.Run(ctx)
 	c.wg.Wait() // wait for background go-routines
+	err = c.closeReport(err)
 
 	return c.reportResults(ctx, err)
 }
@@ -274,6 +374,7 @@ func (c *checkMarch) reportResults(ctx context.Context, err error) error {
 // Check the files in fsrc and fdst according to Size and hash
 func Check(ctx context.Context, opt *CheckOpt) error {
 	optCopy := *opt
+	optCopy.hashType = opt.Fsrc.Hashes().Overlap(opt.Fdst.Hashes()).GetOne()
 	optCopy.Check = func(ctx context.Context, dst, src fs.Object) (differ bool, noHash bool, err error) {
 		same, ht, err := CheckHashes(ctx, src, dst)
 		if err != nil {
@@ -430,11 +531,9 @@ func CheckSum(ctx context.Context, fsrc, fsum fs.Fs, sumFile string, hashType ha
 		return fmt.Errorf("failed to parse sum file: %w", err)
 	}
 
-	ci := fs.GetConfig(ctx)
-	c := &checkMarch{
-		ctx:    ctx,
-		tokens: make(chan struct{}, ci.Checkers),
-		opt:    *opt,
+	c, err := newCheckMarch(ctx, opt)
+	if err != nil {
+		return err
 	}
 	lastErr := ListFn(ctx, opt.Fdst, func(obj fs.Object) {
 		c.checkSum(ctx, obj, download, hashes, hashType)
@@ -458,8 +557,9 @@ func CheckSum(ctx context.Context, fsrc, fsum fs.Fs, sumFile string, hashType ha
 			lastErr = err
 		}
 		c.dstFilesMissing.Add(1)
-		c.reportFilename(filename, opt.MissingOnDst, '+')
+		c.reportSum(opt.MissingOnDst, '+', filename, nil, hashType, hash, "", nil)
 	}
+	lastErr = c.closeReport(lastErr)
 
 	return c.reportResults(ctx, lastErr)
 }
@@ -486,7 +586,7 @@ func (c *checkMarch) checkSum(ctx context.Context, obj fs.Object, download bool,
 		fs.Errorf(obj, "%v", err)
 		c.differences.Add(1)
 		c.srcFilesMissing.Add(1)
-		c.report(obj, c.opt.MissingOnSrc, '-')
+		c.reportSum(c.opt.MissingOnSrc, '-', obj.String(), obj, hashType, "", "", nil)
 		return
 	}
 
@@ -533,22 +633,22 @@ func (c *checkMarch) matchSum(ctx context.Context, sumHash, objHash string, obj
 	case err != nil:
 		_ = fs.CountError(ctx, err)
 		fs.Errorf(obj, "Failed to calculate hash: %v", err)
-		c.report(obj, c.opt.Error, '!')
+		c.reportSum(c.opt.Error, '!', obj.String(), obj, hashType, sumHash, objHash, err)
 	case sumHash == "":
 		err = errors.New("duplicate file")
 		_ = fs.CountError(ctx, err)
 		fs.Errorf(obj, "%v", err)
-		c.report(obj, c.opt.Error, '!')
+		c.reportSum(c.opt.Error, '!', obj.String(), obj, hashType, sumHash, objHash, err)
 	case objHash == "":
 		fs.Debugf(nil, "%v = %s (sum)", hashType, sumHash)
 		fs.Debugf(obj, "%v - could not check hash (%v)", hashType, c.opt.Fdst)
 		c.noHashes.Add(1)
 		c.matches.Add(1)
-		c.report(obj, c.opt.Match, '=')
+		c.reportSum(c.opt.Match, '=', obj.String(), obj, hashType, sumHash, objHash, nil)
 	case objHash == sumHash:
 		fs.Debugf(obj, "%v = %s OK", hashType, sumHash)
 		c.matches.Add(1)
-		c.report(obj, c.opt.Match, '=')
+		c.reportSum(c.opt.Match, '=', obj.String(), obj, hashType, sumHash, objHash, nil)
 	default:
 		err = errors.New("files differ")
 		_ = fs.CountError(ctx, err)
@@ -556,7 +656,7 @@ func (c *checkMarch) matchSum(ctx context.Context, sumHash, objHash string, obj
 		fs.Debugf(obj, "%v = %s (%v)", hashType, objHash, c.opt.Fdst)
 		fs.Errorf(obj, "%v", err)
 		c.differences.Add(1)
-		c.report(obj, c.opt.Differ, '*')
+		c.reportSum(c.opt.Differ, '*', obj.String(), obj, hashType, sumHash, objHash, nil)
 	}
 }
 
@@ -3,6 +3,8 @@ package operations_test
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -215,6 +217,97 @@ func TestCheckSizeOnly(t *testing.T) {
 	TestCheck(t)
 }
 
+func TestCheckReport(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+
+	file1 := r.WriteBoth(ctx, "file1", "file1 contents", t1)
+	file2 := r.WriteFile("file2", "file2 contents", t2)
+	file3 := r.WriteObject(ctx, "file3", "file3 contents", t3)
+	file4a := r.WriteFile("file4", "file4 contents", t3)
+	file4b := r.WriteObject(ctx, "file4", "file4 CONTENTS", t3)
+	r.CheckLocalItems(t, file1, file2, file4a)
+	r.CheckRemoteItems(t, file1, file3, file4b)
+
+	hashType := r.Flocal.Hashes().Overlap(r.Fremote.Hashes()).GetOne()
+
+	doCheck := func(t *testing.T, format string) []byte {
+		var buf bytes.Buffer
+		opt := operations.CheckOpt{
+			Fdst:         r.Fremote,
+			Fsrc:         r.Flocal,
+			Report:       &buf,
+			ReportFormat: format,
+		}
+		accounting.GlobalStats().ResetCounters()
+		err := operations.Check(ctx, &opt)
+		assert.Error(t, err)
+		return buf.Bytes()
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		var entries []operations.CheckReportEntry
+		require.NoError(t, json.Unmarshal(doCheck(t, operations.CheckReportJSON), &entries))
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+		require.Len(t, entries, 4)
+
+		assert.Equal(t, "file1", entries[0].Path)
+		assert.Equal(t, operations.CheckStatusMatch, entries[0].Status)
+		assert.Equal(t, file1.Size, entries[0].SrcSize)
+		assert.Equal(t, file1.Size, entries[0].DstSize)
+
+		assert.Equal(t, "file2", entries[1].Path)
+		assert.Equal(t, operations.CheckStatusMissingOnDst, entries[1].Status)
+		assert.Equal(t, file2.Size, entries[1].SrcSize)
+		assert.Equal(t, int64(-1), entries[1].DstSize)
+		assert.Equal(t, "", entries[1].HashType)
+
+		assert.Equal(t, "file3", entries[2].Path)
+		assert.Equal(t, operations.CheckStatusMissingOnSrc, entries[2].Status)
+		assert.Equal(t, int64(-1), entries[2].SrcSize)
+		assert.Equal(t, file3.Size, entries[2].DstSize)
+
+		assert.Equal(t, "file4", entries[3].Path)
+		assert.Equal(t, operations.CheckStatusDiffer, entries[3].Status)
+		assert.Equal(t, file4a.Size, entries[3].SrcSize)
+		assert.Equal(t, file4b.Size, entries[3].DstSize)
+
+		if hashType != hash.None {
+			assert.Equal(t, hashType.String(), entries[0].HashType)
+			assert.NotEqual(t, "", entries[0].SrcHash)
+			assert.Equal(t, entries[0].SrcHash, entries[0].DstHash)
+			assert.Equal(t, hashType.String(), entries[3].HashType)
+			assert.NotEqual(t, "", entries[3].SrcHash)
+			assert.NotEqual(t, "", entries[3].DstHash)
+			assert.NotEqual(t, entries[3].SrcHash, entries[3].DstHash)
+		}
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		records, err := csv.NewReader(bytes.NewReader(doCheck(t, operations.CheckReportCSV))).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 5)
+		assert.Equal(t, []string{"path", "status", "src_size", "dst_size", "hash_type", "src_hash", "dst_hash", "error"}, records[0])
+		records = records[1:]
+		sort.Slice(records, func(i, j int) bool { return records[i][0] < records[j][0] })
+		assert.Equal(t, []string{"file1", "match", "14", "14"}, records[0][:4])
+		assert.Equal(t, []string{"file2", "missing_on_dst", "14", "-1", "", "", "", ""}, records[1])
+		assert.Equal(t, []string{"file3", "missing_on_src", "-1", "14", "", "", "", ""}, records[2])
+		assert.Equal(t, []string{"file4", "differ", "14", "14"}, records[3][:4])
+	})
+
+	t.Run("BadFormat", func(t *testing.T) {
+		opt := operations.CheckOpt{
+			Fdst:         r.Fremote,
+			Fsrc:         r.Flocal,
+			Report:       io.Discard,
+			ReportFormat: "xml",
+		}
+		err := operations.Check(ctx, &opt)
+		assert.ErrorContains(t, err, "unknown check report format")
+	})
+}
+
 func TestCheckEqualReaders(t *testing.T) {
 	b65a := make([]byte, 65*1024)
 	b65b := make([]byte, 65*1024)
@@ -0,0 +1,141 @@
+package operations
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Check report formats for CheckOpt.ReportFormat
+const (
+	CheckReportJSON = "json"
+	CheckReportCSV  = "csv"
+)
+
+// Check report statuses for CheckReportEntry.Status
+const (
+	CheckStatusMatch        = "match"
+	CheckStatusDiffer       = "differ"
+	CheckStatusMissingOnSrc = "missing_on_src"
+	CheckStatusMissingOnDst = "missing_on_dst"
+	CheckStatusError        = "error"
+)
+
+// CheckReportEntry is one file in the report made by the Check
+// functions when CheckOpt.Report is set
+type CheckReportEntry struct {
+	Path     string // path of the file
+	Status   string // one of the CheckStatus constants
+	SrcSize  int64  // size in the source or -1 if missing or unknown
+	DstSize  int64  // size in the destination or -1 if missing or unknown
+	HashType string `json:",omitempty"` // hash type compared if any
+	SrcHash  string `json:",omitempty"` // hash in the source if compared
+	DstHash  string `json:",omitempty"` // hash in the destination if compared
+	Error    string `json:",omitempty"` // the reason for an error status
+}
+
+// checkStatuses maps the sigils used in the combined report to statuses
+var checkStatuses = map[rune]string{
+	'=': CheckStatusMatch,
+	'*': CheckStatusDiffer,
+	'-': CheckStatusMissingOnSrc,
+	'+': CheckStatusMissingOnDst,
+	'!': CheckStatusError,
+}
+
+// checkReportCSVHeader is the first line of a CSV report
+var checkReportCSVHeader = []string{"path", "status", "src_size", "dst_size", "hash_type", "src_hash", "dst_hash", "error"}
+
+// checkReporter writes CheckReportEntry to a report in a given format
+type checkReporter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	format  string
+	csv     *csv.Writer
+	entries int
+	err     error
+}
+
+// newCheckReporter makes a checkReporter writing to out in format
+func newCheckReporter(out io.Writer, format string) (*checkReporter, error) {
+	r := &checkReporter{
+		out:    out,
+		format: format,
+	}
+	switch format {
+	case "", CheckReportJSON:
+		r.format = CheckReportJSON
+		r.writeString("[\n")
+	case CheckReportCSV:
+		r.csv = csv.NewWriter(out)
+		r.err = r.csv.Write(checkReportCSVHeader)
+	default:
+		return nil, fmt.Errorf("unknown check report format %q - use %q or %q", format, CheckReportJSON, CheckReportCSV)
+	}
+	return r, nil
+}
+
+// writeString writes s to the output remembering the first error
+func (r *checkReporter) writeString(s string) {
+	if r.err == nil {
+		_, r.err = io.WriteString(r.out, s)
+	}
+}
+
+// write the entry to the report
+func (r *checkReporter) write(entry *CheckReportEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	switch r.format {
+	case CheckReportJSON:
+		data, err := json.Marshal(entry)
+		if err != nil {
+			r.err = err
+			return
+		}
+		if r.entries > 0 {
+			r.writeString(",\n")
+		}
+		r.writeString(string(data))
+	case CheckReportCSV:
+		r.err = r.csv.Write([]string{
+			entry.Path,
+			entry.Status,
+			strconv.FormatInt(entry.SrcSize, 10),
+			strconv.FormatInt(entry.DstSize, 10),
+			entry.HashType,
+			entry.SrcHash,
+			entry.DstHash,
+			entry.Error,
+		})
+	}
+	r.entries++
+}
+
+// close finishes the report and returns the first error writing it
+func (r *checkReporter) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch r.format {
+	case CheckReportJSON:
+		if r.entries > 0 {
+			r.writeString("\n")
+		}
+		r.writeString("]\n")
+	case CheckReportCSV:
+		r.csv.Flush()
+		if r.err == nil {
+			r.err = r.csv.Error()
+		}
+	}
+	if r.err != nil {
+		return fmt.Errorf("failed to write check report: %w", r.err)
+	}
+	return nil
+}
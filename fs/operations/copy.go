@@ -253,6 +253,10 @@ func (c *copy) manualCopy(ctx context.Context) (actionTaken string, newDst fs.Ob
 		downloadOptions = append(downloadOptions, option)
 	}
 
+	if updater := c.changedBlocksUpdater(); updater != nil {
+		return c.changedBlocksCopy(ctx, updater, downloadOptions)
+	}
+
 	if doMultiThreadCopy(ctx, c.f, c.src) {
 		return c.multiThreadCopy(ctx, uploadOptions)
 	}
@@ -274,14 +278,16 @@ func (c *copy) manualCopy(ctx context.Context) (actionTaken string, newDst fs.Ob
 func (c *copy) verify(ctx context.Context, newDst fs.Object) (err error) {
 	// Verify sizes are the same after transfer
 	if sizeDiffers(ctx, c.src, newDst) {
-		return fmt.Errorf("corrupted on transfer: sizes differ src(%s) %d vs dst(%s) %d", c.src.Fs(), c.src.Size(), newDst.Fs(), newDst.Size())
+		err = fmt.Errorf("corrupted on transfer: sizes differ src(%s) %d vs dst(%s) %d", c.src.Fs(), c.src.Size(), newDst.Fs(), newDst.Size())
+		return fserrors.CategoryError(err, fserrors.CategoryIntegrity)
 	}
 	// Verify hashes are the same after transfer - ignoring blank hashes
 	if c.hashType != hash.None {
 		// checkHashes has logs and counts errors
 		equal, _, srcSum, dstSum, _ := checkHashes(ctx, c.src, newDst, c.hashType)
 		if !equal {
-			return fmt.Errorf("corrupted on transfer: %v hashes differ src(%s) %q vs dst(%s) %q", c.hashType, c.src.Fs(), srcSum, newDst.Fs(), dstSum)
+			err = fmt.Errorf("corrupted on transfer: %v hashes differ src(%s) %q vs dst(%s) %q", c.hashType, c.src.Fs(), srcSum, newDst.Fs(), dstSum)
+			return fserrors.CategoryError(err, fserrors.CategoryIntegrity)
 		}
 	}
 	return nil
@@ -408,6 +414,10 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 	if err != nil {
 		return nil, err
 	}
+	// Writing the changed blocks always updates the destination in place
+	if c.changedBlocksUpdater() != nil {
+		c.remoteForCopy, c.inplace = c.remote, true
+	}
 	// Do the copy now everything is set up
 	return c.copy(ctx)
 }
@@ -394,6 +394,36 @@ func TestCopyInplace(t *testing.T) {
 	r.CheckRemoteItems(t, file2)
 }
 
+func TestCopyWriteChangedBlocks(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+
+	ci.WriteChangedBlocks = true
+
+	contents := strings.Repeat("0123456789", 100000)
+	file2 := r.WriteObject(ctx, "file1", contents, t1)
+	r.CheckRemoteItems(t, file2)
+	obj, err := r.Fremote.NewObject(ctx, file2.Path)
+	require.NoError(t, err)
+	if _, ok := obj.(fs.OpenPartialUpdater); !ok {
+		t.Skip("Writing changed blocks not supported")
+	}
+
+	for _, newContents := range []string{
+		contents[:500000] + "changed" + contents[500007:],
+		contents + "longer",
+		contents[:123456],
+	} {
+		file1 := r.WriteFile("file1", newContents, t2)
+
+		err := operations.CopyFile(ctx, r.Fremote, r.Flocal, file1.Path, file1.Path)
+		require.NoError(t, err)
+		r.CheckLocalItems(t, file1)
+		r.CheckRemoteItems(t, file1)
+	}
+}
+
 func TestCopyLongFileName(t *testing.T) {
 	ctx := context.Background()
 	ctx, ci := fs.AddConfig(ctx)
@@ -6,10 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/lib/atexit"
 	"github.com/rclone/rclone/lib/multipart"
 	"golang.org/x/sync/errgroup"
@@ -40,7 +43,13 @@ func doMultiThreadCopy(ctx context.Context, f fs.Fs, src fs.Object) bool {
 	}
 	// ...destination doesn't support it
 	dstFeatures := f.Features()
-	if dstFeatures.OpenChunkWriter == nil && dstFeatures.OpenWriterAt == nil {
+	needsTempFile := dstFeatures.OpenChunkWriter == nil && dstFeatures.OpenWriterAt == nil
+	if needsTempFile && !ci.MultiThreadTempFile {
+		return false
+	}
+	// ...the source is local so there is nothing to gain from
+	// downloading it into a temporary file
+	if needsTempFile && src.Fs().Features().IsLocal {
 		return false
 	}
 	// ...if --multi-thread-streams not in use and source and
@@ -62,6 +71,12 @@ type multiThreadCopyState struct {
 	noBuffering bool // set to read the input without buffering
 }
 
+// chunkSize returns the size of chunk
+func (mc *multiThreadCopyState) chunkSize(chunk int) int64 {
+	start := int64(chunk) * mc.partSize
+	return max(0, min(start+mc.partSize, mc.size)-start)
+}
+
 // Copy a single chunk into place
 func (mc *multiThreadCopyState) copyChunk(ctx context.Context, chunk int, writer fs.ChunkWriter) (err error) {
 	defer func() {
@@ -73,8 +88,8 @@ func (mc *multiThreadCopyState) copyChunk(ctx context.Context, chunk int, writer
 	if start >= mc.size {
 		return nil
 	}
-	end := min(start+mc.partSize, mc.size)
-	size := end - start
+	size := mc.chunkSize(chunk)
+	end := start + size
 
 	fs.Debugf(mc.src, "multi-thread copy: chunk %d/%d (%d-%d) size %v starting", chunk+1, mc.numChunks, start, end, fs.SizeSuffix(size))
 
@@ -113,6 +128,24 @@ func (mc *multiThreadCopyState) copyChunk(ctx context.Context, chunk int, writer
 	return nil
 }
 
+// Copy a single chunk into place retrying it if it fails with an
+// error which can be retried.
+//
+// Note that the bytes read by failed tries are accounted again.
+func (mc *multiThreadCopyState) copyChunkWithRetries(ctx context.Context, chunk int, writer fs.ChunkWriter) (err error) {
+	ci := fs.GetConfig(ctx)
+	for tries := 1; ; tries++ {
+		err = mc.copyChunk(ctx, chunk, writer)
+		if err == nil || ctx.Err() != nil || tries >= ci.LowLevelRetries {
+			return err
+		}
+		if !fserrors.ShouldRetry(err) && !fserrors.IsRetryError(err) {
+			return err
+		}
+		fs.Debugf(mc.src, "multi-thread copy: chunk %d/%d: low level retry %d/%d", chunk+1, mc.numChunks, tries, ci.LowLevelRetries)
+	}
+}
+
 // Given a file size and a chunkSize
 // it returns the number of chunks, so that chunkSize * numChunks >= size
 func calculateNumChunks(size int64, chunkSize int64) int {
@@ -126,6 +159,13 @@ func calculateNumChunks(size int64, chunkSize int64) int {
 // Copy src to (f, remote) using streams download threads. It tries to use the OpenChunkWriter feature
 // and if that's not available it creates an adapter using OpenWriterAt
 func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object, concurrency int, tr *accounting.Transfer, options ...fs.OpenOption) (newDst fs.Object, err error) {
+	if src.Size() < 0 {
+		return nil, fmt.Errorf("multi-thread copy: can't copy unknown sized file")
+	}
+	if src.Size() == 0 {
+		return nil, fmt.Errorf("multi-thread copy: can't copy zero sized file")
+	}
+
 	openChunkWriter := f.Features().OpenChunkWriter
 	ci := fs.GetConfig(ctx)
 	noBuffering := false
@@ -133,6 +173,9 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 	if openChunkWriter == nil {
 		openWriterAt := f.Features().OpenWriterAt
 		if openWriterAt == nil {
+			if ci.MultiThreadTempFile {
+				return multiThreadCopyTempFile(ctx, f, remote, src, concurrency, tr, options...)
+			}
 			return nil, errors.New("multi-thread copy: neither OpenChunkWriter nor OpenWriterAt supported")
 		}
 		openChunkWriter = openChunkWriterFromOpenWriterAt(openWriterAt, int64(ci.MultiThreadChunkSize), int64(ci.MultiThreadWriteBufferSize), f)
@@ -151,13 +194,6 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 		noBuffering = true
 	}
 
-	if src.Size() < 0 {
-		return nil, fmt.Errorf("multi-thread copy: can't copy unknown sized file")
-	}
-	if src.Size() == 0 {
-		return nil, fmt.Errorf("multi-thread copy: can't copy zero sized file")
-	}
-
 	info, chunkWriter, err := openChunkWriter(ctx, remote, src, options...)
 	if err != nil {
 		return nil, fmt.Errorf("multi-thread copy: failed to open chunk writer: %w", err)
@@ -178,55 +214,7 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 		}
 	})()
 
-	if info.ChunkSize > src.Size() {
-		fs.Debugf(src, "multi-thread copy: chunk size %v was bigger than source file size %v", fs.SizeSuffix(info.ChunkSize), fs.SizeSuffix(src.Size()))
-		info.ChunkSize = src.Size()
-	}
-
-	// Use the backend concurrency if it is higher than --multi-thread-streams or if --multi-thread-streams wasn't set explicitly
-	if !ci.MultiThreadSet || info.Concurrency > concurrency {
-		fs.Debugf(src, "multi-thread copy: using backend concurrency of %d instead of --multi-thread-streams %d", info.Concurrency, concurrency)
-		concurrency = info.Concurrency
-	}
-
-	numChunks := calculateNumChunks(src.Size(), info.ChunkSize)
-	if concurrency > numChunks {
-		fs.Debugf(src, "multi-thread copy: number of streams %d was bigger than number of chunks %d", concurrency, numChunks)
-		concurrency = numChunks
-	}
-
-	if concurrency < 1 {
-		concurrency = 1
-	}
-
-	g, gCtx := errgroup.WithContext(uploadCtx)
-	g.SetLimit(concurrency)
-
-	mc := &multiThreadCopyState{
-		ctx:         gCtx,
-		size:        src.Size(),
-		src:         src,
-		partSize:    info.ChunkSize,
-		numChunks:   numChunks,
-		noBuffering: noBuffering,
-	}
-
-	// Make accounting
-	mc.acc = tr.Account(gCtx, nil)
-
-	fs.Debugf(src, "Starting multi-thread copy with %d chunks of size %v with %v parallel streams", mc.numChunks, fs.SizeSuffix(mc.partSize), concurrency)
-	for chunk := range mc.numChunks {
-		// Fail fast, in case an errgroup managed function returns an error
-		if gCtx.Err() != nil {
-			break
-		}
-		chunk := chunk
-		g.Go(func() error {
-			return mc.copyChunk(gCtx, chunk, chunkWriter)
-		})
-	}
-
-	err = g.Wait()
+	mc, err := multiThreadCopyChunks(uploadCtx, src, concurrency, tr, info, chunkWriter, noBuffering)
 	if err != nil {
 		return nil, err
 	}
@@ -277,6 +265,74 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 	return obj, nil
 }
 
+// Copy the chunks of src to chunkWriter using concurrency streams
+func multiThreadCopyChunks(ctx context.Context, src fs.Object, concurrency int, tr *accounting.Transfer, info fs.ChunkWriterInfo, chunkWriter fs.ChunkWriter, noBuffering bool) (*multiThreadCopyState, error) {
+	ci := fs.GetConfig(ctx)
+	if info.ChunkSize > src.Size() {
+		fs.Debugf(src, "multi-thread copy: chunk size %v was bigger than source file size %v", fs.SizeSuffix(info.ChunkSize), fs.SizeSuffix(src.Size()))
+		info.ChunkSize = src.Size()
+	}
+
+	// Use the backend concurrency if it is higher than --multi-thread-streams or if --multi-thread-streams wasn't set explicitly
+	if !ci.MultiThreadSet || info.Concurrency > concurrency {
+		fs.Debugf(src, "multi-thread copy: using backend concurrency of %d instead of --multi-thread-streams %d", info.Concurrency, concurrency)
+		concurrency = info.Concurrency
+	}
+
+	numChunks := calculateNumChunks(src.Size(), info.ChunkSize)
+	if concurrency > numChunks {
+		fs.Debugf(src, "multi-thread copy: number of streams %d was bigger than number of chunks %d", concurrency, numChunks)
+		concurrency = numChunks
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// Adapt the number of streams up to --multi-thread-streams-max if set
+	maxStreams := min(ci.MultiThreadStreamsMax, numChunks)
+	limiter := newStreamLimiter(src, concurrency, maxStreams)
+
+	g, gCtx := errgroup.WithContext(ctx)
+
+	mc := &multiThreadCopyState{
+		ctx:         gCtx,
+		size:        src.Size(),
+		src:         src,
+		partSize:    info.ChunkSize,
+		numChunks:   numChunks,
+		noBuffering: noBuffering,
+	}
+
+	// Make accounting
+	mc.acc = tr.Account(gCtx, nil)
+
+	if maxStreams > concurrency {
+		fs.Debugf(src, "Starting multi-thread copy with %d chunks of size %v with %v parallel streams adapting up to %d", mc.numChunks, fs.SizeSuffix(mc.partSize), concurrency, maxStreams)
+	} else {
+		fs.Debugf(src, "Starting multi-thread copy with %d chunks of size %v with %v parallel streams", mc.numChunks, fs.SizeSuffix(mc.partSize), concurrency)
+	}
+	for chunk := range mc.numChunks {
+		limiter.acquire()
+		// Fail fast, in case an errgroup managed function returns an error
+		if gCtx.Err() != nil {
+			limiter.release(0)
+			break
+		}
+		g.Go(func() error {
+			err := mc.copyChunkWithRetries(gCtx, chunk, chunkWriter)
+			var n int64
+			if err == nil {
+				n = mc.chunkSize(chunk)
+			}
+			limiter.release(n)
+			return err
+		})
+	}
+
+	return mc, g.Wait()
+}
+
 // writerAtChunkWriter converts a WriterAtCloser into a ChunkWriter
 type writerAtChunkWriter struct {
 	remote          string
@@ -372,3 +428,111 @@ func openChunkWriterFromOpenWriterAt(openWriterAt fs.OpenWriterAtFn, chunkSize i
 		return info, chunkWriter, nil
 	}
 }
+
+// writerAtNopCloser is an io.WriterAt with a Close method which does nothing
+type writerAtNopCloser struct {
+	io.WriterAt
+}
+
+// Close does nothing
+func (writerAtNopCloser) Close() error {
+	return nil
+}
+
+// Copy src to (f, remote) using streams download threads when f can't
+// write in parallel.
+//
+// The chunks are downloaded into a temporary file which is checked
+// against the hash of src before being uploaded with Put.
+func multiThreadCopyTempFile(ctx context.Context, f fs.Fs, remote string, src fs.Object, concurrency int, tr *accounting.Transfer, options ...fs.OpenOption) (newDst fs.Object, err error) {
+	ci := fs.GetConfig(ctx)
+	tmp, err := os.CreateTemp("", "rclone-multi-thread-")
+	if err != nil {
+		return nil, fmt.Errorf("multi-thread copy: failed to create temporary file: %w", err)
+	}
+	removeTmp := func() {
+		_ = tmp.Close()
+		removeErr := os.Remove(tmp.Name())
+		if removeErr != nil {
+			fs.Debugf(src, "multi-thread copy: failed to remove temporary file: %v", removeErr)
+		}
+	}
+	handle := atexit.Register(removeTmp)
+	defer func() {
+		atexit.Unregister(handle)
+		removeTmp()
+	}()
+	err = tmp.Truncate(src.Size())
+	if err != nil {
+		return nil, fmt.Errorf("multi-thread copy: failed to size temporary file: %w", err)
+	}
+
+	chunkSize := int64(ci.MultiThreadChunkSize)
+	chunkWriter := &writerAtChunkWriter{
+		remote:          tmp.Name(),
+		size:            src.Size(),
+		chunkSize:       chunkSize,
+		chunks:          calculateNumChunks(src.Size(), chunkSize),
+		writerAt:        writerAtNopCloser{tmp},
+		writeBufferSize: int64(ci.MultiThreadWriteBufferSize),
+	}
+	info := fs.ChunkWriterInfo{
+		ChunkSize:   chunkSize,
+		Concurrency: ci.MultiThreadStreams,
+	}
+	fs.Debugf(src, "multi-thread copy: downloading into temporary file %q", tmp.Name())
+	mc, err := multiThreadCopyChunks(ctx, src, concurrency, tr, info, chunkWriter, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkMultiThreadTempFile(ctx, src, tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tmp.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("multi-thread copy: failed to rewind temporary file: %w", err)
+	}
+	var wrappedSrc fs.ObjectInfo = src
+	if src.Remote() != remote {
+		wrappedSrc = fs.NewOverrideRemote(src, remote)
+	}
+	newDst, err = f.Put(ctx, tmp, wrappedSrc, options...)
+	if err != nil {
+		return nil, fmt.Errorf("multi-thread copy: failed to upload temporary file: %w", err)
+	}
+
+	fs.Debugf(src, "Finished multi-thread copy with %d parts of size %v using a temporary file", mc.numChunks, fs.SizeSuffix(mc.partSize))
+	return newDst, nil
+}
+
+// Check the temporary file has the same hash as src so a corrupted
+// download is retried before it is uploaded.
+func checkMultiThreadTempFile(ctx context.Context, src fs.Object, tmp *os.File) error {
+	ci := fs.GetConfig(ctx)
+	hashType := src.Fs().Hashes().GetOne()
+	if ci.IgnoreChecksum || hashType == hash.None {
+		return nil
+	}
+	srcSum, err := src.Hash(ctx, hashType)
+	if err != nil || srcSum == "" {
+		fs.Debugf(src, "multi-thread copy: not checking temporary file as source %v hash not available: %v", hashType, err)
+		return nil
+	}
+	_, err = tmp.Seek(0, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to rewind temporary file: %w", err)
+	}
+	sums, err := hash.StreamTypes(tmp, hash.NewHashSet(hashType))
+	if err != nil {
+		return fmt.Errorf("multi-thread copy: failed to read temporary file: %w", err)
+	}
+	if !hash.Equals(srcSum, sums[hashType]) {
+		err = fmt.Errorf("multi-thread copy: corrupted on transfer: %v hashes differ src %q vs temporary file %q", hashType, srcSum, sums[hashType])
+		return fserrors.RetryError(err)
+	}
+	fs.Debugf(src, "multi-thread copy: %v hash of temporary file OK", hashType)
+	return nil
+}
@@ -0,0 +1,118 @@
+package operations
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// If the throughput of each stream is still at least this fraction
+// of what it was before the number of streams was doubled then the
+// link isn't saturated yet so more streams are worth trying.
+const streamThroughputKept = 0.75
+
+// streamLimiter limits the number of streams running in a
+// multi-thread copy.
+//
+// If max is bigger than the starting limit then the limit adapts to
+// the throughput seen. After each round of limit chunks the limit is
+// doubled if the throughput per stream held up when it was last
+// doubled. Once it stops holding up the limit settles on whichever
+// of the last two limits gave the best total throughput.
+type streamLimiter struct {
+	what    any // for logging
+	mu      sync.Mutex
+	cond    *sync.Cond
+	running int // number of streams running
+	limit   int // max number of streams to run at once
+	max     int // max value limit can grow to
+
+	// adaptive state
+	adapting   bool      // set while still trying more streams
+	roundStart time.Time // when this round started
+	roundBytes int64     // bytes transferred this round
+	roundDone  int       // chunks finished this round
+	lastLimit  int       // limit used in the last round
+	lastRate   float64   // total throughput in the last round in bytes/s
+}
+
+// newStreamLimiter makes a streamLimiter starting with limit streams
+// which can adapt up to max streams
+func newStreamLimiter(what any, limit, max int) *streamLimiter {
+	sl := &streamLimiter{
+		what:       what,
+		limit:      limit,
+		max:        max,
+		adapting:   max > limit,
+		roundStart: time.Now(),
+	}
+	sl.cond = sync.NewCond(&sl.mu)
+	return sl
+}
+
+// acquire waits for a stream to be available
+func (sl *streamLimiter) acquire() {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	for sl.running >= sl.limit {
+		sl.cond.Wait()
+	}
+	sl.running++
+}
+
+// release returns a stream which transferred n bytes
+func (sl *streamLimiter) release(n int64) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	sl.running--
+	if sl.adapting {
+		sl.roundBytes += n
+		sl.roundDone++
+		if sl.roundDone >= sl.limit {
+			sl.adapt()
+		}
+	}
+	sl.cond.Broadcast()
+}
+
+// adapt works out the limit for the next round
+//
+// Call with mu held
+func (sl *streamLimiter) adapt() {
+	elapsed := time.Since(sl.roundStart).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	rate := float64(sl.roundBytes) / elapsed
+	fs.Debugf(sl.what, "multi-thread copy: %d streams transferred at %v/s", sl.limit, fs.SizeSuffix(int64(rate)))
+	switch {
+	case sl.lastRate == 0 || rate/float64(sl.limit) >= streamThroughputKept*sl.lastRate/float64(sl.lastLimit):
+		// First round or throughput per stream held up so try more
+		sl.lastLimit, sl.lastRate = sl.limit, rate
+		sl.limit = min(2*sl.limit, sl.max)
+		sl.adapting = sl.limit > sl.lastLimit
+	case rate < sl.lastRate:
+		// More streams made things worse so go back
+		sl.limit = sl.lastLimit
+		sl.adapting = false
+	default:
+		// Keep the current limit
+		sl.adapting = false
+	}
+	if sl.adapting {
+		fs.Debugf(sl.what, "multi-thread copy: trying %d streams", sl.limit)
+	} else {
+		fs.Debugf(sl.what, "multi-thread copy: settled on %d streams", sl.limit)
+	}
+	sl.roundStart = time.Now()
+	sl.roundBytes = 0
+	sl.roundDone = 0
+}
+
+// streams returns the current limit on the number of streams
+func (sl *streamLimiter) streams() int {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	return sl.limit
+}
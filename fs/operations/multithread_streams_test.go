@@ -0,0 +1,89 @@
+package operations
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testRound runs a round of chunks on sl each transferring n bytes
+// pretending the round took a second
+func (sl *streamLimiter) testRound(n int64) {
+	limit := sl.streams()
+	for range limit {
+		sl.acquire()
+	}
+	sl.mu.Lock()
+	sl.roundStart = time.Now().Add(-time.Second)
+	sl.mu.Unlock()
+	for range limit {
+		sl.release(n)
+	}
+}
+
+func TestStreamLimiterGrows(t *testing.T) {
+	sl := newStreamLimiter(nil, 2, 16)
+	sl.testRound(100) // 200 bytes/s
+	assert.Equal(t, 4, sl.streams())
+	sl.testRound(100) // 400 bytes/s, same per stream
+	assert.Equal(t, 8, sl.streams())
+	sl.testRound(60) // 480 bytes/s, but per stream dropped a lot
+	assert.Equal(t, 8, sl.streams())
+	assert.False(t, sl.adapting)
+	sl.testRound(10)
+	assert.Equal(t, 8, sl.streams())
+}
+
+func TestStreamLimiterBacksOff(t *testing.T) {
+	sl := newStreamLimiter(nil, 2, 16)
+	sl.testRound(100) // 200 bytes/s
+	assert.Equal(t, 4, sl.streams())
+	sl.testRound(40) // 160 bytes/s - worse
+	assert.Equal(t, 2, sl.streams())
+	assert.False(t, sl.adapting)
+}
+
+func TestStreamLimiterMax(t *testing.T) {
+	sl := newStreamLimiter(nil, 2, 3)
+	sl.testRound(100)
+	assert.Equal(t, 3, sl.streams())
+	sl.testRound(100)
+	assert.Equal(t, 3, sl.streams())
+	assert.False(t, sl.adapting)
+
+	// Not adapting if max isn't bigger than limit
+	sl = newStreamLimiter(nil, 4, 0)
+	assert.False(t, sl.adapting)
+	sl.testRound(100)
+	assert.Equal(t, 4, sl.streams())
+}
+
+func TestStreamLimiterLimits(t *testing.T) {
+	sl := newStreamLimiter(nil, 2, 0)
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		running    int
+		maxRunning int
+	)
+	for range 20 {
+		sl.acquire()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			sl.release(1)
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, maxRunning, 2)
+}
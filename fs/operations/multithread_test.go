@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/object"
 	"github.com/rclone/rclone/fstest/mockfs"
@@ -35,10 +36,12 @@ func TestDoMultiThreadCopy(t *testing.T) {
 	oldStreams := ci.MultiThreadStreams
 	oldCutoff := ci.MultiThreadCutoff
 	oldIsSet := ci.MultiThreadSet
+	oldTempFile := ci.MultiThreadTempFile
 	defer func() {
 		ci.MultiThreadStreams = oldStreams
 		ci.MultiThreadCutoff = oldCutoff
 		ci.MultiThreadSet = oldIsSet
+		ci.MultiThreadTempFile = oldTempFile
 	}()
 
 	ci.MultiThreadStreams, ci.MultiThreadCutoff = 4, 50
@@ -67,6 +70,12 @@ func TestDoMultiThreadCopy(t *testing.T) {
 
 	f.Features().OpenWriterAt = nil
 	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadTempFile = true
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+	srcFs.Features().IsLocal = true
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	srcFs.Features().IsLocal = false
+	ci.MultiThreadTempFile = false
 	f.Features().OpenWriterAt = nullWriterAt
 	assert.True(t, doMultiThreadCopy(ctx, f, src))
 
@@ -154,17 +163,20 @@ func TestMultithreadCopy(t *testing.T) {
 
 	for _, upload := range []bool{false, true} {
 		for _, test := range []struct {
-			size    int
-			streams int
+			size       int
+			streams    int
+			maxStreams int
 		}{
 			{size: chunkSize*2 - 1, streams: 2},
 			{size: chunkSize * 2, streams: 2},
 			{size: chunkSize*2 + 1, streams: 2},
+			{size: chunkSize*3 + 1, streams: 1, maxStreams: 4},
 		} {
 			checkMetadata = !checkMetadata
 			ci.Metadata = checkMetadata
-			fileName := fmt.Sprintf("test-multithread-copy-%v-%d-%d", upload, test.size, test.streams)
-			t.Run(fmt.Sprintf("upload=%v,size=%v,streams=%v", upload, test.size, test.streams), func(t *testing.T) {
+			ci.MultiThreadStreamsMax = test.maxStreams
+			fileName := fmt.Sprintf("test-multithread-copy-%v-%d-%d-%d", upload, test.size, test.streams, test.maxStreams)
+			t.Run(fmt.Sprintf("upload=%v,size=%v,streams=%v,maxStreams=%v", upload, test.size, test.streams, test.maxStreams), func(t *testing.T) {
 				if *fstest.SizeLimit > 0 && int64(test.size) > *fstest.SizeLimit {
 					t.Skipf("exceeded file size limit %d > %d", test.size, *fstest.SizeLimit)
 				}
@@ -333,3 +345,85 @@ func TestMultithreadCopyAbort(t *testing.T) {
 		require.NoError(t, o.Remove(ctx))
 	}
 }
+
+// flakyObject fails the first read of each chunk after the first with
+// an error which can be retried
+type flakyObject struct {
+	fs.Object
+	mu     sync.Mutex
+	failed map[int64]bool
+	sum    string // if set return this as the hash
+}
+
+func (o *flakyObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	rc, err := o.Object.Open(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	for _, option := range options {
+		if ropt, ok := option.(*fs.RangeOption); ok && ropt.Start > 0 {
+			o.mu.Lock()
+			defer o.mu.Unlock()
+			if !o.failed[ropt.Start] {
+				o.failed[ropt.Start] = true
+				return retryErrorReadCloser{rc}, nil
+			}
+		}
+	}
+	return rc, nil
+}
+
+func (o *flakyObject) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	if o.sum != "" {
+		return o.sum, nil
+	}
+	return o.Object.Hash(ctx, ht)
+}
+
+type retryErrorReadCloser struct {
+	io.ReadCloser
+}
+
+func (rc retryErrorReadCloser) Read(p []byte) (n int, err error) {
+	return 0, fserrors.RetryErrorf("simulated read failure")
+}
+
+func TestMultithreadCopyTempFile(t *testing.T) {
+	r := fstest.NewRun(t)
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadChunkSize = 1024
+	ci.MultiThreadTempFile = true
+
+	const fileName = "test-multithread-temp-file"
+	t1 := fstest.Time("2001-02-03T04:05:06.499999999Z")
+	file1 := r.WriteFile(fileName, random.String(3*1024+1), t1)
+	r.CheckLocalItems(t, file1)
+	local, err := r.Flocal.NewObject(ctx, fileName)
+	require.NoError(t, err)
+
+	t.Run("BadHash", func(t *testing.T) {
+		if r.Flocal.Hashes().GetOne() == hash.None {
+			t.Skip("no hashes")
+		}
+		src := &flakyObject{Object: local, failed: map[int64]bool{}, sum: "0123456789abcdef0123456789abcdef01234567"}
+		tr := accounting.GlobalStats().NewTransfer(src, nil)
+		defer tr.Done(ctx, nil)
+		_, err := multiThreadCopyTempFile(ctx, r.Fremote, fileName, src, 2, tr)
+		require.Error(t, err)
+		assert.True(t, fserrors.IsRetryError(err))
+		assert.Contains(t, err.Error(), "corrupted on transfer")
+		r.CheckRemoteItems(t)
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		src := &flakyObject{Object: local, failed: map[int64]bool{}}
+		tr := accounting.GlobalStats().NewTransfer(src, nil)
+		defer tr.Done(ctx, nil)
+		dst, err := multiThreadCopyTempFile(ctx, r.Fremote, fileName, src, 2, tr)
+		require.NoError(t, err)
+		assert.Equal(t, fileName, dst.Remote())
+		assert.Len(t, src.failed, 3)
+		r.CheckRemoteItems(t, file1)
+	})
+}
@@ -1473,7 +1473,7 @@ func rcatSrc(ctx context.Context, fdst fs.Fs, dstFileName string, in io.ReadClos
 	}
 	src := object.NewStaticObjectInfo(dstFileName, modTime, int64(readCounter.BytesRead()), false, sums, fdst).WithMetadata(meta)
 	if !equal(ctx, src, dst, opt) {
-		err = fmt.Errorf("corrupted on transfer")
+		err = fserrors.CategoryError(errors.New("corrupted on transfer"), fserrors.CategoryIntegrity)
 		err = fs.CountError(ctx, err)
 		fs.Errorf(dst, "%v", err)
 		return dst, err
@@ -1,7 +1,9 @@
 package operations
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -725,6 +727,7 @@ This takes the following parameters:
 - match - report all matching files (default false)
 - differ - report all non-matching files (default true)
 - error - report all files with errors (hashing or reading) (default true)
+- report - make a machine readable report of all files (default false)
 
 If you supply the download flag, it will download the data from
 both remotes and check them against each other on the fly.  This can
@@ -751,6 +754,16 @@ Returns:
 - match - array of strings of all matching files
 - differ - array of strings of all non-matching files
 - error - array of strings of all files with errors (hashing or reading)
+- report - array of objects, one for each file, as produced by the
+  --report flag of rclone check with these fields
+    - Path - the path of the file
+    - Status - one of match, differ, missing_on_src, missing_on_dst or error
+    - SrcSize - the size in the source or -1 if missing
+    - DstSize - the size in the destination or -1 if missing
+    - HashType - the hash compared, if any
+    - SrcHash - the hash of the source, if compared
+    - DstHash - the hash of the destination, if compared
+    - Error - the reason for the error status, if any
 
 `,
 	})
@@ -854,6 +867,13 @@ func rcCheck(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	opt.Differ = getOutput("differ", true)
 	opt.Error = getOutput("error", true)
 
+	var report *bytes.Buffer
+	if doReport, _ := in.GetBool("report"); doReport {
+		report = new(bytes.Buffer)
+		opt.Report = report
+		opt.ReportFormat = CheckReportJSON
+	}
+
 	if checkFileHash != "" {
 		out["hashType"] = checkFileHashType.String()
 		err = CheckSum(ctx, dstFs, checkFileFs, checkFileRemote, checkFileHashType, opt, download)
@@ -865,6 +885,13 @@ func rcCheck(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 			err = Check(ctx, opt)
 		}
 	}
+	if report != nil {
+		entries := []CheckReportEntry{}
+		if jsonErr := json.Unmarshal(report.Bytes(), &entries); jsonErr != nil {
+			return nil, fmt.Errorf("failed to decode check report: %w", jsonErr)
+		}
+		out["report"] = entries
+	}
 	if err != nil {
 		out["status"] = err.Error()
 		out["success"] = false
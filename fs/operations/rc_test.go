@@ -737,6 +737,31 @@ deadbeefcafe00000000000000000000  subdir/file2
 		})
 	}
 
+	t.Run("Report", func(t *testing.T) {
+		in := rc.Params{
+			"srcFs":  r.LocalName,
+			"dstFs":  r.FremoteName,
+			"report": true,
+		}
+		out, err := call.Fn(ctx, in)
+		require.NoError(t, err)
+		report := out["report"].([]operations.CheckReportEntry)
+		sort.Slice(report, func(i, j int) bool { return report[i].Path < report[j].Path })
+		require.Len(t, report, 4)
+		statuses := map[string]string{}
+		for _, entry := range report {
+			statuses[entry.Path] = entry.Status
+		}
+		assert.Equal(t, map[string]string{
+			file1.Path:  operations.CheckStatusMatch,
+			file2.Path:  operations.CheckStatusMissingOnDst,
+			file3.Path:  operations.CheckStatusMissingOnSrc,
+			file4a.Path: operations.CheckStatusDiffer,
+		}, statuses)
+		assert.Equal(t, "md5", report[0].HashType)
+		assert.Equal(t, report[0].SrcHash, report[0].DstHash)
+	})
+
 	t.Run("CheckFile", func(t *testing.T) {
 		// The checksum file is treated as the source and srcFs is not used
 		in := rc.Params{
@@ -0,0 +1,153 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fspath"
+	"golang.org/x/sync/errgroup"
+)
+
+// SnapshotFormat is the time format used for the names of the
+// snapshots made with --snapshot-dir
+const SnapshotFormat = "2006-01-02-150405"
+
+// SnapshotDir sets up --snapshot-dir
+func SnapshotDir(ctx context.Context, fdst fs.Fs, fsrc fs.Fs) (snapshotDir fs.Fs, err error) {
+	ci := fs.GetConfig(ctx)
+	snapshotDir, err = cache.Get(ctx, ci.SnapshotDir)
+	if err != nil {
+		return nil, fserrors.FatalError(fmt.Errorf("failed to make fs for --snapshot-dir %q: %w", ci.SnapshotDir, err))
+	}
+	if !SameConfig(fdst, snapshotDir) {
+		return nil, fserrors.FatalError(errors.New("parameter to --snapshot-dir has to be on the same remote as destination"))
+	}
+	if OverlappingFilterCheck(ctx, snapshotDir, fdst) {
+		return nil, fserrors.FatalError(errors.New("destination and parameter to --snapshot-dir mustn't overlap"))
+	}
+	if OverlappingFilterCheck(ctx, snapshotDir, fsrc) {
+		return nil, fserrors.FatalError(errors.New("source and parameter to --snapshot-dir mustn't overlap"))
+	}
+	return snapshotDir, nil
+}
+
+// lastSnapshot returns the name of the newest snapshot in snapshotDir
+// or "" if there isn't one
+func lastSnapshot(ctx context.Context, snapshotDir fs.Fs) (name string, err error) {
+	entries, err := snapshotDir.List(ctx, "")
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	entries.ForDir(func(dir fs.Directory) {
+		remote := dir.Remote()
+		if _, err := time.Parse(SnapshotFormat, remote); err == nil && remote > name {
+			name = remote
+		}
+	})
+	return name, nil
+}
+
+// Snapshot makes a new snapshot of fdst in snapshotDir named with the
+// current time in SnapshotFormat.
+//
+// Files which are unchanged since the last snapshot are hard linked
+// from it if the backend supports it, or server-side copied if not.
+// Other files are copied from fdst.
+func Snapshot(ctx context.Context, snapshotDir fs.Fs, fdst fs.Fs) error {
+	ci := fs.GetConfig(ctx)
+	name := time.Now().UTC().Format(SnapshotFormat)
+	if SkipDestructive(ctx, name, "make snapshot") {
+		return nil
+	}
+	last, err := lastSnapshot(ctx, snapshotDir)
+	if err != nil {
+		return fmt.Errorf("failed to find last snapshot: %w", err)
+	}
+	if last >= name {
+		return fmt.Errorf("snapshot %q already exists", last)
+	}
+	fsnap, err := cache.Get(ctx, fspath.JoinRootPath(ci.SnapshotDir, name))
+	if err != nil {
+		return fmt.Errorf("failed to make fs for snapshot %q: %w", name, err)
+	}
+	var flast fs.Fs
+	if last != "" {
+		flast, err = cache.Get(ctx, fspath.JoinRootPath(ci.SnapshotDir, last))
+		if err != nil {
+			return fmt.Errorf("failed to make fs for snapshot %q: %w", last, err)
+		}
+		fs.Debugf(fsnap, "Making snapshot from %q", last)
+	}
+	err = fsnap.Mkdir(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to make snapshot directory: %w", err)
+	}
+
+	var linked, copied atomic.Int64
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(ci.Transfers)
+	err = ListFn(ctx, fdst, func(o fs.Object) {
+		g.Go(func() error {
+			isLinked, err := snapshotObject(gCtx, fsnap, flast, o)
+			if err != nil {
+				return fmt.Errorf("failed to snapshot %q: %w", o.Remote(), err)
+			}
+			if isLinked {
+				linked.Add(1)
+			} else {
+				copied.Add(1)
+			}
+			return nil
+		})
+	})
+	gErr := g.Wait()
+	if err == nil {
+		err = gErr
+	}
+	if err != nil {
+		return fs.CountError(ctx, fmt.Errorf("snapshot %q failed: %w", name, err))
+	}
+	fs.Infof(fsnap, "Made snapshot with %d files unchanged and %d files copied", linked.Load(), copied.Load())
+	return nil
+}
+
+// snapshotObject puts o into fsnap, using the copy in flast if it is
+// unchanged.
+//
+// It returns true if the copy in flast was used.
+func snapshotObject(ctx context.Context, fsnap, flast fs.Fs, o fs.Object) (fromLast bool, err error) {
+	remote := o.Remote()
+	if flast != nil {
+		lastObj, err := flast.NewObject(ctx, remote)
+		if err == nil {
+			opt := defaultEqualOpt(ctx)
+			opt.updateModTime = false
+			if equal(ctx, o, lastObj, opt) {
+				if linker, ok := lastObj.(fs.HardLinker); ok {
+					_, err = linker.HardLink(ctx, fsnap, remote)
+					if err == nil {
+						fs.Debugf(o, "Linked from last snapshot")
+						return true, nil
+					}
+					fs.Debugf(o, "Failed to link from last snapshot, copying instead: %v", err)
+				}
+				if fsnap.Features().Copy != nil {
+					_, err = Copy(ctx, fsnap, nil, remote, lastObj)
+					return true, err
+				}
+			}
+		} else if err != fs.ErrorObjectNotFound {
+			return false, err
+		}
+	}
+	_, err = Copy(ctx, fsnap, nil, remote, o)
+	return false, err
+}
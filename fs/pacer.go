@@ -24,6 +24,9 @@ func NewPacer(ctx context.Context, c pacer.Calculator) *Pacer {
 	ci := GetConfig(ctx)
 	retries := max(ci.LowLevelRetries, 1)
 	maxConnections := max(ci.MaxConnections, 0)
+	if ci.AdaptivePacer {
+		c = newAdaptiveCalculator(c)
+	}
 	p := &Pacer{
 		Pacer: pacer.New(
 			pacer.InvokerOption(pacerInvoker),
@@ -36,6 +39,16 @@ func NewPacer(ctx context.Context, c pacer.Calculator) *Pacer {
 	return p
 }
 
+// newAdaptiveCalculator returns an adaptive calculator wrapping c
+// which starts from the same sleep time as c and never sleeps for less
+// than c would, so the limits of the backend are kept.
+func newAdaptiveCalculator(c pacer.Calculator) pacer.Calculator {
+	if c == nil {
+		c = pacer.NewDefault()
+	}
+	return pacer.NewAdaptive(pacer.StartSleep(c.Calculate(pacer.State{})), pacer.Floor{Calculator: c})
+}
+
 func (d *logCalculator) Calculate(state pacer.State) time.Duration {
 	oldSleepTime := state.SleepTime
 	newSleepTime := d.Calculator.Calculate(state)
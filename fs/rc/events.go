@@ -0,0 +1,104 @@
+package rc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event types published with PublishEvent
+const (
+	EventTransferStart  = "transferStart"  // a transfer has started
+	EventTransferFinish = "transferFinish" // a transfer has finished
+	EventError          = "error"          // an error was counted in the stats
+	EventJob            = "job"            // a job changed state
+)
+
+// Event is something which happened which can be streamed to rc
+// clients so they don't have to poll for it
+type Event struct {
+	Type  string    `json:"type"`
+	Time  time.Time `json:"time"`
+	JobID int64     `json:"jobid,omitempty"` // job the event belongs to if known
+	Group string    `json:"group,omitempty"` // stats group the event belongs to if known
+	Data  Params    `json:"data,omitempty"`
+}
+
+// EventSubscription receives events published after it was created
+//
+// Events are dropped if the subscriber doesn't read them fast enough.
+type EventSubscription struct {
+	C       <-chan Event // events are delivered on here
+	c       chan Event
+	dropped atomic.Int64
+}
+
+// Dropped returns the number of events dropped since the subscription
+// was made because the channel was full
+func (sub *EventSubscription) Dropped() int64 {
+	return sub.dropped.Load()
+}
+
+// the event subscribers
+var events struct {
+	mu   sync.RWMutex
+	n    atomic.Int32 // number of subscribers, read without the lock
+	subs map[*EventSubscription]struct{}
+}
+
+// SubscribeEvents returns a subscription to all events which buffers
+// up to size events
+//
+// Call UnsubscribeEvents when finished with it.
+func SubscribeEvents(size int) *EventSubscription {
+	c := make(chan Event, size)
+	sub := &EventSubscription{C: c, c: c}
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	if events.subs == nil {
+		events.subs = make(map[*EventSubscription]struct{})
+	}
+	events.subs[sub] = struct{}{}
+	events.n.Store(int32(len(events.subs)))
+	return sub
+}
+
+// UnsubscribeEvents stops sub receiving events and closes its channel
+func UnsubscribeEvents(sub *EventSubscription) {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	if _, ok := events.subs[sub]; !ok {
+		return
+	}
+	delete(events.subs, sub)
+	events.n.Store(int32(len(events.subs)))
+	close(sub.c)
+}
+
+// EventsWanted returns true if anything is subscribed to events
+//
+// Use this to avoid the cost of making an event nobody will read.
+func EventsWanted() bool {
+	return events.n.Load() > 0
+}
+
+// PublishEvent sends e to all the subscribers without blocking
+//
+// If Time isn't set it will be set to now.
+func PublishEvent(e Event) {
+	if !EventsWanted() {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	events.mu.RLock()
+	defer events.mu.RUnlock()
+	for sub := range events.subs {
+		select {
+		case sub.c <- e:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package rc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents(t *testing.T) {
+	assert.False(t, EventsWanted())
+	PublishEvent(Event{Type: EventJob}) // nobody listening
+
+	sub1 := SubscribeEvents(1)
+	sub2 := SubscribeEvents(10)
+	assert.True(t, EventsWanted())
+
+	PublishEvent(Event{Type: EventJob, JobID: 1})
+	PublishEvent(Event{Type: EventJob, JobID: 2})
+
+	e := <-sub1.C
+	assert.Equal(t, int64(1), e.JobID)
+	assert.False(t, e.Time.IsZero())
+	assert.Equal(t, int64(1), sub1.Dropped())
+	assert.Equal(t, int64(1), (<-sub2.C).JobID)
+	assert.Equal(t, int64(2), (<-sub2.C).JobID)
+	assert.Equal(t, int64(0), sub2.Dropped())
+
+	UnsubscribeEvents(sub1)
+	UnsubscribeEvents(sub1) // twice is OK
+	_, ok := <-sub1.C
+	assert.False(t, ok)
+	assert.True(t, EventsWanted())
+	UnsubscribeEvents(sub2)
+	assert.False(t, EventsWanted())
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/rc"
 )
 
@@ -27,18 +28,23 @@ func init() {
 
 // Job describes an asynchronous task started via the rc package
 type Job struct {
-	mu        sync.Mutex
-	ID        int64     `json:"id"`
-	Group     string    `json:"group"`
-	StartTime time.Time `json:"startTime"`
-	EndTime   time.Time `json:"endTime"`
-	Error     string    `json:"error"`
-	Finished  bool      `json:"finished"`
-	Success   bool      `json:"success"`
-	Duration  float64   `json:"duration"`
-	Output    rc.Params `json:"output"`
-	Stop      func()    `json:"-"`
-	listeners []*func()
+	mu            sync.Mutex
+	ID            int64     `json:"id"`
+	Group         string    `json:"group"`
+	StartTime     time.Time `json:"startTime"`
+	EndTime       time.Time `json:"endTime"`
+	Error         string    `json:"error"`
+	ErrorCategory string    `json:"errorCategory,omitempty"`
+	Finished      bool      `json:"finished"`
+	Success       bool      `json:"success"`
+	Duration      float64   `json:"duration"`
+	Output        rc.Params `json:"output"`
+	After         []int64   `json:"after,omitempty"`
+	Waiting       bool      `json:"waiting,omitempty"`
+	Stop          func()    `json:"-"`
+	listeners     []*func()
+	after         []*Job // jobs which must succeed before this one starts
+	async         bool   // set if the job was started with _async
 
 	// realErr is the Error before printing it as a string, it's used to return
 	// the real error to the upper application layers while still printing the
@@ -58,13 +64,16 @@ func (job *Job) finish(out rc.Params, err error) {
 	if err != nil {
 		job.realErr = err
 		job.Error = err.Error()
+		job.ErrorCategory = string(fserrors.GetCategory(err))
 		job.Success = false
 	} else {
 		job.realErr = nil
 		job.Error = ""
+		job.ErrorCategory = ""
 		job.Success = true
 	}
 	job.Finished = true
+	job.publish()
 
 	// Notify listeners that the job is finished
 	for i := range job.listeners {
@@ -75,6 +84,36 @@ func (job *Job) finish(out rc.Params, err error) {
 	running.kickExpire() // make sure this job gets expired
 }
 
+// publish sends an event with the state of the job to rc subscribers
+//
+// Call with the mutex held
+func (job *Job) publish() {
+	if !rc.EventsWanted() {
+		return
+	}
+	data := rc.Params{}
+	switch {
+	case job.Finished:
+		data["state"] = "finished"
+		data["success"] = job.Success
+		data["duration"] = job.Duration
+		if job.Error != "" {
+			data["error"] = job.Error
+		}
+	case job.Waiting:
+		data["state"] = "waiting"
+		data["after"] = job.After
+	default:
+		data["state"] = "running"
+	}
+	rc.PublishEvent(rc.Event{
+		Type:  rc.EventJob,
+		JobID: job.ID,
+		Group: job.Group,
+		Data:  data,
+	})
+}
+
 func (job *Job) removeListener(fn *func()) {
 	job.mu.Lock()
 	defer job.mu.Unlock()
@@ -99,13 +138,48 @@ func (job *Job) OnFinish(fn func()) func() {
 	return func() { job.removeListener(&fn) }
 }
 
+// waitFor waits for the jobs in after to finish returning an error if
+// any of them failed or ctx was cancelled
+func waitFor(ctx context.Context, after []*Job) error {
+	for _, dep := range after {
+		done := make(chan struct{})
+		cancel := dep.OnFinish(func() { close(done) })
+		select {
+		case <-done:
+		case <-ctx.Done():
+			cancel()
+			return ctx.Err()
+		}
+		dep.mu.Lock()
+		success, depErr := dep.Success, dep.Error
+		dep.mu.Unlock()
+		if !success {
+			return fmt.Errorf("job %d which this job depends on failed: %s", dep.ID, depErr)
+		}
+	}
+	return nil
+}
+
 // run the job until completion writing the return status
+//
+// If the job depends on other jobs it waits for them to succeed first.
 func (job *Job) run(ctx context.Context, fn rc.Func, in rc.Params) {
 	defer func() {
 		if r := recover(); r != nil {
 			job.finish(nil, fmt.Errorf("panic received: %v \n%s", r, string(debug.Stack())))
 		}
 	}()
+	if len(job.after) > 0 {
+		if err := waitFor(ctx, job.after); err != nil {
+			job.finish(nil, err)
+			return
+		}
+		job.mu.Lock()
+		job.Waiting = false
+		job.StartTime = time.Now()
+		job.publish()
+		job.mu.Unlock()
+	}
 	job.finish(fn(ctx, in))
 }
 
@@ -254,6 +328,34 @@ func getFilter(ctx context.Context, in rc.Params) (context.Context, error) {
 	return ctx, nil
 }
 
+// See if _after is set and if so return the jobs which must succeed
+// before this one can start
+func (jobs *Jobs) getAfter(in rc.Params) ([]*Job, error) {
+	if _, ok := in["_after"]; !ok {
+		return nil, nil
+	}
+	var IDs []int64
+	err := in.GetStruct("_after", &IDs)
+	if err != nil {
+		// allow a single job ID too
+		ID, errInt := in.GetInt64("_after")
+		if errInt != nil {
+			return nil, err
+		}
+		IDs = []int64{ID}
+	}
+	delete(in, "_after") // remove the parameter
+	after := make([]*Job, 0, len(IDs))
+	for _, ID := range IDs {
+		job := jobs.Get(ID)
+		if job == nil {
+			return nil, rc.NewErrParamInvalid(fmt.Errorf("_after: job %d not found", ID))
+		}
+		after = append(after, job)
+	}
+	return after, nil
+}
+
 type jobKeyType struct{}
 
 // Key for adding jobs to ctx
@@ -284,6 +386,11 @@ func (jobs *Jobs) NewJob(ctx context.Context, fn rc.Func, in rc.Params) (job *Jo
 		return nil, nil, err
 	}
 
+	after, err := jobs.getAfter(in)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	stop := func() {
 		cancel()
@@ -295,12 +402,22 @@ func (jobs *Jobs) NewJob(ctx context.Context, fn rc.Func, in rc.Params) (job *Jo
 		Group:     group,
 		StartTime: time.Now(),
 		Stop:      stop,
+		after:     after,
+		async:     isAsync,
+	}
+	for _, dep := range after {
+		job.After = append(job.After, dep.ID)
 	}
+	job.Waiting = len(after) > 0
 
 	jobs.mu.Lock()
 	jobs.jobs[job.ID] = job
 	jobs.mu.Unlock()
 
+	job.mu.Lock()
+	job.publish()
+	job.mu.Unlock()
+
 	// Add the job to the context
 	ctx = context.WithValue(ctx, jobKey, job)
 
@@ -323,6 +440,12 @@ func NewJob(ctx context.Context, fn rc.Func, in rc.Params) (job *Job, out rc.Par
 	return running.NewJob(ctx, fn, in)
 }
 
+// Get gets the job with the given ID from the global job queue or nil
+// if it doesn't exist
+func Get(ID int64) *Job {
+	return running.Get(ID)
+}
+
 // OnFinish adds listener to jobid that will be triggered when job is finished.
 // It returns a function to cancel listening.
 func OnFinish(jobID int64, fn func()) (func(), error) {
@@ -339,6 +462,13 @@ func GetJob(ctx context.Context) (job *Job, ok bool) {
 	return job, ok
 }
 
+// IsAsync returns true if ctx is for a job which was started with
+// _async so runs in the background
+func IsAsync(ctx context.Context) bool {
+	job, ok := GetJob(ctx)
+	return ok && job.async
+}
+
 // GetJobID gets the Job from the context if possible
 func GetJobID(ctx context.Context) (jobID int64, ok bool) {
 	job, ok := GetJob(ctx)
@@ -363,10 +493,13 @@ Results:
 - duration - time in seconds that the job ran for
 - endTime - time the job finished (e.g. "2018-10-26T18:50:20.528746884+01:00")
 - error - error from the job or empty string for no error
+- errorCategory - category of the error if known, see the [error categories](/rc/#error-categories)
 - finished - boolean whether the job has finished or not
 - id - as passed in above
 - startTime - time the job started (e.g. "2018-10-26T18:50:20.528336039+01:00")
 - success - boolean - true for success false otherwise
+- after - ids of the jobs this job waits for if set with _after
+- waiting - boolean - true if the job is waiting for the jobs in after
 - output - output of the job as would have been returned if called synchronously
 - progress - output of the progress related to the underlying job
 `,
@@ -3,6 +3,7 @@ package jobs
 import (
 	"context"
 	"errors"
+	"fmt"
 	"runtime"
 	"testing"
 	"time"
@@ -324,6 +325,23 @@ func TestExecuteJobWithGroup(t *testing.T) {
 	assert.Equal(t, true, called)
 }
 
+func TestExecuteJobIsAsync(t *testing.T) {
+	ctx := context.Background()
+	jobs := newJobs()
+	assert.False(t, IsAsync(ctx))
+	isAsync := make(chan bool, 1)
+	jobFn := func(ctx context.Context, in rc.Params) (rc.Params, error) {
+		isAsync <- IsAsync(ctx)
+		return nil, nil
+	}
+	_, _, err := jobs.NewJob(ctx, jobFn, rc.Params{})
+	require.NoError(t, err)
+	assert.False(t, <-isAsync)
+	_, _, err = jobs.NewJob(ctx, jobFn, rc.Params{"_async": true})
+	require.NoError(t, err)
+	assert.True(t, <-isAsync)
+}
+
 func TestExecuteJobErrorPropagation(t *testing.T) {
 	ctx := context.Background()
 	jobID.Store(0)
@@ -336,6 +354,85 @@ func TestExecuteJobErrorPropagation(t *testing.T) {
 	assert.Equal(t, testErr, err)
 }
 
+func TestJobErrorCategory(t *testing.T) {
+	job := &Job{}
+	job.finish(nil, fmt.Errorf("failed: %w", fs.ErrorObjectNotFound))
+	assert.Equal(t, "not-found", job.ErrorCategory)
+
+	job.finish(nil, nil)
+	assert.Equal(t, "", job.ErrorCategory)
+}
+
+func TestJobAfter(t *testing.T) {
+	ctx := context.Background()
+	jobs := newJobs()
+
+	// A job waits for the jobs it depends on
+	ctx1, cancel1 := context.WithCancel(ctx)
+	job1, _, err := jobs.NewJob(ctx, ctxParmFn(ctx1, false), rc.Params{"_async": true})
+	require.NoError(t, err)
+	job2, _, err := jobs.NewJob(ctx, noopFn, rc.Params{"_async": true, "_after": fmt.Sprintf("[%d]", job1.ID)})
+	require.NoError(t, err)
+	job3, _, err := jobs.NewJob(ctx, noopFn, rc.Params{"_async": true, "_after": []any{float64(job1.ID), float64(job2.ID)}})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{job1.ID, job2.ID}, job3.After)
+
+	time.Sleep(sleepTime)
+	for _, job := range []*Job{job2, job3} {
+		job.mu.Lock()
+		assert.False(t, job.Finished)
+		assert.True(t, job.Waiting)
+		job.mu.Unlock()
+	}
+
+	cancel1()
+	assert.Eventually(t, func() bool {
+		job3.mu.Lock()
+		defer job3.mu.Unlock()
+		return job3.Finished
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.True(t, job2.Success)
+	assert.False(t, job2.Waiting)
+	assert.True(t, job3.Success)
+	assert.Equal(t, rc.Params{}, job3.Output)
+
+	// A job doesn't run if a job it depends on fails
+	ran := false
+	failFn := func(ctx context.Context, in rc.Params) (rc.Params, error) {
+		return nil, errors.New("oops")
+	}
+	job4, _, err := jobs.NewJob(ctx, failFn, rc.Params{"_async": true})
+	require.NoError(t, err)
+	_, _, err = jobs.NewJob(ctx, func(ctx context.Context, in rc.Params) (rc.Params, error) {
+		ran = true
+		return nil, nil
+	}, rc.Params{"_after": job4.ID})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "oops")
+	assert.False(t, ran)
+
+	// Stopping a waiting job stops it waiting
+	job5, _, err := jobs.NewJob(ctx, ctxFn, rc.Params{"_async": true})
+	require.NoError(t, err)
+	job6, _, err := jobs.NewJob(ctx, noopFn, rc.Params{"_async": true, "_after": []int64{job5.ID}})
+	require.NoError(t, err)
+	job6.Stop()
+	assert.Eventually(t, func() bool {
+		job6.mu.Lock()
+		defer job6.mu.Unlock()
+		return job6.Finished
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "context canceled", job6.Error)
+	job5.Stop()
+
+	// Dependencies must exist
+	_, _, err = jobs.NewJob(ctx, noopFn, rc.Params{"_after": []int64{123123123}})
+	require.Error(t, err)
+	assert.True(t, rc.IsErrParamInvalid(err))
+	_, _, err = jobs.NewJob(ctx, noopFn, rc.Params{"_after": "potato"})
+	require.Error(t, err)
+}
+
 func TestRcJobStatus(t *testing.T) {
 	ctx := context.Background()
 	jobID.Store(0)
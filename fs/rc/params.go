@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
 )
 
 // Params is the input and output type for the Func
@@ -307,5 +308,8 @@ func Error(path string, in Params, err error, status int) (Params, int) {
 		"input":  in,
 		"path":   path,
 	}
+	if category := fserrors.GetCategory(err); category != fserrors.CategoryNone {
+		result["errorCategory"] = string(category)
+	}
 	return result, status
 }
@@ -83,6 +83,11 @@ var OptionsInfo = fs.Options{{
 	Default: 10 * time.Second,
 	Help:    "Interval to check for expired async jobs",
 	Groups:  "RC",
+}, {
+	Name:    "rc_schedule_file",
+	Default: "",
+	Help:    "File to save scheduled jobs in for rcd (default schedules.json next to the config file)",
+	Groups:  "RC",
 }, {
 	Name:    "metrics_addr",
 	Default: []string{},
@@ -122,6 +127,7 @@ type Options struct {
 	MetricsTemplate     libhttp.TemplateConfig `config:"metrics"`
 	JobExpireDuration   time.Duration          `config:"rc_job_expire_duration"`
 	JobExpireInterval   time.Duration          `config:"rc_job_expire_interval"`
+	ScheduleFile        string                 `config:"rc_schedule_file"` // file to persist scheduled jobs in
 }
 
 // Opt is the default values used for Options
@@ -0,0 +1,190 @@
+package rcserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fs/rc/jobs"
+	"golang.org/x/net/websocket"
+)
+
+// eventBuffer is the number of events buffered for each client
+// before they start being dropped
+const eventBuffer = 1024
+
+// eventDropped is the type of the event sent when events were dropped
+// because the client wasn't reading them fast enough
+const eventDropped = "dropped"
+
+// eventTypes are the event types the client can filter on
+var eventTypes = []string{rc.EventTransferStart, rc.EventTransferFinish, rc.EventError, rc.EventJob}
+
+// eventFilter selects which events are sent to a client
+type eventFilter struct {
+	types  map[string]struct{} // event types wanted, all if empty
+	jobIDs map[int64]struct{}  // job IDs wanted
+	groups map[string]struct{} // stats groups wanted
+}
+
+// splitList splits the comma separated values for key in q
+func splitList(q url.Values, key string) (out []string) {
+	for _, v := range q[key] {
+		for _, item := range strings.Split(v, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
+
+// newEventFilter makes an eventFilter from the query parameters
+//
+// If neither jobid nor group is set then events for all jobs are sent.
+func newEventFilter(q url.Values) (*eventFilter, error) {
+	f := &eventFilter{
+		types:  map[string]struct{}{},
+		jobIDs: map[int64]struct{}{},
+		groups: map[string]struct{}{},
+	}
+	for _, eventType := range splitList(q, "type") {
+		found := false
+		for _, t := range eventTypes {
+			if t == eventType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown event type %q - must be one of %s", eventType, strings.Join(eventTypes, ", "))
+		}
+		f.types[eventType] = struct{}{}
+	}
+	for _, s := range splitList(q, "jobid") {
+		jobID, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad jobid %q: %w", s, err)
+		}
+		f.jobIDs[jobID] = struct{}{}
+		// Transfers and errors are reported by stats group so
+		// match the default group of the job and its actual group
+		f.groups[fmt.Sprintf("job/%d", jobID)] = struct{}{}
+		if job := jobs.Get(jobID); job != nil {
+			f.groups[job.Group] = struct{}{}
+		}
+	}
+	for _, group := range splitList(q, "group") {
+		f.groups[group] = struct{}{}
+	}
+	return f, nil
+}
+
+// match returns true if e should be sent to the client
+func (f *eventFilter) match(e rc.Event) bool {
+	if len(f.types) > 0 {
+		if _, ok := f.types[e.Type]; !ok {
+			return false
+		}
+	}
+	if len(f.jobIDs) == 0 && len(f.groups) == 0 {
+		return true
+	}
+	if _, ok := f.jobIDs[e.JobID]; ok && e.JobID != 0 {
+		return true
+	}
+	_, ok := f.groups[e.Group]
+	return ok && e.Group != ""
+}
+
+// isWebSocket returns true if r is asking to upgrade to a WebSocket
+func isWebSocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// checkOrigin only allows WebSocket connections from browsers on
+// pages served by us or from the origin allowed by --rc-allow-origin
+//
+// Clients which aren't browsers don't send an Origin.
+func (s *Server) checkOrigin(config *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return fmt.Errorf("bad origin %q: %w", origin, err)
+	}
+	allowOrigin := s.opt.HTTP.AllowOrigin
+	if u.Host == r.Host || allowOrigin == "*" || origin == allowOrigin {
+		return nil
+	}
+	return fmt.Errorf("origin %q not allowed", origin)
+}
+
+// serveEvents streams events to the client over a WebSocket
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := newEventFilter(r.URL.Query())
+	if err != nil {
+		writeError("events", nil, w, err, http.StatusBadRequest)
+		return
+	}
+	server := websocket.Server{
+		Handshake: s.checkOrigin,
+		Handler: func(ws *websocket.Conn) {
+			streamEvents(ws, filter)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// streamEvents sends the events matching filter to ws until the
+// client goes away
+func streamEvents(ws *websocket.Conn, filter *eventFilter) {
+	sub := rc.SubscribeEvents(eventBuffer)
+	defer rc.UnsubscribeEvents(sub)
+
+	// Read and discard anything the client sends so we notice
+	// when it goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var msg []byte
+		for websocket.Message.Receive(ws, &msg) == nil {
+		}
+	}()
+
+	var dropped int64
+	for {
+		select {
+		case <-closed:
+			return
+		case e := <-sub.C:
+			// Tell the client if it has missed some events
+			if n := sub.Dropped(); n != dropped {
+				err := websocket.JSON.Send(ws, rc.Event{
+					Type: eventDropped,
+					Time: e.Time,
+					Data: rc.Params{"count": n - dropped},
+				})
+				if err != nil {
+					fs.Debugf(nil, "rc: events: failed to send: %v", err)
+					return
+				}
+				dropped = n
+			}
+			if !filter.match(e) {
+				continue
+			}
+			if err := websocket.JSON.Send(ws, e); err != nil {
+				fs.Debugf(nil, "rc: events: failed to send: %v", err)
+				return
+			}
+		}
+	}
+}
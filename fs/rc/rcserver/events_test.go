@@ -0,0 +1,95 @@
+package rcserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fs/rc/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestEventFilter(t *testing.T) {
+	f, err := newEventFilter(url.Values{})
+	require.NoError(t, err)
+	assert.True(t, f.match(rc.Event{Type: rc.EventJob, JobID: 1}))
+	assert.True(t, f.match(rc.Event{Type: rc.EventTransferStart}))
+
+	f, err = newEventFilter(url.Values{"type": {"job,error"}, "jobid": {"42"}})
+	require.NoError(t, err)
+	assert.True(t, f.match(rc.Event{Type: rc.EventJob, JobID: 42}))
+	assert.False(t, f.match(rc.Event{Type: rc.EventJob, JobID: 43}))
+	assert.True(t, f.match(rc.Event{Type: rc.EventError, Group: "job/42"}))
+	assert.False(t, f.match(rc.Event{Type: rc.EventError, Group: "job/43"}))
+	assert.False(t, f.match(rc.Event{Type: rc.EventError}))
+	assert.False(t, f.match(rc.Event{Type: rc.EventTransferStart, Group: "job/42"}))
+
+	f, err = newEventFilter(url.Values{"group": {"mygroup"}})
+	require.NoError(t, err)
+	assert.True(t, f.match(rc.Event{Type: rc.EventTransferFinish, Group: "mygroup"}))
+	assert.False(t, f.match(rc.Event{Type: rc.EventTransferFinish, Group: "other"}))
+
+	_, err = newEventFilter(url.Values{"type": {"potato"}})
+	assert.ErrorContains(t, err, "unknown event type")
+	_, err = newEventFilter(url.Values{"jobid": {"potato"}})
+	assert.ErrorContains(t, err, "bad jobid")
+}
+
+func TestEvents(t *testing.T) {
+	opt := newTestOpt()
+	opt.HTTP.ListenAddr = []string{testBindAddress}
+	rcServer, err := newServer(context.Background(), &opt, http.NewServeMux())
+	require.NoError(t, err)
+	require.NoError(t, rcServer.Serve())
+	defer func() {
+		assert.NoError(t, rcServer.Shutdown())
+		rcServer.Wait()
+	}()
+	testURL := rcServer.server.URLs()[0]
+	wsURL := "ws" + strings.TrimPrefix(testURL, "http") + "events"
+
+	// Browsers from other origins are refused
+	_, err = websocket.Dial(wsURL, "", "http://evil.example.com/")
+	require.Error(t, err)
+
+	// Start a job to filter on
+	fn := func(ctx context.Context, in rc.Params) (rc.Params, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	job, _, err := jobs.NewJob(context.Background(), fn, rc.Params{"_async": true})
+	require.NoError(t, err)
+
+	config, err := websocket.NewConfig(fmt.Sprintf("%s?type=job&jobid=%d", wsURL, job.ID), testURL)
+	require.NoError(t, err)
+	ws, err := websocket.DialConfig(config)
+	require.NoError(t, err)
+	defer func() {
+		_ = ws.Close()
+	}()
+
+	// Wait for the server to subscribe
+	require.Eventually(t, rc.EventsWanted, 10*time.Second, time.Millisecond)
+
+	// Events for other jobs aren't sent
+	other, _, err := jobs.NewJob(context.Background(), fn, rc.Params{"_async": true})
+	require.NoError(t, err)
+	other.Stop()
+	job.Stop()
+
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(10*time.Second)))
+	var e rc.Event
+	require.NoError(t, websocket.JSON.Receive(ws, &e))
+	assert.Equal(t, rc.EventJob, e.Type)
+	assert.Equal(t, job.ID, e.JobID)
+	assert.Equal(t, "finished", e.Data["state"])
+	assert.Equal(t, false, e.Data["success"])
+	assert.Equal(t, "context canceled", e.Data["error"])
+}
@@ -374,6 +374,9 @@ func (s *Server) handleGet(w http.ResponseWriter, r *http.Request, path string)
 		// Serve /[fs]/remote files
 		s.serveRemote(w, r, fsMatchResult[2], fsMatchResult[1])
 		return
+	case path == "events" && isWebSocket(r):
+		s.serveEvents(w, r)
+		return
 	case path == "metrics" && s.opt.EnableMetrics:
 		promHandlerFunc(w, r)
 		return
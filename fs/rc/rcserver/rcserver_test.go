@@ -274,6 +274,7 @@ func TestRemoteServing(t *testing.T) {
 			Status: http.StatusNotFound,
 			Expected: `{
 	"error": "failed to list directory: directory not found",
+	"errorCategory": "not-found",
 	"input": null,
 	"path": "",
 	"status": 404
@@ -285,6 +286,7 @@ func TestRemoteServing(t *testing.T) {
 			Status: http.StatusNotFound,
 			Expected: `{
 	"error": "failed to find object: object not found",
+	"errorCategory": "not-found",
 	"input": null,
 	"path": "notfound",
 	"status": 404
@@ -296,6 +298,7 @@ func TestRemoteServing(t *testing.T) {
 			Status: http.StatusNotFound,
 			Expected: `{
 	"error": "failed to list directory: directory not found",
+	"errorCategory": "not-found",
 	"input": null,
 	"path": "dirnotfound",
 	"status": 404
@@ -0,0 +1,184 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronField describes one field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // names for the values starting at min, if any
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	dowField    = cronField{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+// cronDescriptors are the @ shortcuts for common expressions
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// bits is a set of the values in a field
+type bits uint64
+
+func (b bits) has(i int) bool {
+	return b&(1<<uint(i)) != 0
+}
+
+// cron is a parsed cron expression
+type cron struct {
+	minute, hour, dom, month, dow bits
+	domStar, dowStar              bool // set if the field was *
+}
+
+// parseCron parses a standard 5 field cron expression
+//
+//	minute hour day-of-month month day-of-week
+//
+// Each field can be *, a number, a range a-b, a list a,b,c and any of
+// these can have a step /n. Months and days of the week can be given
+// as three letter names. Sunday is 0 or 7. The @hourly, @daily,
+// @weekly, @monthly and @yearly shortcuts are also accepted.
+func parseCron(spec string) (*cron, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields: minute hour day-of-month month day-of-week", spec)
+	}
+	var (
+		c   cron
+		err error
+	)
+	for i, x := range []struct {
+		field *cronField
+		out   *bits
+	}{
+		{&minuteField, &c.minute},
+		{&hourField, &c.hour},
+		{&domField, &c.dom},
+		{&monthField, &c.month},
+		{&dowField, &c.dow},
+	} {
+		*x.out, err = x.field.parse(fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", spec, err)
+		}
+	}
+	// Sunday can be 0 or 7
+	if c.dow.has(7) {
+		c.dow |= 1
+	}
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+	return &c, nil
+}
+
+// parse the field in s
+func (f *cronField) parse(s string) (b bits, err error) {
+	for _, item := range strings.Split(s, ",") {
+		rangeSpec, stepSpec, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			step, err = strconv.Atoi(stepSpec)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad step %q in %s", stepSpec, f.name)
+			}
+		}
+		var start, end int
+		if rangeSpec == "*" {
+			start, end = f.min, f.max
+		} else {
+			startSpec, endSpec, isRange := strings.Cut(rangeSpec, "-")
+			start, err = f.value(startSpec)
+			if err != nil {
+				return 0, err
+			}
+			end = start
+			if isRange {
+				end, err = f.value(endSpec)
+				if err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// a/n means a-max/n
+				end = f.max
+			}
+			if end < start {
+				return 0, fmt.Errorf("bad range %q in %s", rangeSpec, f.name)
+			}
+		}
+		for i := start; i <= end; i += step {
+			b |= 1 << uint(i)
+		}
+	}
+	return b, nil
+}
+
+// value parses a single number or name
+func (f *cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil || i < f.min || i > f.max {
+		return 0, fmt.Errorf("bad value %q in %s: must be %d-%d", s, f.name, f.min, f.max)
+	}
+	return i, nil
+}
+
+// dayMatches returns true if the day of t matches
+//
+// As with traditional cron if both the day of month and the day of
+// week are restricted then either may match.
+func (c *cron) dayMatches(t time.Time) bool {
+	domMatch := c.dom.has(t.Day())
+	dowMatch := c.dow.has(int(t.Weekday()))
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// next returns the first time after t which matches, or the zero time
+// if there isn't one in the next 5 years
+func (c *cron) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case !c.month.has(int(month)):
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+		case !c.hour.has(t.Hour()):
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, loc)
+		case !c.minute.has(t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
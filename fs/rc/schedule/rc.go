@@ -0,0 +1,157 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rclone/rclone/fs/rc"
+)
+
+func init() {
+	rc.Add(rc.Call{
+		Path:         "schedule/create",
+		AuthRequired: true,
+		Fn:           rcCreate,
+		Title:        "Run an rc command on a cron schedule",
+		Help: `This is only available in rclone rcd. The schedules are saved
+to the file set with --rc-schedule-file (default schedules.json next
+to the config file) and are loaded again when rcd restarts.
+
+Parameters:
+
+- cron - cron expression for when to run the command (string)
+- command - rc command to run, e.g. "sync/sync" (string)
+- params - parameters for the command (object, optional)
+- id - name for the schedule (string, optional - a random one is made if not set)
+
+The cron expression has 5 fields - minute, hour, day of month, month
+and day of week - in local time. Each field can be "*", a number, a
+range "1-5", a list "1,3,5" and any of these with a step "*/15".
+Months and days can be given as names, e.g. "jan" or "mon". The
+shortcuts "@hourly", "@daily", "@weekly", "@monthly" and "@yearly"
+can be used too.
+
+Each time the schedule is due the command is started as an async job
+with the params given, as if it was called with "_async": true. If
+the job started last time is still running the command isn't started
+again. Use job/status with the lastJobId from schedule/list to see
+how it went.
+
+For example to sync every night at 2am:
+
+    rclone rc schedule/create id=nightly cron="0 2 * * *" command=sync/sync params='{"srcFs":"src:","dstFs":"dst:"}'
+
+Results:
+
+- id - the id of the schedule
+- next - time the command will next run
+`,
+	})
+}
+
+// Creates a schedule
+func rcCreate(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	s, err := get()
+	if err != nil {
+		return nil, err
+	}
+	sched := &Schedule{}
+	sched.Cron, err = in.GetString("cron")
+	if err != nil {
+		return nil, err
+	}
+	sched.Command, err = in.GetString("command")
+	if err != nil {
+		return nil, err
+	}
+	err = in.GetStructMissingOK("params", &sched.Params)
+	if err != nil {
+		return nil, err
+	}
+	sched.ID, err = in.GetString("id")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	err = s.create(sched)
+	if err != nil {
+		return nil, err
+	}
+	return rc.Params{
+		"id":   sched.ID,
+		"next": sched.Next,
+	}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:         "schedule/list",
+		AuthRequired: true,
+		Fn:           rcList,
+		Title:        "List the scheduled rc commands",
+		Help: `This is only available in rclone rcd.
+
+Parameters: None.
+
+Results:
+
+- schedules - array of schedules, each with
+    - id - id of the schedule
+    - cron - cron expression
+    - command - rc command run
+    - params - parameters for the command
+    - created - time the schedule was created
+    - next - time the command will next run
+    - lastRun - time the command was last started
+    - lastJobId - id of the job started last time
+    - lastError - error starting the command last time, if any
+    - running - true if the job started last time is still running
+`,
+	})
+}
+
+// Lists the schedules
+func rcList(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	s, err := get()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out = make(rc.Params)
+	var schedules []rc.Params
+	err = rc.Reshape(&schedules, s.list())
+	if err != nil {
+		return nil, fmt.Errorf("reshape failed in schedule list: %w", err)
+	}
+	out["schedules"] = schedules
+	return out, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:         "schedule/delete",
+		AuthRequired: true,
+		Fn:           rcDelete,
+		Title:        "Delete a scheduled rc command",
+		Help: `This is only available in rclone rcd. It doesn't stop the
+job if it is running - use job/stop for that.
+
+Parameters:
+
+- id - id of the schedule (string)
+`,
+	})
+}
+
+// Deletes a schedule
+func rcDelete(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	s, err := get()
+	if err != nil {
+		return nil, err
+	}
+	ID, err := in.GetString("id")
+	if err != nil {
+		return nil, err
+	}
+	return rc.Params{}, s.remove(ID)
+}
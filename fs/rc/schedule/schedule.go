@@ -0,0 +1,309 @@
+// Package schedule runs rc commands on a cron schedule in rclone rcd
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fs/rc/jobs"
+)
+
+// Schedule is an rc command run on a cron schedule
+type Schedule struct {
+	ID        string    `json:"id"`
+	Cron      string    `json:"cron"`
+	Command   string    `json:"command"`
+	Params    rc.Params `json:"params"`
+	Created   time.Time `json:"created"`
+	LastRun   time.Time `json:"lastRun,omitempty"`
+	LastJobID int64     `json:"lastJobId,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+	Next      time.Time `json:"next"`    // worked out from Cron when loaded
+	Running   bool      `json:"running"` // set while the job started last is running
+
+	cron *cron
+}
+
+// Scheduler runs the schedules
+type Scheduler struct {
+	ctx       context.Context
+	mu        sync.Mutex
+	path      string // file to save the schedules in, "" for memory only
+	schedules map[string]*Schedule
+	kick      chan struct{} // wake the runner when the schedules change
+	now       func() time.Time
+}
+
+// the scheduler for rcd, nil if not started
+var (
+	schedulerMu sync.Mutex
+	scheduler   *Scheduler
+)
+
+// DefaultPath returns the default file to save the schedules in, or ""
+// if the config isn't being saved to a file
+func DefaultPath() string {
+	configPath := config.GetConfigPath()
+	if configPath == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(configPath), "schedules.json")
+}
+
+// Start loads the schedules saved in path and starts running them
+// until ctx is cancelled. If path is "" the schedules aren't saved.
+func Start(ctx context.Context, path string) error {
+	s, err := newScheduler(ctx, path)
+	if err != nil {
+		return err
+	}
+	schedulerMu.Lock()
+	scheduler = s
+	schedulerMu.Unlock()
+	go s.run()
+	return nil
+}
+
+// newScheduler makes a Scheduler loading the schedules from path
+func newScheduler(ctx context.Context, path string) (*Scheduler, error) {
+	s := &Scheduler{
+		ctx:       ctx,
+		path:      path,
+		schedules: make(map[string]*Schedule),
+		kick:      make(chan struct{}, 1),
+		now:       time.Now,
+	}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read schedules: %w", err)
+	}
+	var schedules []*Schedule
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to parse schedules file %q: %w", path, err)
+	}
+	now := s.now()
+	for _, sched := range schedules {
+		sched.cron, err = parseCron(sched.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", sched.ID, err)
+		}
+		sched.Next = sched.cron.next(now)
+		sched.Running = false
+		s.schedules[sched.ID] = sched
+	}
+	fs.Infof(nil, "Loaded %d schedules from %q", len(s.schedules), path)
+	return s, nil
+}
+
+// save writes the schedules to the file
+//
+// Call with the mutex held
+func (s *Scheduler) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.list(), "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to make schedules directory: %w", err)
+	}
+	// The params may contain secrets so keep the file private
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write schedules: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write schedules: %w", err)
+	}
+	return nil
+}
+
+// list returns the schedules sorted by ID
+//
+// Call with the mutex held
+func (s *Scheduler) list() []*Schedule {
+	schedules := make([]*Schedule, 0, len(s.schedules))
+	for _, sched := range s.schedules {
+		schedules = append(schedules, sched)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].ID < schedules[j].ID
+	})
+	return schedules
+}
+
+// wake makes the runner look at the schedules again
+func (s *Scheduler) wake() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+// create adds a new schedule
+func (s *Scheduler) create(sched *Schedule) error {
+	var err error
+	sched.cron, err = parseCron(sched.Cron)
+	if err != nil {
+		return rc.NewErrParamInvalid(err)
+	}
+	call := rc.Calls.Get(sched.Command)
+	if call == nil {
+		return rc.NewErrParamInvalid(fmt.Errorf("couldn't find command %q", sched.Command))
+	}
+	if call.NeedsRequest || call.NeedsResponse {
+		return rc.NewErrParamInvalid(fmt.Errorf("command %q can't be scheduled", sched.Command))
+	}
+	if sched.Params == nil {
+		sched.Params = rc.Params{}
+	}
+	now := s.now()
+	sched.Created = now
+	sched.Next = sched.cron.next(now)
+	if sched.Next.IsZero() {
+		return rc.NewErrParamInvalid(fmt.Errorf("cron expression %q never matches", sched.Cron))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sched.ID == "" {
+		sched.ID = uuid.New().String()
+	}
+	if _, found := s.schedules[sched.ID]; found {
+		return rc.NewErrParamInvalid(fmt.Errorf("schedule %q already exists", sched.ID))
+	}
+	s.schedules[sched.ID] = sched
+	if err := s.save(); err != nil {
+		delete(s.schedules, sched.ID)
+		return err
+	}
+	s.wake()
+	return nil
+}
+
+// remove deletes the schedule with ID
+func (s *Scheduler) remove(ID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sched, found := s.schedules[ID]
+	if !found {
+		return rc.NewErrParamInvalid(fmt.Errorf("schedule %q not found", ID))
+	}
+	delete(s.schedules, ID)
+	if err := s.save(); err != nil {
+		s.schedules[ID] = sched
+		return err
+	}
+	s.wake()
+	return nil
+}
+
+// runDue starts the schedules which are due at now and returns the
+// time the next one is due, or the zero time if none are
+func (s *Scheduler) runDue(now time.Time) (next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ran := false
+	for _, sched := range s.list() {
+		if !sched.Next.IsZero() && !now.Before(sched.Next) {
+			s.start(sched, now)
+			sched.Next = sched.cron.next(now)
+			ran = true
+		}
+		if !sched.Next.IsZero() && (next.IsZero() || sched.Next.Before(next)) {
+			next = sched.Next
+		}
+	}
+	if ran {
+		if err := s.save(); err != nil {
+			fs.Errorf(nil, "Scheduler: %v", err)
+		}
+	}
+	return next
+}
+
+// start runs sched as an async job
+//
+// If the job started last time is still running it isn't started
+// again.
+//
+// Call with the mutex held
+func (s *Scheduler) start(sched *Schedule, now time.Time) {
+	if sched.Running {
+		fs.Logf(nil, "Scheduler: skipping %q as job %d from last time is still running", sched.ID, sched.LastJobID)
+		return
+	}
+	sched.LastRun = now
+	sched.LastError = ""
+	call := rc.Calls.Get(sched.Command)
+	if call == nil {
+		sched.LastError = fmt.Sprintf("couldn't find command %q", sched.Command)
+		fs.Errorf(nil, "Scheduler: %q: %s", sched.ID, sched.LastError)
+		return
+	}
+	in := sched.Params.Copy()
+	in["_async"] = true
+	job, _, err := jobs.NewJob(s.ctx, call.Fn, in)
+	if err != nil {
+		sched.LastError = err.Error()
+		fs.Errorf(nil, "Scheduler: %q: failed to start %q: %v", sched.ID, sched.Command, err)
+		return
+	}
+	sched.LastJobID = job.ID
+	sched.Running = true
+	job.OnFinish(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		sched.Running = false
+	})
+	fs.Infof(nil, "Scheduler: %q: started %q as job %d", sched.ID, sched.Command, job.ID)
+}
+
+// run the schedules until the context is cancelled
+func (s *Scheduler) run() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.kick:
+		case <-timer.C:
+		}
+		next := s.runDue(s.now())
+		timer.Stop()
+		if next.IsZero() {
+			// Nothing scheduled - wait to be kicked
+			continue
+		}
+		timer.Reset(time.Until(next))
+	}
+}
+
+// get the running scheduler or return an error
+func get() (*Scheduler, error) {
+	schedulerMu.Lock()
+	defer schedulerMu.Unlock()
+	if scheduler == nil {
+		return nil, errors.New("the scheduler is only available in rclone rcd")
+	}
+	return scheduler, nil
+}
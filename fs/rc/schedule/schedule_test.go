@@ -0,0 +1,146 @@
+package schedule
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fs/rc/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testCalls   atomic.Int32
+	testRelease = make(chan struct{})
+)
+
+func init() {
+	rc.Add(rc.Call{
+		Path: "schedule/test",
+		Fn: func(ctx context.Context, in rc.Params) (rc.Params, error) {
+			testCalls.Add(1)
+			<-testRelease
+			return nil, nil
+		},
+	})
+}
+
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	s, err := newScheduler(ctx, path)
+	require.NoError(t, err)
+	s.now = func() time.Time { return now }
+
+	// Bad schedules
+	assert.Error(t, s.create(&Schedule{Cron: "potato", Command: "schedule/test"}))
+	assert.Error(t, s.create(&Schedule{Cron: "* * * * *", Command: "potato/potato"}))
+	assert.Error(t, s.create(&Schedule{Cron: "0 0 31 2 *", Command: "schedule/test"}))
+
+	require.NoError(t, s.create(&Schedule{ID: "test", Cron: "*/5 * * * *", Command: "schedule/test", Params: rc.Params{"a": 1}}))
+	assert.Error(t, s.create(&Schedule{ID: "test", Cron: "* * * * *", Command: "schedule/test"}))
+	assert.Equal(t, time.Date(2025, 1, 15, 10, 35, 0, 0, time.UTC), s.schedules["test"].Next)
+
+	// Nothing is due yet
+	next := s.runDue(now)
+	assert.Equal(t, s.schedules["test"].Next, next)
+	assert.Equal(t, int32(0), testCalls.Load())
+
+	// Run when due
+	now = next
+	next = s.runDue(now)
+	assert.Equal(t, time.Date(2025, 1, 15, 10, 40, 0, 0, time.UTC), next)
+	sched := s.schedules["test"]
+	s.mu.Lock()
+	assert.Equal(t, now, sched.LastRun)
+	assert.True(t, sched.Running)
+	jobID := sched.LastJobID
+	s.mu.Unlock()
+	require.NotNil(t, jobs.Get(jobID))
+	assert.Eventually(t, func() bool { return testCalls.Load() == 1 }, 10*time.Second, time.Millisecond)
+
+	// Don't run again while the last job is running
+	now = next
+	s.runDue(now)
+	s.mu.Lock()
+	assert.Equal(t, jobID, sched.LastJobID)
+	s.mu.Unlock()
+
+	// Runs again once it has finished
+	testRelease <- struct{}{}
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return !sched.Running
+	}, 10*time.Second, time.Millisecond)
+	now = now.Add(5 * time.Minute)
+	s.runDue(now)
+	s.mu.Lock()
+	assert.NotEqual(t, jobID, sched.LastJobID)
+	s.mu.Unlock()
+	assert.Eventually(t, func() bool { return testCalls.Load() == 2 }, 10*time.Second, time.Millisecond)
+	testRelease <- struct{}{}
+
+	// Reload the schedules
+	s2, err := newScheduler(ctx, path)
+	require.NoError(t, err)
+	require.Len(t, s2.schedules, 1)
+	loaded := s2.schedules["test"]
+	assert.Equal(t, "*/5 * * * *", loaded.Cron)
+	assert.Equal(t, "schedule/test", loaded.Command)
+	assert.Equal(t, rc.Params{"a": float64(1)}, loaded.Params)
+	assert.Equal(t, now, loaded.LastRun)
+	assert.False(t, loaded.Running)
+
+	// Delete
+	require.NoError(t, s.remove("test"))
+	assert.Error(t, s.remove("test"))
+	s2, err = newScheduler(ctx, path)
+	require.NoError(t, err)
+	assert.Len(t, s2.schedules, 0)
+}
+
+func TestRcSchedule(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	call := rc.Calls.Get("schedule/list")
+	require.NotNil(t, call)
+
+	// Not started
+	schedulerMu.Lock()
+	scheduler = nil
+	schedulerMu.Unlock()
+	_, err := call.Fn(ctx, rc.Params{})
+	assert.ErrorContains(t, err, "only available in rclone rcd")
+
+	require.NoError(t, Start(ctx, ""))
+
+	out, err := rc.Calls.Get("schedule/create").Fn(ctx, rc.Params{
+		"cron":    "@yearly",
+		"command": "rc/noop",
+		"params":  `{"potato":1}`,
+	})
+	require.NoError(t, err)
+	ID := out["id"].(string)
+	assert.NotEmpty(t, ID)
+
+	out, err = call.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	schedules := out["schedules"].([]rc.Params)
+	require.Len(t, schedules, 1)
+	assert.Equal(t, ID, schedules[0]["id"])
+	assert.Equal(t, "rc/noop", schedules[0]["command"])
+	assert.Equal(t, map[string]any{"potato": float64(1)}, schedules[0]["params"])
+
+	_, err = rc.Calls.Get("schedule/delete").Fn(ctx, rc.Params{"id": ID})
+	require.NoError(t, err)
+	out, err = call.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	assert.Len(t, out["schedules"], 0)
+}
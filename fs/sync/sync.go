@@ -84,6 +84,7 @@ type syncCopyMove struct {
 	renameCheck            []fs.Object            // accumulate files to check for rename here
 	compareCopyDest        []fs.Fs                // place to check for files to server side copy
 	backupDir              fs.Fs                  // place to store overwrites/deletes
+	snapshotDir            fs.Fs                  // place to store snapshots of the destination
 	checkFirst             bool                   // if set run all the checkers before starting transfers
 	maxDurationEndTime     time.Time              // end time if --max-duration is set
 	logger                 operations.LoggerFn    // LoggerFn used to report the results of a sync (or bisync) to an io.Writer
@@ -273,6 +274,14 @@ func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 			return nil, err
 		}
 	}
+	// Make Fs for --snapshot-dir if required
+	if ci.SnapshotDir != "" {
+		var err error
+		s.snapshotDir, err = operations.SnapshotDir(ctx, fdst, fsrc)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if len(ci.CompareDest) > 0 {
 		var err error
 		s.compareCopyDest, err = operations.GetCompareDest(ctx)
@@ -1353,7 +1362,12 @@ func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 	if err != nil {
 		return err
 	}
-	return do.run()
+	err = do.run()
+	// Only snapshot the destination if the sync worked
+	if err == nil && do.snapshotDir != nil {
+		err = operations.Snapshot(ctx, do.snapshotDir, fdst)
+	}
+	return err
 }
 
 // Sync fsrc into fdst
@@ -2439,6 +2439,71 @@ func TestSyncBackupDirSuffixOnly(t *testing.T) {
 	testSyncBackupDir(t, "", ".bak", false)
 }
 
+func TestSyncSnapshotDir(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	r.Mkdir(ctx, r.Fremote)
+
+	ci.SnapshotDir = r.FremoteName + "/snapshots"
+
+	r.WriteFile("one", "one", t1)
+	r.WriteFile("two", "two", t1)
+
+	fdst, err := fs.NewFs(ctx, r.FremoteName+"/dst")
+	require.NoError(t, err)
+
+	// lastSnapshotName returns the name of the newest snapshot
+	lastSnapshotName := func() string {
+		entries, err := r.Fremote.List(ctx, "snapshots")
+		require.NoError(t, err)
+		last := ""
+		for _, entry := range entries {
+			last = max(last, strings.TrimPrefix(entry.Remote(), "snapshots/"))
+		}
+		return last
+	}
+
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, fdst, r.Flocal, false)
+	require.NoError(t, err)
+	snap1 := lastSnapshotName()
+	_, err = time.Parse(operations.SnapshotFormat, snap1)
+	require.NoError(t, err)
+
+	r.CheckRemoteItems(t,
+		fstest.NewItem("dst/one", "one", t1),
+		fstest.NewItem("dst/two", "two", t1),
+		fstest.NewItem("snapshots/"+snap1+"/one", "one", t1),
+		fstest.NewItem("snapshots/"+snap1+"/two", "two", t1),
+	)
+
+	// Snapshots are named to the second so wait for the next one
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+
+	// Change one, delete two and add three
+	r.WriteFile("one", "oneA", t2)
+	r.WriteFile("three", "three", t1)
+	obj, err := r.Flocal.NewObject(ctx, "two")
+	require.NoError(t, err)
+	require.NoError(t, obj.Remove(ctx))
+
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, fdst, r.Flocal, false)
+	require.NoError(t, err)
+	snap2 := lastSnapshotName()
+	assert.Greater(t, snap2, snap1)
+
+	r.CheckRemoteItems(t,
+		fstest.NewItem("dst/one", "oneA", t2),
+		fstest.NewItem("dst/three", "three", t1),
+		fstest.NewItem("snapshots/"+snap1+"/one", "one", t1),
+		fstest.NewItem("snapshots/"+snap1+"/two", "two", t1),
+		fstest.NewItem("snapshots/"+snap2+"/one", "oneA", t2),
+		fstest.NewItem("snapshots/"+snap2+"/three", "three", t1),
+	)
+}
+
 // Test with Suffix set
 func testSyncSuffix(t *testing.T, suffix string, suffixKeepExtension bool) {
 	ctx := context.Background()
@@ -194,6 +194,29 @@ type GetTierer interface {
 	GetTier() string
 }
 
+// OpenPartialUpdater is an optional interface for Object
+//
+// Unlike the OpenWriterAt feature, which truncates any existing
+// object, this updates the Object in place.
+type OpenPartialUpdater interface {
+	// OpenPartialUpdate opens the existing Object for random access
+	// writes without truncating it, and sets its size to size.
+	//
+	// Only the parts of the Object which are written to are changed.
+	// The Object's metadata is read again when the handle is closed.
+	OpenPartialUpdate(ctx context.Context, size int64) (WriterAtCloser, error)
+}
+
+// HardLinker is an optional interface for Object
+type HardLinker interface {
+	// HardLink makes a hard link to the Object at remote in f and
+	// returns the new Object.
+	//
+	// f must be the same type of Fs as the Object. It should return
+	// fs.ErrorCantCopy if the link can't be made.
+	HardLink(ctx context.Context, f Fs, remote string) (Object, error)
+}
+
 // Metadataer is an optional interface for DirEntry
 type Metadataer interface {
 	// Metadata returns metadata for an DirEntry
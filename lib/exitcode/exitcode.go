@@ -24,4 +24,12 @@ const (
 	NoFilesTransferred
 	// DurationExceeded is returned when transfer duration exceeded the quota.
 	DurationExceeded
+	// AuthError is returned when the credentials were rejected or don't allow the operation.
+	AuthError
+	// QuotaError is returned when the storage or transfer quota of the provider was exceeded.
+	QuotaError
+	// RateLimitError is returned when the provider rate limited rclone.
+	RateLimitError
+	// IntegrityError is returned when data was corrupted on transfer.
+	IntegrityError
 )
@@ -38,6 +38,7 @@ type Directory struct {
 	Breadcrumb   []Crumb
 	Sort         string
 	Order        string
+	ZipURL       string // link to download the directory as a zip, "" if not offered
 }
 
 // Crumb is a breadcrumb entry
@@ -0,0 +1,173 @@
+package multipart
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/pool"
+)
+
+// Buffer holds a chunk of a multipart upload.
+//
+// Data is written to it sequentially and it can then be read and
+// seeked, possibly many times if the chunk needs retrying.
+type Buffer interface {
+	io.Reader
+	io.Writer
+	io.Seeker
+	io.Closer
+	pool.DelayAccountinger
+	// Size returns the number of bytes in the buffer
+	Size() int64
+}
+
+// NewBuffer returns a Buffer for a chunk of a multipart upload which
+// calls account (if not nil) after every read.
+//
+// The chunk is kept in memory from the multipart pool unless
+// --multipart-disk-buffer is set when it is kept in a temporary file.
+func NewBuffer(account pool.RWAccount) Buffer {
+	ci := fs.GetConfig(context.Background())
+	if ci.MultipartDiskBuffer {
+		fb, err := newFileBuffer(account)
+		if err == nil {
+			return fb
+		}
+		fs.Errorf(nil, "multipart: failed to make disk buffer, buffering in memory instead: %v", err)
+	}
+	rw := NewRW()
+	if account != nil {
+		rw.SetAccounting(account)
+	}
+	return rw
+}
+
+var (
+	errInvalidWhence = errors.New("multipart buffer Seek: invalid whence")
+	errNegativeSeek  = errors.New("multipart buffer Seek: negative position")
+	errSeekPastEnd   = errors.New("multipart buffer Seek: attempt to seek past end of data")
+)
+
+// fileBuffer is a Buffer backed by a temporary file
+type fileBuffer struct {
+	fd        *os.File
+	account   pool.RWAccount // account for a read
+	accountOn int            // only account on or after this read
+
+	mu    sync.Mutex
+	size  int64 // size written
+	out   int64 // offset we are reading from
+	reads int   // count how many times the data has been read
+}
+
+// newFileBuffer makes a fileBuffer in a new temporary file
+func newFileBuffer(account pool.RWAccount) (*fileBuffer, error) {
+	fd, err := os.CreateTemp("", "rclone-multipart-")
+	if err != nil {
+		return nil, err
+	}
+	return &fileBuffer{
+		fd:      fd,
+		account: account,
+	}, nil
+}
+
+// DelayAccounting makes sure the accounting function only gets called
+// on the i-th or later read of the data from this point (counting
+// from 1).
+//
+// Not thread safe - call in initialization only.
+func (fb *fileBuffer) DelayAccounting(i int) {
+	fb.accountOn = i
+	fb.reads = 0
+}
+
+// Write appends p to the buffer
+func (fb *fileBuffer) Write(p []byte) (n int, err error) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	n, err = fb.fd.WriteAt(p, fb.size)
+	fb.size += int64(n)
+	return n, err
+}
+
+// Read reads up to len(p) bytes from the buffer into p
+func (fb *fileBuffer) Read(p []byte) (n int, err error) {
+	fb.mu.Lock()
+	if fb.out >= fb.size {
+		fb.mu.Unlock()
+		return 0, io.EOF
+	}
+	// Count a read of the data if we read from the start
+	if fb.out == 0 {
+		fb.reads++
+	}
+	p = p[:min(int64(len(p)), fb.size-fb.out)]
+	n, err = fb.fd.ReadAt(p, fb.out)
+	fb.out += int64(n)
+	reads := fb.reads
+	fb.mu.Unlock()
+	if err != nil {
+		return n, err
+	}
+	if fb.account != nil && reads >= fb.accountOn {
+		err = fb.account(n)
+	}
+	return n, err
+}
+
+// Seek sets the offset for the next Read (not Write - this is always
+// appended) to offset, interpreted according to whence.
+//
+// Seeking to an offset before the start of the file is an error. Seeking
+// beyond the end of the written data is an error.
+func (fb *fileBuffer) Seek(offset int64, whence int) (int64, error) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = fb.out + offset
+	case io.SeekEnd:
+		abs = fb.size + offset
+	default:
+		return 0, errInvalidWhence
+	}
+	if abs < 0 {
+		return 0, errNegativeSeek
+	}
+	if abs > fb.size {
+		return offset - (abs - fb.size), errSeekPastEnd
+	}
+	fb.out = abs
+	return abs, nil
+}
+
+// Size returns the number of bytes in the buffer
+func (fb *fileBuffer) Size() int64 {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	return fb.size
+}
+
+// Close the buffer removing the temporary file
+func (fb *fileBuffer) Close() error {
+	err := fb.fd.Close()
+	removeErr := os.Remove(fb.fd.Name())
+	if err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// Check interfaces
+var (
+	_ Buffer = (*fileBuffer)(nil)
+	_ Buffer = (*pool.RW)(nil)
+)
@@ -0,0 +1,84 @@
+package multipart
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileBuffer(t *testing.T) {
+	var accounted int
+	fb, err := newFileBuffer(func(n int) error {
+		accounted += n
+		return nil
+	})
+	require.NoError(t, err)
+	name := fb.fd.Name()
+
+	n, err := io.WriteString(fb, "hello ")
+	require.NoError(t, err)
+	assert.Equal(t, 6, n)
+	_, err = io.WriteString(fb, "world")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), fb.Size())
+
+	// Read it all, not accounting the first read
+	fb.DelayAccounting(2)
+	data, err := io.ReadAll(fb)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+	assert.Equal(t, 0, accounted)
+
+	// Seek back and read again which is accounted
+	pos, err := fb.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pos)
+	data, err = io.ReadAll(fb)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+	assert.Equal(t, 11, accounted)
+
+	pos, err = fb.Seek(-5, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), pos)
+	data, err = io.ReadAll(fb)
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(data))
+
+	_, err = fb.Seek(-1, io.SeekStart)
+	assert.Equal(t, errNegativeSeek, err)
+	_, err = fb.Seek(12, io.SeekStart)
+	assert.Equal(t, errSeekPastEnd, err)
+	_, err = fb.Seek(0, 3)
+	assert.Equal(t, errInvalidWhence, err)
+
+	// Close removes the temporary file
+	require.NoError(t, fb.Close())
+	_, err = os.Stat(name)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNewBuffer(t *testing.T) {
+	ctx := context.Background()
+	ci := fs.GetConfig(ctx)
+
+	buf := NewBuffer(nil)
+	_, isRW := buf.(*pool.RW)
+	assert.True(t, isRW)
+	require.NoError(t, buf.Close())
+
+	ci.MultipartDiskBuffer = true
+	defer func() {
+		ci.MultipartDiskBuffer = false
+	}()
+	buf = NewBuffer(nil)
+	_, isFile := buf.(*fileBuffer)
+	assert.True(t, isFile)
+	require.NoError(t, buf.Close())
+}
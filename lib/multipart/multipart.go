@@ -91,15 +91,16 @@ func UploadMultipart(ctx context.Context, src fs.ObjectInfo, in io.Reader, opt U
 	in, acc := accounting.UnWrapAccounting(in)
 
 	for partNum := int64(0); !finished; partNum++ {
-		// Get a block of memory from the pool and token which limits concurrency.
+		// Get a buffer and token which limits concurrency.
 		tokens.Get()
-		rw := NewRW()
+		var account pool.RWAccount
 		if acc != nil {
-			rw.SetAccounting(acc.AccountRead)
+			account = acc.AccountRead
 		}
+		rw := NewBuffer(account)
 
 		free := func() {
-			// return the memory and token
+			// return the buffer and token
 			_ = rw.Close() // Can't return an error
 			tokens.Put()
 		}
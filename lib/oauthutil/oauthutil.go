@@ -96,6 +96,7 @@ type Config struct {
 	ClientSecret         string
 	TokenURL             string
 	AuthURL              string
+	DeviceAuthURL        string // set if the provider supports the device authorization grant
 	Scopes               []string
 	EndpointParams       url.Values
 	RedirectURL          string
@@ -111,9 +112,10 @@ func (conf *Config) MakeOauth2Config() *oauth2.Config {
 		RedirectURL:  conf.RedirectURL,
 		Scopes:       conf.Scopes,
 		Endpoint: oauth2.Endpoint{
-			AuthURL:   conf.AuthURL,
-			TokenURL:  conf.TokenURL,
-			AuthStyle: conf.AuthStyle,
+			AuthURL:       conf.AuthURL,
+			TokenURL:      conf.TokenURL,
+			DeviceAuthURL: conf.DeviceAuthURL,
+			AuthStyle:     conf.AuthStyle,
 		},
 	}
 }
@@ -347,7 +349,7 @@ func (ts *TokenSource) Token() (*oauth2.Token, error) {
 			break
 		}
 		if newErr := maybeWrapOAuthError(err, ts.name); newErr != err {
-			err = newErr // Fatal OAuth error
+			err = fserrors.CategoryError(newErr, fserrors.CategoryAuth) // Fatal OAuth error
 			break
 		}
 		fs.Debugf(ts.name, "Token refresh failed try %d/%d: %v", i, maxTries, err)
@@ -655,6 +657,23 @@ func ConfigOAuth(ctx context.Context, name string, m configmap.Mapper, ri *fs.Re
 			}
 			return fs.ConfigInput(newState("*oauth-do"), "config_verification_code", fmt.Sprintf("Verification code\n\nGo to this URL, authenticate then paste the code here.\n\n%s\n", authURL))
 		}
+		if deviceFlowSupported(opt) {
+			return fs.ConfigGoto(newState("*oauth-device"))
+		}
+		return fs.ConfigGoto(newState("*oauth-remote-authorize"))
+	case "*oauth-device":
+		opt, err := getOAuth()
+		if err != nil {
+			return nil, err
+		}
+		oauthConfig, _ := OverrideCredentials(name, m, opt.OAuth2Config)
+		err = deviceFlowGetToken(ctx, name, m, oauthConfig, opt)
+		if err != nil {
+			fs.Errorf(nil, "Couldn't authorize with a code, using rclone authorize instead: %v", err)
+			return fs.ConfigGoto(newState("*oauth-remote-authorize"))
+		}
+		return fs.ConfigGoto(newState("*oauth-done"))
+	case "*oauth-remote-authorize":
 		var out strings.Builder
 		fmt.Fprintf(&out, `For this to work, you will need rclone available on a machine that has
 a web browser available.
@@ -828,6 +847,43 @@ func clientCredentialsFlowGetToken(ctx context.Context, name string, m configmap
 	return nil
 }
 
+// deviceFlowSupported returns true if the token can be got with the
+// device authorization grant.
+//
+// This can't be used if the backend needs to check the result of the
+// authorization as that is only returned by the webserver.
+func deviceFlowSupported(opt *Options) bool {
+	return opt.OAuth2Config.DeviceAuthURL != "" && opt.CheckAuth == nil
+}
+
+// deviceFlowGetToken gets a token using the device authorization
+// grant (RFC 8628).
+//
+// This shows the user a short code to enter at the provider's
+// verification URL on any device, then waits for them to do so. This
+// is much easier than running rclone authorize when configuring rclone
+// on a machine without a web browser.
+func deviceFlowGetToken(ctx context.Context, name string, m configmap.Mapper, oauthConfig *Config, opt *Options) error {
+	ctx = Context(ctx, fshttp.NewClient(ctx))
+	oauth2Conf := oauthConfig.MakeOauth2Config()
+	da, err := oauth2Conf.DeviceAuth(ctx, opt.OAuth2Opts...)
+	if err != nil {
+		return fmt.Errorf("failed to start device authorization: %w", err)
+	}
+	fs.Logf(nil, "On a device with a web browser go to the following link: %s\n", da.VerificationURI)
+	fs.Logf(nil, "Log in and enter this code to authorize rclone for access: %s\n", da.UserCode)
+	if da.VerificationURIComplete != "" {
+		fs.Logf(nil, "Or go to this link which has the code filled in: %s\n", da.VerificationURIComplete)
+	}
+	fs.Logf(nil, "Waiting for authorization...\n")
+	token, err := oauth2Conf.DeviceAccessToken(ctx, da)
+	if err != nil {
+		return fmt.Errorf("device authorization failed: %w", err)
+	}
+	fs.Logf(nil, "Got token\n")
+	return PutToken(name, m, token, true)
+}
+
 // configSetup does the initial creation of the token
 //
 // If opt is nil it will use the default Options.
@@ -0,0 +1,185 @@
+package oauthutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// deviceServer is a fake OAuth server supporting the device
+// authorization grant
+type deviceServer struct {
+	mu      sync.Mutex
+	replies []string    // errors to reply to token requests with in turn, "" for the token
+	polls   []time.Time // when each token request was made
+}
+
+func (s *deviceServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = r.ParseForm()
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/device":
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"device_code":      "device-code",
+			"user_code":        "USER-CODE",
+			"verification_uri": "https://example.com/device",
+			"expires_in":       600,
+			"interval":         1,
+		})
+	case "/token":
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" || r.Form.Get("device_code") != "device-code" {
+			http.Error(w, "bad token request", http.StatusBadRequest)
+			return
+		}
+		s.polls = append(s.polls, time.Now())
+		reply := "access_denied"
+		if len(s.replies) > 0 {
+			reply, s.replies = s.replies[0], s.replies[1:]
+		}
+		if reply != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": reply})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newDeviceServer starts a fake OAuth server replying to token
+// requests with replies and returns the config to use it
+func newDeviceServer(t *testing.T, replies ...string) (*deviceServer, *Config) {
+	s := &deviceServer{replies: replies}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return s, &Config{
+		ClientID:      "client-id",
+		AuthURL:       server.URL + "/auth",
+		TokenURL:      server.URL + "/token",
+		DeviceAuthURL: server.URL + "/device",
+		AuthStyle:     oauth2.AuthStyleInParams, // so each poll is one request
+	}
+}
+
+func TestDeviceFlowGetToken(t *testing.T) {
+	ctx := context.Background()
+
+	// gaps returns the time between each poll of s
+	gaps := func(s *deviceServer) (gaps []time.Duration) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i := 1; i < len(s.polls); i++ {
+			gaps = append(gaps, s.polls[i].Sub(s.polls[i-1]))
+		}
+		return gaps
+	}
+
+	t.Run("AuthorizationPending", func(t *testing.T) {
+		t.Parallel()
+		s, conf := newDeviceServer(t, "authorization_pending", "authorization_pending", "")
+		m := configmap.Simple{}
+		require.NoError(t, deviceFlowGetToken(ctx, "test", m, conf, &Options{OAuth2Config: conf}))
+		assert.Contains(t, m["token"], `"access_token":"access-token"`)
+		got := gaps(s)
+		require.Len(t, got, 2)
+		for _, gap := range got {
+			assert.GreaterOrEqual(t, gap, 900*time.Millisecond)
+			assert.Less(t, gap, 5*time.Second)
+		}
+	})
+
+	t.Run("SlowDown", func(t *testing.T) {
+		t.Parallel()
+		s, conf := newDeviceServer(t, "slow_down", "")
+		m := configmap.Simple{}
+		require.NoError(t, deviceFlowGetToken(ctx, "test", m, conf, &Options{OAuth2Config: conf}))
+		assert.Contains(t, m["token"], `"access_token":"access-token"`)
+		// The interval goes up by 5 seconds after slow_down
+		got := gaps(s)
+		require.Len(t, got, 1)
+		assert.GreaterOrEqual(t, got[0], 5900*time.Millisecond)
+	})
+
+	t.Run("ExpiredToken", func(t *testing.T) {
+		t.Parallel()
+		s, conf := newDeviceServer(t, "authorization_pending", "expired_token", "")
+		m := configmap.Simple{}
+		err := deviceFlowGetToken(ctx, "test", m, conf, &Options{OAuth2Config: conf})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "expired_token")
+		assert.NotContains(t, m, "token")
+		assert.Len(t, gaps(s), 1)
+	})
+}
+
+func TestConfigOAuthDevice(t *testing.T) {
+	ctx := context.Background()
+
+	// config runs ConfigOAuth in state for a backend using opt,
+	// returning the state it goes to
+	config := func(t *testing.T, m configmap.Mapper, opt *Options, state string) string {
+		ri := &fs.RegInfo{
+			Name: "test",
+			Config: func(ctx context.Context, name string, m configmap.Mapper, in fs.ConfigIn) (*fs.ConfigOut, error) {
+				return ConfigOut("", opt)
+			},
+		}
+		out, err := ConfigOAuth(ctx, "test", m, ri, fs.ConfigIn{State: fs.StatePush("", state, "return", "main", "")})
+		require.NoError(t, err)
+		next, _, _ := strings.Cut(out.State, ",")
+		return next
+	}
+
+	t.Run("Supported", func(t *testing.T) {
+		_, conf := newDeviceServer(t, "")
+		m := configmap.Simple{}
+		opt := &Options{OAuth2Config: conf}
+		assert.Equal(t, "*oauth-device", config(t, m, opt, "*oauth-remote"))
+		assert.Equal(t, "*oauth-done", config(t, m, opt, "*oauth-device"))
+		assert.Contains(t, m["token"], `"access_token":"access-token"`)
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		_, conf := newDeviceServer(t, "access_denied")
+		m := configmap.Simple{}
+		opt := &Options{OAuth2Config: conf}
+		assert.Equal(t, "*oauth-remote-authorize", config(t, m, opt, "*oauth-device"))
+		assert.NotContains(t, m, "token")
+	})
+
+	t.Run("NoDeviceAuthURL", func(t *testing.T) {
+		_, conf := newDeviceServer(t)
+		conf.DeviceAuthURL = ""
+		opt := &Options{OAuth2Config: conf}
+		assert.Equal(t, "*oauth-remote-authorize", config(t, configmap.Simple{}, opt, "*oauth-remote"))
+	})
+
+	t.Run("CheckAuth", func(t *testing.T) {
+		_, conf := newDeviceServer(t)
+		opt := &Options{
+			OAuth2Config: conf,
+			CheckAuth: func(*Config, *AuthResult) error {
+				return nil
+			},
+		}
+		assert.Equal(t, "*oauth-remote-authorize", config(t, configmap.Simple{}, opt, "*oauth-remote"))
+	})
+}
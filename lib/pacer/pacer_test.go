@@ -251,6 +251,94 @@ func TestS3Pacer(t *testing.T) {
 	}
 }
 
+func TestAdaptivePacer(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewAdaptive(StartSleep(100*time.Millisecond), MinSleep(time.Millisecond), MaxSleep(time.Second))
+	c.now = func() time.Time { return now }
+	c.Update()
+	ok := State{}
+	retry := State{ConsecutiveRetries: 1}
+
+	// Slow start - the rate goes up by one call/s each call
+	assert.Equal(t, time.Second/11, c.Calculate(ok))
+	assert.Equal(t, time.Second/12, c.Calculate(ok))
+
+	// Make calls as fast as the pacer allows for 2s - the rate
+	// should double roughly every second
+	var sleepTime time.Duration
+	for end := now.Add(2 * time.Second); now.Before(end); now = now.Add(sleepTime) {
+		sleepTime = c.Calculate(ok)
+	}
+	assert.True(t, c.slowStart)
+	assert.Greater(t, c.rate, 30.0)
+	assert.Less(t, c.rate, 60.0)
+
+	// A retry halves the measured rate and ends slow start
+	measured := c.measuredRate(now)
+	got := c.Calculate(retry)
+	assert.False(t, c.slowStart)
+	assert.InDelta(t, measured/2, c.rate, 0.001)
+	assert.Equal(t, time.Duration(float64(time.Second)/c.rate), got)
+
+	// Retries from calls in flight don't reduce it further
+	rate := c.rate
+	now = now.Add(adaptiveHoldoff / 2)
+	c.Calculate(retry)
+	assert.Equal(t, rate, c.rate)
+
+	// But a Retry-After is obeyed
+	got = c.Calculate(State{ConsecutiveRetries: 1, LastError: RetryAfterError(errors.New("slow down"), 5*time.Second)})
+	assert.Equal(t, 5*time.Second, got)
+	assert.Equal(t, rate, c.rate)
+
+	// After the rate goes up by a tenth of the retry rate every second
+	retryRate := c.retryRate
+	start := now
+	for end := now.Add(2 * time.Second); now.Before(end); now = now.Add(sleepTime) {
+		sleepTime = c.Calculate(ok)
+	}
+	assert.InDelta(t, rate+now.Sub(start).Seconds()*retryRate/10, c.rate, 1)
+
+	// A retry after the holdoff reduces the rate again
+	rate = c.rate
+	c.Calculate(retry)
+	assert.Less(t, c.rate, rate)
+
+	// The sleep time is kept between MinSleep and MaxSleep
+	for range 20 {
+		now = now.Add(2 * adaptiveHoldoff)
+		got = c.Calculate(retry)
+	}
+	assert.Equal(t, time.Second, got)
+	c.Update(StartSleep(0))
+	assert.Equal(t, time.Millisecond, c.Calculate(ok))
+
+	// With no start sleep the first retry uses the measured rate
+	c.Update(MinSleep(0))
+	for range 100 {
+		now = now.Add(10 * time.Millisecond)
+		assert.Equal(t, time.Duration(0), c.Calculate(ok))
+	}
+	got = c.Calculate(retry)
+	assert.InDelta(t, 20*time.Millisecond, got, float64(time.Millisecond))
+}
+
+func TestAdaptivePacerFloor(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	floor := NewDefault(MinSleep(100*time.Millisecond), MaxSleep(4*time.Second))
+	c := NewAdaptive(StartSleep(time.Millisecond), MinSleep(time.Millisecond), MaxSleep(time.Second), Floor{floor})
+	c.now = func() time.Time { return now }
+	c.Update()
+
+	// The adaptive sleep is less than the floor's minimum sleep
+	assert.Less(t, c.calculate(State{}), 100*time.Millisecond)
+	assert.Equal(t, 100*time.Millisecond, c.Calculate(State{SleepTime: 100 * time.Millisecond}))
+
+	// And the floor backs off beyond the adaptive MaxSleep
+	got := c.Calculate(State{ConsecutiveRetries: 1, SleepTime: 3 * time.Second})
+	assert.Equal(t, 4*time.Second, got)
+}
+
 func TestEndCall(t *testing.T) {
 	p := New(MaxConnectionsOption(5))
 	emptyTokens(p)
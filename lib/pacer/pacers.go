@@ -1,6 +1,7 @@
 package pacer
 
 import (
+	"math"
 	"math/rand"
 	"time"
 
@@ -18,6 +19,11 @@ type (
 	AttackConstant uint
 	// Burst configures the number of API calls to allow without sleeping
 	Burst int
+	// StartSleep configures the initial sleep time of a Calculator
+	StartSleep time.Duration
+	// Floor configures a Calculator whose sleep time a Calculator
+	// never goes below
+	Floor struct{ Calculator }
 )
 
 // Default is a truncated exponential attack and decay.
@@ -292,3 +298,202 @@ func (c *S3) Calculate(state State) time.Duration {
 	}
 	return sleepTime
 }
+
+// Adaptive is a pacer which probes for the highest rate of calls which
+// doesn't get rate limited.
+//
+// It works like TCP congestion control (AIMD). The rate of calls
+// starts at that set with StartSleep and doubles every second until the
+// first retry. On a retry the rate is halved, using the rate calls were
+// actually being made at if that was lower. After that the rate is
+// increased by a tenth of the rate at the last retry every second so it
+// keeps probing for more.
+//
+// Retries within adaptiveHoldoff of the rate being reduced are assumed
+// to be from calls which were already in flight and don't reduce the
+// rate further.
+//
+// The sleep never goes below that set with MinSleep or above that set
+// with MaxSleep. If a Calculator is set with Floor then the sleep never
+// goes below what it returns either, so the limits of the Calculator
+// it replaces are kept.
+type Adaptive struct {
+	minSleep   time.Duration    // minimum sleep time
+	maxSleep   time.Duration    // maximum sleep time
+	startSleep time.Duration    // initial sleep time
+	floor      Calculator       // sleep never goes below this - may be nil
+	now        func() time.Time // for testing
+
+	rate         float64   // current rate limit in calls/s
+	slowStart    bool      // set until the first retry
+	retryRate    float64   // rate when the last retry reduced it
+	lastDecrease time.Time // when the rate was last reduced
+
+	windowStart time.Time // start of the window the call rate is measured in
+	windowCalls int       // calls made in this window
+	callRate    float64   // rate of calls measured in the last window in calls/s
+}
+
+const (
+	adaptiveHoldoff = time.Second // ignore retries for this long after reducing the rate
+	adaptiveWindow  = time.Second // measure the rate of calls over this long
+)
+
+// AdaptiveOption is the interface implemented by all options for the Adaptive Calculator
+type AdaptiveOption interface {
+	ApplyAdaptive(*Adaptive)
+}
+
+// NewAdaptive returns a new Adaptive Calculator with default values
+func NewAdaptive(opts ...AdaptiveOption) *Adaptive {
+	c := &Adaptive{
+		maxSleep:   2 * time.Second,
+		startSleep: 10 * time.Millisecond,
+		now:        time.Now,
+	}
+	c.Update(opts...)
+	return c
+}
+
+// Update applies the Calculator options and restarts the probing.
+func (c *Adaptive) Update(opts ...AdaptiveOption) {
+	for _, opt := range opts {
+		opt.ApplyAdaptive(c)
+	}
+	c.rate = math.Inf(1)
+	if c.startSleep > 0 {
+		c.rate = float64(time.Second) / float64(c.startSleep)
+	}
+	c.rate = min(c.rate, c.maxRate())
+	c.slowStart = true
+	c.retryRate = 0
+	c.lastDecrease = time.Time{}
+	c.windowStart = c.now()
+	c.windowCalls = 0
+	c.callRate = 0
+}
+
+// ApplyAdaptive updates the value on the Calculator
+func (o MinSleep) ApplyAdaptive(c *Adaptive) {
+	c.minSleep = time.Duration(o)
+}
+
+// ApplyAdaptive updates the value on the Calculator
+func (o MaxSleep) ApplyAdaptive(c *Adaptive) {
+	c.maxSleep = time.Duration(o)
+}
+
+// ApplyAdaptive updates the value on the Calculator
+func (o StartSleep) ApplyAdaptive(c *Adaptive) {
+	c.startSleep = time.Duration(o)
+}
+
+// ApplyAdaptive updates the value on the Calculator
+func (o Floor) ApplyAdaptive(c *Adaptive) {
+	c.floor = o.Calculator
+}
+
+// maxRate returns the rate corresponding to minSleep
+func (c *Adaptive) maxRate() float64 {
+	if c.minSleep <= 0 {
+		return math.Inf(1)
+	}
+	return float64(time.Second) / float64(c.minSleep)
+}
+
+// minRate returns the rate corresponding to maxSleep
+func (c *Adaptive) minRate() float64 {
+	if c.maxSleep <= 0 {
+		return math.Inf(1)
+	}
+	return float64(time.Second) / float64(c.maxSleep)
+}
+
+// measure counts a call and updates the measured call rate
+func (c *Adaptive) measure(now time.Time) {
+	c.windowCalls++
+	elapsed := now.Sub(c.windowStart)
+	if elapsed >= adaptiveWindow {
+		c.callRate = float64(c.windowCalls) / elapsed.Seconds()
+		c.windowStart = now
+		c.windowCalls = 0
+	}
+}
+
+// measuredRate returns the rate calls have been made at recently
+func (c *Adaptive) measuredRate(now time.Time) float64 {
+	if c.callRate > 0 {
+		return c.callRate
+	}
+	elapsed := max(now.Sub(c.windowStart), time.Millisecond)
+	return float64(c.windowCalls) / elapsed.Seconds()
+}
+
+// increase the rate after a successful call
+func (c *Adaptive) increase() {
+	if math.IsInf(c.rate, 1) {
+		return
+	}
+	if c.slowStart {
+		// Called rate times a second so doubles every second
+		c.rate++
+	} else {
+		// Called rate times a second so goes up by a tenth of
+		// retryRate every second
+		c.rate += max(1, c.retryRate/10) / c.rate
+	}
+	// Don't probe far beyond the rate calls are being made at
+	// otherwise the rate grows without limit when the pacer
+	// isn't what is limiting the calls.
+	if c.callRate > 0 {
+		c.rate = min(c.rate, 2*c.callRate+1)
+	}
+	c.rate = min(c.rate, c.maxRate())
+}
+
+// decrease the rate after a retry
+func (c *Adaptive) decrease(now time.Time) {
+	if !c.lastDecrease.IsZero() && now.Sub(c.lastDecrease) < adaptiveHoldoff {
+		return
+	}
+	rate := min(c.rate, c.measuredRate(now))
+	c.retryRate = rate
+	c.rate = max(rate/2, c.minRate())
+	c.slowStart = false
+	c.lastDecrease = now
+}
+
+// sleep returns the sleep time for the current rate
+func (c *Adaptive) sleep() time.Duration {
+	sleepTime := c.minSleep
+	if !math.IsInf(c.rate, 1) {
+		sleepTime = time.Duration(float64(time.Second) / c.rate)
+	}
+	return min(max(sleepTime, c.minSleep), c.maxSleep)
+}
+
+// Calculate takes the current Pacer state and return the wait time until the next try.
+func (c *Adaptive) Calculate(state State) time.Duration {
+	sleepTime := c.calculate(state)
+	if c.floor != nil {
+		// Always call the floor so it keeps its own state
+		sleepTime = max(sleepTime, c.floor.Calculate(state))
+	}
+	return sleepTime
+}
+
+// calculate the sleep time from the rate
+func (c *Adaptive) calculate(state State) time.Duration {
+	now := c.now()
+	c.measure(now)
+	if state.ConsecutiveRetries == 0 {
+		c.increase()
+		return c.sleep()
+	}
+	c.decrease(now)
+	sleepTime := c.sleep()
+	if t, ok := IsRetryAfter(state.LastError); ok && t > sleepTime {
+		return t
+	}
+	return sleepTime
+}
@@ -40,9 +40,19 @@ type Pool struct {
 // will not be controlled.
 var totalMemory *semaphore.Weighted
 
+// totalMemoryMax is the size of the totalMemory semaphore
+var totalMemoryMax int64
+
 // Make sure we initialise the totalMemory semaphore once
 var totalMemoryInit sync.Once
 
+// allPools is all the Pools made so the free buffers in them can be
+// released when the total buffer usage is at its limit
+var (
+	allPoolsMu sync.Mutex
+	allPools   []*Pool
+)
+
 // New makes a buffer pool
 //
 // flushTime is the interval the buffer pools is flushed
@@ -68,9 +78,25 @@ func New(flushTime time.Duration, bufferSize, poolSize int, useMmap bool) *Pool
 		}
 	}
 	bp.timer = time.AfterFunc(flushTime, bp.flushAged)
+	allPoolsMu.Lock()
+	allPools = append(allPools, bp)
+	allPoolsMu.Unlock()
 	return bp
 }
 
+// flushAll frees the buffers cached in all the Pools so the memory
+// they hold can be used by other Pools.
+//
+// Call without any Pool mu held
+func flushAll() {
+	allPoolsMu.Lock()
+	pools := append([]*Pool(nil), allPools...)
+	allPoolsMu.Unlock()
+	for _, bp := range pools {
+		bp.Flush()
+	}
+}
+
 // get gets the last buffer in bp.cache
 //
 // Call with mu held
@@ -164,13 +190,23 @@ func (bp *Pool) acquire(mem int64) error {
 
 		// Set max buffer memory limiter
 		if ci.MaxBufferMemory > 0 {
-			totalMemory = semaphore.NewWeighted(int64(ci.MaxBufferMemory))
+			totalMemoryMax = int64(ci.MaxBufferMemory)
+			totalMemory = semaphore.NewWeighted(totalMemoryMax)
 		}
 	})
 
 	if totalMemory == nil {
 		return nil
 	}
+	// A single buffer bigger than the limit would wait forever so
+	// let it use all of it instead
+	mem = min(mem, totalMemoryMax)
+	if totalMemory.TryAcquire(mem) {
+		return nil
+	}
+	// Free the unused buffers in other pools before waiting for
+	// buffers in use to be returned
+	flushAll()
 	return totalMemory.Acquire(ctx, mem)
 }
 
@@ -179,7 +215,7 @@ func (bp *Pool) release(mem int64) {
 	if totalMemory == nil {
 		return
 	}
-	totalMemory.Release(mem)
+	totalMemory.Release(min(mem, totalMemoryMax))
 }
 
 // Get a buffer from the pool or allocate one
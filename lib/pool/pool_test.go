@@ -239,6 +239,7 @@ func TestPoolMaxBufferMemory(t *testing.T) {
 	}()
 	totalMemoryInit = sync.Once{} // reset the sync.Once as it likely has been used
 	totalMemory = nil
+	allPools = nil // forget pools made without the limit
 	bp := New(60*time.Second, 4096, 2, true)
 
 	assert.Equal(t, bp.alloced, 0)
@@ -280,3 +281,61 @@ func TestPoolMaxBufferMemory(t *testing.T) {
 	assert.Equal(t, maxBufs, 4)
 	assert.Equal(t, bp.alloced, 2)
 }
+
+func TestPoolMaxBufferMemoryFlushesOtherPools(t *testing.T) {
+	ctx := context.Background()
+	ci := fs.GetConfig(ctx)
+	ci.MaxBufferMemory = 2 * 4096
+	defer func() {
+		ci.MaxBufferMemory = 0
+		totalMemory = nil
+	}()
+	totalMemoryInit = sync.Once{} // reset the sync.Once as it likely has been used
+	totalMemory = nil
+	allPools = nil // forget pools made without the limit
+	bp1 := New(60*time.Second, 4096, 2, false)
+	bp2 := New(60*time.Second, 4096, 2, false)
+
+	// Fill bp1's cache using all the memory
+	b1, b2 := bp1.Get(), bp1.Get()
+	bp1.Put(b1)
+	bp1.Put(b2)
+	assert.Equal(t, 2, bp1.InPool())
+
+	// Getting from bp2 must free the cached buffers in bp1
+	// rather than waiting forever
+	done := make(chan struct{})
+	go func() {
+		bp2.Put(bp2.Get())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for buffer")
+	}
+	assert.Equal(t, 0, bp1.InPool())
+	assert.Equal(t, 0, bp1.Alloced())
+}
+
+func TestPoolMaxBufferMemoryTooBig(t *testing.T) {
+	ctx := context.Background()
+	ci := fs.GetConfig(ctx)
+	ci.MaxBufferMemory = 4096
+	defer func() {
+		ci.MaxBufferMemory = 0
+		totalMemory = nil
+	}()
+	totalMemoryInit = sync.Once{} // reset the sync.Once as it likely has been used
+	totalMemory = nil
+	allPools = nil // forget pools made without the limit
+
+	// A buffer bigger than the limit uses all of it
+	bp := New(60*time.Second, 2*4096, 2, false)
+	buf := bp.Get()
+	assert.False(t, totalMemory.TryAcquire(1))
+	bp.Put(buf)
+	bp.Flush()
+	assert.True(t, totalMemory.TryAcquire(4096))
+	totalMemory.Release(4096)
+}
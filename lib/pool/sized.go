@@ -0,0 +1,67 @@
+package pool
+
+import (
+	"context"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+const (
+	minClassSize        = 64 * 1024        // smallest buffer handed out by GetBuffer
+	classCacheMemory    = 64 * 1024 * 1024 // max memory to keep in the cache of each size class
+	classCacheFlushTime = 5 * time.Second  // flush the cached buffers after this long
+)
+
+// sized holds a Pool for each size class of buffer used by GetBuffer
+var sized struct {
+	mu      sync.Mutex
+	classes map[int]*Pool
+}
+
+// classSize returns the size of the size class which holds buffers of
+// size bytes.
+//
+// Sizes are rounded up to one of four sizes between each power of two
+// so at most 25% of a buffer is wasted.
+func classSize(size int) int {
+	if size <= minClassSize {
+		return minClassSize
+	}
+	shift := bits.Len(uint(size-1)) - 3
+	step := 1 << shift
+	return (size + step - 1) &^ (step - 1)
+}
+
+// classPool returns the Pool for buffers of classSize bytes
+func classPool(classSize int) *Pool {
+	sized.mu.Lock()
+	defer sized.mu.Unlock()
+	if sized.classes == nil {
+		sized.classes = make(map[int]*Pool)
+	}
+	bp := sized.classes[classSize]
+	if bp == nil {
+		ci := fs.GetConfig(context.Background())
+		poolSize := max(1, classCacheMemory/classSize)
+		bp = New(classCacheFlushTime, classSize, poolSize, ci.UseMmap)
+		sized.classes[classSize] = bp
+	}
+	return bp
+}
+
+// GetBuffer returns a buffer of length size from a shared pool.
+//
+// The memory used counts towards --max-buffer-memory so this may
+// block until other buffers are returned. Return the buffer with
+// PutBuffer when finished with it and don't use it afterwards.
+func GetBuffer(size int) []byte {
+	return classPool(classSize(size)).Get()[:size]
+}
+
+// PutBuffer returns a buffer got from GetBuffer to the pool.
+func PutBuffer(buf []byte) {
+	classPool(cap(buf)).Put(buf)
+}
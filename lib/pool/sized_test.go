@@ -0,0 +1,54 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassSize(t *testing.T) {
+	const (
+		k = 1024
+		m = 1024 * 1024
+	)
+	for _, test := range []struct {
+		in   int
+		want int
+	}{
+		{0, 64 * k},
+		{1, 64 * k},
+		{64 * k, 64 * k},
+		{64*k + 1, 80 * k},
+		{80 * k, 80 * k},
+		{100 * k, 112 * k},
+		{128 * k, 128 * k},
+		{5 * m, 5 * m},
+		{5*m + 1, 6 * m},
+		{8 * m, 8 * m},
+		{48 * m, 48 * m},
+		{49 * m, 56 * m},
+		{64 * m, 64 * m},
+	} {
+		got := classSize(test.in)
+		assert.Equal(t, test.want, got, test.in)
+		assert.GreaterOrEqual(t, got, test.in)
+		assert.LessOrEqual(t, got, max(64*k, test.in+test.in/4), test.in)
+	}
+}
+
+func TestGetPutBuffer(t *testing.T) {
+	buf := GetBuffer(100 * 1024)
+	assert.Equal(t, 100*1024, len(buf))
+	assert.Equal(t, 112*1024, cap(buf))
+	bp := classPool(112 * 1024)
+	assert.Equal(t, 1, bp.InUse())
+	PutBuffer(buf)
+	assert.Equal(t, 0, bp.InUse())
+	assert.Equal(t, 1, bp.InPool())
+
+	// The buffer is reused for a different size in the same class
+	buf2 := GetBuffer(110 * 1024)
+	assert.Equal(t, &buf[0], &buf2[0])
+	PutBuffer(buf2)
+	bp.Flush()
+}
@@ -2,8 +2,6 @@ package readers
 
 import (
 	"io"
-
-	"github.com/rclone/rclone/fs"
 )
 
 // LimitedReadCloser adds io.Closer to io.LimitedReader.  Create one with NewLimitedReadCloser
@@ -16,7 +14,7 @@ type LimitedReadCloser struct {
 func (lrc *LimitedReadCloser) Close() error {
 	err := lrc.Closer.Close()
 	if err != nil && lrc.N == 0 {
-		fs.Debugf(nil, "ignoring close error because we already got all the data")
+		// ignore the close error because we already got all the data
 		err = nil
 	}
 	return err
@@ -0,0 +1,175 @@
+// Package restore implements restoring objects from archive storage
+// classes and copying them once they can be read.
+//
+// This is shared by the backends with archive storage, which supply
+// the calls to read the restore state of an object and to start
+// restoring it.
+package restore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"golang.org/x/sync/errgroup"
+)
+
+// State is how far an archived object is from being readable
+type State int
+
+// States of an archived object
+const (
+	Needed  State = iota // archived and not being restored
+	Ongoing              // being restored
+	Done                 // readable
+)
+
+// The longest time to wait between checking on restores
+const maxPollInterval = 30 * time.Minute
+
+// Options for Copy
+type Options struct {
+	Verb         string        // what restoring is called in messages, e.g. "restore"
+	Noun         string        // the noun for restoring, e.g. "restore" or "rehydration"
+	Past         string        // the past tense of Verb, e.g. "restored"
+	Ongoing      string        // status of objects being restored, e.g. "Restoring"
+	PollInterval time.Duration // initial time between checking on restores
+	MaxWait      time.Duration // time to wait for restores, 0 for no limit
+
+	// State reads the restore state of o
+	State func(ctx context.Context, o fs.Object) (State, error)
+
+	// Start starts restoring o
+	Start func(ctx context.Context, o fs.Object) error
+}
+
+// Status of an object, returned from Copy
+type Status struct {
+	Remote string
+	Status string
+	o      fs.Object
+}
+
+// Copy restores the archived objects in f, waiting for the restores
+// to finish, then copies them to dstFs if they have changed.
+//
+// It returns the status of each object in f.
+func Copy(ctx context.Context, f fs.Fs, dstFs fs.Fs, opt Options) (out []*Status, err error) {
+	var outMu sync.Mutex
+	out = []*Status{}
+	err = operations.ListFn(ctx, f, func(o fs.Object) {
+		outMu.Lock()
+		out = append(out, &Status{Remote: o.Remote(), o: o})
+		outMu.Unlock()
+	})
+	if err != nil {
+		return out, err
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Remote < out[j].Remote
+	})
+
+	pending := out
+	pollInterval := opt.PollInterval
+	start := time.Now()
+	for {
+		var ready []*Status
+		ready, pending = opt.check(ctx, pending)
+		copyReady(ctx, dstFs, ready)
+		if len(pending) == 0 {
+			break
+		}
+		if opt.MaxWait > 0 && time.Since(start)+pollInterval > opt.MaxWait {
+			for _, st := range pending {
+				st.Status = "Timed out waiting for " + opt.Noun
+				err := fmt.Errorf("timed out waiting for %s after %v", opt.Noun, fs.Duration(time.Since(start)))
+				fs.Errorf(st.o, "%v", fs.CountError(ctx, err))
+			}
+			break
+		}
+		fs.Infof(f, "Waiting %v for %d objects to be %s", fs.Duration(pollInterval), len(pending), opt.Past)
+		select {
+		case <-ctx.Done():
+			return out, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+		pollInterval = min(2*pollInterval, max(pollInterval, maxPollInterval))
+	}
+	return out, nil
+}
+
+// check checks the restore state of the objects in sts, starting
+// restores of the ones which need it. It returns the objects which
+// can be read and the objects which are still being restored.
+func (opt *Options) check(ctx context.Context, sts []*Status) (ready, pending []*Status) {
+	var mu sync.Mutex
+	failed := strings.ToUpper(opt.Verb[:1]) + opt.Verb[1:] + " failed"
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(fs.GetConfig(ctx).Checkers)
+	for _, st := range sts {
+		g.Go(func() error {
+			state, err := opt.State(gCtx, st.o)
+			if err == nil && state == Needed {
+				if operations.SkipDestructive(gCtx, st.o, opt.Verb) {
+					st.Status = "Not " + opt.Past
+					return nil
+				}
+				err = opt.Start(gCtx, st.o)
+				if err == nil {
+					state = Ongoing
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				st.Status = fmt.Sprintf("%s: %v", failed, err)
+				fs.Errorf(st.o, "%s: %v", failed, fs.CountError(gCtx, err))
+			case state == Ongoing:
+				st.Status = opt.Ongoing
+				pending = append(pending, st)
+			default:
+				ready = append(ready, st)
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return ready, pending
+}
+
+// copyReady copies the objects in sts to dstFs if they have changed
+func copyReady(ctx context.Context, dstFs fs.Fs, sts []*Status) {
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(fs.GetConfig(ctx).Transfers)
+	for _, st := range sts {
+		g.Go(func() error {
+			dst, err := dstFs.NewObject(gCtx, st.Remote)
+			if errors.Is(err, fs.ErrorObjectNotFound) {
+				dst = nil
+			} else if err != nil {
+				st.Status = fmt.Sprintf("Copy failed: %v", err)
+				fs.Errorf(st.o, "Copy failed: %v", fs.CountError(gCtx, err))
+				return nil
+			}
+			if dst != nil && !operations.NeedTransfer(gCtx, dst, st.o) {
+				st.Status = "Unchanged"
+				return nil
+			}
+			_, err = operations.Copy(gCtx, dstFs, dst, st.Remote, st.o)
+			if err != nil {
+				st.Status = fmt.Sprintf("Copy failed: %v", err)
+				return nil
+			}
+			st.Status = "Copied"
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
@@ -0,0 +1,99 @@
+package restore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/memory"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var t1 = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func put(ctx context.Context, t *testing.T, f fs.Fs, remote, content string) {
+	src := object.NewStaticObjectInfo(remote, t1, int64(len(content)), true, nil, nil)
+	_, err := f.Put(ctx, bytes.NewBufferString(content), src)
+	require.NoError(t, err)
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	srcFs, err := fs.NewFs(ctx, ":memory:src")
+	require.NoError(t, err)
+	dstFs, err := fs.NewFs(ctx, ":memory:dst")
+	require.NoError(t, err)
+	for _, remote := range []string{"archived", "broken", "readable", "slow", "unchanged"} {
+		put(ctx, t, srcFs, remote, remote)
+	}
+	put(ctx, t, dstFs, "unchanged", "unchanged")
+
+	var (
+		mu      sync.Mutex
+		started = map[string]int{}
+		checks  = map[string]int{}
+	)
+	opt := Options{
+		Verb:         "restore",
+		Noun:         "restore",
+		Past:         "restored",
+		Ongoing:      "Restoring",
+		PollInterval: time.Millisecond,
+		MaxWait:      100 * time.Millisecond,
+		State: func(ctx context.Context, o fs.Object) (State, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			remote := o.Remote()
+			checks[remote]++
+			switch remote {
+			case "archived":
+				if started[remote] == 0 {
+					return Needed, nil
+				}
+				return Done, nil
+			case "broken":
+				return Needed, nil
+			case "slow":
+				return Ongoing, nil
+			}
+			return Done, nil
+		},
+		Start: func(ctx context.Context, o fs.Object) error {
+			mu.Lock()
+			defer mu.Unlock()
+			started[o.Remote()]++
+			if o.Remote() == "broken" {
+				return errors.New("boom")
+			}
+			return nil
+		},
+	}
+
+	out, err := Copy(ctx, srcFs, dstFs, opt)
+	require.NoError(t, err)
+	got := map[string]string{}
+	for _, st := range out {
+		got[st.Remote] = st.Status
+	}
+	assert.Equal(t, map[string]string{
+		"archived":  "Copied",
+		"broken":    "Restore failed: boom",
+		"readable":  "Copied",
+		"slow":      "Timed out waiting for restore",
+		"unchanged": "Unchanged",
+	}, got)
+	assert.Equal(t, 1, started["archived"])
+	assert.Equal(t, 1, checks["readable"])
+	assert.Greater(t, checks["slow"], 1)
+
+	_, err = dstFs.NewObject(ctx, "archived")
+	assert.NoError(t, err)
+	_, err = dstFs.NewObject(ctx, "slow")
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+}
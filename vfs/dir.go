@@ -264,6 +264,12 @@ func (d *Dir) ForgetAll() (hasVirtual bool) {
 //
 // It does not invalidate or clear the cache of the parent directory.
 func (d *Dir) forgetDirPath(relativePath string) {
+	if d.vfs.persist != nil {
+		d.mu.RLock()
+		absPath := path.Join(d.path, relativePath)
+		d.mu.RUnlock()
+		d.vfs.persist.purge(absPath, true)
+	}
 	dir := d.cachedDir(relativePath)
 	if dir == nil {
 		return
@@ -273,6 +279,9 @@ func (d *Dir) forgetDirPath(relativePath string) {
 
 // invalidateDir invalidates the directory cache for absPath relative to the root
 func (d *Dir) invalidateDir(absPath string) {
+	if d.vfs.persist != nil {
+		d.vfs.persist.purge(absPath, false)
+	}
 	node := d.vfs.root.cachedNode(absPath)
 	if dir, ok := node.(*Dir); ok {
 		dir.mu.Lock()
@@ -420,6 +429,11 @@ func (d *Dir) rename(newParent *Dir, fsDir fs.Directory) {
 	// Rename any remaining items in the tree that we couldn't forget
 	d.renameTree(d.path)
 
+	if d.vfs.persist != nil {
+		d.vfs.persist.purge(oldPath, true)
+		d.vfs.persist.purge(newPath, true)
+	}
+
 	// Rename in the cache
 	if d.vfs.cache != nil && d.vfs.cache.DirExists(oldPath) {
 		if err := d.vfs.cache.DirRename(oldPath, newPath); err != nil {
@@ -460,6 +474,7 @@ func (d *Dir) addObject(node Node) {
 	d.virtual[leaf] = vAdd
 	fs.Debugf(d.path, "Added virtual directory entry %v: %q", vAdd, leaf)
 	d.mu.Unlock()
+	d.purgePersist()
 }
 
 // AddVirtual adds a virtual object of name and size to the directory
@@ -516,6 +531,19 @@ func (d *Dir) delObject(leaf string) {
 	d.virtual[leaf] = vDel
 	fs.Debugf(d.path, "Added virtual directory entry %v: %q", vDel, leaf)
 	d.mu.Unlock()
+	d.purgePersist()
+}
+
+// purgePersist removes the directory from the persistent directory
+// cache as it has been changed through the VFS
+func (d *Dir) purgePersist() {
+	if d.vfs.persist == nil {
+		return
+	}
+	d.mu.RLock()
+	dirPath := d.path
+	d.mu.RUnlock()
+	d.vfs.persist.purge(dirPath, false)
 }
 
 // DelVirtual removes an object from the directory listing
@@ -539,6 +567,23 @@ func (d *Dir) _readDir() error {
 	} else {
 		return nil
 	}
+	if d.read.IsZero() && d.vfs.persist != nil {
+		if entries := d.vfs.persist.get(d.f, d.path); entries != nil {
+			err := d._readDirFromEntries(entries, nil, time.Time{})
+			if err == nil {
+				d.read = when
+				d.cleanupTimer.Reset(time.Duration(d.vfs.Opt.DirCacheTime * 2))
+				return nil
+			}
+		}
+	}
+	return d._readDirFromRemote()
+}
+
+// read the directory from the remote and sets d.items - must be
+// called with the lock held
+func (d *Dir) _readDirFromRemote() error {
+	when := time.Now()
 	entries, err := list.DirSorted(context.TODO(), d.f, false, d.path)
 	if err == fs.ErrorDirNotFound {
 		// We treat directory not found as empty because we
@@ -580,6 +625,9 @@ func (d *Dir) _readDir() error {
 	if err != nil {
 		return err
 	}
+	if d.vfs.persist != nil {
+		d.vfs.persist.put(d.path, entries, when)
+	}
 
 	d.read = time.Now()
 	d.cleanupTimer.Reset(time.Duration(d.vfs.Opt.DirCacheTime * 2))
@@ -648,7 +696,7 @@ func (d *Dir) _purgeVirtual() {
 				// if writing in progress then leave virtual
 				continue
 			}
-			if d.vfs.Opt.CacheMode >= vfscommon.CacheModeMinimal && d.vfs.cache.InUse(f.CachePath()) {
+			if d.vfs.cacheMode(f.Path()) >= vfscommon.CacheModeMinimal && d.vfs.cache.InUse(f.CachePath()) {
 				// if object in use or dirty then leave virtual
 				continue
 			}
@@ -805,6 +853,9 @@ func (d *Dir) readDirTree() error {
 	if err != nil {
 		return err
 	}
+	if d.vfs.persist != nil {
+		d.vfs.persist.putDirs(dt, when)
+	}
 	fs.Debugf(d.path, "Reading directory tree done in %s", time.Since(when))
 	d.read = when
 	d.cleanupTimer.Reset(time.Duration(d.vfs.Opt.DirCacheTime * 2))
@@ -816,7 +867,7 @@ func (d *Dir) readDir() error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	d.read = time.Time{}
-	return d._readDir()
+	return d._readDirFromRemote()
 }
 
 // jsonErrorf formats the string according to a format specifier and
@@ -265,6 +265,13 @@ func (f *File) rename(ctx context.Context, destDir *Dir, newName string) error {
 				return nil // no need to rename
 			}
 
+			// server-side Move needs the object from the remote
+			o, err = unwrapPersistObject(ctx, o)
+			if err != nil {
+				fs.Errorf(f.Path(), "File.Rename error: %v", err)
+				return err
+			}
+
 			// do the move of the remote object
 			dstOverwritten, _ := d.Fs().NewObject(ctx, newPath)
 			newObject, err = operations.Move(ctx, d.Fs(), dstOverwritten, newPath, o)
@@ -310,7 +317,7 @@ func (f *File) rename(ctx context.Context, destDir *Dir, newName string) error {
 
 	// Delay the rename if not using RW caching. For the minimal case we
 	// need to look in the cache to see if caching is in use.
-	CacheMode := d.vfs.Opt.CacheMode
+	CacheMode := d.vfs.cacheMode(oldPath)
 	if writing &&
 		(CacheMode < vfscommon.CacheModeMinimal ||
 			(CacheMode == vfscommon.CacheModeMinimal && !destDir.vfs.cache.Exists(oldPath))) {
@@ -395,7 +402,7 @@ func (f *File) ModTime() (modTime time.Time) {
 		return d.ModTime()
 	}
 	// Read the modtime from a dirty item if it exists
-	if f.d.vfs.Opt.CacheMode >= vfscommon.CacheModeMinimal {
+	if f.d.vfs.cacheMode(f._path()) >= vfscommon.CacheModeMinimal {
 		if item := f.d.vfs.cache.DirtyItem(f._cachePath()); item != nil {
 			modTime, err := item.GetModTime()
 			if err != nil {
@@ -432,7 +439,7 @@ func (f *File) Size() int64 {
 	defer f.mu.RUnlock()
 
 	// Read the size from a dirty item if it exists
-	if f.d.vfs.Opt.CacheMode >= vfscommon.CacheModeMinimal {
+	if f.d.vfs.cacheMode(f._path()) >= vfscommon.CacheModeMinimal {
 		if item := f.d.vfs.cache.DirtyItem(f._cachePath()); item != nil {
 			size, err := item.GetSize()
 			if err != nil {
@@ -890,7 +897,7 @@ func (f *File) Open(flags int) (fd Handle, err error) {
 	f.mu.RLock()
 	d := f.d
 	f.mu.RUnlock()
-	CacheMode := d.vfs.Opt.CacheMode
+	CacheMode := d.vfs.cacheMode(f.Path())
 	if CacheMode >= vfscommon.CacheModeMinimal && (d.vfs.cache.InUse(f.CachePath()) || d.vfs.cache.Exists(f.CachePath())) {
 		fd, err = f.openRW(flags)
 	} else if read && write {
@@ -0,0 +1,458 @@
+// Persistent directory cache
+
+package vfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/dirtree"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/kv"
+)
+
+// persistFacility is the name of the database the directory listings
+// are kept in
+const persistFacility = "vfsdir"
+
+// dirCachePersist keeps directory listings in a database so they
+// survive the VFS being restarted
+//
+// Listings are written when they are read from the remote and used
+// when a directory hasn't been read yet. The objects in them are
+// checked against the remote the first time they are used.
+//
+// Modification times which are slow to read aren't stored but are
+// read from the remote when they are needed.
+type dirCachePersist struct {
+	vfs    *VFS
+	db     *kv.DB
+	prefix string // prefix of the keys for this VFS
+
+	mu     sync.Mutex
+	purged map[string]struct{} // directories known not to be in the database
+}
+
+// persistRecord is a directory listing as stored in the database
+type persistRecord struct {
+	Read    time.Time      // when the listing was read from the remote
+	Entries []persistEntry // the entries in the directory
+}
+
+// persistEntry is a directory entry as stored in the database
+type persistEntry struct {
+	Leaf        string
+	IsDir       bool
+	Size        int64
+	ModTime     time.Time
+	ID          string // directory ID if known
+	Fingerprint string // fingerprint of the object
+}
+
+// newDirCachePersist opens the persistent directory cache for vfs
+func newDirCachePersist(vfs *VFS) (*dirCachePersist, error) {
+	db, err := kv.Start(context.TODO(), persistFacility, vfs.f)
+	if err != nil {
+		return nil, err
+	}
+	return &dirCachePersist{
+		vfs:    vfs,
+		db:     db,
+		prefix: fs.ConfigString(vfs.f) + "\x00",
+		purged: make(map[string]struct{}),
+	}, nil
+}
+
+// key returns the database key for dirPath
+func (p *dirCachePersist) key(dirPath string) string {
+	return p.prefix + dirPath
+}
+
+// close the database
+func (p *dirCachePersist) close() {
+	err := p.db.Stop(false)
+	if err != nil {
+		fs.Errorf(nil, "vfs: failed to close directory cache database: %v", err)
+	}
+}
+
+// kvGetDir reads a listing from the database
+type kvGetDir struct {
+	key  string
+	data []byte
+}
+
+func (op *kvGetDir) Do(ctx context.Context, b kv.Bucket) error {
+	if data := b.Get([]byte(op.key)); data != nil {
+		op.data = bytes.Clone(data)
+	}
+	return nil
+}
+
+// kvPutDirs writes listings to the database, indexed by key
+type kvPutDirs struct {
+	dirs map[string][]byte
+}
+
+func (op *kvPutDirs) Do(ctx context.Context, b kv.Bucket) error {
+	for key, data := range op.dirs {
+		if err := b.Put([]byte(key), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kvPurgeDir removes the listings of a directory and optionally
+// everything below it from the database
+type kvPurgeDir struct {
+	key       string
+	recursive bool
+}
+
+func (op *kvPurgeDir) Do(ctx context.Context, b kv.Bucket) error {
+	if err := b.Delete([]byte(op.key)); err != nil {
+		return err
+	}
+	if !op.recursive {
+		return nil
+	}
+	dir := op.key
+	if !strings.HasSuffix(dir, "\x00") {
+		dir += "/"
+	}
+	var keys [][]byte
+	cur := b.Cursor()
+	for bkey, _ := cur.Seek([]byte(dir)); bkey != nil && bytes.HasPrefix(bkey, []byte(dir)); bkey, _ = cur.Next() {
+		keys = append(keys, bytes.Clone(bkey))
+	}
+	for _, bkey := range keys {
+		if err := b.Delete(bkey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// get returns the listing of dirPath from the database or nil if it
+// isn't there or is older than --vfs-dir-cache-persist-max-age
+func (p *dirCachePersist) get(f fs.Fs, dirPath string) fs.DirEntries {
+	op := &kvGetDir{key: p.key(dirPath)}
+	err := p.db.Do(false, op)
+	if err != nil && !errors.Is(err, kv.ErrEmpty) {
+		fs.Errorf(dirPath, "vfs: failed to read directory cache database: %v", err)
+		return nil
+	}
+	if op.data == nil {
+		return nil
+	}
+	var record persistRecord
+	if err := gob.NewDecoder(bytes.NewReader(op.data)).Decode(&record); err != nil {
+		fs.Errorf(dirPath, "vfs: failed to decode directory from database: %v", err)
+		return nil
+	}
+	if age := time.Since(record.Read); age > time.Duration(p.vfs.Opt.DirPersistMaxAge) {
+		fs.Debugf(dirPath, "Ignoring persisted directory listing as it is %v old", age)
+		return nil
+	}
+	entries := make(fs.DirEntries, 0, len(record.Entries))
+	for _, e := range record.Entries {
+		remote := path.Join(dirPath, e.Leaf)
+		if e.IsDir {
+			entries = append(entries, fs.NewDir(remote, e.ModTime).SetSize(e.Size).SetID(e.ID))
+		} else {
+			entries = append(entries, &persistObject{
+				p:           p,
+				f:           f,
+				remote:      remote,
+				size:        e.Size,
+				modTime:     e.ModTime,
+				fingerprint: e.Fingerprint,
+			})
+		}
+	}
+	fs.Debugf(dirPath, "Read %d entries from persisted directory listing", len(entries))
+	return entries
+}
+
+// put writes the listing of dirPath to the database
+func (p *dirCachePersist) put(dirPath string, entries fs.DirEntries, when time.Time) {
+	p.putDirs(dirtree.DirTree{dirPath: entries}, when)
+}
+
+// putDirs writes the listings of all the directories in dirs to the
+// database in one transaction
+func (p *dirCachePersist) putDirs(dirs dirtree.DirTree, when time.Time) {
+	op := &kvPutDirs{dirs: make(map[string][]byte, len(dirs))}
+	for dirPath, entries := range dirs {
+		data, err := encodeDir(entries, when)
+		if err != nil {
+			fs.Errorf(dirPath, "vfs: failed to encode directory for database: %v", err)
+			continue
+		}
+		op.dirs[p.key(dirPath)] = data
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err := p.db.Do(true, op)
+	if err != nil {
+		fs.Errorf(nil, "vfs: failed to write directory cache database: %v", err)
+		return
+	}
+	for dirPath := range dirs {
+		delete(p.purged, dirPath)
+	}
+}
+
+// encodeDir encodes the listing entries read at when for the database
+func encodeDir(entries fs.DirEntries, when time.Time) ([]byte, error) {
+	ctx := context.TODO()
+	record := persistRecord{
+		Read:    when,
+		Entries: make([]persistEntry, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		e := persistEntry{
+			Leaf: path.Base(entry.Remote()),
+			Size: entry.Size(),
+		}
+		switch x := entry.(type) {
+		case fs.Directory:
+			e.IsDir = true
+			e.ID = x.ID()
+			e.ModTime = x.ModTime(ctx)
+		case *persistObject:
+			// not checked against the remote yet
+			e.ModTime = x.modTime
+			e.Fingerprint = x.fingerprint
+		case fs.Object:
+			// Don't read the modtime if it needs another transaction
+			if !x.Fs().Features().SlowModTime {
+				e.ModTime = x.ModTime(ctx)
+			}
+			e.Fingerprint = fs.Fingerprint(ctx, x, true)
+		}
+		record.Entries = append(record.Entries, e)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&record); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// purge removes the listing of dirPath and if recursive is set all
+// the directories below it from the database
+//
+// Directories are only removed once until they are written again, so
+// changing many entries in a directory only writes to the database
+// once.
+func (p *dirCachePersist) purge(dirPath string, recursive bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, found := p.purged[dirPath]; found && !recursive {
+		return
+	}
+	err := p.db.Do(true, &kvPurgeDir{key: p.key(dirPath), recursive: recursive})
+	if err != nil && !errors.Is(err, kv.ErrEmpty) {
+		fs.Errorf(dirPath, "vfs: failed to remove directory from cache database: %v", err)
+		return
+	}
+	p.purged[dirPath] = struct{}{}
+}
+
+// persistObject is an object read from the persistent directory cache
+//
+// It is only looked up on the remote when something other than its
+// name, size and modification time is needed. If its fingerprint has
+// changed then the directory it is in is invalidated.
+type persistObject struct {
+	p           *dirCachePersist
+	f           fs.Fs
+	remote      string
+	size        int64
+	modTime     time.Time
+	fingerprint string
+
+	mu  sync.Mutex
+	o   fs.Object // the object on the remote once found
+	err error     // error finding it
+}
+
+// object returns the object on the remote, finding it if necessary
+func (o *persistObject) object(ctx context.Context) (fs.Object, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.o != nil || o.err != nil {
+		return o.o, o.err
+	}
+	obj, err := o.f.NewObject(ctx, o.remote)
+	switch {
+	case errors.Is(err, fs.ErrorObjectNotFound):
+		fs.Debugf(o.remote, "Persisted object not found on remote")
+		o.err = err
+	case err != nil:
+		return nil, err
+	default:
+		o.o = obj
+		fingerprint := fs.Fingerprint(ctx, obj, true)
+		if fingerprint == o.fingerprint {
+			return obj, nil
+		}
+		fs.Debugf(o.remote, "Persisted object has changed on remote: %q != %q", fingerprint, o.fingerprint)
+	}
+	// Re-read the directory next time it is used
+	go o.p.vfs.changeNotify(o.remote, fs.EntryObject)
+	return o.o, o.err
+}
+
+// unwrapPersistObject returns the object on the remote if o came
+// from the persistent directory cache or o otherwise
+func unwrapPersistObject(ctx context.Context, o fs.Object) (fs.Object, error) {
+	if po, ok := o.(*persistObject); ok {
+		return po.object(ctx)
+	}
+	return o, nil
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *persistObject) Fs() fs.Info {
+	return o.f
+}
+
+// String returns the remote path
+func (o *persistObject) String() string {
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *persistObject) Remote() string {
+	return o.remote
+}
+
+// found returns the object on the remote if it has been found
+func (o *persistObject) found() fs.Object {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.o
+}
+
+// ModTime returns the modification date of the object
+//
+// If it wasn't stored then it is read from the remote.
+func (o *persistObject) ModTime(ctx context.Context) time.Time {
+	if obj := o.found(); obj != nil {
+		return obj.ModTime(ctx)
+	}
+	if o.modTime.IsZero() {
+		if obj, err := o.object(ctx); err == nil && obj != nil {
+			return obj.ModTime(ctx)
+		}
+	}
+	return o.modTime
+}
+
+// Size returns the size of the object
+func (o *persistObject) Size() int64 {
+	if obj := o.found(); obj != nil {
+		return obj.Size()
+	}
+	return o.size
+}
+
+// Hash returns the selected checksum of the object
+func (o *persistObject) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return "", err
+	}
+	return obj.Hash(ctx, ht)
+}
+
+// Storable says whether this object can be stored
+func (o *persistObject) Storable() bool {
+	return true
+}
+
+// SetModTime sets the modification time of the object
+func (o *persistObject) SetModTime(ctx context.Context, modTime time.Time) error {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return err
+	}
+	return obj.SetModTime(ctx, modTime)
+}
+
+// Open opens the object for reading
+func (o *persistObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Open(ctx, options...)
+}
+
+// Update the object with the contents of in
+func (o *persistObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return err
+	}
+	return obj.Update(ctx, in, src, options...)
+}
+
+// Remove the object
+func (o *persistObject) Remove(ctx context.Context) error {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return err
+	}
+	return obj.Remove(ctx)
+}
+
+// Metadata returns the metadata of the object
+func (o *persistObject) Metadata(ctx context.Context) (fs.Metadata, error) {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.GetMetadata(ctx, obj)
+}
+
+// SetMetadata sets metadata of the object
+func (o *persistObject) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return err
+	}
+	do, ok := obj.(fs.SetMetadataer)
+	if !ok {
+		return fs.ErrorNotImplemented
+	}
+	return do.SetMetadata(ctx, metadata)
+}
+
+// UnWrap returns the object on the remote
+func (o *persistObject) UnWrap() fs.Object {
+	obj, err := o.object(context.TODO())
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+// Check interfaces
+var (
+	_ fs.Object          = (*persistObject)(nil)
+	_ fs.Metadataer      = (*persistObject)(nil)
+	_ fs.SetMetadataer   = (*persistObject)(nil)
+	_ fs.ObjectUnWrapper = (*persistObject)(nil)
+)
@@ -0,0 +1,133 @@
+package vfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listNames returns the names in dirPath
+func listNames(t *testing.T, vfs *VFS, dirPath string) (names []string) {
+	fis, err := vfs.ReadDir(dirPath)
+	require.NoError(t, err)
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	return names
+}
+
+func TestDirCachePersist(t *testing.T) {
+	oldCacheDir := config.GetCacheDir()
+	require.NoError(t, config.SetCacheDir(t.TempDir()))
+	defer func() {
+		_ = config.SetCacheDir(oldCacheDir)
+	}()
+	ctx := context.Background()
+	opt := vfscommon.Opt
+	opt.DirCachePersist = true
+	opt.PollInterval = 0
+	r, vfs1 := newTestVFSOpt(t, &opt)
+	require.NotNil(t, vfs1.persist)
+
+	file1 := r.WriteObject(ctx, "dir/file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+	assert.Equal(t, []string{"file1"}, listNames(t, vfs1, "dir"))
+	assert.Equal(t, []string{"dir"}, listNames(t, vfs1, ""))
+
+	// Change the remote behind the VFS
+	r.WriteObject(ctx, "dir/file1", "file1 contents changed", t2)
+	r.WriteObject(ctx, "dir/file2", "file2 contents", t1)
+
+	// A new VFS uses the persisted listings
+	opt.DirCacheTime = fs.Duration(time.Hour)
+	vfs2 := New(r.Fremote, &opt)
+	defer cleanupVFS(t, vfs2)
+	require.NotNil(t, vfs2.persist)
+	assert.Equal(t, []string{"file1"}, listNames(t, vfs2, "dir"))
+	node, err := vfs2.Stat("dir/file1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(14), node.Size())
+	_, isPersisted := node.DirEntry().(*persistObject)
+	assert.True(t, isPersisted)
+
+	// Using the changed object invalidates the directory. The read
+	// may fail as the file has changed size.
+	_, _ = vfs2.ReadFile("dir/file1")
+	assert.Eventually(t, func() bool {
+		return len(listNames(t, vfs2, "dir")) == 2
+	}, 10*time.Second, 10*time.Millisecond)
+	node, err = vfs2.Stat("dir/file1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(22), node.Size())
+
+	// Listings which are too old aren't used
+	vfs1.persist.put("old", nil, time.Now().Add(-2*time.Duration(opt.DirPersistMaxAge)))
+	assert.Nil(t, vfs1.persist.get(r.Fremote, "old"))
+
+	// Flushing the directory cache purges it
+	assert.NotNil(t, vfs1.persist.get(r.Fremote, "dir"))
+	vfs1.FlushDirCache()
+	assert.Nil(t, vfs1.persist.get(r.Fremote, "dir"))
+	assert.Nil(t, vfs1.persist.get(r.Fremote, ""))
+}
+
+func TestDirCachePersistChanges(t *testing.T) {
+	oldCacheDir := config.GetCacheDir()
+	require.NoError(t, config.SetCacheDir(t.TempDir()))
+	defer func() {
+		_ = config.SetCacheDir(oldCacheDir)
+	}()
+	opt := vfscommon.Opt
+	opt.DirCachePersist = true
+	_, vfs := newTestVFSOpt(t, &opt)
+
+	require.NoError(t, vfs.Mkdir("dir", 0777))
+	assert.Empty(t, listNames(t, vfs, "dir"))
+	assert.NotNil(t, vfs.persist.get(vfs.f, "dir"))
+
+	// Changing a directory through the VFS removes it from the
+	// persistent cache
+	require.NoError(t, vfs.WriteFile("dir/file1", []byte("hello"), 0666))
+	assert.Nil(t, vfs.persist.get(vfs.f, "dir"))
+	assert.Contains(t, vfs.persist.purged, "dir")
+
+	// Until it is written again
+	assert.Equal(t, []string{"file1"}, listNames(t, vfs, "dir"))
+	vfs.FlushDirCache()
+	assert.Equal(t, []string{"file1"}, listNames(t, vfs, "dir"))
+	assert.NotNil(t, vfs.persist.get(vfs.f, "dir"))
+	assert.NotContains(t, vfs.persist.purged, "dir")
+}
+
+func TestDirCachePersistSlowModTime(t *testing.T) {
+	ctx := context.Background()
+	f, err := mockfs.NewFs(ctx, "mock", "", nil)
+	require.NoError(t, err)
+	o := mockobject.New("file").WithContent([]byte("hello"), mockobject.SeekModeNone)
+	o.SetFs(f)
+	require.NoError(t, o.SetModTime(ctx, t1))
+
+	modTime := func() time.Time {
+		data, err := encodeDir(fs.DirEntries{o}, time.Now())
+		require.NoError(t, err)
+		var record persistRecord
+		require.NoError(t, gob.NewDecoder(bytes.NewReader(data)).Decode(&record))
+		require.Len(t, record.Entries, 1)
+		return record.Entries[0].ModTime
+	}
+	assert.True(t, modTime().Equal(t1))
+
+	// Modtimes which need another transaction aren't stored
+	f.Features().SlowModTime = true
+	assert.True(t, modTime().IsZero())
+}
@@ -0,0 +1,68 @@
+package vfs
+
+import (
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/ranges"
+)
+
+const (
+	// number of sequential reads in a row needed before prefetching
+	prefetchMinSequential = 4
+	// how far a read can start from the end of the last one and
+	// still be sequential, as the kernel may reorder reads a bit
+	prefetchSlack = int64(fs.Mebi)
+)
+
+// prefetcher detects sequential reads on a file handle and works out
+// which chunks should be fetched ahead of them
+//
+// The number of chunks fetched ahead starts at one and doubles each
+// time the reads use up half of them, up to maxChunks.
+type prefetcher struct {
+	chunkSize int64 // size of the chunks to fetch
+	maxChunks int   // max number of chunks to fetch ahead
+	next      int64 // offset a sequential read would start at
+	seq       int   // number of sequential reads in a row
+	chunks    int   // number of chunks being fetched ahead
+	ahead     int64 // offset chunks have been fetched up to
+}
+
+// newPrefetcher makes a prefetcher fetching up to maxChunks chunks of
+// chunkSize ahead or returns nil if prefetching is disabled
+func newPrefetcher(chunkSize int64, maxChunks int) *prefetcher {
+	if chunkSize <= 0 || maxChunks <= 0 {
+		return nil
+	}
+	return &prefetcher{
+		chunkSize: chunkSize,
+		maxChunks: maxChunks,
+	}
+}
+
+// read records a read of size bytes at off and returns the chunks to
+// fetch ahead of it, if any
+func (p *prefetcher) read(off, size int64) (fetch []ranges.Range) {
+	end := off + size
+	if off >= p.next-prefetchSlack && off <= p.next+prefetchSlack {
+		p.seq++
+		p.next = max(p.next, end)
+	} else {
+		// Seeked so start again
+		p.seq, p.chunks, p.ahead = 1, 0, 0
+		p.next = end
+	}
+	if p.seq < prefetchMinSequential {
+		return nil
+	}
+	// Nothing to do while more than half the chunks are left
+	if p.ahead-p.next > int64(p.chunks)*p.chunkSize/2 {
+		return nil
+	}
+	p.chunks = min(max(2*p.chunks, 1), p.maxChunks)
+	aheadEnd := p.next + int64(p.chunks)*p.chunkSize
+	for pos := max(p.ahead, p.next); pos < aheadEnd; pos += p.chunkSize {
+		fetch = append(fetch, ranges.Range{Pos: pos, Size: min(p.chunkSize, aheadEnd-pos)})
+	}
+	p.ahead = aheadEnd
+	return fetch
+}
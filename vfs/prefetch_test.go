@@ -0,0 +1,67 @@
+package vfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPrefetcher(t *testing.T) {
+	assert.Nil(t, newPrefetcher(0, 4))
+	assert.Nil(t, newPrefetcher(100, 0))
+	assert.NotNil(t, newPrefetcher(100, 4))
+}
+
+func TestPrefetcher(t *testing.T) {
+	const (
+		chunk     = 4 * prefetchSlack
+		readSize  = chunk / 8
+		maxChunks = 4
+	)
+	p := newPrefetcher(chunk, maxChunks)
+
+	// Nothing is fetched until the reads are sequential
+	off := int64(0)
+	for range prefetchMinSequential - 1 {
+		assert.Nil(t, p.read(off, readSize))
+		off += readSize
+	}
+
+	// Then the chunks fetched ahead follow on from each other and
+	// double up to the maximum
+	ahead := int64(-1)
+	var chunks []int
+	for range 100 {
+		fetch := p.read(off, readSize)
+		off += readSize
+		if fetch == nil {
+			continue
+		}
+		chunks = append(chunks, p.chunks)
+		if ahead < 0 {
+			assert.Equal(t, off, fetch[0].Pos)
+		} else {
+			assert.Equal(t, ahead, fetch[0].Pos)
+		}
+		for i, r := range fetch {
+			assert.True(t, r.Size > 0 && r.Size <= chunk)
+			if i > 0 {
+				assert.Equal(t, fetch[i-1].End(), r.Pos)
+			}
+		}
+		ahead = fetch[len(fetch)-1].End()
+		assert.LessOrEqual(t, ahead-off, int64(maxChunks*chunk))
+	}
+	require.True(t, len(chunks) > 4)
+	assert.Equal(t, []int{1, 2, 4, 4}, chunks[:4])
+
+	// Reads out of order a little are still sequential
+	assert.Nil(t, p.read(off-readSize, readSize))
+	assert.Equal(t, maxChunks, p.chunks)
+
+	// Seeking starts again
+	assert.Nil(t, p.read(1000*chunk, readSize))
+	assert.Equal(t, 0, p.chunks)
+	assert.Equal(t, 1, p.seq)
+}
@@ -204,7 +204,7 @@ func rcForget(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 
 	forgotten := []string{}
 	if len(in) == 0 {
-		root.ForgetAll()
+		vfs.FlushDirCache()
 	} else {
 		for k, v := range in {
 			path, ok := v.(string)
@@ -359,6 +359,61 @@ func rcPollInterval(ctx context.Context, in rc.Params) (out rc.Params, err error
 	return
 }
 
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/cache-mode-overrides",
+		Fn:    rcCacheModeOverrides,
+		Title: "Get or set the cache modes used for some directories.",
+		Help: `
+Without any parameter given this returns the cache mode and the cache
+mode overrides in use.
+
+When the overrides=string parameter is set, the overrides are replaced
+with those given in the format of |--vfs-cache-mode-overrides|, e.g.
+
+    rclone rc vfs/cache-mode-overrides overrides=/media:full,/scratch:off
+
+Files already open keep the cache mode they were opened with.
+
+This returns an error if an override other than off is set and the VFS
+was started without the disk cache, i.e. with |--vfs-cache-mode| and
+|--vfs-cache-mode-overrides| both off.
+` + getVFSHelp,
+	})
+}
+
+func rcCacheModeOverrides(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := in.GetString("overrides")
+	overridesPresent := !rc.IsErrParamNotFound(err)
+	if overridesPresent && err != nil {
+		return nil, err
+	}
+	delete(in, "overrides")
+	for k, v := range in {
+		return nil, fmt.Errorf("invalid parameter: %s=%s", k, v)
+	}
+	if overridesPresent {
+		err = vfs.SetCacheModeOverrides(overrides)
+		if err != nil {
+			return nil, err
+		}
+	}
+	vfs.cacheModeMu.RLock()
+	defer vfs.cacheModeMu.RUnlock()
+	current := rc.Params{}
+	for _, o := range vfs.cacheModeOverrides {
+		current["/"+o.dir] = o.mode.String()
+	}
+	return rc.Params{
+		"cacheMode": vfs.Opt.CacheMode.String(),
+		"overrides": current,
+	}, nil
+}
+
 func init() {
 	rc.Add(rc.Call{
 		Path:  "vfs/list",
@@ -401,6 +456,9 @@ This returns stats for the selected VFS.
     {
         // Status of the disk cache - only present if --vfs-cache-mode > off
         "diskCache": {
+            // only present if --vfs-cache-block-size is set
+            "blockHits": 0,
+            "blockMisses": 0,
             "bytesUsed": 0,
             "erroredFiles": 0,
             "files": 0,
@@ -27,7 +27,8 @@ type RWFileHandle struct {
 	offset      int64 // file pointer offset
 	closed      bool  // set if handle has been closed
 	opened      bool
-	writeCalled bool // if any Write() methods have been called
+	writeCalled bool        // if any Write() methods have been called
+	prefetcher  *prefetcher // fetches ahead of sequential reads - may be nil
 }
 
 // Lock performs Unix locking, not supported
@@ -53,10 +54,11 @@ func newRWFileHandle(d *Dir, f *File, flags int) (fh *RWFileHandle, err error) {
 	}
 
 	fh = &RWFileHandle{
-		file:  f,
-		d:     d,
-		flags: flags,
-		item:  item,
+		file:       f,
+		d:          d,
+		flags:      flags,
+		item:       item,
+		prefetcher: newPrefetcher(int64(d.vfs.Opt.ChunkSize), d.vfs.Opt.PrefetchChunks),
 	}
 
 	// truncate immediately if O_TRUNC is set or O_CREATE is set and file doesn't exist
@@ -278,6 +280,13 @@ func (fh *RWFileHandle) _readAt(b []byte, off int64, release bool) (n int, err e
 	if release {
 		fh.mu.Lock()
 	}
+	if fh.prefetcher != nil && n > 0 {
+		for _, r := range fh.prefetcher.read(off, int64(n)) {
+			if prefetchErr := fh.item.Prefetch(r.Pos, r.Size); prefetchErr != nil {
+				fs.Debugf(fh.logPrefix(), "failed to prefetch %v: %v", r, prefetchErr)
+			}
+		}
+	}
 	return n, err
 }
 
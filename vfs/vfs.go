@@ -23,6 +23,7 @@ package vfs
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -185,6 +186,92 @@ type VFS struct {
 	usage       *fs.Usage
 	pollChan    chan time.Duration
 	inUse       atomic.Int32 // count of number of opens
+
+	cacheModeMu        sync.RWMutex
+	cacheModeOverrides []cacheModeOverride // cache modes for directories, deepest first
+
+	persist *dirCachePersist // persistent directory cache if enabled
+
+	changeNotifyMu  sync.Mutex
+	changeNotifyID  int
+	changeNotifyFns map[int]ChangeNotifyFunc // called when the backend reports changes
+}
+
+// ChangeNotifyFunc is called with the path of an entry the backend has
+// reported as changed, after the VFS has invalidated its directory
+// cache for it.
+type ChangeNotifyFunc func(path string, entryType fs.EntryType)
+
+// cacheModeOverride is the cache mode to use for a directory tree
+type cacheModeOverride struct {
+	dir  string // directory the override applies to, "" for the root
+	mode vfscommon.CacheMode
+}
+
+// parseCacheModeOverrides parses the comma separated "dir:mode" pairs
+// in overrides returning them deepest directory first
+func parseCacheModeOverrides(overrides string) (out []cacheModeOverride, err error) {
+	for _, item := range strings.Split(overrides, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndex(item, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("cache mode override %q must be dir:mode", item)
+		}
+		var o cacheModeOverride
+		err = o.mode.Set(item[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("cache mode override %q: %w", item, err)
+		}
+		o.dir = path.Clean("/" + item[:i])[1:]
+		out = append(out, o)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return len(out[i].dir) > len(out[j].dir)
+	})
+	return out, nil
+}
+
+// cacheMode returns the cache mode to use for the file at path
+func (vfs *VFS) cacheMode(path string) vfscommon.CacheMode {
+	vfs.cacheModeMu.RLock()
+	defer vfs.cacheModeMu.RUnlock()
+	for _, o := range vfs.cacheModeOverrides {
+		if o.dir == "" || path == o.dir || strings.HasPrefix(path, o.dir+"/") {
+			return o.mode
+		}
+	}
+	return vfs.Opt.CacheMode
+}
+
+// maxCacheMode returns the highest of cacheMode and the overrides
+func maxCacheMode(cacheMode vfscommon.CacheMode, overrides []cacheModeOverride) vfscommon.CacheMode {
+	for _, o := range overrides {
+		cacheMode = max(cacheMode, o.mode)
+	}
+	return cacheMode
+}
+
+// SetCacheModeOverrides sets the cache modes to use for some
+// directories instead of the cache mode
+//
+// overrides is in the format of --vfs-cache-mode-overrides. This
+// returns an error if the overrides need the disk cache and it isn't
+// running.
+func (vfs *VFS) SetCacheModeOverrides(overrides string) error {
+	parsed, err := parseCacheModeOverrides(overrides)
+	if err != nil {
+		return err
+	}
+	if vfs.cache == nil && maxCacheMode(vfscommon.CacheModeOff, parsed) > vfscommon.CacheModeOff {
+		return errors.New("can't use cache mode overrides other than off without the vfs cache running")
+	}
+	vfs.cacheModeMu.Lock()
+	vfs.cacheModeOverrides = parsed
+	vfs.cacheModeMu.Unlock()
+	return nil
 }
 
 // Keep track of active VFS keyed on fs.ConfigString(f)
@@ -226,6 +313,16 @@ func New(f fs.Fs, opt *vfscommon.Options) *VFS {
 	// Put the VFS into the active cache
 	active[configName] = append(active[configName], vfs)
 
+	// Open the persistent directory cache
+	if vfs.Opt.DirCachePersist {
+		persist, err := newDirCachePersist(vfs)
+		if err != nil {
+			fs.Errorf(f, "Failed to open persistent directory cache - disabling: %v", err)
+		} else {
+			vfs.persist = persist
+		}
+	}
+
 	// Create root directory
 	vfs.root = newDir(vfs, f, nil, fsDir)
 
@@ -233,7 +330,7 @@ func New(f fs.Fs, opt *vfscommon.Options) *VFS {
 	features := vfs.f.Features()
 	if do := features.ChangeNotify; do != nil {
 		vfs.pollChan = make(chan time.Duration)
-		do(context.TODO(), vfs.root.changeNotify, vfs.pollChan)
+		do(context.TODO(), vfs.changeNotify, vfs.pollChan)
 		vfs.pollChan <- time.Duration(vfs.Opt.PollInterval)
 	} else if vfs.Opt.PollInterval > 0 {
 		fs.Infof(f, "poll-interval is not supported by this remote")
@@ -259,6 +356,13 @@ func New(f fs.Fs, opt *vfscommon.Options) *VFS {
 		go vfs.refresh()
 	}
 
+	// Read the cache modes for directories
+	overrides, err := parseCacheModeOverrides(vfs.Opt.CacheModeOverrides)
+	if err != nil {
+		fs.Errorf(f, "Ignoring --vfs-cache-mode-overrides: %v", err)
+	}
+	vfs.cacheModeOverrides = overrides
+
 	// This can take some time so do it after the Pin
 	vfs.SetCacheMode(vfs.Opt.CacheMode)
 
@@ -314,6 +418,50 @@ func activeCacheEntries() (vfs *VFS, count int) {
 	return vfs, count
 }
 
+// changeNotify is called by the backend with the paths which have
+// changed on the remote
+func (vfs *VFS) changeNotify(relativePath string, entryType fs.EntryType) {
+	vfs.root.changeNotify(relativePath, entryType)
+	vfs.changeNotifyMu.Lock()
+	fns := make([]ChangeNotifyFunc, 0, len(vfs.changeNotifyFns))
+	for _, fn := range vfs.changeNotifyFns {
+		fns = append(fns, fn)
+	}
+	vfs.changeNotifyMu.Unlock()
+	for _, fn := range fns {
+		fn(relativePath, entryType)
+	}
+}
+
+// AddChangeNotify arranges for fn to be called with the paths the
+// backend reports as changed, if it supports ChangeNotify.
+//
+// This is used by the mounts to tell the kernel about the changes. It
+// returns a function to call to stop fn being called.
+func (vfs *VFS) AddChangeNotify(fn ChangeNotifyFunc) (remove func()) {
+	vfs.changeNotifyMu.Lock()
+	defer vfs.changeNotifyMu.Unlock()
+	if vfs.changeNotifyFns == nil {
+		vfs.changeNotifyFns = make(map[int]ChangeNotifyFunc)
+	}
+	vfs.changeNotifyID++
+	id := vfs.changeNotifyID
+	vfs.changeNotifyFns[id] = fn
+	return func() {
+		vfs.changeNotifyMu.Lock()
+		delete(vfs.changeNotifyFns, id)
+		vfs.changeNotifyMu.Unlock()
+	}
+}
+
+// CachedNode returns the node for path if it is in the directory
+// cache or nil if it isn't.
+//
+// Unlike Stat it never reads directories from the remote.
+func (vfs *VFS) CachedNode(path string) Node {
+	return vfs.root.cachedNode(path)
+}
+
 // Fs returns the Fs passed into the New call
 func (vfs *VFS) Fs() fs.Fs {
 	return vfs.f
@@ -323,12 +471,18 @@ func (vfs *VFS) Fs() fs.Fs {
 func (vfs *VFS) SetCacheMode(cacheMode vfscommon.CacheMode) {
 	vfs.shutdownCache()
 	vfs.cache = nil
-	if cacheMode > vfscommon.CacheModeOff {
+	vfs.cacheModeMu.RLock()
+	maxMode := maxCacheMode(cacheMode, vfs.cacheModeOverrides)
+	vfs.cacheModeMu.RUnlock()
+	if maxMode > vfscommon.CacheModeOff {
 		ctx, cancel := context.WithCancel(context.Background())
 		cache, err := vfscache.New(ctx, vfs.f, &vfs.Opt, vfs.AddVirtual) // FIXME pass on context or get from Opt?
 		if err != nil {
 			fs.Errorf(nil, "Failed to create vfs cache - disabling: %v", err)
 			vfs.Opt.CacheMode = vfscommon.CacheModeOff
+			vfs.cacheModeMu.Lock()
+			vfs.cacheModeOverrides = nil
+			vfs.cacheModeMu.Unlock()
 			cancel()
 			return
 		}
@@ -368,6 +522,11 @@ func (vfs *VFS) Shutdown() {
 
 	vfs.shutdownCache()
 
+	if vfs.persist != nil {
+		vfs.persist.close()
+		vfs.persist = nil
+	}
+
 	if vfs.pollChan != nil {
 		close(vfs.pollChan)
 		vfs.pollChan = nil
@@ -376,7 +535,7 @@ func (vfs *VFS) Shutdown() {
 
 // CleanUp deletes the contents of the on disk cache
 func (vfs *VFS) CleanUp() error {
-	if vfs.Opt.CacheMode == vfscommon.CacheModeOff {
+	if vfs.cache == nil {
 		return nil
 	}
 	return vfs.cache.CleanUp()
@@ -384,6 +543,9 @@ func (vfs *VFS) CleanUp() error {
 
 // FlushDirCache empties the directory cache
 func (vfs *VFS) FlushDirCache() {
+	if vfs.persist != nil {
+		vfs.persist.purge("", true)
+	}
 	vfs.root.ForgetAll()
 }
 
@@ -13,6 +13,7 @@ import (
 
 	_ "github.com/rclone/rclone/backend/all" // import all the backends
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
@@ -172,6 +173,68 @@ func TestVFSNewWithOpts(t *testing.T) {
 	assert.Equal(t, vfscommon.FileMode(0664), vfs.Opt.FilePerms)
 }
 
+func TestParseCacheModeOverrides(t *testing.T) {
+	overrides, err := parseCacheModeOverrides(" /media:full, media/music/:off,/:writes,, scratch:minimal")
+	require.NoError(t, err)
+	assert.Equal(t, []cacheModeOverride{
+		{dir: "media/music", mode: vfscommon.CacheModeOff},
+		{dir: "scratch", mode: vfscommon.CacheModeMinimal},
+		{dir: "media", mode: vfscommon.CacheModeFull},
+		{dir: "", mode: vfscommon.CacheModeWrites},
+	}, overrides)
+
+	for _, bad := range []string{"media", "/media:potato"} {
+		_, err = parseCacheModeOverrides(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestVFSCacheModeOverrides(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheModeOverrides = "/cached:full"
+	r, vfs := newTestVFSOpt(t, &opt)
+	require.NotNil(t, vfs.cache)
+
+	assert.Equal(t, vfscommon.CacheModeFull, vfs.cacheMode("cached"))
+	assert.Equal(t, vfscommon.CacheModeFull, vfs.cacheMode("cached/dir/file"))
+	assert.Equal(t, vfscommon.CacheModeOff, vfs.cacheMode("cachedfile"))
+
+	file1 := r.WriteObject(context.Background(), "cached/file1", "file1 contents", t1)
+	file2 := r.WriteObject(context.Background(), "file2", "file2 contents", t2)
+	r.CheckRemoteItems(t, file1, file2)
+	for _, test := range []struct {
+		path string
+		want Handle
+	}{
+		{"cached/file1", &RWFileHandle{}},
+		{"file2", &ReadFileHandle{}},
+	} {
+		fd, err := vfs.OpenFile(test.path, os.O_RDONLY, 0777)
+		require.NoError(t, err)
+		assert.IsType(t, test.want, fd, test.path)
+		require.NoError(t, fd.Close())
+	}
+
+	// Change the overrides with the rc
+	call := rc.Calls.Get("vfs/cache-mode-overrides")
+	out, err := call.Fn(context.Background(), rc.Params{"fs": fs.ConfigString(r.Fremote), "overrides": "/other:writes"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"cacheMode": "off",
+		"overrides": rc.Params{"/other": "writes"},
+	}, out)
+	assert.Equal(t, vfscommon.CacheModeOff, vfs.cacheMode("cached/file1"))
+	_, err = call.Fn(context.Background(), rc.Params{"fs": fs.ConfigString(r.Fremote), "overrides": "potato"})
+	assert.Error(t, err)
+}
+
+func TestVFSCacheModeOverridesNoCache(t *testing.T) {
+	_, vfs := newTestVFS(t)
+	require.Nil(t, vfs.cache)
+	assert.Error(t, vfs.SetCacheModeOverrides("/media:full"))
+	assert.NoError(t, vfs.SetCacheModeOverrides("/media:off"))
+}
+
 // TestVFSRoot checks root directory is present and correct
 func TestVFSRoot(t *testing.T) {
 	_, vfs := newTestVFS(t)
@@ -501,3 +564,43 @@ func TestVFSIsMetadataFile(t *testing.T) {
 	assert.Equal(t, "leaf", rawName)
 	assert.Equal(t, true, found)
 }
+
+func TestVFSChangeNotify(t *testing.T) {
+	r, vfs := newTestVFS(t)
+
+	file1 := r.WriteObject(context.Background(), "dir/file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+
+	// Nothing is cached until it is read
+	assert.Nil(t, vfs.CachedNode("dir/file1"))
+	node, err := vfs.Stat("dir/file1")
+	require.NoError(t, err)
+	assert.Equal(t, node, vfs.CachedNode("dir/file1"))
+	assert.Nil(t, vfs.CachedNode("dir/potato"))
+	root, err := vfs.Root()
+	require.NoError(t, err)
+	assert.Equal(t, root, vfs.CachedNode(""))
+
+	type change struct {
+		path      string
+		entryType fs.EntryType
+	}
+	var changes []change
+	remove := vfs.AddChangeNotify(func(path string, entryType fs.EntryType) {
+		changes = append(changes, change{path, entryType})
+	})
+
+	vfs.changeNotify("dir/file1", fs.EntryObject)
+	vfs.changeNotify("dir", fs.EntryDirectory)
+	assert.Equal(t, []change{{"dir/file1", fs.EntryObject}, {"dir", fs.EntryDirectory}}, changes)
+
+	// The directory cache was invalidated first
+	dir := vfs.CachedNode("dir").(*Dir)
+	dir.mu.RLock()
+	assert.True(t, dir.read.IsZero())
+	dir.mu.RUnlock()
+
+	remove()
+	vfs.changeNotify("dir/file1", fs.EntryObject)
+	assert.Len(t, changes, 2)
+}
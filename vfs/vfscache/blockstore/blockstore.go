@@ -0,0 +1,204 @@
+// Package blockstore implements a content addressed store of blocks
+// of files for the VFS cache
+//
+// Blocks are stored by a key identifying the contents of the object
+// they came from and their offset in it. If the key is made from a
+// hash of the contents rather than the name of the object, blocks
+// survive the object being renamed and are shared between identical
+// objects.
+package blockstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/file"
+)
+
+// Store is a store of blocks on disk
+type Store struct {
+	root      string       // directory the blocks are stored in
+	blockSize int64        // size of the blocks
+	hits      atomic.Int64 // number of blocks read from the store
+	misses    atomic.Int64 // number of blocks not found in the store
+}
+
+// New makes a store of blockSize blocks in the root directory
+func New(root string, blockSize int64) (*Store, error) {
+	if blockSize <= 0 {
+		return nil, fmt.Errorf("block size must be positive, not %d", blockSize)
+	}
+	err := file.MkdirAll(root, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create block store directory: %w", err)
+	}
+	return &Store{
+		root:      root,
+		blockSize: blockSize,
+	}, nil
+}
+
+// BlockSize returns the size of the blocks in the store
+func (s *Store) BlockSize() int64 {
+	return s.blockSize
+}
+
+// Key returns the key of the blocks of the object on the remote whose
+// contents are identified by id
+func Key(remote, id string) string {
+	return remote + "\x00" + id
+}
+
+// path returns the OS path of the block at off of key
+//
+// The blocks are spread over 256 directories so none get too big.
+func (s *Store) path(key string, off int64) string {
+	sum := sha256.Sum256([]byte(key + "\x00" + strconv.FormatInt(off, 10)))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(s.root, name[:2], name)
+}
+
+// Get reads the block at off of key into b
+//
+// b must be the size the block is expected to be. It returns false if
+// the block isn't in the store or isn't that size.
+func (s *Store) Get(key string, off int64, b []byte) bool {
+	osPath := s.path(key, off)
+	in, err := os.Open(osPath)
+	if err != nil {
+		s.misses.Add(1)
+		return false
+	}
+	defer fs.CheckClose(in, &err)
+	fi, err := in.Stat()
+	if err != nil || fi.Size() != int64(len(b)) {
+		s.misses.Add(1)
+		return false
+	}
+	_, err = io.ReadFull(in, b)
+	if err != nil {
+		fs.Errorf(nil, "vfs cache: failed to read block: %v", err)
+		s.misses.Add(1)
+		return false
+	}
+	s.hits.Add(1)
+	// Mark the block as used so it is removed last
+	now := time.Now()
+	_ = os.Chtimes(osPath, now, now)
+	return true
+}
+
+// Has returns true if the block at off of key is in the store
+func (s *Store) Has(key string, off int64) bool {
+	_, err := os.Stat(s.path(key, off))
+	return err == nil
+}
+
+// Put writes b as the block at off of key
+//
+// The block is written to a temporary file first so readers never see
+// a partial block.
+func (s *Store) Put(key string, off int64, b []byte) (err error) {
+	osPath := s.path(key, off)
+	dir := filepath.Dir(osPath)
+	err = file.MkdirAll(dir, 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create block directory: %w", err)
+	}
+	out, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create block: %w", err)
+	}
+	tmpPath := out.Name()
+	_, err = out.Write(b)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, osPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write block: %w", err)
+	}
+	return nil
+}
+
+// block is a block found on disk by Clean
+type block struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Clean removes the blocks not used for maxAge and then the least
+// recently used blocks until the store is no bigger than maxSize
+//
+// maxSize <= 0 means no limit. It returns the number of blocks and
+// bytes left in the store.
+func (s *Store) Clean(maxAge time.Duration, maxSize int64) (blocks int, used int64, err error) {
+	var found []block
+	cutoff := time.Now().Add(-maxAge)
+	err = filepath.Walk(s.root, func(osPath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		if fi.ModTime().Before(cutoff) {
+			s.remove(osPath)
+			return nil
+		}
+		if strings.HasPrefix(fi.Name(), ".tmp-") {
+			// being written by Put
+			return nil
+		}
+		found = append(found, block{path: osPath, size: fi.Size(), modTime: fi.ModTime()})
+		used += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to walk block store: %w", err)
+	}
+	if maxSize > 0 && used > maxSize {
+		sort.Slice(found, func(i, j int) bool {
+			return found[i].modTime.Before(found[j].modTime)
+		})
+		for len(found) > 0 && used > maxSize {
+			s.remove(found[0].path)
+			used -= found[0].size
+			found = found[1:]
+		}
+	}
+	return len(found), used, nil
+}
+
+// remove the block at osPath
+func (s *Store) remove(osPath string) {
+	err := os.Remove(osPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fs.Errorf(nil, "vfs cache: failed to remove block: %v", err)
+	}
+}
+
+// Stats returns the number of blocks read from the store and not
+// found in it
+func (s *Store) Stats() (hits, misses int64) {
+	return s.hits.Load(), s.misses.Load()
+}
@@ -0,0 +1,62 @@
+package blockstore
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	_, err := New(t.TempDir(), 0)
+	assert.Error(t, err)
+
+	s, err := New(t.TempDir(), 4)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), s.BlockSize())
+	key := Key("remote:", "10,2001-02-03")
+
+	buf := make([]byte, 4)
+	assert.False(t, s.Has(key, 0))
+	assert.False(t, s.Get(key, 0, buf))
+
+	require.NoError(t, s.Put(key, 0, []byte("abcd")))
+	require.NoError(t, s.Put(key, 4, []byte("efgh")))
+	assert.True(t, s.Has(key, 0))
+	assert.True(t, s.Get(key, 0, buf))
+	assert.Equal(t, "abcd", string(buf))
+
+	// Blocks are keyed by the remote and fingerprint
+	assert.False(t, s.Has(Key("other:", "10,2001-02-03"), 0))
+	assert.False(t, s.Has(Key("remote:", "11,2001-02-03"), 0))
+
+	// Blocks of the wrong size aren't returned
+	assert.False(t, s.Get(key, 4, make([]byte, 2)))
+
+	hits, misses := s.Stats()
+	assert.Equal(t, int64(1), hits)
+	assert.Equal(t, int64(2), misses)
+
+	// Make the block at 0 the least recently used
+	old := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(s.path(key, 0), old, old))
+
+	blocks, used, err := s.Clean(time.Hour, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, blocks)
+	assert.Equal(t, int64(8), used)
+
+	blocks, used, err = s.Clean(time.Hour, 6)
+	require.NoError(t, err)
+	assert.Equal(t, 1, blocks)
+	assert.Equal(t, int64(4), used)
+	assert.False(t, s.Has(key, 0))
+	assert.True(t, s.Has(key, 4))
+
+	blocks, used, err = s.Clean(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, blocks)
+	assert.Equal(t, int64(0), used)
+}
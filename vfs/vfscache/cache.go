@@ -25,6 +25,7 @@ import (
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/file"
 	"github.com/rclone/rclone/lib/systemd"
+	"github.com/rclone/rclone/vfs/vfscache/blockstore"
 	"github.com/rclone/rclone/vfs/vfscache/writeback"
 	"github.com/rclone/rclone/vfs/vfscommon"
 )
@@ -51,6 +52,8 @@ type Cache struct {
 	hashOption *fs.HashesOption     // corresponding OpenOption
 	writeback  *writeback.WriteBack // holds Items for writeback
 	avFn       AddVirtualFn         // if set, can be called to add dir entries
+	blocks     *blockstore.Store    // content addressed blocks - may be nil
+	blockKey   string               // remote part of the keys of the blocks
 
 	mu            sync.Mutex       // protects the following variables
 	cond          sync.Cond        // cond lock for synchronous cache cleaning
@@ -129,6 +132,16 @@ func New(ctx context.Context, fremote fs.Fs, opt *vfscommon.Options, avFn AddVir
 		avFn:       avFn,
 	}
 
+	// Open the block store shared by all the remotes if required
+	if opt.CacheBlockSize > 0 {
+		c.blocks, err = blockstore.New(file.UNCPath(filepath.Join(parentOSPath, "vfsBlocks")), int64(opt.CacheBlockSize))
+		if err != nil {
+			return nil, err
+		}
+		c.blockKey = fs.ConfigString(fremote)
+		fs.Debugf(fremote, "vfs cache: caching %v blocks by content", opt.CacheBlockSize)
+	}
+
 	// load in the cache and metadata off disk
 	err = c.reload(ctx)
 	if err != nil {
@@ -166,6 +179,11 @@ func (c *Cache) Stats() (out rc.Params) {
 	out["erroredFiles"] = len(c.errItems)
 	out["bytesUsed"] = c.used
 	out["outOfSpace"] = c.outOfSpace
+	if c.blocks != nil {
+		hits, misses := c.blocks.Stats()
+		out["blockHits"] = hits
+		out["blockMisses"] = misses
+	}
 
 	return out
 }
@@ -809,6 +827,16 @@ func (c *Cache) clean(kicked bool) {
 		c.retryFailedResets()
 	}
 
+	// Remove blocks which are over age or over quota
+	if c.blocks != nil {
+		blocks, used, err := c.blocks.Clean(time.Duration(c.opt.CacheMaxAge), int64(c.opt.CacheMaxSize))
+		if err != nil {
+			fs.Errorf(c.fremote, "vfs cache: failed to clean blocks: %v", err)
+		} else {
+			fs.Infof(c.fremote, "vfs cache: cleaned blocks: %d blocks, total size %v", blocks, fs.SizeSuffix(used))
+		}
+	}
+
 	// Was kicked?
 	if kicked {
 		c.kickerMu.Lock() // Make sure this is called with cache mutex unlocked
@@ -7,14 +7,18 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/lib/file"
 	"github.com/rclone/rclone/lib/ranges"
+	"github.com/rclone/rclone/vfs/vfscache/blockstore"
 	"github.com/rclone/rclone/vfs/vfscache/downloaders"
 	"github.com/rclone/rclone/vfs/vfscache/writeback"
 )
@@ -68,6 +72,9 @@ type Item struct {
 	pendingAccesses int                      // number of threads - cache reset not allowed if not zero
 	modified        bool                     // set if the file has been modified since the last Open
 	beingReset      bool                     // cache cleaner is resetting the cache file, access not allowed
+	writing         int                      // number of WriteAt calls writing with the lock released
+	blockKey        string                   // cached key of the blocks in the block store - see _blockKey
+	blockKeyFor     string                   // name and fingerprint blockKey was made for
 }
 
 // Info is persisted to backing store
@@ -1140,6 +1147,10 @@ func (item *Item) _ensure(offset, size int64) (err error) {
 	}
 	r := ranges.Range{Pos: offset, Size: size}
 	present := item.info.Rs.Present(r)
+	blockKey := ""
+	if !present {
+		blockKey = item._blockKey()
+	}
 	/* This statement simulates a cache space error for test purpose */
 	/* if present != true && item.info.Rs.Size() > 32*1024*1024 {
 		return errors.New("no space left on device")
@@ -1147,6 +1158,10 @@ func (item *Item) _ensure(offset, size int64) (err error) {
 	fs.Debugf(nil, "vfs cache: looking for range=%+v in %+v - present %v", r, item.info.Rs, present)
 	item.mu.Unlock()
 	defer item.mu.Lock()
+	if blockKey != "" {
+		// Fill in what we can from the block store first
+		present = item.readBlocks(blockKey, r)
+	}
 	if present {
 		// This is a file we are writing so no downloaders needed
 		if item.downloaders == nil {
@@ -1175,6 +1190,160 @@ func (item *Item) _ensure(offset, size int64) (err error) {
 	return item.downloaders.Download(r)
 }
 
+// _blockKey returns the key of the blocks of the item in the block
+// store or "" if they shouldn't be read from or written to it
+//
+// Blocks are only shared between objects if the fingerprint includes
+// a hash of the contents, as objects with the same size and
+// modification time may well be different. Otherwise the key includes
+// the name of the item so the blocks are only used for it.
+//
+// call with lock held
+func (item *Item) _blockKey() string {
+	if item.c.blocks == nil || item.o == nil || item.info.Dirty || item.info.Fingerprint == "" {
+		return ""
+	}
+	if item.info.Size <= 0 || item.o.Size() != item.info.Size {
+		return ""
+	}
+	keyFor := item.name + "\x00" + item.info.Fingerprint
+	if item.blockKeyFor != keyFor {
+		id := "name:" + item.name + "," + item.info.Fingerprint
+		if contentHash := fingerprintHash(item.o, item.info.Fingerprint, item.c.opt.FastFingerprint); contentHash != "" {
+			id = "hash:" + contentHash + "," + strconv.FormatInt(item.info.Size, 10)
+		}
+		item.blockKey = blockstore.Key(item.c.blockKey, id)
+		item.blockKeyFor = keyFor
+	}
+	return item.blockKey
+}
+
+// fingerprintHash returns the hash of o which is in its fingerprint
+// as "type:hash" or "" if the fingerprint doesn't have one
+func fingerprintHash(o fs.Object, fingerprint string, fast bool) string {
+	f := o.Fs()
+	// fs.Fingerprint doesn't use slow hashes when fast
+	if fast && f.Features().SlowHash {
+		return ""
+	}
+	hashType := f.Hashes().GetOne()
+	if hashType == hash.None {
+		return ""
+	}
+	sum, err := o.Hash(context.TODO(), hashType)
+	if err != nil || sum == "" || !strings.HasSuffix(fingerprint, ","+sum) {
+		return ""
+	}
+	return hashType.String() + ":" + sum
+}
+
+// blockRanges returns the blocks of blockSize of a file of size
+// overlapping r
+func blockRanges(r ranges.Range, size, blockSize int64) (blocks []ranges.Range) {
+	for pos := r.Pos / blockSize * blockSize; pos < r.End() && pos < size; pos += blockSize {
+		blocks = append(blocks, ranges.Range{Pos: pos, Size: min(blockSize, size-pos)})
+	}
+	return blocks
+}
+
+// readBlocks fills in the parts of r missing from the backing file
+// from the block store if it has them. It returns true if all of r is
+// now present.
+//
+// key should be from _blockKey. Call with lock not held so the block
+// store is read without it.
+func (item *Item) readBlocks(key string, r ranges.Range) bool {
+	item.mu.Lock()
+	size := item.info.Size
+	item.mu.Unlock()
+	for _, block := range blockRanges(r, size, item.c.blocks.BlockSize()) {
+		if item.HasRange(block) {
+			continue
+		}
+		buf := make([]byte, block.Size)
+		if !item.c.blocks.Get(key, block.Pos, buf) {
+			continue
+		}
+		item.mu.Lock()
+		var err error
+		// Check the item hasn't changed while reading the block
+		if item.fd != nil && item.writing == 0 && item._blockKey() == key {
+			_, _, err = item._writeAtNoOverwrite(buf, block.Pos)
+		}
+		item.mu.Unlock()
+		if err != nil {
+			fs.Errorf(item.name, "vfs cache: failed to write block from block store: %v", err)
+			break
+		}
+	}
+	return item.HasRange(r)
+}
+
+// storeBlocks puts the blocks of the item overlapping r which are
+// complete in the backing file into the block store
+//
+// key should be from _blockKey. Call with lock not held so the block
+// store is written without it.
+func (item *Item) storeBlocks(key string, r ranges.Range) {
+	item.mu.Lock()
+	size, fd := item.info.Size, item.fd
+	item.mu.Unlock()
+	if fd == nil {
+		return
+	}
+	for _, block := range blockRanges(r, size, item.c.blocks.BlockSize()) {
+		if !item.HasRange(block) || item.c.blocks.Has(key, block.Pos) {
+			continue
+		}
+		buf := make([]byte, block.Size)
+		_, err := fd.ReadAt(buf, block.Pos)
+		if err != nil {
+			fs.Debugf(item.name, "vfs cache: failed to read block for block store: %v", err)
+			return
+		}
+		// Check the item wasn't written to while reading the block
+		item.mu.Lock()
+		unchanged := item.writing == 0 && item._blockKey() == key
+		item.mu.Unlock()
+		if !unchanged {
+			return
+		}
+		err = item.c.blocks.Put(key, block.Pos, buf)
+		if err != nil {
+			fs.Errorf(item.name, "vfs cache: failed to store block in block store: %v", err)
+			return
+		}
+	}
+}
+
+// Prefetch starts the range from offset, size downloading into the
+// backing file if it isn't present without waiting for it
+func (item *Item) Prefetch(offset, size int64) error {
+	item.preAccess()
+	defer item.postAccess()
+	item.mu.Lock()
+	if item.fd == nil || item.downloaders == nil {
+		item.mu.Unlock()
+		return nil
+	}
+	r := ranges.Range{Pos: offset, Size: size}
+	r.Clip(item.info.Size)
+	present := r.IsEmpty() || item.info.Rs.Present(r)
+	blockKey := ""
+	if !present {
+		blockKey = item._blockKey()
+	}
+	dls := item.downloaders
+	item.mu.Unlock()
+	if blockKey != "" {
+		present = item.readBlocks(blockKey, r)
+	}
+	if present {
+		return nil
+	}
+	return dls.EnsureDownloader(r)
+}
+
 // _written marks the (offset, size) as present in the backing file
 //
 // This is called by the downloader downloading file segments and the
@@ -1312,6 +1481,7 @@ func (item *Item) WriteAt(b []byte, off int64) (n int, err error) {
 		item.mu.Unlock()
 		return 0, errors.New("vfs cache item WriteAt: internal error: didn't Open file")
 	}
+	item.writing++
 	item.mu.Unlock()
 	// Do the writing with Item.mu unlocked
 	n, err = item.fd.WriteAt(b, off)
@@ -1319,6 +1489,7 @@ func (item *Item) WriteAt(b []byte, off int64) (n int, err error) {
 		err = fmt.Errorf("short write: tried to write %d but only %d written", len(b), n)
 	}
 	item.mu.Lock()
+	item.writing--
 	item._written(off, int64(n))
 	if n > 0 {
 		item._dirty()
@@ -1348,7 +1519,23 @@ func (item *Item) WriteAt(b []byte, off int64) (n int, err error) {
 // bytes which were processed but not actually written to the file.
 func (item *Item) WriteAtNoOverwrite(b []byte, off int64) (n int, skipped int, err error) {
 	item.mu.Lock()
+	n, skipped, err = item._writeAtNoOverwrite(b, off)
+	blockKey := ""
+	if err == nil {
+		blockKey = item._blockKey()
+	}
+	item.mu.Unlock()
+	if blockKey != "" {
+		item.storeBlocks(blockKey, ranges.Range{Pos: off, Size: int64(n)})
+	}
+	return n, skipped, err
+}
 
+// _writeAtNoOverwrite writes b to the file, but will not overwrite
+// already present ranges.
+//
+// call with lock held
+func (item *Item) _writeAtNoOverwrite(b []byte, off int64) (n int, skipped int, err error) {
 	var (
 		// Range we wish to write
 		r = ranges.Range{Pos: off, Size: int64(len(b))}
@@ -1389,7 +1576,6 @@ func (item *Item) WriteAtNoOverwrite(b []byte, off int64) (n int, skipped int, e
 			break
 		}
 	}
-	item.mu.Unlock()
 	return n, skipped, err
 }
 
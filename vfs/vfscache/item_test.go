@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/lib/random"
 	"github.com/rclone/rclone/lib/readers"
@@ -211,6 +213,79 @@ func TestItemReadAt(t *testing.T) {
 	require.NoError(t, item.Close(nil))
 }
 
+func newBlockStoreTestCache(t *testing.T, fastFingerprint bool) (r *fstest.Run, c *Cache) {
+	oldCacheDir := config.GetCacheDir()
+	require.NoError(t, config.SetCacheDir(t.TempDir()))
+	t.Cleanup(func() {
+		_ = config.SetCacheDir(oldCacheDir)
+	})
+	opt := vfscommon.Opt
+	opt.CachePollInterval = 0
+	opt.WriteBack = 0
+	opt.CacheBlockSize = 16
+	opt.FastFingerprint = fastFingerprint
+	return newTestCacheOpt(t, opt)
+}
+
+// read all of the 100 byte item
+func readAllItem(t *testing.T, item *Item, obj fs.Object) string {
+	require.NoError(t, item.Open(obj))
+	buf := make([]byte, 100)
+	n, err := item.ReadAt(buf, 0)
+	assert.Equal(t, 100, n)
+	require.NoError(t, err)
+	require.NoError(t, item.Close(nil))
+	return string(buf)
+}
+
+func TestItemBlockStore(t *testing.T) {
+	r, c := newBlockStoreTestCache(t, false)
+	ctx := context.Background()
+
+	// Reading the file stores all its blocks
+	contents, obj, item := newFile(t, r, c, "existing")
+	assert.Equal(t, contents, readAllItem(t, item, obj))
+	hits, _ := c.blocks.Stats()
+	assert.Equal(t, int64(0), hits)
+
+	// Rename the file on the remote and check it is read from
+	// the blocks rather than downloaded again
+	newObj, err := operations.Move(ctx, r.Fremote, nil, "renamed", obj)
+	require.NoError(t, err)
+	item, _ = c.get("renamed")
+	assert.Equal(t, contents, readAllItem(t, item, newObj))
+	hits, _ = c.blocks.Stats()
+	assert.Equal(t, int64(7), hits)
+}
+
+func TestItemBlockStoreNoHash(t *testing.T) {
+	r, c := newBlockStoreTestCache(t, true)
+	ctx := context.Background()
+
+	// Two files with the same size and modification time but
+	// different contents
+	modTime := time.Now()
+	contents1 := random.String(100)
+	contents2 := random.String(100)
+	r.WriteObject(ctx, "file1", contents1, modTime)
+	r.WriteObject(ctx, "file2", contents2, modTime)
+	obj1, err := r.Fremote.NewObject(ctx, "file1")
+	require.NoError(t, err)
+	obj2, err := r.Fremote.NewObject(ctx, "file2")
+	require.NoError(t, err)
+	if fingerprintHash(obj1, fs.Fingerprint(ctx, obj1, true), true) != "" {
+		t.Skip("fingerprint includes a hash on this remote")
+	}
+
+	// They mustn't share blocks
+	item1, _ := c.get("file1")
+	assert.Equal(t, contents1, readAllItem(t, item1, obj1))
+	item2, _ := c.get("file2")
+	assert.Equal(t, contents2, readAllItem(t, item2, obj2))
+	hits, _ := c.blocks.Stats()
+	assert.Equal(t, int64(0), hits)
+}
+
 func TestItemWriteAtNew(t *testing.T) {
 	r, c := newItemTestCache(t)
 	item, _ := c.get("potato")
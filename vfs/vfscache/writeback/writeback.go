@@ -217,17 +217,47 @@ func (wb *WriteBack) _stopTimer() {
 	}
 }
 
+// return the size of the items waiting to be uploaded
+//
+// call with lock held
+func (wb *WriteBack) _queuedSize() (size int64) {
+	for _, wbItem := range wb.items {
+		size += wbItem.size
+	}
+	return size
+}
+
+// return the time uploads may start, which is now unless
+// --vfs-write-back-window is set and it is outside it and less than
+// --vfs-write-back-high-water is waiting
+//
+// call with lock held
+func (wb *WriteBack) _uploadTime() time.Time {
+	now := time.Now()
+	if !wb.opt.WriteBackWindow.IsSet() {
+		return now
+	}
+	if wb.opt.WriteBackHighWater > 0 && wb._queuedSize() > int64(wb.opt.WriteBackHighWater) {
+		return now
+	}
+	return wb.opt.WriteBackWindow.Next(now)
+}
+
 // reset the timer which runs the expiries
 func (wb *WriteBack) _resetTimer() {
 	wbItem := wb._peekItem()
 	if wbItem == nil {
 		wb._stopTimer()
 	} else {
-		if wb.expiry.Equal(wbItem.expiry) {
+		expiry := wbItem.expiry
+		if uploadTime := wb._uploadTime(); uploadTime.After(expiry) {
+			expiry = uploadTime
+		}
+		if wb.expiry.Equal(expiry) {
 			return
 		}
-		wb.expiry = wbItem.expiry
-		dt := max(time.Until(wbItem.expiry), 0)
+		wb.expiry = expiry
+		dt := max(time.Until(expiry), 0)
 		// fs.Debugf(nil, "resetTimer dt=%v", dt)
 		if wb.timer != nil {
 			wb.timer.Stop()
@@ -433,6 +463,14 @@ func (wb *WriteBack) processItems(ctx context.Context) {
 	}
 
 	resetTimer := true
+	if uploadTime := wb._uploadTime(); time.Until(uploadTime) > 0 {
+		if wbItem := wb._peekItem(); wbItem != nil && time.Until(wbItem.expiry) <= 0 {
+			fs.Debugf(wbItem.name, "vfs cache: delaying writeback until %v as outside --vfs-write-back-window", uploadTime)
+		}
+		wb.expiry = time.Time{}
+		wb._resetTimer()
+		return
+	}
 	for wbItem := wb._peekItem(); wbItem != nil && time.Until(wbItem.expiry) <= 0; wbItem = wb._peekItem() {
 		// If reached transfer limit don't restart the timer
 		if wb.uploads >= fs.GetConfig(context.TODO()).Transfers {
@@ -315,6 +315,42 @@ func TestWriteBackAddOK(t *testing.T) {
 	checkNotInLookup(t, wb, wbItem)
 }
 
+// Test uploads wait for --vfs-write-back-window unless over --vfs-write-back-high-water
+func TestWriteBackWindow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := vfscommon.Opt
+	opt.WriteBack = 0
+	// A window which isn't open now
+	now := time.Now()
+	start := time.Duration(now.Hour()+2) % 24 * time.Hour
+	opt.WriteBackWindow = vfscommon.TimeWindow{Start: start, End: (start + time.Hour) % (24 * time.Hour)}
+	opt.WriteBackHighWater = 100
+	wb := New(ctx, &opt)
+
+	pi1 := newPutItem(t)
+	id1 := wb.Add(0, "one", 60, true, pi1.put)
+	time.Sleep(100 * time.Millisecond)
+	pi1.mu.Lock()
+	assert.False(t, pi1.called)
+	pi1.mu.Unlock()
+	wb.mu.Lock()
+	assert.True(t, wb.timer != nil)
+	assert.True(t, time.Until(wb.expiry) > time.Hour)
+	wb.mu.Unlock()
+
+	// Going over the high water mark starts the uploads
+	pi2 := newPutItem(t)
+	id2 := wb.Add(0, "two", 60, true, pi2.put)
+	<-pi1.started
+	<-pi2.started
+	pi1.finish(nil)
+	pi2.finish(nil)
+	waitUntilNoTransfers(t, wb)
+	checkNotInLookup(t, wb, &writeBackItem{id: id1})
+	checkNotInLookup(t, wb, &writeBackItem{id: id2})
+}
+
 // Now test the upload failing and being retried
 func TestWriteBackAddFailRetry(t *testing.T) {
 	wb, cancel := newTestWriteBack(t)
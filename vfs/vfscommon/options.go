@@ -35,6 +35,16 @@ var OptionsInfo = fs.Options{{
 	Default: false,
 	Help:    "Refreshes the directory cache recursively in the background on start",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_dir_cache_persist",
+	Default: false,
+	Help:    "Keep the directory cache in a database so it survives restarts",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_dir_cache_persist_max_age",
+	Default: fs.Duration(24 * time.Hour),
+	Help:    "Max age of directory listings to use from the persistent directory cache",
+	Groups:  "VFS",
 }, {
 	Name:    "poll_interval",
 	Default: fs.Duration(time.Minute),
@@ -55,6 +65,11 @@ var OptionsInfo = fs.Options{{
 	Default: CacheModeOff,
 	Help:    "Cache mode off|minimal|writes|full",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_mode_overrides",
+	Default: "",
+	Help:    "Cache modes to use for some directories instead of --vfs-cache-mode, e.g. \"/media:full,/scratch:off\"",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_cache_poll_interval",
 	Default: fs.Duration(60 * time.Second),
@@ -75,6 +90,11 @@ var OptionsInfo = fs.Options{{
 	Default: fs.SizeSuffix(-1),
 	Help:    "Target minimum free space on the disk containing the cache",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_block_size",
+	Default: fs.SizeSuffix(0),
+	Help:    "Also cache blocks of this size by content so they survive renames and are shared between identical files (0 to disable)",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_read_chunk_size",
 	Default: 128 * fs.Mebi,
@@ -85,6 +105,11 @@ var OptionsInfo = fs.Options{{
 	Default: fs.SizeSuffix(-1),
 	Help:    "If greater than --vfs-read-chunk-size, double the chunk size after each chunk read, until the limit is reached ('off' is unlimited)",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_prefetch_chunks",
+	Default: 0,
+	Help:    "Max number of --vfs-read-chunk-size chunks to fetch ahead of sequential reads in cache mode full (0 to disable)",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_read_chunk_streams",
 	Default: 0,
@@ -130,6 +155,16 @@ var OptionsInfo = fs.Options{{
 	Default: fs.Duration(5 * time.Second),
 	Help:    "Time to writeback files after last use when using cache",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_write_back_window",
+	Default: TimeWindow{},
+	Help:    "Only writeback files during this daily time window, e.g. \"01:00-06:00\" (default always)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_write_back_high_water",
+	Default: fs.SizeSuffix(-1),
+	Help:    "Writeback files outside --vfs-write-back-window if more than this is waiting to be written back",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_read_ahead",
 	Default: 0 * fs.Mebi,
@@ -195,13 +230,20 @@ type Options struct {
 	ChunkSize          fs.SizeSuffix `config:"vfs_read_chunk_size"`       // if > 0 read files in chunks
 	ChunkSizeLimit     fs.SizeSuffix `config:"vfs_read_chunk_size_limit"` // if > ChunkSize double the chunk size after each chunk until reached
 	ChunkStreams       int           `config:"vfs_read_chunk_streams"`    // Number of download streams to use
+	PrefetchChunks     int           `config:"vfs_prefetch_chunks"`       // max number of chunks to fetch ahead of sequential reads
 	CacheMode          CacheMode     `config:"vfs_cache_mode"`
+	CacheModeOverrides string        `config:"vfs_cache_mode_overrides"`
 	CacheMaxAge        fs.Duration   `config:"vfs_cache_max_age"`
 	CacheMaxSize       fs.SizeSuffix `config:"vfs_cache_max_size"`
 	CacheMinFreeSpace  fs.SizeSuffix `config:"vfs_cache_min_free_space"`
 	CachePollInterval  fs.Duration   `config:"vfs_cache_poll_interval"`
+	CacheBlockSize     fs.SizeSuffix `config:"vfs_cache_block_size"` // if > 0 cache blocks of this size by content too
 	CaseInsensitive    bool          `config:"vfs_case_insensitive"`
 	BlockNormDupes     bool          `config:"vfs_block_norm_dupes"`
+	WriteBackWindow    TimeWindow    `config:"vfs_write_back_window"`
+	WriteBackHighWater fs.SizeSuffix `config:"vfs_write_back_high_water"`
+	DirCachePersist    bool          `config:"vfs_dir_cache_persist"`
+	DirPersistMaxAge   fs.Duration   `config:"vfs_dir_cache_persist_max_age"`
 	WriteWait          fs.Duration   `config:"vfs_write_wait"`       // time to wait for in-sequence write
 	ReadWait           fs.Duration   `config:"vfs_read_wait"`        // time to wait for in-sequence read
 	WriteBack          fs.Duration   `config:"vfs_write_back"`       // time to wait before writing back dirty files
@@ -0,0 +1,124 @@
+package vfscommon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeWindow is a command line friendly daily window of time written
+// as "HH:MM-HH:MM"
+//
+// The window spans midnight if End is before Start. The zero value
+// is no window.
+type TimeWindow struct {
+	Start time.Duration // time of day the window opens
+	End   time.Duration // time of day the window closes
+}
+
+// IsSet returns true if the window is set
+func (x TimeWindow) IsSet() bool {
+	return x.Start != x.End
+}
+
+// String turns TimeWindow into a string
+func (x TimeWindow) String() string {
+	if !x.IsSet() {
+		return ""
+	}
+	return formatTimeOfDay(x.Start) + "-" + formatTimeOfDay(x.End)
+}
+
+// formatTimeOfDay formats d as HH:MM
+func formatTimeOfDay(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
+
+// parseTimeOfDay parses HH:MM into the time since midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("bad time of day %q - must be HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Set a TimeWindow
+func (x *TimeWindow) Set(s string) error {
+	if strings.TrimSpace(s) == "" {
+		*x = TimeWindow{}
+		return nil
+	}
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return fmt.Errorf("bad TimeWindow %q - must be HH:MM-HH:MM", s)
+	}
+	var w TimeWindow
+	var err error
+	if w.Start, err = parseTimeOfDay(start); err != nil {
+		return err
+	}
+	if w.End, err = parseTimeOfDay(end); err != nil {
+		return err
+	}
+	if !w.IsSet() {
+		return errors.New("bad TimeWindow - start and end must be different")
+	}
+	*x = w
+	return nil
+}
+
+// Type of the value
+func (x TimeWindow) Type() string {
+	return "TimeWindow"
+}
+
+// MarshalJSON turns the TimeWindow into a JSON string
+func (x TimeWindow) MarshalJSON() ([]byte, error) {
+	return json.Marshal(x.String())
+}
+
+// UnmarshalJSON parses the TimeWindow from a JSON string
+func (x *TimeWindow) UnmarshalJSON(in []byte) error {
+	var s string
+	err := json.Unmarshal(in, &s)
+	if err != nil {
+		return err
+	}
+	return x.Set(s)
+}
+
+// at returns the time of day d on the day of t
+func at(t time.Time, d time.Duration) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, int(d/time.Hour), int(d%time.Hour/time.Minute), 0, 0, t.Location())
+}
+
+// Contains returns true if t is inside the window
+//
+// A window which isn't set contains all times.
+func (x TimeWindow) Contains(t time.Time) bool {
+	if !x.IsSet() {
+		return true
+	}
+	start, end := at(t, x.Start), at(t, x.End)
+	if x.Start < x.End {
+		return !t.Before(start) && t.Before(end)
+	}
+	return !t.Before(start) || t.Before(end)
+}
+
+// Next returns t if it is inside the window or the time the window
+// next opens if not
+func (x TimeWindow) Next(t time.Time) time.Time {
+	if x.Contains(t) {
+		return t
+	}
+	start := at(t, x.Start)
+	if start.Before(t) {
+		start = at(t.AddDate(0, 0, 1), x.Start)
+	}
+	return start
+}
@@ -0,0 +1,83 @@
+package vfscommon
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check it satisfies the interfaces
+var (
+	_ fs.Flagger   = (*TimeWindow)(nil)
+	_ fs.FlaggerNP = TimeWindow{}
+)
+
+func TestTimeWindowSet(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want TimeWindow
+		err  bool
+	}{
+		{"", TimeWindow{}, false},
+		{"01:00-06:00", TimeWindow{Start: time.Hour, End: 6 * time.Hour}, false},
+		{"22:30 - 06:15", TimeWindow{Start: 22*time.Hour + 30*time.Minute, End: 6*time.Hour + 15*time.Minute}, false},
+		{"01:00", TimeWindow{}, true},
+		{"01:00-25:00", TimeWindow{}, true},
+		{"01:00-01:00", TimeWindow{}, true},
+	} {
+		got := TimeWindow{}
+		err := got.Set(test.in)
+		if test.err {
+			require.Error(t, err, test.in)
+		} else {
+			require.NoError(t, err, test.in)
+		}
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestTimeWindowString(t *testing.T) {
+	assert.Equal(t, "", TimeWindow{}.String())
+	assert.Equal(t, "22:30-06:00", TimeWindow{Start: 22*time.Hour + 30*time.Minute, End: 6 * time.Hour}.String())
+}
+
+func TestTimeWindowJSON(t *testing.T) {
+	w := TimeWindow{Start: time.Hour, End: 6 * time.Hour}
+	out, err := json.Marshal(w)
+	require.NoError(t, err)
+	assert.Equal(t, `"01:00-06:00"`, string(out))
+	var got TimeWindow
+	require.NoError(t, json.Unmarshal(out, &got))
+	assert.Equal(t, w, got)
+	assert.Error(t, json.Unmarshal([]byte(`"potato"`), &got))
+}
+
+func TestTimeWindowContainsNext(t *testing.T) {
+	day := func(hour, minute int) time.Time {
+		return time.Date(2020, 1, 2, hour, minute, 0, 0, time.Local)
+	}
+	night := TimeWindow{Start: 22 * time.Hour, End: 6 * time.Hour}
+	morning := TimeWindow{Start: time.Hour, End: 6 * time.Hour}
+	for _, test := range []struct {
+		w        TimeWindow
+		t        time.Time
+		contains bool
+		next     time.Time
+	}{
+		{TimeWindow{}, day(12, 0), true, day(12, 0)},
+		{morning, day(0, 59), false, day(1, 0)},
+		{morning, day(1, 0), true, day(1, 0)},
+		{morning, day(5, 59), true, day(5, 59)},
+		{morning, day(6, 0), false, day(1, 0).AddDate(0, 0, 1)},
+		{night, day(23, 0), true, day(23, 0)},
+		{night, day(3, 0), true, day(3, 0)},
+		{night, day(12, 0), false, day(22, 0)},
+	} {
+		assert.Equal(t, test.contains, test.w.Contains(test.t), "%v %v", test.w, test.t)
+		assert.Equal(t, test.next, test.w.Next(test.t), "%v %v", test.w, test.t)
+	}
+}
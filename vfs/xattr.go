@@ -0,0 +1,119 @@
+// Extended attributes mapped to metadata
+
+package vfs
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// XattrPrefix is the prefix of the extended attributes which are
+// mapped to the metadata of the underlying objects.
+//
+// Extended attributes without this prefix are not supported.
+const XattrPrefix = "user."
+
+// metadataKey returns the metadata key for the extended attribute
+// name or "" if it doesn't map to metadata
+func metadataKey(name string) string {
+	key, ok := strings.CutPrefix(name, XattrPrefix)
+	if !ok {
+		return ""
+	}
+	return key
+}
+
+// readMetadata reads the metadata of node
+//
+// It returns nil if the node has none or the backend doesn't support it.
+func readMetadata(node Node) (fs.Metadata, error) {
+	entry := node.DirEntry()
+	if entry == nil {
+		// No object yet when a file is being written
+		return nil, nil
+	}
+	return fs.GetMetadata(context.TODO(), entry)
+}
+
+// Listxattr returns the names of the extended attributes of node
+func Listxattr(node Node) (names []string, err error) {
+	metadata, err := readMetadata(node)
+	if err != nil {
+		return nil, err
+	}
+	for key := range metadata {
+		names = append(names, XattrPrefix+key)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Getxattr returns the value of the extended attribute name of node
+//
+// It returns ENOATTR if there is no such attribute.
+func Getxattr(node Node, name string) (value []byte, err error) {
+	key := metadataKey(name)
+	if key == "" {
+		return nil, ENOATTR
+	}
+	metadata, err := readMetadata(node)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := metadata[key]
+	if !ok {
+		return nil, ENOATTR
+	}
+	return []byte(v), nil
+}
+
+// Setxattr sets the extended attribute name of node to value
+//
+// If create is set it fails with EEXIST if the attribute exists
+// already and if replace is set it fails with ENOATTR if it doesn't.
+func Setxattr(node Node, name string, value []byte, create, replace bool) error {
+	if node.VFS().Opt.ReadOnly {
+		return EROFS
+	}
+	key := metadataKey(name)
+	if key == "" {
+		return ENOSYS
+	}
+	do, ok := node.DirEntry().(fs.SetMetadataer)
+	if !ok {
+		return ENOSYS
+	}
+	if create || replace {
+		metadata, err := readMetadata(node)
+		if err != nil {
+			return err
+		}
+		_, found := metadata[key]
+		if create && found {
+			return EEXIST
+		}
+		if replace && !found {
+			return ENOATTR
+		}
+	}
+	fs.Debugf(node.Path(), "Setting metadata %q", key)
+	return do.SetMetadata(context.TODO(), fs.Metadata{key: string(value)})
+}
+
+// Removexattr removes the extended attribute name of node
+//
+// Backends can't remove metadata, only set it, so this only checks
+// that the attribute exists and returns ENOSYS if it does.
+func Removexattr(node Node, name string) error {
+	if node.VFS().Opt.ReadOnly {
+		return EROFS
+	}
+	_, err := Getxattr(node, name)
+	if err != nil {
+		return err
+	}
+	return ENOSYS
+}
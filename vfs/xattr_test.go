@@ -0,0 +1,63 @@
+package vfs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXattr(t *testing.T) {
+	r, vfs, file, _ := fileCreate(t, vfscommon.CacheModeOff)
+	features := r.Fremote.Features()
+
+	// Only the user namespace is mapped
+	_, err := Getxattr(file, "security.selinux")
+	assert.Equal(t, ENOATTR, err)
+	assert.Equal(t, ENOSYS, Setxattr(file, "security.selinux", []byte("x"), false, false))
+
+	_, err = Getxattr(file, "user.potato")
+	assert.Equal(t, ENOATTR, err)
+	assert.Equal(t, ENOATTR, Removexattr(file, "user.potato"))
+
+	names, err := Listxattr(file)
+	require.NoError(t, err)
+	if !features.ReadMetadata {
+		assert.Empty(t, names)
+		return
+	}
+	assert.Contains(t, names, "user.mtime")
+	value, err := Getxattr(file, "user.mtime")
+	require.NoError(t, err)
+	assert.Equal(t, file.ModTime().Format(time.RFC3339Nano), string(value))
+
+	// Check the create and replace flags
+	assert.Equal(t, EEXIST, Setxattr(file, "user.mtime", value, true, false))
+	assert.Equal(t, ENOATTR, Setxattr(file, "user.potato", value, false, true))
+
+	if features.WriteMetadata {
+		mtime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC).Format(time.RFC3339Nano)
+		require.NoError(t, Setxattr(file, "user.mtime", []byte(mtime), false, true))
+		value, err = Getxattr(file, "user.mtime")
+		require.NoError(t, err)
+		assert.Equal(t, mtime, string(value))
+	}
+
+	vfs.Opt.ReadOnly = true
+	assert.Equal(t, EROFS, Setxattr(file, "user.mtime", value, false, false))
+	assert.Equal(t, EROFS, Removexattr(file, "user.mtime"))
+}
+
+func TestXattrDir(t *testing.T) {
+	r, _, dir, _ := dirCreate(t)
+
+	names, err := Listxattr(dir)
+	require.NoError(t, err)
+	if r.Fremote.Features().ReadDirMetadata {
+		assert.Contains(t, names, "user.mtime")
+	} else {
+		assert.Empty(t, names)
+	}
+}